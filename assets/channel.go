@@ -22,6 +22,17 @@ const (
 	ChannelRoleUSSD    ChannelRole = "ussd"
 )
 
+// ChannelFeature is an optional messaging capability of a channel
+type ChannelFeature string
+
+// different features that channels can support
+const (
+	ChannelFeatureRichCards    ChannelFeature = "rich_cards"
+	ChannelFeatureCarousels    ChannelFeature = "carousels"
+	ChannelFeatureSuggestions  ChannelFeature = "suggestions"
+	ChannelFeatureQuickReplies ChannelFeature = "quick_replies"
+)
+
 // Channel is something that can send/receive messages.
 //
 //	{
@@ -30,6 +41,7 @@ const (
 //	  "address": "+593979011111",
 //	  "schemes": ["tel"],
 //	  "roles": ["send", "receive"],
+//	  "features": ["rich_cards", "suggestions"],
 //	  "country": "EC"
 //	}
 //
@@ -40,6 +52,7 @@ type Channel interface {
 	Address() string
 	Schemes() []string
 	Roles() []ChannelRole
+	Features() []ChannelFeature
 	Parent() *ChannelReference
 	Country() envs.Country
 	MatchPrefixes() []string
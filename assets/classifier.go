@@ -4,18 +4,23 @@ import (
 	"fmt"
 
 	"github.com/nyaruka/gocommon/uuids"
+
+	"github.com/shopspring/decimal"
 )
 
 // ClassifierUUID is the UUID of an NLU classifier
 type ClassifierUUID uuids.UUID
 
-// Classifier is an NLU classifier.
+// Classifier is an NLU classifier. Classifiers from different vendors report confidence on very different scales, so a
+// classifier can optionally be calibrated with the confidences it reports at evenly spaced percentiles, from lowest to
+// highest. Confidence thresholds in flows are then compared against the percentile of each confidence.
 //
 //	{
 //	  "uuid": "37657cf7-5eab-4286-9cb0-bbf270587bad",
 //	  "name": "Booking",
 //	  "type": "wit",
-//	  "intents": ["book_flight", "book_hotel"]
+//	  "intents": ["book_flight", "book_hotel"],
+//	  "calibration": [0.1, 0.35, 0.5, 0.8, 0.95]
 //	}
 //
 // @asset classifier
@@ -24,6 +29,7 @@ type Classifier interface {
 	Name() string
 	Type() string
 	Intents() []string
+	Calibration() []decimal.Decimal
 }
 
 // ClassifierReference is used to reference a classifier
@@ -0,0 +1,31 @@
+package assets
+
+import "github.com/shopspring/decimal"
+
+// CostType is the type of operation which a cost applies to
+type CostType string
+
+// possible cost types
+const (
+	CostTypeMsg        CostType = "msg"        // each message sent
+	CostTypeWebhook    CostType = "webhook"    // each webhook, resthook, GraphQL or SOAP call
+	CostTypeClassifier CostType = "classifier" // each classifier call
+	CostTypeEmail      CostType = "email"      // each email sent
+	CostTypeTicket     CostType = "ticket"     // each ticket opened
+)
+
+// Cost is the unit cost of an operation that a flow can perform. Message costs can be specific to a channel, and a cost
+// without a channel applies to any channel which doesn't have its own.
+//
+//	{
+//	  "type": "msg",
+//	  "channel": {"uuid": "58e9b092-fe42-4173-876c-ff45a14a24fe", "name": "Vonage"},
+//	  "amount": 0.0075
+//	}
+//
+// @asset cost
+type Cost interface {
+	Type() CostType
+	Channel() *ChannelReference
+	Amount() decimal.Decimal
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/envs"
 )
 
 // FieldUUID is the UUID of a field
@@ -22,7 +23,8 @@ const (
 	FieldTypeState    FieldType = "state"
 )
 
-// Field is a custom contact property.
+// Field is a custom contact property. A field can optionally be tagged with a PII category (`name`, `phone` or `health`)
+// so that environment redaction rules for that category mask its values.
 //
 //	{
 //	  "uuid": "d66a7823-eada-40e5-9a3a-57239d4690bf",
@@ -37,6 +39,7 @@ type Field interface {
 	Key() string
 	Name() string
 	Type() FieldType
+	PII() envs.PIICategory
 }
 
 // FieldReference is a reference to a field
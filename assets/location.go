@@ -1,8 +1,12 @@
 package assets
 
-import "github.com/nyaruka/goflow/envs"
+import (
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/utils"
+)
 
-// LocationHierarchy is a searchable hierarchy of locations.
+// LocationHierarchy is a searchable hierarchy of locations. Locations can optionally have coordinates and a boundary
+// given as a list of `[latitude, longitude]` points, which allow them to be found from coordinates.
 //
 //	{
 //	  "name": "Rwanda",
@@ -11,6 +15,9 @@ import "github.com/nyaruka/goflow/envs"
 //	    {
 //	      "name": "Kigali City",
 //	      "aliases": ["Kigali", "Kigari"],
+//	      "latitude": -1.9441,
+//	      "longitude": 30.0619,
+//	      "boundary": [[-1.85, 29.95], [-1.85, 30.25], [-2.1, 30.25], [-2.1, 29.95]],
 //	      "children": [
 //	        {
 //	          "name": "Gasabo",
@@ -41,4 +48,6 @@ import "github.com/nyaruka/goflow/envs"
 type LocationHierarchy interface {
 	FindByPath(path envs.LocationPath) *envs.Location
 	FindByName(name string, level envs.LocationLevel, parent *envs.Location) []*envs.Location
+	MatchByName(name string, level envs.LocationLevel, parent *envs.Location) []*envs.LocationMatch
+	FindByCoordinates(coords utils.Coordinates) *envs.Location
 }
@@ -0,0 +1,21 @@
+package assets
+
+// LookupTable is a table of rows of keyed data, e.g. a price list or a directory of clinics.
+//
+//	{
+//	  "key": "clinics",
+//	  "name": "Clinics",
+//	  "columns": ["district", "name", "phone"],
+//	  "rows": [
+//	    {"district": "Gasabo", "name": "Kacyiru Health Center", "phone": "+250788111111"},
+//	    {"district": "Nyarugenge", "name": "Muhima Hospital", "phone": "+250788222222"}
+//	  ]
+//	}
+//
+// @asset lookup_table
+type LookupTable interface {
+	Key() string
+	Name() string
+	Columns() []string
+	Rows() []map[string]string
+}
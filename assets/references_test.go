@@ -141,8 +141,21 @@ func TestReferences(t *testing.T) {
 	assert.False(t, topicRef.Variable())
 	assert.NoError(t, utils.Validate(topicRef))
 
-	// topic references must always be concrete
-	assert.EqualError(t, utils.Validate(assets.NewTopicReference("", "Weather")), "field 'uuid' is required")
+	// topic references can be concrete or a name match template
+	assert.NoError(t, utils.Validate(assets.NewVariableTopicReference("@contact.fields.issue")))
+	assert.True(t, assets.NewVariableTopicReference("@contact.fields.issue").Variable())
+
+	// but they can't be neither or both of those things
+	assert.EqualError(t,
+		utils.Validate(assets.NewTopicReference("", "Weather")),
+		"field 'uuid' is mutually exclusive with 'name_match', field 'name_match' is mutually exclusive with 'uuid'",
+	)
+	assert.EqualError(t,
+		utils.Validate(&assets.TopicReference{
+			UUID: "61602f3e-f603-4c70-8a8f-c477505bf4bf",
+			Name: "Weather", NameMatch: "@contact.fields.issue"}),
+		"field 'uuid' is mutually exclusive with 'name_match', field 'name_match' is mutually exclusive with 'uuid'",
+	)
 
 	userRef := assets.NewUserReference("bob@nyaruka.com", "Bob")
 	assert.Equal(t, "user", userRef.Type())
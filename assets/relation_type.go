@@ -0,0 +1,51 @@
+package assets
+
+import (
+	"fmt"
+)
+
+// RelationType is a type of relationship which a contact can have to another contact, e.g. their caregiver or the
+// head of their household.
+//
+//	{
+//	  "key": "caregiver",
+//	  "name": "Caregiver"
+//	}
+//
+// @asset relation_type
+type RelationType interface {
+	Key() string
+	Name() string
+}
+
+// RelationTypeReference is a reference to a relation type
+type RelationTypeReference struct {
+	Key  string `json:"key" validate:"required"`
+	Name string `json:"name"`
+}
+
+// NewRelationTypeReference creates a new relation type reference with the given key and name
+func NewRelationTypeReference(key string, name string) *RelationTypeReference {
+	return &RelationTypeReference{Key: key, Name: name}
+}
+
+// Type returns the name of the asset type
+func (r *RelationTypeReference) Type() string {
+	return "relation_type"
+}
+
+// Identity returns the unique identity of the asset
+func (r *RelationTypeReference) Identity() string {
+	return r.Key
+}
+
+// Variable returns whether this a variable (vs concrete) reference
+func (r *RelationTypeReference) Variable() bool {
+	return false
+}
+
+func (r *RelationTypeReference) String() string {
+	return fmt.Sprintf("%s[key=%s,name=%s]", r.Type(), r.Identity(), r.Name)
+}
+
+var _ Reference = (*RelationTypeReference)(nil)
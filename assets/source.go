@@ -4,6 +4,7 @@ package assets
 type Source interface {
 	Channels() ([]Channel, error)
 	Classifiers() ([]Classifier, error)
+	Costs() ([]Cost, error)
 	Fields() ([]Field, error)
 	FlowByUUID(FlowUUID) (Flow, error)
 	FlowByName(string) (Flow, error)
@@ -11,9 +12,19 @@ type Source interface {
 	Groups() ([]Group, error)
 	Labels() ([]Label, error)
 	Locations() ([]LocationHierarchy, error)
+	LookupTables() ([]LookupTable, error)
+	RelationTypes() ([]RelationType, error)
 	Resthooks() ([]Resthook, error)
 	Templates() ([]Template, error)
 	Ticketers() ([]Ticketer, error)
 	Topics() ([]Topic, error)
 	Users() ([]User, error)
 }
+
+// VersionedSource is a source which can identify the version of the assets it provides, e.g. a hash of when an org's
+// assets were last changed, so that anything constructed from them can be reused until they change
+type VersionedSource interface {
+	Source
+
+	Version() string
+}
@@ -13,6 +13,7 @@ type Channel struct {
 	Address_            string                   `json:"address"`
 	Schemes_            []string                 `json:"schemes" validate:"min=1"`
 	Roles_              []assets.ChannelRole     `json:"roles" validate:"min=1,dive,eq=send|eq=receive|eq=call|eq=answer|eq=ussd"`
+	Features_           []assets.ChannelFeature  `json:"features,omitempty" validate:"dive,eq=rich_cards|eq=carousels|eq=suggestions|eq=quick_replies"`
 	Parent_             *assets.ChannelReference `json:"parent" validate:"omitempty,dive"`
 	Country_            envs.Country             `json:"country,omitempty"`
 	MatchPrefixes_      []string                 `json:"match_prefixes,omitempty"`
@@ -62,6 +63,9 @@ func (c *Channel) Schemes() []string { return c.Schemes_ }
 // Roles returns the roles of this channel
 func (c *Channel) Roles() []assets.ChannelRole { return c.Roles_ }
 
+// Features returns the optional messaging features supported by this channel
+func (c *Channel) Features() []assets.ChannelFeature { return c.Features_ }
+
 // Parent returns a reference to this channel's parent (if any)
 func (c *Channel) Parent() *assets.ChannelReference { return c.Parent_ }
 
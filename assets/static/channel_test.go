@@ -25,6 +25,7 @@ func TestChannel(t *testing.T) {
 	assert.Equal(t, "+234151", channel.Address())
 	assert.Equal(t, []string{"tel"}, channel.Schemes())
 	assert.Equal(t, []assets.ChannelRole{assets.ChannelRoleSend}, channel.Roles())
+	assert.Nil(t, channel.Features())
 	assert.Nil(t, channel.Parent())
 	assert.Equal(t, envs.NilCountry, channel.Country())
 	assert.Nil(t, channel.MatchPrefixes())
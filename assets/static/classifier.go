@@ -2,14 +2,17 @@ package static
 
 import (
 	"github.com/nyaruka/goflow/assets"
+
+	"github.com/shopspring/decimal"
 )
 
 // Classifier is a JSON serializable implementation of a classifier asset
 type Classifier struct {
-	UUID_    assets.ClassifierUUID `json:"uuid" validate:"required,uuid"`
-	Name_    string                `json:"name"`
-	Type_    string                `json:"type"`
-	Intents_ []string              `json:"intents"`
+	UUID_        assets.ClassifierUUID `json:"uuid" validate:"required,uuid"`
+	Name_        string                `json:"name"`
+	Type_        string                `json:"type"`
+	Intents_     []string              `json:"intents"`
+	Calibration_ []decimal.Decimal     `json:"calibration,omitempty"`
 }
 
 // NewClassifier creates a new classifier
@@ -33,3 +36,6 @@ func (c *Classifier) Type() string { return c.Type_ }
 
 // Intents returns the intents of this classifier
 func (c *Classifier) Intents() []string { return c.Intents_ }
+
+// Calibration returns the confidences of this classifier at evenly spaced percentiles (if any)
+func (c *Classifier) Calibration() []decimal.Decimal { return c.Calibration_ }
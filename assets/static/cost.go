@@ -0,0 +1,31 @@
+package static
+
+import (
+	"github.com/nyaruka/goflow/assets"
+	"github.com/shopspring/decimal"
+)
+
+// Cost is a JSON serializable implementation of a cost asset
+type Cost struct {
+	Type_    assets.CostType          `json:"type" validate:"required,eq=msg|eq=webhook|eq=classifier|eq=email|eq=ticket"`
+	Channel_ *assets.ChannelReference `json:"channel,omitempty" validate:"omitempty,dive"`
+	Amount_  decimal.Decimal          `json:"amount"`
+}
+
+// NewCost creates a new cost
+func NewCost(type_ assets.CostType, channel *assets.ChannelReference, amount decimal.Decimal) assets.Cost {
+	return &Cost{
+		Type_:    type_,
+		Channel_: channel,
+		Amount_:  amount,
+	}
+}
+
+// Type returns the type of operation this cost applies to
+func (c *Cost) Type() assets.CostType { return c.Type_ }
+
+// Channel returns the channel this cost is specific to, if any
+func (c *Cost) Channel() *assets.ChannelReference { return c.Channel_ }
+
+// Amount returns the amount of this cost
+func (c *Cost) Amount() decimal.Decimal { return c.Amount_ }
@@ -0,0 +1,18 @@
+package static_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCost(t *testing.T) {
+	channel := assets.NewChannelReference("58e9b092-fe42-4173-876c-ff45a14a24fe", "Vonage")
+	cost := static.NewCost(assets.CostTypeMsg, channel, decimal.RequireFromString("0.0075"))
+	assert.Equal(t, assets.CostTypeMsg, cost.Type())
+	assert.Equal(t, channel, cost.Channel())
+	assert.Equal(t, "0.0075", cost.Amount().String())
+}
@@ -2,6 +2,7 @@ package static
 
 import (
 	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
 )
 
 // Field is a JSON serializable implementation of a field asset
@@ -10,6 +11,7 @@ type Field struct {
 	Key_  string           `json:"key" validate:"required"`
 	Name_ string           `json:"name"`
 	Type_ assets.FieldType `json:"type" validate:"required"`
+	PII_  envs.PIICategory `json:"pii,omitempty" validate:"omitempty,pii_category"`
 }
 
 // NewField creates a new field from the passed in key, name and type
@@ -28,3 +30,6 @@ func (f *Field) Name() string { return f.Name_ }
 
 // Type returns the value type of the field
 func (f *Field) Type() assets.FieldType { return f.Type_ }
+
+// PII returns the PII category of the field (if any)
+func (f *Field) PII() envs.PIICategory { return f.PII_ }
@@ -0,0 +1,35 @@
+package static
+
+import (
+	"github.com/nyaruka/goflow/assets"
+)
+
+// LookupTable is a JSON serializable implementation of a lookup table asset
+type LookupTable struct {
+	Key_     string              `json:"key" validate:"required"`
+	Name_    string              `json:"name"`
+	Columns_ []string            `json:"columns" validate:"required,min=1"`
+	Rows_    []map[string]string `json:"rows"`
+}
+
+// NewLookupTable creates a new lookup table
+func NewLookupTable(key, name string, columns []string, rows []map[string]string) assets.LookupTable {
+	return &LookupTable{
+		Key_:     key,
+		Name_:    name,
+		Columns_: columns,
+		Rows_:    rows,
+	}
+}
+
+// Key returns the key of this lookup table
+func (t *LookupTable) Key() string { return t.Key_ }
+
+// Name returns the name of this lookup table
+func (t *LookupTable) Name() string { return t.Name_ }
+
+// Columns returns the names of the columns of this lookup table
+func (t *LookupTable) Columns() []string { return t.Columns_ }
+
+// Rows returns the rows of this lookup table
+func (t *LookupTable) Rows() []map[string]string { return t.Rows_ }
@@ -0,0 +1,20 @@
+package static_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupTable(t *testing.T) {
+	table := static.NewLookupTable("prices", "Prices", []string{"item", "price"}, []map[string]string{
+		{"item": "Maize", "price": "350"},
+		{"item": "Beans", "price": "800"},
+	})
+	assert.Equal(t, "prices", table.Key())
+	assert.Equal(t, "Prices", table.Name())
+	assert.Equal(t, []string{"item", "price"}, table.Columns())
+	assert.Equal(t, 2, len(table.Rows()))
+	assert.Equal(t, "800", table.Rows()[1]["price"])
+}
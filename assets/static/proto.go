@@ -0,0 +1,389 @@
+package static
+
+import (
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// field numbers and enum values from assets.proto
+const (
+	protoAssetsFlows    protowire.Number = 1
+	protoAssetsFields   protowire.Number = 2
+	protoAssetsGroups   protowire.Number = 3
+	protoAssetsChannels protowire.Number = 4
+
+	protoFlowUUID       protowire.Number = 1
+	protoFlowName       protowire.Number = 2
+	protoFlowDefinition protowire.Number = 3
+
+	protoFieldUUID protowire.Number = 1
+	protoFieldKey  protowire.Number = 2
+	protoFieldName protowire.Number = 3
+	protoFieldType protowire.Number = 4
+
+	protoGroupUUID  protowire.Number = 1
+	protoGroupName  protowire.Number = 2
+	protoGroupQuery protowire.Number = 3
+
+	protoChannelRefUUID protowire.Number = 1
+	protoChannelRefName protowire.Number = 2
+
+	protoChannelUUID               protowire.Number = 1
+	protoChannelName               protowire.Number = 2
+	protoChannelAddress            protowire.Number = 3
+	protoChannelSchemes            protowire.Number = 4
+	protoChannelRoles              protowire.Number = 5
+	protoChannelFeatures           protowire.Number = 6
+	protoChannelParent             protowire.Number = 7
+	protoChannelCountry            protowire.Number = 8
+	protoChannelMatchPrefixes      protowire.Number = 9
+	protoChannelAllowInternational protowire.Number = 10
+)
+
+var protoFieldTypeValues = []assets.FieldType{"", assets.FieldTypeText, assets.FieldTypeNumber, assets.FieldTypeDatetime, assets.FieldTypeState, assets.FieldTypeDistrict, assets.FieldTypeWard}
+var protoChannelRoleValues = []assets.ChannelRole{"", assets.ChannelRoleSend, assets.ChannelRoleReceive, assets.ChannelRoleCall, assets.ChannelRoleAnswer, assets.ChannelRoleUSSD}
+
+// ProtoAssets are the assets which can be exchanged as protocol buffers using the schema in assets.proto
+type ProtoAssets struct {
+	Flows    []assets.Flow
+	Fields   []assets.Field
+	Groups   []assets.Group
+	Channels []assets.Channel
+}
+
+// MarshalProto marshals the given assets as protocol buffers
+func MarshalProto(a *ProtoAssets) []byte {
+	var b []byte
+	for _, f := range a.Flows {
+		b = appendMessage(b, protoAssetsFlows, marshalProtoFlow(f))
+	}
+	for _, f := range a.Fields {
+		b = appendMessage(b, protoAssetsFields, marshalProtoField(f))
+	}
+	for _, g := range a.Groups {
+		b = appendMessage(b, protoAssetsGroups, marshalProtoGroup(g))
+	}
+	for _, c := range a.Channels {
+		b = appendMessage(b, protoAssetsChannels, marshalProtoChannel(c))
+	}
+	return b
+}
+
+// NewProtoSource creates a new static source from the given assets marshaled as protocol buffers
+func NewProtoSource(data []byte) (*StaticSource, error) {
+	s := &StaticSource{}
+
+	err := readProto(data, func(num protowire.Number, v protoValue) error {
+		var err error
+		switch num {
+		case protoAssetsFlows:
+			f := &Flow{}
+			s.s.Flows = append(s.s.Flows, f)
+			err = v.message(f.readProto)
+		case protoAssetsFields:
+			f := &Field{}
+			s.s.Fields = append(s.s.Fields, f)
+			err = v.message(f.readProto)
+		case protoAssetsGroups:
+			g := &Group{}
+			s.s.Groups = append(s.s.Groups, g)
+			err = v.message(g.readProto)
+		case protoAssetsChannels:
+			c := &Channel{}
+			s.s.Channels = append(s.s.Channels, c)
+			err = v.message(c.readProto)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read assets")
+	}
+
+	if err := utils.Validate(&s.s); err != nil {
+		return nil, errors.Wrap(err, "unable to read assets")
+	}
+	return s, nil
+}
+
+func marshalProtoFlow(f assets.Flow) []byte {
+	var b []byte
+	b = appendString(b, protoFlowUUID, string(f.UUID()))
+	b = appendString(b, protoFlowName, f.Name())
+	if len(f.Definition()) > 0 {
+		b = protowire.AppendTag(b, protoFlowDefinition, protowire.BytesType)
+		b = protowire.AppendBytes(b, f.Definition())
+	}
+	return b
+}
+
+func (f *Flow) readProto(num protowire.Number, v protoValue) error {
+	var err error
+	switch num {
+	case protoFlowUUID:
+		err = v.string((*string)(&f.UUID_))
+	case protoFlowName:
+		err = v.string(&f.Name_)
+	case protoFlowDefinition:
+		var definition []byte
+		if definition, err = v.bytes(); err == nil {
+			f.Definition_ = append([]byte(nil), definition...)
+		}
+	}
+	return err
+}
+
+func marshalProtoField(f assets.Field) []byte {
+	var b []byte
+	b = appendString(b, protoFieldUUID, string(f.UUID()))
+	b = appendString(b, protoFieldKey, f.Key())
+	b = appendString(b, protoFieldName, f.Name())
+	b = appendEnum(b, protoFieldType, protoEnum(protoFieldTypeValues, f.Type()))
+	return b
+}
+
+func (f *Field) readProto(num protowire.Number, v protoValue) error {
+	var err error
+	switch num {
+	case protoFieldUUID:
+		err = v.string((*string)(&f.UUID_))
+	case protoFieldKey:
+		err = v.string(&f.Key_)
+	case protoFieldName:
+		err = v.string(&f.Name_)
+	case protoFieldType:
+		err = v.varints(func(n uint64) error {
+			fieldType, err := fromProtoEnum(protoFieldTypeValues, n, "field type")
+			f.Type_ = fieldType
+			return err
+		})
+	}
+	return err
+}
+
+func marshalProtoGroup(g assets.Group) []byte {
+	var b []byte
+	b = appendString(b, protoGroupUUID, string(g.UUID()))
+	b = appendString(b, protoGroupName, g.Name())
+	b = appendString(b, protoGroupQuery, g.Query())
+	return b
+}
+
+func (g *Group) readProto(num protowire.Number, v protoValue) error {
+	var err error
+	switch num {
+	case protoGroupUUID:
+		err = v.string((*string)(&g.UUID_))
+	case protoGroupName:
+		err = v.string(&g.Name_)
+	case protoGroupQuery:
+		err = v.string(&g.Query_)
+	}
+	return err
+}
+
+func marshalProtoChannel(c assets.Channel) []byte {
+	var b []byte
+	b = appendString(b, protoChannelUUID, string(c.UUID()))
+	b = appendString(b, protoChannelName, c.Name())
+	b = appendString(b, protoChannelAddress, c.Address())
+	for _, s := range c.Schemes() {
+		b = appendRepeatedString(b, protoChannelSchemes, s)
+	}
+	if len(c.Roles()) > 0 {
+		var packed []byte
+		for _, r := range c.Roles() {
+			packed = protowire.AppendVarint(packed, protoEnum(protoChannelRoleValues, r))
+		}
+		b = appendMessage(b, protoChannelRoles, packed)
+	}
+	for _, f := range c.Features() {
+		b = appendRepeatedString(b, protoChannelFeatures, string(f))
+	}
+	if p := c.Parent(); p != nil {
+		var parent []byte
+		parent = appendString(parent, protoChannelRefUUID, string(p.UUID))
+		parent = appendString(parent, protoChannelRefName, p.Name)
+		b = appendMessage(b, protoChannelParent, parent)
+	}
+	b = appendString(b, protoChannelCountry, string(c.Country()))
+	for _, p := range c.MatchPrefixes() {
+		b = appendRepeatedString(b, protoChannelMatchPrefixes, p)
+	}
+	if c.AllowInternational() {
+		b = protowire.AppendTag(b, protoChannelAllowInternational, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	return b
+}
+
+func (c *Channel) readProto(num protowire.Number, v protoValue) error {
+	var err error
+	var s string
+
+	switch num {
+	case protoChannelUUID:
+		err = v.string((*string)(&c.UUID_))
+	case protoChannelName:
+		err = v.string(&c.Name_)
+	case protoChannelAddress:
+		err = v.string(&c.Address_)
+	case protoChannelSchemes:
+		if err = v.string(&s); err == nil {
+			c.Schemes_ = append(c.Schemes_, s)
+		}
+	case protoChannelRoles:
+		err = v.varints(func(n uint64) error {
+			role, err := fromProtoEnum(protoChannelRoleValues, n, "channel role")
+			c.Roles_ = append(c.Roles_, role)
+			return err
+		})
+	case protoChannelFeatures:
+		if err = v.string(&s); err == nil {
+			c.Features_ = append(c.Features_, assets.ChannelFeature(s))
+		}
+	case protoChannelParent:
+		c.Parent_ = &assets.ChannelReference{}
+		err = v.message(func(num protowire.Number, v protoValue) error {
+			switch num {
+			case protoChannelRefUUID:
+				return v.string((*string)(&c.Parent_.UUID))
+			case protoChannelRefName:
+				return v.string(&c.Parent_.Name)
+			}
+			return nil
+		})
+	case protoChannelCountry:
+		err = v.string((*string)(&c.Country_))
+	case protoChannelMatchPrefixes:
+		if err = v.string(&s); err == nil {
+			c.MatchPrefixes_ = append(c.MatchPrefixes_, s)
+		}
+	case protoChannelAllowInternational:
+		err = v.varints(func(n uint64) error {
+			c.AllowInternational_ = n != 0
+			return nil
+		})
+	}
+	return err
+}
+
+// proto3 doesn't write fields with default values
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return appendRepeatedString(b, num, s)
+}
+
+// items of repeated fields are written even if they're empty
+func appendRepeatedString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendEnum(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// gets the proto enum value of the given value, which is unspecified if it isn't known
+func protoEnum[T comparable](values []T, v T) uint64 {
+	for i := range values {
+		if values[i] == v {
+			return uint64(i)
+		}
+	}
+	return 0
+}
+
+func fromProtoEnum[T any](values []T, n uint64, name string) (T, error) {
+	if n == 0 || n >= uint64(len(values)) {
+		var zero T
+		return zero, errors.Errorf("invalid %s %d", name, n)
+	}
+	return values[n], nil
+}
+
+// a value read from a protocol buffer
+type protoValue struct {
+	typ  protowire.Type
+	data []byte
+}
+
+// reads each field of the given protocol buffer message, skipping over the values of unknown fields
+func readProto(data []byte, fn func(protowire.Number, protoValue) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		n = protowire.ConsumeFieldValue(num, typ, data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err := fn(num, protoValue{typ: typ, data: data[:n]}); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func (v protoValue) bytes() ([]byte, error) {
+	if v.typ != protowire.BytesType {
+		return nil, errors.Errorf("expected length delimited value, got wire type %d", v.typ)
+	}
+	b, _ := protowire.ConsumeBytes(v.data)
+	return b, nil
+}
+
+func (v protoValue) string(s *string) error {
+	b, err := v.bytes()
+	*s = string(b)
+	return err
+}
+
+func (v protoValue) message(fn func(protowire.Number, protoValue) error) error {
+	b, err := v.bytes()
+	if err != nil {
+		return err
+	}
+	return readProto(b, fn)
+}
+
+// reads a varint value, or a packed list of them
+func (v protoValue) varints(fn func(uint64) error) error {
+	if v.typ == protowire.VarintType {
+		n, _ := protowire.ConsumeVarint(v.data)
+		return fn(n)
+	}
+
+	packed, err := v.bytes()
+	if err != nil {
+		return err
+	}
+	for len(packed) > 0 {
+		n, m := protowire.ConsumeVarint(packed)
+		if m < 0 {
+			return protowire.ParseError(m)
+		}
+		if err := fn(n); err != nil {
+			return err
+		}
+		packed = packed[m:]
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package static_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProto(t *testing.T) {
+	parent := assets.NewChannelReference("fd47b7b9-8a94-4e2a-9d8b-1ac5e8fe05e0", "Android")
+
+	pa := &static.ProtoAssets{
+		Flows: []assets.Flow{
+			static.NewFlow("76f0a02f-3b75-4b86-9064-e9195e1b3a02", "Empty", json.RawMessage(`{"uuid": "76f0a02f-3b75-4b86-9064-e9195e1b3a02", "name": "Empty", "spec_version": "13.2.0", "language": "eng", "type": "messaging", "nodes": []}`)),
+		},
+		Fields: []assets.Field{
+			static.NewField("d66a7823-eada-40e5-9a3a-57239d4690bf", "gender", "Gender", assets.FieldTypeText),
+			static.NewField("f1b5aea6-6586-41c7-9020-1a6326cc6565", "age", "Age", assets.FieldTypeNumber),
+			static.NewField("3c3e3a9e-7ec2-4cd5-a4b3-5a9a6ac1b2f0", "district", "District", assets.FieldTypeDistrict),
+		},
+		Groups: []assets.Group{
+			static.NewGroup("2aad21f6-30b7-42c5-bd7f-1b720c154817", "Survey Audience", ""),
+			static.NewGroup("5a4ee6a7-e3a3-4b8e-b8d3-8f0a3e64c2bd", "Adults", "age > 18"),
+		},
+		Channels: []assets.Channel{
+			static.NewTelChannel("57f1078f-88aa-46f4-a59a-948a5739c03d", "Nexmo", "+12345671111", []assets.ChannelRole{assets.ChannelRoleSend, assets.ChannelRoleCall}, parent, envs.Country("US"), []string{"+1", ""}, false),
+			static.NewChannel("8e21f093-99aa-413b-b55b-758b54308fcb", "Twitter", "nyaruka", []string{"twitter", "twitterid"}, []assets.ChannelRole{assets.ChannelRoleSend, assets.ChannelRoleReceive}, nil),
+		},
+	}
+
+	data := static.MarshalProto(pa)
+
+	// protocol buffers are more compact than JSON
+	asJSON, _ := json.Marshal(map[string]any{"flows": pa.Flows, "fields": pa.Fields, "groups": pa.Groups, "channels": pa.Channels})
+	assert.Less(t, len(data), len(asJSON))
+
+	src, err := static.NewProtoSource(data)
+	require.NoError(t, err)
+
+	flow, err := src.FlowByUUID("76f0a02f-3b75-4b86-9064-e9195e1b3a02")
+	assert.NoError(t, err)
+	assert.Equal(t, "Empty", flow.Name())
+	assert.Equal(t, pa.Flows[0].Definition(), flow.Definition())
+
+	fields, err := src.Fields()
+	assert.NoError(t, err)
+	assert.Equal(t, pa.Fields, fields)
+
+	groups, err := src.Groups()
+	assert.NoError(t, err)
+	assert.Equal(t, pa.Groups, groups)
+
+	channels, err := src.Channels()
+	assert.NoError(t, err)
+	assert.Equal(t, pa.Channels, channels)
+
+	// and marshaling again gives the same bytes
+	assert.Equal(t, data, static.MarshalProto(&static.ProtoAssets{Flows: []assets.Flow{flow}, Fields: fields, Groups: groups, Channels: channels}))
+
+	// check the wire format of a simple message, i.e. Assets{groups: [Group{uuid, name}]}
+	data = static.MarshalProto(&static.ProtoAssets{Groups: []assets.Group{static.NewGroup("2aad21f6-30b7-42c5-bd7f-1b720c154817", "Spam", "")}})
+	assert.Equal(t, append(append([]byte{0x1a, 0x2c, 0x0a, 0x24}, "2aad21f6-30b7-42c5-bd7f-1b720c154817"...), 0x12, 0x04, 'S', 'p', 'a', 'm'), data)
+
+	// unknown fields are ignored so that the schema can be extended
+	data = append([]byte{0x28, 0x07}, data...)
+	src, err = static.NewProtoSource(data)
+	assert.NoError(t, err)
+	groups, _ = src.Groups()
+	assert.Len(t, groups, 1)
+
+	// empty data is an empty set of assets
+	src, err = static.NewProtoSource(nil)
+	assert.NoError(t, err)
+	fields, _ = src.Fields()
+	assert.Len(t, fields, 0)
+
+	_, err = static.NewProtoSource([]byte{0x1a, 0x2c, 0x0a})
+	assert.EqualError(t, err, "unable to read assets: unexpected EOF")
+
+	_, err = static.NewProtoSource([]byte{0x12, 0x04, 0x0a, 0x00, 0x20, 0x09})
+	assert.EqualError(t, err, "unable to read assets: invalid field type 9")
+
+	_, err = static.NewProtoSource([]byte{0x1a, 0x02, 0x08, 0x01})
+	assert.EqualError(t, err, "unable to read assets: expected length delimited value, got wire type 0")
+
+	// assets are validated like they are when read from JSON
+	_, err = static.NewProtoSource(static.MarshalProto(&static.ProtoAssets{Groups: []assets.Group{static.NewGroup("xyz", "Spam", "")}}))
+	assert.EqualError(t, err, "unable to read assets: field 'uuid' must be a valid UUID4")
+}
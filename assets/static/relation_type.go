@@ -0,0 +1,22 @@
+package static
+
+import (
+	"github.com/nyaruka/goflow/assets"
+)
+
+// RelationType is a JSON serializable implementation of a relation type asset
+type RelationType struct {
+	Key_  string `json:"key" validate:"required"`
+	Name_ string `json:"name"`
+}
+
+// NewRelationType creates a new relation type
+func NewRelationType(key, name string) assets.RelationType {
+	return &RelationType{Key_: key, Name_: name}
+}
+
+// Key returns the key of this relation type
+func (t *RelationType) Key() string { return t.Key_ }
+
+// Name returns the name of this relation type
+func (t *RelationType) Name() string { return t.Name_ }
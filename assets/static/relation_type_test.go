@@ -0,0 +1,15 @@
+package static_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/assets/static"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelationType(t *testing.T) {
+	relationType := static.NewRelationType("caregiver", "Caregiver")
+	assert.Equal(t, "caregiver", relationType.Key())
+	assert.Equal(t, "Caregiver", relationType.Name())
+}
@@ -1,34 +1,37 @@
-// Package static is an implementation of Source which loads assets from a static JSON file.
+// Package static is an implementation of Source which loads assets from a static JSON or YAML file, or from protocol buffers.
 package static
 
 import (
 	"encoding/json"
-	"os"
 	"strings"
 
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/utils"
+	"github.com/nyaruka/goflow/utils/yamlx"
 
 	"github.com/pkg/errors"
 )
 
-// StaticSource is an asset source which loads assets from a static JSON file
+// StaticSource is an asset source which loads assets from a static JSON or YAML file
 type StaticSource struct {
 	s struct {
-		Channels    []*Channel                `json:"channels" validate:"omitempty,dive"`
-		Classifiers []*Classifier             `json:"classifiers" validate:"omitempty,dive"`
-		Fields      []*Field                  `json:"fields" validate:"omitempty,dive"`
-		Flows       []*Flow                   `json:"flows" validate:"omitempty,dive"`
-		Globals     []*Global                 `json:"globals" validate:"omitempty,dive"`
-		Groups      []*Group                  `json:"groups" validate:"omitempty,dive"`
-		Labels      []*Label                  `json:"labels" validate:"omitempty,dive"`
-		Locations   []*envs.LocationHierarchy `json:"locations"`
-		Resthooks   []*Resthook               `json:"resthooks" validate:"omitempty,dive"`
-		Templates   []*Template               `json:"templates" validate:"omitempty,dive"`
-		Ticketers   []*Ticketer               `json:"ticketers" validate:"omitempty,dive"`
-		Topics      []*Topic                  `json:"topics" validate:"omitempty,dive"`
-		Users       []*User                   `json:"users" validate:"omitempty,dive"`
+		Channels      []*Channel                `json:"channels" validate:"omitempty,dive"`
+		Classifiers   []*Classifier             `json:"classifiers" validate:"omitempty,dive"`
+		Costs         []*Cost                   `json:"costs" validate:"omitempty,dive"`
+		Fields        []*Field                  `json:"fields" validate:"omitempty,dive"`
+		Flows         []*Flow                   `json:"flows" validate:"omitempty,dive"`
+		Globals       []*Global                 `json:"globals" validate:"omitempty,dive"`
+		Groups        []*Group                  `json:"groups" validate:"omitempty,dive"`
+		Labels        []*Label                  `json:"labels" validate:"omitempty,dive"`
+		Locations     []*envs.LocationHierarchy `json:"locations"`
+		LookupTables  []*LookupTable            `json:"lookup_tables" validate:"omitempty,dive"`
+		RelationTypes []*RelationType           `json:"relation_types" validate:"omitempty,dive"`
+		Resthooks     []*Resthook               `json:"resthooks" validate:"omitempty,dive"`
+		Templates     []*Template               `json:"templates" validate:"omitempty,dive"`
+		Ticketers     []*Ticketer               `json:"ticketers" validate:"omitempty,dive"`
+		Topics        []*Topic                  `json:"topics" validate:"omitempty,dive"`
+		Users         []*User                   `json:"users" validate:"omitempty,dive"`
 	}
 }
 
@@ -46,9 +49,18 @@ func NewSource(data json.RawMessage) (*StaticSource, error) {
 	return s, nil
 }
 
-// LoadSource loads a new static source from the given JSON file
+// NewYAMLSource creates a new static source from the given YAML
+func NewYAMLSource(data []byte) (*StaticSource, error) {
+	converted, err := yamlx.ToJSON(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read assets")
+	}
+	return NewSource(converted)
+}
+
+// LoadSource loads a new static source from the given JSON file, or YAML file if it has a .yaml or .yml extension
 func LoadSource(path string) (*StaticSource, error) {
-	data, err := os.ReadFile(path)
+	data, err := yamlx.ReadFile(path)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error reading file '%s'", path)
 	}
@@ -73,6 +85,15 @@ func (s *StaticSource) Classifiers() ([]assets.Classifier, error) {
 	return set, nil
 }
 
+// Costs returns all cost assets
+func (s *StaticSource) Costs() ([]assets.Cost, error) {
+	set := make([]assets.Cost, len(s.s.Costs))
+	for i := range s.s.Costs {
+		set[i] = s.s.Costs[i]
+	}
+	return set, nil
+}
+
 // Fields returns all field assets
 func (s *StaticSource) Fields() ([]assets.Field, error) {
 	set := make([]assets.Field, len(s.s.Fields))
@@ -138,6 +159,24 @@ func (s *StaticSource) Locations() ([]assets.LocationHierarchy, error) {
 	return set, nil
 }
 
+// LookupTables returns all lookup table assets
+func (s *StaticSource) LookupTables() ([]assets.LookupTable, error) {
+	set := make([]assets.LookupTable, len(s.s.LookupTables))
+	for i := range s.s.LookupTables {
+		set[i] = s.s.LookupTables[i]
+	}
+	return set, nil
+}
+
+// RelationTypes returns all relation type assets
+func (s *StaticSource) RelationTypes() ([]assets.RelationType, error) {
+	set := make([]assets.RelationType, len(s.s.RelationTypes))
+	for i := range s.s.RelationTypes {
+		set[i] = s.s.RelationTypes[i]
+	}
+	return set, nil
+}
+
 // Resthooks returns all resthook assets
 func (s *StaticSource) Resthooks() ([]assets.Resthook, error) {
 	set := make([]assets.Resthook, len(s.s.Resthooks))
@@ -2,10 +2,13 @@ package static_test
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/nyaruka/goflow/assets/static"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var assetsJSON = `{
@@ -19,6 +22,10 @@ var assetsJSON = `{
             "nodes": []
         }
 	],
+	"costs": [
+		{"type": "msg", "amount": 0.01},
+		{"type": "webhook", "amount": 0.002}
+	],
 	"fields": [
         {"uuid": "d66a7823-eada-40e5-9a3a-57239d4690bf", "key": "gender", "name": "Gender", "type": "text"},
         {"uuid": "f1b5aea6-6586-41c7-9020-1a6326cc6565", "key": "age", "name": "Age", "type": "number"}
@@ -35,6 +42,20 @@ var assetsJSON = `{
 			"name": "Spam"
 		}
 	],
+	"lookup_tables": [
+		{
+			"key": "prices",
+			"name": "Prices",
+			"columns": ["item", "price"],
+			"rows": [{"item": "Maize", "price": "350"}]
+		}
+	],
+	"relation_types": [
+		{
+			"key": "caregiver",
+			"name": "Caregiver"
+		}
+	],
 	"resthooks": [
 		{
 			"slug": "new-registration",
@@ -65,6 +86,10 @@ func TestSource(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, classifiers, 0)
 
+	costs, err := src.Costs()
+	assert.NoError(t, err)
+	assert.Len(t, costs, 2)
+
 	fields, err := src.Fields()
 	assert.NoError(t, err)
 	assert.Len(t, fields, 2)
@@ -93,6 +118,14 @@ func TestSource(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, locations, 0)
 
+	lookupTables, err := src.LookupTables()
+	assert.NoError(t, err)
+	assert.Len(t, lookupTables, 1)
+
+	relationTypes, err := src.RelationTypes()
+	assert.NoError(t, err)
+	assert.Len(t, relationTypes, 1)
+
 	resthooks, err := src.Resthooks()
 	assert.NoError(t, err)
 	assert.Len(t, resthooks, 1)
@@ -113,3 +146,41 @@ func TestSource(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, users, 0)
 }
+
+func TestYAMLSource(t *testing.T) {
+	src, err := static.NewYAMLSource([]byte(`
+flows:
+  # flows can be written as YAML too
+  - uuid: 76f0a02f-3b75-4b86-9064-e9195e1b3a02
+    name: Empty
+    spec_version: "13.0"
+    language: eng
+    type: messaging
+    nodes: []
+fields:
+  - {uuid: d66a7823-eada-40e5-9a3a-57239d4690bf, key: gender, name: Gender, type: text}
+`))
+	assert.NoError(t, err)
+
+	flow, err := src.FlowByName("Empty")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"uuid":"76f0a02f-3b75-4b86-9064-e9195e1b3a02","name":"Empty","spec_version":"13.0","language":"eng","type":"messaging","nodes":[]}`, string(flow.Definition()))
+
+	fields, err := src.Fields()
+	assert.NoError(t, err)
+	assert.Len(t, fields, 1)
+
+	_, err = static.NewYAMLSource([]byte("flows: ["))
+	assert.EqualError(t, err, "unable to read assets: unable to parse YAML: yaml: line 1: did not find expected node content")
+
+	// files are read as YAML if they have a YAML extension
+	path := filepath.Join(t.TempDir(), "assets.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("groups:\n  - uuid: 2aad21f6-30b7-42c5-bd7f-1b720c154817\n    name: Survey Audience\n"), 0644))
+
+	src, err = static.LoadSource(path)
+	assert.NoError(t, err)
+
+	groups, err := src.Groups()
+	assert.NoError(t, err)
+	assert.Len(t, groups, 1)
+}
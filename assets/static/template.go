@@ -43,16 +43,18 @@ type TemplateTranslation struct {
 	Locale_        envs.Locale             `json:"locale"          validate:"required"`
 	Namespace_     string                  `json:"namespace"`
 	VariableCount_ int                     `json:"variable_count"`
+	Components_    []*TemplateComponent    `json:"components,omitempty"`
 }
 
 // NewTemplateTranslation creates a new template translation
-func NewTemplateTranslation(channel assets.ChannelReference, locale envs.Locale, content string, variableCount int, namespace string) *TemplateTranslation {
+func NewTemplateTranslation(channel assets.ChannelReference, locale envs.Locale, content string, variableCount int, namespace string, components []*TemplateComponent) *TemplateTranslation {
 	return &TemplateTranslation{
 		Channel_:       channel,
 		Content_:       content,
 		Namespace_:     namespace,
 		Locale_:        locale,
 		VariableCount_: variableCount,
+		Components_:    components,
 	}
 }
 
@@ -70,3 +72,37 @@ func (t *TemplateTranslation) VariableCount() int { return t.VariableCount_ }
 
 // Channel returns the channel this template translation is for
 func (t *TemplateTranslation) Channel() assets.ChannelReference { return t.Channel_ }
+
+// Components returns the components of this template translation
+func (t *TemplateTranslation) Components() []assets.TemplateComponent {
+	cs := make([]assets.TemplateComponent, len(t.Components_))
+	for i := range t.Components_ {
+		cs[i] = t.Components_[i]
+	}
+	return cs
+}
+
+// TemplateComponent represents a component of a template translation
+type TemplateComponent struct {
+	Type_       string `json:"type"        validate:"required"`
+	Name_       string `json:"name"        validate:"required"`
+	Content_    string `json:"content"`
+	ParamCount_ int    `json:"param_count"`
+}
+
+// NewTemplateComponent creates a new template component
+func NewTemplateComponent(type_, name, content string, paramCount int) *TemplateComponent {
+	return &TemplateComponent{Type_: type_, Name_: name, Content_: content, ParamCount_: paramCount}
+}
+
+// Type returns the type of this component, e.g. header, body, button/url
+func (c *TemplateComponent) Type() string { return c.Type_ }
+
+// Name returns the name of this component, e.g. header, body, button.0
+func (c *TemplateComponent) Name() string { return c.Name_ }
+
+// Content returns the content of this component
+func (c *TemplateComponent) Content() string { return c.Content_ }
+
+// ParamCount returns the number of params in the content of this component
+func (c *TemplateComponent) ParamCount() int { return c.ParamCount_ }
@@ -16,12 +16,20 @@ func TestTemplate(t *testing.T) {
 		UUID: assets.ChannelUUID("ffffffff-9b24-92e1-ffff-ffffb207cdb4"),
 	}
 
-	translation := NewTemplateTranslation(channel, envs.Locale("eng-US"), "Hello {{1}}", 1, "0162a7f4_dfe4_4c96_be07_854d5dba3b2b")
+	translation := NewTemplateTranslation(channel, envs.Locale("eng-US"), "Hello {{1}}", 1, "0162a7f4_dfe4_4c96_be07_854d5dba3b2b", []*TemplateComponent{
+		NewTemplateComponent("body", "body", "Hello {{1}}", 1),
+		NewTemplateComponent("button/url", "button.0", "https://example.com/{{1}}", 1),
+	})
 	assert.Equal(t, channel, translation.Channel())
 	assert.Equal(t, envs.Locale("eng-US"), translation.Locale())
 	assert.Equal(t, "Hello {{1}}", translation.Content())
 	assert.Equal(t, 1, translation.VariableCount())
 	assert.Equal(t, "0162a7f4_dfe4_4c96_be07_854d5dba3b2b", translation.Namespace())
+	assert.Equal(t, 2, len(translation.Components()))
+	assert.Equal(t, "button/url", translation.Components()[1].Type())
+	assert.Equal(t, "button.0", translation.Components()[1].Name())
+	assert.Equal(t, "https://example.com/{{1}}", translation.Components()[1].Content())
+	assert.Equal(t, 1, translation.Components()[1].ParamCount())
 
 	template := NewTemplate(assets.TemplateUUID("8a9c1f73-5059-46a0-ba4a-6390979c01d3"), "hello", []*TemplateTranslation{translation})
 	assert.Equal(t, assets.TemplateUUID("8a9c1f73-5059-46a0-ba4a-6390979c01d3"), template.UUID())
@@ -40,4 +48,5 @@ func TestTemplate(t *testing.T) {
 	assert.Equal(t, copy.UUID(), template.UUID())
 	assert.Equal(t, copy.Translations()[0].Content(), template.Translations()[0].Content())
 	assert.Equal(t, copy.Translations()[0].Namespace(), template.Translations()[0].Namespace())
+	assert.Equal(t, copy.Translations()[0].Components(), template.Translations()[0].Components())
 }
@@ -6,20 +6,25 @@ import (
 
 // Topic is a JSON serializable implementation of a topic asset
 type Topic struct {
-	UUID_ assets.TopicUUID `json:"uuid" validate:"required,uuid"`
-	Name_ string           `json:"name"`
+	UUID_  assets.TopicUUID `json:"uuid" validate:"required,uuid"`
+	Name_  string           `json:"name"`
+	Queue_ string           `json:"queue,omitempty"`
 }
 
 // NewTopic creates a new topic
-func NewTopic(uuid assets.TopicUUID, name string) assets.Topic {
+func NewTopic(uuid assets.TopicUUID, name, queue string) assets.Topic {
 	return &Topic{
-		UUID_: uuid,
-		Name_: name,
+		UUID_:  uuid,
+		Name_:  name,
+		Queue_: queue,
 	}
 }
 
-// UUID returns the UUID of this ticketer
+// UUID returns the UUID of this topic
 func (t *Topic) UUID() assets.TopicUUID { return t.UUID_ }
 
-// Name returns the name of this ticketer
+// Name returns the name of this topic
 func (t *Topic) Name() string { return t.Name_ }
+
+// Queue returns the queue of this topic
+func (t *Topic) Queue() string { return t.Queue_ }
@@ -13,7 +13,9 @@ func TestTopic(t *testing.T) {
 	topic := static.NewTopic(
 		assets.TopicUUID("37657cf7-5eab-4286-9cb0-bbf270587bad"),
 		"Weather",
+		"weather-desk",
 	)
 	assert.Equal(t, assets.TopicUUID("37657cf7-5eab-4286-9cb0-bbf270587bad"), topic.UUID())
 	assert.Equal(t, "Weather", topic.Name())
+	assert.Equal(t, "weather-desk", topic.Queue())
 }
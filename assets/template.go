@@ -25,6 +25,21 @@ type TemplateUUID uuids.UUID
 //	       }
 //	    },
 //	    {
+//	       "locale": "spa",
+//	       "content": "Hola {{1}}, todavía tiene su problema?",
+//	       "namespace": "2d40b45c_25cd_4965_9019_f05d0124c5fa",
+//	       "variable_count": 1,
+//	       "channel": {
+//	         "uuid": "cf26be4c-875f-4094-9e08-162c3c9dcb5b",
+//	         "name": "Twilio Channel"
+//	       },
+//	       "components": [
+//	         {"type": "header", "name": "header", "content": "Issue #{{1}}", "param_count": 1},
+//	         {"type": "body", "name": "body", "content": "Hola {{1}}, todavía tiene su problema?", "param_count": 1},
+//	         {"type": "button/url", "name": "button.0", "content": "https://example.com/issues/{{1}}", "param_count": 1}
+//	       ]
+//	    },
+//	    {
 //	       "locale": "fra",
 //	       "content": "Bonjour {{1}}",
 //	       "channel": {
@@ -49,6 +64,16 @@ type TemplateTranslation interface {
 	Namespace() string
 	VariableCount() int
 	Channel() ChannelReference
+	Components() []TemplateComponent
+}
+
+// TemplateComponent is a part of a template translation such as its header, body or a button, whose content can
+// contain params like {{1}}
+type TemplateComponent interface {
+	Type() string
+	Name() string
+	Content() string
+	ParamCount() int
 }
 
 // TemplateReference is used to reference a Template
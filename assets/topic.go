@@ -3,29 +3,39 @@ package assets
 import (
 	"fmt"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/utils"
 )
 
+func init() {
+	utils.RegisterStructValidator(TopicReferenceValidation, TopicReference{})
+}
+
 // TopicUUID is the UUID of a topic
 type TopicUUID uuids.UUID
 
-// Topic categorizes tickets
+// Topic categorizes tickets. It can optionally have a queue which ticketers can use to route tickets with that topic,
+// e.g. to a group of agents, rather than having to work it out from the body of each ticket.
 //
 //	{
 //	  "uuid": "cd48bd11-08b9-44e3-9778-8e26adf08a7a",
-//	  "name": "Weather"
+//	  "name": "Weather",
+//	  "queue": "weather-desk"
 //	}
 //
 // @asset topic
 type Topic interface {
 	UUID() TopicUUID
 	Name() string
+	Queue() string
 }
 
 // TopicReference is used to reference a topic
 type TopicReference struct {
-	UUID TopicUUID `json:"uuid" validate:"required,uuid"`
-	Name string    `json:"name"`
+	UUID      TopicUUID `json:"uuid,omitempty" validate:"omitempty,uuid"`
+	Name      string    `json:"name,omitempty"`
+	NameMatch string    `json:"name_match,omitempty" engine:"evaluated"`
 }
 
 // NewTopicReference creates a new topic reference with the given UUID and name
@@ -33,6 +43,11 @@ func NewTopicReference(uuid TopicUUID, name string) *TopicReference {
 	return &TopicReference{UUID: uuid, Name: name}
 }
 
+// NewVariableTopicReference creates a new topic reference from the given templatized name match
+func NewVariableTopicReference(nameMatch string) *TopicReference {
+	return &TopicReference{NameMatch: nameMatch}
+}
+
 // Type returns the name of the asset type
 func (r *TopicReference) Type() string {
 	return "topic"
@@ -50,7 +65,7 @@ func (r *TopicReference) Identity() string {
 
 // Variable returns whether this a variable (vs concrete) reference
 func (r *TopicReference) Variable() bool {
-	return false
+	return r.Identity() == ""
 }
 
 func (r *TopicReference) String() string {
@@ -58,3 +73,17 @@ func (r *TopicReference) String() string {
 }
 
 var _ UUIDReference = (*TopicReference)(nil)
+
+//------------------------------------------------------------------------------------------
+// Validation
+//------------------------------------------------------------------------------------------
+
+// TopicReferenceValidation validates that the given topic reference is either a concrete
+// reference or a name matcher
+func TopicReferenceValidation(sl validator.StructLevel) {
+	ref := sl.Current().Interface().(TopicReference)
+	if neitherOrBoth(string(ref.UUID), ref.NameMatch) {
+		sl.ReportError(ref.UUID, "uuid", "UUID", "mutually_exclusive", "name_match")
+		sl.ReportError(ref.NameMatch, "name_match", "NameMatch", "mutually_exclusive", "uuid")
+	}
+}
@@ -1,23 +1,26 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"net/http"
 	"os"
 
 	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/services/classification/dialogflow"
 	"github.com/nyaruka/goflow/services/classification/luis"
 	"github.com/nyaruka/goflow/services/classification/wit"
+	"github.com/nyaruka/goflow/utils/netx"
 	"github.com/pkg/errors"
 )
 
 const usage = `usage: classify [flags] <input>`
 
 func main() {
-	var witToken, luisEndpoint, luisAppID, luisKey, luisSlot string
+	var witToken, luisEndpoint, luisAppID, luisKey, luisSlot, dfProject, dfLocation, dfAgent, dfToken string
 	var printLogs bool
 	flags := flag.NewFlagSet("", flag.ExitOnError)
 	flags.StringVar(&witToken, "wit.token", "", "wit.ai: access token")
@@ -25,6 +28,10 @@ func main() {
 	flags.StringVar(&luisAppID, "luis.appid", "", "luis.ai: application ID")
 	flags.StringVar(&luisKey, "luis.key", "production", "luis.ai: subscription key")
 	flags.StringVar(&luisSlot, "luis.slot", "production", "luis.ai: slot")
+	flags.StringVar(&dfProject, "dialogflow.project", "", "dialogflow: project ID")
+	flags.StringVar(&dfLocation, "dialogflow.location", "global", "dialogflow: agent location")
+	flags.StringVar(&dfAgent, "dialogflow.agent", "", "dialogflow: agent ID")
+	flags.StringVar(&dfToken, "dialogflow.token", "", "dialogflow: access token")
 	flags.BoolVar(&printLogs, "logs", false, "whether to print HTTP logs")
 	flags.Parse(os.Args[1:])
 	args := flags.Args()
@@ -35,16 +42,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	httpClient := netx.NewClient(netx.DefaultDialer, 10, 0)
 	svcs := make(map[string]flows.ClassificationService)
 
 	if witToken != "" {
 		c := flows.NewClassifier(static.NewClassifier("72a82155-deee-471a-97c0-02f36cf6a7e5", "Test", "wit", nil))
-		svcs["wit"] = wit.NewService(http.DefaultClient, nil, c, witToken)
+		svcs["wit"] = wit.NewService(httpClient, nil, c, witToken)
 	}
 
 	if luisAppID != "" && luisKey != "" {
 		c := flows.NewClassifier(static.NewClassifier("ea166a58-a71d-404e-91c9-d28aeb396bc5", "Test", "luis", nil))
-		svcs["luis"] = luis.NewService(http.DefaultClient, nil, nil, c, luisEndpoint, luisAppID, luisKey, luisSlot)
+		svcs["luis"] = luis.NewService(httpClient, nil, nil, c, luisEndpoint, luisAppID, luisKey, luisSlot)
+	}
+
+	if dfProject != "" && dfAgent != "" && dfToken != "" {
+		c := flows.NewClassifier(static.NewClassifier("b5a4a8a9-0c61-4ef6-9b5d-bb6c0ab4d8f2", "Test", "dialogflow", nil))
+		svcs["dialogflow"] = dialogflow.NewService(httpClient, nil, c, dfProject, dfLocation, dfAgent, dfToken)
 	}
 
 	classifications, logs, err := classify(svcs, args[0])
@@ -68,9 +81,10 @@ func main() {
 func classify(svcs map[string]flows.ClassificationService, input string) (map[string]*flows.Classification, []*flows.HTTPLog, error) {
 	res := make(map[string]*flows.Classification, len(svcs))
 	log := &flows.HTTPLogger{}
+	env := envs.NewBuilder().Build()
 
 	for t, s := range svcs {
-		c, err := s.Classify(nil, input, log.Log)
+		c, err := s.Classify(context.Background(), env, input, log.Log)
 		if err != nil {
 			return nil, log.Logs, errors.Wrapf(err, "error classifying with %s", t)
 		}
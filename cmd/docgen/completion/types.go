@@ -1,13 +1,9 @@
 package completion
 
 import (
-	"regexp"
 	"strings"
 )
 
-// matches a context property description, e.g. groups:[]group -> the groups the contact belongs to
-var contextPropRegexp = regexp.MustCompile(`(\w+)\:(\[\])?(\w+)\s→\s([\w\s-]+)`)
-
 // Type is a type that exists in the context
 type Type interface {
 	Name() string
@@ -42,6 +38,7 @@ var primitiveTypes = []Type{
 	newPrimitiveType("any"),
 	newPrimitiveType("text"),
 	newPrimitiveType("number"),
+	newPrimitiveType("money"),
 	newPrimitiveType("datetime"),
 }
 
@@ -63,20 +60,6 @@ func NewArrayProperty(key, help string, typeRef string) *Property {
 	return &Property{Key: key, Help: help, Type: typeRef, Array: true}
 }
 
-// ParseProperty parses a property from a docstring line
-func ParseProperty(line string) *Property {
-	matches := contextPropRegexp.FindStringSubmatch(line)
-	if len(matches) != 5 {
-		return nil
-	}
-	return &Property{
-		Key:   matches[1],
-		Help:  matches[4],
-		Type:  matches[3],
-		Array: len(matches[2]) > 0,
-	}
-}
-
 // a type with fixed properties
 type staticType struct {
 	Name_      string      `json:"name"`
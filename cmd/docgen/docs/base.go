@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/goflow/cmd/docgen/completion"
+	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/utils/i18n"
 
 	"github.com/pkg/errors"
@@ -43,6 +45,9 @@ func Generate(baseDir, outputDir, localeDir string) error {
 		return errors.Wrap(err, "error extracting tagged items")
 	}
 
+	// context types aren't documented in docstrings but registered by the types which provide them
+	taggedItems["context"] = contextItems()
+
 	for k, v := range taggedItems {
 		fmt.Printf(" > Found %d tagged items with tag %s\n", len(v), k)
 	}
@@ -114,3 +119,26 @@ func generateForLang(baseDir, outputDir string, items map[string][]*TaggedItem,
 
 	return nil
 }
+
+// creates items for the registered context types
+func contextItems() []*TaggedItem {
+	registered := flows.RegisteredContextTypes()
+	items := make([]*TaggedItem, len(registered))
+	for i, t := range registered {
+		items[i] = &TaggedItem{tagName: "context", tagValue: t.Name, tagTitle: t.Name}
+	}
+	return items
+}
+
+// gets the documented properties of the registered context type with the given name
+func contextProperties(name string) []*completion.Property {
+	props := make([]*completion.Property, 0)
+	for _, p := range flows.GetContextType(name).Documented() {
+		if p.Array {
+			props = append(props, completion.NewArrayProperty(p.Key, p.Help, p.Type))
+		} else {
+			props = append(props, completion.NewProperty(p.Key, p.Help, p.Type))
+		}
+	}
+	return props
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/goflow/cmd/docgen/docs"
+	"github.com/nyaruka/goflow/flows/definition"
+	inspectcontext "github.com/nyaruka/goflow/flows/inspect/context"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,17 +41,57 @@ func TestGenerateDocs(t *testing.T) {
 	completion := readJSONOutput(t, outputDir, "en-us", "editor.json").(map[string]interface{})
 	assert.Contains(t, completion, "functions")
 	assert.Contains(t, completion, "context")
+	assert.Contains(t, completion, "operators")
+	assert.Contains(t, completion, "tests")
+	assert.Equal(t, definition.CurrentSpecVersion.String(), completion["version"])
 
 	context := completion["context"].(map[string]interface{})
 	functions := completion["functions"].([]interface{})
 
-	assert.Equal(t, 86, len(functions))
+	assert.Equal(t, 112, len(functions))
+
+	operators := completion["operators"].([]interface{})
+	assert.Equal(t, 13, len(operators))
+
+	tests := completion["tests"].([]interface{})
+	assert.Equal(t, 34, len(tests))
+	assert.Equal(t, map[string]interface{}{
+		"name":    "add",
+		"symbol":  "+",
+		"summary": "Adds two numbers, or two amounts of money in the same currency.",
+		"examples": []interface{}{
+			map[string]interface{}{"template": `@(2 + 3)`, "output": "5"},
+			map[string]interface{}{"template": `@(fields.age + 10)`, "output": "33"},
+			map[string]interface{}{"template": `@(cart.total + cart.total)`, "output": "RWF 3700.00"},
+		},
+	}, operators[0])
 
 	types := context["types"].([]interface{})
-	assert.Equal(t, 18, len(types))
+	assert.Equal(t, 27, len(types))
 
 	root := context["root"].([]interface{})
-	assert.Equal(t, 14, len(root))
+	assert.Equal(t, 20, len(root))
+
+	// check the types used for context introspection match those in the editor support file
+	for _, typ := range types {
+		typ := typ.(map[string]interface{})
+		name := typ["name"].(string)
+		expected, isStatic := inspectcontext.StaticTypes()[name]
+		if !isStatic || typ["properties"] == nil {
+			continue
+		}
+
+		actual := make([]*inspectcontext.Property, 0)
+		for _, p := range typ["properties"].([]interface{}) {
+			p := p.(map[string]interface{})
+			if p["key"] != "__default__" {
+				array, _ := p["array"].(bool)
+				actual = append(actual, &inspectcontext.Property{Key: p["key"].(string), Type: p["type"].(string), Array: array})
+			}
+		}
+
+		assert.Equal(t, expected, actual, "context introspection properties mismatch for type %s", name)
+	}
 }
 
 func readJSONOutput(t *testing.T, file ...string) interface{} {
@@ -10,8 +10,7 @@ import (
 	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/gocommon/urns"
 	"github.com/nyaruka/goflow/cmd/docgen/completion"
-
-	"github.com/pkg/errors"
+	"github.com/nyaruka/goflow/flows/definition"
 )
 
 func init() {
@@ -30,9 +29,19 @@ type functionListing struct {
 	Examples  []*functionExample `json:"examples"`
 }
 
+type operatorListing struct {
+	Name     string             `json:"name"`
+	Symbol   string             `json:"symbol"`
+	Summary  string             `json:"summary"`
+	Examples []*functionExample `json:"examples"`
+}
+
 type editorSupport struct {
+	Version   string                 `json:"version"`
 	Context   *completion.Completion `json:"context"`
 	Functions []*functionListing     `json:"functions"`
+	Operators []*operatorListing     `json:"operators"`
+	Tests     []*functionListing     `json:"tests"`
 }
 
 type editorSupportGenerator struct{}
@@ -42,7 +51,8 @@ func (g *editorSupportGenerator) Name() string {
 }
 
 func (g *editorSupportGenerator) Generate(baseDir, outputDir string, items map[string][]*TaggedItem, gettext func(string) string) error {
-	es := &editorSupport{}
+	// editor support is versioned with the flow spec so that the editor can pick the bundle matching its flows
+	es := &editorSupport{Version: definition.CurrentSpecVersion.String()}
 	var err error
 
 	es.Context, err = g.buildContextCompletion(items, gettext)
@@ -50,7 +60,9 @@ func (g *editorSupportGenerator) Generate(baseDir, outputDir string, items map[s
 		return err
 	}
 
-	es.Functions = g.buildFunctionListing(items, gettext)
+	es.Functions = g.buildFunctionListing(items["function"], gettext)
+	es.Operators = g.buildOperatorListing(items["operator"], gettext)
+	es.Tests = g.buildFunctionListing(items["test"], gettext)
 
 	outputPath := path.Join(outputDir, "editor.json")
 	marshaled, err := jsonx.MarshalPretty(es)
@@ -74,6 +86,8 @@ func (g *editorSupportGenerator) buildContextCompletion(items map[string][]*Tagg
 	types := []completion.Type{
 		// the dynamic types in the context aren't described in the code so we add them manually here
 		completion.NewDynamicType("fields", "fields", completion.NewProperty("{key}", gettext("{key} for the contact"), "any")),
+		completion.NewDynamicType("relations", "relation_types", completion.NewProperty("{key}", gettext("the contact related as {key}"), "relation")),
+		completion.NewDynamicType("related", "relation_types", completion.NewProperty("{key}", gettext("the contact related as {key}"), "contact")),
 		completion.NewDynamicType("results", "results", completion.NewProperty("{key}", gettext("the result for {key}"), "result")),
 		completion.NewDynamicType("globals", "globals", completion.NewProperty("{key}", gettext("the global value {key}"), "text")),
 
@@ -81,19 +95,13 @@ func (g *editorSupportGenerator) buildContextCompletion(items map[string][]*Tagg
 		createURNsType(gettext),
 	}
 
-	// now collect the types from tagged docstrings
+	// now collect the types registered by the types which provide them
 	var root []*completion.Property
 
 	for _, item := range items["context"] {
-		// examples are actually property descriptors for context items
-		properties := make([]*completion.Property, len(item.examples))
-		for i, propDesc := range item.examples {
-			prop := completion.ParseProperty(propDesc)
-			if prop == nil {
-				return nil, errors.Errorf("invalid format for property description \"%s\"", propDesc)
-			}
+		properties := contextProperties(item.tagValue)
+		for _, prop := range properties {
 			prop.Help = gettext(prop.Help)
-			properties[i] = prop
 		}
 
 		if item.tagValue == "root" {
@@ -112,37 +120,56 @@ func (g *editorSupportGenerator) buildContextCompletion(items map[string][]*Tagg
 	return c, nil
 }
 
-func (g *editorSupportGenerator) buildFunctionListing(items map[string][]*TaggedItem, gettext func(string) string) []*functionListing {
-	funcItems := items["function"]
+func (g *editorSupportGenerator) buildFunctionListing(funcItems []*TaggedItem, gettext func(string) string) []*functionListing {
 	listings := make([]*functionListing, len(funcItems))
 
 	for i, funcItem := range funcItems {
 		summary := funcItem.description[0]
 		detail := strings.TrimSpace(strings.Join(funcItem.description[1:len(funcItem.description)-1], "\n"))
 
-		examples := make([]*functionExample, len(funcItem.examples))
-		for j := range funcItem.examples {
-			parts := strings.Split(funcItem.examples[j], "→")
-			examples[j] = &functionExample{Template: strings.TrimSpace(parts[0]), Output: strings.TrimSpace(parts[1])}
-		}
-
 		listings[i] = &functionListing{
 			Signature: funcItem.tagValue + funcItem.tagExtra,
 			Summary:   gettext(summary),
 			Detail:    gettext(detail),
-			Examples:  examples,
+			Examples:  parseFunctionExamples(funcItem.examples),
 		}
 	}
 
 	return listings
 }
 
+func (g *editorSupportGenerator) buildOperatorListing(opItems []*TaggedItem, gettext func(string) string) []*operatorListing {
+	listings := make([]*operatorListing, len(opItems))
+
+	for i, opItem := range opItems {
+		listings[i] = &operatorListing{
+			Name:     opItem.tagValue,
+			Symbol:   opItem.tagTitle,
+			Summary:  gettext(opItem.description[0]),
+			Examples: parseFunctionExamples(opItem.examples),
+		}
+	}
+
+	return listings
+}
+
+// parses examples of the form "template → output"
+func parseFunctionExamples(lines []string) []*functionExample {
+	examples := make([]*functionExample, len(lines))
+	for i, line := range lines {
+		parts := strings.Split(line, "→")
+		examples[i] = &functionExample{Template: strings.TrimSpace(parts[0]), Output: strings.TrimSpace(parts[1])}
+	}
+	return examples
+}
+
 // creates a text file which lists all the context paths using example fields
 func createContextPathListFile(outputDir string, c *completion.Completion) error {
 	context := completion.NewContext(map[string][]string{
-		"fields":  {"age", "gender"},
-		"globals": {"org_name"},
-		"results": {"response_1"},
+		"fields":         {"age", "gender"},
+		"globals":        {"org_name"},
+		"relation_types": {"caregiver"},
+		"results":        {"response_1"},
 	})
 	nodes := c.EnumerateNodes(context)
 
@@ -1,12 +1,14 @@
 package docs
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/urns"
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/assets/static"
 	"github.com/nyaruka/goflow/cmd/docgen/completion"
@@ -21,7 +23,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-var dynamicContextTypes = []string{"fields", "globals", "results", "urns"}
+var dynamicContextTypes = []string{"fields", "globals", "related", "relations", "results", "urns"}
 
 // function that can render a single tagged item
 type renderFunc func(*strings.Builder, *TaggedItem, flows.Session, flows.Session) error
@@ -143,14 +145,9 @@ func renderContextDoc(output *strings.Builder, item *TaggedItem, session flows.S
 		return nil
 	}
 
-	// examples are actually auto-completion property descriptors
 	var defaultProp *completion.Property
-	properties := make([]*completion.Property, 0, len(item.examples))
-	for _, propDesc := range item.examples {
-		prop := completion.ParseProperty(propDesc)
-		if prop == nil {
-			return errors.Errorf("invalid format for property description \"%s\"", propDesc)
-		}
+	properties := make([]*completion.Property, 0)
+	for _, prop := range contextProperties(item.tagValue) {
 		if prop.Key == "__default__" {
 			defaultProp = prop
 		} else {
@@ -172,26 +169,8 @@ func renderContextDoc(output *strings.Builder, item *TaggedItem, session flows.S
 }
 
 func renderRootContext(items map[string][]*TaggedItem, session flows.Session, voiceSession flows.Session) (map[string]string, error) {
-	var root *TaggedItem
-	for _, item := range items["context"] {
-		if item.tagValue == "root" {
-			root = item
-			break
-		}
-	}
-
-	// examples are actually auto-completion property descriptors
-	properties := make([]*completion.Property, 0, len(root.examples))
-	for _, propDesc := range root.examples {
-		prop := completion.ParseProperty(propDesc)
-		if prop == nil {
-			return nil, errors.Errorf("invalid format for property description \"%s\"", propDesc)
-		}
-		properties = append(properties, prop)
-	}
-
 	output := &strings.Builder{}
-	for _, p := range properties {
+	for _, p := range contextProperties("root") {
 		output.WriteString(fmt.Sprintf(" * `%s` %s (%s)\n", p.Key, p.Help, renderPropertyType(p)))
 	}
 	output.WriteString("\n")
@@ -202,7 +181,7 @@ func renderRootContext(items map[string][]*TaggedItem, session flows.Session, vo
 func renderPropertyType(p *completion.Property) string {
 	if p.Type == "any" || utils.StringSliceContains(dynamicContextTypes, p.Type, true) {
 		return p.Type
-	} else if p.Type == "text" || p.Type == "number" || p.Type == "datetime" {
+	} else if p.Type == "text" || p.Type == "number" || p.Type == "datetime" || p.Type == "money" {
 		return fmt.Sprintf("[type:%s]", p.Type)
 	}
 	return fmt.Sprintf("[context:%s]", p.Type)
@@ -434,6 +413,11 @@ func eventsForAction(action flows.Action, msgSession flows.Session, voiceSession
 		return nil, err
 	}
 
+	// the test contact doesn't have a WhatsApp URN so give it one for actions which need one
+	if action.Type() == actions.TypeSendWhatsAppFlow {
+		session.Contact().AddURN(urns.URN("whatsapp:12024561111"), nil)
+	}
+
 	run := session.Runs()[0]
 	step := run.Path()[len(run.Path())-1]
 	modifierLog := func(flows.Modifier) {}
@@ -444,7 +428,7 @@ func eventsForAction(action flows.Action, msgSession flows.Session, voiceSession
 		eventList = append(eventList, e)
 	}
 
-	err = action.Execute(run, step, modifierLog, eventLog)
+	err = action.Execute(context.Background(), run, step, modifierLog, eventLog)
 	if err != nil {
 		return nil, err
 	}
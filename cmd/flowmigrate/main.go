@@ -3,6 +3,7 @@ package main
 // go install github.com/nyaruka/goflow/cmd/flowmigrate
 // cat legacy_flow.json | flowmigrate
 // cat legacy_export.json | jq '.flows[0]' | flowmigrate
+// cat flow.yaml | flowmigrate
 
 import (
 	"bufio"
@@ -16,6 +17,7 @@ import (
 	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/goflow/flows/definition"
 	"github.com/nyaruka/goflow/flows/definition/migrations"
+	"github.com/nyaruka/goflow/utils/yamlx"
 )
 
 func main() {
@@ -38,13 +40,21 @@ func main() {
 	}
 }
 
-// Migrate reads a flow definition as JSON and migrates it
+// Migrate reads a flow definition as JSON or YAML and migrates it. A YAML definition is written back out as YAML with
+// its comments kept where possible.
 func Migrate(reader io.Reader, toVersion *semver.Version, baseMediaURL string, pretty bool) ([]byte, error) {
-	data, err := io.ReadAll(reader)
+	original, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, err
 	}
 
+	data, isYAML := json.RawMessage(original), !yamlx.IsJSON(original)
+	if isYAML {
+		if data, err = yamlx.ToJSON(original); err != nil {
+			return nil, err
+		}
+	}
+
 	var mCfg *migrations.Config
 	if baseMediaURL != "" {
 		mCfg = &migrations.Config{BaseMediaURL: baseMediaURL}
@@ -63,6 +73,9 @@ func Migrate(reader io.Reader, toVersion *semver.Version, baseMediaURL string, p
 		}
 	}
 
+	if isYAML {
+		return yamlx.Update(original, migrated)
+	}
 	if pretty {
 		return jsonx.MarshalPretty(json.RawMessage(migrated))
 	}
@@ -8,6 +8,7 @@ import (
 	main "github.com/nyaruka/goflow/cmd/flowmigrate"
 	"github.com/nyaruka/goflow/flows/definition"
 	"github.com/nyaruka/goflow/test"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -85,3 +86,33 @@ func TestMigrate(t *testing.T) {
 		test.AssertEqualJSON(t, []byte(tc.output), migrated, "Migrated flow mismatch")
 	}
 }
+
+func TestMigrateYAML(t *testing.T) {
+	input := strings.NewReader(`# an empty flow
+uuid: 76f0a02f-3b75-4b86-9064-e9195e1b3a02
+name: Empty # needs a better name
+spec_version: 13.0.0
+language: eng
+type: messaging
+revision: 1
+expire_after_minutes: 0
+nodes: []
+`)
+
+	migrated, err := main.Migrate(input, nil, "http://temba.io/", true)
+	require.NoError(t, err)
+
+	assert.Equal(t, fmt.Sprintf(`# an empty flow
+uuid: 76f0a02f-3b75-4b86-9064-e9195e1b3a02
+name: Empty # needs a better name
+spec_version: %s
+language: eng
+type: messaging
+revision: 1
+expire_after_minutes: 0
+nodes: []
+`, definition.CurrentSpecVersion), string(migrated))
+
+	_, err = main.Migrate(strings.NewReader("nodes: ["), nil, "http://temba.io/", true)
+	assert.EqualError(t, err, "unable to parse YAML: yaml: line 1: did not find expected node content")
+}
@@ -2,11 +2,11 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -27,6 +27,8 @@ import (
 	"github.com/nyaruka/goflow/services/classification/wit"
 	"github.com/nyaruka/goflow/services/webhooks"
 	"github.com/nyaruka/goflow/utils"
+	"github.com/nyaruka/goflow/utils/netx"
+	"github.com/nyaruka/goflow/utils/yamlx"
 	"github.com/pkg/errors"
 )
 
@@ -87,13 +89,15 @@ func main() {
 }
 
 func createEngine(witToken string) flows.Engine {
+	httpClient := netx.NewClient(netx.DefaultDialer, 10, 0)
+
 	builder := engine.NewBuilder().
-		WithWebhookServiceFactory(webhooks.NewServiceFactory(http.DefaultClient, nil, nil, map[string]string{"User-Agent": "goflow-runner"}, 10000))
+		WithWebhookServiceFactory(webhooks.NewServiceFactory(httpClient, nil, nil, map[string]string{"User-Agent": "goflow-runner"}, 10000, 10000))
 
 	if witToken != "" {
 		builder.WithClassificationServiceFactory(func(classifier *flows.Classifier) (flows.ClassificationService, error) {
 			if classifier.Type() == "wit" {
-				return wit.NewService(http.DefaultClient, nil, classifier, witToken), nil
+				return wit.NewService(httpClient, nil, classifier, witToken), nil
 			}
 			return nil, errors.New("only classifiers of type wit supported")
 		})
@@ -104,7 +108,7 @@ func createEngine(witToken string) flows.Engine {
 
 // RunFlow steps through a flow
 func RunFlow(eng flows.Engine, assetsPath string, flowUUID assets.FlowUUID, initialMsg string, contactLang envs.Language, in io.Reader, out io.Writer) (*Repro, error) {
-	assetsJSON, err := os.ReadFile(assetsPath)
+	assetsJSON, err := yamlx.ReadFile(assetsPath)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error reading assets file '%s'", assetsPath)
 	}
@@ -163,7 +167,7 @@ func RunFlow(eng flows.Engine, assetsPath string, flowUUID assets.FlowUUID, init
 	fmt.Fprintf(out, "Starting flow '%s'....\n---------------------------------------\n", flow.Name())
 
 	// start our session
-	session, sprint, err := eng.NewSession(sa, repro.Trigger)
+	session, sprint, err := eng.NewSession(context.Background(), sa, repro.Trigger)
 	if err != nil {
 		return nil, err
 	}
@@ -193,7 +197,7 @@ func RunFlow(eng flows.Engine, assetsPath string, flowUUID assets.FlowUUID, init
 
 		repro.Resumes = append(repro.Resumes, resume)
 
-		sprint, err := session.Resume(resume)
+		sprint, err := session.Resume(context.Background(), resume)
 		if err != nil {
 			return nil, err
 		}
@@ -230,6 +234,16 @@ func PrintEvent(event flows.Event, out io.Writer) {
 			action = "cleared"
 		}
 		msg = fmt.Sprintf("✏️ field '%s' %s", typed.Field.Key, action)
+	case *events.ContactFieldsChangedEvent:
+		changes := make([]string, len(typed.Changes))
+		for i, c := range typed.Changes {
+			if c.Value != nil {
+				changes[i] = fmt.Sprintf("'%s' changed to '%s'", c.Field.Key, c.Value.Text.Native())
+			} else {
+				changes[i] = fmt.Sprintf("'%s' cleared", c.Field.Key)
+			}
+		}
+		msg = fmt.Sprintf("✏️ fields %s", strings.Join(changes, ", "))
 	case *events.ContactGroupsChangedEvent:
 		msgs := make([]string, 0)
 		if len(typed.GroupsAdded) > 0 {
@@ -259,6 +273,8 @@ func PrintEvent(event flows.Event, out io.Writer) {
 		msg = fmt.Sprintf("☎️ dial ended with '%s'", typed.Dial.Status)
 	case *events.DialWaitEvent:
 		msg = "⏳ waiting for dial (type /dial <answered|no_answer|busy|failed>)..."
+	case *events.EmailReceivedEvent:
+		msg = fmt.Sprintf("📧 email received with subject '%s'", typed.Email.Subject)
 	case *events.EmailSentEvent:
 		msg = fmt.Sprintf("✉️ email sent with subject '%s'", typed.Subject)
 	case *events.EnvironmentRefreshedEvent:
@@ -267,6 +283,8 @@ func PrintEvent(event flows.Event, out io.Writer) {
 		msg = fmt.Sprintf("⚠️ %s", typed.Text)
 	case *events.FailureEvent:
 		msg = fmt.Sprintf("🛑 %s", typed.Text)
+	case *events.WarningEvent:
+		msg = fmt.Sprintf("⚠️ %s", typed.Text)
 	case *events.FlowEnteredEvent:
 		msg = fmt.Sprintf("↪️ entered flow '%s'", typed.Flow.Name)
 	case *events.InputLabelsAddedEvent:
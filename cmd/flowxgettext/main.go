@@ -13,6 +13,7 @@ import (
 	"github.com/nyaruka/goflow/flows/definition"
 	"github.com/nyaruka/goflow/flows/definition/migrations"
 	"github.com/nyaruka/goflow/flows/translation"
+	"github.com/nyaruka/goflow/utils/yamlx"
 	"github.com/pkg/errors"
 )
 
@@ -63,7 +64,7 @@ func FlowXGetText(lang envs.Language, excludeArgs bool, paths []string, writer i
 func loadFlows(paths []string) ([]flows.Flow, error) {
 	flows := make([]flows.Flow, 0)
 	for _, path := range paths {
-		fileJSON, err := os.ReadFile(path)
+		fileJSON, err := yamlx.ReadFile(path)
 		if err != nil {
 			return nil, errors.Wrapf(err, "error reading flow file '%s'", path)
 		}
@@ -1,9 +1,9 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"net/http"
 	"os"
 
 	"github.com/nyaruka/gocommon/jsonx"
@@ -14,7 +14,9 @@ import (
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/engine"
 	"github.com/nyaruka/goflow/flows/triggers"
+	"github.com/nyaruka/goflow/services/airtime/africastalking"
 	"github.com/nyaruka/goflow/services/airtime/dtone"
+	"github.com/nyaruka/goflow/utils/netx"
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 )
@@ -22,10 +24,12 @@ import (
 const usage = `usage: transferairtime [flags] <destnumber> <amount> <currency>`
 
 func main() {
-	var dtoneKey, dtoneSecret string
+	var dtoneKey, dtoneSecret, atUsername, atAPIKey string
 	flags := flag.NewFlagSet("", flag.ExitOnError)
 	flags.StringVar(&dtoneKey, "dtone.key", "", "API key for DTOne service")
 	flags.StringVar(&dtoneSecret, "dtone.secret", "", "API secret for DTOne service")
+	flags.StringVar(&atUsername, "africastalking.username", "", "app username for Africa's Talking service")
+	flags.StringVar(&atAPIKey, "africastalking.apikey", "", "API key for Africa's Talking service")
 	flags.Parse(os.Args[1:])
 	args := flags.Args()
 
@@ -47,15 +51,21 @@ func main() {
 		os.Exit(1)
 	}
 
-	if dtoneKey == "" || dtoneSecret == "" {
+	var svcFactory engine.AirtimeServiceFactory
+
+	if dtoneKey != "" && dtoneSecret != "" {
+		svcFactory = func(flows.SessionAssets) (flows.AirtimeService, error) {
+			return dtone.NewService(netx.NewClient(netx.DefaultDialer, 10, 0), nil, dtoneKey, dtoneSecret), nil
+		}
+	} else if atUsername != "" && atAPIKey != "" {
+		svcFactory = func(flows.SessionAssets) (flows.AirtimeService, error) {
+			return africastalking.NewService(netx.NewClient(netx.DefaultDialer, 10, 0), nil, atUsername, atAPIKey), nil
+		}
+	} else {
 		fmt.Println("no airtime service credentials provided")
 		os.Exit(1)
 	}
 
-	svcFactory := func(flows.SessionAssets) (flows.AirtimeService, error) {
-		return dtone.NewService(http.DefaultClient, nil, dtoneKey, dtoneSecret), nil
-	}
-
 	if err := transferAirtime(destination, amount, args[2], svcFactory); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -116,7 +126,7 @@ func transferAirtime(destination urns.URN, amount decimal.Decimal, currency stri
 
 	trigger := triggers.NewBuilder(env, assets.NewFlowReference(assets.FlowUUID("2374f60d-7412-442c-9177-585967afa972"), "Airtime"), contact).Manual().Build()
 
-	_, sprint, err := eng.NewSession(sa, trigger)
+	_, sprint, err := eng.NewSession(context.Background(), sa, trigger)
 	if err != nil {
 		return err
 	}
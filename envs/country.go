@@ -30,6 +30,25 @@ func DeriveCountryFromTel(number string) Country {
 	return Country(phonenumbers.GetRegionCodeForNumber(parsed))
 }
 
+// MeasurementSystem is a system of units of measurement
+type MeasurementSystem string
+
+const (
+	MeasurementSystemMetric   MeasurementSystem = "metric"
+	MeasurementSystemImperial MeasurementSystem = "imperial"
+)
+
+// countries which don't use the metric system for everyday measurements
+var imperialCountries = map[Country]bool{"US": true, "LR": true, "MM": true}
+
+// MeasurementSystem returns the measurement system used in this country
+func (c Country) MeasurementSystem() MeasurementSystem {
+	if imperialCountries[c] {
+		return MeasurementSystemImperial
+	}
+	return MeasurementSystemMetric
+}
+
 // Place nicely with NULLs if persisting to a database or JSON
 func (c *Country) Scan(value any) error         { return null.ScanString(value, c) }
 func (c Country) Value() (driver.Value, error)  { return null.StringValue(c) }
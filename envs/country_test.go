@@ -28,3 +28,10 @@ func TestDeriveCountryFromTel(t *testing.T) {
 	assert.NoError(t, c.Scan(nil))
 	assert.Equal(t, envs.NilCountry, c)
 }
+
+func TestCountryMeasurementSystem(t *testing.T) {
+	assert.Equal(t, envs.MeasurementSystemMetric, envs.Country("RW").MeasurementSystem())
+	assert.Equal(t, envs.MeasurementSystemImperial, envs.Country("US").MeasurementSystem())
+	assert.Equal(t, envs.MeasurementSystemImperial, envs.Country("LR").MeasurementSystem())
+	assert.Equal(t, envs.MeasurementSystemMetric, envs.NilCountry.MeasurementSystem())
+}
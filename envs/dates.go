@@ -137,6 +137,19 @@ func DateTimeFromString(env Environment, str string, fillTime bool) (time.Time,
 	return time.Date(date.Year, time.Month(date.Month), date.Day, timeOfDay.Hour, timeOfDay.Minute, timeOfDay.Second, timeOfDay.Nanos, env.Timezone()), nil
 }
 
+// DateTimeHasTimezone returns whether the given string is a datetime with an explicit timezone. Datetimes parsed from
+// strings without one by DateTimeFromString are assumed to be in the environment's timezone.
+func DateTimeHasTimezone(str string) bool {
+	str = strings.Trim(str, " \n\r\t")
+
+	for _, format := range isoFormats {
+		if _, err := time.Parse(format, str); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 // DateFromString returns a date constructed from the passed in string, or an error if we
 // are unable to extract one
 func DateFromString(env Environment, str string) (dates.Date, error) {
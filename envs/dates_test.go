@@ -150,6 +150,14 @@ func TestDateFromString(t *testing.T) {
 	}
 }
 
+func TestDateTimeHasTimezone(t *testing.T) {
+	assert.True(t, envs.DateTimeHasTimezone("2018-04-11T13:24:30.123456Z"))
+	assert.True(t, envs.DateTimeHasTimezone(" 2018-04-11T13:24+02:00 "))
+	assert.False(t, envs.DateTimeHasTimezone("2018-04-11T13:24:30"))
+	assert.False(t, envs.DateTimeHasTimezone("2018-04-11 13:24"))
+	assert.False(t, envs.DateTimeHasTimezone("xx"))
+}
+
 func TestTimeFromString(t *testing.T) {
 	testCases := []struct {
 		value    string
@@ -6,7 +6,9 @@ import (
 
 	"github.com/nyaruka/gocommon/dates"
 	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/random"
 	"github.com/nyaruka/goflow/utils"
+	"github.com/shopspring/decimal"
 )
 
 type RedactionPolicy string
@@ -16,15 +18,97 @@ const (
 	RedactionPolicyURNs RedactionPolicy = "urns"
 )
 
-// NumberFormat describes how numbers should be parsed and formatted
+// RedactionRules describes values which are sensitive and so should be masked wherever the engine might expose them
+// outside of the session, i.e. in events, HTTP logs and error messages. Contact URNs and field values are matched by
+// scheme and field key, and headers are matched by name case-insensitively. PII categories match the contact's name,
+// phone URNs, and the values of any fields and results tagged with those categories.
+type RedactionRules struct {
+	URNSchemes []string      `json:"urn_schemes,omitempty" validate:"dive,required"`
+	Fields     []string      `json:"fields,omitempty" validate:"dive,required"`
+	Headers    []string      `json:"headers,omitempty" validate:"dive,required"`
+	PII        []PIICategory `json:"pii,omitempty" validate:"dive,pii_category"`
+}
+
+// HasPII returns whether these rules redact values of the given PII category
+func (r *RedactionRules) HasPII(category PIICategory) bool {
+	if category == NilPIICategory {
+		return false
+	}
+	for _, c := range r.PII {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// NumberFormat describes how numbers should be parsed and formatted. Spoken numbers are numbers written as words, e.g.
+// "twenty five", which some languages support parsing.
 type NumberFormat struct {
 	DecimalSymbol       string `json:"decimal_symbol"`
 	DigitGroupingSymbol string `json:"digit_grouping_symbol"`
+	SpokenNumbers       bool   `json:"spoken_numbers,omitempty"`
 }
 
 // DefaultNumberFormat is the default number formatting, e.g. 1,234.567
 var DefaultNumberFormat = &NumberFormat{DecimalSymbol: `.`, DigitGroupingSymbol: `,`}
 
+// RoundingMode is the strategy used to round numbers to a given number of decimal places
+type RoundingMode string
+
+const (
+	RoundingModeHalfUp   RoundingMode = "half_up"
+	RoundingModeHalfEven RoundingMode = "half_even"
+)
+
+// Round rounds the given decimal to the given number of places using this rounding mode
+func (m RoundingMode) Round(d decimal.Decimal, places int32) decimal.Decimal {
+	if m == RoundingModeHalfEven {
+		return d.RoundBank(places)
+	}
+	return d.Round(places)
+}
+
+// NoDecimalPrecision is the decimal precision of environments which don't round the results of arithmetic
+const NoDecimalPrecision = -1
+
+// ExpressionsVersion is a version of the expressions language. Changes to how expressions are evaluated which would
+// break existing flows are only made in new versions, which flows can opt into.
+type ExpressionsVersion int
+
+const (
+	// ExpressionsVersion1 is the original version of the expressions language
+	ExpressionsVersion1 ExpressionsVersion = 1
+
+	// ExpressionsVersion2 compares values which are both numbers numerically in the = and != operators, and flags
+	// templates which concatenate expressions by placing them next to each other
+	ExpressionsVersion2 ExpressionsVersion = 2
+
+	// LatestExpressionsVersion is the latest version of the expressions language supported by this library
+	LatestExpressionsVersion = ExpressionsVersion2
+)
+
+// TruncationPolicy describes the maximum lengths of different kinds of values, where zero means no specific limit
+type TruncationPolicy struct {
+	FieldValue  int `json:"field_value,omitempty"`
+	ResultValue int `json:"result_value,omitempty"`
+	MsgText     int `json:"msg_text,omitempty"`
+}
+
+// EvaluationLimits describes the resources that evaluating a single template can use, where zero means no limit. Depth
+// is how deeply expressions can nest, time is in milliseconds and output size is the maximum length in bytes of any
+// text value, including the rendered template.
+type EvaluationLimits struct {
+	MaxDepth      int `json:"max_depth,omitempty" validate:"min=0"`
+	MaxTime       int `json:"max_time,omitempty" validate:"min=0"`
+	MaxOutputSize int `json:"max_output_size,omitempty" validate:"min=0"`
+}
+
+// IsZero returns whether these limits don't limit anything
+func (l *EvaluationLimits) IsZero() bool {
+	return l.MaxDepth == 0 && l.MaxTime == 0 && l.MaxOutputSize == 0
+}
+
 // Environment defines the environment that the Excellent function is running in, this includes
 // the timezone the user is in as well as the preferred date and time formats.
 type Environment interface {
@@ -35,16 +119,27 @@ type Environment interface {
 	DefaultCountry() Country
 	NumberFormat() *NumberFormat
 	RedactionPolicy() RedactionPolicy
+	RedactionRules() *RedactionRules
 	MaxValueLength() int
+	TruncationPolicy() *TruncationPolicy
+	EvaluationLimits() *EvaluationLimits
+	DecimalPrecision() int
+	RoundingMode() RoundingMode
+	ExpressionsVersion() ExpressionsVersion
 
 	DefaultLanguage() Language
 	DefaultLocale() Locale
 
 	LocationResolver() LocationResolver
+	LookupResolver() LookupResolver
+	GroupResolver() GroupResolver
 
 	// Convenience method to get the current time in the env timezone
 	Now() time.Time
 
+	// Convenience method to get a random number in [0.0-1.0)
+	Random() decimal.Decimal
+
 	Equal(Environment) bool
 }
 
@@ -56,7 +151,12 @@ type environment struct {
 	defaultCountry   Country
 	numberFormat     *NumberFormat
 	redactionPolicy  RedactionPolicy
+	redactionRules   *RedactionRules
 	maxValueLength   int
+	truncationPolicy *TruncationPolicy
+	evaluationLimits *EvaluationLimits
+	decimalPrecision int
+	roundingMode     RoundingMode
 }
 
 func (e *environment) DateFormat() DateFormat           { return e.dateFormat }
@@ -67,6 +167,53 @@ func (e *environment) DefaultCountry() Country          { return e.defaultCountr
 func (e *environment) NumberFormat() *NumberFormat      { return e.numberFormat }
 func (e *environment) RedactionPolicy() RedactionPolicy { return e.redactionPolicy }
 func (e *environment) MaxValueLength() int              { return e.maxValueLength }
+func (e *environment) DecimalPrecision() int            { return e.decimalPrecision }
+func (e *environment) RoundingMode() RoundingMode       { return e.roundingMode }
+
+// RedactionRules returns the redaction rules, which are empty if none have been set
+func (e *environment) RedactionRules() *RedactionRules {
+	if e.redactionRules == nil {
+		return &RedactionRules{}
+	}
+	return e.redactionRules
+}
+
+// EvaluationLimits returns the limits on template evaluation, which are empty if none have been set
+func (e *environment) EvaluationLimits() *EvaluationLimits {
+	if e.evaluationLimits == nil {
+		return &EvaluationLimits{}
+	}
+	return e.evaluationLimits
+}
+
+// ExpressionsVersion returns the version of the expressions language, which is always the original version for a
+// base environment as later versions are opted into by flows
+func (e *environment) ExpressionsVersion() ExpressionsVersion { return ExpressionsVersion1 }
+
+// TruncationPolicy returns the effective truncation policy, where field and result values fall back to the max value
+// length if they don't have their own limits
+func (e *environment) TruncationPolicy() *TruncationPolicy {
+	policy := &TruncationPolicy{FieldValue: e.maxValueLength, ResultValue: e.maxValueLength}
+
+	if e.truncationPolicy != nil {
+		if e.truncationPolicy.FieldValue > 0 {
+			policy.FieldValue = e.truncationPolicy.FieldValue
+		}
+		if e.truncationPolicy.ResultValue > 0 {
+			policy.ResultValue = e.truncationPolicy.ResultValue
+		}
+		policy.MsgText = e.truncationPolicy.MsgText
+	}
+	return policy
+}
+
+// RoundToPrecision rounds the given decimal to the decimal precision of the given environment, if it has one
+func RoundToPrecision(env Environment, d decimal.Decimal) decimal.Decimal {
+	if env.DecimalPrecision() == NoDecimalPrecision {
+		return d
+	}
+	return env.RoundingMode().Round(d, int32(env.DecimalPrecision()))
+}
 
 // DefaultLanguage is the first allowed language
 func (e *environment) DefaultLanguage() Language {
@@ -82,10 +229,15 @@ func (e *environment) DefaultLocale() Locale {
 }
 
 func (e *environment) LocationResolver() LocationResolver { return nil }
+func (e *environment) LookupResolver() LookupResolver     { return nil }
+func (e *environment) GroupResolver() GroupResolver       { return nil }
 
 // Now gets the current time in the eonvironment's timezone
 func (e *environment) Now() time.Time { return dates.Now().In(e.Timezone()) }
 
+// Random gets a random number in [0.0-1.0)
+func (e *environment) Random() decimal.Decimal { return random.Decimal() }
+
 // Equal returns true if this instance is equal to the given instance
 func (e *environment) Equal(other Environment) bool {
 	asJSON1, _ := jsonx.Marshal(e)
@@ -98,14 +250,19 @@ func (e *environment) Equal(other Environment) bool {
 //------------------------------------------------------------------------------------------
 
 type envEnvelope struct {
-	DateFormat       DateFormat      `json:"date_format" validate:"date_format"`
-	TimeFormat       TimeFormat      `json:"time_format" validate:"time_format"`
-	Timezone         string          `json:"timezone"`
-	AllowedLanguages []Language      `json:"allowed_languages,omitempty" validate:"omitempty,dive,language"`
-	NumberFormat     *NumberFormat   `json:"number_format,omitempty"`
-	DefaultCountry   Country         `json:"default_country,omitempty" validate:"omitempty,country"`
-	RedactionPolicy  RedactionPolicy `json:"redaction_policy" validate:"omitempty,eq=none|eq=urns"`
-	MaxValuelength   int             `json:"max_value_length"`
+	DateFormat       DateFormat        `json:"date_format" validate:"date_format"`
+	TimeFormat       TimeFormat        `json:"time_format" validate:"time_format"`
+	Timezone         string            `json:"timezone"`
+	AllowedLanguages []Language        `json:"allowed_languages,omitempty" validate:"omitempty,dive,language"`
+	NumberFormat     *NumberFormat     `json:"number_format,omitempty"`
+	DefaultCountry   Country           `json:"default_country,omitempty" validate:"omitempty,country"`
+	RedactionPolicy  RedactionPolicy   `json:"redaction_policy" validate:"omitempty,eq=none|eq=urns"`
+	RedactionRules   *RedactionRules   `json:"redaction_rules,omitempty" validate:"omitempty"`
+	MaxValuelength   int               `json:"max_value_length"`
+	TruncationPolicy *TruncationPolicy `json:"truncation_policy,omitempty"`
+	EvaluationLimits *EvaluationLimits `json:"evaluation_limits,omitempty" validate:"omitempty"`
+	DecimalPrecision *int              `json:"decimal_precision,omitempty" validate:"omitempty,min=0,max=9"`
+	RoundingMode     RoundingMode      `json:"rounding_mode,omitempty" validate:"omitempty,eq=half_up|eq=half_even"`
 }
 
 // ReadEnvironment reads an environment from the given JSON
@@ -124,7 +281,17 @@ func ReadEnvironment(data json.RawMessage) (Environment, error) {
 	env.defaultCountry = envelope.DefaultCountry
 	env.numberFormat = envelope.NumberFormat
 	env.redactionPolicy = envelope.RedactionPolicy
+	env.redactionRules = envelope.RedactionRules
 	env.maxValueLength = envelope.MaxValuelength
+	env.truncationPolicy = envelope.TruncationPolicy
+	env.evaluationLimits = envelope.EvaluationLimits
+
+	if envelope.DecimalPrecision != nil {
+		env.decimalPrecision = *envelope.DecimalPrecision
+	}
+	if envelope.RoundingMode != "" {
+		env.roundingMode = envelope.RoundingMode
+	}
 
 	tz, err := time.LoadLocation(envelope.Timezone)
 	if err != nil {
@@ -136,6 +303,15 @@ func ReadEnvironment(data json.RawMessage) (Environment, error) {
 }
 
 func (e *environment) toEnvelope() *envEnvelope {
+	var decimalPrecision *int
+	if e.decimalPrecision != NoDecimalPrecision {
+		decimalPrecision = &e.decimalPrecision
+	}
+	var roundingMode RoundingMode
+	if e.roundingMode != RoundingModeHalfUp {
+		roundingMode = e.roundingMode
+	}
+
 	return &envEnvelope{
 		DateFormat:       e.dateFormat,
 		TimeFormat:       e.timeFormat,
@@ -144,7 +320,12 @@ func (e *environment) toEnvelope() *envEnvelope {
 		DefaultCountry:   e.defaultCountry,
 		NumberFormat:     e.numberFormat,
 		RedactionPolicy:  e.redactionPolicy,
+		RedactionRules:   e.redactionRules,
 		MaxValuelength:   e.maxValueLength,
+		TruncationPolicy: e.truncationPolicy,
+		EvaluationLimits: e.evaluationLimits,
+		DecimalPrecision: decimalPrecision,
+		RoundingMode:     roundingMode,
 	}
 }
 
@@ -174,6 +355,8 @@ func NewBuilder() *EnvironmentBuilder {
 			numberFormat:     DefaultNumberFormat,
 			maxValueLength:   640,
 			redactionPolicy:  RedactionPolicyNone,
+			decimalPrecision: NoDecimalPrecision,
+			roundingMode:     RoundingModeHalfUp,
 		},
 	}
 }
@@ -215,10 +398,39 @@ func (b *EnvironmentBuilder) WithRedactionPolicy(redactionPolicy RedactionPolicy
 	return b
 }
 
+// WithRedactionRules sets the rules for which values are masked in events, HTTP logs and error messages
+func (b *EnvironmentBuilder) WithRedactionRules(rules *RedactionRules) *EnvironmentBuilder {
+	b.env.redactionRules = rules
+	return b
+}
+
 func (b *EnvironmentBuilder) WithMaxValueLength(maxValueLength int) *EnvironmentBuilder {
 	b.env.maxValueLength = maxValueLength
 	return b
 }
 
+func (b *EnvironmentBuilder) WithTruncationPolicy(policy *TruncationPolicy) *EnvironmentBuilder {
+	b.env.truncationPolicy = policy
+	return b
+}
+
+// WithEvaluationLimits sets the limits on the resources that evaluating a single template can use
+func (b *EnvironmentBuilder) WithEvaluationLimits(limits *EvaluationLimits) *EnvironmentBuilder {
+	b.env.evaluationLimits = limits
+	return b
+}
+
+// WithDecimalPrecision sets the number of decimal places that the results of arithmetic are rounded to
+func (b *EnvironmentBuilder) WithDecimalPrecision(places int) *EnvironmentBuilder {
+	b.env.decimalPrecision = places
+	return b
+}
+
+// WithRoundingMode sets the rounding mode used for arithmetic and formatting
+func (b *EnvironmentBuilder) WithRoundingMode(mode RoundingMode) *EnvironmentBuilder {
+	b.env.roundingMode = mode
+	return b
+}
+
 // Build returns the final environment
 func (b *EnvironmentBuilder) Build() Environment { return b.env }
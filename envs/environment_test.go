@@ -7,6 +7,7 @@ import (
 
 	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/goflow/envs"
+	"github.com/shopspring/decimal"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -46,6 +47,10 @@ func TestEnvironmentMarshaling(t *testing.T) {
 	assert.Nil(t, env.AllowedLanguages())
 	assert.Equal(t, envs.NilCountry, env.DefaultCountry())
 	assert.Equal(t, 640, env.MaxValueLength())
+	assert.Equal(t, &envs.TruncationPolicy{FieldValue: 640, ResultValue: 640}, env.TruncationPolicy())
+	assert.Equal(t, &envs.RedactionRules{}, env.RedactionRules())
+	assert.Equal(t, envs.NoDecimalPrecision, env.DecimalPrecision())
+	assert.Equal(t, envs.RoundingModeHalfUp, env.RoundingMode())
 	assert.Nil(t, env.LocationResolver())
 
 	// can create with valid values
@@ -69,6 +74,76 @@ func TestEnvironmentMarshaling(t *testing.T) {
 	data, err := jsonx.Marshal(env)
 	require.NoError(t, err)
 	assert.Equal(t, string(data), `{"date_format":"DD-MM-YYYY","time_format":"tt:mm:ss","timezone":"Africa/Kigali","allowed_languages":["eng","fra"],"number_format":{"decimal_symbol":".","digit_grouping_symbol":","},"default_country":"RW","redaction_policy":"none","max_value_length":640}`)
+
+	// can create with a truncation policy
+	env, err = envs.ReadEnvironment(json.RawMessage(`{"max_value_length": 500, "truncation_policy": {"field_value": 100, "msg_text": 320}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &envs.TruncationPolicy{FieldValue: 100, ResultValue: 500, MsgText: 320}, env.TruncationPolicy())
+
+	data, err = jsonx.Marshal(env)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"truncation_policy":{"field_value":100,"msg_text":320}`)
+
+	// can create with evaluation limits
+	env, err = envs.ReadEnvironment(json.RawMessage(`{"evaluation_limits": {"max_depth": 50, "max_output_size": 10000}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &envs.EvaluationLimits{MaxDepth: 50, MaxOutputSize: 10000}, env.EvaluationLimits())
+
+	data, err = jsonx.Marshal(env)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"evaluation_limits":{"max_depth":50,"max_output_size":10000}`)
+
+	_, err = envs.ReadEnvironment(json.RawMessage(`{"evaluation_limits": {"max_time": -1}}`))
+	assert.Error(t, err)
+
+	// can create with redaction rules
+	env, err = envs.ReadEnvironment(json.RawMessage(`{"redaction_rules": {"urn_schemes": ["tel"], "fields": ["national_id"], "headers": ["X-Api-Key"]}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &envs.RedactionRules{URNSchemes: []string{"tel"}, Fields: []string{"national_id"}, Headers: []string{"X-Api-Key"}}, env.RedactionRules())
+
+	data, err = jsonx.Marshal(env)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"redaction_rules":{"urn_schemes":["tel"],"fields":["national_id"],"headers":["X-Api-Key"]}`)
+
+	// can't create with empty rules
+	_, err = envs.ReadEnvironment(json.RawMessage(`{"redaction_rules": {"fields": [""]}}`))
+	assert.Error(t, err)
+
+	// can create with rules for PII categories
+	env, err = envs.ReadEnvironment(json.RawMessage(`{"redaction_rules": {"pii": ["name", "health"]}}`))
+	assert.NoError(t, err)
+	assert.True(t, env.RedactionRules().HasPII(envs.PIICategoryHealth))
+	assert.False(t, env.RedactionRules().HasPII(envs.PIICategoryPhone))
+	assert.False(t, env.RedactionRules().HasPII(envs.NilPIICategory))
+
+	// but not with invalid categories
+	_, err = envs.ReadEnvironment(json.RawMessage(`{"redaction_rules": {"pii": ["email"]}}`))
+	assert.EqualError(t, err, "field 'redaction_rules.pii[0]' is not a valid PII category")
+
+	// can create with a decimal precision and rounding mode
+	env, err = envs.ReadEnvironment(json.RawMessage(`{"decimal_precision": 0, "rounding_mode": "half_even"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, env.DecimalPrecision())
+	assert.Equal(t, envs.RoundingModeHalfEven, env.RoundingMode())
+
+	data, err = jsonx.Marshal(env)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"decimal_precision":0,"rounding_mode":"half_even"`)
+
+	// can't create with invalid rounding mode or precision
+	_, err = envs.ReadEnvironment(json.RawMessage(`{"rounding_mode": "sideways"}`))
+	assert.Error(t, err)
+	_, err = envs.ReadEnvironment(json.RawMessage(`{"decimal_precision": -1}`))
+	assert.Error(t, err)
+}
+
+func TestRoundToPrecision(t *testing.T) {
+	d := decimal.RequireFromString("2.345")
+
+	assert.Equal(t, "2.345", envs.RoundToPrecision(envs.NewBuilder().Build(), d).String())
+	assert.Equal(t, "2.35", envs.RoundToPrecision(envs.NewBuilder().WithDecimalPrecision(2).Build(), d).String())
+	assert.Equal(t, "2.34", envs.RoundToPrecision(envs.NewBuilder().WithDecimalPrecision(2).WithRoundingMode(envs.RoundingModeHalfEven).Build(), d).String())
+	assert.Equal(t, "2", envs.RoundToPrecision(envs.NewBuilder().WithDecimalPrecision(0).Build(), d).String())
 }
 
 func TestEnvironmentEqual(t *testing.T) {
@@ -107,6 +182,7 @@ func TestEnvironmentBuilder(t *testing.T) {
 		WithNumberFormat(&envs.NumberFormat{DecimalSymbol: "'"}).
 		WithRedactionPolicy(envs.RedactionPolicyURNs).
 		WithMaxValueLength(1024).
+		WithTruncationPolicy(&envs.TruncationPolicy{ResultValue: 256, MsgText: 160}).
 		Build()
 
 	assert.Equal(t, envs.DateFormatDayMonthYear, env.DateFormat())
@@ -117,5 +193,6 @@ func TestEnvironmentBuilder(t *testing.T) {
 	assert.Equal(t, &envs.NumberFormat{DecimalSymbol: "'"}, env.NumberFormat())
 	assert.Equal(t, envs.RedactionPolicyURNs, env.RedactionPolicy())
 	assert.Equal(t, 1024, env.MaxValueLength())
+	assert.Equal(t, &envs.TruncationPolicy{FieldValue: 1024, ResultValue: 256, MsgText: 160}, env.TruncationPolicy())
 	assert.Nil(t, env.LocationResolver())
 }
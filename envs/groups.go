@@ -0,0 +1,9 @@
+package envs
+
+// GroupResolver is used to check membership of query based groups by evaluating their queries against the current
+// state of the contact rather than relying on its stored group membership
+type GroupResolver interface {
+	// CheckQueryBasedMembership returns the name of the given group and whether the contact matches its query, or an
+	// empty name if it isn't a known query based group
+	CheckQueryBasedMembership(groupUUID string) (string, bool)
+}
@@ -5,8 +5,10 @@ import (
 	"testing"
 
 	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/utils"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLocationPaths(t *testing.T) {
@@ -103,6 +105,15 @@ func TestLocationHierarchy(t *testing.T) {
 	assert.Equal(t, []*envs.Location{}, hierarchy.FindByName("kigari", envs.LocationLevel(2), nil))    // wrong level
 	assert.Equal(t, []*envs.Location{}, hierarchy.FindByName("kigari", envs.LocationLevel(2), gasabo)) // wrong parent
 
+	// matching also tells us how the location was matched
+	assert.Equal(t, []*envs.LocationMatch{{Location: kigali, Name: "Kigali City", Confidence: 1}}, hierarchy.MatchByName("KIGALI city", envs.LocationLevel(1), nil))
+	assert.Equal(t, []*envs.LocationMatch{{Location: kigali, Name: "Kigari", Confidence: 0.9}}, hierarchy.MatchByName("kigari", envs.LocationLevel(1), rwanda))
+	assert.Equal(t, []*envs.LocationMatch{{Location: kigali, Name: "Kigali City", Confidence: 0.8}}, hierarchy.MatchByName("Kigalí  City", envs.LocationLevel(1), nil))
+	assert.Equal(t, []*envs.LocationMatch{{Location: kigali, Name: "Kigari", Confidence: 0.7}}, hierarchy.MatchByName("kigarì", envs.LocationLevel(1), nil))
+	assert.Equal(t, []*envs.LocationMatch{{Location: kigali, Name: "Kigali City", Confidence: 1}}, hierarchy.MatchByName("rwanda > kigali city", envs.LocationLevel(1), nil))
+	assert.Equal(t, []*envs.LocationMatch{}, hierarchy.MatchByName("kigarì", envs.LocationLevel(1), gasabo))
+	assert.Equal(t, []*envs.LocationMatch{}, hierarchy.MatchByName("boston", envs.LocationLevel(1), nil))
+
 	assert.Equal(t, rwanda, hierarchy.FindByPath(envs.LocationPath("RWANDA")))
 	assert.Equal(t, kigali, hierarchy.FindByPath("RWANDA > KIGALI 	 CITY"))
 	assert.Equal(t, kigali, hierarchy.FindByPath("RWANDA > KIGALI CITY."))
@@ -112,3 +123,38 @@ func TestLocationHierarchy(t *testing.T) {
 	assert.Equal(t, gasabo, hierarchy.FindByPath("rwanda > kigali city > gasabo"))
 	assert.Equal(t, ndera, hierarchy.FindByPath("rwanda > kigali city > gasabo > ndera"))
 }
+
+func TestLocationCoordinates(t *testing.T) {
+	hierarchy, err := envs.ReadLocationHierarchy(json.RawMessage(`{
+		"name": "Rwanda",
+		"children": [
+			{
+				"name": "Kigali City",
+				"boundary": [[-1.85, 29.95], [-1.85, 30.25], [-2.1, 30.25], [-2.1, 29.95]],
+				"children": [
+					{"name": "Gasabo", "latitude": -1.9, "longitude": 30.1, "boundary": [[-1.85, 30.05], [-1.85, 30.25], [-1.97, 30.25], [-1.97, 30.05]]},
+					{"name": "Nyarugenge"}
+				]
+			}
+		]
+	}`))
+	require.NoError(t, err)
+
+	rwanda := hierarchy.Root()
+	kigali := rwanda.Children()[0]
+	gasabo := kigali.Children()[0]
+
+	assert.Nil(t, rwanda.Coordinates())
+	assert.Nil(t, rwanda.Boundary())
+	assert.Equal(t, &utils.Coordinates{Latitude: -1.975, Longitude: 30.1}, kigali.Coordinates()) // center of boundary
+	assert.Len(t, kigali.Boundary(), 4)
+	assert.Equal(t, &utils.Coordinates{Latitude: -1.9, Longitude: 30.1}, gasabo.Coordinates())
+
+	assert.Equal(t, gasabo, hierarchy.FindByCoordinates(utils.NewCoordinates(-1.9, 30.1)))
+	assert.Equal(t, kigali, hierarchy.FindByCoordinates(utils.NewCoordinates(-2.0, 30.0)))
+	assert.Nil(t, hierarchy.FindByCoordinates(utils.NewCoordinates(0.3, 32.6)))
+
+	// boundaries need at least 3 points
+	_, err = envs.ReadLocationHierarchy(json.RawMessage(`{"name": "Rwanda", "boundary": [[-1.85, 29.95], [-1.85, 30.25]]}`))
+	assert.EqualError(t, err, "field 'boundary' must have a minimum of 3 items")
+}
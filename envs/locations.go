@@ -4,8 +4,13 @@ import (
 	"encoding/json"
 	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/nyaruka/goflow/utils"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
 // LocationLevel is a numeric level, e.g. 0 = country, 1 = state
@@ -18,7 +23,9 @@ type LocationPath string
 type LocationResolver interface {
 	FindLocations(string, LocationLevel, *Location) []*Location
 	FindLocationsFuzzy(string, LocationLevel, *Location) []*Location
+	MatchLocationsFuzzy(string, LocationLevel, *Location) []*LocationMatch
 	LookupLocation(LocationPath) *Location
+	FindLocationByCoordinates(utils.Coordinates) *Location
 }
 
 const (
@@ -68,6 +75,8 @@ type Location struct {
 	name     string
 	path     LocationPath
 	aliases  []string
+	coords   *utils.Coordinates
+	boundary utils.Polygon
 	parent   *Location
 	children []*Location
 }
@@ -89,6 +98,12 @@ func (l *Location) Path() LocationPath { return l.path }
 // Aliases gets the aliases of this location
 func (l *Location) Aliases() []string { return l.aliases }
 
+// Coordinates gets the coordinates of this location, which will be the center of its boundary if they weren't given
+func (l *Location) Coordinates() *utils.Coordinates { return l.coords }
+
+// Boundary gets the boundary of this location if it has one
+func (l *Location) Boundary() utils.Polygon { return l.boundary }
+
 // Parent gets the parent of this location
 func (l *Location) Parent() *Location { return l.parent }
 
@@ -125,13 +140,43 @@ func (n locationNameLookup) addLookup(name string, location *Location) {
 
 func (n locationNameLookup) lookup(name string) []*Location { return n[strings.ToLower(name)] }
 
+// how confident we are in a location match, depending on how it was made
+const (
+	locationConfidenceName                = 1.0
+	locationConfidenceAlias               = 0.9
+	locationConfidenceTransliteratedName  = 0.8
+	locationConfidenceTransliteratedAlias = 0.7
+)
+
+// LocationMatch is a location found from some text, with the name or alias which matched and a confidence from 0 to 1
+type LocationMatch struct {
+	Location   *Location
+	Name       string
+	Confidence float64
+}
+
+// letters which don't decompose into a base letter and diacritics but which people commonly type as plain letters
+var locationLetterReplacer = strings.NewReplacer("ø", "o", "ł", "l", "đ", "d", "ð", "d", "þ", "th", "ß", "ss", "æ", "ae", "œ", "oe", "ı", "i")
+
+// transliterates a location name to lowercase ASCII where possible, e.g. "Bogotá" becomes "bogota", so that names can
+// be matched however they were typed
+func transliterateLocationName(name string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, strings.ToLower(strings.TrimSpace(name)))
+	if err != nil {
+		folded = strings.ToLower(name)
+	}
+	return spaceRegex.ReplaceAllString(locationLetterReplacer.Replace(folded), " ")
+}
+
 // LocationHierarchy is a hierarical tree of locations
 type LocationHierarchy struct {
 	root *Location
 
 	// for faster lookups
-	levelLookups []locationNameLookup
-	pathLookup   locationPathLookup
+	levelLookups               []locationNameLookup
+	transliteratedLevelLookups []locationNameLookup
+	pathLookup                 locationPathLookup
 }
 
 // NewLocationHierarchy cretes a new location hierarchy
@@ -145,10 +190,12 @@ func NewLocationHierarchy(root *Location, numLevels int) *LocationHierarchy {
 func (h *LocationHierarchy) initializeFromRoot(root *Location, numLevels int) {
 	h.root = root
 	h.levelLookups = make([]locationNameLookup, numLevels)
+	h.transliteratedLevelLookups = make([]locationNameLookup, numLevels)
 	h.pathLookup = make(locationPathLookup)
 
 	for i := 0; i < numLevels; i++ {
 		h.levelLookups[i] = make(locationNameLookup)
+		h.transliteratedLevelLookups[i] = make(locationNameLookup)
 	}
 
 	// traverse the hierarchy to setup paths and lookups
@@ -172,6 +219,17 @@ func (h *LocationHierarchy) addNameLookups(location *Location) {
 	for _, alias := range location.aliases {
 		lookups.addLookup(alias, location)
 	}
+
+	// and transliterated versions of the name and aliases, only adding the location once for each
+	transliterated := h.transliteratedLevelLookups[int(location.level)]
+	seen := make(map[string]bool, len(location.aliases)+1)
+	for _, name := range append([]string{location.name}, location.aliases...) {
+		key := transliterateLocationName(name)
+		if !seen[key] {
+			transliterated.addLookup(key, location)
+			seen[key] = true
+		}
+	}
 }
 
 // Root gets the root location of this hierarchy (typically a country)
@@ -210,11 +268,88 @@ func (h *LocationHierarchy) FindByName(name string, level LocationLevel, parent
 	return []*Location{}
 }
 
+// MatchByName looks for locations like FindByName, but falls back to matching transliterated names and aliases, e.g.
+// "Bogota" matches "Bogotá". Each match records whether the name or an alias matched and how confident the match is.
+func (h *LocationHierarchy) MatchByName(name string, level LocationLevel, parent *Location) []*LocationMatch {
+	if locations := h.FindByName(name, level, parent); len(locations) > 0 {
+		matches := make([]*LocationMatch, len(locations))
+		for i, location := range locations {
+			matches[i] = newLocationMatch(location, name, false)
+		}
+		return matches
+	}
+
+	if int(level) < len(h.transliteratedLevelLookups) {
+		matches := make([]*LocationMatch, 0)
+		for _, location := range h.transliteratedLevelLookups[int(level)].lookup(transliterateLocationName(name)) {
+			if parent == nil || location.parent == parent {
+				matches = append(matches, newLocationMatch(location, name, true))
+			}
+		}
+		return matches
+	}
+	return []*LocationMatch{}
+}
+
+// creates a match for a location found by the given name, working out whether that was its name or one of its aliases
+func newLocationMatch(location *Location, name string, transliterated bool) *LocationMatch {
+	equal := strings.EqualFold
+	if transliterated {
+		equal = func(s1, s2 string) bool { return transliterateLocationName(s1) == transliterateLocationName(s2) }
+	}
+
+	// paths and names both count as matching the name
+	if equal(location.name, name) || IsPossibleLocationPath(name) {
+		confidence := locationConfidenceName
+		if transliterated {
+			confidence = locationConfidenceTransliteratedName
+		}
+		return &LocationMatch{Location: location, Name: location.name, Confidence: confidence}
+	}
+
+	confidence := locationConfidenceAlias
+	if transliterated {
+		confidence = locationConfidenceTransliteratedAlias
+	}
+	for _, alias := range location.aliases {
+		if equal(alias, name) {
+			return &LocationMatch{Location: location, Name: alias, Confidence: confidence}
+		}
+	}
+	return &LocationMatch{Location: location, Name: location.name, Confidence: confidence}
+}
+
 // FindByPath looks for a location in the hierarchy with the given path
 func (h *LocationHierarchy) FindByPath(path LocationPath) *Location {
 	return h.pathLookup.lookup(path)
 }
 
+// FindByCoordinates looks for the most specific location in the hierarchy whose boundary contains the given coordinates
+func (h *LocationHierarchy) FindByCoordinates(coords utils.Coordinates) *Location {
+	var match *Location
+
+	for location := h.root; location != nil; {
+		if location.boundary != nil {
+			if !location.boundary.Contains(coords) {
+				break
+			}
+			match = location
+		}
+
+		// descend into the first child that contains these coordinates
+		var next *Location
+		for _, child := range location.children {
+			if child.boundary != nil && child.boundary.Contains(coords) {
+				next = child
+				break
+			}
+		}
+		location = next
+	}
+
+	return match
+}
+
 func (h *LocationHierarchy) UnmarshalJSON(data []byte) error {
 	var le locationEnvelope
 	if err := utils.UnmarshalAndValidate(data, &le); err != nil {
@@ -231,9 +366,12 @@ func (h *LocationHierarchy) UnmarshalJSON(data []byte) error {
 //------------------------------------------------------------------------------------------
 
 type locationEnvelope struct {
-	Name     string              `json:"name" validate:"required"`
-	Aliases  []string            `json:"aliases,omitempty"`
-	Children []*locationEnvelope `json:"children,omitempty"`
+	Name      string              `json:"name" validate:"required"`
+	Aliases   []string            `json:"aliases,omitempty"`
+	Latitude  *float64            `json:"latitude,omitempty" validate:"omitempty,min=-90,max=90"`
+	Longitude *float64            `json:"longitude,omitempty" validate:"omitempty,min=-180,max=180"`
+	Boundary  [][2]float64        `json:"boundary,omitempty" validate:"omitempty,min=3"`
+	Children  []*locationEnvelope `json:"children,omitempty"`
 }
 
 func locationFromEnvelope(envelope *locationEnvelope, currentLevel LocationLevel, parent *Location) *Location {
@@ -244,6 +382,21 @@ func locationFromEnvelope(envelope *locationEnvelope, currentLevel LocationLevel
 		parent:  parent,
 	}
 
+	if len(envelope.Boundary) > 0 {
+		location.boundary = make(utils.Polygon, len(envelope.Boundary))
+		for i, vertex := range envelope.Boundary {
+			location.boundary[i] = utils.NewCoordinates(vertex[0], vertex[1])
+		}
+	}
+
+	if envelope.Latitude != nil && envelope.Longitude != nil {
+		coords := utils.NewCoordinates(*envelope.Latitude, *envelope.Longitude)
+		location.coords = &coords
+	} else if location.boundary != nil {
+		coords := location.boundary.Centroid()
+		location.coords = &coords
+	}
+
 	location.children = make([]*Location, len(envelope.Children))
 	for i := range envelope.Children {
 		location.children[i] = locationFromEnvelope(envelope.Children[i], currentLevel+1, location)
@@ -0,0 +1,6 @@
+package envs
+
+// LookupResolver is used to find values in lookup tables
+type LookupResolver interface {
+	LookupValue(table, keyColumn, key, returnColumn string, fuzzy bool) (string, bool, error)
+}
@@ -0,0 +1,26 @@
+package envs
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	utils.RegisterValidatorAlias("pii_category", "eq=name|eq=phone|eq=health", func(validator.FieldError) string {
+		return "is not a valid PII category"
+	})
+}
+
+// PIICategory is a category of personally identifiable information which contact fields and results can be tagged with,
+// so that redaction rules can mask all values of that category
+type PIICategory string
+
+// possible PII categories
+const (
+	PIICategoryName   PIICategory = "name"
+	PIICategoryPhone  PIICategory = "phone"
+	PIICategoryHealth PIICategory = "health"
+)
+
+// NilPIICategory is the category of values which aren't PII
+const NilPIICategory = PIICategory("")
@@ -0,0 +1,96 @@
+package excellent
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"sync"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+)
+
+// Arena is a source of scratch memory for template evaluation, i.e. the buffers used to scan templates, which is reused
+// between evaluations instead of being allocated for each one. Nothing returned from an evaluation refers to arena
+// memory, so an arena can be released as soon as its evaluations are finished. Steps, events and values aren't pooled
+// because they outlive the sprint in the session and its output. Arenas are safe for concurrent use, and a nil arena
+// is valid and just allocates as normal.
+type Arena struct {
+	mutex sync.Mutex
+	free  []*scratch
+}
+
+// scratch memory for a single scanner
+type scratch struct {
+	reader *bufio.Reader
+	expr   bytes.Buffer
+}
+
+// released arenas are kept for reuse by later callers, e.g. the next sprint
+var arenas = sync.Pool{New: func() any { return &Arena{} }}
+
+// NewArena gets an arena, reusing a previously released one if possible
+func NewArena() *Arena {
+	return arenas.Get().(*Arena)
+}
+
+// Release returns this arena for reuse. It must not be used after it has been released.
+func (a *Arena) Release() {
+	if a != nil {
+		arenas.Put(a)
+	}
+}
+
+// EvaluateTemplate is equivalent to the package level EvaluateTemplate but uses this arena for scratch memory
+func (a *Arena) EvaluateTemplate(env envs.Environment, ctx *types.XObject, template string, escaping Escaping) (string, error) {
+	scope := newEvaluationScope(env, ctx)
+
+	return evaluateTemplate(a, env, ctx, template, escaping, func(expression string) types.XValue {
+		return evaluateExpression(env, scope, expression)
+	})
+}
+
+// EvaluateTemplateValue is equivalent to the package level EvaluateTemplateValue but uses this arena for scratch memory
+func (a *Arena) EvaluateTemplateValue(env envs.Environment, ctx *types.XObject, template string) (types.XValue, error) {
+	return evaluateTemplateValue(a, env, ctx, template)
+}
+
+// creates a scanner for the given template and a function to be called when it's no longer needed
+func (a *Arena) newScanner(template string, identifierTopLevels []string) (*xscanner, func()) {
+	if a == nil {
+		return NewXScanner(strings.NewReader(template), identifierTopLevels).(*xscanner), func() {}
+	}
+
+	s := a.get()
+	s.reader.Reset(strings.NewReader(template))
+
+	scanner := &xscanner{
+		input:               newInput(s.reader),
+		expr:                &s.expr,
+		identifierTopLevels: identifierTopLevels,
+		unescapeBody:        true,
+	}
+	return scanner, func() { a.put(s) }
+}
+
+func (a *Arena) get() *scratch {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if n := len(a.free); n > 0 {
+		s := a.free[n-1]
+		a.free = a.free[:n-1]
+		return s
+	}
+	return &scratch{reader: bufio.NewReader(nil)}
+}
+
+func (a *Arena) put(s *scratch) {
+	s.reader.Reset(nil)
+	s.expr.Reset()
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.free = append(a.free, s)
+}
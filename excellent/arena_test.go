@@ -0,0 +1,113 @@
+package excellent_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArena(t *testing.T) {
+	env := envs.NewBuilder().Build()
+	ctx := types.NewXObject(map[string]types.XValue{
+		"name":  types.NewXText("Bob"),
+		"age":   types.NewXNumberFromInt(23),
+		"words": types.NewXArray(types.NewXText("one"), types.NewXText("two")),
+	})
+
+	templates := []string{
+		``,
+		`hello`,
+		`@name`,
+		`Hi @name, you are @(age + 1) next year`,
+		`@(join(words, "\", \""))`,
+		`@(upper(name)) @(lower(name)) @(len(name))`,
+		`@(` + strings.Repeat("1 + ", 500) + `1)`,
+		`@(1 / 0) and @name`,
+		`@(unclosed`,
+	}
+
+	type result struct {
+		text  string
+		value types.XValue
+		err   string
+	}
+
+	evaluate := func(arena *excellent.Arena, template string) result {
+		text, err := arena.EvaluateTemplate(env, ctx, template, nil)
+		value, _ := arena.EvaluateTemplateValue(env, ctx, template)
+		r := result{text: text, value: value}
+		if err != nil {
+			r.err = err.Error()
+		}
+		return r
+	}
+
+	// a nil arena just allocates as normal
+	expected := make([]result, len(templates))
+	for i, template := range templates {
+		expected[i] = evaluate(nil, template)
+
+		text, err := excellent.EvaluateTemplate(env, ctx, template, nil)
+		assert.Equal(t, expected[i].text, text)
+		assert.Equal(t, expected[i].err != "", err != nil)
+	}
+
+	// an arena can be used by many evaluations at the same time and gives the same results
+	arena := excellent.NewArena()
+
+	wg := &sync.WaitGroup{}
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for n := 0; n < 10; n++ {
+				for i, template := range templates {
+					assert.Equal(t, expected[i], evaluate(arena, template), "result mismatch for template %s", template)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	arena.Release()
+
+	// and once released it can be reused
+	arena = excellent.NewArena()
+	for i, template := range templates {
+		assert.Equal(t, expected[i], evaluate(arena, template))
+	}
+	arena.Release()
+
+	// releasing a nil arena is a noop
+	var nilArena *excellent.Arena
+	nilArena.Release()
+}
+
+func BenchmarkArena(b *testing.B) {
+	env := envs.NewBuilder().Build()
+	ctx := types.NewXObject(map[string]types.XValue{"name": types.NewXText("Bob"), "age": types.NewXNumberFromInt(23)})
+	template := `Hi @name, you are @(age + 1) next year and your name is @(len(name)) letters long`
+
+	b.Run("without arena", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			excellent.EvaluateTemplate(env, ctx, template, nil)
+		}
+	})
+
+	b.Run("with arena", func(b *testing.B) {
+		arena := excellent.NewArena()
+		defer arena.Release()
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			arena.EvaluateTemplate(env, ctx, template, nil)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package excellent
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/antlr/antlr4/runtime/Go/antlr/v4"
@@ -36,14 +37,70 @@ func Parse(expression string, contextCallback func([]string)) (Expression, error
 
 // EvaluateTemplate evaluates the passed in template
 func EvaluateTemplate(env envs.Environment, ctx *types.XObject, template string, escaping Escaping) (string, error) {
+	scope := newEvaluationScope(env, ctx)
+
+	return evaluateTemplate(nil, env, ctx, template, escaping, func(expression string) types.XValue {
+		return evaluateExpression(env, scope, expression)
+	})
+}
+
+// EvaluateAll evaluates each of the passed in templates against the same context, returning the results in the same
+// order. Expressions are only parsed once even if they appear in multiple templates, and errors from all the templates
+// are combined into a single error.
+func EvaluateAll(env envs.Environment, ctx *types.XObject, templates []string) ([]string, error) {
+	type parsed struct {
+		expression Expression
+		err        error
+	}
+
+	cache := make(map[string]parsed, len(templates))
+	var scope *Scope
+
+	evaluate := func(expression string) types.XValue {
+		p, cached := cache[expression]
+		if !cached {
+			p.expression, p.err = Parse(expression, nil)
+			cache[expression] = p
+		}
+		if p.err != nil {
+			return types.NewXError(p.err)
+		}
+		return evaluate(env, scope, p.expression)
+	}
+
+	results := make([]string, len(templates))
+	allErrors := NewTemplateErrors()
+
+	for i, template := range templates {
+		scope = newEvaluationScope(env, ctx)
+
+		var err error
+		results[i], err = evaluateTemplate(nil, env, ctx, template, nil, evaluate)
+		if err != nil {
+			allErrors.errors = append(allErrors.errors, err.(*TemplateErrors).errors...)
+		}
+	}
+
+	if allErrors.HasErrors() {
+		return results, allErrors
+	}
+	return results, nil
+}
+
+func evaluateTemplate(arena *Arena, env envs.Environment, ctx *types.XObject, template string, escaping Escaping, evaluate func(string) types.XValue) (string, error) {
 	var buf strings.Builder
+	limits := env.EvaluationLimits()
 
-	err := VisitTemplate(template, ctx.Properties(), func(tokenType XTokenType, token string) error {
+	err := visitTemplate(arena, template, ctx.Properties(), func(tokenType XTokenType, token string) error {
 		switch tokenType {
 		case BODY:
+			if err := checkOutputSize(limits, buf.Len()+len(token)); err != nil {
+				return err
+			}
+
 			buf.WriteString(token)
 		case IDENTIFIER, EXPRESSION:
-			value := EvaluateExpression(env, ctx, token)
+			value := evaluate(token)
 
 			// if we got an error, return that
 			if types.IsXError(value) {
@@ -58,6 +115,10 @@ func EvaluateTemplate(env envs.Environment, ctx *types.XObject, template string,
 				asString = escaping(asString)
 			}
 
+			if err := checkOutputSize(limits, buf.Len()+len(asString)); err != nil {
+				return err
+			}
+
 			buf.WriteString(asString)
 		}
 		return nil
@@ -70,14 +131,19 @@ func EvaluateTemplate(env envs.Environment, ctx *types.XObject, template string,
 // a single identifier or expression, ie: "@contact" or "@(first(contact.urns))". In these cases we return
 // the typed value from EvaluateExpression instead of stringifying the result.
 func EvaluateTemplateValue(env envs.Environment, ctx *types.XObject, template string) (types.XValue, error) {
+	return evaluateTemplateValue(nil, env, ctx, template)
+}
+
+func evaluateTemplateValue(arena *Arena, env envs.Environment, ctx *types.XObject, template string) (types.XValue, error) {
 	template = strings.TrimSpace(template)
-	scanner := NewXScanner(strings.NewReader(template), ctx.Properties())
+	scanner, release := arena.newScanner(template, ctx.Properties())
 
 	// parse our first token
 	tokenType, token := scanner.Scan()
 
 	// try to scan to our next token
 	nextTT, _ := scanner.Scan()
+	release()
 
 	// if we only have an identifier or an expression, evaluate it on its own
 	if nextTT == EOF {
@@ -88,21 +154,23 @@ func EvaluateTemplateValue(env envs.Environment, ctx *types.XObject, template st
 	}
 
 	// otherwise fallback to full template evaluation
-	asStr, err := EvaluateTemplate(env, ctx, template, nil)
+	asStr, err := arena.EvaluateTemplate(env, ctx, template, nil)
 	return types.NewXText(asStr), err
 }
 
 // EvaluateExpression evalutes the passed in Excellent expression, returning the typed value it evaluates to,
 // which might be an error, e.g. "2 / 3" or "contact.fields.age"
 func EvaluateExpression(env envs.Environment, ctx *types.XObject, expression string) types.XValue {
+	return evaluateExpression(env, newEvaluationScope(env, ctx), expression)
+}
+
+func evaluateExpression(env envs.Environment, scope *Scope, expression string) types.XValue {
 	parsed, err := Parse(expression, nil)
 	if err != nil {
 		return types.NewXError(err)
 	}
 
-	scope := NewScope(ctx, nil)
-
-	return parsed.Evaluate(env, scope)
+	return evaluate(env, scope, parsed)
 }
 
 type lookupNotation string
@@ -153,25 +221,38 @@ func resolveLookup(env envs.Environment, container types.XValue, lookup types.XV
 
 // VisitTemplate scans the given template and calls the callback for each token encountered
 func VisitTemplate(template string, allowedTopLevels []string, callback func(XTokenType, string) error) error {
+	return visitTemplate(nil, template, allowedTopLevels, callback)
+}
+
+func visitTemplate(arena *Arena, template string, allowedTopLevels []string, callback func(XTokenType, string) error) error {
 	// nothing todo for an empty template
 	if template == "" {
 		return nil
 	}
 
-	scanner := NewXScanner(strings.NewReader(template), allowedTopLevels)
+	scanner, release := arena.newScanner(template, allowedTopLevels)
+	defer release()
 	errors := NewTemplateErrors()
 
 	for tokenType, token := scanner.Scan(); tokenType != EOF; tokenType, token = scanner.Scan() {
 		err := callback(tokenType, token)
 		if err != nil {
 			var repr string
-			if tokenType == IDENTIFIER {
+			switch tokenType {
+			case BODY:
+				repr = strconv.Quote(token)
+			case IDENTIFIER:
 				repr = "@" + token
-			} else {
+			default:
 				repr = "@(" + token + ")"
 			}
 
 			errors.Add(repr, err.Error())
+
+			// once a limit has been exceeded, nothing more of the template is evaluated
+			if isLimitError(err) {
+				break
+			}
 		}
 	}
 
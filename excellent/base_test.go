@@ -201,6 +201,11 @@ func TestEvaluateTemplateValue(t *testing.T) {
 		result, err := excellent.EvaluateTemplateValue(env, ctx, tc.template)
 		assert.NoError(t, err)
 
+		// compiled templates should give the same result
+		compiled, err := excellent.CompileTemplate(tc.template, ctx.Properties()).EvaluateValue(env, ctx)
+		assert.NoError(t, err)
+		test.AssertXEqual(t, result, compiled, "compiled output mismatch for template '%s'", tc.template)
+
 		// don't check error equality - just check that we got an error if we expected one
 		if tc.expected == ERROR {
 			assert.True(t, types.IsXError(result), "expecting error, got %T{%s} evaluating template '%s'", result, result, tc.template)
@@ -339,6 +344,10 @@ func TestEvaluateTemplate(t *testing.T) {
 
 		eval, err := excellent.EvaluateTemplate(env, ctx, tc.template, nil)
 
+		compiled, compiledErr := excellent.CompileTemplate(tc.template, ctx.Properties()).Evaluate(env, ctx, nil)
+		assert.Equal(t, eval, compiled, "compiled output mismatch for template '%s'", tc.template)
+		assert.Equal(t, err, compiledErr, "compiled error mismatch for template '%s'", tc.template)
+
 		if tc.hasError {
 			assert.Error(t, err, "expected error evaluating template '%s'", tc.template)
 		} else {
@@ -363,6 +372,24 @@ func TestEvaluateTemplateWithEscaping(t *testing.T) {
 	assert.Equal(t, `Hi \"\"; DROP`, eval)
 }
 
+func TestEvaluateAll(t *testing.T) {
+	ctx := types.NewXObject(map[string]types.XValue{
+		"name":  types.NewXText("Bob"),
+		"count": types.NewXNumberFromInt(3),
+	})
+
+	env := envs.NewBuilder().Build()
+
+	results, err := excellent.EvaluateAll(env, ctx, []string{`Hi @name`, `@(count * 2) items`, ``, `@(upper(name)) @name`})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`Hi Bob`, `6 items`, ``, `BOB Bob`}, results)
+
+	// errors from all templates are combined
+	results, err = excellent.EvaluateAll(env, ctx, []string{`@(1 / 0)`, `Hi @name`, `@('x')`, `@(1 / 0)`})
+	assert.EqualError(t, err, `error evaluating @(1 / 0): division by zero, error evaluating @('x'): syntax error at 'x', error evaluating @(1 / 0): division by zero`)
+	assert.Equal(t, []string{``, `Hi Bob`, ``, ``}, results)
+}
+
 func TestEvaluationErrors(t *testing.T) {
 	env := envs.NewBuilder().Build()
 	ctx := types.NewXObject(map[string]types.XValue{
@@ -414,12 +441,85 @@ func TestEvaluationErrors(t *testing.T) {
 		assert.Equal(t, "", result)
 		assert.NotNil(t, err)
 
+		_, compiledErr := excellent.CompileTemplate(tc.template, ctx.Properties()).Evaluate(env, ctx, nil)
+		assert.Equal(t, err, compiledErr, "compiled error mismatch for template '%s'", tc.template)
+
 		if err != nil {
 			assert.Equal(t, tc.errorMsg, err.Error(), "error message mismatch for template '%s'", tc.template)
 		}
 	}
 }
 
+func TestEvaluationLimits(t *testing.T) {
+	ctx := types.NewXObject(map[string]types.XValue{
+		"name":  types.NewXText("Bob"),
+		"items": types.NewXArray(xs("a"), xs("b"), xs("c")),
+	})
+
+	// no limits by default
+	env := envs.NewBuilder().Build()
+	assert.Equal(t, &envs.EvaluationLimits{}, env.EvaluationLimits())
+
+	result, err := excellent.EvaluateTemplate(env, ctx, `@(repeat(name, 10))`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "BobBobBobBobBobBobBobBobBobBob", result)
+
+	depthLimited := envs.NewBuilder().WithEvaluationLimits(&envs.EvaluationLimits{MaxDepth: 5}).Build()
+	sizeLimited := envs.NewBuilder().WithEvaluationLimits(&envs.EvaluationLimits{MaxOutputSize: 20}).Build()
+
+	tcs := []struct {
+		env      envs.Environment
+		template string
+		output   string
+		errorMsg string
+	}{
+		{depthLimited, `@(upper(name))`, `BOB`, ``},
+		{depthLimited, `@(upper(upper(upper(upper(upper(name))))))`, ``, `error evaluating @(upper(upper(upper(upper(upper(name)))))): error calling upper(...): error calling upper(...): error calling upper(...): error calling upper(...): evaluation exceeded maximum depth of 5`},
+		{depthLimited, `@(join(foreach(items, (x) => upper(upper(upper(x)))), ""))`, ``, `error evaluating @(join(foreach(items, (x) => upper(upper(upper(x)))), "")): error calling join(...): error calling foreach(...): error calling <anon>(...): error calling upper(...): error calling upper(...): evaluation exceeded maximum depth of 5`},
+		{sizeLimited, `@(upper(name))`, `BOB`, ``},
+		{sizeLimited, `@(repeat(name, 10))`, ``, `error evaluating @(repeat(name, 10)): error calling repeat(...): evaluation exceeded maximum output size of 20 bytes`},
+		{sizeLimited, `@(name & name & name & name & name & name & name)`, ``, `error evaluating @(name & name & name & name & name & name & name): evaluation exceeded maximum output size of 20 bytes`},
+		{sizeLimited, `@name @name @name @name @name @name`, `Bob Bob Bob Bob Bob `, `error evaluating @name: evaluation exceeded maximum output size of 20 bytes`},
+		{sizeLimited, `Hello there @name, how are you?`, `Hello there Bob`, `error evaluating ", how are you?": evaluation exceeded maximum output size of 20 bytes`},
+		{sizeLimited, `@name @(repeat(name, 10)) @name @name`, `Bob `, `error evaluating @(repeat(name, 10)): error calling repeat(...): evaluation exceeded maximum output size of 20 bytes`},
+		{sizeLimited, `@name @name @name @name @name @name @name`, `Bob Bob Bob Bob Bob `, `error evaluating @name: evaluation exceeded maximum output size of 20 bytes`},
+	}
+
+	for _, tc := range tcs {
+		env := tc.env
+
+		result, err := excellent.EvaluateTemplate(env, ctx, tc.template, nil)
+		assert.Equal(t, tc.output, result, "output mismatch for template '%s'", tc.template)
+
+		_, compiledErr := excellent.CompileTemplate(tc.template, ctx.Properties()).Evaluate(env, ctx, nil)
+		assert.Equal(t, err, compiledErr, "compiled error mismatch for template '%s'", tc.template)
+
+		if tc.errorMsg != "" {
+			assert.EqualError(t, err, tc.errorMsg, "error message mismatch for template '%s'", tc.template)
+		} else {
+			assert.NoError(t, err, "unexpected error for template '%s'", tc.template)
+		}
+	}
+
+	// limit errors are structured so that callers can tell them apart from other errors
+	value := excellent.EvaluateExpression(sizeLimited, ctx, `repeat(name, 100)`)
+	limitErr := &types.LimitExceededError{}
+	assert.True(t, errors.As(value.(error), &limitErr))
+	assert.Equal(t, &types.LimitExceededError{Limit: types.LimitOutputSize, Max: 20}, limitErr)
+
+	// time is limited for each template
+	env = envs.NewBuilder().WithEvaluationLimits(&envs.EvaluationLimits{MaxTime: 1}).Build()
+	items := make([]types.XValue, 100000)
+	for i := range items {
+		items[i] = xi(i)
+	}
+	ctx = types.NewXObject(map[string]types.XValue{"items": types.NewXArray(items...)})
+
+	value = excellent.EvaluateExpression(env, ctx, `foreach(items, (x) => foreach(items, (y) => x + y))`)
+	assert.True(t, errors.As(value.(error), &limitErr))
+	assert.Equal(t, &types.LimitExceededError{Limit: types.LimitTime, Max: 1}, limitErr)
+}
+
 func TestHasExpressions(t *testing.T) {
 	topLevels := []string{"foo"}
 
@@ -19,6 +19,12 @@ func (e TemplateError) Error() string {
 	return fmt.Sprintf("error evaluating %s: %s", e.expression, e.message)
 }
 
+// Expression returns the expression where the error occurred, e.g. @(1 / 0)
+func (e TemplateError) Expression() string { return e.expression }
+
+// Message returns the message of the error
+func (e TemplateError) Message() string { return e.message }
+
 // TemplateErrors represents the list of all errors encountered during evaluation of a template
 type TemplateErrors struct {
 	errors []*TemplateError
@@ -39,6 +45,11 @@ func (e *TemplateErrors) HasErrors() bool {
 	return len(e.errors) > 0
 }
 
+// Errors returns the individual errors
+func (e *TemplateErrors) Errors() []*TemplateError {
+	return e.errors
+}
+
 // Error returns a single string describing all the errors encountered
 func (e *TemplateErrors) Error() string {
 	messages := make([]string, len(e.errors))
@@ -18,3 +18,14 @@ func RegisterXFunction(name string, f types.XFunc) {
 func Lookup(name string) *types.XFunction {
 	return XFUNCTIONS[strings.ToLower(name)]
 }
+
+// functions which are only kept so that old flows still work, and what should be used instead
+var deprecated = map[string]string{
+	"legacy_add": "the + operator or datetime_add",
+}
+
+// Deprecation returns what should be used instead of the function with the given name (case-insensitive) if it's
+// deprecated, or an empty string if it isn't
+func Deprecation(name string) string {
+	return deprecated[strings.ToLower(name)]
+}
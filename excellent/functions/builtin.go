@@ -8,17 +8,20 @@ import (
 	"net/url"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/nyaruka/gocommon/dates"
-	"github.com/nyaruka/gocommon/random"
 	"github.com/nyaruka/gocommon/urns"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/utils"
+	"github.com/nyaruka/goflow/utils/smsx"
+	"github.com/nyaruka/goflow/utils/xmlx"
+	"github.com/nyaruka/phonenumbers"
 	"github.com/shopspring/decimal"
 )
 
@@ -49,12 +52,15 @@ func init() {
 		"remove_first_word": OneTextFunction(RemoveFirstWord),
 		"word_count":        TextAndOptionalTextFunction(WordCount, types.XTextEmpty),
 		"word_slice":        InitialTextFunction(1, 3, WordSlice),
-		"field":             InitialTextFunction(2, 2, Field),
+		"field":             fieldFunction,
 		"clean":             OneTextFunction(Clean),
 		"text_slice":        InitialTextFunction(1, 3, TextSlice),
 		"lower":             OneTextFunction(Lower),
 		"regex_match":       InitialTextFunction(1, 2, RegexMatch),
+		"regex_groups":      TwoTextFunction(RegexGroups),
 		"text_length":       OneTextFunction(TextLength),
+		"text_segments":     OneTextFunction(TextSegments),
+		"is_gsm7":           OneTextFunction(IsGSM7),
 		"text_compare":      TwoTextFunction(TextCompare),
 		"repeat":            TextAndIntegerFunction(Repeat),
 		"replace":           MinAndMaxArgsCheck(3, 4, Replace),
@@ -69,27 +75,33 @@ func init() {
 		"or":  MinArgsCheck(1, Or),
 
 		// number functions
-		"round":        OneNumberAndOptionalIntegerFunction(Round, 0),
-		"round_up":     OneNumberAndOptionalIntegerFunction(RoundUp, 0),
-		"round_down":   OneNumberAndOptionalIntegerFunction(RoundDown, 0),
-		"max":          MinArgsCheck(1, Max),
-		"min":          MinArgsCheck(1, Min),
-		"mean":         MinArgsCheck(1, Mean),
-		"mod":          TwoNumberFunction(Mod),
-		"rand":         NoArgFunction(Rand),
-		"rand_between": TwoNumberFunction(RandBetween),
-		"abs":          OneNumberFunction(Abs),
+		"round":              OneNumberAndOptionalIntegerFunction(Round, 0),
+		"round_up":           OneNumberAndOptionalIntegerFunction(RoundUp, 0),
+		"round_down":         OneNumberAndOptionalIntegerFunction(RoundDown, 0),
+		"round_to_increment": TwoNumberFunction(RoundToIncrement),
+		"max":                MinArgsCheck(1, Max),
+		"min":                MinArgsCheck(1, Min),
+		"mean":               MinArgsCheck(1, Mean),
+		"mod":                TwoNumberFunction(Mod),
+		"rand":               NoArgFunction(Rand),
+		"rand_between":       TwoNumberFunction(RandBetween),
+		"abs":                OneNumberFunction(Abs),
+		"convert_unit":       MinAndMaxArgsCheck(2, 3, ConvertUnit),
+		"money":              TwoArgFunction(Money),
 
 		// datetime functions
-		"parse_datetime":      MinAndMaxArgsCheck(2, 3, ParseDateTime),
-		"datetime_from_epoch": OneNumberFunction(DateTimeFromEpoch),
-		"datetime_diff":       ThreeArgFunction(DateTimeDiff),
-		"datetime_add":        DateTimeAdd,
-		"replace_time":        TwoArgFunction(ReplaceTime),
-		"tz":                  OneDateTimeFunction(TZ),
-		"tz_offset":           OneDateTimeFunction(TZOffset),
-		"now":                 NoArgFunction(Now),
-		"epoch":               OneDateTimeFunction(Epoch),
+		"parse_datetime":        MinAndMaxArgsCheck(2, 3, ParseDateTime),
+		"datetime_from_epoch":   OneNumberFunction(DateTimeFromEpoch),
+		"datetime_diff":         ThreeArgFunction(DateTimeDiff),
+		"datetime_add":          DateTimeAdd,
+		"datetime_add_duration": TwoArgFunction(DateTimeAddDuration),
+		"duration":              MinAndMaxArgsCheck(1, 2, Duration),
+		"duration_between":      TwoArgFunction(DurationBetween),
+		"replace_time":          TwoArgFunction(ReplaceTime),
+		"tz":                    OneDateTimeFunction(TZ),
+		"tz_offset":             OneDateTimeFunction(TZOffset),
+		"now":                   NoArgFunction(Now),
+		"epoch":                 OneDateTimeFunction(Epoch),
 
 		// date functions
 		"date_from_parts": ThreeIntegerFunction(DateFromParts),
@@ -112,10 +124,16 @@ func init() {
 		// encoded text functions
 		"urn_parts":        OneTextFunction(URNParts),
 		"attachment_parts": OneTextFunction(AttachmentParts),
+		"attachment_type":  OneTextFunction(AttachmentType),
+		"attachment_url":   OneTextFunction(AttachmentURL),
 
 		// json functions
 		"json":       OneArgFunction(JSON),
 		"parse_json": OneTextFunction(ParseJSON),
+		"parse_xml":  OneTextFunction(ParseXML),
+		"jsonpath":   TwoArgFunction(JSONPath),
+		"json_set":   ThreeArgFunction(JSONSet),
+		"json_merge": MinArgsCheck(2, JSONMerge),
 
 		// formatting functions
 		"format":          OneArgFunction(Format),
@@ -124,7 +142,13 @@ func init() {
 		"format_time":     MinAndMaxArgsCheck(1, 2, FormatTime),
 		"format_location": OneTextFunction(FormatLocation),
 		"format_number":   MinAndMaxArgsCheck(1, 3, FormatNumber),
-		"format_urn":      OneTextFunction(FormatURN),
+		"format_money":    OneArgFunction(FormatMoney),
+		"format_urn":      TextAndOptionalTextFunction(FormatURN, types.NewXText("national")),
+
+		// phone functions
+		"parse_phone":   TextAndOptionalTextFunction(ParsePhone, types.XTextEmpty),
+		"format_phone":  TwoTextFunction(FormatPhone),
+		"phone_country": OneTextFunction(PhoneCountry),
 
 		// utility functions
 		"is_error":       OneArgFunction(IsError),
@@ -136,8 +160,18 @@ func init() {
 		"extract_object": MinArgsCheck(2, ExtractObject),
 		"foreach":        MinArgsCheck(2, ForEach),
 		"foreach_value":  MinArgsCheck(2, ForEachValue),
+		"filter":         MinArgsCheck(2, Filter),
+		"reduce":         NumArgsCheck(3, Reduce),
+		"lookup":         MinAndMaxArgsCheck(4, 5, TableLookup),
 
 		"keys": OneObjectFunction(Keys),
+
+		// contact functions
+		"fields_changed_since": TwoArgFunction(FieldsChangedSince),
+
+		// location functions
+		"parse_location": OneTextFunction(ParseLocation),
+		"location_path":  MinAndMaxArgsCheck(1, 2, LocationPath),
 	}
 
 	for name, fn := range builtin {
@@ -643,16 +677,34 @@ func WordCount(env envs.Environment, text types.XText, delimiters types.XText) t
 	return types.NewXNumberFromInt(len(words))
 }
 
+// the field function either splits text or looks up a contact field depending on its arguments
+func fieldFunction(env envs.Environment, args ...types.XValue) types.XValue {
+	if len(args) == 2 {
+		if object, isObject := args[0].(*types.XObject); isObject {
+			return ContactField(env, object, args[1])
+		}
+	}
+	return splitTextField(env, args...)
+}
+
+var splitTextField = InitialTextFunction(2, 2, Field)
+
 // Field splits `text` using the given `delimiter` and returns the field at `index`.
 //
 // The index starts at zero. When splitting with a space, the delimiter is considered to be all whitespace.
 //
+// If instead called with a contact and a field key, it returns the typed value of that field on the contact, which
+// is useful when the key isn't known until the flow is run.
+//
 //	@(field("a,b,c", 1, ",")) -> b
 //	@(field("a,,b,c", 1, ",")) ->
 //	@(field("a   b c", 1, " ")) -> b
 //	@(field("a		b	c	d", 1, "	")) ->
 //	@(field("a\t\tb\tc\td", 1, " ")) ->
 //	@(field("a,b,c", "foo", ",")) -> ERROR
+//	@(field(contact, "age")) -> 23
+//	@(field(contact, "AGE") + 1) -> 24
+//	@(field(contact, "xxxxx")) -> ERROR
 //
 // @function field(text, index, delimiter)
 func Field(env envs.Environment, text types.XText, args ...types.XValue) types.XValue {
@@ -795,6 +847,38 @@ func RegexMatch(env envs.Environment, text types.XText, args ...types.XValue) ty
 	return types.NewXText(groups[groupNum])
 }
 
+// RegexGroups returns the groups of the first match of the regular expression `pattern` in `text`.
+//
+// The returned object contains each group by its number, where 0 is the entire match, and named groups also by
+// their name. If there is no match, an empty object is returned.
+//
+//	@(regex_groups("Bob Smith", "(\w+) (\w+)")) -> {0: Bob Smith, 1: Bob, 2: Smith}
+//	@(regex_groups("Bob Smith", "(?P<first>\w+) (?P<last>\w+)").last) -> Smith
+//	@(regex_groups("Bob", "\d+")) -> {}
+//	@(regex_groups("abc", "[\.")) -> ERROR
+//
+// @function regex_groups(text, pattern)
+func RegexGroups(env envs.Environment, text types.XText, pattern types.XText) types.XValue {
+	exp, err := regexp.Compile(`(?mi)` + pattern.Native())
+	if err != nil {
+		return types.NewXErrorf("invalid regular expression")
+	}
+
+	matches := exp.FindStringSubmatch(text.Native())
+	groups := make(map[string]types.XValue, len(matches))
+
+	if matches != nil {
+		for i, name := range exp.SubexpNames() {
+			groups[strconv.Itoa(i)] = types.NewXText(matches[i])
+			if name != "" {
+				groups[name] = types.NewXText(matches[i])
+			}
+		}
+	}
+
+	return types.NewXObject(groups)
+}
+
 // TextLength returns the length (number of characters) of `value` when converted to text.
 //
 //	@(text_length("abc")) -> 3
@@ -805,6 +889,39 @@ func TextLength(env envs.Environment, value types.XText) types.XValue {
 	return types.NewXNumberFromInt(value.Length())
 }
 
+// TextSegments returns the segments that `text` will be split into if it's sent as SMS.
+//
+// Text which only uses GSM7 characters can be sent in a single segment of up to 160 characters, or split into segments
+// of 153 characters. Other text is sent in segments of 70 or 67 characters. Count the segments to check whether a
+// message will be too long.
+//
+//	@(count(text_segments("Hello"))) -> 1
+//	@(count(text_segments(repeat("a", 200)))) -> 2
+//	@(count(text_segments(repeat("😁", 40)))) -> 2
+//	@(text_segments(repeat("😁", 40))[0]) -> 😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁😁
+//
+// @function text_segments(text)
+func TextSegments(env envs.Environment, text types.XText) types.XValue {
+	segments := smsx.Segment(text.Native()).Segments
+
+	values := make([]types.XValue, len(segments))
+	for i, s := range segments {
+		values[i] = types.NewXText(s)
+	}
+	return types.NewXArray(values...)
+}
+
+// IsGSM7 returns whether `text` only contains characters from the GSM7 alphabet, and so can be sent as SMS without
+// needing the UCS2 encoding which makes segments shorter.
+//
+//	@(is_gsm7("Hello {world}")) -> true
+//	@(is_gsm7("Hello 😁")) -> false
+//
+// @function is_gsm7(text)
+func IsGSM7(env envs.Environment, text types.XText) types.XValue {
+	return types.NewXBoolean(smsx.IsGSM7(text.Native()))
+}
+
 // TextCompare returns the dictionary order of `text1` and `text2`.
 //
 // The return value will be -1 if `text1` comes before `text2`, 0 if they are equal
@@ -830,6 +947,11 @@ func Repeat(env envs.Environment, text types.XText, count int) types.XValue {
 		return types.NewXErrorf("must be called with a positive integer, got %d", count)
 	}
 
+	// check the output size before building it, as this is an easy way for a template to exhaust memory
+	if max := env.EvaluationLimits().MaxOutputSize; max > 0 && count > 0 && len(text.Native()) > max/count {
+		return types.NewXLimitError(types.LimitOutputSize, max)
+	}
+
 	var output bytes.Buffer
 	for j := 0; j < count; j++ {
 		output.WriteString(text.Native())
@@ -941,10 +1063,12 @@ func Abs(env envs.Environment, num types.XNumber) types.XValue {
 // Round rounds `number` to the nearest value.
 //
 // You can optionally pass in the number of decimal places to round to as `places`. If `places` < 0,
-// it will round the integer part to the nearest 10^(-places).
+// it will round the integer part to the nearest 10^(-places). Values halfway between are rounded
+// according to the rounding mode of the environment.
 //
 //	@(round(12)) -> 12
 //	@(round(12.141)) -> 12
+//	@(round(12.5)) -> 13
 //	@(round(12.6)) -> 13
 //	@(round(12.141, 2)) -> 12.14
 //	@(round(12.146, 2)) -> 12.15
@@ -953,7 +1077,7 @@ func Abs(env envs.Environment, num types.XNumber) types.XValue {
 //
 // @function round(number [,places])
 func Round(env envs.Environment, num types.XNumber, places int) types.XValue {
-	return types.NewXNumber(num.Native().Round(int32(places)))
+	return types.NewXNumber(env.RoundingMode().Round(num.Native(), int32(places)))
 }
 
 // RoundUp rounds `number` up to the nearest integer value.
@@ -1004,6 +1128,27 @@ func RoundDown(env envs.Environment, num types.XNumber, places int) types.XValue
 	return types.NewXNumber(roundedDec)
 }
 
+// RoundToIncrement rounds `number` to the nearest multiple of `increment`.
+//
+// Values halfway between two multiples are rounded according to the rounding mode of the environment.
+//
+//	@(round_to_increment(12.37, 0.05)) -> 12.35
+//	@(round_to_increment(12.375, 0.05)) -> 12.4
+//	@(round_to_increment(1234, 100)) -> 1200
+//	@(round_to_increment(12, 0)) -> ERROR
+//
+// @function round_to_increment(number, increment)
+func RoundToIncrement(env envs.Environment, num types.XNumber, increment types.XNumber) types.XValue {
+	inc := increment.Native().Abs()
+	if inc.IsZero() {
+		return types.NewXErrorf("increment must be non-zero")
+	}
+
+	multiples := env.RoundingMode().Round(num.Native().Div(inc), 0)
+
+	return types.NewXNumber(multiples.Mul(inc))
+}
+
 // Max returns the maximum value in `numbers`.
 //
 //	@(max(1, 2)) -> 2
@@ -1088,6 +1233,76 @@ func Mod(env envs.Environment, num1 types.XNumber, num2 types.XNumber) types.XVa
 	return types.NewXNumber(num1.Native().Mod(num2.Native()))
 }
 
+// ConvertUnit converts `value` from the unit `from` to the unit `to`.
+//
+// Supported units are mm, cm, m, km, in, ft, yd and mi for length, mg, g, kg, t, oz, lb and st for weight, ml, l, tsp,
+// tbsp, floz, cup, pt, qt and gal for volume, and c, f and k for temperature. If `to` is omitted, the value is converted
+// to the closest unit in the measurement system of the environment's default country, e.g. kilograms become pounds
+// in the US, and values already in that measurement system are returned unchanged.
+//
+//	@(convert_unit(2, "km", "m")) -> 2000
+//	@(convert_unit(100, "c", "f")) -> 212
+//	@(round(convert_unit(10, "lb", "kg"), 2)) -> 4.54
+//	@(round(convert_unit(5, "ft", "m"), 2)) -> 1.52
+//	@(convert_unit(5, "kg", "m")) -> ERROR
+//	@(convert_unit(5, "parsecs", "m")) -> ERROR
+//
+// @function convert_unit(value, from [,to])
+func ConvertUnit(env envs.Environment, args ...types.XValue) types.XValue {
+	value, xerr := types.ToXNumber(env, args[0])
+	if xerr != nil {
+		return xerr
+	}
+	from, xerr := types.ToXText(env, args[1])
+	if xerr != nil {
+		return xerr
+	}
+
+	var to types.XText
+	if len(args) == 3 {
+		if to, xerr = types.ToXText(env, args[2]); xerr != nil {
+			return xerr
+		}
+	}
+
+	converted, err := convertUnit(value.Native(), from.Native(), to.Native(), env.DefaultCountry().MeasurementSystem())
+	if err != nil {
+		return types.NewXError(err)
+	}
+
+	return types.NewXNumber(converted)
+}
+
+// Money creates a money value from `amount` in the ISO 4217 `currency`.
+//
+// Money values can be added to and subtracted from other money values in the same currency, but trying to combine
+// amounts in different currencies is an error.
+//
+//	@(money(1234.5, "USD")) -> USD 1234.50
+//	@(money("12", "rwf")) -> RWF 12.00
+//	@(money(10, "USD") + money(2.5, "USD")) -> USD 12.50
+//	@(money(10, "USD") + money(2.5, "EUR")) -> ERROR
+//	@(money(10, "XYZ")) -> ERROR
+//
+// @function money(amount, currency)
+func Money(env envs.Environment, amount types.XValue, currency types.XValue) types.XValue {
+	num, xerr := types.ToXNumber(env, amount)
+	if xerr != nil {
+		return xerr
+	}
+	code, xerr := types.ToXText(env, currency)
+	if xerr != nil {
+		return xerr
+	}
+
+	currencyCode := strings.ToUpper(strings.TrimSpace(code.Native()))
+	if !types.IsValidCurrency(currencyCode) {
+		return types.NewXErrorf("%s isn't a valid currency code", code.Native())
+	}
+
+	return types.NewXMoney(num.Native(), currencyCode)
+}
+
 // Rand returns a single random number between [0.0-1.0).
 //
 //	@(rand()) -> 0.6075520156746239
@@ -1095,7 +1310,7 @@ func Mod(env envs.Environment, num1 types.XNumber, num2 types.XNumber) types.XVa
 //
 // @function rand()
 func Rand(env envs.Environment) types.XValue {
-	return types.NewXNumber(random.Decimal())
+	return types.NewXNumber(env.Random())
 }
 
 // RandBetween a single random integer in the given inclusive range.
@@ -1107,7 +1322,7 @@ func Rand(env envs.Environment) types.XValue {
 func RandBetween(env envs.Environment, min types.XNumber, max types.XNumber) types.XValue {
 	span := (max.Native().Sub(min.Native())).Add(decimal.New(1, 0))
 
-	val := random.Decimal().Mul(span).Add(min.Native()).Floor()
+	val := env.Random().Mul(span).Add(min.Native()).Floor()
 
 	return types.NewXNumber(val)
 }
@@ -1306,6 +1521,106 @@ func DateTimeAdd(env envs.Environment, args ...types.XValue) types.XValue {
 	return types.NewXErrorf("unknown unit: %s, must be one of s, m, h, D, W, M, Y", unit)
 }
 
+// DateTimeAddDuration calculates the datetime value arrived at by adding `duration` to `datetime`.
+//
+// Whole days in the duration are added as calendar days, so adding a day always gives the same time on the next day
+// even if there is a daylight savings change in between.
+//
+//	@(datetime_add_duration("2017-01-15 10:45", duration(90, "m"))) -> 2017-01-15T12:15:00.000000-05:00
+//	@(datetime_add_duration("2017-01-15", "P1DT2H")) -> 2017-01-16T02:00:00.000000-05:00
+//	@(datetime_add_duration("2017-01-15", "xxx")) -> ERROR
+//
+// @function datetime_add_duration(datetime, duration)
+func DateTimeAddDuration(env envs.Environment, arg1 types.XValue, arg2 types.XValue) types.XValue {
+	date, xerr := types.ToXDateTime(env, arg1)
+	if xerr != nil {
+		return xerr
+	}
+
+	duration, xerr := types.ToXDuration(env, arg2)
+	if xerr != nil {
+		return xerr
+	}
+
+	days := duration.Native() / (24 * time.Hour)
+	remainder := duration.Native() - days*24*time.Hour
+
+	return types.NewXDateTime(date.Native().AddDate(0, 0, int(days)).Add(remainder))
+}
+
+// Duration creates a duration of `value` number of `unit`, or converts `value` to a duration if no unit is given.
+//
+// Valid units are "W" for weeks, "D" for days, "h" for hours, "m" for minutes and "s" for seconds. Text values are
+// converted from ISO 8601 durations.
+//
+//	@(duration(90, "m")) -> PT1H30M
+//	@(duration(1.5, "D")) -> P1DT12H
+//	@(duration("P1DT2H")) -> P1DT2H
+//	@(format(duration(3, "h"))) -> 3 hours
+//	@(duration(3, "Y")) -> ERROR
+//
+// @function duration(value [,unit])
+func Duration(env envs.Environment, args ...types.XValue) types.XValue {
+	if len(args) == 1 {
+		duration, xerr := types.ToXDuration(env, args[0])
+		if xerr != nil {
+			return xerr
+		}
+		return duration
+	}
+
+	value, xerr := types.ToXNumber(env, args[0])
+	if xerr != nil {
+		return xerr
+	}
+
+	unit, xerr := types.ToXText(env, args[1])
+	if xerr != nil {
+		return xerr
+	}
+
+	var size time.Duration
+	switch unit.Native() {
+	case "s":
+		size = time.Second
+	case "m":
+		size = time.Minute
+	case "h":
+		size = time.Hour
+	case "D":
+		size = 24 * time.Hour
+	case "W":
+		size = 7 * 24 * time.Hour
+	default:
+		return types.NewXErrorf("unknown unit: %s, must be one of s, m, h, D, W", unit.Native())
+	}
+
+	nanos := value.Native().Mul(decimal.NewFromInt(int64(size))).IntPart()
+	return types.NewXDuration(time.Duration(nanos))
+}
+
+// DurationBetween returns the duration between `datetime1` and `datetime2`, which is negative if `datetime2` is
+// before `datetime1`.
+//
+//	@(duration_between("2017-01-15", "2017-01-17 10:30")) -> P2DT10H30M
+//	@(duration_between("2017-01-17 10:30", "2017-01-17 10:00")) -> -PT30M
+//	@(format(duration_between("2017-01-15 10:00", "2017-01-15 11:30"))) -> 1 hour, 30 minutes
+//
+// @function duration_between(datetime1, datetime2)
+func DurationBetween(env envs.Environment, arg1 types.XValue, arg2 types.XValue) types.XValue {
+	date1, xerr := types.ToXDateTime(env, arg1)
+	if xerr != nil {
+		return xerr
+	}
+
+	date2, xerr := types.ToXDateTime(env, arg2)
+	if xerr != nil {
+		return xerr
+	}
+
+	return types.NewXDuration(date2.Native().Sub(date1.Native()))
+}
+
 // ReplaceTime returns a new datetime with the time part replaced by the `time`.
 //
 //	@(replace_time(now(), "10:30")) -> 2018-04-11T10:30:00.000000-05:00
@@ -1705,6 +2020,31 @@ func AttachmentParts(env envs.Environment, attachment types.XText) types.XValue
 	})
 }
 
+// AttachmentType returns the type of media of `attachment`, i.e. its content type without any subtype, which is
+// empty if `attachment` doesn't have a content type.
+//
+//	@(attachment_type("image/jpeg:https://example.com/test.jpg")) -> image
+//	@(attachment_type("audio:https://example.com/test.mp3")) -> audio
+//	@(attachment_type("https://example.com/test.jpg")) ->
+//
+// @function attachment_type(attachment)
+func AttachmentType(env envs.Environment, attachment types.XText) types.XValue {
+	contentType := utils.Attachment(attachment.Native()).ContentType()
+	mediaType, _, _ := strings.Cut(contentType, "/")
+
+	return types.NewXText(mediaType)
+}
+
+// AttachmentURL returns the URL of `attachment`.
+//
+//	@(attachment_url("image/jpeg:https://example.com/test.jpg")) -> https://example.com/test.jpg
+//	@(attachment_url("https://example.com/test.jpg")) -> https://example.com/test.jpg
+//
+// @function attachment_url(attachment)
+func AttachmentURL(env envs.Environment, attachment types.XText) types.XValue {
+	return types.NewXText(utils.Attachment(attachment.Native()).URL())
+}
+
 //------------------------------------------------------------------------------------------
 // JSON Functions
 //------------------------------------------------------------------------------------------
@@ -1738,6 +2078,237 @@ func JSON(env envs.Environment, value types.XValue) types.XValue {
 	return asJSON
 }
 
+// JSONPath returns an array of the values in `value` matched by the JSONPath `query`.
+//
+// Queries start with `$` and can select properties (`.name` or `['name']`), items (`[0]`, `[-1]` or slices like
+// `[1:]`), all children (`*`) or all descendants (`..`), and filter items with expressions like
+// `[?(@.price > 10 && @.in_stock)]`. Queries which visit too many values, e.g. `$..*` on a huge payload, return an error.
+//
+//	@(jsonpath(parse_json("{\"items\": [{\"name\": \"Pen\", \"price\": 5}, {\"name\": \"Book\", \"price\": 12}]}"), "$.items[?(@.price > 10)].name")) -> [Book]
+//	@(jsonpath(parse_json("{\"items\": [{\"name\": \"Pen\"}, {\"name\": \"Book\"}]}"), "$.items[*].name")) -> [Pen, Book]
+//	@(jsonpath(parse_json("{\"a\": {\"id\": 1}, \"b\": [{\"id\": 2}]}"), "$..id")) -> [1, 2]
+//	@(jsonpath(parse_json("[1, 2, 3]"), "$[-1]")) -> [3]
+//	@(jsonpath(parse_json("[1, 2, 3]"), "items")) -> ERROR
+//
+// @function jsonpath(value, query)
+func JSONPath(env envs.Environment, value types.XValue, query types.XValue) types.XValue {
+	if xerr := checkJSONContainer(value); xerr != nil {
+		return xerr
+	}
+	queryText, xerr := types.ToXText(env, query)
+	if xerr != nil {
+		return xerr
+	}
+
+	steps, err := parseJSONPath(queryText.Native())
+	if err != nil {
+		return types.NewXErrorf("invalid JSONPath query: %s", err)
+	}
+
+	matches, err := (&jsonPathEvaluator{}).evaluate(steps, value)
+	if err != nil {
+		return types.NewXError(err)
+	}
+	return types.NewXArray(matches...)
+}
+
+// JSONSet returns a copy of `value` with the property or item at `path` set to `new_value`.
+//
+// The path can only contain property names and item indexes, e.g. `$.order.items[0].qty`. Missing objects along the
+// path are created, but items must already exist.
+//
+//	@(json(json_set(parse_json("{\"name\": \"Bob\"}"), "$.age", 23))) -> {"age":23,"name":"Bob"}
+//	@(json(json_set(parse_json("{\"items\": [1, 2]}"), "$.items[1]", "x"))) -> {"items":[1,"x"]}
+//	@(json(json_set(parse_json("{}"), "$.a.b", true))) -> {"a":{"b":true}}
+//	@(json_set(parse_json("{\"items\": [1, 2]}"), "$.items[5]", 3)) -> ERROR
+//
+// @function json_set(value, path, new_value)
+func JSONSet(env envs.Environment, value types.XValue, path types.XValue, newValue types.XValue) types.XValue {
+	if xerr := checkJSONContainer(value); xerr != nil {
+		return xerr
+	}
+	pathText, xerr := types.ToXText(env, path)
+	if xerr != nil {
+		return xerr
+	}
+
+	steps, err := parseJSONPath(pathText.Native())
+	if err != nil {
+		return types.NewXErrorf("invalid JSONPath query: %s", err)
+	}
+	for _, step := range steps {
+		if step.recursive || step.wildcard || step.filter != nil || step.slice != nil || len(step.names)+len(step.indexes) != 1 {
+			return types.NewXErrorf("path for json_set can only contain property names and item indexes")
+		}
+	}
+	if len(steps) == 0 {
+		return newValue
+	}
+
+	return jsonSet(value, steps, newValue)
+}
+
+func jsonSet(value types.XValue, steps []*jsonPathStep, newValue types.XValue) types.XValue {
+	step := steps[0]
+	child := func(c types.XValue) types.XValue {
+		if len(steps) == 1 {
+			return newValue
+		}
+		return jsonSet(c, steps[1:], newValue)
+	}
+
+	if len(step.names) == 1 {
+		object, isObject := value.(*types.XObject)
+		if !isObject {
+			if !utils.IsNil(value) {
+				return types.NewXErrorf("can't set property '%s' on %s", step.names[0], types.Describe(value))
+			}
+			object = types.XObjectEmpty
+		}
+
+		// property names are matched ignoring case like everywhere else in expressions
+		props := copyXObject(object)
+		key := step.names[0]
+		for _, p := range object.Properties() {
+			if strings.EqualFold(p, key) {
+				key = p
+				break
+			}
+		}
+
+		newChild := child(props[key])
+		if xerr, isErr := newChild.(types.XError); isErr {
+			return xerr
+		}
+		props[key] = newChild
+		return types.NewXObject(props)
+	}
+
+	array, isArray := value.(*types.XArray)
+	if !isArray {
+		return types.NewXErrorf("can't set item %d on %s", step.indexes[0], types.Describe(value))
+	}
+	index := step.indexes[0]
+	if index < 0 {
+		index += array.Count()
+	}
+	if index < 0 || index >= array.Count() {
+		return types.NewXErrorf("index %d out of range for %d items", step.indexes[0], array.Count())
+	}
+
+	items := make([]types.XValue, array.Count())
+	for i := range items {
+		items[i] = array.Get(i)
+	}
+	newChild := child(items[index])
+	if xerr, isErr := newChild.(types.XError); isErr {
+		return xerr
+	}
+	items[index] = newChild
+	return types.NewXArray(items...)
+}
+
+// JSONMerge returns a new object which is a deep merge of the given `objects`.
+//
+// Properties of later objects take precedence over earlier ones. Where both values of a property are objects they are
+// merged, otherwise the later value replaces the earlier one, so arrays aren't combined.
+//
+//	@(json(json_merge(parse_json("{\"a\": 1, \"b\": {\"x\": 1}}"), parse_json("{\"b\": {\"y\": 2}, \"c\": 3}")))) -> {"a":1,"b":{"x":1,"y":2},"c":3}
+//	@(json(json_merge(parse_json("{\"tags\": [1, 2]}"), parse_json("{\"tags\": [3]}")))) -> {"tags":[3]}
+//	@(json_merge(parse_json("{}"), "foo")) -> ERROR
+//
+// @function json_merge(objects...)
+func JSONMerge(env envs.Environment, args ...types.XValue) types.XValue {
+	objects := make([]*types.XObject, len(args))
+	for i, arg := range args {
+		if xerr, isErr := arg.(types.XError); isErr {
+			return xerr
+		}
+		object, isObject := arg.(*types.XObject)
+		if !isObject {
+			return types.NewXErrorf("can only merge objects, got %s", types.Describe(arg))
+		}
+		objects[i] = object
+	}
+
+	merged := objects[0]
+	for _, object := range objects[1:] {
+		merged = jsonMerge(merged, object)
+	}
+	return merged
+}
+
+func jsonMerge(base, other *types.XObject) *types.XObject {
+	props := copyXObject(base)
+
+	for _, key := range other.Properties() {
+		value, _ := other.Get(key)
+
+		// existing properties are matched ignoring case
+		for p := range props {
+			if p != key && strings.EqualFold(p, key) {
+				props[key] = props[p]
+				delete(props, p)
+				break
+			}
+		}
+
+		baseObject, baseIsObject := props[key].(*types.XObject)
+		otherObject, otherIsObject := value.(*types.XObject)
+		if baseIsObject && otherIsObject {
+			props[key] = jsonMerge(baseObject, otherObject)
+		} else {
+			props[key] = value
+		}
+	}
+
+	return types.NewXObject(props)
+}
+
+// checks that the given value is an object or array which can be queried or updated
+func checkJSONContainer(value types.XValue) types.XError {
+	switch typed := value.(type) {
+	case types.XError:
+		return typed
+	case *types.XObject, *types.XArray:
+		return nil
+	}
+	return types.NewXErrorf("expected an object or array, got %s", types.Describe(value))
+}
+
+func copyXObject(object *types.XObject) map[string]types.XValue {
+	props := make(map[string]types.XValue, object.Count())
+	for _, p := range object.Properties() {
+		props[p], _ = object.Get(p)
+	}
+	return props
+}
+
+//------------------------------------------------------------------------------------------
+// XML Functions
+//------------------------------------------------------------------------------------------
+
+// ParseXML tries to parse `text` as an XML document.
+//
+// The result is an object with the root element as its only property. Elements which only contain text become text
+// values, and other elements become objects with a property for each child element, which is an array if that child is
+// repeated. Attributes become properties prefixed with `@`. If the given `text` is not a valid XML document, then an
+// error is returned.
+//
+//	@(parse_xml("<user><name>Bob</name></user>").user.name) -> Bob
+//	@(parse_xml("<list><item>A</item><item>B</item></list>").list.item[1]) -> B
+//	@(parse_xml("<price currency=\"USD\">12.50</price>").price["@currency"]) -> USD
+//	@(parse_xml("invalid xml")) -> ERROR
+//
+// @function parse_xml(text)
+func ParseXML(env envs.Environment, text types.XText) types.XValue {
+	asJSON, err := xmlx.ToJSON([]byte(text.Native()))
+	if err != nil {
+		return types.NewXError(err)
+	}
+	return types.JSONToXValue(asJSON)
+}
+
 //----------------------------------------------------------------------------------------
 // Formatting Functions
 //----------------------------------------------------------------------------------------
@@ -1939,7 +2510,8 @@ func FormatTime(env envs.Environment, args ...types.XValue) types.XValue {
 
 // FormatNumber formats `number` to the given number of decimal `places`.
 //
-// An optional third argument `humanize` can be false to disable the use of thousand separators.
+// An optional third argument `humanize` can be false to disable the use of thousand separators. Values
+// are rounded to `places` according to the rounding mode of the environment.
 //
 //	@(format_number(1234)) -> 1,234
 //	@(format_number(1234.5670)) -> 1,234.567
@@ -1971,9 +2543,33 @@ func FormatNumber(env envs.Environment, args ...types.XValue) types.XValue {
 		}
 	}
 
+	if places >= 0 {
+		num = types.NewXNumber(env.RoundingMode().Round(num.Native(), int32(places)))
+	}
+
 	return types.NewXText(num.FormatCustom(env.NumberFormat(), places, human.Native()))
 }
 
+// FormatMoney formats `money` using the currency symbol of the locale of the environment.
+//
+// The amount is rounded to the number of decimal places standard for the currency, according to the rounding mode of
+// the environment.
+//
+//	@(format_money(money(1234.5, "USD"))) -> $1,234.50
+//	@(format_money(money(2.5, "EUR"))) -> €2.50
+//	@(format_money(cart.total)) -> RWF 1,850
+//	@(format_money(1234)) -> ERROR
+//
+// @function format_money(money)
+func FormatMoney(env envs.Environment, value types.XValue) types.XValue {
+	money, xerr := types.ToXMoney(env, value)
+	if xerr != nil {
+		return xerr
+	}
+
+	return types.NewXText(money.FormatLocalized(env))
+}
+
 // FormatLocation formats the given `location` as its name.
 //
 //	@(format_location("Rwanda")) -> Rwanda
@@ -1985,23 +2581,136 @@ func FormatLocation(env envs.Environment, path types.XText) types.XValue {
 	return types.NewXText(strings.TrimSpace(parts[len(parts)-1]))
 }
 
-// FormatURN formats `urn` into human friendly text.
+// FormatURN formats `urn` into human friendly text. Phone numbers are formatted according to the optional `format`
+// which can be `national` (the default), `international`, or `auto` which uses the national format for numbers from the
+// default country of the environment and the international format for others. Other URNs are formatted as their
+// display value if they have one, e.g. the handle of a social media account, and otherwise as their path.
 //
 //	@(format_urn("tel:+250781234567")) -> 0781 234 567
+//	@(format_urn("tel:+250781234567", "international")) -> +250 781 234 567
+//	@(format_urn("tel:+250781234567", "auto")) -> +250 781 234 567
+//	@(format_urn("tel:+12024561111", "auto")) -> (202) 456-1111
 //	@(format_urn("twitter:134252511151#billy_bob")) -> billy_bob
 //	@(format_urn(contact.urn)) -> (202) 456-1111
 //	@(format_urn(urns.tel)) -> (202) 456-1111
 //	@(format_urn(urns.mailto)) -> foo@bar.com
+//	@(format_urn("tel:+250781234567", "foo")) -> ERROR
 //	@(format_urn("NOT URN")) -> ERROR
 //
-// @function format_urn(urn)
-func FormatURN(env envs.Environment, arg types.XText) types.XValue {
+// @function format_urn(urn [,format])
+func FormatURN(env envs.Environment, arg types.XText, format types.XText) types.XValue {
 	urn, err := urns.Parse(arg.Native())
 	if err != nil {
 		return types.NewXErrorf("%s is not a valid URN: %s", arg.Native(), err)
 	}
 
-	return types.NewXText(urn.Format())
+	phoneFormat := utils.PhoneFormat(strings.ToLower(format.Native()))
+	if phoneFormat != utils.PhoneFormatNational && phoneFormat != utils.PhoneFormatInternational && phoneFormat != "auto" {
+		return types.NewXErrorf("%s is not a valid URN format", format.Native())
+	}
+
+	if urn.Scheme() == urns.TelScheme && phoneFormat != utils.PhoneFormatNational {
+		number, err := utils.ParsePhone(urn.Path(), "")
+		if err == nil {
+			if phoneFormat == "auto" && utils.PhoneCountry(number) != string(env.DefaultCountry()) {
+				phoneFormat = utils.PhoneFormatInternational
+			}
+			if phoneFormat == utils.PhoneFormatInternational {
+				formatted, _ := utils.FormatPhone(number, phoneFormat)
+				return types.NewXText(formatted)
+			}
+		}
+	}
+
+	return types.NewXText(utils.FormatURN(urn))
+}
+
+//------------------------------------------------------------------------------------------
+// Phone Functions
+//------------------------------------------------------------------------------------------
+
+// ParsePhone parses `text`, which can be a phone number or a tel URN, into an object with the number in E164 and
+// national formats, its country and its tel URN. The optional `country` is used for numbers without a country calling
+// code, and defaults to the default country of the environment.
+//
+//	@(parse_phone("+250788383383")) -> {country: RW, e164: +250788383383, national: 0788 383 383, urn: tel:+250788383383}
+//	@(parse_phone("0788 383 383", "RW").e164) -> +250788383383
+//	@(parse_phone("(202) 456-1111").national) -> (202) 456-1111
+//	@(parse_phone("tel:+12024561111").country) -> US
+//	@(parse_phone("12345")) -> ERROR
+//
+// @function parse_phone(text [,country])
+func ParsePhone(env envs.Environment, text types.XText, country types.XText) types.XValue {
+	number, xerr := parsePhone(env, text, country)
+	if xerr != nil {
+		return xerr
+	}
+
+	e164, _ := utils.FormatPhone(number, utils.PhoneFormatE164)
+	national, _ := utils.FormatPhone(number, utils.PhoneFormatNational)
+
+	return types.NewXObject(map[string]types.XValue{
+		"e164":     types.NewXText(e164),
+		"national": types.NewXText(national),
+		"country":  types.NewXText(utils.PhoneCountry(number)),
+		"urn":      types.NewXText(string(urns.URN(urns.TelScheme + ":" + e164))),
+	})
+}
+
+// FormatPhone formats the phone number or tel URN `urn` in the given `format` which can be `E164`, `national` or
+// `international`.
+//
+//	@(format_phone("tel:+250788383383", "E164")) -> +250788383383
+//	@(format_phone("tel:+250788383383", "national")) -> 0788 383 383
+//	@(format_phone("tel:+250788383383", "international")) -> +250 788 383 383
+//	@(format_phone(urns.tel, "national")) -> (202) 456-1111
+//	@(format_phone("202-456-1111", "E164")) -> +12024561111
+//	@(format_phone("tel:+250788383383", "xxx")) -> ERROR
+//
+// @function format_phone(urn, format)
+func FormatPhone(env envs.Environment, urn types.XText, format types.XText) types.XValue {
+	number, xerr := parsePhone(env, urn, types.XTextEmpty)
+	if xerr != nil {
+		return xerr
+	}
+
+	formatted, err := utils.FormatPhone(number, utils.PhoneFormat(format.Native()))
+	if err != nil {
+		return types.NewXError(err)
+	}
+
+	return types.NewXText(formatted)
+}
+
+// PhoneCountry returns the country code of the phone number or tel URN `urn`, or an empty string if the country
+// can't be determined.
+//
+//	@(phone_country("tel:+250788383383")) -> RW
+//	@(phone_country(urns.tel)) -> US
+//	@(phone_country("+442012345678")) -> GB
+//	@(phone_country("mailto:bob@nyaruka.com")) -> ERROR
+//
+// @function phone_country(urn)
+func PhoneCountry(env envs.Environment, urn types.XText) types.XValue {
+	number, xerr := parsePhone(env, urn, types.XTextEmpty)
+	if xerr != nil {
+		return xerr
+	}
+
+	return types.NewXText(utils.PhoneCountry(number))
+}
+
+// parses the given text as a phone number, using the environment's default country if no country is given
+func parsePhone(env envs.Environment, text types.XText, country types.XText) (*phonenumbers.PhoneNumber, types.XError) {
+	if country.Empty() {
+		country = types.NewXText(string(env.DefaultCountry()))
+	}
+
+	number, err := utils.ParsePhone(text.Native(), country.Native())
+	if err != nil {
+		return nil, types.NewXErrorf("%s is not a valid phone number", text.Native())
+	}
+	return number, nil
 }
 
 //------------------------------------------------------------------------------------------
@@ -2088,6 +2797,53 @@ func Extract(env envs.Environment, arg1 types.XValue, arg2 types.XValue) types.X
 	return value
 }
 
+// TableLookup finds the row in the lookup `table` whose `key_column` matches `key` and returns its value for `return_column`.
+//
+// Keys are matched ignoring case and extra whitespace. An optional fifth argument `fuzzy` can be true to allow the
+// closest key to be matched if there's no exact match, e.g. when `key` is a misspelling. If no row matches, an empty
+// value is returned.
+//
+//	@(lookup("prices", "item", "Maize", "price")) -> 350
+//	@(lookup("prices", "item", "beans", "unit")) -> kg
+//	@(lookup("prices", "item", "Maze", "price", true)) -> 350
+//	@(default(lookup("prices", "item", "Rice", "price"), "unknown")) -> unknown
+//	@(lookup("prices", "item", "Maize", "color")) -> ERROR
+//	@(lookup("xxxxxx", "item", "Maize", "price")) -> ERROR
+//
+// @function lookup(table, key_column, key, return_column [, fuzzy])
+func TableLookup(env envs.Environment, args ...types.XValue) types.XValue {
+	texts := make([]string, 4)
+	for i := range texts {
+		text, xerr := types.ToXText(env, args[i])
+		if xerr != nil {
+			return xerr
+		}
+		texts[i] = text.Native()
+	}
+
+	fuzzy := types.XBooleanFalse
+	if len(args) == 5 {
+		var xerr types.XError
+		if fuzzy, xerr = types.ToXBoolean(args[4]); xerr != nil {
+			return xerr
+		}
+	}
+
+	resolver := env.LookupResolver()
+	if resolver == nil {
+		return types.NewXErrorf("no lookup tables available")
+	}
+
+	value, found, err := resolver.LookupValue(texts[0], texts[1], texts[2], texts[3], fuzzy.Native())
+	if err != nil {
+		return types.NewXError(err)
+	}
+	if !found {
+		return nil
+	}
+	return types.NewXText(value)
+}
+
 // ExtractObject takes an object and returns a new object by extracting only the named properties.
 //
 //	@(extract_object(contact.groups[0], "name")) -> {name: Testers}
@@ -2211,6 +2967,82 @@ func ForEachValue(env envs.Environment, args ...types.XValue) types.XValue {
 	return types.NewXObject(result)
 }
 
+// Filter creates a new array from the values in `values` for which `func` returns true.
+//
+// If the given function takes more than one argument, you can pass additional arguments after the function.
+//
+//	@(filter(array(1, 2, 3, 4), (x) => x > 2)) -> [3, 4]
+//	@(filter(array("apple", "banana", "avocado"), (x) => text_slice(x, 0, 1) = "a")) -> [apple, avocado]
+//	@(filter(array("the man", "fox", "jumped up"), (x, n) => word_count(x) = n, 2)) -> [the man, jumped up]
+//	@(filter(array(), (x) => true)) -> []
+//
+// @function filter(values, func, [args...])
+func Filter(env envs.Environment, args ...types.XValue) types.XValue {
+	array, xerr := types.ToXArray(env, args[0])
+	if xerr != nil {
+		return xerr
+	}
+
+	function, isFunction := args[1].(*types.XFunction)
+	if !isFunction {
+		return types.NewXErrorf("requires an function as its second argument")
+	}
+
+	otherArgs := args[2:]
+
+	result := make([]types.XValue, 0, array.Count())
+
+	for i := 0; i < array.Count(); i++ {
+		item := array.Get(i)
+		funcArgs := append([]types.XValue{item}, otherArgs...)
+
+		include, xerr := types.ToXBoolean(function.Call(env, funcArgs))
+		if xerr != nil {
+			return xerr
+		}
+		if include.Native() {
+			result = append(result, item)
+		}
+	}
+
+	return types.NewXArray(result...)
+}
+
+// Reduce combines the values in `values` into a single value by calling `func` with the result so far and each value
+// in turn, starting with `initial`.
+//
+//	@(reduce(array(1, 2, 3), (total, x) => total + x, 0)) -> 6
+//	@(reduce(array("a", "b", "c"), (s, x) => s & upper(x), "")) -> ABC
+//	@(reduce(array(4, 9, 2), max, 0)) -> 9
+//	@(reduce(array(), (total, x) => total + x, 10)) -> 10
+//
+// @function reduce(values, func, initial)
+func Reduce(env envs.Environment, args ...types.XValue) types.XValue {
+	array, xerr := types.ToXArray(env, args[0])
+	if xerr != nil {
+		return xerr
+	}
+
+	function, isFunction := args[1].(*types.XFunction)
+	if !isFunction {
+		return types.NewXErrorf("requires an function as its second argument")
+	}
+
+	result := args[2]
+	if types.IsXError(result) {
+		return result
+	}
+
+	for i := 0; i < array.Count(); i++ {
+		result = function.Call(env, []types.XValue{result, array.Get(i)})
+		if types.IsXError(result) {
+			return result
+		}
+	}
+
+	return result
+}
+
 // LegacyAdd simulates our old + operator, which operated differently based on whether
 // one of the parameters was a date or not. If one is a date, then the other side is
 // expected to be an integer with a number of days to add to the date, otherwise a normal
@@ -2309,3 +3141,171 @@ func ReadChars(env envs.Environment, val types.XText) types.XValue {
 
 	return types.NewXText(output.String())
 }
+
+//------------------------------------------------------------------------------------------
+// Contact Functions
+//------------------------------------------------------------------------------------------
+
+// ContactField returns the typed value of the field with the given `key` on `contact`. It's called by the field
+// function when its first argument is an object.
+func ContactField(env envs.Environment, contact *types.XObject, key types.XValue) types.XValue {
+	fields, xerr := contactProperty(env, contact, "fields")
+	if xerr != nil {
+		return xerr
+	}
+
+	keyText, xerr := types.ToXText(env, key)
+	if xerr != nil {
+		return xerr
+	}
+
+	value, exists := fields.Get(keyText.Native())
+	if !exists {
+		return types.NewXErrorf("%s is not a contact field", keyText.Native())
+	}
+	return value
+}
+
+// FieldsChangedSince returns the keys of the fields of `contact` which have been changed in this session since `datetime`.
+//
+// Passing `run.created_on` as the datetime gives the fields which were changed in the current run.
+//
+//	@(fields_changed_since(contact, "2017-01-01T00:00:00Z")) -> [age]
+//	@(fields_changed_since(contact, "2030-01-01T00:00:00Z")) -> []
+//	@(fields_changed_since(contact, "xxx")) -> ERROR
+//	@(fields_changed_since("xxx", "2017-01-01T00:00:00Z")) -> ERROR
+//
+// @function fields_changed_since(contact, datetime)
+func FieldsChangedSince(env envs.Environment, contact types.XValue, datetime types.XValue) types.XValue {
+	object, xerr := types.ToXObject(env, contact)
+	if xerr != nil {
+		return xerr
+	}
+
+	changedOn, xerr := contactProperty(env, object, "fields_changed_on")
+	if xerr != nil {
+		return xerr
+	}
+
+	since, xerr := types.ToXDateTime(env, datetime)
+	if xerr != nil {
+		return xerr
+	}
+
+	keys := make([]types.XValue, 0)
+	for _, key := range changedOn.Properties() {
+		value, _ := changedOn.Get(key)
+		changed, xerr := types.ToXDateTime(env, value)
+		if xerr == nil && !changed.Native().Before(since.Native()) {
+			keys = append(keys, types.NewXText(key))
+		}
+	}
+	return types.NewXArray(keys...)
+}
+
+// gets the named object property of a contact
+func contactProperty(env envs.Environment, contact *types.XObject, name string) (*types.XObject, types.XError) {
+	value, exists := contact.Get(name)
+	if !exists {
+		return nil, types.NewXErrorf("%s is not a contact", types.Describe(contact))
+	}
+	return types.ToXObject(env, value)
+}
+
+//------------------------------------------------------------------------------------------
+// Location Functions
+//------------------------------------------------------------------------------------------
+
+// ParseLocation resolves `text` to a location in the location hierarchy, where `text` can be coordinates, a location
+// path or the name of a state, district or ward. Coordinates resolve to the most specific location whose boundary
+// contains them.
+//
+//	@(parse_location("Rwanda > Kigali City").name) -> Kigali City
+//	@(parse_location("Gisozi").path) -> Rwanda > Kigali City > Gasabo > Gisozi
+//	@(parse_location("-1.9247,30.0633").path) -> Rwanda > Kigali City > Gasabo > Gisozi
+//	@(parse_location("Gisozi")) -> {latitude: -1.9247, level: 3, longitude: 30.0633, name: Gisozi, path: Rwanda > Kigali City > Gasabo > Gisozi}
+//	@(parse_location("Boston")) -> ERROR
+//
+// @function parse_location(text)
+func ParseLocation(env envs.Environment, text types.XText) types.XValue {
+	location, xerr := resolveLocation(env, text)
+	if xerr != nil {
+		return xerr
+	}
+
+	var latitude, longitude types.XValue
+	if coords := location.Coordinates(); coords != nil {
+		latitude = types.NewXNumber(decimal.NewFromFloat(coords.Latitude))
+		longitude = types.NewXNumber(decimal.NewFromFloat(coords.Longitude))
+	}
+
+	return types.NewXObject(map[string]types.XValue{
+		"name":      types.NewXText(location.Name()),
+		"path":      types.NewXText(string(location.Path())),
+		"level":     types.NewXNumberFromInt(int(location.Level())),
+		"latitude":  latitude,
+		"longitude": longitude,
+	})
+}
+
+// LocationPath resolves `text` to a location like [function:parse_location] and returns its path. If `level` is
+// provided then the path of its ancestor at that level is returned instead.
+//
+//	@(location_path("Gisozi")) -> Rwanda > Kigali City > Gasabo > Gisozi
+//	@(location_path("Gisozi", 1)) -> Rwanda > Kigali City
+//	@(location_path("-1.9247,30.0633", 2)) -> Rwanda > Kigali City > Gasabo
+//	@(location_path("Kigali City", 2)) -> ERROR
+//
+// @function location_path(text [,level])
+func LocationPath(env envs.Environment, args ...types.XValue) types.XValue {
+	text, xerr := types.ToXText(env, args[0])
+	if xerr != nil {
+		return xerr
+	}
+
+	location, xerr := resolveLocation(env, text)
+	if xerr != nil {
+		return xerr
+	}
+
+	if len(args) == 2 {
+		level, xerr := types.ToInteger(env, args[1])
+		if xerr != nil {
+			return xerr
+		}
+		if level < 0 || level > int(location.Level()) {
+			return types.NewXErrorf("%s has no parent location at level %d", location.Path(), level)
+		}
+
+		for int(location.Level()) > level {
+			location = location.Parent()
+		}
+	}
+
+	return types.NewXText(string(location.Path()))
+}
+
+// resolves the given text as coordinates, a location path or a location name
+func resolveLocation(env envs.Environment, text types.XText) (*envs.Location, types.XError) {
+	locations := env.LocationResolver()
+	if locations == nil {
+		return nil, types.NewXErrorf("can't find locations in environment which is not location enabled")
+	}
+
+	if coords, ok := utils.ParseCoordinates(text.Native()); ok {
+		if location := locations.FindLocationByCoordinates(coords); location != nil {
+			return location, nil
+		}
+	} else if location := locations.LookupLocation(envs.LocationPath(text.Native())); location != nil {
+		return location, nil
+	} else {
+		// try as a name at the state, district and ward levels, as long as it's not ambiguous
+		for level := envs.LocationLevel(1); level <= 3; level++ {
+			if matches := locations.FindLocations(text.Native(), level, nil); len(matches) == 1 {
+				return matches[0], nil
+			}
+		}
+	}
+
+	return nil, types.NewXErrorf("unable to find location matching %s", text.Native())
+}
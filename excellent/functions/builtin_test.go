@@ -3,15 +3,20 @@ package functions_test
 import (
 	"fmt"
 	"math"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/nyaruka/gocommon/dates"
 	"github.com/nyaruka/gocommon/random"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/excellent/functions"
 	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/test"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -25,9 +30,14 @@ var xi = types.NewXNumberFromInt
 var xdt = types.NewXDateTime
 var xd = types.NewXDate
 var xt = types.NewXTime
+var xdur = types.NewXDuration
 var xa = types.NewXArray
 var xo = types.NewXObject
+var xm = func(amount, currency string) types.XMoney {
+	return types.NewXMoney(decimal.RequireFromString(amount), currency)
+}
 var xf = functions.Lookup
+var xj = func(s string) types.XValue { return types.JSONToXValue([]byte(s)) }
 var ERROR = types.NewXErrorf("any error")
 
 func TestFunctions(t *testing.T) {
@@ -37,6 +47,50 @@ func TestFunctions(t *testing.T) {
 		WithTimeFormat(envs.TimeFormatHourMinuteAmPm).
 		WithTimezone(la).
 		Build()
+	bankers := envs.NewBuilder().WithRoundingMode(envs.RoundingModeHalfEven).Build()
+	usa := envs.NewBuilder().WithDefaultCountry("US").Build()
+	enUS := envs.NewBuilder().WithAllowedLanguages([]envs.Language{"eng"}).WithDefaultCountry("US").Build()
+	lookups := flows.NewEnvironment(dmy, flows.NewLocationAssets(nil), flows.NewLookupTableAssets([]assets.LookupTable{
+		static.NewLookupTable("prices", "Prices", []string{"item", "price"}, []map[string]string{
+			{"item": "Maize", "price": "350"},
+			{"item": "Beans", "price": "800"},
+		}),
+	}))
+
+	locations, err := envs.ReadLocationHierarchy([]byte(`{
+		"name": "Rwanda",
+		"children": [
+			{
+				"name": "Kigali City",
+				"boundary": [[-1.85, 29.95], [-1.85, 30.25], [-2.1, 30.25], [-2.1, 29.95]],
+				"children": [
+					{
+						"name": "Gasabo",
+						"boundary": [[-1.85, 30.05], [-1.85, 30.25], [-1.97, 30.25], [-1.97, 30.05]],
+						"children": [
+							{"name": "Gisozi", "latitude": -1.9247, "longitude": 30.0633, "boundary": [[-1.91, 30.05], [-1.91, 30.08], [-1.94, 30.08], [-1.94, 30.05]]},
+							{"name": "Ndera"}
+						]
+					},
+					{"name": "Nyarugenge", "children": [{"name": "Ndera"}]}
+				]
+			}
+		]
+	}`))
+	require.NoError(t, err)
+	located := flows.NewEnvironment(dmy, flows.NewLocationAssets([]assets.LocationHierarchy{locations}), nil)
+
+	contact := xo(map[string]types.XValue{
+		"name":              xs("Bob"),
+		"fields":            xo(map[string]types.XValue{"age": xi(23), "gender": nil}),
+		"fields_changed_on": xo(map[string]types.XValue{"age": xdt(time.Date(2018, 4, 11, 13, 24, 30, 0, time.UTC))}),
+	})
+
+	bigItems := make([]types.XValue, 20000)
+	for i := range bigItems {
+		bigItems[i] = xi(i)
+	}
+	bigArray := xa(bigItems...)
 
 	var funcTests = []struct {
 		name     string
@@ -73,6 +127,17 @@ func TestFunctions(t *testing.T) {
 		{"attachment_parts", dmy, []types.XValue{ERROR}, ERROR},
 		{"attachment_parts", dmy, []types.XValue{}, ERROR},
 
+		{"attachment_type", dmy, []types.XValue{xs("image/jpeg:http://s3.com/test.jpg")}, xs("image")},
+		{"attachment_type", dmy, []types.XValue{xs("video:http://s3.com/test.mp4")}, xs("video")},
+		{"attachment_type", dmy, []types.XValue{xs("not_a_thing")}, xs("")},
+		{"attachment_type", dmy, []types.XValue{ERROR}, ERROR},
+		{"attachment_type", dmy, []types.XValue{}, ERROR},
+
+		{"attachment_url", dmy, []types.XValue{xs("image/jpeg:http://s3.com/test.jpg")}, xs("http://s3.com/test.jpg")},
+		{"attachment_url", dmy, []types.XValue{xs("not_a_thing")}, xs("not_a_thing")},
+		{"attachment_url", dmy, []types.XValue{ERROR}, ERROR},
+		{"attachment_url", dmy, []types.XValue{}, ERROR},
+
 		{"boolean", dmy, []types.XValue{xs("abc")}, types.XBooleanTrue},
 		{"boolean", dmy, []types.XValue{xs("false")}, types.XBooleanFalse},
 		{"boolean", dmy, []types.XValue{xs("FALSE")}, types.XBooleanFalse},
@@ -100,6 +165,25 @@ func TestFunctions(t *testing.T) {
 			[]types.XValue{types.NewXObject(map[string]types.XValue{"a": xs("hello"), "b": xi(3)})},
 			xi(2),
 		},
+		{"convert_unit", dmy, []types.XValue{xi(2), xs("km"), xs("m")}, xi(2000)},
+		{"convert_unit", dmy, []types.XValue{xi(12), xs("IN"), xs("ft")}, xi(1)},
+		{"convert_unit", dmy, []types.XValue{xi(1), xs("lb"), xs("g")}, xn("453.59237")},
+		{"convert_unit", dmy, []types.XValue{xi(2), xs("gal"), xs("l")}, xn("7.570823568")},
+		{"convert_unit", dmy, []types.XValue{xi(1), xs("l"), xs("ml")}, xi(1000)},
+		{"convert_unit", dmy, []types.XValue{xi(100), xs("c"), xs("f")}, xi(212)},
+		{"convert_unit", dmy, []types.XValue{xi(212), xs("f"), xs("k")}, xn("373.15")},
+		{"convert_unit", dmy, []types.XValue{xi(0), xs("k"), xs("c")}, xn("-273.15")},
+		{"convert_unit", dmy, []types.XValue{xn("3.048"), xs("m")}, xn("3.048")},
+		{"convert_unit", dmy, []types.XValue{xi(10), xs("ft")}, xn("3.048")},
+		{"convert_unit", dmy, []types.XValue{xi(212), xs("f")}, xi(100)},
+		{"convert_unit", usa, []types.XValue{xn("3.048"), xs("m")}, xi(10)},
+		{"convert_unit", usa, []types.XValue{xi(10), xs("ft")}, xi(10)},
+		{"convert_unit", dmy, []types.XValue{xi(5), xs("kg"), xs("m")}, ERROR},
+		{"convert_unit", dmy, []types.XValue{xi(5), xs("parsecs"), xs("m")}, ERROR},
+		{"convert_unit", dmy, []types.XValue{xi(5), xs("m"), xs("parsecs")}, ERROR},
+		{"convert_unit", dmy, []types.XValue{xs("abc"), xs("m"), xs("ft")}, ERROR},
+		{"convert_unit", dmy, []types.XValue{xi(5)}, ERROR},
+
 		{"count", dmy, []types.XValue{xa(xs("hello"), xi(3))}, xi(2)},
 		{"count", dmy, []types.XValue{xa()}, xi(0)},
 		{"count", dmy, []types.XValue{nil}, xi(0)},
@@ -162,6 +246,31 @@ func TestFunctions(t *testing.T) {
 		{"datetime_add", dmy, []types.XValue{xs("03-12-2017"), xs("2"), ERROR}, ERROR},
 		{"datetime_add", dmy, []types.XValue{xs("22-12-2017")}, ERROR},
 
+		{"datetime_add_duration", dmy, []types.XValue{xs("03-12-2017 10:15pm"), xs("PT2H")}, xdt(time.Date(2017, 12, 4, 0, 15, 0, 0, time.UTC))},
+		{"datetime_add_duration", dmy, []types.XValue{xs("03-12-2017 10:15pm"), xdur(-30 * time.Hour)}, xdt(time.Date(2017, 12, 2, 16, 15, 0, 0, time.UTC))},
+		{"datetime_add_duration", mdy, []types.XValue{xs("03-11-2017 10:00am"), xs("P1D")}, xdt(time.Date(2017, 3, 12, 10, 0, 0, 0, la))}, // DST change
+		{"datetime_add_duration", dmy, []types.XValue{xs("xxx"), xs("PT2H")}, ERROR},
+		{"datetime_add_duration", dmy, []types.XValue{xs("03-12-2017"), xs("xxx")}, ERROR},
+		{"datetime_add_duration", dmy, []types.XValue{xs("03-12-2017")}, ERROR},
+
+		{"duration", dmy, []types.XValue{xi(90), xs("s")}, xdur(90 * time.Second)},
+		{"duration", dmy, []types.XValue{xi(90), xs("m")}, xdur(90 * time.Minute)},
+		{"duration", dmy, []types.XValue{xn("1.5"), xs("h")}, xdur(90 * time.Minute)},
+		{"duration", dmy, []types.XValue{xi(-2), xs("D")}, xdur(-48 * time.Hour)},
+		{"duration", dmy, []types.XValue{xi(1), xs("W")}, xdur(7 * 24 * time.Hour)},
+		{"duration", dmy, []types.XValue{xs("PT5M")}, xdur(5 * time.Minute)},
+		{"duration", dmy, []types.XValue{xdur(time.Minute)}, xdur(time.Minute)},
+		{"duration", dmy, []types.XValue{xs("xxx")}, ERROR},
+		{"duration", dmy, []types.XValue{xs("xxx"), xs("m")}, ERROR},
+		{"duration", dmy, []types.XValue{xi(1), xs("M")}, ERROR},
+		{"duration", dmy, []types.XValue{xi(1), ERROR}, ERROR},
+		{"duration", dmy, []types.XValue{}, ERROR},
+
+		{"duration_between", dmy, []types.XValue{xs("03-12-2017"), xs("04-12-2017 10:30")}, xdur(34*time.Hour + 30*time.Minute)},
+		{"duration_between", dmy, []types.XValue{xs("04-12-2017"), xs("03-12-2017")}, xdur(-24 * time.Hour)},
+		{"duration_between", dmy, []types.XValue{xs("xxx"), xs("03-12-2017")}, ERROR},
+		{"duration_between", dmy, []types.XValue{xs("03-12-2017"), ERROR}, ERROR},
+
 		// check across DST boundaries
 		{"datetime_add", mdy, []types.XValue{xs("03-10-2019 1:00am"), xn("3"), xs("h")}, xdt(time.Date(2019, 3, 10, 5, 0, 0, 0, la))},
 		{"datetime_add", mdy, []types.XValue{xs("03-10-2019 1:00am"), xn("24"), xs("h")}, xdt(time.Date(2019, 3, 11, 2, 0, 0, 0, la))},
@@ -261,6 +370,19 @@ func TestFunctions(t *testing.T) {
 		{"field", dmy, []types.XValue{xs("hello"), ERROR, xs(",")}, ERROR},
 		{"field", dmy, []types.XValue{xs("hello"), xs("1"), ERROR}, ERROR},
 		{"field", dmy, []types.XValue{}, ERROR},
+		{"field", dmy, []types.XValue{contact, xs("age")}, xi(23)},
+		{"field", dmy, []types.XValue{contact, xs("AGE")}, xi(23)},
+		{"field", dmy, []types.XValue{contact, xs("gender")}, nil},
+		{"field", dmy, []types.XValue{contact, xs("xxxx")}, ERROR},
+		{"field", dmy, []types.XValue{contact, ERROR}, ERROR},
+		{"field", dmy, []types.XValue{xo(map[string]types.XValue{"name": xs("Bob")}), xs("age")}, ERROR},
+
+		{"fields_changed_since", dmy, []types.XValue{contact, xs("2018-04-11T13:24:30Z")}, xa(xs("age"))},
+		{"fields_changed_since", dmy, []types.XValue{contact, xs("2018-04-11T13:24:31Z")}, xa()},
+		{"fields_changed_since", dmy, []types.XValue{contact, xs("xxx")}, ERROR},
+		{"fields_changed_since", dmy, []types.XValue{xo(map[string]types.XValue{"name": xs("Bob")}), xs("2018-04-11")}, ERROR},
+		{"fields_changed_since", dmy, []types.XValue{ERROR, xs("2018-04-11")}, ERROR},
+		{"fields_changed_since", dmy, []types.XValue{contact}, ERROR},
 
 		{"foreach", dmy, []types.XValue{xa(xs("a"), xs("b"), xs("c")), xf("upper")}, xa(xs("A"), xs("B"), xs("C"))},
 		{"foreach", dmy, []types.XValue{xa(xs("the man"), xs("fox"), xs("jumped up")), xf("word"), xi(0)}, xa(xs("the"), xs("fox"), xs("jumped"))},
@@ -293,6 +415,20 @@ func TestFunctions(t *testing.T) {
 		{"foreach_value", dmy, []types.XValue{types.NewXObject(map[string]types.XValue{"a": xs("x"), "b": xs("y")}), ERROR}, ERROR},
 		{"foreach_value", dmy, []types.XValue{types.NewXObject(map[string]types.XValue{"a": xs("x"), "b": xs("y")}), xf("abs")}, ERROR},
 
+		{"filter", dmy, []types.XValue{xa(xs("a"), ERROR, xs("b")), xf("is_error")}, xa(ERROR)},
+		{"filter", dmy, []types.XValue{xa(xs("a"), xs("b")), xf("is_error")}, xa()},
+		{"filter", dmy, []types.XValue{ERROR, xf("is_error")}, ERROR},
+		{"filter", dmy, []types.XValue{xa(xs("a"), xs("b")), ERROR}, ERROR},
+		{"filter", dmy, []types.XValue{xa(xs("a"), xs("b")), xf("abs")}, ERROR},
+
+		{"reduce", dmy, []types.XValue{xa(xi(4), xi(9), xi(2)), xf("max"), xi(0)}, xi(9)},
+		{"reduce", dmy, []types.XValue{xa(), xf("max"), xi(3)}, xi(3)},
+		{"reduce", dmy, []types.XValue{xa(xi(4), xi(9)), xf("max"), ERROR}, ERROR},
+		{"reduce", dmy, []types.XValue{ERROR, xf("max"), xi(0)}, ERROR},
+		{"reduce", dmy, []types.XValue{xa(xi(4), xi(9)), ERROR, xi(0)}, ERROR},
+		{"reduce", dmy, []types.XValue{xa(xs("a")), xf("max"), xi(0)}, ERROR},
+		{"reduce", dmy, []types.XValue{xa(xi(4), xi(9)), xf("max")}, ERROR},
+
 		{"format", dmy, []types.XValue{xn("1234")}, xs("1,234")},
 		{"format", dmy, []types.XValue{xd(dates.NewDate(2017, 6, 12))}, xs("12-06-2017")},
 		{"format", dmy, []types.XValue{xdt(time.Date(2017, 6, 12, 16, 56, 59, 0, time.UTC))}, xs("12-06-2017 16:56")},
@@ -339,6 +475,14 @@ func TestFunctions(t *testing.T) {
 		{"format_location", dmy, []types.XValue{ERROR}, ERROR},
 		{"format_location", dmy, []types.XValue{}, ERROR},
 
+		{"format_money", enUS, []types.XValue{xm("1234.5", "USD")}, xs("$1,234.50")},
+		{"format_money", enUS, []types.XValue{xm("1849.5", "RWF")}, xs("RWF 1,850")},
+		{"format_money", bankers, []types.XValue{xm("12.345", "EUR")}, xs("€12.34")},
+		{"format_money", enUS, []types.XValue{xo(map[string]types.XValue{"__default__": xm("2", "USD")})}, xs("$2.00")},
+		{"format_money", enUS, []types.XValue{xi(1234)}, ERROR},
+		{"format_money", enUS, []types.XValue{ERROR}, ERROR},
+		{"format_money", enUS, []types.XValue{}, ERROR},
+
 		{"format_number", dmy, []types.XValue{xn("1234")}, xs("1,234")},
 		{"format_number", dmy, []types.XValue{xn("1234.5670")}, xs("1,234.567")},
 		{"format_number", dmy, []types.XValue{xn("1234.5670"), xi(2)}, xs("1,234.57")},
@@ -347,17 +491,63 @@ func TestFunctions(t *testing.T) {
 		{"format_number", dmy, []types.XValue{xn("31337"), xs("xxx")}, ERROR},
 		{"format_number", dmy, []types.XValue{xn("31337"), xi(12345)}, ERROR},
 		{"format_number", dmy, []types.XValue{xn("31337"), xi(2), ERROR}, ERROR},
+		{"format_number", dmy, []types.XValue{xn("2.345"), xi(2)}, xs("2.35")},
+		{"format_number", bankers, []types.XValue{xn("2.345"), xi(2)}, xs("2.34")},
+		{"format_number", bankers, []types.XValue{xn("2.355"), xi(2)}, xs("2.36")},
 		{"format_number", dmy, []types.XValue{ERROR}, ERROR},
 		{"format_number", dmy, []types.XValue{}, ERROR},
 
 		{"format_urn", dmy, []types.XValue{xs("tel:+14132378053")}, xs("(413) 237-8053")},
 		{"format_urn", dmy, []types.XValue{xs("tel:+250781234567")}, xs("0781 234 567")},
+		{"format_urn", dmy, []types.XValue{xs("tel:+250781234567"), xs("international")}, xs("+250 781 234 567")},
+		{"format_urn", dmy, []types.XValue{xs("tel:+250781234567"), xs("auto")}, xs("+250 781 234 567")},
+		{"format_urn", usa, []types.XValue{xs("tel:+250781234567"), xs("AUTO")}, xs("+250 781 234 567")},
+		{"format_urn", usa, []types.XValue{xs("tel:+14132378053"), xs("auto")}, xs("(413) 237-8053")},
+		{"format_urn", dmy, []types.XValue{xs("tel:+14132378053"), xs("foo")}, ERROR},
+		{"format_urn", dmy, []types.XValue{xs("tel:12345"), xs("international")}, xs("12345")},
 		{"format_urn", dmy, []types.XValue{xs("twitter:134252511151#billy_bob")}, xs("billy_bob")},
+		{"format_urn", dmy, []types.XValue{xs("twitter:billy_bob"), xs("international")}, xs("billy_bob")},
 		{"format_urn", dmy, []types.XValue{xs("NOT URN")}, ERROR},
 		{"format_urn", dmy, []types.XValue{xs("")}, ERROR},
 		{"format_urn", dmy, []types.XValue{ERROR}, ERROR},
 		{"format_urn", dmy, []types.XValue{}, ERROR},
 
+		{"parse_phone", dmy, []types.XValue{xs("+250788383383")}, types.NewXObject(map[string]types.XValue{
+			"e164":     xs("+250788383383"),
+			"national": xs("0788 383 383"),
+			"country":  xs("RW"),
+			"urn":      xs("tel:+250788383383"),
+		})},
+		{"parse_phone", dmy, []types.XValue{xs("0788 383 383"), xs("RW")}, types.NewXObject(map[string]types.XValue{
+			"e164":     xs("+250788383383"),
+			"national": xs("0788 383 383"),
+			"country":  xs("RW"),
+			"urn":      xs("tel:+250788383383"),
+		})},
+		{"parse_phone", usa, []types.XValue{xs("(202) 456-1111")}, types.NewXObject(map[string]types.XValue{
+			"e164":     xs("+12024561111"),
+			"national": xs("(202) 456-1111"),
+			"country":  xs("US"),
+			"urn":      xs("tel:+12024561111"),
+		})},
+		{"parse_phone", dmy, []types.XValue{xs("0788 383 383")}, ERROR},
+		{"parse_phone", dmy, []types.XValue{xs("12345"), xs("RW")}, ERROR},
+		{"parse_phone", dmy, []types.XValue{ERROR}, ERROR},
+		{"parse_phone", dmy, []types.XValue{}, ERROR},
+
+		{"format_phone", dmy, []types.XValue{xs("tel:+250788383383"), xs("E164")}, xs("+250788383383")},
+		{"format_phone", dmy, []types.XValue{xs("tel:+250788383383"), xs("national")}, xs("0788 383 383")},
+		{"format_phone", usa, []types.XValue{xs("202-456-1111"), xs("E164")}, xs("+12024561111")},
+		{"format_phone", dmy, []types.XValue{xs("tel:+250788383383"), xs("xxx")}, ERROR},
+		{"format_phone", dmy, []types.XValue{xs("mailto:bob@nyaruka.com"), xs("E164")}, ERROR},
+		{"format_phone", dmy, []types.XValue{xs("tel:+250788383383")}, ERROR},
+
+		{"phone_country", dmy, []types.XValue{xs("tel:+250788383383")}, xs("RW")},
+		{"phone_country", dmy, []types.XValue{xs("+442012345678")}, xs("GB")},
+		{"phone_country", usa, []types.XValue{xs("202-456-1111")}, xs("US")},
+		{"phone_country", dmy, []types.XValue{xs("202-456-1111")}, ERROR},
+		{"phone_country", dmy, []types.XValue{ERROR}, ERROR},
+
 		{"html_decode", dmy, []types.XValue{xs(`Red&nbsp;&amp;&nbsp;Blue`)}, xs(`Red & Blue`)},
 		{"html_decode", dmy, []types.XValue{ERROR}, ERROR},
 		{"html_decode", dmy, []types.XValue{}, ERROR},
@@ -386,6 +576,42 @@ func TestFunctions(t *testing.T) {
 		{"json", dmy, []types.XValue{nil}, xs(`null`)},
 		{"json", dmy, []types.XValue{ERROR}, ERROR},
 
+		{"json_merge", dmy, []types.XValue{xj(`{"a": 1, "b": {"x": 1}}`), xj(`{"B": {"y": 2}}`), xj(`{"c": [1]}`)}, xj(`{"a": 1, "B": {"x": 1, "y": 2}, "c": [1]}`)},
+		{"json_merge", dmy, []types.XValue{xj(`{"a": {"x": 1}}`), xj(`{"a": 2}`)}, xj(`{"a": 2}`)},
+		{"json_merge", dmy, []types.XValue{xj(`{"a": 1}`), xa()}, ERROR},
+		{"json_merge", dmy, []types.XValue{xj(`{"a": 1}`), ERROR}, ERROR},
+		{"json_merge", dmy, []types.XValue{xj(`{"a": 1}`)}, ERROR},
+
+		{"json_set", dmy, []types.XValue{xj(`{"a": {"b": [1, {"c": 2}]}}`), xs("$.a.b[1].c"), xi(3)}, xj(`{"a": {"b": [1, {"c": 3}]}}`)},
+		{"json_set", dmy, []types.XValue{xj(`{"a": [1, 2]}`), xs("$['A'][-1]"), xs("x")}, xj(`{"a": [1, "x"]}`)},
+		{"json_set", dmy, []types.XValue{xj(`{}`), xs("$.a.b"), nil}, xj(`{"a": {"b": null}}`)},
+		{"json_set", dmy, []types.XValue{xj(`{}`), xs("$"), xi(1)}, xi(1)},
+		{"json_set", dmy, []types.XValue{xj(`{"a": 1}`), xs("$.a.b"), xi(1)}, ERROR},   // can't set property on number
+		{"json_set", dmy, []types.XValue{xj(`{}`), xs("$.a[0]"), xi(1)}, ERROR},        // can't set item on missing array
+		{"json_set", dmy, []types.XValue{xj(`{"a": []}`), xs("$.a[*]"), xi(1)}, ERROR}, // wildcards not allowed
+		{"json_set", dmy, []types.XValue{xs("abc"), xs("$.a"), xi(1)}, ERROR},
+		{"json_set", dmy, []types.XValue{xj(`{}`), ERROR, xi(1)}, ERROR},
+
+		{"jsonpath", dmy, []types.XValue{xj(`{"a": {"b": "x"}}`), xs("$.a.b")}, xa(xs("x"))},
+		{"jsonpath", dmy, []types.XValue{xj(`{"a": {"b": "x"}}`), xs("$['a'][\"b\"]")}, xa(xs("x"))},
+		{"jsonpath", dmy, []types.XValue{xj(`{"a": {"b": "x"}}`), xs("$.a.c")}, xa()},
+		{"jsonpath", dmy, []types.XValue{xj(`[1, 2, 3, 4, 5]`), xs("$[1:3]")}, xa(xi(2), xi(3))},
+		{"jsonpath", dmy, []types.XValue{xj(`[1, 2, 3, 4, 5]`), xs("$[::-2]")}, xa(xi(5), xi(3), xi(1))},
+		{"jsonpath", dmy, []types.XValue{xj(`[1, 2, 3, 4, 5]`), xs("$[0,-1,9]")}, xa(xi(1), xi(5))},
+		{"jsonpath", dmy, []types.XValue{xj(`{"b": 2, "a": 1}`), xs("$.*")}, xa(xi(1), xi(2))},
+		{"jsonpath", dmy, []types.XValue{xj(`{"a": {"id": 1, "b": {"id": 2}}, "id": 3}`), xs("$..id")}, xa(xi(3), xi(1), xi(2))},
+		{"jsonpath", dmy, []types.XValue{xj(`[{"n": "a", "p": 5, "ok": true}, {"n": "b", "p": 12}, {"n": "c", "p": 20, "ok": true}]`), xs("$[?(@.p > 10 && @.ok)].n")}, xa(xs("c"))},
+		{"jsonpath", dmy, []types.XValue{xj(`[{"n": "a", "p": 5, "ok": true}, {"n": "b", "p": 12}, {"n": "c", "p": 20, "ok": true}]`), xs("$[?(@.p <= 5 || @.n == 'b')].n")}, xa(xs("a"), xs("b"))},
+		{"jsonpath", dmy, []types.XValue{xj(`[{"n": "a", "p": 5, "ok": true}, {"n": "b", "p": 12}, {"n": "c", "p": 20, "ok": true}]`), xs("$[?(@.ok != true)].n")}, xa(xs("b"))},
+		{"jsonpath", dmy, []types.XValue{xj(`[{"n": "a", "p": 5}, {"n": "b", "p": "x"}]`), xs("$[?(@.p > 1)].n")}, xa(xs("a"))}, // can't order different types
+		{"jsonpath", dmy, []types.XValue{bigArray, xs("$[*]")}, ERROR},                                                          // too many values
+		{"jsonpath", dmy, []types.XValue{xj(`[1]`), xs("[0]")}, ERROR},
+		{"jsonpath", dmy, []types.XValue{xj(`[1]`), xs("$[0")}, ERROR},
+		{"jsonpath", dmy, []types.XValue{xj(`[1]`), xs("$[?(@.a > x)]")}, ERROR},
+		{"jsonpath", dmy, []types.XValue{xs("abc"), xs("$")}, ERROR},
+		{"jsonpath", dmy, []types.XValue{ERROR, xs("$")}, ERROR},
+		{"jsonpath", dmy, []types.XValue{xj(`[1]`), ERROR}, ERROR},
+
 		{"legacy_add", dmy, []types.XValue{xs("01-12-2017"), xi(2)}, xdt(time.Date(2017, 12, 3, 0, 0, 0, 0, time.UTC))},
 		{"legacy_add", dmy, []types.XValue{xs("2"), xs("01-12-2017 10:15:33pm")}, xdt(time.Date(2017, 12, 3, 22, 15, 33, 0, time.UTC))},
 		{"legacy_add", dmy, []types.XValue{xs("2"), xs("3.5")}, xn("5.5")},
@@ -400,12 +626,33 @@ func TestFunctions(t *testing.T) {
 		{"legacy_add", mdy, []types.XValue{xs("03-10-2019 1:00am"), xn("1")}, xdt(time.Date(2019, 3, 11, 1, 0, 0, 0, la))},
 		{"legacy_add", mdy, []types.XValue{xs("11-03-2019 1:00am"), xn("1")}, xdt(time.Date(2019, 11, 4, 1, 0, 0, 0, la))},
 
+		{"location_path", located, []types.XValue{xs("Gisozi")}, xs("Rwanda > Kigali City > Gasabo > Gisozi")},
+		{"location_path", located, []types.XValue{xs("Gisozi"), xi(0)}, xs("Rwanda")},
+		{"location_path", located, []types.XValue{xs("Gisozi"), xi(2)}, xs("Rwanda > Kigali City > Gasabo")},
+		{"location_path", located, []types.XValue{xs("-1.96,30.2"), xs("1")}, xs("Rwanda > Kigali City")},
+		{"location_path", located, []types.XValue{xs("Gasabo"), xi(3)}, ERROR},
+		{"location_path", located, []types.XValue{xs("Gisozi"), xi(-1)}, ERROR},
+		{"location_path", located, []types.XValue{xs("Gisozi"), xs("x")}, ERROR},
+		{"location_path", located, []types.XValue{ERROR}, ERROR},
+		{"location_path", dmy, []types.XValue{xs("Gisozi")}, ERROR},
+		{"location_path", located, []types.XValue{}, ERROR},
+
 		{"lower", dmy, []types.XValue{xs("HEllo")}, xs("hello")},
 		{"lower", dmy, []types.XValue{xs("  HELLO  WORLD")}, xs("  hello  world")},
 		{"lower", dmy, []types.XValue{xs("")}, xs("")},
 		{"lower", dmy, []types.XValue{xs("😁")}, xs("😁")},
 		{"lower", dmy, []types.XValue{}, ERROR},
 
+		{"lookup", lookups, []types.XValue{xs("prices"), xs("item"), xs("Maize"), xs("price")}, xs("350")},
+		{"lookup", lookups, []types.XValue{xs("prices"), xs("item"), xs("BEANS"), xs("price")}, xs("800")},
+		{"lookup", lookups, []types.XValue{xs("prices"), xs("item"), xs("Beens"), xs("price")}, nil},
+		{"lookup", lookups, []types.XValue{xs("prices"), xs("item"), xs("Beens"), xs("price"), types.XBooleanTrue}, xs("800")},
+		{"lookup", lookups, []types.XValue{xs("prices"), xs("item"), xs("Maize"), xs("color")}, ERROR},
+		{"lookup", lookups, []types.XValue{xs("colors"), xs("item"), xs("Maize"), xs("price")}, ERROR},
+		{"lookup", lookups, []types.XValue{xs("prices"), ERROR, xs("Maize"), xs("price")}, ERROR},
+		{"lookup", lookups, []types.XValue{xs("prices"), xs("item"), xs("Maize")}, ERROR},
+		{"lookup", dmy, []types.XValue{xs("prices"), xs("item"), xs("Maize"), xs("price")}, ERROR},
+
 		{"max", dmy, []types.XValue{xs("10.5"), xs("11")}, xi(11)},
 		{"max", dmy, []types.XValue{xs("10.2"), xs("9")}, xn("10.2")},
 		{"max", dmy, []types.XValue{xs("not_num"), xs("9")}, ERROR},
@@ -424,6 +671,13 @@ func TestFunctions(t *testing.T) {
 		{"mean", dmy, []types.XValue{xs("9"), xs("not_num")}, ERROR},
 		{"mean", dmy, []types.XValue{}, ERROR},
 
+		{"money", dmy, []types.XValue{xn("1234.5"), xs("USD")}, xm("1234.5", "USD")},
+		{"money", dmy, []types.XValue{xs("12"), xs(" rwf ")}, xm("12", "RWF")},
+		{"money", dmy, []types.XValue{xs("abc"), xs("USD")}, ERROR},
+		{"money", dmy, []types.XValue{xi(12), xs("XYZ")}, ERROR},
+		{"money", dmy, []types.XValue{xi(12), ERROR}, ERROR},
+		{"money", dmy, []types.XValue{xi(12)}, ERROR},
+
 		{"mod", dmy, []types.XValue{xs("10"), xs("3")}, xi(1)},
 		{"mod", dmy, []types.XValue{xs("10"), xs("5")}, xi(0)},
 		{"mod", dmy, []types.XValue{xs("not_num"), xs("3")}, ERROR},
@@ -450,6 +704,17 @@ func TestFunctions(t *testing.T) {
 		{"or", dmy, []types.XValue{ERROR}, ERROR},
 		{"or", dmy, []types.XValue{}, ERROR},
 
+		{"parse_location", located, []types.XValue{xs("Gisozi")}, xo(map[string]types.XValue{"name": xs("Gisozi"), "path": xs("Rwanda > Kigali City > Gasabo > Gisozi"), "level": xi(3), "latitude": xn("-1.9247"), "longitude": xn("30.0633")})},
+		{"parse_location", located, []types.XValue{xs("rwanda > kigali city > gasabo > gisozi")}, xo(map[string]types.XValue{"name": xs("Gisozi"), "path": xs("Rwanda > Kigali City > Gasabo > Gisozi"), "level": xi(3), "latitude": xn("-1.9247"), "longitude": xn("30.0633")})},
+		{"parse_location", located, []types.XValue{xs("-1.92,30.06")}, xo(map[string]types.XValue{"name": xs("Gisozi"), "path": xs("Rwanda > Kigali City > Gasabo > Gisozi"), "level": xi(3), "latitude": xn("-1.9247"), "longitude": xn("30.0633")})},
+		{"parse_location", located, []types.XValue{xs("Rwanda")}, xo(map[string]types.XValue{"name": xs("Rwanda"), "path": xs("Rwanda"), "level": xi(0), "latitude": nil, "longitude": nil})},
+		{"parse_location", located, []types.XValue{xs("-1.96,30.2")}, xo(map[string]types.XValue{"name": xs("Gasabo"), "path": xs("Rwanda > Kigali City > Gasabo"), "level": xi(2), "latitude": xn("-1.91"), "longitude": xn("30.15")})},
+		{"parse_location", located, []types.XValue{xs("Ndera")}, ERROR}, // ambiguous
+		{"parse_location", located, []types.XValue{xs("0.5,32.5")}, ERROR},
+		{"parse_location", located, []types.XValue{xs("Boston")}, ERROR},
+		{"parse_location", located, []types.XValue{ERROR}, ERROR},
+		{"parse_location", dmy, []types.XValue{xs("Gisozi")}, ERROR},
+
 		{"parse_time", dmy, []types.XValue{xs("15:28"), xs("tt:mm")}, xt(dates.NewTimeOfDay(15, 28, 0, 0))},
 		{"parse_time", dmy, []types.XValue{xs("2:40 pm"), xs("h:mm aa")}, xt(dates.NewTimeOfDay(14, 40, 0, 0))},
 		{"parse_time", dmy, []types.XValue{xs("xxxx"), xs("tt:mm")}, ERROR}, // unparseable input
@@ -474,6 +739,10 @@ func TestFunctions(t *testing.T) {
 		{"parse_json", dmy, []types.XValue{xs(`{a: b}`)}, ERROR},
 		{"parse_json", dmy, []types.XValue{ERROR}, ERROR},
 
+		{"parse_xml", dmy, []types.XValue{xs(`<name>Bob</name>`)}, types.NewXObject(map[string]types.XValue{"name": xs("Bob")})},
+		{"parse_xml", dmy, []types.XValue{xs(`{"name": "Bob"}`)}, ERROR},
+		{"parse_xml", dmy, []types.XValue{ERROR}, ERROR},
+
 		{"percent", dmy, []types.XValue{xs(".54")}, xs("54%")},
 		{"percent", dmy, []types.XValue{xs("1.246")}, xs("125%")},
 		{"percent", dmy, []types.XValue{xs("")}, ERROR},
@@ -499,6 +768,12 @@ func TestFunctions(t *testing.T) {
 		{"regex_match", dmy, []types.XValue{xs("zAbc"), ERROR}, ERROR},                    // regex is error
 		{"regex_match", dmy, []types.XValue{xs("zAbc"), xs(`a\w`), ERROR}, ERROR},         // group is error
 
+		{"regex_groups", dmy, []types.XValue{xs("<html>"), xs(`<(\w+)>`)}, types.NewXObject(map[string]types.XValue{"0": xs("<html>"), "1": xs("html")})},
+		{"regex_groups", dmy, []types.XValue{xs("Bob Smith"), xs(`(?P<first>\w+) (?P<last>\w+)?`)}, types.NewXObject(map[string]types.XValue{"0": xs("Bob Smith"), "1": xs("Bob"), "2": xs("Smith"), "first": xs("Bob"), "last": xs("Smith")})},
+		{"regex_groups", dmy, []types.XValue{xs("Bob"), xs(`(\w+) (\w+)?`)}, types.NewXObject(map[string]types.XValue{})}, // no match
+		{"regex_groups", dmy, []types.XValue{xs("<html>"), xs(`(??`)}, ERROR},                                             // invalid regex
+		{"regex_groups", dmy, []types.XValue{ERROR, xs(`a\w`)}, ERROR},
+
 		{"remove_first_word", dmy, []types.XValue{xs("hello World")}, xs("World")},
 		{"remove_first_word", dmy, []types.XValue{xs("hello")}, xs("")},
 		{"remove_first_word", dmy, []types.XValue{xs(`"hello"`)}, xs("")},    // " ignored when extracting words
@@ -554,6 +829,9 @@ func TestFunctions(t *testing.T) {
 		{"round", dmy, []types.XValue{xs("12.56"), xs("-1")}, xi(10)},
 		{"round", dmy, []types.XValue{xs("10.5")}, xn("11")},
 		{"round", dmy, []types.XValue{xs("not_num"), xs("1")}, ERROR},
+		{"round", bankers, []types.XValue{xs("10.5")}, xi(10)},
+		{"round", bankers, []types.XValue{xs("11.5")}, xi(12)},
+		{"round", bankers, []types.XValue{xs("10.25"), xs("1")}, xn("10.2")},
 		{"round", dmy, []types.XValue{xs("10.5"), xs("not_num")}, ERROR},
 		{"round", dmy, []types.XValue{xs("10.5"), xs("1"), xs("30")}, ERROR},
 
@@ -563,6 +841,17 @@ func TestFunctions(t *testing.T) {
 		{"round_down", dmy, []types.XValue{xs("not_num")}, ERROR},
 		{"round_down", dmy, []types.XValue{}, ERROR},
 
+		{"round_to_increment", dmy, []types.XValue{xn("12.37"), xn("0.05")}, xn("12.35")},
+		{"round_to_increment", dmy, []types.XValue{xn("12.375"), xn("0.05")}, xn("12.4")},
+		{"round_to_increment", bankers, []types.XValue{xn("12.375"), xn("0.05")}, xn("12.4")},
+		{"round_to_increment", bankers, []types.XValue{xn("12.325"), xn("0.05")}, xn("12.3")},
+		{"round_to_increment", dmy, []types.XValue{xn("1250"), xi(100)}, xi(1300)},
+		{"round_to_increment", bankers, []types.XValue{xn("1250"), xi(100)}, xi(1200)},
+		{"round_to_increment", dmy, []types.XValue{xn("12.37"), xn("-0.05")}, xn("12.35")},
+		{"round_to_increment", dmy, []types.XValue{xn("12.37"), xi(0)}, ERROR},
+		{"round_to_increment", dmy, []types.XValue{xs("not_num"), xi(1)}, ERROR},
+		{"round_to_increment", dmy, []types.XValue{xn("12.37")}, ERROR},
+
 		{"round_up", dmy, []types.XValue{xs("10")}, xi(10)},
 		{"round_up", dmy, []types.XValue{xs("10.5")}, xi(11)},
 		{"round_up", dmy, []types.XValue{xs("10.2")}, xi(11)},
@@ -608,6 +897,17 @@ func TestFunctions(t *testing.T) {
 		{"text_length", dmy, []types.XValue{xs("hello")}, xi(5)},
 		{"text_length", dmy, []types.XValue{xs("")}, xi(0)},
 		{"text_length", dmy, []types.XValue{xs("😁😁")}, xi(2)},
+
+		{"text_segments", dmy, []types.XValue{xs("hello")}, xa(xs("hello"))},
+		{"text_segments", dmy, []types.XValue{xs(strings.Repeat("a", 161))}, xa(xs(strings.Repeat("a", 153)), xs(strings.Repeat("a", 8)))},
+		{"text_segments", dmy, []types.XValue{xs(strings.Repeat("é", 71))}, xa(xs(strings.Repeat("é", 71)))},
+		{"text_segments", dmy, []types.XValue{xs(strings.Repeat("í", 71))}, xa(xs(strings.Repeat("í", 67)), xs(strings.Repeat("í", 4)))},
+		{"text_segments", dmy, []types.XValue{ERROR}, ERROR},
+		{"text_segments", dmy, []types.XValue{}, ERROR},
+
+		{"is_gsm7", dmy, []types.XValue{xs("hello")}, types.XBooleanTrue},
+		{"is_gsm7", dmy, []types.XValue{xs("hí")}, types.XBooleanFalse},
+		{"is_gsm7", dmy, []types.XValue{ERROR}, ERROR},
 		{"text_length", dmy, []types.XValue{xs(" 2♣️ ")}, xi(5)},     // emoji color modifier
 		{"text_length", dmy, []types.XValue{xa(xs("hello"))}, xi(7)}, // [hello]
 		{"text_length", dmy, []types.XValue{xa()}, xi(2)},            // []
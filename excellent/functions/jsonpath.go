@@ -0,0 +1,561 @@
+package functions
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// the maximum number of values which a single JSONPath query can visit, so that queries like $..* on huge payloads
+// can't run away
+const maxJSONPathVisits = 10000
+
+// a step in a JSONPath query which selects zero or more children of each current value
+type jsonPathStep struct {
+	recursive bool // whether this step searches all descendants, i.e. ..
+	wildcard  bool // matches all properties or items
+	names     []string
+	indexes   []int
+	slice     *[3]*int // start, end and step of a slice like [1:3]
+	filter    *jsonPathFilter
+}
+
+// a filter expression like ?(@.price > 10 && @.in_stock)
+type jsonPathFilter struct {
+	or [][]*jsonPathComparison // disjunction of conjunctions
+}
+
+type jsonPathComparison struct {
+	left  jsonPathOperand
+	op    string // empty if just checking the left operand exists and is truthy
+	right jsonPathOperand
+}
+
+type jsonPathOperand struct {
+	path    []*jsonPathStep // relative path from @ if this isn't a literal
+	literal types.XValue
+}
+
+// parses a JSONPath query like $.items[0].name
+func parseJSONPath(query string) ([]*jsonPathStep, error) {
+	query = strings.TrimSpace(query)
+	if !strings.HasPrefix(query, "$") {
+		return nil, errors.New("path must start with $")
+	}
+
+	p := &jsonPathParser{s: query, pos: 1}
+	steps, err := p.parseSteps(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.s) {
+		return nil, errors.Errorf("unexpected '%c' at position %d", p.s[p.pos], p.pos)
+	}
+	return steps, nil
+}
+
+type jsonPathParser struct {
+	s   string
+	pos int
+}
+
+func (p *jsonPathParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *jsonPathParser) skipSpace() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// parses steps until the end of the query, or if inFilter, until something which can't be part of a path
+func (p *jsonPathParser) parseSteps(inFilter bool) ([]*jsonPathStep, error) {
+	steps := make([]*jsonPathStep, 0)
+
+	for p.pos < len(p.s) {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			step := &jsonPathStep{}
+			if p.peek() == '.' {
+				p.pos++
+				step.recursive = true
+			}
+			if p.peek() == '[' {
+				if err := p.parseBracket(step); err != nil {
+					return nil, err
+				}
+			} else if p.peek() == '*' {
+				p.pos++
+				step.wildcard = true
+			} else {
+				name := p.parseName()
+				if name == "" {
+					return nil, errors.Errorf("expected property name at position %d", p.pos)
+				}
+				step.names = []string{name}
+			}
+			steps = append(steps, step)
+		case '[':
+			step := &jsonPathStep{}
+			if err := p.parseBracket(step); err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+		default:
+			if inFilter {
+				return steps, nil
+			}
+			return nil, errors.Errorf("unexpected '%c' at position %d", p.peek(), p.pos)
+		}
+	}
+	return steps, nil
+}
+
+func (p *jsonPathParser) parseName() string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if c == '.' || c == '[' || c == ' ' || c == ')' || c == '=' || c == '!' || c == '<' || c == '>' || c == '&' || c == '|' {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// parses a bracketed selector like [0], [*], ['name'], [1:3] or [?(...)]
+func (p *jsonPathParser) parseBracket(step *jsonPathStep) error {
+	p.pos++ // [
+	p.skipSpace()
+
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		step.wildcard = true
+	case c == '?':
+		p.pos++
+		if p.peek() != '(' {
+			return errors.Errorf("expected ( at position %d", p.pos)
+		}
+		p.pos++
+		filter, err := p.parseFilter()
+		if err != nil {
+			return err
+		}
+		if p.peek() != ')' {
+			return errors.Errorf("expected ) at position %d", p.pos)
+		}
+		p.pos++
+		step.filter = filter
+	case c == '\'' || c == '"':
+		for {
+			name, err := p.parseQuoted()
+			if err != nil {
+				return err
+			}
+			step.names = append(step.names, name)
+			p.skipSpace()
+			if p.peek() != ',' {
+				break
+			}
+			p.pos++
+			p.skipSpace()
+		}
+	default:
+		// either a list of indexes or a slice
+		end := strings.IndexByte(p.s[p.pos:], ']')
+		if end < 0 {
+			return errors.Errorf("expected ] at position %d", len(p.s))
+		}
+		content := p.s[p.pos : p.pos+end]
+		p.pos += end
+
+		if strings.Contains(content, ":") {
+			parts := strings.Split(content, ":")
+			if len(parts) > 3 {
+				return errors.Errorf("invalid slice '%s'", content)
+			}
+			step.slice = &[3]*int{}
+			for i, part := range parts {
+				part = strings.TrimSpace(part)
+				if part == "" {
+					continue
+				}
+				n, err := strconv.Atoi(part)
+				if err != nil {
+					return errors.Errorf("invalid slice '%s'", content)
+				}
+				step.slice[i] = &n
+			}
+		} else {
+			for _, part := range strings.Split(content, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil {
+					return errors.Errorf("invalid index '%s'", strings.TrimSpace(part))
+				}
+				step.indexes = append(step.indexes, n)
+			}
+		}
+	}
+
+	p.skipSpace()
+	if p.peek() != ']' {
+		return errors.Errorf("expected ] at position %d", p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *jsonPathParser) parseQuoted() (string, error) {
+	quote := p.peek()
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] != quote {
+		p.pos++
+	}
+	if p.pos >= len(p.s) {
+		return "", errors.New("unterminated string")
+	}
+	s := p.s[start:p.pos]
+	p.pos++
+	return s, nil
+}
+
+// parses a filter expression up to its closing parenthesis, where && binds tighter than ||
+func (p *jsonPathParser) parseFilter() (*jsonPathFilter, error) {
+	filter := &jsonPathFilter{}
+	and := make([]*jsonPathComparison, 0)
+
+	for {
+		p.skipSpace()
+		cmp, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, cmp)
+
+		p.skipSpace()
+		if strings.HasPrefix(p.s[p.pos:], "&&") {
+			p.pos += 2
+		} else if strings.HasPrefix(p.s[p.pos:], "||") {
+			p.pos += 2
+			filter.or = append(filter.or, and)
+			and = make([]*jsonPathComparison, 0)
+		} else {
+			break
+		}
+	}
+
+	filter.or = append(filter.or, and)
+	return filter, nil
+}
+
+func (p *jsonPathParser) parseComparison() (*jsonPathComparison, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(p.s[p.pos:], op) {
+			p.pos += len(op)
+			p.skipSpace()
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return &jsonPathComparison{left: left, op: op, right: right}, nil
+		}
+	}
+	return &jsonPathComparison{left: left}, nil
+}
+
+func (p *jsonPathParser) parseOperand() (jsonPathOperand, error) {
+	switch c := p.peek(); {
+	case c == '@':
+		p.pos++
+		path, err := p.parseSteps(true)
+		return jsonPathOperand{path: path}, err
+	case c == '\'' || c == '"':
+		s, err := p.parseQuoted()
+		return jsonPathOperand{literal: types.NewXText(s)}, err
+	default:
+		start := p.pos
+		for p.pos < len(p.s) && strings.IndexByte(" )&|=!<>", p.s[p.pos]) < 0 {
+			p.pos++
+		}
+		token := p.s[start:p.pos]
+
+		switch token {
+		case "true":
+			return jsonPathOperand{literal: types.XBooleanTrue}, nil
+		case "false":
+			return jsonPathOperand{literal: types.XBooleanFalse}, nil
+		case "null":
+			return jsonPathOperand{}, nil
+		}
+		if num, err := decimal.NewFromString(token); err == nil {
+			return jsonPathOperand{literal: types.NewXNumber(num)}, nil
+		}
+		return jsonPathOperand{}, errors.Errorf("invalid value '%s' in filter", token)
+	}
+}
+
+// evaluates a parsed JSONPath query against the given value, returning all the matched values
+type jsonPathEvaluator struct {
+	visits int
+}
+
+func (e *jsonPathEvaluator) visit() error {
+	e.visits++
+	if e.visits > maxJSONPathVisits {
+		return errors.Errorf("query exceeded the limit of %d values", maxJSONPathVisits)
+	}
+	return nil
+}
+
+func (e *jsonPathEvaluator) evaluate(steps []*jsonPathStep, root types.XValue) ([]types.XValue, error) {
+	current := []types.XValue{root}
+
+	for _, step := range steps {
+		next := make([]types.XValue, 0)
+
+		for _, v := range current {
+			candidates := []types.XValue{v}
+			if step.recursive {
+				var err error
+				if candidates, err = e.descendants(v, candidates); err != nil {
+					return nil, err
+				}
+			}
+
+			for _, c := range candidates {
+				selected, err := e.selectChildren(step, c)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, selected...)
+			}
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// collects the given value and all its descendants
+func (e *jsonPathEvaluator) descendants(v types.XValue, into []types.XValue) ([]types.XValue, error) {
+	children := jsonPathChildren(v)
+	for _, c := range children {
+		if err := e.visit(); err != nil {
+			return nil, err
+		}
+		into = append(into, c)
+
+		var err error
+		if into, err = e.descendants(c, into); err != nil {
+			return nil, err
+		}
+	}
+	return into, nil
+}
+
+func (e *jsonPathEvaluator) selectChildren(step *jsonPathStep, v types.XValue) ([]types.XValue, error) {
+	selected := make([]types.XValue, 0)
+	add := func(c types.XValue) error {
+		if err := e.visit(); err != nil {
+			return err
+		}
+		selected = append(selected, c)
+		return nil
+	}
+
+	switch typed := v.(type) {
+	case *types.XObject:
+		if step.wildcard || step.filter != nil {
+			for _, c := range jsonPathChildren(typed) {
+				if step.filter == nil || e.matches(step.filter, c) {
+					if err := add(c); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		for _, name := range step.names {
+			if c, exists := typed.Get(name); exists {
+				if err := add(c); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+	case *types.XArray:
+		count := typed.Count()
+
+		if step.wildcard || step.filter != nil {
+			for i := 0; i < count; i++ {
+				if step.filter == nil || e.matches(step.filter, typed.Get(i)) {
+					if err := add(typed.Get(i)); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		for _, i := range step.indexes {
+			if i < 0 {
+				i += count
+			}
+			if i >= 0 && i < count {
+				if err := add(typed.Get(i)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if step.slice != nil {
+			start, end, by := sliceBounds(step.slice, count)
+			for i := start; (by > 0 && i < end) || (by < 0 && i > end); i += by {
+				if err := add(typed.Get(i)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return selected, nil
+}
+
+// resolves the bounds of a slice against an array of the given length, following Python semantics
+func sliceBounds(slice *[3]*int, count int) (int, int, int) {
+	by := 1
+	if slice[2] != nil && *slice[2] != 0 {
+		by = *slice[2]
+	}
+
+	clamp := func(i, min, max int) int {
+		if i < 0 {
+			i += count
+		}
+		if i < min {
+			return min
+		}
+		if i > max {
+			return max
+		}
+		return i
+	}
+
+	if by > 0 {
+		start, end := 0, count
+		if slice[0] != nil {
+			start = clamp(*slice[0], 0, count)
+		}
+		if slice[1] != nil {
+			end = clamp(*slice[1], 0, count)
+		}
+		return start, end, by
+	}
+
+	start, end := count-1, -1
+	if slice[0] != nil {
+		start = clamp(*slice[0], -1, count-1)
+	}
+	if slice[1] != nil {
+		end = clamp(*slice[1], -1, count-1)
+	}
+	return start, end, by
+}
+
+// gets the property values of an object, in property order, or the items of an array
+func jsonPathChildren(v types.XValue) []types.XValue {
+	switch typed := v.(type) {
+	case *types.XObject:
+		children := make([]types.XValue, 0, typed.Count())
+		for _, p := range typed.Properties() {
+			c, _ := typed.Get(p)
+			children = append(children, c)
+		}
+		return children
+	case *types.XArray:
+		children := make([]types.XValue, typed.Count())
+		for i := range children {
+			children[i] = typed.Get(i)
+		}
+		return children
+	}
+	return nil
+}
+
+func (e *jsonPathEvaluator) matches(filter *jsonPathFilter, v types.XValue) bool {
+	for _, and := range filter.or {
+		all := true
+		for _, cmp := range and {
+			if !e.compare(cmp, v) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *jsonPathEvaluator) compare(cmp *jsonPathComparison, v types.XValue) bool {
+	left, leftExists := e.operandValue(cmp.left, v)
+	if cmp.op == "" {
+		return leftExists && types.Truthy(left)
+	}
+	right, rightExists := e.operandValue(cmp.right, v)
+	if !leftExists || !rightExists {
+		return cmp.op == "!=" && leftExists != rightExists
+	}
+
+	switch cmp.op {
+	case "==":
+		return types.Equals(left, right)
+	case "!=":
+		return !types.Equals(left, right)
+	}
+
+	// ordering only makes sense for values of the same comparable type
+	if utils.IsNil(left) || utils.IsNil(right) || !types.SameType(left, right) {
+		return false
+	}
+	if _, comparable := left.(types.XComparable); !comparable {
+		return false
+	}
+
+	c := types.Compare(left, right)
+	switch cmp.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// gets the value of an operand for the given current value, and whether it exists
+func (e *jsonPathEvaluator) operandValue(o jsonPathOperand, v types.XValue) (types.XValue, bool) {
+	if o.path == nil {
+		return o.literal, true
+	}
+	matches, err := e.evaluate(o.path, v)
+	if err != nil || len(matches) == 0 {
+		return nil, false
+	}
+	return matches[0], true
+}
@@ -0,0 +1,124 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+type dimension string
+
+const (
+	dimensionLength      dimension = "length"
+	dimensionWeight      dimension = "weight"
+	dimensionVolume      dimension = "volume"
+	dimensionTemperature dimension = "temperature"
+)
+
+// a unit of measurement where factor is the size of the unit relative to the base unit of its dimension
+// (meters, grams, milliliters), and counterpart is the closest unit in the other measurement system
+type unit struct {
+	dimension   dimension
+	system      envs.MeasurementSystem
+	factor      decimal.Decimal
+	counterpart string
+}
+
+var units = map[string]*unit{
+	"mm": {dimensionLength, envs.MeasurementSystemMetric, decimal.RequireFromString("0.001"), "in"},
+	"cm": {dimensionLength, envs.MeasurementSystemMetric, decimal.RequireFromString("0.01"), "in"},
+	"m":  {dimensionLength, envs.MeasurementSystemMetric, decimal.RequireFromString("1"), "ft"},
+	"km": {dimensionLength, envs.MeasurementSystemMetric, decimal.RequireFromString("1000"), "mi"},
+	"in": {dimensionLength, envs.MeasurementSystemImperial, decimal.RequireFromString("0.0254"), "cm"},
+	"ft": {dimensionLength, envs.MeasurementSystemImperial, decimal.RequireFromString("0.3048"), "m"},
+	"yd": {dimensionLength, envs.MeasurementSystemImperial, decimal.RequireFromString("0.9144"), "m"},
+	"mi": {dimensionLength, envs.MeasurementSystemImperial, decimal.RequireFromString("1609.344"), "km"},
+
+	"mg": {dimensionWeight, envs.MeasurementSystemMetric, decimal.RequireFromString("0.001"), "oz"},
+	"g":  {dimensionWeight, envs.MeasurementSystemMetric, decimal.RequireFromString("1"), "oz"},
+	"kg": {dimensionWeight, envs.MeasurementSystemMetric, decimal.RequireFromString("1000"), "lb"},
+	"t":  {dimensionWeight, envs.MeasurementSystemMetric, decimal.RequireFromString("1000000"), "lb"},
+	"oz": {dimensionWeight, envs.MeasurementSystemImperial, decimal.RequireFromString("28.349523125"), "g"},
+	"lb": {dimensionWeight, envs.MeasurementSystemImperial, decimal.RequireFromString("453.59237"), "kg"},
+	"st": {dimensionWeight, envs.MeasurementSystemImperial, decimal.RequireFromString("6350.29318"), "kg"},
+
+	"ml":   {dimensionVolume, envs.MeasurementSystemMetric, decimal.RequireFromString("1"), "floz"},
+	"l":    {dimensionVolume, envs.MeasurementSystemMetric, decimal.RequireFromString("1000"), "gal"},
+	"tsp":  {dimensionVolume, envs.MeasurementSystemImperial, decimal.RequireFromString("4.92892159375"), "ml"},
+	"tbsp": {dimensionVolume, envs.MeasurementSystemImperial, decimal.RequireFromString("14.78676478125"), "ml"},
+	"floz": {dimensionVolume, envs.MeasurementSystemImperial, decimal.RequireFromString("29.5735295625"), "ml"},
+	"cup":  {dimensionVolume, envs.MeasurementSystemImperial, decimal.RequireFromString("236.5882365"), "ml"},
+	"pt":   {dimensionVolume, envs.MeasurementSystemImperial, decimal.RequireFromString("473.176473"), "l"},
+	"qt":   {dimensionVolume, envs.MeasurementSystemImperial, decimal.RequireFromString("946.352946"), "l"},
+	"gal":  {dimensionVolume, envs.MeasurementSystemImperial, decimal.RequireFromString("3785.411784"), "l"},
+
+	"c": {dimensionTemperature, envs.MeasurementSystemMetric, decimal.Zero, "f"},
+	"f": {dimensionTemperature, envs.MeasurementSystemImperial, decimal.Zero, "c"},
+	"k": {dimensionTemperature, envs.MeasurementSystemMetric, decimal.Zero, "f"},
+}
+
+var (
+	kelvinOffset     = decimal.RequireFromString("273.15")
+	fahrenheitOffset = decimal.NewFromInt(32)
+	fahrenheitScale  = decimal.NewFromInt(9).Div(decimal.NewFromInt(5))
+)
+
+func lookupUnit(name string) (string, *unit, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	u := units[name]
+	if u == nil {
+		return "", nil, errors.Errorf("%s isn't a supported unit", name)
+	}
+	return name, u, nil
+}
+
+// converts the given value from one unit to another, where if to is empty, the value is converted to the closest
+// unit in the given measurement system
+func convertUnit(value decimal.Decimal, from, to string, system envs.MeasurementSystem) (decimal.Decimal, error) {
+	from, fromUnit, err := lookupUnit(from)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	if to == "" {
+		if fromUnit.system == system {
+			return value, nil
+		}
+		to = fromUnit.counterpart
+	}
+
+	to, toUnit, err := lookupUnit(to)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if fromUnit.dimension != toUnit.dimension {
+		return decimal.Zero, errors.Errorf("can't convert from %s to %s", from, to)
+	}
+
+	if fromUnit.dimension == dimensionTemperature {
+		return convertTemperature(value, from, to), nil
+	}
+
+	return value.Mul(fromUnit.factor).Div(toUnit.factor), nil
+}
+
+// temperatures can't be converted by scaling alone so we convert via celsius
+func convertTemperature(value decimal.Decimal, from, to string) decimal.Decimal {
+	celsius := value
+	switch from {
+	case "f":
+		celsius = value.Sub(fahrenheitOffset).Div(fahrenheitScale)
+	case "k":
+		celsius = value.Sub(kelvinOffset)
+	}
+
+	switch to {
+	case "f":
+		return celsius.Mul(fahrenheitScale).Add(fahrenheitOffset)
+	case "k":
+		return celsius.Add(kelvinOffset)
+	}
+	return celsius
+}
@@ -0,0 +1,70 @@
+package excellent
+
+import (
+	"time"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+
+	"github.com/pkg/errors"
+)
+
+// the state of a single template evaluation, used to enforce the evaluation limits of the environment
+type evaluation struct {
+	limits   *envs.EvaluationLimits
+	depth    int
+	deadline time.Time
+}
+
+// creates a new scope for evaluating a template, which is limited if the environment has evaluation limits
+func newEvaluationScope(env envs.Environment, ctx *types.XObject) *Scope {
+	scope := NewScope(ctx, nil)
+
+	if limits := env.EvaluationLimits(); !limits.IsZero() {
+		scope.eval = &evaluation{limits: limits}
+		if limits.MaxTime > 0 {
+			scope.eval.deadline = time.Now().Add(time.Duration(limits.MaxTime) * time.Millisecond)
+		}
+	}
+	return scope
+}
+
+// evaluates the given expression in the given scope, returning an error instead if that would exceed any limits
+func evaluate(env envs.Environment, scope *Scope, exp Expression) types.XValue {
+	e := scope.eval
+	if e == nil {
+		return exp.Evaluate(env, scope)
+	}
+
+	if e.limits.MaxDepth > 0 && e.depth >= e.limits.MaxDepth {
+		return types.NewXLimitError(types.LimitDepth, e.limits.MaxDepth)
+	}
+	if !e.deadline.IsZero() && time.Now().After(e.deadline) {
+		return types.NewXLimitError(types.LimitTime, e.limits.MaxTime)
+	}
+
+	e.depth++
+	value := exp.Evaluate(env, scope)
+	e.depth--
+
+	if text, isText := value.(types.XText); isText {
+		if err := checkOutputSize(e.limits, len(text.Native())); err != nil {
+			return err
+		}
+	}
+	return value
+}
+
+// checks whether the given error is because evaluation exceeded one of the limits
+func isLimitError(err error) bool {
+	limitErr := &types.LimitExceededError{}
+	return errors.As(err, &limitErr)
+}
+
+// checks that the given size of output doesn't exceed the output size limit
+func checkOutputSize(limits *envs.EvaluationLimits, size int) types.XError {
+	if limits.MaxOutputSize > 0 && size > limits.MaxOutputSize {
+		return types.NewXLimitError(types.LimitOutputSize, limits.MaxOutputSize)
+	}
+	return nil
+}
@@ -24,24 +24,30 @@ var Concatenate = textualBinary(func(env envs.Environment, text1 types.XText, te
 
 // Equal returns true if two values are textually equal.
 //
+// In flows using version 2 or later of the expressions language, two values which are both numbers are compared
+// numerically instead, so `"1.0" = 1` is true.
+//
 //	@("hello" = "hello") -> true
 //	@("hello" = "bar") -> false
 //	@(1 = 1) -> true
 //
 // @operator equal "="
-var Equal = textualBinary(func(env envs.Environment, text1 types.XText, text2 types.XText) types.XValue {
-	return types.NewXBoolean(text1.Equals(text2))
+var Equal = equalityBinary(func(env envs.Environment, equal bool) types.XValue {
+	return types.NewXBoolean(equal)
 })
 
 // NotEqual returns true if two values are textually not equal.
 //
+// In flows using version 2 or later of the expressions language, two values which are both numbers are compared
+// numerically instead.
+//
 //	@("hello" != "hello") -> false
 //	@("hello" != "bar") -> true
 //	@(1 != 2) -> true
 //
 // @operator notequal "!="
-var NotEqual = textualBinary(func(env envs.Environment, text1 types.XText, text2 types.XText) types.XValue {
-	return types.NewXBoolean(!text1.Equals(text2))
+var NotEqual = equalityBinary(func(env envs.Environment, equal bool) types.XValue {
+	return types.NewXBoolean(!equal)
 })
 
 // Negate negates a number
@@ -53,24 +59,25 @@ var Negate = numericalUnary(func(env envs.Environment, num types.XNumber) types.
 	return types.NewXNumber(num.Native().Neg())
 })
 
-// Add adds two numbers.
+// Add adds two numbers, or two amounts of money in the same currency.
 //
 //	@(2 + 3) -> 5
 //	@(fields.age + 10) -> 33
+//	@(cart.total + cart.total) -> RWF 3700.00
 //
 // @operator add "+"
-var Add = numericalBinary(func(env envs.Environment, num1 types.XNumber, num2 types.XNumber) types.XValue {
-	return types.NewXNumber(num1.Native().Add(num2.Native()))
+var Add = monetaryBinary(func(d1 decimal.Decimal, d2 decimal.Decimal) decimal.Decimal {
+	return d1.Add(d2)
 })
 
-// Subtract subtracts two numbers.
+// Subtract subtracts two numbers, or two amounts of money in the same currency.
 //
 //	@(3 - 2) -> 1
 //	@(2 - 3) -> -1
 //
 // @operator subtract "- (binary)"
-var Subtract = numericalBinary(func(env envs.Environment, num1 types.XNumber, num2 types.XNumber) types.XValue {
-	return types.NewXNumber(num1.Native().Sub(num2.Native()))
+var Subtract = monetaryBinary(func(d1 decimal.Decimal, d2 decimal.Decimal) decimal.Decimal {
+	return d1.Sub(d2)
 })
 
 // Multiply multiplies two numbers.
@@ -80,7 +87,7 @@ var Subtract = numericalBinary(func(env envs.Environment, num1 types.XNumber, nu
 //
 // @operator multiply "*"
 var Multiply = numericalBinary(func(env envs.Environment, num1 types.XNumber, num2 types.XNumber) types.XValue {
-	return types.NewXNumber(num1.Native().Mul(num2.Native()))
+	return arithmeticResult(env, num1.Native().Mul(num2.Native()))
 })
 
 // Divide divides a number by another.
@@ -96,7 +103,7 @@ var Divide = numericalBinary(func(env envs.Environment, num1 types.XNumber, num2
 		return types.NewXErrorf("division by zero")
 	}
 
-	return types.NewXNumber(num1.Native().Div(num2.Native()))
+	return arithmeticResult(env, num1.Native().Div(num2.Native()))
 })
 
 // Exponent raises a number to the power of a another number.
@@ -113,13 +120,13 @@ var Exponent = numericalBinary(func(env envs.Environment, num1 types.XNumber, nu
 	// we can use the library function, otherwise fallback to float64 math.
 
 	if decimal.New(d2.IntPart(), 0).Equals(d2) {
-		return types.NewXNumber(d1.Pow(d2))
+		return arithmeticResult(env, d1.Pow(d2))
 	}
 
 	f1, _ := d1.Float64()
 	f2, _ := d2.Float64()
 
-	return types.NewXNumber(decimal.NewFromFloat(math.Pow(f1, f2)))
+	return arithmeticResult(env, decimal.NewFromFloat(math.Pow(f1, f2)))
 })
 
 // LessThan returns true if the first number is less than the second.
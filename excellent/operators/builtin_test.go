@@ -8,12 +8,16 @@ import (
 	"github.com/nyaruka/goflow/excellent/operators"
 	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/test"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
 var xs = types.NewXText
 var xn = types.RequireXNumberFromString
 var xi = types.NewXNumberFromInt
+var xm = func(amount, currency string) types.XMoney {
+	return types.NewXMoney(decimal.RequireFromString(amount), currency)
+}
 var ERROR = types.NewXErrorf("any error")
 
 func TestBinaryOperators(t *testing.T) {
@@ -49,18 +53,25 @@ func TestBinaryOperators(t *testing.T) {
 		{operators.Add, xs("1"), xs("3"), xi(4)},
 		{operators.Add, ERROR, xi(1), ERROR},
 		{operators.Add, xi(1), ERROR, ERROR},
+		{operators.Add, xm("10", "USD"), xm("2.5", "USD"), xm("12.5", "USD")},
+		{operators.Add, xm("10", "USD"), xi(2), xi(12)},
+		{operators.Add, xm("10", "USD"), xm("2.5", "EUR"), ERROR},
 
 		{operators.Subtract, xi(1), xi(3), xi(-2)},
 		{operators.Subtract, xi(3), xi(1), xi(2)},
 		{operators.Subtract, xs("3"), xs("1"), xi(2)},
 		{operators.Subtract, ERROR, xi(1), ERROR},
 		{operators.Subtract, xi(1), ERROR, ERROR},
+		{operators.Subtract, xm("10", "USD"), xm("2.5", "USD"), xm("7.5", "USD")},
+		{operators.Subtract, xm("10", "USD"), xm("2.5", "EUR"), ERROR},
 
 		{operators.Multiply, xi(2), xi(3), xi(6)},
 		{operators.Multiply, xn("1.5"), xn("2.3"), xn("3.45")},
 		{operators.Multiply, xs("2"), xs("3"), xi(6)},
 		{operators.Multiply, ERROR, xi(1), ERROR},
 		{operators.Multiply, xi(1), ERROR, ERROR},
+		{operators.Multiply, xm("10", "USD"), xi(3), xi(30)},
+		{operators.Multiply, xm("10", "USD"), xm("2", "EUR"), ERROR},
 
 		{operators.Divide, xi(3), xi(2), xn("1.5")},
 		{operators.Divide, xs("3"), xs("2"), xn("1.5")},
@@ -79,6 +90,8 @@ func TestBinaryOperators(t *testing.T) {
 		{operators.LessThan, xi(2), xi(3), types.XBooleanTrue},
 		{operators.LessThan, xi(3), xi(3), types.XBooleanFalse},
 		{operators.LessThan, xi(4), xi(3), types.XBooleanFalse},
+		{operators.LessThan, xm("2", "USD"), xm("3", "USD"), types.XBooleanTrue},
+		{operators.LessThan, xm("2", "USD"), xm("3", "EUR"), ERROR},
 		{operators.LessThan, ERROR, xi(1), ERROR},
 		{operators.LessThan, xi(1), ERROR, ERROR},
 
@@ -115,6 +128,59 @@ func TestBinaryOperators(t *testing.T) {
 	}
 }
 
+func TestArithmeticPrecision(t *testing.T) {
+	halfUp := envs.NewBuilder().WithDecimalPrecision(2).Build()
+	halfEven := envs.NewBuilder().WithDecimalPrecision(2).WithRoundingMode(envs.RoundingModeHalfEven).Build()
+
+	test.AssertXEqual(t, xn("0.33"), operators.Divide(halfUp, xi(1), xi(3)))
+	test.AssertXEqual(t, xn("0.67"), operators.Divide(halfUp, xi(2), xi(3)))
+	test.AssertXEqual(t, xn("0.13"), operators.Multiply(halfUp, xn("0.125"), xi(1)))
+	test.AssertXEqual(t, xn("0.12"), operators.Multiply(halfEven, xn("0.125"), xi(1)))
+	test.AssertXEqual(t, xn("0.14"), operators.Add(halfEven, xn("0.1"), xn("0.035")))
+	test.AssertXEqual(t, xn("1.41"), operators.Exponent(halfEven, xi(2), xn("0.5")))
+
+	// comparisons aren't affected
+	test.AssertXEqual(t, types.XBooleanTrue, operators.LessThan(halfUp, xn("0.001"), xn("0.002")))
+}
+
+// an environment which uses version 2 of the expressions language
+type v2Environment struct {
+	envs.Environment
+}
+
+func (e *v2Environment) ExpressionsVersion() envs.ExpressionsVersion { return envs.ExpressionsVersion2 }
+
+func TestEqualityByExpressionsVersion(t *testing.T) {
+	v1 := envs.NewBuilder().Build()
+	v2 := &v2Environment{v1}
+
+	testCases := []struct {
+		arg1     types.XValue
+		arg2     types.XValue
+		expected types.XBoolean
+	}{
+		{xs("1.0"), xi(1), types.XBooleanTrue},
+		{xs("01"), xs("1"), types.XBooleanTrue},
+		{xn("1.50"), xn("1.5"), types.XBooleanTrue},
+		{xs("2"), xi(1), types.XBooleanFalse},
+		{xs("hello"), xs("hello"), types.XBooleanTrue}, // values which aren't both numbers are still compared textually
+		{xs("1"), xs("one"), types.XBooleanFalse},
+	}
+
+	for _, tc := range testCases {
+		test.AssertXEqual(t, tc.expected, operators.Equal(v2, tc.arg1, tc.arg2), "equal mismatch for %s, %s", tc.arg1, tc.arg2)
+		test.AssertXEqual(t, types.NewXBoolean(!tc.expected.Native()), operators.NotEqual(v2, tc.arg1, tc.arg2), "not equal mismatch for %s, %s", tc.arg1, tc.arg2)
+	}
+
+	// version 1 compares textually
+	test.AssertXEqual(t, types.XBooleanFalse, operators.Equal(v1, xs("1.0"), xi(1)))
+	test.AssertXEqual(t, types.XBooleanTrue, operators.NotEqual(v1, xs("01"), xs("1")))
+
+	// errors are still returned
+	assert.True(t, types.IsXError(operators.Equal(v2, ERROR, xi(1))))
+	assert.True(t, types.IsXError(operators.NotEqual(v2, xi(1), ERROR)))
+}
+
 func TestUnaryOperators(t *testing.T) {
 	env := envs.NewBuilder().Build()
 
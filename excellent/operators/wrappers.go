@@ -3,6 +3,7 @@ package operators
 import (
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/shopspring/decimal"
 )
 
 // UnaryOperator is an operator which takes a single argument
@@ -26,6 +27,23 @@ func textualBinary(f func(envs.Environment, types.XText, types.XText) types.XVal
 	}
 }
 
+// compares values textually, or numerically if they are both numbers and the environment's expressions version allows it
+func equalityBinary(f func(envs.Environment, bool) types.XValue) BinaryOperator {
+	return func(env envs.Environment, arg1 types.XValue, arg2 types.XValue) types.XValue {
+		if env.ExpressionsVersion() >= envs.ExpressionsVersion2 {
+			num1, xerr1 := types.ToXNumber(env, arg1)
+			num2, xerr2 := types.ToXNumber(env, arg2)
+			if xerr1 == nil && xerr2 == nil {
+				return f(env, num1.Equals(num2))
+			}
+		}
+
+		return textualBinary(func(env envs.Environment, text1 types.XText, text2 types.XText) types.XValue {
+			return f(env, text1.Equals(text2))
+		})(env, arg1, arg2)
+	}
+}
+
 func numericalUnary(f func(envs.Environment, types.XNumber) types.XValue) UnaryOperator {
 	return func(env envs.Environment, arg types.XValue) types.XValue {
 		num, xerr := types.ToXNumber(env, arg)
@@ -39,6 +57,10 @@ func numericalUnary(f func(envs.Environment, types.XNumber) types.XValue) UnaryO
 
 func numericalBinary(f func(envs.Environment, types.XNumber, types.XNumber) types.XValue) BinaryOperator {
 	return func(env envs.Environment, arg1 types.XValue, arg2 types.XValue) types.XValue {
+		if xerr := checkSameCurrency(arg1, arg2); xerr != nil {
+			return xerr
+		}
+
 		num1, xerr := types.ToXNumber(env, arg1)
 		if xerr != nil {
 			return xerr
@@ -51,3 +73,35 @@ func numericalBinary(f func(envs.Environment, types.XNumber, types.XNumber) type
 		return f(env, num1, num2)
 	}
 }
+
+// operates on two money values in the same currency to give money, otherwise operates on the values as numbers
+func monetaryBinary(f func(decimal.Decimal, decimal.Decimal) decimal.Decimal) BinaryOperator {
+	numerical := numericalBinary(func(env envs.Environment, num1 types.XNumber, num2 types.XNumber) types.XValue {
+		return arithmeticResult(env, f(num1.Native(), num2.Native()))
+	})
+
+	return func(env envs.Environment, arg1 types.XValue, arg2 types.XValue) types.XValue {
+		money1, isMoney1 := arg1.(types.XMoney)
+		money2, isMoney2 := arg2.(types.XMoney)
+		if isMoney1 && isMoney2 && money1.Currency() == money2.Currency() {
+			return types.NewXMoney(f(money1.Amount(), money2.Amount()), money1.Currency())
+		}
+
+		return numerical(env, arg1, arg2)
+	}
+}
+
+// returns an error if both values are money but in different currencies
+func checkSameCurrency(arg1 types.XValue, arg2 types.XValue) types.XError {
+	money1, isMoney1 := arg1.(types.XMoney)
+	money2, isMoney2 := arg2.(types.XMoney)
+	if isMoney1 && isMoney2 && money1.Currency() != money2.Currency() {
+		return types.NewXErrorf("can't combine amounts in different currencies %s and %s", money1.Currency(), money2.Currency())
+	}
+	return nil
+}
+
+// creates a number from the result of an arithmetic operation, rounded to the environment's decimal precision
+func arithmeticResult(env envs.Environment, d decimal.Decimal) types.XValue {
+	return types.NewXNumber(envs.RoundToPrecision(env, d))
+}
@@ -39,6 +39,7 @@ type Scanner interface {
 // xscanner represents a lexical scanner.
 type xscanner struct {
 	input               *xinput
+	expr                *bytes.Buffer // reused for each expression
 	identifierTopLevels []string
 	unescapeBody        bool // unescape @@ sequences in the body
 }
@@ -47,6 +48,7 @@ type xscanner struct {
 func NewXScanner(r io.Reader, identifierTopLevels []string) Scanner {
 	return &xscanner{
 		input:               newInput(bufio.NewReader(r)),
+		expr:                &bytes.Buffer{},
 		identifierTopLevels: identifierTopLevels,
 		unescapeBody:        true,
 	}
@@ -59,8 +61,9 @@ func (s *xscanner) SetUnescapeBody(unescape bool) {
 // scanExpression consumes the current rune and all contiguous pieces until the end of the expression
 // our read should be after the '('
 func (s *xscanner) scanExpression() (XTokenType, string) {
-	// create a buffer and read the current character into it.
-	buf := &bytes.Buffer{}
+	// reset our buffer and read the current character into it.
+	buf := s.expr
+	buf.Reset()
 
 	// our parentheses depth
 	parens := 1
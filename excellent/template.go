@@ -0,0 +1,157 @@
+package excellent
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/functions"
+	"github.com/nyaruka/goflow/excellent/types"
+	"golang.org/x/exp/slices"
+)
+
+// Template is a template which has been scanned and had its expressions parsed, so that it can be evaluated many
+// times without being parsed again
+type Template struct {
+	parts     []templatePart
+	functions []string  // the names of the functions called by the template's expressions
+	trimmed   *Template // the compiled form of the template without surrounding whitespace, used for value evaluation
+}
+
+// a part of a template is either body text or an expression
+type templatePart struct {
+	body       string
+	repr       string // the expression as it appears in the template, e.g. @contact or @(1 + 2)
+	expression Expression
+	err        error // the error from parsing the expression
+}
+
+// CompileTemplate scans the given template and parses its expressions. Expressions which can't be parsed don't
+// prevent compilation but their errors are returned by Errors and by evaluation.
+func CompileTemplate(template string, allowedTopLevels []string) *Template {
+	t := compileTemplate(template, allowedTopLevels)
+
+	if trimmed := strings.TrimSpace(template); trimmed != template {
+		t.trimmed = compileTemplate(trimmed, allowedTopLevels)
+	} else {
+		t.trimmed = t
+	}
+	return t
+}
+
+func compileTemplate(template string, allowedTopLevels []string) *Template {
+	t := &Template{}
+
+	VisitTemplate(template, allowedTopLevels, func(tokenType XTokenType, token string) error {
+		switch tokenType {
+		case BODY:
+			t.parts = append(t.parts, templatePart{body: token})
+		case IDENTIFIER, EXPRESSION:
+			part := templatePart{repr: "@(" + token + ")"}
+			if tokenType == IDENTIFIER {
+				part.repr = "@" + token
+			}
+			part.expression, part.err = Parse(token, func(path []string) {
+				if len(path) == 1 && functions.Lookup(path[0]) != nil && !slices.Contains(allowedTopLevels, path[0]) {
+					if !slices.Contains(t.functions, path[0]) {
+						t.functions = append(t.functions, path[0])
+					}
+				}
+			})
+
+			t.parts = append(t.parts, part)
+		}
+		return nil
+	})
+
+	return t
+}
+
+// Functions returns the names of the functions called by this template's expressions
+func (t *Template) Functions() []string {
+	return t.functions
+}
+
+// Errors returns the errors from parsing the expressions in this template
+func (t *Template) Errors() *TemplateErrors {
+	errors := NewTemplateErrors()
+	for _, p := range t.parts {
+		if p.err != nil {
+			errors.Add(p.repr, p.err.Error())
+		}
+	}
+	return errors
+}
+
+// Evaluate evaluates this template as text, and is equivalent to calling EvaluateTemplate with the original template
+func (t *Template) Evaluate(env envs.Environment, ctx *types.XObject, escaping Escaping) (string, error) {
+	var buf strings.Builder
+	errors := NewTemplateErrors()
+	scope := newEvaluationScope(env, ctx)
+	limits := env.EvaluationLimits()
+
+	for _, p := range t.parts {
+		if p.repr == "" {
+			if err := checkOutputSize(limits, buf.Len()+len(p.body)); err != nil {
+				errors.Add(strconv.Quote(p.body), err.Error())
+				break
+			}
+
+			buf.WriteString(p.body)
+			continue
+		}
+
+		value := p.evaluate(env, scope)
+
+		if types.IsXError(value) {
+			errors.Add(p.repr, value.(error).Error())
+
+			// once a limit has been exceeded, nothing more of the template is evaluated
+			if isLimitError(value.(error)) {
+				break
+			}
+			continue
+		}
+
+		asText, _ := types.ToXText(env, value)
+		asString := asText.Native()
+
+		if escaping != nil {
+			asString = escaping(asString)
+		}
+
+		if err := checkOutputSize(limits, buf.Len()+len(asString)); err != nil {
+			errors.Add(p.repr, err.Error())
+			break
+		}
+
+		buf.WriteString(asString)
+	}
+
+	if errors.HasErrors() {
+		return buf.String(), errors
+	}
+	return buf.String(), nil
+}
+
+// EvaluateValue evaluates this template as a value, and is equivalent to calling EvaluateTemplateValue with the
+// original template
+func (t *Template) EvaluateValue(env envs.Environment, ctx *types.XObject) (types.XValue, error) {
+	trimmed := t.trimmed
+
+	// if we only have an identifier or an expression, evaluate it on its own
+	if len(trimmed.parts) == 1 && trimmed.parts[0].repr != "" {
+		return trimmed.parts[0].evaluate(env, newEvaluationScope(env, ctx)), nil
+	}
+
+	// otherwise fallback to full template evaluation
+	asStr, err := trimmed.Evaluate(env, ctx, nil)
+	return types.NewXText(asStr), err
+}
+
+func (p *templatePart) evaluate(env envs.Environment, scope *Scope) types.XValue {
+	if p.err != nil {
+		return types.NewXError(p.err)
+	}
+	return evaluate(env, scope, p.expression)
+}
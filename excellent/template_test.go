@@ -0,0 +1,61 @@
+package excellent_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileTemplate(t *testing.T) {
+	env := envs.NewBuilder().Build()
+	ctx := types.NewXObject(map[string]types.XValue{
+		"name":  types.NewXText("Bob"),
+		"count": types.NewXNumberFromInt(3),
+	})
+
+	tpl := excellent.CompileTemplate(` Hi @name you have @(count * 2) items `, ctx.Properties())
+	assert.False(t, tpl.Errors().HasErrors())
+
+	text, err := tpl.Evaluate(env, ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, ` Hi Bob you have 6 items `, text)
+
+	value, err := tpl.EvaluateValue(env, ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, types.NewXText(`Hi Bob you have 6 items`), value)
+
+	// templates can be evaluated many times against different contexts
+	ctx = types.NewXObject(map[string]types.XValue{"name": types.NewXText("Jim"), "count": types.NewXNumberFromInt(1)})
+	text, _ = tpl.Evaluate(env, ctx, nil)
+	assert.Equal(t, ` Hi Jim you have 2 items `, text)
+
+	// identifiers are only identifiers if they're allowed top-levels
+	tpl = excellent.CompileTemplate(`@name @count`, []string{"name"})
+	text, _ = tpl.Evaluate(env, ctx, nil)
+	assert.Equal(t, `Jim @count`, text)
+
+	// a template which is a single expression with surrounding whitespace evaluates to its value
+	value, err = excellent.CompileTemplate(" @count \n", ctx.Properties()).EvaluateValue(env, ctx)
+	assert.NoError(t, err)
+	test.AssertXEqual(t, types.NewXNumberFromInt(1), value)
+
+	// parse errors don't prevent compilation but are available to callers
+	tpl = excellent.CompileTemplate(`Hi @('x') @name @(0 / )`, ctx.Properties())
+	assert.EqualError(t, tpl.Errors(), `error evaluating @('x'): syntax error at 'x', error evaluating @(0 / ): syntax error at `)
+
+	text, err = tpl.Evaluate(env, ctx, nil)
+	assert.Equal(t, `Hi  Jim `, text)
+	assert.EqualError(t, err, `error evaluating @('x'): syntax error at 'x', error evaluating @(0 / ): syntax error at `)
+
+	// the functions called by a template are recorded, but not top-levels with the same names as functions
+	tpl = excellent.CompileTemplate(`@(UPPER(name)) @(legacy_add(count, 1)) @(upper(text))`, ctx.Properties())
+	assert.Equal(t, []string{"upper", "legacy_add", "text"}, tpl.Functions())
+
+	tpl = excellent.CompileTemplate(`@(text(name))`, []string{"text", "name"})
+	assert.Nil(t, tpl.Functions())
+}
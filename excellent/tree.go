@@ -40,7 +40,7 @@ type DotLookup struct {
 }
 
 func (x *DotLookup) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	containerVal := x.container.Evaluate(env, scope)
+	containerVal := evaluate(env, scope, x.container)
 	if types.IsXError(containerVal) {
 		return containerVal
 	}
@@ -58,12 +58,12 @@ type ArrayLookup struct {
 }
 
 func (x *ArrayLookup) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	containerVal := x.container.Evaluate(env, scope)
+	containerVal := evaluate(env, scope, x.container)
 	if types.IsXError(containerVal) {
 		return containerVal
 	}
 
-	lookupVal := x.lookup.Evaluate(env, scope)
+	lookupVal := evaluate(env, scope, x.lookup)
 	if types.IsXError(lookupVal) {
 		return lookupVal
 	}
@@ -81,7 +81,7 @@ type FunctionCall struct {
 }
 
 func (x *FunctionCall) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	funcVal := x.function.Evaluate(env, scope)
+	funcVal := evaluate(env, scope, x.function)
 	if types.IsXError(funcVal) {
 		return funcVal
 	}
@@ -93,7 +93,7 @@ func (x *FunctionCall) Evaluate(env envs.Environment, scope *Scope) types.XValue
 
 	params := make([]types.XValue, len(x.params))
 	for i := range x.params {
-		params[i] = x.params[i].Evaluate(env, scope)
+		params[i] = evaluate(env, scope, x.params[i])
 	}
 
 	return asFunction.Call(env, params)
@@ -123,7 +123,7 @@ func (x *AnonFunction) Evaluate(env envs.Environment, scope *Scope) types.XValue
 		}
 		childScope := NewScope(types.NewXObject(argsMap), scope)
 
-		return x.body.Evaluate(env, childScope)
+		return evaluate(env, childScope, x.body)
 	}
 
 	return types.NewXFunction("", functions.NumArgsCheck(len(x.args), fn))
@@ -139,7 +139,7 @@ type Concatenation struct {
 }
 
 func (x *Concatenation) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.Concatenate(env, x.exp1.Evaluate(env, scope), x.exp2.Evaluate(env, scope))
+	return operators.Concatenate(env, evaluate(env, scope, x.exp1), evaluate(env, scope, x.exp2))
 }
 
 func (x *Concatenation) String() string {
@@ -152,7 +152,7 @@ type Addition struct {
 }
 
 func (x *Addition) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.Add(env, x.exp1.Evaluate(env, scope), x.exp2.Evaluate(env, scope))
+	return operators.Add(env, evaluate(env, scope, x.exp1), evaluate(env, scope, x.exp2))
 }
 
 func (x *Addition) String() string {
@@ -165,7 +165,7 @@ type Subtraction struct {
 }
 
 func (x *Subtraction) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.Subtract(env, x.exp1.Evaluate(env, scope), x.exp2.Evaluate(env, scope))
+	return operators.Subtract(env, evaluate(env, scope, x.exp1), evaluate(env, scope, x.exp2))
 }
 
 func (x *Subtraction) String() string {
@@ -178,7 +178,7 @@ type Multiplication struct {
 }
 
 func (x *Multiplication) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.Multiply(env, x.exp1.Evaluate(env, scope), x.exp2.Evaluate(env, scope))
+	return operators.Multiply(env, evaluate(env, scope, x.exp1), evaluate(env, scope, x.exp2))
 }
 
 func (x *Multiplication) String() string {
@@ -191,7 +191,7 @@ type Division struct {
 }
 
 func (x *Division) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.Divide(env, x.exp1.Evaluate(env, scope), x.exp2.Evaluate(env, scope))
+	return operators.Divide(env, evaluate(env, scope, x.exp1), evaluate(env, scope, x.exp2))
 }
 
 func (x *Division) String() string {
@@ -204,7 +204,7 @@ type Exponent struct {
 }
 
 func (x *Exponent) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.Exponent(env, x.expression.Evaluate(env, scope), x.exponent.Evaluate(env, scope))
+	return operators.Exponent(env, evaluate(env, scope, x.expression), evaluate(env, scope, x.exponent))
 }
 
 func (x *Exponent) String() string {
@@ -216,7 +216,7 @@ type Negation struct {
 }
 
 func (x *Negation) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.Negate(env, x.exp.Evaluate(env, scope))
+	return operators.Negate(env, evaluate(env, scope, x.exp))
 }
 
 func (x *Negation) String() string {
@@ -229,7 +229,7 @@ type Equality struct {
 }
 
 func (x *Equality) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.Equal(env, x.exp1.Evaluate(env, scope), x.exp2.Evaluate(env, scope))
+	return operators.Equal(env, evaluate(env, scope, x.exp1), evaluate(env, scope, x.exp2))
 }
 
 func (x *Equality) String() string {
@@ -242,7 +242,7 @@ type InEquality struct {
 }
 
 func (x *InEquality) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.NotEqual(env, x.exp1.Evaluate(env, scope), x.exp2.Evaluate(env, scope))
+	return operators.NotEqual(env, evaluate(env, scope, x.exp1), evaluate(env, scope, x.exp2))
 }
 
 func (x *InEquality) String() string {
@@ -255,7 +255,7 @@ type LessThan struct {
 }
 
 func (x *LessThan) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.LessThan(env, x.exp1.Evaluate(env, scope), x.exp2.Evaluate(env, scope))
+	return operators.LessThan(env, evaluate(env, scope, x.exp1), evaluate(env, scope, x.exp2))
 }
 
 func (x *LessThan) String() string {
@@ -268,7 +268,7 @@ type LessThanOrEqual struct {
 }
 
 func (x *LessThanOrEqual) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.LessThanOrEqual(env, x.exp1.Evaluate(env, scope), x.exp2.Evaluate(env, scope))
+	return operators.LessThanOrEqual(env, evaluate(env, scope, x.exp1), evaluate(env, scope, x.exp2))
 }
 
 func (x *LessThanOrEqual) String() string {
@@ -281,7 +281,7 @@ type GreaterThan struct {
 }
 
 func (x *GreaterThan) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.GreaterThan(env, x.exp1.Evaluate(env, scope), x.exp2.Evaluate(env, scope))
+	return operators.GreaterThan(env, evaluate(env, scope, x.exp1), evaluate(env, scope, x.exp2))
 }
 
 func (x *GreaterThan) String() string {
@@ -294,7 +294,7 @@ type GreaterThanOrEqual struct {
 }
 
 func (x *GreaterThanOrEqual) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return operators.GreaterThanOrEqual(env, x.exp1.Evaluate(env, scope), x.exp2.Evaluate(env, scope))
+	return operators.GreaterThanOrEqual(env, evaluate(env, scope, x.exp1), evaluate(env, scope, x.exp2))
 }
 
 func (x *GreaterThanOrEqual) String() string {
@@ -306,7 +306,7 @@ type Parentheses struct {
 }
 
 func (x *Parentheses) Evaluate(env envs.Environment, scope *Scope) types.XValue {
-	return x.exp.Evaluate(env, scope)
+	return evaluate(env, scope, x.exp)
 }
 
 func (x *Parentheses) String() string {
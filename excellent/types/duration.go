@@ -0,0 +1,202 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/utils"
+)
+
+// XDuration is a length of time. It renders as an ISO 8601 duration using days, hours, minutes and seconds, and is
+// formatted as text in the language of the environment.
+//
+//	@(duration(90, "m")) -> PT1H30M
+//	@(format(duration(26, "h"))) -> 1 day, 2 hours
+//	@(json(duration(2, "D"))) -> "P2D"
+//
+// @type duration
+type XDuration struct {
+	native time.Duration
+}
+
+// NewXDuration creates a new duration
+func NewXDuration(value time.Duration) XDuration {
+	return XDuration{native: value}
+}
+
+// Describe returns a representation of this type for error messages
+func (x XDuration) Describe() string { return "duration" }
+
+// Truthy determines truthiness for this type
+func (x XDuration) Truthy() bool {
+	return x.native != 0
+}
+
+// Render returns the canonical text representation
+func (x XDuration) Render() string {
+	d := x.native
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	d -= minutes * time.Minute
+
+	sb := &strings.Builder{}
+	sb.WriteString(sign + "P")
+	if days > 0 {
+		fmt.Fprintf(sb, "%dD", days)
+	}
+	if hours > 0 || minutes > 0 || d > 0 || days == 0 {
+		sb.WriteString("T")
+		if hours > 0 {
+			fmt.Fprintf(sb, "%dH", hours)
+		}
+		if minutes > 0 {
+			fmt.Fprintf(sb, "%dM", minutes)
+		}
+		if d > 0 || (days == 0 && hours == 0 && minutes == 0) {
+			sb.WriteString(strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S")
+		}
+	}
+	return sb.String()
+}
+
+// Format returns the pretty text representation
+func (x XDuration) Format(env envs.Environment) string {
+	units, hasUnits := durationUnits[env.DefaultLanguage()]
+	if !hasUnits {
+		units = durationUnits["eng"]
+	}
+
+	d := x.native
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+
+	parts := make([]string, 0, 4)
+	for i, size := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		count := int64(d / size)
+		d -= time.Duration(count) * size
+
+		if count > 0 {
+			name := units[i][1]
+			if count == 1 {
+				name = units[i][0]
+			}
+			parts = append(parts, fmt.Sprintf("%d %s", count, name))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "0 " + units[3][1]
+	}
+	return sign + strings.Join(parts, ", ")
+}
+
+// singular and plural names of days, hours, minutes and seconds in the languages durations can be formatted in
+var durationUnits = map[envs.Language][4][2]string{
+	"eng": {{"day", "days"}, {"hour", "hours"}, {"minute", "minutes"}, {"second", "seconds"}},
+	"fra": {{"jour", "jours"}, {"heure", "heures"}, {"minute", "minutes"}, {"seconde", "secondes"}},
+	"por": {{"dia", "dias"}, {"hora", "horas"}, {"minuto", "minutos"}, {"segundo", "segundos"}},
+	"spa": {{"día", "días"}, {"hora", "horas"}, {"minuto", "minutos"}, {"segundo", "segundos"}},
+}
+
+// MarshalJSON is called when a struct containing this type is marshaled
+func (x XDuration) MarshalJSON() ([]byte, error) {
+	return jsonx.Marshal(x.Render())
+}
+
+// String returns the native string representation of this type
+func (x XDuration) String() string { return `XDuration(` + x.Render() + `)` }
+
+// Native returns the native value of this type
+func (x XDuration) Native() time.Duration { return x.native }
+
+// Equals determines equality for this type
+func (x XDuration) Equals(o XValue) bool {
+	other := o.(XDuration)
+
+	return x.native == other.native
+}
+
+// Compare compares this duration to another
+func (x XDuration) Compare(o XValue) int {
+	other := o.(XDuration)
+
+	if x.native < other.native {
+		return -1
+	} else if x.native > other.native {
+		return 1
+	}
+	return 0
+}
+
+// XDurationZero is the zero duration value
+var XDurationZero = NewXDuration(0)
+var _ XValue = XDurationZero
+var _ XComparable = XDurationZero
+
+var isoDurationRegex = regexp.MustCompile(`^(-)?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parses an ISO 8601 duration which doesn't use years or months, e.g. P1DT2H30M
+func parseISODuration(s string) (time.Duration, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	m := isoDurationRegex.FindStringSubmatch(s)
+	if m == nil || strings.HasSuffix(s, "P") || strings.HasSuffix(s, "T") {
+		return 0, false
+	}
+
+	var d time.Duration
+	for i, size := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute} {
+		if m[i+2] != "" {
+			n, _ := strconv.ParseInt(m[i+2], 10, 64)
+			d += time.Duration(n) * size
+		}
+	}
+	if m[6] != "" {
+		secs, _ := strconv.ParseFloat(m[6], 64)
+		d += time.Duration(secs * float64(time.Second))
+	}
+
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, true
+}
+
+// ToXDuration converts the given value to a duration or returns an error if that isn't possible
+func ToXDuration(env envs.Environment, x XValue) (XDuration, XError) {
+	if !utils.IsNil(x) {
+		switch typed := x.(type) {
+		case XError:
+			return XDurationZero, typed
+		case XDuration:
+			return typed, nil
+		case XText:
+			parsed, ok := parseISODuration(typed.Native())
+			if ok {
+				return NewXDuration(parsed), nil
+			}
+		case *XObject:
+			if typed.hasDefault() {
+				return ToXDuration(env, typed.Default())
+			}
+		}
+	}
+
+	return XDurationZero, NewXErrorf("unable to convert %s to a duration", Describe(x))
+}
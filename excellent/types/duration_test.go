@@ -0,0 +1,96 @@
+package types_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXDuration(t *testing.T) {
+	env := envs.NewBuilder().Build()
+	spaEnv := envs.NewBuilder().WithAllowedLanguages([]envs.Language{"spa"}).Build()
+	kinEnv := envs.NewBuilder().WithAllowedLanguages([]envs.Language{"kin"}).Build()
+
+	d1 := types.NewXDuration(26*time.Hour + 30*time.Minute)
+	assert.Equal(t, `duration`, d1.Describe())
+	assert.True(t, d1.Truthy())
+	assert.False(t, types.XDurationZero.Truthy())
+	assert.Equal(t, `P1DT2H30M`, d1.Render())
+	assert.Equal(t, `XDuration(P1DT2H30M)`, d1.String())
+	assert.Equal(t, `1 day, 2 hours, 30 minutes`, d1.Format(env))
+	assert.Equal(t, `1 día, 2 horas, 30 minutos`, d1.Format(spaEnv))
+	assert.Equal(t, `1 day, 2 hours, 30 minutes`, d1.Format(kinEnv)) // falls back to English
+
+	tcs := []struct {
+		duration  time.Duration
+		rendered  string
+		formatted string
+	}{
+		{0, `PT0S`, `0 seconds`},
+		{45 * time.Second, `PT45S`, `45 seconds`},
+		{1500 * time.Millisecond, `PT1.5S`, `1 second`},
+		{time.Hour, `PT1H`, `1 hour`},
+		{48 * time.Hour, `P2D`, `2 days`},
+		{48*time.Hour + time.Second, `P2DT1S`, `2 days, 1 second`},
+		{-90 * time.Minute, `-PT1H30M`, `-1 hour, 30 minutes`},
+	}
+	for _, tc := range tcs {
+		d := types.NewXDuration(tc.duration)
+		assert.Equal(t, tc.rendered, d.Render(), "render mismatch for %s", tc.duration)
+		assert.Equal(t, tc.formatted, d.Format(env), "format mismatch for %s", tc.duration)
+	}
+
+	marshaled, err := jsonx.Marshal(d1)
+	assert.NoError(t, err)
+	assert.Equal(t, `"P1DT2H30M"`, string(marshaled))
+
+	// test equality
+	assert.True(t, d1.Equals(types.NewXDuration(26*time.Hour+30*time.Minute)))
+	assert.False(t, d1.Equals(types.NewXDuration(26*time.Hour)))
+
+	// test comparisons
+	assert.Equal(t, 0, types.NewXDuration(26*time.Hour+30*time.Minute).Compare(d1))
+	assert.Equal(t, 1, types.NewXDuration(27*time.Hour).Compare(d1))
+	assert.Equal(t, -1, types.NewXDuration(time.Minute).Compare(d1))
+}
+
+func TestToXDuration(t *testing.T) {
+	var tests = []struct {
+		value    types.XValue
+		expected types.XDuration
+		hasError bool
+	}{
+		{nil, types.XDurationZero, true},
+		{types.NewXError(errors.Errorf("Error")), types.XDurationZero, true},
+		{types.NewXNumberFromInt(123), types.XDurationZero, true},
+		{types.NewXDuration(time.Minute), types.NewXDuration(time.Minute), false},
+		{types.NewXText("PT0S"), types.XDurationZero, false},
+		{types.NewXText("P1DT2H30M"), types.NewXDuration(26*time.Hour + 30*time.Minute), false},
+		{types.NewXText(" p2w "), types.NewXDuration(14 * 24 * time.Hour), false},
+		{types.NewXText("PT1.5S"), types.NewXDuration(1500 * time.Millisecond), false},
+		{types.NewXText("-PT5M"), types.NewXDuration(-5 * time.Minute), false},
+		{types.NewXText("P"), types.XDurationZero, true},
+		{types.NewXText("P1DT"), types.XDurationZero, true},
+		{types.NewXText("P1Y"), types.XDurationZero, true},
+		{types.NewXText("1 hour"), types.XDurationZero, true},
+		{types.NewXObject(map[string]types.XValue{"__default__": types.NewXText("PT1H")}), types.NewXDuration(time.Hour), false},
+	}
+
+	env := envs.NewBuilder().Build()
+
+	for _, test := range tests {
+		result, err := types.ToXDuration(env, test.value)
+
+		if test.hasError {
+			assert.Error(t, err, "expected error for input %T{%s}", test.value, test.value)
+		} else {
+			assert.NoError(t, err, "unexpected error for input %T{%s}", test.value, test.value)
+			assert.Equal(t, test.expected, result, "result mismatch for input %T{%s}", test.value, test.value)
+		}
+	}
+}
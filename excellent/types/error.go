@@ -51,6 +51,9 @@ func (x xerror) Native() error { return x.native }
 
 func (x xerror) Error() string { return x.Native().Error() }
 
+// Unwrap returns the native error so that errors.As can find specific types of error
+func (x xerror) Unwrap() error { return x.native }
+
 // Equals determines equality for this type
 func (x xerror) Equals(o XValue) bool {
 	other := o.(xerror)
@@ -62,6 +65,36 @@ func (x xerror) Equals(o XValue) bool {
 var NilXError = NewXError(nil)
 var _ XError = NilXError
 
+// possible limits which can be exceeded by evaluation
+const (
+	LimitDepth      = "depth"
+	LimitTime       = "time"
+	LimitOutputSize = "output_size"
+)
+
+// LimitExceededError is the native error of an XError returned when evaluation exceeds one of the evaluation limits
+// of the environment
+type LimitExceededError struct {
+	Limit string
+	Max   int
+}
+
+// NewXLimitError creates a new XError for the given exceeded limit
+func NewXLimitError(limit string, max int) XError {
+	return NewXError(&LimitExceededError{Limit: limit, Max: max})
+}
+
+func (e *LimitExceededError) Error() string {
+	switch e.Limit {
+	case LimitDepth:
+		return fmt.Sprintf("evaluation exceeded maximum depth of %d", e.Max)
+	case LimitTime:
+		return fmt.Sprintf("evaluation exceeded maximum time of %dms", e.Max)
+	default:
+		return fmt.Sprintf("evaluation exceeded maximum output size of %d bytes", e.Max)
+	}
+}
+
 // IsXError returns whether the given value is an error value
 func IsXError(x XValue) bool {
 	_, isError := x.(XError)
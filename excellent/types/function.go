@@ -31,7 +31,7 @@ func (x *XFunction) Call(env envs.Environment, params []XValue) XValue {
 
 	// if function returned an error, wrap the error with the function name
 	if IsXError(val) {
-		return NewXErrorf("error calling %s: %s", x.Describe(), val.(XError).Error())
+		return NewXErrorf("error calling %s: %w", x.Describe(), val.(XError))
 	}
 
 	return val
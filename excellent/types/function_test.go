@@ -1,6 +1,7 @@
 package types_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/nyaruka/gocommon/jsonx"
@@ -27,7 +28,10 @@ func TestXFunction(t *testing.T) {
 	assert.Equal(t, types.NewXNumberFromInt(0), func1.Call(env, nil))
 	assert.Equal(t, types.NewXNumberFromInt(2), func1.Call(env, []types.XValue{types.NewXText("a"), types.NewXText("b")}))
 
-	assert.Equal(t, types.NewXErrorf("error calling bad(...): boom"), func2.Call(env, nil))
+	// errors from the function are wrapped rather than replaced
+	callErr := func2.Call(env, nil).(types.XError)
+	assert.EqualError(t, callErr, "error calling bad(...): boom")
+	assert.EqualError(t, errors.Unwrap(errors.Unwrap(callErr)), "boom")
 
 	assert.True(t, anon1.Truthy())
 	assert.Equal(t, `<anon>`, anon1.Render())
@@ -0,0 +1,153 @@
+package types
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/utils"
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// XMoney is an amount of money in a specific currency. It renders as the ISO 4217 currency code followed by the
+// amount fixed to two decimal places, and converts to a number as its amount.
+//
+//	@(cart.total) -> RWF 1850.00
+//	@(format(cart.total)) -> RWF 1,850.00
+//	@(cart.total + 150) -> 2000
+//	@(json(cart.total)) -> {"amount":1850,"currency":"RWF"}
+//
+// @type money
+type XMoney struct {
+	amount   decimal.Decimal
+	currency string
+}
+
+// NewXMoney creates a new money value
+func NewXMoney(amount decimal.Decimal, currency string) XMoney {
+	return XMoney{amount: amount, currency: currency}
+}
+
+// Describe returns a representation of this type for error messages
+func (x XMoney) Describe() string { return x.Render() }
+
+// Truthy determines truthiness for this type
+func (x XMoney) Truthy() bool {
+	return !x.amount.IsZero()
+}
+
+// Render returns the canonical text representation
+func (x XMoney) Render() string {
+	return fmt.Sprintf("%s %s", x.currency, x.amount.StringFixed(2))
+}
+
+// Format returns the pretty text representation
+func (x XMoney) Format(env envs.Environment) string {
+	return x.currency + " " + NewXNumber(x.amount).FormatCustom(env.NumberFormat(), 2, true)
+}
+
+// FormatLocalized returns the text representation using the currency symbol of the environment's locale, and the
+// number of decimal places standard for the currency, e.g. $1,850.00 or RWF 1,850
+func (x XMoney) FormatLocalized(env envs.Environment) string {
+	unit, err := currency.ParseISO(x.currency)
+	if err != nil {
+		return x.Format(env)
+	}
+
+	tag, err := language.Parse(env.DefaultLocale().ToBCP47())
+	if err != nil {
+		tag = language.Und
+	}
+
+	symbol := message.NewPrinter(tag).Sprint(currency.Symbol(unit))
+	amount := NewXNumber(x.Round(env).amount)
+
+	// symbols which end in a letter like RWF or $US are separated from the amount
+	if lastRune, _ := utf8.DecodeLastRuneInString(symbol); unicode.IsLetter(lastRune) {
+		symbol += " "
+	}
+
+	return symbol + amount.FormatCustom(env.NumberFormat(), x.places(), true)
+}
+
+// String returns the native string representation of this type
+func (x XMoney) String() string { return `XMoney(` + x.Render() + `)` }
+
+// Amount returns the amount of this money value
+func (x XMoney) Amount() decimal.Decimal { return x.amount }
+
+// Currency returns the currency code of this money value
+func (x XMoney) Currency() string { return x.currency }
+
+// Round returns this money value rounded to the number of decimal places standard for its currency, according to the
+// rounding mode of the environment
+func (x XMoney) Round(env envs.Environment) XMoney {
+	return NewXMoney(env.RoundingMode().Round(x.amount, int32(x.places())), x.currency)
+}
+
+// gets the number of decimal places standard for the currency of this money value
+func (x XMoney) places() int {
+	unit, err := currency.ParseISO(x.currency)
+	if err != nil {
+		return 2
+	}
+	places, _ := currency.Standard.Rounding(unit)
+	return places
+}
+
+// Equals determines equality for this type
+func (x XMoney) Equals(o XValue) bool {
+	other := o.(XMoney)
+
+	return x.currency == other.currency && x.amount.Equals(other.amount)
+}
+
+// Compare compares this money value to another, ordering first by currency and then by amount
+func (x XMoney) Compare(o XValue) int {
+	other := o.(XMoney)
+
+	if x.currency != other.currency {
+		if x.currency < other.currency {
+			return -1
+		}
+		return 1
+	}
+	return x.amount.Cmp(other.amount)
+}
+
+// MarshalJSON is called when a struct containing this type is marshaled
+func (x XMoney) MarshalJSON() ([]byte, error) {
+	return jsonx.Marshal(map[string]any{"amount": x.amount, "currency": x.currency})
+}
+
+// IsValidCurrency returns whether the given code is a recognized ISO 4217 currency
+func IsValidCurrency(code string) bool {
+	_, err := currency.ParseISO(code)
+	return err == nil
+}
+
+// ToXMoney converts the given value to a money value or returns an error if that isn't possible
+func ToXMoney(env envs.Environment, x XValue) (XMoney, XError) {
+	if !utils.IsNil(x) {
+		switch typed := x.(type) {
+		case XError:
+			return XMoney{}, typed
+		case XMoney:
+			return typed, nil
+		case *XObject:
+			if typed.hasDefault() {
+				return ToXMoney(env, typed.Default())
+			}
+		}
+	}
+
+	return XMoney{}, NewXErrorf("unable to convert %s to money", Describe(x))
+}
+
+var _ XValue = XMoney{}
+var _ XComparable = XMoney{}
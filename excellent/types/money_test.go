@@ -0,0 +1,88 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXMoney(t *testing.T) {
+	env := envs.NewBuilder().Build()
+
+	usd1150 := types.NewXMoney(decimal.RequireFromString("1150"), "USD")
+	usd12 := types.NewXMoney(decimal.RequireFromString("12.5"), "USD")
+	rwf12 := types.NewXMoney(decimal.RequireFromString("12.5"), "RWF")
+
+	// test equality
+	assert.True(t, usd12.Equals(types.NewXMoney(decimal.RequireFromString("12.50"), "USD")))
+	assert.False(t, usd12.Equals(usd1150))
+	assert.False(t, usd12.Equals(rwf12))
+
+	// test comparison
+	assert.Equal(t, 0, usd12.Compare(usd12))
+	assert.Equal(t, -1, usd12.Compare(usd1150))
+	assert.Equal(t, 1, usd1150.Compare(usd12))
+	assert.Equal(t, 1, usd12.Compare(rwf12))
+
+	assert.True(t, usd12.Truthy())
+	assert.False(t, types.NewXMoney(decimal.Zero, "USD").Truthy())
+
+	assert.Equal(t, `USD 1150.00`, usd1150.Render())
+	assert.Equal(t, `USD 1,150.00`, usd1150.Format(env))
+	assert.Equal(t, `USD 1150.00`, usd1150.Describe())
+	assert.Equal(t, `XMoney(USD 12.50)`, usd12.String())
+
+	data, err := jsonx.Marshal(usd12)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":12.5,"currency":"USD"}`, string(data))
+
+	// money converts to a number as its amount
+	num, xerr := types.ToXNumber(env, usd12)
+	assert.Nil(t, xerr)
+	assert.Equal(t, types.RequireXNumberFromString("12.5"), num)
+
+	text, xerr := types.ToXText(env, usd12)
+	assert.Nil(t, xerr)
+	assert.Equal(t, types.NewXText("USD 12.50"), text)
+
+	// test locale-aware formatting
+	rwf1850 := types.NewXMoney(decimal.RequireFromString("1849.5"), "RWF")
+	usd1234 := types.NewXMoney(decimal.RequireFromString("1234.5"), "USD")
+	eur12 := types.NewXMoney(decimal.RequireFromString("12.345"), "EUR")
+	invalid := types.NewXMoney(decimal.RequireFromString("12.5"), "XYZ")
+
+	usEnv := envs.NewBuilder().WithAllowedLanguages([]envs.Language{"eng"}).WithDefaultCountry("US").Build()
+	rwEnv := envs.NewBuilder().WithAllowedLanguages([]envs.Language{"kin"}).WithDefaultCountry("RW").Build()
+	frEnv := envs.NewBuilder().
+		WithAllowedLanguages([]envs.Language{"fra"}).
+		WithDefaultCountry("FR").
+		WithNumberFormat(&envs.NumberFormat{DecimalSymbol: ",", DigitGroupingSymbol: " "}).
+		Build()
+
+	assert.Equal(t, `$1,234.50`, usd1234.FormatLocalized(usEnv))
+	assert.Equal(t, `RWF 1,850`, rwf1850.FormatLocalized(usEnv))
+	assert.Equal(t, `€12.35`, eur12.FormatLocalized(usEnv))
+	assert.Equal(t, `US$1,234.50`, usd1234.FormatLocalized(rwEnv))
+	assert.Equal(t, `RF 1,850`, rwf1850.FormatLocalized(rwEnv))
+	assert.Equal(t, `$US 1 234,50`, usd1234.FormatLocalized(frEnv))
+	assert.Equal(t, `US$1,234.50`, usd1234.FormatLocalized(env)) // no locale
+	assert.Equal(t, `XYZ 12.50`, invalid.FormatLocalized(env))
+
+	assert.Equal(t, types.NewXMoney(decimal.RequireFromString("1850"), "RWF"), rwf1850.Round(env))
+	assert.Equal(t, types.NewXMoney(decimal.RequireFromString("12.35"), "EUR"), eur12.Round(env))
+
+	assert.True(t, types.IsValidCurrency("RWF"))
+	assert.False(t, types.IsValidCurrency("XYZ"))
+	assert.False(t, types.IsValidCurrency(""))
+
+	money, xerr := types.ToXMoney(env, types.NewXObject(map[string]types.XValue{"__default__": usd12}))
+	assert.Nil(t, xerr)
+	assert.Equal(t, usd12, money)
+
+	_, xerr = types.ToXMoney(env, types.NewXNumberFromInt(12))
+	assert.EqualError(t, xerr, "unable to convert 12 to money")
+}
@@ -156,6 +156,8 @@ func ToXNumber(env envs.Environment, x XValue) (XNumber, XError) {
 			return XNumberZero, typed
 		case XNumber:
 			return typed, nil
+		case XMoney:
+			return NewXNumber(typed.Amount()), nil
 		case XText:
 			parsed, err := newXNumberFromString(typed.Native())
 			if err == nil {
@@ -31,6 +31,11 @@ type XObject struct {
 	props          map[string]XValue
 	source         func() map[string]XValue
 	marshalDefault bool
+
+	// objects created by NewXLazyKeyedObject resolve each property only when it's needed
+	keys     []string
+	resolve  func(string) XValue
+	resolved map[string]XValue
 }
 
 // NewXObject returns a new object with the given properties
@@ -45,6 +50,21 @@ func NewXLazyObject(source func() map[string]XValue) *XObject {
 	}
 }
 
+// NewXLazyKeyedObject returns a new lazy object with the given property names, whose values are resolved one at a
+// time by the resolve function as they're looked up. Operations which need every property, e.g. rendering or
+// counting, still resolve them all.
+func NewXLazyKeyedObject(keys []string, resolve func(string) XValue) *XObject {
+	x := &XObject{keys: keys, resolve: resolve}
+	x.source = func() map[string]XValue {
+		props := make(map[string]XValue, len(keys))
+		for _, k := range keys {
+			props[k] = x.resolveKey(k)
+		}
+		return props
+	}
+	return x
+}
+
 // Describe returns a representation of this type for error messages
 func (x *XObject) Describe() string { return "object" }
 
@@ -146,6 +166,17 @@ func (x *XObject) Count() int {
 // Get retrieves the named property
 func (x *XObject) Get(key string) (XValue, bool) {
 	key = strings.ToLower(key)
+
+	// if we haven't already resolved everything, only resolve this property
+	if x.props == nil && x.resolve != nil {
+		for _, k := range x.keys {
+			if k != serializeDefaultAs && strings.ToLower(k) == key {
+				return x.resolveKey(k), true
+			}
+		}
+		return nil, false
+	}
+
 	for p, v := range x.properties() {
 		if strings.ToLower(p) == key {
 			return v, true
@@ -202,6 +233,15 @@ func (x *XObject) properties() map[string]XValue {
 
 // Default returns the default value for this
 func (x *XObject) Default() XValue {
+	if x.props == nil && x.resolve != nil {
+		for _, k := range x.keys {
+			if k == serializeDefaultAs {
+				return x.resolveKey(k)
+			}
+		}
+		return x
+	}
+
 	x.ensureInitialized()
 	return x.def
 }
@@ -231,6 +271,20 @@ func (x *XObject) ensureInitialized() {
 	}
 }
 
+// resolves a single property of a keyed lazy object, remembering its value in case it's needed again
+func (x *XObject) resolveKey(key string) XValue {
+	if v, resolved := x.resolved[key]; resolved {
+		return v
+	}
+	if x.resolved == nil {
+		x.resolved = make(map[string]XValue, len(x.keys))
+	}
+
+	v := x.resolve(key)
+	x.resolved[key] = v
+	return v
+}
+
 // XObjectEmpty is the empty empty
 var XObjectEmpty = NewXObject(map[string]XValue{})
 
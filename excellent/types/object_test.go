@@ -143,6 +143,60 @@ func TestXLazyObject(t *testing.T) {
 	assert.Equal(t, types.NewXText(`{"bar":123,"foo":"abc","zed":false}`), asJSON)
 }
 
+func TestXLazyKeyedObject(t *testing.T) {
+	env := envs.NewBuilder().Build()
+	resolved := make([]string, 0)
+
+	object := types.NewXLazyKeyedObject([]string{"__default__", "foo", "Bar", "zed"}, func(key string) types.XValue {
+		resolved = append(resolved, key)
+
+		switch key {
+		case "__default__":
+			return types.NewXText("abc")
+		case "foo":
+			return types.NewXText("abc")
+		case "Bar":
+			return types.NewXNumberFromInt(123)
+		}
+		return types.XBooleanFalse
+	})
+
+	assert.Equal(t, []string{}, resolved)
+
+	// looking up a property only resolves that property, and only once
+	v, exists := object.Get("bar")
+	assert.True(t, exists)
+	assert.Equal(t, types.NewXNumberFromInt(123), v)
+	v, _ = object.Get("BAR")
+	assert.Equal(t, types.NewXNumberFromInt(123), v)
+	_, exists = object.Get("xxx")
+	assert.False(t, exists)
+	_, exists = object.Get("__default__")
+	assert.False(t, exists)
+
+	assert.Equal(t, []string{"Bar"}, resolved)
+
+	// as does getting the default
+	assert.Equal(t, types.NewXText("abc"), object.Default())
+	assert.Equal(t, "abc", object.Render())
+	assert.Equal(t, []string{"Bar", "__default__"}, resolved)
+
+	// but things which need every property resolve the rest
+	assert.Equal(t, 3, object.Count())
+	assert.Equal(t, []string{"Bar", "foo", "zed"}, object.Properties())
+	assert.ElementsMatch(t, []string{"__default__", "foo", "Bar", "zed"}, resolved)
+
+	v, _ = object.Get("zed")
+	assert.Equal(t, types.XBooleanFalse, v)
+	assert.Len(t, resolved, 4)
+
+	// objects without a default key are their own default
+	object = types.NewXLazyKeyedObject([]string{"foo"}, func(key string) types.XValue { return types.NewXText("abc") })
+	assert.Equal(t, object, object.Default())
+	assert.Equal(t, `{foo: abc}`, object.Render())
+	assert.Equal(t, "foo: abc", object.Format(env))
+}
+
 func TestToXObject(t *testing.T) {
 	var tests = []struct {
 		value    types.XValue
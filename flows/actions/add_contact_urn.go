@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -18,7 +19,8 @@ func init() {
 const TypeAddContactURN string = "add_contact_urn"
 
 // AddContactURNAction can be used to add a URN to the current contact. A [event:contact_urns_changed] event
-// will be created when this action is encountered.
+// will be created when this action is encountered. The URN is normalized according to its scheme, and if that changes
+// it, a [event:contact_urn_normalized] event will be created.
 //
 //	{
 //	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
@@ -46,7 +48,7 @@ func NewAddContactURN(uuid flows.ActionUUID, scheme string, path string) *AddCon
 }
 
 // Execute runs the labeling action
-func (a *AddContactURNAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *AddContactURNAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	// only generate event if run has a contact
 	contact := run.Contact()
 	if contact == nil {
@@ -67,9 +69,13 @@ func (a *AddContactURNAction) Execute(run flows.Run, step flows.Step, logModifie
 		return nil
 	}
 
-	// create URN - modifier will take care of validating it
-	urn := urns.URN(fmt.Sprintf("%s:%s", a.Scheme, evaluatedPath))
+	// create and normalize URN - modifier will take care of validating it
+	raw := urns.URN(fmt.Sprintf("%s:%s", a.Scheme, evaluatedPath))
+	urn := flows.NormalizeURN(run.Environment(), raw)
+	if urn != raw {
+		logEvent(events.NewContactURNNormalized(raw, urn))
+	}
 
-	a.applyModifier(run, modifiers.NewURNs([]urns.URN{urn}, modifiers.URNsAppend), logModifier, logEvent)
+	a.applyModifier(ctx, run, modifiers.NewURNs([]urns.URN{urn}, modifiers.URNsAppend), logModifier, logEvent)
 	return nil
 }
@@ -0,0 +1,143 @@
+package actions
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+func init() {
+	registerType(TypeAddToCart, func() flows.Action { return &AddToCartAction{} })
+}
+
+var cartCategories = []string{CategorySuccess, CategoryFailure}
+
+// TypeAddToCart is the type for the add to cart action
+const TypeAddToCart string = "add_to_cart"
+
+// AddToCartAction can be used to add a product to the session's cart, which is available in expressions as `@cart`.
+// The product is looked up using the commerce service so that the cart always uses catalog names and prices. If the
+// session doesn't have a cart yet, one is created in the currency of the product.
+//
+// Both the product and quantity are evaluated as templates. If a result name is given, a result is saved whose value is
+// the number of units in the cart.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "add_to_cart",
+//	  "product": "maize",
+//	  "quantity": "@(3)",
+//	  "result_name": "Cart"
+//	}
+//
+// @action add_to_cart
+type AddToCartAction struct {
+	baseAction
+	onlineAction
+
+	Product    string `json:"product" validate:"required" engine:"evaluated"`
+	Quantity   string `json:"quantity,omitempty" engine:"evaluated"`
+	ResultName string `json:"result_name,omitempty"`
+}
+
+// NewAddToCart creates a new add to cart action
+func NewAddToCart(uuid flows.ActionUUID, product, quantity, resultName string) *AddToCartAction {
+	return &AddToCartAction{
+		baseAction: newBaseAction(TypeAddToCart, uuid),
+		Product:    product,
+		Quantity:   quantity,
+		ResultName: resultName,
+	}
+}
+
+// Execute runs this action
+func (a *AddToCartAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	productID, added := a.add(ctx, run, logEvent)
+
+	if a.ResultName != "" {
+		category := CategoryFailure
+		if added {
+			category = CategorySuccess
+		}
+
+		count := 0
+		if cart := run.Session().Cart(); cart != nil {
+			count = cart.Count()
+		}
+
+		a.saveResult(run, step, a.ResultName, strconv.Itoa(count), category, "", productID, nil, logEvent)
+	}
+
+	return nil
+}
+
+// tries to add the product to the cart, returning the evaluated product ID and whether it was added
+func (a *AddToCartAction) add(ctx context.Context, run flows.Run, logEvent flows.EventCallback) (string, bool) {
+	productID, err := run.EvaluateTemplate(a.Product)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+	productID = strings.TrimSpace(productID)
+	if productID == "" {
+		logEvent(events.NewErrorf("product evaluated to empty string"))
+		return productID, false
+	}
+
+	quantity := 1
+	quantityStr, err := run.EvaluateTemplate(a.Quantity)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+	if quantityStr = strings.TrimSpace(quantityStr); quantityStr != "" {
+		quantity, err = strconv.Atoi(quantityStr)
+		if err != nil || quantity < 1 {
+			logEvent(events.NewErrorf("quantity evaluated to invalid value '%s'", quantityStr))
+			return productID, false
+		}
+	}
+
+	svc, err := run.Session().Engine().Services().Commerce(run.Session().Assets())
+	if err != nil {
+		logEvent(events.NewError(err))
+		return productID, false
+	}
+
+	httpLogger := newHTTPLogger(ctx, run)
+
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeCommerce)
+	defer cancel()
+
+	product, err := svc.LookupProduct(ctx, run.Environment(), productID, httpLogger.Log)
+
+	if len(httpLogger.Logs) > 0 {
+		logEvent(events.NewCommerceCalled(httpLogger.Logs))
+	}
+
+	if err != nil {
+		logEvent(events.NewError(err))
+		return productID, false
+	}
+
+	cart := run.Session().Cart()
+	if cart == nil {
+		cart = flows.NewOrder(product.Currency)
+	}
+
+	if _, err := cart.Add(product, quantity); err != nil {
+		logEvent(events.NewError(err))
+		return productID, false
+	}
+
+	run.Session().SetCart(cart)
+	return productID, true
+}
+
+// Results enumerates any results generated by this flow object
+func (a *AddToCartAction) Results(include func(*flows.ResultInfo)) {
+	if a.ResultName != "" {
+		include(flows.NewResultInfo(a.ResultName, cartCategories))
+	}
+}
@@ -1,13 +1,15 @@
 package actions
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
-	"github.com/nyaruka/gocommon/dates"
 	"github.com/nyaruka/gocommon/stringsx"
 	"github.com/nyaruka/gocommon/urns"
 	"github.com/nyaruka/gocommon/uuids"
@@ -18,6 +20,7 @@ import (
 	"github.com/nyaruka/goflow/flows/events"
 	"github.com/nyaruka/goflow/flows/modifiers"
 	"github.com/nyaruka/goflow/utils"
+	"github.com/nyaruka/goflow/utils/jsonschema"
 	"github.com/pkg/errors"
 )
 
@@ -30,11 +33,16 @@ const maxAttachmentLength = 2048
 // max length of a quick reply
 const maxQuickReplyLength = 64
 
+// max number of bytes of inline keyboard button data (Telegram's limit for callback data)
+const maxInlineButtonDataLength = 64
+
 // common category names
 const (
 	CategorySuccess = "Success"
 	CategorySkipped = "Skipped"
 	CategoryFailure = "Failure"
+
+	CategoryInvalidResponse = "Invalid Response"
 )
 
 var webhookCategories = []string{CategorySuccess, CategoryFailure}
@@ -45,6 +53,13 @@ var webhookStatusCategories = map[flows.CallStatus]string{
 	flows.CallStatusSubscriberGone:  CategoryFailure,
 }
 
+var callTransferCategories = []string{"Completed", "Busy", "Failed"}
+var callTransferStatusCategories = map[flows.CallTransferStatus]string{
+	flows.CallTransferStatusCompleted: "Completed",
+	flows.CallTransferStatusBusy:      "Busy",
+	flows.CallTransferStatusFailed:    "Failed",
+}
+
 var registeredTypes = map[string](func() flows.Action){}
 
 // registers a new type of action
@@ -83,16 +98,33 @@ func (a *baseAction) Validate() error { return nil }
 func (a *baseAction) LocalizationUUID() uuids.UUID { return uuids.UUID(a.UUID_) }
 
 // helper function for actions that send a message (text + attachments) that must be localized and evalulated
-func (a *baseAction) evaluateMessage(run flows.Run, languages []envs.Language, actionText string, actionAttachments []string, actionQuickReplies []string, logEvent flows.EventCallback) (string, []utils.Attachment, []string, envs.Language) {
+func (a *baseAction) evaluateMessage(ctx context.Context, run flows.Run, languages []envs.Language, actionText string, actionAttachments []string, actionQuickReplies []string, logEvent flows.EventCallback) (string, []utils.Attachment, []string, envs.Language) {
+	return evaluateMessage(ctx, run, a.LocalizationUUID(), languages, actionText, actionAttachments, actionQuickReplies, logEvent)
+}
+
+// truncates the given message text to the limit of the run's truncation policy
+func truncateMsgText(run flows.Run, text string, logEvent flows.EventCallback) string {
+	if limit := run.Environment().TruncationPolicy().MsgText; limit > 0 {
+		if length := utf8.RuneCountInString(text); length > limit {
+			logEvent(events.NewValueTruncated(events.TruncationTargetMsg, "", length, limit))
+			return stringsx.Truncate(text, limit)
+		}
+	}
+	return text
+}
+
+// localizes and evaluates a message whose translations are keyed by the given localization UUID
+func evaluateMessage(ctx context.Context, run flows.Run, localizationUUID uuids.UUID, languages []envs.Language, actionText string, actionAttachments []string, actionQuickReplies []string, logEvent flows.EventCallback) (string, []utils.Attachment, []string, envs.Language) {
 	// localize and evaluate the message text
-	localizedText, txtLang := run.GetTextArray(uuids.UUID(a.UUID()), "text", []string{actionText}, languages)
+	localizedText, txtLang := run.GetTextArray(localizationUUID, "text", []string{actionText}, languages)
 	evaluatedText, err := run.EvaluateTemplate(localizedText[0])
 	if err != nil {
 		logEvent(events.NewError(err))
 	}
+	evaluatedText = truncateMsgText(run, evaluatedText, logEvent)
 
 	// localize and evaluate the message attachments
-	translatedAttachments, attLang := run.GetTextArray(uuids.UUID(a.UUID()), "attachments", actionAttachments, languages)
+	translatedAttachments, attLang := run.GetTextArray(localizationUUID, "attachments", actionAttachments, languages)
 	evaluatedAttachments := make([]utils.Attachment, 0, len(translatedAttachments))
 	for _, a := range translatedAttachments {
 		evaluatedAttachment, err := run.EvaluateTemplate(a)
@@ -107,11 +139,15 @@ func (a *baseAction) evaluateMessage(run flows.Run, languages []envs.Language, a
 			logEvent(events.NewErrorf("evaluated attachment is longer than %d limit, skipping", maxAttachmentLength))
 			continue
 		}
-		evaluatedAttachments = append(evaluatedAttachments, utils.Attachment(evaluatedAttachment))
+		attachment, ok := processAttachment(ctx, run, utils.Attachment(evaluatedAttachment), logEvent)
+		if !ok {
+			continue
+		}
+		evaluatedAttachments = append(evaluatedAttachments, attachment)
 	}
 
 	// localize and evaluate the quick replies
-	translatedQuickReplies, qrsLang := run.GetTextArray(uuids.UUID(a.UUID()), "quick_replies", actionQuickReplies, languages)
+	translatedQuickReplies, qrsLang := run.GetTextArray(localizationUUID, "quick_replies", actionQuickReplies, languages)
 	evaluatedQuickReplies := make([]string, 0, len(translatedQuickReplies))
 	for _, qr := range translatedQuickReplies {
 		evaluatedQuickReply, err := run.EvaluateTemplate(qr)
@@ -122,7 +158,11 @@ func (a *baseAction) evaluateMessage(run flows.Run, languages []envs.Language, a
 			logEvent(events.NewErrorf("quick reply text evaluated to empty string, skipping"))
 			continue
 		}
-		evaluatedQuickReplies = append(evaluatedQuickReplies, stringsx.TruncateEllipsis(evaluatedQuickReply, maxQuickReplyLength))
+		if utf8.RuneCountInString(evaluatedQuickReply) > maxQuickReplyLength {
+			logEvent(events.NewWarningf("quick reply truncated to %d characters", maxQuickReplyLength))
+			evaluatedQuickReply = stringsx.TruncateEllipsis(evaluatedQuickReply, maxQuickReplyLength)
+		}
+		evaluatedQuickReplies = append(evaluatedQuickReplies, evaluatedQuickReply)
 	}
 
 	// although it's possible for the different parts of the message to have different languages, we want to resolve
@@ -139,18 +179,73 @@ func (a *baseAction) evaluateMessage(run flows.Run, languages []envs.Language, a
 	return evaluatedText, evaluatedAttachments, evaluatedQuickReplies, lang
 }
 
+// passes an outgoing attachment through the session's attachment service, if one is configured, returning the attachment
+// to send and whether it should be sent at all
+func processAttachment(ctx context.Context, run flows.Run, attachment utils.Attachment, logEvent flows.EventCallback) (utils.Attachment, bool) {
+	svc, err := run.Session().Engine().Services().Attachment(run.Session().Assets())
+	if err != nil {
+		return attachment, true
+	}
+
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeAttachment)
+	defer cancel()
+
+	processed, err := svc.Process(ctx, attachment)
+	if err != nil {
+		if rejected, isRejected := err.(*flows.AttachmentRejectedError); isRejected {
+			logEvent(events.NewAttachmentRejected(attachment, rejected.Reason, rejected.Size, rejected.Limit))
+			return "", false
+		}
+
+		// if the service fails we still send the attachment as is
+		logEvent(events.NewError(err))
+		return attachment, true
+	}
+	return processed, true
+}
+
 // helper to save a run result and log it as an event
 func (a *baseAction) saveResult(run flows.Run, step flows.Step, name, value, category, categoryLocalized string, input string, extra json.RawMessage, logEvent flows.EventCallback) {
-	result := flows.NewResult(name, value, category, categoryLocalized, step.NodeUUID(), input, extra, dates.Now())
+	a.saveTypedResult(run, step, name, value, category, categoryLocalized, input, extra, nil, envs.NilPIICategory, logEvent)
+}
+
+// helper to save a run result whose value is checked against the given schema (if any) and tagged with the given PII
+// category (if any), and log it as an event
+func (a *baseAction) saveTypedResult(run flows.Run, step flows.Step, name, value, category, categoryLocalized string, input string, extra json.RawMessage, schema *flows.ResultSchema, pii envs.PIICategory, logEvent flows.EventCallback) {
+	if limit := run.Environment().TruncationPolicy().ResultValue; utf8.RuneCountInString(value) > limit {
+		logEvent(events.NewValueTruncated(events.TruncationTargetResult, name, utf8.RuneCountInString(value), limit))
+	}
+
+	result := flows.NewResult(name, value, category, categoryLocalized, step.NodeUUID(), input, extra, run.Session().Sources().Now())
+	if schema != nil {
+		if err := schema.Apply(run.Environment(), result); err != nil {
+			logEvent(events.NewError(err))
+		}
+	}
+	result.PII = pii
 	run.SaveResult(result)
 	logEvent(events.NewRunResultChanged(result))
 }
 
-// helper to save a run result based on a webhook call and log it as an event
-func (a *baseAction) saveWebhookResult(run flows.Run, step flows.Step, name string, call *flows.WebhookCall, status flows.CallStatus, logEvent flows.EventCallback) {
+// helper to save a run result based on a call transfer, whose value is the number of seconds the contact was transferred
+// for, and log it as an event. A nil transfer means the transfer couldn't be attempted.
+func (a *baseAction) saveCallTransferResult(run flows.Run, step flows.Step, name string, transfer *flows.CallTransfer, input string, logEvent flows.EventCallback) {
+	if transfer == nil {
+		transfer = flows.NewCallTransfer(flows.CallTransferStatusFailed, 0)
+	}
+
+	a.saveResult(run, step, name, strconv.Itoa(transfer.Duration), callTransferStatusCategories[transfer.Status], "", input, nil, logEvent)
+}
+
+// helper to save a run result based on a webhook call and log it as an event. A call whose response didn't match the
+// action's response schema is categorized as an invalid response rather than by its status.
+func (a *baseAction) saveWebhookResult(run flows.Run, step flows.Step, name string, call *flows.WebhookCall, status flows.CallStatus, validResponse bool, logEvent flows.EventCallback) {
 	input := fmt.Sprintf("%s %s", call.Request.Method, call.Request.URL.String())
 	value := "0"
 	category := webhookStatusCategories[status]
+	if !validResponse {
+		category = CategoryInvalidResponse
+	}
 	var extra json.RawMessage
 
 	if call.Response != nil {
@@ -164,8 +259,181 @@ func (a *baseAction) saveWebhookResult(run flows.Run, step flows.Step, name stri
 	a.saveResult(run, step, name, value, category, "", input, extra, logEvent)
 }
 
+// helper to call a webhook with the given templated headers and optional named credential for the Authorization
+// header, logging the call and saving a result if a result name is given. If a response schema is given, successful
+// responses are checked against it.
+func (a *baseAction) callWebhook(ctx context.Context, run flows.Run, step flows.Step, req *http.Request, headers map[string]string, credential, resultName string, responseSchema *jsonschema.Schema, logEvent flows.EventCallback) {
+	input := fmt.Sprintf("%s %s", req.Method, req.URL.String())
+
+	// add the custom headers, substituting any template vars
+	for key, value := range headers {
+		headerValue, err := run.EvaluateTemplate(value)
+		if err != nil {
+			logEvent(events.NewError(err))
+		}
+
+		req.Header.Add(key, headerValue)
+	}
+
+	var redact stringsx.Redactor
+
+	if credential != "" {
+		auth, err := resolveCredential(ctx, run, credential)
+		if err != nil {
+			logEvent(events.NewError(err))
+
+			if resultName != "" {
+				a.saveResult(run, step, resultName, "0", CategoryFailure, "", input, nil, logEvent)
+			}
+			return
+		}
+
+		req.Header.Set("Authorization", auth)
+		redact = stringsx.NewRedactor(flows.RedactionMask, auth)
+	}
+
+	svc, err := run.Session().Engine().Services().Webhook(run.Session().Assets())
+	if err != nil {
+		logEvent(events.NewError(err))
+		return
+	}
+
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeWebhook)
+	defer cancel()
+
+	call, err := svc.Call(req.WithContext(ctx))
+
+	if err != nil {
+		logWebhookError(err, logEvent)
+
+		// if the service refused to make the call, there's no call to save but we still want to route to failure
+		var blocked *flows.WebhookBlockedError
+		var denied *flows.WebhookDeniedError
+		var tooLarge *flows.WebhookRequestTooLargeError
+		if (errors.As(err, &blocked) || errors.As(err, &denied) || errors.As(err, &tooLarge)) && resultName != "" {
+			a.saveResult(run, step, resultName, "0", CategoryFailure, "", input, nil, logEvent)
+		}
+	}
+	if call != nil {
+		if call.Breaker != nil {
+			logEvent(events.NewWebhookBreakerChanged(call.Breaker))
+		}
+
+		a.updateWebhook(run, call)
+
+		status := callStatus(call, err, false)
+
+		logEvent(events.NewWebhookCalled(call, status, "", redact))
+		storeWebhookCall(ctx, run, call, status, redact)
+
+		if call.ResponseTruncated {
+			logEvent(events.NewWebhookResponseTruncated(call.Request.URL.String(), len(call.ResponseBody)))
+		}
+
+		validResponse := status != flows.CallStatusSuccess || checkWebhookResponse(responseSchema, call.Request.URL.String(), call.ResponseJSON, logEvent)
+
+		if resultName != "" {
+			a.saveWebhookResult(run, step, resultName, call, status, validResponse, logEvent)
+		}
+	}
+}
+
+// checks a webhook response against the given schema (if any), logging how it doesn't match, and returns whether it does
+func checkWebhookResponse(schema *jsonschema.Schema, url string, responseJSON []byte, logEvent flows.EventCallback) bool {
+	if schema == nil {
+		return true
+	}
+	if len(responseJSON) == 0 {
+		logEvent(events.NewWebhookResponseInvalid(url, []*jsonschema.Violation{{Path: "$", Message: "response isn't valid JSON"}}))
+		return false
+	}
+
+	violations, err := schema.Validate(responseJSON)
+	if err != nil {
+		logEvent(events.NewError(err))
+		return false
+	}
+	if len(violations) > 0 {
+		logEvent(events.NewWebhookResponseInvalid(url, violations))
+		return false
+	}
+	return true
+}
+
+// logs an error returned by a webhook service, which is its own event if the call was denied by the host policy
+func logWebhookError(err error, logEvent flows.EventCallback) {
+	var denied *flows.WebhookDeniedError
+	if errors.As(err, &denied) {
+		logEvent(events.NewWebhookBlocked(denied.URL, denied.Reason))
+	} else {
+		logEvent(events.NewError(err))
+	}
+}
+
+// resolves the named credential to the value of an Authorization header
+func resolveCredential(ctx context.Context, run flows.Run, name string) (string, error) {
+	svc, err := run.Session().Engine().Services().Credential(run.Session().Assets())
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeCredential)
+	defer cancel()
+
+	return svc.Authorization(ctx, name)
+}
+
+// derives the context for a call to the given service, which is cancelled when the sprint's context is, or when the
+// engine's timeout for that service is reached. If the sprint is being profiled, the call is timed until it's cancelled.
+func serviceContext(ctx context.Context, run flows.Run, service flows.ServiceType) (context.Context, context.CancelFunc) {
+	var cancel context.CancelFunc
+	if timeout := run.Session().Engine().ServiceTimeout(service); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	if profiler := run.Session().Profiler(); profiler != nil {
+		stop := profiler.Start(flows.ProfileCategoryService, string(service))
+		return ctx, func() {
+			cancel()
+			stop()
+		}
+	}
+	return ctx, cancel
+}
+
+// gets the HTTP log sink for the session, if one is configured
+func httpLogSink(run flows.Run) flows.HTTPLogSink {
+	sink, err := run.Session().Engine().Services().HTTPLogSink(run.Session().Assets())
+	if err != nil {
+		return nil
+	}
+	return sink
+}
+
+// creates a logger for the HTTP calls made by a service, which also sends them to the session's HTTP log sink
+func newHTTPLogger(ctx context.Context, run flows.Run) *flows.HTTPLogger {
+	return flows.NewHTTPLogger(ctx, httpLogSink(run))
+}
+
+// sends the trace of a webhook call to the session's HTTP log sink
+func storeWebhookCall(ctx context.Context, run flows.Run, call *flows.WebhookCall, status flows.CallStatus, redact stringsx.Redactor) {
+	if sink := httpLogSink(run); sink != nil {
+		sink.Store(ctx, &flows.HTTPLog{
+			HTTPLogWithoutTime: flows.NewHTTPLogWithoutTime(call.Trace, status, redact),
+			CreatedOn:          call.StartTime,
+		})
+	}
+}
+
 func (a *baseAction) updateWebhook(run flows.Run, call *flows.WebhookCall) {
-	parsed := types.JSONToXValue(call.ResponseJSON)
+	a.setWebhook(run, call.ResponseJSON)
+}
+
+// sets @webhook from the given JSON response, or to an empty object if the response wasn't JSON
+func (a *baseAction) setWebhook(run flows.Run, responseJSON []byte) {
+	parsed := types.JSONToXValue(responseJSON)
 
 	switch typed := parsed.(type) {
 	case nil, types.XError:
@@ -176,9 +444,9 @@ func (a *baseAction) updateWebhook(run flows.Run, call *flows.WebhookCall) {
 }
 
 // helper to apply a contact modifier
-func (a *baseAction) applyModifier(run flows.Run, mod flows.Modifier, logModifier flows.ModifierCallback, logEvent flows.EventCallback) bool {
+func (a *baseAction) applyModifier(ctx context.Context, run flows.Run, mod flows.Modifier, logModifier flows.ModifierCallback, logEvent flows.EventCallback) bool {
 	logModifier(mod)
-	return modifiers.Apply(run.Environment(), run.Session().Engine().Services(), run.Session().Assets(), run.Contact(), mod, logEvent)
+	return modifiers.Apply(ctx, run.Environment(), run.Session().Engine().Services(), run.Session().Assets(), run.Contact(), mod, logEvent)
 }
 
 // helper to log a failure
@@ -219,6 +487,12 @@ func (a *voiceAction) AllowedFlowTypes() []flows.FlowType {
 	return []flows.FlowType{flows.FlowTypeVoice}
 }
 
+// utility struct for actions which can be executed concurrently in the branches of a parallel router
+type concurrentAction struct{}
+
+// Concurrent returns whether this action can be executed concurrently with others
+func (a *concurrentAction) Concurrent() bool { return true }
+
 // utility struct for actions which operate on other contacts
 type otherContactsAction struct {
 	URNs         []urns.URN                `json:"urns,omitempty"`
@@ -331,6 +605,33 @@ func resolveGroups(run flows.Run, references []*assets.GroupReference, logEvent
 	return groups
 }
 
+// helper function to resolve a topic reference to an actual topic
+func resolveTopic(run flows.Run, ref *assets.TopicReference, logEvent flows.EventCallback) *flows.Topic {
+	topicAssets := run.Session().Assets().Topics()
+	var topic *flows.Topic
+
+	if ref.Variable() {
+		// is an expression that evaluates to an existing topic's name
+		evaluatedName, err := run.EvaluateTemplate(ref.NameMatch)
+		if err != nil {
+			logEvent(events.NewError(err))
+		} else {
+			topic = topicAssets.FindByName(evaluatedName)
+			if topic == nil {
+				logEvent(events.NewErrorf("no such topic with name '%s'", evaluatedName))
+			}
+		}
+	} else {
+		// topic is a fixed topic with a UUID
+		topic = topicAssets.Get(ref.UUID)
+		if topic == nil {
+			logEvent(events.NewDependencyError(ref))
+		}
+	}
+
+	return topic
+}
+
 // helper function for actions that have a set of label references that must be resolved to actual labels
 func resolveLabels(run flows.Run, references []*assets.LabelReference, logEvent flows.EventCallback) []*flows.Label {
 	labelAssets := run.Session().Assets().Labels()
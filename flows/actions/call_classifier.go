@@ -1,6 +1,8 @@
 package actions
 
 import (
+	"context"
+
 	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/flows"
@@ -18,7 +20,7 @@ const TypeCallClassifier string = "call_classifier"
 
 // CallClassifierAction can be used to classify the intent and entities from a given input using an NLU classifier. It always
 // saves a result indicating whether the classification was successful, skipped or failed, and what the extracted intents
-// and entities were.
+// and entities were. The intents are also available as @intents until the end of the sprint.
 //
 //	{
 //	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
@@ -35,6 +37,7 @@ const TypeCallClassifier string = "call_classifier"
 type CallClassifierAction struct {
 	baseAction
 	onlineAction
+	concurrentAction
 
 	Classifier *assets.ClassifierReference `json:"classifier" validate:"required"`
 	Input      string                      `json:"input" validate:"required" engine:"evaluated"`
@@ -52,7 +55,7 @@ func NewCallClassifier(uuid flows.ActionUUID, classifier *assets.ClassifierRefer
 }
 
 // Execute runs this action
-func (a *CallClassifierAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *CallClassifierAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	classifiers := run.Session().Assets().Classifiers()
 	classifier := classifiers.Get(a.Classifier.UUID)
 
@@ -62,8 +65,11 @@ func (a *CallClassifierAction) Execute(run flows.Run, step flows.Step, logModifi
 		logEvent(events.NewError(err))
 	}
 
-	classification, skipped := a.classify(run, step, input, classifier, logEvent)
+	classification, skipped := a.classify(ctx, run, step, input, classifier, logEvent)
 	if classification != nil {
+		classifier.Calibrate(classification)
+		run.Session().SetClassification(classification)
+
 		a.saveSuccess(run, step, input, classification, logEvent)
 	} else if skipped {
 		a.saveSkipped(run, step, input, logEvent)
@@ -74,7 +80,7 @@ func (a *CallClassifierAction) Execute(run flows.Run, step flows.Step, logModifi
 	return nil
 }
 
-func (a *CallClassifierAction) classify(run flows.Run, step flows.Step, input string, classifier *flows.Classifier, logEvent flows.EventCallback) (*flows.Classification, bool) {
+func (a *CallClassifierAction) classify(ctx context.Context, run flows.Run, step flows.Step, input string, classifier *flows.Classifier, logEvent flows.EventCallback) (*flows.Classification, bool) {
 	if input == "" {
 		logEvent(events.NewErrorf("can't classify empty input, skipping classification"))
 		return nil, true
@@ -90,9 +96,12 @@ func (a *CallClassifierAction) classify(run flows.Run, step flows.Step, input st
 		return nil, false
 	}
 
-	httpLogger := &flows.HTTPLogger{}
+	httpLogger := newHTTPLogger(ctx, run)
+
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeClassification)
+	defer cancel()
 
-	classification, err := svc.Classify(run.Environment(), input, httpLogger.Log)
+	classification, err := svc.Classify(ctx, run.Environment(), input, httpLogger.Log)
 
 	if len(httpLogger.Logs) > 0 {
 		logEvent(events.NewClassifierCalled(classifier.Reference(), httpLogger.Logs))
@@ -0,0 +1,175 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils/graphql"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpguts"
+)
+
+func init() {
+	registerType(TypeCallGraphQL, func() flows.Action { return &CallGraphQLAction{} })
+}
+
+// TypeCallGraphQL is the type for the call GraphQL action
+const TypeCallGraphQL string = "call_graphql"
+
+// CallGraphQLAction can be used to make a query or mutation against a GraphQL API. The query itself is fixed and is
+// checked to be valid GraphQL when the flow is read, and values are passed in as `variables` which are templates
+// evaluated at runtime. Each variable keeps the type of its evaluated expression, so `@fields.age` is sent as a number
+// if the contact has a numeric age. The url and headers may also be templates, and like [action:call_webhook] a
+// `credential` can be used to set the Authorization header.
+//
+// The query is POSTed as JSON and a [event:webhook_called] event is created with the results. If this action has a
+// `result_name`, a result is created whose value is the status code and category is `Success` or `Failure`, with the
+// `data` and `errors` of the response accessible through `extra` on the result. The response is also accessible in
+// expressions as `@webhook`.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "call_graphql",
+//	  "url": "http://localhost:49998/graphql",
+//	  "query": "query Customer($phone: String!) { customer(phone: $phone) { name points } }",
+//	  "variables": {
+//	    "phone": "@(urn_parts(contact.urn).path)"
+//	  },
+//	  "result_name": "customer"
+//	}
+//
+// @action call_graphql
+type CallGraphQLAction struct {
+	baseAction
+	onlineAction
+	concurrentAction
+
+	URL        string            `json:"url" validate:"required" engine:"evaluated"`
+	Query      string            `json:"query" validate:"required"`
+	Variables  map[string]string `json:"variables,omitempty" engine:"evaluated"`
+	Headers    map[string]string `json:"headers,omitempty" engine:"evaluated"`
+	Credential string            `json:"credential,omitempty"`
+	ResultName string            `json:"result_name,omitempty"`
+}
+
+// NewCallGraphQL creates a new call GraphQL action
+func NewCallGraphQL(uuid flows.ActionUUID, url, query string, variables, headers map[string]string, credential, resultName string) *CallGraphQLAction {
+	return &CallGraphQLAction{
+		baseAction: newBaseAction(TypeCallGraphQL, uuid),
+		URL:        url,
+		Query:      query,
+		Variables:  variables,
+		Headers:    headers,
+		Credential: credential,
+		ResultName: resultName,
+	}
+}
+
+// Validate validates our action is valid
+func (a *CallGraphQLAction) Validate() error {
+	if err := graphql.Validate(a.Query); err != nil {
+		return errors.Wrap(err, "invalid query")
+	}
+
+	for key := range a.Headers {
+		if !httpguts.ValidHeaderFieldName(key) {
+			return errors.Errorf("header '%s' is not a valid HTTP header", key)
+		}
+		if a.Credential != "" && http.CanonicalHeaderKey(key) == "Authorization" {
+			return errors.New("can't specify both a credential and an Authorization header")
+		}
+	}
+
+	return nil
+}
+
+// Execute runs this action
+func (a *CallGraphQLAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	url, err := run.EvaluateTemplate(a.URL)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+
+	url = strings.TrimSpace(url)
+
+	if url == "" {
+		logEvent(events.NewErrorf("GraphQL URL evaluated to empty string"))
+		return nil
+	}
+	if !isValidURL(url) {
+		logEvent(events.NewErrorf("GraphQL URL evaluated to an invalid URL: '%s'", url))
+		return nil
+	}
+
+	body, err := jsonx.Marshal(&graphQLRequest{Query: a.Query, Variables: a.evaluateVariables(run, logEvent)})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if !a.hasHeader("Content-Type") {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	a.callWebhook(ctx, run, step, req, a.Headers, a.Credential, a.ResultName, nil, logEvent)
+	return nil
+}
+
+// evaluates our variables, each to the JSON representation of its value
+func (a *CallGraphQLAction) evaluateVariables(run flows.Run, logEvent flows.EventCallback) map[string]json.RawMessage {
+	if len(a.Variables) == 0 {
+		return nil
+	}
+
+	variables := make(map[string]json.RawMessage, len(a.Variables))
+
+	for key, template := range a.Variables {
+		var asJSON types.XText
+
+		value, err := run.EvaluateTemplateValue(template)
+		if err == nil {
+			asJSON, err = types.ToXJSON(value)
+		}
+		if err != nil {
+			logEvent(events.NewError(err))
+			asJSON = types.NewXText(`null`)
+		}
+
+		variables[key] = json.RawMessage(asJSON.Native())
+	}
+
+	return variables
+}
+
+func (a *CallGraphQLAction) hasHeader(name string) bool {
+	for key := range a.Headers {
+		if http.CanonicalHeaderKey(key) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Results enumerates any results generated by this flow object
+func (a *CallGraphQLAction) Results(include func(*flows.ResultInfo)) {
+	if a.ResultName != "" {
+		include(flows.NewResultInfo(a.ResultName, webhookCategories))
+	}
+}
+
+type graphQLRequest struct {
+	Query     string                     `json:"query"`
+	Variables map[string]json.RawMessage `json:"variables,omitempty"`
+}
@@ -1,9 +1,11 @@
 package actions
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
@@ -54,23 +56,35 @@ const TypeCallResthook string = "call_resthook"
 // A [event:webhook_called] event will be created for each subscriber of the resthook with the results
 // of the HTTP call. If the action has `result_name` set, a result will
 // be created with that name, and if the resthook returns valid JSON, that will be accessible
-// through `extra` on the result.
+// through `extra` on the result. If a subscriber responds with a 410 status, a [event:resthook_subscriber_gone]
+// event is created so that the subscription can be removed.
+//
+// If `retries` is set, calls to a subscriber which fail with a connection error, a 429 or a 5XX status are retried up
+// to that many times, waiting `retry_backoff` milliseconds (default 1000) before the first retry and twice as long
+// before each retry after that. A [event:webhook_called] event is created for every attempt.
 //
 //	{
 //	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
 //	  "type": "call_resthook",
-//	  "resthook": "new-registration"
+//	  "resthook": "new-registration",
+//	  "retries": 2
 //	}
 //
 // @action call_resthook
 type CallResthookAction struct {
 	baseAction
 	onlineAction
+	concurrentAction
 
-	Resthook   string `json:"resthook" validate:"required"`
-	ResultName string `json:"result_name,omitempty"`
+	Resthook     string `json:"resthook" validate:"required"`
+	ResultName   string `json:"result_name,omitempty"`
+	Retries      int    `json:"retries,omitempty" validate:"min=0,max=5"`
+	RetryBackoff int    `json:"retry_backoff,omitempty" validate:"min=0,max=60000"`
 }
 
+// the wait before the first retry of a subscriber call if the action doesn't specify one
+const defaultResthookRetryBackoff = time.Second
+
 // NewCallResthook creates a new call resthook action
 func NewCallResthook(uuid flows.ActionUUID, resthook string, resultName string) *CallResthookAction {
 	return &CallResthookAction{
@@ -81,7 +95,7 @@ func NewCallResthook(uuid flows.ActionUUID, resthook string, resultName string)
 }
 
 // Execute runs this action
-func (a *CallResthookAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *CallResthookAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	// NOOP if resthook doesn't exist
 	resthook := run.Session().Assets().Resthooks().FindBySlug(a.Resthook)
 	if resthook == nil {
@@ -107,28 +121,18 @@ func (a *CallResthookAction) Execute(run flows.Run, step flows.Step, logModifier
 	calls := make([]*flows.WebhookCall, 0, len(resthook.Subscribers()))
 
 	for _, url := range resthook.Subscribers() {
-		req, err := http.NewRequest("POST", url, strings.NewReader(payload))
+		call, err := a.callSubscriber(ctx, run, url, payload, logEvent)
 		if err != nil {
 			logEvent(events.NewError(err))
 			return nil
 		}
 
-		req.Header.Add("Content-Type", "application/json")
-
-		svc, err := run.Session().Engine().Services().Webhook(run.Session().Assets())
-		if err != nil {
-			logEvent(events.NewError(err))
-			return nil
-		}
-
-		call, err := svc.Call(req)
-
-		if err != nil {
-			logEvent(events.NewError(err))
-		}
 		if call != nil {
 			calls = append(calls, call)
-			logEvent(events.NewWebhookCalled(call, callStatus(call, nil, true), a.Resthook))
+
+			if callStatus(call, nil, true) == flows.CallStatusSubscriberGone {
+				logEvent(events.NewResthookSubscriberGone(a.Resthook, url))
+			}
 		}
 	}
 
@@ -139,7 +143,7 @@ func (a *CallResthookAction) Execute(run flows.Run, step flows.Step, logModifier
 
 	if a.ResultName != "" {
 		if asResult != nil {
-			a.saveWebhookResult(run, step, a.ResultName, asResult, callStatus(asResult, nil, true), logEvent)
+			a.saveWebhookResult(run, step, a.ResultName, asResult, callStatus(asResult, nil, true), true, logEvent)
 		} else {
 			a.saveResult(run, step, a.ResultName, "no subscribers", "Failure", "", "", nil, logEvent)
 		}
@@ -148,6 +152,84 @@ func (a *CallResthookAction) Execute(run flows.Run, step flows.Step, logModifier
 	return nil
 }
 
+// calls a single subscriber, retrying if the action has retries and the call fails in a way that might be temporary,
+// and returns the last call made. An error is only returned if the call couldn't be attempted at all.
+func (a *CallResthookAction) callSubscriber(ctx context.Context, run flows.Run, url, payload string, logEvent flows.EventCallback) (*flows.WebhookCall, error) {
+	svc, err := run.Session().Engine().Services().Webhook(run.Session().Assets())
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := defaultResthookRetryBackoff
+	if a.RetryBackoff > 0 {
+		backoff = time.Duration(a.RetryBackoff) * time.Millisecond
+	}
+
+	var call *flows.WebhookCall
+
+	for attempt := 0; attempt <= a.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return call, nil
+			}
+		}
+
+		req, err := http.NewRequest("POST", url, strings.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Add("Content-Type", "application/json")
+
+		callCtx, cancel := serviceContext(ctx, run, flows.ServiceTypeWebhook)
+		attemptCall, err := svc.Call(req.WithContext(callCtx))
+		cancel()
+
+		if err != nil {
+			logWebhookError(err, logEvent)
+		}
+		if attemptCall == nil {
+			return call, nil
+		}
+
+		call = attemptCall
+
+		if call.Breaker != nil {
+			logEvent(events.NewWebhookBreakerChanged(call.Breaker))
+		}
+
+		status := callStatus(call, nil, true)
+
+		logEvent(events.NewWebhookCalled(call, status, a.Resthook, nil))
+		storeWebhookCall(ctx, run, call, status, nil)
+
+		if call.ResponseTruncated {
+			logEvent(events.NewWebhookResponseTruncated(url, len(call.ResponseBody)))
+		}
+
+		if !isRetryableCall(call, status) {
+			break
+		}
+	}
+
+	return call, nil
+}
+
+// determines whether a failed subscriber call might succeed if it's retried
+func isRetryableCall(call *flows.WebhookCall, status flows.CallStatus) bool {
+	if status == flows.CallStatusConnectionError {
+		return true
+	}
+	if status == flows.CallStatusResponseError {
+		code := call.Response.StatusCode
+		return code == http.StatusTooManyRequests || code/100 == 5
+	}
+	return false
+}
+
 // picks one of the resthook calls to become the result generated by this action
 func (a *CallResthookAction) pickResultCall(calls []*flows.WebhookCall) *flows.WebhookCall {
 	var lastSuccess, last410, lastFailure *flows.WebhookCall
@@ -0,0 +1,195 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils"
+	"github.com/nyaruka/goflow/utils/xmlx"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpguts"
+)
+
+func init() {
+	registerType(TypeCallSOAP, func() flows.Action { return &CallSOAPAction{} })
+
+	utils.RegisterValidatorAlias("soap_version", "eq=1.1|eq=1.2", func(validator.FieldError) string {
+		return "is not a supported SOAP version"
+	})
+}
+
+// TypeCallSOAP is the type for the call SOAP action
+const TypeCallSOAP string = "call_soap"
+
+// supported SOAP versions
+const (
+	SOAPVersion11 = "1.1"
+	SOAPVersion12 = "1.2"
+)
+
+var soapEnvelopeNamespaces = map[string]string{
+	SOAPVersion11: "http://schemas.xmlsoap.org/soap/envelope/",
+	SOAPVersion12: "http://www.w3.org/2003/05/soap-envelope",
+}
+
+// CallSOAPAction can be used to call a SOAP service. The `body` is the XML of the request which is wrapped in a SOAP
+// envelope, along with the optional `header` XML, and POSTed to the url. The url, header and body may be templates,
+// and values inserted into the header and body are XML escaped. The `soap_action` identifies the operation being
+// called, and the `soap_version` can be `1.1` (the default) or `1.2`. Like [action:call_webhook], custom HTTP
+// `headers` can be sent and a `credential` can be used to set the Authorization header.
+//
+// A [event:webhook_called] event is created with the results. The XML response is converted so that it can be
+// accessed in expressions as `@webhook` in the same way as a JSON response. Each element becomes a property, repeated
+// elements become arrays, and attributes become properties prefixed with `@`. If this action has a `result_name`, a
+// result is created whose value is the status code and category is `Success` or `Failure`, with the converted response
+// accessible through `extra` on the result.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "call_soap",
+//	  "url": "http://localhost:49998/?cmd=soap",
+//	  "soap_action": "http://bank.example.com/GetBalance",
+//	  "body": "<GetBalance xmlns=\"http://bank.example.com/\"><Phone>@(urn_parts(contact.urn).path)</Phone></GetBalance>",
+//	  "result_name": "balance"
+//	}
+//
+// @action call_soap
+type CallSOAPAction struct {
+	baseAction
+	onlineAction
+	concurrentAction
+
+	URL         string            `json:"url" validate:"required" engine:"evaluated"`
+	SOAPAction  string            `json:"soap_action,omitempty"`
+	SOAPVersion string            `json:"soap_version,omitempty" validate:"omitempty,soap_version"`
+	Header      string            `json:"header,omitempty" engine:"evaluated"`
+	Body        string            `json:"body" validate:"required" engine:"evaluated"`
+	Headers     map[string]string `json:"headers,omitempty" engine:"evaluated"`
+	Credential  string            `json:"credential,omitempty"`
+	ResultName  string            `json:"result_name,omitempty"`
+}
+
+// NewCallSOAP creates a new call SOAP action
+func NewCallSOAP(uuid flows.ActionUUID, url, soapAction, soapVersion, header, body string, headers map[string]string, credential, resultName string) *CallSOAPAction {
+	return &CallSOAPAction{
+		baseAction:  newBaseAction(TypeCallSOAP, uuid),
+		URL:         url,
+		SOAPAction:  soapAction,
+		SOAPVersion: soapVersion,
+		Header:      header,
+		Body:        body,
+		Headers:     headers,
+		Credential:  credential,
+		ResultName:  resultName,
+	}
+}
+
+// Validate validates our action is valid
+func (a *CallSOAPAction) Validate() error {
+	for key := range a.Headers {
+		if !httpguts.ValidHeaderFieldName(key) {
+			return errors.Errorf("header '%s' is not a valid HTTP header", key)
+		}
+		if a.Credential != "" && http.CanonicalHeaderKey(key) == "Authorization" {
+			return errors.New("can't specify both a credential and an Authorization header")
+		}
+	}
+
+	return nil
+}
+
+// Execute runs this action
+func (a *CallSOAPAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	url, err := run.EvaluateTemplate(a.URL)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+
+	url = strings.TrimSpace(url)
+
+	if url == "" {
+		logEvent(events.NewErrorf("SOAP URL evaluated to empty string"))
+		return nil
+	}
+	if !isValidURL(url) {
+		logEvent(events.NewErrorf("SOAP URL evaluated to an invalid URL: '%s'", url))
+		return nil
+	}
+
+	envelope := a.buildEnvelope(run, logEvent)
+
+	// an envelope which isn't well-formed is only going to get a fault back so don't bother calling
+	if err := xmlx.Validate([]byte(envelope)); err != nil {
+		logEvent(events.NewError(errors.Wrap(err, "SOAP envelope isn't valid XML")))
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(envelope))
+	if err != nil {
+		return err
+	}
+
+	if a.version() == SOAPVersion12 {
+		contentType := "application/soap+xml; charset=utf-8"
+		if a.SOAPAction != "" {
+			contentType += fmt.Sprintf(`; action="%s"`, a.SOAPAction)
+		}
+		req.Header.Set("Content-Type", contentType)
+	} else {
+		req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+
+		// set directly to keep the conventional casing of the header name which some older servers require
+		req.Header["SOAPAction"] = []string{fmt.Sprintf(`"%s"`, a.SOAPAction)}
+	}
+
+	a.callWebhook(ctx, run, step, req, a.Headers, a.Credential, a.ResultName, nil, logEvent)
+	return nil
+}
+
+// evaluates our header and body templates and wraps them in a SOAP envelope
+func (a *CallSOAPAction) buildEnvelope(run flows.Run, logEvent flows.EventCallback) string {
+	evaluate := func(template string) string {
+		// like webhook bodies, these aren't truncated like other templates
+		evaluated, err := run.EvaluateTemplateText(template, flows.XMLEscaping, false)
+		if err != nil {
+			logEvent(events.NewError(err))
+		}
+		return evaluated
+	}
+
+	b := &strings.Builder{}
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	b.WriteString(fmt.Sprintf(`<soap:Envelope xmlns:soap="%s">`, soapEnvelopeNamespaces[a.version()]))
+
+	if a.Header != "" {
+		b.WriteString(`<soap:Header>`)
+		b.WriteString(evaluate(a.Header))
+		b.WriteString(`</soap:Header>`)
+	}
+
+	b.WriteString(`<soap:Body>`)
+	b.WriteString(evaluate(a.Body))
+	b.WriteString(`</soap:Body></soap:Envelope>`)
+
+	return b.String()
+}
+
+func (a *CallSOAPAction) version() string {
+	if a.SOAPVersion == "" {
+		return SOAPVersion11
+	}
+	return a.SOAPVersion
+}
+
+// Results enumerates any results generated by this flow object
+func (a *CallSOAPAction) Results(include func(*flows.ResultInfo)) {
+	if a.ResultName != "" {
+		include(flows.NewResultInfo(a.ResultName, webhookCategories))
+	}
+}
@@ -1,13 +1,18 @@
 package actions
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils/jsonschema"
 
 	"github.com/pkg/errors"
 	"golang.org/x/net/http/httpguts"
@@ -28,13 +33,35 @@ func init() {
 // TypeCallWebhook is the type for the call webhook action
 const TypeCallWebhook string = "call_webhook"
 
+// BodyModeJSON is the body mode for JSON aware evaluation of webhook bodies
+const BodyModeJSON = "json"
+
 // CallWebhookAction can be used to call an external service. The body, header and url fields may be
 // templates and will be evaluated at runtime. A [event:webhook_called] event will be created based on
 // the results of the HTTP call. If this action has a `result_name`, then additionally it will create
 // a new result with that name. The value of the result will be the status code and the category will be
 // `Success` or `Failed`. If the webhook returned valid JSON which is less than 10000 bytes, that will be
 // accessible through `extra` on the result. The last JSON response from a webhook call in the current
-// sprint will additionally be accessible in expressions as `@webhook` regardless of size.
+// sprint will additionally be accessible in expressions as `@webhook` regardless of size. If the response body is
+// larger than the webhook service allows, it is cut off and a [event:webhook_response_truncated] event is created.
+//
+// If the action is `async`, the engine doesn't make the request itself but creates a [event:webhook_requested] event
+// with the evaluated request and parks the run until the caller resumes the session with a `webhook_callback` resume
+// containing the response. The result and `@webhook` are then set from that response as if the call had been made
+// synchronously, and execution continues with the rest of the node.
+//
+// If the `body_mode` is `json`, expressions in the body are encoded according to where they appear in the JSON, so
+// values inside strings are escaped and values outside of strings are written as JSON values. This means a body like
+// `{"name": "@contact.name", "fields": @contact.fields}` is always valid JSON, even if the contact's name contains quotes.
+//
+// Rather than putting secrets like API tokens in its headers, the action can specify the name of a `credential`
+// which the engine's credential service resolves to the value of the Authorization header. That value is redacted
+// from the [event:webhook_called] event.
+//
+// The action can declare a `response_schema`, a JSON Schema which successful responses are expected to match. A response
+// which doesn't match creates a [event:webhook_response_invalid] event describing how, and its result has the category
+// `Invalid Response` instead of `Success`, so that flows can route around an API which has changed rather than carry
+// on with missing values.
 //
 //	{
 //	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
@@ -51,39 +78,56 @@ const TypeCallWebhook string = "call_webhook"
 type CallWebhookAction struct {
 	baseAction
 	onlineAction
+	concurrentAction
 
 	Method     string            `json:"method" validate:"required,http_method"`
 	URL        string            `json:"url" validate:"required" engine:"evaluated"`
 	Headers    map[string]string `json:"headers,omitempty" engine:"evaluated"`
+	Credential string            `json:"credential,omitempty"`
 	Body       string            `json:"body,omitempty" engine:"evaluated"`
+	BodyMode   string            `json:"body_mode,omitempty" validate:"omitempty,eq=json"`
 	ResultName string            `json:"result_name,omitempty"`
+	Async      bool              `json:"async,omitempty"`
+
+	ResponseSchema *jsonschema.Schema `json:"response_schema,omitempty"`
 }
 
 // NewCallWebhook creates a new call webhook action
-func NewCallWebhook(uuid flows.ActionUUID, method string, url string, headers map[string]string, body string, resultName string) *CallWebhookAction {
+func NewCallWebhook(uuid flows.ActionUUID, method string, url string, headers map[string]string, credential string, body string, resultName string) *CallWebhookAction {
 	return &CallWebhookAction{
 		baseAction: newBaseAction(TypeCallWebhook, uuid),
 		Method:     method,
 		URL:        url,
 		Headers:    headers,
+		Credential: credential,
 		Body:       body,
 		ResultName: resultName,
 	}
 }
 
+// Concurrent returns whether this action can be executed concurrently with others, which it can't be if it's going
+// to park its run
+func (a *CallWebhookAction) Concurrent() bool { return !a.Async }
+
 // Validate validates our action is valid
 func (a *CallWebhookAction) Validate() error {
 	for key := range a.Headers {
 		if !httpguts.ValidHeaderFieldName(key) {
 			return errors.Errorf("header '%s' is not a valid HTTP header", key)
 		}
+		if a.Credential != "" && http.CanonicalHeaderKey(key) == "Authorization" {
+			return errors.New("can't specify both a credential and an Authorization header")
+		}
+	}
+	if a.ResponseSchema != nil {
+		return a.ResponseSchema.Check()
 	}
 
 	return nil
 }
 
 // Execute runs this action
-func (a *CallWebhookAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *CallWebhookAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 
 	// substitute any variables in our url
 	url, err := run.EvaluateTemplate(a.URL)
@@ -108,63 +152,105 @@ func (a *CallWebhookAction) Execute(run flows.Run, step flows.Step, logModifier
 	// substitute any body variables
 	if body != "" {
 		// webhook bodies aren't truncated like other templates
-		body, err = run.EvaluateTemplateText(body, nil, false)
+		if a.BodyMode == BodyModeJSON {
+			body, err = flows.EvaluateJSONTemplate(run, body)
+		} else {
+			body, err = run.EvaluateTemplateText(body, nil, false)
+		}
 		if err != nil {
 			logEvent(events.NewError(err))
 		}
 	}
 
-	return a.call(run, step, url, method, body, logEvent)
+	if a.Async {
+		a.request(run, url, method, body, logEvent)
+		return nil
+	}
+
+	return a.call(ctx, run, step, url, method, body, logEvent)
 }
 
-// Execute runs this action
-func (a *CallWebhookAction) call(run flows.Run, step flows.Step, url, method, body string, logEvent flows.EventCallback) error {
-	// build our request
-	req, err := http.NewRequest(method, url, strings.NewReader(body))
-	if err != nil {
-		return err
+// hands the request off to the caller, which will resume the session with the response
+func (a *CallWebhookAction) request(run flows.Run, url, method, body string, logEvent flows.EventCallback) {
+	var headers map[string]string
+	if len(a.Headers) > 0 {
+		headers = make(map[string]string, len(a.Headers))
+		for key, value := range a.Headers {
+			headerValue, err := run.EvaluateTemplate(value)
+			if err != nil {
+				logEvent(events.NewError(err))
+			}
+			headers[key] = headerValue
+		}
 	}
 
-	// add the custom headers, substituting any template vars
-	for key, value := range a.Headers {
-		headerValue, err := run.EvaluateTemplate(value)
-		if err != nil {
-			logEvent(events.NewError(err))
-		}
+	logEvent(events.NewWebhookRequested(flows.WebhookRequestUUID(run.Session().Sources().NewUUID()), method, url, headers, a.Credential, body))
+}
 
-		req.Header.Add(key, headerValue)
+// ResumeCallback handles the response to an asynchronous request made by this action
+func (a *CallWebhookAction) ResumeCallback(run flows.Run, step flows.Step, callback *flows.WebhookCallback, logEvent flows.EventCallback) {
+	var requested *events.WebhookRequestedEvent
+	for _, e := range run.Events() {
+		if r, isRequested := e.(*events.WebhookRequestedEvent); isRequested && r.RequestUUID == callback.RequestUUID {
+			requested = r
+		}
 	}
-
-	svc, err := run.Session().Engine().Services().Webhook(run.Session().Assets())
-	if err != nil {
-		logEvent(events.NewError(err))
-		return nil
+	if requested == nil {
+		logEvent(events.NewErrorf("no webhook request found with UUID %s", callback.RequestUUID))
+		return
 	}
 
-	call, err := svc.Call(req)
+	// like synchronous calls, only JSON responses can be accessed in expressions
+	responseJSON := []byte(nil)
+	if json.Valid([]byte(callback.Body)) {
+		responseJSON = []byte(callback.Body)
+	}
+	a.setWebhook(run, responseJSON)
 
-	if err != nil {
-		logEvent(events.NewError(err))
+	status := flows.CallStatusResponseError
+	if callback.StatusCode == 0 {
+		status = flows.CallStatusConnectionError
+	} else if callback.StatusCode/100 == 2 {
+		status = flows.CallStatusSuccess
 	}
-	if call != nil {
-		a.updateWebhook(run, call)
 
-		status := callStatus(call, err, false)
+	validResponse := status != flows.CallStatusSuccess || checkWebhookResponse(a.ResponseSchema, requested.URL, responseJSON, logEvent)
 
-		logEvent(events.NewWebhookCalled(call, status, ""))
+	if a.ResultName != "" {
+		category := webhookStatusCategories[status]
+		if !validResponse {
+			category = CategoryInvalidResponse
+		}
 
-		if a.ResultName != "" {
-			a.saveWebhookResult(run, step, a.ResultName, call, status, logEvent)
+		var extra json.RawMessage
+		if len(responseJSON) > 0 && len(responseJSON) < resultExtraMaxBytes {
+			extra = responseJSON
 		}
+
+		input := fmt.Sprintf("%s %s", requested.Method, requested.URL)
+		a.saveResult(run, step, a.ResultName, strconv.Itoa(callback.StatusCode), category, "", input, extra, logEvent)
+	}
+}
+
+// builds our request and makes the call
+func (a *CallWebhookAction) call(ctx context.Context, run flows.Run, step flows.Step, url, method, body string, logEvent flows.EventCallback) error {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		return err
 	}
 
+	a.callWebhook(ctx, run, step, req, a.Headers, a.Credential, a.ResultName, a.ResponseSchema, logEvent)
 	return nil
 }
 
 // Results enumerates any results generated by this flow object
 func (a *CallWebhookAction) Results(include func(*flows.ResultInfo)) {
 	if a.ResultName != "" {
-		include(flows.NewResultInfo(a.ResultName, webhookCategories))
+		categories := webhookCategories
+		if a.ResponseSchema != nil {
+			categories = []string{CategorySuccess, CategoryInvalidResponse, CategoryFailure}
+		}
+		include(flows.NewResultInfo(a.ResultName, categories))
 	}
 }
 
@@ -0,0 +1,49 @@
+package actions
+
+import (
+	"context"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+func init() {
+	registerType(TypeCancelTimer, func() flows.Action { return &CancelTimerAction{} })
+}
+
+// TypeCancelTimer is the type for the cancel timer action
+const TypeCancelTimer string = "cancel_timer"
+
+// CancelTimerAction can be used to cancel a timer previously set in this run with [action:set_timer], e.g. once the
+// contact has replied and no longer needs nudging. If the run has a timer with that name, a [event:timer_cancelled]
+// event will be created.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "cancel_timer",
+//	  "name": "followup"
+//	}
+//
+// @action cancel_timer
+type CancelTimerAction struct {
+	baseAction
+	interactiveAction
+
+	Name string `json:"name" validate:"required,max=64"`
+}
+
+// NewCancelTimer creates a new cancel timer action
+func NewCancelTimer(uuid flows.ActionUUID, name string) *CancelTimerAction {
+	return &CancelTimerAction{
+		baseAction: newBaseAction(TypeCancelTimer, uuid),
+		Name:       name,
+	}
+}
+
+// Execute runs this action
+func (a *CancelTimerAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	if run.CancelTimer(a.Name) != nil {
+		logEvent(events.NewTimerCancelled(a.Name))
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package actions
+
+import (
+	"context"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+func init() {
+	registerType(TypeCheckout, func() flows.Action { return &CheckoutAction{} })
+}
+
+var checkoutCategories = []string{CategorySuccess, CategoryFailure}
+
+// TypeCheckout is the type for the checkout action
+const TypeCheckout string = "checkout"
+
+// CheckoutAction places an order for the items in the session's cart using the commerce service. If the order is
+// placed, the cart is emptied and a result is saved whose value is the order reference. The placed order is saved in the
+// extra of the result, so it can be accessed like `@results.order.extra.total.amount`.
+//
+// If the cart is empty or the order can't be placed, the cart is left unchanged and the result category is _Failure_.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "checkout",
+//	  "result_name": "Order"
+//	}
+//
+// @action checkout
+type CheckoutAction struct {
+	baseAction
+	onlineAction
+
+	ResultName string `json:"result_name" validate:"required"`
+}
+
+// NewCheckout creates a new checkout action
+func NewCheckout(uuid flows.ActionUUID, resultName string) *CheckoutAction {
+	return &CheckoutAction{
+		baseAction: newBaseAction(TypeCheckout, uuid),
+		ResultName: resultName,
+	}
+}
+
+// Execute runs this action
+func (a *CheckoutAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	cart := run.Session().Cart()
+	if cart == nil || len(cart.Items) == 0 {
+		logEvent(events.NewErrorf("can't checkout an empty cart"))
+		a.saveResult(run, step, a.ResultName, "", CategoryFailure, "", "", nil, logEvent)
+		return nil
+	}
+
+	total := types.NewXMoney(cart.Total(), cart.Currency).Render()
+
+	reference := a.placeOrder(ctx, run, cart, logEvent)
+	if reference == "" {
+		a.saveResult(run, step, a.ResultName, "", CategoryFailure, "", total, nil, logEvent)
+		return nil
+	}
+
+	extra, _ := jsonx.Marshal(flows.Context(run.Environment(), cart))
+
+	run.Session().SetCart(nil)
+
+	a.saveResult(run, step, a.ResultName, reference, CategorySuccess, "", total, extra, logEvent)
+	return nil
+}
+
+func (a *CheckoutAction) placeOrder(ctx context.Context, run flows.Run, cart *flows.Order, logEvent flows.EventCallback) string {
+	svc, err := run.Session().Engine().Services().Commerce(run.Session().Assets())
+	if err != nil {
+		logEvent(events.NewError(err))
+		return ""
+	}
+
+	httpLogger := newHTTPLogger(ctx, run)
+
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeCommerce)
+	defer cancel()
+
+	reference, err := svc.PlaceOrder(ctx, run.Environment(), run.Contact(), cart, httpLogger.Log)
+
+	if len(httpLogger.Logs) > 0 {
+		logEvent(events.NewCommerceCalled(httpLogger.Logs))
+	}
+
+	if err != nil {
+		logEvent(events.NewError(err))
+		return ""
+	}
+
+	return reference
+}
+
+// Results enumerates any results generated by this flow object
+func (a *CheckoutAction) Results(include func(*flows.ResultInfo)) {
+	include(flows.NewResultInfo(a.ResultName, checkoutCategories))
+}
@@ -1,6 +1,8 @@
 package actions
 
 import (
+	"context"
+
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
@@ -45,7 +47,7 @@ func NewEnterFlow(uuid flows.ActionUUID, flow *assets.FlowReference, terminal bo
 }
 
 // Execute runs our action
-func (a *EnterFlowAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *EnterFlowAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	flow, err := run.Session().Assets().Flows().Get(a.Flow.UUID)
 
 	// we ignore other missing asset types but a missing flow means we don't know how to route so we can't continue
@@ -0,0 +1,75 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+func init() {
+	registerType(TypeExitWith, func() flows.Action { return &ExitWithAction{} })
+}
+
+// TypeExitWith is the type for the exit with action
+const TypeExitWith string = "exit_with"
+
+// ExitWithAction can be used to complete the current run and return values to the run which entered it. Each of the
+// returns is a template which is evaluated to a value, which can be text or a structured value like an array or
+// object. When the parent run resumes, the values are available in the context as @child.returns.[name], so they don't
+// collide with the results of either flow. Any remaining actions on the node are not executed.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "exit_with",
+//	  "returns": {
+//	    "color": "@results.favorite_color.value",
+//	    "contact": "@(object(\"name\", contact.name, \"phone\", results.phone_number.value))"
+//	  }
+//	}
+//
+// @action exit_with
+type ExitWithAction struct {
+	baseAction
+	universalAction
+
+	Returns map[string]string `json:"returns" validate:"required" engine:"evaluated"`
+}
+
+// NewExitWith creates a new exit with action
+func NewExitWith(uuid flows.ActionUUID, returns map[string]string) *ExitWithAction {
+	return &ExitWithAction{
+		baseAction: newBaseAction(TypeExitWith, uuid),
+		Returns:    returns,
+	}
+}
+
+// Execute runs this action
+func (a *ExitWithAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	returns := make(map[string]json.RawMessage, len(a.Returns))
+
+	for name, template := range a.Returns {
+		returns[name] = json.RawMessage(`null`)
+
+		value, err := run.EvaluateTemplateValue(template)
+		if err != nil {
+			logEvent(events.NewError(err))
+			continue
+		}
+
+		// the value might be an error from evaluating a single expression
+		asJSON, xerr := types.ToXJSON(value)
+		if xerr != nil {
+			logEvent(events.NewError(xerr))
+			continue
+		}
+
+		returns[name] = json.RawMessage(asJSON.Native())
+	}
+
+	run.SetReturns(returns)
+	run.Exit(flows.RunStatusCompleted)
+	return nil
+}
@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+func init() {
+	registerType(TypeForwardCall, func() flows.Action { return &ForwardCallAction{} })
+}
+
+// TypeForwardCall is the type for the forward call action
+const TypeForwardCall string = "forward_call"
+
+// ForwardCallAction can be used to forward the call in a voice flow to another phone number, such as a human agent.
+// Once the forwarded call ends, a result is saved whose value is the number of seconds the contact spent in it, and
+// whose category is _Completed_, _Busy_ or _Failed_. An [event:call_transferred] event will be created if the call
+// could be forwarded.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "forward_call",
+//	  "phone": "+12065551313",
+//	  "result_name": "Agent Call"
+//	}
+//
+// @action forward_call
+type ForwardCallAction struct {
+	baseAction
+	voiceAction
+
+	Phone      string `json:"phone" validate:"required" engine:"evaluated"`
+	ResultName string `json:"result_name" validate:"required"`
+}
+
+// NewForwardCall creates a new forward call action
+func NewForwardCall(uuid flows.ActionUUID, phone, resultName string) *ForwardCallAction {
+	return &ForwardCallAction{
+		baseAction: newBaseAction(TypeForwardCall, uuid),
+		Phone:      phone,
+		ResultName: resultName,
+	}
+}
+
+// Execute runs this action
+func (a *ForwardCallAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	phone, err := run.EvaluateTemplate(a.Phone)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+
+	phone = strings.TrimSpace(phone)
+
+	urn, err := urns.NewTelURNForCountry(phone, string(run.Environment().DefaultCountry()))
+	if err != nil {
+		logEvent(events.NewError(err))
+		a.saveCallTransferResult(run, step, a.ResultName, nil, phone, logEvent)
+		return nil
+	}
+
+	transfer := a.forward(ctx, run, urn, logEvent)
+
+	a.saveCallTransferResult(run, step, a.ResultName, transfer, string(urn), logEvent)
+	return nil
+}
+
+func (a *ForwardCallAction) forward(ctx context.Context, run flows.Run, urn urns.URN, logEvent flows.EventCallback) *flows.CallTransfer {
+	svc, err := run.Session().Engine().Services().CallTransfer(run.Session().Assets())
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	// an IVR flow must have been started with a call
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeCallTransfer)
+	defer cancel()
+
+	transfer, err := svc.Forward(ctx, run.Session().Trigger().Call(), urn)
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	logEvent(events.NewCallForwarded(urn, transfer))
+	return transfer
+}
+
+// Results enumerates any results generated by this flow object
+func (a *ForwardCallAction) Results(include func(*flows.ResultInfo)) {
+	include(flows.NewResultInfo(a.ResultName, callTransferCategories))
+}
@@ -0,0 +1,92 @@
+package actions
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+func init() {
+	registerType(TypeJoinConference, func() flows.Action { return &JoinConferenceAction{} })
+}
+
+// TypeJoinConference is the type for the join conference action
+const TypeJoinConference string = "join_conference"
+
+// JoinConferenceAction can be used to join the call in a voice flow to a named conference, for example so that an agent
+// can take over from the flow. Once the contact leaves the conference, a result is saved whose value is the number of
+// seconds they spent in it, and whose category is _Completed_, _Busy_ or _Failed_. An [event:call_transferred] event
+// will be created if the call could be joined to the conference.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "join_conference",
+//	  "conference": "support-@contact.language",
+//	  "result_name": "Support Call"
+//	}
+//
+// @action join_conference
+type JoinConferenceAction struct {
+	baseAction
+	voiceAction
+
+	Conference string `json:"conference" validate:"required" engine:"evaluated"`
+	ResultName string `json:"result_name" validate:"required"`
+}
+
+// NewJoinConference creates a new join conference action
+func NewJoinConference(uuid flows.ActionUUID, conference, resultName string) *JoinConferenceAction {
+	return &JoinConferenceAction{
+		baseAction: newBaseAction(TypeJoinConference, uuid),
+		Conference: conference,
+		ResultName: resultName,
+	}
+}
+
+// Execute runs this action
+func (a *JoinConferenceAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	conference, err := run.EvaluateTemplate(a.Conference)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+
+	conference = strings.TrimSpace(conference)
+	if conference == "" {
+		logEvent(events.NewErrorf("conference name evaluated to empty, skipping"))
+		a.saveCallTransferResult(run, step, a.ResultName, nil, "", logEvent)
+		return nil
+	}
+
+	transfer := a.join(ctx, run, conference, logEvent)
+
+	a.saveCallTransferResult(run, step, a.ResultName, transfer, conference, logEvent)
+	return nil
+}
+
+func (a *JoinConferenceAction) join(ctx context.Context, run flows.Run, conference string, logEvent flows.EventCallback) *flows.CallTransfer {
+	svc, err := run.Session().Engine().Services().CallTransfer(run.Session().Assets())
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	// an IVR flow must have been started with a call
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeCallTransfer)
+	defer cancel()
+
+	transfer, err := svc.JoinConference(ctx, run.Session().Trigger().Call(), conference)
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	logEvent(events.NewConferenceJoined(conference, transfer))
+	return transfer
+}
+
+// Results enumerates any results generated by this flow object
+func (a *JoinConferenceAction) Results(include func(*flows.ResultInfo)) {
+	include(flows.NewResultInfo(a.ResultName, callTransferCategories))
+}
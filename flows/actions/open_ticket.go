@@ -1,6 +1,8 @@
 package actions
 
 import (
+	"context"
+
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
@@ -14,7 +16,11 @@ func init() {
 // TypeOpenTicket is the type for the open ticket action
 const TypeOpenTicket string = "open_ticket"
 
-// OpenTicketAction is used to open a ticket for the contact.
+var ticketCategories = []string{CategorySuccess, CategoryFailure}
+
+// OpenTicketAction is used to open a ticket for the contact. The topic and assignee can be given as templates which
+// are evaluated to the name of a topic and the email of a user. If a result name is given, the UUID of the opened ticket
+// is saved as a result, with a category of Success or Failure.
 //
 //	{
 //	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
@@ -41,7 +47,7 @@ type OpenTicketAction struct {
 	Topic      *assets.TopicReference    `json:"topic" validate:"omitempty,dive"`
 	Body       string                    `json:"body" engine:"evaluated"`
 	Assignee   *assets.UserReference     `json:"assignee" validate:"omitempty,dive"`
-	ResultName string                    `json:"result_name" validate:"required"`
+	ResultName string                    `json:"result_name,omitempty"`
 }
 
 // NewOpenTicket creates a new open ticket action
@@ -57,14 +63,14 @@ func NewOpenTicket(uuid flows.ActionUUID, ticketer *assets.TicketerReference, to
 }
 
 // Execute runs this action
-func (a *OpenTicketAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *OpenTicketAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	sa := run.Session().Assets()
 
 	ticketer := sa.Ticketers().Get(a.Ticketer.UUID)
 
 	var topic *flows.Topic
 	if a.Topic != nil {
-		topic = sa.Topics().Get(a.Topic.UUID)
+		topic = resolveTopic(run, a.Topic, logEvent)
 	} else {
 		topic = sa.Topics().FindByName("General") // TODO remove when editor adds topics
 	}
@@ -78,17 +84,20 @@ func (a *OpenTicketAction) Execute(run flows.Run, step flows.Step, logModifier f
 		logEvent(events.NewError(err))
 	}
 
-	ticket := a.open(run, step, ticketer, topic, evaluatedBody, assignee, logModifier, logEvent)
-	if ticket != nil {
-		a.saveResult(run, step, a.ResultName, string(ticket.UUID()), CategorySuccess, "", "", nil, logEvent)
-	} else {
-		a.saveResult(run, step, a.ResultName, "", CategoryFailure, "", "", nil, logEvent)
+	ticket := a.open(ctx, run, step, ticketer, topic, evaluatedBody, assignee, logModifier, logEvent)
+
+	if a.ResultName != "" {
+		if ticket != nil {
+			a.saveResult(run, step, a.ResultName, string(ticket.UUID()), CategorySuccess, "", "", nil, logEvent)
+		} else {
+			a.saveResult(run, step, a.ResultName, "", CategoryFailure, "", "", nil, logEvent)
+		}
 	}
 
 	return nil
 }
 
-func (a *OpenTicketAction) open(run flows.Run, step flows.Step, ticketer *flows.Ticketer, topic *flows.Topic, body string, assignee *flows.User, logModifier flows.ModifierCallback, logEvent flows.EventCallback) *flows.Ticket {
+func (a *OpenTicketAction) open(ctx context.Context, run flows.Run, step flows.Step, ticketer *flows.Ticketer, topic *flows.Topic, body string, assignee *flows.User, logModifier flows.ModifierCallback, logEvent flows.EventCallback) *flows.Ticket {
 	if run.Session().BatchStart() {
 		logEvent(events.NewErrorf("can't open tickets during batch starts"))
 		return nil
@@ -99,16 +108,25 @@ func (a *OpenTicketAction) open(run flows.Run, step flows.Step, ticketer *flows.
 		return nil
 	}
 	if a.Topic != nil && topic == nil {
-		logEvent(events.NewDependencyError(a.Topic))
-		return nil
+		return nil // error already logged when resolving the topic
 	}
 
 	mod := modifiers.NewTicket(ticketer, topic, body, assignee)
 
-	if a.applyModifier(run, mod, logModifier, logEvent) {
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeTicket)
+	defer cancel()
+
+	if a.applyModifier(ctx, run, mod, logModifier, logEvent) {
 		// if we were able to open a ticket, it's the last in the list
 		tickets := run.Session().Contact().Tickets().All()
 		return tickets[len(tickets)-1]
 	}
 	return nil
 }
+
+// Results enumerates any results generated by this flow object
+func (a *OpenTicketAction) Results(include func(*flows.ResultInfo)) {
+	if a.ResultName != "" {
+		include(flows.NewResultInfo(a.ResultName, ticketCategories))
+	}
+}
@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"context"
 	"strings"
 
 	"github.com/nyaruka/gocommon/uuids"
@@ -42,7 +43,7 @@ func NewPlayAudio(uuid flows.ActionUUID, audioURL string) *PlayAudioAction {
 }
 
 // Execute runs this action
-func (a *PlayAudioAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *PlayAudioAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	// localize and evaluate audio URL
 	localizedAudioURL, urlLang := run.GetText(uuids.UUID(a.UUID()), "audio_url", a.AudioURL)
 	evaluatedAudioURL, err := run.EvaluateTemplate(localizedAudioURL)
@@ -61,7 +62,7 @@ func (a *PlayAudioAction) Execute(run flows.Run, step flows.Step, logModifier fl
 	call := run.Session().Trigger().Call()
 
 	// if we have an audio URL, turn it into a message
-	msg := flows.NewIVRMsgOut(call.URN(), call.Channel(), "", evaluatedAudioURL, currentLocale(run, urlLang))
+	msg := flows.NewIVRMsgOut(flows.MsgUUID(run.Session().Sources().NewUUID()), call.URN(), call.Channel(), "", evaluatedAudioURL, currentLocale(run, urlLang))
 	logEvent(events.NewIVRCreated(msg))
 
 	return nil
@@ -0,0 +1,148 @@
+package actions
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+func init() {
+	registerType(TypeQueryCollection, func() flows.Action { return &QueryCollectionAction{} })
+}
+
+var queryCategories = []string{CategorySuccess, CategoryFailure}
+
+// the number of rows returned if the action doesn't specify a limit
+const defaultQueryLimit = 10
+
+// TypeQueryCollection is the type for the query collection action
+const TypeQueryCollection string = "query_collection"
+
+// QueryCollectionAction queries a named external data collection, e.g. a product catalog, and saves a result whose
+// value is the total number of matching rows. The rows in the requested page are saved as an array in the extra of the
+// result, so they can be accessed like `@results.products.extra[0].name`.
+//
+// The filter is evaluated as a template and then interpreted by the data collection service. The sort can be prefixed
+// with `-` for descending order, and the offset is evaluated as a template so flows can page through rows.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "query_collection",
+//	  "collection": "products",
+//	  "filter": "category = cereal",
+//	  "sort": "-price",
+//	  "limit": 2,
+//	  "offset": "0",
+//	  "result_name": "Products"
+//	}
+//
+// @action query_collection
+type QueryCollectionAction struct {
+	baseAction
+	onlineAction
+	concurrentAction
+
+	Collection string `json:"collection" validate:"required"`
+	Filter     string `json:"filter,omitempty" engine:"evaluated"`
+	Sort       string `json:"sort,omitempty"`
+	Limit      int    `json:"limit,omitempty" validate:"omitempty,min=1,max=100"`
+	Offset     string `json:"offset,omitempty" engine:"evaluated"`
+	ResultName string `json:"result_name" validate:"required"`
+}
+
+// NewQueryCollection creates a new query collection action
+func NewQueryCollection(uuid flows.ActionUUID, collection, filter, sort string, limit int, offset, resultName string) *QueryCollectionAction {
+	return &QueryCollectionAction{
+		baseAction: newBaseAction(TypeQueryCollection, uuid),
+		Collection: collection,
+		Filter:     filter,
+		Sort:       sort,
+		Limit:      limit,
+		Offset:     offset,
+		ResultName: resultName,
+	}
+}
+
+// Execute runs this action
+func (a *QueryCollectionAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	query := &flows.DataQuery{Sort: a.Sort, Limit: a.Limit}
+	if query.Limit == 0 {
+		query.Limit = defaultQueryLimit
+	}
+
+	filter, err := run.EvaluateTemplate(a.Filter)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+	query.Filter = strings.TrimSpace(filter)
+
+	offset, err := run.EvaluateTemplate(a.Offset)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+	if offset = strings.TrimSpace(offset); offset != "" {
+		query.Offset, err = strconv.Atoi(offset)
+		if err != nil || query.Offset < 0 {
+			logEvent(events.NewErrorf("offset evaluated to invalid value '%s', ignoring", offset))
+			query.Offset = 0
+		}
+	}
+
+	page := a.query(ctx, run, query, logEvent)
+	if page != nil {
+		a.saveSuccess(run, step, query.Filter, page, logEvent)
+	} else {
+		a.saveFailure(run, step, query.Filter, logEvent)
+	}
+
+	return nil
+}
+
+func (a *QueryCollectionAction) query(ctx context.Context, run flows.Run, query *flows.DataQuery, logEvent flows.EventCallback) *flows.DataPage {
+	svc, err := run.Session().Engine().Services().DataCollection(run.Session().Assets())
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	httpLogger := newHTTPLogger(ctx, run)
+
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeDataCollection)
+	defer cancel()
+
+	page, err := svc.Query(ctx, run.Environment(), a.Collection, query, httpLogger.Log)
+
+	if len(httpLogger.Logs) > 0 {
+		logEvent(events.NewDataCollectionCalled(a.Collection, httpLogger.Logs))
+	}
+
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	return page
+}
+
+func (a *QueryCollectionAction) saveSuccess(run flows.Run, step flows.Step, input string, page *flows.DataPage, logEvent flows.EventCallback) {
+	rows := page.Rows
+	if rows == nil {
+		rows = []map[string]any{}
+	}
+	extra, _ := jsonx.Marshal(rows)
+
+	a.saveResult(run, step, a.ResultName, strconv.Itoa(page.Total), CategorySuccess, "", input, extra, logEvent)
+}
+
+func (a *QueryCollectionAction) saveFailure(run flows.Run, step flows.Step, input string, logEvent flows.EventCallback) {
+	a.saveResult(run, step, a.ResultName, "0", CategoryFailure, "", input, nil, logEvent)
+}
+
+// Results enumerates any results generated by this flow object
+func (a *QueryCollectionAction) Results(include func(*flows.ResultInfo)) {
+	include(flows.NewResultInfo(a.ResultName, queryCategories))
+}
@@ -1,6 +1,8 @@
 package actions
 
 import (
+	"context"
+
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
@@ -56,7 +58,7 @@ func (a *RemoveContactGroupsAction) Validate() error {
 }
 
 // Execute runs the action
-func (a *RemoveContactGroupsAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *RemoveContactGroupsAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	contact := run.Contact()
 	if contact == nil {
 		logEvent(events.NewErrorf("can't execute action in session without a contact"))
@@ -75,6 +77,6 @@ func (a *RemoveContactGroupsAction) Execute(run flows.Run, step flows.Step, logM
 		groups = resolveGroups(run, a.Groups, logEvent)
 	}
 
-	a.applyModifier(run, modifiers.NewGroups(groups, modifiers.GroupsRemove), logModifier, logEvent)
+	a.applyModifier(ctx, run, modifiers.NewGroups(groups, modifiers.GroupsRemove), logModifier, logEvent)
 	return nil
 }
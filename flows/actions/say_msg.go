@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"context"
 	"strings"
 
 	"github.com/nyaruka/gocommon/uuids"
@@ -47,7 +48,7 @@ func NewSayMsg(uuid flows.ActionUUID, text string, audioURL string) *SayMsgActio
 }
 
 // Execute runs this action
-func (a *SayMsgAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *SayMsgAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	// localize and evaluate the message text
 	localizedText, textLang := run.GetText(uuids.UUID(a.UUID()), "text", a.Text)
 	evaluatedText, err := run.EvaluateTemplate(localizedText)
@@ -68,7 +69,7 @@ func (a *SayMsgAction) Execute(run flows.Run, step flows.Step, logModifier flows
 	// an IVR flow must have been started with a call
 	call := run.Session().Trigger().Call()
 
-	msg := flows.NewIVRMsgOut(call.URN(), call.Channel(), evaluatedText, localizedAudioURL, currentLocale(run, textLang))
+	msg := flows.NewIVRMsgOut(flows.MsgUUID(run.Session().Sources().NewUUID()), call.URN(), call.Channel(), evaluatedText, localizedAudioURL, currentLocale(run, textLang))
 	logEvent(events.NewIVRCreated(msg))
 
 	return nil
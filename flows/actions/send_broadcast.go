@@ -1,6 +1,8 @@
 package actions
 
 import (
+	"context"
+
 	"github.com/nyaruka/gocommon/urns"
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/envs"
@@ -55,7 +57,7 @@ func NewSendBroadcast(uuid flows.ActionUUID, text string, attachments []string,
 }
 
 // Execute runs this action
-func (a *SendBroadcastAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *SendBroadcastAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	groupRefs, contactRefs, contactQuery, urnList, err := a.resolveRecipients(run, logEvent)
 	if err != nil {
 		return err
@@ -74,7 +76,7 @@ func (a *SendBroadcastAction) Execute(run flows.Run, step flows.Step, logModifie
 	for _, language := range languages {
 		languages := []envs.Language{language, run.Flow().Language()}
 
-		evaluatedText, evaluatedAttachments, evaluatedQuickReplies, _ := a.evaluateMessage(run, languages, a.Text, a.Attachments, a.QuickReplies, logEvent)
+		evaluatedText, evaluatedAttachments, evaluatedQuickReplies, _ := a.evaluateMessage(ctx, run, languages, a.Text, a.Attachments, a.QuickReplies, logEvent)
 		translations[language] = &flows.BroadcastTranslation{
 			Text:         evaluatedText,
 			Attachments:  evaluatedAttachments,
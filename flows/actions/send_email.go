@@ -1,12 +1,16 @@
 package actions
 
 import (
+	"context"
 	"regexp"
 	"strings"
 
 	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils"
+
 	"github.com/pkg/errors"
 )
 
@@ -18,7 +22,9 @@ func init() {
 const TypeSendEmail string = "send_email"
 
 // SendEmailAction can be used to send an email to one or more recipients. The subject, body and addresses
-// can all contain expressions.
+// can all contain expressions. An HTML version of the body can also be given, as can attachments in the form
+// <content-type>:<url> which are fetched and attached to the email. Replies can be directed to the address which is
+// the value of the global referenced by `reply_to`.
 //
 // An [event:email_sent] event will be created if the email could be sent.
 //
@@ -27,7 +33,9 @@ const TypeSendEmail string = "send_email"
 //	  "type": "send_email",
 //	  "addresses": ["@urns.mailto"],
 //	  "subject": "Here is your activation token",
-//	  "body": "Your activation token is @contact.fields.activation_token"
+//	  "body": "Your activation token is @contact.fields.activation_token",
+//	  "html_body": "<p>Your activation token is <b>@contact.fields.activation_token</b></p>",
+//	  "attachments": ["application/pdf:https://example.com/terms.pdf"]
 //	}
 //
 // @action send_email
@@ -35,9 +43,12 @@ type SendEmailAction struct {
 	baseAction
 	onlineAction
 
-	Addresses []string `json:"addresses" validate:"required,min=1" engine:"evaluated"`
-	Subject   string   `json:"subject" validate:"required" engine:"localized,evaluated"`
-	Body      string   `json:"body" validate:"required" engine:"localized,evaluated"`
+	Addresses   []string                `json:"addresses" validate:"required,min=1" engine:"evaluated"`
+	Subject     string                  `json:"subject" validate:"required" engine:"localized,evaluated"`
+	Body        string                  `json:"body" validate:"required" engine:"localized,evaluated"`
+	HTMLBody    string                  `json:"html_body,omitempty" engine:"localized,evaluated"`
+	Attachments []string                `json:"attachments,omitempty" engine:"localized,evaluated"`
+	ReplyTo     *assets.GlobalReference `json:"reply_to,omitempty" validate:"omitempty"`
 }
 
 // NewSendEmail creates a new send email action
@@ -51,7 +62,7 @@ func NewSendEmail(uuid flows.ActionUUID, addresses []string, subject string, bod
 }
 
 // Execute creates the email events
-func (a *SendEmailAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *SendEmailAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	localizedSubject, _ := run.GetText(uuids.UUID(a.UUID()), "subject", a.Subject)
 	evaluatedSubject, err := run.EvaluateTemplate(localizedSubject)
 	if err != nil {
@@ -100,18 +111,96 @@ func (a *SendEmailAction) Execute(run flows.Run, step flows.Step, logModifier fl
 		return nil
 	}
 
+	email := &flows.Email{
+		Addresses:   evaluatedAddresses,
+		ReplyTo:     a.resolveReplyTo(run, logEvent),
+		Subject:     evaluatedSubject,
+		Body:        evaluatedBody,
+		HTMLBody:    a.evaluateHTMLBody(run, logEvent),
+		Attachments: a.evaluateAttachments(run, logEvent),
+	}
+
 	svc, err := run.Session().Engine().Services().Email(run.Session().Assets())
 	if err != nil {
 		logEvent(events.NewError(err))
 		return nil
 	}
 
-	err = svc.Send(evaluatedAddresses, evaluatedSubject, evaluatedBody)
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeEmail)
+	defer cancel()
+
+	if richSvc, isRich := svc.(flows.RichEmailService); isRich {
+		err = richSvc.SendEmail(ctx, email)
+	} else {
+		if email.ReplyTo != "" || email.HTMLBody != "" || len(email.Attachments) > 0 {
+			logEvent(events.NewErrorf("email service doesn't support HTML bodies, attachments or reply-to addresses, sending plain text only"))
+			email = &flows.Email{Addresses: email.Addresses, Subject: email.Subject, Body: email.Body}
+		}
+		err = svc.Send(ctx, email.Addresses, email.Subject, email.Body)
+	}
+
 	if err != nil {
 		logEvent(events.NewError(errors.Wrap(err, "unable to send email")))
 	} else {
-		logEvent(events.NewEmailSent(evaluatedAddresses, evaluatedSubject, evaluatedBody))
+		logEvent(events.NewEmailSent(email))
 	}
 
 	return nil
 }
+
+// looks up the reply-to address from the referenced global
+func (a *SendEmailAction) resolveReplyTo(run flows.Run, logEvent flows.EventCallback) string {
+	if a.ReplyTo == nil {
+		return ""
+	}
+
+	global := run.Session().Assets().Globals().Get(a.ReplyTo.Key)
+	if global == nil {
+		logEvent(events.NewDependencyError(a.ReplyTo))
+		return ""
+	}
+
+	replyTo := strings.TrimPrefix(strings.TrimSpace(global.Value()), "mailto:")
+	if replyTo == "" {
+		logEvent(events.NewErrorf("reply-to global %s has no value, ignoring", a.ReplyTo.Key))
+	}
+	return replyTo
+}
+
+func (a *SendEmailAction) evaluateHTMLBody(run flows.Run, logEvent flows.EventCallback) string {
+	if a.HTMLBody == "" {
+		return ""
+	}
+
+	localizedHTMLBody, _ := run.GetText(uuids.UUID(a.UUID()), "html_body", a.HTMLBody)
+	evaluatedHTMLBody, err := run.EvaluateTemplate(localizedHTMLBody)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+	return evaluatedHTMLBody
+}
+
+func (a *SendEmailAction) evaluateAttachments(run flows.Run, logEvent flows.EventCallback) []utils.Attachment {
+	localizedAttachments, _ := run.GetTextArray(uuids.UUID(a.UUID()), "attachments", a.Attachments, nil)
+	evaluatedAttachments := make([]utils.Attachment, 0, len(localizedAttachments))
+
+	for _, attachment := range localizedAttachments {
+		evaluatedAttachment, err := run.EvaluateTemplate(attachment)
+		if err != nil {
+			logEvent(events.NewError(err))
+		}
+		if evaluatedAttachment == "" {
+			logEvent(events.NewErrorf("attachment evaluated to empty string, skipping"))
+			continue
+		}
+
+		contentType, url := utils.Attachment(evaluatedAttachment).ToParts()
+		if contentType == "" || url == "" {
+			logEvent(events.NewErrorf("attachment '%s' isn't in the form <content-type>:<url>, skipping", evaluatedAttachment))
+			continue
+		}
+		evaluatedAttachments = append(evaluatedAttachments, utils.Attachment(evaluatedAttachment))
+	}
+
+	return evaluatedAttachments
+}
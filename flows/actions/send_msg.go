@@ -1,12 +1,18 @@
 package actions
 
 import (
+	"context"
+	"unicode/utf8"
+
 	"github.com/nyaruka/gocommon/urns"
 	"github.com/nyaruka/gocommon/uuids"
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
 )
 
 func init() {
@@ -20,6 +26,21 @@ const TypeSendMsg string = "send_msg"
 // will attempt to find pairs of URNs and channels which can be used for sending. If it can't find such a pair, it will
 // create a message without a channel or URN.
 //
+// An inline keyboard can be included for channels such as Telegram which support it. Unlike a quick reply, pressing an
+// inline keyboard button doesn't send its text back as a message, but instead sends its data as a callback query which
+// is available to routers as `@input.callback_data`.
+//
+// Rich cards and suggested replies or actions can be included for RCS channels. A single card is sent as a rich card and
+// several cards as a carousel. If the channel doesn't have the features required, the unsupported content is instead
+// rendered as plain text and appended to the message text.
+//
+// Messages to WeChat, Viber and LINE contacts are restricted to what those schemes allow. WeChat doesn't support quick
+// replies so they are rendered as text unless the channel has the `quick_replies` feature, and quick replies or
+// attachments beyond the scheme's limits are dropped with a warning.
+//
+// A priority of `transactional` or `bulk` can be set so that messages such as OTPs can be queued ahead of bulk messages
+// by the caller.
+//
 // A [event:msg_created] event will be created with the evaluated text.
 //
 //	{
@@ -36,7 +57,12 @@ const TypeSendMsg string = "send_msg"
 //	    },
 //	    "variables": ["@contact.name"]
 //	  },
-//	  "topic": "event"
+//	  "inline_keyboard": [
+//	    {"uuid": "ab79e1b8-2a17-4d5c-9a31-7c2f3b9b1e25", "text": "Yes", "data": "survey_yes"},
+//	    {"uuid": "3f2e4b9a-0c5d-4e8f-b1a7-6d9c2e5f8a13", "text": "Not now", "data": "survey_later"}
+//	  ],
+//	  "topic": "event",
+//	  "priority": "transactional"
 //	}
 //
 // @action send_msg
@@ -45,9 +71,13 @@ type SendMsgAction struct {
 	universalAction
 	createMsgAction
 
-	AllURNs    bool           `json:"all_urns,omitempty"`
-	Templating *Templating    `json:"templating,omitempty" validate:"omitempty,dive"`
-	Topic      flows.MsgTopic `json:"topic,omitempty" validate:"omitempty,msg_topic"`
+	AllURNs        bool              `json:"all_urns,omitempty"`
+	Templating     *Templating       `json:"templating,omitempty" validate:"omitempty,dive"`
+	InlineKeyboard []*InlineButton   `json:"inline_keyboard,omitempty" validate:"omitempty,dive"`
+	RichCards      []*RichCard       `json:"rich_cards,omitempty" validate:"omitempty,max=10,dive"`
+	Suggestions    []*Suggestion     `json:"suggestions,omitempty" validate:"omitempty,max=11,dive"`
+	Topic          flows.MsgTopic    `json:"topic,omitempty" validate:"omitempty,msg_topic"`
+	Priority       flows.MsgPriority `json:"priority,omitempty" validate:"omitempty,msg_priority"`
 }
 
 // Templating represents the templating that should be used if possible
@@ -60,6 +90,41 @@ type Templating struct {
 // LocalizationUUID gets the UUID which identifies this object for localization
 func (t *Templating) LocalizationUUID() uuids.UUID { return t.UUID }
 
+// InlineButton is a button of an inline keyboard
+type InlineButton struct {
+	UUID uuids.UUID `json:"uuid" validate:"required,uuid4"`
+	Text string     `json:"text" validate:"required" engine:"localized,evaluated"`
+	Data string     `json:"data" validate:"required" engine:"evaluated"`
+}
+
+// LocalizationUUID gets the UUID which identifies this object for localization
+func (b *InlineButton) LocalizationUUID() uuids.UUID { return b.UUID }
+
+// RichCard is a card of an RCS message
+type RichCard struct {
+	UUID        uuids.UUID    `json:"uuid" validate:"required,uuid4"`
+	Title       string        `json:"title,omitempty" engine:"localized,evaluated"`
+	Description string        `json:"description,omitempty" engine:"localized,evaluated"`
+	Media       string        `json:"media,omitempty" engine:"evaluated"`
+	Suggestions []*Suggestion `json:"suggestions,omitempty" validate:"omitempty,max=4,dive"`
+}
+
+// LocalizationUUID gets the UUID which identifies this object for localization
+func (c *RichCard) LocalizationUUID() uuids.UUID { return c.UUID }
+
+// Suggestion is a suggested reply or action of an RCS message or card
+type Suggestion struct {
+	UUID  uuids.UUID           `json:"uuid" validate:"required,uuid4"`
+	Type  flows.SuggestionType `json:"type" validate:"required,suggestion_type"`
+	Text  string               `json:"text" validate:"required" engine:"localized,evaluated"`
+	Data  string               `json:"data,omitempty" engine:"evaluated"`
+	URL   string               `json:"url,omitempty" engine:"evaluated"`
+	Phone string               `json:"phone,omitempty" engine:"evaluated"`
+}
+
+// LocalizationUUID gets the UUID which identifies this object for localization
+func (s *Suggestion) LocalizationUUID() uuids.UUID { return s.UUID }
+
 // NewSendMsg creates a new send msg action
 func NewSendMsg(uuid flows.ActionUUID, text string, attachments []string, quickReplies []string, allURNs bool) *SendMsgAction {
 	return &SendMsgAction{
@@ -73,8 +138,33 @@ func NewSendMsg(uuid flows.ActionUUID, text string, attachments []string, quickR
 	}
 }
 
+// Validate validates our action is valid
+func (a *SendMsgAction) Validate() error {
+	for i, card := range a.RichCards {
+		if card.Title == "" && card.Description == "" && card.Media == "" {
+			return errors.Errorf("rich card %d must have a title, description or media", i)
+		}
+		if err := validateSuggestions(card.Suggestions); err != nil {
+			return err
+		}
+	}
+	return validateSuggestions(a.Suggestions)
+}
+
+func validateSuggestions(suggestions []*Suggestion) error {
+	for _, s := range suggestions {
+		if s.Type == flows.SuggestionTypeOpenURL && s.URL == "" {
+			return errors.Errorf("suggestion '%s' of type open_url must have a URL", s.Text)
+		}
+		if s.Type == flows.SuggestionTypeDial && s.Phone == "" {
+			return errors.Errorf("suggestion '%s' of type dial must have a phone number", s.Text)
+		}
+	}
+	return nil
+}
+
 // Execute runs this action
-func (a *SendMsgAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *SendMsgAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	if run.Contact() == nil {
 		logEvent(events.NewErrorf("can't execute action in session without a contact"))
 		return nil
@@ -86,8 +176,18 @@ func (a *SendMsgAction) Execute(run flows.Run, step flows.Step, logModifier flow
 		unsendableReason = flows.UnsendableReasonContactStatus
 	}
 
-	evaluatedText, evaluatedAttachments, evaluatedQuickReplies, lang := a.evaluateMessage(run, nil, a.Text, a.Attachments, a.QuickReplies, logEvent)
+	evaluatedText, evaluatedAttachments, evaluatedQuickReplies, lang := a.evaluateMessage(ctx, run, nil, a.Text, a.Attachments, a.QuickReplies, logEvent)
 	locale := currentLocale(run, lang)
+	evaluatedKeyboard := a.evaluateInlineKeyboard(run, logEvent)
+	evaluatedCards, evaluatedSuggestions := a.evaluateRichContent(run, logEvent)
+
+	// if this message is the prompt for a USSD wait, only its first page is sent now and the wait shows the rest
+	var pages []string
+	if wait := promptedWait(run.Flow(), step.NodeUUID()); wait != nil {
+		if pages = wait.Paginate(evaluatedText); pages != nil {
+			evaluatedText = pages[0]
+		}
+	}
 
 	destinations := run.Contact().ResolveDestinations(a.AllURNs)
 
@@ -127,16 +227,184 @@ func (a *SendMsgAction) Execute(run flows.Run, step flows.Step, logModifier flow
 			}
 		}
 
-		msg := flows.NewMsgOut(urn, channelRef, evaluatedText, evaluatedAttachments, evaluatedQuickReplies, templating, a.Topic, locale, unsendableReason)
-		logEvent(events.NewMsgCreated(msg))
+		msg := flows.NewMsgOut(flows.MsgUUID(run.Session().Sources().NewUUID()), urn, channelRef, evaluatedText, evaluatedAttachments, evaluatedQuickReplies, evaluatedKeyboard, templating, a.Topic, locale, unsendableReason)
+		msg.SetPriority(a.Priority)
+		setRichContent(run, msg, dest.Channel, evaluatedCards, evaluatedSuggestions, logEvent)
+		applySchemeCapabilities(run, msg, dest.Channel, logEvent)
+		logEvent(newPagedMsgCreated(msg, pages))
 	}
 
 	// if we couldn't find a destination, create a msg without a URN or channel and it's up to the caller
 	// to handle that as they want
 	if len(destinations) == 0 {
-		msg := flows.NewMsgOut(urns.NilURN, nil, evaluatedText, evaluatedAttachments, evaluatedQuickReplies, nil, a.Topic, locale, flows.UnsendableReasonNoDestination)
-		logEvent(events.NewMsgCreated(msg))
+		msg := flows.NewMsgOut(flows.MsgUUID(run.Session().Sources().NewUUID()), urns.NilURN, nil, evaluatedText, evaluatedAttachments, evaluatedQuickReplies, evaluatedKeyboard, nil, a.Topic, locale, flows.UnsendableReasonNoDestination)
+		msg.SetPriority(a.Priority)
+		setRichContent(run, msg, nil, evaluatedCards, evaluatedSuggestions, logEvent)
+		logEvent(newPagedMsgCreated(msg, pages))
 	}
 
 	return nil
 }
+
+// finds the wait that a message sent from the given node is the prompt for, which is the wait on that node or on the
+// first node with a router that's reached from it through nodes which only have actions
+func promptedWait(flow flows.Flow, nodeUUID flows.NodeUUID) flows.PagedWait {
+	visited := make(map[flows.NodeUUID]bool)
+
+	for node := flow.GetNode(nodeUUID); node != nil && !visited[node.UUID()]; {
+		visited[node.UUID()] = true
+
+		if node.Router() != nil {
+			paged, _ := node.Router().Wait().(flows.PagedWait)
+			return paged
+		}
+		if len(node.Exits()) != 1 || node.Exits()[0].DestinationUUID() == "" {
+			return nil
+		}
+		node = flow.GetNode(node.Exits()[0].DestinationUUID())
+	}
+	return nil
+}
+
+// creates a msg_created event for a message which is the first of the given pages, if it's been paginated
+func newPagedMsgCreated(msg *flows.MsgOut, pages []string) *events.MsgCreatedEvent {
+	event := events.NewMsgCreated(msg)
+	if pages != nil {
+		event.Segments = nil // paginated messages are USSD responses which aren't sent as SMS
+		event.MorePages = pages[1:]
+	}
+	return event
+}
+
+// localizes and evaluates the buttons of our inline keyboard, skipping any which can't be sent
+func (a *SendMsgAction) evaluateInlineKeyboard(run flows.Run, logEvent flows.EventCallback) []flows.InlineButton {
+	if len(a.InlineKeyboard) == 0 {
+		return nil
+	}
+
+	buttons := make([]flows.InlineButton, 0, len(a.InlineKeyboard))
+	for _, button := range a.InlineKeyboard {
+		localizedText, _ := run.GetText(button.UUID, "text", button.Text)
+
+		text, err := run.EvaluateTemplate(localizedText)
+		if err != nil {
+			logEvent(events.NewError(err))
+		}
+		data, err := run.EvaluateTemplate(button.Data)
+		if err != nil {
+			logEvent(events.NewError(err))
+		}
+
+		if text == "" || data == "" {
+			logEvent(events.NewErrorf("inline button text or data evaluated to empty string, skipping"))
+			continue
+		}
+		if len(data) > maxInlineButtonDataLength {
+			logEvent(events.NewErrorf("evaluated inline button data is longer than %d byte limit, skipping", maxInlineButtonDataLength))
+			continue
+		}
+
+		buttons = append(buttons, flows.InlineButton{Text: text, Data: data})
+	}
+	return buttons
+}
+
+// localizes and evaluates our rich cards and suggestions
+func (a *SendMsgAction) evaluateRichContent(run flows.Run, logEvent flows.EventCallback) ([]flows.RichCard, []flows.Suggestion) {
+	evaluate := func(template string) string {
+		value, err := run.EvaluateTemplate(template)
+		if err != nil {
+			logEvent(events.NewError(err))
+		}
+		return value
+	}
+
+	var cards []flows.RichCard
+	for _, card := range a.RichCards {
+		title, _ := run.GetText(card.UUID, "title", card.Title)
+		description, _ := run.GetText(card.UUID, "description", card.Description)
+
+		cards = append(cards, flows.RichCard{
+			Title:       evaluate(title),
+			Description: evaluate(description),
+			Media:       utils.Attachment(evaluate(card.Media)),
+			Suggestions: evaluateSuggestions(run, card.Suggestions, evaluate, logEvent),
+		})
+	}
+
+	return cards, evaluateSuggestions(run, a.Suggestions, evaluate, logEvent)
+}
+
+func evaluateSuggestions(run flows.Run, suggestions []*Suggestion, evaluate func(string) string, logEvent flows.EventCallback) []flows.Suggestion {
+	var evaluated []flows.Suggestion
+	for _, s := range suggestions {
+		text, _ := run.GetText(s.UUID, "text", s.Text)
+
+		e := flows.Suggestion{Type: s.Type, Text: evaluate(text), Data: evaluate(s.Data), URL: evaluate(s.URL), Phone: evaluate(s.Phone)}
+		if e.Text == "" {
+			logEvent(events.NewErrorf("suggestion text evaluated to empty string, skipping"))
+			continue
+		}
+		evaluated = append(evaluated, e)
+	}
+	return evaluated
+}
+
+// sets the rich content on the given message which the channel supports, and renders the rest as plain text which is
+// truncated like any other message text
+func setRichContent(run flows.Run, msg *flows.MsgOut, channel *flows.Channel, cards []flows.RichCard, suggestions []flows.Suggestion, logEvent flows.EventCallback) {
+	var fallbackCards []flows.RichCard
+	var fallbackSuggestions []flows.Suggestion
+
+	if len(cards) > 0 {
+		feature := assets.ChannelFeatureRichCards
+		if len(cards) > 1 {
+			feature = assets.ChannelFeatureCarousels
+		}
+		if channel == nil || !channel.HasFeature(feature) {
+			cards, fallbackCards = nil, cards
+		}
+	}
+	if len(suggestions) > 0 && (channel == nil || !channel.HasFeature(assets.ChannelFeatureSuggestions)) {
+		suggestions, fallbackSuggestions = nil, suggestions
+	}
+
+	msg.SetRichContent(cards, suggestions)
+
+	if len(fallbackCards) > 0 || len(fallbackSuggestions) > 0 {
+		msg.SetText(truncateMsgText(run, flows.RichContentFallback(msg.Text(), fallbackCards, fallbackSuggestions), logEvent))
+	}
+}
+
+// restricts the given message to what can be sent to URNs of its scheme, rendering quick replies as plain text where
+// they aren't supported by the scheme or declared as a feature of the channel
+func applySchemeCapabilities(run flows.Run, msg *flows.MsgOut, channel *flows.Channel, logEvent flows.EventCallback) {
+	scheme := msg.URN().Scheme()
+	caps := flows.CapabilitiesForScheme(scheme)
+	if caps == nil {
+		return
+	}
+
+	if quickReplies := msg.QuickReplies(); len(quickReplies) > 0 {
+		if !caps.QuickReplies && (channel == nil || !channel.HasFeature(assets.ChannelFeatureQuickReplies)) {
+			suggestions := make([]flows.Suggestion, len(quickReplies))
+			for i, qr := range quickReplies {
+				suggestions[i] = flows.Suggestion{Type: flows.SuggestionTypeReply, Text: qr}
+			}
+			msg.SetQuickReplies(nil)
+			msg.SetText(truncateMsgText(run, flows.RichContentFallback(msg.Text(), nil, suggestions), logEvent))
+		} else if caps.MaxQuickReplies > 0 && len(quickReplies) > caps.MaxQuickReplies {
+			logEvent(events.NewWarningf("%s channels support at most %d quick replies, ignoring %d", scheme, caps.MaxQuickReplies, len(quickReplies)-caps.MaxQuickReplies))
+			msg.SetQuickReplies(quickReplies[:caps.MaxQuickReplies])
+		}
+	}
+
+	if attachments := msg.Attachments(); caps.MaxAttachments > 0 && len(attachments) > caps.MaxAttachments {
+		logEvent(events.NewWarningf("%s channels support at most %d attachments, ignoring %d", scheme, caps.MaxAttachments, len(attachments)-caps.MaxAttachments))
+		msg.SetAttachments(attachments[:caps.MaxAttachments])
+	}
+
+	if length := utf8.RuneCountInString(msg.Text()); caps.MaxTextLength > 0 && length > caps.MaxTextLength {
+		logEvent(events.NewWarningf("message text has %d characters which is more than the %d supported by %s channels", length, caps.MaxTextLength, scheme))
+	}
+}
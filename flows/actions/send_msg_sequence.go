@@ -0,0 +1,120 @@
+package actions
+
+import (
+	"context"
+	"time"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerType(TypeSendMsgSequence, func() flows.Action { return &SendMsgSequenceAction{} })
+}
+
+// TypeSendMsgSequence is the type for the send message sequence action
+const TypeSendMsgSequence string = "send_msg_sequence"
+
+// the longest a sequence can take from the first message to the last
+const maxSequenceDuration = 90 * 24 * time.Hour
+
+// SendMsgSequenceAction can be used to send the current contact a sequence of messages spread out over time, without
+// the flow having to wait between them. Each message has a delay in seconds from the previous message, or for the first
+// message, from when the action is executed. All messages are localized and evaluated when the action is executed.
+//
+// A [event:msg_scheduled] event will be created for each message, and it is up to the caller to send them at their
+// scheduled times.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "send_msg_sequence",
+//	  "messages": [
+//	    {"uuid": "3f2e4b9a-0c5d-4e8f-b1a7-6d9c2e5f8a13", "text": "Welcome to the program @contact.name!", "delay_seconds": 0},
+//	    {"uuid": "ab79e1b8-2a17-4d5c-9a31-7c2f3b9b1e25", "text": "Here's your first tip...", "delay_seconds": 86400},
+//	    {"uuid": "6c4b5f1e-7a3d-4f2b-9e8c-1d0a2b3c4d5e", "text": "How are you getting on?", "quick_replies": ["Good", "Bad"], "delay_seconds": 259200}
+//	  ]
+//	}
+//
+// @action send_msg_sequence
+type SendMsgSequenceAction struct {
+	baseAction
+	universalAction
+
+	Messages []*SequenceMsg `json:"messages" validate:"required,min=1,max=20,dive"`
+}
+
+// SequenceMsg is a message of a sequence
+type SequenceMsg struct {
+	UUID         uuids.UUID `json:"uuid" validate:"required,uuid4"`
+	Text         string     `json:"text" validate:"required" engine:"localized,evaluated"`
+	Attachments  []string   `json:"attachments,omitempty" engine:"localized,evaluated"`
+	QuickReplies []string   `json:"quick_replies,omitempty" engine:"localized,evaluated"`
+	DelaySeconds int        `json:"delay_seconds" validate:"min=0"`
+}
+
+// LocalizationUUID gets the UUID which identifies this object for localization
+func (m *SequenceMsg) LocalizationUUID() uuids.UUID { return m.UUID }
+
+// NewSendMsgSequence creates a new send msg sequence action
+func NewSendMsgSequence(uuid flows.ActionUUID, messages []*SequenceMsg) *SendMsgSequenceAction {
+	return &SendMsgSequenceAction{
+		baseAction: newBaseAction(TypeSendMsgSequence, uuid),
+		Messages:   messages,
+	}
+}
+
+// Validate validates our action is valid
+func (a *SendMsgSequenceAction) Validate() error {
+	total := time.Duration(0)
+	for _, m := range a.Messages {
+		total += time.Duration(m.DelaySeconds) * time.Second
+	}
+	if total > maxSequenceDuration {
+		return errors.Errorf("sequence can't take longer than %d days", maxSequenceDuration/(24*time.Hour))
+	}
+	return nil
+}
+
+// Execute runs this action
+func (a *SendMsgSequenceAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	if run.Contact() == nil {
+		logEvent(events.NewErrorf("can't execute action in session without a contact"))
+		return nil
+	}
+
+	// a message to a non-active contact is unsendable but can still be created
+	unsendableReason := flows.NilUnsendableReason
+	if run.Contact().Status() != flows.ContactStatusActive {
+		unsendableReason = flows.UnsendableReasonContactStatus
+	}
+
+	destinations := run.Contact().ResolveDestinations(false)
+	sendOn := run.Session().Sources().Now()
+
+	for _, m := range a.Messages {
+		sendOn = sendOn.Add(time.Duration(m.DelaySeconds) * time.Second)
+
+		text, attachments, quickReplies, lang := evaluateMessage(ctx, run, m.UUID, nil, m.Text, m.Attachments, m.QuickReplies, logEvent)
+		locale := currentLocale(run, lang)
+
+		if len(destinations) > 0 {
+			dest := destinations[0]
+			channelRef := assets.NewChannelReference(dest.Channel.UUID(), dest.Channel.Name())
+
+			msg := flows.NewMsgOut(flows.MsgUUID(run.Session().Sources().NewUUID()), dest.URN.URN(), channelRef, text, attachments, quickReplies, nil, nil, flows.NilMsgTopic, locale, unsendableReason)
+			applySchemeCapabilities(run, msg, dest.Channel, logEvent)
+			logEvent(events.NewMsgScheduled(msg, sendOn))
+		} else {
+			// as with send_msg, it's up to the caller to handle messages without a URN or channel
+			msg := flows.NewMsgOut(flows.MsgUUID(run.Session().Sources().NewUUID()), urns.NilURN, nil, text, attachments, quickReplies, nil, nil, flows.NilMsgTopic, locale, flows.UnsendableReasonNoDestination)
+			logEvent(events.NewMsgScheduled(msg, sendOn))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package actions
+
+import (
+	"context"
+	"regexp"
+	"sort"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerType(TypeSendWhatsAppFlow, func() flows.Action { return &SendWhatsAppFlowAction{} })
+}
+
+// TypeSendWhatsAppFlow is the type for the send WhatsApp flow action
+const TypeSendWhatsAppFlow string = "send_whatsapp_flow"
+
+// WhatsApp requires screen IDs to be uppercase and form field names to be simple identifiers
+var whatsAppScreenRegex = regexp.MustCompile(`^[A-Z][A-Z_]*$`)
+var whatsAppFieldRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// SendWhatsAppFlowAction can be used to send a WhatsApp Flow form to the contact. The form is opened at the given
+// screen, and the data payload is evaluated and passed to that screen. The body and button text may contain templates.
+// The action will use the first WhatsApp URN of the contact that has a channel.
+//
+// When the contact submits the form, the session is resumed with a `whatsapp_flow` resume, and each submitted field
+// listed in the results mapping is saved as a result with the given name.
+//
+// A [event:whatsapp_flow_created] event will be created with the evaluated body, button and data.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "send_whatsapp_flow",
+//	  "flow_id": "1234567890",
+//	  "screen": "SIGN_UP",
+//	  "body": "Hi @contact.name, please complete your registration",
+//	  "button": "Sign up",
+//	  "data": {"name": "@contact.name"},
+//	  "results": {"age": "Age", "email": "Email"}
+//	}
+//
+// @action send_whatsapp_flow
+type SendWhatsAppFlowAction struct {
+	baseAction
+
+	FlowID      string            `json:"flow_id" validate:"required"`
+	Screen      string            `json:"screen" validate:"required"`
+	Body        string            `json:"body" validate:"required" engine:"localized,evaluated"`
+	Button      string            `json:"button" validate:"required" engine:"localized,evaluated"`
+	Data        map[string]string `json:"data,omitempty" engine:"evaluated"`
+	ResultNames map[string]string `json:"results,omitempty"` // form field name to result name
+}
+
+// NewSendWhatsAppFlow creates a new send WhatsApp flow action
+func NewSendWhatsAppFlow(uuid flows.ActionUUID, flowID, screen, body, button string, data, resultNames map[string]string) *SendWhatsAppFlowAction {
+	return &SendWhatsAppFlowAction{
+		baseAction:  newBaseAction(TypeSendWhatsAppFlow, uuid),
+		FlowID:      flowID,
+		Screen:      screen,
+		Body:        body,
+		Button:      button,
+		Data:        data,
+		ResultNames: resultNames,
+	}
+}
+
+// AllowedFlowTypes returns the flow types which this action is allowed to occur in
+func (a *SendWhatsAppFlowAction) AllowedFlowTypes() []flows.FlowType {
+	return []flows.FlowType{flows.FlowTypeMessaging}
+}
+
+// Validate validates our action is valid
+func (a *SendWhatsAppFlowAction) Validate() error {
+	if !whatsAppScreenRegex.MatchString(a.Screen) {
+		return errors.Errorf("screen '%s' is not a valid WhatsApp flow screen", a.Screen)
+	}
+	for key := range a.Data {
+		if !whatsAppFieldRegex.MatchString(key) {
+			return errors.Errorf("data key '%s' is not a valid WhatsApp flow field name", key)
+		}
+	}
+
+	resultFields := make(map[string]string, len(a.ResultNames))
+	for _, field := range a.resultFields() {
+		resultName := a.ResultNames[field]
+		if !whatsAppFieldRegex.MatchString(field) {
+			return errors.Errorf("results field '%s' is not a valid WhatsApp flow field name", field)
+		}
+		resultKey := utils.Snakify(resultName)
+		if resultKey == "" {
+			return errors.Errorf("results field '%s' must map to a result name", field)
+		}
+		if other, seen := resultFields[resultKey]; seen {
+			return errors.Errorf("results fields '%s' and '%s' map to the same result", other, field)
+		}
+		resultFields[resultKey] = field
+	}
+
+	return nil
+}
+
+// Execute runs this action
+func (a *SendWhatsAppFlowAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	if run.Contact() == nil {
+		logEvent(events.NewErrorf("can't execute action in session without a contact"))
+		return nil
+	}
+
+	var destination *flows.Destination
+	for _, dest := range run.Contact().ResolveDestinations(true) {
+		if dest.URN.URN().Scheme() == urns.WhatsAppScheme {
+			destination = &dest
+			break
+		}
+	}
+	if destination == nil {
+		logEvent(events.NewErrorf("contact has no WhatsApp URN with a channel"))
+		return nil
+	}
+
+	localizedBody, _ := run.GetText(uuids.UUID(a.UUID()), "body", a.Body)
+	localizedButton, _ := run.GetText(uuids.UUID(a.UUID()), "button", a.Button)
+
+	body, err := run.EvaluateTemplate(localizedBody)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+	button, err := run.EvaluateTemplate(localizedButton)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+
+	var data map[string]string
+	if len(a.Data) > 0 {
+		data = make(map[string]string, len(a.Data))
+		for key, value := range a.Data {
+			data[key], err = run.EvaluateTemplate(value)
+			if err != nil {
+				logEvent(events.NewError(err))
+			}
+		}
+	}
+
+	logEvent(events.NewWhatsAppFlowCreated(&flows.WhatsAppFlow{
+		URN:       destination.URN.URN(),
+		Channel:   assets.NewChannelReference(destination.Channel.UUID(), destination.Channel.Name()),
+		FlowID:    a.FlowID,
+		FlowToken: string(run.Session().Sources().NewUUID()),
+		Screen:    a.Screen,
+		Body:      body,
+		Button:    button,
+		Data:      data,
+		Results:   a.ResultNames,
+	}))
+
+	return nil
+}
+
+// Results enumerates any results generated by this flow object
+func (a *SendWhatsAppFlowAction) Results(include func(*flows.ResultInfo)) {
+	for _, field := range a.resultFields() {
+		include(flows.NewResultInfo(a.ResultNames[field], []string{}))
+	}
+}
+
+// gets the form fields in our results mapping in a stable order
+func (a *SendWhatsAppFlowAction) resultFields() []string {
+	fields := make([]string, 0, len(a.ResultNames))
+	for field := range a.ResultNames {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,188 @@
+package actions
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerType(TypeSendWhatsAppTemplate, func() flows.Action { return &SendWhatsAppTemplateAction{} })
+}
+
+// TypeSendWhatsAppTemplate is the type for the send WhatsApp template action
+const TypeSendWhatsAppTemplate string = "send_whatsapp_template"
+
+// the names of template components which can have params
+var templateComponentRegex = regexp.MustCompile(`^(header|body|button\.\d+)$`)
+
+// SendWhatsAppTemplateAction can be used to send an approved WhatsApp template message to the contact. The params for
+// each component of the template, e.g. its header, body and buttons, are given separately and may contain templates.
+// The `namespace` is optional and if not given, the namespace of the template translation is used.
+//
+// The template translation used is the first one found for a channel of the contact, in the contact's locale or
+// the environment default. Flow validation checks that the number of params given for each component matches the
+// template.
+//
+// A [event:whatsapp_template_created] event will be created with the evaluated params of each component.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "send_whatsapp_template",
+//	  "template": {
+//	    "uuid": "9c22b594-fcab-4b29-9bcb-ce4404894a80",
+//	    "name": "appointment"
+//	  },
+//	  "components": [
+//	    {"name": "header", "params": ["Dentist"]},
+//	    {"name": "body", "params": ["@contact.name", "Tuesday"]}
+//	  ]
+//	}
+//
+// @action send_whatsapp_template
+type SendWhatsAppTemplateAction struct {
+	baseAction
+
+	Template   *assets.TemplateReference  `json:"template" validate:"required"`
+	Namespace  string                     `json:"namespace,omitempty"`
+	Components []*TemplateComponentParams `json:"components,omitempty" validate:"dive"`
+}
+
+// TemplateComponentParams are the params for a component of a template
+type TemplateComponentParams struct {
+	Name   string   `json:"name" validate:"required"`
+	Params []string `json:"params" engine:"evaluated"`
+}
+
+// NewSendWhatsAppTemplate creates a new send WhatsApp template action
+func NewSendWhatsAppTemplate(uuid flows.ActionUUID, template *assets.TemplateReference, namespace string, components []*TemplateComponentParams) *SendWhatsAppTemplateAction {
+	return &SendWhatsAppTemplateAction{
+		baseAction: newBaseAction(TypeSendWhatsAppTemplate, uuid),
+		Template:   template,
+		Namespace:  namespace,
+		Components: components,
+	}
+}
+
+// AllowedFlowTypes returns the flow types which this action is allowed to occur in
+func (a *SendWhatsAppTemplateAction) AllowedFlowTypes() []flows.FlowType {
+	return []flows.FlowType{flows.FlowTypeMessaging}
+}
+
+// Validate validates our action is valid
+func (a *SendWhatsAppTemplateAction) Validate() error {
+	seen := make(map[string]bool, len(a.Components))
+
+	for _, c := range a.Components {
+		if !templateComponentRegex.MatchString(c.Name) {
+			return errors.Errorf("'%s' is not a valid template component name", c.Name)
+		}
+		if seen[c.Name] {
+			return errors.Errorf("template component '%s' is repeated", c.Name)
+		}
+		seen[c.Name] = true
+	}
+
+	return nil
+}
+
+// Execute runs this action
+func (a *SendWhatsAppTemplateAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	if run.Contact() == nil {
+		logEvent(events.NewErrorf("can't execute action in session without a contact"))
+		return nil
+	}
+
+	template := run.Session().Assets().Templates().Get(a.Template.UUID)
+	if template == nil {
+		logEvent(events.NewDependencyError(a.Template))
+		return nil
+	}
+
+	// looks for a translation in the contact locale or environment default
+	locales := []envs.Locale{
+		run.Environment().DefaultLocale(),
+		run.Session().Environment().DefaultLocale(),
+	}
+
+	// use the first destination whose channel has a translation of this template
+	var destination *flows.Destination
+	var translation *flows.TemplateTranslation
+	for _, dest := range run.Contact().ResolveDestinations(true) {
+		if translation = template.FindTranslation(dest.Channel.UUID(), locales); translation != nil {
+			destination = &dest
+			break
+		}
+	}
+	if destination == nil {
+		logEvent(events.NewErrorf("no translation of template '%s' for any channel of the contact", template.Name()))
+		return nil
+	}
+
+	// check we're not giving params for components the template doesn't have
+	for _, c := range a.Components {
+		if translation.Component(c.Name) == nil {
+			logEvent(events.NewErrorf("template '%s' has no component '%s'", template.Name(), c.Name))
+			return nil
+		}
+	}
+
+	params := a.paramsByComponent()
+	components := make([]*flows.WhatsAppTemplateComponent, 0, len(translation.Components()))
+	var text string
+
+	for _, comp := range translation.Components() {
+		given := params[comp.Name()]
+		if len(given) != comp.ParamCount() {
+			logEvent(events.NewErrorf("template '%s' component '%s' expects %d params but %d given", template.Name(), comp.Name(), comp.ParamCount(), len(given)))
+			return nil
+		}
+
+		evaluated := make([]string, len(given))
+		for i, param := range given {
+			var err error
+			evaluated[i], err = run.EvaluateTemplate(param)
+			if err != nil {
+				logEvent(events.NewError(err))
+			}
+		}
+
+		components = append(components, &flows.WhatsAppTemplateComponent{Type: comp.Type(), Name: comp.Name(), Params: evaluated})
+
+		if comp.Name() == "body" {
+			text = flows.SubstituteTemplateParams(comp.Content(), evaluated)
+		}
+	}
+
+	namespace := a.Namespace
+	if namespace == "" {
+		namespace = translation.Namespace()
+	}
+
+	logEvent(events.NewWhatsAppTemplateCreated(&flows.WhatsAppTemplateMsg{
+		URN:        destination.URN.URN(),
+		Channel:    assets.NewChannelReference(destination.Channel.UUID(), destination.Channel.Name()),
+		Template:   template.Reference(),
+		Namespace:  namespace,
+		Locale:     translation.Locale(),
+		Components: components,
+		Text:       text,
+	}))
+
+	return nil
+}
+
+// gets our params keyed by component name
+func (a *SendWhatsAppTemplateAction) paramsByComponent() map[string][]string {
+	params := make(map[string][]string, len(a.Components))
+	for _, c := range a.Components {
+		params[c.Name] = c.Params
+	}
+	return params
+}
@@ -1,6 +1,8 @@
 package actions
 
 import (
+	"context"
+
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
@@ -42,7 +44,7 @@ func NewSetContactChannel(uuid flows.ActionUUID, channel *assets.ChannelReferenc
 }
 
 // Execute runs our action
-func (a *SetContactChannelAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *SetContactChannelAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	contact := run.Contact()
 	if contact == nil {
 		logEvent(events.NewErrorf("can't execute action in session without a contact"))
@@ -58,6 +60,6 @@ func (a *SetContactChannelAction) Execute(run flows.Run, step flows.Step, logMod
 		}
 	}
 
-	a.applyModifier(run, modifiers.NewChannel(channel), logModifier, logEvent)
+	a.applyModifier(ctx, run, modifiers.NewChannel(channel), logModifier, logEvent)
 	return nil
 }
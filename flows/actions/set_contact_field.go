@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"context"
 	"strings"
 
 	"github.com/nyaruka/goflow/assets"
@@ -46,7 +47,7 @@ func NewSetContactField(uuid flows.ActionUUID, field *assets.FieldReference, val
 }
 
 // Execute runs this action
-func (a *SetContactFieldAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *SetContactFieldAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	if run.Contact() == nil {
 		logEvent(events.NewErrorf("can't execute action in session without a contact"))
 		return nil
@@ -65,7 +66,7 @@ func (a *SetContactFieldAction) Execute(run flows.Run, step flows.Step, logModif
 	field := fields.Get(a.Field.Key)
 
 	if field != nil {
-		a.applyModifier(run, modifiers.NewField(field, value), logModifier, logEvent)
+		a.applyModifier(ctx, run, modifiers.NewField(field, value), logModifier, logEvent)
 	} else {
 		logEvent(events.NewDependencyError(a.Field))
 	}
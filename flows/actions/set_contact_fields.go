@@ -0,0 +1,139 @@
+package actions
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/modifiers"
+)
+
+func init() {
+	registerType(TypeSetContactFields, func() flows.Action { return &SetContactFieldsAction{} })
+}
+
+// TypeSetContactFields is the type for the set contact fields action
+const TypeSetContactFields string = "set_contact_fields"
+
+// FieldMapping maps a value in an object, identified by a path of property names and array indexes separated by
+// periods, e.g. `profile.phones.0`, to a contact field
+type FieldMapping struct {
+	Field *assets.FieldReference `json:"field" validate:"required"`
+	Path  string                 `json:"path" validate:"required"`
+}
+
+// SetContactFieldsAction can be used to update several field values on the contact at once from an object such as the
+// JSON response of a webhook. The values template should evaluate to an object, and each mapping sets a field to the
+// value at its path in that object. Paths which don't exist in the object are skipped and null values clear the field.
+// If any of the fields don't exist then no fields are changed. A single [event:contact_fields_changed] event will be
+// created with all the values which changed.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "set_contact_fields",
+//	  "values": "@(parse_json(\"{\\\"profile\\\": {\\\"gender\\\": \\\"Female\\\", \\\"age\\\": 33}}\"))",
+//	  "mapping": [
+//	    {"field": {"key": "gender", "name": "Gender"}, "path": "profile.gender"},
+//	    {"field": {"key": "age", "name": "Age"}, "path": "profile.age"}
+//	  ]
+//	}
+//
+// @action set_contact_fields
+type SetContactFieldsAction struct {
+	baseAction
+	universalAction
+
+	Values  string          `json:"values" validate:"required" engine:"evaluated"`
+	Mapping []*FieldMapping `json:"mapping" validate:"required,min=1,dive"`
+}
+
+// NewSetContactFields creates a new set contact fields action
+func NewSetContactFields(uuid flows.ActionUUID, values string, mapping []*FieldMapping) *SetContactFieldsAction {
+	return &SetContactFieldsAction{
+		baseAction: newBaseAction(TypeSetContactFields, uuid),
+		Values:     values,
+		Mapping:    mapping,
+	}
+}
+
+// Execute runs this action
+func (a *SetContactFieldsAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	if run.Contact() == nil {
+		logEvent(events.NewErrorf("can't execute action in session without a contact"))
+		return nil
+	}
+
+	values, err := run.EvaluateTemplateValue(a.Values)
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	object, isObject := values.(*types.XObject)
+	if !isObject {
+		logEvent(events.NewErrorf("values must evaluate to an object, got %s", types.Describe(values)))
+		return nil
+	}
+
+	// resolve all the fields first so that we either change all of them or none of them
+	fields := run.Session().Assets().Fields()
+	resolved := make([]*flows.Field, len(a.Mapping))
+	for i, m := range a.Mapping {
+		if resolved[i] = fields.Get(m.Field.Key); resolved[i] == nil {
+			logEvent(events.NewDependencyError(m.Field))
+			return nil
+		}
+	}
+
+	fieldValues := make([]*modifiers.FieldValue, 0, len(a.Mapping))
+
+	for i, m := range a.Mapping {
+		value, found := lookupPath(object, m.Path)
+		if !found {
+			continue
+		}
+
+		text := ""
+		if value != nil {
+			asText, xerr := types.ToXText(run.Environment(), value)
+			if xerr != nil {
+				logEvent(events.NewError(xerr))
+				continue
+			}
+			text = strings.TrimSpace(asText.Native())
+		}
+
+		fieldValues = append(fieldValues, &modifiers.FieldValue{Field: resolved[i], Value: text})
+	}
+
+	if len(fieldValues) > 0 {
+		a.applyModifier(ctx, run, modifiers.NewFields(fieldValues), logModifier, logEvent)
+	}
+	return nil
+}
+
+// looks up the value at the given path in an object, returning whether it was found
+func lookupPath(value types.XValue, path string) (types.XValue, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch typed := value.(type) {
+		case *types.XObject:
+			var found bool
+			if value, found = typed.Get(key); !found {
+				return nil, false
+			}
+		case *types.XArray:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= typed.Count() {
+				return nil, false
+			}
+			value = typed.Get(index)
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
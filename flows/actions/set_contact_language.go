@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"context"
 	"strings"
 
 	"github.com/nyaruka/goflow/envs"
@@ -43,7 +44,7 @@ func NewSetContactLanguage(uuid flows.ActionUUID, language string) *SetContactLa
 }
 
 // Execute runs this action
-func (a *SetContactLanguageAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *SetContactLanguageAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	if run.Contact() == nil {
 		logEvent(events.NewErrorf("can't execute action in session without a contact"))
 		return nil
@@ -68,6 +69,6 @@ func (a *SetContactLanguageAction) Execute(run flows.Run, step flows.Step, logMo
 		}
 	}
 
-	a.applyModifier(run, modifiers.NewLanguage(lang), logModifier, logEvent)
+	a.applyModifier(ctx, run, modifiers.NewLanguage(lang), logModifier, logEvent)
 	return nil
 }
@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"context"
 	"strings"
 
 	"github.com/nyaruka/goflow/flows"
@@ -42,7 +43,7 @@ func NewSetContactName(uuid flows.ActionUUID, name string) *SetContactNameAction
 }
 
 // Execute runs this action
-func (a *SetContactNameAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *SetContactNameAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	if run.Contact() == nil {
 		logEvent(events.NewErrorf("can't execute action in session without a contact"))
 		return nil
@@ -57,6 +58,6 @@ func (a *SetContactNameAction) Execute(run flows.Run, step flows.Step, logModifi
 		return nil
 	}
 
-	a.applyModifier(run, modifiers.NewName(name), logModifier, logEvent)
+	a.applyModifier(ctx, run, modifiers.NewName(name), logModifier, logEvent)
 	return nil
 }
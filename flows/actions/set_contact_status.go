@@ -1,6 +1,8 @@
 package actions
 
 import (
+	"context"
+
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
 	"github.com/nyaruka/goflow/flows/modifiers"
@@ -39,12 +41,12 @@ func NewSetContactStatus(uuid flows.ActionUUID, status flows.ContactStatus) *Set
 }
 
 // Execute runs this action
-func (a *SetContactStatusAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *SetContactStatusAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	if run.Contact() == nil {
 		logEvent(events.NewErrorf("can't execute action in session without a contact"))
 		return nil
 	}
 
-	a.applyModifier(run, modifiers.NewStatus(a.Status), logModifier, logEvent)
+	a.applyModifier(ctx, run, modifiers.NewStatus(a.Status), logModifier, logEvent)
 	return nil
 }
@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"context"
 	"strings"
 	"time"
 
@@ -43,7 +44,7 @@ func NewSetContactTimezone(uuid flows.ActionUUID, timezone string) *SetContactTi
 }
 
 // Execute runs this action
-func (a *SetContactTimezoneAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *SetContactTimezoneAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	if run.Contact() == nil {
 		logEvent(events.NewErrorf("can't execute action in session without a contact"))
 		return nil
@@ -68,6 +69,6 @@ func (a *SetContactTimezoneAction) Execute(run flows.Run, step flows.Step, logMo
 		}
 	}
 
-	a.applyModifier(run, modifiers.NewTimezone(tz), logModifier, logEvent)
+	a.applyModifier(ctx, run, modifiers.NewTimezone(tz), logModifier, logEvent)
 	return nil
 }
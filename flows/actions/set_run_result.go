@@ -1,9 +1,14 @@
 package actions
 
 import (
+	"context"
+
 	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
+
+	"github.com/pkg/errors"
 )
 
 func init() {
@@ -18,14 +23,17 @@ const TypeSetRunResult string = "set_run_result"
 // this can be useful for reporting or analytics.
 //
 // Both the value and category fields may be templates. A [event:run_result_changed] event will be created with the
-// final values.
+// final values. An optional schema declares the type of the value, which is then validated and made available to
+// expressions as a typed value. An optional PII category (`name`, `phone` or `health`) tags the result so that
+// environment redaction rules for that category mask its value.
 //
 //	{
 //	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
 //	  "type": "set_run_result",
 //	  "name": "Gender",
 //	  "value": "m",
-//	  "category": "Male"
+//	  "category": "Male",
+//	  "schema": {"type": "option", "options": ["m", "f"]}
 //	}
 //
 // @action set_run_result
@@ -33,9 +41,11 @@ type SetRunResultAction struct {
 	baseAction
 	universalAction
 
-	Name     string `json:"name" validate:"required"`
-	Value    string `json:"value" engine:"evaluated"`
-	Category string `json:"category,omitempty" engine:"localized"`
+	Name     string              `json:"name" validate:"required"`
+	Value    string              `json:"value" engine:"evaluated"`
+	Category string              `json:"category,omitempty" engine:"localized"`
+	Schema   *flows.ResultSchema `json:"schema,omitempty"`
+	PII      envs.PIICategory    `json:"pii,omitempty" validate:"omitempty,pii_category"`
 }
 
 // NewSetRunResult creates a new set run result action
@@ -48,8 +58,16 @@ func NewSetRunResult(uuid flows.ActionUUID, name string, value string, category
 	}
 }
 
+// Validate validates our action is valid
+func (a *SetRunResultAction) Validate() error {
+	if a.Schema != nil {
+		return errors.Wrap(a.Schema.Validate(), "invalid schema")
+	}
+	return nil
+}
+
 // Execute runs this action
-func (a *SetRunResultAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+func (a *SetRunResultAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
 	// get our evaluated value
 	value, err := run.EvaluateTemplate(a.Value)
 
@@ -64,7 +82,7 @@ func (a *SetRunResultAction) Execute(run flows.Run, step flows.Step, logModifier
 		categoryLocalized = ""
 	}
 
-	a.saveResult(run, step, a.Name, value, a.Category, categoryLocalized, "", nil, logEvent)
+	a.saveTypedResult(run, step, a.Name, value, a.Category, categoryLocalized, "", nil, a.Schema, a.PII, logEvent)
 	return nil
 }
 
@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nyaruka/goflow/excellent"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+func init() {
+	registerType(TypeSetTimer, func() flows.Action { return &SetTimerAction{} })
+}
+
+// TypeSetTimer is the type for the set timer action
+const TypeSetTimer string = "set_timer"
+
+// SetTimerAction can be used to set a named timer which, when it fires, takes the run to the given node wherever it
+// is waiting in the flow. This allows a flow to nudge a contact who hasn't replied, or to follow up after a few days,
+// without every wait needing its own timeout. The delay is a template which should evaluate to a number followed by a
+// unit of `s`, `m`, `h`, `d` or `w`, e.g. `2d`, and if it has no expressions, it's checked when the flow is read.
+// Setting a timer with the same name as an existing timer replaces it.
+//
+// A [event:timer_set] event will be created which the caller should use to schedule a `timer_fired` resume.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "set_timer",
+//	  "name": "followup",
+//	  "delay": "2d",
+//	  "node_uuid": "3dcccbb4-d29c-41dd-a01f-16d814c9ab82"
+//	}
+//
+// @action set_timer
+type SetTimerAction struct {
+	baseAction
+	interactiveAction
+
+	Name     string         `json:"name" validate:"required,max=64"`
+	Delay    string         `json:"delay" validate:"required" engine:"evaluated"`
+	NodeUUID flows.NodeUUID `json:"node_uuid" validate:"required,uuid4"`
+}
+
+// NewSetTimer creates a new set timer action
+func NewSetTimer(uuid flows.ActionUUID, name, delay string, nodeUUID flows.NodeUUID) *SetTimerAction {
+	return &SetTimerAction{
+		baseAction: newBaseAction(TypeSetTimer, uuid),
+		Name:       name,
+		Delay:      delay,
+		NodeUUID:   nodeUUID,
+	}
+}
+
+// Validate validates our action is valid
+func (a *SetTimerAction) Validate() error {
+	// a delay without expressions can be checked now rather than when the timer is set
+	if !excellent.HasExpressions(a.Delay, flows.RunContextTopLevels) {
+		if _, err := flows.ParseTimerDelay(a.Delay); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Execute runs this action
+func (a *SetTimerAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	if run.Flow().GetNode(a.NodeUUID) == nil {
+		logEvent(events.NewErrorf("node %s for timer '%s' doesn't exist in this flow", a.NodeUUID, a.Name))
+		return nil
+	}
+
+	evaluatedDelay, err := run.EvaluateTemplate(a.Delay)
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	delay, err := flows.ParseTimerDelay(strings.TrimSpace(evaluatedDelay))
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	timer := flows.NewTimer(a.Name, a.NodeUUID, run.Session().Sources().Now().Add(delay))
+
+	run.SetTimer(timer)
+	logEvent(events.NewTimerSet(timer))
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package actions
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+func init() {
+	registerType(TypeStartRecording, func() flows.Action { return &StartRecordingAction{} })
+}
+
+// TypeStartRecording is the type for the start recording action
+const TypeStartRecording string = "start_recording"
+
+// StartRecordingAction can be used to start recording the call in a voice flow. If a consent prompt is given, it is
+// played to the contact as an [event:ivr_created] event before recording starts. Recording continues until a
+// `stop_recording` action is reached.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "start_recording",
+//	  "consent_prompt": "This call will be recorded for quality purposes."
+//	}
+//
+// @action start_recording
+type StartRecordingAction struct {
+	baseAction
+	voiceAction
+
+	ConsentPrompt string `json:"consent_prompt,omitempty" engine:"localized,evaluated"`
+}
+
+// NewStartRecording creates a new start recording action
+func NewStartRecording(uuid flows.ActionUUID, consentPrompt string) *StartRecordingAction {
+	return &StartRecordingAction{
+		baseAction:    newBaseAction(TypeStartRecording, uuid),
+		ConsentPrompt: consentPrompt,
+	}
+}
+
+// Execute runs this action
+func (a *StartRecordingAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	// an IVR flow must have been started with a call
+	call := run.Session().Trigger().Call()
+
+	if a.ConsentPrompt != "" {
+		localizedPrompt, promptLang := run.GetText(uuids.UUID(a.UUID()), "consent_prompt", a.ConsentPrompt)
+		evaluatedPrompt, err := run.EvaluateTemplate(localizedPrompt)
+		if err != nil {
+			logEvent(events.NewError(err))
+		}
+		evaluatedPrompt = strings.TrimSpace(evaluatedPrompt)
+
+		// without the prompt we can't record with consent, so don't record at all
+		if evaluatedPrompt == "" {
+			logEvent(events.NewErrorf("consent prompt evaluated to empty, not recording"))
+			return nil
+		}
+
+		msg := flows.NewIVRMsgOut(flows.MsgUUID(run.Session().Sources().NewUUID()), call.URN(), call.Channel(), evaluatedPrompt, "", currentLocale(run, promptLang))
+		logEvent(events.NewIVRCreated(msg))
+	}
+
+	svc, err := run.Session().Engine().Services().CallRecording(run.Session().Assets())
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeCallRecording)
+	defer cancel()
+
+	if err := svc.StartRecording(ctx, call); err != nil {
+		logEvent(events.NewError(err))
+	}
+
+	return nil
+}
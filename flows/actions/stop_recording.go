@@ -0,0 +1,58 @@
+package actions
+
+import (
+	"context"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+func init() {
+	registerType(TypeStopRecording, func() flows.Action { return &StopRecordingAction{} })
+}
+
+// TypeStopRecording is the type for the stop recording action
+const TypeStopRecording string = "stop_recording"
+
+// StopRecordingAction can be used to stop recording the call in a voice flow. It will generate a
+// [event:recording_created] event with the URL of the recorded media.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "stop_recording"
+//	}
+//
+// @action stop_recording
+type StopRecordingAction struct {
+	baseAction
+	voiceAction
+}
+
+// NewStopRecording creates a new stop recording action
+func NewStopRecording(uuid flows.ActionUUID) *StopRecordingAction {
+	return &StopRecordingAction{
+		baseAction: newBaseAction(TypeStopRecording, uuid),
+	}
+}
+
+// Execute runs this action
+func (a *StopRecordingAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	svc, err := run.Session().Engine().Services().CallRecording(run.Session().Assets())
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	// an IVR flow must have been started with a call
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeCallRecording)
+	defer cancel()
+
+	url, err := svc.StopRecording(ctx, run.Session().Trigger().Call())
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	logEvent(events.NewRecordingCreated(url))
+	return nil
+}
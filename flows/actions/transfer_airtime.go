@@ -1,6 +1,9 @@
 package actions
 
 import (
+	"context"
+	"sort"
+
 	"github.com/nyaruka/gocommon/urns"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
@@ -13,14 +16,19 @@ func init() {
 	registerType(TypeTransferAirtime, func() flows.Action { return &TransferAirtimeAction{} })
 }
 
-var transferCategories = []string{CategorySuccess, CategoryFailure}
+// CategoryInsufficientBalance is the category for transfers which weren't attempted because the balance was too low
+const CategoryInsufficientBalance = "Insufficient Balance"
+
+var transferCategories = []string{CategorySuccess, CategoryFailure, CategoryInsufficientBalance}
 
 // TypeTransferAirtime is the type for the transfer airtime action
 const TypeTransferAirtime string = "transfer_airtime"
 
 // TransferAirtimeAction attempts to make an airtime transfer to the contact.
 //
-// An [event:airtime_transferred] event will be created if the airtime could be sent.
+// An [event:airtime_transferred] event will be created if the airtime could be sent. If the transfer fails and the
+// balance reported by the transfer response is less than the amount to be sent, an [event:airtime_balance_insufficient]
+// event is created instead, and the result has the category `Insufficient Balance`.
 //
 //	{
 //	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
@@ -48,12 +56,14 @@ func NewTransferAirtime(uuid flows.ActionUUID, amounts map[string]decimal.Decima
 }
 
 // Execute executes the transfer action
-func (a *TransferAirtimeAction) Execute(run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
-	transfer, err := a.transfer(run, step, logEvent)
+func (a *TransferAirtimeAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	transfer, insufficient, err := a.transfer(ctx, run, step, logEvent)
 	if err != nil {
 		logEvent(events.NewError(err))
 
 		a.saveFailure(run, step, logEvent)
+	} else if insufficient {
+		a.saveResult(run, step, a.ResultName, "0", CategoryInsufficientBalance, "", "", nil, logEvent)
 	} else {
 		a.saveSuccess(run, step, transfer, logEvent)
 	}
@@ -61,17 +71,17 @@ func (a *TransferAirtimeAction) Execute(run flows.Run, step flows.Step, logModif
 	return nil
 }
 
-func (a *TransferAirtimeAction) transfer(run flows.Run, step flows.Step, logEvent flows.EventCallback) (*flows.AirtimeTransfer, error) {
+func (a *TransferAirtimeAction) transfer(ctx context.Context, run flows.Run, step flows.Step, logEvent flows.EventCallback) (*flows.AirtimeTransfer, bool, error) {
 	// fail if we don't have a contact
 	contact := run.Contact()
 	if contact == nil {
-		return nil, errors.New("can't execute action in session without a contact")
+		return nil, false, errors.New("can't execute action in session without a contact")
 	}
 
 	// fail if the contact doesn't have a tel URN
 	telURNs := contact.URNs().WithScheme(urns.TelScheme)
 	if len(telURNs) == 0 {
-		return nil, errors.New("can't transfer airtime to contact without a tel URN")
+		return nil, false, errors.New("can't transfer airtime to contact without a tel URN")
 	}
 
 	// if contact's preferred channel is tel, use that as the sender
@@ -83,17 +93,55 @@ func (a *TransferAirtimeAction) transfer(run flows.Run, step flows.Step, logEven
 
 	svc, err := run.Session().Engine().Services().Airtime(run.Session().Assets())
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	httpLogger := &flows.HTTPLogger{}
+	httpLogger := newHTTPLogger(ctx, run)
+
+	ctx, cancel := serviceContext(ctx, run, flows.ServiceTypeAirtime)
+	defer cancel()
+
+	transfer, err := svc.Transfer(ctx, sender, telURNs[0].URN(), a.Amounts, httpLogger.Log)
+
+	// if the transfer failed and the balance it reported is too low, that's why
+	if err != nil && transfer != nil {
+		if currency, balance, found := insufficientBalance(transfer.Balances, a.Amounts); found {
+			logEvent(events.NewAirtimeBalanceInsufficient(currency, balance, a.Amounts[currency], httpLogger.Logs))
+			return nil, true, nil
+		}
+	}
 
-	transfer, err := svc.Transfer(sender, telURNs[0].URN(), a.Amounts, httpLogger.Log)
 	if transfer != nil {
 		logEvent(events.NewAirtimeTransferred(transfer, httpLogger.Logs))
 	}
 
-	return transfer, err
+	return transfer, false, err
+}
+
+// checks whether the given balances are too low to transfer any of the given amounts. Currencies which we don't have
+// a balance for might still be transferable, so the balance is only insufficient if there are no such currencies and
+// every one we do have a balance for is less than its amount. Returns the first insufficient currency and balance.
+func insufficientBalance(balances, amounts map[string]decimal.Decimal) (string, decimal.Decimal, bool) {
+	currencies := make([]string, 0, len(amounts))
+	for currency := range amounts {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	var firstCurrency string
+	var firstBalance decimal.Decimal
+
+	for _, currency := range currencies {
+		balance, known := balances[currency]
+		if !known || balance.GreaterThanOrEqual(amounts[currency]) {
+			return "", decimal.Zero, false
+		}
+		if firstCurrency == "" {
+			firstCurrency, firstBalance = currency, balance
+		}
+	}
+
+	return firstCurrency, firstBalance, firstCurrency != ""
 }
 
 func (a *TransferAirtimeAction) saveSuccess(run flows.Run, step flows.Step, transfer *flows.AirtimeTransfer, logEvent flows.EventCallback) {
@@ -0,0 +1,61 @@
+package actions
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+func init() {
+	registerType(TypeTransferCall, func() flows.Action { return &TransferCallAction{} })
+}
+
+// TypeTransferCall is the type for the transfer call action
+const TypeTransferCall string = "transfer_call"
+
+// TransferCallAction can be used to hand off the call in a voice flow to another phone number. Unlike
+// [action:forward_call], the call doesn't return to the flow so this should be the last action the contact takes
+// in the flow. A [event:call_transfer_requested] event will be created which the caller should act on once the
+// sprint has ended.
+//
+//	{
+//	  "uuid": "8eebd020-1af5-431c-b943-aa670fc74da9",
+//	  "type": "transfer_call",
+//	  "phone": "+12065551313"
+//	}
+//
+// @action transfer_call
+type TransferCallAction struct {
+	baseAction
+	voiceAction
+
+	Phone string `json:"phone" validate:"required" engine:"evaluated"`
+}
+
+// NewTransferCall creates a new transfer call action
+func NewTransferCall(uuid flows.ActionUUID, phone string) *TransferCallAction {
+	return &TransferCallAction{
+		baseAction: newBaseAction(TypeTransferCall, uuid),
+		Phone:      phone,
+	}
+}
+
+// Execute runs this action
+func (a *TransferCallAction) Execute(ctx context.Context, run flows.Run, step flows.Step, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	phone, err := run.EvaluateTemplate(a.Phone)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+
+	urn, err := urns.NewTelURNForCountry(strings.TrimSpace(phone), string(run.Environment().DefaultCountry()))
+	if err != nil {
+		logEvent(events.NewError(err))
+		return nil
+	}
+
+	logEvent(events.NewCallTransferRequested(urn))
+	return nil
+}
@@ -0,0 +1,56 @@
+package flows
+
+import (
+	"golang.org/x/exp/slices"
+)
+
+// Capabilities describes what an engine supports. Editors and servers exchange these when connecting to an engine so
+// that deployments where they're running different versions can avoid the features which the other side doesn't have.
+type Capabilities struct {
+	SpecVersions []string      `json:"spec_versions"`
+	ActionTypes  []string      `json:"action_types"`
+	ResumeTypes  []string      `json:"resume_types"`
+	Services     []ServiceType `json:"services"`
+}
+
+// SupportsSpecVersion returns whether flow definitions with the given spec version can be read
+func (c *Capabilities) SupportsSpecVersion(version string) bool {
+	return slices.Contains(c.SpecVersions, version)
+}
+
+// SupportsActionType returns whether flows can use actions of the given type
+func (c *Capabilities) SupportsActionType(typeName string) bool {
+	return slices.Contains(c.ActionTypes, typeName)
+}
+
+// SupportsResumeType returns whether sessions can be resumed with resumes of the given type
+func (c *Capabilities) SupportsResumeType(typeName string) bool {
+	return slices.Contains(c.ResumeTypes, typeName)
+}
+
+// HasService returns whether the given service has been configured
+func (c *Capabilities) HasService(service ServiceType) bool {
+	return slices.Contains(c.Services, service)
+}
+
+// Negotiate returns the capabilities which both these and the given capabilities have, i.e. what can be used when
+// talking to the other side
+func (c *Capabilities) Negotiate(other *Capabilities) *Capabilities {
+	return &Capabilities{
+		SpecVersions: intersect(c.SpecVersions, other.SpecVersions),
+		ActionTypes:  intersect(c.ActionTypes, other.ActionTypes),
+		ResumeTypes:  intersect(c.ResumeTypes, other.ResumeTypes),
+		Services:     intersect(c.Services, other.Services),
+	}
+}
+
+// returns the items of a which are also in b, in the order of a
+func intersect[T comparable](a, b []T) []T {
+	both := make([]T, 0, len(a))
+	for _, i := range a {
+		if slices.Contains(b, i) {
+			both = append(both, i)
+		}
+	}
+	return both
+}
@@ -11,6 +11,15 @@ import (
 	"github.com/nyaruka/goflow/utils"
 )
 
+func init() {
+	RegisterContextType("channel",
+		NewContextProperty("__default__", "text", "the name"),
+		NewContextProperty("uuid", "text", "the UUID of the channel"),
+		NewContextProperty("name", "text", "the name of the channel"),
+		NewContextProperty("address", "text", "the address of the channel"),
+	)
+}
+
 // Channel represents a means for sending and receiving input during a flow run
 type Channel struct {
 	assets.Channel
@@ -52,19 +61,22 @@ func (c *Channel) HasRole(role assets.ChannelRole) bool {
 	return false
 }
 
+// HasFeature returns whether this channel supports the given feature
+func (c *Channel) HasFeature(feature assets.ChannelFeature) bool {
+	for _, f := range c.Features() {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
 // HasParent returns whether this channel has a parent
 func (c *Channel) HasParent() bool {
 	return c.Parent() != nil
 }
 
 // Context returns the properties available in expressions
-//
-//	__default__:text -> the name
-//	uuid:text -> the UUID of the channel
-//	name:text -> the name of the channel
-//	address:text -> the address of the channel
-//
-// @context channel
 func (c *Channel) Context(env envs.Environment) map[string]types.XValue {
 	return map[string]types.XValue{
 		"__default__": types.NewXText(c.Name()),
@@ -6,6 +6,7 @@ import (
 	"github.com/nyaruka/gocommon/urns"
 	"github.com/nyaruka/gocommon/uuids"
 	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/flows"
@@ -39,6 +40,17 @@ func TestChannel(t *testing.T) {
 	assert.Equal(t, assets.NewChannelReference(ch.UUID(), "Android"), ch.Reference())
 	assert.True(t, ch.HasRole(assets.ChannelRoleSend))
 	assert.False(t, ch.HasRole(assets.ChannelRoleCall))
+	assert.False(t, ch.HasFeature(assets.ChannelFeatureRichCards))
+
+	rcs := flows.NewChannel(&static.Channel{
+		UUID_:     "4bb288a0-7fca-4da1-abe8-59a593aff648",
+		Name_:     "RCS",
+		Schemes_:  []string{"tel"},
+		Roles_:    rolesDefault,
+		Features_: []assets.ChannelFeature{assets.ChannelFeatureRichCards, assets.ChannelFeatureSuggestions},
+	})
+	assert.True(t, rcs.HasFeature(assets.ChannelFeatureRichCards))
+	assert.False(t, rcs.HasFeature(assets.ChannelFeatureCarousels))
 
 	// nil object returns nil reference
 	assert.Nil(t, (*flows.Channel)(nil).Reference())
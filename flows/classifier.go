@@ -1,15 +1,48 @@
 package flows
 
-import "github.com/nyaruka/goflow/assets"
+import (
+	"sort"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	RegisterContextType("intent",
+		NewContextProperty("__default__", "text", "the name of the intent"),
+		NewContextProperty("name", "text", "the name of the intent"),
+		NewContextProperty("confidence", "number", "the confidence of the classifier in the intent"),
+		NewContextProperty("calibrated", "number", "the percentile of the confidence if the classifier is calibrated"),
+	)
+
+	RegisterContextType("intents",
+		NewContextProperty("__default__", "text", "the name of the top ranked intent"),
+		NewContextProperty("top", "intent", "the top ranked intent"),
+		NewContextArrayProperty("ranking", "intent", "all the intents, ranked by confidence"),
+	)
+}
 
 // Classifier represents an NLU classifier.
 type Classifier struct {
 	assets.Classifier
+
+	calibration []decimal.Decimal
 }
 
 // NewClassifier returns a new classifier object from the given classifier asset
 func NewClassifier(asset assets.Classifier) *Classifier {
-	return &Classifier{Classifier: asset}
+	// calibration only makes sense with at least the lowest and highest confidences
+	var calibration []decimal.Decimal
+	if len(asset.Calibration()) >= 2 {
+		calibration = make([]decimal.Decimal, len(asset.Calibration()))
+		copy(calibration, asset.Calibration())
+		sort.Slice(calibration, func(i, j int) bool { return calibration[i].LessThan(calibration[j]) })
+	}
+
+	return &Classifier{Classifier: asset, calibration: calibration}
 }
 
 // Asset returns the underlying asset
@@ -20,6 +53,71 @@ func (c *Classifier) Reference() *assets.ClassifierReference {
 	return assets.NewClassifierReference(c.UUID(), c.Name())
 }
 
+// Calibrate sets the calibrated confidence of each intent of the given classification if this classifier is calibrated
+func (c *Classifier) Calibrate(classification *Classification) {
+	if c.calibration == nil {
+		return
+	}
+	for i := range classification.Intents {
+		calibrated := c.percentile(classification.Intents[i].Confidence)
+		classification.Intents[i].Calibrated = &calibrated
+	}
+}
+
+// gets the percentile of the given confidence by interpolating between the confidences at the calibration percentiles
+func (c *Classifier) percentile(confidence decimal.Decimal) decimal.Decimal {
+	last := len(c.calibration) - 1
+
+	if confidence.LessThanOrEqual(c.calibration[0]) {
+		return decimal.Zero
+	}
+	if confidence.GreaterThanOrEqual(c.calibration[last]) {
+		return decimal.NewFromInt(1)
+	}
+
+	// find the last calibration point which isn't above the confidence
+	i := sort.Search(len(c.calibration), func(i int) bool { return c.calibration[i].GreaterThan(confidence) }) - 1
+	lower, upper := c.calibration[i], c.calibration[i+1]
+
+	fraction := confidence.Sub(lower).Div(upper.Sub(lower))
+	return decimal.NewFromInt(int64(i)).Add(fraction).Div(decimal.NewFromInt(int64(last))).Round(4)
+}
+
+// Context returns the properties available in expressions
+func (i *ExtractedIntent) Context(env envs.Environment) map[string]types.XValue {
+	var calibrated types.XValue
+	if i.Calibrated != nil {
+		calibrated = types.NewXNumber(*i.Calibrated)
+	}
+
+	return map[string]types.XValue{
+		"__default__": types.NewXText(i.Name),
+		"name":        types.NewXText(i.Name),
+		"confidence":  types.NewXNumber(i.Confidence),
+		"calibrated":  calibrated,
+	}
+}
+
+// Context returns the properties available in expressions
+func (c *Classification) Context(env envs.Environment) map[string]types.XValue {
+	ranking := make([]types.XValue, len(c.Intents))
+	for i := range c.Intents {
+		ranking[i] = Context(env, &c.Intents[i])
+	}
+
+	var top, name types.XValue
+	if len(c.Intents) > 0 {
+		top = ranking[0]
+		name = types.NewXText(c.Intents[0].Name)
+	}
+
+	return map[string]types.XValue{
+		"__default__": name,
+		"top":         top,
+		"ranking":     types.NewXArray(ranking...),
+	}
+}
+
 // ClassifierAssets provides access to all classifier assets
 type ClassifierAssets struct {
 	byUUID map[assets.ClassifierUUID]*Classifier
@@ -40,3 +138,18 @@ func NewClassifierAssets(classifiers []assets.Classifier) *ClassifierAssets {
 func (s *ClassifierAssets) Get(uuid assets.ClassifierUUID) *Classifier {
 	return s.byUUID[uuid]
 }
+
+// All returns all the classifiers sorted by name
+func (s *ClassifierAssets) All() []*Classifier {
+	all := make([]*Classifier, 0, len(s.byUUID))
+	for _, x := range s.byUUID {
+		all = append(all, x)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Name() != all[j].Name() {
+			return all[i].Name() < all[j].Name()
+		}
+		return all[i].UUID() < all[j].UUID()
+	})
+	return all
+}
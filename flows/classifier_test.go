@@ -0,0 +1,101 @@
+package flows_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifierCalibration(t *testing.T) {
+	d := decimal.RequireFromString
+
+	newClassification := func(confidences ...string) *flows.Classification {
+		c := &flows.Classification{}
+		for i, conf := range confidences {
+			c.Intents = append(c.Intents, flows.ExtractedIntent{Name: []string{"book_flight", "book_hotel", "other"}[i], Confidence: d(conf)})
+		}
+		return c
+	}
+
+	// an uncalibrated classifier leaves intents as they are
+	classifier := flows.NewClassifier(static.NewClassifier("37657cf7-5eab-4286-9cb0-bbf270587bad", "Booking", "wit", []string{"book_flight"}))
+	classification := newClassification("0.5")
+	classifier.Calibrate(classification)
+	assert.Nil(t, classification.Intents[0].Calibrated)
+
+	// calibrations are sorted so order doesn't matter
+	classifier = flows.NewClassifier(&static.Classifier{
+		UUID_:        "37657cf7-5eab-4286-9cb0-bbf270587bad",
+		Name_:        "Booking",
+		Type_:        "wit",
+		Calibration_: []decimal.Decimal{d("0.9"), d("0.1"), d("0.3"), d("0.3"), d("0.5")},
+	})
+
+	classification = newClassification("0.05", "0.1", "0.2")
+	classifier.Calibrate(classification)
+	assert.Equal(t, "0", classification.Intents[0].Calibrated.String())
+	assert.Equal(t, "0", classification.Intents[1].Calibrated.String())
+	assert.Equal(t, "0.125", classification.Intents[2].Calibrated.String())
+
+	classification = newClassification("0.3", "0.7", "0.95")
+	classifier.Calibrate(classification)
+	assert.Equal(t, "0.5", classification.Intents[0].Calibrated.String())
+	assert.Equal(t, "0.875", classification.Intents[1].Calibrated.String())
+	assert.Equal(t, "1", classification.Intents[2].Calibrated.String())
+
+	// a single calibration point isn't enough
+	classifier = flows.NewClassifier(&static.Classifier{UUID_: "37657cf7-5eab-4286-9cb0-bbf270587bad", Calibration_: []decimal.Decimal{d("0.5")}})
+	classification = newClassification("0.5")
+	classifier.Calibrate(classification)
+	assert.Nil(t, classification.Intents[0].Calibrated)
+}
+
+func TestClassificationContext(t *testing.T) {
+	env := envs.NewBuilder().Build()
+	calibrated := decimal.RequireFromString("0.875")
+
+	classification := &flows.Classification{
+		Intents: []flows.ExtractedIntent{
+			{Name: "book_flight", Confidence: decimal.RequireFromString("0.7"), Calibrated: &calibrated},
+			{Name: "book_hotel", Confidence: decimal.RequireFromString("0.2")},
+		},
+	}
+
+	test.AssertXEqual(t, types.NewXObject(map[string]types.XValue{
+		"__default__": types.NewXText("book_flight"),
+		"top": types.NewXObject(map[string]types.XValue{
+			"__default__": types.NewXText("book_flight"),
+			"name":        types.NewXText("book_flight"),
+			"confidence":  types.RequireXNumberFromString("0.7"),
+			"calibrated":  types.RequireXNumberFromString("0.875"),
+		}),
+		"ranking": types.NewXArray(
+			types.NewXObject(map[string]types.XValue{
+				"__default__": types.NewXText("book_flight"),
+				"name":        types.NewXText("book_flight"),
+				"confidence":  types.RequireXNumberFromString("0.7"),
+				"calibrated":  types.RequireXNumberFromString("0.875"),
+			}),
+			types.NewXObject(map[string]types.XValue{
+				"__default__": types.NewXText("book_hotel"),
+				"name":        types.NewXText("book_hotel"),
+				"confidence":  types.RequireXNumberFromString("0.2"),
+				"calibrated":  nil,
+			}),
+		),
+	}), flows.Context(env, classification))
+
+	// a classification without intents has no top intent
+	test.AssertXEqual(t, types.NewXObject(map[string]types.XValue{
+		"__default__": nil,
+		"top":         nil,
+		"ranking":     types.NewXArray(),
+	}), flows.Context(env, &flows.Classification{}))
+}
@@ -24,6 +24,27 @@ func init() {
 	utils.RegisterValidatorAlias("contact_status", "eq=active|eq=blocked|eq=stopped|eq=archived", func(validator.FieldError) string {
 		return "is not a valid contact status"
 	})
+
+	RegisterContextType("contact",
+		NewContextProperty("__default__", "text", "the name or URN"),
+		NewContextProperty("uuid", "text", "the UUID of the contact"),
+		NewContextProperty("id", "text", "the numeric ID of the contact"),
+		NewContextProperty("first_name", "text", "the first name of the contact"),
+		NewContextProperty("name", "text", "the name of the contact"),
+		NewContextProperty("language", "text", "the language of the contact as 3-letter ISO code"),
+		NewContextProperty("timezone", "text", "the timezone of the contact"),
+		NewContextProperty("status", "text", "the status of the contact"),
+		NewContextProperty("created_on", "datetime", "the creation date of the contact"),
+		NewContextProperty("last_seen_on", "any", "the last seen date of the contact"),
+		NewContextArrayProperty("urns", "text", "the URNs belonging to the contact"),
+		NewContextProperty("urn", "text", "the preferred URN of the contact"),
+		NewContextArrayProperty("groups", "group", "the groups the contact belongs to"),
+		NewContextProperty("fields", "fields", "the custom field values of the contact"),
+		NewContextProperty("fields_changed_on", "any", "when custom field values were last changed in this session, by field key"),
+		NewContextProperty("relations", "relations", "the related contacts of the contact, by relation type"),
+		NewContextProperty("channel", "channel", "the preferred channel of the contact"),
+		NewContextArrayProperty("tickets", "ticket", "the open tickets of the contact"),
+	)
 }
 
 // ContactStatus is status in which a contact is in
@@ -56,8 +77,12 @@ type Contact struct {
 	urns       URNList
 	groups     *GroupList
 	fields     FieldValues
+	relations  Relations
 	tickets    *TicketList
 
+	// when field values were changed in the current session, by field key
+	fieldChanges map[string]time.Time
+
 	// transient fields
 	assets SessionAssets
 }
@@ -100,6 +125,7 @@ func NewContact(
 		urns:       urnList,
 		groups:     groupList,
 		fields:     fieldValues,
+		relations:  NewRelations(sa, nil, missing),
 		tickets:    ticketList,
 		assets:     sa,
 	}, nil
@@ -118,6 +144,7 @@ func NewEmptyContact(sa SessionAssets, name string, language envs.Language, time
 		urns:       URNList{},
 		groups:     NewGroupList(sa, nil, assets.IgnoreMissing),
 		fields:     make(FieldValues),
+		relations:  make(Relations),
 		tickets:    NewTicketList([]*Ticket{}),
 		assets:     sa,
 	}
@@ -141,11 +168,25 @@ func (c *Contact) Clone() *Contact {
 		urns:       c.urns.clone(),
 		groups:     c.groups.clone(),
 		fields:     c.fields.clone(),
+		relations:  c.relations.clone(),
 		tickets:    c.tickets.clone(),
 		assets:     c.assets,
+
+		fieldChanges: c.cloneFieldChanges(),
 	}
 }
 
+func (c *Contact) cloneFieldChanges() map[string]time.Time {
+	if c.fieldChanges == nil {
+		return nil
+	}
+	clone := make(map[string]time.Time, len(c.fieldChanges))
+	for k, t := range c.fieldChanges {
+		clone[k] = t
+	}
+	return clone
+}
+
 // Equal returns true if this instance is equal to the given instance
 func (c *Contact) Equal(other *Contact) bool {
 	asJSON1, _ := jsonx.Marshal(c)
@@ -235,6 +276,28 @@ func (c *Contact) ClearURNs() bool {
 	return hadURNS
 }
 
+// NormalizeURNs normalizes the URNs on this contact, calling the given function for each URN whose raw value was
+// changed. Any URNs which become duplicates of earlier ones are removed.
+func (c *Contact) NormalizeURNs(env envs.Environment, changed func(original, normalized urns.URN)) {
+	normalized := make(URNList, 0, len(c.urns))
+	seen := make(map[urns.URN]bool, len(c.urns))
+
+	for _, u := range c.urns {
+		urn := NormalizeURN(env, u.URN())
+		if urn != u.URN() {
+			changed(u.URN(), urn)
+			u = NewContactURN(urn, u.Channel())
+		}
+
+		if !seen[urn.Identity()] {
+			seen[urn.Identity()] = true
+			normalized = append(normalized, u)
+		}
+	}
+
+	c.urns = normalized
+}
+
 // AddURN adds a new URN to this contact
 func (c *Contact) AddURN(urn urns.URN, channel *Channel) bool {
 	if c.HasURN(urn) {
@@ -277,6 +340,20 @@ func (c *Contact) HasURN(urn urns.URN) bool {
 // Fields returns this contact's field values
 func (c *Contact) Fields() FieldValues { return c.fields }
 
+// FieldChanges returns when field values were last changed in the current session, by field key
+func (c *Contact) FieldChanges() map[string]time.Time { return c.fieldChanges }
+
+// RecordFieldChange records that the value of the given field was changed at the given time
+func (c *Contact) RecordFieldChange(field *Field, on time.Time) {
+	if c.fieldChanges == nil {
+		c.fieldChanges = make(map[string]time.Time)
+	}
+	c.fieldChanges[field.Key()] = on
+}
+
+// Relations returns this contact's relations to other contacts
+func (c *Contact) Relations() Relations { return c.relations }
+
 // Groups returns the groups that this contact belongs to
 func (c *Contact) Groups() *GroupList { return c.groups }
 
@@ -303,72 +380,88 @@ func (c *Contact) Format(env envs.Environment) string {
 		return strconv.Itoa(int(c.id))
 	}
 	if len(c.urns) > 0 {
-		return c.urns[0].URN().Format()
+		return utils.FormatURN(c.urns[0].URN())
 	}
 
 	return ""
 }
 
 // Context returns the properties available in expressions
-//
-//	__default__:text -> the name or URN
-//	uuid:text -> the UUID of the contact
-//	id:text -> the numeric ID of the contact
-//	first_name:text -> the first name of the contact
-//	name:text -> the name of the contact
-//	language:text -> the language of the contact as 3-letter ISO code
-//	status:text -> the status of the contact
-//	created_on:datetime -> the creation date of the contact
-//	last_seen_on:any -> the last seen date of the contact
-//	urns:[]text -> the URNs belonging to the contact
-//	urn:text -> the preferred URN of the contact
-//	groups:[]group -> the groups the contact belongs to
-//	fields:fields -> the custom field values of the contact
-//	channel:channel -> the preferred channel of the contact
-//	tickets:[]ticket -> the open tickets of the contact
-//
-// @context contact
 func (c *Contact) Context(env envs.Environment) map[string]types.XValue {
-	var firstName, urn, timezone, lastSeenOn types.XValue
-
-	if c.timezone != nil {
-		timezone = types.NewXText(c.timezone.String())
-	}
-
-	preferredURN := c.PreferredURN()
-	if preferredURN != nil {
-		urn = preferredURN.ToXValue(env)
-	}
-
-	names := utils.TokenizeString(c.name)
-	if len(names) >= 1 {
-		firstName = types.NewXText(names[0])
-	}
-
-	if c.lastSeenOn != nil {
-		lastSeenOn = types.NewXDateTime(*c.lastSeenOn)
-	}
-
-	return map[string]types.XValue{
-		"__default__":  types.NewXText(c.Format(env)),
-		"uuid":         types.NewXText(string(c.uuid)),
-		"id":           types.NewXText(strconv.Itoa(int(c.id))),
-		"name":         types.NewXText(c.name),
-		"first_name":   firstName,
-		"language":     types.NewXText(string(c.language)),
-		"timezone":     timezone,
-		"status":       types.NewXText(string(c.status)),
-		"created_on":   types.NewXDateTime(c.createdOn),
-		"last_seen_on": lastSeenOn,
-		"urns":         c.urns.ToXValue(env),
-		"urn":          urn,
-		"groups":       c.groups.ToXValue(env),
-		"fields":       Context(env, c.Fields()),
-		"channel":      Context(env, c.PreferredChannel()),
-		"tickets":      c.tickets.ToXValue(env),
+	context := make(map[string]types.XValue, len(contactContextKeys))
+	for _, key := range contactContextKeys {
+		context[key] = c.ContextProperty(env, key)
+	}
+	return context
+}
+
+var contactContextKeys = []string{
+	"__default__", "uuid", "id", "name", "first_name", "language", "timezone", "status", "created_on", "last_seen_on",
+	"urns", "urn", "groups", "fields", "fields_changed_on", "relations", "channel", "tickets",
+}
+
+// ContextKeys returns the names of the properties available in expressions
+func (c *Contact) ContextKeys() []string { return contactContextKeys }
+
+// ContextProperty returns a single property available in expressions, so that lookups like @contact.name don't
+// have to build the contact's URNs, groups and fields
+func (c *Contact) ContextProperty(env envs.Environment, key string) types.XValue {
+	switch key {
+	case "__default__":
+		return types.NewXText(c.Format(env))
+	case "uuid":
+		return types.NewXText(string(c.uuid))
+	case "id":
+		return types.NewXText(strconv.Itoa(int(c.id)))
+	case "name":
+		return types.NewXText(c.name)
+	case "first_name":
+		names := utils.TokenizeString(c.name)
+		if len(names) >= 1 {
+			return types.NewXText(names[0])
+		}
+	case "language":
+		return types.NewXText(string(c.language))
+	case "timezone":
+		if c.timezone != nil {
+			return types.NewXText(c.timezone.String())
+		}
+	case "status":
+		return types.NewXText(string(c.status))
+	case "created_on":
+		return types.NewXDateTime(c.createdOn)
+	case "last_seen_on":
+		if c.lastSeenOn != nil {
+			return types.NewXDateTime(*c.lastSeenOn)
+		}
+	case "urns":
+		return c.urns.ToXValue(env)
+	case "urn":
+		if preferredURN := c.PreferredURN(); preferredURN != nil {
+			return preferredURN.ToXValue(env)
+		}
+	case "groups":
+		return c.groups.ToXValue(env)
+	case "fields":
+		return Context(env, c.Fields())
+	case "fields_changed_on":
+		fieldsChangedOn := make(map[string]types.XValue, len(c.fieldChanges))
+		for k, t := range c.fieldChanges {
+			fieldsChangedOn[k] = types.NewXDateTime(t)
+		}
+		return types.NewXObject(fieldsChangedOn)
+	case "relations":
+		return Context(env, c.Relations())
+	case "channel":
+		return Context(env, c.PreferredChannel())
+	case "tickets":
+		return c.tickets.ToXValue(env)
 	}
+	return nil
 }
 
+var _ KeyedContextable = (*Contact)(nil)
+
 // Destination is a sendable channel and URN pair
 type Destination struct {
 	Channel *Channel
@@ -452,17 +545,25 @@ func (c *Contact) UpdatePreferredChannel(channel *Channel) bool {
 	return !oldURNs.Equal(c.urns)
 }
 
-// ReevaluateQueryBasedGroups reevaluates membership of all query based groups for this contact
-func (c *Contact) ReevaluateQueryBasedGroups(env envs.Environment) ([]*Group, []*Group) {
+// ReevaluateQueryBasedGroups reevaluates membership of all query based groups for this contact using the given
+// service. Groups for which the service errors are left as they are, and the first such error is returned.
+func (c *Contact) ReevaluateQueryBasedGroups(env envs.Environment, svc GroupMembershipService) ([]*Group, []*Group, error) {
 	added := make([]*Group, 0)
 	removed := make([]*Group, 0)
+	var firstErr error
 
 	for _, group := range c.assets.Groups().All() {
 		if !group.UsesQuery() {
 			continue
 		}
 
-		qualifies := group.CheckQueryBasedMembership(env, c)
+		qualifies, err := svc.Qualifies(env, group, c)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "unable to evaluate membership of group '%s'", group.Name())
+			}
+			continue
+		}
 
 		if qualifies {
 			if c.groups.Add(group) {
@@ -475,7 +576,7 @@ func (c *Contact) ReevaluateQueryBasedGroups(env envs.Environment) ([]*Group, []
 		}
 	}
 
-	return added, removed
+	return added, removed, firstErr
 }
 
 // QueryProperty resolves a contact query search key for this contact
@@ -572,20 +673,23 @@ var _ assets.Reference = (*ContactReference)(nil)
 //------------------------------------------------------------------------------------------
 
 type contactEnvelope struct {
-	UUID       ContactUUID              `json:"uuid"                validate:"required,uuid4"`
-	ID         ContactID                `json:"id,omitempty"`
-	Name       string                   `json:"name,omitempty"`
-	Language   envs.Language            `json:"language,omitempty"`
-	Status     ContactStatus            `json:"status,omitempty"    validate:"omitempty,contact_status"`
-	Stopped    bool                     `json:"stopped,omitempty"`
-	Blocked    bool                     `json:"blocked,omitempty"`
-	Timezone   string                   `json:"timezone,omitempty"`
-	CreatedOn  time.Time                `json:"created_on"          validate:"required"`
-	LastSeenOn *time.Time               `json:"last_seen_on,omitempty"`
-	URNs       []urns.URN               `json:"urns,omitempty"      validate:"dive,urn"`
-	Groups     []*assets.GroupReference `json:"groups,omitempty"    validate:"dive"`
-	Fields     map[string]*Value        `json:"fields,omitempty"`
-	Tickets    []json.RawMessage        `json:"tickets,omitempty"`
+	UUID       ContactUUID                  `json:"uuid"                validate:"required,uuid4"`
+	ID         ContactID                    `json:"id,omitempty"`
+	Name       string                       `json:"name,omitempty"`
+	Language   envs.Language                `json:"language,omitempty"`
+	Status     ContactStatus                `json:"status,omitempty"    validate:"omitempty,contact_status"`
+	Stopped    bool                         `json:"stopped,omitempty"`
+	Blocked    bool                         `json:"blocked,omitempty"`
+	Timezone   string                       `json:"timezone,omitempty"`
+	CreatedOn  time.Time                    `json:"created_on"          validate:"required"`
+	LastSeenOn *time.Time                   `json:"last_seen_on,omitempty"`
+	URNs       []urns.URN                   `json:"urns,omitempty"      validate:"dive,urn"`
+	Groups     []*assets.GroupReference     `json:"groups,omitempty"    validate:"dive"`
+	Fields     map[string]*Value            `json:"fields,omitempty"`
+	Relations  map[string]*ContactReference `json:"relations,omitempty" validate:"dive"`
+	Tickets    []json.RawMessage            `json:"tickets,omitempty"`
+
+	FieldChanges map[string]time.Time `json:"field_changes,omitempty"`
 }
 
 // ReadContact decodes a contact from the passed in JSON
@@ -606,6 +710,8 @@ func ReadContact(sa SessionAssets, data json.RawMessage, missing assets.MissingC
 		createdOn:  envelope.CreatedOn,
 		lastSeenOn: envelope.LastSeenOn,
 		assets:     sa,
+
+		fieldChanges: envelope.FieldChanges,
 	}
 
 	// it's possible older sessions won't have contact status
@@ -629,6 +735,7 @@ func ReadContact(sa SessionAssets, data json.RawMessage, missing assets.MissingC
 
 	c.groups = NewGroupList(sa, envelope.Groups, missing)
 	c.fields = NewFieldValues(sa, envelope.Fields, missing)
+	c.relations = NewRelations(sa, envelope.Relations, missing)
 
 	tickets := make([]*Ticket, len(envelope.Tickets))
 	for i := range envelope.Tickets {
@@ -664,6 +771,8 @@ func (c *Contact) MarshalJSON() ([]byte, error) {
 		URNs:       c.urns.RawURNs(),
 		Groups:     c.groups.references(),
 		Tickets:    tickets,
+
+		FieldChanges: c.fieldChanges,
 	}
 
 	if c.timezone != nil {
@@ -677,5 +786,12 @@ func (c *Contact) MarshalJSON() ([]byte, error) {
 		}
 	}
 
+	ce.Relations = make(map[string]*ContactReference)
+	for k, v := range c.relations {
+		if v != nil {
+			ce.Relations[k] = v.contact
+		}
+	}
+
 	return jsonx.Marshal(ce)
 }
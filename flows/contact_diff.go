@@ -0,0 +1,134 @@
+package flows
+
+import (
+	"sort"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+)
+
+// ContactDiff is the set of changes which turn one version of a contact into another
+type ContactDiff struct {
+	Name          *string                      `json:"name,omitempty"`
+	Language      *envs.Language               `json:"language,omitempty"`
+	Status        ContactStatus                `json:"status,omitempty"`
+	URNsAdded     []urns.URN                   `json:"urns_added,omitempty"`
+	URNsRemoved   []urns.URN                   `json:"urns_removed,omitempty"`
+	GroupsAdded   []*assets.GroupReference     `json:"groups_added,omitempty"`
+	GroupsRemoved []*assets.GroupReference     `json:"groups_removed,omitempty"`
+	Fields        map[string]*Value            `json:"fields,omitempty"`    // nil values are fields which were cleared
+	Relations     map[string]*ContactReference `json:"relations,omitempty"` // nil values are relations which were cleared
+}
+
+// IsEmpty returns whether this diff contains no changes
+func (d *ContactDiff) IsEmpty() bool {
+	return d.Name == nil && d.Language == nil && d.Status == "" &&
+		len(d.URNsAdded) == 0 && len(d.URNsRemoved) == 0 &&
+		len(d.GroupsAdded) == 0 && len(d.GroupsRemoved) == 0 &&
+		len(d.Fields) == 0 && len(d.Relations) == 0
+}
+
+// Diff returns the changes which would turn this contact into the other contact. URNs are compared by identity
+// and groups by UUID, so changes to URN priority or group names aren't included.
+func (c *Contact) Diff(other *Contact) *ContactDiff {
+	diff := &ContactDiff{}
+
+	if c.name != other.name {
+		diff.Name = &other.name
+	}
+	if c.language != other.language {
+		diff.Language = &other.language
+	}
+	if c.status != other.status {
+		diff.Status = other.status
+	}
+
+	diff.URNsAdded = urnsNotIn(other.urns, c.urns)
+	diff.URNsRemoved = urnsNotIn(c.urns, other.urns)
+	diff.GroupsAdded = groupsNotIn(other.groups, c.groups)
+	diff.GroupsRemoved = groupsNotIn(c.groups, other.groups)
+
+	keys := make(map[string]bool, len(c.fields))
+	for k := range c.fields {
+		keys[k] = true
+	}
+	for k := range other.fields {
+		keys[k] = true
+	}
+
+	for k := range keys {
+		before, after := c.fields[k], other.fields[k]
+		var beforeVal, afterVal *Value
+		if before != nil {
+			beforeVal = before.Value
+		}
+		if after != nil {
+			afterVal = after.Value
+		}
+
+		if !beforeVal.Equals(afterVal) {
+			if diff.Fields == nil {
+				diff.Fields = make(map[string]*Value)
+			}
+			diff.Fields[k] = afterVal
+		}
+	}
+
+	relationKeys := make(map[string]bool, len(c.relations))
+	for k := range c.relations {
+		relationKeys[k] = true
+	}
+	for k := range other.relations {
+		relationKeys[k] = true
+	}
+
+	for k := range relationKeys {
+		before, after := c.relations[k], other.relations[k]
+		var beforeContact, afterContact *ContactReference
+		if before != nil {
+			beforeContact = before.contact
+		}
+		if after != nil {
+			afterContact = after.contact
+		}
+
+		if (beforeContact == nil) != (afterContact == nil) || (beforeContact != nil && beforeContact.UUID != afterContact.UUID) {
+			if diff.Relations == nil {
+				diff.Relations = make(map[string]*ContactReference)
+			}
+			diff.Relations[k] = afterContact
+		}
+	}
+
+	return diff
+}
+
+// gets the URNs in l1 whose identities aren't in l2
+func urnsNotIn(l1, l2 URNList) []urns.URN {
+	identities := make(map[urns.URN]bool, len(l2))
+	for _, u := range l2 {
+		identities[u.URN().Identity()] = true
+	}
+
+	var missing []urns.URN
+	for _, u := range l1 {
+		if !identities[u.URN().Identity()] {
+			missing = append(missing, u.URN())
+		}
+	}
+	return missing
+}
+
+// gets references to the groups in l1 which aren't in l2, sorted by name
+func groupsNotIn(l1, l2 *GroupList) []*assets.GroupReference {
+	var missing []*assets.GroupReference
+	for _, g := range l1.All() {
+		if l2.FindByUUID(g.UUID()) == nil {
+			missing = append(missing, g.Reference())
+		}
+	}
+
+	sort.SliceStable(missing, func(i, j int) bool { return missing[i].Name < missing[j].Name })
+	return missing
+}
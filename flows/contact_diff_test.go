@@ -0,0 +1,94 @@
+package flows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/modifiers"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContactDiff(t *testing.T) {
+	_, session, _ := test.NewSessionBuilder().MustBuild()
+	sa := session.Assets()
+
+	contact, err := flows.ReadContact(sa, []byte(`{
+		"uuid": "ba96bf7f-bc2a-4873-a7c7-254d1927c4e3",
+		"name": "Ben Haggerty",
+		"language": "eng",
+		"status": "active",
+		"created_on": "2000-01-01T00:00:00.000000000-00:00",
+		"urns": ["tel:+12065551212", "twitter:ben"],
+		"groups": [
+			{"uuid": "b7cf0d83-f1c9-411c-96fd-c511a4cfa86d", "name": "Testers"},
+			{"uuid": "4f1f98fc-27a7-4a69-bbdb-24744ba739a9", "name": "Males"}
+		],
+		"fields": {
+			"gender": {"text": "Male"},
+			"age": {"text": "37", "number": 37}
+		},
+		"relations": {
+			"caregiver": {"uuid": "0cb17b2a-3bfe-4a19-8c99-98ab9561045d", "name": "Sarah Haggerty"}
+		}
+	}`), assets.PanicOnMissing)
+	require.NoError(t, err)
+
+	// a contact has no differences with itself or a clone
+	assert.True(t, contact.Diff(contact).IsEmpty())
+	assert.True(t, contact.Diff(contact.Clone()).IsEmpty())
+
+	other := contact.Clone()
+	other.SetName("Ben")
+	other.SetLanguage("fra")
+	other.SetStatus(flows.ContactStatusStopped)
+	other.RemoveURN("twitter:ben")
+	other.AddURN("mailto:ben@example.com", nil)
+	other.AddURN("tel:+12065551212", nil) // already has this one
+	other.Groups().Remove(sa.Groups().Get("4f1f98fc-27a7-4a69-bbdb-24744ba739a9"))
+	other.Groups().Add(sa.Groups().Get("1e1ce1e1-9288-4504-869e-022d1003c72a"))
+	other.Fields().Set(sa.Fields().Get("gender"), nil)
+	other.Fields().Set(sa.Fields().Get("activation_token"), other.Fields().Parse(session.Environment(), sa.Fields(), sa.Fields().Get("activation_token"), "XYZ"))
+	other.Relations().Set(sa.RelationTypes().Get("caregiver"), nil)
+	other.Relations().Set(sa.RelationTypes().Get("household_head"), flows.NewContactReference("b5a6f4d2-0c37-4a4a-8d8e-e7c5ac5d1a62", "Jim Haggerty"))
+
+	diff := contact.Diff(other)
+	assert.False(t, diff.IsEmpty())
+
+	test.AssertEqualJSON(t, []byte(`{
+		"name": "Ben",
+		"language": "fra",
+		"status": "stopped",
+		"urns_added": ["mailto:ben@example.com"],
+		"urns_removed": ["twitter:ben"],
+		"groups_added": [{"uuid": "1e1ce1e1-9288-4504-869e-022d1003c72a", "name": "Customers"}],
+		"groups_removed": [{"uuid": "4f1f98fc-27a7-4a69-bbdb-24744ba739a9", "name": "Males"}],
+		"fields": {
+			"activation_token": {"text": "XYZ"},
+			"gender": null
+		},
+		"relations": {
+			"caregiver": null,
+			"household_head": {"uuid": "b5a6f4d2-0c37-4a4a-8d8e-e7c5ac5d1a62", "name": "Jim Haggerty"}
+		}
+	}`), jsonx.MustMarshal(diff), "diff mismatch")
+
+	// changing the name to empty is still a change
+	other = contact.Clone()
+	other.SetName("")
+	diff = contact.Diff(other)
+	require.NotNil(t, diff.Name)
+	assert.Equal(t, "", *diff.Name)
+
+	// can be used to check what a modifier changed
+	other = contact.Clone()
+	modifiers.NewName("Benny").Apply(context.Background(), session.Environment(), nil, sa, other, func(flows.Event) {})
+
+	newName := "Benny"
+	assert.Equal(t, &flows.ContactDiff{Name: &newName}, contact.Diff(other))
+}
@@ -1,6 +1,7 @@
 package flows_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -171,24 +172,32 @@ func TestContact(t *testing.T) {
 	assert.Nil(t, mrNil.Clone())
 
 	test.AssertXEqual(t, types.NewXObject(map[string]types.XValue{
-		"__default__":  types.NewXText("Joe Bloggs"),
-		"channel":      flows.Context(env, android),
-		"created_on":   types.NewXDateTime(contact.CreatedOn()),
-		"last_seen_on": types.NewXDateTime(*contact.LastSeenOn()),
-		"fields":       flows.Context(env, contact.Fields()),
-		"first_name":   types.NewXText("Joe"),
-		"groups":       contact.Groups().ToXValue(env),
-		"id":           types.NewXText("12345"),
-		"language":     types.NewXText("eng"),
-		"name":         types.NewXText("Joe Bloggs"),
-		"tickets":      contact.Tickets().ToXValue(env),
-		"timezone":     types.NewXText("America/Bogota"),
-		"status":       types.NewXText(string(contact.Status())),
-		"urn":          contact.URNs()[0].ToXValue(env),
-		"urns":         contact.URNs().ToXValue(env),
-		"uuid":         types.NewXText(string(contact.UUID())),
+		"__default__":       types.NewXText("Joe Bloggs"),
+		"channel":           flows.Context(env, android),
+		"created_on":        types.NewXDateTime(contact.CreatedOn()),
+		"last_seen_on":      types.NewXDateTime(*contact.LastSeenOn()),
+		"fields":            flows.Context(env, contact.Fields()),
+		"fields_changed_on": types.XObjectEmpty,
+		"relations":         flows.Context(env, contact.Relations()),
+		"first_name":        types.NewXText("Joe"),
+		"groups":            contact.Groups().ToXValue(env),
+		"id":                types.NewXText("12345"),
+		"language":          types.NewXText("eng"),
+		"name":              types.NewXText("Joe Bloggs"),
+		"tickets":           contact.Tickets().ToXValue(env),
+		"timezone":          types.NewXText("America/Bogota"),
+		"status":            types.NewXText(string(contact.Status())),
+		"urn":               contact.URNs()[0].ToXValue(env),
+		"urns":              contact.URNs().ToXValue(env),
+		"uuid":              types.NewXText(string(contact.UUID())),
 	}), flows.Context(env, contact))
 
+	// properties can also be resolved individually
+	firstName, _ := flows.Context(env, contact).(*types.XObject).Get("first_name")
+	assert.Equal(t, types.NewXText("Joe"), firstName)
+	assert.Equal(t, types.NewXText("America/Bogota"), contact.ContextProperty(env, "timezone"))
+	assert.Nil(t, contact.ContextProperty(env, "xxx"))
+
 	assert.True(t, contact.ClearURNs()) // did have URNs
 	assert.False(t, contact.ClearURNs())
 	assert.Equal(t, flows.URNList{}, contact.URNs())
@@ -350,7 +359,7 @@ func TestReevaluateQueryBasedGroups(t *testing.T) {
 		).Manual().Build()
 
 		eng := engine.NewBuilder().Build()
-		session, _, _ := eng.NewSession(sa, trigger)
+		session, _, _ := eng.NewSession(context.Background(), sa, trigger)
 		afterJSON := jsonx.MustMarshal(session.Contact())
 
 		test.AssertEqualJSON(t, tc.ContactAfter, afterJSON, "contact JSON mismatch in '%s'", tc.Description)
@@ -396,6 +405,47 @@ func TestContactEqual(t *testing.T) {
 	assert.False(t, contact1.Equal(contact2))
 }
 
+func TestContactFieldChanges(t *testing.T) {
+	_, session, _ := test.NewSessionBuilder().MustBuild()
+
+	contact, err := flows.ReadContact(session.Assets(), []byte(`{
+		"uuid": "ba96bf7f-bc2a-4873-a7c7-254d1927c4e3",
+		"created_on": "2000-01-01T00:00:00.000000000-00:00",
+		"field_changes": {"gender": "2018-04-11T13:24:30.123456Z"}
+	}`), assets.PanicOnMissing)
+	require.NoError(t, err)
+
+	gender := session.Assets().Fields().Get("gender")
+	age := session.Assets().Fields().Get("age")
+	changedOn := time.Date(2018, 4, 12, 10, 0, 0, 0, time.UTC)
+
+	clone := contact.Clone()
+	contact.RecordFieldChange(age, changedOn)
+
+	assert.Equal(t, map[string]time.Time{
+		"gender": time.Date(2018, 4, 11, 13, 24, 30, 123456000, time.UTC),
+		"age":    changedOn,
+	}, contact.FieldChanges())
+	assert.Len(t, clone.FieldChanges(), 1) // clones have their own changes
+
+	clone.RecordFieldChange(gender, changedOn)
+	assert.Equal(t, map[string]time.Time{"gender": changedOn}, clone.FieldChanges())
+
+	// changes are available in the context
+	fieldsChangedOn, _ := flows.Context(session.Environment(), contact).(*types.XObject).Get("fields_changed_on")
+	test.AssertXEqual(t, types.NewXObject(map[string]types.XValue{
+		"gender": types.NewXDateTime(time.Date(2018, 4, 11, 13, 24, 30, 123456000, time.UTC)),
+		"age":    types.NewXDateTime(changedOn),
+	}), fieldsChangedOn)
+
+	// and survive a JSON round trip
+	marshaled, err := jsonx.Marshal(contact)
+	require.NoError(t, err)
+	contact, err = flows.ReadContact(session.Assets(), marshaled, assets.PanicOnMissing)
+	require.NoError(t, err)
+	assert.Len(t, contact.FieldChanges(), 2)
+}
+
 func TestContactQuery(t *testing.T) {
 	_, session, _ := test.NewSessionBuilder().MustBuild()
 
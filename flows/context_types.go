@@ -0,0 +1,81 @@
+package flows
+
+import (
+	"sort"
+)
+
+// ContextProperty describes a property of a type in the expression context
+type ContextProperty struct {
+	Key    string
+	Type   string
+	Array  bool
+	Help   string
+	Hidden bool
+}
+
+// NewContextProperty creates a new documented property of the given type
+func NewContextProperty(key, type_, help string) *ContextProperty {
+	return &ContextProperty{Key: key, Type: type_, Help: help}
+}
+
+// NewContextArrayProperty creates a new documented property which is an array of the given type
+func NewContextArrayProperty(key, type_, help string) *ContextProperty {
+	return &ContextProperty{Key: key, Type: type_, Array: true, Help: help}
+}
+
+// NewHiddenContextProperty creates a new property which exists in the context but isn't documented or offered for
+// autocompletion, e.g. because it's only kept for backwards compatibility
+func NewHiddenContextProperty(key, type_ string) *ContextProperty {
+	return &ContextProperty{Key: key, Type: type_, Hidden: true}
+}
+
+// ContextType describes a type in the expression context, i.e. the properties provided by a Context method
+type ContextType struct {
+	Name       string
+	Properties []*ContextProperty
+}
+
+// Keys returns the keys of all the properties of this type, including hidden ones
+func (t *ContextType) Keys() []string {
+	keys := make([]string, len(t.Properties))
+	for i, p := range t.Properties {
+		keys[i] = p.Key
+	}
+	return keys
+}
+
+// Documented returns the properties of this type which aren't hidden
+func (t *ContextType) Documented() []*ContextProperty {
+	props := make([]*ContextProperty, 0, len(t.Properties))
+	for _, p := range t.Properties {
+		if !p.Hidden {
+			props = append(props, p)
+		}
+	}
+	return props
+}
+
+var registeredContextTypes = map[string]*ContextType{}
+
+// RegisterContextType registers the properties of a type in the expression context. These are the source for the
+// context documentation and autocompletion, so should be registered alongside the Context method which provides them.
+func RegisterContextType(name string, props ...*ContextProperty) *ContextType {
+	t := &ContextType{Name: name, Properties: props}
+	registeredContextTypes[name] = t
+	return t
+}
+
+// GetContextType gets the registered context type with the given name
+func GetContextType(name string) *ContextType {
+	return registeredContextTypes[name]
+}
+
+// RegisteredContextTypes returns all the registered context types sorted by name
+func RegisteredContextTypes() []*ContextType {
+	types := make([]*ContextType, 0, len(registeredContextTypes))
+	for _, t := range registeredContextTypes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types
+}
@@ -0,0 +1,85 @@
+package flows_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextTypes(t *testing.T) {
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	dates.SetNowSource(dates.NewSequentialNowSource(time.Date(2018, 4, 11, 13, 24, 30, 123456000, time.UTC)))
+
+	session, _, err := test.CreateTestSession("", envs.RedactionPolicyNone)
+	require.NoError(t, err)
+
+	run := session.Runs()[0]
+	root := types.NewXObject(run.RootContext(session.Environment()))
+
+	// check that the keys of every registered type we can find in a real context match the registered properties
+	checked := make(map[string]bool)
+	checkContextType(t, checked, "root", root)
+
+	assert.True(t, checked["root"])
+	assert.True(t, checked["contact"])
+	assert.True(t, checked["run"])
+	assert.True(t, checked["result"])
+
+	names := make([]string, 0)
+	for _, ct := range flows.RegisteredContextTypes() {
+		names = append(names, ct.Name)
+	}
+	assert.True(t, sort.StringsAreSorted(names))
+}
+
+func checkContextType(t *testing.T, checked map[string]bool, name string, value types.XValue) {
+	ct := flows.GetContextType(name)
+	obj, isObj := value.(*types.XObject)
+	if ct == nil || !isObj || checked[name] {
+		return
+	}
+	checked[name] = true
+
+	expected := make([]string, 0)
+	for _, k := range ct.Keys() {
+		if k != "__default__" {
+			expected = append(expected, k)
+		}
+	}
+	sort.Strings(expected)
+	actual := make([]string, 0)
+	for _, k := range obj.Properties() {
+		if k != "__default__" {
+			actual = append(actual, k)
+		}
+	}
+	assert.Equal(t, expected, actual, "context keys mismatch for type %s", name)
+
+	for _, p := range ct.Properties {
+		v, _ := obj.Get(p.Key)
+		if p.Array {
+			if arr, isArr := v.(*types.XArray); isArr && arr.Count() > 0 {
+				v = arr.Get(0)
+			} else {
+				continue
+			}
+		}
+		if results, isObj := v.(*types.XObject); isObj && p.Type == "results" {
+			for _, k := range results.Properties() {
+				r, _ := results.Get(k)
+				checkContextType(t, checked, "result", r)
+			}
+			continue
+		}
+		checkContextType(t, checked, p.Type, v)
+	}
+}
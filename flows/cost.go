@@ -0,0 +1,62 @@
+package flows
+
+import (
+	"github.com/nyaruka/goflow/assets"
+	"github.com/shopspring/decimal"
+)
+
+// Cost represents the unit cost of an operation that a flow can perform
+type Cost struct {
+	assets.Cost
+}
+
+// NewCost returns a new cost object from the given cost asset
+func NewCost(asset assets.Cost) *Cost {
+	return &Cost{Cost: asset}
+}
+
+// Asset returns the underlying asset
+func (c *Cost) Asset() assets.Cost { return c.Cost }
+
+// CostAssets provides access to all cost assets
+type CostAssets struct {
+	all []*Cost
+}
+
+// NewCostAssets creates a new set of cost assets
+func NewCostAssets(costs []assets.Cost) *CostAssets {
+	s := &CostAssets{all: make([]*Cost, len(costs))}
+	for i, asset := range costs {
+		s.all[i] = NewCost(asset)
+	}
+	return s
+}
+
+// All returns all the costs
+func (s *CostAssets) All() []*Cost {
+	return s.all
+}
+
+// UnitCost returns the cost of a single operation of the given type. If a channel is given, a cost specific to that
+// channel is preferred over one without a channel. Operations without a cost are free.
+func (s *CostAssets) UnitCost(type_ assets.CostType, channel *assets.ChannelReference) decimal.Decimal {
+	var general *Cost
+
+	for _, c := range s.all {
+		if c.Type() != type_ {
+			continue
+		}
+		if c.Channel() == nil {
+			if general == nil {
+				general = c
+			}
+		} else if channel != nil && c.Channel().UUID == channel.UUID {
+			return c.Amount()
+		}
+	}
+
+	if general != nil {
+		return general.Amount()
+	}
+	return decimal.Zero
+}
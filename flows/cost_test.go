@@ -0,0 +1,29 @@
+package flows_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCostAssets(t *testing.T) {
+	vonage := assets.NewChannelReference("58e9b092-fe42-4173-876c-ff45a14a24fe", "Vonage")
+	twilio := assets.NewChannelReference("57f1078f-88aa-46f4-a59a-948a5739c03d", "Twilio")
+
+	costs := flows.NewCostAssets([]assets.Cost{
+		static.NewCost(assets.CostTypeMsg, nil, decimal.RequireFromString("0.01")),
+		static.NewCost(assets.CostTypeMsg, vonage, decimal.RequireFromString("0.0075")),
+		static.NewCost(assets.CostTypeWebhook, nil, decimal.RequireFromString("0.002")),
+	})
+
+	assert.Len(t, costs.All(), 3)
+	assert.Equal(t, "0.0075", costs.UnitCost(assets.CostTypeMsg, vonage).String())
+	assert.Equal(t, "0.01", costs.UnitCost(assets.CostTypeMsg, twilio).String())
+	assert.Equal(t, "0.01", costs.UnitCost(assets.CostTypeMsg, nil).String())
+	assert.Equal(t, "0.002", costs.UnitCost(assets.CostTypeWebhook, vonage).String())
+	assert.Equal(t, "0", costs.UnitCost(assets.CostTypeEmail, nil).String())
+}
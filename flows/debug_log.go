@@ -0,0 +1,64 @@
+package flows
+
+import (
+	"sync"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/excellent/types"
+)
+
+// TemplateTrace is the evaluation of a single template during a sprint, along with where in the flow it was evaluated
+type TemplateTrace struct {
+	RunUUID  RunUUID         `json:"run_uuid"`
+	FlowUUID assets.FlowUUID `json:"flow_uuid"`
+	NodeUUID NodeUUID        `json:"node_uuid,omitempty"`
+	Template string          `json:"template"`
+	Value    types.XValue    `json:"value"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// DebugLog records the value of every template evaluated during a sprint, in the order they were evaluated, so that
+// flow authors can step through what a flow saw. Unlike events, traces aren't part of the session. A nil debug log is
+// valid and records nothing.
+type DebugLog struct {
+	mutex  sync.Mutex
+	traces []*TemplateTrace
+}
+
+// NewDebugLog creates a new empty debug log
+func NewDebugLog() *DebugLog {
+	return &DebugLog{traces: make([]*TemplateTrace, 0, 10)}
+}
+
+// Record adds the evaluation of the given template in the given run
+func (l *DebugLog) Record(run Run, template string, value types.XValue, err error) {
+	if l == nil {
+		return
+	}
+
+	trace := &TemplateTrace{RunUUID: run.UUID(), FlowUUID: run.FlowReference().UUID, Template: template, Value: value}
+
+	if path := run.Path(); len(path) > 0 {
+		trace.NodeUUID = path[len(path)-1].NodeUUID()
+	}
+	if err != nil {
+		trace.Error = err.Error()
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.traces = append(l.traces, trace)
+}
+
+// Traces returns the traces recorded so far
+func (l *DebugLog) Traces() []*TemplateTrace {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.traces
+}
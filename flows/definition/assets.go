@@ -11,7 +11,8 @@ import (
 
 // implemention of FlowAssets which provides lazy loading and validation of flows
 type flowAssets struct {
-	cache map[assets.FlowUUID]flows.Flow
+	cache   map[assets.FlowUUID]flows.Flow
+	loading map[assets.FlowUUID]*flowLoad
 
 	mutex  sync.Mutex
 	source assets.Source
@@ -19,37 +20,64 @@ type flowAssets struct {
 	migrationConfig *migrations.Config
 }
 
+// a flow which is being loaded, so that other callers which need it can wait for it rather than load it again
+type flowLoad struct {
+	done chan struct{}
+	flow flows.Flow
+	err  error
+}
+
 // NewFlowAssets creates a new flow assets
 func NewFlowAssets(source assets.Source, migrationConfig *migrations.Config) flows.FlowAssets {
 	return &flowAssets{
 		cache:           make(map[assets.FlowUUID]flows.Flow),
+		loading:         make(map[assets.FlowUUID]*flowLoad),
 		source:          source,
 		migrationConfig: migrationConfig,
 	}
 }
 
-// Get returns the flow with the given UUID
+// Get returns the flow with the given UUID. Different flows can be loaded concurrently.
 func (a *flowAssets) Get(uuid assets.FlowUUID) (flows.Flow, error) {
 	a.mutex.Lock()
-	defer a.mutex.Unlock()
 
-	flow := a.cache[uuid]
-	if flow != nil {
+	if flow := a.cache[uuid]; flow != nil {
+		a.mutex.Unlock()
 		return flow, nil
 	}
 
-	asset, err := a.source.FlowByUUID(uuid)
-	if err != nil {
-		return nil, err
+	// if this flow is already being loaded, wait for that
+	if load := a.loading[uuid]; load != nil {
+		a.mutex.Unlock()
+		<-load.done
+		return load.flow, load.err
 	}
 
-	flow, err = ReadAsset(asset, a.migrationConfig)
+	load := &flowLoad{done: make(chan struct{})}
+	a.loading[uuid] = load
+	a.mutex.Unlock()
+
+	load.flow, load.err = a.load(uuid)
+
+	a.mutex.Lock()
+	if load.err == nil {
+		a.cache[uuid] = load.flow
+	}
+	delete(a.loading, uuid)
+	a.mutex.Unlock()
+
+	close(load.done)
+
+	return load.flow, load.err
+}
+
+func (a *flowAssets) load(uuid assets.FlowUUID) (flows.Flow, error) {
+	asset, err := a.source.FlowByUUID(uuid)
 	if err != nil {
 		return nil, err
 	}
 
-	a.cache[flow.UUID()] = flow
-	return flow, nil
+	return ReadAsset(asset, a.migrationConfig)
 }
 
 // FindByName tries to find a flow with the given name
@@ -0,0 +1,126 @@
+package definition
+
+import (
+	"encoding/json"
+
+	"github.com/buger/jsonparser"
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+
+	"github.com/pkg/errors"
+)
+
+// CompiledFlowVersion is the version of the compiled flow format written by this library. Compiled flows written by
+// other versions have to be recompiled from their definitions.
+const CompiledFlowVersion = 1
+
+// CompiledFlow is a flow which has been validated, had all its templates parsed and all its router tests resolved, so
+// that it can be executed repeatedly without any of that being done again.
+type CompiledFlow struct {
+	*flow
+}
+
+// Compile compiles the given flow, returning an error if any of its templates can't be parsed or any of its router
+// tests don't exist. The given flow isn't modified.
+func Compile(f flows.Flow) (*CompiledFlow, error) {
+	switch typed := f.(type) {
+	case *CompiledFlow:
+		return typed, nil
+	case *flow:
+		cp, err := typed.copy()
+		if err != nil {
+			return nil, err
+		}
+		cp.asset = typed.asset
+
+		for _, t := range cp.ExtractTemplates() {
+			if errs := cp.templates[t].Errors(); errs.HasErrors() {
+				return nil, errors.Wrapf(errs, "unable to compile template '%s'", t)
+			}
+		}
+
+		if err := cp.resolveTests(); err != nil {
+			return nil, err
+		}
+
+		return &CompiledFlow{flow: cp}, nil
+	default:
+		return nil, errors.Errorf("can't compile flow of type %T", f)
+	}
+}
+
+// resolves the test functions used by routers in this flow
+func (f *flow) resolveTests() error {
+	for _, node := range f.nodes {
+		if resolver, ok := node.Router().(interface{ ResolveTests() error }); ok {
+			if err := resolver.ResolveTests(); err != nil {
+				return errors.Wrapf(err, "unable to compile node[uuid=%s]", node.UUID())
+			}
+		}
+	}
+	return nil
+}
+
+var _ flows.Flow = (*CompiledFlow)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+// the UUID and name are included so that compiled flows can be stored in asset sources like flow definitions
+type compiledFlowEnvelope struct {
+	UUID       assets.FlowUUID `json:"uuid"`
+	Name       string          `json:"name"`
+	Compiled   int             `json:"compiled"`
+	Definition json.RawMessage `json:"definition"`
+}
+
+// ReadCompiledFlow reads a compiled flow which was written by this version of the library. Unlike reading a flow
+// definition, this doesn't migrate or validate the flow.
+func ReadCompiledFlow(data json.RawMessage) (*CompiledFlow, error) {
+	return readCompiledFlow(data, nil)
+}
+
+func readCompiledFlow(data json.RawMessage, a assets.Flow) (*CompiledFlow, error) {
+	e := &compiledFlowEnvelope{}
+	if err := jsonx.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	if e.Compiled != CompiledFlowVersion {
+		return nil, errors.Errorf("compiled flow version %d isn't supported by this library", e.Compiled)
+	}
+
+	fe := &flowEnvelope{}
+	if err := jsonx.Unmarshal(e.Definition, fe); err != nil {
+		return nil, err
+	}
+	if fe.SpecVersion == nil || !fe.SpecVersion.Equal(CurrentSpecVersion) {
+		return nil, errors.Errorf("compiled flow has spec version %s but this library requires %s", fe.SpecVersion, CurrentSpecVersion)
+	}
+
+	f := newFlow(fe.UUID, fe.Name, fe.Language, fe.Type, fe.Revision, fe.ExpireAfterMinutes, fe.ExpressionsVersion, fe.localization(), fe.LanguageFallbacks, fe.Constants, fe.ExitWebhook, fe.InputProcessing, fe.nodes(), fe.UI, a)
+	f.compileTemplates()
+
+	if err := f.resolveTests(); err != nil {
+		return nil, err
+	}
+
+	return &CompiledFlow{flow: f}, nil
+}
+
+// returns whether the given JSON looks like a compiled flow rather than a flow definition
+func isCompiledFlow(data json.RawMessage) bool {
+	_, err := jsonparser.GetInt(data, "compiled")
+	return err == nil
+}
+
+// MarshalJSON marshals this compiled flow into JSON
+func (f *CompiledFlow) MarshalJSON() ([]byte, error) {
+	definition, err := f.flow.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(&compiledFlowEnvelope{UUID: f.uuid, Name: f.name, Compiled: CompiledFlowVersion, Definition: definition})
+}
@@ -0,0 +1,107 @@
+package definition_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows/definition"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	env := envs.NewBuilder().Build()
+
+	flow, err := test.LoadFlowFromAssets(env, "../../test/testdata/runner/two_questions.json", "615b8a0f-588c-4d20-a05f-363b0b4ce6f4")
+	require.NoError(t, err)
+
+	compiled, err := definition.Compile(flow)
+	require.NoError(t, err)
+	assert.Equal(t, flow.UUID(), compiled.UUID())
+	assert.Equal(t, len(flow.Nodes()), len(compiled.Nodes()))
+
+	// compiling a compiled flow is a noop
+	recompiled, err := definition.Compile(compiled)
+	assert.NoError(t, err)
+	assert.Same(t, compiled, recompiled)
+
+	// compiled form wraps the flow definition
+	compiledJSON := jsonx.MustMarshal(compiled)
+	envelope := &struct {
+		UUID       assets.FlowUUID `json:"uuid"`
+		Compiled   int             `json:"compiled"`
+		Definition json.RawMessage `json:"definition"`
+	}{}
+	jsonx.MustUnmarshal(compiledJSON, envelope)
+	assert.Equal(t, flow.UUID(), envelope.UUID)
+	assert.Equal(t, definition.CompiledFlowVersion, envelope.Compiled)
+	test.AssertEqualJSON(t, jsonx.MustMarshal(flow), envelope.Definition, "definition mismatch")
+
+	// and can be read back
+	read, err := definition.ReadCompiledFlow(compiledJSON)
+	require.NoError(t, err)
+	assert.Equal(t, flow.UUID(), read.UUID())
+	test.AssertEqualJSON(t, compiledJSON, jsonx.MustMarshal(read), "compiled form mismatch")
+
+	// including when it's provided as a flow asset
+	fromAsset, err := definition.ReadAsset(static.NewFlow(flow.UUID(), flow.Name(), compiledJSON), nil)
+	require.NoError(t, err)
+	assert.IsType(t, &definition.CompiledFlow{}, fromAsset)
+	assert.Equal(t, flow.UUID(), fromAsset.Asset().UUID())
+
+	// flows with templates that can't be parsed can't be compiled
+	broken, err := definition.ReadFlow([]byte(`{
+		"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+		"name": "Test Flow",
+		"spec_version": "13.2.0",
+		"language": "eng",
+		"type": "messaging",
+		"nodes": [
+			{
+				"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"actions": [{"uuid": "ad154980-7bf7-4ab8-8728-545fd6378912", "type": "send_msg", "text": "Hi @(contact.name +)"}],
+				"exits": [{"uuid": "3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b"}]
+			}
+		]
+	}`), nil)
+	require.NoError(t, err)
+
+	_, err = definition.Compile(broken)
+	assert.EqualError(t, err, "unable to compile template 'Hi @(contact.name +)': error evaluating @(contact.name +): syntax error at ")
+
+	// compiled forms from other versions of the library can't be read
+	_, err = definition.ReadCompiledFlow([]byte(`{"compiled": 999, "definition": {}}`))
+	assert.EqualError(t, err, "compiled flow version 999 isn't supported by this library")
+
+	_, err = definition.ReadCompiledFlow([]byte(fmt.Sprintf(`{"compiled": %d, "definition": {"uuid": "8ca44c09-791d-453a-9799-a70dd3303306", "name": "Test Flow", "spec_version": "13.0.0", "nodes": []}}`, definition.CompiledFlowVersion)))
+	assert.EqualError(t, err, fmt.Sprintf("compiled flow has spec version 13.0.0 but this library requires %s", definition.CurrentSpecVersion))
+
+	_, err = definition.ReadCompiledFlow([]byte(`{"compiled": "x"}`))
+	assert.Error(t, err)
+}
+
+func TestCompiledFlowAssets(t *testing.T) {
+	env := envs.NewBuilder().Build()
+
+	flow, err := test.LoadFlowFromAssets(env, "../../test/testdata/runner/two_questions.json", "615b8a0f-588c-4d20-a05f-363b0b4ce6f4")
+	require.NoError(t, err)
+
+	compiled, err := definition.Compile(flow)
+	require.NoError(t, err)
+
+	source, err := static.NewSource([]byte(fmt.Sprintf(`{"flows": [%s]}`, jsonx.MustMarshal(compiled))))
+	require.NoError(t, err)
+
+	fa := definition.NewFlowAssets(source, nil)
+
+	loaded, err := fa.Get(assets.FlowUUID("615b8a0f-588c-4d20-a05f-363b0b4ce6f4"))
+	require.NoError(t, err)
+	assert.IsType(t, &definition.CompiledFlow{}, loaded)
+}
@@ -2,22 +2,38 @@ package definition
 
 import (
 	"encoding/json"
+	"regexp"
+	"strings"
 
 	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/gocommon/uuids"
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent"
+	"github.com/nyaruka/goflow/excellent/functions"
 	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/actions"
 	"github.com/nyaruka/goflow/flows/definition/migrations"
 	"github.com/nyaruka/goflow/flows/inspect"
 	"github.com/nyaruka/goflow/flows/inspect/issues"
 	"github.com/nyaruka/goflow/utils"
+	"github.com/nyaruka/goflow/utils/yamlx"
 
 	"github.com/Masterminds/semver"
 	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
 )
 
+func init() {
+	flows.RegisterContextType("flow",
+		flows.NewContextProperty("__default__", "text", "the name"),
+		flows.NewContextProperty("uuid", "text", "the UUID of the flow"),
+		flows.NewContextProperty("name", "text", "the name of the flow"),
+		flows.NewContextProperty("revision", "text", "the revision number of the flow"),
+	)
+}
+
 // CurrentSpecVersion is the flow spec version supported by this library
 var CurrentSpecVersion = semver.MustParse("13.2.0")
 
@@ -36,7 +52,12 @@ type flow struct {
 	flowType           flows.FlowType
 	revision           int
 	expireAfterMinutes int
+	expressionsVersion envs.ExpressionsVersion
 	localization       flows.Localization
+	languageFallbacks  map[envs.Language][]envs.Language
+	constants          map[string]string
+	exitWebhook        *flows.ExitWebhook
+	inputProcessing    *flows.InputProcessing
 	nodes              []flows.Node
 
 	// optional properties not used by engine itself
@@ -46,11 +67,25 @@ type flow struct {
 	asset assets.Flow
 
 	// internal state
-	nodeMap map[flows.NodeUUID]flows.Node
+	nodeMap      map[flows.NodeUUID]flows.Node
+	templates    map[string]*excellent.Template
+	deprecations map[uuids.UUID][]string // deprecated functions used by each action or router, keyed by action or node UUID
 }
 
 // NewFlow creates a new flow
-func NewFlow(uuid assets.FlowUUID, name string, language envs.Language, flowType flows.FlowType, revision int, expireAfterMinutes int, localization flows.Localization, nodes []flows.Node, ui json.RawMessage, a assets.Flow) (flows.Flow, error) {
+func NewFlow(uuid assets.FlowUUID, name string, language envs.Language, flowType flows.FlowType, revision int, expireAfterMinutes int, expressionsVersion envs.ExpressionsVersion, localization flows.Localization, languageFallbacks map[envs.Language][]envs.Language, constants map[string]string, exitWebhook *flows.ExitWebhook, inputProcessing *flows.InputProcessing, nodes []flows.Node, ui json.RawMessage, a assets.Flow) (flows.Flow, error) {
+	f := newFlow(uuid, name, language, flowType, revision, expireAfterMinutes, expressionsVersion, localization, languageFallbacks, constants, exitWebhook, inputProcessing, nodes, ui, a)
+
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+
+	f.compileTemplates()
+
+	return f, nil
+}
+
+func newFlow(uuid assets.FlowUUID, name string, language envs.Language, flowType flows.FlowType, revision int, expireAfterMinutes int, expressionsVersion envs.ExpressionsVersion, localization flows.Localization, languageFallbacks map[envs.Language][]envs.Language, constants map[string]string, exitWebhook *flows.ExitWebhook, inputProcessing *flows.InputProcessing, nodes []flows.Node, ui json.RawMessage, a assets.Flow) *flow {
 	f := &flow{
 		uuid:               uuid,
 		name:               name,
@@ -59,7 +94,12 @@ func NewFlow(uuid assets.FlowUUID, name string, language envs.Language, flowType
 		flowType:           flowType,
 		revision:           revision,
 		expireAfterMinutes: expireAfterMinutes,
+		expressionsVersion: expressionsVersion,
 		localization:       localization,
+		languageFallbacks:  languageFallbacks,
+		constants:          constants,
+		exitWebhook:        exitWebhook,
+		inputProcessing:    inputProcessing,
 		nodes:              nodes,
 		nodeMap:            make(map[flows.NodeUUID]flows.Node, len(nodes)),
 		ui:                 ui,
@@ -70,26 +110,114 @@ func NewFlow(uuid assets.FlowUUID, name string, language envs.Language, flowType
 		f.nodeMap[node.UUID()] = node
 	}
 
-	if err := f.validate(); err != nil {
-		return nil, err
+	return f
+}
+
+func (f *flow) UUID() assets.FlowUUID        { return f.uuid }
+func (f *flow) Name() string                 { return f.name }
+func (f *flow) SpecVersion() *semver.Version { return f.specVersion }
+func (f *flow) Revision() int                { return f.revision }
+func (f *flow) Language() envs.Language      { return f.language }
+func (f *flow) Type() flows.FlowType         { return f.flowType }
+func (f *flow) ExpireAfterMinutes() int      { return f.expireAfterMinutes }
+func (f *flow) Nodes() []flows.Node          { return f.nodes }
+
+// ExpressionsVersion returns the version of the expressions language used by this flow
+func (f *flow) ExpressionsVersion() envs.ExpressionsVersion {
+	if f.expressionsVersion == 0 {
+		return envs.ExpressionsVersion1
 	}
+	return f.expressionsVersion
+}
 
-	return f, nil
+func (f *flow) Localization() flows.Localization { return f.localization }
+
+// LanguageFallbacks returns the languages to try, in order, when text isn't translated into the given language
+func (f *flow) LanguageFallbacks(lang envs.Language) []envs.Language {
+	return f.languageFallbacks[lang]
 }
 
-func (f *flow) UUID() assets.FlowUUID                  { return f.uuid }
-func (f *flow) Name() string                           { return f.name }
-func (f *flow) SpecVersion() *semver.Version           { return f.specVersion }
-func (f *flow) Revision() int                          { return f.revision }
-func (f *flow) Language() envs.Language                { return f.language }
-func (f *flow) Type() flows.FlowType                   { return f.flowType }
-func (f *flow) ExpireAfterMinutes() int                { return f.expireAfterMinutes }
-func (f *flow) Nodes() []flows.Node                    { return f.nodes }
-func (f *flow) Localization() flows.Localization       { return f.localization }
+// Constants returns the templates of the constants defined by this flow, keyed by name
+func (f *flow) Constants() map[string]string { return f.constants }
+
+// ExitWebhook returns the webhook to be called when runs of this flow exit, if there is one
+func (f *flow) ExitWebhook() *flows.ExitWebhook { return f.exitWebhook }
+
+// InputProcessing returns the processing to be applied to the text of incoming messages, if there is any
+func (f *flow) InputProcessing() *flows.InputProcessing { return f.inputProcessing }
+
 func (f *flow) UI() json.RawMessage                    { return f.ui }
 func (f *flow) GetNode(uuid flows.NodeUUID) flows.Node { return f.nodeMap[uuid] }
 
+// CompiledTemplate returns the compiled form of the given template if it's one of this flow's templates
+func (f *flow) CompiledTemplate(template string) *excellent.Template {
+	return f.templates[template]
+}
+
+// DeprecatedFunctions returns the names of the deprecated functions called by the templates of the action with the
+// given UUID, or of the router of the node with the given UUID
+func (f *flow) DeprecatedFunctions(uuid uuids.UUID) []string {
+	return f.deprecations[uuid]
+}
+
+// compiles all the templates in this flow, including translations, so that runs don't have to parse them every time
+// they're evaluated, and records which deprecated functions each action and router uses
+func (f *flow) compileTemplates() {
+	f.templates = make(map[string]*excellent.Template)
+	f.deprecations = make(map[uuids.UUID][]string)
+
+	for _, t := range f.ExtractTemplates() {
+		if _, seen := f.templates[t]; !seen {
+			f.templates[t] = excellent.CompileTemplate(t, flows.RunContextTopLevels)
+		}
+	}
+
+	for _, n := range f.nodes {
+		n.EnumerateTemplates(f.Localization(), func(a flows.Action, r flows.Router, l envs.Language, t string) {
+			compiled := f.templates[t]
+			if compiled == nil {
+				return
+			}
+
+			owner := uuids.UUID(n.UUID())
+			if a != nil {
+				owner = uuids.UUID(a.UUID())
+			}
+
+			for _, name := range compiled.Functions() {
+				if functions.Deprecation(name) != "" && !slices.Contains(f.deprecations[owner], name) {
+					f.deprecations[owner] = append(f.deprecations[owner], name)
+				}
+			}
+		})
+	}
+}
+
 func (f *flow) validate() error {
+	if f.expressionsVersion < 0 || f.expressionsVersion > envs.LatestExpressionsVersion {
+		return errors.Errorf("expressions version %d isn't supported by this library", f.expressionsVersion)
+	}
+
+	for lang, fallbacks := range f.languageFallbacks {
+		for _, fallback := range fallbacks {
+			if fallback == lang {
+				return errors.Errorf("language %s can't fall back to itself", lang)
+			}
+		}
+	}
+
+	for _, name := range utils.SortedKeys(f.constants) {
+		if err := validateConstant(name, f.constants[name]); err != nil {
+			return errors.Wrapf(err, "invalid constant '%s'", name)
+		}
+	}
+
+	if f.exitWebhook != nil {
+		if err := f.exitWebhook.Validate(); err != nil {
+			return errors.Wrap(err, "invalid exit webhook")
+		}
+	}
+
 	// track UUIDs used by nodes and actions to ensure that they are unique
 	seenUUIDs := make(map[uuids.UUID]bool)
 
@@ -108,6 +236,37 @@ func (f *flow) validate() error {
 	return nil
 }
 
+// constant names must be usable as keys in expressions like @consts.max_tries
+var constantNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// checks the name and template of a constant, which can't refer to other constants since they're all evaluated together
+func validateConstant(name, template string) error {
+	if !constantNameRegex.MatchString(name) {
+		return errors.New("name must be lowercase letters, digits and underscores, starting with a letter")
+	}
+
+	if errs := excellent.CompileTemplate(template, flows.RunContextTopLevels).Errors(); errs.HasErrors() {
+		return errs
+	}
+
+	var refersToConsts bool
+	excellent.VisitTemplate(template, flows.RunContextTopLevels, func(tokenType excellent.XTokenType, token string) error {
+		switch tokenType {
+		case excellent.IDENTIFIER, excellent.EXPRESSION:
+			excellent.Parse(token, func(path []string) {
+				if strings.ToLower(path[0]) == "consts" {
+					refersToConsts = true
+				}
+			})
+		}
+		return nil
+	})
+	if refersToConsts {
+		return errors.New("constants can't refer to other constants")
+	}
+	return nil
+}
+
 // Inspect enumerates dependencies, results etc
 func (f *flow) Inspect(sa flows.SessionAssets) *flows.Inspection {
 	templates, assetRefs, parentRefs := f.extract()
@@ -118,17 +277,20 @@ func (f *flow) Inspect(sa flows.SessionAssets) *flows.Inspection {
 		WaitingExits: f.extractExitsFromWaits(),
 		ParentRefs:   parentRefs,
 		Issues:       issues.Check(sa, f, templates, assetRefs),
+
+		MsgPriorities: f.extractMsgPriorities(),
 	}
 }
 
+// Analyze finds structural problems like dead nodes, exits that can't be taken and loops without waits, which unlike
+// the issues found by Inspect, don't depend on assets
+func (f *flow) Analyze() []flows.Issue {
+	templates, _, _ := f.extract()
+
+	return issues.Analyze(f, templates, f.extractResults())
+}
+
 // Context returns the properties available in expressions
-//
-//	__default__:text -> the name
-//	uuid:text -> the UUID of the flow
-//	name:text -> the name of the flow
-//	revision:text -> the revision number of the flow
-//
-// @context flow
 func (f *flow) Context(env envs.Environment) map[string]types.XValue {
 	return map[string]types.XValue{
 		"__default__": types.NewXText(f.name),
@@ -161,6 +323,15 @@ func (f *flow) ExtractTemplates() []string {
 	for _, n := range f.nodes {
 		n.EnumerateTemplates(f.Localization(), include)
 	}
+	for _, name := range utils.SortedKeys(f.constants) {
+		include(nil, nil, envs.NilLanguage, f.constants[name])
+	}
+	if f.exitWebhook != nil {
+		include(nil, nil, envs.NilLanguage, f.exitWebhook.URL)
+		for _, key := range utils.SortedKeys(f.exitWebhook.Headers) {
+			include(nil, nil, envs.NilLanguage, f.exitWebhook.Headers[key])
+		}
+	}
 
 	return templates
 }
@@ -183,6 +354,46 @@ func (f *flow) ExtractLocalizables() []string {
 	return texts
 }
 
+// the localizable properties counted by LocalizationCoverage, i.e. message text, quick replies and category names
+var coverageProperties = map[string]bool{"text": true, "quick_replies": true, "name": true}
+
+// LocalizationCoverage returns, for each language which this flow has translations in, how many of its translatable
+// strings there are and how many of those are missing a translation
+func (f *flow) LocalizationCoverage() map[envs.Language]*flows.LocalizationCoverage {
+	coverage := make(map[envs.Language]*flows.LocalizationCoverage)
+	for _, lang := range f.localization.Languages() {
+		if lang != f.language {
+			coverage[lang] = &flows.LocalizationCoverage{Missing: make(map[string]int)}
+		}
+	}
+
+	include := func(uuid uuids.UUID, property string, texts []string, w func([]string)) {
+		if !coverageProperties[property] {
+			return
+		}
+
+		for lang, c := range coverage {
+			translation := f.localization.GetItemTranslation(lang, uuid, property)
+
+			for i, text := range texts {
+				if text == "" {
+					continue
+				}
+				c.Total++
+				if i >= len(translation) || translation[i] == "" {
+					c.Missing[property]++
+				}
+			}
+		}
+	}
+
+	for _, n := range f.nodes {
+		n.EnumerateLocalizables(include)
+	}
+
+	return coverage
+}
+
 // ChangeLanguage changes the language of the flow saving the current flow text as a translation and replacing it with
 // the specified translation. It returns an error if there are missing translations.
 func (f *flow) ChangeLanguage(lang envs.Language) (flows.Flow, error) {
@@ -225,6 +436,49 @@ func (f *flow) ChangeLanguage(lang envs.Language) (flows.Flow, error) {
 	return copy, nil
 }
 
+// ExtractDependencies extracts the assets referenced by this flow, grouped by type, along with the nodes and actions
+// which reference them
+func (f *flow) ExtractDependencies() *flows.FlowDependencies {
+	_, assetRefs, _ := f.extract()
+
+	return flows.NewFlowDependencies(assetRefs)
+}
+
+// RepairDependencies returns a copy of this flow in which references to assets that don't exist in the given session
+// assets are repaired by pointing them at assets of the same type and name, or otherwise stripped where that can be done
+// without making the flow invalid. References which can be neither repaired nor stripped are reported to the missing
+// callback.
+func (f *flow) RepairDependencies(sa flows.SessionAssets, missing assets.MissingCallback) (flows.Flow, error) {
+	copy, err := f.copy()
+	if err != nil {
+		return nil, err
+	}
+
+	isMissing := func(ref assets.Reference) bool { return !inspect.CheckReference(sa, ref) }
+
+	for _, n := range copy.nodes {
+		n.EnumerateDependencies(copy.Localization(), func(a flows.Action, r flows.Router, l envs.Language, ref assets.Reference) {
+			if ref != nil && !ref.Variable() && isMissing(ref) {
+				inspect.RepairReference(sa, ref)
+			}
+		})
+
+		for _, a := range n.Actions() {
+			inspect.StripReferences(a, isMissing)
+		}
+	}
+
+	// anything still missing, e.g. references in expressions, is left for the caller to deal with
+	_, assetRefs, _ := copy.extract()
+	for _, dep := range inspect.NewDependencies(assetRefs, sa) {
+		if dep.Missing() {
+			missing(dep.Reference(), nil)
+		}
+	}
+
+	return copy, nil
+}
+
 // makes a copy of this flow which this differs from cloning as UUIDs are preserved
 func (f *flow) copy() (*flow, error) {
 	// by marshaling and unmarshaling...
@@ -285,6 +539,23 @@ func (f *flow) extractResults() []flows.ExtractedResult {
 }
 
 // extracts all exits coming from nodes with waits
+// counts the send_msg actions in this flow which have a priority, by priority
+func (f *flow) extractMsgPriorities() map[flows.MsgPriority]int {
+	var counts map[flows.MsgPriority]int
+
+	for _, n := range f.nodes {
+		for _, a := range n.Actions() {
+			if send, isSend := a.(*actions.SendMsgAction); isSend && send.Priority != flows.NilMsgPriority {
+				if counts == nil {
+					counts = make(map[flows.MsgPriority]int)
+				}
+				counts[send.Priority]++
+			}
+		}
+	}
+	return counts
+}
+
 func (f *flow) extractExitsFromWaits() []flows.ExitUUID {
 	exitUUIDs := make([]flows.ExitUUID, 0)
 	include := func(e flows.ExitUUID) { exitUUIDs = append(exitUUIDs, e) }
@@ -311,13 +582,18 @@ var _ flows.Flow = (*flow)(nil)
 type flowEnvelope struct {
 	migrations.Header13
 
-	Language           envs.Language   `json:"language" validate:"required,language"`
-	Type               flows.FlowType  `json:"type" validate:"required,flow_type"`
-	Revision           int             `json:"revision"`
-	ExpireAfterMinutes int             `json:"expire_after_minutes"`
-	Localization       localization    `json:"localization"`
-	Nodes              []*node         `json:"nodes"`
-	UI                 json.RawMessage `json:"_ui,omitempty"`
+	Language           envs.Language                     `json:"language" validate:"required,language"`
+	Type               flows.FlowType                    `json:"type" validate:"required,flow_type"`
+	Revision           int                               `json:"revision"`
+	ExpireAfterMinutes int                               `json:"expire_after_minutes"`
+	ExpressionsVersion envs.ExpressionsVersion           `json:"expressions_version,omitempty"`
+	Localization       localization                      `json:"localization"`
+	LanguageFallbacks  map[envs.Language][]envs.Language `json:"language_fallbacks,omitempty" validate:"omitempty,dive,keys,language,endkeys,dive,language"`
+	Constants          map[string]string                 `json:"constants,omitempty"`
+	ExitWebhook        *flows.ExitWebhook                `json:"exit_webhook,omitempty" validate:"omitempty"`
+	InputProcessing    *flows.InputProcessing            `json:"input_processing,omitempty" validate:"omitempty"`
+	Nodes              []*node                           `json:"nodes"`
+	UI                 json.RawMessage                   `json:"_ui,omitempty"`
 }
 
 // ReadFlow reads a flow definition from the passed in byte array, migrating it to the spec version of the engine if necessary
@@ -325,8 +601,31 @@ func ReadFlow(data json.RawMessage, mc *migrations.Config) (flows.Flow, error) {
 	return readFlow(data, mc, nil)
 }
 
-// ReadAsset reads a flow definition from the passed in flow asset, migrating it to the spec version of the engine if necessary
+// ReadFlowYAML reads a flow definition written as YAML, migrating it to the spec version of the engine if necessary
+func ReadFlowYAML(data []byte, mc *migrations.Config) (flows.Flow, error) {
+	converted, err := yamlx.ToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return ReadFlow(converted, mc)
+}
+
+// MarshalFlowYAML marshals the given flow as YAML. If the flow was read from YAML then that can be passed as original,
+// and its comments are kept wherever the parts of the flow they describe still exist.
+func MarshalFlowYAML(flow flows.Flow, original []byte) ([]byte, error) {
+	marshaled, err := jsonx.Marshal(flow)
+	if err != nil {
+		return nil, err
+	}
+	return yamlx.Update(original, marshaled)
+}
+
+// ReadAsset reads a flow definition from the passed in flow asset, migrating it to the spec version of the engine if necessary.
+// If the asset contains a compiled flow then that is read without migration or validation.
 func ReadAsset(a assets.Flow, mc *migrations.Config) (flows.Flow, error) {
+	if isCompiledFlow(a.Definition()) {
+		return readCompiledFlow(a.Definition(), a)
+	}
 	return readFlow(a.Definition(), mc, a)
 }
 
@@ -335,6 +634,10 @@ func readFlow(data json.RawMessage, mc *migrations.Config, a assets.Flow) (flows
 		mc = migrations.DefaultConfig
 	}
 
+	// the spec version the definition was written for, which is unknown for legacy definitions
+	original := &migrations.Header13{}
+	jsonx.Unmarshal(data, original)
+
 	var err error
 	data, err = migrations.MigrateToLatest(data, mc)
 	if err != nil {
@@ -353,16 +656,35 @@ func readFlow(data json.RawMessage, mc *migrations.Config, a assets.Flow) (flows
 		return nil, err
 	}
 
+	f, err := NewFlow(e.UUID, e.Name, e.Language, e.Type, e.Revision, e.ExpireAfterMinutes, e.ExpressionsVersion, e.localization(), e.LanguageFallbacks, e.Constants, e.ExitWebhook, e.InputProcessing, e.nodes(), e.UI, a)
+	if err != nil {
+		return nil, err
+	}
+
+	if original.SpecVersion != nil {
+		for _, feature := range InspectSpecRequirements(f).Features {
+			if feature.SpecVersion.GreaterThan(original.SpecVersion) {
+				return nil, errors.Errorf("%s requires spec version %s but definition has spec version %s", feature.Description, feature.SpecVersion, original.SpecVersion)
+			}
+		}
+	}
+
+	return f, nil
+}
+
+func (e *flowEnvelope) nodes() []flows.Node {
 	nodes := make([]flows.Node, len(e.Nodes))
 	for i := range e.Nodes {
 		nodes[i] = e.Nodes[i]
 	}
+	return nodes
+}
 
+func (e *flowEnvelope) localization() flows.Localization {
 	if e.Localization == nil {
-		e.Localization = make(localization)
+		return make(localization)
 	}
-
-	return NewFlow(e.UUID, e.Name, e.Language, e.Type, e.Revision, e.ExpireAfterMinutes, e.Localization, nodes, e.UI, a)
+	return e.Localization
 }
 
 // MarshalJSON marshals this flow into JSON
@@ -377,7 +699,12 @@ func (f *flow) MarshalJSON() ([]byte, error) {
 		Type:               f.flowType,
 		Revision:           f.revision,
 		ExpireAfterMinutes: f.expireAfterMinutes,
+		ExpressionsVersion: f.expressionsVersion,
 		Localization:       f.localization.(localization),
+		LanguageFallbacks:  f.languageFallbacks,
+		Constants:          f.constants,
+		ExitWebhook:        f.exitWebhook,
+		InputProcessing:    f.inputProcessing,
 		Nodes:              make([]*node, len(f.nodes)),
 		UI:                 f.ui,
 	}
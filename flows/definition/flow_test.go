@@ -116,6 +116,7 @@ func TestNewFlow(t *testing.T) {
     "type": "messaging",
     "revision": 123,
     "expire_after_minutes": 30,
+    "expressions_version": 2,
     "localization": {},
     "nodes": [
         {
@@ -209,7 +210,12 @@ func TestNewFlow(t *testing.T) {
 		flows.FlowTypeMessaging,
 		123, // revision
 		30,  // expires after minutes
+		envs.ExpressionsVersion2,
 		definition.NewLocalization(),
+		nil, // language fallbacks
+		nil, // constants
+		nil, // exit webhook
+		nil, // input processing
 		[]flows.Node{
 			definition.NewNode(
 				flows.NodeUUID("a58be63b-907d-4a1a-856b-0bb5579d7507"),
@@ -223,7 +229,7 @@ func TestNewFlow(t *testing.T) {
 					),
 				},
 				routers.NewSwitch(
-					waits.NewMsgWait(nil, hints.NewImageHint()),
+					waits.NewMsgWait(nil, hints.NewImageHint(), nil, nil),
 					"Response 1",
 					[]flows.Category{
 						routers.NewCategory(
@@ -306,7 +312,15 @@ func TestNewFlow(t *testing.T) {
 				"type": "label"
 			}
 		],
-		"issues": [],
+		"issues": [
+			{
+				"case_uuid": "9f593e22-7886-4c08-a52f-0e8780504d75",
+				"description": "invalid arguments for case 9f593e22-7886-4c08-a52f-0e8780504d75: has_any_word takes 1 argument, got 2",
+				"node_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"test": "has_any_word",
+				"type": "invalid_case_arguments"
+			}
+		],
 		"parent_refs": [],
 		"results": [
 			{
@@ -413,6 +427,20 @@ func TestReadFlow(t *testing.T) {
   }`), nil)
 	assert.EqualError(t, err, "field 'type' is required")
 
+	// try reading a definition with an unsupported expressions version
+	_, err = definition.ReadFlow([]byte(`{
+		"uuid": "8ca44c09-791d-453a-9799-a70dd3303306", 
+		"name": "Test Flow",
+		"spec_version": "13.0",
+		"language": "eng",
+		"type": "messaging",
+		"revision": 123,
+		"expire_after_minutes": 30,
+		"expressions_version": 9999,
+		"nodes": []
+	}`), nil)
+	assert.EqualError(t, err, "expressions version 9999 isn't supported by this library")
+
 	// try reading a definition with UI
 	flow, err := definition.ReadFlow([]byte(`{
 		"uuid": "8ca44c09-791d-453a-9799-a70dd3303306", 
@@ -451,6 +479,9 @@ func TestReadFlow(t *testing.T) {
 		"stickies": {}
 	}`), flow.UI(), "ui mismatch for read flow")
 
+	// flows without an expressions version use the original version
+	assert.Equal(t, envs.ExpressionsVersion1, flow.ExpressionsVersion())
+
 	// try reading a legacy definition
 	flow, err = definition.ReadFlow([]byte(`{
 		"base_language": "eng",
@@ -582,6 +613,12 @@ func TestExtractTemplatesAndLocalizables(t *testing.T) {
 		templates := flow.ExtractTemplates()
 		assert.Equal(t, tc.templates, templates, "extracted templates mismatch for flow %s[uuid=%s]", tc.path, tc.uuid)
 
+		// and all templates should have been compiled when the flow was read
+		for _, template := range templates {
+			assert.NotNil(t, flow.CompiledTemplate(template), "missing compiled template %s in flow %s[uuid=%s]", template, tc.path, tc.uuid)
+		}
+		assert.Nil(t, flow.CompiledTemplate("@(not_in_flow)"))
+
 		// try extracting all localizable text
 		localizables := flow.ExtractLocalizables()
 		assert.Equal(t, tc.localizables, localizables, "extracted localizables mismatch for flow %s[uuid=%s]", tc.path, tc.uuid)
@@ -644,6 +681,29 @@ func TestInspection(t *testing.T) {
 	}
 }
 
+func TestInspectionMsgPriorities(t *testing.T) {
+	env := envs.NewBuilder().Build()
+
+	sa, err := test.LoadSessionAssets(env, "testdata/msg_priorities.json")
+	require.NoError(t, err)
+
+	flow, err := sa.Flows().Get("6f1d8a2c-3b4e-4c5d-9e6f-7a8b9c0d1e2f")
+	require.NoError(t, err)
+
+	// messages are totaled by priority across all nodes, and those without a priority aren't counted
+	info := flow.Inspect(sa)
+	assert.Equal(t, map[flows.MsgPriority]int{flows.MsgPriorityTransactional: 2, flows.MsgPriorityBulk: 1}, info.MsgPriorities)
+	assert.Contains(t, string(jsonx.MustMarshal(info)), `"msg_priorities":{"bulk":1,"transactional":2}`)
+
+	// flows which don't send any messages with priorities don't have totals
+	flow, err = sa.Flows().Get("9b2c4e6a-8d1f-4a3b-b5c7-d9e1f3a5b7c9")
+	require.NoError(t, err)
+
+	info = flow.Inspect(sa)
+	assert.Nil(t, info.MsgPriorities)
+	assert.NotContains(t, string(jsonx.MustMarshal(info)), `msg_priorities`)
+}
+
 func TestChangeLanguage(t *testing.T) {
 	env := envs.NewBuilder().Build()
 
@@ -667,3 +727,302 @@ func TestChangeLanguage(t *testing.T) {
 	assertLanguageChange("ara") // missing translations will be left in eng
 	assertLanguageChange("kin") // everything is missing and will be left in eng
 }
+
+func TestExtractAndRepairDependencies(t *testing.T) {
+	env := envs.NewBuilder().Build()
+
+	sa, err := test.LoadSessionAssets(env, "testdata/dependencies.json")
+	require.NoError(t, err)
+
+	flow, err := sa.Flows().Get("b7bb5e7c-ad49-4e65-9e24-bf7f1e4ff00a")
+	require.NoError(t, err)
+
+	deps := flow.ExtractDependencies()
+	assert.Len(t, deps.Groups, 2)
+	assert.Len(t, deps.Labels, 1)
+	assert.Len(t, deps.Fields, 2)
+	assert.Equal(t, "gender", deps.Fields[0].Reference.Key)
+	assert.Equal(t, []*flows.DependencyLocation{
+		{NodeUUID: "a58be63b-907d-4a1a-856b-0bb5579d7507", ActionUUID: "e1d2c3b4-a5f6-4e7d-8c9b-0a1b2c3d4e5f"},
+	}, deps.Fields[0].Locations)
+
+	test.AssertEqualJSON(t, []byte(`{
+		"fields": [
+			{"key": "gender", "name": "", "locations": [{"node_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507", "action_uuid": "e1d2c3b4-a5f6-4e7d-8c9b-0a1b2c3d4e5f"}]},
+			{"key": "old_age", "name": "Age", "locations": [{"node_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507", "action_uuid": "c0f3b5a2-1d4e-4f6a-9b8c-7d6e5f4a3b2c"}]}
+		],
+		"groups": [
+			{"uuid": "3e6f2b3c-6a0e-4d5e-8f6b-6b2f6c1d9d42", "name": "testers", "locations": [{"node_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507", "action_uuid": "ad154980-7bf7-4ab8-8728-545fd6378912"}]},
+			{"uuid": "b9e0b3a4-6f2e-4f0c-9c3a-2d1c8f3a1e5b", "name": "Spammers", "locations": [{"node_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507", "action_uuid": "ad154980-7bf7-4ab8-8728-545fd6378912"}]}
+		],
+		"labels": [
+			{"uuid": "f7c0d2e1-3b4a-4c5d-8e6f-7a8b9c0d1e2f", "name": "Spam", "locations": [{"node_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507", "action_uuid": "6a0b4cb8-0b2c-4f1e-9d0e-8c3f1a7b2d4e"}]}
+		]
+	}`), jsonx.MustMarshal(deps), "dependencies JSON mismatch")
+
+	missing := make([]assets.Reference, 0)
+	repaired, err := flow.RepairDependencies(sa, func(r assets.Reference, err error) { missing = append(missing, r) })
+	require.NoError(t, err)
+
+	// references are repaired by name or stripped, and the gender field can be neither
+	deps = repaired.ExtractDependencies()
+	assert.Len(t, deps.Groups, 1)
+	assert.Equal(t, assets.GroupUUID("b7cf0d83-f1c9-411c-96fd-c511a4cfa86d"), deps.Groups[0].Reference.UUID)
+	assert.Len(t, deps.Labels, 0)
+	assert.Len(t, deps.Fields, 2)
+	assert.Equal(t, "age", deps.Fields[1].Reference.Key)
+	assert.Equal(t, []assets.Reference{assets.NewFieldReference("gender", "")}, missing)
+
+	// and the repaired flow is still valid
+	_, err = definition.ReadFlow(jsonx.MustMarshal(repaired), nil)
+	assert.NoError(t, err)
+
+	// but the original flow is unchanged
+	assert.Len(t, flow.ExtractDependencies().Groups, 2)
+}
+
+func TestLanguageFallbacksAndCoverage(t *testing.T) {
+	flow, err := definition.ReadFlow([]byte(`{
+		"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+		"name": "Cheese",
+		"spec_version": "13.2.0",
+		"language": "eng",
+		"type": "messaging",
+		"language_fallbacks": {"quz": ["spa", "eng"]},
+		"localization": {
+			"spa": {
+				"ad154980-7bf7-4ab8-8728-545fd6378912": {"text": ["¿Te gusta el queso?"], "quick_replies": ["Sí", ""]},
+				"97b9451c-2856-475b-af38-32af68100897": {"name": ["Sí"]}
+			},
+			"quz": {
+				"ad154980-7bf7-4ab8-8728-545fd6378912": {"text": ["Quesota munankichu?"]}
+			}
+		},
+		"nodes": [
+			{
+				"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"actions": [
+					{"uuid": "ad154980-7bf7-4ab8-8728-545fd6378912", "type": "send_msg", "text": "Do you like cheese?", "quick_replies": ["Yes", "No"]}
+				],
+				"router": {
+					"type": "switch",
+					"wait": {"type": "msg"},
+					"operand": "@input.text",
+					"cases": [
+						{"uuid": "9f593e22-7886-4c08-a52f-0e8780504d75", "type": "has_any_word", "arguments": ["yes"], "category_uuid": "97b9451c-2856-475b-af38-32af68100897"}
+					],
+					"categories": [
+						{"uuid": "97b9451c-2856-475b-af38-32af68100897", "name": "Yes", "exit_uuid": "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d"},
+						{"uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3", "name": "Other", "exit_uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}
+					],
+					"default_category_uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3"
+				},
+				"exits": [
+					{"uuid": "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d"},
+					{"uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}
+				]
+			}
+		]
+	}`), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []envs.Language{"spa", "eng"}, flow.LanguageFallbacks("quz"))
+	assert.Nil(t, flow.LanguageFallbacks("spa"))
+
+	// fallbacks are written back out with the flow
+	assert.Contains(t, string(jsonx.MustMarshal(flow)), `"language_fallbacks":{"quz":["spa","eng"]}`)
+
+	// the flow has 5 translatable strings: the message text, 2 quick replies and 2 category names
+	assert.Equal(t, map[envs.Language]*flows.LocalizationCoverage{
+		"spa": {Total: 5, Missing: map[string]int{"quick_replies": 1, "name": 1}},
+		"quz": {Total: 5, Missing: map[string]int{"quick_replies": 2, "name": 2}},
+	}, flow.LocalizationCoverage())
+
+	// languages can't fall back to themselves
+	_, err = definition.ReadFlow([]byte(`{"uuid": "8ca44c09-791d-453a-9799-a70dd3303306", "name": "Test", "spec_version": "13.2.0", "language": "eng", "type": "messaging", "language_fallbacks": {"spa": ["spa"]}, "nodes": []}`), nil)
+	assert.EqualError(t, err, "language spa can't fall back to itself")
+
+	// or to things which aren't languages
+	_, err = definition.ReadFlow([]byte(`{"uuid": "8ca44c09-791d-453a-9799-a70dd3303306", "name": "Test", "spec_version": "13.2.0", "language": "eng", "type": "messaging", "language_fallbacks": {"spa": ["es"]}, "nodes": []}`), nil)
+	assert.Error(t, err)
+}
+
+func TestConstants(t *testing.T) {
+	readWithConstants := func(constants string) (flows.Flow, error) {
+		return definition.ReadFlow([]byte(fmt.Sprintf(`{
+			"uuid": "76f0a02f-3b75-4b86-9064-e9195e1b3a02",
+			"name": "Constants",
+			"spec_version": "13.0",
+			"language": "eng",
+			"type": "messaging",
+			"constants": %s,
+			"nodes": []
+		}`, constants)), nil)
+	}
+
+	flow, err := readWithConstants(`{"max_tries": "3", "greeting": "Hi @contact.name"}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"max_tries": "3", "greeting": "Hi @contact.name"}, flow.Constants())
+	assert.Equal(t, []string{"Hi @contact.name", "3"}, flow.ExtractTemplates())
+
+	marshaled, err := jsonx.Marshal(flow)
+	require.NoError(t, err)
+	assert.Contains(t, string(marshaled), `"constants":{"greeting":"Hi @contact.name","max_tries":"3"}`)
+
+	_, err = readWithConstants(`{"Max Tries": "3"}`)
+	assert.EqualError(t, err, "invalid constant 'Max Tries': name must be lowercase letters, digits and underscores, starting with a letter")
+
+	_, err = readWithConstants(`{"total": "@(1 + )"}`)
+	assert.ErrorContains(t, err, "invalid constant 'total': ")
+
+	_, err = readWithConstants(`{"total": "@(consts.max_tries * 2)"}`)
+	assert.EqualError(t, err, "invalid constant 'total': constants can't refer to other constants")
+}
+
+func TestDeprecatedFunctions(t *testing.T) {
+	flow, err := definition.ReadFlow([]byte(`{
+		"uuid": "76f0a02f-3b75-4b86-9064-e9195e1b3a02",
+		"name": "Deprecated",
+		"spec_version": "13.0",
+		"language": "eng",
+		"type": "messaging",
+		"nodes": [
+			{
+				"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"actions": [
+					{"uuid": "e97cd6d5-3354-4dbd-85bc-6c1f87849eec", "type": "send_msg", "text": "@(legacy_add(now(), 1)) @(LEGACY_ADD(now(), 2))"},
+					{"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a", "type": "send_msg", "text": "@(upper(contact.name))"}
+				],
+				"router": {
+					"type": "switch",
+					"operand": "@(legacy_add(now(), 1))",
+					"categories": [{"uuid": "3ffb6f24-2ed8-4fd5-bcc0-b2e2668672a8", "name": "All", "exit_uuid": "37d8813f-1402-4ad2-9cc2-e9054a96525b"}],
+					"default_category_uuid": "3ffb6f24-2ed8-4fd5-bcc0-b2e2668672a8"
+				},
+				"exits": [{"uuid": "37d8813f-1402-4ad2-9cc2-e9054a96525b"}]
+			}
+		]
+	}`), nil)
+	require.NoError(t, err)
+
+	// worked out when the flow is read, for each action and router
+	assert.Equal(t, []string{"legacy_add"}, flow.DeprecatedFunctions("e97cd6d5-3354-4dbd-85bc-6c1f87849eec"))
+	assert.Nil(t, flow.DeprecatedFunctions("0a8467eb-911a-41db-8101-ccf415c48e6a"))
+	assert.Equal(t, []string{"legacy_add"}, flow.DeprecatedFunctions("a58be63b-907d-4a1a-856b-0bb5579d7507"))
+}
+
+func TestExitWebhook(t *testing.T) {
+	readWithWebhook := func(webhook string) (flows.Flow, error) {
+		return definition.ReadFlow([]byte(fmt.Sprintf(`{
+			"uuid": "76f0a02f-3b75-4b86-9064-e9195e1b3a02",
+			"name": "Exit Webhook",
+			"spec_version": "13.0",
+			"language": "eng",
+			"type": "messaging",
+			"exit_webhook": %s,
+			"nodes": []
+		}`, webhook)), nil)
+	}
+
+	flow, err := readWithWebhook(`{"url": "http://temba.io/runs/@run.uuid", "headers": {"Authorization": "Token @globals.token"}, "retries": 2}`)
+	require.NoError(t, err)
+	assert.Equal(t, &flows.ExitWebhook{URL: "http://temba.io/runs/@run.uuid", Headers: map[string]string{"Authorization": "Token @globals.token"}, Retries: 2}, flow.ExitWebhook())
+	assert.Equal(t, []string{"http://temba.io/runs/@run.uuid", "Token @globals.token"}, flow.ExtractTemplates())
+
+	_, err = readWithWebhook(`{"url": ""}`)
+	assert.EqualError(t, err, "field 'exit_webhook.url' is required")
+
+	_, err = readWithWebhook(`{"url": "http://temba.io", "retries": 10}`)
+	assert.EqualError(t, err, "field 'exit_webhook.retries' must be less than or equal to 3")
+
+	_, err = readWithWebhook(`{"url": "http://temba.io", "headers": {"Bad Header": "x"}}`)
+	assert.EqualError(t, err, "invalid exit webhook: header 'Bad Header' is not a valid HTTP header")
+}
+
+func TestInputProcessing(t *testing.T) {
+	readWithProcessing := func(processing string) (flows.Flow, error) {
+		return definition.ReadFlow([]byte(fmt.Sprintf(`{
+			"uuid": "76f0a02f-3b75-4b86-9064-e9195e1b3a02",
+			"name": "Input Processing",
+			"spec_version": "13.0",
+			"language": "eng",
+			"type": "messaging",
+			"input_processing": %s,
+			"nodes": []
+		}`, processing)), nil)
+	}
+
+	flow, err := readWithProcessing(`{"trim": true, "collapse_whitespace": true, "strip_emojis": true, "max_length": 100}`)
+	require.NoError(t, err)
+	assert.Equal(t, &flows.InputProcessing{Trim: true, CollapseWhitespace: true, StripEmojis: true, MaxLength: 100}, flow.InputProcessing())
+
+	marshaled, err := jsonx.Marshal(flow)
+	require.NoError(t, err)
+	assert.Contains(t, string(marshaled), `"input_processing":{"trim":true,"collapse_whitespace":true,"strip_emojis":true,"max_length":100}`)
+
+	_, err = readWithProcessing(`{"trim": true, "max_length": -1}`)
+	assert.EqualError(t, err, "field 'input_processing.max_length' must be greater than or equal to 0")
+}
+
+func TestFlowYAML(t *testing.T) {
+	original := []byte(`# asks for the contact's name
+uuid: 8ca44c09-791d-453a-9799-a70dd3303306
+name: Ask Name
+spec_version: "13.0"
+language: eng
+type: messaging
+nodes:
+  - uuid: a58be63b-907d-4a1a-856b-0bb5579d7507 # first node
+    actions:
+      # greet them before asking
+      - uuid: ad154980-7bf7-4ab8-8728-545fd6378912
+        type: send_msg
+        text: |-
+          Hi there!
+          What is your name?
+    exits:
+      - uuid: 3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b
+`)
+
+	flow, err := definition.ReadFlowYAML(original, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Ask Name", flow.Name())
+	assert.Equal(t, "Hi there!\nWhat is your name?", flow.Nodes()[0].Actions()[0].(*actions.SendMsgAction).Text)
+
+	// writing it back out as YAML keeps comments and property order, even though the flow has been migrated
+	marshaled, err := definition.MarshalFlowYAML(flow, original)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`# asks for the contact's name
+uuid: 8ca44c09-791d-453a-9799-a70dd3303306
+name: Ask Name
+spec_version: %s
+language: eng
+type: messaging
+nodes:
+  - uuid: a58be63b-907d-4a1a-856b-0bb5579d7507 # first node
+    actions:
+      # greet them before asking
+      - uuid: ad154980-7bf7-4ab8-8728-545fd6378912
+        type: send_msg
+        text: |-
+          Hi there!
+          What is your name?
+    exits:
+      - uuid: 3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b
+revision: 0
+expire_after_minutes: 0
+localization: {}
+`, definition.CurrentSpecVersion), string(marshaled))
+
+	// and can be read back
+	_, err = definition.ReadFlowYAML(marshaled, nil)
+	assert.NoError(t, err)
+
+	// without the original, there are no comments
+	marshaled, err = definition.MarshalFlowYAML(flow, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, string(marshaled), "#")
+
+	_, err = definition.ReadFlowYAML([]byte("nodes: ["), nil)
+	assert.EqualError(t, err, "unable to parse YAML: yaml: line 1: did not find expected node content")
+}
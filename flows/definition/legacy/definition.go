@@ -309,7 +309,7 @@ var testTypeMappings = map[string]string{
 }
 
 // migrates the given legacy action to a new action
-func migrateAction(baseLanguage envs.Language, a Action, localization migratedLocalization, baseMediaURL string) (migratedAction, error) {
+func migrateAction(baseLanguage envs.Language, a Action, localization migratedLocalization, baseMediaURL string, losses *losses) (migratedAction, error) {
 	switch a.Type {
 	case "add_label":
 		labels := make([]*assets.LabelReference, len(a.Labels))
@@ -330,11 +330,11 @@ func migrateAction(baseLanguage envs.Language, a Action, localization migratedLo
 			return nil, err
 		}
 
-		migratedSubject, _ := expressions.MigrateTemplate(a.Subject, nil)
-		migratedBody, _ := expressions.MigrateTemplate(msg, nil)
+		migratedSubject := losses.migrateTemplate(a.Subject, nil)
+		migratedBody := losses.migrateTemplate(msg, nil)
 		migratedEmails := make([]string, len(a.Emails))
 		for i, email := range a.Emails {
-			migratedEmails[i], _ = expressions.MigrateTemplate(email, nil)
+			migratedEmails[i] = losses.migrateTemplate(email, nil)
 		}
 
 		return newSendEmailAction(a.UUID, migratedEmails, migratedSubject, migratedBody), nil
@@ -364,7 +364,7 @@ func migrateAction(baseLanguage envs.Language, a Action, localization migratedLo
 			if variable.ID == "@new_contact" {
 				createContact = true
 			} else {
-				migratedVar, _ := expressions.MigrateTemplate(variable.ID, nil)
+				migratedVar := losses.migrateTemplate(variable.ID, nil)
 				variables = append(variables, migratedVar)
 			}
 		}
@@ -437,7 +437,7 @@ func migrateAction(baseLanguage envs.Language, a Action, localization migratedLo
 		}
 		variables := make([]string, 0, len(a.Variables))
 		for _, variable := range a.Variables {
-			migratedVar, _ := expressions.MigrateTemplate(variable.ID, nil)
+			migratedVar := losses.migrateTemplate(variable.ID, nil)
 			variables = append(variables, migratedVar)
 		}
 
@@ -459,7 +459,7 @@ func migrateAction(baseLanguage envs.Language, a Action, localization migratedLo
 		allGroups := len(groups) == 0
 		return newRemoveContactGroupsAction(a.UUID, groups, allGroups), nil
 	case "save":
-		migratedValue, _ := expressions.MigrateTemplate(a.Value, nil)
+		migratedValue := losses.migrateTemplate(a.Value, nil)
 
 		// flows now have different action for name changing
 		if a.Field == "name" || a.Field == "first_name" {
@@ -503,7 +503,7 @@ func migrateAction(baseLanguage envs.Language, a Action, localization migratedLo
 		return newSayMsgAction(a.UUID, migratedText, migratedAudioURL), nil
 	case "play":
 		// note this URL is already assumed to be absolute
-		migratedAudioURL, _ := expressions.MigrateTemplate(a.URL, nil)
+		migratedAudioURL := losses.migrateTemplate(a.URL, nil)
 
 		return newPlayAudioAction(a.UUID, migratedAudioURL), nil
 	default:
@@ -512,14 +512,14 @@ func migrateAction(baseLanguage envs.Language, a Action, localization migratedLo
 }
 
 // migrates the given legacy rulset to a node with a router
-func migrateRuleSet(lang envs.Language, r RuleSet, validDests map[uuids.UUID]bool, localization migratedLocalization) (migratedNode, UINodeType, NodeUIConfig, error) {
+func migrateRuleSet(lang envs.Language, r RuleSet, validDests map[uuids.UUID]bool, localization migratedLocalization, losses *losses) (migratedNode, UINodeType, NodeUIConfig, error) {
 	var newActions []migratedAction
 	var router migratedRouter
 	var wait migratedWait
 	var uiType UINodeType
 	uiConfig := make(NodeUIConfig)
 
-	cases, categories, defaultCategory, timeoutCategory, exits, err := migrateRules(lang, r, validDests, localization, uiConfig)
+	cases, categories, defaultCategory, timeoutCategory, exits, err := migrateRules(lang, r, validDests, localization, uiConfig, losses)
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -553,7 +553,7 @@ func migrateRuleSet(lang envs.Language, r RuleSet, validDests map[uuids.UUID]boo
 		uiType = UINodeTypeSplitBySubflow
 
 	case "webhook":
-		migratedURL, _ := expressions.MigrateTemplate(config.Webhook, &expressions.MigrateOptions{URLEncode: true})
+		migratedURL := losses.migrateTemplate(config.Webhook, &expressions.MigrateOptions{URLEncode: true})
 		headers := make(map[string]string, len(config.WebhookHeaders))
 		body := ""
 		method := strings.ToUpper(config.WebhookAction)
@@ -569,7 +569,9 @@ func migrateRuleSet(lang envs.Language, r RuleSet, validDests map[uuids.UUID]boo
 		for _, header := range config.WebhookHeaders {
 			// ignore empty headers sometimes left in flow definitions
 			if header.Name != "" {
-				headers[header.Name], _ = expressions.MigrateTemplate(header.Value, nil)
+				headers[header.Name] = losses.migrateTemplate(header.Value, nil)
+			} else if header.Value != "" {
+				losses.add("removed webhook header without a name from rule_set[uuid=%s]", r.UUID)
 			}
 		}
 
@@ -593,7 +595,7 @@ func migrateRuleSet(lang envs.Language, r RuleSet, validDests map[uuids.UUID]boo
 		uiType = UINodeTypeSplitByResthook
 
 	case "form_field":
-		operand, _ := expressions.MigrateTemplate(r.Operand, nil)
+		operand := losses.migrateTemplate(r.Operand, nil)
 		operand = fmt.Sprintf("@(field(%s, %d, \"%s\"))", operand[1:], config.FieldIndex, config.FieldDelimiter)
 		router = newSwitchRouter(nil, resultName, categories, operand, cases, defaultCategory)
 
@@ -683,7 +685,7 @@ func migrateRuleSet(lang envs.Language, r RuleSet, validDests map[uuids.UUID]boo
 			uiType = UINodeTypeSplitByExpression
 		}
 
-		operand, _ := expressions.MigrateTemplate(r.Operand, &expressions.MigrateOptions{DefaultToSelf: defaultToSelf})
+		operand := losses.migrateTemplate(r.Operand, &expressions.MigrateOptions{DefaultToSelf: defaultToSelf})
 		if operand == "" {
 			operand = "@input"
 		}
@@ -753,7 +755,7 @@ type categoryAndExit struct {
 }
 
 // migrates a set of legacy rules to sets of categories, cases and exits
-func migrateRules(baseLanguage envs.Language, r RuleSet, validDests map[uuids.UUID]bool, localization migratedLocalization, uiConfig NodeUIConfig) ([]migratedCase, []migratedCategory, uuids.UUID, uuids.UUID, []migratedExit, error) {
+func migrateRules(baseLanguage envs.Language, r RuleSet, validDests map[uuids.UUID]bool, localization migratedLocalization, uiConfig NodeUIConfig, losses *losses) ([]migratedCase, []migratedCategory, uuids.UUID, uuids.UUID, []migratedExit, error) {
 	cases := make([]migratedCase, 0, len(r.Rules))
 	categories := make([]migratedCategory, 0, len(r.Rules))
 	exits := make([]migratedExit, 0, len(r.Rules))
@@ -777,6 +779,8 @@ func migrateRules(baseLanguage envs.Language, r RuleSet, validDests map[uuids.UU
 			var destinationUUID uuids.UUID
 			if validDests[rule.Destination] {
 				destinationUUID = rule.Destination
+			} else if rule.Destination != "" {
+				losses.add("removed destination %s of rule[uuid=%s] which isn't a valid node", rule.Destination, rule.UUID)
 			}
 
 			// rule UUIDs in legacy flows determine path data, so their UUIDs become the exit UUIDs
@@ -814,7 +818,7 @@ func migrateRules(baseLanguage envs.Language, r RuleSet, validDests map[uuids.UU
 			defaultCategoryUUID = uuids.UUID(converted.category.UUID())
 		}
 
-		kase, caseUI, err := migrateRule(baseLanguage, rule, converted.category, localization)
+		kase, caseUI, err := migrateRule(baseLanguage, rule, converted.category, localization, losses)
 		if err != nil {
 			return nil, nil, "", "", nil, err
 		}
@@ -832,7 +836,7 @@ func migrateRules(baseLanguage envs.Language, r RuleSet, validDests map[uuids.UU
 }
 
 // migrates the given legacy rule to a router case
-func migrateRule(baseLanguage envs.Language, r Rule, category migratedCategory, localization migratedLocalization) (migratedCase, map[string]interface{}, error) {
+func migrateRule(baseLanguage envs.Language, r Rule, category migratedCategory, localization migratedLocalization, losses *losses) (migratedCase, map[string]interface{}, error) {
 	newType := testTypeMappings[r.Test.Type]
 	var arguments []string
 	var err error
@@ -853,7 +857,7 @@ func migrateRule(baseLanguage envs.Language, r Rule, category migratedCategory,
 		if err != nil {
 			return nil, nil, err
 		}
-		migratedTest, _ := expressions.MigrateTemplate(string(test.Test), nil)
+		migratedTest := losses.migrateTemplate(string(test.Test), nil)
 		arguments = []string{migratedTest}
 
 	case "between":
@@ -863,8 +867,8 @@ func migrateRule(baseLanguage envs.Language, r Rule, category migratedCategory,
 			return nil, nil, err
 		}
 
-		migratedMin, _ := expressions.MigrateTemplate(test.Min, nil)
-		migratedMax, _ := expressions.MigrateTemplate(test.Max, nil)
+		migratedMin := losses.migrateTemplate(test.Min, nil)
+		migratedMax := losses.migrateTemplate(test.Max, nil)
 
 		arguments = []string{migratedMin, migratedMax}
 
@@ -880,7 +884,7 @@ func migrateRule(baseLanguage envs.Language, r Rule, category migratedCategory,
 
 		// all the tests are evaluated as templates.. except regex
 		if r.Test.Type != "regex" {
-			baseTest, _ = expressions.MigrateTemplate(baseTest, nil)
+			baseTest = losses.migrateTemplate(baseTest, nil)
 
 		}
 		arguments = []string{baseTest}
@@ -894,7 +898,7 @@ func migrateRule(baseLanguage envs.Language, r Rule, category migratedCategory,
 		if err != nil {
 			return nil, nil, err
 		}
-		migratedTest, _ := expressions.MigrateTemplate(test.Test, &expressions.MigrateOptions{RawDates: true})
+		migratedTest := losses.migrateTemplate(test.Test, &expressions.MigrateOptions{RawDates: true})
 
 		var delta int
 		match := relativeDateTest.FindStringSubmatch(test.Test)
@@ -950,7 +954,7 @@ func migrateRule(baseLanguage envs.Language, r Rule, category migratedCategory,
 			return nil, nil, err
 		}
 
-		migratedState, _ := expressions.MigrateTemplate(test.Test, nil)
+		migratedState := losses.migrateTemplate(test.Test, nil)
 
 		arguments = []string{migratedState}
 
@@ -961,8 +965,8 @@ func migrateRule(baseLanguage envs.Language, r Rule, category migratedCategory,
 			return nil, nil, err
 		}
 
-		migratedDistrict, _ := expressions.MigrateTemplate(test.District, nil)
-		migratedState, _ := expressions.MigrateTemplate(test.State, nil)
+		migratedDistrict := losses.migrateTemplate(test.District, nil)
+		migratedState := losses.migrateTemplate(test.State, nil)
 
 		arguments = []string{migratedDistrict, migratedState}
 
@@ -974,12 +978,12 @@ func migrateRule(baseLanguage envs.Language, r Rule, category migratedCategory,
 }
 
 // migrates the given legacy actionset to a node with a set of migrated actions and a single exit
-func migrateActionSet(lang envs.Language, a ActionSet, validDests map[uuids.UUID]bool, localization migratedLocalization, baseMediaURL string) (migratedNode, error) {
+func migrateActionSet(lang envs.Language, a ActionSet, validDests map[uuids.UUID]bool, localization migratedLocalization, baseMediaURL string, losses *losses) (migratedNode, error) {
 	actions := make([]migratedAction, len(a.Actions))
 
 	// migrate each action
 	for i := range a.Actions {
-		action, err := migrateAction(lang, a.Actions[i], localization, baseMediaURL)
+		action, err := migrateAction(lang, a.Actions[i], localization, baseMediaURL, losses)
 		if err != nil {
 			return nil, errors.Wrapf(err, "error migrating action[type=%s]", a.Actions[i].Type)
 		}
@@ -990,6 +994,8 @@ func migrateActionSet(lang envs.Language, a ActionSet, validDests map[uuids.UUID
 	var destinationUUID uuids.UUID
 	if validDests[a.Destination] {
 		destinationUUID = a.Destination
+	} else if a.Destination != "" {
+		losses.add("removed destination %s of action_set[uuid=%s] which isn't a valid node", a.Destination, a.UUID)
 	}
 
 	exit := newExit(a.ExitUUID, destinationUUID)
@@ -1010,7 +1016,7 @@ func readLegacyFlow(data json.RawMessage) (*Flow, error) {
 	return f, nil
 }
 
-func migrateNodes(f *Flow, baseMediaURL string) ([]migratedNode, map[uuids.UUID]*NodeUI, migratedLocalization, error) {
+func migrateNodes(f *Flow, baseMediaURL string, losses *losses) ([]migratedNode, map[uuids.UUID]*NodeUI, migratedLocalization, error) {
 	localization := make(migratedLocalization)
 	numNodes := len(f.ActionSets) + len(f.RuleSets)
 	nodes := make([]migratedNode, numNodes)
@@ -1026,7 +1032,7 @@ func migrateNodes(f *Flow, baseMediaURL string) ([]migratedNode, map[uuids.UUID]
 	}
 
 	for i, actionSet := range f.ActionSets {
-		node, err := migrateActionSet(f.BaseLanguage, actionSet, validDestinations, localization, baseMediaURL)
+		node, err := migrateActionSet(f.BaseLanguage, actionSet, validDestinations, localization, baseMediaURL, losses)
 		if err != nil {
 			return nil, nil, nil, errors.Wrapf(err, "error migrating action_set[uuid=%s]", actionSet.UUID)
 		}
@@ -1035,7 +1041,7 @@ func migrateNodes(f *Flow, baseMediaURL string) ([]migratedNode, map[uuids.UUID]
 	}
 
 	for i, ruleSet := range f.RuleSets {
-		node, uiType, uiNodeConfig, err := migrateRuleSet(f.BaseLanguage, ruleSet, validDestinations, localization)
+		node, uiType, uiNodeConfig, err := migrateRuleSet(f.BaseLanguage, ruleSet, validDestinations, localization, losses)
 		if err != nil {
 			return nil, nil, nil, errors.Wrapf(err, "error migrating rule_set[uuid=%s]", ruleSet.UUID)
 		}
@@ -1072,7 +1078,11 @@ func migrateNodes(f *Flow, baseMediaURL string) ([]migratedNode, map[uuids.UUID]
 
 // Migrate migrates this legacy flow to the new format
 func (f *Flow) Migrate(baseMediaURL string) ([]byte, error) {
-	nodes, nodeUIs, localization, err := migrateNodes(f, baseMediaURL)
+	return f.migrate(baseMediaURL, &losses{})
+}
+
+func (f *Flow) migrate(baseMediaURL string, losses *losses) ([]byte, error) {
+	nodes, nodeUIs, localization, err := migrateNodes(f, baseMediaURL, losses)
 	if err != nil {
 		return nil, err
 	}
@@ -1130,10 +1140,22 @@ func IsPossibleDefinition(data json.RawMessage) bool {
 
 // MigrateDefinition migrates a legacy definition to 13.0.0
 func MigrateDefinition(data json.RawMessage, baseMediaURL string) (json.RawMessage, error) {
+	migrated, _, err := MigrateDefinitionWithLosses(data, baseMediaURL)
+	return migrated, err
+}
+
+// MigrateDefinitionWithLosses migrates a legacy definition to 13.0.0, and also returns descriptions of the parts of it
+// which couldn't be migrated exactly, e.g. expressions which couldn't be parsed and exits to nodes which don't exist
+func MigrateDefinitionWithLosses(data json.RawMessage, baseMediaURL string) (json.RawMessage, []string, error) {
 	legacyFlow, err := readLegacyFlow(data)
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to read legacy flow")
+		return nil, nil, errors.Wrap(err, "unable to read legacy flow")
 	}
 
-	return legacyFlow.Migrate(baseMediaURL)
+	losses := &losses{}
+	migrated, err := legacyFlow.migrate(baseMediaURL, losses)
+	if err != nil {
+		return nil, nil, err
+	}
+	return migrated, losses.descriptions, nil
 }
@@ -327,3 +327,38 @@ func TestMigrateDefinition(t *testing.T) {
 		}
 	}`), migrated, "migrated flow mismatch")
 }
+
+func TestMigrateDefinitionWithLosses(t *testing.T) {
+	migrated, losses, err := legacy.MigrateDefinitionWithLosses([]byte(`{
+		"flow_type": "F",
+		"base_language": "eng",
+		"entry": "10e483a8-5ffb-4c4f-917b-d43ce86c1d65",
+		"action_sets": [{
+			"uuid": "10e483a8-5ffb-4c4f-917b-d43ce86c1d65",
+			"y": 100,
+			"x": 100,
+			"destination": "e8b5e2b1-7b2b-4c47-8d35-a8b8e1b0a7a6",
+			"exit_uuid": "cfcf5cef-49f9-41a6-886b-f466575a3045",
+			"actions": [
+				{"type": "email", "uuid": "8ba5b7d1-8bdb-4a5b-b1b3-4f5ac0e2c3e1", "emails": ["bob@nyaruka.com"], "subject": "Hi @(contact.name +)", "msg": "Hello"}
+			]
+		}],
+		"rule_sets": [],
+		"metadata": {
+			"uuid": "061be894-4507-470c-a20b-34273bf915be",
+			"name": "Lossy"
+		}
+	}`), "")
+
+	require.NoError(t, err)
+	assert.NotNil(t, migrated)
+	assert.Equal(t, []string{
+		"unable to migrate expressions in 'Hi @(contact.name +)': error evaluating @(contact.name +): syntax error at ",
+		"removed destination e8b5e2b1-7b2b-4c47-8d35-a8b8e1b0a7a6 of action_set[uuid=10e483a8-5ffb-4c4f-917b-d43ce86c1d65] which isn't a valid node",
+	}, losses)
+
+	// a definition which migrates exactly has no losses
+	_, losses, err = legacy.MigrateDefinitionWithLosses([]byte(`{"flow_type": "S", "action_sets": [], "rule_sets": [], "base_language": "eng", "metadata": {"uuid": "061be894-4507-470c-a20b-34273bf915be", "name": "Survey"}}`), "")
+	require.NoError(t, err)
+	assert.Len(t, losses, 0)
+}
@@ -0,0 +1,25 @@
+package legacy
+
+import (
+	"fmt"
+
+	"github.com/nyaruka/goflow/flows/definition/legacy/expressions"
+)
+
+// collects descriptions of the parts of a legacy definition which can't be migrated exactly
+type losses struct {
+	descriptions []string
+}
+
+func (l *losses) add(description string, args ...any) {
+	l.descriptions = append(l.descriptions, fmt.Sprintf(description, args...))
+}
+
+// migrates the given template, noting a loss if any of its expressions can't be migrated and so are left as they are
+func (l *losses) migrateTemplate(template string, options *expressions.MigrateOptions) string {
+	migrated, err := expressions.MigrateTemplate(template, options)
+	if err != nil {
+		l.add("unable to migrate expressions in '%s': %s", template, err)
+	}
+	return migrated
+}
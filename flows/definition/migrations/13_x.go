@@ -22,6 +22,9 @@ func Migrate13_2(f Flow, cfg *Config) (Flow, error) {
 	if len(language) != 3 {
 		f["language"] = "und"
 		if localization != nil {
+			if und, _ := localization["und"].(map[string]interface{}); len(und) > 0 {
+				cfg.addLoss("removed 'und' translations as that is now the flow language")
+			}
 			delete(localization, "und")
 		}
 	}
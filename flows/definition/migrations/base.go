@@ -1,6 +1,7 @@
 package migrations
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 
@@ -39,6 +40,21 @@ type Header13 struct {
 // Config configures how flow migrations are handled
 type Config struct {
 	BaseMediaURL string
+
+	reportLoss func(string) // set while migrating with loss reporting
+}
+
+// notes a part of the definition which the current migration can't convert exactly
+func (c *Config) addLoss(description string, args ...any) {
+	if c.reportLoss != nil {
+		c.reportLoss(fmt.Sprintf(description, args...))
+	}
+}
+
+// Loss is a part of a flow definition which couldn't be migrated exactly, e.g. an expression which couldn't be parsed
+type Loss struct {
+	Version     string `json:"version"`
+	Description string `json:"description"`
 }
 
 var DefaultConfig = &Config{}
@@ -48,8 +64,27 @@ func MigrateToLatest(data []byte, cfg *Config) ([]byte, error) {
 	return MigrateToVersion(data, nil, cfg)
 }
 
+// MigrateToLatestWithLosses migrates the given flow definition to the latest version, and also returns the parts of it
+// which couldn't be migrated exactly by each version's migration
+func MigrateToLatestWithLosses(data []byte, cfg *Config) ([]byte, []*Loss, error) {
+	losses := make([]*Loss, 0)
+	migrated, err := migrateToVersion(data, nil, cfg, &losses)
+	if err != nil {
+		return nil, nil, err
+	}
+	return migrated, losses, nil
+}
+
 // MigrateToVersion migrates the given flow definition to the given version
 func MigrateToVersion(data []byte, to *semver.Version, cfg *Config) ([]byte, error) {
+	return migrateToVersion(data, to, cfg, nil)
+}
+
+func migrateToVersion(data []byte, to *semver.Version, cfg *Config, losses *[]*Loss) ([]byte, error) {
+	if cfg == nil {
+		cfg = DefaultConfig
+	}
+
 	// try to read new style header (uuid, name, spec_version)
 	header := &Header13{}
 	err := utils.UnmarshalAndValidate(data, header)
@@ -59,10 +94,17 @@ func MigrateToVersion(data []byte, to *semver.Version, cfg *Config) ([]byte, err
 		if legacy.IsPossibleDefinition(data) {
 			// try to migrate it forwards to 13.0.0
 			var err error
-			data, err = legacy.MigrateDefinition(data, cfg.BaseMediaURL)
+			var legacyLosses []string
+			data, legacyLosses, err = legacy.MigrateDefinitionWithLosses(data, cfg.BaseMediaURL)
 			if err != nil {
 				return nil, errors.Wrap(err, "error migrating what appears to be a legacy definition")
 			}
+
+			if losses != nil {
+				for _, description := range legacyLosses {
+					*losses = append(*losses, &Loss{Version: "13.0.0", Description: description})
+				}
+			}
 		}
 
 		// try reading header again
@@ -73,10 +115,10 @@ func MigrateToVersion(data []byte, to *semver.Version, cfg *Config) ([]byte, err
 		return nil, errors.Wrap(err, "unable to read flow header")
 	}
 
-	return migrate(data, header.SpecVersion, to, cfg)
+	return migrate(data, header.SpecVersion, to, cfg, losses)
 }
 
-func migrate(data []byte, from *semver.Version, to *semver.Version, cfg *Config) ([]byte, error) {
+func migrate(data []byte, from *semver.Version, to *semver.Version, cfg *Config, losses *[]*Loss) ([]byte, error) {
 	// get all newer versions than this version
 	versions := make([]*semver.Version, 0)
 	for v := range registered {
@@ -99,7 +141,19 @@ func migrate(data []byte, from *semver.Version, to *semver.Version, cfg *Config)
 	}
 
 	for _, version := range versions {
-		migrated, err = registered[version](migrated, cfg)
+		versionCfg := cfg
+
+		// give the migration a copy of the config which reports losses against this version
+		if losses != nil {
+			version := version
+			copied := *cfg
+			copied.reportLoss = func(description string) {
+				*losses = append(*losses, &Loss{Version: version.String(), Description: description})
+			}
+			versionCfg = &copied
+		}
+
+		migrated, err = registered[version](migrated, versionCfg)
 		if err != nil {
 			return nil, errors.Wrapf(err, "unable to migrate to version %s", version.String())
 		}
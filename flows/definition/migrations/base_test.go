@@ -140,6 +140,65 @@ func TestMigrateToLatest(t *testing.T) {
 	test.AssertEqualJSON(t, []byte(expected), migrated, "flow migration mismatch")
 }
 
+func TestMigrateToLatestWithLosses(t *testing.T) {
+	// a legacy definition with an exit to a node which doesn't exist
+	migrated, losses, err := migrations.MigrateToLatestWithLosses([]byte(`{
+		"base_language": "base",
+		"entry": "10e483a8-5ffb-4c4f-917b-d43ce86c1d65",
+		"flow_type": "M",
+		"action_sets": [{
+			"uuid": "10e483a8-5ffb-4c4f-917b-d43ce86c1d65",
+			"y": 100,
+			"x": 100,
+			"destination": "e8b5e2b1-7b2b-4c47-8d35-a8b8e1b0a7a6",
+			"exit_uuid": "cfcf5cef-49f9-41a6-886b-f466575a3045",
+			"actions": [{"type": "lang", "uuid": "8ba5b7d1-8bdb-4a5b-b1b3-4f5ac0e2c3e1", "lang": "fra", "name": "French"}]
+		}],
+		"metadata": {
+			"uuid": "50c3706e-fedb-42c0-8eab-dda3335714b7",
+			"name": "Test Flow"
+		}
+	}`), migrations.DefaultConfig)
+
+	require.NoError(t, err)
+	assert.Contains(t, string(migrated), `"language":"und"`)
+	assert.Equal(t, []*migrations.Loss{
+		{Version: "13.0.0", Description: "removed destination e8b5e2b1-7b2b-4c47-8d35-a8b8e1b0a7a6 of action_set[uuid=10e483a8-5ffb-4c4f-917b-d43ce86c1d65] which isn't a valid node"},
+	}, losses)
+
+	// a definition which uses base as its language and also has und translations
+	_, losses, err = migrations.MigrateToLatestWithLosses([]byte(`{
+		"uuid": "76f0a02f-3b75-4b86-9064-e9195e1b3a02",
+		"name": "Base Flow",
+		"spec_version": "13.1.0",
+		"language": "base",
+		"type": "messaging",
+		"localization": {"und": {"e97cd6d5-3354-4dbd-85bc-6c1f87849eec": {"text": ["Hola"]}}},
+		"nodes": []
+	}`), migrations.DefaultConfig)
+
+	require.NoError(t, err)
+	assert.Equal(t, []*migrations.Loss{
+		{Version: "13.2.0", Description: "removed 'und' translations as that is now the flow language"},
+	}, losses)
+
+	// a current definition has no losses
+	_, losses, err = migrations.MigrateToLatestWithLosses([]byte(`{
+		"uuid": "76f0a02f-3b75-4b86-9064-e9195e1b3a02",
+		"name": "Empty Flow",
+		"spec_version": "13.0",
+		"language": "eng",
+		"type": "messaging",
+		"nodes": []
+	}`), migrations.DefaultConfig)
+
+	require.NoError(t, err)
+	assert.Equal(t, []*migrations.Loss{}, losses)
+
+	_, _, err = migrations.MigrateToLatestWithLosses([]byte(`[]`), migrations.DefaultConfig)
+	assert.EqualError(t, err, "unable to read flow header: json: cannot unmarshal array into Go value of type migrations.Header13")
+}
+
 func TestClone(t *testing.T) {
 	env := envs.NewBuilder().Build()
 
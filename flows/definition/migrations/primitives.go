@@ -11,6 +11,23 @@ func (f Flow) Localization() Localization {
 
 type Localization map[string]interface{}
 
+// ExpressionsVersion returns the version of the expressions language used by this flow, which is 1 if not set
+func (f Flow) ExpressionsVersion() int {
+	switch v := f["expressions_version"].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 1
+}
+
+// SetExpressionsVersion sets the version of the expressions language used by this flow, e.g. when a migration
+// rewrites expressions so that they work the same in a later version
+func (f Flow) SetExpressionsVersion(v int) {
+	f["expressions_version"] = v
+}
+
 // Nodes returns the nodes in this flow
 func (f Flow) Nodes() []Node {
 	d, _ := f["nodes"].([]interface{})
@@ -24,6 +41,12 @@ func (f Flow) Nodes() []Node {
 // Node holds a node definition
 type Node map[string]interface{}
 
+// UUID returns the UUID of this node
+func (n Node) UUID() string {
+	d, _ := n["uuid"].(string)
+	return d
+}
+
 // Actions returns the actions on this node
 func (n Node) Actions() []Action {
 	d, _ := n["actions"].([]interface{})
@@ -40,6 +63,42 @@ func (n Node) Router() Router {
 	return Router(d)
 }
 
+// Exits returns the exits of this node
+func (n Node) Exits() []Exit {
+	d, _ := n["exits"].([]interface{})
+	exits := make([]Exit, len(d))
+	for i := range d {
+		exits[i] = Exit(d[i].(map[string]interface{}))
+	}
+	return exits
+}
+
+// AddExit adds an exit to this node
+func (n Node) AddExit(e Exit) {
+	d, _ := n["exits"].([]interface{})
+	n["exits"] = append(d, map[string]interface{}(e))
+}
+
+// Exit holds an exit definition
+type Exit map[string]interface{}
+
+// UUID returns the UUID of this exit
+func (e Exit) UUID() string {
+	d, _ := e["uuid"].(string)
+	return d
+}
+
+// DestinationUUID returns the UUID of the node this exit goes to, if any
+func (e Exit) DestinationUUID() string {
+	d, _ := e["destination_uuid"].(string)
+	return d
+}
+
+// ClearDestination removes the destination of this exit
+func (e Exit) ClearDestination() {
+	delete(e, "destination_uuid")
+}
+
 // Action holds an action definition
 type Action map[string]interface{}
 
@@ -57,3 +116,75 @@ func (r Router) Type() string {
 	d, _ := r["type"].(string)
 	return d
 }
+
+// Categories returns the categories of this router
+func (r Router) Categories() []Category {
+	d, _ := r["categories"].([]interface{})
+	categories := make([]Category, len(d))
+	for i := range d {
+		categories[i] = Category(d[i].(map[string]interface{}))
+	}
+	return categories
+}
+
+// AddCategory adds a category to this router
+func (r Router) AddCategory(c Category) {
+	d, _ := r["categories"].([]interface{})
+	r["categories"] = append(d, map[string]interface{}(c))
+}
+
+// DefaultCategoryUUID returns the UUID of the default category of this router, if it has one
+func (r Router) DefaultCategoryUUID() string {
+	d, _ := r["default_category_uuid"].(string)
+	return d
+}
+
+// SetDefaultCategoryUUID sets the UUID of the default category of this router
+func (r Router) SetDefaultCategoryUUID(uuid string) {
+	r["default_category_uuid"] = uuid
+}
+
+// Cases returns the cases of this router
+func (r Router) Cases() []Case {
+	d, _ := r["cases"].([]interface{})
+	cases := make([]Case, len(d))
+	for i := range d {
+		cases[i] = Case(d[i].(map[string]interface{}))
+	}
+	return cases
+}
+
+// Category holds a category definition
+type Category map[string]interface{}
+
+// UUID returns the UUID of this category
+func (c Category) UUID() string {
+	d, _ := c["uuid"].(string)
+	return d
+}
+
+// Name returns the name of this category
+func (c Category) Name() string {
+	d, _ := c["name"].(string)
+	return d
+}
+
+// ExitUUID returns the UUID of the exit of this category
+func (c Category) ExitUUID() string {
+	d, _ := c["exit_uuid"].(string)
+	return d
+}
+
+// SetExitUUID sets the UUID of the exit of this category
+func (c Category) SetExitUUID(uuid string) {
+	c["exit_uuid"] = uuid
+}
+
+// Case holds a case definition
+type Case map[string]interface{}
+
+// CategoryUUID returns the UUID of the category of this case
+func (c Case) CategoryUUID() string {
+	d, _ := c["category_uuid"].(string)
+	return d
+}
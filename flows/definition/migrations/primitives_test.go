@@ -22,6 +22,13 @@ func TestMigrationPrimitives(t *testing.T) {
 		map[string]interface{}{},
 	}})
 	assert.Equal(t, []migrations.Node{migrations.Node(map[string]interface{}{})}, f.Nodes())
+	assert.Equal(t, 1, f.ExpressionsVersion()) // expressions version not set
+
+	f = migrations.Flow(map[string]interface{}{"expressions_version": float64(2)}) // as read from JSON
+	assert.Equal(t, 2, f.ExpressionsVersion())
+
+	f.SetExpressionsVersion(3)
+	assert.Equal(t, 3, f.ExpressionsVersion())
 
 	n := migrations.Node(map[string]interface{}{}) // actions and router are not set
 	assert.Equal(t, []migrations.Action{}, n.Actions())
@@ -38,6 +45,32 @@ func TestMigrationPrimitives(t *testing.T) {
 	assert.Equal(t, []migrations.Action{}, n.Actions())
 	assert.Equal(t, migrations.Router(map[string]interface{}{}), n.Router())
 
+	n = migrations.Node(map[string]interface{}{"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507"}) // exits not set
+	assert.Equal(t, "a58be63b-907d-4a1a-856b-0bb5579d7507", n.UUID())
+	assert.Equal(t, []migrations.Exit{}, n.Exits())
+
+	n.AddExit(migrations.Exit{"uuid": "3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b", "destination_uuid": "b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e"})
+	assert.Len(t, n.Exits(), 1)
+	assert.Equal(t, "3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b", n.Exits()[0].UUID())
+	assert.Equal(t, "b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e", n.Exits()[0].DestinationUUID())
+
+	n.Exits()[0].ClearDestination()
+	assert.Equal(t, "", n.Exits()[0].DestinationUUID())
+
+	r := migrations.Router(map[string]interface{}{}) // categories, cases and default not set
+	assert.Equal(t, []migrations.Category{}, r.Categories())
+	assert.Equal(t, []migrations.Case{}, r.Cases())
+	assert.Equal(t, "", r.DefaultCategoryUUID())
+
+	r.AddCategory(migrations.Category{"uuid": "97b9451c-2856-475b-af38-32af68100897", "name": "Other"})
+	r.SetDefaultCategoryUUID("97b9451c-2856-475b-af38-32af68100897")
+	assert.Equal(t, "Other", r.Categories()[0].Name())
+	assert.Equal(t, "", r.Categories()[0].ExitUUID())
+	assert.Equal(t, "97b9451c-2856-475b-af38-32af68100897", r.DefaultCategoryUUID())
+
+	r.Categories()[0].SetExitUUID("3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b")
+	assert.Equal(t, "3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b", r.Categories()[0].ExitUUID())
+
 	a := migrations.Action(map[string]interface{}{}) // type not set
 	assert.Equal(t, "", a.Type())
 
@@ -0,0 +1,181 @@
+package definition
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/definition/migrations"
+)
+
+// RepairType is the type of a repair made to a flow definition
+type RepairType string
+
+// possible types of repair
+const (
+	RepairTypeDanglingExit           RepairType = "dangling_exit"
+	RepairTypeExitlessNode           RepairType = "exitless_node"
+	RepairTypeExitlessCategory       RepairType = "exitless_category"
+	RepairTypeMissingDefaultCategory RepairType = "missing_default_category"
+)
+
+// name of the category added to switch routers which don't have a default
+const defaultCategoryName = "Other"
+
+// Repair is a change made to a flow definition to fix a problem with it
+type Repair struct {
+	Type        RepairType     `json:"type"`
+	NodeUUID    flows.NodeUUID `json:"node_uuid"`
+	Description string         `json:"description"`
+}
+
+func newRepair(type_ RepairType, node migrations.Node, description string, args ...any) *Repair {
+	return &Repair{Type: type_, NodeUUID: flows.NodeUUID(node.UUID()), Description: fmt.Sprintf(description, args...)}
+}
+
+// RepairFlow reads the given flow definition after fixing problems which are common in generated definitions and which
+// would otherwise make it invalid or fail at runtime. These are exits which go to nodes which don't exist, nodes and
+// categories without exits, and switch routers without a default category. It returns the repaired flow and the
+// repairs which were made.
+func RepairFlow(data json.RawMessage, mc *migrations.Config) (flows.Flow, []*Repair, error) {
+	if mc == nil {
+		mc = migrations.DefaultConfig
+	}
+
+	migrated, err := migrations.MigrateToLatest(data, mc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var f migrations.Flow
+	if err := jsonx.Unmarshal(migrated, &f); err != nil {
+		return nil, nil, err
+	}
+
+	repairs := repairDefinition(f)
+
+	flow, err := ReadFlow(jsonx.MustMarshal(f), mc)
+	if err != nil {
+		return nil, repairs, err
+	}
+	return flow, repairs, nil
+}
+
+// repairs the given definition in place, returning the repairs made
+func repairDefinition(f migrations.Flow) []*Repair {
+	repairs := make([]*Repair, 0)
+
+	nodeUUIDs := make(map[string]bool)
+	for _, node := range f.Nodes() {
+		nodeUUIDs[node.UUID()] = true
+	}
+
+	for _, node := range f.Nodes() {
+		router := node.Router()
+
+		if router != nil {
+			repairs = append(repairs, repairCategoryExits(node, router)...)
+
+			if router.Type() == "switch" {
+				if repair := repairDefaultCategory(node, router); repair != nil {
+					repairs = append(repairs, repair)
+				}
+			}
+		}
+
+		// every node needs at least one exit
+		if len(node.Exits()) == 0 {
+			exit := newExit()
+			node.AddExit(exit)
+			repairs = append(repairs, newRepair(RepairTypeExitlessNode, node, "added exit %s to node without exits", exit.UUID()))
+		}
+
+		for _, exit := range node.Exits() {
+			if dest := exit.DestinationUUID(); dest != "" && !nodeUUIDs[dest] {
+				exit.ClearDestination()
+				repairs = append(repairs, newRepair(RepairTypeDanglingExit, node, "removed destination %s of exit %s which isn't a known node", dest, exit.UUID()))
+			}
+		}
+	}
+
+	return repairs
+}
+
+// gives an exit to each category of the given router which doesn't have a valid one
+func repairCategoryExits(node migrations.Node, router migrations.Router) []*Repair {
+	exitUUIDs := make(map[string]bool)
+	for _, exit := range node.Exits() {
+		exitUUIDs[exit.UUID()] = true
+	}
+
+	repairs := make([]*Repair, 0)
+
+	for _, category := range router.Categories() {
+		if !exitUUIDs[category.ExitUUID()] {
+			exit := newExit()
+			node.AddExit(exit)
+			exitUUIDs[exit.UUID()] = true
+			category.SetExitUUID(exit.UUID())
+
+			repairs = append(repairs, newRepair(RepairTypeExitlessCategory, node, "added exit %s for category '%s' which didn't have a valid exit", exit.UUID(), category.Name()))
+		}
+	}
+	return repairs
+}
+
+// makes sure that the given switch router has a default category, by using a category that isn't otherwise used or
+// by adding one
+func repairDefaultCategory(node migrations.Node, router migrations.Router) *Repair {
+	categoryUUIDs := make(map[string]bool)
+	for _, category := range router.Categories() {
+		categoryUUIDs[category.UUID()] = true
+	}
+
+	if categoryUUIDs[router.DefaultCategoryUUID()] {
+		return nil
+	}
+
+	used := usedCategories(router)
+	for _, category := range router.Categories() {
+		if !used[category.UUID()] {
+			router.SetDefaultCategoryUUID(category.UUID())
+			return newRepair(RepairTypeMissingDefaultCategory, node, "set default category to unused category '%s'", category.Name())
+		}
+	}
+
+	exit := newExit()
+	node.AddExit(exit)
+
+	category := migrations.Category{"uuid": string(uuids.New()), "name": defaultCategoryName, "exit_uuid": exit.UUID()}
+	router.AddCategory(category)
+	router.SetDefaultCategoryUUID(category.UUID())
+
+	return newRepair(RepairTypeMissingDefaultCategory, node, "added default category '%s' with exit %s", defaultCategoryName, exit.UUID())
+}
+
+// gets the UUIDs of the categories of the given router which are used by its cases or its wait
+func usedCategories(router migrations.Router) map[string]bool {
+	used := make(map[string]bool)
+	for _, c := range router.Cases() {
+		used[c.CategoryUUID()] = true
+	}
+
+	wait, _ := router["wait"].(map[string]interface{})
+	if timeout, _ := wait["timeout"].(map[string]interface{}); timeout != nil {
+		uuid, _ := timeout["category_uuid"].(string)
+		used[uuid] = true
+	}
+	branches, _ := wait["branches"].([]interface{})
+	for _, b := range branches {
+		branch, _ := b.(map[string]interface{})
+		uuid, _ := branch["category_uuid"].(string)
+		used[uuid] = true
+	}
+	return used
+}
+
+func newExit() migrations.Exit {
+	return migrations.Exit{"uuid": string(uuids.New())}
+}
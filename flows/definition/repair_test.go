@@ -0,0 +1,150 @@
+package definition_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/definition"
+	"github.com/nyaruka/goflow/flows/routers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairFlow(t *testing.T) {
+	defer uuids.SetGenerator(uuids.DefaultGenerator)
+	uuids.SetGenerator(uuids.NewSeededGenerator(12345))
+
+	broken := []byte(`{
+		"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+		"name": "Generated Flow",
+		"spec_version": "13.2.0",
+		"language": "eng",
+		"type": "messaging",
+		"nodes": [
+			{
+				"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"actions": [{"uuid": "ad154980-7bf7-4ab8-8728-545fd6378912", "type": "send_msg", "text": "Do you like cheese?"}],
+				"exits": [{"uuid": "3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b", "destination_uuid": "b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e"}]
+			},
+			{
+				"uuid": "b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e",
+				"router": {
+					"type": "switch",
+					"wait": {"type": "msg"},
+					"operand": "@input.text",
+					"cases": [
+						{"uuid": "9f593e22-7886-4c08-a52f-0e8780504d75", "type": "has_any_word", "arguments": ["yes"], "category_uuid": "97b9451c-2856-475b-af38-32af68100897"},
+						{"uuid": "c2e4b3a1-0b8a-4c6d-9e5f-1a2b3c4d5e6f", "type": "has_any_word", "arguments": ["no"], "category_uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3"}
+					],
+					"categories": [
+						{"uuid": "97b9451c-2856-475b-af38-32af68100897", "name": "Yes", "exit_uuid": "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d"},
+						{"uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3", "name": "No", "exit_uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}
+					]
+				},
+				"exits": [
+					{"uuid": "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d", "destination_uuid": "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b"}
+				]
+			},
+			{
+				"uuid": "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b",
+				"actions": [{"uuid": "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f", "type": "send_msg", "text": "Great!"}]
+			}
+		]
+	}`)
+
+	// the broken definition can't be read as is
+	_, err := definition.ReadFlow(broken, nil)
+	assert.Error(t, err)
+
+	flow, repairs, err := definition.RepairFlow(broken, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []*definition.Repair{
+		{
+			Type:        definition.RepairTypeExitlessCategory,
+			NodeUUID:    "b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e",
+			Description: "added exit 1ae96956-4b34-433e-8d1a-f05fe6923d6d for category 'No' which didn't have a valid exit",
+		},
+		{
+			Type:        definition.RepairTypeMissingDefaultCategory,
+			NodeUUID:    "b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e",
+			Description: "added default category 'Other' with exit e7187099-7d38-4f60-955c-325957214c42",
+		},
+		{
+			Type:        definition.RepairTypeExitlessNode,
+			NodeUUID:    "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b",
+			Description: "added exit 9688d21d-95aa-4bed-afc7-f31b35731a3d to node without exits",
+		},
+	}, repairs)
+
+	router := flow.GetNode("b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e").Router().(*routers.SwitchRouter)
+	assert.Len(t, router.Categories(), 3)
+	assert.Equal(t, "Other", router.Categories()[2].Name())
+	assert.Equal(t, router.Categories()[2].UUID(), router.DefaultCategoryUUID())
+	assert.Len(t, flow.GetNode("b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e").Exits(), 3)
+
+	// a node which goes to a node that doesn't exist has that destination removed
+	dangling := []byte(`{
+		"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+		"name": "Generated Flow",
+		"spec_version": "13.2.0",
+		"language": "eng",
+		"type": "messaging",
+		"nodes": [
+			{
+				"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"router": {
+					"type": "switch",
+					"operand": "@contact.name",
+					"cases": [
+						{"uuid": "9f593e22-7886-4c08-a52f-0e8780504d75", "type": "has_text", "arguments": [], "category_uuid": "97b9451c-2856-475b-af38-32af68100897"}
+					],
+					"categories": [
+						{"uuid": "97b9451c-2856-475b-af38-32af68100897", "name": "Has Name", "exit_uuid": "3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b"},
+						{"uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3", "name": "Anonymous", "exit_uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}
+					]
+				},
+				"exits": [
+					{"uuid": "3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b", "destination_uuid": "714f1409-486e-4e8e-bb08-23e2943ef9f6"},
+					{"uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}
+				]
+			}
+		]
+	}`)
+
+	flow, repairs, err = definition.RepairFlow(dangling, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []*definition.Repair{
+		{
+			Type:        definition.RepairTypeMissingDefaultCategory,
+			NodeUUID:    "a58be63b-907d-4a1a-856b-0bb5579d7507",
+			Description: "set default category to unused category 'Anonymous'",
+		},
+		{
+			Type:        definition.RepairTypeDanglingExit,
+			NodeUUID:    "a58be63b-907d-4a1a-856b-0bb5579d7507",
+			Description: "removed destination 714f1409-486e-4e8e-bb08-23e2943ef9f6 of exit 3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b which isn't a known node",
+		},
+	}, repairs)
+	assert.Equal(t, flows.NodeUUID(""), flow.Nodes()[0].Exits()[0].DestinationUUID())
+
+	// repairs can be marshaled, e.g. to be reported to the user
+	marshaled := jsonx.MustMarshal(repairs[1])
+	assert.JSONEq(t, `{"type": "dangling_exit", "node_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507", "description": "removed destination 714f1409-486e-4e8e-bb08-23e2943ef9f6 of exit 3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b which isn't a known node"}`, string(marshaled))
+
+	// valid flows don't need repairing
+	_, repairs, err = definition.RepairFlow(jsonx.MustMarshal(flow), nil)
+	assert.NoError(t, err)
+	assert.Len(t, repairs, 0)
+
+	// and problems which can't be repaired are still errors
+	_, _, err = definition.RepairFlow([]byte(`{"uuid": "8ca44c09-791d-453a-9799-a70dd3303306", "name": "Broken", "spec_version": "13.2.0", "language": "eng", "type": "spaceship", "nodes": []}`), nil)
+	assert.EqualError(t, err, "field 'type' is not a valid flow type")
+
+	_, _, err = definition.RepairFlow([]byte(`[]`), nil)
+	assert.Error(t, err)
+}
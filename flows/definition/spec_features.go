@@ -0,0 +1,96 @@
+package definition
+
+import (
+	"fmt"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/routers"
+
+	"github.com/Masterminds/semver"
+)
+
+// the spec versions in which types of action were introduced, for those which weren't part of 13.0
+var actionSpecVersions = map[string]*semver.Version{
+	"add_to_cart":            semver.MustParse("13.2.0"),
+	"call_graphql":           semver.MustParse("13.2.0"),
+	"call_soap":              semver.MustParse("13.2.0"),
+	"cancel_timer":           semver.MustParse("13.2.0"),
+	"checkout":               semver.MustParse("13.2.0"),
+	"exit_with":              semver.MustParse("13.2.0"),
+	"forward_call":           semver.MustParse("13.2.0"),
+	"join_conference":        semver.MustParse("13.2.0"),
+	"query_collection":       semver.MustParse("13.2.0"),
+	"send_whatsapp_flow":     semver.MustParse("13.2.0"),
+	"send_whatsapp_template": semver.MustParse("13.2.0"),
+	"set_contact_fields":     semver.MustParse("13.2.0"),
+	"set_timer":              semver.MustParse("13.2.0"),
+	"start_recording":        semver.MustParse("13.2.0"),
+	"stop_recording":         semver.MustParse("13.2.0"),
+	"transfer_call":          semver.MustParse("13.2.0"),
+}
+
+// the spec versions in which router tests were introduced, for those which weren't part of 13.0
+var testSpecVersions = map[string]*semver.Version{
+	"has_duration_gt":     semver.MustParse("13.2.0"),
+	"has_duration_lt":     semver.MustParse("13.2.0"),
+	"has_location_within": semver.MustParse("13.2.0"),
+	"has_valid_phone":     semver.MustParse("13.2.0"),
+}
+
+// the spec version in which routers could declare expiration, duplicate input and invalid input categories
+var routerSpecialCategoriesSpecVersion = semver.MustParse("13.2.0")
+
+// SpecFeature is a part of a flow which requires a newer spec version than 13.0
+type SpecFeature struct {
+	NodeUUID    flows.NodeUUID  `json:"node_uuid"`
+	Description string          `json:"description"`
+	SpecVersion *semver.Version `json:"spec_version"`
+}
+
+// SpecRequirements is the minimum spec version required by a flow and the features which require it
+type SpecRequirements struct {
+	MinSpecVersion *semver.Version `json:"min_spec_version"`
+	Features       []*SpecFeature  `json:"features"`
+}
+
+// InspectSpecRequirements reports the minimum spec version which a definition of the given flow must have, based on
+// the types of actions and router tests that it uses, and whether its routers have expiration, duplicate input or
+// invalid input categories
+func InspectSpecRequirements(flow flows.Flow) *SpecRequirements {
+	r := &SpecRequirements{MinSpecVersion: semver.MustParse("13.0.0"), Features: make([]*SpecFeature, 0)}
+
+	add := func(node flows.Node, version *semver.Version, description string) {
+		r.Features = append(r.Features, &SpecFeature{NodeUUID: node.UUID(), Description: description, SpecVersion: version})
+		if version.GreaterThan(r.MinSpecVersion) {
+			r.MinSpecVersion = version
+		}
+	}
+
+	for _, node := range flow.Nodes() {
+		for _, action := range node.Actions() {
+			if v := actionSpecVersions[action.Type()]; v != nil {
+				add(node, v, fmt.Sprintf("action type '%s'", action.Type()))
+			}
+		}
+
+		if switchRouter, isSwitch := node.Router().(*routers.SwitchRouter); isSwitch {
+			for _, c := range switchRouter.Cases() {
+				if v := testSpecVersions[c.Type]; v != nil {
+					add(node, v, fmt.Sprintf("router test '%s'", c.Type))
+				}
+			}
+		}
+
+		if node.Router() != nil && node.Router().AllowExpiration() {
+			add(node, routerSpecialCategoriesSpecVersion, "router expiration category")
+		}
+		if node.Router() != nil && node.Router().AllowDuplicateInput() {
+			add(node, routerSpecialCategoriesSpecVersion, "router duplicate input category")
+		}
+		if node.Router() != nil && node.Router().AllowInvalidInput() {
+			add(node, routerSpecialCategoriesSpecVersion, "router invalid input category")
+		}
+	}
+
+	return r
+}
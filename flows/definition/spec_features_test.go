@@ -0,0 +1,82 @@
+package definition_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nyaruka/goflow/flows/definition"
+
+	"github.com/Masterminds/semver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecRequirements(t *testing.T) {
+	flowJSON := func(specVersion string) []byte {
+		return []byte(fmt.Sprintf(`{
+			"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+			"name": "Reminders",
+			"spec_version": "%s",
+			"language": "eng",
+			"type": "messaging",
+			"nodes": [
+				{
+					"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+					"actions": [
+						{"uuid": "ad154980-7bf7-4ab8-8728-545fd6378912", "type": "send_msg", "text": "How long did it take?"},
+						{"uuid": "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f", "type": "cancel_timer", "name": "reminder"}
+					],
+					"router": {
+						"type": "switch",
+						"wait": {"type": "msg", "validation": {"test": "@(has_text(input.text))", "message": "Please reply"}},
+						"operand": "@input.text",
+						"cases": [
+							{"uuid": "9f593e22-7886-4c08-a52f-0e8780504d75", "type": "has_duration_gt", "arguments": ["PT1H"], "category_uuid": "97b9451c-2856-475b-af38-32af68100897"},
+							{"uuid": "3b5fe2b4-2d8a-4f4b-8b2e-1c0d7e8c5a4f", "type": "has_text", "category_uuid": "97b9451c-2856-475b-af38-32af68100897"}
+						],
+						"categories": [
+							{"uuid": "97b9451c-2856-475b-af38-32af68100897", "name": "Long", "exit_uuid": "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d"},
+							{"uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3", "name": "Other", "exit_uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}
+						],
+						"default_category_uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3",
+						"expiration_category_uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3",
+						"duplicate_input_category_uuid": "97b9451c-2856-475b-af38-32af68100897",
+						"invalid_input_category_uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3"
+					},
+					"exits": [
+						{"uuid": "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d"},
+						{"uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}
+					]
+				}
+			]
+		}`, specVersion))
+	}
+
+	// definitions which use newer actions and tests must declare a spec version which has them
+	_, err := definition.ReadFlow(flowJSON("13.0.0"), nil)
+	assert.EqualError(t, err, "action type 'cancel_timer' requires spec version 13.2.0 but definition has spec version 13.0.0")
+
+	_, err = definition.ReadFlow(flowJSON("13.1"), nil)
+	assert.EqualError(t, err, "action type 'cancel_timer' requires spec version 13.2.0 but definition has spec version 13.1.0")
+
+	flow, err := definition.ReadFlow(flowJSON("13.2.0"), nil)
+	require.NoError(t, err)
+
+	reqs := definition.InspectSpecRequirements(flow)
+	assert.Equal(t, semver.MustParse("13.2.0"), reqs.MinSpecVersion)
+	assert.Equal(t, []*definition.SpecFeature{
+		{NodeUUID: "a58be63b-907d-4a1a-856b-0bb5579d7507", Description: "action type 'cancel_timer'", SpecVersion: semver.MustParse("13.2.0")},
+		{NodeUUID: "a58be63b-907d-4a1a-856b-0bb5579d7507", Description: "router test 'has_duration_gt'", SpecVersion: semver.MustParse("13.2.0")},
+		{NodeUUID: "a58be63b-907d-4a1a-856b-0bb5579d7507", Description: "router expiration category", SpecVersion: semver.MustParse("13.2.0")},
+		{NodeUUID: "a58be63b-907d-4a1a-856b-0bb5579d7507", Description: "router duplicate input category", SpecVersion: semver.MustParse("13.2.0")},
+		{NodeUUID: "a58be63b-907d-4a1a-856b-0bb5579d7507", Description: "router invalid input category", SpecVersion: semver.MustParse("13.2.0")},
+	}, reqs.Features)
+
+	// flows which only use 13.0 features can be read as any version
+	emptyFlow, err := definition.ReadFlow([]byte(`{"uuid": "8ca44c09-791d-453a-9799-a70dd3303306", "name": "Empty", "spec_version": "13.0", "language": "eng", "type": "messaging", "nodes": []}`), nil)
+	require.NoError(t, err)
+
+	reqs = definition.InspectSpecRequirements(emptyFlow)
+	assert.Equal(t, semver.MustParse("13.0.0"), reqs.MinSpecVersion)
+	assert.Len(t, reqs.Features, 0)
+}
@@ -0,0 +1,246 @@
+package definition
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/definition/migrations"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+// TemplateParamType is the type of value a flow template parameter takes
+type TemplateParamType string
+
+// possible types of flow template parameter
+const (
+	TemplateParamTypeGroup   TemplateParamType = "group"
+	TemplateParamTypeField   TemplateParamType = "field"
+	TemplateParamTypeChannel TemplateParamType = "channel"
+	TemplateParamTypeText    TemplateParamType = "text"
+)
+
+var validTemplateParamTypes = map[TemplateParamType]bool{
+	TemplateParamTypeGroup:   true,
+	TemplateParamTypeField:   true,
+	TemplateParamTypeChannel: true,
+	TemplateParamTypeText:    true,
+}
+
+// the property of an object in a template definition which makes it a placeholder, e.g. {"$param": "welcome"}
+const templatePlaceholderProperty = "$param"
+
+// TemplateParam is a parameter of a flow template which has to be given a value when the template is instantiated,
+// unless it has a default
+type TemplateParam struct {
+	Key         string            `json:"key" validate:"required"`
+	Type        TemplateParamType `json:"type" validate:"required"`
+	Description string            `json:"description,omitempty"`
+	Default     json.RawMessage   `json:"default,omitempty"`
+}
+
+// FlowTemplate is a flow definition which can be reused by instantiating it with values for its parameters. Anywhere
+// in the definition, an object like {"$param": "key"} is a placeholder which is replaced by the value of that
+// parameter, i.e. a group, field or channel reference, or a string.
+type FlowTemplate struct {
+	Params     []*TemplateParam `json:"parameters" validate:"dive"`
+	Definition json.RawMessage  `json:"definition" validate:"required"`
+}
+
+// ReadFlowTemplate reads a flow template, checking that its parameters are valid and that its definition only uses
+// parameters which it declares
+func ReadFlowTemplate(data json.RawMessage) (*FlowTemplate, error) {
+	t := &FlowTemplate{}
+	if err := utils.UnmarshalAndValidate(data, t); err != nil {
+		return nil, errors.Wrap(err, "unable to read flow template")
+	}
+
+	declared := make(map[string]*TemplateParam, len(t.Params))
+	for _, p := range t.Params {
+		if declared[p.Key] != nil {
+			return nil, errors.Errorf("parameter '%s' is declared more than once", p.Key)
+		}
+		declared[p.Key] = p
+
+		if !validTemplateParamTypes[p.Type] {
+			return nil, errors.Errorf("parameter '%s' has invalid type '%s'", p.Key, p.Type)
+		}
+		if p.Default != nil {
+			if _, err := p.readValue(p.Default); err != nil {
+				return nil, errors.Wrapf(err, "invalid default for parameter '%s'", p.Key)
+			}
+		}
+	}
+
+	definition, err := jsonx.DecodeGeneric(t.Definition)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read flow template definition")
+	}
+
+	undeclared := make([]string, 0)
+	for _, key := range templatePlaceholders(definition) {
+		if declared[key] == nil {
+			undeclared = append(undeclared, key)
+		}
+	}
+	if len(undeclared) > 0 {
+		sort.Strings(undeclared)
+		return nil, errors.Errorf("definition uses undeclared parameters: %s", strings.Join(undeclared, ", "))
+	}
+
+	return t, nil
+}
+
+// Instantiate creates a new flow from this template by replacing its placeholders with the given parameter values.
+// The new flow and everything in it are given new UUIDs, except for the groups and channels given as values.
+func (t *FlowTemplate) Instantiate(values map[string]json.RawMessage, mc *migrations.Config) (flows.Flow, error) {
+	missing := make([]string, 0)
+	resolved := make(map[string]any, len(t.Params))
+	keepUUIDs := make(map[uuids.UUID]uuids.UUID)
+
+	for _, p := range t.Params {
+		data, supplied := values[p.Key]
+		if !supplied {
+			if p.Default == nil {
+				missing = append(missing, p.Key)
+				continue
+			}
+			data = p.Default
+		}
+
+		value, err := p.readValue(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value for parameter '%s'", p.Key)
+		}
+
+		switch typed := value.(type) {
+		case *assets.GroupReference:
+			keepUUIDs[uuids.UUID(typed.UUID)] = uuids.UUID(typed.UUID)
+		case *assets.ChannelReference:
+			keepUUIDs[uuids.UUID(typed.UUID)] = uuids.UUID(typed.UUID)
+		}
+
+		resolved[p.Key] = value
+	}
+
+	if len(missing) > 0 {
+		return nil, errors.Errorf("missing values for parameters: %s", strings.Join(missing, ", "))
+	}
+
+	unknown := make([]string, 0)
+	for key := range values {
+		if _, declared := resolved[key]; !declared {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, errors.Errorf("values given for unknown parameters: %s", strings.Join(unknown, ", "))
+	}
+
+	definition, err := jsonx.DecodeGeneric(t.Definition)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read flow template definition")
+	}
+
+	substituted, err := jsonx.Marshal(substituteTemplateParams(definition, resolved))
+	if err != nil {
+		return nil, err
+	}
+
+	cloned, err := migrations.Clone(substituted, keepUUIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadFlow(cloned, mc)
+}
+
+// reads and validates a value of this parameter's type
+func (p *TemplateParam) readValue(data json.RawMessage) (any, error) {
+	switch p.Type {
+	case TemplateParamTypeGroup:
+		group := &assets.GroupReference{}
+		if err := utils.UnmarshalAndValidate(data, group); err != nil {
+			return nil, err
+		}
+		if group.UUID == "" {
+			return nil, errors.New("group must have a UUID")
+		}
+		return group, nil
+	case TemplateParamTypeField:
+		field := &assets.FieldReference{}
+		if err := utils.UnmarshalAndValidate(data, field); err != nil {
+			return nil, err
+		}
+		return field, nil
+	case TemplateParamTypeChannel:
+		channel := &assets.ChannelReference{}
+		if err := utils.UnmarshalAndValidate(data, channel); err != nil {
+			return nil, err
+		}
+		return channel, nil
+	default:
+		var text string
+		if err := jsonx.Unmarshal(data, &text); err != nil {
+			return nil, errors.New("value must be a string")
+		}
+		return text, nil
+	}
+}
+
+// gets the key of the parameter if the given generic JSON value is a placeholder
+func templatePlaceholder(v any) (string, bool) {
+	obj, isObj := v.(map[string]any)
+	if !isObj || len(obj) != 1 {
+		return "", false
+	}
+	key, isString := obj[templatePlaceholderProperty].(string)
+	return key, isString
+}
+
+// gets the keys of the parameters used by placeholders in the given generic JSON value
+func templatePlaceholders(v any) []string {
+	keys := make([]string, 0)
+
+	if key, ok := templatePlaceholder(v); ok {
+		return append(keys, key)
+	}
+
+	switch typed := v.(type) {
+	case map[string]any:
+		for _, child := range typed {
+			keys = append(keys, templatePlaceholders(child)...)
+		}
+	case []any:
+		for _, child := range typed {
+			keys = append(keys, templatePlaceholders(child)...)
+		}
+	}
+	return keys
+}
+
+// replaces the placeholders in the given generic JSON value with the given parameter values
+func substituteTemplateParams(v any, values map[string]any) any {
+	if key, ok := templatePlaceholder(v); ok {
+		return values[key]
+	}
+
+	switch typed := v.(type) {
+	case map[string]any:
+		for k, child := range typed {
+			typed[k] = substituteTemplateParams(child, values)
+		}
+	case []any:
+		for i, child := range typed {
+			typed[i] = substituteTemplateParams(child, values)
+		}
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+package definition_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows/actions"
+	"github.com/nyaruka/goflow/flows/definition"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var registrationTemplate = `{
+	"parameters": [
+		{"key": "registered", "type": "group", "description": "Group to add registered contacts to"},
+		{"key": "age", "type": "field"},
+		{"key": "channel", "type": "channel"},
+		{"key": "welcome", "type": "text", "default": "Welcome!"}
+	],
+	"definition": {
+		"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+		"name": "Registration",
+		"spec_version": "13.2.0",
+		"language": "eng",
+		"type": "messaging",
+		"nodes": [
+			{
+				"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"actions": [
+					{"uuid": "ad154980-7bf7-4ab8-8728-545fd6378912", "type": "send_msg", "text": {"$param": "welcome"}},
+					{"uuid": "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f", "type": "add_contact_groups", "groups": [{"$param": "registered"}]},
+					{"uuid": "9c1d2e3f-4a5b-4c6d-8e7f-0a1b2c3d4e5f", "type": "set_contact_field", "field": {"$param": "age"}, "value": "@input.text"},
+					{"uuid": "e3f4a5b6-c7d8-4e9f-8a0b-1c2d3e4f5a6b", "type": "set_contact_channel", "channel": {"$param": "channel"}}
+				],
+				"exits": [{"uuid": "3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b"}]
+			}
+		]
+	}
+}`
+
+func TestFlowTemplates(t *testing.T) {
+	template, err := definition.ReadFlowTemplate([]byte(registrationTemplate))
+	require.NoError(t, err)
+	assert.Len(t, template.Params, 4)
+
+	values := map[string]json.RawMessage{
+		"registered": []byte(`{"uuid": "2aad21f6-30b7-42c5-bd7f-1b720c154817", "name": "Registered"}`),
+		"age":        []byte(`{"key": "age", "name": "Age"}`),
+		"channel":    []byte(`{"uuid": "57f1078f-88aa-46f4-a59a-948a5739c03d", "name": "SMS"}`),
+	}
+
+	flow1, err := template.Instantiate(values, nil)
+	require.NoError(t, err)
+
+	actions1 := flow1.Nodes()[0].Actions()
+	assert.Equal(t, "Welcome!", actions1[0].(*actions.SendMsgAction).Text)
+	assert.Equal(t, "2aad21f6-30b7-42c5-bd7f-1b720c154817", string(actions1[1].(*actions.AddContactGroupsAction).Groups[0].UUID))
+	assert.Equal(t, "age", actions1[2].(*actions.SetContactFieldAction).Field.Key)
+	assert.Equal(t, "57f1078f-88aa-46f4-a59a-948a5739c03d", string(actions1[3].(*actions.SetContactChannelAction).Channel.UUID))
+
+	// each instance of a template is a new flow
+	values["welcome"] = []byte(`"Bienvenue!"`)
+
+	flow2, err := template.Instantiate(values, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, flow1.UUID(), flow2.UUID())
+	assert.NotEqual(t, flow1.Nodes()[0].UUID(), flow2.Nodes()[0].UUID())
+	assert.Equal(t, "Bienvenue!", flow2.Nodes()[0].Actions()[0].(*actions.SendMsgAction).Text)
+
+	// and the template isn't changed by instantiating it
+	assert.Contains(t, string(template.Definition), `{"$param": "welcome"}`)
+
+	// all parameters without defaults must be given values
+	_, err = template.Instantiate(map[string]json.RawMessage{"age": values["age"]}, nil)
+	assert.EqualError(t, err, "missing values for parameters: registered, channel")
+
+	// values must be valid for the type of parameter
+	_, err = template.Instantiate(map[string]json.RawMessage{"registered": []byte(`{"uuid": "xyz", "name": "Registered"}`), "age": values["age"], "channel": values["channel"]}, nil)
+	assert.EqualError(t, err, "invalid value for parameter 'registered': field 'uuid' must be a valid UUID4")
+
+	_, err = template.Instantiate(map[string]json.RawMessage{"registered": values["registered"], "age": []byte(`{"name": "Age"}`), "channel": values["channel"]}, nil)
+	assert.EqualError(t, err, "invalid value for parameter 'age': field 'key' is required")
+
+	_, err = template.Instantiate(map[string]json.RawMessage{"registered": values["registered"], "age": values["age"], "channel": values["channel"], "welcome": []byte(`123`)}, nil)
+	assert.EqualError(t, err, "invalid value for parameter 'welcome': value must be a string")
+
+	// and only for parameters that the template has
+	values["color"] = []byte(`"red"`)
+	_, err = template.Instantiate(values, nil)
+	assert.EqualError(t, err, "values given for unknown parameters: color")
+}
+
+func TestReadFlowTemplate(t *testing.T) {
+	_, err := definition.ReadFlowTemplate([]byte(`{"parameters": [{"key": "welcome", "type": "color"}], "definition": {}}`))
+	assert.EqualError(t, err, "parameter 'welcome' has invalid type 'color'")
+
+	_, err = definition.ReadFlowTemplate([]byte(`{"parameters": [{"key": "welcome", "type": "text"}, {"key": "welcome", "type": "text"}], "definition": {}}`))
+	assert.EqualError(t, err, "parameter 'welcome' is declared more than once")
+
+	_, err = definition.ReadFlowTemplate([]byte(`{"parameters": [{"key": "welcome", "type": "text", "default": 123}], "definition": {}}`))
+	assert.EqualError(t, err, "invalid default for parameter 'welcome': value must be a string")
+
+	_, err = definition.ReadFlowTemplate(jsonx.MustMarshal(map[string]any{
+		"parameters": []any{},
+		"definition": map[string]any{"nodes": []any{map[string]any{"actions": []any{map[string]any{"text": map[string]any{"$param": "welcome"}}}}}},
+	}))
+	assert.EqualError(t, err, "definition uses undeclared parameters: welcome")
+}
@@ -0,0 +1,100 @@
+package flows
+
+import (
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+)
+
+// DependencyLocation is a place in a flow which references an asset
+type DependencyLocation struct {
+	NodeUUID   NodeUUID      `json:"node_uuid"`
+	ActionUUID ActionUUID    `json:"action_uuid,omitempty"`
+	Language   envs.Language `json:"language,omitempty"`
+}
+
+// FlowDependency is an asset referenced by a flow and the places in the flow which reference it
+type FlowDependency[R assets.Reference] struct {
+	Reference R
+	Locations []*DependencyLocation
+}
+
+// MarshalJSON marshals this dependency into JSON
+func (d *FlowDependency[R]) MarshalJSON() ([]byte, error) {
+	return jsonx.MarshalMerged(d.Reference, &struct {
+		Locations []*DependencyLocation `json:"locations"`
+	}{Locations: d.Locations})
+}
+
+// FlowDependencies are the assets referenced by a flow, grouped by type
+type FlowDependencies struct {
+	Channels    []*FlowDependency[*assets.ChannelReference]    `json:"channels,omitempty"`
+	Classifiers []*FlowDependency[*assets.ClassifierReference] `json:"classifiers,omitempty"`
+	Contacts    []*FlowDependency[*ContactReference]           `json:"contacts,omitempty"`
+	Fields      []*FlowDependency[*assets.FieldReference]      `json:"fields,omitempty"`
+	Flows       []*FlowDependency[*assets.FlowReference]       `json:"flows,omitempty"`
+	Globals     []*FlowDependency[*assets.GlobalReference]     `json:"globals,omitempty"`
+	Groups      []*FlowDependency[*assets.GroupReference]      `json:"groups,omitempty"`
+	Labels      []*FlowDependency[*assets.LabelReference]      `json:"labels,omitempty"`
+	Templates   []*FlowDependency[*assets.TemplateReference]   `json:"templates,omitempty"`
+	Ticketers   []*FlowDependency[*assets.TicketerReference]   `json:"ticketers,omitempty"`
+	Topics      []*FlowDependency[*assets.TopicReference]      `json:"topics,omitempty"`
+	Users       []*FlowDependency[*assets.UserReference]       `json:"users,omitempty"`
+}
+
+// NewFlowDependencies groups the given extracted references by asset type, merging references to the same asset
+func NewFlowDependencies(refs []ExtractedReference) *FlowDependencies {
+	d := &FlowDependencies{}
+
+	for _, er := range refs {
+		loc := &DependencyLocation{NodeUUID: er.Node.UUID(), Language: er.Language}
+		if er.Action != nil {
+			loc.ActionUUID = er.Action.UUID()
+		}
+
+		switch typed := er.Reference.(type) {
+		case *assets.ChannelReference:
+			d.Channels = addDependency(d.Channels, typed, loc)
+		case *assets.ClassifierReference:
+			d.Classifiers = addDependency(d.Classifiers, typed, loc)
+		case *ContactReference:
+			d.Contacts = addDependency(d.Contacts, typed, loc)
+		case *assets.FieldReference:
+			d.Fields = addDependency(d.Fields, typed, loc)
+		case *assets.FlowReference:
+			d.Flows = addDependency(d.Flows, typed, loc)
+		case *assets.GlobalReference:
+			d.Globals = addDependency(d.Globals, typed, loc)
+		case *assets.GroupReference:
+			d.Groups = addDependency(d.Groups, typed, loc)
+		case *assets.LabelReference:
+			d.Labels = addDependency(d.Labels, typed, loc)
+		case *assets.TemplateReference:
+			d.Templates = addDependency(d.Templates, typed, loc)
+		case *assets.TicketerReference:
+			d.Ticketers = addDependency(d.Ticketers, typed, loc)
+		case *assets.TopicReference:
+			d.Topics = addDependency(d.Topics, typed, loc)
+		case *assets.UserReference:
+			d.Users = addDependency(d.Users, typed, loc)
+		}
+	}
+
+	return d
+}
+
+// adds a location to the dependency for the given reference, creating that dependency if it doesn't exist yet
+func addDependency[R assets.Reference](deps []*FlowDependency[R], ref R, loc *DependencyLocation) []*FlowDependency[R] {
+	for _, d := range deps {
+		if d.Reference.Identity() == ref.Identity() {
+			for _, l := range d.Locations {
+				if *l == *loc {
+					return deps
+				}
+			}
+			d.Locations = append(d.Locations, loc)
+			return deps
+		}
+	}
+	return append(deps, &FlowDependency[R]{Reference: ref, Locations: []*DependencyLocation{loc}})
+}
@@ -0,0 +1,16 @@
+package flows
+
+import (
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/utils"
+)
+
+// EmailIn is an incoming email from the session contact, e.g. one sent to a support inbox
+type EmailIn struct {
+	UUID        uuids.UUID         `json:"uuid" validate:"required,uuid"`
+	From        string             `json:"from" validate:"required,email"`
+	Subject     string             `json:"subject"`
+	Body        string             `json:"body"`
+	HTMLBody    string             `json:"html_body,omitempty"`
+	Attachments []utils.Attachment `json:"attachments,omitempty"`
+}
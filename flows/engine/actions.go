@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent"
+	"github.com/nyaruka/goflow/excellent/functions"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/inspect"
+)
+
+// executes the given action, calling the engine's action hooks before and after it. An action blocked by a hook isn't
+// executed and instead the hook's error is logged as an error event.
+func executeAction(ctx context.Context, run flows.Run, step flows.Step, action flows.Action, logModifier flows.ModifierCallback, logEvent flows.EventCallback) error {
+	if run.Session().Engine().EventTimings() {
+		logEvent = timeEvents(logEvent)
+	}
+
+	hooks := run.Session().Engine().ActionHooks()
+	if len(hooks) == 0 {
+		warnDeprecatedFunctions(run.Flow(), uuids.UUID(action.UUID()), logEvent)
+		return action.Execute(ctx, run, step, logModifier, logEvent)
+	}
+
+	for _, hook := range hooks {
+		if err := hook.Before(ctx, run, step, action); err != nil {
+			logEvent(events.NewError(err))
+			return nil
+		}
+	}
+
+	generated := make([]flows.Event, 0)
+	logGenerated := func(e flows.Event) {
+		generated = append(generated, e)
+		logEvent(e)
+	}
+
+	warnDeprecatedFunctions(run.Flow(), uuids.UUID(action.UUID()), logGenerated)
+
+	err := action.Execute(ctx, run, step, logModifier, logGenerated)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range hooks {
+		hook.After(ctx, run, step, action, generated)
+	}
+	return nil
+}
+
+// buffers the events and modifiers of an action so that they can be discarded if the action fails
+type actionBuffer struct {
+	events    []flows.Event
+	modifiers []flows.Modifier
+}
+
+func (b *actionBuffer) logEvent(e flows.Event)       { b.events = append(b.events, e) }
+func (b *actionBuffer) logModifier(m flows.Modifier) { b.modifiers = append(b.modifiers, m) }
+
+// flushes the buffered modifiers and events to the given callbacks
+func (b *actionBuffer) flush(logModifier flows.ModifierCallback, logEvent flows.EventCallback) {
+	for _, m := range b.modifiers {
+		logModifier(m)
+	}
+	for _, e := range b.events {
+		logEvent(e)
+	}
+}
+
+// discards the buffered modifiers and events, except for error events which explain why the action failed
+func (b *actionBuffer) discard(logEvent flows.EventCallback) {
+	for _, e := range b.events {
+		if _, isError := e.(*events.ErrorEvent); isError {
+			logEvent(e)
+		}
+	}
+}
+
+// logs a warning for each deprecated function called by the templates of the action with the given UUID, or the
+// router of the node with the given UUID, which the flow worked out when it was read
+func warnDeprecatedFunctions(flow flows.Flow, uuid uuids.UUID, logEvent flows.EventCallback) {
+	for _, name := range flow.DeprecatedFunctions(uuid) {
+		logEvent(events.NewWarningf("%s is deprecated, use %s instead", name, functions.Deprecation(name)))
+	}
+}
+
+// wraps the given event callback so that events are stamped with the timing of the action which is starting now.
+// Events are stamped as they're generated rather than once the action finishes, so that an event sink sees them as
+// they'll be saved.
+func timeEvents(logEvent flows.EventCallback) flows.EventCallback {
+	startedOn := dates.Now()
+
+	return func(e flows.Event) {
+		e.SetTiming(flows.NewEventTiming(startedOn, dates.Since(startedOn)))
+		logEvent(e)
+	}
+}
+
+// parts of the run context which concurrent actions can change, e.g. by saving results or calling webhooks
+var concurrentlyWrittenTopLevels = map[string]bool{"results": true, "run": true, "webhook": true, "legacy_extra": true}
+
+// returns the actions at the start of the given actions which can be executed concurrently. These are actions which
+// can be executed concurrently and which don't use anything in the context that the actions before them might change.
+func concurrentBatch(flow flows.Flow, actions []flows.Action) []flows.Action {
+	n := 0
+	for _, action := range actions {
+		if concurrent, ok := action.(flows.ConcurrentAction); !ok || !concurrent.Concurrent() {
+			break
+		}
+		if n > 0 && readsConcurrentWrites(flow, action) {
+			break
+		}
+		n++
+	}
+	return actions[:n]
+}
+
+// checks whether any of the templates of the given action use parts of the context which concurrent actions change
+func readsConcurrentWrites(flow flows.Flow, action flows.Action) bool {
+	reads := false
+
+	inspect.Templates(action, flow.Localization(), func(l envs.Language, template string) {
+		excellent.VisitTemplate(template, flows.RunContextTopLevels, func(tokenType excellent.XTokenType, token string) error {
+			switch tokenType {
+			case excellent.IDENTIFIER, excellent.EXPRESSION:
+				excellent.Parse(token, func(path []string) {
+					if concurrentlyWrittenTopLevels[strings.ToLower(path[0])] {
+						reads = true
+					}
+				})
+			}
+			return nil
+		})
+	})
+
+	return reads
+}
+
+// executes the given actions concurrently and then joins them by adding their results and events to the run in action
+// order, so that the output is the same as if they'd been executed one after another
+func (s *session) executeConcurrently(ctx context.Context, sprint *sprint, run flows.Run, step flows.Step, actions []flows.Action) error {
+	branches := make([]*branchRun, len(actions))
+	wg := &sync.WaitGroup{}
+
+	for i := range actions {
+		branches[i] = newBranchRun(run, step)
+		wg.Add(1)
+
+		go func(b *branchRun, action flows.Action) {
+			defer wg.Done()
+			b.execute(ctx, []flows.Action{action}, s.engine.StrictTemplates())
+		}(branches[i], actions[i])
+	}
+
+	wg.Wait()
+
+	for _, b := range branches {
+		if b.err != nil {
+			return b.err
+		}
+
+		if s.joinBranch(sprint, run, b) {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/triggers"
+	"github.com/nyaruka/goflow/services/webhooks"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentActions(t *testing.T) {
+	// a webhook server which waits for the requested delay before responding
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay, _ := time.ParseDuration(r.URL.Query().Get("delay"))
+		time.Sleep(delay)
+		fmt.Fprintf(w, `{"delay": "%s"}`, delay)
+	}))
+	defer server.Close()
+
+	// the third call uses the result of the first so has to wait for it, and the message has to wait for all of them
+	flowJSON := []byte(fmt.Sprintf(`{
+		"flows": [
+			{
+				"uuid": "1b462ce8-983a-4393-b133-e15a0efdb70c",
+				"name": "Lookups",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "dd8d3c2b-9e7b-4a7e-8e0a-b5b0a3c3bb8f",
+						"actions": [
+							{"uuid": "b1f2b1c0-5b7e-4e7c-9f1d-000000000000", "type": "call_webhook", "method": "GET", "url": "%[1]s?delay=300ms", "result_name": "Call 0"},
+							{"uuid": "b1f2b1c0-5b7e-4e7c-9f1d-000000000001", "type": "call_webhook", "method": "GET", "url": "%[1]s?delay=200ms", "result_name": "Call 1"},
+							{"uuid": "b1f2b1c0-5b7e-4e7c-9f1d-000000000002", "type": "call_webhook", "method": "GET", "url": "%[1]s?delay=100ms&prev=@results.call_0.category", "result_name": "Call 2"},
+							{"uuid": "b1f2b1c0-5b7e-4e7c-9f1d-000000000003", "type": "send_msg", "text": "@results.call_0.category @results.call_1.category @results.call_2.category"}
+						],
+						"exits": [{"uuid": "c1f2b1c0-5b7e-4e7c-9f1d-000000000000"}]
+					}
+				]
+			}
+		]
+	}`, server.URL))
+
+	runFlow := func(concurrent bool) (flows.Session, flows.Sprint, time.Duration) {
+		eng := engine.NewBuilder().
+			WithWebhookServiceFactory(webhooks.NewServiceFactory(http.DefaultClient, nil, nil, nil, 10000, 0)).
+			WithConcurrentActions(concurrent).
+			Build()
+
+		sa, err := test.CreateSessionAssets(flowJSON, "")
+		require.NoError(t, err)
+
+		env := envs.NewBuilder().Build()
+		contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+		trigger := triggers.NewBuilder(env, assets.NewFlowReference("1b462ce8-983a-4393-b133-e15a0efdb70c", "Lookups"), contact).Manual().Build()
+
+		start := time.Now()
+		session, sprint, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+
+		return session, sprint, time.Since(start)
+	}
+
+	// by default actions are executed one after another
+	session, sprint, elapsed := runFlow(false)
+
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.GreaterOrEqual(t, elapsed, 600*time.Millisecond)
+
+	expectedTypes := []string{"webhook_called", "run_result_changed", "webhook_called", "run_result_changed", "webhook_called", "run_result_changed", "msg_created"}
+	assert.Equal(t, expectedTypes, eventTypes(sprint.Events()))
+
+	// when enabled, the first two calls are executed concurrently
+	session, sprint, elapsed = runFlow(true)
+
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Less(t, elapsed, 550*time.Millisecond)
+
+	// but events are still logged in action order
+	assert.Equal(t, expectedTypes, eventTypes(sprint.Events()))
+	assert.Equal(t, server.URL+"?delay=300ms", sprint.Events()[0].(*events.WebhookCalledEvent).URL)
+	assert.Equal(t, server.URL+"?delay=200ms", sprint.Events()[2].(*events.WebhookCalledEvent).URL)
+	assert.Equal(t, server.URL+"?delay=100ms&prev=Success", sprint.Events()[4].(*events.WebhookCalledEvent).URL)
+	assert.Equal(t, "Success Success Success", sprint.Events()[6].(*events.MsgCreatedEvent).Msg.Text())
+
+	// and the last call determines the webhook value
+	run := session.Runs()[0]
+	assert.Equal(t, `{delay: 100ms}`, run.Webhook().Render())
+
+	// all events belong to the node's step
+	for _, e := range sprint.Events() {
+		assert.Equal(t, run.Path()[0].UUID(), e.StepUUID())
+	}
+}
+
+// a hook which blocks actions of the given types and records what it sees
+type testActionHook struct {
+	blocked  map[string]bool
+	executed []string
+	events   map[string][]string
+}
+
+func (h *testActionHook) Before(ctx context.Context, run flows.Run, step flows.Step, action flows.Action) error {
+	if h.blocked[action.Type()] {
+		return errors.Errorf("%s actions are blocked during quiet hours", action.Type())
+	}
+	return nil
+}
+
+func (h *testActionHook) After(ctx context.Context, run flows.Run, step flows.Step, action flows.Action, evts []flows.Event) {
+	h.executed = append(h.executed, action.Type())
+	for _, e := range evts {
+		h.events[action.Type()] = append(h.events[action.Type()], e.Type())
+	}
+}
+
+func TestActionHooks(t *testing.T) {
+	sa, err := test.CreateSessionAssets([]byte(`{
+		"flows": [
+			{
+				"uuid": "1b462ce8-983a-4393-b133-e15a0efdb70c",
+				"name": "Hooked",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "dd8d3c2b-9e7b-4a7e-8e0a-b5b0a3c3bb8f",
+						"actions": [
+							{"uuid": "b1f2b1c0-5b7e-4e7c-9f1d-000000000000", "type": "set_contact_name", "name": "Robert"},
+							{"uuid": "b1f2b1c0-5b7e-4e7c-9f1d-000000000001", "type": "send_msg", "text": "Hi @contact.name"},
+							{"uuid": "b1f2b1c0-5b7e-4e7c-9f1d-000000000002", "type": "set_run_result", "name": "Greeted", "value": "yes"}
+						],
+						"exits": [{"uuid": "c1f2b1c0-5b7e-4e7c-9f1d-000000000000"}]
+					}
+				]
+			}
+		]
+	}`), "")
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	flow := assets.NewFlowReference("1b462ce8-983a-4393-b133-e15a0efdb70c", "Hooked")
+
+	runFlow := func(hooks ...flows.ActionHook) (flows.Session, flows.Sprint) {
+		b := engine.NewBuilder()
+		for _, h := range hooks {
+			b.RegisterActionHook(h)
+		}
+
+		contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+		session, sprint, err := b.Build().NewSession(context.Background(), sa, triggers.NewBuilder(env, flow, contact).Manual().Build())
+		require.NoError(t, err)
+		return session, sprint
+	}
+
+	// hooks see every action and the events it generated
+	recorder := &testActionHook{events: map[string][]string{}}
+	session, sprint := runFlow(recorder)
+
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, []string{"set_contact_name", "send_msg", "set_run_result"}, recorder.executed)
+	assert.Equal(t, map[string][]string{
+		"set_contact_name": {"contact_name_changed"},
+		"send_msg":         {"msg_created"},
+		"set_run_result":   {"run_result_changed"},
+	}, recorder.events)
+	assert.Len(t, sprint.Events(), 3)
+
+	// a hook can block an action, which is skipped with an error event and isn't seen by the hooks after it
+	blocker := &testActionHook{blocked: map[string]bool{"send_msg": true}, events: map[string][]string{}}
+	recorder = &testActionHook{events: map[string][]string{}}
+	session, sprint = runFlow(blocker, recorder)
+
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, []string{"set_contact_name", "set_run_result"}, blocker.executed)
+	assert.Equal(t, []string{"set_contact_name", "set_run_result"}, recorder.executed)
+
+	eventTypes := make([]string, len(sprint.Events()))
+	for i, e := range sprint.Events() {
+		eventTypes[i] = e.Type()
+	}
+	assert.Equal(t, []string{"contact_name_changed", "error", "run_result_changed"}, eventTypes)
+	assert.Equal(t, "send_msg actions are blocked during quiet hours", sprint.Events()[1].(*events.ErrorEvent).Text)
+}
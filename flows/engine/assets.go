@@ -14,12 +14,15 @@ type sessionAssets struct {
 
 	channels    *flows.ChannelAssets
 	classifiers *flows.ClassifierAssets
+	costs       *flows.CostAssets
 	fields      *flows.FieldAssets
 	flows       flows.FlowAssets
 	globals     *flows.GlobalAssets
 	groups      *flows.GroupAssets
 	labels      *flows.LabelAssets
 	locations   *flows.LocationAssets
+	lookups     *flows.LookupTableAssets
+	relations   *flows.RelationTypeAssets
 	resthooks   *flows.ResthookAssets
 	templates   *flows.TemplateAssets
 	ticketers   *flows.TicketerAssets
@@ -39,6 +42,10 @@ func NewSessionAssets(env envs.Environment, source assets.Source, migrationConfi
 	if err != nil {
 		return nil, err
 	}
+	costs, err := source.Costs()
+	if err != nil {
+		return nil, err
+	}
 	fields, err := source.Fields()
 	if err != nil {
 		return nil, err
@@ -59,6 +66,14 @@ func NewSessionAssets(env envs.Environment, source assets.Source, migrationConfi
 	if err != nil {
 		return nil, err
 	}
+	lookupTables, err := source.LookupTables()
+	if err != nil {
+		return nil, err
+	}
+	relationTypes, err := source.RelationTypes()
+	if err != nil {
+		return nil, err
+	}
 	resthooks, err := source.Resthooks()
 	if err != nil {
 		return nil, err
@@ -87,12 +102,15 @@ func NewSessionAssets(env envs.Environment, source assets.Source, migrationConfi
 		source:      source,
 		channels:    flows.NewChannelAssets(channels),
 		classifiers: flows.NewClassifierAssets(classifiers),
+		costs:       flows.NewCostAssets(costs),
 		fields:      fieldAssets,
 		flows:       definition.NewFlowAssets(source, migrationConfig),
 		globals:     flows.NewGlobalAssets(globals),
 		groups:      groupAssets,
 		labels:      flows.NewLabelAssets(labels),
 		locations:   flows.NewLocationAssets(locations),
+		lookups:     flows.NewLookupTableAssets(lookupTables),
+		relations:   flows.NewRelationTypeAssets(relationTypes),
 		resthooks:   flows.NewResthookAssets(resthooks),
 		templates:   flows.NewTemplateAssets(templates),
 		ticketers:   flows.NewTicketerAssets(ticketers),
@@ -101,20 +119,23 @@ func NewSessionAssets(env envs.Environment, source assets.Source, migrationConfi
 	}, nil
 }
 
-func (s *sessionAssets) Source() assets.Source                { return s.source }
-func (s *sessionAssets) Channels() *flows.ChannelAssets       { return s.channels }
-func (s *sessionAssets) Classifiers() *flows.ClassifierAssets { return s.classifiers }
-func (s *sessionAssets) Fields() *flows.FieldAssets           { return s.fields }
-func (s *sessionAssets) Flows() flows.FlowAssets              { return s.flows }
-func (s *sessionAssets) Globals() *flows.GlobalAssets         { return s.globals }
-func (s *sessionAssets) Groups() *flows.GroupAssets           { return s.groups }
-func (s *sessionAssets) Labels() *flows.LabelAssets           { return s.labels }
-func (s *sessionAssets) Locations() *flows.LocationAssets     { return s.locations }
-func (s *sessionAssets) Resthooks() *flows.ResthookAssets     { return s.resthooks }
-func (s *sessionAssets) Templates() *flows.TemplateAssets     { return s.templates }
-func (s *sessionAssets) Ticketers() *flows.TicketerAssets     { return s.ticketers }
-func (s *sessionAssets) Topics() *flows.TopicAssets           { return s.topics }
-func (s *sessionAssets) Users() *flows.UserAssets             { return s.users }
+func (s *sessionAssets) Source() assets.Source                    { return s.source }
+func (s *sessionAssets) Channels() *flows.ChannelAssets           { return s.channels }
+func (s *sessionAssets) Classifiers() *flows.ClassifierAssets     { return s.classifiers }
+func (s *sessionAssets) Costs() *flows.CostAssets                 { return s.costs }
+func (s *sessionAssets) Fields() *flows.FieldAssets               { return s.fields }
+func (s *sessionAssets) Flows() flows.FlowAssets                  { return s.flows }
+func (s *sessionAssets) Globals() *flows.GlobalAssets             { return s.globals }
+func (s *sessionAssets) Groups() *flows.GroupAssets               { return s.groups }
+func (s *sessionAssets) Labels() *flows.LabelAssets               { return s.labels }
+func (s *sessionAssets) Locations() *flows.LocationAssets         { return s.locations }
+func (s *sessionAssets) LookupTables() *flows.LookupTableAssets   { return s.lookups }
+func (s *sessionAssets) RelationTypes() *flows.RelationTypeAssets { return s.relations }
+func (s *sessionAssets) Resthooks() *flows.ResthookAssets         { return s.resthooks }
+func (s *sessionAssets) Templates() *flows.TemplateAssets         { return s.templates }
+func (s *sessionAssets) Ticketers() *flows.TicketerAssets         { return s.ticketers }
+func (s *sessionAssets) Topics() *flows.TopicAssets               { return s.topics }
+func (s *sessionAssets) Users() *flows.UserAssets                 { return s.users }
 
 // Resolver methods used by contactql
 
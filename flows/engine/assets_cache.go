@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/definition/migrations"
+)
+
+// an LRU cache of session assets keyed by the version of the source they were constructed from
+type assetsCache struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List // most recently used at the front
+	stats   flows.AssetsCacheStats
+}
+
+type assetsCacheEntry struct {
+	version string
+	assets  flows.SessionAssets
+}
+
+func newAssetsCache(size int) *assetsCache {
+	return &assetsCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// gets session assets for the given source, constructing them if the source isn't versioned or we don't have them
+// for its current version
+func (c *assetsCache) get(env envs.Environment, source assets.Source, migrationConfig *migrations.Config) (flows.SessionAssets, error) {
+	versioned, isVersioned := source.(assets.VersionedSource)
+	if !isVersioned || c.size <= 0 {
+		return NewSessionAssets(env, source, migrationConfig)
+	}
+
+	version := versioned.Version()
+
+	c.mutex.Lock()
+	if el := c.entries[version]; el != nil {
+		c.order.MoveToFront(el)
+		c.stats.Hits++
+		c.mutex.Unlock()
+
+		return el.Value.(*assetsCacheEntry).assets, nil
+	}
+	c.stats.Misses++
+	c.mutex.Unlock()
+
+	// construct outside of the lock as this will usually involve fetching assets
+	sa, err := NewSessionAssets(env, source, migrationConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// another caller may have constructed the same version while we were
+	if el := c.entries[version]; el != nil {
+		c.order.MoveToFront(el)
+		return el.Value.(*assetsCacheEntry).assets, nil
+	}
+
+	c.entries[version] = c.order.PushFront(&assetsCacheEntry{version: version, assets: sa})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*assetsCacheEntry).version)
+		c.stats.Evictions++
+	}
+
+	return sa, nil
+}
+
+func (c *assetsCache) getStats() flows.AssetsCacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
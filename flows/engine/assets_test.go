@@ -7,6 +7,7 @@ import (
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/assets/static"
 	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/engine"
 
 	"github.com/pkg/errors"
@@ -116,13 +117,98 @@ func TestSessionAssetsWithSourceErrors(t *testing.T) {
 	_, err = sa.Flows().FindByName("Catch All")
 	assert.EqualError(t, err, "unable to load flow assets")
 
-	for _, errType := range []string{"channels", "classifiers", "fields", "globals", "groups", "labels", "locations", "resthooks", "templates", "users"} {
+	for _, errType := range []string{"channels", "classifiers", "costs", "fields", "globals", "groups", "labels", "locations", "lookup_tables", "relation_types", "resthooks", "templates", "users"} {
 		source.currentErrType = errType
 		_, err = engine.NewSessionAssets(env, source, nil)
 		assert.EqualError(t, err, fmt.Sprintf("unable to load %s assets", errType), "error mismatch for type %s", errType)
 	}
 }
 
+func TestSessionAssetsCache(t *testing.T) {
+	env := envs.NewBuilder().Build()
+
+	staticSource, err := static.NewSource([]byte(assetsJSON))
+	require.NoError(t, err)
+
+	v1 := &versionedSource{StaticSource: staticSource, version: "1"}
+	v2 := &versionedSource{StaticSource: staticSource, version: "2"}
+	v3 := &versionedSource{StaticSource: staticSource, version: "3"}
+
+	// by default nothing is cached
+	eng := engine.NewBuilder().Build()
+
+	sa1, err := eng.SessionAssets(env, v1)
+	require.NoError(t, err)
+	sa2, err := eng.SessionAssets(env, v1)
+	require.NoError(t, err)
+
+	assert.NotSame(t, sa1, sa2)
+	assert.Equal(t, flows.AssetsCacheStats{}, eng.AssetsCacheStats())
+
+	eng = engine.NewBuilder().WithAssetsCacheSize(2).Build()
+
+	// assets for the same version are reused, including any flows they've already read
+	sa1, err = eng.SessionAssets(env, v1)
+	require.NoError(t, err)
+	flow1, err := sa1.Flows().Get("76f0a02f-3b75-4b86-9064-e9195e1b3a02")
+	require.NoError(t, err)
+
+	sa2, err = eng.SessionAssets(env, v1)
+	require.NoError(t, err)
+	flow2, err := sa2.Flows().Get("76f0a02f-3b75-4b86-9064-e9195e1b3a02")
+	require.NoError(t, err)
+
+	assert.Same(t, sa1, sa2)
+	assert.Same(t, flow1, flow2)
+	assert.Equal(t, flows.AssetsCacheStats{Hits: 1, Misses: 1, Size: 1}, eng.AssetsCacheStats())
+
+	// sources which aren't versioned aren't cached
+	sa3, err := eng.SessionAssets(env, staticSource)
+	require.NoError(t, err)
+	assert.NotSame(t, sa1, sa3)
+	assert.Equal(t, flows.AssetsCacheStats{Hits: 1, Misses: 1, Size: 1}, eng.AssetsCacheStats())
+
+	// once the cache is full, the least recently used version is evicted
+	_, err = eng.SessionAssets(env, v2)
+	require.NoError(t, err)
+	_, err = eng.SessionAssets(env, v1)
+	require.NoError(t, err)
+	_, err = eng.SessionAssets(env, v3)
+	require.NoError(t, err)
+
+	assert.Equal(t, flows.AssetsCacheStats{Hits: 2, Misses: 3, Evictions: 1, Size: 2}, eng.AssetsCacheStats())
+
+	sa4, err := eng.SessionAssets(env, v1)
+	require.NoError(t, err)
+	assert.Same(t, sa1, sa4)
+
+	_, err = eng.SessionAssets(env, v2)
+	require.NoError(t, err)
+	assert.Equal(t, flows.AssetsCacheStats{Hits: 3, Misses: 4, Evictions: 2, Size: 2}, eng.AssetsCacheStats())
+
+	// errors from the source aren't cached
+	_, err = eng.SessionAssets(env, &versionedSource{StaticSource: staticSource, version: "4", err: errors.New("boom")})
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, flows.AssetsCacheStats{Hits: 3, Misses: 5, Evictions: 2, Size: 2}, eng.AssetsCacheStats())
+}
+
+// a static source with a version and which can be made to fail
+type versionedSource struct {
+	*static.StaticSource
+
+	version string
+	err     error
+}
+
+func (s *versionedSource) Version() string { return s.version }
+
+func (s *versionedSource) Channels() ([]assets.Channel, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.StaticSource.Channels()
+}
+
 // a source for testing which will return an err when requested an asset of currentErrType
 type testSource struct {
 	currentErrType string
@@ -143,6 +229,10 @@ func (s *testSource) Classifiers() ([]assets.Classifier, error) {
 	return nil, s.err("classifiers")
 }
 
+func (s *testSource) Costs() ([]assets.Cost, error) {
+	return nil, s.err("costs")
+}
+
 func (s *testSource) Fields() ([]assets.Field, error) {
 	return nil, s.err("fields")
 }
@@ -171,6 +261,14 @@ func (s *testSource) Locations() ([]assets.LocationHierarchy, error) {
 	return nil, s.err("locations")
 }
 
+func (s *testSource) LookupTables() ([]assets.LookupTable, error) {
+	return nil, s.err("lookup_tables")
+}
+
+func (s *testSource) RelationTypes() ([]assets.RelationType, error) {
+	return nil, s.err("relation_types")
+}
+
 func (s *testSource) Resthooks() ([]assets.Resthook, error) {
 	return nil, s.err("resthooks")
 }
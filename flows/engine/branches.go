@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nyaruka/gocommon/stringsx"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+
+	"github.com/pkg/errors"
+)
+
+// branchRun is the view of a run given to the actions of a branch of a forking router. Reads go to the actual run,
+// which isn't modified while branches are executing, and writes are kept here until the branches are joined.
+type branchRun struct {
+	flows.Run
+
+	step             flows.Step
+	results          flows.Results
+	saved            []*flows.Result
+	webhook          types.XValue
+	events           []flows.Event
+	modifiers        []flows.Modifier
+	evaluationErrors int
+	failure          error
+	err              error
+}
+
+func newBranchRun(run flows.Run, step flows.Step) *branchRun {
+	return &branchRun{Run: run, step: step, results: run.Results().Clone()}
+}
+
+func (r *branchRun) Results() flows.Results { return r.results }
+
+func (r *branchRun) SaveResult(result *flows.Result) {
+	result.Value = stringsx.Truncate(result.Value, r.Environment().TruncationPolicy().ResultValue)
+
+	r.results.Save(result)
+	r.saved = append(r.saved, result)
+}
+
+func (r *branchRun) Webhook() types.XValue {
+	if r.webhook != nil {
+		return r.webhook
+	}
+	return r.Run.Webhook()
+}
+
+func (r *branchRun) SetWebhook(value types.XValue) { r.webhook = value }
+
+func (r *branchRun) LogEvent(s flows.Step, event flows.Event) { r.events = append(r.events, event) }
+
+func (r *branchRun) LogError(s flows.Step, err error) { r.LogEvent(s, events.NewError(err)) }
+
+func (r *branchRun) RootContext(env envs.Environment) map[string]types.XValue {
+	c := r.Run.RootContext(env)
+	c["results"] = flows.Context(env, r.results)
+	c["webhook"] = r.Webhook()
+	return c
+}
+
+func (r *branchRun) EvaluateTemplateValue(template string) (types.XValue, error) {
+	defer r.Session().Profiler().Start(flows.ProfileCategoryTemplate, template)()
+
+	ctx := types.NewXObject(r.RootContext(r.Environment()))
+
+	var value types.XValue
+	var err error
+	if compiled := r.Flow().CompiledTemplate(template); compiled != nil {
+		value, err = compiled.EvaluateValue(r.Environment(), ctx)
+	} else {
+		value, err = r.Session().Arena().EvaluateTemplateValue(r.Environment(), ctx, template)
+	}
+	if err != nil {
+		r.evaluationErrors++
+	}
+	r.Session().DebugLog().Record(r, template, value, err)
+	return value, err
+}
+
+func (r *branchRun) EvaluateTemplateText(template string, escaping excellent.Escaping, truncate bool) (string, error) {
+	defer r.Session().Profiler().Start(flows.ProfileCategoryTemplate, template)()
+
+	ctx := types.NewXObject(r.RootContext(r.Environment()))
+
+	var value string
+	var err error
+	if compiled := r.Flow().CompiledTemplate(template); compiled != nil {
+		value, err = compiled.Evaluate(r.Environment(), ctx, escaping)
+	} else {
+		value, err = r.Session().Arena().EvaluateTemplate(r.Environment(), ctx, template, escaping)
+	}
+	if err != nil {
+		r.evaluationErrors++
+	}
+	if truncate {
+		value = stringsx.TruncateEllipsis(value, r.Session().Engine().MaxTemplateChars())
+	}
+	r.Session().DebugLog().Record(r, template, types.NewXText(value), err)
+	return value, err
+}
+
+func (r *branchRun) EvaluateTemplate(template string) (string, error) {
+	return r.EvaluateTemplateText(template, nil, true)
+}
+
+func (r *branchRun) EvaluationErrors() int { return r.Run.EvaluationErrors() + r.evaluationErrors }
+
+// executes the given actions, e.g. those of a branch node
+func (r *branchRun) execute(ctx context.Context, actions []flows.Action, strictTemplates bool) {
+	logModifier := func(m flows.Modifier) { r.modifiers = append(r.modifiers, m) }
+	logEvent := func(e flows.Event) { r.LogEvent(r.step, e) }
+
+	for _, action := range actions {
+		evaluationErrors := r.EvaluationErrors()
+
+		actionModifier, actionEvent := logModifier, logEvent
+		var buffer *actionBuffer
+		if strictTemplates {
+			buffer = &actionBuffer{}
+			actionModifier, actionEvent = buffer.logModifier, buffer.logEvent
+		}
+
+		stop := r.Session().Profiler().Start(flows.ProfileCategoryAction, actionProfileName(action))
+		err := executeAction(ctx, r, r.step, action, actionModifier, actionEvent)
+		stop()
+
+		if err != nil {
+			r.err = errors.Wrapf(err, "error executing action[type=%s,uuid=%s]", action.Type(), action.UUID())
+			return
+		}
+
+		if buffer != nil {
+			if r.EvaluationErrors() > evaluationErrors {
+				buffer.discard(logEvent)
+				r.failure = errors.Errorf("action[type=%s,uuid=%s] failed to evaluate a template", action.Type(), action.UUID())
+				return
+			}
+			buffer.flush(logModifier, logEvent)
+		}
+	}
+}
+
+// visits the branch nodes of a forking router, executing their actions concurrently, and then joins them by adding
+// their results and events to the run in branch order. Returns whether the branches timed out.
+func (s *session) visitBranches(ctx context.Context, sprint *sprint, run flows.Run, router flows.ForkingRouter) (bool, error) {
+	if timeout := router.BranchTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	nodes := make([]flows.Node, len(router.Branches()))
+	branches := make([]*branchRun, len(router.Branches()))
+
+	for i, uuid := range router.Branches() {
+		nodes[i] = run.Flow().GetNode(uuid)
+		if nodes[i] == nil {
+			return false, errors.Errorf("unable to find branch node %s in flow %s", uuid, run.Flow().UUID())
+		}
+
+		branches[i] = newBranchRun(run, run.CreateStep(nodes[i]))
+	}
+
+	wg := &sync.WaitGroup{}
+
+	for i := range branches {
+		wg.Add(1)
+
+		go func(b *branchRun, node flows.Node) {
+			defer wg.Done()
+			b.execute(ctx, node.Actions(), s.engine.StrictTemplates())
+		}(branches[i], nodes[i])
+	}
+
+	// branches only stop early if the context is cancelled, and we need to wait for them regardless before the run
+	// can be modified again
+	wg.Wait()
+
+	timedOut := router.BranchTimeout() > 0 && ctx.Err() == context.DeadlineExceeded
+
+	for _, b := range branches {
+		if b.err != nil {
+			return false, b.err
+		}
+
+		if s.joinBranch(sprint, run, b) {
+			return false, nil
+		}
+	}
+
+	return timedOut, nil
+}
+
+// joins a branch run back into the run by adding its results, modifiers and events. Returns whether the branch failed
+// in which case the run is failed too.
+func (s *session) joinBranch(sprint *sprint, run flows.Run, b *branchRun) bool {
+	for _, result := range b.saved {
+		run.SaveResult(result)
+	}
+	if b.webhook != nil {
+		run.SetWebhook(b.webhook)
+	}
+	for _, m := range b.modifiers {
+		sprint.logModifier(m)
+	}
+	for _, e := range b.events {
+		run.LogEvent(b.step, e)
+		sprint.logEvent(e)
+	}
+
+	// like any other node, a branch which fails stops the run
+	if b.failure != nil {
+		failRun(sprint, run, b.step, b.failure)
+		return true
+	}
+	return false
+}
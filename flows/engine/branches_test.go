@@ -0,0 +1,141 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/triggers"
+	"github.com/nyaruka/goflow/services/webhooks"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelBranches(t *testing.T) {
+	// branches call the test sources concurrently
+	uuids.SetGenerator(test.NewSeededUUIDGenerator(123456))
+	dates.SetNowSource(test.NewSequentialNowSource(time.Date(2018, 7, 6, 12, 30, 0, 123456789, time.UTC)))
+	defer uuids.SetGenerator(uuids.DefaultGenerator)
+	defer dates.SetNowSource(dates.DefaultNowSource)
+
+	// a webhook server where the call of each numbered branch only gets its response once the call of the next branch
+	// has had its response, so that the branches can only complete if they're executed concurrently, and do so in
+	// reverse order. The call of a hanging branch never gets a response.
+	responded := map[string]chan struct{}{"0": make(chan struct{}), "1": make(chan struct{}), "2": make(chan struct{})}
+	next := map[string]string{"0": "1", "1": "2"}
+	var responseOrder []string
+	var mutex sync.Mutex
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		branch := r.URL.Query().Get("branch")
+		if branch == "hang" {
+			<-r.Context().Done()
+			return
+		}
+		if n, ok := next[branch]; ok {
+			select {
+			case <-responded[n]:
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		fmt.Fprintf(w, `{"branch": "%s"}`, branch)
+
+		mutex.Lock()
+		responseOrder = append(responseOrder, branch)
+		mutex.Unlock()
+
+		if ch, ok := responded[branch]; ok {
+			close(ch)
+		}
+	}))
+
+	// listen on the port used by the testdata flows
+	listener, err := net.Listen("tcp", "127.0.0.1:49989")
+	require.NoError(t, err)
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	assetsJSON, err := os.ReadFile("testdata/parallel_branches.json")
+	require.NoError(t, err)
+
+	sa, err := test.CreateSessionAssets(assetsJSON, "")
+	require.NoError(t, err)
+
+	eng := engine.NewBuilder().WithWebhookServiceFactory(webhooks.NewServiceFactory(http.DefaultClient, nil, nil, nil, 10000, 0)).Build()
+
+	runFlow := func(flow *assets.FlowReference) (flows.Session, flows.Sprint) {
+		env := envs.NewBuilder().Build()
+		contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+		trigger := triggers.NewBuilder(env, flow, contact).Manual().Build()
+
+		session, sprint, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+
+		return session, sprint
+	}
+
+	// branches are executed concurrently so they can all complete
+	session, sprint := runFlow(assets.NewFlowReference("1b462ce8-983a-4393-b133-e15a0efdb70c", "Lookups"))
+
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, []string{"2", "1", "0"}, responseOrder)
+
+	// events are added to the run in branch order regardless of which branch finished first
+	assert.Equal(t, []string{"webhook_called", "run_result_changed", "webhook_called", "run_result_changed", "webhook_called", "run_result_changed", "run_result_changed"}, eventTypes(sprint.Events()))
+	assert.Equal(t, "http://127.0.0.1:49989/?branch=0", sprint.Events()[0].(*events.WebhookCalledEvent).URL)
+	assert.Equal(t, "http://127.0.0.1:49989/?branch=1", sprint.Events()[2].(*events.WebhookCalledEvent).URL)
+	assert.Equal(t, "http://127.0.0.1:49989/?branch=2", sprint.Events()[4].(*events.WebhookCalledEvent).URL)
+
+	run := session.Runs()[0]
+	assert.Equal(t, "Success", run.Results().Get("call_0").Category)
+	assert.Equal(t, "Success", run.Results().Get("call_1").Category)
+	assert.Equal(t, "Success", run.Results().Get("call_2").Category)
+	assert.Equal(t, "Completed", run.Results().Get("lookups").Category)
+
+	// and the last branch determines the webhook value
+	assert.Equal(t, `{branch: 2}`, run.Webhook().Render())
+
+	// each branch node gets a step on the run path after the router's node
+	path := run.Path()
+	assert.Equal(t, 4, len(path))
+	assert.Equal(t, flows.NodeUUID("a1f2b1c0-5b7e-4e7c-9f1d-000000000000"), path[0].NodeUUID())
+	assert.Equal(t, flows.NodeUUID("a1f2b1c1-5b7e-4e7c-9f1d-000000000000"), path[1].NodeUUID())
+	assert.Equal(t, flows.NodeUUID("a1f2b1c1-5b7e-4e7c-9f1d-000000000001"), path[2].NodeUUID())
+	assert.Equal(t, flows.NodeUUID("a1f2b1c1-5b7e-4e7c-9f1d-000000000002"), path[3].NodeUUID())
+	assert.Equal(t, path[1].UUID(), sprint.Events()[0].StepUUID())
+	assert.Equal(t, path[2].UUID(), sprint.Events()[2].StepUUID())
+
+	// branches which haven't completed before the timeout have their calls cancelled
+	session, sprint = runFlow(assets.NewFlowReference("2b462ce8-983a-4393-b133-e15a0efdb70c", "Slow Lookups"))
+
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, []string{"webhook_called", "run_result_changed", "webhook_called", "run_result_changed", "run_result_changed"}, eventTypes(sprint.Events()))
+
+	run = session.Runs()[0]
+	assert.Equal(t, "Success", run.Results().Get("call_0").Category)
+	assert.Equal(t, "Failure", run.Results().Get("call_1").Category)
+	assert.Equal(t, "Timed Out", run.Results().Get("lookups").Category)
+
+	// branch nodes can only have actions which can be executed concurrently
+	_, err = sa.Flows().Get("3b462ce8-983a-4393-b133-e15a0efdb70c")
+	assert.EqualError(t, err, "invalid node[uuid=c1f2b1c0-5b7e-4e7c-9f1d-000000000000]: invalid router: action type 'send_msg' can't be used in branch node c1f2b1c1-5b7e-4e7c-9f1d-000000000000")
+}
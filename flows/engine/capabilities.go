@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/actions"
+	"github.com/nyaruka/goflow/flows/definition/migrations"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/Masterminds/semver"
+	"golang.org/x/exp/slices"
+)
+
+// the first spec version of the current major version, which older definitions are migrated to before any registered
+// migrations are applied
+var baseSpecVersion = semver.MustParse("13.0.0")
+
+// Capabilities returns the spec versions, action types and resume types supported by this engine, and the services
+// which it has been configured with
+func (e *engine) Capabilities() *flows.Capabilities {
+	return &flows.Capabilities{
+		SpecVersions: specVersions(),
+		ActionTypes:  utils.SortedKeys(actions.RegisteredTypes()),
+		ResumeTypes:  utils.SortedKeys(resumes.RegisteredTypes()),
+		Services:     utils.SortedKeys(e.services.configured),
+	}
+}
+
+// gets the spec versions which flow definitions can have, oldest first
+func specVersions() []string {
+	versions := []*semver.Version{baseSpecVersion}
+	for v := range migrations.Registered() {
+		versions = append(versions, v)
+	}
+	slices.SortFunc(versions, func(a, b *semver.Version) bool { return a.LessThan(b) })
+
+	names := make([]string, len(versions))
+	for i, v := range versions {
+		names[i] = v.String()
+	}
+	return names
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+// checks whether the given message duplicates the last message received in this session, returning the reason if so.
+// A message is a duplicate if it has the same external ID, or the same text and attachments and was received within
+// the engine's duplicate input window.
+func (s *session) duplicateInputReason(msg *flows.MsgIn) events.MsgIgnoredReason {
+	last := s.lastMsgReceived()
+	if last == nil {
+		return ""
+	}
+
+	if msg.ExternalID() != "" && msg.ExternalID() == last.Msg.ExternalID() {
+		return events.MsgIgnoredReasonDuplicateExternalID
+	}
+
+	if msg.Text() == last.Msg.Text() && sameAttachments(msg, &last.Msg) && s.sources.Now().Sub(last.CreatedOn()) <= s.engine.DuplicateInputWindow() {
+		return events.MsgIgnoredReasonDuplicateText
+	}
+
+	return ""
+}
+
+// finds the most recent msg_received event across all the runs in this session
+func (s *session) lastMsgReceived() *events.MsgReceivedEvent {
+	var last *events.MsgReceivedEvent
+
+	for _, r := range s.runs {
+		runEvents := r.Events()
+		for i := len(runEvents) - 1; i >= 0; i-- {
+			if received, isReceived := runEvents[i].(*events.MsgReceivedEvent); isReceived {
+				if last == nil || received.CreatedOn().After(last.CreatedOn()) {
+					last = received
+				}
+				break
+			}
+		}
+	}
+
+	return last
+}
+
+func sameAttachments(msg1, msg2 *flows.MsgIn) bool {
+	if len(msg1.Attachments()) != len(msg2.Attachments()) {
+		return false
+	}
+	for i := range msg1.Attachments() {
+		if msg1.Attachments()[i] != msg2.Attachments()[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/flows/triggers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateInputs(t *testing.T) {
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	dates.SetNowSource(dates.NewSequentialNowSource(time.Date(2018, 10, 18, 14, 20, 30, 0, time.UTC)))
+
+	source, err := static.NewSource([]byte(`{
+		"flows": [
+			{
+				"uuid": "5472a1c3-63e1-484f-8485-cc8ecb16a058",
+				"name": "Main",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f01",
+						"router": {
+							"type": "switch",
+							"wait": {"type": "msg"},
+							"operand": "@input.text",
+							"categories": [
+								{"uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1", "name": "All", "exit_uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}
+							],
+							"default_category_uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1"
+						},
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a", "destination_uuid": "4b6a8c2e-1d3f-4e5a-8b7c-9d0e1f2a3b4c"}]
+					},
+					{
+						"uuid": "4b6a8c2e-1d3f-4e5a-8b7c-9d0e1f2a3b4c",
+						"router": {
+							"type": "switch",
+							"wait": {"type": "msg"},
+							"operand": "@input.text",
+							"result_name": "Answer",
+							"categories": [
+								{"uuid": "9c1d3e5f-7a2b-4c6d-8e0f-1a3b5c7d9e2f", "name": "Other", "exit_uuid": "0e2f4a6b-8c1d-4e3f-9a5b-7c9d1e3f5a7b"},
+								{"uuid": "3e5f7a9b-1c2d-4e6f-8a0b-2c4d6e8f0a1b", "name": "Duplicate", "exit_uuid": "7a9b1c3d-5e2f-4a4b-8c6d-8e0f2a4b6c8d"}
+							],
+							"default_category_uuid": "9c1d3e5f-7a2b-4c6d-8e0f-1a3b5c7d9e2f",
+							"duplicate_input_category_uuid": "3e5f7a9b-1c2d-4e6f-8a0b-2c4d6e8f0a1b"
+						},
+						"exits": [{"uuid": "0e2f4a6b-8c1d-4e3f-9a5b-7c9d1e3f5a7b"}, {"uuid": "7a9b1c3d-5e2f-4a4b-8c6d-8e0f2a4b6c8d"}]
+					}
+				]
+			}
+		]
+	}`))
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	newMsg := func(text, externalID string) *flows.MsgIn {
+		msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), "tel:+12065551212", nil, text, nil)
+		msg.SetExternalID(externalID)
+		return msg
+	}
+
+	// starts a session and resumes it with a first message so that it's waiting at the second node
+	startSession := func(eng flows.Engine) flows.Session {
+		contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+		trigger := triggers.NewBuilder(env, assets.NewFlowReference("5472a1c3-63e1-484f-8485-cc8ecb16a058", "Main"), contact).Manual().Build()
+
+		session, _, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+
+		_, err = session.Resume(context.Background(), resumes.NewMsg(nil, nil, newMsg("Hello", "EXT1")))
+		require.NoError(t, err)
+		require.Equal(t, flows.SessionStatusWaiting, session.Status())
+		return session
+	}
+
+	resume := func(session flows.Session, msg *flows.MsgIn) flows.Sprint {
+		sprint, err := session.Resume(context.Background(), resumes.NewMsg(nil, nil, msg))
+		require.NoError(t, err)
+		return sprint
+	}
+
+	// by default duplicates resume the session like any other message
+	session := startSession(engine.NewBuilder().Build())
+	resume(session, newMsg("Hello", "EXT1"))
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, "Other", session.Runs()[0].Results().Get("answer").Category)
+
+	// with an ignore policy, a message with the same external ID is ignored and the session keeps waiting
+	ignoreEng := engine.NewBuilder().WithDuplicateInputs(flows.DuplicateInputIgnore, time.Minute).Build()
+
+	session = startSession(ignoreEng)
+	sprint := resume(session, newMsg("Hello again", "EXT1"))
+	assert.Equal(t, flows.SessionStatusWaiting, session.Status())
+	require.Len(t, sprint.Events(), 1)
+	require.Equal(t, events.TypeMsgIgnored, sprint.Events()[0].Type())
+	assert.Equal(t, events.MsgIgnoredReasonDuplicateExternalID, sprint.Events()[0].(*events.MsgIgnoredEvent).Reason)
+
+	// as is a message with the same text within the window
+	sprint = resume(session, newMsg("Hello", "EXT2"))
+	assert.Equal(t, flows.SessionStatusWaiting, session.Status())
+	require.Len(t, sprint.Events(), 1)
+	assert.Equal(t, events.MsgIgnoredReasonDuplicateText, sprint.Events()[0].(*events.MsgIgnoredEvent).Reason)
+
+	// but a different message resumes the session
+	resume(session, newMsg("Goodbye", "EXT3"))
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, "Other", session.Runs()[0].Results().Get("answer").Category)
+
+	// same text outside of the window isn't a duplicate
+	shortWindowEng := engine.NewBuilder().WithDuplicateInputs(flows.DuplicateInputIgnore, time.Millisecond).Build()
+
+	session = startSession(shortWindowEng)
+	resume(session, newMsg("Hello", "EXT2"))
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+
+	// with a route policy, duplicates are routed to the duplicate input category of the router
+	routeEng := engine.NewBuilder().WithDuplicateInputs(flows.DuplicateInputRoute, time.Minute).Build()
+
+	session = startSession(routeEng)
+	resume(session, newMsg("Hello", "EXT1"))
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, "Duplicate", session.Runs()[0].Results().Get("answer").Category)
+	assert.Equal(t, "Hello", session.Runs()[0].Results().Get("answer").Value)
+}
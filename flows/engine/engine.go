@@ -1,34 +1,98 @@
 package engine
 
 import (
+	"context"
 	"encoding/json"
+	"time"
 
-	"github.com/nyaruka/gocommon/uuids"
 	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/definition/migrations"
+
+	"github.com/pkg/errors"
 )
 
 // an instance of the engine
 type engine struct {
 	services             *services
+	serviceTimeouts      map[flows.ServiceType]time.Duration
 	maxStepsPerSprint    int
 	maxResumesPerSession int
 	maxTemplateChars     int
+	strictTemplates      bool
+	stagedContactChanges bool
+	inlineGroupQueries   bool
+	sprintArenas         bool
+	templateCacheSize    int
+	concurrentActions    bool
+	prefetch             bool
+	profiling            bool
+	debugging            bool
+	eventTimings         bool
+	sessionDiffs         bool
+	runArchive           flows.RunArchive
+	maxEndedRuns         int
+	duplicateInputPolicy flows.DuplicateInputPolicy
+	duplicateInputWindow time.Duration
+	simulation           *flows.Simulation
+	assetsCache          *assetsCache
+	migrationConfig      *migrations.Config
+	eventSink            flows.EventSink
+	contactProvider      flows.ContactProvider
+	actionHooks          []flows.ActionHook
+	redactionPolicy      envs.RedactionPolicy
+	lifecycle            *lifecycle
+	shadowing            *shadowing
+
+	// the name of this engine's profile, the engine it's a profile of, and all the profiles of that engine
+	profile  string
+	base     *engine
+	profiles map[string]*engine
 }
 
 // NewSession creates a new session
-func (e *engine) NewSession(sa flows.SessionAssets, trigger flows.Trigger) (flows.Session, flows.Sprint, error) {
+func (e *engine) NewSession(ctx context.Context, sa flows.SessionAssets, trigger flows.Trigger) (flows.Session, flows.Sprint, error) {
+	// a trigger can select which profile of the engine the session is run with
+	if trigger.Profile() != "" && trigger.Profile() != e.profile {
+		profile := e.Profile(trigger.Profile())
+		if profile == nil {
+			return nil, nil, errors.Errorf("no engine profile named '%s'", trigger.Profile())
+		}
+		return profile.NewSession(ctx, sa, trigger)
+	}
+
+	sources := sprintSources(e, 0)
+	ctx = flows.ContextWithSources(ctx, sources)
+
 	s := &session{
-		uuid:       flows.SessionUUID(uuids.New()),
+		uuid:       flows.SessionUUID(sources.NewUUID()),
 		engine:     e,
+		lifecycle:  e.lifecycle,
+		shadowing:  e.shadowing,
 		assets:     sa,
 		trigger:    trigger,
 		status:     flows.SessionStatusActive,
 		batchStart: trigger.Batch(),
 		runsByUUID: make(map[flows.RunUUID]flows.Run),
+		sources:    sources,
+	}
+
+	end, err := e.lifecycle.begin(s.uuid)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer end()
+
+	// a shadow session is only started once this session's sprint is over, and only if the engine accepted the sprint
+	if e.shadowing != nil {
+		var recording *serviceRecording
+		if ctx, recording = e.shadowing.sample(ctx, sa, trigger.Flow().UUID); recording != nil {
+			defer e.shadowing.start(sa, trigger, recording)
+		}
 	}
 
-	sprint, err := s.start(trigger)
+	sprint, err := s.start(ctx, trigger)
 
 	return s, sprint, err
 }
@@ -38,10 +102,78 @@ func (e *engine) ReadSession(sa flows.SessionAssets, data json.RawMessage, missi
 	return readSession(e, sa, data, missing)
 }
 
-func (e *engine) Services() flows.Services  { return e.services }
-func (e *engine) MaxStepsPerSprint() int    { return e.maxStepsPerSprint }
-func (e *engine) MaxResumesPerSession() int { return e.maxResumesPerSession }
-func (e *engine) MaxTemplateChars() int     { return e.maxTemplateChars }
+// SessionAssets returns session assets for the given source. If the source is versioned, assets are constructed once
+// for each version and reused by later sessions, with the least recently used versions evicted when the engine's cache
+// is full. An engine should only be used with sources whose assets for a version don't depend on the environment.
+func (e *engine) SessionAssets(env envs.Environment, source assets.Source) (flows.SessionAssets, error) {
+	return e.assetsCache.get(env, source, e.migrationConfig)
+}
+
+// Stop stops the engine, and every profile of it, from starting or resuming sessions, and waits for the sprints which
+// are in flight, and any shadow sprints they started, to finish. If the given context is done first, the UUIDs of the
+// sessions whose sprints are still in flight are returned with the context's error, and those sessions shouldn't be
+// saved as their sprints were abandoned.
+func (e *engine) Stop(ctx context.Context) ([]flows.SessionUUID, error) {
+	return e.lifecycle.stop(ctx)
+}
+
+// AssetsCacheStats returns the metrics of the cache used by SessionAssets
+func (e *engine) AssetsCacheStats() flows.AssetsCacheStats { return e.assetsCache.getStats() }
+
+func (e *engine) Services() flows.Services   { return e.services }
+func (e *engine) MaxStepsPerSprint() int     { return e.maxStepsPerSprint }
+func (e *engine) MaxResumesPerSession() int  { return e.maxResumesPerSession }
+func (e *engine) MaxTemplateChars() int      { return e.maxTemplateChars }
+func (e *engine) StrictTemplates() bool      { return e.strictTemplates }
+func (e *engine) StagedContactChanges() bool { return e.stagedContactChanges }
+func (e *engine) InlineGroupQueries() bool   { return e.inlineGroupQueries }
+func (e *engine) SprintArenas() bool         { return e.sprintArenas }
+func (e *engine) TemplateCacheSize() int     { return e.templateCacheSize }
+func (e *engine) ConcurrentActions() bool    { return e.concurrentActions }
+func (e *engine) Prefetch() bool             { return e.prefetch }
+func (e *engine) Profiling() bool            { return e.profiling }
+func (e *engine) Debugging() bool            { return e.debugging }
+func (e *engine) EventTimings() bool         { return e.eventTimings }
+func (e *engine) SessionDiffs() bool         { return e.sessionDiffs }
+func (e *engine) MaxEndedRuns() int          { return e.maxEndedRuns }
+
+func (e *engine) DuplicateInputPolicy() flows.DuplicateInputPolicy { return e.duplicateInputPolicy }
+func (e *engine) DuplicateInputWindow() time.Duration              { return e.duplicateInputWindow }
+func (e *engine) EventSink() flows.EventSink                       { return e.eventSink }
+
+func (e *engine) ContactProvider() flows.ContactProvider { return e.contactProvider }
+func (e *engine) Simulation() *flows.Simulation          { return e.simulation }
+func (e *engine) ActionHooks() []flows.ActionHook        { return e.actionHooks }
+func (e *engine) RunArchive() flows.RunArchive           { return e.runArchive }
+
+func (e *engine) RedactionPolicy() envs.RedactionPolicy { return e.redactionPolicy }
+func (e *engine) ProfileName() string                   { return e.profile }
+
+// Profile returns the named profile of this engine, which is the engine itself if name is empty, or nil if there is
+// no such profile
+func (e *engine) Profile(name string) flows.Engine {
+	if profile := e.profileNamed(name); profile != nil {
+		return profile
+	}
+	return nil
+}
+
+func (e *engine) profileNamed(name string) *engine {
+	base := e
+	if e.base != nil {
+		base = e.base
+	}
+	if name == "" {
+		return base
+	}
+	return base.profiles[name]
+}
+
+// ServiceTimeout returns the timeout for calls to the given service, or zero if calls are only limited by the
+// context of the sprint
+func (e *engine) ServiceTimeout(service flows.ServiceType) time.Duration {
+	return e.serviceTimeouts[service]
+}
 
 var _ flows.Engine = (*engine)(nil)
 
@@ -51,7 +183,9 @@ var _ flows.Engine = (*engine)(nil)
 
 // Builder is a builder for engine configs
 type Builder struct {
-	eng *engine
+	eng      *engine
+	profiles []*profileConfig
+	shadow   *shadowConfig
 }
 
 // NewBuilder creates a new engine builder
@@ -59,9 +193,12 @@ func NewBuilder() *Builder {
 	return &Builder{
 		eng: &engine{
 			services:             newEmptyServices(),
+			serviceTimeouts:      make(map[flows.ServiceType]time.Duration),
 			maxStepsPerSprint:    100,
 			maxResumesPerSession: 500,
 			maxTemplateChars:     10000,
+			assetsCache:          newAssetsCache(0),
+			lifecycle:            newLifecycle(),
 		},
 	}
 }
@@ -69,30 +206,132 @@ func NewBuilder() *Builder {
 // WithEmailServiceFactory sets the email service factory
 func (b *Builder) WithEmailServiceFactory(f EmailServiceFactory) *Builder {
 	b.eng.services.email = f
+	b.eng.services.configured[flows.ServiceTypeEmail] = true
 	return b
 }
 
 // WithWebhookServiceFactory sets the webhook service factory
 func (b *Builder) WithWebhookServiceFactory(f WebhookServiceFactory) *Builder {
 	b.eng.services.webhook = f
+	b.eng.services.configured[flows.ServiceTypeWebhook] = true
+	return b
+}
+
+// WithWebhookRoute routes webhook calls whose URLs match the given pattern, e.g. http://*.svc.cluster.local/*, to
+// the service of the given factory instead of the default webhook service. Patterns are tried in the order they were
+// added and * matches any sequence of characters.
+func (b *Builder) WithWebhookRoute(pattern string, f WebhookServiceFactory) *Builder {
+	b.eng.services.webhookRoutes = append(b.eng.services.webhookRoutes, newWebhookRoute(pattern, f))
+	b.eng.services.configured[flows.ServiceTypeWebhook] = true
 	return b
 }
 
 // WithClassificationServiceFactory sets the NLU service factory
 func (b *Builder) WithClassificationServiceFactory(f ClassificationServiceFactory) *Builder {
 	b.eng.services.classification = f
+	b.eng.services.configured[flows.ServiceTypeClassification] = true
 	return b
 }
 
 // WithTicketServiceFactory sets the ticket service factory
 func (b *Builder) WithTicketServiceFactory(f TicketServiceFactory) *Builder {
 	b.eng.services.ticket = f
+	b.eng.services.configured[flows.ServiceTypeTicket] = true
 	return b
 }
 
 // WithAirtimeServiceFactory sets the airtime service factory
 func (b *Builder) WithAirtimeServiceFactory(f AirtimeServiceFactory) *Builder {
 	b.eng.services.airtime = f
+	b.eng.services.configured[flows.ServiceTypeAirtime] = true
+	return b
+}
+
+// WithDataCollectionServiceFactory sets the data collection service factory
+func (b *Builder) WithDataCollectionServiceFactory(f DataCollectionServiceFactory) *Builder {
+	b.eng.services.dataCollection = f
+	b.eng.services.configured[flows.ServiceTypeDataCollection] = true
+	return b
+}
+
+// WithCommerceServiceFactory sets the commerce service factory
+func (b *Builder) WithCommerceServiceFactory(f CommerceServiceFactory) *Builder {
+	b.eng.services.commerce = f
+	b.eng.services.configured[flows.ServiceTypeCommerce] = true
+	return b
+}
+
+// WithCallRecordingServiceFactory sets the call recording service factory
+func (b *Builder) WithCallRecordingServiceFactory(f CallRecordingServiceFactory) *Builder {
+	b.eng.services.callRecording = f
+	b.eng.services.configured[flows.ServiceTypeCallRecording] = true
+	return b
+}
+
+// WithCallTransferServiceFactory sets the call transfer service factory
+func (b *Builder) WithCallTransferServiceFactory(f CallTransferServiceFactory) *Builder {
+	b.eng.services.callTransfer = f
+	b.eng.services.configured[flows.ServiceTypeCallTransfer] = true
+	return b
+}
+
+// WithCredentialServiceFactory sets the credential service factory
+func (b *Builder) WithCredentialServiceFactory(f CredentialServiceFactory) *Builder {
+	b.eng.services.credential = f
+	b.eng.services.configured[flows.ServiceTypeCredential] = true
+	return b
+}
+
+// WithExchangeRateServiceFactory sets the exchange rate service factory
+func (b *Builder) WithExchangeRateServiceFactory(f ExchangeRateServiceFactory) *Builder {
+	b.eng.services.exchangeRate = f
+	b.eng.services.configured[flows.ServiceTypeExchangeRate] = true
+	return b
+}
+
+// WithAttachmentServiceFactory sets the attachment service factory
+func (b *Builder) WithAttachmentServiceFactory(f AttachmentServiceFactory) *Builder {
+	b.eng.services.attachment = f
+	b.eng.services.configured[flows.ServiceTypeAttachment] = true
+	return b
+}
+
+// WithGroupMembershipServiceFactory sets the group membership service factory. By default group queries are evaluated
+// by the engine itself.
+func (b *Builder) WithGroupMembershipServiceFactory(f GroupMembershipServiceFactory) *Builder {
+	b.eng.services.groupMembership = f
+	return b
+}
+
+// WithHTTPLogSinkFactory sets the HTTP log sink factory
+func (b *Builder) WithHTTPLogSinkFactory(f HTTPLogSinkFactory) *Builder {
+	b.eng.services.httpLogSink = f
+	return b
+}
+
+// WithEventSink sets a sink which receives the events of each sprint as they're generated
+func (b *Builder) WithEventSink(sink flows.EventSink) *Builder {
+	b.eng.eventSink = sink
+	return b
+}
+
+// WithContactProvider sets a provider from which the related contacts of the contact are loaded at the start of each
+// sprint, giving expressions read-only access to them as @related
+func (b *Builder) WithContactProvider(provider flows.ContactProvider) *Builder {
+	b.eng.contactProvider = provider
+	return b
+}
+
+// RegisterActionHook adds a hook which is called before and after every action is executed. Hooks are called in the
+// order they were registered and the first to return an error from Before blocks the action.
+func (b *Builder) RegisterActionHook(hook flows.ActionHook) *Builder {
+	b.eng.actionHooks = append(b.eng.actionHooks, hook)
+	return b
+}
+
+// WithServiceTimeout sets the timeout for each call to the given service, after which the call is cancelled
+func (b *Builder) WithServiceTimeout(service flows.ServiceType, timeout time.Duration) *Builder {
+	b.eng.serviceTimeouts[service] = timeout
 	return b
 }
 
@@ -114,5 +353,188 @@ func (b *Builder) WithMaxTemplateChars(max int) *Builder {
 	return b
 }
 
+// WithStrictTemplates sets whether template evaluation errors in actions should fail the run
+func (b *Builder) WithStrictTemplates(strict bool) *Builder {
+	b.eng.strictTemplates = strict
+	return b
+}
+
+// WithSprintArenas sets whether each sprint should use an arena of pooled scratch memory for template evaluation,
+// which reduces garbage collection in busy deployments at the cost of holding onto that memory between sprints
+func (b *Builder) WithSprintArenas(enabled bool) *Builder {
+	b.eng.sprintArenas = enabled
+	return b
+}
+
+// WithTemplateCacheSize sets how many template evaluations can be cached during each sprint. Caching is disabled by
+// default.
+func (b *Builder) WithTemplateCacheSize(size int) *Builder {
+	b.eng.templateCacheSize = size
+	return b
+}
+
+// WithConcurrentActions sets whether consecutive actions in a node which only call services, e.g. webhook calls, and
+// don't depend on each other's results should be executed concurrently
+func (b *Builder) WithConcurrentActions(enabled bool) *Builder {
+	b.eng.concurrentActions = enabled
+	return b
+}
+
+// WithPrefetch sets whether sessions which are waiting should load the flows they're likely to enter when resumed, so
+// that the templates and router tests of those flows are already compiled in the session assets
+func (b *Builder) WithPrefetch(enabled bool) *Builder {
+	b.eng.prefetch = enabled
+	return b
+}
+
+// WithProfiling sets whether the time spent on actions, routers, templates and service calls should be recorded during
+// each sprint, and the slowest of them reported in a sprint_profile event at the end of the sprint
+func (b *Builder) WithProfiling(enabled bool) *Builder {
+	b.eng.profiling = enabled
+	return b
+}
+
+// WithDebugging sets whether the value of every template evaluated during each sprint should be recorded, along with
+// where in the flow it was evaluated, in a debug log attached to the sprint
+func (b *Builder) WithDebugging(enabled bool) *Builder {
+	b.eng.debugging = enabled
+	return b
+}
+
+// WithEventTimings sets whether events generated by actions should record when the action started and how long it had
+// been executing for when the event was generated
+func (b *Builder) WithEventTimings(enabled bool) *Builder {
+	b.eng.eventTimings = enabled
+	return b
+}
+
+// WithSessionDiffs sets whether sprints should record the changes they make to their sessions, which requires taking
+// a snapshot of the session at the start of each sprint
+func (b *Builder) WithSessionDiffs(enabled bool) *Builder {
+	b.eng.sessionDiffs = enabled
+	return b
+}
+
+// WithRunArchive sets the archive which runs that have ended are moved to at the end of each sprint, keeping only the
+// given number of the most recently ended runs in the session
+func (b *Builder) WithRunArchive(archive flows.RunArchive, maxEndedRuns int) *Builder {
+	b.eng.runArchive = archive
+	b.eng.maxEndedRuns = maxEndedRuns
+	return b
+}
+
+// WithDuplicateInputs sets what should happen when a session is resumed with a message which duplicates the last message
+// in the session, i.e. has the same external ID, or the same text and attachments and was received within the given
+// window of the last message
+func (b *Builder) WithDuplicateInputs(policy flows.DuplicateInputPolicy, window time.Duration) *Builder {
+	b.eng.duplicateInputPolicy = policy
+	b.eng.duplicateInputWindow = window
+	return b
+}
+
+// WithSimulation sets whether sessions should be run deterministically, with random numbers and UUIDs generated from
+// the seed of the given simulation and the current time fixed to its now. Each session has its own sources of these so
+// simulated sessions don't affect any other sessions running in the same process.
+func (b *Builder) WithSimulation(sim *flows.Simulation) *Builder {
+	b.eng.simulation = sim
+	return b
+}
+
+// WithAssetsCacheSize sets how many versions of session assets are cached by SessionAssets, or zero to disable caching
+func (b *Builder) WithAssetsCacheSize(size int) *Builder {
+	b.eng.assetsCache = newAssetsCache(size)
+	return b
+}
+
+// WithMigrationConfig sets the migration config used for flows in session assets returned by SessionAssets
+func (b *Builder) WithMigrationConfig(config *migrations.Config) *Builder {
+	b.eng.migrationConfig = config
+	return b
+}
+
+// WithStagedContactChanges sets whether changes to the contact during a sprint are only kept if the sprint doesn't fail
+func (b *Builder) WithStagedContactChanges(staged bool) *Builder {
+	b.eng.stagedContactChanges = staged
+	return b
+}
+
+// WithInlineGroupQueries sets whether group tests evaluate the queries of query based groups against the contact
+// rather than relying on the contact's stored group membership
+func (b *Builder) WithInlineGroupQueries(inline bool) *Builder {
+	b.eng.inlineGroupQueries = inline
+	return b
+}
+
+// WithRedactionPolicy sets a redaction policy which overrides that of the environment of every session
+func (b *Builder) WithRedactionPolicy(policy envs.RedactionPolicy) *Builder {
+	b.eng.redactionPolicy = policy
+	return b
+}
+
+// WithProfile adds a named profile of the engine, e.g. for a tenant with its own quotas and service providers. Each
+// profile starts as a copy of the built engine which the given function can then reconfigure with the builder, i.e. to
+// change limits, service factories, options or the redaction policy. Sessions are run with a profile if their trigger
+// names it, or if they're started with the engine returned by Profile, and are read with the same profile.
+func (b *Builder) WithProfile(name string, configure func(*Builder)) *Builder {
+	b.profiles = append(b.profiles, &profileConfig{name: name, configure: configure})
+	return b
+}
+
+// WithShadowRuns makes the engine shadow the given rate (0 to 1) of the sprints it runs for flows which have a draft
+// revision. A started session is shadowed by a session started with the same trigger, and a resumed session by a copy
+// of it resumed with the same resume, which run the draft revisions of flows. Shadow sprints are run in the background
+// once the sprint they shadow is over, and are passed to the given sink when they've finished. They're run by a copy
+// of the engine without an event sink, action hooks or a run archive, and whose services replay the results of the
+// calls made by the sprint being shadowed, so that they have no side effects, which the given function can reconfigure.
+func (b *Builder) WithShadowRuns(drafts DraftProvider, rate float64, sink ShadowSink, configure func(*Builder)) *Builder {
+	config := b.shadowConfig()
+	config.drafts = drafts
+	config.rate = rate
+	config.sink = sink
+	config.configure = configure
+	return b
+}
+
+// WithShadowRunLimits sets the max number of shadow sprints which can be running at once, beyond which sampled sprints
+// aren't shadowed, and how long each shadow sprint can run for
+func (b *Builder) WithShadowRunLimits(maxConcurrent int, timeout time.Duration) *Builder {
+	config := b.shadowConfig()
+	config.maxConcurrent = maxConcurrent
+	config.timeout = timeout
+	return b
+}
+
+func (b *Builder) shadowConfig() *shadowConfig {
+	if b.shadow == nil {
+		b.shadow = &shadowConfig{maxConcurrent: 10, timeout: time.Minute}
+	}
+	return b.shadow
+}
+
 // Build returns the final engine
-func (b *Builder) Build() flows.Engine { return b.eng }
+func (b *Builder) Build() flows.Engine {
+	if len(b.profiles) > 0 {
+		b.eng.profiles = make(map[string]*engine, len(b.profiles))
+
+		for _, p := range b.profiles {
+			pb := &Builder{eng: b.eng.clone()}
+			p.configure(pb)
+
+			pb.eng.profile = p.name
+			pb.eng.base = b.eng
+			b.eng.profiles[p.name] = pb.eng
+		}
+	}
+
+	if b.shadow != nil && b.shadow.drafts != nil {
+		b.eng.shadowing = newShadowing(b.eng, b.shadow)
+		b.eng.services = b.eng.services.recording()
+
+		for _, p := range b.eng.profiles {
+			p.shadowing = newShadowing(p, b.shadow)
+			p.services = p.services.recording()
+		}
+	}
+
+	return b.eng
+}
@@ -1,27 +1,81 @@
 package engine_test
 
 import (
+	"context"
 	"net/http"
 	"testing"
+	"time"
 
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/triggers"
 	"github.com/nyaruka/goflow/services/webhooks"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBuilder(t *testing.T) {
 	// create engine with no services
-	eng := engine.NewBuilder().WithMaxStepsPerSprint(123).WithMaxResumesPerSession(567).Build()
+	eng := engine.NewBuilder().
+		WithMaxStepsPerSprint(123).
+		WithMaxResumesPerSession(567).
+		WithStrictTemplates(true).
+		WithSprintArenas(true).
+		WithTemplateCacheSize(50).
+		WithConcurrentActions(true).
+		WithPrefetch(true).
+		WithProfiling(true).
+		WithDebugging(true).
+		WithRunArchive(nil, 10).
+		WithEventTimings(true).
+		WithSessionDiffs(true).
+		WithDuplicateInputs(flows.DuplicateInputRoute, time.Minute).
+		WithSimulation(&flows.Simulation{Seed: 123, Now: time.Date(2022, 2, 3, 13, 45, 30, 0, time.UTC)}).
+		WithServiceTimeout(flows.ServiceTypeWebhook, 15*time.Second).
+		Build()
 
 	assert.Equal(t, 123, eng.MaxStepsPerSprint())
 	assert.Equal(t, 567, eng.MaxResumesPerSession())
+	assert.True(t, eng.StrictTemplates())
+	assert.True(t, eng.SprintArenas())
+	assert.Equal(t, 50, eng.TemplateCacheSize())
+	assert.True(t, eng.ConcurrentActions())
+	assert.True(t, eng.Prefetch())
+	assert.True(t, eng.Profiling())
+	assert.True(t, eng.Debugging())
+	assert.Nil(t, eng.RunArchive())
+	assert.Equal(t, 10, eng.MaxEndedRuns())
+	assert.True(t, eng.EventTimings())
+	assert.True(t, eng.SessionDiffs())
+	assert.Equal(t, flows.DuplicateInputRoute, eng.DuplicateInputPolicy())
+	assert.Equal(t, time.Minute, eng.DuplicateInputWindow())
+	assert.Equal(t, int64(123), eng.Simulation().Seed)
+	assert.Equal(t, 15*time.Second, eng.ServiceTimeout(flows.ServiceTypeWebhook))
+	assert.Equal(t, time.Duration(0), eng.ServiceTimeout(flows.ServiceTypeEmail))
 
 	_, err := eng.Services().Email(nil)
 	assert.EqualError(t, err, "no email service factory configured")
 	_, err = eng.Services().Airtime(nil)
 	assert.EqualError(t, err, "no airtime service factory configured")
+	_, err = eng.Services().DataCollection(nil)
+	assert.EqualError(t, err, "no data collection service factory configured")
+	_, err = eng.Services().Commerce(nil)
+	assert.EqualError(t, err, "no commerce service factory configured")
+	_, err = eng.Services().CallRecording(nil)
+	assert.EqualError(t, err, "no call recording service factory configured")
+	_, err = eng.Services().CallTransfer(nil)
+	assert.EqualError(t, err, "no call transfer service factory configured")
+	_, err = eng.Services().Credential(nil)
+	assert.EqualError(t, err, "no credential service factory configured")
+	_, err = eng.Services().ExchangeRate(nil)
+	assert.EqualError(t, err, "no exchange rate service factory configured")
+	_, err = eng.Services().Attachment(nil)
+	assert.EqualError(t, err, "no attachment service factory configured")
 	_, err = eng.Services().Classification(nil)
 	assert.EqualError(t, err, "no classification service factory configured")
 	_, err = eng.Services().Ticket(nil)
@@ -29,8 +83,13 @@ func TestBuilder(t *testing.T) {
 	_, err = eng.Services().Webhook(nil)
 	assert.EqualError(t, err, "no webhook service factory configured")
 
+	// group membership is evaluated by the engine unless a factory is configured
+	groupSvc, err := eng.Services().GroupMembership(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, flows.NewQueryGroupMembership(), groupSvc)
+
 	// include a webhook service
-	webhookSvc := webhooks.NewService(&http.Client{}, nil, nil, map[string]string{"User-Agent": "goflow"}, 1000)
+	webhookSvc := webhooks.NewService(&http.Client{}, nil, nil, map[string]string{"User-Agent": "goflow"}, 1000, 1000)
 
 	eng = engine.NewBuilder().
 		WithWebhookServiceFactory(func(flows.SessionAssets) (flows.WebhookService, error) { return webhookSvc, nil }).
@@ -40,3 +99,120 @@ func TestBuilder(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, webhookSvc, svc)
 }
+
+func TestCapabilities(t *testing.T) {
+	eng := engine.NewBuilder().Build()
+	caps := eng.Capabilities()
+
+	assert.Equal(t, []string{"13.0.0", "13.1.0", "13.2.0"}, caps.SpecVersions)
+	assert.True(t, caps.SupportsActionType("send_msg"))
+	assert.False(t, caps.SupportsActionType("do_the_foo"))
+	assert.True(t, caps.SupportsResumeType("msg"))
+	assert.Equal(t, []flows.ServiceType{}, caps.Services)
+
+	// services are only advertised if they have been configured
+	eng = engine.NewBuilder().
+		WithWebhookServiceFactory(webhooks.NewServiceFactory(http.DefaultClient, nil, nil, nil, 1000, 0)).
+		WithEmailServiceFactory(func(flows.SessionAssets) (flows.EmailService, error) { return nil, nil }).
+		Build()
+
+	caps = eng.Capabilities()
+	assert.Equal(t, []flows.ServiceType{flows.ServiceTypeEmail, flows.ServiceTypeWebhook}, caps.Services)
+	assert.True(t, caps.HasService(flows.ServiceTypeWebhook))
+	assert.False(t, caps.HasService(flows.ServiceTypeAirtime))
+
+	// negotiating with an older engine leaves only what both have
+	older := &flows.Capabilities{
+		SpecVersions: []string{"13.0.0", "13.1.0"},
+		ActionTypes:  []string{"send_msg", "set_run_result"},
+		ResumeTypes:  []string{"msg", "wait_timeout"},
+		Services:     []flows.ServiceType{flows.ServiceTypeWebhook},
+	}
+	assert.Equal(t, &flows.Capabilities{
+		SpecVersions: []string{"13.0.0", "13.1.0"},
+		ActionTypes:  []string{"send_msg", "set_run_result"},
+		ResumeTypes:  []string{"msg", "wait_timeout"},
+		Services:     []flows.ServiceType{flows.ServiceTypeWebhook},
+	}, caps.Negotiate(older))
+	assert.False(t, caps.Negotiate(older).SupportsSpecVersion("13.2.0"))
+
+	assert.Equal(t, `{"spec_versions":["13.0.0","13.1.0"],"action_types":["send_msg","set_run_result"],"resume_types":["msg","wait_timeout"],"services":["webhook"]}`, string(jsonx.MustMarshal(older)))
+}
+
+func TestProfiles(t *testing.T) {
+	env := envs.NewBuilder().Build()
+	source, err := static.NewSource([]byte(`{
+		"flows": [
+			{"uuid": "5472a1c3-63e1-484f-8485-cc8ecb16a058", "name": "Empty", "spec_version": "13.1", "language": "eng", "type": "messaging", "nodes": []}
+		]
+	}`))
+	require.NoError(t, err)
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	eng := engine.NewBuilder().
+		WithMaxStepsPerSprint(123).
+		WithProfile("acme", func(b *engine.Builder) {
+			b.WithMaxStepsPerSprint(10).
+				WithServiceTimeout(flows.ServiceTypeWebhook, 5*time.Second).
+				WithEmailServiceFactory(func(flows.SessionAssets) (flows.EmailService, error) { return nil, nil }).
+				WithRedactionPolicy(envs.RedactionPolicyURNs)
+		}).
+		WithStrictTemplates(true).
+		Build()
+
+	acme := eng.Profile("acme")
+	require.NotNil(t, acme)
+	assert.Equal(t, "acme", acme.ProfileName())
+	assert.Equal(t, 10, acme.MaxStepsPerSprint())
+	assert.Equal(t, 5*time.Second, acme.ServiceTimeout(flows.ServiceTypeWebhook))
+	assert.True(t, acme.StrictTemplates()) // inherited from the engine
+	assert.True(t, acme.Capabilities().HasService(flows.ServiceTypeEmail))
+	assert.Equal(t, envs.RedactionPolicyURNs, acme.RedactionPolicy())
+
+	// the engine itself isn't changed by its profiles
+	assert.Equal(t, "", eng.ProfileName())
+	assert.Equal(t, 123, eng.MaxStepsPerSprint())
+	assert.Equal(t, time.Duration(0), eng.ServiceTimeout(flows.ServiceTypeWebhook))
+	assert.False(t, eng.Capabilities().HasService(flows.ServiceTypeEmail))
+	assert.Equal(t, envs.RedactionPolicy(""), eng.RedactionPolicy())
+
+	assert.Equal(t, eng, acme.Profile(""))
+	assert.Equal(t, acme, acme.Profile("acme"))
+	assert.Nil(t, eng.Profile("xyz"))
+
+	// a trigger can select a profile
+	flow := assets.NewFlowReference("5472a1c3-63e1-484f-8485-cc8ecb16a058", "Empty")
+	contact := flows.NewEmptyContact(sa, "Bob", envs.NilLanguage, nil)
+	trigger := triggers.NewBuilder(env, flow, contact).WithProfile("acme").Manual().Build()
+
+	session, _, err := eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+	assert.Equal(t, acme, session.Engine())
+	assert.Equal(t, envs.RedactionPolicyURNs, session.Environment().RedactionPolicy())
+
+	// and sessions are read with the profile they were started with, without its overrides being saved
+	sessionJSON := jsonx.MustMarshal(session)
+	assert.Contains(t, string(sessionJSON), `"profile":"acme"`)
+	assert.Contains(t, string(sessionJSON), `"redaction_policy":"none"`)
+
+	session, err = eng.ReadSession(sa, sessionJSON, assets.PanicOnMissing)
+	require.NoError(t, err)
+	assert.Equal(t, acme, session.Engine())
+	assert.Equal(t, envs.RedactionPolicyURNs, session.Environment().RedactionPolicy())
+
+	// as are sessions started with a profile by the caller
+	session, _, err = acme.NewSession(context.Background(), sa, triggers.NewBuilder(env, flow, contact).Manual().Build())
+	require.NoError(t, err)
+
+	session, err = eng.ReadSession(sa, jsonx.MustMarshal(session), assets.PanicOnMissing)
+	require.NoError(t, err)
+	assert.Equal(t, acme, session.Engine())
+
+	// but not with profiles which don't exist
+	_, _, err = eng.NewSession(context.Background(), sa, triggers.NewBuilder(env, flow, contact).WithProfile("xyz").Manual().Build())
+	assert.EqualError(t, err, "no engine profile named 'xyz'")
+
+	_, err = engine.NewBuilder().Build().ReadSession(sa, sessionJSON, assets.PanicOnMissing)
+	assert.EqualError(t, err, "unable to read session with no engine profile named 'acme'")
+}
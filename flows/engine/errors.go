@@ -6,6 +6,10 @@ const (
 	ErrorResumeNonWaitingSession int = 101
 	ErrorResumeNoWaitingRun      int = 102
 	ErrorResumeRejectedByWait    int = 103
+	ErrorResumeNoSuchTimer       int = 104
+	ErrorResumeThreadMismatch    int = 105
+
+	ErrorEngineStopped int = 201
 )
 
 type Error struct {
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+
+	"github.com/pkg/errors"
+)
+
+// the body posted to the exit webhook of a flow when one of its runs exits
+type exitWebhookPayload struct {
+	SessionUUID flows.SessionUUID       `json:"session_uuid"`
+	RunUUID     flows.RunUUID           `json:"run_uuid"`
+	Flow        *assets.FlowReference   `json:"flow"`
+	Contact     *flows.ContactReference `json:"contact,omitempty"`
+	Status      flows.RunStatus         `json:"status"`
+	Results     flows.Results           `json:"results"`
+	CreatedOn   time.Time               `json:"created_on"`
+	ExitedOn    *time.Time              `json:"exited_on"`
+}
+
+// gets the UUIDs of the runs which have already exited, so that we can tell which exit during a sprint
+func (s *session) exitedRuns() map[flows.RunUUID]bool {
+	exited := make(map[flows.RunUUID]bool, len(s.runs))
+	for _, r := range s.runs {
+		if r.ExitedOn() != nil {
+			exited[r.UUID()] = true
+		}
+	}
+	return exited
+}
+
+// calls the exit webhooks of the flows of runs which have exited during this sprint, i.e. which have exited but aren't in
+// the given set of runs which had already exited before it
+func (s *session) callExitWebhooks(ctx context.Context, sprint *sprint, alreadyExited map[flows.RunUUID]bool) {
+	for _, run := range s.runs {
+		if run.ExitedOn() == nil || alreadyExited[run.UUID()] || run.Flow() == nil || run.Flow().ExitWebhook() == nil {
+			continue
+		}
+
+		logEvent := func(e flows.Event) {
+			run.LogEvent(nil, e)
+			sprint.logEvent(e)
+		}
+
+		s.callExitWebhook(ctx, run, run.Flow().ExitWebhook(), logEvent)
+	}
+}
+
+// calls the given exit webhook for the given run, retrying calls which fail with a connection error or a server error
+func (s *session) callExitWebhook(ctx context.Context, run flows.Run, webhook *flows.ExitWebhook, logEvent flows.EventCallback) {
+	url, err := run.EvaluateTemplate(webhook.URL)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
+
+	headers := make(map[string]string, len(webhook.Headers))
+	for key, value := range webhook.Headers {
+		headers[key], err = run.EvaluateTemplate(value)
+		if err != nil {
+			logEvent(events.NewError(err))
+		}
+	}
+
+	body := jsonx.MustMarshal(&exitWebhookPayload{
+		SessionUUID: s.uuid,
+		RunUUID:     run.UUID(),
+		Flow:        run.FlowReference(),
+		Contact:     run.Contact().Reference(),
+		Status:      run.Status(),
+		Results:     run.Results(),
+		CreatedOn:   run.CreatedOn(),
+		ExitedOn:    run.ExitedOn(),
+	})
+
+	svc, err := s.Engine().Services().Webhook(s.Assets())
+	if err != nil {
+		logEvent(events.NewError(err))
+		return
+	}
+
+	for attempt := 0; attempt <= webhook.Retries; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			logEvent(events.NewError(errors.Wrap(err, "unable to create exit webhook request")))
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+
+		if retry := s.callExitWebhookOnce(ctx, svc, req, logEvent); !retry {
+			return
+		}
+	}
+}
+
+// makes a single call to an exit webhook, returning whether it failed in a way that's worth retrying, i.e. because we
+// couldn't connect or the server returned an error
+func (s *session) callExitWebhookOnce(ctx context.Context, svc flows.WebhookService, req *http.Request, logEvent flows.EventCallback) bool {
+	if timeout := s.engine.ServiceTimeout(flows.ServiceTypeWebhook); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	call, err := svc.Call(req.WithContext(ctx))
+	if err != nil {
+		var denied *flows.WebhookDeniedError
+		if errors.As(err, &denied) {
+			logEvent(events.NewWebhookBlocked(denied.URL, denied.Reason))
+		} else {
+			logEvent(events.NewError(err))
+		}
+	}
+	if call == nil {
+		return false // the service refused to make the call
+	}
+
+	if call.Response == nil || err != nil {
+		logEvent(events.NewWebhookCalled(call, flows.CallStatusConnectionError, "", nil))
+		return true
+	}
+
+	status := flows.CallStatusSuccess
+	if call.Response.StatusCode/100 != 2 {
+		status = flows.CallStatusResponseError
+	}
+
+	logEvent(events.NewWebhookCalled(call, status, "", nil))
+	return call.Response.StatusCode/100 == 5
+}
@@ -0,0 +1,130 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/flows/triggers"
+	"github.com/nyaruka/goflow/services/webhooks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitWebhooks(t *testing.T) {
+	type received struct {
+		auth string
+		body map[string]any
+	}
+	var requests []received
+	statuses := []int{503, 200}
+
+	// a server which records requests and responds with each of the statuses in turn
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec := received{auth: r.Header.Get("Authorization")}
+		jsonx.MustUnmarshal(body, &rec.body)
+		requests = append(requests, rec)
+
+		w.WriteHeader(statuses[0])
+		statuses = statuses[1:]
+		fmt.Fprint(w, `{"ok": true}`)
+	}))
+	defer server.Close()
+
+	source, err := static.NewSource([]byte(fmt.Sprintf(`{
+		"flows": [
+			{
+				"uuid": "5472a1c3-63e1-484f-8485-cc8ecb16a058",
+				"name": "Main",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"exit_webhook": {
+					"url": "%s/runs/@run.uuid",
+					"headers": {"Authorization": "Token @(upper(\"abc\"))"},
+					"retries": 1
+				},
+				"nodes": [
+					{
+						"uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f01",
+						"actions": [
+							{"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a", "type": "send_msg", "text": "Hi @contact.name"}
+						],
+						"router": {
+							"type": "switch",
+							"wait": {"type": "msg"},
+							"result_name": "Reply",
+							"operand": "@input.text",
+							"categories": [
+								{"uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1", "name": "All", "exit_uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}
+							],
+							"default_category_uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1"
+						},
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}]
+					}
+				]
+			}
+		]
+	}`, server.URL)))
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	eng := engine.NewBuilder().
+		WithWebhookServiceFactory(webhooks.NewServiceFactory(http.DefaultClient, nil, nil, nil, 10000, 0)).
+		Build()
+
+	contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+	trigger := triggers.NewBuilder(env, assets.NewFlowReference("5472a1c3-63e1-484f-8485-cc8ecb16a058", "Main"), contact).Manual().Build()
+
+	// the run is waiting so the webhook isn't called yet
+	session, _, err := eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+	assert.Len(t, requests, 0)
+
+	msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), urns.NilURN, nil, "Hello", nil)
+	sprint, err := session.Resume(context.Background(), resumes.NewMsg(env, nil, msg))
+	require.NoError(t, err)
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+
+	// the first call failed with a server error so was retried
+	var called []*events.WebhookCalledEvent
+	for _, e := range sprint.Events() {
+		if typed, ok := e.(*events.WebhookCalledEvent); ok {
+			called = append(called, typed)
+		}
+	}
+	require.Len(t, called, 2)
+	assert.Equal(t, flows.CallStatusResponseError, called[0].Status)
+	assert.Equal(t, flows.CallStatusSuccess, called[1].Status)
+	assert.Equal(t, fmt.Sprintf("%s/runs/%s", server.URL, session.Runs()[0].UUID()), called[1].URL)
+
+	require.Len(t, requests, 2)
+	assert.Equal(t, "Token ABC", requests[1].auth)
+	assert.Equal(t, string(session.Runs()[0].UUID()), requests[1].body["run_uuid"])
+	assert.Equal(t, "completed", requests[1].body["status"])
+	assert.Equal(t, "Bob", requests[1].body["contact"].(map[string]any)["name"])
+	assert.Equal(t, "Hello", requests[1].body["results"].(map[string]any)["reply"].(map[string]any)["value"])
+
+	// the webhook isn't called again for runs which had already exited
+	_, err = session.Resume(context.Background(), resumes.NewMsg(env, nil, msg))
+	assert.Error(t, err)
+	assert.Len(t, requests, 2)
+}
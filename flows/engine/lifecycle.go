@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/nyaruka/goflow/flows"
+)
+
+// tracks the sprints in flight on an engine, and the work they leave running in the background, so that it can be
+// stopped without losing them
+type lifecycle struct {
+	mutex      sync.Mutex
+	stopped    bool
+	inFlight   map[flows.SessionUUID]int
+	background int
+	drained    chan struct{}
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{inFlight: make(map[flows.SessionUUID]int)}
+}
+
+// records the start of a sprint of the given session, returning a function to record its end, or an error if the
+// engine has been stopped
+func (l *lifecycle) begin(uuid flows.SessionUUID) (func(), error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.stopped {
+		return nil, newError(ErrorEngineStopped, "engine has been stopped and isn't accepting new sprints")
+	}
+
+	l.inFlight[uuid]++
+
+	return func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		if l.inFlight[uuid]--; l.inFlight[uuid] <= 0 {
+			delete(l.inFlight, uuid)
+		}
+		l.checkDrained()
+	}, nil
+}
+
+// runs the given function in the background on behalf of a sprint in flight, e.g. a shadow sprint, so that stopping
+// waits for it to finish too
+func (l *lifecycle) goBackground(fn func()) {
+	l.mutex.Lock()
+	l.background++
+	l.mutex.Unlock()
+
+	go func() {
+		defer func() {
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+
+			l.background--
+			l.checkDrained()
+		}()
+
+		fn()
+	}()
+}
+
+// closes the drained channel if there's nothing left in flight, must be called with the mutex held
+func (l *lifecycle) checkDrained() {
+	if l.drained != nil && len(l.inFlight) == 0 && l.background == 0 {
+		close(l.drained)
+		l.drained = nil
+	}
+}
+
+// refuses new sprints and waits for those in flight, and their background work, to finish or for the given context to
+// be done, in which case the sessions of the sprints still in flight are returned
+func (l *lifecycle) stop(ctx context.Context) ([]flows.SessionUUID, error) {
+	l.mutex.Lock()
+	l.stopped = true
+
+	if len(l.inFlight) == 0 && l.background == 0 {
+		l.mutex.Unlock()
+		return nil, nil
+	}
+	if l.drained == nil {
+		l.drained = make(chan struct{})
+	}
+	drained := l.drained
+	l.mutex.Unlock()
+
+	select {
+	case <-drained:
+		return nil, nil
+	case <-ctx.Done():
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	abandoned := make([]flows.SessionUUID, 0, len(l.inFlight))
+	for uuid := range l.inFlight {
+		abandoned = append(abandoned, uuid)
+	}
+	sort.Slice(abandoned, func(i, j int) bool { return abandoned[i] < abandoned[j] })
+
+	return abandoned, ctx.Err()
+}
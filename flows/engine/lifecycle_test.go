@@ -0,0 +1,141 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/flows/triggers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// a hook which holds up actions until released
+type blockingActionHook struct {
+	started chan bool
+	release chan bool
+}
+
+func (h *blockingActionHook) Before(ctx context.Context, run flows.Run, step flows.Step, action flows.Action) error {
+	if h.release != nil {
+		h.started <- true
+		<-h.release
+	}
+	return nil
+}
+
+func (h *blockingActionHook) After(ctx context.Context, run flows.Run, step flows.Step, action flows.Action, evts []flows.Event) {
+}
+
+func TestStop(t *testing.T) {
+	source, err := static.NewSource([]byte(`{
+		"flows": [
+			{
+				"uuid": "5472a1c3-63e1-484f-8485-cc8ecb16a058",
+				"name": "Main",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f01",
+						"actions": [
+							{"uuid": "b1f2b1c0-5b7e-4e7c-9f1d-000000000000", "type": "set_run_result", "name": "Started", "value": "yes"}
+						],
+						"router": {
+							"type": "switch",
+							"wait": {"type": "msg"},
+							"operand": "@input.text",
+							"categories": [
+								{"uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1", "name": "All", "exit_uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}
+							],
+							"default_category_uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1"
+						},
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}]
+					}
+				]
+			}
+		]
+	}`))
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	hook := &blockingActionHook{}
+	eng := engine.NewBuilder().RegisterActionHook(hook).WithProfile("acme", func(*engine.Builder) {}).Build()
+
+	newTrigger := func() flows.Trigger {
+		contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+		return triggers.NewBuilder(env, assets.NewFlowReference("5472a1c3-63e1-484f-8485-cc8ecb16a058", "Main"), contact).Manual().Build()
+	}
+	newMsg := func() *flows.MsgIn {
+		return flows.NewMsgIn(flows.MsgUUID(uuids.New()), "tel:+12065551212", nil, "Hi", nil)
+	}
+
+	waiting, _, err := eng.NewSession(context.Background(), sa, newTrigger())
+	require.NoError(t, err)
+	require.Equal(t, flows.SessionStatusWaiting, waiting.Status())
+
+	// start a session whose sprint will still be in flight when we stop the engine
+	hook.started, hook.release = make(chan bool), make(chan bool)
+	inFlight := make(chan flows.Session)
+	go func() {
+		session, _, _ := eng.NewSession(context.Background(), sa, newTrigger())
+		inFlight <- session
+	}()
+	<-hook.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	abandoned, err := eng.Stop(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	require.Len(t, abandoned, 1)
+
+	// new sprints are refused by the engine and its profiles
+	_, _, err = eng.NewSession(context.Background(), sa, newTrigger())
+	assert.EqualError(t, err, "engine has been stopped and isn't accepting new sprints")
+	assert.Equal(t, engine.ErrorEngineStopped, err.(*engine.Error).Code())
+
+	_, _, err = eng.Profile("acme").NewSession(context.Background(), sa, newTrigger())
+	assert.EqualError(t, err, "engine has been stopped and isn't accepting new sprints")
+
+	_, err = waiting.Resume(context.Background(), resumes.NewMsg(nil, nil, newMsg()))
+	assert.EqualError(t, err, "engine has been stopped and isn't accepting new sprints")
+	assert.Equal(t, flows.SessionStatusWaiting, waiting.Status())
+
+	// the abandoned sprint still finishes, and stopping again waits for it
+	hook.release <- true
+	assert.Equal(t, abandoned[0], (<-inFlight).UUID())
+
+	abandoned, err = eng.Stop(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, abandoned)
+
+	// an engine with in-flight sprints which finish in time
+	eng = engine.NewBuilder().RegisterActionHook(hook).Build()
+
+	go func() {
+		eng.NewSession(context.Background(), sa, newTrigger())
+	}()
+	<-hook.started
+
+	stopped := make(chan error)
+	go func() {
+		_, err := eng.Stop(context.Background())
+		stopped <- err
+	}()
+
+	hook.release <- true
+	assert.NoError(t, <-stopped)
+}
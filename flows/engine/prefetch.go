@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/actions"
+)
+
+// max number of nodes after a wait which are analyzed to find flows to prefetch
+const maxPrefetchNodes = 50
+
+// finds the flows which might be entered after the given waiting node is resumed, by following the exits of that node,
+// including its timeout, through the nodes that can be reached before the next wait. Flows are returned in the order
+// they're found, so the most likely come first.
+func prefetchFlows(flow flows.Flow, waitNode flows.Node) []*assets.FlowReference {
+	refs := make([]*assets.FlowReference, 0)
+	seenFlows := map[assets.FlowUUID]bool{flow.UUID(): true}
+	seenNodes := map[flows.NodeUUID]bool{waitNode.UUID(): true}
+	queue := []flows.Node{waitNode}
+
+	for len(queue) > 0 && len(seenNodes) <= maxPrefetchNodes {
+		node := queue[0]
+		queue = queue[1:]
+
+		// the actions of the waiting node have already been executed
+		if node != waitNode {
+			for _, action := range node.Actions() {
+				if enter, ok := action.(*actions.EnterFlowAction); ok && !seenFlows[enter.Flow.UUID] {
+					refs = append(refs, enter.Flow)
+					seenFlows[enter.Flow.UUID] = true
+				}
+			}
+
+			// once we reach another wait, that's as far as the next sprint can go
+			if node.Router() != nil && node.Router().Wait() != nil {
+				continue
+			}
+		}
+
+		for _, exit := range node.Exits() {
+			if dest := exit.DestinationUUID(); dest != "" && !seenNodes[dest] {
+				if next := flow.GetNode(dest); next != nil {
+					seenNodes[dest] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	return refs
+}
+
+// loads the given flows, which compiles their templates and resolves their router tests, so that they're cached in the
+// session assets when they're needed
+func prefetch(sa flows.SessionAssets, refs []*assets.FlowReference) {
+	for _, ref := range refs {
+		// a flow which can't be loaded isn't an error until it's actually entered
+		_, _ = sa.Flows().Get(ref.UUID)
+	}
+}
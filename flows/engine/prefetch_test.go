@@ -0,0 +1,78 @@
+package engine_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/triggers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// an asset source which records which flows are requested from it
+type flowRecordingSource struct {
+	assets.Source
+
+	requested []assets.FlowUUID
+}
+
+func (s *flowRecordingSource) FlowByUUID(uuid assets.FlowUUID) (assets.Flow, error) {
+	s.requested = append(s.requested, uuid)
+	return s.Source.FlowByUUID(uuid)
+}
+
+func TestPrefetch(t *testing.T) {
+	// the first node of the Main flow waits and can then go to a node which enters the Registration flow, or on timeout
+	// to a node which enters the Survey flow. The Feedback flow comes after another wait so won't be needed by the next
+	// sprint.
+	assetsJSON, err := os.ReadFile("testdata/prefetch.json")
+	require.NoError(t, err)
+
+	staticSource, err := static.NewSource(assetsJSON)
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+
+	startSession := func(eng flows.Engine) (flows.Session, []assets.FlowUUID) {
+		source := &flowRecordingSource{Source: staticSource}
+		sa, err := engine.NewSessionAssets(env, source, nil)
+		require.NoError(t, err)
+
+		contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+		trigger := triggers.NewBuilder(env, assets.NewFlowReference("5472a1c3-63e1-484f-8485-cc8ecb16a058", "Main"), contact).Manual().Build()
+
+		session, _, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+		require.Equal(t, flows.SessionStatusWaiting, session.Status())
+
+		return session, source.requested
+	}
+
+	// by default only the flow being started is loaded
+	_, requested := startSession(engine.NewBuilder().Build())
+	assert.Equal(t, []assets.FlowUUID{"5472a1c3-63e1-484f-8485-cc8ecb16a058"}, requested)
+
+	// when enabled, flows which might be entered before the next wait are loaded before the sprint ends
+	session, requested := startSession(engine.NewBuilder().WithPrefetch(true).Build())
+	assert.Equal(t, []assets.FlowUUID{
+		"5472a1c3-63e1-484f-8485-cc8ecb16a058",
+		"a1b2a1c3-63e1-484f-8485-cc8ecb16a058",
+		"b1b2a1c3-63e1-484f-8485-cc8ecb16a058",
+	}, requested)
+
+	// and so are already in the session assets when the session is resumed
+	flow, err := session.Assets().Flows().Get("a1b2a1c3-63e1-484f-8485-cc8ecb16a058")
+	require.NoError(t, err)
+	assert.Equal(t, "Registration", flow.Name())
+
+	// without anything being added to the session itself
+	assert.NotContains(t, string(jsonx.MustMarshal(session)), `"prefetch"`)
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+)
+
+// a named profile of an engine, which is configured by applying its configure function to a copy of the engine
+type profileConfig struct {
+	name      string
+	configure func(*Builder)
+}
+
+// creates a copy of this engine which can be configured independently of it
+func (e *engine) clone() *engine {
+	c := *e
+	c.services = e.services.clone()
+	c.serviceTimeouts = make(map[flows.ServiceType]time.Duration, len(e.serviceTimeouts))
+	for k, v := range e.serviceTimeouts {
+		c.serviceTimeouts[k] = v
+	}
+	c.actionHooks = append([]flows.ActionHook(nil), e.actionHooks...)
+	return &c
+}
+
+func (s *services) clone() *services {
+	c := *s
+	c.webhookRoutes = append([]*webhookRoute(nil), s.webhookRoutes...)
+	c.configured = make(map[flows.ServiceType]bool, len(s.configured))
+	for k, v := range s.configured {
+		c.configured[k] = v
+	}
+	return &c
+}
+
+// applies the overrides of the given engine's profile to a session environment
+func profileEnvironment(eng flows.Engine, env envs.Environment) envs.Environment {
+	if policy := eng.RedactionPolicy(); env != nil && policy != "" && policy != env.RedactionPolicy() {
+		return &redactionOverride{Environment: env, policy: policy}
+	}
+	return env
+}
+
+// an environment whose redaction policy is overridden by an engine profile. Only the original environment is
+// marshaled, so the override is applied again by whichever profile reads the session.
+type redactionOverride struct {
+	envs.Environment
+
+	policy envs.RedactionPolicy
+}
+
+func (e *redactionOverride) RedactionPolicy() envs.RedactionPolicy { return e.policy }
+
+func (e *redactionOverride) MarshalJSON() ([]byte, error) { return jsonx.Marshal(e.Environment) }
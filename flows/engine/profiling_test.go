@@ -0,0 +1,248 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/flows/triggers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiling(t *testing.T) {
+	source, err := static.NewSource([]byte(`{
+		"flows": [
+			{
+				"uuid": "5472a1c3-63e1-484f-8485-cc8ecb16a058",
+				"name": "Main",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f01",
+						"actions": [
+							{"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a", "type": "send_msg", "text": "Hi @contact.name"}
+						],
+						"router": {
+							"type": "switch",
+							"wait": {"type": "msg"},
+							"operand": "@input.text",
+							"categories": [
+								{"uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1", "name": "All", "exit_uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}
+							],
+							"default_category_uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1"
+						},
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}]
+					}
+				]
+			}
+		]
+	}`))
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	startSession := func(eng flows.Engine) (flows.Session, flows.Sprint) {
+		contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+		trigger := triggers.NewBuilder(env, assets.NewFlowReference("5472a1c3-63e1-484f-8485-cc8ecb16a058", "Main"), contact).Manual().Build()
+
+		session, sprint, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+		return session, sprint
+	}
+
+	// by default sprints aren't profiled
+	_, sprint := startSession(engine.NewBuilder().Build())
+	for _, e := range sprint.Events() {
+		assert.NotEqual(t, events.TypeSprintProfile, e.Type())
+	}
+
+	// when enabled, the last event of each sprint is its profile
+	session, sprint := startSession(engine.NewBuilder().WithProfiling(true).Build())
+	last := sprint.Events()[len(sprint.Events())-1]
+	require.Equal(t, events.TypeSprintProfile, last.Type())
+
+	profile := last.(*events.SprintProfileEvent)
+	require.Len(t, profile.Actions, 1)
+	assert.Equal(t, "send_msg[uuid=0a8467eb-911a-41db-8101-ccf415c48e6a]", profile.Actions[0].Name)
+	assert.Equal(t, 1, profile.Actions[0].Count)
+	require.Len(t, profile.Templates, 1)
+	assert.Equal(t, "Hi @contact.name", profile.Templates[0].Name)
+	assert.Len(t, profile.Routers, 0) // the node is waiting so hasn't routed yet
+	assert.Len(t, profile.Services, 0)
+
+	// resuming the session times the router
+	msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), urns.NilURN, nil, "Hello", nil)
+	sprint, err = session.Resume(context.Background(), resumes.NewMsg(env, nil, msg))
+	require.NoError(t, err)
+
+	last = sprint.Events()[len(sprint.Events())-1]
+	require.Equal(t, events.TypeSprintProfile, last.Type())
+
+	profile = last.(*events.SprintProfileEvent)
+	require.Len(t, profile.Routers, 1)
+	assert.Equal(t, "switch[node=8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f01]", profile.Routers[0].Name)
+	assert.Len(t, profile.Actions, 0)
+}
+
+func TestEventTimings(t *testing.T) {
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	dates.SetNowSource(dates.NewFixedNowSource(time.Date(2018, 10, 18, 14, 20, 30, 123456, time.UTC)))
+
+	source, err := static.NewSource([]byte(`{
+		"flows": [
+			{
+				"uuid": "5472a1c3-63e1-484f-8485-cc8ecb16a058",
+				"name": "Main",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f01",
+						"actions": [
+							{"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a", "type": "send_msg", "text": "Hi @contact.name"},
+							{"uuid": "3a8c4b6e-2f1d-4c5a-9b7e-8d6f4a2c1e09", "type": "set_contact_name", "name": "Bobby"}
+						],
+						"router": {
+							"type": "switch",
+							"wait": {"type": "msg"},
+							"operand": "@input.text",
+							"categories": [
+								{"uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1", "name": "All", "exit_uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}
+							],
+							"default_category_uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1"
+						},
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}]
+					}
+				]
+			}
+		]
+	}`))
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	startSession := func(eng flows.Engine) flows.Sprint {
+		contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+		trigger := triggers.NewBuilder(env, assets.NewFlowReference("5472a1c3-63e1-484f-8485-cc8ecb16a058", "Main"), contact).Manual().Build()
+
+		_, sprint, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+		return sprint
+	}
+
+	// by default events aren't timed
+	sprint := startSession(engine.NewBuilder().Build())
+	for _, e := range sprint.Events() {
+		assert.Nil(t, e.Timing())
+	}
+
+	// when enabled, events generated by actions have the timing of their action
+	sprint = startSession(engine.NewBuilder().WithEventTimings(true).Build())
+	require.Len(t, sprint.Events(), 3)
+
+	assert.Equal(t, events.TypeMsgCreated, sprint.Events()[0].Type())
+	assert.Equal(t, &flows.EventTiming{StartedOn: time.Date(2018, 10, 18, 14, 20, 30, 123456, time.UTC), ElapsedMS: 0}, sprint.Events()[0].Timing())
+	assert.Equal(t, events.TypeContactNameChanged, sprint.Events()[1].Type())
+	assert.NotNil(t, sprint.Events()[1].Timing())
+
+	// but events generated by routers and waits aren't
+	assert.Equal(t, events.TypeMsgWait, sprint.Events()[2].Type())
+	assert.Nil(t, sprint.Events()[2].Timing())
+
+	// timing is included when events are marshaled
+	eventJSON := jsonx.MustMarshal(sprint.Events()[0])
+	assert.Contains(t, string(eventJSON), `"timing":{"started_on":"2018-10-18T14:20:30.000123456Z","elapsed_ms":0}`)
+}
+
+func TestDebugging(t *testing.T) {
+	source, err := static.NewSource([]byte(`{
+		"flows": [
+			{
+				"uuid": "5472a1c3-63e1-484f-8485-cc8ecb16a058",
+				"name": "Main",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f01",
+						"actions": [
+							{"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a", "type": "send_msg", "text": "Hi @contact.name, you owe @(1 / 0)"}
+						],
+						"router": {
+							"type": "switch",
+							"wait": {"type": "msg"},
+							"operand": "@input.text",
+							"categories": [
+								{"uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1", "name": "All", "exit_uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}
+							],
+							"default_category_uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1"
+						},
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}]
+					}
+				]
+			}
+		]
+	}`))
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	startSession := func(eng flows.Engine) (flows.Session, flows.Sprint) {
+		contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+		trigger := triggers.NewBuilder(env, assets.NewFlowReference("5472a1c3-63e1-484f-8485-cc8ecb16a058", "Main"), contact).Manual().Build()
+
+		session, sprint, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+		return session, sprint
+	}
+
+	// by default sprints don't have a debug log
+	_, sprint := startSession(engine.NewBuilder().Build())
+	assert.Nil(t, sprint.DebugLog())
+	assert.Nil(t, sprint.DebugLog().Traces())
+
+	// when enabled, every evaluated template is recorded with where it was evaluated
+	session, sprint := startSession(engine.NewBuilder().WithDebugging(true).Build())
+	traces := sprint.DebugLog().Traces()
+	require.Len(t, traces, 1)
+	assert.Equal(t, session.Runs()[0].UUID(), traces[0].RunUUID)
+	assert.Equal(t, assets.FlowUUID("5472a1c3-63e1-484f-8485-cc8ecb16a058"), traces[0].FlowUUID)
+	assert.Equal(t, flows.NodeUUID("8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f01"), traces[0].NodeUUID)
+	assert.Equal(t, "Hi @contact.name, you owe @(1 / 0)", traces[0].Template)
+	assert.Equal(t, "Hi Bob, you owe ", traces[0].Value.Render())
+	assert.Equal(t, "error evaluating @(1 / 0): division by zero", traces[0].Error)
+
+	// the session only has the debug log of the current sprint
+	assert.Nil(t, session.DebugLog())
+
+	// each sprint gets its own debug log
+	msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), urns.NilURN, nil, "Hello", nil)
+	sprint, err = session.Resume(context.Background(), resumes.NewMsg(env, nil, msg))
+	require.NoError(t, err)
+
+	traceJSON := jsonx.MustMarshal(sprint.DebugLog().Traces())
+	assert.Contains(t, string(traceJSON), `"template":"@input.text","value":"Hello"`)
+}
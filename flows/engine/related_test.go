@@ -0,0 +1,68 @@
+package engine_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/triggers"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// a contact provider which is always unavailable
+type failingContactProvider struct{}
+
+func (p *failingContactProvider) GetContacts(context.Context, flows.SessionAssets, []flows.ContactUUID) ([]*flows.Contact, error) {
+	return nil, errors.New("contact store unavailable")
+}
+
+func TestRelatedContacts(t *testing.T) {
+	assetsJSON, err := os.ReadFile("testdata/related_contacts.json")
+	require.NoError(t, err)
+
+	sa, err := test.CreateSessionAssets(assetsJSON, "")
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	contact, err := flows.ReadContact(sa, []byte(`{
+		"uuid": "5d76d86b-3bb9-4d5a-b822-c9d86f5d8e4f",
+		"name": "Bob",
+		"status": "active",
+		"created_on": "2018-06-20T11:40:30.123456789-00:00",
+		"relations": {"caregiver": {"uuid": "0cb17b2a-3bfe-4a19-8c99-98ab9561045d", "name": "Sarah Haggerty"}}
+	}`), assets.PanicOnMissing)
+	require.NoError(t, err)
+
+	trigger := triggers.NewBuilder(env, assets.NewFlowReference("4f1b2c3d-5e6f-4a7b-8c9d-0e1f2a3b4c5d", "Caregiver Check"), contact).Manual().Build()
+
+	startSession := func(eng flows.Engine) flows.Sprint {
+		_, sprint, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+		return sprint
+	}
+
+	// related contacts are loaded from the contact provider at the start of the sprint
+	sprint := startSession(engine.NewBuilder().WithContactProvider(test.NewContactProvider(test.ReferencedContacts...)).Build())
+	assert.Equal(t, []string{"msg_created"}, eventTypes(sprint.Events()))
+	assert.Equal(t, "Is Sarah (0cb17b2a-3bfe-4a19-8c99-98ab9561045d) still your caregiver?", sprint.Events()[0].(*events.MsgCreatedEvent).Msg.Text())
+
+	// without a contact provider they can't be referenced
+	sprint = startSession(engine.NewBuilder().Build())
+	assert.Equal(t, []string{"error", "msg_created"}, eventTypes(sprint.Events()))
+	assert.Equal(t, "Is  () still your caregiver?", sprint.Events()[1].(*events.MsgCreatedEvent).Msg.Text())
+
+	// and if the contact provider fails, that's logged as an error but the sprint continues
+	sprint = startSession(engine.NewBuilder().WithContactProvider(&failingContactProvider{}).Build())
+	assert.Equal(t, []string{"error", "error", "msg_created"}, eventTypes(sprint.Events()))
+	assert.Equal(t, "error loading related contacts: contact store unavailable", sprint.Events()[0].(*events.ErrorEvent).Text)
+	assert.Equal(t, "Is  () still your caregiver?", sprint.Events()[2].(*events.MsgCreatedEvent).Msg.Text())
+}
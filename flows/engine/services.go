@@ -1,6 +1,10 @@
 package engine
 
 import (
+	"net/http"
+	"regexp"
+	"strings"
+
 	"github.com/nyaruka/goflow/flows"
 
 	"github.com/pkg/errors"
@@ -21,12 +25,52 @@ type TicketServiceFactory func(*flows.Ticketer) (flows.TicketService, error)
 // AirtimeServiceFactory resolves a session to an airtime service
 type AirtimeServiceFactory func(flows.SessionAssets) (flows.AirtimeService, error)
 
+// DataCollectionServiceFactory resolves a session to a data collection service
+type DataCollectionServiceFactory func(flows.SessionAssets) (flows.DataCollectionService, error)
+
+// CommerceServiceFactory resolves a session to a commerce service
+type CommerceServiceFactory func(flows.SessionAssets) (flows.CommerceService, error)
+
+// CallRecordingServiceFactory resolves a session to a call recording service
+type CallRecordingServiceFactory func(flows.SessionAssets) (flows.CallRecordingService, error)
+
+// CallTransferServiceFactory resolves a session to a call transfer service
+type CallTransferServiceFactory func(flows.SessionAssets) (flows.CallTransferService, error)
+
+// CredentialServiceFactory resolves a session to a credential service
+type CredentialServiceFactory func(flows.SessionAssets) (flows.CredentialService, error)
+
+// ExchangeRateServiceFactory resolves a session to an exchange rate service
+type ExchangeRateServiceFactory func(flows.SessionAssets) (flows.ExchangeRateService, error)
+
+// AttachmentServiceFactory resolves a session to an attachment service
+type AttachmentServiceFactory func(flows.SessionAssets) (flows.AttachmentService, error)
+
+// GroupMembershipServiceFactory resolves a session to a group membership service, which may be nil to disable the
+// re-evaluation of query based groups
+type GroupMembershipServiceFactory func(flows.SessionAssets) (flows.GroupMembershipService, error)
+
+// HTTPLogSinkFactory resolves a session to an HTTP log sink
+type HTTPLogSinkFactory func(flows.SessionAssets) (flows.HTTPLogSink, error)
+
 type services struct {
-	email          EmailServiceFactory
-	webhook        WebhookServiceFactory
-	classification ClassificationServiceFactory
-	ticket         TicketServiceFactory
-	airtime        AirtimeServiceFactory
+	email           EmailServiceFactory
+	webhook         WebhookServiceFactory
+	classification  ClassificationServiceFactory
+	ticket          TicketServiceFactory
+	airtime         AirtimeServiceFactory
+	dataCollection  DataCollectionServiceFactory
+	commerce        CommerceServiceFactory
+	callRecording   CallRecordingServiceFactory
+	callTransfer    CallTransferServiceFactory
+	credential      CredentialServiceFactory
+	exchangeRate    ExchangeRateServiceFactory
+	attachment      AttachmentServiceFactory
+	groupMembership GroupMembershipServiceFactory
+	httpLogSink     HTTPLogSinkFactory
+	webhookRoutes   []*webhookRoute
+
+	configured map[flows.ServiceType]bool
 }
 
 func newEmptyServices() *services {
@@ -46,6 +90,34 @@ func newEmptyServices() *services {
 		airtime: func(flows.SessionAssets) (flows.AirtimeService, error) {
 			return nil, errors.New("no airtime service factory configured")
 		},
+		dataCollection: func(flows.SessionAssets) (flows.DataCollectionService, error) {
+			return nil, errors.New("no data collection service factory configured")
+		},
+		commerce: func(flows.SessionAssets) (flows.CommerceService, error) {
+			return nil, errors.New("no commerce service factory configured")
+		},
+		callRecording: func(flows.SessionAssets) (flows.CallRecordingService, error) {
+			return nil, errors.New("no call recording service factory configured")
+		},
+		callTransfer: func(flows.SessionAssets) (flows.CallTransferService, error) {
+			return nil, errors.New("no call transfer service factory configured")
+		},
+		credential: func(flows.SessionAssets) (flows.CredentialService, error) {
+			return nil, errors.New("no credential service factory configured")
+		},
+		exchangeRate: func(flows.SessionAssets) (flows.ExchangeRateService, error) {
+			return nil, errors.New("no exchange rate service factory configured")
+		},
+		attachment: func(flows.SessionAssets) (flows.AttachmentService, error) {
+			return nil, errors.New("no attachment service factory configured")
+		},
+		groupMembership: func(flows.SessionAssets) (flows.GroupMembershipService, error) {
+			return flows.NewQueryGroupMembership(), nil
+		},
+		httpLogSink: func(flows.SessionAssets) (flows.HTTPLogSink, error) {
+			return nil, errors.New("no HTTP log sink factory configured")
+		},
+		configured: make(map[flows.ServiceType]bool),
 	}
 }
 
@@ -54,7 +126,10 @@ func (s *services) Email(sa flows.SessionAssets) (flows.EmailService, error) {
 }
 
 func (s *services) Webhook(sa flows.SessionAssets) (flows.WebhookService, error) {
-	return s.webhook(sa)
+	if len(s.webhookRoutes) == 0 {
+		return s.webhook(sa)
+	}
+	return &routedWebhookService{sa: sa, routes: s.webhookRoutes, fallback: s.webhook}, nil
 }
 
 func (s *services) Classification(classifier *flows.Classifier) (flows.ClassificationService, error) {
@@ -68,3 +143,75 @@ func (s *services) Ticket(ticketer *flows.Ticketer) (flows.TicketService, error)
 func (s *services) Airtime(sa flows.SessionAssets) (flows.AirtimeService, error) {
 	return s.airtime(sa)
 }
+
+func (s *services) DataCollection(sa flows.SessionAssets) (flows.DataCollectionService, error) {
+	return s.dataCollection(sa)
+}
+
+func (s *services) Commerce(sa flows.SessionAssets) (flows.CommerceService, error) {
+	return s.commerce(sa)
+}
+
+func (s *services) CallRecording(sa flows.SessionAssets) (flows.CallRecordingService, error) {
+	return s.callRecording(sa)
+}
+
+func (s *services) CallTransfer(sa flows.SessionAssets) (flows.CallTransferService, error) {
+	return s.callTransfer(sa)
+}
+
+func (s *services) Credential(sa flows.SessionAssets) (flows.CredentialService, error) {
+	return s.credential(sa)
+}
+
+func (s *services) ExchangeRate(sa flows.SessionAssets) (flows.ExchangeRateService, error) {
+	return s.exchangeRate(sa)
+}
+
+func (s *services) Attachment(sa flows.SessionAssets) (flows.AttachmentService, error) {
+	return s.attachment(sa)
+}
+
+func (s *services) GroupMembership(sa flows.SessionAssets) (flows.GroupMembershipService, error) {
+	return s.groupMembership(sa)
+}
+
+func (s *services) HTTPLogSink(sa flows.SessionAssets) (flows.HTTPLogSink, error) {
+	return s.httpLogSink(sa)
+}
+
+// a route which sends webhook calls whose URLs match its pattern to the service of its factory
+type webhookRoute struct {
+	pattern *regexp.Regexp
+	factory WebhookServiceFactory
+}
+
+// creates a route from a URL pattern in which * matches any sequence of characters
+func newWebhookRoute(pattern string, factory WebhookServiceFactory) *webhookRoute {
+	expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `.*`)
+	return &webhookRoute{pattern: regexp.MustCompile(`^` + expr + `$`), factory: factory}
+}
+
+// a webhook service which resolves the service for each call from the first route matching its URL, falling back to
+// the default webhook service factory
+type routedWebhookService struct {
+	sa       flows.SessionAssets
+	routes   []*webhookRoute
+	fallback WebhookServiceFactory
+}
+
+func (s *routedWebhookService) Call(request *http.Request) (*flows.WebhookCall, error) {
+	factory := s.fallback
+	for _, route := range s.routes {
+		if route.pattern.MatchString(request.URL.String()) {
+			factory = route.factory
+			break
+		}
+	}
+
+	svc, err := factory(s.sa)
+	if err != nil {
+		return nil, err
+	}
+	return svc.Call(request)
+}
@@ -1,10 +1,15 @@
 package engine_test
 
 import (
+	"net/http"
 	"testing"
 
+	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/engine"
+
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEmptyServices(t *testing.T) {
@@ -22,4 +27,58 @@ func TestEmptyServices(t *testing.T) {
 	airtimeSvc, err := eng.Services().Airtime(nil)
 	assert.EqualError(t, err, "no airtime service factory configured")
 	assert.Nil(t, airtimeSvc)
+
+	httpLogSink, err := eng.Services().HTTPLogSink(nil)
+	assert.EqualError(t, err, "no HTTP log sink factory configured")
+	assert.Nil(t, httpLogSink)
+}
+
+// a webhook service which records the URLs it's called with
+type recordingWebhookService struct {
+	urls []string
+}
+
+func (s *recordingWebhookService) Call(request *http.Request) (*flows.WebhookCall, error) {
+	s.urls = append(s.urls, request.URL.String())
+	return &flows.WebhookCall{}, nil
+}
+
+func TestWebhookRoutes(t *testing.T) {
+	mesh := &recordingWebhookService{}
+	external := &recordingWebhookService{}
+	factoryFor := func(svc flows.WebhookService) engine.WebhookServiceFactory {
+		return func(flows.SessionAssets) (flows.WebhookService, error) { return svc, nil }
+	}
+
+	eng := engine.NewBuilder().
+		WithWebhookServiceFactory(factoryFor(external)).
+		WithWebhookRoute("http://*.svc.cluster.local/*", factoryFor(mesh)).
+		WithWebhookRoute("https://billing.internal/*", func(flows.SessionAssets) (flows.WebhookService, error) {
+			return nil, errors.New("billing is unavailable")
+		}).
+		Build()
+
+	call := func(url string) error {
+		svc, err := eng.Services().Webhook(nil)
+		require.NoError(t, err)
+
+		request, _ := http.NewRequest("GET", url, nil)
+		_, err = svc.Call(request)
+		return err
+	}
+
+	assert.NoError(t, call("http://orders.svc.cluster.local/api/v1/orders"))
+	assert.NoError(t, call("https://example.com/hook"))
+	assert.NoError(t, call("http://svc.cluster.local.example.com/"))
+	assert.EqualError(t, call("https://billing.internal/invoices"), "billing is unavailable")
+
+	assert.Equal(t, []string{"http://orders.svc.cluster.local/api/v1/orders"}, mesh.urls)
+	assert.Equal(t, []string{"https://example.com/hook", "http://svc.cluster.local.example.com/"}, external.urls)
+
+	// routes alone make webhooks available, with calls that match no route failing as there's no default service
+	eng = engine.NewBuilder().WithWebhookRoute("http://*.svc.cluster.local/*", factoryFor(mesh)).Build()
+	assert.True(t, eng.Capabilities().HasService(flows.ServiceTypeWebhook))
+
+	assert.NoError(t, call("http://users.svc.cluster.local/me"))
+	assert.EqualError(t, call("https://example.com/hook"), "no webhook service factory configured")
 }
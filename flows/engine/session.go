@@ -1,13 +1,17 @@
 package engine
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/uuids"
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent"
 	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
@@ -20,6 +24,9 @@ import (
 	"github.com/pkg/errors"
 )
 
+// max number of timings in each category of a sprint profile
+const maxProfileTimings = 10
+
 // used to spawn a new run or sub-flow in the event loop
 type pushedFlow struct {
 	flow      flows.Flow
@@ -28,7 +35,9 @@ type pushedFlow struct {
 }
 
 type session struct {
-	assets flows.SessionAssets
+	assets    flows.SessionAssets
+	lifecycle *lifecycle
+	shadowing *shadowing
 
 	// state which is maintained between engine calls
 	uuid          flows.SessionUUID
@@ -38,14 +47,26 @@ type session struct {
 	currentResume flows.Resume
 	contact       *flows.Contact
 	runs          []flows.Run
+	archivedRuns  []*flows.ArchivedRun
 	status        flows.SessionStatus
 	input         flows.Input
+	cart          *flows.Order
 
 	// state which is temporary to each call
-	batchStart bool
-	runsByUUID map[flows.RunUUID]flows.Run
-	pushedFlow *pushedFlow
-	parentRun  flows.RunSummary
+	ctx            context.Context
+	sprint         *sprint
+	batchStart     bool
+	runsByUUID     map[flows.RunUUID]flows.Run
+	loadedRuns     map[flows.RunUUID]flows.Run // archived runs which have been loaded since the session was read
+	pushedFlow     *pushedFlow
+	parentRun      flows.RunSummary
+	arena          *excellent.Arena
+	templateCache  *flows.TemplateCache
+	profiler       *flows.Profiler
+	debugLog       *flows.DebugLog
+	classification *flows.Classification                // the last classification of the current sprint
+	related        map[flows.ContactUUID]*flows.Contact // the related contacts loaded at the start of the last sprint
+	sources        flows.Sources                        // where the current sprint gets UUIDs, random numbers and time from
 
 	engine flows.Engine
 }
@@ -56,18 +77,28 @@ func (s *session) CurrentResume() flows.Resume { return s.currentResume }
 
 func (s *session) UUID() flows.SessionUUID { return s.uuid }
 
-func (s *session) Type() flows.FlowType         { return s.type_ }
-func (s *session) SetType(type_ flows.FlowType) { s.type_ = type_ }
+func (s *session) Type() flows.FlowType { return s.type_ }
+func (s *session) SetType(type_ flows.FlowType) {
+	s.type_ = type_
+	s.templateCache.Invalidate()
+}
 
-func (s *session) Environment() envs.Environment       { return s.env }
-func (s *session) SetEnvironment(env envs.Environment) { s.env = env }
+func (s *session) Environment() envs.Environment { return s.env }
+func (s *session) SetEnvironment(env envs.Environment) {
+	s.env = profileEnvironment(s.engine, env)
+	s.templateCache.Invalidate()
+}
 
-func (s *session) Contact() *flows.Contact           { return s.contact }
-func (s *session) SetContact(contact *flows.Contact) { s.contact = contact }
+func (s *session) Contact() *flows.Contact { return s.contact }
+func (s *session) SetContact(contact *flows.Contact) {
+	s.contact = contact
+	s.templateCache.Invalidate()
+}
 
 func (s *session) Input() flows.Input { return s.input }
 func (s *session) SetInput(input flows.Input) {
 	s.input = input
+	s.templateCache.Invalidate()
 
 	// if we have a contact, update their last seen date
 	if input != nil && s.contact != nil {
@@ -75,6 +106,44 @@ func (s *session) SetInput(input flows.Input) {
 	}
 }
 
+func (s *session) Cart() *flows.Order { return s.cart }
+func (s *session) SetCart(cart *flows.Order) {
+	s.cart = cart
+	s.templateCache.Invalidate()
+}
+
+func (s *session) Classification() *flows.Classification { return s.classification }
+func (s *session) SetClassification(classification *flows.Classification) {
+	s.classification = classification
+	s.templateCache.Invalidate()
+}
+
+// RelatedContact returns the related contact with the given UUID if it was loaded at the start of the last sprint
+func (s *session) RelatedContact(uuid flows.ContactUUID) *flows.Contact { return s.related[uuid] }
+
+// SprintContext returns the context of the current sprint, e.g. for service calls made by expression functions
+func (s *session) SprintContext() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// LogSprintEvent logs an event which didn't come from an action or router, e.g. from an expression function, on the
+// given run at its current step and in the current sprint
+func (s *session) LogSprintEvent(run flows.Run, event flows.Event) {
+	var step flows.Step
+	if path := run.Path(); len(path) > 0 {
+		step = path[len(path)-1]
+	}
+
+	run.LogEvent(step, event)
+
+	if s.sprint != nil {
+		s.sprint.logEvent(event)
+	}
+}
+
 func (s *session) BatchStart() bool { return s.batchStart }
 
 func (s *session) PushFlow(flow flows.Flow, parentRun flows.Run, terminal bool) {
@@ -82,14 +151,50 @@ func (s *session) PushFlow(flow flows.Flow, parentRun flows.Run, terminal bool)
 }
 
 func (s *session) Runs() []flows.Run { return s.runs }
+
+// ArchivedRuns returns what the session keeps of the runs which have been moved to the engine's run archive
+func (s *session) ArchivedRuns() []*flows.ArchivedRun { return s.archivedRuns }
+
 func (s *session) GetRun(uuid flows.RunUUID) (flows.Run, error) {
 	run, exists := s.runsByUUID[uuid]
 	if exists {
 		return run, nil
 	}
+	if run, loaded := s.loadedRuns[uuid]; loaded {
+		return run, nil
+	}
+	for _, a := range s.archivedRuns {
+		if a.UUID == uuid {
+			return s.loadArchivedRun(uuid)
+		}
+	}
 	return nil, errors.Errorf("unable to find run with UUID '%s'", uuid)
 }
 
+// loads a run from the engine's run archive, keeping it so it's only loaded once
+func (s *session) loadArchivedRun(uuid flows.RunUUID) (flows.Run, error) {
+	archive := s.engine.RunArchive()
+	if archive == nil {
+		return nil, errors.Errorf("unable to load archived run with UUID '%s' without a run archive", uuid)
+	}
+
+	data, err := archive.LoadRun(s.uuid, uuid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to load archived run with UUID '%s'", uuid)
+	}
+
+	run, err := runs.ReadRun(s, data, assets.IgnoreMissing)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read archived run with UUID '%s'", uuid)
+	}
+
+	if s.loadedRuns == nil {
+		s.loadedRuns = make(map[flows.RunUUID]flows.Run)
+	}
+	s.loadedRuns[uuid] = run
+	return run, nil
+}
+
 func (s *session) FindStep(uuid flows.StepUUID) (flows.Run, flows.Step) {
 	for _, r := range s.runs {
 		for _, t := range r.Path() {
@@ -113,6 +218,17 @@ func (s *session) GetCurrentChild(run flows.Run) flows.Run {
 			return s.runs[i]
 		}
 	}
+
+	// runs are archived oldest first so any archived children are older than resident ones
+	for i := len(s.archivedRuns) - 1; i >= 0; i-- {
+		if s.archivedRuns[i].ParentUUID == run.UUID() {
+			child, err := s.GetRun(s.archivedRuns[i].UUID)
+			if err != nil {
+				return nil
+			}
+			return child
+		}
+	}
 	return nil
 }
 
@@ -131,6 +247,21 @@ func (s *session) CurrentContext() *types.XObject {
 	return types.NewXObject(run.RootContext(s.env))
 }
 
+// Arena returns the arena of the current sprint, or nil if sprints don't use arenas
+func (s *session) Arena() *excellent.Arena { return s.arena }
+
+// TemplateCache returns the template cache of the current sprint, or nil if template caching is disabled
+func (s *session) TemplateCache() *flows.TemplateCache { return s.templateCache }
+
+// Profiler returns the profiler of the current sprint, or nil if profiling is disabled
+func (s *session) Profiler() *flows.Profiler { return s.profiler }
+
+// DebugLog returns the debug log of the current sprint, or nil if debugging is disabled
+func (s *session) DebugLog() *flows.DebugLog { return s.debugLog }
+
+// Sources returns where this session gets new UUIDs, random numbers and the current time from
+func (s *session) Sources() flows.Sources { return s.sources }
+
 // looks through this session's run for the one that was last modified
 func (s *session) currentRun() flows.Run {
 	var lastRun flows.Run
@@ -166,9 +297,51 @@ func (s *session) Engine() flows.Engine { return s.engine }
 // Flow execution
 //------------------------------------------------------------------------------------------
 
-// Start initializes this session with the given trigger and runs the flow to the first wait
-func (s *session) start(trigger flows.Trigger) (flows.Sprint, error) {
+// creates a new sprint for this session which passes its events to the engine's event sink if there is one
+func (s *session) newSprint(ctx context.Context) *sprint {
 	sprint := newEmptySprint()
+	sprint.thread = s.trigger.Thread()
+	sprint.sources = s.sources
+	sprint.stampEvents = s.engine.Simulation() != nil
+
+	s.ctx = ctx
+	s.sprint = sprint
+
+	if sink := s.engine.EventSink(); sink != nil {
+		sprint.sink = func(e flows.Event) { sink.Receive(ctx, s, e) }
+	}
+	if s.engine.SprintArenas() {
+		s.arena = excellent.NewArena()
+	}
+	if size := s.engine.TemplateCacheSize(); size > 0 {
+		s.templateCache = flows.NewTemplateCache(size)
+	}
+	if s.engine.Profiling() {
+		s.profiler = flows.NewProfiler()
+	}
+	if s.engine.Debugging() {
+		s.debugLog = flows.NewDebugLog()
+		sprint.debugLog = s.debugLog
+	}
+	return sprint
+}
+
+// releases the arena, template cache and other state of the sprint which has just finished
+func (s *session) releaseSprintState() {
+	s.ctx = nil
+	s.sprint = nil
+	s.arena.Release()
+	s.arena = nil
+	s.templateCache = nil
+	s.profiler = nil
+	s.debugLog = nil
+	s.classification = nil
+}
+
+// Start initializes this session with the given trigger and runs the flow to the first wait
+func (s *session) start(ctx context.Context, trigger flows.Trigger) (flows.Sprint, error) {
+	sprint := s.newSprint(ctx)
+	defer s.releaseSprintState()
 
 	if err := s.prepareForSprint(); err != nil {
 		return sprint, err
@@ -178,20 +351,65 @@ func (s *session) start(trigger flows.Trigger) (flows.Sprint, error) {
 		return sprint, err
 	}
 
+	savepoint := s.contactSavepoint()
+	snapshot := s.snapshot(true)
+
 	// ensure groups are correct
 	s.ensureQueryBasedGroups(sprint.logEvent)
 
+	s.loadRelatedContacts(ctx, sprint)
+
 	// off to the races...
-	if err := s.continueUntilWait(sprint, nil, nil, nil, "", nil, trigger); err != nil {
-		return sprint, err
+	err := s.continueUntilWait(ctx, sprint, nil, nil, nil, "", "", nil, trigger)
+	if err == nil {
+		s.callExitWebhooks(ctx, sprint, nil)
+	}
+	s.endSprint(sprint, savepoint, err)
+	if snapshot != nil {
+		sprint.diff = snapshot.Diff(s)
 	}
+	s.logProfile(sprint)
+	if err == nil {
+		s.archiveRuns(sprint)
+	}
+
+	return sprint, err
+}
 
-	return sprint, nil
+// takes a snapshot of this session at the start of a sprint if the engine records the changes sprints make
+func (s *session) snapshot(isNew bool) *flows.SessionSnapshot {
+	if !s.engine.SessionDiffs() {
+		return nil
+	}
+	return flows.NewSessionSnapshot(s, isNew)
 }
 
 // Resume tries to resume a waiting session
-func (s *session) Resume(resume flows.Resume) (flows.Sprint, error) {
-	sprint := newEmptySprint()
+func (s *session) Resume(ctx context.Context, resume flows.Resume) (flows.Sprint, error) {
+	s.sources = sprintSources(s.engine, s.countWaits())
+	ctx = flows.ContextWithSources(ctx, s.sources)
+
+	end, err := s.lifecycle.begin(s.uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	// a shadowed resume is repeated on a copy of the session as it was before this sprint, once this sprint is over
+	if s.shadowing != nil {
+		var recording *serviceRecording
+		if ctx, recording = s.shadowing.sample(ctx, s.assets, s.flowUUIDs()...); recording != nil {
+			data, err := jsonx.Marshal(s)
+			var resumeData []byte
+			if err == nil {
+				resumeData, err = jsonx.Marshal(resume)
+			}
+			defer s.shadowing.resume(s.assets, s.trigger, data, resumeData, err, recording)
+		}
+	}
+
+	sprint := s.newSprint(ctx)
+	defer s.releaseSprintState()
 
 	if err := s.prepareForSprint(); err != nil {
 		return sprint, err
@@ -206,11 +424,135 @@ func (s *session) Resume(resume flows.Resume) (flows.Sprint, error) {
 		return sprint, newError(ErrorResumeNoWaitingRun, "session doesn't contain any runs which are waiting")
 	}
 
-	if err := s.tryToResume(sprint, waitingRun, resume); err != nil {
+	if resume.Thread() != s.trigger.Thread() {
+		return sprint, newError(ErrorResumeThreadMismatch, "resume for thread '%s' can't resume session of thread '%s'", resume.Thread(), s.trigger.Thread())
+	}
+
+	savepoint := s.contactSavepoint()
+	snapshot := s.snapshot(false)
+
+	exited := s.exitedRuns()
+
+	s.loadRelatedContacts(ctx, sprint)
+
+	err = s.tryToResume(ctx, sprint, waitingRun, resume)
+	if err == nil {
+		s.callExitWebhooks(ctx, sprint, exited)
+	}
+	s.endSprint(sprint, savepoint, err)
+	if snapshot != nil {
+		sprint.diff = snapshot.Diff(s)
+	}
+	s.logProfile(sprint)
+	if err == nil {
+		s.archiveRuns(sprint)
+	}
+
+	// a failed resume only returns its sprint if there are staged changes in it to inspect
+	if err != nil && !s.engine.StagedContactChanges() {
 		return nil, err
 	}
+	return sprint, err
+}
 
-	return sprint, nil
+// the UUIDs of the flows of this session's runs
+func (s *session) flowUUIDs() []assets.FlowUUID {
+	uuids := make([]assets.FlowUUID, 0, len(s.runs))
+	for _, r := range s.runs {
+		if r.Flow() != nil {
+			uuids = append(uuids, r.Flow().UUID())
+		}
+	}
+	return uuids
+}
+
+// if contact changes are being staged, takes a copy of the contact which can be restored if the sprint fails
+func (s *session) contactSavepoint() *flows.Contact {
+	if s.engine.StagedContactChanges() {
+		return s.contact.Clone()
+	}
+	return nil
+}
+
+// if contact changes are being staged and the sprint failed, restores the contact to how it was before the sprint
+// and discards the sprint's modifiers and the events of the changes they made
+func (s *session) endSprint(sprint *sprint, savepoint *flows.Contact, err error) {
+	if s.engine.StagedContactChanges() && (err != nil || s.status == flows.SessionStatusFailed) {
+		s.contact = savepoint
+		sprint.discardContactChanges()
+	}
+}
+
+// if profiling is enabled, logs where the time in the sprint went
+func (s *session) logProfile(sprint *sprint) {
+	if s.profiler != nil {
+		sprint.logEvent(events.NewSprintProfile(s.profiler, maxProfileTimings))
+	}
+}
+
+// if the engine has a run archive, moves the oldest runs which have ended to it, so that the session keeps at most the
+// engine's max number of ended runs
+func (s *session) archiveRuns(sprint *sprint) {
+	archive := s.engine.RunArchive()
+	if archive == nil {
+		return
+	}
+
+	ended := make([]flows.Run, 0)
+	for _, r := range s.runs {
+		if r.ExitedOn() != nil {
+			ended = append(ended, r)
+		}
+	}
+	if len(ended) <= s.engine.MaxEndedRuns() {
+		return
+	}
+
+	candidates := make(map[flows.RunUUID]bool, len(ended)-s.engine.MaxEndedRuns())
+	for _, r := range ended[:len(ended)-s.engine.MaxEndedRuns()] {
+		candidates[r.UUID()] = true
+	}
+
+	// a run stays in the session whilst another run which stays has it as its parent, and parents always come before
+	// their children so we can work that out in a single pass from the end
+	toArchive := make([]flows.Run, 0, len(candidates))
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		r := s.runs[i]
+		if candidates[r.UUID()] {
+			toArchive = append(toArchive, r)
+		} else if parent := r.ParentInSession(); parent != nil {
+			delete(candidates, parent.UUID())
+		}
+	}
+	if len(toArchive) == 0 {
+		return
+	}
+
+	marshaled := make(map[flows.RunUUID]json.RawMessage, len(toArchive))
+	for _, r := range toArchive {
+		data, err := jsonx.Marshal(r)
+		if err != nil {
+			sprint.logEvent(events.NewError(errors.Wrap(err, "unable to marshal run for archiving")))
+			return
+		}
+		marshaled[r.UUID()] = data
+	}
+
+	if err := archive.StoreRuns(s.uuid, marshaled); err != nil {
+		sprint.logEvent(events.NewError(errors.Wrap(err, "unable to archive runs")))
+		return
+	}
+
+	resident := make([]flows.Run, 0, len(s.runs)-len(toArchive))
+	for _, r := range s.runs {
+		if _, archived := marshaled[r.UUID()]; archived {
+			s.archivedRuns = append(s.archivedRuns, flows.NewArchivedRun(r))
+			delete(s.runsByUUID, r.UUID())
+		} else {
+			resident = append(resident, r)
+		}
+	}
+	s.runs = resident
 }
 
 // prepares the session for starting/resuming
@@ -229,8 +571,39 @@ func (s *session) prepareForSprint() error {
 	return nil
 }
 
+// loads the contacts related to the session contact from the engine's contact provider, so that they can be referenced
+// by expressions during the sprint without lookups during evaluation
+func (s *session) loadRelatedContacts(ctx context.Context, sprint *sprint) {
+	provider := s.engine.ContactProvider()
+	if provider == nil || s.contact == nil {
+		return
+	}
+
+	uuids := make([]flows.ContactUUID, 0, len(s.contact.Relations()))
+	for _, relation := range s.contact.Relations() {
+		if relation != nil {
+			uuids = append(uuids, relation.Contact().UUID)
+		}
+	}
+	s.related = nil
+	if len(uuids) == 0 {
+		return
+	}
+
+	contacts, err := provider.GetContacts(ctx, s.assets, uuids)
+	if err != nil {
+		sprint.logEvent(events.NewError(errors.Wrap(err, "error loading related contacts")))
+		return
+	}
+
+	s.related = make(map[flows.ContactUUID]*flows.Contact, len(contacts))
+	for _, c := range contacts {
+		s.related[c.UUID()] = c
+	}
+}
+
 // tries to resume a waiting session with the given resume
-func (s *session) tryToResume(sprint *sprint, waitingRun flows.Run, resume flows.Resume) error {
+func (s *session) tryToResume(ctx context.Context, sprint *sprint, waitingRun flows.Run, resume flows.Resume) error {
 	failSession := func(msg string, args ...any) {
 		// put failure event in waiting run
 		failRun(sprint, waitingRun, nil, errors.Errorf(msg, args...))
@@ -263,44 +636,200 @@ func (s *session) tryToResume(sprint *sprint, waitingRun flows.Run, resume flows
 		return nil
 	}
 
+	// the run might be waiting for the response to a webhook request made by one of the node's actions
+	if wait := pendingCallback(waitingRun); wait != nil {
+		index := actionIndex(node, wait.ActionUUID)
+		if index < 0 {
+			failSession("action %s of callback wait no longer exists", wait.ActionUUID)
+			return nil
+		}
+		return s.resumeCallback(ctx, sprint, waitingRun, step, node, index, wait.RequestUUID, resume)
+	}
+
 	if node.Router() == nil || node.Router().Wait() == nil {
 		failSession("can't resume from node without a router or wait")
 		return nil
 	}
 
-	// check that the wait accepts this resume - not a permanent error - caller can retry with different resume
-	if !node.Router().Wait().Accepts(resume) {
+	// a fired timer bypasses the wait and takes the run to the timer's node, as long as the run still has that timer
+	var timer *flows.Timer
+	if fired, isTimer := resume.(*resumes.TimerFiredResume); isTimer {
+		if timer = findTimer(waitingRun, fired.Timer()); timer == nil {
+			return newError(ErrorResumeNoSuchTimer, "waiting run has no timer named '%s'", fired.Timer())
+		}
+	} else if !node.Router().Wait().Accepts(resume) {
+		// check that the wait accepts this resume - not a permanent error - caller can retry with different resume
 		return newError(ErrorResumeRejectedByWait, "resume of type %s not accepted by wait of type %s", resume.Type(), node.Router().Wait().Type())
 	}
 
-	s.status = flows.SessionStatusActive
-	s.currentResume = resume
-
 	logEvent := func(e flows.Event) {
 		waitingRun.LogEvent(step, e)
 		sprint.logEvent(e)
 	}
 
+	// a message which duplicates the last one, e.g. a channel retry, is either ignored or routed to its own category
+	var duplicate bool
+	if msgResume, isMsg := resume.(*resumes.MsgResume); isMsg && s.engine.DuplicateInputPolicy() != flows.DuplicateInputAccept {
+		if reason := s.duplicateInputReason(msgResume.Msg()); reason != "" {
+			if s.engine.DuplicateInputPolicy() != flows.DuplicateInputRoute || !node.Router().AllowDuplicateInput() {
+				logEvent(events.NewMsgIgnored(msgResume.Msg(), reason))
+				return nil
+			}
+			duplicate = true
+		}
+	}
+
+	s.status = flows.SessionStatusActive
+	s.currentResume = resume
+
 	// resumes are allowed to make state changes
 	resume.Apply(waitingRun, logEvent)
 
+	// a paged wait might consume this resume by showing the next page of its prompt, in which case we keep waiting
+	if paged, isPaged := node.Router().Wait().(flows.PagedWait); isPaged && timer == nil && !duplicate && paged.ShowMore(waitingRun, resume, logEvent) {
+		waitingRun.SetStatus(flows.RunStatusWaiting)
+		s.status = flows.SessionStatusWaiting
+		return nil
+	}
+
+	// a validating wait might find this input invalid, in which case we keep waiting unless the contact is out of attempts
+	var invalid bool
+	if validating, isValidating := node.Router().Wait().(flows.ValidatingWait); isValidating && timer == nil && !duplicate {
+		switch validating.ValidateInput(waitingRun, resume, logEvent) {
+		case flows.InputRetry:
+			waitingRun.SetStatus(flows.RunStatusWaiting)
+			s.status = flows.SessionStatusWaiting
+			return nil
+		case flows.InputInvalid:
+			invalid = node.Router().AllowInvalidInput()
+		}
+	}
+
+	// a racing wait is finished by this resume and might tell us which category to route to
+	var raceCategory flows.CategoryUUID
+	if racing, isRacing := node.Router().Wait().(flows.RacingWait); isRacing {
+		raceCategory = racing.Finish(waitingRun, resume, logEvent)
+	}
+
 	// ensure groups are correct
 	s.ensureQueryBasedGroups(logEvent)
 
-	_, isTimeout := resume.(*resumes.WaitTimeoutResume)
+	if timer != nil {
+		waitingRun.CancelTimer(timer.Name)
+
+		if waitingRun.Flow().GetNode(timer.NodeUUID) == nil {
+			failSession("node %s for timer '%s' no longer exists", timer.NodeUUID, timer.Name)
+			return nil
+		}
+
+		return s.continueUntilWait(ctx, sprint, waitingRun, node, nil, timer.NodeUUID, "", step, nil)
+	}
+
+	route := routeNormal
+	if duplicate {
+		route = routeDuplicateInput
+	} else if invalid {
+		route = routeInvalidInput
+	} else if _, isTimeout := resume.(*resumes.WaitTimeoutResume); isTimeout {
+		route = routeTimeout
+	} else if _, isExpiration := resume.(*resumes.ExpirationResume); isExpiration {
+		route = routeExpiration
+	}
 
-	exit, operand, err := s.findResumeExit(sprint, waitingRun, isTimeout)
+	exit, operand, err := s.findResumeExit(sprint, waitingRun, route, raceCategory)
 	if err != nil {
 		failSession(fmt.Sprintf("unable to resolve router exit: %s", err.Error()))
 		return nil
 	}
 
 	// off to the races again...
-	return s.continueUntilWait(sprint, waitingRun, node, exit, operand, step, nil)
+	return s.continueUntilWait(ctx, sprint, waitingRun, node, exit, "", operand, step, nil)
+}
+
+// resumes a run which was waiting for the response to a webhook request, continuing with the rest of its node
+func (s *session) resumeCallback(ctx context.Context, sprint *sprint, run flows.Run, step flows.Step, node flows.Node, actionIndex int, requestUUID flows.WebhookRequestUUID, resume flows.Resume) error {
+	callback, isCallback := resume.(*resumes.WebhookCallbackResume)
+	_, isRunExpiration := resume.(*resumes.RunExpirationResume)
+	_, isExpiration := resume.(*resumes.ExpirationResume)
+	isExpiration = isExpiration || isRunExpiration
+
+	if !isCallback && !isExpiration {
+		return newError(ErrorResumeRejectedByWait, "resume of type %s not accepted by wait for callback", resume.Type())
+	}
+	if isCallback && callback.Callback().RequestUUID != requestUUID {
+		return newError(ErrorResumeRejectedByWait, "callback for request %s doesn't match request %s being waited for", callback.Callback().RequestUUID, requestUUID)
+	}
+
+	s.status = flows.SessionStatusActive
+	s.currentResume = resume
+
+	logEvent := func(e flows.Event) {
+		run.LogEvent(step, e)
+		sprint.logEvent(e)
+	}
+
+	resume.Apply(run, logEvent)
+
+	s.ensureQueryBasedGroups(logEvent)
+
+	// an expired run has nowhere to go but we may need to resume a parent
+	if isExpiration {
+		return s.continueUntilWait(ctx, sprint, run, node, nil, "", "", step, nil)
+	}
+
+	if action, isCallbackAction := node.Actions()[actionIndex].(flows.CallbackAction); isCallbackAction {
+		action.ResumeCallback(run, step, callback.Callback(), logEvent)
+	}
+
+	exit, operand, err := s.executeNode(ctx, sprint, run, node, step, actionIndex+1)
+	if err != nil || s.status == flows.SessionStatusWaiting {
+		return err
+	}
+
+	return s.continueUntilWait(ctx, sprint, run, node, exit, "", operand, step, nil)
+}
+
+// a run waiting for a callback expires after the expiry duration of its flow like any other waiting run
+func callbackExpiresOn(run flows.Run) *time.Time {
+	if mins := run.Flow().ExpireAfterMinutes(); mins > 0 {
+		expiresOn := run.Session().Sources().Now().Add(time.Duration(mins) * time.Minute)
+		return &expiresOn
+	}
+	return nil
+}
+
+// gets the callback wait that the given run is parked at, if any
+func pendingCallback(run flows.Run) *events.CallbackWaitEvent {
+	runEvents := run.Events()
+	if run.Status() != flows.RunStatusWaiting || len(runEvents) == 0 {
+		return nil
+	}
+	wait, _ := runEvents[len(runEvents)-1].(*events.CallbackWaitEvent)
+	return wait
+}
+
+// finds the index of the action with the given UUID in the given node, or -1 if it doesn't exist
+func actionIndex(node flows.Node, uuid flows.ActionUUID) int {
+	for i, a := range node.Actions() {
+		if a.UUID() == uuid {
+			return i
+		}
+	}
+	return -1
+}
+
+// finds the timer with the given name in the given run
+func findTimer(run flows.Run, name string) *flows.Timer {
+	for _, t := range run.Timers() {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
 }
 
 // finds the exit from a the current node in a run that may have been waiting or a parent paused for a child subflow
-func (s *session) findResumeExit(sprint *sprint, run flows.Run, isTimeout bool) (flows.Exit, string, error) {
+func (s *session) findResumeExit(sprint *sprint, run flows.Run, route routeKind, raceCategory flows.CategoryUUID) (flows.Exit, string, error) {
 	// we might have no immediate destination in this run, but continueUntilWait can resume a parent run
 	if run.Status() != flows.RunStatusActive {
 		return nil, "", nil
@@ -316,19 +845,24 @@ func (s *session) findResumeExit(sprint *sprint, run flows.Run, isTimeout bool)
 	}
 
 	// see if this node can now pick a destination
-	return s.pickNodeExit(sprint, run, node, step, isTimeout, logEvent)
+	return s.pickNodeExit(sprint, run, node, step, route, raceCategory, logEvent)
 }
 
 // the main flow execution loop
-func (s *session) continueUntilWait(sprint *sprint, currentRun flows.Run, node flows.Node, exit flows.Exit, operand string, step flows.Step, trigger flows.Trigger) (err error) {
+func (s *session) continueUntilWait(ctx context.Context, sprint *sprint, currentRun flows.Run, node flows.Node, exit flows.Exit, jumpTo flows.NodeUUID, operand string, step flows.Step, trigger flows.Trigger) (err error) {
 	var destination flows.NodeUUID
 	var numNewSteps int
 
 	for {
 		// start by picking a destination node...
 
-		// if a new flow has been pushed, find a destination there
-		if s.pushedFlow != nil {
+		if jumpTo != "" {
+			// we've been told to go straight to a node in the current run, e.g. by a timer
+			destination, jumpTo = jumpTo, ""
+
+		} else if s.pushedFlow != nil {
+			// if a new flow has been pushed, find a destination there
+
 			// if this is terminal, then we need to mark all other runs as completed so we don't try to resume them
 			if s.pushedFlow.terminal {
 				for _, run := range s.runs {
@@ -341,6 +875,12 @@ func (s *session) continueUntilWait(sprint *sprint, currentRun flows.Run, node f
 			currentRun = runs.NewRun(s, s.pushedFlow.flow, currentRun)
 			s.addRun(currentRun)
 
+			run := currentRun
+			run.EvaluateConstants(func(e flows.Event) {
+				run.LogEvent(nil, e)
+				sprint.logEvent(e)
+			})
+
 			// our destination is the first node in that flow... if such a node exists
 			if len(flow.Nodes()) > 0 {
 				destination = flow.Nodes()[0].UUID()
@@ -387,7 +927,7 @@ func (s *session) continueUntilWait(sprint *sprint, currentRun flows.Run, node f
 					if currentRun.Flow() == nil {
 						failRun(sprint, currentRun, nil, errors.New("can't resume run with missing flow asset"))
 					} else {
-						if exit, operand, err = s.findResumeExit(sprint, currentRun, false); err != nil {
+						if exit, operand, err = s.findResumeExit(sprint, currentRun, routeNormal, ""); err != nil {
 							failRun(sprint, currentRun, nil, errors.Wrapf(err, "can't resume run as node no longer exists"))
 						}
 					}
@@ -423,7 +963,7 @@ func (s *session) continueUntilWait(sprint *sprint, currentRun flows.Run, node f
 					return errors.Errorf("unable to find destination node %s in flow %s", destination, currentRun.Flow().UUID())
 				}
 
-				step, exit, operand, err = s.visitNode(sprint, currentRun, node, trigger)
+				step, exit, operand, err = s.visitNode(ctx, sprint, currentRun, node, trigger)
 				if err != nil {
 					return err
 				}
@@ -441,13 +981,15 @@ func (s *session) continueUntilWait(sprint *sprint, currentRun flows.Run, node f
 }
 
 // visits the given node, creating a step in our current run path
-func (s *session) visitNode(sprint *sprint, run flows.Run, node flows.Node, trigger flows.Trigger) (flows.Step, flows.Exit, string, error) {
+func (s *session) visitNode(ctx context.Context, sprint *sprint, run flows.Run, node flows.Node, trigger flows.Trigger) (flows.Step, flows.Exit, string, error) {
 	step := run.CreateStep(node)
 	logEvent := func(e flows.Event) {
 		run.LogEvent(step, e)
 		sprint.logEvent(e)
 	}
 
+	leaveLoops(run, node)
+
 	// this might be the first run of the session in which case a trigger might need to initialize the run
 	if trigger != nil {
 		if err := trigger.InitializeRun(run, logEvent); err != nil {
@@ -455,24 +997,125 @@ func (s *session) visitNode(sprint *sprint, run flows.Run, node flows.Node, trig
 		}
 	}
 
+	exit, operand, err := s.executeNode(ctx, sprint, run, node, step, 0)
+	return step, exit, operand, err
+}
+
+// ends any loops of the given run whose bodies it has left by visiting the given node
+func leaveLoops(run flows.Run, node flows.Node) {
+	for _, loop := range run.Loops() {
+		if loop.NodeUUID == node.UUID() {
+			continue
+		}
+
+		loopNode := run.Flow().GetNode(loop.NodeUUID)
+		if loopNode != nil {
+			if router, isLooping := loopNode.Router().(flows.LoopingRouter); isLooping && router.InLoop(run.Flow(), loopNode, node.UUID()) {
+				continue
+			}
+		}
+
+		// ending a loop also ends the loops inside it
+		run.EndLoop(loop.NodeUUID)
+		return
+	}
+}
+
+// executes the given node's actions, starting at the given action, and then its router
+func (s *session) executeNode(ctx context.Context, sprint *sprint, run flows.Run, node flows.Node, step flows.Step, from int) (flows.Exit, string, error) {
+	var requested *events.WebhookRequestedEvent
+	logEvent := func(e flows.Event) {
+		if r, isRequested := e.(*events.WebhookRequestedEvent); isRequested {
+			requested = r
+		}
+		run.LogEvent(step, e)
+		sprint.logEvent(e)
+	}
+
 	// execute our node's actions
-	if node.Actions() != nil {
-		for _, action := range node.Actions() {
-			if err := action.Execute(run, step, sprint.logModifier, logEvent); err != nil {
-				return step, nil, "", errors.Wrapf(err, "error executing action[type=%s,uuid=%s]", action.Type(), action.UUID())
+	actions := node.Actions()
+	for i := from; i < len(actions); i++ {
+		action := actions[i]
+
+		// if enabled, actions which don't depend on each other are executed concurrently
+		if s.engine.ConcurrentActions() {
+			if batch := concurrentBatch(run.Flow(), actions[i:]); len(batch) > 1 {
+				if err := s.executeConcurrently(ctx, sprint, run, step, batch); err != nil {
+					return nil, "", err
+				}
+				if run.Status() == flows.RunStatusFailed {
+					return nil, "", nil
+				}
+
+				i += len(batch) - 1
+				continue
 			}
+		}
+
+		evaluationErrors := run.EvaluationErrors()
+
+		// in strict mode, an action's events and modifiers are buffered until we know it evaluated all its templates
+		actionModifier, actionEvent := sprint.logModifier, logEvent
+		var buffer *actionBuffer
+		if s.engine.StrictTemplates() {
+			buffer = &actionBuffer{}
+			actionModifier, actionEvent = buffer.logModifier, buffer.logEvent
+		}
+
+		stop := s.profiler.Start(flows.ProfileCategoryAction, actionProfileName(action))
+		err := executeAction(ctx, run, step, action, actionModifier, actionEvent)
+		stop()
+
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "error executing action[type=%s,uuid=%s]", action.Type(), action.UUID())
+		}
 
-			// check if this action has errored the run
-			if run.Status() == flows.RunStatusFailed {
-				return step, nil, "", nil
+		// an action which couldn't evaluate one of its templates fails the run without any of its output
+		if buffer != nil {
+			if run.EvaluationErrors() > evaluationErrors {
+				buffer.discard(logEvent)
+				failRun(sprint, run, step, errors.Errorf("action[type=%s,uuid=%s] failed to evaluate a template", action.Type(), action.UUID()))
+				return nil, "", nil
 			}
+			buffer.flush(sprint.logModifier, logEvent)
+		}
+
+		// check if this action has errored the run
+		if run.Status() == flows.RunStatusFailed {
+			return nil, "", nil
+		}
+
+		// or completed it, e.g. an exit_with action
+		if run.Status() == flows.RunStatusCompleted {
+			return nil, "", nil
+		}
+
+		// or handed off a request to the caller, in which case we wait for the callback with its response
+		if requested != nil {
+			logEvent(events.NewCallbackWait(requested.RequestUUID, action.UUID()))
+			run.SetExpiresOn(callbackExpiresOn(run))
+			run.SetStatus(flows.RunStatusWaiting)
+			s.status = flows.SessionStatusWaiting
+			return nil, "", nil
 		}
 	}
 
 	// a start flow action may have triggered a subflow in which case we're done on this node for now
 	// and it will be resumed when the subflow finishes
 	if s.pushedFlow != nil {
-		return step, nil, "", nil
+		return nil, "", nil
+	}
+
+	// a forking router has its branches visited before it can route
+	var timedOut bool
+	if forking, ok := node.Router().(flows.ForkingRouter); ok {
+		var err error
+		if timedOut, err = s.visitBranches(ctx, sprint, run, forking); err != nil {
+			return nil, "", err
+		}
+		if run.Status() == flows.RunStatusFailed {
+			return nil, "", nil
+		}
 	}
 
 	// our node might have a router with a wait
@@ -488,25 +1131,57 @@ func (s *session) visitNode(sprint *sprint, run flows.Run, node flows.Node, trig
 			run.SetStatus(flows.RunStatusWaiting)
 			s.status = flows.SessionStatusWaiting
 
-			return step, nil, "", nil
+			// load the flows which might be needed when we're resumed so their templates and router tests are ready
+			if s.engine.Prefetch() {
+				prefetch(s.assets, prefetchFlows(run.Flow(), node))
+			}
+
+			return nil, "", nil
 		}
 	}
 
 	// use our node's router to determine where to go next
-	exit, operand, err := s.pickNodeExit(sprint, run, node, step, false, logEvent)
-	return step, exit, operand, err
+	route := routeNormal
+	if timedOut {
+		route = routeTimeout
+	}
+
+	return s.pickNodeExit(sprint, run, node, step, route, "", logEvent)
 }
 
+// the ways in which a router can be asked to pick a category
+type routeKind int
+
+const (
+	routeNormal         routeKind = iota // router evaluates its cases
+	routeTimeout                         // wait timed out
+	routeExpiration                      // run expired whilst waiting
+	routeDuplicateInput                  // run was resumed with a message which duplicates the last one
+	routeInvalidInput                    // run was resumed with invalid input and no attempts left
+)
+
 // picks the exit to use on the given node
-func (s *session) pickNodeExit(sprint *sprint, run flows.Run, node flows.Node, step flows.Step, isTimeout bool, logEvent flows.EventCallback) (flows.Exit, string, error) {
+func (s *session) pickNodeExit(sprint *sprint, run flows.Run, node flows.Node, step flows.Step, route routeKind, raceCategory flows.CategoryUUID, logEvent flows.EventCallback) (flows.Exit, string, error) {
 	var exitUUID flows.ExitUUID
 	var operand string
 	var err error
 
 	if node.Router() != nil {
-		if isTimeout {
+		defer s.profiler.Start(flows.ProfileCategoryRouter, fmt.Sprintf("%s[node=%s]", node.Router().Type(), node.UUID()))()
+
+		switch {
+		case route == routeTimeout:
 			exitUUID, err = node.Router().RouteTimeout(run, step, logEvent)
-		} else {
+		case route == routeExpiration:
+			exitUUID, err = node.Router().RouteExpiration(run, step, logEvent)
+		case route == routeDuplicateInput:
+			exitUUID, err = node.Router().RouteDuplicateInput(run, step, logEvent)
+		case route == routeInvalidInput:
+			exitUUID, err = node.Router().RouteInvalidInput(run, step, logEvent)
+		case raceCategory != "":
+			exitUUID, err = node.Router().RouteRace(run, step, raceCategory, logEvent)
+		default:
+			warnDeprecatedFunctions(run.Flow(), uuids.UUID(node.UUID()), logEvent)
 			exitUUID, operand, err = node.Router().Route(run, step, logEvent)
 		}
 
@@ -535,13 +1210,30 @@ func (s *session) pickNodeExit(sprint *sprint, run flows.Run, node flows.Node, s
 	return nil, "", nil // no where to go in the flow...
 }
 
+// the name used for an action in profiles
+func actionProfileName(action flows.Action) string {
+	return fmt.Sprintf("%s[uuid=%s]", action.Type(), action.UUID())
+}
+
 // ensures that our session contact is in the correct query based groups as as far as the engine is concerned
 func (s *session) ensureQueryBasedGroups(logEvent flows.EventCallback) {
 	if s.contact == nil {
 		return
 	}
 
-	added, removed := s.contact.ReevaluateQueryBasedGroups(s.Environment())
+	svc, err := s.Engine().Services().GroupMembership(s.Assets())
+	if err != nil {
+		logEvent(events.NewError(err))
+		return
+	}
+	if svc == nil {
+		return
+	}
+
+	added, removed, err := s.contact.ReevaluateQueryBasedGroups(s.Environment(), svc)
+	if err != nil {
+		logEvent(events.NewError(err))
+	}
 
 	// add groups changed event for the groups we were added/removed to/from
 	if len(added) > 0 || len(removed) > 0 {
@@ -551,6 +1243,9 @@ func (s *session) ensureQueryBasedGroups(logEvent flows.EventCallback) {
 
 func (s *session) countWaits() int {
 	waits := 0
+	for _, a := range s.archivedRuns {
+		waits += a.Waits
+	}
 	for _, r := range s.runs {
 		for _, e := range r.Events() {
 			if strings.HasSuffix(e.Type(), "_wait") {
@@ -574,19 +1269,22 @@ func failRun(sp *sprint, run flows.Run, step flows.Step, err error) {
 //------------------------------------------------------------------------------------------
 
 type sessionEnvelope struct {
-	UUID        flows.SessionUUID   `json:"uuid"` // TODO validate:"required"`
-	Type        flows.FlowType      `json:"type"` // TODO validate:"required"`
-	Environment json.RawMessage     `json:"environment"`
-	Trigger     json.RawMessage     `json:"trigger" validate:"required"`
-	Contact     *json.RawMessage    `json:"contact,omitempty"`
-	Runs        []json.RawMessage   `json:"runs"`
-	Status      flows.SessionStatus `json:"status" validate:"required"`
-	Wait        json.RawMessage     `json:"wait,omitempty"`
-	Input       json.RawMessage     `json:"input,omitempty" validate:"omitempty"`
+	UUID        flows.SessionUUID    `json:"uuid"` // TODO validate:"required"`
+	Type        flows.FlowType       `json:"type"` // TODO validate:"required"`
+	Environment json.RawMessage      `json:"environment"`
+	Trigger     json.RawMessage      `json:"trigger" validate:"required"`
+	Contact     *json.RawMessage     `json:"contact,omitempty"`
+	Runs        []json.RawMessage    `json:"runs"`
+	Archived    []*flows.ArchivedRun `json:"archived_runs,omitempty" validate:"omitempty,dive"`
+	Status      flows.SessionStatus  `json:"status" validate:"required"`
+	Wait        json.RawMessage      `json:"wait,omitempty"`
+	Input       json.RawMessage      `json:"input,omitempty" validate:"omitempty"`
+	Cart        *flows.Order         `json:"cart,omitempty" validate:"omitempty"`
+	Profile     string               `json:"profile,omitempty"`
 }
 
 // ReadSession decodes a session from the passed in JSON
-func readSession(eng flows.Engine, sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Session, error) {
+func readSession(eng *engine, sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Session, error) {
 	e := &sessionEnvelope{}
 	var err error
 
@@ -594,20 +1292,33 @@ func readSession(eng flows.Engine, sessionAssets flows.SessionAssets, data json.
 		return nil, errors.Wrap(err, "unable to read session")
 	}
 
+	// sessions are read with the engine profile they were started with
+	if e.Profile != eng.ProfileName() {
+		if eng = eng.profileNamed(e.Profile); eng == nil {
+			return nil, errors.Errorf("unable to read session with no engine profile named '%s'", e.Profile)
+		}
+	}
+
 	s := &session{
-		engine:     eng,
-		assets:     sessionAssets,
-		uuid:       e.UUID,
-		type_:      e.Type,
-		status:     e.Status,
-		runsByUUID: make(map[flows.RunUUID]flows.Run),
+		engine:       eng,
+		lifecycle:    eng.lifecycle,
+		shadowing:    eng.shadowing,
+		assets:       sessionAssets,
+		uuid:         e.UUID,
+		type_:        e.Type,
+		status:       e.Status,
+		cart:         e.Cart,
+		archivedRuns: e.Archived,
+		runsByUUID:   make(map[flows.RunUUID]flows.Run),
+		sources:      flows.DefaultSources,
 	}
 
 	// read our environment
-	s.env, err = envs.ReadEnvironment(e.Environment)
+	env, err := envs.ReadEnvironment(e.Environment)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to read environment")
 	}
+	s.env = profileEnvironment(eng, env)
 
 	// read our trigger
 	if e.Trigger != nil {
@@ -645,9 +1356,12 @@ func readSession(eng flows.Engine, sessionAssets flows.SessionAssets, data json.
 // MarshalJSON marshals this session into JSON
 func (s *session) MarshalJSON() ([]byte, error) {
 	e := &sessionEnvelope{
-		UUID:   s.uuid,
-		Type:   s.type_,
-		Status: s.status,
+		UUID:     s.uuid,
+		Type:     s.type_,
+		Status:   s.status,
+		Cart:     s.cart,
+		Archived: s.archivedRuns,
+		Profile:  s.engine.ProfileName(),
 	}
 	var err error
 
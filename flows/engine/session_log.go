@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"encoding/json"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils/jsonpatch"
+
+	"github.com/pkg/errors"
+)
+
+// SessionLog is a delta-encoded form of a session, i.e. a snapshot of the session as it was at some point, followed by
+// the patches made to it by each later sprint
+type SessionLog struct {
+	Snapshot json.RawMessage          `json:"snapshot"`
+	Deltas   [][]*jsonpatch.Operation `json:"deltas,omitempty"`
+}
+
+// SessionCodec encodes sessions as session logs. Sessions which are resumed many times mostly grow by appending to
+// their runs and events, so storing only what each sprint changed is much smaller than storing the whole session after
+// every sprint.
+type SessionCodec struct {
+	compactEvery int
+}
+
+// NewSessionCodec creates a new session codec which keeps at most the given number of deltas in a log, replacing them
+// with a new snapshot when there are more. Zero means logs are never compacted.
+func NewSessionCodec(compactEvery int) *SessionCodec {
+	return &SessionCodec{compactEvery: compactEvery}
+}
+
+// Encode returns the log updated with the current state of the given session. If the log is nil, a new log is created
+// with the session as its snapshot. The given log isn't modified.
+func (c *SessionCodec) Encode(log *SessionLog, session flows.Session) (*SessionLog, error) {
+	current, err := jsonx.Marshal(session)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal session")
+	}
+
+	if log == nil {
+		return &SessionLog{Snapshot: current}, nil
+	}
+
+	previous, err := c.Decode(log)
+	if err != nil {
+		return nil, err
+	}
+
+	delta, err := jsonpatch.Diff(previous, current)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to diff session")
+	}
+	if len(delta) == 0 {
+		return log, nil
+	}
+
+	if c.compactEvery > 0 && len(log.Deltas) >= c.compactEvery {
+		return &SessionLog{Snapshot: current}, nil
+	}
+
+	deltas := make([][]*jsonpatch.Operation, len(log.Deltas), len(log.Deltas)+1)
+	copy(deltas, log.Deltas)
+
+	return &SessionLog{Snapshot: log.Snapshot, Deltas: append(deltas, delta)}, nil
+}
+
+// Decode returns the serialized session by applying the deltas of the given log to its snapshot
+func (c *SessionCodec) Decode(log *SessionLog) (json.RawMessage, error) {
+	data := []byte(log.Snapshot)
+
+	for i, delta := range log.Deltas {
+		var err error
+		if data, err = jsonpatch.Apply(data, delta); err != nil {
+			return nil, errors.Wrapf(err, "unable to apply delta %d", i)
+		}
+	}
+
+	return data, nil
+}
+
+// Read reads the session encoded in the given log
+func (c *SessionCodec) Read(eng flows.Engine, sa flows.SessionAssets, log *SessionLog, missing assets.MissingCallback) (flows.Session, error) {
+	data, err := c.Decode(log)
+	if err != nil {
+		return nil, err
+	}
+	return eng.ReadSession(sa, data, missing)
+}
@@ -0,0 +1,108 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/test"
+	"github.com/nyaruka/goflow/utils/jsonpatch"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionCodec(t *testing.T) {
+	assetsJSON := []byte(`{
+		"flows": [
+			{
+				"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+				"name": "Chat",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+						"actions": [{"uuid": "ad154980-7bf7-4ab8-8728-545fd6378912", "type": "send_msg", "text": "Tell me more"}],
+						"router": {
+							"type": "switch",
+							"wait": {"type": "msg"},
+							"operand": "@input.text",
+							"result_name": "Reply",
+							"categories": [{"uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3", "name": "All Responses", "exit_uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}],
+							"default_category_uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3"
+						},
+						"exits": [{"uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d", "destination_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507"}]
+					}
+				]
+			}
+		]
+	}`)
+
+	sa, session, _ := test.NewSessionBuilder().WithAssetsJSON(assetsJSON).WithFlow("8ca44c09-791d-453a-9799-a70dd3303306").MustBuild()
+	codec := engine.NewSessionCodec(3)
+
+	// a new log is just a snapshot of the session
+	log, err := codec.Encode(nil, session)
+	require.NoError(t, err)
+	assert.Len(t, log.Deltas, 0)
+	test.AssertEqualJSON(t, jsonx.MustMarshal(session), log.Snapshot, "snapshot mismatch")
+
+	// encoding an unchanged session doesn't add a delta
+	unchanged, err := codec.Encode(log, session)
+	require.NoError(t, err)
+	assert.Equal(t, log, unchanged)
+
+	snapshot := log.Snapshot
+
+	for i, expectedDeltas := range []int{1, 2, 3, 0, 1} {
+		msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), urns.URN("tel:+12065551212"), nil, "more", nil)
+		_, err := session.Resume(context.Background(), resumes.NewMsg(session.Environment(), nil, msg))
+		require.NoError(t, err)
+
+		prev := log
+		log, err = codec.Encode(log, session)
+		require.NoError(t, err)
+		assert.Len(t, log.Deltas, expectedDeltas, "delta count mismatch after resume %d", i)
+
+		// logs are compacted into a new snapshot when they have too many deltas
+		if expectedDeltas == 0 {
+			assert.NotEqual(t, string(snapshot), string(log.Snapshot))
+			snapshot = log.Snapshot
+		} else {
+			assert.Equal(t, string(snapshot), string(log.Snapshot))
+			assert.Len(t, prev.Deltas, expectedDeltas-1, "previous log shouldn't be modified")
+		}
+
+		// deltas are smaller than the session itself
+		if expectedDeltas > 0 {
+			assert.Less(t, len(jsonx.MustMarshal(log.Deltas[expectedDeltas-1])), len(jsonx.MustMarshal(session)))
+		}
+
+		// and the decoded log always matches the session
+		decoded, err := codec.Decode(log)
+		require.NoError(t, err)
+		test.AssertEqualJSON(t, jsonx.MustMarshal(session), decoded, "decoded session mismatch after resume %d", i)
+
+		// logs survive being serialized
+		logJSON := jsonx.MustMarshal(log)
+		log = &engine.SessionLog{}
+		jsonx.MustUnmarshal(logJSON, log)
+	}
+
+	// which can be read back into a session
+	read, err := codec.Read(session.Engine(), sa, log, nil)
+	require.NoError(t, err)
+	assert.Equal(t, session.UUID(), read.UUID())
+	assert.Len(t, read.Runs()[0].Path(), 6)
+
+	// a log whose deltas don't apply to its snapshot is an error
+	_, err = codec.Decode(&engine.SessionLog{Snapshot: []byte(`{}`), Deltas: [][]*jsonpatch.Operation{{{Op: "remove", Path: "/runs"}}}})
+	assert.EqualError(t, err, "unable to apply delta 0: unable to apply remove operation at '/runs': no such property 'runs'")
+}
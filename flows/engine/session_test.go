@@ -1,8 +1,13 @@
 package engine_test
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"runtime"
 	"sort"
 	"testing"
 	"time"
@@ -19,10 +24,13 @@ import (
 	"github.com/nyaruka/goflow/flows/events"
 	"github.com/nyaruka/goflow/flows/resumes"
 	"github.com/nyaruka/goflow/flows/triggers"
+	"github.com/nyaruka/goflow/services/webhooks"
 	"github.com/nyaruka/goflow/test"
+	"github.com/nyaruka/goflow/utils"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/maps"
 )
 
 func TestEvaluateTemplate(t *testing.T) {
@@ -122,6 +130,46 @@ func BenchmarkEvaluateTemplate(b *testing.B) {
 	}
 }
 
+func BenchmarkReadSession(b *testing.B) {
+	// create a session with a long path by giving lots of answers which aren't accepted
+	sa, session, _ := test.NewSessionBuilder().WithAssetsPath("../../test/testdata/runner/two_questions.json").WithFlow("615b8a0f-588c-4d20-a05f-363b0b4ce6f4").MustBuild()
+	for i := 0; i < 50; i++ {
+		msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), "tel:+12065551212", nil, "purple", nil)
+		_, err := session.Resume(context.Background(), resumes.NewMsg(nil, nil, msg))
+		require.NoError(b, err)
+	}
+
+	sessionJSON := jsonx.MustMarshal(session)
+	eng := test.NewEngine()
+
+	// measures how much memory is retained per session when holding many sessions, with and without interning
+	for _, interner := range []*utils.Interner{nil, utils.DefaultInterner} {
+		b.Run(fmt.Sprintf("interning=%v", interner != nil), func(b *testing.B) {
+			defer func(i *utils.Interner) { utils.DefaultInterner = i }(utils.DefaultInterner)
+			utils.DefaultInterner = interner
+
+			sessions := make([]flows.Session, 0, b.N)
+
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+
+			for n := 0; n < b.N; n++ {
+				s, err := eng.ReadSession(sa, sessionJSON, assets.PanicOnMissing)
+				require.NoError(b, err)
+
+				sessions = append(sessions, s)
+			}
+
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+
+			b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc))/float64(b.N), "retained-B/session")
+			runtime.KeepAlive(sessions)
+		})
+	}
+}
+
 func TestReadWithMissingAssets(t *testing.T) {
 	// create standard test session and marshal to JSON
 	session, _, err := test.CreateTestSession("", envs.RedactionPolicyNone)
@@ -166,6 +214,8 @@ func TestReadWithMissingAssets(t *testing.T) {
 		"group[uuid=4f1f98fc-27a7-4a69-bbdb-24744ba739a9,name=Males]",
 		"group[uuid=b7cf0d83-f1c9-411c-96fd-c511a4cfa86d,name=Testers]",
 		"group[uuid=b7cf0d83-f1c9-411c-96fd-c511a4cfa86d,name=Testers]",
+		"relation_type[key=caregiver,name=]",
+		"relation_type[key=caregiver,name=]",
 		"ticketer[uuid=19dc6346-9623-4fe4-be80-538d493ecdf5,name=Support Tickets]",
 		"ticketer[uuid=19dc6346-9623-4fe4-be80-538d493ecdf5,name=Support Tickets]",
 		"ticketer[uuid=19dc6346-9623-4fe4-be80-538d493ecdf5,name=Support Tickets]",
@@ -203,7 +253,7 @@ func TestQueryBasedGroupReevaluationOnTrigger(t *testing.T) {
 	trigger := triggers.NewBuilder(env, assets.NewFlowReference("1b462ce8-983a-4393-b133-e15a0efdb70c", ""), contact).Manual().Build()
 	eng := engine.NewBuilder().Build()
 
-	session, sprint, err := eng.NewSession(sa, trigger)
+	session, sprint, err := eng.NewSession(context.Background(), sa, trigger)
 	require.NoError(t, err)
 
 	assert.Equal(t, 1, len(sprint.Events()))
@@ -293,7 +343,7 @@ func TestWaitTimeout(t *testing.T) {
 	waitEvent := run.Events()[1].(*events.MsgWaitEvent)
 	require.Equal(t, 600, *waitEvent.TimeoutSeconds)
 
-	_, err := session.Resume(resumes.NewWaitTimeout(nil, nil))
+	_, err := session.Resume(context.Background(), resumes.NewWaitTimeout(nil, nil))
 	require.NoError(t, err)
 
 	require.Equal(t, flows.SessionStatusCompleted, session.Status())
@@ -311,27 +361,27 @@ func TestCurrentContext(t *testing.T) {
 
 	assert.Equal(t, string(flows.SessionStatusWaiting), string(session.Status()))
 
-	context := session.CurrentContext()
-	assert.NotNil(t, context)
+	ctx := session.CurrentContext()
+	assert.NotNil(t, ctx)
 
-	runContext, _ := context.Get("run")
+	runContext, _ := ctx.Get("run")
 	flowContext, _ := runContext.(*types.XObject).Get("flow")
 	flowName, _ := flowContext.(*types.XObject).Get("name")
 	assert.Equal(t, types.NewXText("Child flow"), flowName)
 
 	// check we can marshal it
-	_, err := jsonx.Marshal(context)
+	_, err := jsonx.Marshal(ctx)
 	assert.NoError(t, err)
 
 	// end it
-	session.Resume(resumes.NewRunExpiration(nil, nil))
+	session.Resume(context.Background(), resumes.NewRunExpiration(nil, nil))
 	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
 
 	// can still get context of completed session
-	context = session.CurrentContext()
-	assert.NotNil(t, context)
+	ctx = session.CurrentContext()
+	assert.NotNil(t, ctx)
 
-	runContext, _ = context.Get("run")
+	runContext, _ = ctx.Get("run")
 	flowContext, _ = runContext.(*types.XObject).Get("flow")
 	flowName, _ = flowContext.(*types.XObject).Get("name")
 	assert.Equal(t, types.NewXText("Parent Flow"), flowName)
@@ -362,7 +412,7 @@ func TestSessionHistory(t *testing.T) {
 
 	// trigger session manually which will have no history
 	eng := engine.NewBuilder().Build()
-	session1, _, err := eng.NewSession(sa, triggers.NewBuilder(env, flow, contact).Manual().Build())
+	session1, _, err := eng.NewSession(context.Background(), sa, triggers.NewBuilder(env, flow, contact).Manual().Build())
 	require.NoError(t, err)
 
 	assert.Equal(t, flows.EmptyHistory, session1.History())
@@ -372,7 +422,7 @@ func TestSessionHistory(t *testing.T) {
 	runSummaryJSON := jsonx.MustMarshal(runSummary)
 	history := flows.NewChildHistory(session1)
 
-	session2, _, err := eng.NewSession(sa, triggers.NewBuilder(env, flow, contact).FlowAction(history, runSummaryJSON).Build())
+	session2, _, err := eng.NewSession(context.Background(), sa, triggers.NewBuilder(env, flow, contact).FlowAction(history, runSummaryJSON).Build())
 	require.NoError(t, err)
 
 	assert.Equal(t, &flows.SessionHistory{
@@ -392,7 +442,7 @@ func TestMaxResumesPerSession(t *testing.T) {
 		resume := resumes.NewMsg(nil, nil, msg)
 		numResumes++
 
-		_, err := session.Resume(resume)
+		_, err := session.Resume(context.Background(), resume)
 		require.NoError(t, err)
 
 		if session.Status() == flows.SessionStatusFailed {
@@ -421,7 +471,7 @@ func TestEngineErrors(t *testing.T) {
 	_, session, _ := test.NewSessionBuilder().WithAssetsPath("../../test/testdata/runner/empty.json").WithFlow("76f0a02f-3b75-4b86-9064-e9195e1b3a02").MustBuild()
 	require.Equal(t, flows.SessionStatusCompleted, session.Status())
 
-	_, err := session.Resume(nil)
+	_, err := session.Resume(context.Background(), nil)
 	assert.EqualError(t, err, "only waiting sessions can be resumed")
 	assert.Equal(t, engine.ErrorResumeNonWaitingSession, err.(*engine.Error).Code())
 
@@ -429,7 +479,818 @@ func TestEngineErrors(t *testing.T) {
 	_, session, _ = test.NewSessionBuilder().MustBuild()
 	require.Equal(t, flows.SessionStatusWaiting, session.Status())
 
-	_, err = session.Resume(resumes.NewDial(nil, nil, flows.NewDial(flows.DialStatusAnswered, 10)))
+	_, err = session.Resume(context.Background(), resumes.NewDial(nil, nil, flows.NewDial(flows.DialStatusAnswered, 10)))
 	assert.EqualError(t, err, "resume of type dial not accepted by wait of type msg")
 	assert.Equal(t, engine.ErrorResumeRejectedByWait, err.(*engine.Error).Code())
 }
+
+func TestSessionThreads(t *testing.T) {
+	assetsJSON, err := os.ReadFile("../../test/testdata/runner/two_questions.json")
+	require.NoError(t, err)
+
+	sa, err := test.CreateSessionAssets(assetsJSON, "")
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+	flow := assets.NewFlowReference("615b8a0f-588c-4d20-a05f-363b0b4ce6f4", "Two Questions")
+	eng := engine.NewBuilder().Build()
+
+	session, sprint, err := eng.NewSession(context.Background(), sa, triggers.NewBuilder(env, flow, contact).WithThread("order-123").Manual().Build())
+	require.NoError(t, err)
+	require.Equal(t, flows.SessionStatusWaiting, session.Status())
+
+	// thread key is available in expressions and is stamped on events
+	thread, err := session.Runs()[0].EvaluateTemplate("@trigger.thread")
+	assert.NoError(t, err)
+	assert.Equal(t, "order-123", thread)
+
+	for _, e := range sprint.Events() {
+		assert.Equal(t, "order-123", e.Thread())
+	}
+
+	// a resume for another thread, or for no thread, is rejected
+	msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), "tel:+593979123456", nil, "Teal", nil)
+	resume := resumes.NewMsg(nil, nil, msg)
+
+	_, err = session.Resume(context.Background(), resume)
+	assert.EqualError(t, err, "resume for thread '' can't resume session of thread 'order-123'")
+	assert.Equal(t, engine.ErrorResumeThreadMismatch, err.(*engine.Error).Code())
+
+	resume.SetThread("order-456")
+	_, err = session.Resume(context.Background(), resume)
+	assert.EqualError(t, err, "resume for thread 'order-456' can't resume session of thread 'order-123'")
+	assert.Equal(t, flows.SessionStatusWaiting, session.Status())
+
+	// but one with a matching thread key is accepted
+	resume.SetThread("order-123")
+	sprint, err = session.Resume(context.Background(), resume)
+	require.NoError(t, err)
+	assert.Greater(t, len(sprint.Events()), 0)
+	assert.Equal(t, "order-123", sprint.Events()[0].Thread())
+
+	// a session without a thread can't be resumed with one
+	session, _, err = eng.NewSession(context.Background(), sa, triggers.NewBuilder(env, flow, contact).Manual().Build())
+	require.NoError(t, err)
+
+	_, err = session.Resume(context.Background(), resume)
+	assert.EqualError(t, err, "resume for thread 'order-123' can't resume session of thread ''")
+}
+
+func TestStrictTemplates(t *testing.T) {
+	assetsJSON := []byte(`{
+		"flows": [
+			{
+				"uuid": "1b462ce8-983a-4393-b133-e15a0efdb70c",
+				"name": "Payment",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "dd8d3c2b-9e7b-4a7e-8e0a-b5b0a3c3bb8f",
+						"actions": [
+							{"uuid": "8eebd020-1af5-431c-b943-aa670fc74da9", "type": "send_msg", "text": "You owe @(1 / 0) dollars"},
+							{"uuid": "3c9ea8b6-5bfb-4f1f-8ad2-6bbf0f4a3a8b", "type": "send_msg", "text": "Thanks!"}
+						],
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}]
+					}
+				]
+			}
+		]
+	}`)
+
+	sa, err := test.CreateSessionAssets(assetsJSON, "")
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+	trigger := triggers.NewBuilder(env, assets.NewFlowReference("1b462ce8-983a-4393-b133-e15a0efdb70c", "Payment"), contact).Manual().Build()
+
+	// by default, template errors are logged but don't stop the flow
+	session, sprint, err := engine.NewBuilder().Build().NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, []string{"error", "msg_created", "msg_created"}, eventTypes(sprint.Events()))
+
+	// in strict mode, they fail the run
+	session, sprint, err = engine.NewBuilder().WithStrictTemplates(true).Build().NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	assert.Equal(t, flows.SessionStatusFailed, session.Status())
+	assert.Equal(t, []string{"error", "failure"}, eventTypes(sprint.Events()))
+	assert.NotContains(t, eventTypes(sprint.Events()), "msg_created") // the message with the blank amount isn't sent
+	assert.Equal(t, "action[type=send_msg,uuid=8eebd020-1af5-431c-b943-aa670fc74da9] failed to evaluate a template", sprint.Events()[1].(*events.FailureEvent).Text)
+}
+
+func TestStagedContactChanges(t *testing.T) {
+	assetsJSON := []byte(`{
+		"flows": [
+			{
+				"uuid": "1b462ce8-983a-4393-b133-e15a0efdb70c",
+				"name": "Registration",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "dd8d3c2b-9e7b-4a7e-8e0a-b5b0a3c3bb8f",
+						"actions": [
+							{"uuid": "8eebd020-1af5-431c-b943-aa670fc74da9", "type": "set_contact_name", "name": "Robert"},
+							{"uuid": "3c9ea8b6-5bfb-4f1f-8ad2-6bbf0f4a3a8b", "type": "send_msg", "text": "You owe @(1 / 0) dollars"}
+						],
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}]
+					}
+				]
+			}
+		]
+	}`)
+
+	sa, err := test.CreateSessionAssets(assetsJSON, "")
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+	trigger := triggers.NewBuilder(env, assets.NewFlowReference("1b462ce8-983a-4393-b133-e15a0efdb70c", "Registration"), contact).Manual().Build()
+
+	// by default, changes are kept even if the sprint fails
+	session, sprint, err := engine.NewBuilder().WithStrictTemplates(true).Build().NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	assert.Equal(t, flows.SessionStatusFailed, session.Status())
+	assert.Equal(t, "Robert", session.Contact().Name())
+	assert.Len(t, sprint.Modifiers(), 1)
+	assert.Len(t, sprint.Staged(), 0)
+
+	// with staging, changes are kept if the sprint succeeds
+	session, sprint, err = engine.NewBuilder().WithStagedContactChanges(true).Build().NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, "Robert", session.Contact().Name())
+	assert.Len(t, sprint.Modifiers(), 1)
+	assert.Len(t, sprint.Staged(), 0)
+
+	// but discarded if it fails
+	session, sprint, err = engine.NewBuilder().WithStrictTemplates(true).WithStagedContactChanges(true).Build().NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	assert.Equal(t, flows.SessionStatusFailed, session.Status())
+	assert.Equal(t, "Bob", session.Contact().Name())
+	assert.Len(t, sprint.Modifiers(), 0)
+	require.Len(t, sprint.Staged(), 1)
+	assert.Equal(t, "name", sprint.Staged()[0].Type())
+
+	// along with the events of the changes, so that the sprint's events don't contradict the contact
+	assert.Equal(t, []string{"error", "failure"}, eventTypes(sprint.Events()))
+	assert.Equal(t, []string{"contact_name_changed"}, eventTypes(sprint.StagedEvents()))
+
+	// a resume which errors only returns its sprint if changes are being staged
+	assetsJSON = []byte(`{
+		"flows": [
+			{
+				"uuid": "1b462ce8-983a-4393-b133-e15a0efdb70c",
+				"name": "Registration",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "dd8d3c2b-9e7b-4a7e-8e0a-b5b0a3c3bb8f",
+						"router": {
+							"type": "switch",
+							"wait": {"type": "msg"},
+							"categories": [{"uuid": "5b3d1a8c-2f6e-4d7a-9b8c-0e1f2a3b4c5d", "name": "All Responses", "exit_uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}],
+							"operand": "@input.text",
+							"default_category_uuid": "5b3d1a8c-2f6e-4d7a-9b8c-0e1f2a3b4c5d"
+						},
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}]
+					}
+				]
+			}
+		]
+	}`)
+	sa, err = test.CreateSessionAssets(assetsJSON, "")
+	require.NoError(t, err)
+
+	for _, staged := range []bool{false, true} {
+		session, _, err = engine.NewBuilder().WithStagedContactChanges(staged).Build().NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+
+		sprint, err = session.Resume(context.Background(), resumes.NewWaitTimeout(env, contact))
+		assert.EqualError(t, err, "resume of type wait_timeout not accepted by wait of type msg")
+		assert.Equal(t, staged, sprint != nil)
+	}
+}
+
+func eventTypes(evts []flows.Event) []string {
+	ts := make([]string, len(evts))
+	for i := range evts {
+		ts[i] = evts[i].Type()
+	}
+	return ts
+}
+
+func TestSprintArenas(t *testing.T) {
+	assetsJSON, err := os.ReadFile("../../test/testdata/runner/two_questions.json")
+	require.NoError(t, err)
+
+	sa, err := test.CreateSessionAssets(assetsJSON, "")
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+	trigger := triggers.NewBuilder(env, assets.NewFlowReference("615b8a0f-588c-4d20-a05f-363b0b4ce6f4", "Two Questions"), contact).Manual().Build()
+
+	// runs the flow to completion and returns the text of every message sent
+	runFlow := func(eng flows.Engine) []string {
+		session, sprint, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+		assert.Nil(t, session.Arena())
+
+		sprints := []flows.Sprint{sprint}
+		for _, text := range []string{"Teal", "Red", "Bob"} {
+			msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), "tel:+593979123456", nil, text, nil)
+			sprint, err := session.Resume(context.Background(), resumes.NewMsg(nil, nil, msg))
+			require.NoError(t, err)
+			assert.Nil(t, session.Arena())
+
+			sprints = append(sprints, sprint)
+		}
+
+		texts := make([]string, 0)
+		for _, sprint := range sprints {
+			for _, e := range sprint.Events() {
+				if created, isCreated := e.(*events.MsgCreatedEvent); isCreated {
+					texts = append(texts, created.Msg.Text())
+				}
+			}
+		}
+		return texts
+	}
+
+	withoutArenas := runFlow(engine.NewBuilder().Build())
+	withArenas := runFlow(engine.NewBuilder().WithSprintArenas(true).Build())
+
+	assert.Greater(t, len(withoutArenas), 2)
+	assert.Equal(t, withoutArenas, withArenas)
+}
+
+func TestServiceTimeouts(t *testing.T) {
+	// a webhook server which never responds until the request is cancelled
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	assetsJSON := []byte(`{
+		"flows": [
+			{
+				"uuid": "1b462ce8-983a-4393-b133-e15a0efdb70c",
+				"name": "Lookup",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "dd8d3c2b-9e7b-4a7e-8e0a-b5b0a3c3bb8f",
+						"actions": [
+							{"uuid": "8eebd020-1af5-431c-b943-aa670fc74da9", "type": "call_webhook", "method": "GET", "url": "` + server.URL + `"}
+						],
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}]
+					}
+				]
+			}
+		]
+	}`)
+
+	sa, err := test.CreateSessionAssets(assetsJSON, "")
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+	trigger := triggers.NewBuilder(env, assets.NewFlowReference("1b462ce8-983a-4393-b133-e15a0efdb70c", "Lookup"), contact).Manual().Build()
+
+	builder := func() *engine.Builder {
+		return engine.NewBuilder().WithWebhookServiceFactory(webhooks.NewServiceFactory(http.DefaultClient, nil, nil, nil, 10000, 0))
+	}
+
+	assertTimedOut := func(sprint flows.Sprint) {
+		assert.Equal(t, []string{"webhook_called"}, eventTypes(sprint.Events()))
+		assert.Equal(t, flows.CallStatusConnectionError, sprint.Events()[0].(*events.WebhookCalledEvent).Status)
+	}
+
+	// call is cancelled when the engine's timeout for webhooks is reached
+	start := time.Now()
+	session, sprint, err := builder().WithServiceTimeout(flows.ServiceTypeWebhook, 50*time.Millisecond).Build().NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assertTimedOut(sprint)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// or when the sprint's context is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start = time.Now()
+	session, sprint, err = builder().Build().NewSession(ctx, sa, trigger)
+	require.NoError(t, err)
+
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assertTimedOut(sprint)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+type testEventSink struct {
+	sessions []flows.Session
+	events   []flows.Event
+}
+
+func (s *testEventSink) Receive(ctx context.Context, session flows.Session, event flows.Event) {
+	s.sessions = append(s.sessions, session)
+	s.events = append(s.events, event)
+}
+
+func TestEventSink(t *testing.T) {
+	assetsJSON := []byte(`{
+		"flows": [
+			{
+				"uuid": "1b462ce8-983a-4393-b133-e15a0efdb70c",
+				"name": "Survey",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "dd8d3c2b-9e7b-4a7e-8e0a-b5b0a3c3bb8f",
+						"actions": [
+							{"uuid": "8eebd020-1af5-431c-b943-aa670fc74da9", "type": "send_msg", "text": "What's your name?"}
+						],
+						"router": {
+							"type": "switch",
+							"wait": {"type": "msg"},
+							"categories": [
+								{"uuid": "5ea2ef4c-c2d4-4a27-a3ff-e6d5b1b0b5ea", "name": "All Responses", "exit_uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}
+							],
+							"operand": "@input.text",
+							"default_category_uuid": "5ea2ef4c-c2d4-4a27-a3ff-e6d5b1b0b5ea"
+						},
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a", "destination_uuid": "0d3dc5b5-7c8a-4b2b-9a3b-0e6a1cbd3c5e"}]
+					},
+					{
+						"uuid": "0d3dc5b5-7c8a-4b2b-9a3b-0e6a1cbd3c5e",
+						"actions": [
+							{"uuid": "ab6e3b63-5fd3-4c3b-8c5d-6a0e7b5a3f24", "type": "send_msg", "text": "Thanks @input.text"}
+						],
+						"exits": [{"uuid": "6c2e7e4b-1d9d-4b5e-a0a2-1f0b1c9f5b8e"}]
+					}
+				]
+			}
+		]
+	}`)
+
+	sa, err := test.CreateSessionAssets(assetsJSON, "")
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+	trigger := triggers.NewBuilder(env, assets.NewFlowReference("1b462ce8-983a-4393-b133-e15a0efdb70c", "Survey"), contact).Manual().Build()
+
+	sink := &testEventSink{}
+	eng := engine.NewBuilder().WithEventSink(sink).Build()
+
+	assert.Equal(t, sink, eng.EventSink())
+
+	session, sprint, err := eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	// sink receives the same events as the sprint, in the same order
+	assert.Equal(t, []string{"msg_created", "msg_wait"}, eventTypes(sink.events))
+	assert.Equal(t, sprint.Events(), sink.events)
+	assert.Equal(t, []flows.Session{session, session}, sink.sessions)
+
+	sink.events = nil
+
+	msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), "tel:+12065551212", nil, "Bob", nil)
+	sprint, err = session.Resume(context.Background(), resumes.NewMsg(env, contact, msg))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"msg_received", "msg_created"}, eventTypes(sink.events))
+	assert.Equal(t, sprint.Events(), sink.events)
+}
+
+// records events as they were when they were received
+type jsonEventSink struct {
+	events []string
+}
+
+func (s *jsonEventSink) Receive(ctx context.Context, session flows.Session, event flows.Event) {
+	s.events = append(s.events, string(jsonx.MustMarshal(event)))
+}
+
+func TestEventSinkWithUSSDPrompts(t *testing.T) {
+	env := envs.NewBuilder().Build()
+	sa, err := test.LoadSessionAssets(env, "../../test/testdata/runner/ussd_menu.json")
+	require.NoError(t, err)
+
+	contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+	contact.AddURN("tel:+12065551212", nil)
+	trigger := triggers.NewBuilder(env, assets.NewFlowReference("5d8e2f1a-3b4c-4d6e-8f7a-9b0c1d2e3f4a", "USSD Menu"), contact).Manual().Build()
+
+	sink := &jsonEventSink{}
+	_, sprint, err := engine.NewBuilder().WithEventSink(sink).Build().NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	// the prompt is paginated before it's logged, so the sink receives it as it's saved
+	require.Len(t, sink.events, len(sprint.Events()))
+	for i, e := range sprint.Events() {
+		assert.JSONEq(t, string(jsonx.MustMarshal(e)), sink.events[i])
+	}
+
+	prompt := sprint.Events()[0].(*events.MsgCreatedEvent)
+	assert.Equal(t, "Welcome Bob! Choose a service:\n1. Check balance\n2. Buy airtime\n99. More", prompt.Msg.Text())
+	assert.Len(t, prompt.MorePages, 2)
+}
+
+func TestInlineGroupQueries(t *testing.T) {
+	assetsJSON := []byte(`{
+		"fields": [{"uuid": "d66a7823-eada-40e5-9a3a-57239d4690bf", "key": "gender", "name": "Gender", "type": "text"}],
+		"groups": [
+			{"uuid": "1e1ce1e1-9288-4504-869e-022d1003c72a", "name": "Females", "query": "gender = F"},
+			{"uuid": "b7cf0d83-f1c9-411c-96fd-c511a4cfa86d", "name": "Testers"}
+		],
+		"flows": [
+			{
+				"uuid": "1b462ce8-983a-4393-b133-e15a0efdb70c",
+				"name": "Segment",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "dd8d3c2b-9e7b-4a7e-8e0a-b5b0a3c3bb8f",
+						"actions": [
+							{"uuid": "8eebd020-1af5-431c-b943-aa670fc74da9", "type": "set_contact_field", "field": {"key": "gender", "name": "Gender"}, "value": "F"}
+						],
+						"router": {
+							"type": "switch",
+							"operand": "@(array())",
+							"result_name": "Segment",
+							"cases": [
+								{"uuid": "9f593e22-7886-4c08-a52f-0e8780504d75", "type": "has_group", "arguments": ["1e1ce1e1-9288-4504-869e-022d1003c72a"], "category_uuid": "97b9451c-2856-475b-af38-32af68100897"},
+								{"uuid": "c2e4b3a1-0b8a-4c6d-9e5f-1a2b3c4d5e6f", "type": "has_group", "arguments": ["b7cf0d83-f1c9-411c-96fd-c511a4cfa86d"], "category_uuid": "97b9451c-2856-475b-af38-32af68100897"}
+							],
+							"categories": [
+								{"uuid": "97b9451c-2856-475b-af38-32af68100897", "name": "Target", "exit_uuid": "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d"},
+								{"uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3", "name": "Other", "exit_uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}
+							],
+							"default_category_uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3"
+						},
+						"exits": [
+							{"uuid": "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d"},
+							{"uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}
+						]
+					}
+				]
+			}
+		]
+	}`)
+
+	sa, err := test.CreateSessionAssets(assetsJSON, "")
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+	trigger := triggers.NewBuilder(env, assets.NewFlowReference("1b462ce8-983a-4393-b133-e15a0efdb70c", "Segment"), contact).Manual().Build()
+
+	// by default, group tests only look at the groups they're given
+	session, _, err := engine.NewBuilder().Build().NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+	assert.Equal(t, "Other", session.Runs()[0].Results().Get("segment").Category)
+
+	// with inline group queries, a query based group is checked against the contact as it is after the field change
+	session, _, err = engine.NewBuilder().WithInlineGroupQueries(true).Build().NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	result := session.Runs()[0].Results().Get("segment")
+	assert.Equal(t, "Target", result.Category)
+	assert.Equal(t, `{name: Females, uuid: 1e1ce1e1-9288-4504-869e-022d1003c72a}`, result.Value)
+}
+
+func TestSprintDiff(t *testing.T) {
+	assetsJSON := []byte(`{
+		"flows": [
+			{
+				"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+				"name": "Registration",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+						"actions": [{"uuid": "ad154980-7bf7-4ab8-8728-545fd6378912", "type": "set_contact_name", "name": "Ann"}],
+						"exits": [{"uuid": "3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b", "destination_uuid": "b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e"}]
+					},
+					{
+						"uuid": "b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e",
+						"router": {
+							"type": "switch",
+							"wait": {"type": "msg"},
+							"operand": "@input.text",
+							"result_name": "Surname",
+							"categories": [{"uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3", "name": "All Responses", "exit_uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}],
+							"default_category_uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3"
+						},
+						"exits": [{"uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d", "destination_uuid": "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b"}]
+					},
+					{
+						"uuid": "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b",
+						"actions": [{"uuid": "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f", "type": "set_contact_name", "name": "Ann @results.surname"}],
+						"exits": [{"uuid": "9688d21d-95aa-4bed-afc7-f31b35731a3d"}]
+					}
+				]
+			}
+		]
+	}`)
+
+	// sprints don't record diffs unless the engine is configured to
+	_, _, sprint := test.NewSessionBuilder().WithAssetsJSON(assetsJSON).WithFlow("8ca44c09-791d-453a-9799-a70dd3303306").MustBuild()
+	assert.Nil(t, sprint.Diff())
+
+	eng := engine.NewBuilder().WithSessionDiffs(true).Build()
+
+	_, session, sprint := test.NewSessionBuilder().WithEngine(eng).WithAssetsJSON(assetsJSON).WithFlow("8ca44c09-791d-453a-9799-a70dd3303306").MustBuild()
+	run := session.Runs()[0]
+
+	// starting a session reports everything as new
+	diff := sprint.Diff()
+	assert.True(t, diff.New)
+	assert.Equal(t, flows.SessionStatusWaiting, diff.Status)
+	assert.Equal(t, flows.SessionStatus(""), diff.PreviousStatus)
+	assert.Len(t, diff.Runs, 1)
+	assert.True(t, diff.Runs[0].New)
+	assert.Equal(t, run.UUID(), diff.Runs[0].UUID)
+	assert.Equal(t, flows.RunStatusWaiting, diff.Runs[0].Status)
+	assert.Len(t, diff.Runs[0].Steps, 2)
+	assert.Equal(t, "Ann", *diff.Contact.Name)
+
+	msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), "tel:+12065551212", nil, "Smith", nil)
+	sprint, err := session.Resume(context.Background(), resumes.NewMsg(nil, nil, msg))
+	require.NoError(t, err)
+
+	// resuming reports only what changed, including the waiting step which was exited
+	diff = sprint.Diff()
+	assert.False(t, diff.New)
+	assert.Equal(t, flows.SessionStatusCompleted, diff.Status)
+	assert.Equal(t, flows.SessionStatusWaiting, diff.PreviousStatus)
+	assert.Len(t, diff.Runs, 1)
+	assert.False(t, diff.Runs[0].New)
+	assert.Equal(t, flows.RunStatusCompleted, diff.Runs[0].Status)
+	assert.Equal(t, flows.RunStatusWaiting, diff.Runs[0].PreviousStatus)
+	assert.Equal(t, []string{"surname"}, maps.Keys(diff.Runs[0].Results))
+	assert.Equal(t, "Smith", diff.Runs[0].Results["surname"].Value)
+	assert.Equal(t, run.Path()[1:], diff.Runs[0].Steps)
+	assert.Equal(t, "Ann Smith", *diff.Contact.Name)
+
+	// and the diff can be marshaled so callers can persist it
+	diffJSON := jsonx.MustMarshal(diff)
+	assert.Contains(t, string(diffJSON), `"status":"completed","previous_status":"waiting"`)
+
+	// sprints not created by the engine have no diff
+	assert.Nil(t, engine.NewSprint(nil, nil, nil).Diff())
+}
+
+func TestInputValidation(t *testing.T) {
+	source, err := static.NewSource([]byte(`{
+		"flows": [
+			{
+				"uuid": "5472a1c3-63e1-484f-8485-cc8ecb16a058",
+				"name": "Age",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f01",
+						"actions": [
+							{"uuid": "e97cd6d5-3354-4dbd-85bc-6c1f87849eec", "type": "send_msg", "text": "How old are you?"}
+						],
+						"router": {
+							"type": "switch",
+							"wait": {
+								"type": "msg",
+								"validation": {"test": "@(has_number(input.text))", "message": "Sorry, @input.text isn't a number", "max_attempts": 2}
+							},
+							"operand": "@input.text",
+							"result_name": "Age",
+							"cases": [
+								{"uuid": "1e4d5f6a-7b8c-4d9e-8f0a-1b2c3d4e5f6a", "type": "has_number", "arguments": [], "category_uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1"}
+							],
+							"categories": [
+								{"uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1", "name": "Number", "exit_uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"},
+								{"uuid": "9c1d3e5f-7a2b-4c6d-8e0f-1a3b5c7d9e2f", "name": "Other", "exit_uuid": "0e2f4a6b-8c1d-4e3f-9a5b-7c9d1e3f5a7b"},
+								{"uuid": "3e5f7a9b-1c2d-4e6f-8a0b-2c4d6e8f0a1b", "name": "Invalid", "exit_uuid": "7a9b1c3d-5e2f-4a4b-8c6d-8e0f2a4b6c8d"}
+							],
+							"default_category_uuid": "9c1d3e5f-7a2b-4c6d-8e0f-1a3b5c7d9e2f",
+							"invalid_input_category_uuid": "3e5f7a9b-1c2d-4e6f-8a0b-2c4d6e8f0a1b"
+						},
+						"exits": [
+							{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"},
+							{"uuid": "0e2f4a6b-8c1d-4e3f-9a5b-7c9d1e3f5a7b"},
+							{"uuid": "7a9b1c3d-5e2f-4a4b-8c6d-8e0f2a4b6c8d"}
+						]
+					}
+				]
+			}
+		]
+	}`))
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	eng := engine.NewBuilder().Build()
+
+	startSession := func() flows.Session {
+		contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+		contact.AddURN("tel:+12065551212", nil)
+		trigger := triggers.NewBuilder(env, assets.NewFlowReference("5472a1c3-63e1-484f-8485-cc8ecb16a058", "Age"), contact).Manual().Build()
+
+		session, _, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+		return session
+	}
+
+	resume := func(session flows.Session, text string) flows.Sprint {
+		msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), "tel:+12065551212", nil, text, nil)
+		sprint, err := session.Resume(context.Background(), resumes.NewMsg(nil, nil, msg))
+		require.NoError(t, err)
+		return sprint
+	}
+
+	// invalid input gets the error message and the session keeps waiting
+	session := startSession()
+	sprint := resume(session, "old enough")
+	assert.Equal(t, flows.SessionStatusWaiting, session.Status())
+	assert.Equal(t, []string{"msg_received", "msg_created", "msg_wait"}, eventTypes(sprint.Events()))
+	assert.Equal(t, "Sorry, old enough isn't a number", sprint.Events()[1].(*events.MsgCreatedEvent).Msg.Text())
+	assert.Equal(t, 1, sprint.Events()[2].(*events.MsgWaitEvent).InvalidAttempts)
+	assert.Nil(t, session.Runs()[0].Results().Get("age"))
+
+	// valid input is routed as normal
+	resume(session, "I'm 34")
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, "Number", session.Runs()[0].Results().Get("age").Category)
+
+	// once the contact is out of attempts, input is routed to the invalid input category
+	session = startSession()
+	resume(session, "old enough")
+	sprint = resume(session, "very old")
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, []string{"msg_received", "run_result_changed"}, eventTypes(sprint.Events()))
+	assert.Equal(t, "Invalid", session.Runs()[0].Results().Get("age").Category)
+	assert.Equal(t, "very old", session.Runs()[0].Results().Get("age").Value)
+}
+
+type testRunArchive struct {
+	runs  map[flows.RunUUID]json.RawMessage
+	loads int
+}
+
+func (a *testRunArchive) StoreRuns(session flows.SessionUUID, runs map[flows.RunUUID]json.RawMessage) error {
+	for uuid, data := range runs {
+		a.runs[uuid] = data
+	}
+	return nil
+}
+
+func (a *testRunArchive) LoadRun(session flows.SessionUUID, uuid flows.RunUUID) (json.RawMessage, error) {
+	a.loads++
+	data, exists := a.runs[uuid]
+	if !exists {
+		return nil, fmt.Errorf("no such run %s", uuid)
+	}
+	return data, nil
+}
+
+func TestRunArchive(t *testing.T) {
+	source, err := static.NewSource([]byte(`{
+		"flows": [
+			{
+				"uuid": "5472a1c3-63e1-484f-8485-cc8ecb16a058",
+				"name": "Parent",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f01",
+						"actions": [
+							{"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a", "type": "enter_flow", "flow": {"uuid": "a1b2a1c3-63e1-484f-8485-cc8ecb16a058", "name": "Child"}, "terminal": false}
+						],
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a", "destination_uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f02"}]
+					},
+					{
+						"uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f02",
+						"router": {
+							"type": "switch",
+							"wait": {"type": "msg"},
+							"operand": "@input.text",
+							"categories": [
+								{"uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1", "name": "All", "exit_uuid": "3b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}
+							],
+							"default_category_uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1"
+						},
+						"exits": [{"uuid": "3b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a", "destination_uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f03"}]
+					},
+					{
+						"uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f03",
+						"actions": [
+							{"uuid": "1a8467eb-911a-41db-8101-ccf415c48e6a", "type": "send_msg", "text": "You said @child.results.color"}
+						],
+						"exits": [{"uuid": "4b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}]
+					}
+				]
+			},
+			{
+				"uuid": "a1b2a1c3-63e1-484f-8485-cc8ecb16a058",
+				"name": "Child",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "9f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f01",
+						"actions": [
+							{"uuid": "2a8467eb-911a-41db-8101-ccf415c48e6a", "type": "set_run_result", "name": "Color", "value": "blue"}
+						],
+						"exits": [{"uuid": "5b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}]
+					}
+				]
+			}
+		]
+	}`))
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().Build()
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	archive := &testRunArchive{runs: make(map[flows.RunUUID]json.RawMessage)}
+	eng := engine.NewBuilder().WithRunArchive(archive, 0).Build()
+
+	contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+	trigger := triggers.NewBuilder(env, assets.NewFlowReference("5472a1c3-63e1-484f-8485-cc8ecb16a058", "Parent"), contact).Manual().Build()
+
+	session, _, err := eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	// the child run has ended so is moved to the archive, leaving only the waiting parent run in the session
+	require.Len(t, session.Runs(), 1)
+	assert.Equal(t, flows.RunStatusWaiting, session.Runs()[0].Status())
+	require.Len(t, session.ArchivedRuns(), 1)
+	childUUID := session.ArchivedRuns()[0].UUID
+	assert.Equal(t, session.Runs()[0].UUID(), session.ArchivedRuns()[0].ParentUUID)
+	assert.Equal(t, "Child", session.ArchivedRuns()[0].Flow.Name)
+	assert.Equal(t, flows.RunStatusCompleted, session.ArchivedRuns()[0].Status)
+	assert.Contains(t, archive.runs, childUUID)
+
+	// archived runs are kept in the marshaled session
+	sessionJSON := jsonx.MustMarshal(session)
+	assert.NotContains(t, string(sessionJSON), `"Color"`)
+
+	session, err = eng.ReadSession(sa, sessionJSON, assets.PanicOnMissing)
+	require.NoError(t, err)
+	require.Len(t, session.ArchivedRuns(), 1)
+	assert.Equal(t, 0, archive.loads)
+
+	// and only loaded when they're needed
+	msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), "tel:+12065551212", nil, "Hi", nil)
+	sprint, err := session.Resume(context.Background(), resumes.NewMsg(nil, nil, msg))
+	require.NoError(t, err)
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, 1, archive.loads)
+
+	var msgCreated *events.MsgCreatedEvent
+	for _, e := range sprint.Events() {
+		if typed, isMsg := e.(*events.MsgCreatedEvent); isMsg {
+			msgCreated = typed
+		}
+	}
+	require.NotNil(t, msgCreated)
+	assert.Equal(t, "You said blue", msgCreated.Msg.Text())
+
+	// the parent has now ended too so is also archived
+	assert.Len(t, session.Runs(), 0)
+	assert.Len(t, session.ArchivedRuns(), 2)
+
+	// an engine without an archive can still read the session but can't load archived runs
+	session, err = engine.NewBuilder().Build().ReadSession(sa, sessionJSON, assets.PanicOnMissing)
+	require.NoError(t, err)
+	_, err = session.GetRun(childUUID)
+	assert.EqualError(t, err, fmt.Sprintf("unable to load archived run with UUID '%s' without a run archive", childUUID))
+}
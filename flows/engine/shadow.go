@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nyaruka/gocommon/random"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/resumes"
+)
+
+// DraftProvider returns the draft revision of the flow with the given UUID, or nil if it doesn't have one
+type DraftProvider func(flows.SessionAssets, assets.FlowUUID) flows.Flow
+
+// ShadowRun is a sprint of a session run against the draft revisions of flows, with the same trigger or resume as a
+// sprint of a session run against their published revisions. Resume is nil if the sprint started the session, and
+// otherwise is a copy of the resume of the sprint being shadowed.
+type ShadowRun struct {
+	Trigger flows.Trigger
+	Resume  flows.Resume
+	Session flows.Session
+	Sprint  flows.Sprint
+	Err     error
+}
+
+// ShadowSink receives shadow runs once their sprint has finished
+type ShadowSink func(context.Context, *ShadowRun)
+
+// the configuration of shadow runs, applied to the engine and each of its profiles when it's built
+type shadowConfig struct {
+	drafts        DraftProvider
+	rate          float64
+	sink          ShadowSink
+	configure     func(*Builder)
+	maxConcurrent int
+	timeout       time.Duration
+}
+
+// the shadowing of an engine's sessions by sessions of another engine which runs draft flows
+type shadowing struct {
+	engine    *engine
+	lifecycle *lifecycle
+	drafts    DraftProvider
+	rate      float64
+	sink      ShadowSink
+	timeout   time.Duration
+	slots     chan struct{}
+}
+
+// creates the shadowing of the given engine, whose shadow engine is a copy without anything which could have side
+// effects, and whose services replay the results of the calls made by the sprints being shadowed
+func newShadowing(e *engine, config *shadowConfig) *shadowing {
+	shadow := e.clone()
+	shadow.services = e.services.replaying()
+	shadow.eventSink = nil
+	shadow.actionHooks = nil
+	shadow.runArchive = nil
+	shadow.base = nil
+	shadow.profiles = nil
+	shadow.shadowing = nil
+	shadow.lifecycle = newLifecycle()
+
+	if config.configure != nil {
+		config.configure(&Builder{eng: shadow})
+	}
+
+	return &shadowing{
+		engine:    shadow,
+		lifecycle: e.lifecycle,
+		drafts:    config.drafts,
+		rate:      config.rate,
+		sink:      config.sink,
+		timeout:   config.timeout,
+		slots:     make(chan struct{}, config.maxConcurrent),
+	}
+}
+
+// decides whether a sprint involving the given flows should be shadowed, which requires one of them to have a draft,
+// and if so returns a context which records the service calls made by the sprint
+func (s *shadowing) sample(ctx context.Context, sa flows.SessionAssets, flowUUIDs ...assets.FlowUUID) (context.Context, *serviceRecording) {
+	for _, flowUUID := range flowUUIDs {
+		if s.drafts(sa, flowUUID) != nil {
+			if random.Float64() >= s.rate {
+				return ctx, nil
+			}
+			recording := newServiceRecording()
+			return withServiceRecording(ctx, recording), recording
+		}
+	}
+	return ctx, nil
+}
+
+// starts a shadow session with the given trigger
+func (s *shadowing) start(sa flows.SessionAssets, trigger flows.Trigger, recording *serviceRecording) {
+	s.run(recording, func(ctx context.Context) *ShadowRun {
+		session, sprint, err := s.engine.NewSession(ctx, s.draftAssets(sa), trigger)
+
+		return &ShadowRun{Trigger: trigger, Session: session, Sprint: sprint, Err: err}
+	})
+}
+
+// resumes a shadow copy of a session, read from the given marshaling of it before it was resumed, with a copy of the
+// resume read from the given marshaling of it, so that the shadow shares no state, e.g. the resume's contact, with the
+// session being shadowed
+func (s *shadowing) resume(sa flows.SessionAssets, trigger flows.Trigger, data, resumeData json.RawMessage, err error, recording *serviceRecording) {
+	s.run(recording, func(ctx context.Context) *ShadowRun {
+		if err != nil {
+			return &ShadowRun{Trigger: trigger, Err: err}
+		}
+
+		draftAssets := s.draftAssets(sa)
+
+		resume, err := resumes.ReadResume(draftAssets, resumeData, assets.IgnoreMissing)
+		if err != nil {
+			return &ShadowRun{Trigger: trigger, Err: err}
+		}
+
+		session, err := s.engine.ReadSession(draftAssets, data, assets.IgnoreMissing)
+		if err != nil {
+			return &ShadowRun{Trigger: trigger, Resume: resume, Err: err}
+		}
+
+		sprint, err := session.Resume(ctx, resume)
+
+		return &ShadowRun{Trigger: trigger, Resume: resume, Session: session, Sprint: sprint, Err: err}
+	})
+}
+
+// runs a shadow sprint in the background, unless the max number of shadow sprints are already running in which case
+// it's dropped. Sprints are run with their own timeout as the context of the sprint being shadowed will usually be done
+// by then, and stopping the engine of the sprint being shadowed waits for them to finish.
+func (s *shadowing) run(recording *serviceRecording, fn func(context.Context) *ShadowRun) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return
+	}
+
+	s.lifecycle.goBackground(func() {
+		defer func() { <-s.slots }()
+
+		ctx, cancel := context.WithTimeout(withServiceRecording(context.Background(), recording), s.timeout)
+		defer cancel()
+
+		s.sink(ctx, fn(ctx))
+	})
+}
+
+func (s *shadowing) draftAssets(sa flows.SessionAssets) flows.SessionAssets {
+	return &draftAssets{SessionAssets: sa, flows: &draftFlowAssets{FlowAssets: sa.Flows(), sa: sa, drafts: s.drafts}}
+}
+
+// session assets whose flows are replaced by their drafts where they have them
+type draftAssets struct {
+	flows.SessionAssets
+
+	flows flows.FlowAssets
+}
+
+func (a *draftAssets) Flows() flows.FlowAssets { return a.flows }
+
+type draftFlowAssets struct {
+	flows.FlowAssets
+
+	sa     flows.SessionAssets
+	drafts DraftProvider
+}
+
+func (a *draftFlowAssets) Get(uuid assets.FlowUUID) (flows.Flow, error) {
+	if draft := a.drafts(a.sa, uuid); draft != nil {
+		return draft, nil
+	}
+	return a.FlowAssets.Get(uuid)
+}
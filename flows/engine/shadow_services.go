@@ -0,0 +1,557 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// the result of a call to a service
+type serviceResult struct {
+	value any
+	err   error
+}
+
+// the results of the service calls made in a sprint, keyed by service, method and arguments, so that they can be
+// replayed to its shadow sprint
+type serviceRecording struct {
+	mutex   sync.Mutex
+	results map[string][]serviceResult
+}
+
+func newServiceRecording() *serviceRecording {
+	return &serviceRecording{results: make(map[string][]serviceResult)}
+}
+
+func (r *serviceRecording) add(key string, value any, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.results[key] = append(r.results[key], serviceResult{value: value, err: err})
+}
+
+// takes the oldest result recorded for the given key
+func (r *serviceRecording) take(key string) (serviceResult, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	results := r.results[key]
+	if len(results) == 0 {
+		return serviceResult{}, false
+	}
+	r.results[key] = results[1:]
+	return results[0], true
+}
+
+type serviceRecordingKey struct{}
+
+func withServiceRecording(ctx context.Context, r *serviceRecording) context.Context {
+	return context.WithValue(ctx, serviceRecordingKey{}, r)
+}
+
+func serviceRecordingFrom(ctx context.Context) *serviceRecording {
+	r, _ := ctx.Value(serviceRecordingKey{}).(*serviceRecording)
+	return r
+}
+
+// makes a call to a real service, recording its result if the context has a recording
+func recordCall[T any](ctx context.Context, key string, call func() (T, error)) (T, error) {
+	value, err := call()
+	if r := serviceRecordingFrom(ctx); r != nil {
+		r.add(key, value, err)
+	}
+	return value, err
+}
+
+// replays the result of a call recorded in the context, or errors if no such call was recorded
+func replayCall[T any](ctx context.Context, key string) (T, error) {
+	var zero T
+
+	r := serviceRecordingFrom(ctx)
+	if r == nil {
+		return zero, errors.Errorf("no recorded service calls to replay for %s", key)
+	}
+	result, ok := r.take(key)
+	if !ok {
+		return zero, errors.Errorf("no matching service call was made by the primary sprint for %s", key)
+	}
+	if result.value == nil {
+		return zero, result.err
+	}
+	return result.value.(T), result.err
+}
+
+// returns a copy of these services whose services record the results of their calls when made with a context which
+// has a recording
+func (s *services) recording() *services {
+	c := s.clone()
+
+	email := s.email
+	c.email = func(sa flows.SessionAssets) (flows.EmailService, error) {
+		svc, err := email(sa)
+		if err != nil || svc == nil {
+			return svc, err
+		}
+		if rich, isRich := svc.(flows.RichEmailService); isRich {
+			return &recordingRichEmail{recordingEmail{rich}, rich}, nil
+		}
+		return &recordingEmail{svc}, nil
+	}
+
+	c.webhook = recordingWebhookFactory(s.webhook)
+	for i, route := range s.webhookRoutes {
+		c.webhookRoutes[i] = &webhookRoute{pattern: route.pattern, factory: recordingWebhookFactory(route.factory)}
+	}
+
+	classification := s.classification
+	c.classification = func(classifier *flows.Classifier) (flows.ClassificationService, error) {
+		svc, err := classification(classifier)
+		if err != nil || svc == nil {
+			return svc, err
+		}
+		return &recordingClassification{svc, classifier}, nil
+	}
+
+	ticket := s.ticket
+	c.ticket = func(ticketer *flows.Ticketer) (flows.TicketService, error) {
+		svc, err := ticket(ticketer)
+		if err != nil || svc == nil {
+			return svc, err
+		}
+		return &recordingTicket{svc, ticketer}, nil
+	}
+
+	airtime := s.airtime
+	c.airtime = func(sa flows.SessionAssets) (flows.AirtimeService, error) {
+		svc, err := airtime(sa)
+		if err != nil || svc == nil {
+			return svc, err
+		}
+		return &recordingAirtime{svc}, nil
+	}
+
+	dataCollection := s.dataCollection
+	c.dataCollection = func(sa flows.SessionAssets) (flows.DataCollectionService, error) {
+		svc, err := dataCollection(sa)
+		if err != nil || svc == nil {
+			return svc, err
+		}
+		return &recordingDataCollection{svc}, nil
+	}
+
+	commerce := s.commerce
+	c.commerce = func(sa flows.SessionAssets) (flows.CommerceService, error) {
+		svc, err := commerce(sa)
+		if err != nil || svc == nil {
+			return svc, err
+		}
+		return &recordingCommerce{svc}, nil
+	}
+
+	callRecording := s.callRecording
+	c.callRecording = func(sa flows.SessionAssets) (flows.CallRecordingService, error) {
+		svc, err := callRecording(sa)
+		if err != nil || svc == nil {
+			return svc, err
+		}
+		return &recordingCallRecording{svc}, nil
+	}
+
+	callTransfer := s.callTransfer
+	c.callTransfer = func(sa flows.SessionAssets) (flows.CallTransferService, error) {
+		svc, err := callTransfer(sa)
+		if err != nil || svc == nil {
+			return svc, err
+		}
+		return &recordingCallTransfer{svc}, nil
+	}
+
+	credential := s.credential
+	c.credential = func(sa flows.SessionAssets) (flows.CredentialService, error) {
+		svc, err := credential(sa)
+		if err != nil || svc == nil {
+			return svc, err
+		}
+		return &recordingCredential{svc}, nil
+	}
+
+	exchangeRate := s.exchangeRate
+	c.exchangeRate = func(sa flows.SessionAssets) (flows.ExchangeRateService, error) {
+		svc, err := exchangeRate(sa)
+		if err != nil || svc == nil {
+			return svc, err
+		}
+		return &recordingExchangeRate{svc}, nil
+	}
+
+	attachment := s.attachment
+	c.attachment = func(sa flows.SessionAssets) (flows.AttachmentService, error) {
+		svc, err := attachment(sa)
+		if err != nil || svc == nil {
+			return svc, err
+		}
+		return &recordingAttachment{svc}, nil
+	}
+
+	return c
+}
+
+func recordingWebhookFactory(f WebhookServiceFactory) WebhookServiceFactory {
+	return func(sa flows.SessionAssets) (flows.WebhookService, error) {
+		svc, err := f(sa)
+		if err != nil || svc == nil {
+			return svc, err
+		}
+		return &recordingWebhook{svc}, nil
+	}
+}
+
+// returns services which are configured like these but whose services replay the results of the calls recorded in the
+// context they're called with instead of calling anything, and which don't store HTTP logs
+func (s *services) replaying() *services {
+	c := newEmptyServices()
+	c.groupMembership = s.groupMembership
+
+	for service, configured := range s.configured {
+		if !configured {
+			continue
+		}
+		c.configured[service] = true
+
+		switch service {
+		case flows.ServiceTypeEmail:
+			c.email = func(flows.SessionAssets) (flows.EmailService, error) { return &replayingEmail{}, nil }
+		case flows.ServiceTypeWebhook:
+			c.webhook = func(flows.SessionAssets) (flows.WebhookService, error) { return &replayingWebhook{}, nil }
+		case flows.ServiceTypeClassification:
+			c.classification = func(classifier *flows.Classifier) (flows.ClassificationService, error) {
+				return &replayingClassification{classifier}, nil
+			}
+		case flows.ServiceTypeTicket:
+			c.ticket = func(ticketer *flows.Ticketer) (flows.TicketService, error) { return &replayingTicket{ticketer}, nil }
+		case flows.ServiceTypeAirtime:
+			c.airtime = func(flows.SessionAssets) (flows.AirtimeService, error) { return &replayingAirtime{}, nil }
+		case flows.ServiceTypeDataCollection:
+			c.dataCollection = func(flows.SessionAssets) (flows.DataCollectionService, error) { return &replayingDataCollection{}, nil }
+		case flows.ServiceTypeCommerce:
+			c.commerce = func(flows.SessionAssets) (flows.CommerceService, error) { return &replayingCommerce{}, nil }
+		case flows.ServiceTypeCallRecording:
+			c.callRecording = func(flows.SessionAssets) (flows.CallRecordingService, error) { return &replayingCallRecording{}, nil }
+		case flows.ServiceTypeCallTransfer:
+			c.callTransfer = func(flows.SessionAssets) (flows.CallTransferService, error) { return &replayingCallTransfer{}, nil }
+		case flows.ServiceTypeCredential:
+			c.credential = func(flows.SessionAssets) (flows.CredentialService, error) { return &replayingCredential{}, nil }
+		case flows.ServiceTypeExchangeRate:
+			c.exchangeRate = func(flows.SessionAssets) (flows.ExchangeRateService, error) { return &replayingExchangeRate{}, nil }
+		case flows.ServiceTypeAttachment:
+			c.attachment = func(flows.SessionAssets) (flows.AttachmentService, error) { return &replayingAttachment{}, nil }
+		}
+	}
+
+	return c
+}
+
+// the keys which calls are recorded with, which include the arguments that select what's being called
+func emailKey() string                             { return "email" }
+func webhookKey(r *http.Request) string            { return fmt.Sprintf("webhook %s %s", r.Method, r.URL) }
+func classificationKey(c *flows.Classifier) string { return fmt.Sprintf("classification %s", c.UUID()) }
+func ticketKey(t *flows.Ticketer) string           { return fmt.Sprintf("ticket %s", t.UUID()) }
+func airtimeTransferKey(recipient urns.URN) string {
+	return fmt.Sprintf("airtime transfer %s", recipient)
+}
+func airtimeBalanceKey() string { return "airtime balance" }
+func dataCollectionKey(collection string) string {
+	return fmt.Sprintf("data collection %s", collection)
+}
+func commerceLookupKey(productID string) string  { return fmt.Sprintf("commerce lookup %s", productID) }
+func commerceOrderKey() string                   { return "commerce order" }
+func callRecordingStartKey() string              { return "call recording start" }
+func callRecordingStopKey() string               { return "call recording stop" }
+func callTransferForwardKey(urn urns.URN) string { return fmt.Sprintf("call transfer forward %s", urn) }
+func callTransferConferenceKey(name string) string {
+	return fmt.Sprintf("call transfer conference %s", name)
+}
+func credentialKey(name string) string       { return fmt.Sprintf("credential %s", name) }
+func exchangeRateKey(from, to string) string { return fmt.Sprintf("exchange rate %s %s", from, to) }
+func attachmentKey(attachment utils.Attachment) string {
+	return fmt.Sprintf("attachment %s", attachment)
+}
+
+//------------------------------------------------------------------------------------------
+// Recording services
+//------------------------------------------------------------------------------------------
+
+type recordingEmail struct {
+	base flows.EmailService
+}
+
+func (s *recordingEmail) Send(ctx context.Context, addresses []string, subject, body string) error {
+	_, err := recordCall(ctx, emailKey(), func() (struct{}, error) { return struct{}{}, s.base.Send(ctx, addresses, subject, body) })
+	return err
+}
+
+type recordingRichEmail struct {
+	recordingEmail
+	rich flows.RichEmailService
+}
+
+func (s *recordingRichEmail) SendEmail(ctx context.Context, email *flows.Email) error {
+	_, err := recordCall(ctx, emailKey(), func() (struct{}, error) { return struct{}{}, s.rich.SendEmail(ctx, email) })
+	return err
+}
+
+type recordingWebhook struct {
+	base flows.WebhookService
+}
+
+func (s *recordingWebhook) Call(request *http.Request) (*flows.WebhookCall, error) {
+	return recordCall(request.Context(), webhookKey(request), func() (*flows.WebhookCall, error) { return s.base.Call(request) })
+}
+
+type recordingClassification struct {
+	base       flows.ClassificationService
+	classifier *flows.Classifier
+}
+
+func (s *recordingClassification) Classify(ctx context.Context, env envs.Environment, input string, logHTTP flows.HTTPLogCallback) (*flows.Classification, error) {
+	return recordCall(ctx, classificationKey(s.classifier), func() (*flows.Classification, error) {
+		return s.base.Classify(ctx, env, input, logHTTP)
+	})
+}
+
+type recordingTicket struct {
+	base     flows.TicketService
+	ticketer *flows.Ticketer
+}
+
+func (s *recordingTicket) Open(ctx context.Context, env envs.Environment, contact *flows.Contact, topic *flows.Topic, body string, assignee *flows.User, logHTTP flows.HTTPLogCallback) (*flows.Ticket, error) {
+	return recordCall(ctx, ticketKey(s.ticketer), func() (*flows.Ticket, error) {
+		return s.base.Open(ctx, env, contact, topic, body, assignee, logHTTP)
+	})
+}
+
+type recordingAirtime struct {
+	base flows.AirtimeService
+}
+
+func (s *recordingAirtime) Transfer(ctx context.Context, sender urns.URN, recipient urns.URN, amounts map[string]decimal.Decimal, logHTTP flows.HTTPLogCallback) (*flows.AirtimeTransfer, error) {
+	return recordCall(ctx, airtimeTransferKey(recipient), func() (*flows.AirtimeTransfer, error) {
+		return s.base.Transfer(ctx, sender, recipient, amounts, logHTTP)
+	})
+}
+
+func (s *recordingAirtime) Balance(ctx context.Context, logHTTP flows.HTTPLogCallback) (map[string]decimal.Decimal, error) {
+	return recordCall(ctx, airtimeBalanceKey(), func() (map[string]decimal.Decimal, error) { return s.base.Balance(ctx, logHTTP) })
+}
+
+type recordingDataCollection struct {
+	base flows.DataCollectionService
+}
+
+func (s *recordingDataCollection) Query(ctx context.Context, env envs.Environment, collection string, query *flows.DataQuery, logHTTP flows.HTTPLogCallback) (*flows.DataPage, error) {
+	return recordCall(ctx, dataCollectionKey(collection), func() (*flows.DataPage, error) {
+		return s.base.Query(ctx, env, collection, query, logHTTP)
+	})
+}
+
+type recordingCommerce struct {
+	base flows.CommerceService
+}
+
+func (s *recordingCommerce) LookupProduct(ctx context.Context, env envs.Environment, productID string, logHTTP flows.HTTPLogCallback) (*flows.Product, error) {
+	return recordCall(ctx, commerceLookupKey(productID), func() (*flows.Product, error) {
+		return s.base.LookupProduct(ctx, env, productID, logHTTP)
+	})
+}
+
+func (s *recordingCommerce) PlaceOrder(ctx context.Context, env envs.Environment, contact *flows.Contact, order *flows.Order, logHTTP flows.HTTPLogCallback) (string, error) {
+	return recordCall(ctx, commerceOrderKey(), func() (string, error) { return s.base.PlaceOrder(ctx, env, contact, order, logHTTP) })
+}
+
+type recordingCallRecording struct {
+	base flows.CallRecordingService
+}
+
+func (s *recordingCallRecording) StartRecording(ctx context.Context, call *flows.Call) error {
+	_, err := recordCall(ctx, callRecordingStartKey(), func() (struct{}, error) { return struct{}{}, s.base.StartRecording(ctx, call) })
+	return err
+}
+
+func (s *recordingCallRecording) StopRecording(ctx context.Context, call *flows.Call) (string, error) {
+	return recordCall(ctx, callRecordingStopKey(), func() (string, error) { return s.base.StopRecording(ctx, call) })
+}
+
+type recordingCallTransfer struct {
+	base flows.CallTransferService
+}
+
+func (s *recordingCallTransfer) Forward(ctx context.Context, call *flows.Call, urn urns.URN) (*flows.CallTransfer, error) {
+	return recordCall(ctx, callTransferForwardKey(urn), func() (*flows.CallTransfer, error) { return s.base.Forward(ctx, call, urn) })
+}
+
+func (s *recordingCallTransfer) JoinConference(ctx context.Context, call *flows.Call, conference string) (*flows.CallTransfer, error) {
+	return recordCall(ctx, callTransferConferenceKey(conference), func() (*flows.CallTransfer, error) {
+		return s.base.JoinConference(ctx, call, conference)
+	})
+}
+
+type recordingCredential struct {
+	base flows.CredentialService
+}
+
+func (s *recordingCredential) Authorization(ctx context.Context, name string) (string, error) {
+	return recordCall(ctx, credentialKey(name), func() (string, error) { return s.base.Authorization(ctx, name) })
+}
+
+type recordingExchangeRate struct {
+	base flows.ExchangeRateService
+}
+
+func (s *recordingExchangeRate) Rate(ctx context.Context, from, to string, logHTTP flows.HTTPLogCallback) (decimal.Decimal, error) {
+	return recordCall(ctx, exchangeRateKey(from, to), func() (decimal.Decimal, error) { return s.base.Rate(ctx, from, to, logHTTP) })
+}
+
+type recordingAttachment struct {
+	base flows.AttachmentService
+}
+
+func (s *recordingAttachment) Process(ctx context.Context, attachment utils.Attachment) (utils.Attachment, error) {
+	return recordCall(ctx, attachmentKey(attachment), func() (utils.Attachment, error) { return s.base.Process(ctx, attachment) })
+}
+
+//------------------------------------------------------------------------------------------
+// Replaying services
+//------------------------------------------------------------------------------------------
+
+type replayingEmail struct{}
+
+func (s *replayingEmail) Send(ctx context.Context, addresses []string, subject, body string) error {
+	_, err := replayCall[struct{}](ctx, emailKey())
+	return err
+}
+
+func (s *replayingEmail) SendEmail(ctx context.Context, email *flows.Email) error {
+	_, err := replayCall[struct{}](ctx, emailKey())
+	return err
+}
+
+type replayingWebhook struct{}
+
+func (s *replayingWebhook) Call(request *http.Request) (*flows.WebhookCall, error) {
+	return replayCall[*flows.WebhookCall](request.Context(), webhookKey(request))
+}
+
+type replayingClassification struct {
+	classifier *flows.Classifier
+}
+
+func (s *replayingClassification) Classify(ctx context.Context, env envs.Environment, input string, logHTTP flows.HTTPLogCallback) (*flows.Classification, error) {
+	return replayCall[*flows.Classification](ctx, classificationKey(s.classifier))
+}
+
+type replayingTicket struct {
+	ticketer *flows.Ticketer
+}
+
+func (s *replayingTicket) Open(ctx context.Context, env envs.Environment, contact *flows.Contact, topic *flows.Topic, body string, assignee *flows.User, logHTTP flows.HTTPLogCallback) (*flows.Ticket, error) {
+	return replayCall[*flows.Ticket](ctx, ticketKey(s.ticketer))
+}
+
+type replayingAirtime struct{}
+
+func (s *replayingAirtime) Transfer(ctx context.Context, sender urns.URN, recipient urns.URN, amounts map[string]decimal.Decimal, logHTTP flows.HTTPLogCallback) (*flows.AirtimeTransfer, error) {
+	return replayCall[*flows.AirtimeTransfer](ctx, airtimeTransferKey(recipient))
+}
+
+func (s *replayingAirtime) Balance(ctx context.Context, logHTTP flows.HTTPLogCallback) (map[string]decimal.Decimal, error) {
+	return replayCall[map[string]decimal.Decimal](ctx, airtimeBalanceKey())
+}
+
+type replayingDataCollection struct{}
+
+func (s *replayingDataCollection) Query(ctx context.Context, env envs.Environment, collection string, query *flows.DataQuery, logHTTP flows.HTTPLogCallback) (*flows.DataPage, error) {
+	return replayCall[*flows.DataPage](ctx, dataCollectionKey(collection))
+}
+
+type replayingCommerce struct{}
+
+func (s *replayingCommerce) LookupProduct(ctx context.Context, env envs.Environment, productID string, logHTTP flows.HTTPLogCallback) (*flows.Product, error) {
+	return replayCall[*flows.Product](ctx, commerceLookupKey(productID))
+}
+
+func (s *replayingCommerce) PlaceOrder(ctx context.Context, env envs.Environment, contact *flows.Contact, order *flows.Order, logHTTP flows.HTTPLogCallback) (string, error) {
+	return replayCall[string](ctx, commerceOrderKey())
+}
+
+type replayingCallRecording struct{}
+
+func (s *replayingCallRecording) StartRecording(ctx context.Context, call *flows.Call) error {
+	_, err := replayCall[struct{}](ctx, callRecordingStartKey())
+	return err
+}
+
+func (s *replayingCallRecording) StopRecording(ctx context.Context, call *flows.Call) (string, error) {
+	return replayCall[string](ctx, callRecordingStopKey())
+}
+
+type replayingCallTransfer struct{}
+
+func (s *replayingCallTransfer) Forward(ctx context.Context, call *flows.Call, urn urns.URN) (*flows.CallTransfer, error) {
+	return replayCall[*flows.CallTransfer](ctx, callTransferForwardKey(urn))
+}
+
+func (s *replayingCallTransfer) JoinConference(ctx context.Context, call *flows.Call, conference string) (*flows.CallTransfer, error) {
+	return replayCall[*flows.CallTransfer](ctx, callTransferConferenceKey(conference))
+}
+
+type replayingCredential struct{}
+
+func (s *replayingCredential) Authorization(ctx context.Context, name string) (string, error) {
+	return replayCall[string](ctx, credentialKey(name))
+}
+
+type replayingExchangeRate struct{}
+
+func (s *replayingExchangeRate) Rate(ctx context.Context, from, to string, logHTTP flows.HTTPLogCallback) (decimal.Decimal, error) {
+	return replayCall[decimal.Decimal](ctx, exchangeRateKey(from, to))
+}
+
+type replayingAttachment struct{}
+
+func (s *replayingAttachment) Process(ctx context.Context, attachment utils.Attachment) (utils.Attachment, error) {
+	return replayCall[utils.Attachment](ctx, attachmentKey(attachment))
+}
+
+var _ flows.RichEmailService = (*recordingRichEmail)(nil)
+var _ flows.RichEmailService = (*replayingEmail)(nil)
+var _ flows.WebhookService = (*recordingWebhook)(nil)
+var _ flows.WebhookService = (*replayingWebhook)(nil)
+var _ flows.ClassificationService = (*recordingClassification)(nil)
+var _ flows.ClassificationService = (*replayingClassification)(nil)
+var _ flows.TicketService = (*recordingTicket)(nil)
+var _ flows.TicketService = (*replayingTicket)(nil)
+var _ flows.AirtimeService = (*recordingAirtime)(nil)
+var _ flows.AirtimeService = (*replayingAirtime)(nil)
+var _ flows.DataCollectionService = (*recordingDataCollection)(nil)
+var _ flows.DataCollectionService = (*replayingDataCollection)(nil)
+var _ flows.CommerceService = (*recordingCommerce)(nil)
+var _ flows.CommerceService = (*replayingCommerce)(nil)
+var _ flows.CallRecordingService = (*recordingCallRecording)(nil)
+var _ flows.CallRecordingService = (*replayingCallRecording)(nil)
+var _ flows.CallTransferService = (*recordingCallTransfer)(nil)
+var _ flows.CallTransferService = (*replayingCallTransfer)(nil)
+var _ flows.CredentialService = (*recordingCredential)(nil)
+var _ flows.CredentialService = (*replayingCredential)(nil)
+var _ flows.ExchangeRateService = (*recordingExchangeRate)(nil)
+var _ flows.ExchangeRateService = (*replayingExchangeRate)(nil)
+var _ flows.AttachmentService = (*recordingAttachment)(nil)
+var _ flows.AttachmentService = (*replayingAttachment)(nil)
@@ -0,0 +1,196 @@
+package engine_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/definition"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/flows/triggers"
+	"github.com/nyaruka/goflow/services/webhooks"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShadowRuns(t *testing.T) {
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	// the shadow sprints replay the primary's webhook calls so only the primary's calls are mocked
+	mocks := httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"http://example.com/hello": {
+			httpx.NewMockResponse(200, nil, []byte(`{"ok": true}`)),
+			httpx.NewMockResponse(200, nil, []byte(`{"ok": true}`)),
+			httpx.NewMockResponse(200, nil, []byte(`{"ok": true}`)),
+			httpx.NewMockResponse(200, nil, []byte(`{"ok": true}`)),
+			httpx.NewMockResponse(200, nil, []byte(`{"ok": true}`)),
+		},
+		"http://example.com/bye": {
+			httpx.NewMockResponse(503, nil, []byte(`{"ok": false}`)),
+		},
+	})
+	httpx.SetRequestor(mocks)
+
+	assetsJSON, err := os.ReadFile("testdata/shadow.json")
+	require.NoError(t, err)
+	sa, err := test.CreateSessionAssets(assetsJSON, "")
+	require.NoError(t, err)
+
+	draftJSON, err := os.ReadFile("testdata/shadow_draft.json")
+	require.NoError(t, err)
+	draft, err := definition.ReadFlow(draftJSON, nil)
+	require.NoError(t, err)
+
+	drafts := func(sa flows.SessionAssets, uuid assets.FlowUUID) flows.Flow {
+		if uuid == draft.UUID() {
+			return draft
+		}
+		return nil
+	}
+
+	env := envs.NewBuilder().Build()
+	contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+	trigger := triggers.NewBuilder(env, assets.NewFlowReference("7a1e0ea4-d0e3-4b2b-9c6a-1f2d3e4f5a6b", "Greeting"), contact).Manual().Build()
+
+	msgTexts := func(sprint flows.Sprint) []string {
+		texts := make([]string, 0)
+		for _, e := range sprint.Events() {
+			if msg, ok := e.(*events.MsgCreatedEvent); ok {
+				texts = append(texts, msg.Msg.Text())
+			}
+		}
+		return texts
+	}
+
+	shadows := make(chan *engine.ShadowRun, 10)
+	sink := func(ctx context.Context, r *engine.ShadowRun) { shadows <- r }
+
+	nextShadow := func() *engine.ShadowRun {
+		select {
+		case r := <-shadows:
+			return r
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "shadow run wasn't passed to the sink")
+			return nil
+		}
+	}
+
+	newEngine := func(drafts engine.DraftProvider, rate float64, maxConcurrent int, configure func(*engine.Builder)) flows.Engine {
+		return engine.NewBuilder().
+			WithWebhookServiceFactory(webhooks.NewServiceFactory(http.DefaultClient, nil, nil, nil, 10000, 0)).
+			WithShadowRuns(drafts, rate, sink, configure).
+			WithShadowRunLimits(maxConcurrent, time.Minute).
+			Build()
+	}
+
+	eventSink := &testEventSink{}
+	configured := false
+
+	eng := newEngine(drafts, 1, 10, func(b *engine.Builder) {
+		b.WithEventSink(eventSink)
+		configured = true
+	})
+	assert.True(t, configured)
+
+	session, sprint, err := eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	// the real session runs the published flow
+	assert.Equal(t, eng, session.Engine())
+	assert.Equal(t, []string{"Hello Success"}, msgTexts(sprint))
+
+	// the shadow session runs the draft with the same trigger, on its own engine, with the webhook response which the
+	// real session got
+	shadow := nextShadow()
+	assert.NoError(t, shadow.Err)
+	assert.Equal(t, trigger, shadow.Trigger)
+	assert.Nil(t, shadow.Resume)
+	assert.NotEqual(t, session.UUID(), shadow.Session.UUID())
+	assert.NotEqual(t, eng, shadow.Session.Engine())
+	assert.Equal(t, []string{"Hi there Success"}, msgTexts(shadow.Sprint))
+
+	// and its events only reach the event sink it was configured with
+	assert.Equal(t, shadow.Sprint.Events(), eventSink.events)
+
+	// resuming the real session also resumes a shadow copy of it
+	msg := flows.NewMsgIn(flows.MsgUUID("1e3a2f4d-8b5c-4d6e-9f7a-0b1c2d3e4f5a"), urns.NilURN, nil, "Hi", nil)
+	resume := resumes.NewMsg(env, flows.NewEmptyContact(sa, "Robert", envs.Language("eng"), nil), msg)
+
+	sprint, err = session.Resume(context.Background(), resume)
+	require.NoError(t, err)
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+	assert.Equal(t, []string{"Bye Failure"}, msgTexts(sprint))
+
+	shadow = nextShadow()
+	assert.NoError(t, shadow.Err)
+	assert.Equal(t, "Robert", session.Contact().Name())
+
+	// the shadow is resumed with its own copy of the resume, so it doesn't share the resume's contact
+	assert.NotSame(t, resume, shadow.Resume)
+	assert.NotSame(t, resume.Contact(), shadow.Resume.Contact())
+	test.AssertEqualJSON(t, jsonx.MustMarshal(resume), jsonx.MustMarshal(shadow.Resume), "shadow resume mismatch")
+	assert.Equal(t, "Robert", shadow.Session.Contact().Name())
+	assert.Equal(t, session.UUID(), shadow.Session.UUID())
+	assert.Equal(t, flows.SessionStatusCompleted, shadow.Session.Status())
+	assert.Equal(t, []string{"Goodbye Failure"}, msgTexts(shadow.Sprint))
+
+	// flows without drafts aren't shadowed
+	eng = newEngine(func(flows.SessionAssets, assets.FlowUUID) flows.Flow { return nil }, 1, 10, nil)
+	_, _, err = eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	// and nor are sprints which aren't sampled
+	eng = newEngine(drafts, 0, 10, nil)
+	_, _, err = eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	// or which would take the number of shadow sprints running over the limit
+	eng = newEngine(drafts, 1, 0, nil)
+	_, _, err = eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	assert.Len(t, shadows, 0)
+
+	// stopping an engine waits for the shadow sprints started by its sprints
+	release := make(chan bool)
+	eng = engine.NewBuilder().
+		WithWebhookServiceFactory(webhooks.NewServiceFactory(http.DefaultClient, nil, nil, nil, 10000, 0)).
+		WithShadowRuns(drafts, 1, func(ctx context.Context, r *engine.ShadowRun) { <-release; sink(ctx, r) }, nil).
+		Build()
+
+	_, _, err = eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	abandoned, err := eng.Stop(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Len(t, abandoned, 0)
+
+	release <- true
+	assert.NoError(t, nextShadow().Err)
+
+	_, err = eng.Stop(context.Background())
+	assert.NoError(t, err)
+
+	// and once stopped doesn't start any more shadow sprints
+	_, _, err = eng.NewSession(context.Background(), sa, trigger)
+	assert.EqualError(t, err, "engine has been stopped and isn't accepting new sprints")
+
+	assert.Len(t, shadows, 0)
+	assert.Len(t, mocks.Requests(), 6)
+	assert.False(t, mocks.HasUnused())
+}
@@ -0,0 +1,17 @@
+package engine
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+// returns the sources for the sprint with the given index of a session of the given engine. If the engine is
+// simulating, these are seeded from the simulation's seed and the sprint index, so that a sprint doesn't repeat the
+// UUIDs of earlier sprints, and their clock is fixed.
+func sprintSources(eng flows.Engine, index int) flows.Sources {
+	sim := eng.Simulation()
+	if sim == nil {
+		return flows.DefaultSources
+	}
+
+	return flows.NewSeededSources(sim.Seed+int64(index), sim.Now)
+}
@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"time"
 
-	"github.com/nyaruka/gocommon/dates"
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
 )
 
 type segment struct {
@@ -48,9 +48,18 @@ func (s *segment) MarshalJSON() ([]byte, error) {
 var _ flows.Segment = (*segment)(nil)
 
 type sprint struct {
-	modifiers []flows.Modifier
-	events    []flows.Event
-	segments  []flows.Segment
+	modifiers    []flows.Modifier
+	staged       []flows.Modifier
+	stagedEvents []flows.Event
+	events       []flows.Event
+	segments     []flows.Segment
+	diff         *flows.SessionDiff
+	debugLog     *flows.DebugLog
+
+	thread      string            // thread key of the session which is stamped on events
+	sink        func(flows.Event) // optional callback for events as they're logged
+	sources     flows.Sources     // sources of the session, e.g. for the times of segments
+	stampEvents bool              // whether events are stamped with the time of the sources as they're logged
 }
 
 // creates a new empty sprint
@@ -59,24 +68,101 @@ func newEmptySprint() *sprint {
 		modifiers: make([]flows.Modifier, 0, 10),
 		events:    make([]flows.Event, 0, 10),
 		segments:  make([]flows.Segment, 0, 10),
+		sources:   flows.DefaultSources,
 	}
 }
 
 // NewSprint creates a new sprint - engine doesn't use this but we do it when handling surveyor responses
 func NewSprint(modifiers []flows.Modifier, events []flows.Event, segments []flows.Segment) flows.Sprint {
-	return &sprint{modifiers: modifiers, events: events, segments: segments}
+	return &sprint{modifiers: modifiers, events: events, segments: segments, sources: flows.DefaultSources}
 }
 
 func (s *sprint) Modifiers() []flows.Modifier { return s.modifiers }
 func (s *sprint) Events() []flows.Event       { return s.events }
 func (s *sprint) Segments() []flows.Segment   { return s.segments }
 
+// Diff returns the changes this sprint made to the session, or nil if the engine doesn't record session diffs or this
+// sprint wasn't created by the engine
+func (s *sprint) Diff() *flows.SessionDiff { return s.diff }
+
+// DebugLog returns the values of the templates evaluated during this sprint, or nil if the engine doesn't have debugging
+// enabled
+func (s *sprint) DebugLog() *flows.DebugLog { return s.debugLog }
+
+// Staged returns the modifiers which were staged during this sprint but discarded because it failed
+func (s *sprint) Staged() []flows.Modifier { return s.staged }
+
+// StagedEvents returns the events of the changes which were staged during this sprint but discarded because it failed
+func (s *sprint) StagedEvents() []flows.Event { return s.stagedEvents }
+
+// Summary returns counts of the events in this sprint by severity
+func (s *sprint) Summary() *flows.SprintSummary {
+	summary := &flows.SprintSummary{Events: len(s.events)}
+
+	for _, e := range s.events {
+		switch e.Type() {
+		case events.TypeError:
+			summary.Errors++
+		case events.TypeWarning:
+			summary.Warnings++
+		case events.TypeFailure:
+			summary.Failures++
+		}
+	}
+	return summary
+}
+
 func (s *sprint) logModifier(m flows.Modifier) {
 	s.modifiers = append(s.modifiers, m)
 }
 
+// events which record changes to the contact and so are discarded when those changes are rolled back
+var contactChangeEvents = map[string]bool{
+	events.TypeContactFieldChanged:    true,
+	events.TypeContactFieldsChanged:   true,
+	events.TypeContactGroupsChanged:   true,
+	events.TypeContactLanguageChanged: true,
+	events.TypeContactMerged:          true,
+	events.TypeContactNameChanged:     true,
+	events.TypeContactRefreshed:       true,
+	events.TypeContactRelationChanged: true,
+	events.TypeContactStatusChanged:   true,
+	events.TypeContactTimezoneChanged: true,
+	events.TypeContactURNNormalized:   true,
+	events.TypeContactURNsChanged:     true,
+}
+
+// discards the contact changes of this sprint, keeping its modifiers and their events as staged so they can still be
+// inspected
+func (s *sprint) discardContactChanges() {
+	s.staged = s.modifiers
+	s.modifiers = make([]flows.Modifier, 0)
+
+	kept := make([]flows.Event, 0, len(s.events))
+	s.stagedEvents = make([]flows.Event, 0)
+	for _, e := range s.events {
+		if contactChangeEvents[e.Type()] {
+			s.stagedEvents = append(s.stagedEvents, e)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	s.events = kept
+}
+
 func (s *sprint) logEvent(e flows.Event) {
+	if s.thread != "" {
+		e.SetThread(s.thread)
+	}
+	if s.stampEvents {
+		e.SetCreatedOn(s.sources.Now())
+	}
+
 	s.events = append(s.events, e)
+
+	if s.sink != nil {
+		s.sink(e)
+	}
 }
 
 func (s *sprint) logSegment(flow flows.Flow, node flows.Node, exit flows.Exit, operand string, dest flows.Node) {
@@ -86,7 +172,7 @@ func (s *sprint) logSegment(flow flows.Flow, node flows.Node, exit flows.Exit, o
 		exit:        exit,
 		operand:     operand,
 		destination: dest,
-		time:        dates.Now(),
+		time:        s.sources.Now(),
 	})
 }
 
@@ -82,6 +82,7 @@ func TestSprint(t *testing.T) {
 
 	event1 := events.NewError(errors.New("error 1"))
 	event2 := events.NewError(errors.New("error 1"))
+	event3 := events.NewWarningf("value truncated")
 
 	dates.SetNowSource(dates.NewSequentialNowSource(time.Date(2021, 12, 8, 10, 13, 30, 0, time.UTC)))
 
@@ -92,14 +93,17 @@ func TestSprint(t *testing.T) {
 	sprint.logSegment(flow, node2, node2Exit1, "", node3)
 	sprint.logModifier(mod2)
 	sprint.logEvent(event2)
+	sprint.logEvent(event3)
 
 	var seg1 flows.Segment = &segment{flow: flow, node: node1, exit: node1Exit1, operand: "yes", destination: node2, time: time.Date(2021, 12, 8, 10, 13, 30, 0, time.UTC)}
 	var seg2 flows.Segment = &segment{flow: flow, node: node2, exit: node2Exit1, destination: node3, time: time.Date(2021, 12, 8, 10, 13, 31, 0, time.UTC)}
 
 	assert.Equal(t, []flows.Modifier{mod1, mod2}, sprint.Modifiers())
-	assert.Equal(t, []flows.Event{event1, event2}, sprint.Events())
+	assert.Equal(t, []flows.Event{event1, event2, event3}, sprint.Events())
 	assert.Equal(t, []flows.Segment{seg1, seg2}, sprint.Segments())
 
+	assert.Equal(t, &flows.SprintSummary{Events: 3, Errors: 2, Warnings: 1}, sprint.Summary())
+
 	seg := sprint.Segments()[0]
 	assert.Equal(t, flow, seg.Flow())
 	assert.Equal(t, node1, seg.Node())
@@ -114,7 +118,7 @@ func TestSprint(t *testing.T) {
 
 	assert.Equal(t, sprint, NewSprint(
 		[]flows.Modifier{mod1, mod2},
-		[]flows.Event{event1, event2},
+		[]flows.Event{event1, event2, event3},
 		[]flows.Segment{seg1, seg2},
 	))
 }
@@ -6,16 +6,18 @@ import (
 
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/utils"
 )
 
 type environment struct {
 	envs.Environment
 
 	locationResolver envs.LocationResolver
+	lookupResolver   envs.LookupResolver
 }
 
 // NewEnvironment creates a new environment
-func NewEnvironment(base envs.Environment, la *LocationAssets) envs.Environment {
+func NewEnvironment(base envs.Environment, la *LocationAssets, lt *LookupTableAssets) envs.Environment {
 	var locationResolver envs.LocationResolver
 
 	hierarchies := la.Hierarchies()
@@ -23,13 +25,22 @@ func NewEnvironment(base envs.Environment, la *LocationAssets) envs.Environment
 		locationResolver = &assetLocationResolver{hierarchies[0]}
 	}
 
-	return &environment{base, locationResolver}
+	var lookupResolver envs.LookupResolver
+	if lt != nil && len(lt.All()) > 0 {
+		lookupResolver = lt
+	}
+
+	return &environment{base, locationResolver, lookupResolver}
 }
 
 func (e *environment) LocationResolver() envs.LocationResolver {
 	return e.locationResolver
 }
 
+func (e *environment) LookupResolver() envs.LookupResolver {
+	return e.lookupResolver
+}
+
 type assetLocationResolver struct {
 	locations assets.LocationHierarchy
 }
@@ -45,37 +56,70 @@ func (r *assetLocationResolver) FindLocations(name string, level envs.LocationLe
 //   2. Match with punctuation removed
 //   3. Split input into words and try to match each word
 //   4. Try to match pairs of words
+//
+// Each strategy also tries matching transliterated names and aliases.
 func (r *assetLocationResolver) FindLocationsFuzzy(text string, level envs.LocationLevel, parent *envs.Location) []*envs.Location {
+	matches := r.MatchLocationsFuzzy(text, level, parent)
+	locations := make([]*envs.Location, len(matches))
+	for i := range matches {
+		locations[i] = matches[i].Location
+	}
+	return locations
+}
+
+// non-word characters, which unlike \W, doesn't include letters with diacritics
+var nonWordRegex = regexp.MustCompile(`[^\pL\pM\pN_]+`)
+
+// how much less confident we are in matches found by each fuzzy matching strategy after the first
+const (
+	locationConfidenceStripped = 0.95
+	locationConfidenceWords    = 0.9
+)
+
+// MatchLocationsFuzzy returns matches like FindLocationsFuzzy with their confidence adjusted by the strategy used
+func (r *assetLocationResolver) MatchLocationsFuzzy(text string, level envs.LocationLevel, parent *envs.Location) []*envs.LocationMatch {
 	// try matching name exactly
-	if locations := r.FindLocations(text, level, parent); len(locations) > 0 {
-		return locations
+	if matches := r.locations.MatchByName(text, level, parent); len(matches) > 0 {
+		return matches
 	}
 
 	// try with punctuation removed
-	stripped := strings.TrimSpace(regexp.MustCompile(`\W+`).ReplaceAllString(text, ""))
-	if locations := r.FindLocations(stripped, level, parent); len(locations) > 0 {
-		return locations
+	stripped := strings.TrimSpace(nonWordRegex.ReplaceAllString(text, ""))
+	if matches := r.locations.MatchByName(stripped, level, parent); len(matches) > 0 {
+		return scaleLocationConfidence(matches, locationConfidenceStripped)
 	}
 
 	// try on each tokenized word
-	words := regexp.MustCompile(`\W+`).Split(text, -1)
+	words := nonWordRegex.Split(text, -1)
 	for _, word := range words {
-		if locations := r.FindLocations(word, level, parent); len(locations) > 0 {
-			return locations
+		if matches := r.locations.MatchByName(word, level, parent); len(matches) > 0 {
+			return scaleLocationConfidence(matches, locationConfidenceWords)
 		}
 	}
 
 	// try with each pair of words
 	for i := 0; i < len(words)-1; i++ {
 		wordPair := strings.Join(words[i:i+2], " ")
-		if locations := r.FindLocations(wordPair, level, parent); len(locations) > 0 {
-			return locations
+		if matches := r.locations.MatchByName(wordPair, level, parent); len(matches) > 0 {
+			return scaleLocationConfidence(matches, locationConfidenceWords)
 		}
 	}
 
-	return []*envs.Location{}
+	return []*envs.LocationMatch{}
+}
+
+func scaleLocationConfidence(matches []*envs.LocationMatch, scale float64) []*envs.LocationMatch {
+	for _, m := range matches {
+		m.Confidence *= scale
+	}
+	return matches
 }
 
 func (r *assetLocationResolver) LookupLocation(path envs.LocationPath) *envs.Location {
 	return r.locations.FindByPath(path)
 }
+
+// FindLocationByCoordinates returns the most specific location whose boundary contains the given coordinates
+func (r *assetLocationResolver) FindLocationByCoordinates(coords utils.Coordinates) *envs.Location {
+	return r.locations.FindByCoordinates(coords)
+}
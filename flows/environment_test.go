@@ -72,7 +72,7 @@ func TestEnvironment(t *testing.T) {
 	sa, err := engine.NewSessionAssets(env, source, nil)
 	require.NoError(t, err)
 
-	fenv := flows.NewEnvironment(env, sa.Locations())
+	fenv := flows.NewEnvironment(env, sa.Locations(), sa.LookupTables())
 	assert.Equal(t, envs.Country("RW"), fenv.DefaultCountry())
 	require.NotNil(t, fenv.LocationResolver())
 
@@ -82,4 +82,7 @@ func TestEnvironment(t *testing.T) {
 	matches := fenv.LocationResolver().FindLocationsFuzzy("gisozi town", flows.LocationLevelWard, nil)
 	assert.Equal(t, 1, len(matches))
 	assert.Equal(t, "Gisozi", matches[0].Name())
+
+	// no lookup tables so no lookup resolver
+	assert.Nil(t, fenv.LookupResolver())
 }
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	registerType(TypeAirtimeBalanceInsufficient, func() flows.Event { return &AirtimeBalanceInsufficientEvent{} })
+}
+
+// TypeAirtimeBalanceInsufficient is the type of our airtime balance insufficient event
+const TypeAirtimeBalanceInsufficient string = "airtime_balance_insufficient"
+
+// AirtimeBalanceInsufficientEvent events are created when an airtime transfer failed and the balance of the account
+// reported by the transfer is less than the amount to be transferred.
+//
+//	{
+//	  "type": "airtime_balance_insufficient",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "currency": "USD",
+//	  "balance": 0.25,
+//	  "amount": 0.5,
+//	  "http_logs": [
+//	    {
+//	      "url": "https://dvs-api.dtone.com/v1/sync/transactions",
+//	      "status": "success",
+//	      "request": "POST /v1/sync/transactions HTTP/1.1\r\n\r\n{}",
+//	      "response": "HTTP/1.1 200 OK\r\n\r\n{}",
+//	      "created_on": "2006-01-02T15:04:05Z",
+//	      "elapsed_ms": 123
+//	    }
+//	  ]
+//	}
+//
+// @event airtime_balance_insufficient
+type AirtimeBalanceInsufficientEvent struct {
+	BaseEvent
+
+	Currency string           `json:"currency"`
+	Balance  decimal.Decimal  `json:"balance"`
+	Amount   decimal.Decimal  `json:"amount"`
+	HTTPLogs []*flows.HTTPLog `json:"http_logs"`
+}
+
+// NewAirtimeBalanceInsufficient creates a new airtime balance insufficient event
+func NewAirtimeBalanceInsufficient(currency string, balance, amount decimal.Decimal, httpLogs []*flows.HTTPLog) *AirtimeBalanceInsufficientEvent {
+	return &AirtimeBalanceInsufficientEvent{
+		BaseEvent: NewBaseEvent(TypeAirtimeBalanceInsufficient),
+		Currency:  currency,
+		Balance:   balance,
+		Amount:    amount,
+		HTTPLogs:  httpLogs,
+	}
+}
+
+var _ flows.Event = (*AirtimeBalanceInsufficientEvent)(nil)
+
+// Redact masks sensitive values in the HTTP logs of this event
+func (e *AirtimeBalanceInsufficientEvent) Redact(r *flows.Redactor) { r.RedactHTTPLogs(e.HTTPLogs) }
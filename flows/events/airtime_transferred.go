@@ -60,3 +60,6 @@ func NewAirtimeTransferred(t *flows.AirtimeTransfer, httpLogs []*flows.HTTPLog)
 		HTTPLogs:      httpLogs,
 	}
 }
+
+// Redact masks sensitive values in the HTTP logs of this event
+func (e *AirtimeTransferredEvent) Redact(r *flows.Redactor) { r.RedactHTTPLogs(e.HTTPLogs) }
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeAttachmentRejected, func() flows.Event { return &AttachmentRejectedEvent{} })
+}
+
+// TypeAttachmentRejected is the type of our attachment rejected event
+const TypeAttachmentRejected string = "attachment_rejected"
+
+// AttachmentRejectedEvent events are created when the attachment service rejects an attachment of an outgoing message,
+// which is then sent without it, e.g. because its URL is a dead link. Size and limit are in bytes and are only included for attachments which are too large.
+//
+//	{
+//	  "type": "attachment_rejected",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "attachment": "video/mp4:http://s3.amazon.com/bucket/test.mp4",
+//	  "reason": "size",
+//	  "size": 26214400,
+//	  "limit": 20971520
+//	}
+//
+// @event attachment_rejected
+type AttachmentRejectedEvent struct {
+	BaseEvent
+
+	Attachment utils.Attachment               `json:"attachment" validate:"required"`
+	Reason     flows.AttachmentRejectedReason `json:"reason" validate:"required"`
+	Size       int                            `json:"size,omitempty"`
+	Limit      int                            `json:"limit,omitempty"`
+}
+
+// NewAttachmentRejected returns a new attachment rejected event
+func NewAttachmentRejected(attachment utils.Attachment, reason flows.AttachmentRejectedReason, size, limit int) *AttachmentRejectedEvent {
+	return &AttachmentRejectedEvent{
+		BaseEvent:  NewBaseEvent(TypeAttachmentRejected),
+		Attachment: attachment,
+		Reason:     reason,
+		Size:       size,
+		Limit:      limit,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/utils"
 
@@ -23,6 +24,9 @@ type BaseEvent struct {
 	Type_      string         `json:"type" validate:"required"`
 	CreatedOn_ time.Time      `json:"created_on" validate:"required"`
 	StepUUID_  flows.StepUUID `json:"step_uuid,omitempty" validate:"omitempty,uuid4"`
+	Thread_    string         `json:"thread,omitempty"`
+
+	Timing_ *flows.EventTiming `json:"timing,omitempty"`
 }
 
 // NewBaseEvent creates a new base event
@@ -36,12 +40,27 @@ func (e *BaseEvent) Type() string { return e.Type_ }
 // CreatedOn returns the created on time of this event
 func (e *BaseEvent) CreatedOn() time.Time { return e.CreatedOn_ }
 
+// SetCreatedOn sets the created on time of this event
+func (e *BaseEvent) SetCreatedOn(createdOn time.Time) { e.CreatedOn_ = createdOn }
+
 // StepUUID returns the UUID of the step in the path where this event occurred
 func (e *BaseEvent) StepUUID() flows.StepUUID { return e.StepUUID_ }
 
 // SetStepUUID sets the UUID of the step in the path where this event occurred
 func (e *BaseEvent) SetStepUUID(stepUUID flows.StepUUID) { e.StepUUID_ = stepUUID }
 
+// Thread returns the thread key of the session where this event occurred
+func (e *BaseEvent) Thread() string { return e.Thread_ }
+
+// SetThread sets the thread key of the session where this event occurred
+func (e *BaseEvent) SetThread(thread string) { e.Thread_ = thread }
+
+// Timing returns the timing of the action which generated this event, if event timings are enabled
+func (e *BaseEvent) Timing() *flows.EventTiming { return e.Timing_ }
+
+// SetTiming sets the timing of the action which generated this event
+func (e *BaseEvent) SetTiming(timing *flows.EventTiming) { e.Timing_ = timing }
+
 //------------------------------------------------------------------------------------------
 // JSON Encoding / Decoding
 //------------------------------------------------------------------------------------------
@@ -61,3 +80,23 @@ func ReadEvent(data json.RawMessage) (flows.Event, error) {
 	event := f()
 	return event, utils.UnmarshalAndValidate(data, event)
 }
+
+// MarshalRedacted marshals the given event with the sensitive values masked by the given redactor, leaving the event
+// itself unchanged. Events which can't contain sensitive values are marshaled as they are, as are all events if the
+// redactor is nil.
+func MarshalRedacted(event flows.Event, redactor *flows.Redactor) ([]byte, error) {
+	data, err := jsonx.Marshal(event)
+	if _, redactable := event.(flows.RedactableEvent); err != nil || !redactable || redactor == nil {
+		return data, err
+	}
+
+	// redact a copy of the event so that the one held in memory stays as it was generated
+	redacted, err := ReadEvent(data)
+	if err != nil {
+		return nil, err
+	}
+
+	redacted.(flows.RedactableEvent).Redact(redactor)
+
+	return jsonx.Marshal(redacted)
+}
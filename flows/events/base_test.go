@@ -24,6 +24,7 @@ import (
 	"github.com/nyaruka/goflow/services/webhooks"
 	"github.com/nyaruka/goflow/test"
 	"github.com/nyaruka/goflow/utils"
+	"github.com/nyaruka/goflow/utils/jsonschema"
 	"github.com/shopspring/decimal"
 
 	"github.com/stretchr/testify/assert"
@@ -43,12 +44,17 @@ func TestEventMarshaling(t *testing.T) {
 	tz, _ := time.LoadLocation("Africa/Kigali")
 	timeout := 500
 	expiresOn := time.Date(2022, 2, 3, 13, 45, 30, 0, time.UTC)
+	retryOn := time.Date(2018, 10, 18, 14, 21, 30, 0, time.UTC)
 	gender := session.Assets().Fields().Get("gender")
 	mailgun := session.Assets().Ticketers().Get("19dc6346-9623-4fe4-be80-538d493ecdf5")
 	weather := session.Assets().Topics().Get("472a7a73-96cb-4736-b567-056d987cc5b4")
 	user := session.Assets().Users().Get("bob@nyaruka.com")
 	ticket := flows.NewTicket("7481888c-07dd-47dc-bf22-ef7448696ffe", mailgun, weather, "Where are my cookies?", "1243252", user)
 
+	profiler := flows.NewProfiler()
+	profiler.Start(flows.ProfileCategoryTemplate, "Hi @contact.name")()
+	profiler.Start(flows.ProfileCategoryService, "webhook")()
+
 	eventTests := []struct {
 		event     flows.Event
 		marshaled string
@@ -100,6 +106,28 @@ func TestEventMarshaling(t *testing.T) {
 				"type": "airtime_transferred"
 			}`,
 		},
+		{
+			events.NewAirtimeBalanceInsufficient("USD", decimal.RequireFromString("0.25"), decimal.RequireFromString("0.50"), []*flows.HTTPLog{}),
+			`{
+				"type": "airtime_balance_insufficient",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"currency": "USD",
+				"balance": 0.25,
+				"amount": 0.5,
+				"http_logs": []
+			}`,
+		},
+		{
+			events.NewAttachmentRejected("video/mp4:http://s3.amazon.com/bucket/test.mp4", flows.AttachmentRejectedReasonSize, 26214400, 20971520),
+			`{
+				"type": "attachment_rejected",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"attachment": "video/mp4:http://s3.amazon.com/bucket/test.mp4",
+				"reason": "size",
+				"size": 26214400,
+				"limit": 20971520
+			}`,
+		},
 		{
 			events.NewBroadcastCreated(
 				flows.BroadcastTranslations{
@@ -191,6 +219,7 @@ func TestEventMarshaling(t *testing.T) {
 			events.NewContactFieldChanged(
 				gender,
 				flows.NewValue(types.NewXText("male"), nil, nil, "", "", ""),
+				nil,
 			),
 			`{
 				"created_on": "2018-10-18T14:20:30.000123456Z",
@@ -204,10 +233,32 @@ func TestEventMarshaling(t *testing.T) {
 				}
 			}`,
 		},
+		{
+			events.NewContactFieldChanged(
+				gender,
+				flows.NewValue(types.NewXText("female"), nil, nil, "", "", ""),
+				flows.NewValue(types.NewXText("male"), nil, nil, "", "", ""),
+			),
+			`{
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"field": {
+					"key": "gender",
+					"name": "Gender"
+				},
+				"type": "contact_field_changed",
+				"value": {
+					"text": "female"
+				},
+				"previous_value": {
+					"text": "male"
+				}
+			}`,
+		},
 		{
 			events.NewContactFieldChanged(
 				gender,
 				nil, // value being cleared
+				flows.NewValue(types.NewXText("female"), nil, nil, "", "", ""),
 			),
 			`{
 				"created_on": "2018-10-18T14:20:30.000123456Z",
@@ -216,7 +267,72 @@ func TestEventMarshaling(t *testing.T) {
 					"name": "Gender"
 				},
 				"type": "contact_field_changed",
-				"value": null
+				"value": null,
+				"previous_value": {
+					"text": "female"
+				}
+			}`,
+		},
+		{
+			events.NewContactFieldsChanged([]*events.FieldChange{
+				{Field: gender.Reference(), Value: flows.NewValue(types.NewXText("female"), nil, nil, "", "", ""), PreviousValue: flows.NewValue(types.NewXText("male"), nil, nil, "", "", "")},
+				{Field: assets.NewFieldReference("age", "Age"), Value: nil, PreviousValue: nil},
+			}),
+			`{
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"type": "contact_fields_changed",
+				"changes": [
+					{
+						"field": {
+							"key": "gender",
+							"name": "Gender"
+						},
+						"value": {
+							"text": "female"
+						},
+						"previous_value": {
+							"text": "male"
+						}
+					},
+					{
+						"field": {
+							"key": "age",
+							"name": "Age"
+						},
+						"value": null
+					}
+				]
+			}`,
+		},
+		{
+			events.NewContactRelationChanged(
+				session.Assets().RelationTypes().Get("caregiver"),
+				flows.NewContactReference("0cb17b2a-3bfe-4a19-8c99-98ab9561045d", "Sarah Haggerty"),
+			),
+			`{
+				"contact": {
+					"name": "Sarah Haggerty",
+					"uuid": "0cb17b2a-3bfe-4a19-8c99-98ab9561045d"
+				},
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"relation_type": {
+					"key": "caregiver",
+					"name": "Caregiver"
+				},
+				"type": "contact_relation_changed"
+			}`,
+		},
+		{
+			events.NewContactMerged(
+				flows.NewContactReference("0cb17b2a-3bfe-4a19-8c99-98ab9561045d", "Sarah Haggerty"),
+			),
+			`{
+				"contact": {
+					"name": "Sarah Haggerty",
+					"uuid": "0cb17b2a-3bfe-4a19-8c99-98ab9561045d"
+				},
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"type": "contact_merged"
 			}`,
 		},
 		{
@@ -272,6 +388,9 @@ func TestEventMarshaling(t *testing.T) {
 			`{
 				"contact": {
 					"created_on": "2018-06-20T11:40:30.123456789Z",
+					"field_changes": {
+						"age": "2018-10-18T14:20:30.000123456Z"
+					},
 					"fields": {
 						"activation_token": {
 							"text": "AACC55"
@@ -302,6 +421,12 @@ func TestEventMarshaling(t *testing.T) {
 					"language": "eng",
 					"last_seen_on": "2017-12-31T11:35:10.035757258-02:00",
 					"name": "Ryan Lewis",
+					"relations": {
+						"caregiver": {
+							"name": "Sarah Haggerty",
+							"uuid": "0cb17b2a-3bfe-4a19-8c99-98ab9561045d"
+						}
+					},
 					"status": "active",
 					"tickets": [
 						{
@@ -358,6 +483,15 @@ func TestEventMarshaling(t *testing.T) {
 				"type": "contact_timezone_changed"
 			}`,
 		},
+		{
+			events.NewContactURNNormalized(urns.URN("tel:0788 123 123"), urns.URN("tel:+250788123123")),
+			`{
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"type": "contact_urn_normalized",
+				"original": "tel:0788 123 123",
+				"urn": "tel:+250788123123"
+			}`,
+		},
 		{
 			events.NewContactURNsChanged([]urns.URN{
 				urns.URN("tel:+12345678900"),
@@ -373,7 +507,20 @@ func TestEventMarshaling(t *testing.T) {
 			}`,
 		},
 		{
-			events.NewEmailSent([]string{"bob@nyaruka.com", "jim@nyaruka.com"}, "Update", "Flows are great!"),
+			events.NewEmailReceived(&flows.EmailIn{UUID: "2d611e17-fb22-457f-b802-b8f7ec5cda5b", From: "bob@nyaruka.com", Subject: "Order #1234", Body: "Where is my order?"}),
+			`{
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"type": "email_received",
+				"email": {
+					"uuid": "2d611e17-fb22-457f-b802-b8f7ec5cda5b",
+					"from": "bob@nyaruka.com",
+					"subject": "Order #1234",
+					"body": "Where is my order?"
+				}
+			}`,
+		},
+		{
+			events.NewEmailSent(&flows.Email{Addresses: []string{"bob@nyaruka.com", "jim@nyaruka.com"}, Subject: "Update", Body: "Flows are great!"}),
 			`{
 				"created_on": "2018-10-18T14:20:30.000123456Z",
 				"type": "email_sent",
@@ -421,6 +568,25 @@ func TestEventMarshaling(t *testing.T) {
 				"type": "failure"
 			}`,
 		},
+		{
+			events.NewValueTruncated(events.TruncationTargetResult, "Favorite Color", 700, 640),
+			`{
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"length": 700,
+				"limit": 640,
+				"name": "Favorite Color",
+				"target": "result",
+				"type": "value_truncated"
+			}`,
+		},
+		{
+			events.NewWarningf("value truncated to %d characters", 640),
+			`{
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"text": "value truncated to 640 characters",
+				"type": "warning"
+			}`,
+		},
 		{
 			events.NewDependencyError(assets.NewFieldReference("age", "Age")),
 			`{
@@ -432,6 +598,7 @@ func TestEventMarshaling(t *testing.T) {
 		{
 			events.NewIVRCreated(
 				flows.NewIVRMsgOut(
+					flows.MsgUUID(uuids.New()),
 					urns.URN("tel:+12345678900"),
 					assets.NewChannelReference(assets.ChannelUUID("57f1078f-88aa-46f4-a59a-948a5739c03d"), "My Android Phone"),
 					"Hi there",
@@ -458,10 +625,11 @@ func TestEventMarshaling(t *testing.T) {
 		{
 			events.NewMsgCreated(
 				flows.NewMsgOut(
+					flows.MsgUUID(uuids.New()),
 					urns.URN("tel:+12345678900"),
 					assets.NewChannelReference(assets.ChannelUUID("57f1078f-88aa-46f4-a59a-948a5739c03d"), "My Android Phone"),
 					"Hi there",
-					nil, nil, nil,
+					nil, nil, nil, nil,
 					flows.NilMsgTopic,
 					envs.NilLocale,
 					flows.NilUnsendableReason,
@@ -484,12 +652,14 @@ func TestEventMarshaling(t *testing.T) {
 		{
 			events.NewMsgCreated(
 				flows.NewMsgOut(
+					flows.MsgUUID(uuids.New()),
 					urns.URN("tel:+12345678900"),
 					assets.NewChannelReference(assets.ChannelUUID("57f1078f-88aa-46f4-a59a-948a5739c03d"), "My Android Phone"),
 					"Hi there",
 					[]utils.Attachment{"image/jpeg:http://s3.amazon.com/bucket/test.jpg"},
 					[]string{"yes", "no"},
 					nil,
+					nil,
 					flows.MsgTopicAgent,
 					"eng-US",
 					flows.UnsendableReasonContactStatus,
@@ -514,6 +684,115 @@ func TestEventMarshaling(t *testing.T) {
 				}
 			}`,
 		},
+		{
+			events.NewMsgIgnored(
+				flows.NewMsgIn(
+					flows.MsgUUID("2d611e17-fb22-457f-b802-b8f7ec5cda5b"),
+					urns.URN("tel:+12345678900"),
+					nil,
+					"Hi there",
+					nil,
+				),
+				events.MsgIgnoredReasonDuplicateText,
+			),
+			`{
+				"type": "msg_ignored",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"msg": {
+					"uuid": "2d611e17-fb22-457f-b802-b8f7ec5cda5b",
+					"urn": "tel:+12345678900",
+					"text": "Hi there"
+				},
+				"reason": "duplicate_text"
+			}`,
+		},
+		{
+			events.NewMsgScheduled(
+				flows.NewMsgOut(
+					flows.MsgUUID(uuids.New()),
+					urns.URN("tel:+12345678900"),
+					assets.NewChannelReference(assets.ChannelUUID("57f1078f-88aa-46f4-a59a-948a5739c03d"), "My Android Phone"),
+					"Don't forget your appointment",
+					nil, nil, nil, nil,
+					flows.NilMsgTopic,
+					envs.NilLocale,
+					flows.NilUnsendableReason,
+				),
+				time.Date(2018, 10, 19, 14, 20, 30, 0, time.UTC),
+			),
+			`{
+				"type": "msg_scheduled",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"msg": {
+					"uuid": "5b835baa-3607-48cb-a489-7cc248dc15c5",
+					"urn": "tel:+12345678900",
+					"channel": {
+						"name": "My Android Phone",
+						"uuid": "57f1078f-88aa-46f4-a59a-948a5739c03d"
+					},
+					"text": "Don't forget your appointment"
+				},
+				"send_on": "2018-10-19T14:20:30Z"
+			}`,
+		},
+		{
+			events.NewWhatsAppFlowCreated(&flows.WhatsAppFlow{
+				URN:       urns.URN("whatsapp:12065551212"),
+				Channel:   assets.NewChannelReference("57f1078f-88aa-46f4-a59a-948a5739c03d", "WhatsApp"),
+				FlowID:    "1234567890",
+				FlowToken: "2d611e17-fb22-457f-b802-b8f7ec5cda5b",
+				Screen:    "SIGN_UP",
+				Body:      "Please complete your registration",
+				Button:    "Sign up",
+				Data:      map[string]string{"name": "Bob"},
+				Results:   map[string]string{"age": "Age"},
+			}),
+			`{
+				"type": "whatsapp_flow_created",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"flow": {
+					"urn": "whatsapp:12065551212",
+					"channel": {"uuid": "57f1078f-88aa-46f4-a59a-948a5739c03d", "name": "WhatsApp"},
+					"flow_id": "1234567890",
+					"flow_token": "2d611e17-fb22-457f-b802-b8f7ec5cda5b",
+					"screen": "SIGN_UP",
+					"body": "Please complete your registration",
+					"button": "Sign up",
+					"data": {"name": "Bob"},
+					"results": {"age": "Age"}
+				}
+			}`,
+		},
+		{
+			events.NewWhatsAppTemplateCreated(&flows.WhatsAppTemplateMsg{
+				URN:       urns.URN("whatsapp:12065551212"),
+				Channel:   assets.NewChannelReference("57f1078f-88aa-46f4-a59a-948a5739c03d", "WhatsApp"),
+				Template:  assets.NewTemplateReference("5722e1fd-fe32-4e74-ac78-3cf41a6adb7e", "appointment"),
+				Namespace: "2d40b45c_25cd_4965_9019_f05d0124c5fa",
+				Locale:    "eng-US",
+				Components: []*flows.WhatsAppTemplateComponent{
+					{Type: "header", Name: "header", Params: []string{"Dentist"}},
+					{Type: "body", Name: "body", Params: []string{"Bob", "Tuesday"}},
+				},
+				Text: "Hi Bob, your appointment is on Tuesday",
+			}),
+			`{
+				"type": "whatsapp_template_created",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"msg": {
+					"urn": "whatsapp:12065551212",
+					"channel": {"uuid": "57f1078f-88aa-46f4-a59a-948a5739c03d", "name": "WhatsApp"},
+					"template": {"uuid": "5722e1fd-fe32-4e74-ac78-3cf41a6adb7e", "name": "appointment"},
+					"namespace": "2d40b45c_25cd_4965_9019_f05d0124c5fa",
+					"locale": "eng-US",
+					"components": [
+						{"type": "header", "name": "header", "params": ["Dentist"]},
+						{"type": "body", "name": "body", "params": ["Bob", "Tuesday"]}
+					],
+					"text": "Hi Bob, your appointment is on Tuesday"
+				}
+			}`,
+		},
 		{
 			events.NewMsgWait(&timeout, &expiresOn, hints.NewImageHint()),
 			`{
@@ -553,6 +832,68 @@ func TestEventMarshaling(t *testing.T) {
 				"expires_on": "2022-02-03T13:45:30Z"
 			}`,
 		},
+		{
+			events.NewWebhookRequested("b7c7a6d4-4e3f-4d8a-9b0e-2f5c1a8d9e7f", "POST", "http://example.com/lookup", map[string]string{"X-Key": "123"}, "crm", `{"name": "Bob"}`),
+			`{
+				"type": "webhook_requested",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"request_uuid": "b7c7a6d4-4e3f-4d8a-9b0e-2f5c1a8d9e7f",
+				"method": "POST",
+				"url": "http://example.com/lookup",
+				"headers": {"X-Key": "123"},
+				"credential": "crm",
+				"body": "{\"name\": \"Bob\"}"
+			}`,
+		},
+		{
+			events.NewCallbackWait("b7c7a6d4-4e3f-4d8a-9b0e-2f5c1a8d9e7f", "8eebd020-1af5-431c-b943-aa670fc74da9"),
+			`{
+				"type": "callback_wait",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"request_uuid": "b7c7a6d4-4e3f-4d8a-9b0e-2f5c1a8d9e7f",
+				"action_uuid": "8eebd020-1af5-431c-b943-aa670fc74da9"
+			}`,
+		},
+		{
+			events.NewSprintProfile(profiler, 10),
+			`{
+				"type": "sprint_profile",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"elapsed_ms": 0,
+				"actions": [],
+				"routers": [],
+				"templates": [{"name": "Hi @contact.name", "count": 1, "elapsed_ms": 0}],
+				"services": [{"name": "webhook", "count": 1, "elapsed_ms": 0}]
+			}`,
+		},
+		{
+			events.NewDigitsReceived("1234"),
+			`{
+				"type": "digits_received",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"digits": "1234"
+			}`,
+		},
+		{
+			events.NewDigitsWait(4, "#", &timeout, &expiresOn),
+			`{
+				"type": "digits_wait",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"max_digits": 4,
+				"finish_on_key": "#",
+				"timeout_seconds": 500,
+				"expires_on": "2022-02-03T13:45:30Z"
+			}`,
+		},
+		{
+			events.NewSessionScheduled(time.Date(2022, 2, 1, 9, 0, 0, 0, time.UTC), &expiresOn),
+			`{
+				"type": "session_scheduled",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"resume_on": "2022-02-01T09:00:00Z",
+				"expires_on": "2022-02-03T13:45:30Z"
+			}`,
+		},
 		{
 			events.NewSessionTriggered(
 				assets.NewFlowReference(assets.FlowUUID("e4d441f0-24e3-4627-85fb-1e99e733baf0"), "Collect Age"),
@@ -620,6 +961,7 @@ func TestEventMarshaling(t *testing.T) {
 					},
 					"body": "Where are my cookies?",
 					"external_id": "1243252",
+					"queue": "weather-desk",
 					"assignee": {
 						"email": "bob@nyaruka.com",
 						"name": "Bob"
@@ -668,6 +1010,206 @@ func TestEventMarshaling(t *testing.T) {
 				]
 			}`,
 		},
+		{
+			events.NewCommerceCalled(
+				[]*flows.HTTPLog{
+					{
+						HTTPLogWithoutTime: &flows.HTTPLogWithoutTime{
+							LogWithoutTime: &httpx.LogWithoutTime{
+								URL:        "https://shop.example.com/orders",
+								StatusCode: 200,
+								Request:    "POST /orders HTTP/1.1\r\nHost: shop.example.com\r\n\r\n",
+								Response:   "HTTP/1.0 200 OK\r\nContent-Length: 0\r\n\r\n",
+								ElapsedMS:  12,
+							},
+							Status: flows.CallStatusSuccess,
+						},
+						CreatedOn: dates.Now(),
+					},
+				},
+			),
+			`{
+				"type": "service_called",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"service": "commerce",
+				"http_logs": [
+					{
+						"url": "https://shop.example.com/orders",
+						"status_code": 200,
+						"status": "success",
+						"request": "POST /orders HTTP/1.1\r\nHost: shop.example.com\r\n\r\n",
+						"response": "HTTP/1.0 200 OK\r\nContent-Length: 0\r\n\r\n",
+						"elapsed_ms": 12,
+						"retries": 0,
+						"created_on": "2018-10-18T14:20:30.000123456Z"
+					}
+				]
+			}`,
+		},
+		{
+			events.NewDataCollectionCalled(
+				"products",
+				[]*flows.HTTPLog{
+					{
+						HTTPLogWithoutTime: &flows.HTTPLogWithoutTime{
+							LogWithoutTime: &httpx.LogWithoutTime{
+								URL:        "https://data.example.com/products",
+								StatusCode: 200,
+								Request:    "GET /products HTTP/1.1\r\nHost: data.example.com\r\n\r\n",
+								Response:   "HTTP/1.0 200 OK\r\nContent-Length: 0\r\n\r\n",
+								ElapsedMS:  12,
+							},
+							Status: flows.CallStatusSuccess,
+						},
+						CreatedOn: dates.Now(),
+					},
+				},
+			),
+			`{
+				"type": "service_called",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"service": "data_collection",
+				"collection": "products",
+				"http_logs": [
+					{
+						"url": "https://data.example.com/products",
+						"status_code": 200,
+						"status": "success",
+						"request": "GET /products HTTP/1.1\r\nHost: data.example.com\r\n\r\n",
+						"response": "HTTP/1.0 200 OK\r\nContent-Length: 0\r\n\r\n",
+						"elapsed_ms": 12,
+						"retries": 0,
+						"created_on": "2018-10-18T14:20:30.000123456Z"
+					}
+				]
+			}`,
+		},
+		{
+			events.NewCallTransferRequested(urns.URN("tel:+12065551313")),
+			`{
+				"type": "call_transfer_requested",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"urn": "tel:+12065551313"
+			}`,
+		},
+		{
+			events.NewCallForwarded(urns.URN("tel:+12065551313"), flows.NewCallTransfer(flows.CallTransferStatusCompleted, 42)),
+			`{
+				"type": "call_transferred",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"urn": "tel:+12065551313",
+				"status": "completed",
+				"duration": 42
+			}`,
+		},
+		{
+			events.NewConferenceJoined("support", flows.NewCallTransfer(flows.CallTransferStatusBusy, 0)),
+			`{
+				"type": "call_transferred",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"conference": "support",
+				"status": "busy",
+				"duration": 0
+			}`,
+		},
+		{
+			events.NewWebhookBreakerChanged(&flows.WebhookBreaker{Host: "api.example.com", State: flows.WebhookBreakerOpen, RetryOn: &retryOn}),
+			`{
+				"type": "webhook_breaker_changed",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"host": "api.example.com",
+				"state": "open",
+				"retry_on": "2018-10-18T14:21:30Z"
+			}`,
+		},
+		{
+			events.NewResthookSubscriberGone("new-registration", "http://api.example.com/hooks/1"),
+			`{
+				"type": "resthook_subscriber_gone",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"resthook": "new-registration",
+				"url": "http://api.example.com/hooks/1"
+			}`,
+		},
+		{
+			events.NewWebhookBlocked("http://169.254.169.254/latest/meta-data/", "address isn't allowed"),
+			`{
+				"type": "webhook_blocked",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"url": "http://169.254.169.254/latest/meta-data/",
+				"reason": "address isn't allowed"
+			}`,
+		},
+		{
+			events.NewWebhookResponseTruncated("http://api.example.com/users", 10000),
+			`{
+				"type": "webhook_response_truncated",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"url": "http://api.example.com/users",
+				"limit": 10000
+			}`,
+		},
+		{
+			events.NewWebhookResponseInvalid("http://api.example.com/users", []*jsonschema.Violation{{Path: "$.id", Message: "expected integer but found string"}}),
+			`{
+				"type": "webhook_response_invalid",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"url": "http://api.example.com/users",
+				"violations": [{"path": "$.id", "message": "expected integer but found string"}]
+			}`,
+		},
+		{
+			events.NewRaceWait([]string{"msg", "dial"}, &timeout),
+			`{
+				"type": "race_wait",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"waits": ["msg", "dial"],
+				"timeout_seconds": 500
+			}`,
+		},
+		{
+			events.NewRaceEnded("dial", []string{"msg"}),
+			`{
+				"type": "race_ended",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"winner": "dial",
+				"cancelled": ["msg"]
+			}`,
+		},
+		{
+			events.NewRecordingCreated("http://recordings.example.com/12065551212.mp3"),
+			`{
+				"type": "recording_created",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"url": "http://recordings.example.com/12065551212.mp3"
+			}`,
+		},
+		{
+			events.NewTimerSet(flows.NewTimer("followup", "b7c1e6a8-2bd1-4b1f-9c3f-1c9a8f3e4d52", time.Date(2018, 10, 20, 14, 20, 30, 0, time.UTC))),
+			`{
+				"type": "timer_set",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"name": "followup",
+				"node_uuid": "b7c1e6a8-2bd1-4b1f-9c3f-1c9a8f3e4d52",
+				"fires_on": "2018-10-20T14:20:30Z"
+			}`,
+		},
+		{
+			events.NewTimerCancelled("followup"),
+			`{
+				"type": "timer_cancelled",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"name": "followup"
+			}`,
+		},
+		{
+			events.NewTimerFired("followup"),
+			`{
+				"type": "timer_fired",
+				"created_on": "2018-10-18T14:20:30.000123456Z",
+				"name": "followup"
+			}`,
+		},
 	}
 
 	for _, tc := range eventTests {
@@ -712,14 +1254,14 @@ func TestWebhookCalledEventTrimming(t *testing.T) {
 
 	request, _ := http.NewRequest("GET", "http://temba.io/", strings.NewReader(strings.Repeat("X", 20000)))
 
-	svc := webhooks.NewService(http.DefaultClient, nil, nil, nil, 1024*1024)
+	svc := webhooks.NewService(http.DefaultClient, nil, nil, nil, 1024*1024, 0)
 	call, err := svc.Call(request)
 	require.NoError(t, err)
 
 	assert.Equal(t, 42, len(call.ResponseTrace))
 	assert.Equal(t, 20000, len(call.ResponseBody))
 
-	event := events.NewWebhookCalled(call, flows.CallStatusSuccess, "")
+	event := events.NewWebhookCalled(call, flows.CallStatusSuccess, "", nil)
 
 	assert.Equal(t, "http://temba.io/", event.URL)
 	assert.Equal(t, 10000, len(event.Request))
@@ -739,11 +1281,11 @@ func TestWebhookCalledEventValid(t *testing.T) {
 
 	request, _ := http.NewRequest("GET", "http://temba.io/", nil)
 
-	svc := webhooks.NewService(http.DefaultClient, nil, nil, nil, 1024*1024)
+	svc := webhooks.NewService(http.DefaultClient, nil, nil, nil, 1024*1024, 0)
 	call, err := svc.Call(request)
 	require.NoError(t, err)
 
-	event := events.NewWebhookCalled(call, flows.CallStatusSuccess, "")
+	event := events.NewWebhookCalled(call, flows.CallStatusSuccess, "", nil)
 
 	assert.Equal(t, "http://temba.io/", event.URL)
 	assert.Equal(t, "HTTP/1.0 200 OK\r\nContent-Length: 14\r\nHeader: hello\r\n\r\n{\"foo\": \"bar\"}", event.Response)
@@ -762,11 +1304,11 @@ func TestWebhookCalledEventNullChar(t *testing.T) {
 
 	request, _ := http.NewRequest("GET", "http://temba.io/", nil)
 
-	svc := webhooks.NewService(http.DefaultClient, nil, nil, nil, 1024*1024)
+	svc := webhooks.NewService(http.DefaultClient, nil, nil, nil, 1024*1024, 0)
 	call, err := svc.Call(request)
 	require.NoError(t, err)
 
-	event := events.NewWebhookCalled(call, flows.CallStatusSuccess, "")
+	event := events.NewWebhookCalled(call, flows.CallStatusSuccess, "", nil)
 
 	// actual null will have been stripped, escaped null will remain
 	assert.Equal(t, "http://temba.io/", event.URL)
@@ -786,11 +1328,11 @@ func TestWebhookCalledEventBadUTF8(t *testing.T) {
 
 	request, _ := http.NewRequest("GET", "http://temba.io/", nil)
 
-	svc := webhooks.NewService(http.DefaultClient, nil, nil, nil, 1024*1024)
+	svc := webhooks.NewService(http.DefaultClient, nil, nil, nil, 1024*1024, 0)
 	call, err := svc.Call(request)
 	require.NoError(t, err)
 
-	event := events.NewWebhookCalled(call, flows.CallStatusSuccess, "")
+	event := events.NewWebhookCalled(call, flows.CallStatusSuccess, "", nil)
 
 	assert.Equal(t, "http://temba.io/", event.URL)
 	assert.Equal(t, "HTTP/1.0 200 OK\r\nContent-Length: 13\r\nBad-Header: �\r\n\r\n...", event.Response)
@@ -0,0 +1,39 @@
+package events
+
+import (
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeCallTransferRequested, func() flows.Event { return &CallTransferRequestedEvent{} })
+}
+
+// TypeCallTransferRequested is the type of our call transfer requested event
+const TypeCallTransferRequested string = "call_transfer_requested"
+
+// CallTransferRequestedEvent events are created when a flow asks for the call to be handed off to another number. The
+// caller should transfer the call once the sprint has ended, after which the contact is no longer in the flow.
+//
+//	{
+//	  "type": "call_transfer_requested",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "urn": "tel:+12065551313"
+//	}
+//
+// @event call_transfer_requested
+type CallTransferRequestedEvent struct {
+	BaseEvent
+
+	URN urns.URN `json:"urn" validate:"required,urn"`
+}
+
+// NewCallTransferRequested returns a new call transfer requested event
+func NewCallTransferRequested(urn urns.URN) *CallTransferRequestedEvent {
+	return &CallTransferRequestedEvent{
+		BaseEvent: NewBaseEvent(TypeCallTransferRequested),
+		URN:       urn,
+	}
+}
+
+var _ flows.Event = (*CallTransferRequestedEvent)(nil)
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeCallTransferred, func() flows.Event { return &CallTransferredEvent{} })
+}
+
+// TypeCallTransferred is the type of our call transferred event
+const TypeCallTransferred string = "call_transferred"
+
+// CallTransferredEvent events are created when a call has been forwarded to another number or joined to a conference.
+// Only one of `urn` or `conference` will be set.
+//
+//	{
+//	  "type": "call_transferred",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "urn": "tel:+12065551313",
+//	  "status": "completed",
+//	  "duration": 42
+//	}
+//
+// @event call_transferred
+type CallTransferredEvent struct {
+	BaseEvent
+
+	URN        urns.URN                 `json:"urn,omitempty" validate:"omitempty,urn"`
+	Conference string                   `json:"conference,omitempty"`
+	Status     flows.CallTransferStatus `json:"status" validate:"required,call_transfer_status"`
+	Duration   int                      `json:"duration"`
+}
+
+// NewCallForwarded creates a new call transferred event for a call forwarded to the given URN
+func NewCallForwarded(urn urns.URN, transfer *flows.CallTransfer) *CallTransferredEvent {
+	return &CallTransferredEvent{
+		BaseEvent: NewBaseEvent(TypeCallTransferred),
+		URN:       urn,
+		Status:    transfer.Status,
+		Duration:  transfer.Duration,
+	}
+}
+
+// NewConferenceJoined creates a new call transferred event for a call joined to the given conference
+func NewConferenceJoined(conference string, transfer *flows.CallTransfer) *CallTransferredEvent {
+	return &CallTransferredEvent{
+		BaseEvent:  NewBaseEvent(TypeCallTransferred),
+		Conference: conference,
+		Status:     transfer.Status,
+		Duration:   transfer.Duration,
+	}
+}
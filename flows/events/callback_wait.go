@@ -0,0 +1,41 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeCallbackWait, func() flows.Event { return &CallbackWaitEvent{} })
+}
+
+// TypeCallbackWait is the type of our callback wait event
+const TypeCallbackWait string = "callback_wait"
+
+// CallbackWaitEvent events are created when a flow pauses waiting for the response to an asynchronous webhook
+// request, which should be delivered with a `webhook_callback` resume.
+//
+//	{
+//	  "type": "callback_wait",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "request_uuid": "b7c7a6d4-4e3f-4d8a-9b0e-2f5c1a8d9e7f",
+//	  "action_uuid": "8eebd020-1af5-431c-b943-aa670fc74da9"
+//	}
+//
+// @event callback_wait
+type CallbackWaitEvent struct {
+	BaseEvent
+
+	RequestUUID flows.WebhookRequestUUID `json:"request_uuid" validate:"required,uuid4"`
+	ActionUUID  flows.ActionUUID         `json:"action_uuid" validate:"required,uuid4"`
+}
+
+// NewCallbackWait returns a new callback wait for the given request made by the given action
+func NewCallbackWait(requestUUID flows.WebhookRequestUUID, actionUUID flows.ActionUUID) *CallbackWaitEvent {
+	return &CallbackWaitEvent{
+		BaseEvent:   NewBaseEvent(TypeCallbackWait),
+		RequestUUID: requestUUID,
+		ActionUUID:  actionUUID,
+	}
+}
+
+var _ flows.Event = (*CallbackWaitEvent)(nil)
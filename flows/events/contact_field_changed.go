@@ -2,6 +2,7 @@ package events
 
 import (
 	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
 )
 
@@ -13,28 +14,39 @@ func init() {
 const TypeContactFieldChanged string = "contact_field_changed"
 
 // ContactFieldChangedEvent events are created when a custom field value of the contact has been changed.
-// A null values indicates that the field value has been cleared.
+// A null values indicates that the field value has been cleared. If the field previously had a value, that is
+// included as `previous_value`. If the field is tagged as PII, its PII category is included as `pii`.
 //
 //	{
 //	  "type": "contact_field_changed",
 //	  "created_on": "2006-01-02T15:04:05Z",
 //	  "field": {"key": "gender", "name": "Gender"},
-//	  "value": {"text": "Male"}
+//	  "value": {"text": "Male"},
+//	  "previous_value": {"text": "Female"}
 //	}
 //
 // @event contact_field_changed
 type ContactFieldChangedEvent struct {
 	BaseEvent
 
-	Field *assets.FieldReference `json:"field" validate:"required"`
-	Value *flows.Value           `json:"value"`
+	Field         *assets.FieldReference `json:"field" validate:"required"`
+	Value         *flows.Value           `json:"value"`
+	PreviousValue *flows.Value           `json:"previous_value,omitempty"`
+	PII           envs.PIICategory       `json:"pii,omitempty"`
 }
 
 // NewContactFieldChanged returns a new save to contact event
-func NewContactFieldChanged(field *flows.Field, value *flows.Value) *ContactFieldChangedEvent {
+func NewContactFieldChanged(field *flows.Field, value, previous *flows.Value) *ContactFieldChangedEvent {
+	pii := envs.NilPIICategory
+	if field != nil {
+		pii = field.PII()
+	}
+
 	return &ContactFieldChangedEvent{
-		BaseEvent: NewBaseEvent(TypeContactFieldChanged),
-		Field:     field.Reference(),
-		Value:     value,
+		BaseEvent:     NewBaseEvent(TypeContactFieldChanged),
+		Field:         field.Reference(),
+		Value:         value,
+		PreviousValue: previous,
+		PII:           pii,
 	}
 }
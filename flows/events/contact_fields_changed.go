@@ -0,0 +1,56 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeContactFieldsChanged, func() flows.Event { return &ContactFieldsChangedEvent{} })
+}
+
+// TypeContactFieldsChanged is the type of our contact fields changed event
+const TypeContactFieldsChanged string = "contact_fields_changed"
+
+// FieldChange is a change to the value of a single field
+type FieldChange struct {
+	Field         *assets.FieldReference `json:"field" validate:"required"`
+	Value         *flows.Value           `json:"value"`
+	PreviousValue *flows.Value           `json:"previous_value,omitempty"`
+}
+
+// ContactFieldsChangedEvent events are created when several custom field values of the contact have been changed
+// together. Each change is described as in a [event:contact_field_changed] event.
+//
+//	{
+//	  "type": "contact_fields_changed",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "changes": [
+//	    {
+//	      "field": {"key": "gender", "name": "Gender"},
+//	      "value": {"text": "Male"},
+//	      "previous_value": {"text": "Female"}
+//	    },
+//	    {
+//	      "field": {"key": "age", "name": "Age"},
+//	      "value": {"text": "37", "number": 37}
+//	    }
+//	  ]
+//	}
+//
+// @event contact_fields_changed
+type ContactFieldsChangedEvent struct {
+	BaseEvent
+
+	Changes []*FieldChange `json:"changes" validate:"required,min=1,dive"`
+}
+
+// NewContactFieldsChanged returns a new contact fields changed event
+func NewContactFieldsChanged(changes []*FieldChange) *ContactFieldsChangedEvent {
+	return &ContactFieldsChangedEvent{
+		BaseEvent: NewBaseEvent(TypeContactFieldsChanged),
+		Changes:   changes,
+	}
+}
+
+var _ flows.Event = (*ContactFieldsChangedEvent)(nil)
@@ -0,0 +1,39 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeContactMerged, func() flows.Event { return &ContactMergedEvent{} })
+}
+
+// TypeContactMerged is the type of our contact merged event
+const TypeContactMerged string = "contact_merged"
+
+// ContactMergedEvent events are created when another contact, usually a duplicate, has been merged into the contact.
+// Any changes to the contact's URNs, fields and groups are described by their own events, and it's up to the caller
+// to remove the merged contact.
+//
+//	{
+//	  "type": "contact_merged",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "contact": {"uuid": "0cb17b2a-3bfe-4a19-8c99-98ab9561045d", "name": "Sarah Haggerty"}
+//	}
+//
+// @event contact_merged
+type ContactMergedEvent struct {
+	BaseEvent
+
+	Contact *flows.ContactReference `json:"contact" validate:"required"`
+}
+
+// NewContactMerged returns a new contact merged event
+func NewContactMerged(contact *flows.ContactReference) *ContactMergedEvent {
+	return &ContactMergedEvent{
+		BaseEvent: NewBaseEvent(TypeContactMerged),
+		Contact:   contact,
+	}
+}
+
+var _ flows.Event = (*ContactMergedEvent)(nil)
@@ -0,0 +1,40 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeContactRelationChanged, func() flows.Event { return &ContactRelationChangedEvent{} })
+}
+
+// TypeContactRelationChanged is the type of our contact relation changed event
+const TypeContactRelationChanged string = "contact_relation_changed"
+
+// ContactRelationChangedEvent events are created when the contact related to the contact by a relation type has been
+// changed. A null contact indicates that the relation has been cleared.
+//
+//	{
+//	  "type": "contact_relation_changed",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "relation_type": {"key": "caregiver", "name": "Caregiver"},
+//	  "contact": {"uuid": "0cb17b2a-3bfe-4a19-8c99-98ab9561045d", "name": "Sarah Haggerty"}
+//	}
+//
+// @event contact_relation_changed
+type ContactRelationChangedEvent struct {
+	BaseEvent
+
+	RelationType *assets.RelationTypeReference `json:"relation_type" validate:"required"`
+	Contact      *flows.ContactReference       `json:"contact"`
+}
+
+// NewContactRelationChanged returns a new contact relation changed event
+func NewContactRelationChanged(relationType *flows.RelationType, contact *flows.ContactReference) *ContactRelationChangedEvent {
+	return &ContactRelationChangedEvent{
+		BaseEvent:    NewBaseEvent(TypeContactRelationChanged),
+		RelationType: relationType.Reference(),
+		Contact:      contact,
+	}
+}
@@ -0,0 +1,40 @@
+package events
+
+import (
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeContactURNNormalized, func() flows.Event { return &ContactURNNormalizedEvent{} })
+}
+
+// TypeContactURNNormalized is the type of our URN normalized event
+const TypeContactURNNormalized string = "contact_urn_normalized"
+
+// ContactURNNormalizedEvent events are created when normalizing a URN changed its raw value, e.g. when a phone
+// number was formatted as E.164 using the environment's default country.
+//
+//	{
+//	  "type": "contact_urn_normalized",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "original": "tel:0788 123 123",
+//	  "urn": "tel:+250788123123"
+//	}
+//
+// @event contact_urn_normalized
+type ContactURNNormalizedEvent struct {
+	BaseEvent
+
+	Original urns.URN `json:"original" validate:"required"`
+	URN      urns.URN `json:"urn" validate:"required,urn"`
+}
+
+// NewContactURNNormalized returns a new URN normalized event
+func NewContactURNNormalized(original, urn urns.URN) *ContactURNNormalizedEvent {
+	return &ContactURNNormalizedEvent{
+		BaseEvent: NewBaseEvent(TypeContactURNNormalized),
+		Original:  original,
+		URN:       urn,
+	}
+}
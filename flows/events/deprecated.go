@@ -19,3 +19,6 @@ type ClassifierCalledEvent struct {
 	Classifier *assets.ClassifierReference `json:"classifier" validate:"required"`
 	HTTPLogs   []*flows.HTTPLog            `json:"http_logs"`
 }
+
+// Redact masks sensitive values in the HTTP logs of this event
+func (e *ClassifierCalledEvent) Redact(r *flows.Redactor) { r.RedactHTTPLogs(e.HTTPLogs) }
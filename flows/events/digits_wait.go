@@ -0,0 +1,51 @@
+package events
+
+import (
+	"time"
+
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeDigitsWait, func() flows.Event { return &DigitsWaitEvent{} })
+}
+
+// TypeDigitsWait is the type of our digits wait event
+const TypeDigitsWait string = "digits_wait"
+
+// DigitsWaitEvent events are created when a flow pauses waiting for the contact to enter digits on their dial pad. The
+// caller should stop gathering digits once `max_digits` have been entered or the `finish_on_key` key is pressed.
+//
+//	{
+//	  "type": "digits_wait",
+//	  "created_on": "2019-01-02T15:04:05Z",
+//	  "max_digits": 4,
+//	  "finish_on_key": "#",
+//	  "timeout_seconds": 10,
+//	  "expires_on": "2022-02-02T13:27:30Z"
+//	}
+//
+// @event digits_wait
+type DigitsWaitEvent struct {
+	BaseEvent
+
+	MaxDigits      int    `json:"max_digits,omitempty"`
+	FinishOnKey    string `json:"finish_on_key,omitempty"`
+	TimeoutSeconds *int   `json:"timeout_seconds,omitempty"`
+
+	// when this wait expires and the whole run can be expired
+	ExpiresOn *time.Time `json:"expires_on,omitempty"`
+}
+
+// NewDigitsWait returns a new digits wait event
+func NewDigitsWait(maxDigits int, finishOnKey string, timeoutSeconds *int, expiresOn *time.Time) *DigitsWaitEvent {
+	return &DigitsWaitEvent{
+		BaseEvent:      NewBaseEvent(TypeDigitsWait),
+		MaxDigits:      maxDigits,
+		FinishOnKey:    finishOnKey,
+		TimeoutSeconds: timeoutSeconds,
+		ExpiresOn:      expiresOn,
+	}
+}
+
+var _ flows.Event = (*DigitsWaitEvent)(nil)
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeEmailReceived, func() flows.Event { return &EmailReceivedEvent{} })
+}
+
+// TypeEmailReceived is a constant for incoming emails
+const TypeEmailReceived string = "email_received"
+
+// EmailReceivedEvent events are created when an email is received from the contact.
+//
+//	{
+//	  "type": "email_received",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "email": {
+//	    "uuid": "2d611e17-fb22-457f-b802-b8f7ec5cda5b",
+//	    "from": "bob@nyaruka.com",
+//	    "subject": "Order #1234",
+//	    "body": "Where is my order?",
+//	    "html_body": "<p>Where is my order?</p>",
+//	    "attachments": ["image/jpeg:https://s3.amazon.com/mybucket/receipt.jpg"]
+//	  }
+//	}
+//
+// @event email_received
+type EmailReceivedEvent struct {
+	BaseEvent
+
+	Email *flows.EmailIn `json:"email" validate:"required,dive"`
+}
+
+// NewEmailReceived creates a new incoming email event for the passed in email
+func NewEmailReceived(email *flows.EmailIn) *EmailReceivedEvent {
+	return &EmailReceivedEvent{
+		BaseEvent: NewBaseEvent(TypeEmailReceived),
+		Email:     email,
+	}
+}
+
+var _ flows.Event = (*EmailReceivedEvent)(nil)
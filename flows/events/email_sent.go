@@ -2,6 +2,7 @@ package events
 
 import (
 	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
 )
 
 func init() {
@@ -11,31 +12,46 @@ func init() {
 // TypeEmailSent is our type for the email event
 const TypeEmailSent string = "email_sent"
 
-// EmailSentEvent events are created when an action has sent an email.
+// EmailSentEvent events are created when an action has sent an email. Any credentials in the URLs of attachments are
+// redacted.
 //
 //	{
 //	  "type": "email_sent",
 //	  "created_on": "2006-01-02T15:04:05Z",
 //	  "to": ["foo@bar.com"],
+//	  "reply_to": "support@bar.com",
 //	  "subject": "Your activation token",
-//	  "body": "Your activation token is AAFFKKEE"
+//	  "body": "Your activation token is AAFFKKEE",
+//	  "html_body": "<p>Your activation token is <b>AAFFKKEE</b></p>",
+//	  "attachments": ["application/pdf:https://bar.com/terms.pdf"]
 //	}
 //
 // @event email_sent
 type EmailSentEvent struct {
 	BaseEvent
 
-	To      []string `json:"to" validate:"required,min=1"`
-	Subject string   `json:"subject" validate:"required"`
-	Body    string   `json:"body"`
+	To          []string           `json:"to" validate:"required,min=1"`
+	ReplyTo     string             `json:"reply_to,omitempty"`
+	Subject     string             `json:"subject" validate:"required"`
+	Body        string             `json:"body"`
+	HTMLBody    string             `json:"html_body,omitempty"`
+	Attachments []utils.Attachment `json:"attachments,omitempty"`
 }
 
-// NewEmailSent returns a new email event with the passed in subject, body and emails
-func NewEmailSent(to []string, subject string, body string) *EmailSentEvent {
+// NewEmailSent returns a new email event for the given email
+func NewEmailSent(email *flows.Email) *EmailSentEvent {
+	var attachments []utils.Attachment
+	for _, a := range email.Attachments {
+		attachments = append(attachments, a.Redacted())
+	}
+
 	return &EmailSentEvent{
-		BaseEvent: NewBaseEvent(TypeEmailSent),
-		To:        to,
-		Subject:   subject,
-		Body:      body,
+		BaseEvent:   NewBaseEvent(TypeEmailSent),
+		To:          email.Addresses,
+		ReplyTo:     email.ReplyTo,
+		Subject:     email.Subject,
+		Body:        email.Body,
+		HTMLBody:    email.HTMLBody,
+		Attachments: attachments,
 	}
 }
@@ -0,0 +1,102 @@
+// Package encoders provides encoders which write the events of a sprint in formats read by common log ingestion
+// pipelines, i.e. GELF, OTLP logs and newline-delimited JSON, with the nested fields of events flattened.
+package encoders
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+
+	"github.com/pkg/errors"
+)
+
+// Encoder writes a list of events in some ingestion format
+type Encoder interface {
+	Encode(w io.Writer, events []flows.Event) error
+}
+
+// Severity is the severity of an event
+type Severity string
+
+// severities of events
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+	SeverityFailure Severity = "failure"
+)
+
+// SeverityOf returns the severity of the given event, which is info for all events other than warnings, errors and
+// failures
+func SeverityOf(e flows.Event) Severity {
+	switch e.Type() {
+	case events.TypeWarning:
+		return SeverityWarning
+	case events.TypeError:
+		return SeverityError
+	case events.TypeFailure:
+		return SeverityFailure
+	}
+	return SeverityInfo
+}
+
+// Flatten returns the fields of the given event as a flat map of values keyed by their paths in the event's JSON, e.g.
+// "msg.urn" or "msg.attachments.0". Values are strings, booleans or json.Number.
+func Flatten(e flows.Event) (map[string]any, error) {
+	eventJSON, err := jsonx.Marshal(e)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error marshaling %s event", e.Type())
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(eventJSON))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, errors.Wrapf(err, "error decoding %s event", e.Type())
+	}
+
+	fields := make(map[string]any)
+	flatten("", value, fields)
+	return fields, nil
+}
+
+func flatten(path string, value any, fields map[string]any) {
+	switch typed := value.(type) {
+	case map[string]any:
+		for k, v := range typed {
+			flatten(join(path, k), v, fields)
+		}
+	case []any:
+		for i, v := range typed {
+			flatten(join(path, strconv.Itoa(i)), v, fields)
+		}
+	case nil:
+		// nulls are left out
+	default:
+		fields[path] = typed
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// replaces the characters of the given name which aren't letters, digits, underscores, periods or dashes
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,111 @@
+package encoders_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/events/encoders"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEvents() []flows.Event {
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	dates.SetNowSource(dates.NewFixedNowSource(time.Date(2018, 10, 18, 14, 20, 30, 123456789, time.UTC)))
+
+	result := flows.NewResult("Score", "12", "High", "", "", "12", []byte(`{"id": 12, "ratio": 0.5, "ok": true, "tags": ["a", "b"], "none": null}`), dates.Now())
+
+	return []flows.Event{
+		events.NewRunResultChanged(result),
+		events.NewWarningf("field 'age' doesn't exist"),
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	evts := testEvents()
+
+	fields, err := encoders.Flatten(evts[0])
+	require.NoError(t, err)
+	assert.Equal(t, "run_result_changed", fields["type"])
+	assert.Equal(t, "2018-10-18T14:20:30.123456789Z", fields["created_on"])
+	assert.Equal(t, "12", fields["value"])
+	assert.Equal(t, true, fields["extra.ok"])
+	assert.Equal(t, "b", fields["extra.tags.1"])
+	assert.NotContains(t, fields, "extra.none")
+	assert.NotContains(t, fields, "extra")
+
+	assert.Equal(t, encoders.SeverityInfo, encoders.SeverityOf(evts[0]))
+	assert.Equal(t, encoders.SeverityWarning, encoders.SeverityOf(evts[1]))
+	assert.Equal(t, encoders.SeverityError, encoders.SeverityOf(events.NewErrorf("boom")))
+}
+
+func TestEncoders(t *testing.T) {
+	evts := testEvents()
+	metadata := map[string]string{"session_uuid": "2d611e17-fb22-457f-b802-b8f7ec5cda5b"}
+
+	tcs := []struct {
+		encoder  encoders.Encoder
+		expected string
+	}{
+		{
+			encoders.NewNDJSONEncoder(metadata),
+			`{"category":"High","created_on":"2018-10-18T14:20:30.123456789Z","extra.id":12,"extra.ok":true,"extra.ratio":0.5,"extra.tags.0":"a","extra.tags.1":"b","input":"12","name":"Score","session_uuid":"2d611e17-fb22-457f-b802-b8f7ec5cda5b","type":"run_result_changed","value":"12"}` + "\n" +
+				`{"created_on":"2018-10-18T14:20:30.123456789Z","session_uuid":"2d611e17-fb22-457f-b802-b8f7ec5cda5b","text":"field 'age' doesn't exist","type":"warning"}` + "\n",
+		},
+		{
+			encoders.NewGELFEncoder("mailroom", metadata),
+			`{"_category":"High","_created_on":"2018-10-18T14:20:30.123456789Z","_extra.id":12,"_extra.ok":"true","_extra.ratio":0.5,"_extra.tags.0":"a","_extra.tags.1":"b","_input":"12","_name":"Score","_session_uuid":"2d611e17-fb22-457f-b802-b8f7ec5cda5b","_type":"run_result_changed","_value":"12","host":"mailroom","level":6,"short_message":"run_result_changed","timestamp":1539872430.123,"version":"1.1"}` + "\x00" +
+				`{"_created_on":"2018-10-18T14:20:30.123456789Z","_session_uuid":"2d611e17-fb22-457f-b802-b8f7ec5cda5b","_text":"field 'age' doesn't exist","_type":"warning","host":"mailroom","level":4,"short_message":"warning","timestamp":1539872430.123,"version":"1.1"}` + "\x00",
+		},
+	}
+
+	for _, tc := range tcs {
+		buf := &bytes.Buffer{}
+		require.NoError(t, tc.encoder.Encode(buf, evts))
+		assert.Equal(t, tc.expected, buf.String())
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, encoders.NewOTLPEncoder("mailroom", metadata).Encode(buf, evts[1:]))
+	assert.JSONEq(t, `{
+		"resourceLogs": [
+			{
+				"resource": {
+					"attributes": [
+						{"key": "service.name", "value": {"stringValue": "mailroom"}},
+						{"key": "session_uuid", "value": {"stringValue": "2d611e17-fb22-457f-b802-b8f7ec5cda5b"}}
+					]
+				},
+				"scopeLogs": [
+					{
+						"scope": {"name": "goflow"},
+						"logRecords": [
+							{
+								"timeUnixNano": "1539872430123456789",
+								"severityNumber": 13,
+								"severityText": "WARN",
+								"body": {"stringValue": "warning"},
+								"attributes": [
+									{"key": "created_on", "value": {"stringValue": "2018-10-18T14:20:30.123456789Z"}},
+									{"key": "text", "value": {"stringValue": "field 'age' doesn't exist"}},
+									{"key": "type", "value": {"stringValue": "warning"}}
+								]
+							}
+						]
+					}
+				]
+			}
+		]
+	}`, buf.String())
+
+	buf = &bytes.Buffer{}
+	require.NoError(t, encoders.NewOTLPEncoder("mailroom", nil).Encode(buf, evts[:1]))
+	assert.Contains(t, buf.String(), `{"key":"extra.id","value":{"intValue":"12"}}`)
+	assert.Contains(t, buf.String(), `{"key":"extra.ok","value":{"boolValue":true}}`)
+	assert.Contains(t, buf.String(), `{"key":"extra.ratio","value":{"doubleValue":0.5}}`)
+}
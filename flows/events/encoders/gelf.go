@@ -0,0 +1,81 @@
+package encoders
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows"
+
+	"github.com/pkg/errors"
+)
+
+// syslog levels used by GELF
+var gelfLevels = map[Severity]int{
+	SeverityInfo:    6,
+	SeverityWarning: 4,
+	SeverityError:   3,
+	SeverityFailure: 2,
+}
+
+// GELFEncoder writes each event as a GELF 1.1 message, with the event type as the short message and the flattened
+// fields of the event and any metadata as additional fields. Messages are terminated by null bytes as expected by GELF
+// TCP inputs.
+//
+//	{"version":"1.1","host":"mailroom","short_message":"msg_created","timestamp":1539872430.000,"level":6,"_msg.text":"Hi there",...}
+type GELFEncoder struct {
+	Host     string
+	Metadata map[string]string
+}
+
+// NewGELFEncoder creates a new GELF encoder
+func NewGELFEncoder(host string, metadata map[string]string) *GELFEncoder {
+	return &GELFEncoder{Host: host, Metadata: metadata}
+}
+
+// Encode writes the given events
+func (g *GELFEncoder) Encode(w io.Writer, events []flows.Event) error {
+	for _, e := range events {
+		fields, err := Flatten(e)
+		if err != nil {
+			return err
+		}
+
+		msg := map[string]any{
+			"version":       "1.1",
+			"host":          g.Host,
+			"short_message": e.Type(),
+			"timestamp":     json.Number(fmt.Sprintf("%d.%03d", e.CreatedOn().Unix(), e.CreatedOn().Nanosecond()/1000000)),
+			"level":         gelfLevels[SeverityOf(e)],
+		}
+		for k, v := range g.Metadata {
+			msg[gelfFieldName(k)] = v
+		}
+		for k, v := range fields {
+			// GELF additional fields can only be strings or numbers
+			if b, isBool := v.(bool); isBool {
+				v = fmt.Sprint(b)
+			}
+			msg[gelfFieldName(k)] = v
+		}
+
+		msgJSON, err := jsonx.Marshal(msg)
+		if err != nil {
+			return errors.Wrap(err, "error marshaling GELF message")
+		}
+		if _, err := w.Write(append(msgJSON, 0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// additional field names are prefixed with an underscore, and _id is reserved
+func gelfFieldName(name string) string {
+	name = "_" + sanitizeName(name)
+	if name == "_id" {
+		return "_event_id"
+	}
+	return name
+}
@@ -0,0 +1,46 @@
+package encoders
+
+import (
+	"io"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows"
+
+	"github.com/pkg/errors"
+)
+
+// NDJSONEncoder writes each event as a line of JSON with flattened fields, along with any metadata fields
+//
+//	{"created_on":"2018-10-18T14:20:30.000123456Z","msg.text":"Hi there","msg.urn":"tel:+12065551212","session_uuid":"...","type":"msg_created"}
+type NDJSONEncoder struct {
+	Metadata map[string]string
+}
+
+// NewNDJSONEncoder creates a new newline-delimited JSON encoder
+func NewNDJSONEncoder(metadata map[string]string) *NDJSONEncoder {
+	return &NDJSONEncoder{Metadata: metadata}
+}
+
+// Encode writes the given events
+func (n *NDJSONEncoder) Encode(w io.Writer, events []flows.Event) error {
+	for _, e := range events {
+		fields, err := Flatten(e)
+		if err != nil {
+			return err
+		}
+		for k, v := range n.Metadata {
+			if _, exists := fields[k]; !exists {
+				fields[k] = v
+			}
+		}
+
+		line, err := jsonx.Marshal(fields)
+		if err != nil {
+			return errors.Wrap(err, "error marshaling event fields")
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package encoders
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+// OTLP severity numbers and texts
+var otlpSeverities = map[Severity]struct {
+	number int
+	text   string
+}{
+	SeverityInfo:    {9, "INFO"},
+	SeverityWarning: {13, "WARN"},
+	SeverityError:   {17, "ERROR"},
+	SeverityFailure: {21, "FATAL"},
+}
+
+// OTLPEncoder writes events as an OTLP logs export request in the JSON encoding, which can be posted to the
+// /v1/logs endpoint of an OpenTelemetry collector. Each event is a log record with the event type as its body and the
+// flattened fields of the event as attributes. Metadata is added as attributes of the resource.
+type OTLPEncoder struct {
+	ServiceName string
+	Metadata    map[string]string
+}
+
+// NewOTLPEncoder creates a new OTLP logs encoder
+func NewOTLPEncoder(serviceName string, metadata map[string]string) *OTLPEncoder {
+	return &OTLPEncoder{ServiceName: serviceName, Metadata: metadata}
+}
+
+type otlpValue struct {
+	StringValue *string      `json:"stringValue,omitempty"`
+	BoolValue   *bool        `json:"boolValue,omitempty"`
+	IntValue    *string      `json:"intValue,omitempty"`
+	DoubleValue *json.Number `json:"doubleValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string     `json:"key"`
+	Value *otlpValue `json:"value"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string           `json:"timeUnixNano"`
+	SeverityNumber int              `json:"severityNumber"`
+	SeverityText   string           `json:"severityText"`
+	Body           *otlpValue       `json:"body"`
+	Attributes     []*otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	LogRecords []*otlpLogRecord `json:"logRecords"`
+}
+
+type otlpResourceLogs struct {
+	Resource struct {
+		Attributes []*otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeLogs []*otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpRequest struct {
+	ResourceLogs []*otlpResourceLogs `json:"resourceLogs"`
+}
+
+// Encode writes the given events
+func (o *OTLPEncoder) Encode(w io.Writer, events []flows.Event) error {
+	records := make([]*otlpLogRecord, len(events))
+	for i, e := range events {
+		fields, err := Flatten(e)
+		if err != nil {
+			return err
+		}
+
+		severity := otlpSeverities[SeverityOf(e)]
+		records[i] = &otlpLogRecord{
+			TimeUnixNano:   strconv.FormatInt(e.CreatedOn().UnixNano(), 10),
+			SeverityNumber: severity.number,
+			SeverityText:   severity.text,
+			Body:           newOTLPValue(e.Type()),
+			Attributes:     otlpAttributes(fields),
+		}
+	}
+
+	resource := map[string]any{"service.name": o.ServiceName}
+	for k, v := range o.Metadata {
+		resource[k] = v
+	}
+
+	scopeLogs := &otlpScopeLogs{LogRecords: records}
+	scopeLogs.Scope.Name = "goflow"
+
+	resourceLogs := &otlpResourceLogs{ScopeLogs: []*otlpScopeLogs{scopeLogs}}
+	resourceLogs.Resource.Attributes = otlpAttributes(resource)
+
+	request := &otlpRequest{ResourceLogs: []*otlpResourceLogs{resourceLogs}}
+
+	requestJSON, err := jsonx.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling OTLP request")
+	}
+	_, err = w.Write(requestJSON)
+	return err
+}
+
+func otlpAttributes(fields map[string]any) []*otlpAttribute {
+	attrs := make([]*otlpAttribute, 0, len(fields))
+	for _, k := range utils.SortedKeys(fields) {
+		attrs = append(attrs, &otlpAttribute{Key: k, Value: newOTLPValue(fields[k])})
+	}
+	return attrs
+}
+
+func newOTLPValue(v any) *otlpValue {
+	switch typed := v.(type) {
+	case bool:
+		return &otlpValue{BoolValue: &typed}
+	case json.Number:
+		if !strings.ContainsAny(string(typed), ".eE") {
+			s := string(typed)
+			return &otlpValue{IntValue: &s}
+		}
+		return &otlpValue{DoubleValue: &typed}
+	case string:
+		return &otlpValue{StringValue: &typed}
+	}
+	s := jsonx.MustMarshal(v)
+	str := string(s)
+	return &otlpValue{StringValue: &str}
+}
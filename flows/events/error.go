@@ -46,3 +46,6 @@ func NewErrorf(format string, a ...interface{}) *ErrorEvent {
 func NewDependencyError(ref assets.Reference) *ErrorEvent {
 	return NewErrorf("missing dependency: %s", ref.String())
 }
+
+// Redact masks sensitive values in the text of this event
+func (e *ErrorEvent) Redact(r *flows.Redactor) { e.Text = r.Redact(e.Text) }
@@ -33,3 +33,6 @@ func NewFailure(err error) *FailureEvent {
 		Text:      err.Error(),
 	}
 }
+
+// Redact masks sensitive values in the text of this event
+func (e *FailureEvent) Redact(r *flows.Redactor) { e.Text = r.Redact(e.Text) }
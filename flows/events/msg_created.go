@@ -1,7 +1,9 @@
 package events
 
 import (
+	"github.com/nyaruka/gocommon/urns"
 	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils/smsx"
 )
 
 func init() {
@@ -11,7 +13,10 @@ func init() {
 // TypeMsgCreated is a constant for incoming messages
 const TypeMsgCreated string = "msg_created"
 
-// MsgCreatedEvent events are created when an action wants to send a reply to the current contact.
+// MsgCreatedEvent events are created when an action wants to send a reply to the current contact. If the message
+// will be sent as SMS and is too long for a single segment, the event includes the encoding and the text of each of
+// the segments it will be split into. If the message is the prompt for a USSD wait and is too long for a USSD
+// response, the message is its first page and the event includes the remaining pages.
 //
 //	{
 //	  "type": "msg_created",
@@ -29,7 +34,9 @@ const TypeMsgCreated string = "msg_created"
 type MsgCreatedEvent struct {
 	BaseEvent
 
-	Msg *flows.MsgOut `json:"msg" validate:"required,dive"`
+	Msg       *flows.MsgOut      `json:"msg" validate:"required,dive"`
+	Segments  *smsx.Segmentation `json:"segments,omitempty"`
+	MorePages []string           `json:"more_pages,omitempty"`
 }
 
 // NewMsgCreated creates a new outgoing msg event to a single contact
@@ -37,5 +44,17 @@ func NewMsgCreated(msg *flows.MsgOut) *MsgCreatedEvent {
 	return &MsgCreatedEvent{
 		BaseEvent: NewBaseEvent(TypeMsgCreated),
 		Msg:       msg,
+		Segments:  segmentSMS(msg),
 	}
 }
+
+// works out how the given message will be split if it's sent as a multipart SMS, or returns nil if it won't be
+func segmentSMS(msg *flows.MsgOut) *smsx.Segmentation {
+	if msg.URN().Scheme() != urns.TelScheme || len(msg.Attachments()) > 0 {
+		return nil
+	}
+	if seg := smsx.Segment(msg.Text()); seg.Count > 1 {
+		return seg
+	}
+	return nil
+}
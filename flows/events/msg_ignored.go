@@ -0,0 +1,55 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeMsgIgnored, func() flows.Event { return &MsgIgnoredEvent{} })
+}
+
+// TypeMsgIgnored is the type of our msg ignored event
+const TypeMsgIgnored string = "msg_ignored"
+
+// MsgIgnoredReason is the reason an incoming message was ignored
+type MsgIgnoredReason string
+
+// possible reasons for ignoring an incoming message
+const (
+	MsgIgnoredReasonDuplicateExternalID MsgIgnoredReason = "duplicate_external_id"
+	MsgIgnoredReasonDuplicateText       MsgIgnoredReason = "duplicate_text"
+)
+
+// MsgIgnoredEvent events are created when a session is resumed with a message which the engine is configured to
+// ignore, e.g. a channel retry of the last message. The session isn't resumed and continues waiting.
+//
+//	{
+//	  "type": "msg_ignored",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "msg": {
+//	    "uuid": "2d611e17-fb22-457f-b802-b8f7ec5cda5b",
+//	    "urn": "tel:+12065551212",
+//	    "text": "hi there",
+//	    "external_id": "SMS1234"
+//	  },
+//	  "reason": "duplicate_external_id"
+//	}
+//
+// @event msg_ignored
+type MsgIgnoredEvent struct {
+	BaseEvent
+
+	Msg    flows.MsgIn      `json:"msg" validate:"required,dive"`
+	Reason MsgIgnoredReason `json:"reason" validate:"required"`
+}
+
+// NewMsgIgnored returns a new msg ignored event
+func NewMsgIgnored(msg *flows.MsgIn, reason MsgIgnoredReason) *MsgIgnoredEvent {
+	return &MsgIgnoredEvent{
+		BaseEvent: NewBaseEvent(TypeMsgIgnored),
+		Msg:       *msg,
+		Reason:    reason,
+	}
+}
+
+var _ flows.Event = (*MsgIgnoredEvent)(nil)
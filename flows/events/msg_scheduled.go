@@ -0,0 +1,48 @@
+package events
+
+import (
+	"time"
+
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeMsgScheduled, func() flows.Event { return &MsgScheduledEvent{} })
+}
+
+// TypeMsgScheduled is a constant for outgoing messages which should be sent later
+const TypeMsgScheduled string = "msg_scheduled"
+
+// MsgScheduledEvent events are created when an action wants to send a message to the current contact at a later time.
+// The caller is responsible for sending the message at `send_on`, regardless of whether the session is still active.
+//
+//	{
+//	  "type": "msg_scheduled",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "msg": {
+//	    "uuid": "2d611e17-fb22-457f-b802-b8f7ec5cda5b",
+//	    "channel": {"uuid": "61602f3e-f603-4c70-8a8f-c477505bf4bf", "name": "Twilio"},
+//	    "urn": "tel:+12065551212",
+//	    "text": "Don't forget to take your medication today"
+//	  },
+//	  "send_on": "2006-01-03T15:04:05Z"
+//	}
+//
+// @event msg_scheduled
+type MsgScheduledEvent struct {
+	BaseEvent
+
+	Msg    *flows.MsgOut `json:"msg" validate:"required,dive"`
+	SendOn time.Time     `json:"send_on" validate:"required"`
+}
+
+// NewMsgScheduled creates a new scheduled outgoing msg event for the given message
+func NewMsgScheduled(msg *flows.MsgOut, sendOn time.Time) *MsgScheduledEvent {
+	return &MsgScheduledEvent{
+		BaseEvent: NewBaseEvent(TypeMsgScheduled),
+		Msg:       msg,
+		SendOn:    sendOn,
+	}
+}
+
+var _ flows.Event = (*MsgScheduledEvent)(nil)
@@ -44,6 +44,12 @@ type MsgWaitEvent struct {
 	ExpiresOn *time.Time `json:"expires_on,omitempty"`
 
 	Hint flows.Hint `json:"hint,omitempty"`
+
+	// The remaining pages of a paginated USSD menu which will be shown if the contact replies with the more option
+	MorePages []string `json:"more_pages,omitempty"`
+
+	// The number of times the contact has already replied with input which the wait found invalid
+	InvalidAttempts int `json:"invalid_attempts,omitempty"`
 }
 
 // NewMsgWait returns a new msg wait with the passed in timeout
@@ -63,9 +69,11 @@ func NewMsgWait(timeoutSeconds *int, expiresOn *time.Time, hint flows.Hint) *Msg
 type msgWaitEnvelope struct {
 	BaseEvent
 
-	TimeoutSeconds *int            `json:"timeout_seconds,omitempty"`
-	ExpiresOn      *time.Time      `json:"expires_on,omitempty"`
-	Hint           json.RawMessage `json:"hint,omitempty"`
+	TimeoutSeconds  *int            `json:"timeout_seconds,omitempty"`
+	ExpiresOn       *time.Time      `json:"expires_on,omitempty"`
+	Hint            json.RawMessage `json:"hint,omitempty"`
+	MorePages       []string        `json:"more_pages,omitempty"`
+	InvalidAttempts int             `json:"invalid_attempts,omitempty"`
 }
 
 // UnmarshalJSON unmarshals this event from the given JSON
@@ -78,6 +86,8 @@ func (e *MsgWaitEvent) UnmarshalJSON(data []byte) error {
 	e.BaseEvent = v.BaseEvent
 	e.TimeoutSeconds = v.TimeoutSeconds
 	e.ExpiresOn = v.ExpiresOn
+	e.MorePages = v.MorePages
+	e.InvalidAttempts = v.InvalidAttempts
 
 	var err error
 	if v.Hint != nil {
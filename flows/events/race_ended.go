@@ -0,0 +1,41 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeRaceEnded, func() flows.Event { return &RaceEndedEvent{} })
+}
+
+// TypeRaceEnded is the type of our race ended event
+const TypeRaceEnded string = "race_ended"
+
+// RaceEndedEvent events are created when a session waiting on several waits at once is resumed. The `winner` is the
+// type of the resume and `cancelled` are the types of the waits which lost, and which the caller should stop waiting on.
+//
+//	{
+//	  "type": "race_ended",
+//	  "created_on": "2019-01-02T15:04:05Z",
+//	  "winner": "dial",
+//	  "cancelled": ["msg"]
+//	}
+//
+// @event race_ended
+type RaceEndedEvent struct {
+	BaseEvent
+
+	Winner    string   `json:"winner" validate:"required"`
+	Cancelled []string `json:"cancelled"`
+}
+
+// NewRaceEnded returns a new race ended event
+func NewRaceEnded(winner string, cancelled []string) *RaceEndedEvent {
+	return &RaceEndedEvent{
+		BaseEvent: NewBaseEvent(TypeRaceEnded),
+		Winner:    winner,
+		Cancelled: cancelled,
+	}
+}
+
+var _ flows.Event = (*RaceEndedEvent)(nil)
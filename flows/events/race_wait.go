@@ -0,0 +1,43 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeRaceWait, func() flows.Event { return &RaceWaitEvent{} })
+}
+
+// TypeRaceWait is the type of our race wait event
+const TypeRaceWait string = "race_wait"
+
+// RaceWaitEvent events are created when a flow pauses waiting on several waits at once. Each of those waits will also
+// have created its own event, and the first of them to be resumed wins.
+//
+//	{
+//	  "type": "race_wait",
+//	  "created_on": "2019-01-02T15:04:05Z",
+//	  "waits": ["msg", "dial"],
+//	  "timeout_seconds": 300
+//	}
+//
+// @event race_wait
+type RaceWaitEvent struct {
+	BaseEvent
+
+	Waits []string `json:"waits" validate:"required,min=1"`
+
+	// how long the race will wait before timing out
+	TimeoutSeconds *int `json:"timeout_seconds,omitempty"`
+}
+
+// NewRaceWait returns a new race wait event
+func NewRaceWait(waits []string, timeoutSeconds *int) *RaceWaitEvent {
+	return &RaceWaitEvent{
+		BaseEvent:      NewBaseEvent(TypeRaceWait),
+		Waits:          waits,
+		TimeoutSeconds: timeoutSeconds,
+	}
+}
+
+var _ flows.Event = (*RaceWaitEvent)(nil)
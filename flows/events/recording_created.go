@@ -0,0 +1,36 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeRecordingCreated, func() flows.Event { return &RecordingCreatedEvent{} })
+}
+
+// TypeRecordingCreated is a constant for recording created events
+const TypeRecordingCreated string = "recording_created"
+
+// RecordingCreatedEvent events are created when the recording of a call is stopped, and contain the URL of the recorded
+// media.
+//
+//	{
+//	  "type": "recording_created",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "url": "https://s3.amazon.com/mybucket/recording.mp3"
+//	}
+//
+// @event recording_created
+type RecordingCreatedEvent struct {
+	BaseEvent
+
+	URL string `json:"url" validate:"required"`
+}
+
+// NewRecordingCreated returns a new recording created event
+func NewRecordingCreated(url string) *RecordingCreatedEvent {
+	return &RecordingCreatedEvent{
+		BaseEvent: NewBaseEvent(TypeRecordingCreated),
+		URL:       url,
+	}
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeResthookSubscriberGone, func() flows.Event { return &ResthookSubscriberGoneEvent{} })
+}
+
+// TypeResthookSubscriberGone is the type of our resthook subscriber gone event
+const TypeResthookSubscriberGone string = "resthook_subscriber_gone"
+
+// ResthookSubscriberGoneEvent events are created when a subscriber of a resthook responds with a 410 status to say
+// that it no longer wants to be called, so that the caller can remove that subscription.
+//
+//	{
+//	  "type": "resthook_subscriber_gone",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "resthook": "new-registration",
+//	  "url": "http://localhost:49998/?cmd=gone"
+//	}
+//
+// @event resthook_subscriber_gone
+type ResthookSubscriberGoneEvent struct {
+	BaseEvent
+
+	Resthook string `json:"resthook" validate:"required"`
+	URL      string `json:"url" validate:"required"`
+}
+
+// NewResthookSubscriberGone returns a new resthook subscriber gone event
+func NewResthookSubscriberGone(resthook, url string) *ResthookSubscriberGoneEvent {
+	return &ResthookSubscriberGoneEvent{
+		BaseEvent: NewBaseEvent(TypeResthookSubscriberGone),
+		Resthook:  resthook,
+		URL:       url,
+	}
+}
+
+var _ flows.Event = (*ResthookSubscriberGoneEvent)(nil)
@@ -3,6 +3,7 @@ package events
 import (
 	"encoding/json"
 
+	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
 )
 
@@ -15,7 +16,8 @@ const TypeRunResultChanged string = "run_result_changed"
 
 // RunResultChangedEvent events are created when a run result is saved. They contain not only
 // the name, value and category of the result, but also the UUID of the node where
-// the result was generated.
+// the result was generated. If the result has a schema, the typed value of the result is also included, and if it's
+// tagged as PII, so is its PII category.
 //
 //	{
 //	  "type": "run_result_changed",
@@ -32,12 +34,15 @@ const TypeRunResultChanged string = "run_result_changed"
 type RunResultChangedEvent struct {
 	BaseEvent
 
-	Name              string          `json:"name" validate:"required"`
-	Value             string          `json:"value"`
-	Category          string          `json:"category"`
-	CategoryLocalized string          `json:"category_localized,omitempty"`
-	Input             string          `json:"input,omitempty"`
-	Extra             json.RawMessage `json:"extra,omitempty"`
+	Name              string           `json:"name" validate:"required"`
+	Value             string           `json:"value"`
+	Category          string           `json:"category"`
+	CategoryLocalized string           `json:"category_localized,omitempty"`
+	Input             string           `json:"input,omitempty"`
+	Extra             json.RawMessage  `json:"extra,omitempty"`
+	ValueType         flows.ResultType `json:"value_type,omitempty"`
+	TypedValue        json.RawMessage  `json:"typed_value,omitempty"`
+	PII               envs.PIICategory `json:"pii,omitempty"`
 }
 
 // NewRunResultChanged returns a new save result event for the passed in values
@@ -50,5 +55,8 @@ func NewRunResultChanged(result *flows.Result) *RunResultChangedEvent {
 		CategoryLocalized: result.CategoryLocalized,
 		Input:             result.Input,
 		Extra:             result.Extra,
+		ValueType:         result.ValueType,
+		TypedValue:        result.TypedValue,
+		PII:               result.PII,
 	}
 }
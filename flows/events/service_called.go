@@ -38,6 +38,7 @@ type ServiceCalledEvent struct {
 	Service    string                      `json:"service"`
 	Classifier *assets.ClassifierReference `json:"classifier,omitempty"`
 	Ticketer   *assets.TicketerReference   `json:"ticketer,omitempty"`
+	Collection string                      `json:"collection,omitempty"`
 	HTTPLogs   []*flows.HTTPLog            `json:"http_logs"`
 }
 
@@ -60,3 +61,34 @@ func NewTicketerCalled(ticketer *assets.TicketerReference, httpLogs []*flows.HTT
 		HTTPLogs:  httpLogs,
 	}
 }
+
+// NewCommerceCalled returns a service called event for a commerce service
+func NewCommerceCalled(httpLogs []*flows.HTTPLog) *ServiceCalledEvent {
+	return &ServiceCalledEvent{
+		BaseEvent: NewBaseEvent(TypeServiceCalled),
+		Service:   "commerce",
+		HTTPLogs:  httpLogs,
+	}
+}
+
+// NewDataCollectionCalled returns a service called event for a data collection
+func NewDataCollectionCalled(collection string, httpLogs []*flows.HTTPLog) *ServiceCalledEvent {
+	return &ServiceCalledEvent{
+		BaseEvent:  NewBaseEvent(TypeServiceCalled),
+		Service:    "data_collection",
+		Collection: collection,
+		HTTPLogs:   httpLogs,
+	}
+}
+
+// NewExchangeRateCalled returns a service called event for an exchange rate service
+func NewExchangeRateCalled(httpLogs []*flows.HTTPLog) *ServiceCalledEvent {
+	return &ServiceCalledEvent{
+		BaseEvent: NewBaseEvent(TypeServiceCalled),
+		Service:   "exchange_rate",
+		HTTPLogs:  httpLogs,
+	}
+}
+
+// Redact masks sensitive values in the HTTP logs of this event
+func (e *ServiceCalledEvent) Redact(r *flows.Redactor) { r.RedactHTTPLogs(e.HTTPLogs) }
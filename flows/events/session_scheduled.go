@@ -0,0 +1,45 @@
+package events
+
+import (
+	"time"
+
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeSessionScheduled, func() flows.Event { return &SessionScheduledEvent{} })
+}
+
+// TypeSessionScheduled is the type of our session scheduled event
+const TypeSessionScheduled string = "session_scheduled"
+
+// SessionScheduledEvent events are created when a flow pauses until a point in time. The caller should resume the
+// session with a `time_reached` resume at `resume_on`.
+//
+//	{
+//	  "type": "session_scheduled",
+//	  "created_on": "2019-01-02T15:04:05Z",
+//	  "resume_on": "2019-01-03T09:00:00Z",
+//	  "expires_on": "2019-01-10T09:00:00Z"
+//	}
+//
+// @event session_scheduled
+type SessionScheduledEvent struct {
+	BaseEvent
+
+	ResumeOn time.Time `json:"resume_on" validate:"required"`
+
+	// when this wait expires and the whole run can be expired
+	ExpiresOn *time.Time `json:"expires_on,omitempty"`
+}
+
+// NewSessionScheduled returns a new session scheduled event
+func NewSessionScheduled(resumeOn time.Time, expiresOn *time.Time) *SessionScheduledEvent {
+	return &SessionScheduledEvent{
+		BaseEvent: NewBaseEvent(TypeSessionScheduled),
+		ResumeOn:  resumeOn,
+		ExpiresOn: expiresOn,
+	}
+}
+
+var _ flows.Event = (*SessionScheduledEvent)(nil)
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeSprintProfile, func() flows.Event { return &SprintProfileEvent{} })
+}
+
+// TypeSprintProfile is the type of our sprint profile event
+const TypeSprintProfile string = "sprint_profile"
+
+// SprintProfileEvent events are created at the end of each sprint when the engine has profiling enabled. They list
+// the actions, routers, templates and service calls which took the most time, slowest first.
+//
+//	{
+//	  "type": "sprint_profile",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "elapsed_ms": 1523.25,
+//	  "actions": [{"name": "call_webhook[uuid=9f3c2d1e-6b1f-4a8a-9d2e-1c1f0a7b5e4d]", "count": 1, "elapsed_ms": 1502.1}],
+//	  "routers": [{"name": "switch[node=5b8a5c6d-0f4c-4e6e-9a1d-3c6e2f1a0b9e]", "count": 1, "elapsed_ms": 0.12}],
+//	  "templates": [{"name": "Hi @contact.name", "count": 2, "elapsed_ms": 0.08}],
+//	  "services": [{"name": "webhook", "count": 1, "elapsed_ms": 1501.9}]
+//	}
+//
+// @event sprint_profile
+type SprintProfileEvent struct {
+	BaseEvent
+
+	ElapsedMS float64                `json:"elapsed_ms"`
+	Actions   []*flows.ProfileTiming `json:"actions"`
+	Routers   []*flows.ProfileTiming `json:"routers"`
+	Templates []*flows.ProfileTiming `json:"templates"`
+	Services  []*flows.ProfileTiming `json:"services"`
+}
+
+// NewSprintProfile returns a new sprint profile event listing up to limit of the slowest things in each category
+func NewSprintProfile(profiler *flows.Profiler, limit int) *SprintProfileEvent {
+	return &SprintProfileEvent{
+		BaseEvent: NewBaseEvent(TypeSprintProfile),
+		ElapsedMS: profiler.ElapsedMS(),
+		Actions:   profiler.Slowest(flows.ProfileCategoryAction, limit),
+		Routers:   profiler.Slowest(flows.ProfileCategoryRouter, limit),
+		Templates: profiler.Slowest(flows.ProfileCategoryTemplate, limit),
+		Services:  profiler.Slowest(flows.ProfileCategoryService, limit),
+	}
+}
+
+var _ flows.Event = (*SprintProfileEvent)(nil)
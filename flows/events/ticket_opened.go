@@ -16,12 +16,14 @@ type Ticket struct {
 	UUID       flows.TicketUUID          `json:"uuid"                   validate:"required,uuid4"`
 	Ticketer   *assets.TicketerReference `json:"ticketer"               validate:"required,dive"`
 	Topic      *assets.TopicReference    `json:"topic"                  validate:"omitempty,dive"`
+	Queue      string                    `json:"queue,omitempty"`
 	Body       string                    `json:"body"`
 	ExternalID string                    `json:"external_id,omitempty"`
 	Assignee   *assets.UserReference     `json:"assignee,omitempty"     validate:"omitempty,dive"`
 }
 
-// TicketOpenedEvent events are created when a new ticket is opened.
+// TicketOpenedEvent events are created when a new ticket is opened. If the ticket has a topic with a queue, that is
+// included so that the ticket can be routed without looking up the topic.
 //
 //	{
 //	  "type": "ticket_opened",
@@ -36,6 +38,7 @@ type Ticket struct {
 //	      "uuid": "add17edf-0b6e-4311-bcd7-a64b2a459157",
 //	      "name": "Weather"
 //	    },
+//	    "queue": "weather-desk",
 //	    "body": "Where are my cookies?",
 //	    "external_id": "32526523",
 //	    "assignee": {"email": "bob@nyaruka.com", "name": "Bob"}
@@ -51,12 +54,18 @@ type TicketOpenedEvent struct {
 
 // NewTicketOpened returns a new ticket opened event
 func NewTicketOpened(ticket *flows.Ticket) *TicketOpenedEvent {
+	var queue string
+	if ticket.Topic() != nil {
+		queue = ticket.Topic().Queue()
+	}
+
 	return &TicketOpenedEvent{
 		BaseEvent: NewBaseEvent(TypeTicketOpened),
 		Ticket: &Ticket{
 			UUID:       ticket.UUID(),
 			Ticketer:   ticket.Ticketer().Reference(),
 			Topic:      ticket.Topic().Reference(),
+			Queue:      queue,
 			Body:       ticket.Body(),
 			ExternalID: ticket.ExternalID(),
 			Assignee:   ticket.Assignee().Reference(),
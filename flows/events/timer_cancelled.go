@@ -0,0 +1,38 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeTimerCancelled, func() flows.Event { return &TimerCancelledEvent{} })
+}
+
+// TypeTimerCancelled is the type of our timer cancelled event
+const TypeTimerCancelled string = "timer_cancelled"
+
+// TimerCancelledEvent events are created when a run cancels a timer it set earlier. The caller should unschedule the
+// timer with that name.
+//
+//	{
+//	  "type": "timer_cancelled",
+//	  "created_on": "2019-01-02T15:04:05Z",
+//	  "name": "followup"
+//	}
+//
+// @event timer_cancelled
+type TimerCancelledEvent struct {
+	BaseEvent
+
+	Name string `json:"name" validate:"required"`
+}
+
+// NewTimerCancelled returns a new timer cancelled event
+func NewTimerCancelled(name string) *TimerCancelledEvent {
+	return &TimerCancelledEvent{
+		BaseEvent: NewBaseEvent(TypeTimerCancelled),
+		Name:      name,
+	}
+}
+
+var _ flows.Event = (*TimerCancelledEvent)(nil)
@@ -0,0 +1,37 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeTimerFired, func() flows.Event { return &TimerFiredEvent{} })
+}
+
+// TypeTimerFired is the type of our timer fired event
+const TypeTimerFired string = "timer_fired"
+
+// TimerFiredEvent events are created when a session is resumed because a timer set by one of its runs has fired.
+//
+//	{
+//	  "type": "timer_fired",
+//	  "created_on": "2019-01-02T15:04:05Z",
+//	  "name": "followup"
+//	}
+//
+// @event timer_fired
+type TimerFiredEvent struct {
+	BaseEvent
+
+	Name string `json:"name" validate:"required"`
+}
+
+// NewTimerFired returns a new timer fired event
+func NewTimerFired(name string) *TimerFiredEvent {
+	return &TimerFiredEvent{
+		BaseEvent: NewBaseEvent(TypeTimerFired),
+		Name:      name,
+	}
+}
+
+var _ flows.Event = (*TimerFiredEvent)(nil)
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"time"
+
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeTimerSet, func() flows.Event { return &TimerSetEvent{} })
+}
+
+// TypeTimerSet is the type of our timer set event
+const TypeTimerSet string = "timer_set"
+
+// TimerSetEvent events are created when a run sets a named timer. The caller should schedule a `timer_fired` resume
+// for the session at `fires_on`, replacing any timer it has already scheduled with the same name.
+//
+//	{
+//	  "type": "timer_set",
+//	  "created_on": "2019-01-02T15:04:05Z",
+//	  "name": "followup",
+//	  "node_uuid": "3dcccbb4-d29c-41dd-a01f-16d814c9ab82",
+//	  "fires_on": "2019-01-04T15:04:05Z"
+//	}
+//
+// @event timer_set
+type TimerSetEvent struct {
+	BaseEvent
+
+	Name     string         `json:"name" validate:"required"`
+	NodeUUID flows.NodeUUID `json:"node_uuid" validate:"required,uuid4"`
+	FiresOn  time.Time      `json:"fires_on" validate:"required"`
+}
+
+// NewTimerSet returns a new timer set event for the given timer
+func NewTimerSet(timer *flows.Timer) *TimerSetEvent {
+	return &TimerSetEvent{
+		BaseEvent: NewBaseEvent(TypeTimerSet),
+		Name:      timer.Name,
+		NodeUUID:  timer.NodeUUID,
+		FiresOn:   timer.FiresOn,
+	}
+}
+
+var _ flows.Event = (*TimerSetEvent)(nil)
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeValueTruncated, func() flows.Event { return &ValueTruncatedEvent{} })
+}
+
+// TypeValueTruncated is the type of our value truncated event
+const TypeValueTruncated string = "value_truncated"
+
+// TruncationTarget is the kind of value which was truncated
+type TruncationTarget string
+
+// possible targets of truncation
+const (
+	TruncationTargetField  TruncationTarget = "field"
+	TruncationTargetResult TruncationTarget = "result"
+	TruncationTargetMsg    TruncationTarget = "msg"
+)
+
+// ValueTruncatedEvent events are created when a value is longer than the limit allowed by the environment's truncation
+// policy and has been truncated. The name is the field key or result name, and is omitted for message text.
+//
+//	{
+//	  "type": "value_truncated",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "target": "field",
+//	  "name": "gender",
+//	  "length": 700,
+//	  "limit": 640
+//	}
+//
+// @event value_truncated
+type ValueTruncatedEvent struct {
+	BaseEvent
+
+	Target TruncationTarget `json:"target" validate:"required,eq=field|eq=result|eq=msg"`
+	Name   string           `json:"name,omitempty"`
+	Length int              `json:"length"`
+	Limit  int              `json:"limit"`
+}
+
+// NewValueTruncated returns a new value truncated event
+func NewValueTruncated(target TruncationTarget, name string, length, limit int) *ValueTruncatedEvent {
+	return &ValueTruncatedEvent{
+		BaseEvent: NewBaseEvent(TypeValueTruncated),
+		Target:    target,
+		Name:      name,
+		Length:    length,
+		Limit:     limit,
+	}
+}
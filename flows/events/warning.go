@@ -0,0 +1,38 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeWarning, func() flows.Event { return &WarningEvent{} })
+}
+
+// TypeWarning is the type of our warning events
+const TypeWarning string = "warning"
+
+// WarningEvent events are created when something went wrong during flow execution but the engine was able to recover
+// from it, e.g. a value was truncated.
+//
+//	{
+//	  "type": "warning",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "text": "quick reply truncated to 64 characters"
+//	}
+//
+// @event warning
+type WarningEvent struct {
+	BaseEvent
+
+	Text string `json:"text" validate:"required"`
+}
+
+// NewWarningf returns a new warning event for the passed in format string and args
+func NewWarningf(format string, a ...interface{}) *WarningEvent {
+	return &WarningEvent{
+		BaseEvent: NewBaseEvent(TypeWarning),
+		Text:      fmt.Sprintf(format, a...),
+	}
+}
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeWebhookBlocked, func() flows.Event { return &WebhookBlockedEvent{} })
+}
+
+// TypeWebhookBlocked is the type of our webhook blocked event
+const TypeWebhookBlocked string = "webhook_blocked"
+
+// WebhookBlockedEvent events are created instead of an error event when a webhook call isn't made because its URL, or
+// an address that its host resolves to, isn't allowed by the host policy of the webhook service, e.g. because it's a
+// private or link-local address.
+//
+//	{
+//	  "type": "webhook_blocked",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "url": "http://169.254.169.254/latest/meta-data/",
+//	  "reason": "address isn't allowed"
+//	}
+//
+// @event webhook_blocked
+type WebhookBlockedEvent struct {
+	BaseEvent
+
+	URL    string `json:"url" validate:"required"`
+	Reason string `json:"reason"`
+}
+
+// NewWebhookBlocked returns a new webhook blocked event
+func NewWebhookBlocked(url, reason string) *WebhookBlockedEvent {
+	return &WebhookBlockedEvent{
+		BaseEvent: NewBaseEvent(TypeWebhookBlocked),
+		URL:       url,
+		Reason:    reason,
+	}
+}
+
+var _ flows.Event = (*WebhookBlockedEvent)(nil)
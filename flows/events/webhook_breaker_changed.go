@@ -0,0 +1,45 @@
+package events
+
+import (
+	"time"
+
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeWebhookBreakerChanged, func() flows.Event { return &WebhookBreakerChangedEvent{} })
+}
+
+// TypeWebhookBreakerChanged is the type of our webhook breaker changed event
+const TypeWebhookBreakerChanged string = "webhook_breaker_changed"
+
+// WebhookBreakerChangedEvent events are created when a webhook call changes the state of the circuit breaker for its
+// host. An `open` breaker means calls to that host are temporarily disabled until `retry_on`, and a `closed` breaker
+// means the host has recovered.
+//
+//	{
+//	  "type": "webhook_breaker_changed",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "host": "api.example.com",
+//	  "state": "open",
+//	  "retry_on": "2006-01-02T15:05:05Z"
+//	}
+//
+// @event webhook_breaker_changed
+type WebhookBreakerChangedEvent struct {
+	BaseEvent
+
+	Host    string                    `json:"host" validate:"required"`
+	State   flows.WebhookBreakerState `json:"state" validate:"required"`
+	RetryOn *time.Time                `json:"retry_on,omitempty"`
+}
+
+// NewWebhookBreakerChanged returns a new webhook breaker changed event
+func NewWebhookBreakerChanged(breaker *flows.WebhookBreaker) *WebhookBreakerChangedEvent {
+	return &WebhookBreakerChangedEvent{
+		BaseEvent: NewBaseEvent(TypeWebhookBreakerChanged),
+		Host:      breaker.Host,
+		State:     breaker.State,
+		RetryOn:   breaker.RetryOn,
+	}
+}
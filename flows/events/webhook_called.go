@@ -1,6 +1,7 @@
 package events
 
 import (
+	"github.com/nyaruka/gocommon/stringsx"
 	"github.com/nyaruka/goflow/flows"
 )
 
@@ -18,6 +19,7 @@ const (
 	ExtractionValid   Extraction = "valid"   // body was valid JSON
 	ExtractionCleaned Extraction = "cleaned" // body could be made into JSON with some cleaning
 	ExtractionIgnored Extraction = "ignored" // body couldn't be made into JSON and was ignored
+	ExtractionXML     Extraction = "xml"     // body was XML which was converted to JSON
 )
 
 // WebhookCalledEvent events are created when a webhook is called. The event contains
@@ -47,12 +49,14 @@ type WebhookCalledEvent struct {
 	Extraction Extraction `json:"extraction"`
 }
 
-// NewWebhookCalled returns a new webhook called event
-func NewWebhookCalled(call *flows.WebhookCall, status flows.CallStatus, resthook string) *WebhookCalledEvent {
+// NewWebhookCalled returns a new webhook called event, with any secrets in the request or response redacted
+func NewWebhookCalled(call *flows.WebhookCall, status flows.CallStatus, resthook string, redact stringsx.Redactor) *WebhookCalledEvent {
 	extraction := ExtractionNone
 	if len(call.ResponseBody) > 0 {
 		if len(call.ResponseJSON) > 0 {
-			if call.ResponseCleaned {
+			if call.ResponseFromXML {
+				extraction = ExtractionXML
+			} else if call.ResponseCleaned {
 				extraction = ExtractionCleaned
 			} else {
 				extraction = ExtractionValid
@@ -64,8 +68,11 @@ func NewWebhookCalled(call *flows.WebhookCall, status flows.CallStatus, resthook
 
 	return &WebhookCalledEvent{
 		BaseEvent:          NewBaseEvent(TypeWebhookCalled),
-		HTTPLogWithoutTime: flows.NewHTTPLogWithoutTime(call.Trace, status, nil),
+		HTTPLogWithoutTime: flows.NewHTTPLogWithoutTime(call.Trace, status, redact),
 		Resthook:           resthook,
 		Extraction:         extraction,
 	}
 }
+
+// Redact masks sensitive values in the request and response of this event
+func (e *WebhookCalledEvent) Redact(r *flows.Redactor) { r.RedactHTTPLog(e.HTTPLogWithoutTime) }
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeWebhookRequested, func() flows.Event { return &WebhookRequestedEvent{} })
+}
+
+// TypeWebhookRequested is the type for our webhook requested events
+const TypeWebhookRequested string = "webhook_requested"
+
+// WebhookRequestedEvent events are created when a webhook is called asynchronously. Rather than making the request
+// itself, the engine hands it off to the caller which should make the request and resume the session with a
+// `webhook_callback` resume once it has the response. If a `credential` is specified, the caller should resolve it to
+// the value of the Authorization header. As this is the request the caller is expected to make, it isn't masked by
+// the environment's redaction rules.
+//
+//	{
+//	  "type": "webhook_requested",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "request_uuid": "b7c7a6d4-4e3f-4d8a-9b0e-2f5c1a8d9e7f",
+//	  "method": "POST",
+//	  "url": "http://localhost:49998/?cmd=success",
+//	  "headers": {"Content-Type": "application/json"},
+//	  "credential": "crm_token",
+//	  "body": "{\"contact\": \"Bob\"}"
+//	}
+//
+// @event webhook_requested
+type WebhookRequestedEvent struct {
+	BaseEvent
+
+	RequestUUID flows.WebhookRequestUUID `json:"request_uuid" validate:"required,uuid4"`
+	Method      string                   `json:"method" validate:"required"`
+	URL         string                   `json:"url" validate:"required"`
+	Headers     map[string]string        `json:"headers,omitempty"`
+	Credential  string                   `json:"credential,omitempty"`
+	Body        string                   `json:"body,omitempty"`
+}
+
+// NewWebhookRequested returns a new webhook requested event
+func NewWebhookRequested(uuid flows.WebhookRequestUUID, method, url string, headers map[string]string, credential, body string) *WebhookRequestedEvent {
+	return &WebhookRequestedEvent{
+		BaseEvent:   NewBaseEvent(TypeWebhookRequested),
+		RequestUUID: uuid,
+		Method:      method,
+		URL:         url,
+		Headers:     headers,
+		Credential:  credential,
+		Body:        body,
+	}
+}
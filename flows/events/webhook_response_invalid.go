@@ -0,0 +1,44 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils/jsonschema"
+)
+
+func init() {
+	registerType(TypeWebhookResponseInvalid, func() flows.Event { return &WebhookResponseInvalidEvent{} })
+}
+
+// TypeWebhookResponseInvalid is the type of our webhook response invalid event
+const TypeWebhookResponseInvalid string = "webhook_response_invalid"
+
+// WebhookResponseInvalidEvent events are created when the response to a webhook call doesn't match the response schema
+// declared by the action, with the `violations` of that schema found in the response.
+//
+//	{
+//	  "type": "webhook_response_invalid",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "url": "http://localhost:49998/?cmd=success",
+//	  "violations": [
+//	    {"path": "$.order.id", "message": "expected integer but found string"}
+//	  ]
+//	}
+//
+// @event webhook_response_invalid
+type WebhookResponseInvalidEvent struct {
+	BaseEvent
+
+	URL        string                  `json:"url" validate:"required"`
+	Violations []*jsonschema.Violation `json:"violations" validate:"required,min=1"`
+}
+
+// NewWebhookResponseInvalid returns a new webhook response invalid event
+func NewWebhookResponseInvalid(url string, violations []*jsonschema.Violation) *WebhookResponseInvalidEvent {
+	return &WebhookResponseInvalidEvent{
+		BaseEvent:  NewBaseEvent(TypeWebhookResponseInvalid),
+		URL:        url,
+		Violations: violations,
+	}
+}
+
+var _ flows.Event = (*WebhookResponseInvalidEvent)(nil)
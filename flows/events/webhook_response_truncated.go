@@ -0,0 +1,41 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeWebhookResponseTruncated, func() flows.Event { return &WebhookResponseTruncatedEvent{} })
+}
+
+// TypeWebhookResponseTruncated is the type of our webhook response truncated event
+const TypeWebhookResponseTruncated string = "webhook_response_truncated"
+
+// WebhookResponseTruncatedEvent events are created when the body of a webhook response was larger than the size limit
+// and was cut off after `limit` bytes. The rest of the response isn't read and what was read isn't extracted as JSON.
+//
+//	{
+//	  "type": "webhook_response_truncated",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "url": "http://localhost:49998/?cmd=success",
+//	  "limit": 10000
+//	}
+//
+// @event webhook_response_truncated
+type WebhookResponseTruncatedEvent struct {
+	BaseEvent
+
+	URL   string `json:"url" validate:"required"`
+	Limit int    `json:"limit"`
+}
+
+// NewWebhookResponseTruncated returns a new webhook response truncated event
+func NewWebhookResponseTruncated(url string, limit int) *WebhookResponseTruncatedEvent {
+	return &WebhookResponseTruncatedEvent{
+		BaseEvent: NewBaseEvent(TypeWebhookResponseTruncated),
+		URL:       url,
+		Limit:     limit,
+	}
+}
+
+var _ flows.Event = (*WebhookResponseTruncatedEvent)(nil)
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeWhatsAppFlowCreated, func() flows.Event { return &WhatsAppFlowCreatedEvent{} })
+}
+
+// TypeWhatsAppFlowCreated is a constant for WhatsApp Flow forms sent to the contact
+const TypeWhatsAppFlowCreated string = "whatsapp_flow_created"
+
+// WhatsAppFlowCreatedEvent events are created when an action wants to send a WhatsApp Flow form to the contact.
+//
+//	{
+//	  "type": "whatsapp_flow_created",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "flow": {
+//	    "channel": {"uuid": "61602f3e-f603-4c70-8a8f-c477505bf4bf", "name": "WhatsApp"},
+//	    "urn": "whatsapp:12065551212",
+//	    "flow_id": "1234567890",
+//	    "flow_token": "2d611e17-fb22-457f-b802-b8f7ec5cda5b",
+//	    "screen": "SIGN_UP",
+//	    "body": "Please complete your registration",
+//	    "button": "Sign up",
+//	    "data": {"name": "Bob"},
+//	    "results": {"age": "Age"}
+//	  }
+//	}
+//
+// @event whatsapp_flow_created
+type WhatsAppFlowCreatedEvent struct {
+	BaseEvent
+
+	Flow *flows.WhatsAppFlow `json:"flow" validate:"required,dive"`
+}
+
+// NewWhatsAppFlowCreated creates a new WhatsApp Flow created event
+func NewWhatsAppFlowCreated(flow *flows.WhatsAppFlow) *WhatsAppFlowCreatedEvent {
+	return &WhatsAppFlowCreatedEvent{
+		BaseEvent: NewBaseEvent(TypeWhatsAppFlowCreated),
+		Flow:      flow,
+	}
+}
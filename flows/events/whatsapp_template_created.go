@@ -0,0 +1,47 @@
+package events
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeWhatsAppTemplateCreated, func() flows.Event { return &WhatsAppTemplateCreatedEvent{} })
+}
+
+// TypeWhatsAppTemplateCreated is a constant for WhatsApp template messages sent to the contact
+const TypeWhatsAppTemplateCreated string = "whatsapp_template_created"
+
+// WhatsAppTemplateCreatedEvent events are created when an action wants to send an approved WhatsApp template message
+// to the contact. The params of each component of the template are included after being evaluated.
+//
+//	{
+//	  "type": "whatsapp_template_created",
+//	  "created_on": "2006-01-02T15:04:05Z",
+//	  "msg": {
+//	    "channel": {"uuid": "61602f3e-f603-4c70-8a8f-c477505bf4bf", "name": "WhatsApp"},
+//	    "urn": "whatsapp:12065551212",
+//	    "template": {"uuid": "9c22b594-fcab-4b29-9bcb-ce4404894a80", "name": "appointment"},
+//	    "namespace": "2d40b45c_25cd_4965_9019_f05d0124c5fa",
+//	    "locale": "eng-US",
+//	    "components": [
+//	      {"type": "header", "name": "header", "params": ["Dentist"]},
+//	      {"type": "body", "name": "body", "params": ["Bob", "Tuesday"]}
+//	    ],
+//	    "text": "Hi Bob, your appointment is on Tuesday"
+//	  }
+//	}
+//
+// @event whatsapp_template_created
+type WhatsAppTemplateCreatedEvent struct {
+	BaseEvent
+
+	Msg *flows.WhatsAppTemplateMsg `json:"msg" validate:"required,dive"`
+}
+
+// NewWhatsAppTemplateCreated creates a new WhatsApp template created event
+func NewWhatsAppTemplateCreated(msg *flows.WhatsAppTemplateMsg) *WhatsAppTemplateCreatedEvent {
+	return &WhatsAppTemplateCreatedEvent{
+		BaseEvent: NewBaseEvent(TypeWhatsAppTemplateCreated),
+		Msg:       msg,
+	}
+}
@@ -0,0 +1,25 @@
+package flows
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpguts"
+)
+
+// ExitWebhook is a webhook declared by a flow which the engine calls with a summary of each run of that flow when it
+// exits, whether that's by completing, expiring, being interrupted or failing. The URL and header values can contain
+// expressions which are evaluated in the context of the exited run.
+type ExitWebhook struct {
+	URL     string            `json:"url" validate:"required"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Retries int               `json:"retries,omitempty" validate:"min=0,max=3"`
+}
+
+// Validate checks that the headers of this webhook are valid
+func (w *ExitWebhook) Validate() error {
+	for key := range w.Headers {
+		if !httpguts.ValidHeaderFieldName(key) {
+			return errors.Errorf("header '%s' is not a valid HTTP header", key)
+		}
+	}
+	return nil
+}
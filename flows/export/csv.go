@@ -0,0 +1,45 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// WriteCSV writes the given table as CSV with a header row of column names. Datetimes are written in RFC3339 format
+// and missing values as empty strings.
+func WriteCSV(w io.Writer, t *Table) error {
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = c.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(t.Columns))
+	for _, row := range t.Rows {
+		for i, v := range row {
+			switch typed := v.(type) {
+			case string:
+				record[i] = typed
+			case decimal.Decimal:
+				record[i] = typed.String()
+			case time.Time:
+				record[i] = typed.UTC().Format(time.RFC3339Nano)
+			default:
+				record[i] = ""
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,120 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/export"
+	"github.com/nyaruka/goflow/flows/triggers"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var assetsJSON = `{
+	"flows": [
+		{
+			"uuid": "7c3db26f-e12a-48af-9673-e2feefdf8516",
+			"name": "Registration",
+			"spec_version": "13.0",
+			"language": "eng",
+			"type": "messaging",
+			"nodes": [
+				{
+					"uuid": "46d51f50-58de-49da-8d13-dadbf322685d",
+					"actions": [
+						{"uuid": "e97cd6d5-3354-4dbd-85bc-6c1f87849eec", "type": "set_run_result", "name": "Age", "value": "@(if(contact.name = \"Bob\", 23, \"unknown\"))", "schema": {"type": "number"}, "pii": "health"},
+						{"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a", "type": "set_run_result", "name": "Joined", "value": "2020-01-02T03:04:05Z", "schema": {"type": "datetime"}},
+						{"uuid": "d2a4052a-3fa9-4608-ab3e-5b9631440447", "type": "set_run_result", "name": "Favorite Color", "value": "@(upper(contact.name)), \"red\"", "category": "Red"}
+					],
+					"exits": [{"uuid": "7651ca02-775c-42f0-bfad-72ef1776c332"}]
+				}
+			]
+		}
+	]
+}`
+
+func TestExport(t *testing.T) {
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	defer uuids.SetGenerator(uuids.DefaultGenerator)
+
+	dates.SetNowSource(dates.NewFixedNowSource(time.Date(2018, 10, 18, 14, 20, 30, 123456000, time.UTC)))
+	uuids.SetGenerator(uuids.NewSeededGenerator(12345))
+
+	env := envs.NewBuilder().Build()
+	source, err := static.NewSource([]byte(assetsJSON))
+	require.NoError(t, err)
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	flow, err := sa.Flows().Get("7c3db26f-e12a-48af-9673-e2feefdf8516")
+	require.NoError(t, err)
+
+	eng := engine.NewBuilder().Build()
+	sessions := make([]flows.Session, 0, 2)
+	for _, name := range []string{"Bob", "Ann"} {
+		contact := flows.NewEmptyContact(sa, name, envs.NilLanguage, nil)
+		trigger := triggers.NewBuilder(env, flow.Reference(false), contact).Manual().Build()
+		session, _, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+		sessions = append(sessions, session)
+	}
+
+	table := export.NewTable(sa, flow, sessions)
+
+	columns := make([]string, len(table.Columns))
+	for i, c := range table.Columns {
+		columns[i] = c.Name + ":" + string(c.Type)
+	}
+	assert.Equal(t, []string{
+		"run_uuid:text", "contact_uuid:text", "contact_name:text", "created_on:datetime", "exited_on:datetime",
+		"age:number", "joined:datetime", "favorite_color:text",
+	}, columns)
+
+	require.Equal(t, 2, len(table.Rows))
+	assert.Equal(t, "Bob", table.Rows[0][2])
+	assert.Equal(t, decimal.RequireFromString("23"), table.Rows[0][5])
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), table.Rows[0][6].(time.Time).UTC())
+	assert.Nil(t, table.Rows[1][5]) // not a valid number
+
+	csv := &bytes.Buffer{}
+	require.NoError(t, export.WriteCSV(csv, table))
+	assert.Equal(t, `run_uuid,contact_uuid,contact_name,created_on,exited_on,age,joined,favorite_color
+`+string(sessions[0].Runs()[0].UUID())+`,`+string(sessions[0].Contact().UUID())+`,Bob,2018-10-18T14:20:30.123456Z,2018-10-18T14:20:30.123456Z,23,2020-01-02T03:04:05Z,"BOB, ""red"""
+`+string(sessions[1].Runs()[0].UUID())+`,`+string(sessions[1].Contact().UUID())+`,Ann,2018-10-18T14:20:30.123456Z,2018-10-18T14:20:30.123456Z,,2020-01-02T03:04:05Z,"ANN, ""red"""
+`, csv.String())
+
+	parquet := &bytes.Buffer{}
+	require.NoError(t, export.WriteParquet(parquet, table))
+
+	data := parquet.Bytes()
+	assert.Equal(t, "PAR1", string(data[:4]))
+	assert.Equal(t, "PAR1", string(data[len(data)-4:]))
+
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8 : len(data)-4]))
+	assert.Less(t, footerLen, len(data)-12)
+	assert.Contains(t, string(data[len(data)-8-footerLen:]), "favorite_color")
+
+	// columns are tagged with the PII categories of their values, which can be redacted
+	assert.Equal(t, envs.PIICategoryName, table.Columns[2].PII)
+	assert.Equal(t, envs.PIICategoryHealth, table.Columns[5].PII)
+	assert.Equal(t, envs.NilPIICategory, table.Columns[7].PII)
+
+	table.Redact(&envs.RedactionRules{PII: []envs.PIICategory{envs.PIICategoryHealth}})
+
+	assert.Equal(t, "Bob", table.Rows[0][2])
+	assert.Equal(t, export.ColumnTypeText, table.Columns[5].Type)
+	assert.Equal(t, flows.RedactionMask, table.Rows[0][5])
+	assert.Nil(t, table.Rows[1][5])
+	assert.Equal(t, "BOB, \"red\"", table.Rows[0][7])
+}
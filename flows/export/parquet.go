@@ -0,0 +1,260 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// parquet physical types, converted types and other enums which we use
+const (
+	parquetTypeInt64     = 2
+	parquetTypeDouble    = 5
+	parquetTypeByteArray = 6
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMicros = 10
+
+	parquetRepetitionOptional = 1
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetPageTypeData = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+// WriteParquet writes the given table as an uncompressed Parquet file with a single row group. Every column is optional
+// so that missing values can be written as nulls. Text is written as UTF8 byte arrays, numbers as doubles and datetimes
+// as microsecond timestamps.
+func WriteParquet(w io.Writer, t *Table) error {
+	buf := &bytes.Buffer{}
+	buf.Write(parquetMagic)
+
+	chunks := make([]*thriftWriter, len(t.Columns))
+	totalSize := 0
+
+	for i, col := range t.Columns {
+		offset := buf.Len()
+		page := parquetPage(t, i)
+
+		header := &thriftWriter{}
+		header.fieldI32(1, parquetPageTypeData)
+		header.fieldI32(2, int32(len(page)))
+		header.fieldI32(3, int32(len(page)))
+		header.fieldStructBegin(5)
+		header.fieldI32(1, int32(len(t.Rows)))
+		header.fieldI32(2, parquetEncodingPlain)
+		header.fieldI32(3, parquetEncodingRLE)
+		header.fieldI32(4, parquetEncodingRLE)
+		header.structEnd()
+		header.structEnd()
+
+		buf.Write(header.Bytes())
+		buf.Write(page)
+
+		size := int64(buf.Len() - offset)
+		totalSize += int(size)
+
+		chunk := &thriftWriter{}
+		chunk.fieldI64(2, int64(offset))
+		chunk.fieldStructBegin(3)
+		chunk.fieldI32(1, parquetPhysicalType(col.Type))
+		chunk.fieldListBegin(2, thriftTypeI32, 2)
+		chunk.i32(parquetEncodingPlain)
+		chunk.i32(parquetEncodingRLE)
+		chunk.fieldListBegin(3, thriftTypeBinary, 1)
+		chunk.binary([]byte(col.Name))
+		chunk.fieldI32(4, 0) // uncompressed
+		chunk.fieldI64(5, int64(len(t.Rows)))
+		chunk.fieldI64(6, size)
+		chunk.fieldI64(7, size)
+		chunk.fieldI64(9, int64(offset))
+		chunk.structEnd()
+		chunk.structEnd()
+
+		chunks[i] = chunk
+	}
+
+	// the file metadata is written as the footer
+	meta := &thriftWriter{}
+	meta.fieldI32(1, 1)
+	meta.fieldListBegin(2, thriftTypeStruct, len(t.Columns)+1)
+	meta.structBegin()
+	meta.fieldBinary(4, []byte("schema"))
+	meta.fieldI32(5, int32(len(t.Columns)))
+	meta.structEnd()
+	for _, col := range t.Columns {
+		meta.structBegin()
+		meta.fieldI32(1, parquetPhysicalType(col.Type))
+		meta.fieldI32(3, parquetRepetitionOptional)
+		meta.fieldBinary(4, []byte(col.Name))
+		switch col.Type {
+		case ColumnTypeText:
+			meta.fieldI32(6, parquetConvertedUTF8)
+		case ColumnTypeDatetime:
+			meta.fieldI32(6, parquetConvertedTimestampMicros)
+		}
+		meta.structEnd()
+	}
+	meta.fieldI64(3, int64(len(t.Rows)))
+	meta.fieldListBegin(4, thriftTypeStruct, 1)
+	meta.structBegin()
+	meta.fieldListBegin(1, thriftTypeStruct, len(chunks))
+	for _, chunk := range chunks {
+		meta.Write(chunk.Bytes())
+	}
+	meta.fieldI64(2, int64(totalSize))
+	meta.fieldI64(3, int64(len(t.Rows)))
+	meta.structEnd()
+	meta.fieldBinary(6, []byte("goflow"))
+	meta.structEnd()
+
+	buf.Write(meta.Bytes())
+	binary.Write(buf, binary.LittleEndian, uint32(meta.Len()))
+	buf.Write(parquetMagic)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func parquetPhysicalType(t ColumnType) int32 {
+	switch t {
+	case ColumnTypeNumber:
+		return parquetTypeDouble
+	case ColumnTypeDatetime:
+		return parquetTypeInt64
+	}
+	return parquetTypeByteArray
+}
+
+// encodes the values of the given column as a data page, i.e. definition levels followed by the non-null values
+func parquetPage(t *Table, col int) []byte {
+	levels := make([]byte, len(t.Rows))
+	values := &bytes.Buffer{}
+
+	for r, row := range t.Rows {
+		switch typed := row[col].(type) {
+		case string:
+			binary.Write(values, binary.LittleEndian, uint32(len(typed)))
+			values.WriteString(typed)
+		case decimal.Decimal:
+			f, _ := typed.Float64()
+			binary.Write(values, binary.LittleEndian, math.Float64bits(f))
+		case time.Time:
+			binary.Write(values, binary.LittleEndian, typed.UnixMicro())
+		default:
+			continue
+		}
+		levels[r] = 1
+	}
+
+	// definition levels are encoded as RLE runs, prefixed by their length
+	encoded := &bytes.Buffer{}
+	for i := 0; i < len(levels); {
+		run := 1
+		for i+run < len(levels) && levels[i+run] == levels[i] {
+			run++
+		}
+		encoded.Write(binary.AppendUvarint(nil, uint64(run)<<1))
+		encoded.WriteByte(levels[i])
+		i += run
+	}
+
+	page := &bytes.Buffer{}
+	binary.Write(page, binary.LittleEndian, uint32(encoded.Len()))
+	page.Write(encoded.Bytes())
+	page.Write(values.Bytes())
+	return page.Bytes()
+}
+
+// thrift compact protocol types
+const (
+	thriftTypeI32    = 5
+	thriftTypeI64    = 6
+	thriftTypeBinary = 8
+	thriftTypeList   = 9
+	thriftTypeStruct = 12
+)
+
+// thriftWriter writes structs in the thrift compact protocol which is used for Parquet metadata. Field IDs are written
+// relative to the previous field in the same struct, so the last field ID of each enclosing struct is kept on a stack.
+type thriftWriter struct {
+	bytes.Buffer
+	lastID  int16
+	lastIDs []int16
+}
+
+func (w *thriftWriter) fieldHeader(id int16, typ byte) {
+	if delta := id - w.lastID; delta > 0 && delta <= 15 {
+		w.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.WriteByte(typ)
+		w.varint(int64(id))
+	}
+	w.lastID = id
+}
+
+func (w *thriftWriter) varint(v int64) {
+	w.Write(binary.AppendUvarint(nil, uint64((v<<1)^(v>>63))))
+}
+
+func (w *thriftWriter) i32(v int32) { w.varint(int64(v)) }
+
+func (w *thriftWriter) binary(b []byte) {
+	w.Write(binary.AppendUvarint(nil, uint64(len(b))))
+	w.Write(b)
+}
+
+func (w *thriftWriter) fieldI32(id int16, v int32) {
+	w.fieldHeader(id, thriftTypeI32)
+	w.i32(v)
+}
+
+func (w *thriftWriter) fieldI64(id int16, v int64) {
+	w.fieldHeader(id, thriftTypeI64)
+	w.varint(v)
+}
+
+func (w *thriftWriter) fieldBinary(id int16, b []byte) {
+	w.fieldHeader(id, thriftTypeBinary)
+	w.binary(b)
+}
+
+// begins a list field, which must be followed by its elements
+func (w *thriftWriter) fieldListBegin(id int16, elemType byte, size int) {
+	w.fieldHeader(id, thriftTypeList)
+	if size < 15 {
+		w.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		w.WriteByte(0xF0 | elemType)
+		w.Write(binary.AppendUvarint(nil, uint64(size)))
+	}
+}
+
+// begins a struct field, which must be followed by its fields and its end
+func (w *thriftWriter) fieldStructBegin(id int16) {
+	w.fieldHeader(id, thriftTypeStruct)
+	w.structBegin()
+}
+
+// begins a struct which isn't a field, e.g. an element of a list
+func (w *thriftWriter) structBegin() {
+	w.lastIDs = append(w.lastIDs, w.lastID)
+	w.lastID = 0
+}
+
+func (w *thriftWriter) structEnd() {
+	w.WriteByte(0)
+	if n := len(w.lastIDs); n > 0 {
+		w.lastID = w.lastIDs[n-1]
+		w.lastIDs = w.lastIDs[:n-1]
+	} else {
+		w.lastID = 0
+	}
+}
@@ -0,0 +1,165 @@
+// Package export converts the runs of completed sessions into tables with a column for each result of their flow,
+// which can be written as CSV or Parquet for loading into a data warehouse.
+package export
+
+import (
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/actions"
+)
+
+// ColumnType is the type of the values in a column
+type ColumnType string
+
+// possible column types
+const (
+	ColumnTypeText     ColumnType = "text"
+	ColumnTypeNumber   ColumnType = "number"
+	ColumnTypeDatetime ColumnType = "datetime"
+)
+
+// Column is a column in a table, which is tagged with a PII category if its values are personally identifiable
+type Column struct {
+	Name string
+	Type ColumnType
+	PII  envs.PIICategory
+}
+
+// Table is a table of runs. Values are nil, strings, decimals or times depending on the type of their column.
+type Table struct {
+	Columns []*Column
+	Rows    [][]any
+}
+
+// the columns of every table which come before the result columns
+var runColumns = []*Column{
+	{Name: "run_uuid", Type: ColumnTypeText},
+	{Name: "contact_uuid", Type: ColumnTypeText},
+	{Name: "contact_name", Type: ColumnTypeText, PII: envs.PIICategoryName},
+	{Name: "created_on", Type: ColumnTypeDatetime},
+	{Name: "exited_on", Type: ColumnTypeDatetime},
+}
+
+// NewTable creates a table of the runs of the given flow in the given sessions. Results are found by inspecting the flow
+// and each gets a column named by its key, whose type is taken from the result's schema if it has one, and which is
+// tagged with the result's PII category if it has one.
+func NewTable(sa flows.SessionAssets, flow flows.Flow, sessions []flows.Session) *Table {
+	schemas, piis := resultDeclarations(flow)
+	results := flow.Inspect(sa).Results
+
+	columns := append([]*Column(nil), runColumns...)
+	for _, r := range results {
+		columns = append(columns, &Column{Name: r.Key, Type: columnType(schemas[r.Key]), PII: piis[r.Key]})
+	}
+
+	rows := make([][]any, 0, len(sessions))
+	for _, session := range sessions {
+		for _, run := range session.Runs() {
+			if run.FlowReference().UUID != flow.UUID() {
+				continue
+			}
+
+			row := make([]any, len(columns))
+			row[0] = string(run.UUID())
+			if contact := run.Contact(); contact != nil {
+				row[1] = string(contact.UUID())
+				row[2] = contact.Name()
+			}
+			row[3] = run.CreatedOn()
+			if run.ExitedOn() != nil {
+				row[4] = *run.ExitedOn()
+			}
+
+			for i, r := range results {
+				if result := run.Results().Get(r.Key); result != nil {
+					row[len(runColumns)+i] = cellValue(run, result, columns[len(runColumns)+i].Type)
+				}
+			}
+
+			rows = append(rows, row)
+		}
+	}
+
+	return &Table{Columns: columns, Rows: rows}
+}
+
+// Redact replaces the values of columns tagged with PII categories which the given rules redact. Those columns become
+// text columns since their values are all masks.
+func (t *Table) Redact(rules *envs.RedactionRules) {
+	for c, col := range t.Columns {
+		if !rules.HasPII(col.PII) {
+			continue
+		}
+		col.Type = ColumnTypeText
+		for _, row := range t.Rows {
+			if row[c] != nil {
+				row[c] = flows.RedactionMask
+			}
+		}
+	}
+}
+
+// finds the declared schemas and PII categories of the results of the given flow, keyed by result key
+func resultDeclarations(flow flows.Flow) (map[string]*flows.ResultSchema, map[string]envs.PIICategory) {
+	schemas := make(map[string]*flows.ResultSchema)
+	piis := make(map[string]envs.PIICategory)
+
+	declare := func(name string, schema *flows.ResultSchema, pii envs.PIICategory) {
+		key := flows.NewResultInfo(name, nil).Key
+		if schema != nil {
+			schemas[key] = schema
+		}
+		if pii != envs.NilPIICategory {
+			piis[key] = pii
+		}
+	}
+
+	for _, node := range flow.Nodes() {
+		for _, action := range node.Actions() {
+			if setResult, isSetResult := action.(*actions.SetRunResultAction); isSetResult {
+				declare(setResult.Name, setResult.Schema, setResult.PII)
+			}
+		}
+		if router, isDeclaring := node.Router().(interface {
+			ResultSchema() *flows.ResultSchema
+			ResultPII() envs.PIICategory
+		}); isDeclaring && node.Router().ResultName() != "" {
+			declare(node.Router().ResultName(), router.ResultSchema(), router.ResultPII())
+		}
+	}
+
+	return schemas, piis
+}
+
+func columnType(schema *flows.ResultSchema) ColumnType {
+	if schema != nil {
+		switch schema.Type {
+		case flows.ResultTypeNumber:
+			return ColumnTypeNumber
+		case flows.ResultTypeDatetime:
+			return ColumnTypeDatetime
+		}
+	}
+	return ColumnTypeText
+}
+
+// gets the value of the given result for a column of the given type, which is nil if the value isn't of that type
+func cellValue(run flows.Run, result *flows.Result, typ ColumnType) any {
+	env := run.Environment()
+	value := result.ContextProperty(env, "value")
+
+	switch typ {
+	case ColumnTypeNumber:
+		if num, xerr := types.ToXNumber(env, value); xerr == nil {
+			return num.Native()
+		}
+		return nil
+	case ColumnTypeDatetime:
+		if dt, xerr := types.ToXDateTime(env, value); xerr == nil {
+			return dt.Native()
+		}
+		return nil
+	}
+	return result.Value
+}
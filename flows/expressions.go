@@ -1,9 +1,12 @@
 package flows
 
 import (
+	"encoding/xml"
 	"strconv"
+	"strings"
 
 	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent"
 	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/utils"
 )
@@ -13,8 +16,22 @@ type Contextable interface {
 	Context(env envs.Environment) map[string]types.XValue
 }
 
+// KeyedContextable is a contextable whose properties can be resolved one at a time, so that expressions which only
+// look up some of them don't pay for building the rest
+type KeyedContextable interface {
+	Contextable
+
+	ContextKeys() []string
+	ContextProperty(envs.Environment, string) types.XValue
+}
+
 // Context generates a lazy object for use in expressions
 func Context(env envs.Environment, contextable Contextable) types.XValue {
+	if keyed, isKeyed := contextable.(KeyedContextable); isKeyed && !utils.IsNil(keyed) {
+		return types.NewXLazyKeyedObject(keyed.ContextKeys(), func(key string) types.XValue {
+			return keyed.ContextProperty(env, key)
+		})
+	}
 	if !utils.IsNil(contextable) {
 		return types.NewXLazyObject(func() map[string]types.XValue {
 			return contextable.Context(env)
@@ -32,14 +49,20 @@ func ContextFunc(env envs.Environment, fn func(envs.Environment) map[string]type
 
 // RunContextTopLevels are the allowed top-level variables for expression evaluations
 var RunContextTopLevels = []string{
+	"cart",
 	"child",
+	"consts",
 	"contact",
 	"fields",
 	"globals",
+	"index",
 	"input",
+	"intents",
+	"item",
 	"legacy_extra",
 	"node",
 	"parent",
+	"related",
 	"results",
 	"resume",
 	"run",
@@ -53,3 +76,59 @@ var RunContextTopLevels = []string{
 func ContactQueryEscaping(s string) string {
 	return strconv.Quote(s)
 }
+
+// XMLEscaping is the escaping function used for expressions in XML documents
+func XMLEscaping(s string) string {
+	b := &strings.Builder{}
+	xml.EscapeText(b, []byte(s))
+	return b.String()
+}
+
+// EvaluateJSONTemplate evaluates a template of a JSON document in the context of the given run, encoding the values of
+// expressions according to where they appear. Inside strings values are escaped as string content, and elsewhere they
+// are encoded as JSON values, e.g. text is quoted and objects are written as JSON objects.
+func EvaluateJSONTemplate(run Run, template string) (string, error) {
+	var buf strings.Builder
+	inString, escaped := false, false
+
+	err := excellent.VisitTemplate(template, RunContextTopLevels, func(tokenType excellent.XTokenType, token string) error {
+		switch tokenType {
+		case excellent.BODY:
+			for _, c := range token {
+				if escaped {
+					escaped = false
+				} else if inString && c == '\\' {
+					escaped = true
+				} else if c == '"' {
+					inString = !inString
+				}
+			}
+			buf.WriteString(token)
+		case excellent.IDENTIFIER, excellent.EXPRESSION:
+			expression := "@" + token
+			if tokenType == excellent.EXPRESSION {
+				expression = "@(" + token + ")"
+			}
+
+			value, _ := run.EvaluateTemplateValue(expression)
+			if types.IsXError(value) {
+				return value.(error)
+			}
+
+			if inString {
+				asText, _ := types.ToXText(run.Environment(), value)
+				asJSON, _ := types.ToXJSON(asText)
+				buf.WriteString(strings.TrimSuffix(strings.TrimPrefix(asJSON.Native(), `"`), `"`))
+			} else {
+				asJSON, xerr := types.ToXJSON(value)
+				if xerr != nil {
+					return xerr
+				}
+				buf.WriteString(asJSON.Native())
+			}
+		}
+		return nil
+	})
+
+	return buf.String(), err
+}
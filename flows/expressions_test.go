@@ -3,9 +3,12 @@ package flows_test
 import (
 	"testing"
 
+	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/test"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestContactQueryEscaping(t *testing.T) {
@@ -14,3 +17,34 @@ func TestContactQueryEscaping(t *testing.T) {
 	assert.Equal(t, `"\"\" OR (id = 1)"`, flows.ContactQueryEscaping(`"" OR (id = 1)`))
 	assert.Equal(t, `"\\\"foo"`, flows.ContactQueryEscaping(`\"foo`))
 }
+
+func TestEvaluateJSONTemplate(t *testing.T) {
+	session, _, err := test.CreateTestSession("", envs.RedactionPolicyNone)
+	require.NoError(t, err)
+
+	run := session.Runs()[0]
+
+	tcs := []struct {
+		template string
+		expected string
+		err      string
+	}{
+		{``, ``, ""},
+		{`{"name": "@contact.name"}`, `{"name": "Ryan Lewis"}`, ""},
+		{`{"name": @contact.name}`, `{"name": "Ryan Lewis"}`, ""},
+		{`{"text": "@("a \"b\"")", "raw": @("a \"b\"")}`, `{"text": "a \"b\"", "raw": "a \"b\""}`, ""},
+		{`{"text": "x\"@(1 + 2)", "after": @(1 + 2)}`, `{"text": "x\"3", "after": 3}`, ""},
+		{`{"text": "\\", "list": @(array(1, "a")), "none": @(null)}`, `{"text": "\\", "list": [1,"a"], "none": null}`, ""},
+		{`{"error": @(1 / 0)}`, `{"error": }`, "error evaluating @(1 / 0): division by zero"},
+	}
+
+	for _, tc := range tcs {
+		actual, err := flows.EvaluateJSONTemplate(run, tc.template)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, "error mismatch for template %s", tc.template)
+		} else {
+			assert.NoError(t, err, "unexpected error for template %s", tc.template)
+		}
+		assert.Equal(t, tc.expected, actual, "output mismatch for template %s", tc.template)
+	}
+}
@@ -52,6 +52,11 @@ func (s *GlobalAssets) Get(key string) *Global {
 	return s.byKey[key]
 }
 
+// All returns all the globals in this set
+func (s *GlobalAssets) All() []*Global {
+	return s.all
+}
+
 // Context returns the properties available in expressions
 func (s *GlobalAssets) Context(env envs.Environment) map[string]types.XValue {
 	entries := make(map[string]types.XValue, len(s.all)+1)
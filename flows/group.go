@@ -9,6 +9,13 @@ import (
 	"github.com/nyaruka/goflow/excellent/types"
 )
 
+func init() {
+	RegisterContextType("group",
+		NewContextProperty("uuid", "text", "the UUID of the group"),
+		NewContextProperty("name", "text", "the name of the group"),
+	)
+}
+
 // Group adds some functionality to group assets.
 type Group struct {
 	assets.Group
@@ -50,6 +57,15 @@ func (g *Group) CheckQueryBasedMembership(env envs.Environment, contact *Contact
 	return contactql.EvaluateQuery(env, g.parsedQuery, contact)
 }
 
+type queryGroupMembership struct{}
+
+// NewQueryGroupMembership returns a group membership service which evaluates group queries against contacts itself
+func NewQueryGroupMembership() GroupMembershipService { return queryGroupMembership{} }
+
+func (queryGroupMembership) Qualifies(env envs.Environment, group *Group, contact *Contact) (bool, error) {
+	return group.CheckQueryBasedMembership(env, contact), nil
+}
+
 // Reference returns a reference to this group
 func (g *Group) Reference() *assets.GroupReference {
 	if g == nil {
@@ -59,11 +75,6 @@ func (g *Group) Reference() *assets.GroupReference {
 }
 
 // ToXValue returns a representation of this object for use in expressions
-//
-//	uuid:text -> the UUID of the group
-//	name:text -> the name of the group
-//
-// @context group
 func (g *Group) ToXValue(env envs.Environment) types.XValue {
 	return types.NewXObject(map[string]types.XValue{
 		"uuid": types.NewXText(string(g.UUID())),
@@ -36,5 +36,10 @@ func sessionReceivedInput(s Session) bool {
 			return true
 		}
 	}
+	for _, r := range s.ArchivedRuns() {
+		if r.ReceivedInput {
+			return true
+		}
+	}
 	return false
 }
@@ -1,6 +1,7 @@
 package flows_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/nyaruka/goflow/assets"
@@ -38,7 +39,7 @@ func TestHistory(t *testing.T) {
 	contact := flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
 
 	eng := engine.NewBuilder().Build()
-	session, _, err := eng.NewSession(sa, triggers.NewBuilder(env, flow, contact).Manual().Build())
+	session, _, err := eng.NewSession(context.Background(), sa, triggers.NewBuilder(env, flow, contact).Manual().Build())
 	require.NoError(t, err)
 
 	assert.Equal(t, flows.SessionUUID(""), session.History().ParentUUID)
@@ -0,0 +1,183 @@
+package hooks
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nyaruka/goflow/flows"
+
+	"github.com/pkg/errors"
+)
+
+// Handler handles the events of a sprint which a hook is interested in, in the order they were generated
+type Handler func(ctx context.Context, session flows.Session, events []flows.Event) error
+
+// Hook is a named handler for events of one or more types. Hooks are how embedders apply the side effects of a sprint
+// outside of the engine, e.g. saving contact changes to a database or queuing messages to be sent.
+type Hook struct {
+	name       string
+	eventTypes []string
+	after      []string
+	handle     Handler
+}
+
+// NewHook creates a new hook which handles events of the given types
+func NewHook(name string, eventTypes []string, handle Handler) *Hook {
+	return &Hook{name: name, eventTypes: eventTypes, handle: handle}
+}
+
+// NewTypedHook creates a new hook which handles events of a single type as their concrete type, e.g.
+//
+//	NewTypedHook("queue_msgs", events.TypeMsgCreated, func(ctx context.Context, s flows.Session, msgs []*events.MsgCreatedEvent) error {
+//	  ...
+//	})
+func NewTypedHook[E flows.Event](name, eventType string, handle func(context.Context, flows.Session, []E) error) *Hook {
+	return NewHook(name, []string{eventType}, func(ctx context.Context, session flows.Session, evts []flows.Event) error {
+		typed := make([]E, len(evts))
+		for i, e := range evts {
+			t, ok := e.(E)
+			if !ok {
+				return errors.Errorf("event of type '%s' has unexpected type %T", e.Type(), e)
+			}
+			typed[i] = t
+		}
+		return handle(ctx, session, typed)
+	})
+}
+
+// After sets the names of hooks which must be run before this one and returns this hook
+func (h *Hook) After(names ...string) *Hook {
+	h.after = append(h.after, names...)
+	return h
+}
+
+// Name returns the name of this hook
+func (h *Hook) Name() string { return h.name }
+
+// EventTypes returns the types of events handled by this hook
+func (h *Hook) EventTypes() []string { return h.eventTypes }
+
+// Registry is a set of hooks which are run in an order which satisfies their dependencies
+type Registry struct {
+	hooks  []*Hook
+	byName map[string]*Hook
+	order  []*Hook
+}
+
+// NewRegistry creates a new empty registry
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]*Hook)}
+}
+
+// Register adds the given hook to this registry
+func (r *Registry) Register(hook *Hook) error {
+	if _, exists := r.byName[hook.name]; exists {
+		return errors.Errorf("hook '%s' is already registered", hook.name)
+	}
+
+	r.hooks = append(r.hooks, hook)
+	r.byName[hook.name] = hook
+	r.order = nil
+	return nil
+}
+
+// Order returns the hooks of this registry in the order they are run. Hooks run after all the hooks they depend on,
+// and otherwise in the order they were registered.
+func (r *Registry) Order() ([]*Hook, error) {
+	if r.order != nil {
+		return r.order, nil
+	}
+
+	for _, h := range r.hooks {
+		for _, dep := range h.after {
+			if r.byName[dep] == nil {
+				return nil, errors.Errorf("hook '%s' depends on unknown hook '%s'", h.name, dep)
+			}
+		}
+	}
+
+	order := make([]*Hook, 0, len(r.hooks))
+	added := make(map[string]bool, len(r.hooks))
+
+	// repeatedly add the first hook whose dependencies have all been added
+	for len(order) < len(r.hooks) {
+		var next *Hook
+
+		for _, h := range r.hooks {
+			if !added[h.name] && r.dependenciesMet(h, added) {
+				next = h
+				break
+			}
+		}
+
+		if next == nil {
+			remaining := make([]string, 0, len(r.hooks)-len(order))
+			for _, h := range r.hooks {
+				if !added[h.name] {
+					remaining = append(remaining, h.name)
+				}
+			}
+			return nil, errors.Errorf("hooks have circular dependencies: %s", strings.Join(remaining, ", "))
+		}
+
+		order = append(order, next)
+		added[next.name] = true
+	}
+
+	r.order = order
+	return order, nil
+}
+
+func (r *Registry) dependenciesMet(h *Hook, added map[string]bool) bool {
+	for _, dep := range h.after {
+		if !added[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// Run runs the hooks of this registry for the given events, typically those of a sprint. Each hook is called once with
+// all the events of its types, or not at all if there are none, and running stops at the first hook which errors.
+func (r *Registry) Run(ctx context.Context, session flows.Session, evts []flows.Event) error {
+	order, err := r.Order()
+	if err != nil {
+		return err
+	}
+
+	byType := make(map[string][]flows.Event)
+	for _, e := range evts {
+		byType[e.Type()] = append(byType[e.Type()], e)
+	}
+
+	for _, h := range order {
+		matching := h.matching(evts, byType)
+		if len(matching) == 0 {
+			continue
+		}
+
+		if err := h.handle(ctx, session, matching); err != nil {
+			return errors.Wrapf(err, "error running hook '%s'", h.name)
+		}
+	}
+
+	return nil
+}
+
+// gets the events this hook handles, keeping them in the order they were generated
+func (h *Hook) matching(evts []flows.Event, byType map[string][]flows.Event) []flows.Event {
+	if len(h.eventTypes) == 1 {
+		return byType[h.eventTypes[0]]
+	}
+
+	var matching []flows.Event
+	for _, e := range evts {
+		for _, t := range h.eventTypes {
+			if e.Type() == t {
+				matching = append(matching, e)
+				break
+			}
+		}
+	}
+	return matching
+}
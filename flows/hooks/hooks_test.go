@@ -0,0 +1,127 @@
+package hooks_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/hooks"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	_, session, _ := test.NewSessionBuilder().MustBuild()
+
+	var calls []string
+
+	queueMsgs := hooks.NewTypedHook("queue_msgs", events.TypeMsgCreated, func(ctx context.Context, s flows.Session, evts []*events.MsgCreatedEvent) error {
+		for _, e := range evts {
+			calls = append(calls, "queue_msgs:"+e.Msg.Text())
+		}
+		return nil
+	}).After("save_contact")
+
+	saveContact := hooks.NewHook("save_contact", []string{events.TypeContactNameChanged, events.TypeContactLanguageChanged}, func(ctx context.Context, s flows.Session, evts []flows.Event) error {
+		for _, e := range evts {
+			calls = append(calls, "save_contact:"+e.Type())
+		}
+		return nil
+	})
+
+	logRun := hooks.NewHook("log_run", []string{events.TypeFlowEntered}, func(ctx context.Context, s flows.Session, evts []flows.Event) error {
+		calls = append(calls, "log_run")
+		return nil
+	})
+
+	reg := hooks.NewRegistry()
+	require.NoError(t, reg.Register(queueMsgs))
+	require.NoError(t, reg.Register(logRun))
+	require.NoError(t, reg.Register(saveContact))
+
+	assert.EqualError(t, reg.Register(hooks.NewHook("log_run", nil, nil)), "hook 'log_run' is already registered")
+
+	order, err := reg.Order()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"log_run", "save_contact", "queue_msgs"}, hookNames(order))
+	assert.Equal(t, []string{events.TypeContactNameChanged, events.TypeContactLanguageChanged}, order[1].EventTypes())
+
+	evts := []flows.Event{
+		events.NewMsgCreated(flows.NewMsgOut(flows.MsgUUID(uuids.New()), urns.URN("tel:+12065551212"), nil, "Hi there", nil, nil, nil, nil, flows.NilMsgTopic, envs.NilLocale, flows.NilUnsendableReason)),
+		events.NewContactLanguageChanged("spa"),
+		events.NewMsgCreated(flows.NewMsgOut(flows.MsgUUID(uuids.New()), urns.URN("tel:+12065551212"), nil, "Bye", nil, nil, nil, nil, flows.NilMsgTopic, envs.NilLocale, flows.NilUnsendableReason)),
+		events.NewContactNameChanged("Bob"),
+	}
+
+	// hooks run in order and each gets its events in the order they were generated, and hooks without events aren't run
+	err = reg.Run(context.Background(), session, evts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"save_contact:contact_language_changed",
+		"save_contact:contact_name_changed",
+		"queue_msgs:Hi there",
+		"queue_msgs:Bye",
+	}, calls)
+
+	// a hook which errors stops any later hooks from running
+	reg = hooks.NewRegistry()
+	reg.Register(hooks.NewHook("save_contact", []string{events.TypeContactNameChanged}, func(ctx context.Context, s flows.Session, evts []flows.Event) error {
+		return errors.New("database unavailable")
+	}))
+	reg.Register(queueMsgs)
+
+	calls = nil
+	err = reg.Run(context.Background(), session, evts)
+	assert.EqualError(t, err, "error running hook 'save_contact': database unavailable")
+	assert.Nil(t, calls)
+}
+
+func TestRegistryDependencyErrors(t *testing.T) {
+	noop := func(ctx context.Context, s flows.Session, evts []flows.Event) error { return nil }
+
+	reg := hooks.NewRegistry()
+	reg.Register(hooks.NewHook("queue_msgs", []string{events.TypeMsgCreated}, noop).After("save_contact"))
+
+	_, err := reg.Order()
+	assert.EqualError(t, err, "hook 'queue_msgs' depends on unknown hook 'save_contact'")
+
+	err = reg.Run(context.Background(), nil, nil)
+	assert.EqualError(t, err, "hook 'queue_msgs' depends on unknown hook 'save_contact'")
+
+	reg.Register(hooks.NewHook("save_contact", []string{events.TypeContactNameChanged}, noop).After("apply_groups"))
+	reg.Register(hooks.NewHook("apply_groups", []string{events.TypeContactGroupsChanged}, noop).After("queue_msgs"))
+	reg.Register(hooks.NewHook("log_run", []string{events.TypeFlowEntered}, noop))
+
+	_, err = reg.Order()
+	assert.EqualError(t, err, "hooks have circular dependencies: queue_msgs, save_contact, apply_groups")
+}
+
+func TestTypedHookWithWrongType(t *testing.T) {
+	reg := hooks.NewRegistry()
+	reg.Register(hooks.NewTypedHook("queue_msgs", events.TypeMsgCreated, func(ctx context.Context, s flows.Session, evts []*events.MsgCreatedEvent) error {
+		return nil
+	}))
+
+	// an event which claims to be of the hook's type but isn't
+	err := reg.Run(context.Background(), nil, []flows.Event{&fakeEvent{events.NewBaseEvent(events.TypeMsgCreated)}})
+	assert.EqualError(t, err, "error running hook 'queue_msgs': event of type 'msg_created' has unexpected type *hooks_test.fakeEvent")
+}
+
+type fakeEvent struct {
+	events.BaseEvent
+}
+
+func hookNames(hs []*hooks.Hook) []string {
+	names := make([]string, len(hs))
+	for i, h := range hs {
+		names[i] = h.Name()
+	}
+	return names
+}
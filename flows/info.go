@@ -53,6 +53,9 @@ type Inspection struct {
 	Results      []*ResultSpec `json:"results"`
 	WaitingExits []ExitUUID    `json:"waiting_exits"`
 	ParentRefs   []string      `json:"parent_refs"`
+
+	// number of messages sent by the flow with each priority, if any have priorities
+	MsgPriorities map[MsgPriority]int `json:"msg_priorities,omitempty"`
 }
 
 // ResultInfo is possible result that a flow might generate
@@ -0,0 +1,35 @@
+package flows
+
+import (
+	"strings"
+
+	"github.com/nyaruka/gocommon/stringsx"
+	"github.com/nyaruka/goflow/utils"
+)
+
+// InputProcessing is the cleaning a flow declares should be applied to the text of incoming messages before they're
+// used as input, so that its routers don't each have to do it. The original text remains available as the raw text
+// of the input.
+type InputProcessing struct {
+	Trim               bool `json:"trim,omitempty"`
+	CollapseWhitespace bool `json:"collapse_whitespace,omitempty"`
+	StripEmojis        bool `json:"strip_emojis,omitempty"`
+	MaxLength          int  `json:"max_length,omitempty" validate:"min=0"`
+}
+
+// Process applies this processing to the given text
+func (p *InputProcessing) Process(text string) string {
+	if p.StripEmojis {
+		text = utils.StripEmojis(text)
+	}
+	if p.CollapseWhitespace {
+		text = utils.CollapseWhitespace(text)
+	}
+	if p.Trim {
+		text = strings.TrimSpace(text)
+	}
+	if p.MaxLength > 0 {
+		text = stringsx.Truncate(text, p.MaxLength)
+	}
+	return text
+}
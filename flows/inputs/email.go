@@ -0,0 +1,155 @@
+package inputs
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeEmail, readEmailInput)
+
+	flows.RegisterContextType("email",
+		flows.NewContextProperty("__default__", "text", "the subject and body"),
+		flows.NewContextProperty("from", "text", "the address the email was sent from"),
+		flows.NewContextProperty("subject", "text", "the subject of the email"),
+		flows.NewContextProperty("text", "text", "the plain text body of the email"),
+		flows.NewContextProperty("html", "text", "the HTML body of the email if it had one"),
+		flows.NewContextArrayProperty("attachments", "text", "any attachments on the email"),
+	)
+}
+
+// TypeEmail is a constant for incoming emails
+const TypeEmail string = "email"
+
+// EmailInput is an email which can be used as input. Its plain text body is the text of the input so that routers
+// written for messages work unchanged, and the rest of the email is available as @input.email.
+type EmailInput struct {
+	baseInput
+
+	email   *flows.EmailIn
+	text    string
+	rawText string
+}
+
+// NewEmail creates a new user input based on an email, applying the given processing to its body if there is any
+func NewEmail(email *flows.EmailIn, createdOn time.Time, processing *flows.InputProcessing) *EmailInput {
+	text := email.Body
+	if processing != nil {
+		text = processing.Process(text)
+	}
+
+	return &EmailInput{
+		baseInput: newBaseInput(TypeEmail, flows.InputUUID(email.UUID), nil, createdOn),
+		email:     email,
+		text:      text,
+		rawText:   email.Body,
+	}
+}
+
+// Email returns the email this input is based on
+func (i *EmailInput) Email() *flows.EmailIn { return i.email }
+
+// Context returns the properties available in expressions
+func (i *EmailInput) Context(env envs.Environment) map[string]types.XValue {
+	attachments := make([]types.XValue, len(i.email.Attachments))
+
+	for i, attachment := range i.email.Attachments {
+		attachments[i] = types.NewXText(string(attachment))
+	}
+
+	email := types.NewXObject(map[string]types.XValue{
+		"__default__": types.NewXText(strings.TrimSpace(i.email.Subject + "\n" + i.email.Body)),
+		"from":        types.NewXText(i.email.From),
+		"subject":     types.NewXText(i.email.Subject),
+		"text":        types.NewXText(i.email.Body),
+		"html":        types.NewXText(i.email.HTMLBody),
+		"attachments": types.NewXArray(attachments...),
+	})
+
+	return map[string]types.XValue{
+		"__default__":   types.NewXText(i.format()),
+		"type":          types.NewXText(i.type_),
+		"uuid":          types.NewXText(string(i.uuid)),
+		"created_on":    types.NewXDateTime(i.createdOn),
+		"channel":       nil,
+		"urn":           types.NewXText(string(i.urn())),
+		"text":          types.NewXText(i.text),
+		"raw_text":      types.NewXText(i.rawText),
+		"attachments":   types.NewXArray(attachments...),
+		"external_id":   types.NewXText(""),
+		"callback_data": types.NewXText(""),
+		"email":         email,
+	}
+}
+
+// the sender of the email as a mailto URN
+func (i *EmailInput) urn() urns.URN {
+	urn, _ := urns.NewURNFromParts(urns.EmailScheme, i.email.From, "", "")
+	return urn
+}
+
+func (i *EmailInput) format() string {
+	var parts []string
+	if i.text != "" {
+		parts = append(parts, i.text)
+	}
+	for _, attachment := range i.email.Attachments {
+		parts = append(parts, attachment.URL())
+	}
+	return strings.Join(parts, "\n")
+}
+
+var _ flows.Input = (*EmailInput)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type emailInputEnvelope struct {
+	baseInputEnvelope
+	Email   *flows.EmailIn `json:"email" validate:"required,dive"`
+	Text    string         `json:"text"`
+	RawText string         `json:"raw_text,omitempty"`
+}
+
+func readEmailInput(sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Input, error) {
+	e := &emailInputEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	i := &EmailInput{email: e.Email, text: e.Text, rawText: e.RawText}
+
+	// raw text is only written when it differs from the processed text
+	if i.rawText == "" {
+		i.rawText = i.text
+	}
+
+	if err := i.unmarshal(sessionAssets, &e.baseInputEnvelope, missing); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// MarshalJSON marshals this email input into JSON
+func (i *EmailInput) MarshalJSON() ([]byte, error) {
+	e := &emailInputEnvelope{Email: i.email, Text: i.text}
+
+	if i.rawText != i.text {
+		e.RawText = i.rawText
+	}
+
+	i.marshal(&e.baseInputEnvelope)
+
+	return jsonx.Marshal(e)
+}
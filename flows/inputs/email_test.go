@@ -0,0 +1,68 @@
+package inputs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/inputs"
+	"github.com/nyaruka/goflow/test"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailInput(t *testing.T) {
+	_, session, _ := test.NewSessionBuilder().MustBuild()
+	env := session.Environment()
+
+	email := &flows.EmailIn{
+		UUID:        "f51d7220-10b3-4faa-a91c-1ae70beaae3e",
+		From:        "bob@nyaruka.com",
+		Subject:     "Order #1234",
+		Body:        "  Where is my order?  ",
+		HTMLBody:    "<p>Where is my order?</p>",
+		Attachments: []utils.Attachment{"image/jpeg:http://example.com/receipt.jpg"},
+	}
+
+	input := inputs.NewEmail(email, time.Date(2018, 10, 22, 16, 12, 30, 123456, time.UTC), &flows.InputProcessing{Trim: true})
+	assert.Equal(t, "email", input.Type())
+	assert.Equal(t, flows.InputUUID("f51d7220-10b3-4faa-a91c-1ae70beaae3e"), input.UUID())
+	assert.Nil(t, input.Channel())
+	assert.Equal(t, email, input.Email())
+
+	// check use in expressions
+	test.AssertXEqual(t, types.NewXObject(map[string]types.XValue{
+		"__default__":   types.NewXText("Where is my order?\nhttp://example.com/receipt.jpg"),
+		"type":          types.NewXText("email"),
+		"uuid":          types.NewXText("f51d7220-10b3-4faa-a91c-1ae70beaae3e"),
+		"channel":       nil,
+		"created_on":    types.NewXDateTime(input.CreatedOn()),
+		"urn":           types.NewXText("mailto:bob@nyaruka.com"),
+		"text":          types.NewXText("Where is my order?"),
+		"raw_text":      types.NewXText("  Where is my order?  "),
+		"attachments":   types.NewXArray(types.NewXText("image/jpeg:http://example.com/receipt.jpg")),
+		"external_id":   types.NewXText(""),
+		"callback_data": types.NewXText(""),
+		"email": types.NewXObject(map[string]types.XValue{
+			"__default__": types.NewXText("Order #1234\n  Where is my order?"),
+			"from":        types.NewXText("bob@nyaruka.com"),
+			"subject":     types.NewXText("Order #1234"),
+			"text":        types.NewXText("  Where is my order?  "),
+			"html":        types.NewXText("<p>Where is my order?</p>"),
+			"attachments": types.NewXArray(types.NewXText("image/jpeg:http://example.com/receipt.jpg")),
+		}),
+	}), flows.Context(env, input))
+
+	// check marshaling to JSON and back
+	marshaled, err := jsonx.Marshal(input)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"email","uuid":"f51d7220-10b3-4faa-a91c-1ae70beaae3e","created_on":"2018-10-22T16:12:30.000123456Z","email":{"uuid":"f51d7220-10b3-4faa-a91c-1ae70beaae3e","from":"bob@nyaruka.com","subject":"Order #1234","body":"  Where is my order?  ","html_body":"<p>Where is my order?</p>","attachments":["image/jpeg:http://example.com/receipt.jpg"]},"text":"Where is my order?","raw_text":"  Where is my order?  "}`, string(marshaled))
+
+	read, err := inputs.ReadInput(session.Assets(), marshaled, nil)
+	require.NoError(t, err)
+	assert.Equal(t, input, read)
+}
@@ -16,6 +16,21 @@ import (
 
 func init() {
 	registerType(TypeMsg, readMsgInput)
+
+	flows.RegisterContextType("input",
+		flows.NewContextProperty("__default__", "text", "the text and attachments"),
+		flows.NewContextProperty("type", "text", "the type of the input"),
+		flows.NewContextProperty("uuid", "text", "the UUID of the input"),
+		flows.NewContextProperty("created_on", "datetime", "the creation date of the input"),
+		flows.NewContextProperty("channel", "channel", "the channel that the input was received on"),
+		flows.NewContextProperty("urn", "text", "the contact URN that the input was received on"),
+		flows.NewContextProperty("text", "text", "the text part of the input, after any input processing of the flow"),
+		flows.NewContextProperty("raw_text", "text", "the text part of the input as it was received"),
+		flows.NewContextArrayProperty("attachments", "text", "any attachments on the input"),
+		flows.NewContextProperty("external_id", "text", "the external ID of the input"),
+		flows.NewContextProperty("callback_data", "text", "the data of the inline keyboard button pressed, if the input is a callback query"),
+		flows.NewContextProperty("email", "email", "the subject, body and sender if the input is an email"),
+	)
 }
 
 // TypeMsg is a constant for incoming messages
@@ -25,41 +40,39 @@ const TypeMsg string = "msg"
 type MsgInput struct {
 	baseInput
 
-	urn         *flows.ContactURN
-	text        string
-	attachments []utils.Attachment
-	externalID  string
+	urn          *flows.ContactURN
+	text         string
+	rawText      string
+	attachments  []utils.Attachment
+	externalID   string
+	callbackData string
 }
 
-// NewMsg creates a new user input based on a message
-func NewMsg(assets flows.SessionAssets, msg *flows.MsgIn, createdOn time.Time) *MsgInput {
+// NewMsg creates a new user input based on a message, applying the given processing to its text if there is any
+func NewMsg(assets flows.SessionAssets, msg *flows.MsgIn, createdOn time.Time, processing *flows.InputProcessing) *MsgInput {
 	// load the channel
 	var channel *flows.Channel
 	if msg.Channel() != nil {
 		channel = assets.Channels().Get(msg.Channel().UUID)
 	}
 
+	text := msg.Text()
+	if processing != nil {
+		text = processing.Process(text)
+	}
+
 	return &MsgInput{
-		baseInput:   newBaseInput(TypeMsg, flows.InputUUID(msg.UUID()), channel, createdOn),
-		urn:         flows.NewContactURN(msg.URN(), nil),
-		text:        msg.Text(),
-		attachments: msg.Attachments(),
-		externalID:  msg.ExternalID(),
+		baseInput:    newBaseInput(TypeMsg, flows.InputUUID(msg.UUID()), channel, createdOn),
+		urn:          flows.NewContactURN(msg.URN(), nil),
+		text:         text,
+		rawText:      msg.Text(),
+		attachments:  msg.Attachments(),
+		externalID:   msg.ExternalID(),
+		callbackData: msg.CallbackData(),
 	}
 }
 
 // Context returns the properties available in expressions
-//
-//	__default__:text -> the text and attachments
-//	uuid:text -> the UUID of the input
-//	created_on:datetime -> the creation date of the input
-//	channel:channel -> the channel that the input was received on
-//	urn:text -> the contact URN that the input was received on
-//	text:text -> the text part of the input
-//	attachments:[]text -> any attachments on the input
-//	external_id:text -> the external ID of the input
-//
-// @context input
 func (i *MsgInput) Context(env envs.Environment) map[string]types.XValue {
 	attachments := make([]types.XValue, len(i.attachments))
 
@@ -73,15 +86,18 @@ func (i *MsgInput) Context(env envs.Environment) map[string]types.XValue {
 	}
 
 	return map[string]types.XValue{
-		"__default__": types.NewXText(i.format()),
-		"type":        types.NewXText(i.type_),
-		"uuid":        types.NewXText(string(i.uuid)),
-		"created_on":  types.NewXDateTime(i.createdOn),
-		"channel":     flows.Context(env, i.channel),
-		"urn":         urn,
-		"text":        types.NewXText(i.text),
-		"attachments": types.NewXArray(attachments...),
-		"external_id": types.NewXText(i.externalID),
+		"__default__":   types.NewXText(i.format()),
+		"type":          types.NewXText(i.type_),
+		"uuid":          types.NewXText(string(i.uuid)),
+		"created_on":    types.NewXDateTime(i.createdOn),
+		"channel":       flows.Context(env, i.channel),
+		"urn":           urn,
+		"text":          types.NewXText(i.text),
+		"raw_text":      types.NewXText(i.rawText),
+		"attachments":   types.NewXArray(attachments...),
+		"external_id":   types.NewXText(i.externalID),
+		"callback_data": types.NewXText(i.callbackData),
+		"email":         nil,
 	}
 }
 
@@ -104,10 +120,12 @@ var _ flows.Input = (*MsgInput)(nil)
 
 type msgInputEnvelope struct {
 	baseInputEnvelope
-	URN         urns.URN           `json:"urn" validate:"omitempty,urn"`
-	Text        string             `json:"text"`
-	Attachments []utils.Attachment `json:"attachments,omitempty"`
-	ExternalID  string             `json:"external_id,omitempty"`
+	URN          urns.URN           `json:"urn" validate:"omitempty,urn"`
+	Text         string             `json:"text"`
+	RawText      string             `json:"raw_text,omitempty"`
+	Attachments  []utils.Attachment `json:"attachments,omitempty"`
+	ExternalID   string             `json:"external_id,omitempty"`
+	CallbackData string             `json:"callback_data,omitempty"`
 }
 
 func readMsgInput(sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Input, error) {
@@ -118,10 +136,17 @@ func readMsgInput(sessionAssets flows.SessionAssets, data json.RawMessage, missi
 	}
 
 	i := &MsgInput{
-		urn:         flows.NewContactURN(e.URN, nil),
-		text:        e.Text,
-		attachments: e.Attachments,
-		externalID:  e.ExternalID,
+		urn:          flows.NewContactURN(e.URN, nil),
+		text:         e.Text,
+		rawText:      e.RawText,
+		attachments:  e.Attachments,
+		externalID:   e.ExternalID,
+		callbackData: e.CallbackData,
+	}
+
+	// raw text is only written when it differs from the processed text
+	if i.rawText == "" {
+		i.rawText = i.text
 	}
 
 	if err := i.unmarshal(sessionAssets, &e.baseInputEnvelope, missing); err != nil {
@@ -134,10 +159,15 @@ func readMsgInput(sessionAssets flows.SessionAssets, data json.RawMessage, missi
 // MarshalJSON marshals this msg input into JSON
 func (i *MsgInput) MarshalJSON() ([]byte, error) {
 	e := &msgInputEnvelope{
-		URN:         i.urn.URN(),
-		Text:        i.text,
-		Attachments: i.attachments,
-		ExternalID:  i.externalID,
+		URN:          i.urn.URN(),
+		Text:         i.text,
+		Attachments:  i.attachments,
+		ExternalID:   i.externalID,
+		CallbackData: i.callbackData,
+	}
+
+	if i.rawText != i.text {
+		e.RawText = i.rawText
 	}
 
 	i.marshal(&e.baseInputEnvelope)
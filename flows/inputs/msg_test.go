@@ -34,7 +34,7 @@ func TestMsgInput(t *testing.T) {
 	)
 	msg.SetExternalID("ext12345")
 
-	input := inputs.NewMsg(session.Assets(), msg, time.Date(2018, 10, 22, 16, 12, 30, 123456, time.UTC))
+	input := inputs.NewMsg(session.Assets(), msg, time.Date(2018, 10, 22, 16, 12, 30, 123456, time.UTC), nil)
 	assert.Equal(t, "msg", input.Type())
 	assert.Equal(t, flows.InputUUID("f51d7220-10b3-4faa-a91c-1ae70beaae3e"), input.UUID())
 	assert.Equal(t, channel, input.Channel())
@@ -42,19 +42,60 @@ func TestMsgInput(t *testing.T) {
 
 	// check use in expressions
 	test.AssertXEqual(t, types.NewXObject(map[string]types.XValue{
-		"__default__": types.NewXText("Hi there!\nhttp://example.com/test.jpg\nhttp://example.com/test.mp4"),
-		"type":        types.NewXText("msg"),
-		"uuid":        types.NewXText("f51d7220-10b3-4faa-a91c-1ae70beaae3e"),
-		"channel":     flows.Context(env, channel),
-		"created_on":  types.NewXDateTime(input.CreatedOn()),
-		"urn":         types.NewXText("tel:+1234567890"),
-		"text":        types.NewXText("Hi there!"),
-		"attachments": types.NewXArray(types.NewXText("image/jpg:http://example.com/test.jpg"), types.NewXText("video/mp4:http://example.com/test.mp4")),
-		"external_id": types.NewXText("ext12345"),
+		"__default__":   types.NewXText("Hi there!\nhttp://example.com/test.jpg\nhttp://example.com/test.mp4"),
+		"type":          types.NewXText("msg"),
+		"uuid":          types.NewXText("f51d7220-10b3-4faa-a91c-1ae70beaae3e"),
+		"channel":       flows.Context(env, channel),
+		"created_on":    types.NewXDateTime(input.CreatedOn()),
+		"urn":           types.NewXText("tel:+1234567890"),
+		"text":          types.NewXText("Hi there!"),
+		"raw_text":      types.NewXText("Hi there!"),
+		"attachments":   types.NewXArray(types.NewXText("image/jpg:http://example.com/test.jpg"), types.NewXText("video/mp4:http://example.com/test.mp4")),
+		"external_id":   types.NewXText("ext12345"),
+		"callback_data": types.NewXText(""),
+		"email":         nil,
 	}), flows.Context(env, input))
 
 	// check marshaling to JSON
 	marshaled, err := jsonx.Marshal(input)
 	assert.NoError(t, err)
 	assert.Equal(t, `{"type":"msg","uuid":"f51d7220-10b3-4faa-a91c-1ae70beaae3e","channel":{"uuid":"57f1078f-88aa-46f4-a59a-948a5739c03d","name":"My Android Phone"},"created_on":"2018-10-22T16:12:30.000123456Z","urn":"tel:+1234567890","text":"Hi there!","attachments":["image/jpg:http://example.com/test.jpg","video/mp4:http://example.com/test.mp4"],"external_id":"ext12345"}`, string(marshaled))
+
+	// a callback query from an inline keyboard button has its data kept separate from the text
+	callback := flows.NewMsgIn(
+		flows.MsgUUID("a2c5ecfe-3aa5-4ae6-a2de-b9d33ab85dd1"),
+		urns.URN("telegram:12345"),
+		nil,
+		"",
+		nil,
+	)
+	callback.SetCallbackData("choice_yes")
+
+	input = inputs.NewMsg(session.Assets(), callback, time.Date(2018, 10, 22, 16, 12, 30, 123456, time.UTC), nil)
+
+	test.AssertXEqual(t, types.NewXText(""), input.Context(env)["text"])
+	test.AssertXEqual(t, types.NewXText("choice_yes"), input.Context(env)["callback_data"])
+
+	marshaled, err = jsonx.Marshal(input)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"msg","uuid":"a2c5ecfe-3aa5-4ae6-a2de-b9d33ab85dd1","created_on":"2018-10-22T16:12:30.000123456Z","urn":"telegram:12345","text":"","callback_data":"choice_yes"}`, string(marshaled))
+
+	// flows can declare processing of the text, in which case the raw text is also kept
+	messy := flows.NewMsgIn(
+		flows.MsgUUID("b8d6e3a2-6f1c-4b7e-9a0d-2c4e6f8a1b3d"),
+		urns.URN("tel:+1234567890"),
+		nil,
+		"  Yes 👍🏽   please!  ",
+		nil,
+	)
+
+	processing := &flows.InputProcessing{Trim: true, CollapseWhitespace: true, StripEmojis: true, MaxLength: 8}
+	input = inputs.NewMsg(session.Assets(), messy, time.Date(2018, 10, 22, 16, 12, 30, 123456, time.UTC), processing)
+
+	test.AssertXEqual(t, types.NewXText("Yes plea"), input.Context(env)["text"])
+	test.AssertXEqual(t, types.NewXText("  Yes 👍🏽   please!  "), input.Context(env)["raw_text"])
+
+	marshaled, err = jsonx.Marshal(input)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"msg","uuid":"b8d6e3a2-6f1c-4b7e-9a0d-2c4e6f8a1b3d","created_on":"2018-10-22T16:12:30.000123456Z","urn":"tel:+1234567890","text":"Yes plea","raw_text":"  Yes 👍🏽   please!  "}`, string(marshaled))
 }
@@ -0,0 +1,188 @@
+// Package context enumerates the keys which can be referenced in the expression context of a flow, along with their
+// types, e.g. for flow editors to provide autocompletion.
+package context
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/excellent/tools"
+	"github.com/nyaruka/goflow/flows"
+)
+
+// Key is a path in the expression context which can be referenced in expressions, e.g. contact.fields.age
+type Key struct {
+	Path  string `json:"path"`
+	Type  string `json:"type"`
+	Array bool   `json:"array,omitempty"`
+}
+
+// types whose properties depend on the flow and its assets
+const (
+	typeFields    = "fields"
+	typeResults   = "results"
+	typeGlobals   = "globals"
+	typeRelations = "relations"
+	typeURNs      = "urns"
+)
+
+// Enumerate enumerates all the keys reachable in the expression context of a run of the given flow. Contact fields
+// and globals are taken from the session assets, and results from those which the flow can save. Because @webhook,
+// @trigger.params and @child.returns have no fixed structure, their keys are those which are referenced by templates
+// in the flow.
+func Enumerate(flow flows.Flow, sa flows.SessionAssets) []*Key {
+	dynamic := map[string][]*Property{
+		typeFields:    fieldProperties(sa),
+		typeResults:   resultProperties(flow, sa),
+		typeGlobals:   globalProperties(sa),
+		typeRelations: relationProperties(sa),
+		typeURNs:      urnProperties(),
+	}
+	static := StaticTypes()
+	refs := referencedPaths(flow)
+
+	keys := make([]*Key, 0, 100)
+
+	var enumerate func(string, *Property)
+	enumerate = func(base string, p *Property) {
+		path := p.Key
+		if base != "" {
+			path = base + "." + p.Key
+		}
+
+		keys = append(keys, &Key{Path: path, Type: p.Type, Array: p.Array})
+
+		if p.Array {
+			path += "[0]"
+		}
+
+		props := static[p.Type]
+		if props == nil {
+			props = dynamic[p.Type]
+		}
+
+		for _, pp := range props {
+			enumerate(path, pp)
+		}
+
+		// untyped values might have keys which the flow references
+		if p.Type == TypeAny && refs[path] != nil {
+			for _, ref := range refs[path] {
+				keys = append(keys, &Key{Path: path + ref, Type: TypeAny})
+			}
+		}
+	}
+
+	for _, p := range rootProperties() {
+		enumerate("", p)
+	}
+
+	return keys
+}
+
+func fieldProperties(sa flows.SessionAssets) []*Property {
+	props := make([]*Property, 0, len(sa.Fields().All()))
+	for _, f := range sa.Fields().All() {
+		props = append(props, prop(f.Key(), fieldValueType(f.Type())))
+	}
+	return sortProperties(props)
+}
+
+func fieldValueType(t assets.FieldType) string {
+	switch t {
+	case assets.FieldTypeNumber:
+		return TypeNumber
+	case assets.FieldTypeDatetime:
+		return TypeDatetime
+	default:
+		return TypeText
+	}
+}
+
+func resultProperties(flow flows.Flow, sa flows.SessionAssets) []*Property {
+	results := flow.Inspect(sa).Results
+	props := make([]*Property, len(results))
+	for i, r := range results {
+		props[i] = prop(r.Key, "result")
+	}
+	return sortProperties(props)
+}
+
+func globalProperties(sa flows.SessionAssets) []*Property {
+	props := make([]*Property, 0, len(sa.Globals().All()))
+	for _, g := range sa.Globals().All() {
+		props = append(props, prop(g.Key(), TypeText))
+	}
+	return sortProperties(props)
+}
+
+func relationProperties(sa flows.SessionAssets) []*Property {
+	props := make([]*Property, 0, len(sa.RelationTypes().All()))
+	for _, t := range sa.RelationTypes().All() {
+		props = append(props, prop(t.Key(), "relation"))
+	}
+	return sortProperties(props)
+}
+
+func urnProperties() []*Property {
+	props := make([]*Property, 0, len(urns.ValidSchemes))
+	for scheme := range urns.ValidSchemes {
+		props = append(props, prop(scheme, TypeText))
+	}
+	return sortProperties(props)
+}
+
+func sortProperties(props []*Property) []*Property {
+	sort.SliceStable(props, func(i, j int) bool { return props[i].Key < props[j].Key })
+	return props
+}
+
+// the untyped values whose keys are taken from references in the flow's templates
+var referenceable = []string{"webhook", "trigger.params", "child.returns"}
+
+// finds the paths referenced under untyped values in the flow's templates, e.g. @webhook.results.0.name gives
+// .results, .results[0] and .results[0].name under webhook
+func referencedPaths(flow flows.Flow) map[string][]string {
+	seen := make(map[string]bool)
+	refs := make(map[string][]string)
+
+	for _, template := range flow.ExtractTemplates() {
+		tools.FindContextRefsInTemplate(template, flows.RunContextTopLevels, func(path []string) {
+			lowered := make([]string, len(path))
+			for i := range path {
+				lowered[i] = strings.ToLower(path[i])
+			}
+			joined := strings.Join(lowered, ".")
+
+			for _, base := range referenceable {
+				if !strings.HasPrefix(joined, base+".") {
+					continue
+				}
+
+				rest := lowered[strings.Count(base, ".")+1:]
+				sub := &strings.Builder{}
+				for _, p := range rest {
+					if _, err := strconv.Atoi(p); err == nil {
+						sub.WriteString("[" + p + "]")
+					} else {
+						sub.WriteString("." + p)
+					}
+				}
+
+				if key := base + sub.String(); !seen[key] {
+					seen[key] = true
+					refs[base] = append(refs[base], sub.String())
+				}
+			}
+		})
+	}
+
+	for base := range refs {
+		sort.Strings(refs[base])
+	}
+
+	return refs
+}
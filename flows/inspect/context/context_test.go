@@ -0,0 +1,93 @@
+package context_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/flows/inspect/context"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnumerate(t *testing.T) {
+	sa, err := test.CreateSessionAssets([]byte(`{
+		"flows": [
+			{
+				"uuid": "1b462ce8-983a-4393-b133-e15a0efdb70c",
+				"name": "Lookup",
+				"spec_version": "13.2.0",
+				"language": "eng",
+				"type": "messaging",
+				"nodes": [
+					{
+						"uuid": "dd8d3c2b-9e7b-4a7e-8e0a-b5b0a3c3bb8f",
+						"actions": [
+							{"uuid": "8eebd020-1af5-431c-b943-aa670fc74da9", "type": "call_webhook", "method": "GET", "url": "http://example.com/?source=@trigger.params.source", "result_name": "Customer Lookup"},
+							{"uuid": "5a2e3e0d-2d7b-4a1b-8f4c-b5c3e8d1f2a3", "type": "send_msg", "text": "Hi @webhook.customer.Name, your last order was @(webhook.orders.0.sku) and you are @child.returns.age"}
+						],
+						"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a"}]
+					}
+				]
+			}
+		],
+		"fields": [
+			{"uuid": "d66a7823-eada-40e5-9a3a-57239d4690bf", "key": "gender", "name": "Gender", "type": "text"},
+			{"uuid": "f1b5aea6-6586-41c7-9020-1a6326cc6565", "key": "age", "name": "Age", "type": "number"}
+		],
+		"globals": [
+			{"key": "org_name", "name": "Org Name", "value": "Nyaruka"}
+		]
+	}`), "")
+	require.NoError(t, err)
+
+	flow, err := sa.Flows().Get("1b462ce8-983a-4393-b133-e15a0efdb70c")
+	require.NoError(t, err)
+
+	keys := context.Enumerate(flow, sa)
+
+	types := make(map[string]string, len(keys))
+	arrays := make([]string, 0)
+	for _, k := range keys {
+		types[k.Path] = k.Type
+		if k.Array {
+			arrays = append(arrays, k.Path)
+		}
+	}
+
+	assert.Equal(t, "contact", types["contact"])
+	assert.Equal(t, "text", types["contact.name"])
+	assert.Equal(t, "datetime", types["contact.created_on"])
+	assert.Equal(t, "number", types["contact.fields.age"])
+	assert.Equal(t, "text", types["contact.fields.gender"])
+	assert.Equal(t, "number", types["fields.age"])
+	assert.Equal(t, "text", types["contact.groups[0].name"])
+	assert.Equal(t, "text", types["urns.tel"])
+	assert.Equal(t, "result", types["results.customer_lookup"])
+	assert.Equal(t, "text", types["results.customer_lookup.category"])
+	assert.Equal(t, "result", types["run.results.customer_lookup"])
+	assert.Equal(t, "text", types["globals.org_name"])
+	assert.Equal(t, "number", types["cart.count"])
+	assert.Equal(t, "text", types["child.contact.name"])
+	assert.Equal(t, "any", types["parent.results"])
+
+	// keys of untyped values are found from templates
+	assert.Equal(t, "any", types["webhook"])
+	assert.Equal(t, "any", types["webhook.customer"])
+	assert.Equal(t, "any", types["webhook.customer.name"])
+	assert.Equal(t, "any", types["webhook.orders[0].sku"])
+	assert.Equal(t, "any", types["trigger.params.source"])
+	assert.Equal(t, "any", types["child.returns.age"])
+
+	assert.Equal(t, []string{
+		"contact.urns", "contact.groups", "contact.tickets", "input.attachments", "input.email.attachments", "run.contact.urns", "run.contact.groups", "run.contact.tickets",
+		"child.contact.urns", "child.contact.groups", "child.contact.tickets", "parent.contact.urns", "parent.contact.groups", "parent.contact.tickets",
+		"cart.items",
+		"intents.ranking",
+	}, arrays)
+
+	// paths which don't exist
+	assert.NotContains(t, types, "contact.foo")
+	assert.NotContains(t, types, "fields.height")
+	assert.NotContains(t, types, "results.favorite_color")
+}
@@ -0,0 +1,52 @@
+package context
+
+import (
+	"github.com/nyaruka/goflow/flows"
+)
+
+// Property is a property of a type in the expression context
+type Property struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Array bool   `json:"array,omitempty"`
+}
+
+func prop(key, type_ string) *Property { return &Property{Key: key, Type: type_} }
+
+// primitive types which have no properties
+const (
+	TypeAny      = "any"
+	TypeText     = "text"
+	TypeNumber   = "number"
+	TypeMoney    = "money"
+	TypeDatetime = "datetime"
+)
+
+// StaticTypes returns the types in the context whose properties are always the same, keyed by name. These are the
+// types registered by the flow objects which provide them.
+func StaticTypes() map[string][]*Property {
+	types := make(map[string][]*Property)
+	for _, t := range flows.RegisteredContextTypes() {
+		if t.Name != "root" {
+			types[t.Name] = typeProperties(t)
+		}
+	}
+	return types
+}
+
+// the properties at the root of the context of a run
+func rootProperties() []*Property {
+	return typeProperties(flows.GetContextType("root"))
+}
+
+// gets the properties of the given context type which can be referenced, i.e. excluding hidden properties and the
+// default value
+func typeProperties(t *flows.ContextType) []*Property {
+	props := make([]*Property, 0, len(t.Properties))
+	for _, p := range t.Documented() {
+		if p.Key != "__default__" {
+			props = append(props, &Property{Key: p.Key, Type: p.Type, Array: p.Array})
+		}
+	}
+	return props
+}
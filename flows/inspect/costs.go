@@ -0,0 +1,124 @@
+package inspect
+
+import (
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/actions"
+
+	"github.com/shopspring/decimal"
+)
+
+// the most paths through a flow which will be costed
+const maxCostPaths = 1000
+
+// PathCost is the estimated cost of one path through a flow
+type PathCost struct {
+	Nodes      []flows.NodeUUID        `json:"nodes"`
+	Operations map[assets.CostType]int `json:"operations"`
+	Cost       decimal.Decimal         `json:"cost"`
+}
+
+// CostEstimate is the estimated cost per contact of running a flow
+type CostEstimate struct {
+	Min       decimal.Decimal `json:"min"`
+	Avg       decimal.Decimal `json:"avg"`
+	Max       decimal.Decimal `json:"max"`
+	Paths     []*PathCost     `json:"paths"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// EstimateCosts estimates the cost per contact of running the given flow from the given cost assets. If a channel is
+// given, its own message costs are used where they exist. Every path from the entry node to where the flow ends, or
+// loops back to a node already on the path, is costed and the average treats each path as equally likely. Subflows and
+// messages to other contacts aren't included.
+func EstimateCosts(flow flows.Flow, costs *flows.CostAssets, channel *assets.ChannelReference) *CostEstimate {
+	estimate := &CostEstimate{Paths: make([]*PathCost, 0)}
+
+	if len(flow.Nodes()) == 0 {
+		return estimate
+	}
+
+	var walk func(flows.Node, []flows.NodeUUID, map[flows.NodeUUID]bool)
+	walk = func(node flows.Node, path []flows.NodeUUID, onPath map[flows.NodeUUID]bool) {
+		if len(estimate.Paths) >= maxCostPaths {
+			estimate.Truncated = true
+			return
+		}
+
+		path = append(path, node.UUID())
+		onPath[node.UUID()] = true
+		defer delete(onPath, node.UUID())
+
+		// a path continues through each distinct destination of the node's exits which isn't already on the path
+		destinations := make([]flows.Node, 0, len(node.Exits()))
+		seen := make(map[flows.NodeUUID]bool)
+		for _, exit := range node.Exits() {
+			dest := exit.DestinationUUID()
+			if dest != "" && !onPath[dest] && !seen[dest] {
+				if destNode := flow.GetNode(dest); destNode != nil {
+					destinations = append(destinations, destNode)
+				}
+			}
+			seen[dest] = true
+		}
+
+		if len(destinations) == 0 {
+			estimate.Paths = append(estimate.Paths, costPath(flow, append([]flows.NodeUUID(nil), path...), costs, channel))
+			return
+		}
+
+		for _, dest := range destinations {
+			walk(dest, path, onPath)
+		}
+	}
+
+	walk(flow.Nodes()[0], nil, make(map[flows.NodeUUID]bool))
+
+	total := decimal.Zero
+	for i, p := range estimate.Paths {
+		if i == 0 || p.Cost.LessThan(estimate.Min) {
+			estimate.Min = p.Cost
+		}
+		if i == 0 || p.Cost.GreaterThan(estimate.Max) {
+			estimate.Max = p.Cost
+		}
+		total = total.Add(p.Cost)
+	}
+	estimate.Avg = total.Div(decimal.NewFromInt(int64(len(estimate.Paths)))).Round(6)
+
+	return estimate
+}
+
+func costPath(flow flows.Flow, nodes []flows.NodeUUID, costs *flows.CostAssets, channel *assets.ChannelReference) *PathCost {
+	p := &PathCost{Nodes: nodes, Operations: make(map[assets.CostType]int), Cost: decimal.Zero}
+
+	for _, nodeUUID := range nodes {
+		for _, action := range flow.GetNode(nodeUUID).Actions() {
+			type_, count := actionOperations(action)
+			if count > 0 {
+				p.Operations[type_] += count
+				p.Cost = p.Cost.Add(costs.UnitCost(type_, channel).Mul(decimal.NewFromInt(int64(count))))
+			}
+		}
+	}
+	return p
+}
+
+// gets the type and number of billable operations performed by the given action
+func actionOperations(action flows.Action) (assets.CostType, int) {
+	switch a := action.(type) {
+	case *actions.SendMsgAction, *actions.SendWhatsAppTemplateAction, *actions.SendWhatsAppFlowAction:
+		return assets.CostTypeMsg, 1
+	case *actions.SendMsgSequenceAction:
+		return assets.CostTypeMsg, len(a.Messages)
+	case *actions.CallWebhookAction, *actions.CallResthookAction, *actions.CallGraphQLAction, *actions.CallSOAPAction:
+		return assets.CostTypeWebhook, 1
+	case *actions.CallClassifierAction:
+		return assets.CostTypeClassifier, 1
+	case *actions.SendEmailAction:
+		return assets.CostTypeEmail, 1
+	case *actions.OpenTicketAction:
+		return assets.CostTypeTicket, 1
+	}
+	return "", 0
+}
@@ -0,0 +1,109 @@
+package inspect_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/definition"
+	"github.com/nyaruka/goflow/flows/inspect"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateCosts(t *testing.T) {
+	flow, err := definition.ReadFlow([]byte(`{
+		"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+		"name": "Onboarding",
+		"spec_version": "13.2.0",
+		"language": "eng",
+		"type": "messaging",
+		"nodes": [
+			{
+				"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"actions": [
+					{"uuid": "ad154980-7bf7-4ab8-8728-545fd6378912", "type": "send_msg", "text": "Welcome!"}
+				],
+				"router": {
+					"type": "random",
+					"categories": [
+						{"uuid": "598ae7a5-2f81-48f1-afac-595262514aa1", "name": "A", "exit_uuid": "49a47f31-ec90-42b5-a0d8-6efb5b1fa57b"},
+						{"uuid": "c70fe86c-9aac-4cc2-a5cb-d35cbe3fed6e", "name": "B", "exit_uuid": "5bd6a427-2b9a-4a4d-ad3f-eb39eaaa7e5a"},
+						{"uuid": "78ae8f05-f92e-43b2-a886-406eaea1b8e0", "name": "C", "exit_uuid": "b787ffe3-c21a-46ad-9475-954614b52477"}
+					]
+				},
+				"exits": [
+					{"uuid": "49a47f31-ec90-42b5-a0d8-6efb5b1fa57b", "destination_uuid": "3dcccbb4-d29c-41dd-a01f-16d814c9ab82"},
+					{"uuid": "5bd6a427-2b9a-4a4d-ad3f-eb39eaaa7e5a", "destination_uuid": "c52a4ee9-1e4f-4e8b-a4c6-2c9ae1c6c6b1"},
+					{"uuid": "b787ffe3-c21a-46ad-9475-954614b52477", "destination_uuid": "c52a4ee9-1e4f-4e8b-a4c6-2c9ae1c6c6b1"}
+				]
+			},
+			{
+				"uuid": "3dcccbb4-d29c-41dd-a01f-16d814c9ab82",
+				"actions": [
+					{"uuid": "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f", "type": "call_webhook", "method": "GET", "url": "http://example.com"},
+					{"uuid": "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b", "type": "send_msg", "text": "Thanks"}
+				],
+				"exits": [{"uuid": "9688d21d-95aa-4bed-afc7-f31b35731a3d", "destination_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507"}]
+			},
+			{
+				"uuid": "c52a4ee9-1e4f-4e8b-a4c6-2c9ae1c6c6b1",
+				"actions": [
+					{
+						"uuid": "4f1b7d9e-3a2c-4e5f-8b6a-9c0d1e2f3a4b",
+						"type": "send_msg_sequence",
+						"messages": [
+							{"uuid": "3f2e4b9a-0c5d-4e8f-b1a7-6d9c2e5f8a13", "text": "Tip 1", "delay_seconds": 0},
+							{"uuid": "ab79e1b8-2a17-4d5c-9a31-7c2f3b9b1e25", "text": "Tip 2", "delay_seconds": 86400}
+						]
+					}
+				],
+				"exits": [{"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a"}]
+			}
+		]
+	}`), nil)
+	require.NoError(t, err)
+
+	vonage := assets.NewChannelReference("58e9b092-fe42-4173-876c-ff45a14a24fe", "Vonage")
+	costs := flows.NewCostAssets([]assets.Cost{
+		static.NewCost(assets.CostTypeMsg, nil, decimal.RequireFromString("0.01")),
+		static.NewCost(assets.CostTypeMsg, vonage, decimal.RequireFromString("0.005")),
+		static.NewCost(assets.CostTypeWebhook, nil, decimal.RequireFromString("0.002")),
+	})
+
+	// paths end where the flow ends or where it loops back, and exits to the same node are only followed once
+	estimate := inspect.EstimateCosts(flow, costs, nil)
+
+	test.AssertEqualJSON(t, []byte(`{
+		"min": 0.022,
+		"avg": 0.026,
+		"max": 0.03,
+		"paths": [
+			{
+				"nodes": ["a58be63b-907d-4a1a-856b-0bb5579d7507", "3dcccbb4-d29c-41dd-a01f-16d814c9ab82"],
+				"operations": {"msg": 2, "webhook": 1},
+				"cost": 0.022
+			},
+			{
+				"nodes": ["a58be63b-907d-4a1a-856b-0bb5579d7507", "c52a4ee9-1e4f-4e8b-a4c6-2c9ae1c6c6b1"],
+				"operations": {"msg": 3},
+				"cost": 0.03
+			}
+		]
+	}`), jsonx.MustMarshal(estimate), "cost estimate mismatch")
+
+	// channel specific message costs are used if a channel is given
+	estimate = inspect.EstimateCosts(flow, costs, vonage)
+	assert.Equal(t, "0.012", estimate.Min.String())
+	assert.Equal(t, "0.015", estimate.Max.String())
+
+	// flows with no costs configured are free
+	estimate = inspect.EstimateCosts(flow, flows.NewCostAssets(nil), nil)
+	assert.True(t, estimate.Max.IsZero())
+	assert.Len(t, estimate.Paths, 2)
+}
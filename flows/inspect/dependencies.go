@@ -3,6 +3,7 @@ package inspect
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/goflow/assets"
@@ -137,3 +138,103 @@ func extractAssetReferences(v reflect.Value, include func(envs.Language, assets.
 		}
 	}
 }
+
+// RepairReference tries to repair a reference to a missing asset by finding an asset of the same type with the same
+// name, which is what happens when a flow is imported from another workspace. It returns whether it was repaired.
+func RepairReference(sa flows.SessionAssets, ref assets.Reference) bool {
+	switch typed := ref.(type) {
+	case *assets.ClassifierReference:
+		for _, c := range sa.Classifiers().All() {
+			if strings.EqualFold(c.Name(), typed.Name) {
+				typed.UUID, typed.Name = c.UUID(), c.Name()
+				return true
+			}
+		}
+	case *assets.FieldReference:
+		for _, f := range sa.Fields().All() {
+			if strings.EqualFold(f.Name(), typed.Name) {
+				typed.Key, typed.Name = f.Key(), f.Name()
+				return true
+			}
+		}
+	case *assets.FlowReference:
+		if f, err := sa.Flows().FindByName(typed.Name); err == nil {
+			typed.UUID, typed.Name = f.UUID(), f.Name()
+			return true
+		}
+	case *assets.GlobalReference:
+		for _, g := range sa.Globals().All() {
+			if strings.EqualFold(g.Name(), typed.Name) {
+				typed.Key, typed.Name = g.Key(), g.Name()
+				return true
+			}
+		}
+	case *assets.GroupReference:
+		if g := sa.Groups().FindByName(typed.Name); g != nil {
+			typed.UUID, typed.Name = g.UUID(), g.Name()
+			return true
+		}
+	case *assets.LabelReference:
+		if l := sa.Labels().FindByName(typed.Name); l != nil {
+			typed.UUID, typed.Name = l.UUID(), l.Name()
+			return true
+		}
+	case *assets.TicketerReference:
+		for _, t := range sa.Ticketers().All() {
+			if strings.EqualFold(t.Name(), typed.Name) {
+				typed.UUID, typed.Name = t.UUID(), t.Name()
+				return true
+			}
+		}
+	case *assets.TopicReference:
+		if t := sa.Topics().FindByName(typed.Name); t != nil {
+			typed.UUID, typed.Name = t.UUID(), t.Name()
+			return true
+		}
+	}
+	return false
+}
+
+// StripReferences removes references for which the given function returns true from the engine fields of the given
+// flow object, where that can be done without making it invalid, i.e. from lists of references and from optional
+// references. It returns the references which were removed.
+func StripReferences(s any, strip func(assets.Reference) bool) []assets.Reference {
+	stripped := make([]assets.Reference, 0)
+
+	walk(reflect.ValueOf(s), nil, func(sv reflect.Value, fv reflect.Value, ef *EngineField) {
+		if !fv.CanSet() {
+			return
+		}
+
+		if fv.Kind() == reflect.Slice {
+			kept := reflect.MakeSlice(fv.Type(), 0, fv.Len())
+			for i := 0; i < fv.Len(); i++ {
+				if ref := asReference(fv.Index(i)); ref != nil && strip(ref) {
+					stripped = append(stripped, ref)
+				} else {
+					kept = reflect.Append(kept, fv.Index(i))
+				}
+			}
+			if kept.Len() < fv.Len() {
+				fv.Set(kept)
+			}
+		} else if ref := asReference(fv); ref != nil && !ef.Required && strip(ref) {
+			fv.Set(reflect.Zero(fv.Type()))
+			stripped = append(stripped, ref)
+		}
+	})
+
+	return stripped
+}
+
+// gets the given value as an asset reference if it is one
+func asReference(v reflect.Value) assets.Reference {
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil
+	}
+	ref, isRef := v.Interface().(assets.Reference)
+	if !isRef {
+		return nil
+	}
+	return ref
+}
@@ -0,0 +1,72 @@
+package issues
+
+import (
+	"fmt"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent"
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeAmbiguousConcatenation, AmbiguousConcatenationCheck)
+}
+
+// TypeAmbiguousConcatenation is our type for this issue
+const TypeAmbiguousConcatenation string = "ambiguous_concatenation"
+
+// AmbiguousConcatenation is a template which concatenates expressions by placing them next to each other
+type AmbiguousConcatenation struct {
+	baseIssue
+
+	Template string `json:"template"`
+}
+
+func newAmbiguousConcatenation(nodeUUID flows.NodeUUID, actionUUID flows.ActionUUID, language envs.Language, template, first, second string) *AmbiguousConcatenation {
+	return &AmbiguousConcatenation{
+		baseIssue: newBaseIssue(
+			TypeAmbiguousConcatenation,
+			nodeUUID,
+			actionUUID,
+			language,
+			fmt.Sprintf("adjacent expressions %s%s should be concatenated with the & operator", first, second),
+		),
+		Template: template,
+	}
+}
+
+// AmbiguousConcatenationCheck checks for templates where expressions directly follow each other, e.g.
+// @contact.first_name@contact.last_name, which are easily misread and should be written as a single expression using
+// the & operator instead. This is only checked in flows which use version 2 or later of the expressions language.
+func AmbiguousConcatenationCheck(sa flows.SessionAssets, flow flows.Flow, tpls []flows.ExtractedTemplate, refs []flows.ExtractedReference, report func(flows.Issue)) {
+	if flow.ExpressionsVersion() < envs.ExpressionsVersion2 {
+		return
+	}
+
+	for _, t := range tpls {
+		var previous string
+		reported := false
+
+		excellent.VisitTemplate(t.Template, flows.RunContextTopLevels, func(tokenType excellent.XTokenType, token string) error {
+			var current string
+			switch tokenType {
+			case excellent.IDENTIFIER:
+				current = "@" + token
+			case excellent.EXPRESSION:
+				current = "@(" + token + ")"
+			}
+
+			if previous != "" && current != "" && !reported {
+				var actionUUID flows.ActionUUID
+				if t.Action != nil {
+					actionUUID = t.Action.UUID()
+				}
+				report(newAmbiguousConcatenation(t.Node.UUID(), actionUUID, t.Language, t.Template, previous, current))
+				reported = true
+			}
+
+			previous = current
+			return nil
+		})
+	}
+}
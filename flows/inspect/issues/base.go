@@ -5,6 +5,8 @@ import (
 
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 type reportFunc func(flows.SessionAssets, flows.Flow, []flows.ExtractedTemplate, []flows.ExtractedReference, func(flows.Issue))
@@ -16,6 +18,16 @@ func registerType(name string, report reportFunc) {
 	RegisteredTypes[name] = report
 }
 
+type analyzeFunc func(flows.Flow, []flows.ExtractedTemplate, []flows.ExtractedResult, func(flows.Issue))
+
+// RegisteredAnalyses are the types of issue found by analyzing the structure of a flow without any assets
+var RegisteredAnalyses = map[string]analyzeFunc{}
+
+// registers a new type of issue found by analysis
+func registerAnalysis(name string, analyze analyzeFunc) {
+	RegisteredAnalyses[name] = analyze
+}
+
 // base of all issue types
 type baseIssue struct {
 	Type_        string           `json:"type"`
@@ -58,11 +70,39 @@ func Check(sa flows.SessionAssets, flow flows.Flow, tpls []flows.ExtractedTempla
 		issues = append(issues, i)
 	}
 
-	for _, fn := range RegisteredTypes {
-		fn(sa, flow, tpls, refs, report)
+	// run checks in a fixed order so that issues on the same node are always reported in the same order
+	names := maps.Keys(RegisteredTypes)
+	slices.Sort(names)
+
+	for _, name := range names {
+		RegisteredTypes[name](sa, flow, tpls, refs, report)
 	}
 
-	// sort issues by node order
+	sortByNode(flow, issues)
+	return issues
+}
+
+// Analyze returns all issues found by analyzing the structure of the given flow, such as dead nodes and loops
+func Analyze(flow flows.Flow, tpls []flows.ExtractedTemplate, results []flows.ExtractedResult) []flows.Issue {
+	issues := make([]flows.Issue, 0)
+	report := func(i flows.Issue) {
+		issues = append(issues, i)
+	}
+
+	// run analyses in a fixed order so that issues on the same node are always reported in the same order
+	names := maps.Keys(RegisteredAnalyses)
+	slices.Sort(names)
+
+	for _, name := range names {
+		RegisteredAnalyses[name](flow, tpls, results, report)
+	}
+
+	sortByNode(flow, issues)
+	return issues
+}
+
+// sorts issues by the order of their nodes in the flow
+func sortByNode(flow flows.Flow, issues []flows.Issue) {
 	nodeOrder := make(map[flows.NodeUUID]int, len(flow.Nodes()))
 	for i, node := range flow.Nodes() {
 		nodeOrder[node.UUID()] = i
@@ -70,6 +110,4 @@ func Check(sa flows.SessionAssets, flow flows.Flow, tpls []flows.ExtractedTempla
 	sort.SliceStable(issues, func(i, j int) bool {
 		return nodeOrder[issues[i].NodeUUID()] < nodeOrder[issues[j].NodeUUID()]
 	})
-
-	return issues
 }
@@ -23,11 +23,24 @@ func TestIssueTypes(t *testing.T) {
 	require.NoError(t, err)
 
 	for typeName := range issues.RegisteredTypes {
-		testIssueType(t, assets, typeName)
+		testIssueType(t, typeName, func(flow flows.Flow, noAssets bool) []flows.Issue {
+			if noAssets {
+				return flow.Inspect(nil).Issues
+			}
+			return flow.Inspect(assets).Issues
+		})
+	}
+}
+
+func TestAnalysisTypes(t *testing.T) {
+	for typeName := range issues.RegisteredAnalyses {
+		testIssueType(t, typeName, func(flow flows.Flow, noAssets bool) []flows.Issue {
+			return flow.Analyze()
+		})
 	}
 }
 
-func testIssueType(t *testing.T, sa flows.SessionAssets, typeName string) {
+func testIssueType(t *testing.T, typeName string, find func(flows.Flow, bool) []flows.Issue) {
 	testPath := fmt.Sprintf("testdata/%s.json", typeName)
 	testFile, err := os.ReadFile(testPath)
 	require.NoError(t, err)
@@ -49,13 +62,7 @@ func testIssueType(t *testing.T, sa flows.SessionAssets, typeName string) {
 		flow, err := definition.ReadFlow(tc.Flow, nil)
 		require.NoError(t, err, "error reading flow in %s", testName)
 
-		var sessionAssets flows.SessionAssets
-		if !tc.NoAssets {
-			sessionAssets = sa
-		}
-
-		info := flow.Inspect(sessionAssets)
-		issuesJSON := jsonx.MustMarshal(info.Issues)
+		issuesJSON := jsonx.MustMarshal(find(flow, tc.NoAssets))
 
 		// clone test case and populate with actual values
 		actual := tc
@@ -0,0 +1,46 @@
+package issues
+
+import (
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerAnalysis(TypeDeadNode, DeadNodeAnalysis)
+}
+
+// TypeDeadNode is our type for a node which can't be reached
+const TypeDeadNode string = "dead_node"
+
+// DeadNode is a node which can't be reached from the start of the flow
+type DeadNode struct {
+	baseIssue
+}
+
+func newDeadNode(nodeUUID flows.NodeUUID) *DeadNode {
+	return &DeadNode{
+		baseIssue: newBaseIssue(
+			TypeDeadNode,
+			nodeUUID,
+			"",
+			envs.NilLanguage,
+			"node can't be reached from the start of the flow",
+		),
+	}
+}
+
+// DeadNodeAnalysis looks for nodes which no path from the first node leads to
+func DeadNodeAnalysis(flow flows.Flow, tpls []flows.ExtractedTemplate, results []flows.ExtractedResult, report func(flows.Issue)) {
+	if len(flow.Nodes()) == 0 {
+		return
+	}
+
+	entry := flow.Nodes()[0].UUID()
+	reachable := newFlowGraph(flow).descendants(entry)
+
+	for _, node := range flow.Nodes()[1:] {
+		if !reachable[node.UUID()] {
+			report(newDeadNode(node.UUID()))
+		}
+	}
+}
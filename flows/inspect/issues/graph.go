@@ -0,0 +1,181 @@
+package issues
+
+import (
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/actions"
+)
+
+// the nodes of a flow connected by the exits, branches and timers which lead from one node to another
+type flowGraph struct {
+	flow         flows.Flow
+	successors   map[flows.NodeUUID][]flows.NodeUUID
+	predecessors map[flows.NodeUUID][]flows.NodeUUID
+	immediate    map[flows.NodeUUID][]flows.NodeUUID // successors which are visited straight away, i.e. not by timers
+}
+
+func newFlowGraph(flow flows.Flow) *flowGraph {
+	g := &flowGraph{
+		flow:         flow,
+		successors:   make(map[flows.NodeUUID][]flows.NodeUUID, len(flow.Nodes())),
+		predecessors: make(map[flows.NodeUUID][]flows.NodeUUID, len(flow.Nodes())),
+		immediate:    make(map[flows.NodeUUID][]flows.NodeUUID, len(flow.Nodes())),
+	}
+
+	addEdge := func(from, to flows.NodeUUID, immediate bool) {
+		if flow.GetNode(to) != nil {
+			g.successors[from] = append(g.successors[from], to)
+			g.predecessors[to] = append(g.predecessors[to], from)
+			if immediate {
+				g.immediate[from] = append(g.immediate[from], to)
+			}
+		}
+	}
+
+	for _, node := range flow.Nodes() {
+		for _, exit := range node.Exits() {
+			if exit.DestinationUUID() != "" {
+				addEdge(node.UUID(), exit.DestinationUUID(), true)
+			}
+		}
+
+		if forking, isForking := node.Router().(flows.ForkingRouter); isForking {
+			for _, branch := range forking.Branches() {
+				addEdge(node.UUID(), branch, true)
+			}
+		}
+
+		for _, a := range node.Actions() {
+			if timer, isTimer := a.(*actions.SetTimerAction); isTimer {
+				addEdge(node.UUID(), timer.NodeUUID, false)
+			}
+		}
+	}
+
+	return g
+}
+
+// finds the nodes that can be reached from the given node by following at least one edge in the given direction
+func (g *flowGraph) reachable(from flows.NodeUUID, edges map[flows.NodeUUID][]flows.NodeUUID) map[flows.NodeUUID]bool {
+	seen := make(map[flows.NodeUUID]bool)
+	queue := append([]flows.NodeUUID(nil), edges[from]...)
+
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		if !seen[n] {
+			seen[n] = true
+			queue = append(queue, edges[n]...)
+		}
+	}
+	return seen
+}
+
+// finds the nodes which can be visited after the given node
+func (g *flowGraph) descendants(uuid flows.NodeUUID) map[flows.NodeUUID]bool {
+	return g.reachable(uuid, g.successors)
+}
+
+// finds the nodes which can be visited before the given node, which includes the node itself if it's in a loop
+func (g *flowGraph) ancestors(uuid flows.NodeUUID) map[flows.NodeUUID]bool {
+	return g.reachable(uuid, g.predecessors)
+}
+
+// finds the groups of nodes which form loops that don't pass through any node which might wait, ordered by flow order
+func (g *flowGraph) loopsWithoutWaits() [][]flows.NodeUUID {
+	// use Tarjan's algorithm to find the strongly connected components of the non-waiting nodes
+	index := make(map[flows.NodeUUID]int)
+	lowLink := make(map[flows.NodeUUID]int)
+	onStack := make(map[flows.NodeUUID]bool)
+	stack := make([]flows.NodeUUID, 0)
+	components := make([][]flows.NodeUUID, 0)
+
+	var connect func(flows.NodeUUID)
+	connect = func(n flows.NodeUUID) {
+		index[n] = len(index)
+		lowLink[n] = index[n]
+		stack = append(stack, n)
+		onStack[n] = true
+
+		for _, s := range g.immediate[n] {
+			if mightWait(g.flow.GetNode(s)) {
+				continue
+			}
+			if _, visited := index[s]; !visited {
+				connect(s)
+				lowLink[n] = min(lowLink[n], lowLink[s])
+			} else if onStack[s] {
+				lowLink[n] = min(lowLink[n], index[s])
+			}
+		}
+
+		if lowLink[n] == index[n] {
+			component := make([]flows.NodeUUID, 0)
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == n {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for _, node := range g.flow.Nodes() {
+		if _, visited := index[node.UUID()]; !visited && !mightWait(node) {
+			connect(node.UUID())
+		}
+	}
+
+	// discard components which aren't loops and order the nodes of each loop by flow order
+	loops := make([][]flows.NodeUUID, 0)
+	for _, c := range components {
+		if len(c) == 1 && !g.hasImmediateEdge(c[0], c[0]) {
+			continue
+		}
+
+		loop := make([]flows.NodeUUID, 0, len(c))
+		for _, node := range g.flow.Nodes() {
+			for _, n := range c {
+				if n == node.UUID() {
+					loop = append(loop, n)
+				}
+			}
+		}
+		loops = append(loops, loop)
+	}
+
+	return loops
+}
+
+func (g *flowGraph) hasImmediateEdge(from, to flows.NodeUUID) bool {
+	for _, s := range g.immediate[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checks whether the given node might wait, i.e. it has a wait or enters a flow which might
+func mightWait(node flows.Node) bool {
+	if node.Router() != nil && node.Router().Wait() != nil {
+		return true
+	}
+	for _, a := range node.Actions() {
+		if a.Type() == actions.TypeEnterFlow {
+			return true
+		}
+	}
+	return false
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,74 @@
+package issues
+
+import (
+	"fmt"
+
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/routers"
+	"github.com/nyaruka/goflow/flows/routers/cases"
+)
+
+func init() {
+	registerType(TypeInvalidCaseArguments, InvalidCaseArgumentsCheck)
+}
+
+// TypeInvalidCaseArguments is our type for a router case whose test can't be called with its arguments
+const TypeInvalidCaseArguments string = "invalid_case_arguments"
+
+// InvalidCaseArguments is a router case whose arguments are the wrong number or kind for its test
+type InvalidCaseArguments struct {
+	baseIssue
+
+	CaseUUID uuids.UUID `json:"case_uuid"`
+	Test     string     `json:"test"`
+}
+
+func newInvalidCaseArguments(nodeUUID flows.NodeUUID, language envs.Language, caseUUID uuids.UUID, test, problem string) *InvalidCaseArguments {
+	return &InvalidCaseArguments{
+		baseIssue: newBaseIssue(
+			TypeInvalidCaseArguments,
+			nodeUUID,
+			"",
+			language,
+			fmt.Sprintf("invalid arguments for case %s: %s", caseUUID, problem),
+		),
+		CaseUUID: caseUUID,
+		Test:     test,
+	}
+}
+
+// InvalidCaseArgumentsCheck checks for switch router cases whose arguments, or translations of them, are the wrong
+// number or kind for their test. The groups of has_group cases are checked against assets as dependencies so a group
+// which doesn't exist is reported as a missing dependency.
+func InvalidCaseArgumentsCheck(sa flows.SessionAssets, flow flows.Flow, tpls []flows.ExtractedTemplate, refs []flows.ExtractedReference, report func(flows.Issue)) {
+	for _, node := range flow.Nodes() {
+		if node.Router() == nil || node.Router().Type() != routers.TypeSwitch {
+			continue
+		}
+
+		for _, kase := range node.Router().(*routers.SwitchRouter).Cases() {
+			checkArgs := func(lang envs.Language, args []string) {
+				if err := cases.ValidateArguments(kase.Type, args); err != nil {
+					report(newInvalidCaseArguments(node.UUID(), lang, kase.UUID, kase.Type, err.Error()))
+				}
+			}
+
+			checkArgs(envs.NilLanguage, kase.Arguments)
+
+			// translations with a different number of arguments to the case are ignored when routing
+			for _, lang := range flow.Localization().Languages() {
+				localized := flow.Localization().GetItemTranslation(lang, kase.UUID, "arguments")
+				if len(localized) == 0 {
+					continue
+				}
+				if len(localized) != len(kase.Arguments) {
+					report(newInvalidCaseArguments(node.UUID(), lang, kase.UUID, kase.Type, fmt.Sprintf("translation has %d arguments but case has %d", len(localized), len(kase.Arguments))))
+					continue
+				}
+				checkArgs(lang, localized)
+			}
+		}
+	}
+}
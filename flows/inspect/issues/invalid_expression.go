@@ -0,0 +1,55 @@
+package issues
+
+import (
+	"fmt"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent"
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerType(TypeInvalidExpression, InvalidExpressionCheck)
+}
+
+// TypeInvalidExpression is our type for an expression which can't be parsed
+const TypeInvalidExpression string = "invalid_expression"
+
+// InvalidExpression is an expression in a template which can't be parsed
+type InvalidExpression struct {
+	baseIssue
+
+	Expression string `json:"expression"`
+}
+
+func newInvalidExpression(nodeUUID flows.NodeUUID, actionUUID flows.ActionUUID, language envs.Language, expression, message string) *InvalidExpression {
+	return &InvalidExpression{
+		baseIssue: newBaseIssue(
+			TypeInvalidExpression,
+			nodeUUID,
+			actionUUID,
+			language,
+			fmt.Sprintf("invalid expression %s: %s", expression, message),
+		),
+		Expression: expression,
+	}
+}
+
+// InvalidExpressionCheck checks for expressions which can't be parsed, using the templates compiled when the flow was
+// read, so that these are found when the flow is saved rather than when a run tries to evaluate them
+func InvalidExpressionCheck(sa flows.SessionAssets, flow flows.Flow, tpls []flows.ExtractedTemplate, refs []flows.ExtractedReference, report func(flows.Issue)) {
+	for _, t := range tpls {
+		compiled := flow.CompiledTemplate(t.Template)
+		if compiled == nil {
+			compiled = excellent.CompileTemplate(t.Template, flows.RunContextTopLevels)
+		}
+
+		for _, err := range compiled.Errors().Errors() {
+			var actionUUID flows.ActionUUID
+			if t.Action != nil {
+				actionUUID = t.Action.UUID()
+			}
+			report(newInvalidExpression(t.Node.UUID(), actionUUID, t.Language, err.Expression(), err.Message()))
+		}
+	}
+}
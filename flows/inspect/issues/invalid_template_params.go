@@ -0,0 +1,95 @@
+package issues
+
+import (
+	"fmt"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/actions"
+)
+
+func init() {
+	registerType(TypeInvalidTemplateParams, InvalidTemplateParamsCheck)
+}
+
+// TypeInvalidTemplateParams is our type for this issue
+const TypeInvalidTemplateParams string = "invalid_template_params"
+
+// InvalidTemplateParams is a template component with the wrong number of params
+type InvalidTemplateParams struct {
+	baseIssue
+
+	Component string `json:"component"`
+	Expected  int    `json:"expected"`
+	Given     int    `json:"given"`
+}
+
+func newInvalidTemplateParams(nodeUUID flows.NodeUUID, actionUUID flows.ActionUUID, template, component string, expected, given int, missing bool) *InvalidTemplateParams {
+	description := fmt.Sprintf("template '%s' component '%s' expects %d params but %d given", template, component, expected, given)
+	if missing {
+		description = fmt.Sprintf("template '%s' has no component '%s'", template, component)
+	}
+
+	return &InvalidTemplateParams{
+		baseIssue: newBaseIssue(
+			TypeInvalidTemplateParams,
+			nodeUUID,
+			actionUUID,
+			envs.NilLanguage,
+			description,
+		),
+		Component: component,
+		Expected:  expected,
+		Given:     given,
+	}
+}
+
+// InvalidTemplateParamsCheck checks that send_whatsapp_template actions give the number of params for each component
+// that every translation of their template expects
+func InvalidTemplateParamsCheck(sa flows.SessionAssets, flow flows.Flow, tpls []flows.ExtractedTemplate, refs []flows.ExtractedReference, report func(flows.Issue)) {
+	// can't check without assets
+	if sa == nil {
+		return
+	}
+
+	for _, node := range flow.Nodes() {
+		for _, a := range node.Actions() {
+			if a.Type() != actions.TypeSendWhatsAppTemplate {
+				continue
+			}
+
+			action := a.(*actions.SendWhatsAppTemplateAction)
+			template := sa.Templates().Get(action.Template.UUID)
+			if template == nil {
+				continue // will be reported as a missing dependency
+			}
+
+			given := make(map[string]int, len(action.Components))
+			for _, c := range action.Components {
+				given[c.Name] = len(c.Params)
+			}
+
+			// translations often share components so only report each mismatch once
+			reported := make(map[string]bool)
+			check := func(component string, expected, actual int, missing bool) {
+				key := fmt.Sprintf("%s:%d:%d", component, expected, actual)
+				if expected != actual && !reported[key] {
+					report(newInvalidTemplateParams(node.UUID(), a.UUID(), template.Name(), component, expected, actual, missing))
+					reported[key] = true
+				}
+			}
+
+			for _, t := range template.Translations() {
+				trans := flows.NewTemplateTranslation(t)
+				for _, comp := range trans.Components() {
+					check(comp.Name(), comp.ParamCount(), given[comp.Name()], false)
+				}
+				for _, c := range action.Components {
+					if trans.Component(c.Name) == nil {
+						check(c.Name, 0, len(c.Params), true)
+					}
+				}
+			}
+		}
+	}
+}
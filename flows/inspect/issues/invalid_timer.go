@@ -0,0 +1,49 @@
+package issues
+
+import (
+	"fmt"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/actions"
+)
+
+func init() {
+	registerType(TypeInvalidTimer, InvalidTimerCheck)
+}
+
+// TypeInvalidTimer is our type for a timer which can't fire
+const TypeInvalidTimer string = "invalid_timer"
+
+// InvalidTimer is a timer whose node doesn't exist in the flow
+type InvalidTimer struct {
+	baseIssue
+
+	Name          string         `json:"name"`
+	TimerNodeUUID flows.NodeUUID `json:"timer_node_uuid"`
+}
+
+func newInvalidTimer(nodeUUID flows.NodeUUID, actionUUID flows.ActionUUID, name string, timerNodeUUID flows.NodeUUID) *InvalidTimer {
+	return &InvalidTimer{
+		baseIssue: newBaseIssue(
+			TypeInvalidTimer,
+			nodeUUID,
+			actionUUID,
+			envs.NilLanguage,
+			fmt.Sprintf("node %s for timer '%s' doesn't exist in this flow", timerNodeUUID, name),
+		),
+		Name:          name,
+		TimerNodeUUID: timerNodeUUID,
+	}
+}
+
+// InvalidTimerCheck checks for set_timer actions whose node doesn't exist in the flow
+func InvalidTimerCheck(sa flows.SessionAssets, flow flows.Flow, tpls []flows.ExtractedTemplate, refs []flows.ExtractedReference, report func(flows.Issue)) {
+	for _, node := range flow.Nodes() {
+		for _, action := range node.Actions() {
+			if setTimer, isSetTimer := action.(*actions.SetTimerAction); isSetTimer && flow.GetNode(setTimer.NodeUUID) == nil {
+				report(newInvalidTimer(node.UUID(), action.UUID(), setTimer.Name, setTimer.NodeUUID))
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package issues
+
+import (
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerAnalysis(TypeLoopWithoutWait, LoopWithoutWaitAnalysis)
+}
+
+// TypeLoopWithoutWait is our type for a loop which never waits
+const TypeLoopWithoutWait string = "loop_without_wait"
+
+// LoopWithoutWait is a loop of nodes none of which waits, which risks looping until the engine's step limit is reached
+type LoopWithoutWait struct {
+	baseIssue
+
+	NodeUUIDs []flows.NodeUUID `json:"node_uuids"`
+}
+
+func newLoopWithoutWait(nodeUUIDs []flows.NodeUUID) *LoopWithoutWait {
+	return &LoopWithoutWait{
+		baseIssue: newBaseIssue(
+			TypeLoopWithoutWait,
+			nodeUUIDs[0],
+			"",
+			envs.NilLanguage,
+			"loop doesn't wait for anything and could repeat forever",
+		),
+		NodeUUIDs: nodeUUIDs,
+	}
+}
+
+// LoopWithoutWaitAnalysis looks for loops of nodes which don't include any waits or subflows. Such a loop isn't
+// necessarily infinite because its routers might exit the loop, but these are worth checking.
+func LoopWithoutWaitAnalysis(flow flows.Flow, tpls []flows.ExtractedTemplate, results []flows.ExtractedResult, report func(flows.Issue)) {
+	for _, loop := range newFlowGraph(flow).loopsWithoutWaits() {
+		report(newLoopWithoutWait(loop))
+	}
+}
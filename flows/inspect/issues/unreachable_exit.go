@@ -0,0 +1,103 @@
+package issues
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/routers"
+	"github.com/nyaruka/goflow/flows/routers/waits"
+)
+
+func init() {
+	registerAnalysis(TypeUnreachableExit, UnreachableExitAnalysis)
+}
+
+// TypeUnreachableExit is our type for an exit which can never be taken
+const TypeUnreachableExit string = "unreachable_exit"
+
+// UnreachableExit is an exit of a router which can never be taken
+type UnreachableExit struct {
+	baseIssue
+
+	ExitUUID   flows.ExitUUID `json:"exit_uuid"`
+	Categories []string       `json:"categories"`
+}
+
+func newUnreachableExit(nodeUUID flows.NodeUUID, exitUUID flows.ExitUUID, categories []string) *UnreachableExit {
+	var description string
+	if len(categories) == 0 {
+		description = "exit isn't used by any category"
+	} else {
+		description = fmt.Sprintf("exit can never be taken as nothing routes to its categories: '%s'", strings.Join(categories, "', '"))
+	}
+
+	return &UnreachableExit{
+		baseIssue:  newBaseIssue(TypeUnreachableExit, nodeUUID, "", envs.NilLanguage, description),
+		ExitUUID:   exitUUID,
+		Categories: categories,
+	}
+}
+
+// UnreachableExitAnalysis looks for router exits which aren't used by any category, or whose categories can never be
+// picked, e.g. a category of a switch router which has no case, isn't the default and isn't used by its wait
+func UnreachableExitAnalysis(flow flows.Flow, tpls []flows.ExtractedTemplate, results []flows.ExtractedResult, report func(flows.Issue)) {
+	for _, node := range flow.Nodes() {
+		router := node.Router()
+		if router == nil {
+			continue
+		}
+
+		// for switch routers we can work out which categories can actually be picked
+		var pickable map[flows.CategoryUUID]bool
+		if switchRouter, isSwitch := router.(*routers.SwitchRouter); isSwitch {
+			pickable = switchCategories(switchRouter)
+		}
+
+		for _, exit := range node.Exits() {
+			categories := make([]string, 0)
+			reachable := false
+
+			for _, c := range router.Categories() {
+				if c.ExitUUID() == exit.UUID() {
+					categories = append(categories, c.Name())
+					if pickable == nil || pickable[c.UUID()] {
+						reachable = true
+					}
+				}
+			}
+
+			if !reachable {
+				report(newUnreachableExit(node.UUID(), exit.UUID(), categories))
+			}
+		}
+	}
+}
+
+// gets the categories of a switch router which can be picked by its cases, by default or by its wait
+func switchCategories(router *routers.SwitchRouter) map[flows.CategoryUUID]bool {
+	pickable := make(map[flows.CategoryUUID]bool)
+
+	for _, c := range router.Cases() {
+		pickable[c.CategoryUUID] = true
+	}
+	if router.DefaultCategoryUUID() != "" {
+		pickable[router.DefaultCategoryUUID()] = true
+	}
+
+	if router.AllowTimeout() {
+		pickable[router.Wait().Timeout().CategoryUUID()] = true
+	}
+	if wait := router.Wait(); wait != nil {
+		if race, isRace := wait.(*waits.RaceWait); isRace {
+			for _, b := range race.Branches() {
+				if b.CategoryUUID() != "" {
+					pickable[b.CategoryUUID()] = true
+				}
+			}
+		}
+	}
+
+	return pickable
+}
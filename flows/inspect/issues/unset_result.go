@@ -0,0 +1,108 @@
+package issues
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/tools"
+	"github.com/nyaruka/goflow/flows"
+)
+
+func init() {
+	registerAnalysis(TypeUnsetResult, UnsetResultAnalysis)
+}
+
+// TypeUnsetResult is our type for a reference to a result which can't have been set yet
+const TypeUnsetResult string = "unset_result"
+
+// UnsetResult is a template reference to a result which no earlier node or action can have set
+type UnsetResult struct {
+	baseIssue
+
+	Result string `json:"result"`
+}
+
+func newUnsetResult(nodeUUID flows.NodeUUID, actionUUID flows.ActionUUID, language envs.Language, result string) *UnsetResult {
+	return &UnsetResult{
+		baseIssue: newBaseIssue(
+			TypeUnsetResult,
+			nodeUUID,
+			actionUUID,
+			language,
+			fmt.Sprintf("result '%s' is referenced before any node can set it", result),
+		),
+		Result: result,
+	}
+}
+
+// UnsetResultAnalysis looks for templates which reference results of the current run which can't have been set by
+// the time the template is evaluated
+func UnsetResultAnalysis(flow flows.Flow, tpls []flows.ExtractedTemplate, results []flows.ExtractedResult, report func(flows.Issue)) {
+	graph := newFlowGraph(flow)
+	ancestors := make(map[flows.NodeUUID]map[flows.NodeUUID]bool)
+	reported := make(map[string]bool)
+
+	// checks whether the given result can have been set before the given action or router of the given node
+	canBeSet := func(key string, node flows.Node, action flows.Action) bool {
+		if ancestors[node.UUID()] == nil {
+			ancestors[node.UUID()] = graph.ancestors(node.UUID())
+		}
+
+		for _, r := range results {
+			if r.Info.Key != key {
+				continue
+			}
+			if ancestors[node.UUID()][r.Node.UUID()] {
+				return true
+			}
+			if r.Node.UUID() == node.UUID() && r.Action != nil && actionIndex(node, r.Action) < actionIndex(node, action) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, t := range tpls {
+		tools.FindContextRefsInTemplate(t.Template, flows.RunContextTopLevels, func(path []string) {
+			key := runResultKey(path)
+			if key == "" || canBeSet(key, t.Node, t.Action) {
+				return
+			}
+
+			var actionUUID flows.ActionUUID
+			if t.Action != nil {
+				actionUUID = t.Action.UUID()
+			}
+
+			dedupeKey := fmt.Sprintf("%s|%s|%s|%s", t.Node.UUID(), actionUUID, t.Language, key)
+			if !reported[dedupeKey] {
+				reported[dedupeKey] = true
+				report(newUnsetResult(t.Node.UUID(), actionUUID, t.Language, key))
+			}
+		})
+	}
+}
+
+// gets the key of the result of the current run referenced by the given context path, e.g. results.age or run.results.age
+func runResultKey(path []string) string {
+	if len(path) > 1 && strings.ToLower(path[0]) == "results" {
+		return strings.ToLower(path[1])
+	}
+	if len(path) > 2 && strings.ToLower(path[0]) == "run" && strings.ToLower(path[1]) == "results" {
+		return strings.ToLower(path[2])
+	}
+	return ""
+}
+
+// gets the position of the given action in the given node, with no action (i.e. the router) coming after all actions
+func actionIndex(node flows.Node, action flows.Action) int {
+	if action != nil {
+		for i, a := range node.Actions() {
+			if a.UUID() == action.UUID() {
+				return i
+			}
+		}
+	}
+	return len(node.Actions())
+}
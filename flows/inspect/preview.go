@@ -0,0 +1,307 @@
+package inspect
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/stringsx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+	"golang.org/x/exp/slices"
+)
+
+// sample values used for contact fields based on their type
+var sampleFieldValues = map[assets.FieldType]string{
+	assets.FieldTypeText:   "Example",
+	assets.FieldTypeNumber: "42",
+}
+
+// SampleContext generates a plausible context for the given flow which can be used to preview templates. It contains
+// a fake contact with a value for each text, number and datetime field, a result for every result the flow can
+// generate, and an example webhook response.
+func SampleContext(env envs.Environment, sa flows.SessionAssets, flow flows.Flow) *types.XObject {
+	contact := sampleContact(env, sa, env.DefaultLanguage())
+
+	results := flows.NewResults()
+	for _, spec := range flow.Inspect(sa).Results {
+		var value, category string
+		if len(spec.Categories) > 0 {
+			value, category = spec.Categories[0], spec.Categories[0]
+		} else {
+			value = "Example"
+		}
+
+		var nodeUUID flows.NodeUUID
+		if len(spec.NodeUUIDs) > 0 {
+			nodeUUID = flows.NodeUUID(spec.NodeUUIDs[0])
+		}
+
+		results.Save(flows.NewResult(spec.Name, value, category, "", nodeUUID, value, nil, dates.Now()))
+	}
+
+	webhook := types.NewXObject(map[string]types.XValue{
+		"status": types.NewXText("success"),
+		"results": types.NewXArray(types.NewXObject(map[string]types.XValue{
+			"id":   types.NewXNumberFromInt(123),
+			"name": types.NewXText("Example"),
+		})),
+	})
+
+	return types.NewXObject(map[string]types.XValue{
+		"contact": flows.Context(env, contact),
+		"fields":  flows.Context(env, contact.Fields()),
+		"urns":    flows.ContextFunc(env, contact.URNs().MapContext),
+		"results": flows.Context(env, results),
+		"globals": flows.Context(env, sa.Globals()),
+		"webhook": webhook,
+	})
+}
+
+// creates a fake contact with the given language and a value for each text, number and datetime field
+func sampleContact(env envs.Environment, sa flows.SessionAssets, language envs.Language) *flows.Contact {
+	contact := flows.NewEmptyContact(sa, "Jane Doe", language, env.Timezone())
+	contact.AddURN(urns.URN("tel:+12065551212"), nil)
+
+	for _, field := range sa.Fields().All() {
+		raw, hasSample := sampleFieldValues[field.Type()]
+		if field.Type() == assets.FieldTypeDatetime {
+			raw, hasSample = dates.Now().In(env.Timezone()).Format("2006-01-02T15:04:05Z07:00"), true
+		}
+		if hasSample {
+			contact.Fields().Set(field, contact.Fields().Parse(env, sa.Fields(), field, raw))
+		}
+	}
+	return contact
+}
+
+// PreviewTemplates renders the given templates against a sample context for the given flow
+func PreviewTemplates(env envs.Environment, sa flows.SessionAssets, flow flows.Flow, templates []string) ([]string, error) {
+	return excellent.EvaluateAll(env, SampleContext(env, sa, flow), templates)
+}
+
+// BroadcastPreview is the content of a broadcast as it would be sent to a single contact
+type BroadcastPreview struct {
+	Contact      *flows.ContactReference  `json:"contact"`
+	Language     envs.Language            `json:"language"`
+	URN          urns.URN                 `json:"urn,omitempty"`
+	Channel      *assets.ChannelReference `json:"channel,omitempty"`
+	Text         string                   `json:"text"`
+	Attachments  []utils.Attachment       `json:"attachments,omitempty"`
+	QuickReplies []string                 `json:"quick_replies,omitempty"`
+	Errors       []string                 `json:"errors,omitempty"`
+}
+
+// PreviewBroadcast renders the given broadcast content for each of the given contacts. The translation for each contact
+// is selected in the same way as when the broadcast is sent, and its templates are evaluated against a context with
+// that contact, their fields and URNs, and the globals. The URN and channel are those the message would be sent with,
+// and are empty if the contact has no sendable URN. Templates which fail to evaluate are reported as errors on the
+// preview rather than failing the whole batch.
+func PreviewBroadcast(env envs.Environment, sa flows.SessionAssets, translations flows.BroadcastTranslations, baseLanguage envs.Language, contacts []*flows.Contact) []*BroadcastPreview {
+	previews := make([]*BroadcastPreview, len(contacts))
+
+	for i, contact := range contacts {
+		previews[i] = previewBroadcastForContact(env, sa, translations, baseLanguage, contact)
+	}
+
+	return previews
+}
+
+func previewBroadcastForContact(env envs.Environment, sa flows.SessionAssets, translations flows.BroadcastTranslations, baseLanguage envs.Language, contact *flows.Contact) *BroadcastPreview {
+	translation, language := translations.ForContact(env, contact, baseLanguage)
+	preview := &BroadcastPreview{Contact: contact.Reference(), Language: language}
+
+	destinations := contact.ResolveDestinations(false)
+	if len(destinations) > 0 {
+		preview.URN = destinations[0].URN.URN().Identity()
+		preview.Channel = destinations[0].Channel.Reference()
+	}
+
+	if translation == nil {
+		return preview
+	}
+
+	rendered, errs := renderTranslation(env, sa, translation, contact)
+	preview.Text = rendered.Text
+	preview.Attachments = rendered.Attachments
+	preview.QuickReplies = rendered.QuickReplies
+	preview.Errors = errs
+
+	return preview
+}
+
+// evaluates the templates of the given broadcast content against a context with the given contact, their fields and
+// URNs, and the globals, returning the rendered content and any evaluation errors
+func renderTranslation(env envs.Environment, sa flows.SessionAssets, translation *flows.BroadcastTranslation, contact *flows.Contact) (*flows.BroadcastTranslation, []string) {
+	rendered := &flows.BroadcastTranslation{}
+	var errs []string
+
+	contactEnv := &contactEnvironment{flows.NewEnvironment(env, sa.Locations(), sa.LookupTables()), contact}
+	ctx := types.NewXObject(map[string]types.XValue{
+		"contact": flows.Context(contactEnv, contact),
+		"fields":  flows.Context(contactEnv, contact.Fields()),
+		"urns":    flows.ContextFunc(contactEnv, contact.URNs().MapContext),
+		"globals": flows.Context(contactEnv, sa.Globals()),
+	})
+
+	evaluate := func(template string) string {
+		evaluated, err := excellent.EvaluateTemplate(contactEnv, ctx, template, nil)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		return evaluated
+	}
+
+	rendered.Text = evaluate(translation.Text)
+	if limit := env.TruncationPolicy().MsgText; limit > 0 && utf8.RuneCountInString(rendered.Text) > limit {
+		rendered.Text = stringsx.Truncate(rendered.Text, limit)
+	}
+
+	for _, attachment := range translation.Attachments {
+		if evaluated := evaluate(string(attachment)); evaluated != "" {
+			rendered.Attachments = append(rendered.Attachments, utils.Attachment(evaluated))
+		}
+	}
+	for _, quickReply := range translation.QuickReplies {
+		if evaluated := evaluate(quickReply); evaluated != "" {
+			rendered.QuickReplies = append(rendered.QuickReplies, evaluated)
+		}
+	}
+
+	return rendered, errs
+}
+
+// MissingTranslation is a part of some content which isn't translated into a language, and the language whose
+// translation was used instead
+type MissingTranslation struct {
+	Property string        `json:"property"`
+	Used     envs.Language `json:"used"`
+}
+
+// RenderedTranslation is some content rendered in a single language
+type RenderedTranslation struct {
+	Language     envs.Language         `json:"language"`
+	Text         string                `json:"text"`
+	Attachments  []utils.Attachment    `json:"attachments,omitempty"`
+	QuickReplies []string              `json:"quick_replies,omitempty"`
+	Missing      []*MissingTranslation `json:"missing,omitempty"`
+	Errors       []string              `json:"errors,omitempty"`
+}
+
+// RenderTranslations renders the given content into each of the environment's allowed languages, or just the base
+// language if there are none. Each of the text, attachments and quick replies is taken from the first language to
+// have it out of the language itself, the fallbacks for that language (which may be nil), and then the base language.
+// Any part taken from another language is reported as missing. Templates are evaluated against a sample contact who
+// speaks the language being rendered.
+func RenderTranslations(env envs.Environment, sa flows.SessionAssets, translations flows.BroadcastTranslations, baseLanguage envs.Language, fallbacks func(envs.Language) []envs.Language) []*RenderedTranslation {
+	languages := env.AllowedLanguages()
+	if len(languages) == 0 {
+		languages = []envs.Language{baseLanguage}
+	}
+
+	rendered := make([]*RenderedTranslation, len(languages))
+	for i, language := range languages {
+		chain := []envs.Language{language}
+		if fallbacks != nil {
+			chain = append(chain, fallbacks(language)...)
+		}
+		chain = append(chain, baseLanguage)
+
+		rendered[i] = renderLanguage(env, sa, translations, language, chain)
+	}
+	return rendered
+}
+
+func renderLanguage(env envs.Environment, sa flows.SessionAssets, translations flows.BroadcastTranslations, language envs.Language, chain []envs.Language) *RenderedTranslation {
+	result := &RenderedTranslation{Language: language}
+	content := &flows.BroadcastTranslation{}
+
+	// finds the first language in the chain which has the given property
+	resolve := func(property string, has func(*flows.BroadcastTranslation) bool) *flows.BroadcastTranslation {
+		for _, lang := range chain {
+			if t := translations[lang]; t != nil && has(t) {
+				if lang != language {
+					result.Missing = append(result.Missing, &MissingTranslation{Property: property, Used: lang})
+				}
+				return t
+			}
+		}
+		return nil
+	}
+
+	if t := resolve("text", func(t *flows.BroadcastTranslation) bool { return t.Text != "" }); t != nil {
+		content.Text = t.Text
+	}
+	if t := resolve("attachments", func(t *flows.BroadcastTranslation) bool { return len(t.Attachments) > 0 }); t != nil {
+		content.Attachments = t.Attachments
+	}
+	if t := resolve("quick_replies", func(t *flows.BroadcastTranslation) bool { return len(t.QuickReplies) > 0 }); t != nil {
+		content.QuickReplies = t.QuickReplies
+	}
+
+	rendered, errs := renderTranslation(env, sa, content, sampleContact(env, sa, language))
+	result.Text = rendered.Text
+	result.Attachments = rendered.Attachments
+	result.QuickReplies = rendered.QuickReplies
+	result.Errors = errs
+
+	return result
+}
+
+// LocalizedTranslations builds broadcast content in each language of the given localization from the base content of
+// an item, e.g. a send_msg action, and its translations
+func LocalizedTranslations(localization flows.Localization, itemUUID uuids.UUID, base *flows.BroadcastTranslation, baseLanguage envs.Language) flows.BroadcastTranslations {
+	translations := flows.BroadcastTranslations{baseLanguage: base}
+
+	for _, lang := range localization.Languages() {
+		t := &flows.BroadcastTranslation{}
+		if text := localization.GetItemTranslation(lang, itemUUID, "text"); len(text) > 0 {
+			t.Text = text[0]
+		}
+		for _, a := range localization.GetItemTranslation(lang, itemUUID, "attachments") {
+			t.Attachments = append(t.Attachments, utils.Attachment(a))
+		}
+		t.QuickReplies = localization.GetItemTranslation(lang, itemUUID, "quick_replies")
+
+		translations[lang] = t
+	}
+	return translations
+}
+
+// an environment which takes the timezone, language and country of a contact where they have them
+type contactEnvironment struct {
+	envs.Environment
+
+	contact *flows.Contact
+}
+
+func (e *contactEnvironment) Timezone() *time.Location {
+	if e.contact.Timezone() != nil {
+		return e.contact.Timezone()
+	}
+	return e.Environment.Timezone()
+}
+
+func (e *contactEnvironment) DefaultLanguage() envs.Language {
+	if e.contact.Language() != envs.NilLanguage && slices.Contains(e.AllowedLanguages(), e.contact.Language()) {
+		return e.contact.Language()
+	}
+	return e.Environment.DefaultLanguage()
+}
+
+func (e *contactEnvironment) DefaultCountry() envs.Country {
+	if cc := e.contact.Country(); cc != envs.NilCountry {
+		return cc
+	}
+	return e.Environment.DefaultCountry()
+}
+
+func (e *contactEnvironment) DefaultLocale() envs.Locale {
+	return envs.NewLocale(e.DefaultLanguage(), e.DefaultCountry())
+}
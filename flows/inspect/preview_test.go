@@ -0,0 +1,195 @@
+package inspect_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/definition"
+	"github.com/nyaruka/goflow/flows/inspect"
+	"github.com/nyaruka/goflow/test"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewTemplates(t *testing.T) {
+	dates.SetNowSource(dates.NewFixedNowSource(time.Date(2023, 3, 1, 12, 30, 0, 0, time.UTC)))
+	defer dates.SetNowSource(dates.DefaultNowSource)
+
+	env := envs.NewBuilder().Build()
+	sa, err := test.LoadSessionAssets(env, "../../test/testdata/runner/two_questions.json")
+	require.NoError(t, err)
+
+	flow, err := sa.Flows().Get(assets.FlowUUID("615b8a0f-588c-4d20-a05f-363b0b4ce6f4"))
+	require.NoError(t, err)
+
+	ctx := inspect.SampleContext(env, sa, flow)
+	assert.ElementsMatch(t, []string{"contact", "fields", "globals", "results", "urns", "webhook"}, ctx.Properties())
+
+	previews, err := inspect.PreviewTemplates(env, sa, flow, []string{
+		`Hi @contact.first_name`,
+		`Your number is @urns.tel`,
+		`You like @results.favorite_color and @results.soda.category`,
+		`Gender: @fields.gender`,
+		`Webhook said @webhook.status`,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`Hi Jane`,
+		`Your number is tel:+12065551212`,
+		`You like Red and Pepsi`,
+		`Gender: Example`,
+		`Webhook said success`,
+	}, previews)
+
+	// errors are reported for templates which reference things that don't exist
+	_, err = inspect.PreviewTemplates(env, sa, flow, []string{`@results.age`})
+	assert.EqualError(t, err, `error evaluating @results.age: object has no property 'age'`)
+}
+
+func TestPreviewBroadcast(t *testing.T) {
+	session, _, err := test.CreateTestSession("", envs.RedactionPolicyNone)
+	require.NoError(t, err)
+
+	env := session.Environment()
+	sa := session.Assets()
+
+	// a Spanish speaking contact with no URNs and a contact with a language that isn't allowed
+	maria := flows.NewEmptyContact(sa, "María", "spa", nil)
+	jean := flows.NewEmptyContact(sa, "Jean", "fra", nil)
+
+	translations := flows.BroadcastTranslations{
+		"eng": {
+			Text:         "Hi @contact.first_name, your gender is @fields.gender",
+			Attachments:  []utils.Attachment{"image/jpeg:http://example.com/@(contact.language).jpg"},
+			QuickReplies: []string{"Yes", "@(\"\")"},
+		},
+		"spa": {
+			Text: "Hola @contact.first_name, @(1 / 0)",
+		},
+	}
+
+	previews := inspect.PreviewBroadcast(env, sa, translations, "eng", []*flows.Contact{session.Contact(), maria, jean})
+	require.Len(t, previews, 3)
+
+	assert.Equal(t, &inspect.BroadcastPreview{
+		Contact:      session.Contact().Reference(),
+		Language:     "eng",
+		URN:          "tel:+12024561111",
+		Channel:      assets.NewChannelReference("57f1078f-88aa-46f4-a59a-948a5739c03d", "My Android Phone"),
+		Text:         "Hi Ryan, your gender is Male",
+		Attachments:  []utils.Attachment{"image/jpeg:http://example.com/eng.jpg"},
+		QuickReplies: []string{"Yes"},
+	}, previews[0])
+
+	assert.Equal(t, &inspect.BroadcastPreview{
+		Contact:  maria.Reference(),
+		Language: "spa",
+		Text:     "Hola María, ",
+		Errors:   []string{"error evaluating @(1 / 0): division by zero"},
+	}, previews[1])
+
+	assert.Equal(t, "eng", string(previews[2].Language))
+	assert.Equal(t, "Hi Jean, your gender is ", previews[2].Text)
+}
+
+func TestRenderTranslations(t *testing.T) {
+	session, _, err := test.CreateTestSession("", envs.RedactionPolicyNone)
+	require.NoError(t, err)
+
+	env := envs.NewBuilder().WithAllowedLanguages([]envs.Language{"eng", "spa", "kin"}).Build()
+	sa := session.Assets()
+
+	translations := flows.BroadcastTranslations{
+		"eng": {
+			Text:         "Hi @contact.first_name",
+			Attachments:  []utils.Attachment{"image/jpeg:http://example.com/@(contact.language).jpg"},
+			QuickReplies: []string{"Yes", "No"},
+		},
+		"spa": {
+			Text: "Hola @contact.first_name @(1 / 0)",
+		},
+	}
+	fallbacks := func(lang envs.Language) []envs.Language {
+		if lang == "kin" {
+			return []envs.Language{"spa"}
+		}
+		return nil
+	}
+
+	rendered := inspect.RenderTranslations(env, sa, translations, "eng", fallbacks)
+	require.Len(t, rendered, 3)
+
+	assert.Equal(t, &inspect.RenderedTranslation{
+		Language:     "eng",
+		Text:         "Hi Jane",
+		Attachments:  []utils.Attachment{"image/jpeg:http://example.com/eng.jpg"},
+		QuickReplies: []string{"Yes", "No"},
+	}, rendered[0])
+
+	assert.Equal(t, &inspect.RenderedTranslation{
+		Language:     "spa",
+		Text:         "Hola Jane ",
+		Attachments:  []utils.Attachment{"image/jpeg:http://example.com/spa.jpg"},
+		QuickReplies: []string{"Yes", "No"},
+		Missing: []*inspect.MissingTranslation{
+			{Property: "attachments", Used: "eng"},
+			{Property: "quick_replies", Used: "eng"},
+		},
+		Errors: []string{"error evaluating @(1 / 0): division by zero"},
+	}, rendered[1])
+
+	// languages without any translation use their fallbacks before the base language
+	assert.Equal(t, "kin", string(rendered[2].Language))
+	assert.Equal(t, "Hola Jane ", rendered[2].Text)
+	assert.Equal(t, []*inspect.MissingTranslation{
+		{Property: "text", Used: "spa"},
+		{Property: "attachments", Used: "eng"},
+		{Property: "quick_replies", Used: "eng"},
+	}, rendered[2].Missing)
+
+	// without allowed languages, only the base language is rendered
+	rendered = inspect.RenderTranslations(envs.NewBuilder().Build(), sa, translations, "eng", nil)
+	require.Len(t, rendered, 1)
+	assert.Equal(t, "Hi Jane", rendered[0].Text)
+
+	// content can also come from the localization of a flow
+	flow, err := definition.ReadFlow([]byte(`{
+		"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+		"name": "Greeting",
+		"spec_version": "13.2.0",
+		"language": "eng",
+		"type": "messaging",
+		"localization": {
+			"spa": {
+				"ad154980-7bf7-4ab8-8728-545fd6378912": {"text": ["Hola @contact.first_name"], "quick_replies": ["Sí"]}
+			}
+		},
+		"nodes": [
+			{
+				"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"actions": [
+					{"uuid": "ad154980-7bf7-4ab8-8728-545fd6378912", "type": "send_msg", "text": "Hi @contact.first_name", "quick_replies": ["Yes"]}
+				],
+				"exits": [{"uuid": "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d"}]
+			}
+		]
+	}`), nil)
+	require.NoError(t, err)
+
+	localized := inspect.LocalizedTranslations(flow.Localization(), "ad154980-7bf7-4ab8-8728-545fd6378912", &flows.BroadcastTranslation{Text: "Hi @contact.first_name", QuickReplies: []string{"Yes"}}, "eng")
+	assert.Equal(t, flows.BroadcastTranslations{
+		"eng": {Text: "Hi @contact.first_name", QuickReplies: []string{"Yes"}},
+		"spa": {Text: "Hola @contact.first_name", QuickReplies: []string{"Sí"}},
+	}, localized)
+
+	rendered = inspect.RenderTranslations(env, sa, localized, "eng", flow.LanguageFallbacks)
+	assert.Equal(t, "Hola Jane", rendered[1].Text)
+	assert.Equal(t, []string{"Sí"}, rendered[1].QuickReplies)
+	assert.Nil(t, rendered[1].Missing)
+}
@@ -0,0 +1,271 @@
+package inspect
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/actions"
+	"github.com/nyaruka/goflow/utils"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ReadabilityFlag is a way in which a message exceeds the readability thresholds
+type ReadabilityFlag string
+
+// possible readability flags
+const (
+	ReadabilityFlagReadingLevel ReadabilityFlag = "reading_level"
+	ReadabilityFlagLength       ReadabilityFlag = "length"
+	ReadabilityFlagEmojiDensity ReadabilityFlag = "emoji_density"
+	ReadabilityFlagURLDensity   ReadabilityFlag = "url_density"
+)
+
+// ReadabilityThresholds are the limits above which messages are flagged. A zero threshold isn't checked.
+type ReadabilityThresholds struct {
+	MaxReadingLevel float64 `json:"max_reading_level"` // school grade level
+	MaxLength       int     `json:"max_length"`        // characters
+	MaxEmojiDensity float64 `json:"max_emoji_density"` // emojis per word
+	MaxURLDensity   float64 `json:"max_url_density"`   // URLs per word
+}
+
+// DefaultReadabilityThresholds returns thresholds suitable for content aimed at a general audience
+func DefaultReadabilityThresholds() *ReadabilityThresholds {
+	return &ReadabilityThresholds{MaxReadingLevel: 8, MaxLength: 320, MaxEmojiDensity: 0.2, MaxURLDensity: 0.1}
+}
+
+// MessageReadability is the readability of a send_msg action's text in one language
+type MessageReadability struct {
+	NodeUUID     flows.NodeUUID    `json:"node_uuid"`
+	ActionUUID   flows.ActionUUID  `json:"action_uuid"`
+	Language     envs.Language     `json:"language"`
+	Text         string            `json:"text"`
+	Length       int               `json:"length"`
+	Words        int               `json:"words"`
+	ReadingLevel float64           `json:"reading_level"`
+	EmojiDensity float64           `json:"emoji_density"`
+	URLDensity   float64           `json:"url_density"`
+	Flags        []ReadabilityFlag `json:"flags,omitempty"`
+
+	emojis int
+	urls   int
+}
+
+// LengthDistribution summarizes the lengths of a set of messages
+type LengthDistribution struct {
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median int     `json:"median"`
+	P90    int     `json:"p90"`
+}
+
+// LanguageReadability is the readability of all of a flow's messages in one language
+type LanguageReadability struct {
+	Language     envs.Language       `json:"language"`
+	Messages     int                 `json:"messages"`
+	Flagged      int                 `json:"flagged"`
+	Lengths      *LengthDistribution `json:"lengths"`
+	ReadingLevel float64             `json:"reading_level"`
+	EmojiDensity float64             `json:"emoji_density"`
+	URLDensity   float64             `json:"url_density"`
+}
+
+// ReadabilityReport is the readability of a flow's messages
+type ReadabilityReport struct {
+	Languages []*LanguageReadability `json:"languages"`
+	Messages  []*MessageReadability  `json:"messages"`
+}
+
+// Readability analyzes the text of every send_msg action in the given flow, in its base language and each translation,
+// and flags messages which exceed the given thresholds. Reading levels are Flesch-Kincaid grade levels, which are
+// calibrated for English and so only approximate for other languages. Expressions aren't counted as words since their
+// values aren't known, but lengths are of the text as written.
+func Readability(flow flows.Flow, thresholds *ReadabilityThresholds) *ReadabilityReport {
+	report := &ReadabilityReport{Languages: make([]*LanguageReadability, 0), Messages: make([]*MessageReadability, 0)}
+
+	for _, node := range flow.Nodes() {
+		for _, action := range node.Actions() {
+			send, isSend := action.(*actions.SendMsgAction)
+			if !isSend {
+				continue
+			}
+
+			analyze := func(lang envs.Language, text string) {
+				m := analyzeMessage(lang, text, thresholds)
+				m.NodeUUID = node.UUID()
+				m.ActionUUID = action.UUID()
+				report.Messages = append(report.Messages, m)
+			}
+
+			analyze(flow.Language(), send.Text)
+
+			if flow.Localization() != nil {
+				Translations(flow.Localization(), send.LocalizationUUID(), "text", analyze)
+			}
+		}
+	}
+
+	byLanguage := make(map[envs.Language][]*MessageReadability)
+	for _, m := range report.Messages {
+		byLanguage[m.Language] = append(byLanguage[m.Language], m)
+	}
+
+	for lang, messages := range byLanguage {
+		report.Languages = append(report.Languages, summarizeLanguage(lang, messages))
+	}
+
+	// base language first, then translations
+	sort.Slice(report.Languages, func(i, j int) bool {
+		li, lj := report.Languages[i].Language, report.Languages[j].Language
+		if li == flow.Language() || lj == flow.Language() {
+			return li == flow.Language()
+		}
+		return li < lj
+	})
+
+	return report
+}
+
+var urlRegex = regexp.MustCompile(`(?i)\b(https?://|www\.)\S+`)
+var emojiSymbolRegex = regexp.MustCompile(`\p{So}`)
+var sentenceEndRegex = regexp.MustCompile(`[.!?\x{2026}]+(\s|$)`)
+
+func analyzeMessage(lang envs.Language, text string, thresholds *ReadabilityThresholds) *MessageReadability {
+	// only consider the literal text, not expressions
+	literal := &strings.Builder{}
+	excellent.VisitTemplate(text, flows.RunContextTopLevels, func(tokenType excellent.XTokenType, token string) error {
+		if tokenType == excellent.BODY {
+			literal.WriteString(token)
+		} else {
+			literal.WriteString(" ")
+		}
+		return nil
+	})
+
+	body := literal.String()
+	urls := len(urlRegex.FindAllString(body, -1))
+	body = urlRegex.ReplaceAllString(body, " ")
+	emojis := len(emojiSymbolRegex.FindAllString(body, -1))
+
+	words := utils.TokenizeString(utils.StripEmojis(body))
+	syllables := 0
+	for _, w := range words {
+		syllables += countSyllables(lang, w)
+	}
+
+	sentences := len(sentenceEndRegex.FindAllString(strings.TrimSpace(body)+" ", -1))
+	if sentences == 0 {
+		sentences = 1
+	}
+
+	m := &MessageReadability{
+		Language: lang,
+		Text:     text,
+		Length:   utf8.RuneCountInString(text),
+		Words:    len(words) + urls,
+		emojis:   emojis,
+		urls:     urls,
+	}
+
+	if len(words) > 0 {
+		level := 0.39*float64(len(words))/float64(sentences) + 11.8*float64(syllables)/float64(len(words)) - 15.59
+		m.ReadingLevel = round2(math.Max(level, 0))
+	}
+	if m.Words > 0 {
+		m.EmojiDensity = round2(float64(emojis) / float64(m.Words))
+		m.URLDensity = round2(float64(urls) / float64(m.Words))
+	}
+
+	if thresholds.MaxReadingLevel > 0 && m.ReadingLevel > thresholds.MaxReadingLevel {
+		m.Flags = append(m.Flags, ReadabilityFlagReadingLevel)
+	}
+	if thresholds.MaxLength > 0 && m.Length > thresholds.MaxLength {
+		m.Flags = append(m.Flags, ReadabilityFlagLength)
+	}
+	if thresholds.MaxEmojiDensity > 0 && m.EmojiDensity > thresholds.MaxEmojiDensity {
+		m.Flags = append(m.Flags, ReadabilityFlagEmojiDensity)
+	}
+	if thresholds.MaxURLDensity > 0 && m.URLDensity > thresholds.MaxURLDensity {
+		m.Flags = append(m.Flags, ReadabilityFlagURLDensity)
+	}
+
+	return m
+}
+
+// counts the syllables in a word as the number of groups of vowels, ignoring accents, and for English, a silent e at
+// the end of the word. Words in scripts without vowels are counted as one syllable.
+func countSyllables(lang envs.Language, word string) int {
+	count := 0
+	inVowels := false
+	for _, r := range norm.NFD.String(strings.ToLower(word)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		isVowel := strings.ContainsRune("aeiouy", r)
+		if isVowel && !inVowels {
+			count++
+		}
+		inVowels = isVowel
+	}
+
+	if lang == "eng" && count > 1 && strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") {
+		count--
+	}
+	if count == 0 {
+		count = 1
+	}
+	return count
+}
+
+func summarizeLanguage(lang envs.Language, messages []*MessageReadability) *LanguageReadability {
+	lengths := make([]int, len(messages))
+	totalLength, totalLevel, totalWords, totalEmojis, totalURLs, flagged := 0, 0.0, 0, 0, 0, 0
+
+	for i, m := range messages {
+		lengths[i] = m.Length
+		totalLength += m.Length
+		totalLevel += m.ReadingLevel
+		totalWords += m.Words
+		totalEmojis += m.emojis
+		totalURLs += m.urls
+		if len(m.Flags) > 0 {
+			flagged++
+		}
+	}
+
+	sort.Ints(lengths)
+	percentile := func(p float64) int {
+		return lengths[int(math.Ceil(p*float64(len(lengths))))-1]
+	}
+
+	s := &LanguageReadability{
+		Language: lang,
+		Messages: len(messages),
+		Flagged:  flagged,
+		Lengths: &LengthDistribution{
+			Min:    lengths[0],
+			Max:    lengths[len(lengths)-1],
+			Mean:   round2(float64(totalLength) / float64(len(messages))),
+			Median: percentile(0.5),
+			P90:    percentile(0.9),
+		},
+		ReadingLevel: round2(totalLevel / float64(len(messages))),
+	}
+	if totalWords > 0 {
+		s.EmojiDensity = round2(float64(totalEmojis) / float64(totalWords))
+		s.URLDensity = round2(float64(totalURLs) / float64(totalWords))
+	}
+	return s
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
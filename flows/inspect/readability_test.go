@@ -0,0 +1,148 @@
+package inspect_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows/definition"
+	"github.com/nyaruka/goflow/flows/inspect"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadability(t *testing.T) {
+	flow, err := definition.ReadFlow([]byte(`{
+		"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+		"name": "Clinic",
+		"spec_version": "13.2.0",
+		"language": "eng",
+		"type": "messaging",
+		"nodes": [
+			{
+				"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"actions": [
+					{"uuid": "ad154980-7bf7-4ab8-8728-545fd6378912", "type": "send_msg", "text": "Hi @contact.name! Your visit is on Monday."},
+					{"uuid": "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f", "type": "send_msg", "text": "Comprehensive immunization documentation necessitates authorization from participating administrative institutions."},
+					{"uuid": "b1f2b1c0-5b7e-4e7c-9f1d-000000000000", "type": "set_contact_name", "name": "Bob"},
+					{"uuid": "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b", "type": "send_msg", "text": "Tap 👉 https://clinic.example.com/book 🎉🎉"}
+				],
+				"exits": [{"uuid": "9688d21d-95aa-4bed-afc7-f31b35731a3d"}]
+			}
+		],
+		"localization": {
+			"spa": {
+				"ad154980-7bf7-4ab8-8728-545fd6378912": {"text": ["¡Hola @contact.name! Su cita es el lunes."]}
+			}
+		}
+	}`), nil)
+	require.NoError(t, err)
+
+	report := inspect.Readability(flow, inspect.DefaultReadabilityThresholds())
+
+	test.AssertEqualJSON(t, []byte(`{
+		"languages": [
+			{
+				"language": "eng",
+				"messages": 3,
+				"flagged": 2,
+				"lengths": {
+					"min": 40,
+					"max": 115,
+					"mean": 65.67,
+					"median": 42,
+					"p90": 115
+				},
+				"reading_level": 13.45,
+				"emoji_density": 0.18,
+				"url_density": 0.06
+			},
+			{
+				"language": "spa",
+				"messages": 1,
+				"flagged": 0,
+				"lengths": {
+					"min": 41,
+					"max": 41,
+					"mean": 41,
+					"median": 41,
+					"p90": 41
+				},
+				"reading_level": 3.28,
+				"emoji_density": 0,
+				"url_density": 0
+			}
+		],
+		"messages": [
+			{
+				"node_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"action_uuid": "ad154980-7bf7-4ab8-8728-545fd6378912",
+				"language": "eng",
+				"text": "Hi @contact.name! Your visit is on Monday.",
+				"length": 42,
+				"words": 6,
+				"reading_level": 1.31,
+				"emoji_density": 0,
+				"url_density": 0
+			},
+			{
+				"node_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"action_uuid": "ad154980-7bf7-4ab8-8728-545fd6378912",
+				"language": "spa",
+				"text": "¡Hola @contact.name! Su cita es el lunes.",
+				"length": 41,
+				"words": 6,
+				"reading_level": 3.28,
+				"emoji_density": 0,
+				"url_density": 0
+			},
+			{
+				"node_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"action_uuid": "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f",
+				"language": "eng",
+				"text": "Comprehensive immunization documentation necessitates authorization from participating administrative institutions.",
+				"length": 115,
+				"words": 9,
+				"reading_level": 39.05,
+				"emoji_density": 0,
+				"url_density": 0,
+				"flags": [
+					"reading_level"
+				]
+			},
+			{
+				"node_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+				"action_uuid": "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b",
+				"language": "eng",
+				"text": "Tap 👉 https://clinic.example.com/book 🎉🎉",
+				"length": 40,
+				"words": 2,
+				"reading_level": 0,
+				"emoji_density": 1.5,
+				"url_density": 0.5,
+				"flags": [
+					"emoji_density",
+					"url_density"
+				]
+			}
+		]
+	}`), jsonx.MustMarshal(report), "readability report mismatch")
+
+	// thresholds can be changed, and zero thresholds aren't checked
+	report = inspect.Readability(flow, &inspect.ReadabilityThresholds{MaxLength: 41})
+
+	flagged := make([]string, 0)
+	for _, m := range report.Messages {
+		if len(m.Flags) > 0 {
+			flagged = append(flagged, fmt.Sprintf("%s:%s:%v", m.ActionUUID, m.Language, m.Flags))
+		}
+	}
+	assert.Equal(t, []string{
+		"ad154980-7bf7-4ab8-8728-545fd6378912:eng:[length]",
+		"5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f:eng:[length]",
+	}, flagged)
+	assert.Equal(t, 2, report.Languages[0].Flagged)
+	assert.Equal(t, 0, report.Languages[1].Flagged)
+}
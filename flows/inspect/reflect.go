@@ -14,6 +14,7 @@ type EngineField struct {
 	JSONName  string
 	Localized bool
 	Evaluated bool
+	Required  bool
 	Getter    func(reflect.Value) reflect.Value
 }
 
@@ -52,6 +53,7 @@ func extractEngineFieldsFromType(ct reflect.Type, rt reflect.Type, loc []int, in
 			JSONName:  jsonName,
 			Localized: localized,
 			Evaluated: evaluated,
+			Required:  isRequired(f),
 			Getter:    func(v reflect.Value) reflect.Value { return v.FieldByIndex(index) },
 		})
 	}
@@ -67,6 +69,16 @@ func jsonNameTag(f reflect.StructField) string {
 	return ""
 }
 
+// checks whether the given field has a required validation tag
+func isRequired(f reflect.StructField) bool {
+	for _, v := range strings.Split(f.Tag.Get("validate"), ",") {
+		if v == "required" {
+			return true
+		}
+	}
+	return false
+}
+
 // parses the engine tag on a field if it exists
 func parseEngineTag(st reflect.Type, f reflect.StructField) (localized bool, evaluated bool) {
 	t := f.Type
@@ -138,12 +150,20 @@ func derefValue(v reflect.Value) reflect.Value {
 }
 
 func walkTypes(t reflect.Type, path string, visitField func(string, *EngineField)) {
+	walkTypesFrom(t, path, visitField, make(map[reflect.Type]bool))
+}
+
+// walks the given type, skipping struct types which we're already inside of so that recursive types terminate
+func walkTypesFrom(t reflect.Type, path string, visitField func(string, *EngineField), inside map[reflect.Type]bool) {
 	// get the real underlying type
 	rt := derefType(t)
 
 	if rt.Kind() == reflect.Slice {
-		walkTypes(rt.Elem(), path+"[*]", visitField)
-	} else if rt.Kind() == reflect.Struct {
+		walkTypesFrom(rt.Elem(), path+"[*]", visitField, inside)
+	} else if rt.Kind() == reflect.Struct && !inside[rt] {
+		inside[rt] = true
+		defer delete(inside, rt)
+
 		fields := extractEngineFields(t, rt)
 
 		for _, ef := range fields {
@@ -152,7 +172,7 @@ func walkTypes(t reflect.Type, path string, visitField func(string, *EngineField
 				visitField(fp, ef)
 			}
 
-			walkTypes(ef.Type, fp, visitField)
+			walkTypesFrom(ef.Type, fp, visitField, inside)
 		}
 	}
 }
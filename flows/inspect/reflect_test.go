@@ -161,4 +161,17 @@ func TestWalkTypes(t *testing.T) {
 	})
 
 	assert.Equal(t, []string{".foo", ".bar", ".sub", ".sub.zed", ".slice", ".slice[*].zed"}, paths)
+
+	// recursive types are only walked once on each path
+	type treeType struct {
+		Name     string      `json:"name"`
+		Children []*treeType `json:"children"`
+	}
+
+	paths = make([]string, 0)
+	walkTypes(reflect.TypeOf(treeType{}), "", func(path string, ef *EngineField) {
+		paths = append(paths, path)
+	})
+
+	assert.Equal(t, []string{".name", ".children"}, paths)
 }
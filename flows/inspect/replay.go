@@ -0,0 +1,182 @@
+package inspect
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+// ReplayIssueType is the type of a problem found by replaying a stored run against its flow
+type ReplayIssueType string
+
+// possible types of replay issue
+const (
+	ReplayIssueMissingFlow     ReplayIssueType = "missing_flow"
+	ReplayIssueMissingNode     ReplayIssueType = "missing_node"
+	ReplayIssueMissingExit     ReplayIssueType = "missing_exit"
+	ReplayIssueBrokenPath      ReplayIssueType = "broken_path"
+	ReplayIssueUnknownStep     ReplayIssueType = "unknown_step"
+	ReplayIssueUnknownResult   ReplayIssueType = "unknown_result"
+	ReplayIssueUnknownCategory ReplayIssueType = "unknown_category"
+)
+
+// ReplayIssue is a problem found by replaying a stored run against its flow, i.e. something in the run which couldn't
+// have been produced by the current version of the flow
+type ReplayIssue struct {
+	Type        ReplayIssueType `json:"type"`
+	RunUUID     flows.RunUUID   `json:"run_uuid,omitempty"`
+	StepUUID    flows.StepUUID  `json:"step_uuid,omitempty"`
+	NodeUUID    flows.NodeUUID  `json:"node_uuid,omitempty"`
+	Description string          `json:"description"`
+}
+
+// the parts of a stored run that are checked when it is replayed
+type replayRun struct {
+	UUID   flows.RunUUID         `json:"uuid"`
+	Flow   *assets.FlowReference `json:"flow" validate:"required"`
+	Path   []*replayStep         `json:"path" validate:"dive"`
+	Events []*replayEvent        `json:"events,omitempty"`
+}
+
+type replayStep struct {
+	UUID     flows.StepUUID `json:"uuid" validate:"required"`
+	NodeUUID flows.NodeUUID `json:"node_uuid" validate:"required"`
+	ExitUUID flows.ExitUUID `json:"exit_uuid,omitempty"`
+}
+
+// only the properties of events which are checked are read, so that events of any type can be replayed
+type replayEvent struct {
+	Type     string         `json:"type"`
+	StepUUID flows.StepUUID `json:"step_uuid,omitempty"`
+	Name     string         `json:"name,omitempty"`
+	Category string         `json:"category,omitempty"`
+}
+
+// ReplaySession replays each of the runs in the given serialized session against its flow from the given assets
+func ReplaySession(sa flows.SessionAssets, data json.RawMessage) ([]*ReplayIssue, error) {
+	s := &struct {
+		Runs []json.RawMessage `json:"runs"`
+	}{}
+	if err := utils.UnmarshalAndValidate(data, s); err != nil {
+		return nil, errors.Wrap(err, "unable to read session")
+	}
+
+	issues := make([]*ReplayIssue, 0)
+
+	for _, runJSON := range s.Runs {
+		run := &replayRun{}
+		if err := utils.UnmarshalAndValidate(runJSON, run); err != nil {
+			return nil, errors.Wrap(err, "unable to read run")
+		}
+
+		flow, err := sa.Flows().Get(run.Flow.UUID)
+		if err != nil {
+			issues = append(issues, &ReplayIssue{
+				Type:        ReplayIssueMissingFlow,
+				RunUUID:     run.UUID,
+				Description: fmt.Sprintf("flow %s doesn't exist", run.Flow),
+			})
+			continue
+		}
+
+		issues = append(issues, run.replay(flow)...)
+	}
+
+	return issues, nil
+}
+
+// ReplayRun replays the given serialized run against the given flow
+func ReplayRun(flow flows.Flow, data json.RawMessage) ([]*ReplayIssue, error) {
+	run := &replayRun{}
+	if err := utils.UnmarshalAndValidate(data, run); err != nil {
+		return nil, errors.Wrap(err, "unable to read run")
+	}
+	return run.replay(flow), nil
+}
+
+func (r *replayRun) replay(flow flows.Flow) []*ReplayIssue {
+	issues := make([]*ReplayIssue, 0)
+	report := func(t ReplayIssueType, s *replayStep, format string, a ...any) {
+		issue := &ReplayIssue{Type: t, RunUUID: r.UUID, Description: fmt.Sprintf(format, a...)}
+		if s != nil {
+			issue.StepUUID = s.UUID
+			issue.NodeUUID = s.NodeUUID
+		}
+		issues = append(issues, issue)
+	}
+
+	// check that every step is at a node which exists and leaves by one of its exits to the node of the next step
+	nodesByStep := make(map[flows.StepUUID]flows.Node, len(r.Path))
+
+	for i, s := range r.Path {
+		node := flow.GetNode(s.NodeUUID)
+		if node == nil {
+			report(ReplayIssueMissingNode, s, "node %s doesn't exist in the flow", s.NodeUUID)
+			continue
+		}
+		nodesByStep[s.UUID] = node
+
+		if s.ExitUUID == "" {
+			continue
+		}
+
+		var exit flows.Exit
+		for _, e := range node.Exits() {
+			if e.UUID() == s.ExitUUID {
+				exit = e
+				break
+			}
+		}
+		if exit == nil {
+			report(ReplayIssueMissingExit, s, "exit %s doesn't exist on node %s", s.ExitUUID, s.NodeUUID)
+			continue
+		}
+
+		if i < len(r.Path)-1 && exit.DestinationUUID() != r.Path[i+1].NodeUUID {
+			report(ReplayIssueBrokenPath, s, "exit %s of node %s doesn't lead to node %s", s.ExitUUID, s.NodeUUID, r.Path[i+1].NodeUUID)
+		}
+	}
+
+	// check that events belong to steps and that saved results could have come from their nodes
+	stepsByUUID := make(map[flows.StepUUID]*replayStep, len(r.Path))
+	for _, s := range r.Path {
+		stepsByUUID[s.UUID] = s
+	}
+
+	for _, e := range r.Events {
+		if e.StepUUID == "" {
+			continue
+		}
+		step := stepsByUUID[e.StepUUID]
+		if step == nil {
+			report(ReplayIssueUnknownStep, nil, "%s event references step %s which isn't in the path", e.Type, e.StepUUID)
+			continue
+		}
+
+		node := nodesByStep[e.StepUUID]
+		if node == nil || e.Type != "run_result_changed" {
+			continue
+		}
+
+		var info *flows.ResultInfo
+		node.EnumerateResults(func(a flows.Action, rt flows.Router, i *flows.ResultInfo) {
+			if i.Name == e.Name {
+				info = i
+			}
+		})
+
+		if info == nil {
+			report(ReplayIssueUnknownResult, step, "result '%s' isn't saved by node %s", e.Name, step.NodeUUID)
+		} else if e.Category != "" && len(info.Categories) > 0 && !slices.Contains(info.Categories, e.Category) {
+			report(ReplayIssueUnknownCategory, step, "category '%s' isn't a category of result '%s'", e.Category, e.Name)
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,157 @@
+package inspect_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/inspect"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const replayAssetsJSON = `{
+	"flows": [
+		{
+			"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+			"name": "Cheese",
+			"spec_version": "13.2.0",
+			"language": "eng",
+			"type": "messaging",
+			"nodes": [
+				{
+					"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+					"actions": [{"uuid": "ad154980-7bf7-4ab8-8728-545fd6378912", "type": "send_msg", "text": "Do you like cheese?"}],
+					"exits": [{"uuid": "3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b", "destination_uuid": "b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e"}]
+				},
+				{
+					"uuid": "b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e",
+					"router": {
+						"type": "switch",
+						"wait": {"type": "msg"},
+						"operand": "@input.text",
+						"result_name": "Likes Cheese",
+						"cases": [
+							{"uuid": "9f593e22-7886-4c08-a52f-0e8780504d75", "type": "has_any_word", "arguments": ["yes"], "category_uuid": "97b9451c-2856-475b-af38-32af68100897"}
+						],
+						"categories": [
+							{"uuid": "97b9451c-2856-475b-af38-32af68100897", "name": "Yes", "exit_uuid": "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d"},
+							{"uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3", "name": "Other", "exit_uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}
+						],
+						"default_category_uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3"
+					},
+					"exits": [
+						{"uuid": "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d", "destination_uuid": "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b"},
+						{"uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}
+					]
+				},
+				{
+					"uuid": "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b",
+					"actions": [{"uuid": "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f", "type": "send_msg", "text": "Great!"}],
+					"exits": [{"uuid": "9688d21d-95aa-4bed-afc7-f31b35731a3d"}]
+				}
+			]
+		}
+	]
+}`
+
+func TestReplaySession(t *testing.T) {
+	sa, session, _ := test.NewSessionBuilder().WithAssetsJSON([]byte(replayAssetsJSON)).WithFlow("8ca44c09-791d-453a-9799-a70dd3303306").MustBuild()
+
+	msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), urns.URN("tel:+12065551212"), nil, "yes", nil)
+	_, err := session.Resume(context.Background(), resumes.NewMsg(session.Environment(), nil, msg))
+	require.NoError(t, err)
+
+	sessionJSON := jsonx.MustMarshal(session)
+	runUUID := session.Runs()[0].UUID()
+	path := session.Runs()[0].Path()
+
+	// a session replayed against the flow that produced it has no issues
+	issues, err := inspect.ReplaySession(sa, sessionJSON)
+	require.NoError(t, err)
+	assert.Len(t, issues, 0)
+
+	// but if the flow has since been edited...
+	edited := strings.NewReplacer(
+		`"name": "Yes"`, `"name": "Agree"`, // category renamed
+		`"destination_uuid": "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b"`, `"destination_uuid": null`, // exit disconnected
+	).Replace(replayAssetsJSON)
+
+	editedSA, _, _ := test.NewSessionBuilder().WithAssetsJSON([]byte(edited)).WithFlow("8ca44c09-791d-453a-9799-a70dd3303306").MustBuild()
+
+	issues, err = inspect.ReplaySession(editedSA, sessionJSON)
+	require.NoError(t, err)
+	assert.Equal(t, []*inspect.ReplayIssue{
+		{
+			Type:        inspect.ReplayIssueBrokenPath,
+			RunUUID:     runUUID,
+			StepUUID:    path[1].UUID(),
+			NodeUUID:    "b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e",
+			Description: "exit 1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d of node b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e doesn't lead to node e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b",
+		},
+		{
+			Type:        inspect.ReplayIssueUnknownCategory,
+			RunUUID:     runUUID,
+			StepUUID:    path[1].UUID(),
+			NodeUUID:    "b3c2d6f1-4a1a-4c8e-9d2e-1f0a9b8c7d6e",
+			Description: "category 'Yes' isn't a category of result 'Likes Cheese'",
+		},
+	}, issues)
+
+	// or a node has been removed
+	flow, err := sa.Flows().Get("8ca44c09-791d-453a-9799-a70dd3303306")
+	require.NoError(t, err)
+
+	runJSON := strings.Replace(string(jsonx.MustMarshal(session.Runs()[0])), "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b", "a0a6c0ad-5bb8-4d8b-8a39-7c8a7bd62dd5", -1)
+
+	issues, err = inspect.ReplayRun(flow, []byte(runJSON))
+	require.NoError(t, err)
+	assert.Len(t, issues, 2)
+	assert.Equal(t, inspect.ReplayIssueBrokenPath, issues[0].Type)
+	assert.Equal(t, inspect.ReplayIssueMissingNode, issues[1].Type)
+	assert.Equal(t, "node a0a6c0ad-5bb8-4d8b-8a39-7c8a7bd62dd5 doesn't exist in the flow", issues[1].Description)
+
+	// events which reference steps that aren't in the path are reported
+	issues, err = inspect.ReplayRun(flow, []byte(`{
+		"uuid": "9f7ede93-4b16-4692-80ad-b7dc54a1cd81",
+		"flow": {"uuid": "8ca44c09-791d-453a-9799-a70dd3303306", "name": "Cheese"},
+		"path": [{"uuid": "5802813d-6c58-4292-8228-9728778b6c98", "node_uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507"}],
+		"events": [
+			{"type": "msg_created", "step_uuid": "5802813d-6c58-4292-8228-9728778b6c98"},
+			{"type": "run_result_changed", "step_uuid": "5802813d-6c58-4292-8228-9728778b6c98", "name": "Likes Cheese", "category": "Yes"},
+			{"type": "msg_created", "step_uuid": "b0c7e5f2-7d5b-4a3a-9b5c-0e6f0a8b9c1d"}
+		]
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, []*inspect.ReplayIssue{
+		{
+			Type:        inspect.ReplayIssueUnknownResult,
+			RunUUID:     "9f7ede93-4b16-4692-80ad-b7dc54a1cd81",
+			StepUUID:    "5802813d-6c58-4292-8228-9728778b6c98",
+			NodeUUID:    "a58be63b-907d-4a1a-856b-0bb5579d7507",
+			Description: "result 'Likes Cheese' isn't saved by node a58be63b-907d-4a1a-856b-0bb5579d7507",
+		},
+		{
+			Type:        inspect.ReplayIssueUnknownStep,
+			RunUUID:     "9f7ede93-4b16-4692-80ad-b7dc54a1cd81",
+			Description: "msg_created event references step b0c7e5f2-7d5b-4a3a-9b5c-0e6f0a8b9c1d which isn't in the path",
+		},
+	}, issues)
+
+	// flows which no longer exist are reported
+	emptySA, _, _ := test.NewSessionBuilder().MustBuild()
+	issues, err = inspect.ReplaySession(emptySA, sessionJSON)
+	require.NoError(t, err)
+	assert.Equal(t, inspect.ReplayIssueMissingFlow, issues[0].Type)
+
+	// and invalid runs are errors
+	_, err = inspect.ReplayRun(flow, []byte(`{"uuid": "9f7ede93-4b16-4692-80ad-b7dc54a1cd81"}`))
+	assert.EqualError(t, err, "unable to read run: field 'flow' is required")
+}
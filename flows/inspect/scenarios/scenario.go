@@ -0,0 +1,189 @@
+// Package scenarios generates skeleton test scenarios for flows, with a case for every category of every router, which
+// can be filled in to give tests covering every path through a flow.
+package scenarios
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/routers"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+// Case is a category of a router which a test should reach and the input which should reach it. Inputs which couldn't
+// be worked out from the router are placeholders, written in angle brackets, which need to be replaced.
+type Case struct {
+	NodeUUID     flows.NodeUUID     `json:"node_uuid" validate:"required"`
+	ResultName   string             `json:"result_name,omitempty"`
+	Operand      string             `json:"operand,omitempty"`
+	CategoryUUID flows.CategoryUUID `json:"category_uuid" validate:"required"`
+	Category     string             `json:"category"`
+	Input        string             `json:"input"`
+	Placeholder  bool               `json:"placeholder,omitempty"`
+}
+
+// Scenario is a set of cases for a flow
+//
+//	{
+//	  "flow": {"uuid": "50c3706e-fedb-42c0-8eab-dda3335714b7", "name": "Registration"},
+//	  "cases": [
+//	    {
+//	      "node_uuid": "46d51f50-58de-49da-8d13-dadbf322685d",
+//	      "result_name": "Favorite Color",
+//	      "operand": "@input.text",
+//	      "category_uuid": "598ae7a5-2f81-48f1-afac-595262514aa1",
+//	      "category": "Red",
+//	      "input": "red"
+//	    },
+//	    {
+//	      "node_uuid": "46d51f50-58de-49da-8d13-dadbf322685d",
+//	      "result_name": "Favorite Color",
+//	      "operand": "@input.text",
+//	      "category_uuid": "78ae8f05-f92e-43b2-a886-406eaea1b8e0",
+//	      "category": "Other",
+//	      "input": "<anything else>",
+//	      "placeholder": true
+//	    }
+//	  ]
+//	}
+type Scenario struct {
+	Flow  *assets.FlowReference `json:"flow" validate:"required"`
+	Cases []*Case               `json:"cases" validate:"dive"`
+}
+
+// ReadScenario reads a scenario from the given JSON
+func ReadScenario(data json.RawMessage) (*Scenario, error) {
+	s := &Scenario{}
+	if err := utils.UnmarshalAndValidate(data, s); err != nil {
+		return nil, errors.Wrap(err, "unable to read scenario")
+	}
+	return s, nil
+}
+
+// Generate generates a new scenario for the given flow, with a case for each category of each of its routers
+func Generate(flow flows.Flow) *Scenario {
+	s := &Scenario{Flow: flow.Reference(false), Cases: make([]*Case, 0)}
+
+	for _, node := range flow.Nodes() {
+		if node.Router() != nil {
+			s.Cases = append(s.Cases, routerCases(node.UUID(), node.Router())...)
+		}
+	}
+
+	return s
+}
+
+// Update returns a copy of this scenario updated for a new version of its flow. Cases of categories which still exist
+// keep their inputs, cases of categories which have been removed are dropped, and new categories get generated cases.
+func (s *Scenario) Update(flow flows.Flow) *Scenario {
+	existing := make(map[flows.CategoryUUID]*Case, len(s.Cases))
+	for _, c := range s.Cases {
+		existing[c.CategoryUUID] = c
+	}
+
+	updated := Generate(flow)
+	for _, c := range updated.Cases {
+		if e := existing[c.CategoryUUID]; e != nil {
+			c.Input = e.Input
+			c.Placeholder = e.Placeholder
+		}
+	}
+
+	return updated
+}
+
+// Placeholders returns the cases whose inputs are still placeholders
+func (s *Scenario) Placeholders() []*Case {
+	placeholders := make([]*Case, 0)
+	for _, c := range s.Cases {
+		if c.Placeholder {
+			placeholders = append(placeholders, c)
+		}
+	}
+	return placeholders
+}
+
+// generates a case for each category of the given router
+func routerCases(nodeUUID flows.NodeUUID, router flows.Router) []*Case {
+	inputs := make(map[flows.CategoryUUID]string)
+	var operand string
+
+	if router.AllowTimeout() {
+		inputs[router.Wait().Timeout().CategoryUUID()] = "<timeout>"
+	}
+
+	if switchRouter, isSwitch := router.(*routers.SwitchRouter); isSwitch {
+		operand = switchRouter.Operand()
+
+		inputs[switchRouter.DefaultCategoryUUID()] = "<anything else>"
+
+		// cases are tested in order so the first case of each category is the one which needs to be matched
+		for i := len(switchRouter.Cases()) - 1; i >= 0; i-- {
+			c := switchRouter.Cases()[i]
+			inputs[c.CategoryUUID] = caseInput(c)
+		}
+	}
+
+	cases := make([]*Case, len(router.Categories()))
+	for i, category := range router.Categories() {
+		input, found := inputs[category.UUID()]
+		if !found {
+			input = fmt.Sprintf("<%s>", strings.ToLower(category.Name()))
+		}
+
+		cases[i] = &Case{
+			NodeUUID:     nodeUUID,
+			ResultName:   router.ResultName(),
+			Operand:      operand,
+			CategoryUUID: category.UUID(),
+			Category:     category.Name(),
+			Input:        input,
+			Placeholder:  strings.HasPrefix(input, "<"),
+		}
+	}
+	return cases
+}
+
+// tests whose first argument is itself an input which passes them
+var literalArgTests = map[string]bool{
+	"has_all_words":      true,
+	"has_any_word":       true,
+	"has_beginning":      true,
+	"has_number_between": true,
+	"has_number_eq":      true,
+	"has_number_gte":     true,
+	"has_number_lte":     true,
+	"has_only_phrase":    true,
+	"has_only_text":      true,
+	"has_phrase":         true,
+}
+
+// works out an input which will match the given case, or a placeholder if that isn't possible
+func caseInput(c *routers.Case) string {
+	if len(c.Arguments) > 0 && literalArgTests[c.Type] && !strings.Contains(c.Arguments[0], "@") {
+		if c.Type == "has_any_word" {
+			if words := strings.Fields(c.Arguments[0]); len(words) > 0 {
+				return words[0]
+			}
+		} else if strings.TrimSpace(c.Arguments[0]) != "" {
+			return c.Arguments[0]
+		}
+	}
+
+	switch c.Type {
+	case "has_text":
+		return "<any text>"
+	case "has_number":
+		return "1"
+	}
+
+	if len(c.Arguments) > 0 {
+		return fmt.Sprintf("<%s %s>", c.Type, strings.Join(c.Arguments, " "))
+	}
+	return fmt.Sprintf("<%s>", c.Type)
+}
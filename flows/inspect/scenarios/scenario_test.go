@@ -0,0 +1,68 @@
+package scenarios_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows/definition"
+	"github.com/nyaruka/goflow/flows/inspect/scenarios"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScenarios(t *testing.T) {
+	sa, err := test.LoadSessionAssets(envs.NewBuilder().Build(), "../../../test/testdata/runner/two_questions.json")
+	require.NoError(t, err)
+
+	flow, err := sa.Flows().Get("615b8a0f-588c-4d20-a05f-363b0b4ce6f4")
+	require.NoError(t, err)
+
+	scenario := scenarios.Generate(flow)
+	assert.Equal(t, "Two Questions", scenario.Flow.Name)
+
+	test.AssertEqualJSON(t, []byte(`[
+		{"node_uuid": "46d51f50-58de-49da-8d13-dadbf322685d", "result_name": "Favorite Color", "operand": "@input.text", "category_uuid": "598ae7a5-2f81-48f1-afac-595262514aa1", "category": "Red", "input": "red"},
+		{"node_uuid": "46d51f50-58de-49da-8d13-dadbf322685d", "result_name": "Favorite Color", "operand": "@input.text", "category_uuid": "c70fe86c-9aac-4cc2-a5cb-d35cbe3fed6e", "category": "Blue", "input": "blue"},
+		{"node_uuid": "46d51f50-58de-49da-8d13-dadbf322685d", "result_name": "Favorite Color", "operand": "@input.text", "category_uuid": "78ae8f05-f92e-43b2-a886-406eaea1b8e0", "category": "Other", "input": "<anything else>", "placeholder": true},
+		{"node_uuid": "46d51f50-58de-49da-8d13-dadbf322685d", "result_name": "Favorite Color", "operand": "@input.text", "category_uuid": "1024833c-91aa-4873-a3b5-3bac1ef55812", "category": "No Response", "input": "<timeout>", "placeholder": true},
+		{"node_uuid": "11a772f3-3ca2-4429-8b33-20fdcfc2b69e", "result_name": "Soda", "operand": "@input.text", "category_uuid": "2ab9b033-77a8-4e56-a558-b568c00c9492", "category": "Pepsi", "input": "pepsi"},
+		{"node_uuid": "11a772f3-3ca2-4429-8b33-20fdcfc2b69e", "result_name": "Soda", "operand": "@input.text", "category_uuid": "c7bca181-0cb3-4ec6-8555-f7e5644238ad", "category": "Coke", "input": "coke"},
+		{"node_uuid": "11a772f3-3ca2-4429-8b33-20fdcfc2b69e", "result_name": "Soda", "operand": "@input.text", "category_uuid": "5ce6c69a-fdfe-4594-ab71-26be534d31c3", "category": "Other", "input": "<anything else>", "placeholder": true}
+	]`), jsonx.MustMarshal(scenario.Cases), "cases mismatch")
+
+	assert.Equal(t, 3, len(scenario.Placeholders()))
+
+	// fill in the placeholders and write it out
+	scenario.Cases[2].Input, scenario.Cases[2].Placeholder = "green", false
+	scenario.Cases[6].Input, scenario.Cases[6].Placeholder = "fanta", false
+
+	scenarioJSON := jsonx.MustMarshal(scenario)
+
+	read, err := scenarios.ReadScenario(scenarioJSON)
+	require.NoError(t, err)
+	assert.Equal(t, scenario, read)
+
+	_, err = scenarios.ReadScenario([]byte(`{"cases": []}`))
+	assert.EqualError(t, err, "unable to read scenario: field 'flow' is required")
+
+	// change the flow so the first router loses its timeout and the second gets a new category
+	flowJSON := jsonx.MustMarshal(flow)
+	flowJSON = test.JSONDelete(flowJSON, []string{"nodes", "[0]", "router", "wait", "timeout"})
+	flowJSON = test.JSONDelete(flowJSON, []string{"nodes", "[0]", "router", "categories", "[3]"})
+	flowJSON = test.JSONReplace(flowJSON, []string{"nodes", "[1]", "router", "categories", "[2]", "name"}, []byte(`"Fanta"`))
+	flowJSON = test.JSONReplace(flowJSON, []string{"nodes", "[1]", "router", "categories", "[2]", "uuid"}, []byte(`"2f1c7e3b-5c3a-4b6e-9a8d-1f2e3d4c5b6a"`))
+	flowJSON = test.JSONReplace(flowJSON, []string{"nodes", "[1]", "router", "default_category_uuid"}, []byte(`"2f1c7e3b-5c3a-4b6e-9a8d-1f2e3d4c5b6a"`))
+
+	changed, err := definition.ReadFlow(flowJSON, nil)
+	require.NoError(t, err)
+
+	updated := read.Update(changed)
+	assert.Equal(t, 6, len(updated.Cases))
+	assert.Equal(t, "green", updated.Cases[2].Input)
+	assert.Equal(t, "Fanta", updated.Cases[5].Category)
+	assert.Equal(t, "<anything else>", updated.Cases[5].Input)
+	assert.True(t, updated.Cases[5].Placeholder)
+}
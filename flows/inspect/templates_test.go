@@ -78,13 +78,28 @@ func TestTemplatePaths(t *testing.T) {
 		"$.nodes[*].actions[@.type=\"add_contact_groups\"].groups[*].name_match",
 		"$.nodes[*].actions[@.type=\"add_contact_urn\"].path",
 		"$.nodes[*].actions[@.type=\"add_input_labels\"].labels[*].name_match",
+		"$.nodes[*].actions[@.type=\"add_to_cart\"].product",
+		"$.nodes[*].actions[@.type=\"add_to_cart\"].quantity",
 		"$.nodes[*].actions[@.type=\"call_classifier\"].input",
+		"$.nodes[*].actions[@.type=\"call_graphql\"].headers[*]",
+		"$.nodes[*].actions[@.type=\"call_graphql\"].url",
+		"$.nodes[*].actions[@.type=\"call_graphql\"].variables[*]",
+		"$.nodes[*].actions[@.type=\"call_soap\"].body",
+		"$.nodes[*].actions[@.type=\"call_soap\"].header",
+		"$.nodes[*].actions[@.type=\"call_soap\"].headers[*]",
+		"$.nodes[*].actions[@.type=\"call_soap\"].url",
 		"$.nodes[*].actions[@.type=\"call_webhook\"].body",
 		"$.nodes[*].actions[@.type=\"call_webhook\"].headers[*]",
 		"$.nodes[*].actions[@.type=\"call_webhook\"].url",
+		"$.nodes[*].actions[@.type=\"exit_with\"].returns[*]",
+		"$.nodes[*].actions[@.type=\"forward_call\"].phone",
+		"$.nodes[*].actions[@.type=\"join_conference\"].conference",
 		"$.nodes[*].actions[@.type=\"open_ticket\"].assignee.email_match",
 		"$.nodes[*].actions[@.type=\"open_ticket\"].body",
+		"$.nodes[*].actions[@.type=\"open_ticket\"].topic.name_match",
 		"$.nodes[*].actions[@.type=\"play_audio\"].audio_url",
+		"$.nodes[*].actions[@.type=\"query_collection\"].filter",
+		"$.nodes[*].actions[@.type=\"query_collection\"].offset",
 		"$.nodes[*].actions[@.type=\"remove_contact_groups\"].groups[*].name_match",
 		"$.nodes[*].actions[@.type=\"say_msg\"].text",
 		"$.nodes[*].actions[@.type=\"send_broadcast\"].attachments[*]",
@@ -94,20 +109,46 @@ func TestTemplatePaths(t *testing.T) {
 		"$.nodes[*].actions[@.type=\"send_broadcast\"].quick_replies[*]",
 		"$.nodes[*].actions[@.type=\"send_broadcast\"].text",
 		"$.nodes[*].actions[@.type=\"send_email\"].addresses[*]",
+		"$.nodes[*].actions[@.type=\"send_email\"].attachments[*]",
 		"$.nodes[*].actions[@.type=\"send_email\"].body",
+		"$.nodes[*].actions[@.type=\"send_email\"].html_body",
 		"$.nodes[*].actions[@.type=\"send_email\"].subject",
 		"$.nodes[*].actions[@.type=\"send_msg\"].attachments[*]",
+		"$.nodes[*].actions[@.type=\"send_msg\"].inline_keyboard[*].data",
+		"$.nodes[*].actions[@.type=\"send_msg\"].inline_keyboard[*].text",
 		"$.nodes[*].actions[@.type=\"send_msg\"].quick_replies[*]",
+		"$.nodes[*].actions[@.type=\"send_msg\"].rich_cards[*].description",
+		"$.nodes[*].actions[@.type=\"send_msg\"].rich_cards[*].media",
+		"$.nodes[*].actions[@.type=\"send_msg\"].rich_cards[*].suggestions[*].data",
+		"$.nodes[*].actions[@.type=\"send_msg\"].rich_cards[*].suggestions[*].phone",
+		"$.nodes[*].actions[@.type=\"send_msg\"].rich_cards[*].suggestions[*].text",
+		"$.nodes[*].actions[@.type=\"send_msg\"].rich_cards[*].suggestions[*].url",
+		"$.nodes[*].actions[@.type=\"send_msg\"].rich_cards[*].title",
+		"$.nodes[*].actions[@.type=\"send_msg\"].suggestions[*].data",
+		"$.nodes[*].actions[@.type=\"send_msg\"].suggestions[*].phone",
+		"$.nodes[*].actions[@.type=\"send_msg\"].suggestions[*].text",
+		"$.nodes[*].actions[@.type=\"send_msg\"].suggestions[*].url",
 		"$.nodes[*].actions[@.type=\"send_msg\"].templating.variables[*]",
 		"$.nodes[*].actions[@.type=\"send_msg\"].text",
+		"$.nodes[*].actions[@.type=\"send_msg_sequence\"].messages[*].attachments[*]",
+		"$.nodes[*].actions[@.type=\"send_msg_sequence\"].messages[*].quick_replies[*]",
+		"$.nodes[*].actions[@.type=\"send_msg_sequence\"].messages[*].text",
+		"$.nodes[*].actions[@.type=\"send_whatsapp_flow\"].body",
+		"$.nodes[*].actions[@.type=\"send_whatsapp_flow\"].button",
+		"$.nodes[*].actions[@.type=\"send_whatsapp_flow\"].data[*]",
+		"$.nodes[*].actions[@.type=\"send_whatsapp_template\"].components[*].params[*]",
 		"$.nodes[*].actions[@.type=\"set_contact_field\"].value",
+		"$.nodes[*].actions[@.type=\"set_contact_fields\"].values",
 		"$.nodes[*].actions[@.type=\"set_contact_language\"].language",
 		"$.nodes[*].actions[@.type=\"set_contact_name\"].name",
 		"$.nodes[*].actions[@.type=\"set_contact_timezone\"].timezone",
 		"$.nodes[*].actions[@.type=\"set_run_result\"].value",
+		"$.nodes[*].actions[@.type=\"set_timer\"].delay",
+		"$.nodes[*].actions[@.type=\"start_recording\"].consent_prompt",
 		"$.nodes[*].actions[@.type=\"start_session\"].contact_query",
 		"$.nodes[*].actions[@.type=\"start_session\"].groups[*].name_match",
 		"$.nodes[*].actions[@.type=\"start_session\"].legacy_vars[*]",
+		"$.nodes[*].actions[@.type=\"transfer_call\"].phone",
 	}, paths)
 }
 
@@ -0,0 +1,167 @@
+package inspect
+
+import (
+	"sort"
+	"time"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+
+	"github.com/pkg/errors"
+)
+
+// SessionState is the state of a session as of an event in its log, i.e. what the engine knew when that event was
+// created
+type SessionState struct {
+	Index   int            `json:"index"`
+	Event   flows.Event    `json:"event"`
+	Contact *flows.Contact `json:"contact,omitempty"`
+	Runs    []*RunState    `json:"runs"`
+}
+
+// RunState is the state of a run as of an event in its session's log
+type RunState struct {
+	UUID    flows.RunUUID         `json:"uuid"`
+	Flow    *assets.FlowReference `json:"flow"`
+	Path    []flows.Step          `json:"path"`
+	Results flows.Results         `json:"results"`
+}
+
+// SessionLog returns the events of all the runs of the given session in the order they were created. Events of runs
+// which have been archived are no longer part of the log.
+func SessionLog(session flows.Session) []flows.Event {
+	log := make([]flows.Event, 0)
+	for _, run := range session.Runs() {
+		log = append(log, run.Events()...)
+	}
+
+	sort.SliceStable(log, func(i, j int) bool { return log[i].CreatedOn().Before(log[j].CreatedOn()) })
+	return log
+}
+
+// SessionAt reconstructs the state of the given session as of the event at the given index in its log, without the
+// session having stored any intermediate snapshots. The contact is the contact of the session's trigger with the
+// changes of every contact event up to and including that event applied, so changes made to the contact outside of the
+// session aren't known. Results are rebuilt from result events and paths include only the steps which had been taken
+// when the event was created.
+func SessionAt(session flows.Session, index int) (*SessionState, error) {
+	log := SessionLog(session)
+	if index < 0 || index >= len(log) {
+		return nil, errors.Errorf("event index %d is out of range, session has %d events", index, len(log))
+	}
+
+	at := log[index].CreatedOn()
+	state := &SessionState{Index: index, Event: log[index], Runs: make([]*RunState, 0)}
+
+	if contact := session.Trigger().Contact(); contact != nil {
+		state.Contact = contact.Clone()
+
+		for _, e := range log[:index+1] {
+			if err := applyContactEvent(session.Assets(), state.Contact, e); err != nil {
+				return nil, errors.Wrapf(err, "unable to apply %s event", e.Type())
+			}
+		}
+	}
+
+	seen := make(map[flows.Event]bool, index+1)
+	for _, e := range log[:index+1] {
+		seen[e] = true
+	}
+
+	for _, run := range session.Runs() {
+		if run.CreatedOn().After(at) {
+			continue
+		}
+
+		rs := &RunState{UUID: run.UUID(), Flow: run.FlowReference(), Path: make([]flows.Step, 0), Results: flows.NewResults()}
+		nodesByStep := make(map[flows.StepUUID]flows.NodeUUID)
+
+		for _, step := range run.Path() {
+			if !step.ArrivedOn().After(at) {
+				rs.Path = append(rs.Path, step)
+				nodesByStep[step.UUID()] = step.NodeUUID()
+			}
+		}
+
+		for _, e := range run.Events() {
+			if changed, isResult := e.(*events.RunResultChangedEvent); isResult && seen[e] {
+				rs.Results.Save(resultFromEvent(changed, nodesByStep[e.StepUUID()]))
+			}
+		}
+
+		state.Runs = append(state.Runs, rs)
+	}
+
+	return state, nil
+}
+
+// rebuilds the result that was saved when the given event was created
+func resultFromEvent(e *events.RunResultChangedEvent, nodeUUID flows.NodeUUID) *flows.Result {
+	result := flows.NewResult(e.Name, e.Value, e.Category, e.CategoryLocalized, nodeUUID, e.Input, e.Extra, e.CreatedOn())
+	result.ValueType = e.ValueType
+	result.TypedValue = e.TypedValue
+	result.PII = e.PII
+	return result
+}
+
+// applies the change recorded by the given event to the given contact, if it's a contact event
+func applyContactEvent(sa flows.SessionAssets, contact *flows.Contact, e flows.Event) error {
+	switch typed := e.(type) {
+	case *events.ContactNameChangedEvent:
+		contact.SetName(typed.Name)
+	case *events.ContactLanguageChangedEvent:
+		contact.SetLanguage(envs.Language(typed.Language))
+	case *events.ContactStatusChangedEvent:
+		contact.SetStatus(typed.Status)
+	case *events.ContactTimezoneChangedEvent:
+		var tz *time.Location
+		if typed.Timezone != "" {
+			var err error
+			if tz, err = time.LoadLocation(typed.Timezone); err != nil {
+				return err
+			}
+		}
+		contact.SetTimezone(tz)
+	case *events.ContactFieldChangedEvent:
+		setFieldValue(sa, contact, typed.Field, typed.Value)
+	case *events.ContactFieldsChangedEvent:
+		for _, change := range typed.Changes {
+			setFieldValue(sa, contact, change.Field, change.Value)
+		}
+	case *events.ContactGroupsChangedEvent:
+		for _, ref := range typed.GroupsAdded {
+			if group := sa.Groups().Get(ref.UUID); group != nil {
+				contact.Groups().Add(group)
+			}
+		}
+		for _, ref := range typed.GroupsRemoved {
+			if group := sa.Groups().Get(ref.UUID); group != nil {
+				contact.Groups().Remove(group)
+			}
+		}
+	case *events.ContactURNsChangedEvent:
+		contact.ClearURNs()
+		for _, urn := range typed.URNs {
+			contact.AddURN(urn, nil)
+		}
+	case *events.ContactRelationChangedEvent:
+		if relationType := sa.RelationTypes().Get(typed.RelationType.Key); relationType != nil {
+			contact.Relations().Set(relationType, typed.Contact)
+		}
+	case *events.ContactRefreshedEvent:
+		refreshed, err := flows.ReadContact(sa, typed.Contact, assets.IgnoreMissing)
+		if err != nil {
+			return err
+		}
+		*contact = *refreshed
+	}
+	return nil
+}
+
+func setFieldValue(sa flows.SessionAssets, contact *flows.Contact, ref *assets.FieldReference, value *flows.Value) {
+	if field := sa.Fields().Get(ref.Key); field != nil {
+		contact.Fields().Set(field, value)
+	}
+}
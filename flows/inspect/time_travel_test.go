@@ -0,0 +1,107 @@
+package inspect_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/inspect"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const timeTravelAssetsJSON = `{
+	"flows": [
+		{
+			"uuid": "8ca44c09-791d-453a-9799-a70dd3303306",
+			"name": "Registration",
+			"spec_version": "13.2.0",
+			"language": "eng",
+			"type": "messaging",
+			"nodes": [
+				{
+					"uuid": "a58be63b-907d-4a1a-856b-0bb5579d7507",
+					"actions": [
+						{"uuid": "ad154980-7bf7-4ab8-8728-545fd6378912", "type": "set_run_result", "name": "Color", "value": "red"},
+						{"uuid": "2d10ea6e-9b3e-4d4c-8d1e-4b9bd6e3c0a1", "type": "send_msg", "text": "What's your name?"}
+					],
+					"router": {
+						"type": "switch",
+						"wait": {"type": "msg"},
+						"operand": "@input.text",
+						"result_name": "Name",
+						"categories": [{"uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3", "name": "All Responses", "exit_uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d"}],
+						"default_category_uuid": "d1ce3c92-7025-4607-a910-444361a6b9b3"
+					},
+					"exits": [{"uuid": "0f7f9a5c-2f4e-4a3b-9c8d-7e6f5a4b3c2d", "destination_uuid": "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b"}]
+				},
+				{
+					"uuid": "e1f2a3b4-c5d6-4e7f-8a9b-0c1d2e3f4a5b",
+					"actions": [
+						{"uuid": "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f", "type": "set_contact_name", "name": "@results.name"},
+						{"uuid": "9688d21d-95aa-4bed-afc7-f31b35731a3d", "type": "set_run_result", "name": "Color", "value": "blue"}
+					],
+					"exits": [{"uuid": "3e9b6e76-2c3b-4b8b-a8a1-3a2c0d1e0e1b"}]
+				}
+			]
+		}
+	]
+}`
+
+func TestSessionAt(t *testing.T) {
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	dates.SetNowSource(dates.NewSequentialNowSource(time.Date(2018, 10, 18, 14, 20, 30, 0, time.UTC)))
+
+	_, session, _ := test.NewSessionBuilder().WithAssetsJSON([]byte(timeTravelAssetsJSON)).WithFlow("8ca44c09-791d-453a-9799-a70dd3303306").MustBuild()
+
+	msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), urns.URN("tel:+12065551212"), nil, "Robert", nil)
+	_, err := session.Resume(context.Background(), resumes.NewMsg(session.Environment(), nil, msg))
+	require.NoError(t, err)
+
+	log := inspect.SessionLog(session)
+	eventTypes := make([]string, len(log))
+	for i, e := range log {
+		eventTypes[i] = e.Type()
+	}
+	assert.Equal(t, []string{"run_result_changed", "msg_created", "msg_wait", "msg_received", "run_result_changed", "contact_name_changed", "run_result_changed"}, eventTypes)
+
+	// before the contact replied, we only know the first color result and the contact has their original name
+	state, err := inspect.SessionAt(session, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, state.Index)
+	assert.Equal(t, "msg_wait", state.Event.Type())
+	assert.Equal(t, "Bob", state.Contact.Name())
+	require.Len(t, state.Runs, 1)
+	assert.Len(t, state.Runs[0].Path, 1)
+	assert.Len(t, state.Runs[0].Results, 1)
+	assert.Equal(t, "red", state.Runs[0].Results.Get("color").Value)
+	assert.Equal(t, flows.NodeUUID("a58be63b-907d-4a1a-856b-0bb5579d7507"), state.Runs[0].Results.Get("color").NodeUUID)
+
+	// once the contact's name was changed
+	state, err = inspect.SessionAt(session, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "Robert", state.Contact.Name())
+	assert.Len(t, state.Runs[0].Path, 2)
+	assert.Equal(t, "Robert", state.Runs[0].Results.Get("name").Value)
+	assert.Equal(t, "red", state.Runs[0].Results.Get("color").Value)
+
+	// and at the end, the state matches the session
+	state, err = inspect.SessionAt(session, len(log)-1)
+	require.NoError(t, err)
+	assert.Equal(t, "blue", state.Runs[0].Results.Get("color").Value)
+	assert.Equal(t, session.Contact().Name(), state.Contact.Name())
+	assert.Equal(t, session.Runs()[0].Path(), state.Runs[0].Path)
+
+	// the session itself isn't changed
+	assert.Equal(t, "Robert", session.Contact().Name())
+
+	_, err = inspect.SessionAt(session, 7)
+	assert.EqualError(t, err, "event index 7 is out of range, session has 7 events")
+}
@@ -1,6 +1,7 @@
 package flows
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 
@@ -103,12 +104,15 @@ type SessionAssets interface {
 
 	Channels() *ChannelAssets
 	Classifiers() *ClassifierAssets
+	Costs() *CostAssets
 	Fields() *FieldAssets
 	Flows() FlowAssets
 	Globals() *GlobalAssets
 	Groups() *GroupAssets
 	Labels() *LabelAssets
 	Locations() *LocationAssets
+	LookupTables() *LookupTableAssets
+	RelationTypes() *RelationTypeAssets
 	Resthooks() *ResthookAssets
 	Templates() *TemplateAssets
 	Ticketers() *TicketerAssets
@@ -132,18 +136,29 @@ type Flow interface {
 	Language() envs.Language
 	Type() FlowType
 	ExpireAfterMinutes() int
+	ExpressionsVersion() envs.ExpressionsVersion
 	Localization() Localization
+	LanguageFallbacks(envs.Language) []envs.Language
+	Constants() map[string]string
+	ExitWebhook() *ExitWebhook
+	InputProcessing() *InputProcessing
 	UI() json.RawMessage
 	Nodes() []Node
 	GetNode(uuid NodeUUID) Node
 
 	Asset() assets.Flow
 	Reference(bool) *assets.FlowReference
+	CompiledTemplate(string) *excellent.Template
+	DeprecatedFunctions(uuids.UUID) []string
 
 	Inspect(sa SessionAssets) *Inspection
+	Analyze() []Issue
 	ExtractTemplates() []string
 	ExtractLocalizables() []string
+	LocalizationCoverage() map[envs.Language]*LocalizationCoverage
 	ChangeLanguage(envs.Language) (Flow, error)
+	ExtractDependencies() *FlowDependencies
+	RepairDependencies(SessionAssets, assets.MissingCallback) (Flow, error)
 }
 
 // Node is a single node in a flow
@@ -168,10 +183,26 @@ type Action interface {
 	FlowTypeRestricted
 
 	UUID() ActionUUID
-	Execute(Run, Step, ModifierCallback, EventCallback) error
+	Execute(context.Context, Run, Step, ModifierCallback, EventCallback) error
 	Validate() error
 }
 
+// ConcurrentAction is an action which only calls services and saves results on its run, and so can be executed
+// concurrently with other such actions in the branches of a forking router
+type ConcurrentAction interface {
+	Action
+
+	Concurrent() bool
+}
+
+// CallbackAction is an action which can hand a request off to the host and park its run until the host resumes the
+// session with the response
+type CallbackAction interface {
+	Action
+
+	ResumeCallback(Run, Step, *WebhookCallback, EventCallback)
+}
+
 // Category is how routers map results to exits
 type Category interface {
 	Localizable
@@ -191,8 +222,15 @@ type Router interface {
 
 	Validate(Flow, []Exit) error
 	AllowTimeout() bool
+	AllowExpiration() bool
+	AllowDuplicateInput() bool
+	AllowInvalidInput() bool
 	Route(Run, Step, EventCallback) (ExitUUID, string, error)
 	RouteTimeout(Run, Step, EventCallback) (ExitUUID, error)
+	RouteExpiration(Run, Step, EventCallback) (ExitUUID, error)
+	RouteDuplicateInput(Run, Step, EventCallback) (ExitUUID, error)
+	RouteInvalidInput(Run, Step, EventCallback) (ExitUUID, error)
+	RouteRace(Run, Step, CategoryUUID, EventCallback) (ExitUUID, error)
 
 	EnumerateTemplates(Localization, func(envs.Language, string))
 	EnumerateDependencies(Localization, func(envs.Language, assets.Reference))
@@ -200,6 +238,23 @@ type Router interface {
 	EnumerateLocalizables(func(uuids.UUID, string, []string, func([]string)))
 }
 
+// ForkingRouter is a router whose branch nodes are visited, and their actions executed concurrently, before it routes.
+// If its branches haven't all completed before the branch timeout, it routes as a timeout.
+type ForkingRouter interface {
+	Router
+
+	Branches() []NodeUUID
+	BranchTimeout() time.Duration
+}
+
+// LoopingRouter is a router which loops over the nodes which make up the body of its loop. Its loop ends if the run
+// visits a node outside of that body.
+type LoopingRouter interface {
+	Router
+
+	InLoop(Flow, Node, NodeUUID) bool
+}
+
 // Exit is a route out of a node and optionally to another node
 type Exit interface {
 	UUID() ExitUUID
@@ -223,6 +278,39 @@ type Wait interface {
 	Accepts(Resume) bool
 }
 
+// PagedWait is a wait which can split its prompt into pages, and show the next page in response to a resume, and keep
+// waiting rather than routing the resume. Paginate returns nil if the wait doesn't split prompts.
+type PagedWait interface {
+	Wait
+
+	Paginate(string) []string
+	ShowMore(Run, Resume, EventCallback) bool
+}
+
+// InputValidity is the outcome of a wait validating the input it was resumed with
+type InputValidity int
+
+// possible input validities
+const (
+	InputValid   InputValidity = iota // input is routed as normal
+	InputRetry                        // input is invalid and the contact has been prompted to try again
+	InputInvalid                      // input is invalid and the contact has no more attempts
+)
+
+// ValidatingWait is a wait which validates the input it's resumed with before that input is routed
+type ValidatingWait interface {
+	Wait
+
+	ValidateInput(Run, Resume, EventCallback) InputValidity
+}
+
+// RacingWait is a wait on several other waits at once, where the first to be resumed wins and the others are cancelled
+type RacingWait interface {
+	Wait
+
+	Finish(Run, Resume, EventCallback) CategoryUUID
+}
+
 // Hint tells the caller what type of input the flow is expecting
 type Hint interface {
 	utils.Typed
@@ -235,6 +323,13 @@ type Localization interface {
 	Languages() []envs.Language
 }
 
+// LocalizationCoverage is how much of the message text, quick replies and category names of a flow are translated into
+// a language, counted as individual strings
+type LocalizationCoverage struct {
+	Total   int            `json:"total"`
+	Missing map[string]int `json:"missing"` // keyed by property, i.e. text, quick_replies or name
+}
+
 // Trigger represents something which can initiate a session with the flow engine
 type Trigger interface {
 	utils.Typed
@@ -250,6 +345,8 @@ type Trigger interface {
 	Batch() bool
 	Params() *types.XObject
 	History() *SessionHistory
+	Profile() string
+	Thread() string
 	TriggeredOn() time.Time
 }
 
@@ -269,6 +366,7 @@ type Resume interface {
 
 	Environment() envs.Environment
 	Contact() *Contact
+	Thread() string
 	ResumedOn() time.Time
 }
 
@@ -276,7 +374,7 @@ type Resume interface {
 type Modifier interface {
 	utils.Typed
 
-	Apply(envs.Environment, Services, SessionAssets, *Contact, EventCallback) bool
+	Apply(context.Context, envs.Environment, Services, SessionAssets, *Contact, EventCallback) bool
 }
 
 // ModifierCallback is a callback invoked when a modifier has been generated
@@ -287,13 +385,43 @@ type Event interface {
 	utils.Typed
 
 	CreatedOn() time.Time
+	SetCreatedOn(time.Time)
 	StepUUID() StepUUID
 	SetStepUUID(StepUUID)
+	Thread() string
+	SetThread(string)
+	Timing() *EventTiming
+	SetTiming(*EventTiming)
 }
 
 // EventCallback is a callback invoked when an event has been generated
 type EventCallback func(Event)
 
+// EventSink receives the events of a sprint as they're generated rather than once the sprint is complete, e.g. so that
+// a server can stream the progress of a long running sprint to its client. Events are received synchronously so a sink
+// should return quickly, and the session shouldn't be modified or serialized until the sprint is complete.
+type EventSink interface {
+	Receive(ctx context.Context, session Session, event Event)
+}
+
+// ActionHook intercepts the execution of actions, e.g. so that a host can apply its own policies to the actions of
+// every flow. Hooks are called from the goroutine executing the action, which may not be the only one when actions are
+// executed concurrently.
+type ActionHook interface {
+	// Before is called before an action is executed and can return an error to block it
+	Before(ctx context.Context, run Run, step Step, action Action) error
+
+	// After is called after an action has been executed with the events that it generated
+	After(ctx context.Context, run Run, step Step, action Action, events []Event)
+}
+
+// ContactProvider provides read-only access to other contacts of the workspace, so that expressions can reference the
+// details of related contacts, e.g. a caregiver. Contacts returned by a provider are never modified by the engine.
+type ContactProvider interface {
+	// GetContacts gets the contacts with the given UUIDs, omitting any which don't exist
+	GetContacts(context.Context, SessionAssets, []ContactUUID) ([]*Contact, error)
+}
+
 // Input describes input from the contact and currently we only support one type of input: `msg`
 type Input interface {
 	utils.Typed
@@ -316,15 +444,69 @@ type Step interface {
 	Leave(ExitUUID)
 }
 
+// AssetsCacheStats are the metrics of an engine's cache of session assets
+type AssetsCacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Size      int   `json:"size"`
+}
+
+// Simulation is the configuration of an engine which runs sessions deterministically, e.g. for flow tests and editor
+// simulators. Each sprint of a simulated session generates its random numbers and UUIDs from the seed and has a fixed
+// now, without affecting any other sessions.
+type Simulation struct {
+	Seed int64
+	Now  time.Time
+}
+
+// DuplicateInputPolicy is what an engine does when a session is resumed with a message which duplicates the last
+// message received in the session, e.g. because a channel retried sending it
+type DuplicateInputPolicy string
+
+// possible duplicate input policies
+const (
+	DuplicateInputAccept DuplicateInputPolicy = ""       // duplicates resume the session like any other message
+	DuplicateInputIgnore DuplicateInputPolicy = "ignore" // duplicates are ignored and the session keeps waiting
+	DuplicateInputRoute  DuplicateInputPolicy = "route"  // duplicates are routed to the duplicate input category of the router, or ignored if it doesn't have one
+)
+
 // Engine provides callers with session starting and resuming
 type Engine interface {
-	NewSession(SessionAssets, Trigger) (Session, Sprint, error)
+	NewSession(context.Context, SessionAssets, Trigger) (Session, Sprint, error)
 	ReadSession(SessionAssets, json.RawMessage, assets.MissingCallback) (Session, error)
+	SessionAssets(envs.Environment, assets.Source) (SessionAssets, error)
+	AssetsCacheStats() AssetsCacheStats
+	Capabilities() *Capabilities
+	Stop(context.Context) ([]SessionUUID, error)
 
 	Services() Services
+	ServiceTimeout(ServiceType) time.Duration
 	MaxStepsPerSprint() int
 	MaxResumesPerSession() int
 	MaxTemplateChars() int
+	StrictTemplates() bool
+	StagedContactChanges() bool
+	InlineGroupQueries() bool
+	SprintArenas() bool
+	TemplateCacheSize() int
+	ConcurrentActions() bool
+	Prefetch() bool
+	Profiling() bool
+	Debugging() bool
+	EventTimings() bool
+	SessionDiffs() bool
+	RunArchive() RunArchive
+	MaxEndedRuns() int
+	DuplicateInputPolicy() DuplicateInputPolicy
+	DuplicateInputWindow() time.Duration
+	Simulation() *Simulation
+	EventSink() EventSink
+	ContactProvider() ContactProvider
+	ActionHooks() []ActionHook
+	RedactionPolicy() envs.RedactionPolicy
+	ProfileName() string
+	Profile(string) Engine
 }
 
 // Segment is a movement on the flow graph from an exit to another node
@@ -340,8 +522,21 @@ type Segment interface {
 // Sprint is an interaction with the engine - i.e. a start or resume of a session
 type Sprint interface {
 	Modifiers() []Modifier
+	Staged() []Modifier
+	StagedEvents() []Event
 	Events() []Event
 	Segments() []Segment
+	Summary() *SprintSummary
+	Diff() *SessionDiff
+	DebugLog() *DebugLog
+}
+
+// SprintSummary summarizes the events of a sprint by severity
+type SprintSummary struct {
+	Events   int `json:"events"`
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+	Failures int `json:"failures"`
 }
 
 // Session represents the session of a flow run which may contain many runs
@@ -361,20 +556,36 @@ type Session interface {
 	Input() Input
 	SetInput(Input)
 
+	Cart() *Order
+	SetCart(*Order)
+
+	Classification() *Classification
+	SetClassification(*Classification)
+	RelatedContact(ContactUUID) *Contact
+
+	SprintContext() context.Context
+	LogSprintEvent(Run, Event)
+
 	Status() SessionStatus
 	Trigger() Trigger
 	CurrentResume() Resume
 	BatchStart() bool
 	PushFlow(Flow, Run, bool)
 
-	Resume(Resume) (Sprint, error)
+	Resume(context.Context, Resume) (Sprint, error)
 	Runs() []Run
+	ArchivedRuns() []*ArchivedRun
 	GetRun(RunUUID) (Run, error)
 	FindStep(uuid StepUUID) (Run, Step)
 	GetCurrentChild(Run) Run
 	ParentRun() RunSummary
 	CurrentContext() *types.XObject
 	History() *SessionHistory
+	Arena() *excellent.Arena
+	TemplateCache() *TemplateCache
+	Profiler() *Profiler
+	DebugLog() *DebugLog
+	Sources() Sources
 
 	Engine() Engine
 }
@@ -387,6 +598,7 @@ type RunSummary interface {
 	Flow() Flow
 	Status() RunStatus
 	Results() Results
+	Returns() map[string]json.RawMessage
 }
 
 // Run is a single contact's journey through a flow. It records the path they have taken,
@@ -400,8 +612,19 @@ type Run interface {
 	Session() Session
 	SaveResult(*Result)
 	SetStatus(RunStatus)
+	ExpiresOn() *time.Time
+	SetExpiresOn(*time.Time)
 	Webhook() types.XValue
 	SetWebhook(types.XValue)
+	EvaluateConstants(EventCallback)
+	SetReturns(map[string]json.RawMessage)
+	Timers() []*Timer
+	SetTimer(*Timer)
+	CancelTimer(string) *Timer
+	Loops() []*Loop
+	Loop(NodeUUID) *Loop
+	StartLoop(*Loop)
+	EndLoop(NodeUUID)
 
 	CreateStep(Node) Step
 	Path() []Step
@@ -415,6 +638,7 @@ type Run interface {
 	EvaluateTemplateValue(string) (types.XValue, error)
 	EvaluateTemplateText(string, excellent.Escaping, bool) (string, error)
 	EvaluateTemplate(string) (string, error)
+	EvaluationErrors() int
 	RootContext(envs.Environment) map[string]types.XValue
 
 	GetText(uuids.UUID, string, string) (string, envs.Language)
@@ -11,6 +11,14 @@ func init() {
 	utils.RegisterValidatorAlias("dial_status", "eq=answered|eq=no_answer|eq=busy|eq=failed", func(validator.FieldError) string {
 		return "is not a valid dial status"
 	})
+	utils.RegisterValidatorAlias("call_transfer_status", "eq=completed|eq=busy|eq=failed", func(validator.FieldError) string {
+		return "is not a valid call transfer status"
+	})
+
+	RegisterContextType("dial",
+		NewContextProperty("status", "text", "the status of the dial"),
+		NewContextProperty("duration", "number", "the duration of the call in seconds"),
+	)
 }
 
 // DialStatus is the type for different dial statuses
@@ -42,3 +50,25 @@ func (d *Dial) Context(env envs.Environment) map[string]types.XValue {
 		"duration": types.NewXNumberFromInt(d.Duration),
 	}
 }
+
+// CallTransferStatus is the type for different call transfer statuses
+type CallTransferStatus string
+
+// possible call transfer status values
+const (
+	CallTransferStatusCompleted CallTransferStatus = "completed"
+	CallTransferStatusBusy      CallTransferStatus = "busy"
+	CallTransferStatusFailed    CallTransferStatus = "failed"
+)
+
+// CallTransfer represents a call which was forwarded to another number or joined to a conference, and how long the
+// contact spent there in seconds
+type CallTransfer struct {
+	Status   CallTransferStatus `json:"status" validate:"required,call_transfer_status"`
+	Duration int                `json:"duration"`
+}
+
+// NewCallTransfer creates a new call transfer
+func NewCallTransfer(status CallTransferStatus, duration int) *CallTransfer {
+	return &CallTransfer{Status: status, Duration: duration}
+}
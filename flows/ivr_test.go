@@ -36,3 +36,19 @@ func TestDial(t *testing.T) {
 		"duration": types.NewXNumberFromInt(5),
 	}, d.Context(envs.NewBuilder().Build()))
 }
+
+func TestCallTransfer(t *testing.T) {
+	ct := flows.NewCallTransfer(flows.CallTransferStatusCompleted, 42)
+
+	marshalled, err := jsonx.Marshal(ct)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"status":"completed","duration":42}`, string(marshalled))
+
+	ct2 := &flows.CallTransfer{}
+	err = utils.UnmarshalAndValidate(marshalled, ct2)
+	assert.NoError(t, err)
+	assert.Equal(t, ct, ct2)
+
+	err = utils.UnmarshalAndValidate([]byte(`{"status":"no_answer","duration":0}`), ct2)
+	assert.EqualError(t, err, "field 'status' is not a valid call transfer status")
+}
@@ -0,0 +1,112 @@
+package flows
+
+import (
+	"strings"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+// LookupTable represents a table of keyed data which can be queried in expressions
+type LookupTable struct {
+	assets.LookupTable
+}
+
+// NewLookupTable returns a new lookup table object from the given lookup table asset
+func NewLookupTable(asset assets.LookupTable) *LookupTable {
+	return &LookupTable{LookupTable: asset}
+}
+
+// Asset returns the underlying asset
+func (t *LookupTable) Asset() assets.LookupTable { return t.LookupTable }
+
+// HasColumn returns whether this table has a column with the given name
+func (t *LookupTable) HasColumn(name string) bool {
+	return utils.StringSliceContains(t.Columns(), name, true)
+}
+
+// FindRow returns the first row whose value in the key column matches the given key (case-insensitive). If fuzzy is
+// true and there is no exact match, then the row whose value is closest to the key is returned, provided it's no more
+// than a third of the key's length away.
+func (t *LookupTable) FindRow(keyColumn, key string, fuzzy bool) map[string]string {
+	key = normalizeLookupKey(key)
+
+	for _, row := range t.Rows() {
+		if normalizeLookupKey(row[keyColumn]) == key {
+			return row
+		}
+	}
+
+	if !fuzzy || key == "" {
+		return nil
+	}
+
+	var closest map[string]string
+	closestDistance := len([]rune(key))/3 + 1
+
+	for _, row := range t.Rows() {
+		distance := utils.EditDistance(normalizeLookupKey(row[keyColumn]), key)
+		if distance < closestDistance {
+			closest, closestDistance = row, distance
+		}
+	}
+	return closest
+}
+
+func normalizeLookupKey(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// LookupTableAssets provides access to all lookup table assets
+type LookupTableAssets struct {
+	all   []*LookupTable
+	byKey map[string]*LookupTable
+}
+
+// NewLookupTableAssets creates a new set of lookup table assets
+func NewLookupTableAssets(tables []assets.LookupTable) *LookupTableAssets {
+	s := &LookupTableAssets{
+		all:   make([]*LookupTable, len(tables)),
+		byKey: make(map[string]*LookupTable, len(tables)),
+	}
+	for i, asset := range tables {
+		table := NewLookupTable(asset)
+		s.all[i] = table
+		s.byKey[table.Key()] = table
+	}
+	return s
+}
+
+// All returns all the lookup tables
+func (s *LookupTableAssets) All() []*LookupTable {
+	return s.all
+}
+
+// Get returns the lookup table with the given key
+func (s *LookupTableAssets) Get(key string) *LookupTable {
+	return s.byKey[key]
+}
+
+// LookupValue looks up the value of the return column in the row of the given table matching the given key
+func (s *LookupTableAssets) LookupValue(table, keyColumn, key, returnColumn string, fuzzy bool) (string, bool, error) {
+	t := s.Get(table)
+	if t == nil {
+		return "", false, errors.Errorf("no such lookup table '%s'", table)
+	}
+	for _, column := range []string{keyColumn, returnColumn} {
+		if !t.HasColumn(column) {
+			return "", false, errors.Errorf("lookup table '%s' has no column '%s'", table, column)
+		}
+	}
+
+	row := t.FindRow(keyColumn, key, fuzzy)
+	if row == nil {
+		return "", false, nil
+	}
+	return row[returnColumn], true, nil
+}
+
+var _ envs.LookupResolver = (*LookupTableAssets)(nil)
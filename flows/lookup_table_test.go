@@ -0,0 +1,69 @@
+package flows_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupTables(t *testing.T) {
+	ta := static.NewLookupTable("clinics", "Clinics", []string{"district", "name", "phone"}, []map[string]string{
+		{"district": "Gasabo", "name": "Kacyiru Health Center", "phone": "+250788111111"},
+		{"district": "Nyarugenge", "name": "Muhima Hospital", "phone": "+250788222222"},
+		{"district": "Kicukiro", "name": "Gikondo Clinic"},
+	})
+
+	lt := flows.NewLookupTableAssets([]assets.LookupTable{ta})
+
+	clinics := lt.Get("clinics")
+	assert.Equal(t, "Clinics", clinics.Name())
+	assert.Equal(t, ta, clinics.Asset())
+	assert.True(t, clinics.HasColumn("phone"))
+	assert.False(t, clinics.HasColumn("Phone"))
+	assert.Nil(t, lt.Get("prices"))
+
+	tcs := []struct {
+		table        string
+		keyColumn    string
+		key          string
+		returnColumn string
+		fuzzy        bool
+		value        string
+		found        bool
+		err          string
+	}{
+		{"clinics", "district", "Nyarugenge", "name", false, "Muhima Hospital", true, ""},
+		{"clinics", "district", " nyarugenge ", "phone", false, "+250788222222", true, ""},
+		{"clinics", "district", "Nyarugenje", "name", false, "", false, ""},
+		{"clinics", "district", "Nyarugenje", "name", true, "Muhima Hospital", true, ""},
+		{"clinics", "district", "Gasbo", "name", true, "Kacyiru Health Center", true, ""},
+		{"clinics", "district", "Kigali", "name", true, "", false, ""},
+		{"clinics", "district", "", "name", true, "", false, ""},
+		{"clinics", "district", "Kicukiro", "phone", false, "", true, ""},
+		{"clinics", "province", "Kigali", "name", false, "", false, "lookup table 'clinics' has no column 'province'"},
+		{"clinics", "district", "Gasabo", "email", false, "", false, "lookup table 'clinics' has no column 'email'"},
+		{"prices", "item", "Maize", "price", false, "", false, "no such lookup table 'prices'"},
+	}
+
+	for _, tc := range tcs {
+		value, found, err := lt.LookupValue(tc.table, tc.keyColumn, tc.key, tc.returnColumn, tc.fuzzy)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err)
+		} else {
+			require.NoError(t, err)
+			assert.Equal(t, tc.value, value, "value mismatch looking up '%s'", tc.key)
+			assert.Equal(t, tc.found, found, "found mismatch looking up '%s'", tc.key)
+		}
+	}
+
+	// environments only get a lookup resolver if there are tables
+	env := envs.NewBuilder().Build()
+	assert.NotNil(t, flows.NewEnvironment(env, flows.NewLocationAssets(nil), lt).LookupResolver())
+	assert.Nil(t, flows.NewEnvironment(env, flows.NewLocationAssets(nil), flows.NewLookupTableAssets(nil)).LookupResolver())
+}
@@ -0,0 +1,31 @@
+package flows
+
+import (
+	"encoding/json"
+
+	"github.com/nyaruka/goflow/excellent/types"
+)
+
+// Loop is the state of a foreach loop in a run, i.e. the items being iterated over and the index of the current item.
+// Items are stored as JSON so that a loop which waits for input in its body can be continued in a later sprint.
+type Loop struct {
+	NodeUUID NodeUUID          `json:"node_uuid" validate:"required,uuid4"`
+	Items    []json.RawMessage `json:"items"`
+	Index    int               `json:"index"`
+}
+
+// NewLoop creates a new loop over the given items
+func NewLoop(nodeUUID NodeUUID, items []json.RawMessage) *Loop {
+	return &Loop{NodeUUID: nodeUUID, Items: items}
+}
+
+// Done returns whether all the items of this loop have been iterated over
+func (l *Loop) Done() bool { return l.Index >= len(l.Items) }
+
+// Item returns the current item of this loop
+func (l *Loop) Item() types.XValue {
+	if l.Done() {
+		return nil
+	}
+	return types.JSONToXValue(l.Items[l.Index])
+}
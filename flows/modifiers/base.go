@@ -1,6 +1,7 @@
 package modifiers
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/nyaruka/goflow/assets"
@@ -39,17 +40,28 @@ func newBaseModifier(typeName string) baseModifier {
 func (m *baseModifier) Type() string { return m.Type_ }
 
 // Apply applies the given modifier to the given contact and re-evaluates query based groups if necessary
-func Apply(env envs.Environment, svcs flows.Services, sa flows.SessionAssets, c *flows.Contact, mod flows.Modifier, logEvent flows.EventCallback) bool {
-	modified := mod.Apply(env, svcs, sa, c, logEvent)
+func Apply(ctx context.Context, env envs.Environment, svcs flows.Services, sa flows.SessionAssets, c *flows.Contact, mod flows.Modifier, logEvent flows.EventCallback) bool {
+	modified := mod.Apply(ctx, env, svcs, sa, c, logEvent)
 	if modified {
-		ReevaluateGroups(env, c, logEvent)
+		ReevaluateGroups(env, svcs, sa, c, logEvent)
 	}
 	return modified
 }
 
-// ReevaluateGroups is a helper to re-evaluate groups and log any changes to membership
-func ReevaluateGroups(env envs.Environment, contact *flows.Contact, log flows.EventCallback) {
-	added, removed := contact.ReevaluateQueryBasedGroups(env)
+// ReevaluateGroups is a helper to re-evaluate groups and log any changes to membership. Query based groups are only
+// re-evaluated if the services provide a group membership service.
+func ReevaluateGroups(env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) {
+	added, removed := make([]*flows.Group, 0), make([]*flows.Group, 0)
+
+	svc, err := svcs.GroupMembership(sa)
+	if err != nil {
+		log(events.NewError(err))
+	} else if svc != nil {
+		added, removed, err = contact.ReevaluateQueryBasedGroups(env, svc)
+		if err != nil {
+			log(events.NewError(err))
+		}
+	}
 
 	// make sure from all static groups are removed for non-active contacts
 	if contact.Status() != flows.ContactStatusActive {
@@ -1,6 +1,7 @@
 package modifiers_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -16,9 +17,11 @@ import (
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/events"
 	"github.com/nyaruka/goflow/flows/modifiers"
 	"github.com/nyaruka/goflow/test"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -73,7 +76,7 @@ func testModifierType(t *testing.T, svcs flows.Services, sa flows.SessionAssets,
 		// apply the modifier
 		env := envs.NewBuilder().WithMaxValueLength(256).Build()
 		eventLog := test.NewEventLog()
-		modifiers.Apply(env, svcs, sa, contact, modifier, eventLog.Log)
+		modifiers.Apply(context.Background(), env, svcs, sa, contact, modifier, eventLog.Log)
 
 		// clone test case and populate with actual values
 		actual := tc
@@ -159,6 +162,12 @@ func TestConstructors(t *testing.T) {
 				"modification": "add"
 			}`,
 		},
+		{
+			modifiers.NewDedupeURNs(),
+			`{
+				"type": "dedupe_urns"
+			}`,
+		},
 		{
 			modifiers.NewLanguage(envs.Language("fra")),
 			`{
@@ -308,3 +317,68 @@ func TestFieldValueTypes(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "39 years", mod.(*modifiers.FieldModifier).Value())
 }
+
+type testGroupMembership func(*flows.Group) (bool, error)
+
+func (m testGroupMembership) Qualifies(env envs.Environment, group *flows.Group, contact *flows.Contact) (bool, error) {
+	return m(group)
+}
+
+func TestGroupMembershipService(t *testing.T) {
+	env := envs.NewBuilder().Build()
+	sa, err := test.LoadSessionAssets(env, "testdata/_assets.json")
+	require.NoError(t, err)
+
+	applyName := func(eng flows.Engine) (*flows.Contact, []flows.Event) {
+		contact := flows.NewEmptyContact(sa, "", envs.NilLanguage, nil)
+		contact.Groups().Add(sa.Groups().Get("5389414a-66b8-408b-afec-07c5d68f6784")) // Nameless
+
+		eventLog := test.NewEventLog()
+		modifiers.Apply(context.Background(), env, eng.Services(), sa, contact, modifiers.NewName("Bob"), eventLog.Log)
+		return contact, eventLog.Events
+	}
+
+	// by default the engine evaluates group queries itself, so contact is no longer nameless
+	contact, evts := applyName(engine.NewBuilder().Build())
+	assert.Equal(t, 0, contact.Groups().Count())
+	assert.Equal(t, []string{"contact_name_changed", "contact_groups_changed"}, eventTypes(evts))
+	assert.Equal(t, "Nameless", evts[1].(*events.ContactGroupsChangedEvent).GroupsRemoved[0].Name)
+
+	// hosts can use their own query engine
+	eng := engine.NewBuilder().WithGroupMembershipServiceFactory(func(flows.SessionAssets) (flows.GroupMembershipService, error) {
+		return testGroupMembership(func(g *flows.Group) (bool, error) { return g.Name() == "Males", nil }), nil
+	}).Build()
+
+	contact, evts = applyName(eng)
+	assert.Equal(t, "Males", contact.Groups().All()[0].Name())
+	assert.Equal(t, []string{"contact_name_changed", "contact_groups_changed"}, eventTypes(evts))
+	assert.Equal(t, "Males", evts[1].(*events.ContactGroupsChangedEvent).GroupsAdded[0].Name)
+	assert.Equal(t, "Nameless", evts[1].(*events.ContactGroupsChangedEvent).GroupsRemoved[0].Name)
+
+	// errors from the service are logged and leave membership unchanged
+	eng = engine.NewBuilder().WithGroupMembershipServiceFactory(func(flows.SessionAssets) (flows.GroupMembershipService, error) {
+		return testGroupMembership(func(g *flows.Group) (bool, error) { return false, errors.New("boom") }), nil
+	}).Build()
+
+	contact, evts = applyName(eng)
+	assert.Equal(t, 1, contact.Groups().Count())
+	assert.Equal(t, []string{"contact_name_changed", "error"}, eventTypes(evts))
+	assert.Equal(t, "unable to evaluate membership of group 'Males': boom", evts[1].(*events.ErrorEvent).Text)
+
+	// or they can opt out of re-evaluation entirely
+	eng = engine.NewBuilder().WithGroupMembershipServiceFactory(func(flows.SessionAssets) (flows.GroupMembershipService, error) {
+		return nil, nil
+	}).Build()
+
+	contact, evts = applyName(eng)
+	assert.Equal(t, 1, contact.Groups().Count())
+	assert.Equal(t, []string{"contact_name_changed"}, eventTypes(evts))
+}
+
+func eventTypes(evts []flows.Event) []string {
+	types := make([]string, len(evts))
+	for i, e := range evts {
+		types[i] = e.Type()
+	}
+	return types
+}
@@ -1,6 +1,7 @@
 package modifiers
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/nyaruka/gocommon/jsonx"
@@ -34,7 +35,7 @@ func NewChannel(channel *flows.Channel) *ChannelModifier {
 }
 
 // Apply applies this modification to the given contact
-func (m *ChannelModifier) Apply(env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
+func (m *ChannelModifier) Apply(ctx context.Context, env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
 	if m.channel != nil && !m.channel.HasRole(assets.ChannelRoleSend) {
 		log(events.NewErrorf("can't set channel that can't send as the preferred channel"))
 
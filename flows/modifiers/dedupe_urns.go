@@ -0,0 +1,61 @@
+package modifiers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeDedupeURNs, readDedupeURNsModifier)
+}
+
+// TypeDedupeURNs is the type of our dedupe URNs modifier
+const TypeDedupeURNs string = "dedupe_urns"
+
+// DedupeURNsModifier normalizes the URNs on a contact and removes any which are the same as an earlier URN once
+// normalized, e.g. tel:0788123123 and tel:+250788123123 in Rwanda. The first of the duplicates is kept along with its
+// channel affinity.
+type DedupeURNsModifier struct {
+	baseModifier
+}
+
+// NewDedupeURNs creates a new dedupe URNs modifier
+func NewDedupeURNs() *DedupeURNsModifier {
+	return &DedupeURNsModifier{
+		baseModifier: newBaseModifier(TypeDedupeURNs),
+	}
+}
+
+// Apply applies this modification to the given contact
+func (m *DedupeURNsModifier) Apply(ctx context.Context, env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
+	original := contact.URNs()
+
+	contact.ClearURNs()
+
+	for _, u := range original {
+		contact.AddURN(flows.NormalizeURN(env, u.URN()), u.Channel())
+	}
+
+	if !contact.URNs().Equal(original) {
+		log(events.NewContactURNsChanged(contact.URNs().RawURNs()))
+		return true
+	}
+	return false
+}
+
+var _ flows.Modifier = (*DedupeURNsModifier)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+func readDedupeURNsModifier(assets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Modifier, error) {
+	m := &DedupeURNsModifier{}
+	return m, utils.UnmarshalAndValidate(data, m)
+}
@@ -1,7 +1,9 @@
 package modifiers
 
 import (
+	"context"
 	"encoding/json"
+	"unicode/utf8"
 
 	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/gocommon/stringsx"
@@ -38,19 +40,31 @@ func NewField(field *flows.Field, value string) *FieldModifier {
 }
 
 // Apply applies this modification to the given contact
-func (m *FieldModifier) Apply(env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
+func (m *FieldModifier) Apply(ctx context.Context, env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
 	oldValue := contact.Fields().Get(m.field)
 
 	newValue := contact.Fields().Parse(env, sa.Fields(), m.field, m.value)
 
 	// truncate text value if necessary
 	if newValue != nil {
-		newValue.Text = types.NewXText(stringsx.Truncate(newValue.Text.Native(), env.MaxValueLength()))
+		limit := env.TruncationPolicy().FieldValue
+		if length := utf8.RuneCountInString(newValue.Text.Native()); length > limit {
+			newValue.Text = types.NewXText(stringsx.Truncate(newValue.Text.Native(), limit))
+			log(events.NewValueTruncated(events.TruncationTargetField, m.field.Key(), length, limit))
+		}
+	}
+
+	// datetimes without a timezone are assumed to be in the environment's
+	if m.field.Type() == assets.FieldTypeDatetime && newValue != nil && newValue.Datetime != nil && !envs.DateTimeHasTimezone(m.value) {
+		log(events.NewWarningf("no timezone in value of field '%s' so assumed %s", m.field.Key(), env.Timezone()))
 	}
 
 	if !newValue.Equals(oldValue) {
+		event := events.NewContactFieldChanged(m.field, newValue, oldValue)
+
 		contact.Fields().Set(m.field, newValue)
-		log(events.NewContactFieldChanged(m.field, newValue))
+		contact.RecordFieldChange(m.field, event.CreatedOn())
+		log(event)
 		return true
 	}
 	return false
@@ -0,0 +1,141 @@
+package modifiers
+
+import (
+	"context"
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/stringsx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeFields, readFieldsModifier)
+}
+
+// TypeFields is the type of our fields modifier
+const TypeFields string = "fields"
+
+// FieldValue is a value to set on a field of the contact
+type FieldValue struct {
+	Field *flows.Field
+	Value string
+}
+
+// FieldsModifier modifies several field values on the contact together
+type FieldsModifier struct {
+	baseModifier
+
+	values []*FieldValue
+}
+
+// NewFields creates a new fields modifier
+func NewFields(values []*FieldValue) *FieldsModifier {
+	return &FieldsModifier{
+		baseModifier: newBaseModifier(TypeFields),
+		values:       values,
+	}
+}
+
+// Apply applies this modification to the given contact
+func (m *FieldsModifier) Apply(ctx context.Context, env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
+	changes := make([]*events.FieldChange, 0, len(m.values))
+	changed := make([]*flows.Field, 0, len(m.values))
+	newValues := make([]*flows.Value, 0, len(m.values))
+
+	for _, v := range m.values {
+		oldValue := contact.Fields().Get(v.Field)
+		newValue := contact.Fields().Parse(env, sa.Fields(), v.Field, v.Value)
+
+		// truncate text value if necessary
+		if newValue != nil {
+			limit := env.TruncationPolicy().FieldValue
+			if length := utf8.RuneCountInString(newValue.Text.Native()); length > limit {
+				newValue.Text = types.NewXText(stringsx.Truncate(newValue.Text.Native(), limit))
+				log(events.NewValueTruncated(events.TruncationTargetField, v.Field.Key(), length, limit))
+			}
+		}
+
+		if !newValue.Equals(oldValue) {
+			changes = append(changes, &events.FieldChange{Field: v.Field.Reference(), Value: newValue, PreviousValue: oldValue})
+			changed = append(changed, v.Field)
+			newValues = append(newValues, newValue)
+		}
+	}
+
+	if len(changes) == 0 {
+		return false
+	}
+
+	event := events.NewContactFieldsChanged(changes)
+
+	for i, field := range changed {
+		contact.Fields().Set(field, newValues[i])
+		contact.RecordFieldChange(field, event.CreatedOn())
+	}
+
+	log(event)
+	return true
+}
+
+// Values returns the field values of this modifier
+func (m *FieldsModifier) Values() []*FieldValue {
+	return m.values
+}
+
+var _ flows.Modifier = (*FieldsModifier)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type fieldValueEnvelope struct {
+	Field *assets.FieldReference `json:"field" validate:"required"`
+	Value string                 `json:"value"`
+}
+
+type fieldsModifierEnvelope struct {
+	utils.TypedEnvelope
+	Values []*fieldValueEnvelope `json:"values" validate:"required,min=1,dive"`
+}
+
+func readFieldsModifier(assets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Modifier, error) {
+	e := &fieldsModifierEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	values := make([]*FieldValue, 0, len(e.Values))
+	for _, v := range e.Values {
+		field := assets.Fields().Get(v.Field.Key)
+		if field == nil {
+			missing(v.Field, nil)
+			continue
+		}
+		values = append(values, &FieldValue{Field: field, Value: v.Value})
+	}
+
+	if len(values) == 0 {
+		return nil, ErrNoModifier // nothing left to modify without any fields
+	}
+
+	return NewFields(values), nil
+}
+
+func (m *FieldsModifier) MarshalJSON() ([]byte, error) {
+	values := make([]*fieldValueEnvelope, len(m.values))
+	for i, v := range m.values {
+		values[i] = &fieldValueEnvelope{Field: v.Field.Reference(), Value: v.Value}
+	}
+
+	return jsonx.Marshal(&fieldsModifierEnvelope{
+		TypedEnvelope: utils.TypedEnvelope{Type: m.Type()},
+		Values:        values,
+	})
+}
@@ -1,6 +1,7 @@
 package modifiers
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/nyaruka/gocommon/jsonx"
@@ -45,7 +46,7 @@ func NewGroups(groups []*flows.Group, modification GroupsModification) *GroupsMo
 }
 
 // Apply applies this modification to the given contact
-func (m *GroupsModifier) Apply(env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
+func (m *GroupsModifier) Apply(ctx context.Context, env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
 	if contact.Status() == flows.ContactStatusBlocked || contact.Status() == flows.ContactStatusStopped {
 		log(events.NewErrorf("can't add blocked or stopped contacts to groups"))
 		return false
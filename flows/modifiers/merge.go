@@ -0,0 +1,177 @@
+package modifiers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeMerge, readMergeModifier)
+}
+
+// TypeMerge is the type of our merge modifier
+const TypeMerge string = "merge"
+
+// FieldPrecedence decides which value is kept when both contacts in a merge have a value for a field
+type FieldPrecedence string
+
+// the supported field precedences
+const (
+	FieldPrecedenceContact FieldPrecedence = "contact" // keep the value of the contact being merged into
+	FieldPrecedenceOther   FieldPrecedence = "other"   // take the value of the contact being merged
+	FieldPrecedenceNewest  FieldPrecedence = "newest"  // take whichever value was changed most recently
+)
+
+// MergeModifier merges another contact, usually a duplicate, into the contact. URNs and static groups of the other
+// contact are added, its name and language are only used if the contact doesn't have them, and its field values are
+// used according to the field precedence, with the times those values were changed preserved.
+type MergeModifier struct {
+	baseModifier
+
+	other           *flows.Contact
+	fieldPrecedence FieldPrecedence
+}
+
+// NewMerge creates a new merge modifier
+func NewMerge(other *flows.Contact, fieldPrecedence FieldPrecedence) *MergeModifier {
+	return &MergeModifier{
+		baseModifier:    newBaseModifier(TypeMerge),
+		other:           other,
+		fieldPrecedence: fieldPrecedence,
+	}
+}
+
+// Apply applies this modification to the given contact
+func (m *MergeModifier) Apply(ctx context.Context, env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
+	// a contact can't be merged into itself
+	if m.other.UUID() == contact.UUID() {
+		return false
+	}
+
+	log(events.NewContactMerged(m.other.Reference()))
+
+	if contact.Name() == "" && m.other.Name() != "" {
+		contact.SetName(m.other.Name())
+		log(events.NewContactNameChanged(m.other.Name()))
+	}
+
+	if contact.Language() == envs.NilLanguage && m.other.Language() != envs.NilLanguage {
+		contact.SetLanguage(m.other.Language())
+		log(events.NewContactLanguageChanged(m.other.Language()))
+	}
+
+	urnsChanged := false
+	for _, u := range m.other.URNs() {
+		if contact.AddURN(u.URN(), u.Channel()) {
+			urnsChanged = true
+		}
+	}
+	if urnsChanged {
+		log(events.NewContactURNsChanged(contact.URNs().RawURNs()))
+	}
+
+	m.mergeFields(sa, contact, log)
+
+	added := make([]*flows.Group, 0)
+	for _, g := range m.other.Groups().All() {
+		if !g.UsesQuery() && contact.Groups().Add(g) {
+			added = append(added, g)
+		}
+	}
+	if len(added) > 0 {
+		log(events.NewContactGroupsChanged(added, nil))
+	}
+
+	return true
+}
+
+func (m *MergeModifier) mergeFields(sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) {
+	for _, field := range sa.Fields().All() {
+		otherValue := m.other.Fields().Get(field)
+		if otherValue == nil {
+			continue
+		}
+
+		oldValue := contact.Fields().Get(field)
+		if otherValue.Equals(oldValue) || (oldValue != nil && !m.preferOther(contact, field.Key())) {
+			continue
+		}
+
+		event := events.NewContactFieldChanged(field, otherValue, oldValue)
+
+		contact.Fields().Set(field, otherValue)
+
+		// preserve when the value was changed if we know it
+		changedOn, known := m.other.FieldChanges()[field.Key()]
+		if !known {
+			changedOn = event.CreatedOn()
+		}
+		contact.RecordFieldChange(field, changedOn)
+
+		log(event)
+	}
+}
+
+// whether the other contact's value for the given field should replace the contact's value
+func (m *MergeModifier) preferOther(contact *flows.Contact, key string) bool {
+	switch m.fieldPrecedence {
+	case FieldPrecedenceOther:
+		return true
+	case FieldPrecedenceNewest:
+		otherChangedOn, otherKnown := m.other.FieldChanges()[key]
+		changedOn, known := contact.FieldChanges()[key]
+		return otherKnown && (!known || otherChangedOn.After(changedOn))
+	}
+	return false
+}
+
+var _ flows.Modifier = (*MergeModifier)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type mergeModifierEnvelope struct {
+	utils.TypedEnvelope
+	Contact         json.RawMessage `json:"contact" validate:"required"`
+	FieldPrecedence FieldPrecedence `json:"field_precedence,omitempty" validate:"omitempty,eq=contact|eq=other|eq=newest"`
+}
+
+func readMergeModifier(assets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Modifier, error) {
+	e := &mergeModifierEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	other, err := flows.ReadContact(assets, e.Contact, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldPrecedence := e.FieldPrecedence
+	if fieldPrecedence == "" {
+		fieldPrecedence = FieldPrecedenceContact
+	}
+
+	return NewMerge(other, fieldPrecedence), nil
+}
+
+func (m *MergeModifier) MarshalJSON() ([]byte, error) {
+	contactJSON, err := jsonx.Marshal(m.other)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(&mergeModifierEnvelope{
+		TypedEnvelope:   utils.TypedEnvelope{Type: m.Type()},
+		Contact:         contactJSON,
+		FieldPrecedence: m.fieldPrecedence,
+	})
+}
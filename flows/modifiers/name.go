@@ -1,6 +1,7 @@
 package modifiers
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/nyaruka/gocommon/stringsx"
@@ -34,7 +35,7 @@ func NewName(name string) *NameModifier {
 }
 
 // Apply applies this modification to the given contact
-func (m *NameModifier) Apply(env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
+func (m *NameModifier) Apply(ctx context.Context, env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
 	if contact.Name() != m.Name {
 		// truncate value if necessary
 		name := stringsx.Truncate(m.Name, env.MaxValueLength())
@@ -0,0 +1,85 @@
+package modifiers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeRelation, readRelationModifier)
+}
+
+// TypeRelation is the type of our relation modifier
+const TypeRelation string = "relation"
+
+// RelationModifier modifies which contact is related to the contact by a relation type
+type RelationModifier struct {
+	baseModifier
+
+	relationType *flows.RelationType
+	contact      *flows.ContactReference
+}
+
+// NewRelation creates a new relation modifier
+func NewRelation(relationType *flows.RelationType, contact *flows.ContactReference) *RelationModifier {
+	return &RelationModifier{
+		baseModifier: newBaseModifier(TypeRelation),
+		relationType: relationType,
+		contact:      contact,
+	}
+}
+
+// Apply applies this modification to the given contact
+func (m *RelationModifier) Apply(ctx context.Context, env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
+	existing := contact.Relations().Get(m.relationType)
+
+	if (existing == nil && m.contact == nil) || (existing != nil && m.contact != nil && existing.UUID == m.contact.UUID) {
+		return false
+	}
+
+	contact.Relations().Set(m.relationType, m.contact)
+	log(events.NewContactRelationChanged(m.relationType, m.contact))
+	return true
+}
+
+var _ flows.Modifier = (*RelationModifier)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type relationModifierEnvelope struct {
+	utils.TypedEnvelope
+	RelationType *assets.RelationTypeReference `json:"relation_type" validate:"required"`
+	Contact      *flows.ContactReference       `json:"contact"`
+}
+
+func readRelationModifier(assets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Modifier, error) {
+	e := &relationModifierEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	relationType := assets.RelationTypes().Get(e.RelationType.Key)
+	if relationType == nil {
+		missing(e.RelationType, nil)
+		return nil, ErrNoModifier // nothing left to modify without the relation type
+	}
+
+	return NewRelation(relationType, e.Contact), nil
+}
+
+func (m *RelationModifier) MarshalJSON() ([]byte, error) {
+	return jsonx.Marshal(&relationModifierEnvelope{
+		TypedEnvelope: utils.TypedEnvelope{Type: m.Type()},
+		RelationType:  m.relationType.Reference(),
+		Contact:       m.contact,
+	})
+}
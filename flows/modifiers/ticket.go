@@ -1,6 +1,7 @@
 package modifiers
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/nyaruka/gocommon/jsonx"
@@ -40,8 +41,10 @@ func NewTicket(ticketer *flows.Ticketer, topic *flows.Topic, body string, assign
 }
 
 // Apply applies this modification to the given contact
-func (m *TicketModifier) Apply(env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
-	httpLogger := &flows.HTTPLogger{}
+func (m *TicketModifier) Apply(ctx context.Context, env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
+	// a sink for HTTP logs is optional
+	sink, _ := svcs.HTTPLogSink(sa)
+	httpLogger := flows.NewHTTPLogger(ctx, sink)
 
 	// try to get a ticket service for this ticketer
 	svc, err := svcs.Ticket(m.ticketer)
@@ -50,7 +53,7 @@ func (m *TicketModifier) Apply(env envs.Environment, svcs flows.Services, sa flo
 		return false
 	}
 
-	ticket, err := svc.Open(env, contact, m.topic, m.body, m.assignee, httpLogger.Log)
+	ticket, err := svc.Open(ctx, env, contact, m.topic, m.body, m.assignee, httpLogger.Log)
 	if err != nil {
 		log(events.NewError(err))
 	}
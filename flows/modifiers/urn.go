@@ -1,6 +1,7 @@
 package modifiers
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/nyaruka/gocommon/urns"
@@ -46,8 +47,8 @@ func NewURN(urn urns.URN, modification URNModification) *URNModifier {
 }
 
 // Apply applies this modification to the given contact
-func (m *URNModifier) Apply(env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
-	urn := m.URN.Normalize(string(env.DefaultCountry()))
+func (m *URNModifier) Apply(ctx context.Context, env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
+	urn := flows.NormalizeURN(env, m.URN)
 	modified := false
 
 	if m.Modification == URNAppend {
@@ -1,6 +1,7 @@
 package modifiers
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/nyaruka/gocommon/urns"
@@ -46,7 +47,7 @@ func NewURNs(urnz []urns.URN, modification URNsModification) *URNsModifier {
 }
 
 // Apply applies this modification to the given contact
-func (m *URNsModifier) Apply(env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
+func (m *URNsModifier) Apply(ctx context.Context, env envs.Environment, svcs flows.Services, sa flows.SessionAssets, contact *flows.Contact, log flows.EventCallback) bool {
 	modified := false
 
 	if m.Modification == URNsSet {
@@ -55,9 +56,9 @@ func (m *URNsModifier) Apply(env envs.Environment, svcs flows.Services, sa flows
 
 	for _, urn := range m.URNs {
 		// normalize the URN
-		urn := urn.Normalize(string(env.DefaultCountry()))
+		urn := flows.NormalizeURN(env, urn)
 
-		if err := urn.Validate(); err != nil {
+		if err := utils.ValidateURN(urn); err != nil {
 			log(events.NewErrorf("'%s' is not valid URN", urn))
 		} else {
 			if m.Modification == URNsAppend || m.Modification == URNsSet {
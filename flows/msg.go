@@ -8,7 +8,6 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/nyaruka/gocommon/urns"
-	"github.com/nyaruka/gocommon/uuids"
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/utils"
@@ -19,6 +18,9 @@ func init() {
 	utils.RegisterValidatorAlias("msg_topic", "eq=event|eq=account|eq=purchase|eq=agent", func(validator.FieldError) string {
 		return "is not a valid message topic"
 	})
+	utils.RegisterValidatorAlias("msg_priority", "eq=transactional|eq=bulk", func(validator.FieldError) string {
+		return "is not a valid message priority"
+	})
 }
 
 type UnsendableReason string
@@ -41,6 +43,17 @@ const (
 	MsgTopicAgent    MsgTopic = "agent"
 )
 
+// MsgPriority is the priority of an outgoing message, which callers can use to queue transactional messages such as
+// OTPs ahead of bulk messages
+type MsgPriority string
+
+// possible msg priority values
+const (
+	NilMsgPriority           MsgPriority = ""
+	MsgPriorityTransactional MsgPriority = "transactional"
+	MsgPriorityBulk          MsgPriority = "bulk"
+)
+
 // BaseMsg represents a incoming or outgoing message with the session contact
 type BaseMsg struct {
 	UUID_        MsgUUID                  `json:"uuid"`
@@ -55,7 +68,8 @@ type BaseMsg struct {
 type MsgIn struct {
 	BaseMsg
 
-	ExternalID_ string `json:"external_id,omitempty"`
+	ExternalID_   string `json:"external_id,omitempty"`
+	CallbackData_ string `json:"callback_data,omitempty"`
 }
 
 // MsgOut represents a outgoing message to the session contact
@@ -63,8 +77,12 @@ type MsgOut struct {
 	BaseMsg
 
 	QuickReplies_     []string         `json:"quick_replies,omitempty"`
+	InlineKeyboard_   []InlineButton   `json:"inline_keyboard,omitempty"`
+	RichCards_        []RichCard       `json:"rich_cards,omitempty"`
+	Suggestions_      []Suggestion     `json:"suggestions,omitempty"`
 	Templating_       *MsgTemplating   `json:"templating,omitempty"`
 	Topic_            MsgTopic         `json:"topic,omitempty"`
+	Priority_         MsgPriority      `json:"priority,omitempty"`
 	Locale_           envs.Locale      `json:"locale,omitempty"`
 	UnsendableReason_ UnsendableReason `json:"unsendable_reason,omitempty"`
 }
@@ -83,16 +101,17 @@ func NewMsgIn(uuid MsgUUID, urn urns.URN, channel *assets.ChannelReference, text
 }
 
 // NewMsgOut creates a new outgoing message
-func NewMsgOut(urn urns.URN, channel *assets.ChannelReference, text string, attachments []utils.Attachment, quickReplies []string, templating *MsgTemplating, topic MsgTopic, locale envs.Locale, reason UnsendableReason) *MsgOut {
+func NewMsgOut(uuid MsgUUID, urn urns.URN, channel *assets.ChannelReference, text string, attachments []utils.Attachment, quickReplies []string, inlineKeyboard []InlineButton, templating *MsgTemplating, topic MsgTopic, locale envs.Locale, reason UnsendableReason) *MsgOut {
 	return &MsgOut{
 		BaseMsg: BaseMsg{
-			UUID_:        MsgUUID(uuids.New()),
+			UUID_:        uuid,
 			URN_:         urn,
 			Channel_:     channel,
 			Text_:        text,
 			Attachments_: attachments,
 		},
 		QuickReplies_:     quickReplies,
+		InlineKeyboard_:   inlineKeyboard,
 		Templating_:       templating,
 		Topic_:            topic,
 		Locale_:           locale,
@@ -101,7 +120,7 @@ func NewMsgOut(urn urns.URN, channel *assets.ChannelReference, text string, atta
 }
 
 // NewIVRMsgOut creates a new outgoing message for IVR
-func NewIVRMsgOut(urn urns.URN, channel *assets.ChannelReference, text string, audioURL string, locale envs.Locale) *MsgOut {
+func NewIVRMsgOut(uuid MsgUUID, urn urns.URN, channel *assets.ChannelReference, text string, audioURL string, locale envs.Locale) *MsgOut {
 	var attachments []utils.Attachment
 	if audioURL != "" {
 		attachments = []utils.Attachment{utils.Attachment(fmt.Sprintf("audio:%s", audioURL))}
@@ -109,7 +128,7 @@ func NewIVRMsgOut(urn urns.URN, channel *assets.ChannelReference, text string, a
 
 	return &MsgOut{
 		BaseMsg: BaseMsg{
-			UUID_:        MsgUUID(uuids.New()),
+			UUID_:        uuid,
 			URN_:         urn,
 			Channel_:     channel,
 			Text_:        text,
@@ -143,30 +162,90 @@ func (m *BaseMsg) Channel() *assets.ChannelReference { return m.Channel_ }
 // Text returns the text of this message
 func (m *BaseMsg) Text() string { return m.Text_ }
 
+// SetText sets the text of this message
+func (m *BaseMsg) SetText(text string) { m.Text_ = text }
+
 // Attachments returns the attachments of this message
 func (m *BaseMsg) Attachments() []utils.Attachment { return m.Attachments_ }
 
+// SetAttachments sets the attachments of this message
+func (m *BaseMsg) SetAttachments(attachments []utils.Attachment) { m.Attachments_ = attachments }
+
 // ExternalID returns the optional external ID of this incoming message
 func (m *MsgIn) ExternalID() string { return m.ExternalID_ }
 
 // SetExternalID sets the external ID of this message
 func (m *MsgIn) SetExternalID(id string) { m.ExternalID_ = id }
 
+// CallbackData returns the data of the inline keyboard button pressed if this message is a callback query
+func (m *MsgIn) CallbackData() string { return m.CallbackData_ }
+
+// SetCallbackData sets the data of the inline keyboard button pressed
+func (m *MsgIn) SetCallbackData(data string) { m.CallbackData_ = data }
+
 // QuickReplies returns the quick replies of this outgoing message
 func (m *MsgOut) QuickReplies() []string { return m.QuickReplies_ }
 
+// SetQuickReplies sets the quick replies of this outgoing message
+func (m *MsgOut) SetQuickReplies(quickReplies []string) { m.QuickReplies_ = quickReplies }
+
+// InlineKeyboard returns the inline keyboard buttons of this outgoing message
+func (m *MsgOut) InlineKeyboard() []InlineButton { return m.InlineKeyboard_ }
+
+// RichCards returns the rich cards of this outgoing message, which are shown as a carousel if there are several
+func (m *MsgOut) RichCards() []RichCard { return m.RichCards_ }
+
+// Suggestions returns the suggested replies and actions of this outgoing message
+func (m *MsgOut) Suggestions() []Suggestion { return m.Suggestions_ }
+
+// SetRichContent sets the rich cards and suggestions of this outgoing message
+func (m *MsgOut) SetRichContent(cards []RichCard, suggestions []Suggestion) {
+	m.RichCards_ = cards
+	m.Suggestions_ = suggestions
+}
+
 // Templating returns the templating to use to send this message (if any)
 func (m *MsgOut) Templating() *MsgTemplating { return m.Templating_ }
 
 // Topic returns the topic to use to send this message (if any)
 func (m *MsgOut) Topic() MsgTopic { return m.Topic_ }
 
+// Priority returns the priority to use to send this message (if any)
+func (m *MsgOut) Priority() MsgPriority { return m.Priority_ }
+
+// SetPriority sets the priority to use to send this message
+func (m *MsgOut) SetPriority(priority MsgPriority) { m.Priority_ = priority }
+
 // Locale returns the locale of this message (if any)
 func (m *MsgOut) Locale() envs.Locale { return m.Locale_ }
 
 // UnsendableReason returns the reason this message can't be sent (if any)
 func (m *MsgOut) UnsendableReason() UnsendableReason { return m.UnsendableReason_ }
 
+// InlineButton is a button shown alongside an outgoing message which, rather than sending its text as a new message
+// when pressed, sends back its data as a callback query
+type InlineButton struct {
+	Text string `json:"text"`
+	Data string `json:"data"`
+}
+
+// CallbackQuery is the press of an inline button by the contact, which sends back the button's data
+type CallbackQuery struct {
+	UUID       MsgUUID                  `json:"uuid" validate:"required,uuid4"`
+	URN        urns.URN                 `json:"urn,omitempty" validate:"omitempty,urn"`
+	Channel    *assets.ChannelReference `json:"channel,omitempty"`
+	Data       string                   `json:"data" validate:"required"`
+	ExternalID string                   `json:"external_id,omitempty"`
+}
+
+// Msg returns the callback query as an incoming message with no text, whose callback data is the data of the query
+func (q *CallbackQuery) Msg() *MsgIn {
+	msg := NewMsgIn(q.UUID, q.URN, q.Channel, "", nil)
+	msg.SetExternalID(q.ExternalID)
+	msg.SetCallbackData(q.Data)
+	return msg
+}
+
 // MsgTemplating represents any substituted message template that should be applied when sending this message
 type MsgTemplating struct {
 	Template_  *assets.TemplateReference `json:"template"`
@@ -30,6 +30,7 @@ func TestMsgIn(t *testing.T) {
 	)
 	msg.SetID(123)
 	msg.SetExternalID("EX346436734")
+	msg.SetCallbackData("choice_yes")
 
 	// test marshaling our msg
 	marshaled, err := jsonx.Marshal(msg)
@@ -44,7 +45,8 @@ func TestMsgIn(t *testing.T) {
 		"text":"Hi there",
 		"attachments":["image/jpeg:https://example.com/test.jpg",
 		"audio/mp3:https://example.com/test.mp3"],
-		"external_id":"EX346436734"
+		"external_id":"EX346436734",
+		"callback_data":"choice_yes"
 	}`), marshaled, "JSON mismatch")
 
 	// test unmarshaling
@@ -58,6 +60,7 @@ func TestMsgIn(t *testing.T) {
 	assert.Equal(t, assets.ChannelUUID("61f38f46-a856-4f90-899e-905691784159"), msg.Channel().UUID)
 	assert.Equal(t, "My Android", msg.Channel().Name)
 	assert.Equal(t, "EX346436734", msg.ExternalID())
+	assert.Equal(t, "choice_yes", msg.CallbackData())
 }
 
 func TestMsgOut(t *testing.T) {
@@ -65,6 +68,7 @@ func TestMsgOut(t *testing.T) {
 	defer uuids.SetGenerator(uuids.DefaultGenerator)
 
 	msg := flows.NewMsgOut(
+		flows.MsgUUID(uuids.New()),
 		urns.URN("tel:+1234567890"),
 		assets.NewChannelReference(assets.ChannelUUID("61f38f46-a856-4f90-899e-905691784159"), "My Android"),
 		"Hi there",
@@ -73,6 +77,7 @@ func TestMsgOut(t *testing.T) {
 			utils.Attachment("audio/mp3:https://example.com/test.mp3"),
 		},
 		nil,
+		[]flows.InlineButton{{Text: "Yes", Data: "choice_yes"}, {Text: "No", Data: "choice_no"}},
 		nil,
 		flows.MsgTopicAgent,
 		"eng-US",
@@ -89,9 +94,12 @@ func TestMsgOut(t *testing.T) {
 		"channel": {"uuid":"61f38f46-a856-4f90-899e-905691784159", "name":"My Android"},
 		"text": "Hi there",
 		"attachments": ["image/jpeg:https://example.com/test.jpg", "audio/mp3:https://example.com/test.mp3"],
+		"inline_keyboard": [{"text": "Yes", "data": "choice_yes"}, {"text": "No", "data": "choice_no"}],
 		"topic": "agent",
 		"locale": "eng-US"
 	}`), marshaled, "JSON mismatch")
+
+	assert.Equal(t, []flows.InlineButton{{Text: "Yes", Data: "choice_yes"}, {Text: "No", Data: "choice_no"}}, msg.InlineKeyboard())
 }
 
 func TestIVRMsgOut(t *testing.T) {
@@ -99,6 +107,7 @@ func TestIVRMsgOut(t *testing.T) {
 	defer uuids.SetGenerator(uuids.DefaultGenerator)
 
 	msg := flows.NewIVRMsgOut(
+		flows.MsgUUID(uuids.New()),
 		urns.URN("tel:+1234567890"),
 		assets.NewChannelReference(assets.ChannelUUID("61f38f46-a856-4f90-899e-905691784159"), "My Android"),
 		"Hi there",
@@ -0,0 +1,218 @@
+// Package offline provides bundles of flows for running on devices which aren't connected, e.g. surveyor style data
+// collection, and the importing of sessions completed with them.
+package offline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/actions"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+// MediaItem is a media file used by a bundled flow which a device should download before going offline
+type MediaItem struct {
+	ContentType string `json:"content_type"`
+	URL         string `json:"url"`
+}
+
+// Bundle is a flow along with everything needed to run it offline, i.e. the flows it enters and the assets they
+// reference, in the same format as a static asset source. Channels, classifiers, ticketers and templates are left out
+// as they can only be used online, as are any referenced assets which don't exist.
+//
+//	{
+//	  "version": "6a7c3f1e9b2d4c80",
+//	  "flow": {"uuid": "50c3706e-fedb-42c0-8eab-dda3335714b7", "name": "Registration"},
+//	  "created_on": "2018-10-18T14:20:30.000123456Z",
+//	  "assets": {"flows": [...], "fields": [...], "groups": [...]},
+//	  "media": [{"content_type": "image/jpeg", "url": "https://example.com/logo.jpg"}]
+//	}
+type Bundle struct {
+	Version   string                `json:"version" validate:"required"`
+	Flow      *assets.FlowReference `json:"flow" validate:"required"`
+	CreatedOn time.Time             `json:"created_on"`
+	Assets    json.RawMessage       `json:"assets" validate:"required"`
+	Media     []*MediaItem          `json:"media"`
+}
+
+// the assets of a bundle, marshaled in the format read by static sources
+type bundleAssets struct {
+	Flows   []json.RawMessage `json:"flows"`
+	Fields  []assets.Field    `json:"fields,omitempty"`
+	Globals []assets.Global   `json:"globals,omitempty"`
+	Groups  []assets.Group    `json:"groups,omitempty"`
+	Labels  []assets.Label    `json:"labels,omitempty"`
+	Topics  []assets.Topic    `json:"topics,omitempty"`
+	Users   []assets.User     `json:"users,omitempty"`
+
+	added map[string]bool // assets already added, as they can be referenced by more than one flow
+}
+
+// returns whether the asset with the given type and identity is new to the bundle, marking it as added
+func (a *bundleAssets) isNew(typeName, identity string) bool {
+	key := typeName + ":" + identity
+	if a.added[key] {
+		return false
+	}
+	a.added[key] = true
+	return true
+}
+
+// NewBundle creates a new bundle of the given offline flow
+func NewBundle(sa flows.SessionAssets, flow flows.Flow) (*Bundle, error) {
+	if flow.Type() != flows.FlowTypeMessagingOffline {
+		return nil, errors.Errorf("can't bundle flow of type '%s'", flow.Type())
+	}
+
+	ba := &bundleAssets{added: make(map[string]bool)}
+	media := make(map[string]*MediaItem)
+	seen := make(map[assets.FlowUUID]bool)
+
+	if err := ba.addFlow(sa, flow, seen, media); err != nil {
+		return nil, err
+	}
+
+	assetsJSON, err := jsonx.Marshal(ba)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling bundle assets")
+	}
+
+	mediaItems := make([]*MediaItem, 0, len(media))
+	for _, url := range utils.SortedKeys(media) {
+		mediaItems = append(mediaItems, media[url])
+	}
+
+	return &Bundle{
+		Version:   bundleVersion(assetsJSON),
+		Flow:      flow.Reference(false),
+		CreatedOn: dates.Now(),
+		Assets:    assetsJSON,
+		Media:     mediaItems,
+	}, nil
+}
+
+// ReadBundle reads a bundle from the given JSON
+func ReadBundle(data json.RawMessage) (*Bundle, error) {
+	b := &Bundle{}
+	if err := utils.UnmarshalAndValidate(data, b); err != nil {
+		return nil, errors.Wrap(err, "unable to read bundle")
+	}
+
+	if bundleVersion(b.Assets) != b.Version {
+		return nil, errors.Errorf("bundle assets don't match version '%s'", b.Version)
+	}
+
+	return b, nil
+}
+
+// Source returns a static asset source for the assets in this bundle
+func (b *Bundle) Source() (*static.StaticSource, error) {
+	return static.NewSource(b.Assets)
+}
+
+// adds the given flow and its dependencies, and then any flows it enters
+func (a *bundleAssets) addFlow(sa flows.SessionAssets, flow flows.Flow, seen map[assets.FlowUUID]bool, media map[string]*MediaItem) error {
+	seen[flow.UUID()] = true
+
+	definition, err := jsonx.Marshal(flow)
+	if err != nil {
+		return errors.Wrapf(err, "error marshaling flow '%s'", flow.UUID())
+	}
+	a.Flows = append(a.Flows, definition)
+
+	deps := flow.ExtractDependencies()
+
+	for _, d := range deps.Fields {
+		if f := sa.Fields().Get(d.Reference.Key); f != nil && a.isNew("field", f.Key()) {
+			a.Fields = append(a.Fields, static.NewField(f.UUID(), f.Key(), f.Name(), f.Type()))
+		}
+	}
+	for _, d := range deps.Globals {
+		if g := sa.Globals().Get(d.Reference.Key); g != nil && a.isNew("global", g.Key()) {
+			a.Globals = append(a.Globals, static.NewGlobal(g.Key(), g.Name(), g.Value()))
+		}
+	}
+	for _, d := range deps.Groups {
+		if g := sa.Groups().Get(d.Reference.UUID); g != nil && a.isNew("group", string(g.UUID())) {
+			a.Groups = append(a.Groups, static.NewGroup(g.UUID(), g.Name(), g.Query()))
+		}
+	}
+	for _, d := range deps.Labels {
+		if l := sa.Labels().Get(d.Reference.UUID); l != nil && a.isNew("label", string(l.UUID())) {
+			a.Labels = append(a.Labels, static.NewLabel(l.UUID(), l.Name()))
+		}
+	}
+	for _, d := range deps.Topics {
+		if t := sa.Topics().Get(d.Reference.UUID); t != nil && a.isNew("topic", string(t.UUID())) {
+			a.Topics = append(a.Topics, static.NewTopic(t.UUID(), t.Name(), t.Queue()))
+		}
+	}
+	for _, d := range deps.Users {
+		if u := sa.Users().Get(d.Reference.Email); u != nil && a.isNew("user", u.Email()) {
+			a.Users = append(a.Users, static.NewUser(u.Email(), u.Name()))
+		}
+	}
+
+	addMedia(flow, media)
+
+	for _, d := range deps.Flows {
+		if seen[d.Reference.UUID] {
+			continue
+		}
+
+		child, err := sa.Flows().Get(d.Reference.UUID)
+		if err != nil {
+			continue
+		}
+		if err := a.addFlow(sa, child, seen, media); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// adds the attachments of messages sent by the given flow, in any language, which aren't expressions
+func addMedia(flow flows.Flow, media map[string]*MediaItem) {
+	localization := flow.Localization()
+
+	for _, node := range flow.Nodes() {
+		for _, action := range node.Actions() {
+			sendMsg, isSendMsg := action.(*actions.SendMsgAction)
+			if !isSendMsg {
+				continue
+			}
+
+			attachments := append([]string(nil), sendMsg.Attachments...)
+			if localization != nil {
+				for _, lang := range localization.Languages() {
+					attachments = append(attachments, localization.GetItemTranslation(lang, action.LocalizationUUID(), "attachments")...)
+				}
+			}
+
+			for _, a := range attachments {
+				attachment := utils.Attachment(a)
+				if strings.Contains(a, "@") || attachment.ContentType() == "" {
+					continue
+				}
+				media[attachment.URL()] = &MediaItem{ContentType: attachment.ContentType(), URL: attachment.URL()}
+			}
+		}
+	}
+}
+
+// the version of a bundle is derived from its assets so that it changes whenever they do
+func bundleVersion(assetsJSON json.RawMessage) string {
+	hash := sha256.Sum256(assetsJSON)
+	return hex.EncodeToString(hash[:8])
+}
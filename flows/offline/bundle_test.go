@@ -0,0 +1,270 @@
+package offline_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/offline"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/flows/triggers"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var assetsJSON = `{
+	"flows": [
+		{
+			"uuid": "7c3db26f-e12a-48af-9673-e2feefdf8516",
+			"name": "Survey",
+			"spec_version": "13.0",
+			"language": "eng",
+			"type": "messaging_offline",
+			"localization": {
+				"spa": {
+					"e97cd6d5-3354-4dbd-85bc-6c1f87849eec": {
+						"text": ["¿Cuántos años tienes?"],
+						"attachments": ["image/jpeg:https://example.com/edad.jpg"]
+					}
+				}
+			},
+			"nodes": [
+				{
+					"uuid": "46d51f50-58de-49da-8d13-dadbf322685d",
+					"actions": [
+						{
+							"uuid": "e97cd6d5-3354-4dbd-85bc-6c1f87849eec",
+							"type": "send_msg",
+							"text": "How old are you?",
+							"attachments": ["image/jpeg:https://example.com/age.jpg", "image/jpeg:@fields.photo"]
+						},
+						{
+							"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a",
+							"type": "add_contact_groups",
+							"groups": [{"uuid": "b7cf0d83-f1c9-411c-96fd-c511a4cfa86d", "name": "Surveyed"}]
+						}
+					],
+					"router": {
+						"type": "switch",
+						"wait": {"type": "msg"},
+						"result_name": "Age",
+						"categories": [
+							{"uuid": "598ae7a5-2f81-48f1-afac-595262514aa1", "name": "All Responses", "exit_uuid": "7651ca02-775c-42f0-bfad-72ef1776c332"}
+						],
+						"default_category_uuid": "598ae7a5-2f81-48f1-afac-595262514aa1",
+						"operand": "@input.text",
+						"cases": []
+					},
+					"exits": [{"uuid": "7651ca02-775c-42f0-bfad-72ef1776c332", "destination_uuid": "11a772f3-3ca2-4429-8b33-20fdcfc2b69e"}]
+				},
+				{
+					"uuid": "11a772f3-3ca2-4429-8b33-20fdcfc2b69e",
+					"actions": [
+						{
+							"uuid": "d2a4052a-3fa9-4608-ab3e-5b9631440447",
+							"type": "set_contact_field",
+							"field": {"key": "age", "name": "Age"},
+							"value": "@results.age"
+						},
+						{
+							"uuid": "6a61a2e1-49e6-45f4-ae29-4e1d1ce4d05b",
+							"type": "enter_flow",
+							"flow": {"uuid": "e5aed6b1-a2ab-4e8c-bd5a-b2e1a54cfe7b", "name": "Thanks"}
+						}
+					],
+					"exits": [{"uuid": "78b3fa3d-5c0a-4db3-8026-3d04ead714b2"}]
+				}
+			]
+		},
+		{
+			"uuid": "e5aed6b1-a2ab-4e8c-bd5a-b2e1a54cfe7b",
+			"name": "Thanks",
+			"spec_version": "13.0",
+			"language": "eng",
+			"type": "messaging_offline",
+			"nodes": [
+				{
+					"uuid": "b1e8ab76-d5b1-4a0f-8e34-e0b7ad1bc6a5",
+					"actions": [
+						{
+							"uuid": "c4bd6a1e-6d8e-4ae0-9c1e-8e3a7e6d1b44",
+							"type": "send_msg",
+							"text": "Thanks, you are @fields.age",
+							"attachments": ["image/jpeg:https://example.com/age.jpg", "audio/mp3:https://example.com/thanks.mp3"]
+						}
+					],
+					"exits": [{"uuid": "f5b2a9e4-2d3c-4e7b-9a1f-6c8d0e2b4a73"}]
+				}
+			]
+		},
+		{
+			"uuid": "0ee4a1a6-5cb6-4a59-8e69-6e4a8a9d1f2c",
+			"name": "Online",
+			"spec_version": "13.0",
+			"language": "eng",
+			"type": "messaging",
+			"nodes": []
+		}
+	],
+	"fields": [
+		{"uuid": "f1b5aea6-6586-41c7-9020-1a6326cc6565", "key": "age", "name": "Age", "type": "number"},
+		{"uuid": "6c86d5ab-3fd9-4a5c-a5b6-48168b016747", "key": "photo", "name": "Photo", "type": "text"},
+		{"uuid": "c88d2640-d124-438a-b666-5ec53a353dcd", "key": "district", "name": "District", "type": "text"}
+	],
+	"groups": [
+		{"uuid": "b7cf0d83-f1c9-411c-96fd-c511a4cfa86d", "name": "Surveyed"},
+		{"uuid": "2aad21f6-30b7-42c5-bd7f-1b720c154817", "name": "Testers"}
+	]
+}`
+
+func TestBundle(t *testing.T) {
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	dates.SetNowSource(dates.NewFixedNowSource(time.Date(2018, 10, 18, 14, 20, 30, 123456, time.UTC)))
+
+	env := envs.NewBuilder().Build()
+	source, err := static.NewSource([]byte(assetsJSON))
+	require.NoError(t, err)
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	// only offline flows can be bundled
+	online, err := sa.Flows().Get("0ee4a1a6-5cb6-4a59-8e69-6e4a8a9d1f2c")
+	require.NoError(t, err)
+	_, err = offline.NewBundle(sa, online)
+	assert.EqualError(t, err, "can't bundle flow of type 'messaging'")
+
+	flow, err := sa.Flows().Get("7c3db26f-e12a-48af-9673-e2feefdf8516")
+	require.NoError(t, err)
+
+	bundle, err := offline.NewBundle(sa, flow)
+	require.NoError(t, err)
+
+	assert.Len(t, bundle.Version, 16)
+	assert.Equal(t, "Survey", bundle.Flow.Name)
+	assert.Equal(t, dates.Now(), bundle.CreatedOn)
+	assert.Equal(t, []*offline.MediaItem{
+		{ContentType: "image/jpeg", URL: "https://example.com/age.jpg"},
+		{ContentType: "image/jpeg", URL: "https://example.com/edad.jpg"},
+		{ContentType: "audio/mp3", URL: "https://example.com/thanks.mp3"},
+	}, bundle.Media)
+
+	// bundled assets are the survey, the flow it enters and the assets they reference
+	bundleSource, err := bundle.Source()
+	require.NoError(t, err)
+
+	_, err = bundleSource.FlowByUUID("7c3db26f-e12a-48af-9673-e2feefdf8516")
+	assert.NoError(t, err)
+	_, err = bundleSource.FlowByUUID("e5aed6b1-a2ab-4e8c-bd5a-b2e1a54cfe7b")
+	assert.NoError(t, err)
+	_, err = bundleSource.FlowByUUID("0ee4a1a6-5cb6-4a59-8e69-6e4a8a9d1f2c")
+	assert.Error(t, err)
+
+	bundledFields, _ := bundleSource.Fields()
+	bundledGroups, _ := bundleSource.Groups()
+	assert.Equal(t, 2, len(bundledFields))
+	assert.Equal(t, "photo", bundledFields[0].Key())
+	assert.Equal(t, "age", bundledFields[1].Key())
+	assert.Equal(t, 1, len(bundledGroups))
+	assert.Equal(t, "Surveyed", bundledGroups[0].Name())
+
+	// bundling again gives the same version
+	bundle2, err := offline.NewBundle(sa, flow)
+	require.NoError(t, err)
+	assert.Equal(t, bundle.Version, bundle2.Version)
+
+	// bundles can be marshaled and read back
+	bundleJSON, err := jsonx.Marshal(bundle)
+	require.NoError(t, err)
+
+	read, err := offline.ReadBundle(bundleJSON)
+	require.NoError(t, err)
+	assert.Equal(t, bundle.Version, read.Version)
+	assert.Equal(t, bundle.Media, read.Media)
+
+	// but not if the assets have been changed
+	_, err = offline.ReadBundle(test.JSONReplace(bundleJSON, []string{"assets", "groups", "[0]", "name"}, []byte(`"Changed"`)))
+	assert.EqualError(t, err, fmt.Sprintf("bundle assets don't match version '%s'", bundle.Version))
+
+	_, err = offline.ReadBundle([]byte(`{"flow": {"uuid": "7c3db26f-e12a-48af-9673-e2feefdf8516", "name": "Survey"}}`))
+	assert.EqualError(t, err, "unable to read bundle: field 'version' is required, field 'assets' is required")
+}
+
+func TestImportSessions(t *testing.T) {
+	env := envs.NewBuilder().Build()
+	source, err := static.NewSource([]byte(assetsJSON))
+	require.NoError(t, err)
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	flow, err := sa.Flows().Get("7c3db26f-e12a-48af-9673-e2feefdf8516")
+	require.NoError(t, err)
+
+	bundle, err := offline.NewBundle(sa, flow)
+	require.NoError(t, err)
+
+	// run the survey as a device would, using only the bundle
+	bundleSource, err := bundle.Source()
+	require.NoError(t, err)
+	bundleSA, err := engine.NewSessionAssets(env, bundleSource, nil)
+	require.NoError(t, err)
+
+	eng := engine.NewBuilder().Build()
+	contact := flows.NewEmptyContact(bundleSA, "Bob", envs.NilLanguage, nil)
+	trigger := triggers.NewBuilder(env, flow.Reference(false), contact).Manual().Build()
+
+	session, _, err := eng.NewSession(context.Background(), bundleSA, trigger)
+	require.NoError(t, err)
+	waitingJSON, err := jsonx.Marshal(session)
+	require.NoError(t, err)
+
+	msg := flows.NewMsgIn(flows.MsgUUID("f51d7220-10b3-4faa-a91c-1ae70beaae3e"), urns.NilURN, nil, "32", nil)
+	_, err = session.Resume(context.Background(), resumes.NewMsg(env, nil, msg))
+	require.NoError(t, err)
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+
+	completedJSON, err := jsonx.Marshal(session)
+	require.NoError(t, err)
+
+	submission := func(version string, session json.RawMessage) json.RawMessage {
+		return jsonx.MustMarshal(&offline.Submission{BundleVersion: version, Session: session})
+	}
+
+	batch := fmt.Sprintf(`[%s, %s, %s, %s]`,
+		submission(bundle.Version, completedJSON),
+		submission("0123456789abcdef", completedJSON),
+		submission(bundle.Version, waitingJSON),
+		submission(bundle.Version, []byte(`{"uuid": "2d611e17-fb22-457f-b802-b8f7ec5cda5b"}`)),
+	)
+
+	imported, err := bundle.ImportSessions(eng, env, []byte(batch))
+	require.NoError(t, err)
+	require.Equal(t, 4, len(imported))
+
+	assert.NoError(t, imported[0].Error)
+	assert.Equal(t, session.UUID(), imported[0].Session.UUID())
+	assert.Equal(t, "32", imported[0].Session.Runs()[0].Results().Get("age").Value)
+
+	assert.Nil(t, imported[1].Session)
+	assert.EqualError(t, imported[1].Error, fmt.Sprintf("session was run with bundle version '0123456789abcdef' but current version is '%s'", bundle.Version))
+
+	assert.Nil(t, imported[2].Session)
+	assert.EqualError(t, imported[2].Error, "session has status 'waiting' but only completed sessions can be imported")
+
+	assert.Nil(t, imported[3].Session)
+	assert.Error(t, imported[3].Error)
+
+	// error if the batch itself can't be read
+	_, err = bundle.ImportSessions(eng, env, []byte(`[{"session": {}}]`))
+	assert.EqualError(t, err, "unable to read submissions: field 'bundle_version' is required")
+}
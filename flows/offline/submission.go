@@ -0,0 +1,79 @@
+package offline
+
+import (
+	"encoding/json"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+// Submission is a session which was run offline with a bundle, as submitted by the device which ran it
+//
+//	{
+//	  "bundle_version": "6a7c3f1e9b2d4c80",
+//	  "session": {"uuid": "2d611e17-fb22-457f-b802-b8f7ec5cda5b", "status": "completed", ...}
+//	}
+type Submission struct {
+	BundleVersion string          `json:"bundle_version" validate:"required"`
+	Session       json.RawMessage `json:"session" validate:"required"`
+}
+
+// ImportedSession is the result of importing a single submission, which is either a session or the reason it was
+// rejected
+type ImportedSession struct {
+	Session flows.Session
+	Error   error
+}
+
+// ImportSessions imports a batch of submissions of sessions run with this bundle. Submissions are rejected if they were
+// made with a different version of the bundle, aren't sessions of the bundled flow, or are sessions which haven't
+// completed. An error is only returned if the batch itself can't be read.
+func (b *Bundle) ImportSessions(eng flows.Engine, env envs.Environment, data json.RawMessage) ([]*ImportedSession, error) {
+	var submissions []*Submission
+	if err := utils.UnmarshalAndValidate(data, &submissions); err != nil {
+		return nil, errors.Wrap(err, "unable to read submissions")
+	}
+
+	source, err := b.Source()
+	if err != nil {
+		return nil, err
+	}
+
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating session assets from bundle")
+	}
+
+	imported := make([]*ImportedSession, len(submissions))
+	for i, s := range submissions {
+		session, err := b.importSession(eng, sa, s)
+		imported[i] = &ImportedSession{Session: session, Error: err}
+	}
+
+	return imported, nil
+}
+
+func (b *Bundle) importSession(eng flows.Engine, sa flows.SessionAssets, s *Submission) (flows.Session, error) {
+	if s.BundleVersion != b.Version {
+		return nil, errors.Errorf("session was run with bundle version '%s' but current version is '%s'", s.BundleVersion, b.Version)
+	}
+
+	session, err := eng.ReadSession(sa, s.Session, assets.IgnoreMissing)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read session")
+	}
+
+	if flow := session.Runs()[0].FlowReference(); flow.UUID != b.Flow.UUID {
+		return nil, errors.Errorf("session is of flow '%s' but bundle is of flow '%s'", flow.UUID, b.Flow.UUID)
+	}
+	if session.Status() != flows.SessionStatusCompleted {
+		return nil, errors.Errorf("session has status '%s' but only completed sessions can be imported", session.Status())
+	}
+
+	return session, nil
+}
@@ -0,0 +1,120 @@
+package flows
+
+import (
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	RegisterContextType("cart_item",
+		NewContextProperty("product_id", "text", "the ID of the product"),
+		NewContextProperty("name", "text", "the name of the product"),
+		NewContextProperty("quantity", "number", "the quantity of the product"),
+		NewContextProperty("unit_price", "money", "the price of a single unit of the product"),
+		NewContextProperty("total", "money", "the total price of this item"),
+	)
+
+	RegisterContextType("cart",
+		NewContextProperty("__default__", "money", "the total price"),
+		NewContextArrayProperty("items", "cart_item", "the line items in the cart"),
+		NewContextProperty("count", "number", "the total number of units in the cart"),
+		NewContextProperty("total", "money", "the total price of all items in the cart"),
+		NewContextProperty("currency", "text", "the currency of the cart"),
+	)
+}
+
+// OrderItem is a line item in an order
+type OrderItem struct {
+	ProductID string          `json:"product_id" validate:"required"`
+	Name      string          `json:"name"`
+	Quantity  int             `json:"quantity" validate:"min=1"`
+	UnitPrice decimal.Decimal `json:"unit_price"`
+}
+
+// Total returns the total price of this line item
+func (i *OrderItem) Total() decimal.Decimal {
+	return i.UnitPrice.Mul(decimal.NewFromInt(int64(i.Quantity)))
+}
+
+// Context returns the properties available in expressions
+func (i *OrderItem) context(currency string) map[string]types.XValue {
+	return map[string]types.XValue{
+		"product_id": types.NewXText(i.ProductID),
+		"name":       types.NewXText(i.Name),
+		"quantity":   types.NewXNumberFromInt(i.Quantity),
+		"unit_price": types.NewXMoney(i.UnitPrice, currency),
+		"total":      types.NewXMoney(i.Total(), currency),
+	}
+}
+
+// Order is a list of line items in a single currency. A session's cart is an order which hasn't been placed yet.
+type Order struct {
+	Currency string       `json:"currency" validate:"required"`
+	Items    []*OrderItem `json:"items" validate:"dive"`
+}
+
+// NewOrder creates a new empty order in the given currency
+func NewOrder(currency string) *Order {
+	return &Order{Currency: currency, Items: []*OrderItem{}}
+}
+
+// Add adds the given quantity of a product to this order, merging with any existing line item for that product
+func (o *Order) Add(product *Product, quantity int) (*OrderItem, error) {
+	if product.Currency != o.Currency {
+		return nil, errors.Errorf("product '%s' is priced in %s but order is in %s", product.ID, product.Currency, o.Currency)
+	}
+	if quantity < 1 {
+		return nil, errors.Errorf("quantity must be at least 1, got %d", quantity)
+	}
+
+	for _, item := range o.Items {
+		if item.ProductID == product.ID {
+			item.Quantity += quantity
+			item.UnitPrice = product.Price
+			return item, nil
+		}
+	}
+
+	item := &OrderItem{ProductID: product.ID, Name: product.Name, Quantity: quantity, UnitPrice: product.Price}
+	o.Items = append(o.Items, item)
+	return item, nil
+}
+
+// Count returns the total number of units in this order
+func (o *Order) Count() int {
+	count := 0
+	for _, item := range o.Items {
+		count += item.Quantity
+	}
+	return count
+}
+
+// Total returns the total price of this order
+func (o *Order) Total() decimal.Decimal {
+	total := decimal.Zero
+	for _, item := range o.Items {
+		total = total.Add(item.Total())
+	}
+	return total
+}
+
+// Context returns the properties available in expressions
+func (o *Order) Context(env envs.Environment) map[string]types.XValue {
+	items := make([]types.XValue, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = types.NewXObject(item.context(o.Currency))
+	}
+
+	total := types.NewXMoney(o.Total(), o.Currency)
+
+	return map[string]types.XValue{
+		"__default__": total,
+		"items":       types.NewXArray(items...),
+		"count":       types.NewXNumberFromInt(o.Count()),
+		"total":       total,
+		"currency":    types.NewXText(o.Currency),
+	}
+}
@@ -0,0 +1,60 @@
+package flows_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrder(t *testing.T) {
+	env := envs.NewBuilder().Build()
+
+	maize := &flows.Product{ID: "maize", Name: "Maize", Price: decimal.RequireFromString("350"), Currency: "RWF"}
+	beans := &flows.Product{ID: "beans", Name: "Beans", Price: decimal.RequireFromString("800.50"), Currency: "RWF"}
+	pens := &flows.Product{ID: "pens", Name: "Pens", Price: decimal.RequireFromString("2.50"), Currency: "USD"}
+
+	order := flows.NewOrder("RWF")
+	assert.Equal(t, 0, order.Count())
+	assert.Equal(t, "0", order.Total().String())
+
+	_, err := order.Add(maize, 2)
+	require.NoError(t, err)
+	_, err = order.Add(beans, 1)
+	require.NoError(t, err)
+
+	// adding the same product again increases the quantity of the existing line item
+	item, err := order.Add(maize, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, item.Quantity)
+	assert.Equal(t, "1050", item.Total().String())
+
+	_, err = order.Add(pens, 1)
+	assert.EqualError(t, err, "product 'pens' is priced in USD but order is in RWF")
+
+	_, err = order.Add(beans, 0)
+	assert.EqualError(t, err, "quantity must be at least 1, got 0")
+
+	assert.Equal(t, 2, len(order.Items))
+	assert.Equal(t, 4, order.Count())
+	assert.Equal(t, "1850.5", order.Total().String())
+
+	test.AssertXEqual(t, types.NewXMoney(decimal.RequireFromString("1850.5"), "RWF"), flows.Context(env, order).(*types.XObject).Default())
+	test.AssertXEqual(t, types.NewXNumberFromInt(4), order.Context(env)["count"])
+	test.AssertXEqual(t, types.NewXText("RWF"), order.Context(env)["currency"])
+
+	test.AssertEqualJSON(t, []byte(`{
+		"currency": "RWF",
+		"items": [
+			{"product_id": "maize", "name": "Maize", "quantity": 3, "unit_price": 350},
+			{"product_id": "beans", "name": "Beans", "quantity": 1, "unit_price": 800.5}
+		]
+	}`), jsonx.MustMarshal(order), "order JSON mismatch")
+}
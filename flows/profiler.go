@@ -0,0 +1,128 @@
+package flows
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/stringsx"
+)
+
+// ProfileCategory is a category of work timed by a profiler
+type ProfileCategory string
+
+// possible categories of profiled work
+const (
+	ProfileCategoryAction   ProfileCategory = "action"
+	ProfileCategoryRouter   ProfileCategory = "router"
+	ProfileCategoryTemplate ProfileCategory = "template"
+	ProfileCategoryService  ProfileCategory = "service"
+)
+
+// max length of names, e.g. of templates, that are kept by a profiler
+const maxProfileNameLength = 100
+
+// ProfileTiming is the total time spent on one thing, e.g. a template, during a sprint
+type ProfileTiming struct {
+	Name      string  `json:"name"`
+	Count     int     `json:"count"`
+	ElapsedMS float64 `json:"elapsed_ms"`
+
+	elapsed time.Duration
+}
+
+type profileKey struct {
+	category ProfileCategory
+	name     string
+}
+
+// Profiler records the wall time spent executing actions, routing, evaluating templates and calling services during
+// a sprint. Work done more than once, e.g. a template evaluated in a loop, is added up. A nil profiler is valid and
+// records nothing.
+type Profiler struct {
+	mutex   sync.Mutex
+	start   time.Time
+	timings map[profileKey]*ProfileTiming
+}
+
+// NewProfiler creates a new profiler which starts timing the sprint now
+func NewProfiler() *Profiler {
+	return &Profiler{start: dates.Now(), timings: make(map[profileKey]*ProfileTiming)}
+}
+
+var noopStop = func() {}
+
+// Start starts timing some work and returns the function to call when that work is finished
+func (p *Profiler) Start(category ProfileCategory, name string) func() {
+	if p == nil {
+		return noopStop
+	}
+
+	start := dates.Now()
+	return func() { p.record(category, name, dates.Since(start)) }
+}
+
+func (p *Profiler) record(category ProfileCategory, name string, elapsed time.Duration) {
+	key := profileKey{category: category, name: stringsx.TruncateEllipsis(name, maxProfileNameLength)}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	timing := p.timings[key]
+	if timing == nil {
+		timing = &ProfileTiming{Name: key.name}
+		p.timings[key] = timing
+	}
+	timing.Count++
+	timing.elapsed += elapsed
+	timing.ElapsedMS = durationToMS(timing.elapsed)
+}
+
+// ElapsedMS returns the time in milliseconds since this profiler was created
+func (p *Profiler) ElapsedMS() float64 {
+	return durationToMS(dates.Since(p.start))
+}
+
+// Slowest returns up to limit timings of the given category, slowest first
+func (p *Profiler) Slowest(category ProfileCategory, limit int) []*ProfileTiming {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	slowest := make([]*ProfileTiming, 0, limit)
+	for k, t := range p.timings {
+		if k.category == category {
+			slowest = append(slowest, t)
+		}
+	}
+
+	sort.SliceStable(slowest, func(i, j int) bool {
+		if slowest[i].elapsed != slowest[j].elapsed {
+			return slowest[i].elapsed > slowest[j].elapsed
+		}
+		return slowest[i].Name < slowest[j].Name
+	})
+
+	if len(slowest) > limit {
+		slowest = slowest[:limit]
+	}
+	return slowest
+}
+
+// converts the given duration to milliseconds, keeping microsecond precision
+func durationToMS(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
+
+// EventTiming is the timing of the action which generated an event, i.e. when it started and how long it had been
+// executing for when the event was generated
+type EventTiming struct {
+	StartedOn time.Time `json:"started_on"`
+	ElapsedMS float64   `json:"elapsed_ms"`
+}
+
+// NewEventTiming creates a new event timing for an action which started at the given time
+func NewEventTiming(startedOn time.Time, elapsed time.Duration) *EventTiming {
+	return &EventTiming{StartedOn: startedOn, ElapsedMS: durationToMS(elapsed)}
+}
@@ -0,0 +1,59 @@
+package flows_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/goflow/flows"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiler(t *testing.T) {
+	defer dates.SetNowSource(dates.DefaultNowSource)
+
+	// each call to now is a second after the previous one
+	dates.SetNowSource(dates.NewSequentialNowSource(time.Date(2022, 2, 3, 13, 45, 30, 0, time.UTC)))
+
+	p := flows.NewProfiler()
+
+	stopOuter := p.Start(flows.ProfileCategoryAction, "call_webhook")
+	p.Start(flows.ProfileCategoryService, "webhook")()
+	stopOuter()
+
+	p.Start(flows.ProfileCategoryTemplate, "@contact.name")()
+	p.Start(flows.ProfileCategoryTemplate, "@contact.name")()
+	p.Start(flows.ProfileCategoryTemplate, "@fields.age")()
+
+	stop := p.Start(flows.ProfileCategoryTemplate, strings.Repeat("x", 200))
+	dates.Now()
+	dates.Now()
+	stop()
+
+	assert.Equal(t, []*flows.ProfileTiming{{Name: "call_webhook", Count: 1, ElapsedMS: 3000}}, profileTimings(p.Slowest(flows.ProfileCategoryAction, 5)))
+	assert.Equal(t, []*flows.ProfileTiming{{Name: "webhook", Count: 1, ElapsedMS: 1000}}, profileTimings(p.Slowest(flows.ProfileCategoryService, 5)))
+	assert.Equal(t, []*flows.ProfileTiming{}, profileTimings(p.Slowest(flows.ProfileCategoryRouter, 5)))
+
+	// timings of the same thing are added up and long names are truncated
+	assert.Equal(t, []*flows.ProfileTiming{
+		{Name: strings.Repeat("x", 97) + "...", Count: 1, ElapsedMS: 3000},
+		{Name: "@contact.name", Count: 2, ElapsedMS: 2000},
+	}, profileTimings(p.Slowest(flows.ProfileCategoryTemplate, 2)))
+
+	assert.Equal(t, 15000.0, p.ElapsedMS())
+
+	// a nil profiler records nothing
+	var nilProfiler *flows.Profiler
+	nilProfiler.Start(flows.ProfileCategoryAction, "send_msg")()
+}
+
+// strips the unexported fields of the given timings so they can be compared
+func profileTimings(timings []*flows.ProfileTiming) []*flows.ProfileTiming {
+	stripped := make([]*flows.ProfileTiming, len(timings))
+	for i, t := range timings {
+		stripped[i] = &flows.ProfileTiming{Name: t.Name, Count: t.Count, ElapsedMS: t.ElapsedMS}
+	}
+	return stripped
+}
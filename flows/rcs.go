@@ -0,0 +1,90 @@
+package flows
+
+import (
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	utils.RegisterValidatorAlias("suggestion_type", "eq=reply|eq=open_url|eq=dial", func(validator.FieldError) string {
+		return "is not a valid suggestion type"
+	})
+}
+
+// SuggestionType is the type of a suggested reply or action
+type SuggestionType string
+
+// possible suggestion type values
+const (
+	SuggestionTypeReply   SuggestionType = "reply"
+	SuggestionTypeOpenURL SuggestionType = "open_url"
+	SuggestionTypeDial    SuggestionType = "dial"
+)
+
+// Suggestion is a suggested reply or action shown as a chip with a message or rich card. A reply sends back its data
+// when tapped, whereas an action opens a URL or dials a phone number on the contact's device.
+type Suggestion struct {
+	Type  SuggestionType `json:"type"`
+	Text  string         `json:"text"`
+	Data  string         `json:"data,omitempty"`
+	URL   string         `json:"url,omitempty"`
+	Phone string         `json:"phone,omitempty"`
+}
+
+// fallback renders this suggestion as a line of plain text
+func (s *Suggestion) fallback() string {
+	switch s.Type {
+	case SuggestionTypeOpenURL:
+		return s.Text + ": " + s.URL
+	case SuggestionTypeDial:
+		return s.Text + ": " + s.Phone
+	}
+	return s.Text
+}
+
+// RichCard is a card with a title, description and media, and its own suggestions. Several cards sent together are
+// shown as a carousel.
+type RichCard struct {
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Media       utils.Attachment `json:"media,omitempty"`
+	Suggestions []Suggestion     `json:"suggestions,omitempty"`
+}
+
+// RichContentFallback generates the plain text version of a message with rich cards and suggestions, for sending on
+// channels which don't support them
+func RichContentFallback(text string, cards []RichCard, suggestions []Suggestion) string {
+	blocks := make([]string, 0, len(cards)+2)
+	if text != "" {
+		blocks = append(blocks, text)
+	}
+
+	for _, card := range cards {
+		lines := make([]string, 0, 3+len(card.Suggestions))
+		if card.Title != "" {
+			lines = append(lines, card.Title)
+		}
+		if card.Description != "" {
+			lines = append(lines, card.Description)
+		}
+		if card.Media != "" {
+			lines = append(lines, card.Media.URL())
+		}
+		for _, s := range card.Suggestions {
+			lines = append(lines, "- "+s.fallback())
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+
+	if len(suggestions) > 0 {
+		lines := make([]string, len(suggestions))
+		for i := range suggestions {
+			lines[i] = "- " + suggestions[i].fallback()
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+
+	return strings.Join(blocks, "\n\n")
+}
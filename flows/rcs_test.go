@@ -0,0 +1,29 @@
+package flows_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRichContentFallback(t *testing.T) {
+	cards := []flows.RichCard{
+		{
+			Title:       "Maize flour",
+			Description: "2kg for RWF 1,200",
+			Media:       utils.Attachment("image/jpeg:https://example.com/maize.jpg"),
+			Suggestions: []flows.Suggestion{{Type: flows.SuggestionTypeOpenURL, Text: "Details", URL: "https://example.com/maize"}},
+		},
+		{Title: "Beans"},
+	}
+	suggestions := []flows.Suggestion{
+		{Type: flows.SuggestionTypeReply, Text: "Order now", Data: "order"},
+		{Type: flows.SuggestionTypeDial, Text: "Call us", Phone: "+250788123123"},
+	}
+
+	assert.Equal(t, "Deals:", flows.RichContentFallback("Deals:", nil, nil))
+	assert.Equal(t, "Deals:\n\n- Order now\n- Call us: +250788123123", flows.RichContentFallback("Deals:", nil, suggestions))
+	assert.Equal(t, "Maize flour\n2kg for RWF 1,200\nhttps://example.com/maize.jpg\n- Details: https://example.com/maize\n\nBeans", flows.RichContentFallback("", cards, nil))
+}
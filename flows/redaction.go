@@ -0,0 +1,133 @@
+package flows
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/nyaruka/gocommon/stringsx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/envs"
+)
+
+// RedactableEvent is an event which can contain sensitive values that should be masked before it leaves the engine
+type RedactableEvent interface {
+	Event
+
+	Redact(*Redactor)
+}
+
+// Redactor masks the sensitive values described by an environment's redaction rules. Contact URNs, field values and
+// values tagged with PII categories are masked wherever they appear, and the values of named headers are masked in
+// HTTP logs.
+type Redactor struct {
+	values  stringsx.Redactor
+	headers *regexp.Regexp
+}
+
+// NewRedactor creates a new redactor for the given environment and contact, which may be nil. Any additional values
+// given, e.g. service credentials, are masked too. Returns nil if there's nothing to redact.
+func NewRedactor(env envs.Environment, contact *Contact, values ...string) *Redactor {
+	rules := env.RedactionRules()
+	masked := make([]string, 0, len(values))
+
+	for _, v := range values {
+		if v != "" {
+			masked = append(masked, v)
+		}
+	}
+
+	if contact != nil {
+		allURNs := env.RedactionPolicy() == envs.RedactionPolicyURNs
+		phones := rules.HasPII(envs.PIICategoryPhone)
+
+		for _, u := range contact.URNs() {
+			scheme, path := u.URN().Scheme(), u.URN().Path()
+			if path != "" && (allURNs || containsFold(rules.URNSchemes, scheme) || (phones && isPhoneScheme(scheme))) {
+				masked = append(masked, path)
+			}
+		}
+		for key, fv := range contact.Fields() {
+			if fv != nil && fv.Value != nil && fv.Text.Native() != "" && (containsFold(rules.Fields, key) || rules.HasPII(fv.field.PII())) {
+				masked = append(masked, fv.Text.Native())
+			}
+		}
+		if contact.Name() != "" && rules.HasPII(envs.PIICategoryName) {
+			masked = append(masked, contact.Name())
+		}
+	}
+
+	if len(masked) == 0 && len(rules.Headers) == 0 {
+		return nil
+	}
+
+	r := &Redactor{}
+	if len(masked) > 0 {
+		r.values = stringsx.NewRedactor(RedactionMask, masked...)
+	}
+	if len(rules.Headers) > 0 {
+		names := make([]string, len(rules.Headers))
+		for i, h := range rules.Headers {
+			names[i] = regexp.QuoteMeta(h)
+		}
+		r.headers = regexp.MustCompile(`(?im)^((?:` + strings.Join(names, "|") + `):[ \t]*)[^\r\n]+`)
+	}
+	return r
+}
+
+// Redact masks the sensitive values in the given text, e.g. an error message
+func (r *Redactor) Redact(s string) string {
+	if r == nil || r.values == nil {
+		return s
+	}
+	return r.values(s)
+}
+
+// RedactHTTPLog masks the sensitive values in the URL, request and response of the given HTTP log, as well as the
+// values of any named headers
+func (r *Redactor) RedactHTTPLog(l *HTTPLogWithoutTime) {
+	if r == nil || l == nil || l.LogWithoutTime == nil {
+		return
+	}
+
+	if r.headers != nil {
+		l.Request = r.headers.ReplaceAllString(l.Request, "${1}"+RedactionMask)
+		l.Response = r.headers.ReplaceAllString(l.Response, "${1}"+RedactionMask)
+	}
+
+	l.URL = r.Redact(l.URL)
+	l.Request = r.Redact(l.Request)
+	l.Response = r.Redact(l.Response)
+}
+
+// RedactHTTPLogs masks the sensitive values in all the given HTTP logs
+func (r *Redactor) RedactHTTPLogs(logs []*HTTPLog) {
+	for _, l := range logs {
+		if l != nil {
+			r.RedactHTTPLog(l.HTTPLogWithoutTime)
+		}
+	}
+}
+
+// PIIValues returns the values of the given results which are tagged with PII categories that the given rules redact
+func PIIValues(rules *envs.RedactionRules, results Results) []string {
+	values := make([]string, 0)
+	for _, r := range results {
+		if r.Value != "" && rules.HasPII(r.PII) {
+			values = append(values, r.Value)
+		}
+	}
+	return values
+}
+
+func isPhoneScheme(scheme string) bool {
+	return scheme == urns.TelScheme || scheme == urns.WhatsAppScheme
+}
+
+func containsFold(values []string, v string) bool {
+	for _, s := range values {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package flows_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor(t *testing.T) {
+	session, _, err := test.CreateTestSession("", envs.RedactionPolicyNone)
+	require.NoError(t, err)
+	contact := session.Contact()
+
+	// nothing to redact without rules
+	assert.Nil(t, flows.NewRedactor(envs.NewBuilder().Build(), contact))
+
+	env := envs.NewBuilder().WithRedactionRules(&envs.RedactionRules{
+		URNSchemes: []string{"tel"},
+		Fields:     []string{"activation_token", "unknown"},
+		Headers:    []string{"X-Api-Key"},
+	}).Build()
+
+	redactor := flows.NewRedactor(env, contact, "sesame")
+	assert.Equal(t, "call **************** with code ****************", redactor.Redact("call +12024561111 with code AACC55"))
+	assert.Equal(t, "email foo@bar.com, password ****************", redactor.Redact("email foo@bar.com, password sesame"))
+
+	log := &flows.HTTPLogWithoutTime{LogWithoutTime: &httpx.LogWithoutTime{
+		URL:      "http://example.com/lookup?phone=+12024561111",
+		Request:  "GET /lookup?phone=+12024561111 HTTP/1.1\r\nHost: example.com\r\nx-api-key: 123456\r\n\r\n",
+		Response: "HTTP/1.1 200 OK\r\n\r\n{\"token\": \"AACC55\"}",
+	}}
+	redactor.RedactHTTPLog(log)
+
+	assert.Equal(t, "http://example.com/lookup?phone=****************", log.URL)
+	assert.Equal(t, "GET /lookup?phone=**************** HTTP/1.1\r\nHost: example.com\r\nx-api-key: ****************\r\n\r\n", log.Request)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n\r\n{\"token\": \"****************\"}", log.Response)
+
+	// a redaction policy of URNs masks URNs of every scheme
+	redactor = flows.NewRedactor(envs.NewBuilder().WithRedactionPolicy(envs.RedactionPolicyURNs).Build(), contact)
+	assert.Equal(t, "email ****************", redactor.Redact("email foo@bar.com"))
+
+	// events which can contain sensitive values can be redacted
+	event := events.NewError(errors.New("unable to send to +12024561111"))
+	var redactable flows.RedactableEvent = event
+	redactable.Redact(redactor)
+	assert.Equal(t, "unable to send to ****************", event.Text)
+
+	// but not webhook requests which the caller has to make as they are
+	_, isRedactable := flows.Event(events.NewWebhookRequested("b7c7a6d4-4e3f-4d8a-9b0e-2f5c1a8d9e7f", "GET", "http://example.com/?phone=+12024561111", nil, "", "")).(flows.RedactableEvent)
+	assert.False(t, isRedactable)
+
+	// PII categories mask the contact's name, phone URNs, tagged field values and tagged result values
+	source, err := static.NewSource([]byte(`{
+		"fields": [
+			{"uuid": "d66a7823-eada-40e5-9a3a-57239d4690bf", "key": "diagnosis", "name": "Diagnosis", "type": "text", "pii": "health"},
+			{"uuid": "f1b5aea6-6586-41c7-9020-1a6326cc6565", "key": "gender", "name": "Gender", "type": "text"}
+		]
+	}`))
+	require.NoError(t, err)
+
+	env = envs.NewBuilder().WithRedactionRules(&envs.RedactionRules{PII: []envs.PIICategory{envs.PIICategoryName, envs.PIICategoryPhone, envs.PIICategoryHealth}}).Build()
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	contact, err = flows.ReadContact(sa, []byte(`{
+		"uuid": "5d76d86b-3bb9-4d5a-b822-c9d86f5d8e4f",
+		"name": "Ryan Lewis",
+		"created_on": "2018-06-20T11:40:30.123456789Z",
+		"urns": ["tel:+12024561111", "whatsapp:250788123123", "mailto:foo@bar.com"],
+		"fields": {"diagnosis": {"text": "malaria"}, "gender": {"text": "Male"}}
+	}`), assets.PanicOnMissing)
+	require.NoError(t, err)
+
+	results := flows.Results{
+		"blood_type": &flows.Result{Name: "Blood Type", Value: "O+", PII: envs.PIICategoryHealth},
+		"color":      &flows.Result{Name: "Color", Value: "red"},
+	}
+	assert.Equal(t, []string{"O+"}, flows.PIIValues(env.RedactionRules(), results))
+
+	redactor = flows.NewRedactor(env, contact, flows.PIIValues(env.RedactionRules(), results)...)
+	assert.Equal(t, "**************** (****************) has ****************, blood type ****************", redactor.Redact("Ryan Lewis (+12024561111) has malaria, blood type O+"))
+	assert.Equal(t, "**************** is Male, likes red, email foo@bar.com", redactor.Redact("250788123123 is Male, likes red, email foo@bar.com"))
+
+	// and only the categories in the rules
+	env = envs.NewBuilder().WithRedactionRules(&envs.RedactionRules{PII: []envs.PIICategory{envs.PIICategoryHealth}}).Build()
+	redactor = flows.NewRedactor(env, contact)
+	assert.Equal(t, "Ryan Lewis (+12024561111) has ****************", redactor.Redact("Ryan Lewis (+12024561111) has malaria"))
+
+	// and a nil redactor does nothing
+	var nilRedactor *flows.Redactor
+	assert.Equal(t, "+12024561111", nilRedactor.Redact("+12024561111"))
+}
@@ -0,0 +1,163 @@
+package flows
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+)
+
+func init() {
+	RegisterContextType("relation",
+		NewContextProperty("__default__", "text", "the name of the related contact"),
+		NewContextProperty("uuid", "text", "the UUID of the related contact"),
+		NewContextProperty("name", "text", "the name of the related contact"),
+	)
+}
+
+// RelationType represents a type of relationship between contacts
+type RelationType struct {
+	assets.RelationType
+}
+
+// NewRelationType creates a new relation type from the given asset
+func NewRelationType(asset assets.RelationType) *RelationType {
+	return &RelationType{RelationType: asset}
+}
+
+// Asset returns the underlying asset
+func (t *RelationType) Asset() assets.RelationType { return t.RelationType }
+
+// Reference returns a reference to this relation type
+func (t *RelationType) Reference() *assets.RelationTypeReference {
+	if t == nil {
+		return nil
+	}
+	return assets.NewRelationTypeReference(t.Key(), t.Name())
+}
+
+// Relation is a relationship of a contact to another contact, e.g. the other contact is their caregiver
+type Relation struct {
+	relationType *RelationType
+	contact      *ContactReference
+}
+
+// NewRelation creates a new relation
+func NewRelation(relationType *RelationType, contact *ContactReference) *Relation {
+	return &Relation{relationType: relationType, contact: contact}
+}
+
+// Type returns the type of this relation
+func (r *Relation) Type() *RelationType { return r.relationType }
+
+// Contact returns a reference to the related contact
+func (r *Relation) Contact() *ContactReference { return r.contact }
+
+// Context returns the properties available in expressions
+func (r *Relation) Context(env envs.Environment) map[string]types.XValue {
+	return map[string]types.XValue{
+		"__default__": types.NewXText(r.contact.Name),
+		"uuid":        types.NewXText(string(r.contact.UUID)),
+		"name":        types.NewXText(r.contact.Name),
+	}
+}
+
+// Relations is the set of all relations of a contact, keyed by relation type
+type Relations map[string]*Relation
+
+// NewRelations creates a new set of relations from the given related contacts keyed by relation type
+func NewRelations(a SessionAssets, contacts map[string]*ContactReference, missing assets.MissingCallback) Relations {
+	allTypes := a.RelationTypes().All()
+	relations := make(Relations, len(allTypes))
+	for _, relationType := range allTypes {
+		relations.Set(relationType, contacts[relationType.Key()])
+	}
+
+	// log any unmatched relation type keys as missing assets
+	for key := range contacts {
+		_, valid := relations[key]
+		if !valid {
+			missing(assets.NewRelationTypeReference(key, ""), nil)
+		}
+	}
+
+	return relations
+}
+
+func (r Relations) clone() Relations {
+	clone := make(Relations, len(r))
+	for k, v := range r {
+		clone[k] = v
+	}
+	return clone
+}
+
+// Get gets the contact related by the given relation type
+func (r Relations) Get(relationType *RelationType) *ContactReference {
+	relation := r[relationType.Key()]
+	if relation != nil {
+		return relation.contact
+	}
+	return nil
+}
+
+// Set sets the contact related by the given relation type (can be nil to clear it)
+func (r Relations) Set(relationType *RelationType, contact *ContactReference) {
+	var relation *Relation
+	if contact != nil {
+		relation = NewRelation(relationType, contact)
+	}
+	r[relationType.Key()] = relation
+}
+
+// Context returns the properties available in expressions
+func (r Relations) Context(env envs.Environment) map[string]types.XValue {
+	entries := make(map[string]types.XValue, len(r)+1)
+	lines := make([]string, 0, len(r))
+
+	for k, v := range r {
+		entries[k] = Context(env, v)
+
+		if v != nil {
+			lines = append(lines, fmt.Sprintf("%s: %s", v.relationType.Name(), v.contact.Name))
+		}
+	}
+
+	sort.Strings(lines)
+	entries["__default__"] = types.NewXText(strings.Join(lines, "\n"))
+
+	return entries
+}
+
+// RelationTypeAssets provides access to all relation type assets
+type RelationTypeAssets struct {
+	all   []*RelationType
+	byKey map[string]*RelationType
+}
+
+// NewRelationTypeAssets creates a new set of relation type assets
+func NewRelationTypeAssets(relationTypes []assets.RelationType) *RelationTypeAssets {
+	s := &RelationTypeAssets{
+		all:   make([]*RelationType, len(relationTypes)),
+		byKey: make(map[string]*RelationType, len(relationTypes)),
+	}
+	for i, asset := range relationTypes {
+		relationType := NewRelationType(asset)
+		s.all[i] = relationType
+		s.byKey[relationType.Key()] = relationType
+	}
+	return s
+}
+
+// Get returns the relation type with the given key
+func (s *RelationTypeAssets) Get(key string) *RelationType {
+	return s.byKey[key]
+}
+
+// All returns all the relation types in this set
+func (s *RelationTypeAssets) All() []*RelationType {
+	return s.all
+}
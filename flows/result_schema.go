@@ -0,0 +1,128 @@
+package flows
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	utils.RegisterValidatorAlias("result_type", "eq=number|eq=datetime|eq=option", func(validator.FieldError) string {
+		return "is not a valid result type"
+	})
+}
+
+// ResultType is the type of value which a result is declared to have
+type ResultType string
+
+// possible types of result value
+const (
+	ResultTypeNumber   ResultType = "number"
+	ResultTypeDatetime ResultType = "datetime"
+	ResultTypeOption   ResultType = "option"
+)
+
+// ResultSchema declares the type of value that a result should have. Values are checked against the schema when the
+// result is saved and, if valid, are also stored as typed values which are what expressions like @results.age.value
+// return.
+//
+//	{
+//	  "type": "option",
+//	  "options": ["Red", "Green", "Blue"]
+//	}
+type ResultSchema struct {
+	Type    ResultType `json:"type" validate:"required,result_type"`
+	Options []string   `json:"options,omitempty"`
+}
+
+// NewResultSchema creates a new result schema
+func NewResultSchema(type_ ResultType, options []string) *ResultSchema {
+	return &ResultSchema{Type: type_, Options: options}
+}
+
+// Validate checks that this schema is valid
+func (s *ResultSchema) Validate() error {
+	if err := utils.Validate(s); err != nil {
+		return err
+	}
+	if s.Type == ResultTypeOption && len(s.Options) == 0 {
+		return errors.New("option results must have at least one option")
+	}
+	if s.Type != ResultTypeOption && len(s.Options) > 0 {
+		return errors.Errorf("%s results can't have options", s.Type)
+	}
+	return nil
+}
+
+// Coerce converts the given value to the type of this schema, returning an error if it isn't a valid value
+func (s *ResultSchema) Coerce(env envs.Environment, value string) (types.XValue, error) {
+	trimmed := strings.TrimSpace(value)
+
+	switch s.Type {
+	case ResultTypeNumber:
+		if num, xerr := types.ToXNumber(env, types.NewXText(trimmed)); xerr == nil {
+			return num, nil
+		}
+	case ResultTypeDatetime:
+		if dt, xerr := types.ToXDateTime(env, types.NewXText(trimmed)); xerr == nil {
+			return dt, nil
+		}
+	case ResultTypeOption:
+		for _, option := range s.Options {
+			if strings.EqualFold(option, trimmed) {
+				return types.NewXText(option), nil
+			}
+		}
+		return nil, errors.Errorf("'%s' isn't one of the options %s", value, strings.Join(s.Options, ", "))
+	}
+	return nil, errors.Errorf("'%s' isn't a valid %s", value, s.Type)
+}
+
+// Apply coerces the value of the given result to the type of this schema, and if that succeeds, sets the result's
+// typed value
+func (s *ResultSchema) Apply(env envs.Environment, result *Result) error {
+	typed, err := s.Coerce(env, result.Value)
+	if err != nil {
+		return errors.Wrapf(err, "invalid value for result '%s'", result.Name)
+	}
+
+	result.ValueType = s.Type
+	result.TypedValue = marshalTypedValue(typed)
+	return nil
+}
+
+func marshalTypedValue(v types.XValue) json.RawMessage {
+	if num, isNum := v.(types.XNumber); isNum {
+		return json.RawMessage(num.Native().String())
+	}
+	return jsonx.MustMarshal(v)
+}
+
+// reads a typed value of the given type from JSON
+func readTypedValue(t ResultType, data json.RawMessage) types.XValue {
+	switch t {
+	case ResultTypeNumber:
+		num := types.XNumberZero
+		if err := jsonx.Unmarshal(data, &num); err == nil {
+			return num
+		}
+	case ResultTypeDatetime:
+		dt := types.XDateTimeZero
+		if err := jsonx.Unmarshal(data, &dt); err == nil {
+			return dt
+		}
+	case ResultTypeOption:
+		var text string
+		if err := jsonx.Unmarshal(data, &text); err == nil {
+			return types.NewXText(text)
+		}
+	}
+	return nil
+}
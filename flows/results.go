@@ -5,24 +5,53 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/utils"
 )
 
+func init() {
+	resultContextKeys = RegisterContextType("result",
+		NewContextProperty("__default__", "text", "the value"),
+		NewContextProperty("name", "text", "the name of the result"),
+		NewContextProperty("value", "any", "the value of the result, typed according to the result's schema if it has one"),
+		NewHiddenContextProperty("values", "any"),
+		NewContextProperty("category", "text", "the category of the result"),
+		NewHiddenContextProperty("categories", "text"),
+		NewContextProperty("category_localized", "text", "the localized category of the result"),
+		NewHiddenContextProperty("categories_localized", "text"),
+		NewContextProperty("input", "text", "the input of the result"),
+		NewContextProperty("extra", "any", "the extra data of the result such as a webhook response"),
+		NewContextProperty("node_uuid", "text", "the UUID of the node in the flow that generated the result"),
+		NewContextProperty("created_on", "datetime", "the creation date of the result"),
+	).Keys()
+}
+
 // Result describes a value captured during a run's execution. It might have been implicitly created by a router, or explicitly
 // created by a [set_run_result](#action:set_run_result) action.
 type Result struct {
-	Name              string          `json:"name" validate:"required"`
-	Value             string          `json:"value"`
-	Category          string          `json:"category,omitempty"`
-	CategoryLocalized string          `json:"category_localized,omitempty"`
-	NodeUUID          NodeUUID        `json:"node_uuid"`
-	Input             string          `json:"input,omitempty"` // should be called operand but too late now
-	Extra             json.RawMessage `json:"extra,omitempty"`
-	CreatedOn         time.Time       `json:"created_on" validate:"required"`
+	Name              string           `json:"name" validate:"required"`
+	Value             string           `json:"value"`
+	Category          string           `json:"category,omitempty"`
+	CategoryLocalized string           `json:"category_localized,omitempty"`
+	NodeUUID          NodeUUID         `json:"node_uuid"`
+	Input             string           `json:"input,omitempty"` // should be called operand but too late now
+	Extra             json.RawMessage  `json:"extra,omitempty"`
+	ValueType         ResultType       `json:"value_type,omitempty"`
+	TypedValue        json.RawMessage  `json:"typed_value,omitempty"`
+	PII               envs.PIICategory `json:"pii,omitempty"`
+	CreatedOn         time.Time        `json:"created_on" validate:"required"`
+
+	// most extras are never read again after the result is created, so we only parse them if they're accessed in an
+	// expression, and then only once for as long as this result is loaded
+	extraOnce  sync.Once
+	extraValue types.XValue
+	typedOnce  sync.Once
+	typedValue types.XValue
 }
 
 // NewResult creates a new result
@@ -40,38 +69,95 @@ func NewResult(name string, value string, category string, categoryLocalized str
 }
 
 // Context returns the properties available in expressions
-//
-//	__default__:text -> the value
-//	name:text -> the name of the result
-//	value:text -> the value of the result
-//	category:text -> the category of the result
-//	category_localized:text -> the localized category of the result
-//	input:text -> the input of the result
-//	extra:any -> the extra data of the result such as a webhook response
-//	node_uuid:text -> the UUID of the node in the flow that generated the result
-//	created_on:datetime -> the creation date of the result
-//
-// @context result
 func (r *Result) Context(env envs.Environment) map[string]types.XValue {
-	categoryLocalized := r.CategoryLocalized
-	if categoryLocalized == "" {
-		categoryLocalized = r.Category
+	context := make(map[string]types.XValue, len(resultContextKeys))
+	for _, key := range resultContextKeys {
+		context[key] = r.ContextProperty(env, key)
 	}
+	return context
+}
 
-	return map[string]types.XValue{
-		"__default__":          types.NewXText(r.Value),
-		"name":                 types.NewXText(r.Name),
-		"value":                types.NewXText(r.Value),
-		"values":               types.NewXArray(types.NewXText(r.Value)),
-		"category":             types.NewXText(r.Category),
-		"categories":           types.NewXArray(types.NewXText(r.Category)),
-		"category_localized":   types.NewXText(categoryLocalized),
-		"categories_localized": types.NewXArray(types.NewXText(categoryLocalized)),
-		"input":                types.NewXText(r.Input),
-		"extra":                types.JSONToXValue(r.Extra),
-		"node_uuid":            types.NewXText(string(r.NodeUUID)),
-		"created_on":           types.NewXDateTime(r.CreatedOn),
+// the keys of the result context, which are those of the registered context type
+var resultContextKeys []string
+
+// ContextKeys returns the names of the properties available in expressions
+func (r *Result) ContextKeys() []string { return resultContextKeys }
+
+// ContextProperty returns a single property available in expressions, so that lookups like @results.foo.value
+// don't have to parse the result's extra
+func (r *Result) ContextProperty(env envs.Environment, key string) types.XValue {
+	switch key {
+	case "__default__":
+		return types.NewXText(r.Value)
+	case "value":
+		return r.value()
+	case "name":
+		return types.NewXText(r.Name)
+	case "values":
+		return types.NewXArray(r.value())
+	case "category":
+		return types.NewXText(r.Category)
+	case "categories":
+		return types.NewXArray(types.NewXText(r.Category))
+	case "category_localized":
+		return types.NewXText(r.categoryLocalized())
+	case "categories_localized":
+		return types.NewXArray(types.NewXText(r.categoryLocalized()))
+	case "input":
+		return types.NewXText(r.Input)
+	case "extra":
+		return r.extra()
+	case "node_uuid":
+		return types.NewXText(string(r.NodeUUID))
+	case "created_on":
+		return types.NewXDateTime(r.CreatedOn)
 	}
+	return nil
+}
+
+func (r *Result) categoryLocalized() string {
+	if r.CategoryLocalized == "" {
+		return r.Category
+	}
+	return r.CategoryLocalized
+}
+
+// gets the extra of this result as a value, parsing it the first time it's needed
+func (r *Result) extra() types.XValue {
+	r.extraOnce.Do(func() {
+		r.extraValue = types.JSONToXValue(r.Extra)
+	})
+	return r.extraValue
+}
+
+// gets the value of this result, which is its typed value if it has one
+func (r *Result) value() types.XValue {
+	r.typedOnce.Do(func() {
+		if r.ValueType != "" {
+			r.typedValue = readTypedValue(r.ValueType, r.TypedValue)
+		}
+	})
+	if r.typedValue != nil {
+		return r.typedValue
+	}
+	return types.NewXText(r.Value)
+}
+
+var _ KeyedContextable = (*Result)(nil)
+
+// UnmarshalJSON unmarshals a result from the given JSON, interning the values which are repeated across sessions
+func (r *Result) UnmarshalJSON(data []byte) error {
+	type resultEnvelope Result
+
+	if err := jsonx.Unmarshal(data, (*resultEnvelope)(r)); err != nil {
+		return err
+	}
+
+	r.Name = utils.Intern(r.Name)
+	r.Category = utils.Intern(r.Category)
+	r.CategoryLocalized = utils.Intern(r.CategoryLocalized)
+	r.NodeUUID = utils.Intern(r.NodeUUID)
+	return nil
 }
 
 // Results is our wrapper around a map of snakified result names to result objects
@@ -96,6 +182,20 @@ func (r Results) Save(result *Result) {
 	r[utils.Snakify(result.Name)] = result
 }
 
+// UnmarshalJSON unmarshals results from the given JSON, interning their keys
+func (r *Results) UnmarshalJSON(data []byte) error {
+	var results map[string]*Result
+	if err := jsonx.Unmarshal(data, &results); err != nil {
+		return err
+	}
+
+	*r = make(Results, len(results))
+	for k, v := range results {
+		(*r)[utils.Intern(k)] = v
+	}
+	return nil
+}
+
 // Get returns the result with the given key
 func (r Results) Get(key string) *Result {
 	return r[key]
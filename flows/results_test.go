@@ -4,7 +4,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent"
 	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/test"
@@ -60,3 +62,86 @@ func TestResults(t *testing.T) {
 		}),
 	}), resultsAsContext)
 }
+
+func TestResultExtra(t *testing.T) {
+	env := envs.NewBuilder().Build()
+
+	result := flows.NewResult("Webhook", "200", "Success", "", flows.NodeUUID("26493ebb-a254-4461-a28d-c7761784e276"), "", []byte(`{"foo": [1, 2]}`), time.Date(2019, 4, 5, 14, 16, 30, 123456, time.UTC))
+
+	// extra isn't needed to look up other properties
+	context := flows.Context(env, result).(*types.XObject)
+	value, _ := context.Get("category")
+	assert.Equal(t, types.NewXText("Success"), value)
+
+	extra, _ := context.Get("extra")
+	test.AssertXEqual(t, types.NewXObject(map[string]types.XValue{
+		"foo": types.NewXArray(types.NewXNumberFromInt(1), types.NewXNumberFromInt(2)),
+	}), extra)
+
+	// and is only parsed once
+	extra2, _ := flows.Context(env, result).(*types.XObject).Get("extra")
+	assert.Same(t, extra, extra2)
+
+	// invalid JSON is an error when accessed
+	result = flows.NewResult("Webhook", "200", "Success", "", flows.NodeUUID("26493ebb-a254-4461-a28d-c7761784e276"), "", []byte(`{"foo"`), time.Date(2019, 4, 5, 14, 16, 30, 123456, time.UTC))
+	extra, _ = flows.Context(env, result).(*types.XObject).Get("extra")
+	assert.Equal(t, types.NewXErrorf("invalid JSON"), extra)
+}
+
+func TestResultSchemas(t *testing.T) {
+	env := envs.NewBuilder().WithDateFormat(envs.DateFormatDayMonthYear).Build()
+
+	tcs := []struct {
+		schema   *flows.ResultSchema
+		value    string
+		expected types.XValue
+		err      string
+	}{
+		{flows.NewResultSchema(flows.ResultTypeNumber, nil), " 23 ", types.NewXNumberFromInt(23), ""},
+		{flows.NewResultSchema(flows.ResultTypeNumber, nil), "-12.5", types.RequireXNumberFromString("-12.5"), ""},
+		{flows.NewResultSchema(flows.ResultTypeNumber, nil), "twenty", nil, "invalid value for result 'Age': 'twenty' isn't a valid number"},
+		{flows.NewResultSchema(flows.ResultTypeDatetime, nil), "5/4/2019 14:16", types.NewXDateTime(time.Date(2019, 4, 5, 14, 16, 0, 0, time.UTC)), ""},
+		{flows.NewResultSchema(flows.ResultTypeDatetime, nil), "soon", nil, "invalid value for result 'Age': 'soon' isn't a valid datetime"},
+		{flows.NewResultSchema(flows.ResultTypeOption, []string{"Young", "Old"}), "old", types.NewXText("Old"), ""},
+		{flows.NewResultSchema(flows.ResultTypeOption, []string{"Young", "Old"}), "ancient", nil, "invalid value for result 'Age': 'ancient' isn't one of the options Young, Old"},
+	}
+
+	for _, tc := range tcs {
+		result := flows.NewResult("Age", tc.value, "", "", flows.NodeUUID("26493ebb-a254-4461-a28d-c7761784e276"), "", nil, time.Date(2019, 4, 5, 14, 16, 30, 123456, time.UTC))
+		err := tc.schema.Apply(env, result)
+
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err)
+			assert.Equal(t, flows.ResultType(""), result.ValueType)
+			test.AssertXEqual(t, types.NewXText(tc.value), result.ContextProperty(env, "value"))
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, tc.schema.Type, result.ValueType)
+
+			// typed value survives the result being marshaled and unmarshaled
+			read := &flows.Result{}
+			jsonx.MustUnmarshal(jsonx.MustMarshal(result), read)
+
+			test.AssertXEqual(t, tc.expected, read.ContextProperty(env, "value"), "typed value mismatch for %s", tc.value)
+			test.AssertXEqual(t, types.NewXArray(tc.expected), read.ContextProperty(env, "values"))
+			test.AssertXEqual(t, types.NewXText(tc.value), read.ContextProperty(env, "__default__"))
+		}
+	}
+
+	// typed values can be compared in expressions without casting
+	result := flows.NewResult("Age", "23", "", "", flows.NodeUUID("26493ebb-a254-4461-a28d-c7761784e276"), "", nil, time.Date(2019, 4, 5, 14, 16, 30, 123456, time.UTC))
+	assert.NoError(t, flows.NewResultSchema(flows.ResultTypeNumber, nil).Apply(env, result))
+
+	results := flows.NewResults()
+	results.Save(result)
+	ctx := types.NewXObject(map[string]types.XValue{"results": flows.Context(env, results)})
+
+	val := excellent.EvaluateExpression(env, ctx, "results.age.value > 18")
+	test.AssertXEqual(t, types.XBooleanTrue, val)
+
+	// schemas are validated
+	assert.NoError(t, flows.NewResultSchema(flows.ResultTypeNumber, nil).Validate())
+	assert.EqualError(t, flows.NewResultSchema(flows.ResultTypeOption, nil).Validate(), "option results must have at least one option")
+	assert.EqualError(t, flows.NewResultSchema(flows.ResultTypeNumber, []string{"1"}).Validate(), "number results can't have options")
+	assert.EqualError(t, flows.NewResultSchema("color", nil).Validate(), "field 'type' is not a valid result type")
+}
@@ -16,6 +16,14 @@ import (
 	"github.com/pkg/errors"
 )
 
+func init() {
+	flows.RegisterContextType("resume",
+		flows.NewContextProperty("type", "text", "the type of resume that resumed this session"),
+		flows.NewContextProperty("dial", "dial", "the outcome of the dial if this is a dial resume"),
+		flows.NewContextProperty("digits", "text", "the entered digits if this is a digits resume"),
+	)
+}
+
 // ReadFunc is a function that can read a resume from JSON
 type ReadFunc func(flows.SessionAssets, json.RawMessage, assets.MissingCallback) (flows.Resume, error)
 
@@ -36,6 +44,7 @@ type baseResume struct {
 	type_       string
 	environment envs.Environment
 	contact     *flows.Contact
+	thread      string
 	resumedOn   time.Time
 }
 
@@ -49,8 +58,12 @@ func (r *baseResume) Type() string { return r.type_ }
 
 func (r *baseResume) Environment() envs.Environment { return r.environment }
 func (r *baseResume) Contact() *flows.Contact       { return r.contact }
+func (r *baseResume) Thread() string                { return r.thread }
 func (r *baseResume) ResumedOn() time.Time          { return r.resumedOn }
 
+// SetThread sets the key of the conversation thread this resume is for, which must match that of the session
+func (r *baseResume) SetThread(thread string) { r.thread = thread }
+
 // Apply applies our state changes and saves any events to the run
 func (r *baseResume) Apply(run flows.Run, logEvent flows.EventCallback) {
 	if r.environment != nil {
@@ -82,14 +95,16 @@ func (r *baseResume) Apply(run flows.Run, logEvent flows.EventCallback) {
 
 // Context is the schema of trigger objects in the context, across all types
 type Context struct {
-	type_ string
-	dial  types.XValue
+	type_  string
+	dial   types.XValue
+	digits types.XValue
 }
 
 func (c *Context) asMap() map[string]types.XValue {
 	return map[string]types.XValue{
-		"type": types.NewXText(c.type_),
-		"dial": c.dial,
+		"type":   types.NewXText(c.type_),
+		"dial":   c.dial,
+		"digits": c.digits,
 	}
 }
 
@@ -98,10 +113,6 @@ func (r *baseResume) context() *Context {
 }
 
 // Context returns the properties available in expressions
-//
-//	type:text -> the type of resume that resumed this session
-//
-// @context resume
 func (r *baseResume) Context(env envs.Environment) map[string]types.XValue {
 	return r.context().asMap()
 }
@@ -114,6 +125,7 @@ type baseResumeEnvelope struct {
 	Type        string          `json:"type" validate:"required"`
 	Environment json.RawMessage `json:"environment,omitempty"`
 	Contact     json.RawMessage `json:"contact,omitempty"`
+	Thread      string          `json:"thread,omitempty"`
 	ResumedOn   time.Time       `json:"resumed_on" validate:"required"`
 }
 
@@ -135,6 +147,7 @@ func (r *baseResume) unmarshal(sessionAssets flows.SessionAssets, e *baseResumeE
 	var err error
 
 	r.type_ = e.Type
+	r.thread = e.Thread
 	r.resumedOn = e.ResumedOn
 
 	if e.Environment != nil {
@@ -153,6 +166,7 @@ func (r *baseResume) unmarshal(sessionAssets flows.SessionAssets, e *baseResumeE
 func (r *baseResume) marshal(e *baseResumeEnvelope) error {
 	var err error
 	e.Type = r.type_
+	e.Thread = r.thread
 	e.ResumedOn = r.resumedOn
 
 	if r.environment != nil {
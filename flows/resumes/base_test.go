@@ -1,6 +1,7 @@
 package resumes_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -104,12 +105,12 @@ func testResumeType(t *testing.T, assetsJSON json.RawMessage, typeName string) {
 			tb = tb.WithCall(channel.Reference(), urns.URN("tel:+12065551212"))
 		}
 		trigger := tb.Build()
-		session, _, err := eng.NewSession(sa, trigger)
+		session, _, err := eng.NewSession(context.Background(), sa, trigger)
 		require.NoError(t, err)
 		require.Equal(t, flows.SessionStatusWaiting, session.Status())
 
 		// resume with our resume...
-		sprint, err := session.Resume(resume)
+		sprint, err := session.Resume(context.Background(), resume)
 
 		actual := tc
 		actual.Resume = jsonx.MustMarshal(resume) // re-marshal the resume
@@ -178,8 +179,9 @@ func TestResumeContext(t *testing.T) {
 	)
 
 	assert.Equal(t, map[string]types.XValue{
-		"type": types.NewXText("msg"),
-		"dial": nil,
+		"type":   types.NewXText("msg"),
+		"dial":   nil,
+		"digits": nil,
 	}, resume.Context(env))
 
 	resume = resumes.NewDial(env, nil, flows.NewDial(flows.DialStatusNoAnswer, 5))
@@ -187,4 +189,10 @@ func TestResumeContext(t *testing.T) {
 
 	assert.Equal(t, types.NewXText("dial"), context["type"])
 	assert.NotNil(t, context["dial"])
+
+	resume = resumes.NewDigits(env, nil, "1234")
+	context = resume.Context(env)
+
+	assert.Equal(t, types.NewXText("digits"), context["type"])
+	assert.Equal(t, types.NewXText("1234"), context["digits"])
 }
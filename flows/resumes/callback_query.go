@@ -0,0 +1,103 @@
+package resumes
+
+import (
+	"encoding/json"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/inputs"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeCallbackQuery, readCallbackQueryResume)
+}
+
+// TypeCallbackQuery is the type for resuming a session with a callback query
+const TypeCallbackQuery string = "callback_query"
+
+// CallbackQueryResume is used when a session is resumed because the contact pressed an inline button, e.g. on Telegram.
+// The input is a message without text, so that routers can match on `@input.callback_data` without the data of a
+// button being confused with a message the contact typed.
+//
+//	{
+//	  "type": "callback_query",
+//	  "query": {
+//	    "uuid": "2d611e17-fb22-457f-b802-b8f7ec5cda5b",
+//	    "channel": {"uuid": "57f1078f-88aa-46f4-a59a-948a5739c03d", "name": "Telegram"},
+//	    "urn": "telegram:12345",
+//	    "data": "ready"
+//	  },
+//	  "resumed_on": "2000-01-01T00:00:00.000000000-00:00"
+//	}
+//
+// @resume callback_query
+type CallbackQueryResume struct {
+	baseResume
+
+	query *flows.CallbackQuery
+}
+
+// NewCallbackQuery creates a new callback query resume with the passed in values
+func NewCallbackQuery(env envs.Environment, contact *flows.Contact, query *flows.CallbackQuery) *CallbackQueryResume {
+	return &CallbackQueryResume{
+		baseResume: newBaseResume(TypeCallbackQuery, env, contact),
+		query:      query,
+	}
+}
+
+// Query returns the callback query this resume is based on
+func (r *CallbackQueryResume) Query() *flows.CallbackQuery { return r.query }
+
+// Apply applies our state changes and saves any events to the run
+func (r *CallbackQueryResume) Apply(run flows.Run, logEvent flows.EventCallback) {
+	r.baseResume.Apply(run, logEvent)
+
+	msg := r.query.Msg()
+	input := inputs.NewMsg(run.Session().Assets(), msg, r.ResumedOn(), run.Flow().InputProcessing())
+
+	run.Session().SetInput(input)
+
+	logEvent(events.NewMsgReceived(msg))
+}
+
+var _ flows.Resume = (*CallbackQueryResume)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type callbackQueryResumeEnvelope struct {
+	baseResumeEnvelope
+
+	Query *flows.CallbackQuery `json:"query" validate:"required,dive"`
+}
+
+func readCallbackQueryResume(sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Resume, error) {
+	e := &callbackQueryResumeEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	r := &CallbackQueryResume{query: e.Query}
+
+	if err := r.unmarshal(sessionAssets, &e.baseResumeEnvelope, missing); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// MarshalJSON marshals this resume into JSON
+func (r *CallbackQueryResume) MarshalJSON() ([]byte, error) {
+	e := &callbackQueryResumeEnvelope{Query: r.query}
+
+	if err := r.marshal(&e.baseResumeEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
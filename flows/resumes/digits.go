@@ -0,0 +1,99 @@
+package resumes
+
+import (
+	"encoding/json"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeDigits, readDigitsResume)
+}
+
+// TypeDigits is the type for digits resumes
+const TypeDigits string = "digits"
+
+// DigitsResume is used when a session is resumed with digits entered on the contact's dial pad. The digits don't
+// include the finish key if one was pressed.
+//
+//	{
+//	  "type": "digits",
+//	  "resumed_on": "2021-01-20T12:18:30Z",
+//	  "digits": "1234"
+//	}
+//
+// @resume digits
+type DigitsResume struct {
+	baseResume
+
+	digits string
+}
+
+// NewDigits creates a new digits resume
+func NewDigits(env envs.Environment, contact *flows.Contact, digits string) *DigitsResume {
+	return &DigitsResume{
+		baseResume: newBaseResume(TypeDigits, env, contact),
+		digits:     digits,
+	}
+}
+
+// Digits returns the digits that were entered
+func (r *DigitsResume) Digits() string { return r.digits }
+
+// Apply applies our state changes and saves any events to the run
+func (r *DigitsResume) Apply(run flows.Run, logEvent flows.EventCallback) {
+	logEvent(events.NewDigitsReceived(r.digits))
+
+	r.baseResume.Apply(run, logEvent)
+}
+
+// Context for digits resumes additionally exposes the entered digits
+func (r *DigitsResume) Context(env envs.Environment) map[string]types.XValue {
+	c := r.context()
+	c.digits = types.NewXText(r.digits)
+	return c.asMap()
+}
+
+var _ flows.Resume = (*DigitsResume)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type digitsResumeEnvelope struct {
+	baseResumeEnvelope
+
+	Digits string `json:"digits" validate:"max=100"`
+}
+
+func readDigitsResume(sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Resume, error) {
+	e := &digitsResumeEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	r := &DigitsResume{digits: e.Digits}
+
+	if err := r.unmarshal(sessionAssets, &e.baseResumeEnvelope, missing); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// MarshalJSON marshals this resume into JSON
+func (r *DigitsResume) MarshalJSON() ([]byte, error) {
+	e := &digitsResumeEnvelope{Digits: r.digits}
+
+	if err := r.marshal(&e.baseResumeEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
@@ -0,0 +1,105 @@
+package resumes
+
+import (
+	"encoding/json"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/inputs"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeEmail, readEmailResume)
+}
+
+// TypeEmail is the type for resuming a session with an email
+const TypeEmail string = "email"
+
+// EmailResume is used when a session is resumed with a new email from the contact
+//
+//	{
+//	  "type": "email",
+//	  "contact": {
+//	    "uuid": "9f7ede93-4b16-4692-80ad-b7dc54a1cd81",
+//	    "name": "Bob",
+//	    "created_on": "2018-01-01T12:00:00.000000Z",
+//	    "language": "fra",
+//	    "fields": {"gender": {"text": "Male"}},
+//	    "groups": []
+//	  },
+//	  "email": {
+//	    "uuid": "2d611e17-fb22-457f-b802-b8f7ec5cda5b",
+//	    "from": "bob@nyaruka.com",
+//	    "subject": "Re: Your order",
+//	    "body": "Yes please",
+//	    "html_body": "<p>Yes please</p>"
+//	  },
+//	  "resumed_on": "2000-01-01T00:00:00.000000000-00:00"
+//	}
+//
+// @resume email
+type EmailResume struct {
+	baseResume
+	email *flows.EmailIn
+}
+
+// NewEmail creates a new email resume with the passed in values
+func NewEmail(env envs.Environment, contact *flows.Contact, email *flows.EmailIn) *EmailResume {
+	return &EmailResume{
+		baseResume: newBaseResume(TypeEmail, env, contact),
+		email:      email,
+	}
+}
+
+// Email returns the email this resume is based on
+func (r *EmailResume) Email() *flows.EmailIn { return r.email }
+
+// Apply applies our state changes and saves any events to the run
+func (r *EmailResume) Apply(run flows.Run, logEvent flows.EventCallback) {
+	r.baseResume.Apply(run, logEvent)
+
+	run.Session().SetInput(inputs.NewEmail(r.email, r.ResumedOn(), run.Flow().InputProcessing()))
+
+	logEvent(events.NewEmailReceived(r.email))
+}
+
+var _ flows.Resume = (*EmailResume)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type emailResumeEnvelope struct {
+	baseResumeEnvelope
+	Email *flows.EmailIn `json:"email" validate:"required,dive"`
+}
+
+func readEmailResume(sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Resume, error) {
+	e := &emailResumeEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	r := &EmailResume{email: e.Email}
+
+	if err := r.unmarshal(sessionAssets, &e.baseResumeEnvelope, missing); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// MarshalJSON marshals this resume into JSON
+func (r *EmailResume) MarshalJSON() ([]byte, error) {
+	e := &emailResumeEnvelope{Email: r.email}
+
+	if err := r.marshal(&e.baseResumeEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
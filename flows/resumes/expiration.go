@@ -0,0 +1,102 @@
+package resumes
+
+import (
+	"encoding/json"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeExpiration, readExpirationResume)
+}
+
+// TypeExpiration is the type for resuming a session when the waiting run has reached its expires_on
+const TypeExpiration string = "expiration"
+
+// ExpirationResume is used when a session is resumed because the waiting run has reached its expires_on. If the
+// router of the node it's waiting at has an expiration category, the run continues through that category's exit,
+// otherwise the run is exited as expired.
+//
+//	{
+//	  "type": "expiration",
+//	  "contact": {
+//	    "uuid": "9f7ede93-4b16-4692-80ad-b7dc54a1cd81",
+//	    "name": "Bob",
+//	    "created_on": "2018-01-01T12:00:00.000000Z",
+//	    "language": "fra",
+//	    "fields": {"gender": {"text": "Male"}},
+//	    "groups": []
+//	  },
+//	  "resumed_on": "2000-01-01T00:00:00.000000000-00:00"
+//	}
+//
+// @resume expiration
+type ExpirationResume struct {
+	baseResume
+}
+
+// NewExpiration creates a new expiration resume with the passed in values
+func NewExpiration(env envs.Environment, contact *flows.Contact) *ExpirationResume {
+	return &ExpirationResume{
+		baseResume: newBaseResume(TypeExpiration, env, contact),
+	}
+}
+
+// Apply applies our state changes and saves any events to the run
+func (r *ExpirationResume) Apply(run flows.Run, logEvent flows.EventCallback) {
+	if !canRouteExpiration(run) {
+		run.Exit(flows.RunStatusExpired)
+	}
+
+	logEvent(events.NewRunExpired(run))
+
+	r.baseResume.Apply(run, logEvent)
+}
+
+// a run can continue after expiring if it's waiting at a router with an expiration category, and not for a callback
+func canRouteExpiration(run flows.Run) bool {
+	runEvents := run.Events()
+	if len(runEvents) > 0 && runEvents[len(runEvents)-1].Type() == events.TypeCallbackWait {
+		return false
+	}
+
+	_, node, err := run.PathLocation()
+	return err == nil && node.Router() != nil && node.Router().AllowExpiration()
+}
+
+var _ flows.Resume = (*ExpirationResume)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+func readExpirationResume(sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Resume, error) {
+	e := &baseResumeEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	r := &ExpirationResume{}
+
+	if err := r.unmarshal(sessionAssets, e, missing); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// MarshalJSON marshals this resume into JSON
+func (r *ExpirationResume) MarshalJSON() ([]byte, error) {
+	e := &baseResumeEnvelope{}
+
+	if err := r.marshal(e); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
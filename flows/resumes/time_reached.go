@@ -0,0 +1,78 @@
+package resumes
+
+import (
+	"encoding/json"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeTimeReached, readTimeReachedResume)
+}
+
+// TypeTimeReached is the type for resuming a session when the time it was waiting for has been reached
+const TypeTimeReached string = "time_reached"
+
+// TimeReachedResume is used when a session waiting at a time wait is resumed because the time it was scheduled to
+// resume at has been reached.
+//
+//	{
+//	  "type": "time_reached",
+//	  "contact": {
+//	    "uuid": "9f7ede93-4b16-4692-80ad-b7dc54a1cd81",
+//	    "name": "Bob",
+//	    "created_on": "2018-01-01T12:00:00.000000Z",
+//	    "language": "fra",
+//	    "fields": {"gender": {"text": "Male"}},
+//	    "groups": []
+//	  },
+//	  "resumed_on": "2000-01-01T00:00:00.000000000-00:00"
+//	}
+//
+// @resume time_reached
+type TimeReachedResume struct {
+	baseResume
+}
+
+// NewTimeReached creates a new time reached resume with the passed in values
+func NewTimeReached(env envs.Environment, contact *flows.Contact) *TimeReachedResume {
+	return &TimeReachedResume{
+		baseResume: newBaseResume(TypeTimeReached, env, contact),
+	}
+}
+
+var _ flows.Resume = (*TimeReachedResume)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+func readTimeReachedResume(sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Resume, error) {
+	e := &baseResumeEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	r := &TimeReachedResume{}
+
+	if err := r.unmarshal(sessionAssets, e, missing); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// MarshalJSON marshals this resume into JSON
+func (r *TimeReachedResume) MarshalJSON() ([]byte, error) {
+	e := &baseResumeEnvelope{}
+
+	if err := r.marshal(e); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
@@ -0,0 +1,91 @@
+package resumes
+
+import (
+	"encoding/json"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeTimerFired, readTimerFiredResume)
+}
+
+// TypeTimerFired is the type for resuming a session when a timer has fired
+const TypeTimerFired string = "timer_fired"
+
+// TimerFiredResume is used when a session is resumed because a timer set by the waiting run has fired. Rather than
+// being routed by its wait, the run continues at the node the timer was set for.
+//
+//	{
+//	  "type": "timer_fired",
+//	  "resumed_on": "2000-01-01T00:00:00.000000000-00:00",
+//	  "timer": "followup"
+//	}
+//
+// @resume timer_fired
+type TimerFiredResume struct {
+	baseResume
+
+	timer string
+}
+
+// NewTimerFired creates a new timer fired resume for the timer with the given name
+func NewTimerFired(env envs.Environment, contact *flows.Contact, timer string) *TimerFiredResume {
+	return &TimerFiredResume{
+		baseResume: newBaseResume(TypeTimerFired, env, contact),
+		timer:      timer,
+	}
+}
+
+// Timer returns the name of the timer which fired
+func (r *TimerFiredResume) Timer() string { return r.timer }
+
+// Apply applies our state changes and saves any events to the run
+func (r *TimerFiredResume) Apply(run flows.Run, logEvent flows.EventCallback) {
+	logEvent(events.NewTimerFired(r.timer))
+
+	r.baseResume.Apply(run, logEvent)
+}
+
+var _ flows.Resume = (*TimerFiredResume)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type timerFiredResumeEnvelope struct {
+	baseResumeEnvelope
+
+	Timer string `json:"timer" validate:"required"`
+}
+
+func readTimerFiredResume(sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Resume, error) {
+	e := &timerFiredResumeEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	r := &TimerFiredResume{timer: e.Timer}
+
+	if err := r.unmarshal(sessionAssets, &e.baseResumeEnvelope, missing); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// MarshalJSON marshals this resume into JSON
+func (r *TimerFiredResume) MarshalJSON() ([]byte, error) {
+	e := &timerFiredResumeEnvelope{Timer: r.timer}
+
+	if err := r.marshal(&e.baseResumeEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
@@ -0,0 +1,88 @@
+package resumes
+
+import (
+	"encoding/json"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeWebhookCallback, readWebhookCallbackResume)
+}
+
+// TypeWebhookCallback is the type for resuming a session with the response to an asynchronous webhook request
+const TypeWebhookCallback string = "webhook_callback"
+
+// WebhookCallbackResume is used when a session is resumed with the response to a webhook request which the caller made
+// on behalf of the engine after a [event:webhook_requested] event. A status code of zero means the caller couldn't
+// connect.
+//
+//	{
+//	  "type": "webhook_callback",
+//	  "callback": {
+//	    "request_uuid": "b7c7a6d4-4e3f-4d8a-9b0e-2f5c1a8d9e7f",
+//	    "status_code": 200,
+//	    "body": "{\"ok\": true}"
+//	  },
+//	  "resumed_on": "2000-01-01T00:00:00.000000000-00:00"
+//	}
+//
+// @resume webhook_callback
+type WebhookCallbackResume struct {
+	baseResume
+
+	callback *flows.WebhookCallback
+}
+
+// NewWebhookCallback creates a new webhook callback resume with the passed in values
+func NewWebhookCallback(env envs.Environment, contact *flows.Contact, callback *flows.WebhookCallback) *WebhookCallbackResume {
+	return &WebhookCallbackResume{
+		baseResume: newBaseResume(TypeWebhookCallback, env, contact),
+		callback:   callback,
+	}
+}
+
+// Callback returns the response this resume is delivering
+func (r *WebhookCallbackResume) Callback() *flows.WebhookCallback { return r.callback }
+
+var _ flows.Resume = (*WebhookCallbackResume)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type webhookCallbackResumeEnvelope struct {
+	baseResumeEnvelope
+
+	Callback *flows.WebhookCallback `json:"callback" validate:"required"`
+}
+
+func readWebhookCallbackResume(sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Resume, error) {
+	e := &webhookCallbackResumeEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	r := &WebhookCallbackResume{callback: e.Callback}
+
+	if err := r.unmarshal(sessionAssets, &e.baseResumeEnvelope, missing); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// MarshalJSON marshals this resume into JSON
+func (r *WebhookCallbackResume) MarshalJSON() ([]byte, error) {
+	e := &webhookCallbackResumeEnvelope{Callback: r.callback}
+
+	if err := r.marshal(&e.baseResumeEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
@@ -0,0 +1,133 @@
+package resumes
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeWhatsAppFlow, readWhatsAppFlowResume)
+}
+
+// TypeWhatsAppFlow is the type for resuming a session with a WhatsApp Flow form submission
+const TypeWhatsAppFlow string = "whatsapp_flow"
+
+// WhatsAppFlowResume is used when a session is resumed because the contact submitted a WhatsApp Flow form. The flow
+// token identifies the form that was sent, and any submitted fields in the results mapping of that form are saved as
+// results.
+//
+//	{
+//	  "type": "whatsapp_flow",
+//	  "response": {
+//	    "flow_token": "2d611e17-fb22-457f-b802-b8f7ec5cda5b",
+//	    "fields": {"age": "32", "email": "bob@nyaruka.com"}
+//	  },
+//	  "resumed_on": "2000-01-01T00:00:00.000000000-00:00"
+//	}
+//
+// @resume whatsapp_flow
+type WhatsAppFlowResume struct {
+	baseResume
+
+	response *flows.WhatsAppFlowResponse
+}
+
+// NewWhatsAppFlow creates a new WhatsApp Flow resume with the passed in values
+func NewWhatsAppFlow(env envs.Environment, contact *flows.Contact, response *flows.WhatsAppFlowResponse) *WhatsAppFlowResume {
+	return &WhatsAppFlowResume{
+		baseResume: newBaseResume(TypeWhatsAppFlow, env, contact),
+		response:   response,
+	}
+}
+
+// Response returns the form submission this resume is based on
+func (r *WhatsAppFlowResume) Response() *flows.WhatsAppFlowResponse { return r.response }
+
+// Apply applies our state changes and saves any events to the run
+func (r *WhatsAppFlowResume) Apply(run flows.Run, logEvent flows.EventCallback) {
+	r.baseResume.Apply(run, logEvent)
+
+	created, step := findWhatsAppFlow(run, r.response.FlowToken)
+	if created == nil {
+		logEvent(events.NewErrorf("no WhatsApp flow found with token '%s'", r.response.FlowToken))
+		return
+	}
+
+	fields := make([]string, 0, len(created.Flow.Results))
+	for field := range created.Flow.Results {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		value, submitted := r.response.Fields[field]
+		if !submitted {
+			continue
+		}
+
+		result := flows.NewResult(created.Flow.Results[field], value, "", "", step.NodeUUID(), value, nil, run.Session().Sources().Now())
+		run.SaveResult(result)
+		logEvent(events.NewRunResultChanged(result))
+	}
+}
+
+// finds the event and step where the WhatsApp flow with the given token was sent
+func findWhatsAppFlow(run flows.Run, token string) (*events.WhatsAppFlowCreatedEvent, flows.Step) {
+	runEvents := run.Events()
+	for i := len(runEvents) - 1; i >= 0; i-- {
+		created, isCreated := runEvents[i].(*events.WhatsAppFlowCreatedEvent)
+		if isCreated && created.Flow.FlowToken == token {
+			for _, step := range run.Path() {
+				if step.UUID() == created.StepUUID() {
+					return created, step
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+var _ flows.Resume = (*WhatsAppFlowResume)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type whatsAppFlowResumeEnvelope struct {
+	baseResumeEnvelope
+
+	Response *flows.WhatsAppFlowResponse `json:"response" validate:"required,dive"`
+}
+
+func readWhatsAppFlowResume(sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Resume, error) {
+	e := &whatsAppFlowResumeEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	r := &WhatsAppFlowResume{response: e.Response}
+
+	if err := r.unmarshal(sessionAssets, &e.baseResumeEnvelope, missing); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// MarshalJSON marshals this resume into JSON
+func (r *WhatsAppFlowResume) MarshalJSON() ([]byte, error) {
+	e := &whatsAppFlowResumeEnvelope{Response: r.response}
+
+	if err := r.marshal(&e.baseResumeEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
@@ -3,6 +3,7 @@ package routers
 import (
 	"encoding/json"
 	"time"
+	"unicode/utf8"
 
 	"github.com/nyaruka/gocommon/dates"
 	"github.com/nyaruka/gocommon/jsonx"
@@ -38,10 +39,16 @@ func RegisteredTypes() []string {
 
 // baseRouter is the base class for all router types
 type baseRouter struct {
-	type_      string
-	wait       flows.Wait
-	resultName string
-	categories []flows.Category
+	type_        string
+	wait         flows.Wait
+	resultName   string
+	resultSchema *flows.ResultSchema
+	resultPII    envs.PIICategory
+	categories   []flows.Category
+
+	expirationCategoryUUID     flows.CategoryUUID
+	duplicateInputCategoryUUID flows.CategoryUUID
+	invalidInputCategoryUUID   flows.CategoryUUID
 }
 
 // creates a new base router
@@ -63,11 +70,36 @@ func (r *baseRouter) AllowTimeout() bool {
 	return r.wait != nil && !utils.IsNil(r.wait.Timeout())
 }
 
+// AllowExpiration returns whether this router can be resumed at with an expiration which it routes through a category
+func (r *baseRouter) AllowExpiration() bool {
+	return r.wait != nil && r.expirationCategoryUUID != ""
+}
+
+// AllowDuplicateInput returns whether this router can route messages which duplicate the last message to a category
+func (r *baseRouter) AllowDuplicateInput() bool {
+	return r.wait != nil && r.duplicateInputCategoryUUID != ""
+}
+
+// AllowInvalidInput returns whether this router can route input which its wait found invalid to a category
+func (r *baseRouter) AllowInvalidInput() bool {
+	return r.wait != nil && r.invalidInputCategoryUUID != ""
+}
+
 // ResultName returns the name which the result of this router should be saved as (if any)
 func (r *baseRouter) ResultName() string { return r.resultName }
 
+// ResultSchema returns the declared type of the result of this router (if any)
+func (r *baseRouter) ResultSchema() *flows.ResultSchema { return r.resultSchema }
+
+// ResultPII returns the PII category of the result of this router (if any)
+func (r *baseRouter) ResultPII() envs.PIICategory { return r.resultPII }
+
 // EnumerateTemplates enumerates all expressions on this object and its children
 func (r *baseRouter) EnumerateTemplates(localization flows.Localization, include func(envs.Language, string)) {
+	if msgWait, isMsg := r.wait.(*waits.MsgWait); isMsg && msgWait.Validation() != nil {
+		include(envs.NilLanguage, msgWait.Validation().Test)
+		include(envs.NilLanguage, msgWait.Validation().Message)
+	}
 }
 
 // EnumerateDependencies enumerates all dependencies on this object
@@ -97,11 +129,62 @@ func (r *baseRouter) EnumerateLocalizables(include func(uuids.UUID, string, []st
 }
 
 func (r *baseRouter) validate(flow flows.Flow, exits []flows.Exit) error {
+	if r.resultSchema != nil {
+		if r.resultName == "" {
+			return errors.New("result schema can't be set without a result name")
+		}
+		if err := r.resultSchema.Validate(); err != nil {
+			return errors.Wrap(err, "invalid result schema")
+		}
+	}
+	if r.resultPII != envs.NilPIICategory && r.resultName == "" {
+		return errors.New("result PII category can't be set without a result name")
+	}
+
 	// check wait timeout category is valid
 	if r.AllowTimeout() && !r.isValidCategory(r.wait.Timeout().CategoryUUID()) {
 		return errors.Errorf("timeout category %s is not a valid category", r.wait.Timeout().CategoryUUID())
 	}
 
+	// check expiration category is valid
+	if r.expirationCategoryUUID != "" {
+		if r.wait == nil {
+			return errors.New("expiration category can't be set on a router without a wait")
+		}
+		if !r.isValidCategory(r.expirationCategoryUUID) {
+			return errors.Errorf("expiration category %s is not a valid category", r.expirationCategoryUUID)
+		}
+	}
+
+	// check duplicate input category is valid
+	if r.duplicateInputCategoryUUID != "" {
+		if r.wait == nil {
+			return errors.New("duplicate input category can't be set on a router without a wait")
+		}
+		if !r.isValidCategory(r.duplicateInputCategoryUUID) {
+			return errors.Errorf("duplicate input category %s is not a valid category", r.duplicateInputCategoryUUID)
+		}
+	}
+
+	// check invalid input category is valid
+	if r.invalidInputCategoryUUID != "" {
+		if msgWait, isMsg := r.wait.(*waits.MsgWait); !isMsg || msgWait.Validation() == nil {
+			return errors.New("invalid input category can't be set on a router without a validating wait")
+		}
+		if !r.isValidCategory(r.invalidInputCategoryUUID) {
+			return errors.Errorf("invalid input category %s is not a valid category", r.invalidInputCategoryUUID)
+		}
+	}
+
+	// check race wait categories are valid
+	if race, isRace := r.wait.(*waits.RaceWait); isRace {
+		for _, b := range race.Branches() {
+			if b.CategoryUUID() != "" && !r.isValidCategory(b.CategoryUUID()) {
+				return errors.Errorf("race category %s is not a valid category", b.CategoryUUID())
+			}
+		}
+	}
+
 	// check each category points to a valid exit
 	for _, c := range r.categories {
 		if c.ExitUUID() != "" && !r.isValidExit(c.ExitUUID(), exits) {
@@ -155,6 +238,50 @@ func (r *baseRouter) RouteTimeout(run flows.Run, step flows.Step, logEvent flows
 	return r.routeToCategory(run, step, r.wait.Timeout().CategoryUUID(), dates.FormatISO(timedOutOn), "", nil, logEvent)
 }
 
+// RouteExpiration routes in the case that the run expired whilst waiting at this router
+func (r *baseRouter) RouteExpiration(run flows.Run, step flows.Step, logEvent flows.EventCallback) (flows.ExitUUID, error) {
+	if !r.AllowExpiration() {
+		return "", errors.New("can't call route expiration on router with no expiration category")
+	}
+
+	return r.routeToCategory(run, step, r.expirationCategoryUUID, dates.FormatISO(run.Session().Sources().Now()), "", nil, logEvent)
+}
+
+// RouteDuplicateInput routes in the case that the run was resumed with a message which duplicates the last message
+func (r *baseRouter) RouteDuplicateInput(run flows.Run, step flows.Step, logEvent flows.EventCallback) (flows.ExitUUID, error) {
+	if !r.AllowDuplicateInput() {
+		return "", errors.New("can't call route duplicate input on router with no duplicate input category")
+	}
+
+	// use the text of the duplicate message as the match
+	return r.routeToCategory(run, step, r.duplicateInputCategoryUUID, lastReceivedText(run), "", nil, logEvent)
+}
+
+// RouteInvalidInput routes in the case that the contact has used up their attempts to give input which the wait finds valid
+func (r *baseRouter) RouteInvalidInput(run flows.Run, step flows.Step, logEvent flows.EventCallback) (flows.ExitUUID, error) {
+	if !r.AllowInvalidInput() {
+		return "", errors.New("can't call route invalid input on router with no invalid input category")
+	}
+
+	return r.routeToCategory(run, step, r.invalidInputCategoryUUID, lastReceivedText(run), "", nil, logEvent)
+}
+
+// gets the text of the last message received in the given run
+func lastReceivedText(run flows.Run) string {
+	runEvents := run.Events()
+	for i := len(runEvents) - 1; i >= 0; i-- {
+		if received, isReceived := runEvents[i].(*events.MsgReceivedEvent); isReceived {
+			return received.Msg.Text()
+		}
+	}
+	return ""
+}
+
+// RouteRace routes in the case that this router's wait is a race which was won by a wait with its own category
+func (r *baseRouter) RouteRace(run flows.Run, step flows.Step, categoryUUID flows.CategoryUUID, logEvent flows.EventCallback) (flows.ExitUUID, error) {
+	return r.routeToCategory(run, step, categoryUUID, run.Session().CurrentResume().Type(), "", nil, logEvent)
+}
+
 func (r *baseRouter) routeToCategory(run flows.Run, step flows.Step, categoryUUID flows.CategoryUUID, match string, operand string, extra *types.XObject, logEvent flows.EventCallback) (flows.ExitUUID, error) {
 	// router failed to pick a category
 	if categoryUUID == "" {
@@ -183,7 +310,17 @@ func (r *baseRouter) routeToCategory(run flows.Run, step flows.Step, categoryUUI
 		if extra != nil {
 			extraJSON, _ = jsonx.Marshal(extra)
 		}
-		result := flows.NewResult(r.resultName, match, category.Name(), localizedCategory, step.NodeUUID(), operand, extraJSON, dates.Now())
+		if limit := run.Environment().TruncationPolicy().ResultValue; utf8.RuneCountInString(match) > limit {
+			logEvent(events.NewValueTruncated(events.TruncationTargetResult, r.resultName, utf8.RuneCountInString(match), limit))
+		}
+
+		result := flows.NewResult(r.resultName, match, category.Name(), localizedCategory, step.NodeUUID(), operand, extraJSON, run.Session().Sources().Now())
+		if r.resultSchema != nil {
+			if err := r.resultSchema.Apply(run.Environment(), result); err != nil {
+				logEvent(events.NewError(err))
+			}
+		}
+		result.PII = r.resultPII
 		run.SaveResult(result)
 		logEvent(events.NewRunResultChanged(result))
 	}
@@ -196,10 +333,16 @@ func (r *baseRouter) routeToCategory(run flows.Run, step flows.Step, categoryUUI
 //------------------------------------------------------------------------------------------
 
 type baseRouterEnvelope struct {
-	Type       string            `json:"type"                  validate:"required"`
-	Wait       json.RawMessage   `json:"wait,omitempty"`
-	ResultName string            `json:"result_name,omitempty"`
-	Categories []json.RawMessage `json:"categories,omitempty"  validate:"required,min=1"`
+	Type         string              `json:"type"                  validate:"required"`
+	Wait         json.RawMessage     `json:"wait,omitempty"`
+	ResultName   string              `json:"result_name,omitempty"`
+	ResultSchema *flows.ResultSchema `json:"result_schema,omitempty"`
+	ResultPII    envs.PIICategory    `json:"result_pii,omitempty"     validate:"omitempty,pii_category"`
+	Categories   []json.RawMessage   `json:"categories,omitempty"  validate:"required,min=1"`
+
+	ExpirationCategoryUUID     flows.CategoryUUID `json:"expiration_category_uuid,omitempty" validate:"omitempty,uuid4"`
+	DuplicateInputCategoryUUID flows.CategoryUUID `json:"duplicate_input_category_uuid,omitempty" validate:"omitempty,uuid4"`
+	InvalidInputCategoryUUID   flows.CategoryUUID `json:"invalid_input_category_uuid,omitempty" validate:"omitempty,uuid4"`
 }
 
 // ReadRouter reads a router from the given JSON
@@ -222,6 +365,11 @@ func (r *baseRouter) unmarshal(e *baseRouterEnvelope) error {
 
 	r.type_ = e.Type
 	r.resultName = e.ResultName
+	r.resultSchema = e.ResultSchema
+	r.resultPII = e.ResultPII
+	r.expirationCategoryUUID = e.ExpirationCategoryUUID
+	r.duplicateInputCategoryUUID = e.DuplicateInputCategoryUUID
+	r.invalidInputCategoryUUID = e.InvalidInputCategoryUUID
 	r.categories = make([]flows.Category, len(e.Categories))
 
 	for i, c := range e.Categories {
@@ -246,6 +394,11 @@ func (r *baseRouter) marshal(e *baseRouterEnvelope) error {
 
 	e.Type = r.type_
 	e.ResultName = r.resultName
+	e.ResultSchema = r.resultSchema
+	e.ResultPII = r.resultPII
+	e.ExpirationCategoryUUID = r.expirationCategoryUUID
+	e.DuplicateInputCategoryUUID = r.duplicateInputCategoryUUID
+	e.InvalidInputCategoryUUID = r.invalidInputCategoryUUID
 	e.Categories = make([]json.RawMessage, len(r.categories))
 
 	for i, c := range r.categories {
@@ -1,6 +1,7 @@
 package routers_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -73,7 +74,7 @@ func testRouterType(t *testing.T, assetsJSON json.RawMessage, typeName string) {
 
 	for i, tc := range tests {
 		dates.SetNowSource(dates.NewFixedNowSource(time.Date(2018, 10, 18, 14, 20, 30, 123456, time.UTC)))
-		uuids.SetGenerator(uuids.NewSeededGenerator(12345))
+		uuids.SetGenerator(test.NewSeededUUIDGenerator(12345))
 		random.SetGenerator(random.NewSeededGenerator(123456))
 
 		testName := fmt.Sprintf("test '%s' for router type '%s'", tc.Description, typeName)
@@ -103,7 +104,7 @@ func testRouterType(t *testing.T, assetsJSON json.RawMessage, typeName string) {
 		trigger := triggers.NewBuilder(envs.NewBuilder().Build(), flow.Reference(false), contact).Manual().Build()
 
 		eng := test.NewEngine()
-		session, _, err := eng.NewSession(sa, trigger)
+		session, _, err := eng.NewSession(context.Background(), sa, trigger)
 		require.NoError(t, err)
 
 		// clone test case and populate with actual values
@@ -0,0 +1,185 @@
+package cases
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nyaruka/goflow/excellent"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+
+	"github.com/pkg/errors"
+)
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// the kinds of value that router test arguments can take
+type argKind int
+
+const (
+	argText argKind = iota
+	argNumber
+	argDuration
+	argUUID
+)
+
+// describes the arguments that a router test takes, not including the operand. Dates aren't checked as parsing them
+// depends on the environment, and regexes aren't checked as invalid ones are reported as issues.
+type argsSpec struct {
+	min   int
+	max   int       // -1 for no maximum
+	kinds []argKind // the last kind applies to any further arguments
+}
+
+func newArgsSpec(min, max int, kinds ...argKind) *argsSpec {
+	return &argsSpec{min: min, max: max, kinds: kinds}
+}
+
+var noArgs = newArgsSpec(0, 0)
+var oneText = newArgsSpec(1, 1, argText)
+var oneNumber = newArgsSpec(1, 1, argNumber)
+
+// the arguments taken by each builtin router test
+var xtestArgs = map[string]*argsSpec{
+	"has_error": noArgs,
+
+	"has_only_text":   oneText,
+	"has_phrase":      oneText,
+	"has_only_phrase": oneText,
+	"has_any_word":    oneText,
+	"has_all_words":   oneText,
+	"has_beginning":   oneText,
+	"has_text":        noArgs,
+	"has_pattern":     oneText,
+
+	"has_number":         noArgs,
+	"has_number_between": newArgsSpec(2, 2, argNumber),
+	"has_number_lt":      oneNumber,
+	"has_number_lte":     oneNumber,
+	"has_number_eq":      oneNumber,
+	"has_number_gte":     oneNumber,
+	"has_number_gt":      oneNumber,
+
+	"has_date":    noArgs,
+	"has_date_lt": oneText,
+	"has_date_eq": oneText,
+	"has_date_gt": oneText,
+
+	"has_duration_lt": newArgsSpec(1, 1, argDuration),
+	"has_duration_gt": newArgsSpec(1, 1, argDuration),
+
+	"has_time":        noArgs,
+	"has_phone":       newArgsSpec(0, 1, argText),
+	"has_valid_phone": newArgsSpec(0, 1, argText),
+	"has_email":       noArgs,
+	"has_group":       newArgsSpec(1, 2, argUUID, argText),
+
+	"has_category":   newArgsSpec(1, -1, argText),
+	"has_intent":     newArgsSpec(2, 2, argText, argNumber),
+	"has_top_intent": newArgsSpec(2, 2, argText, argNumber),
+
+	"has_state":    noArgs,
+	"has_district": newArgsSpec(0, 1, argText),
+	"has_ward":     newArgsSpec(0, 2, argText),
+
+	"has_location_within": newArgsSpec(3, 3, argNumber),
+
+	"has_value": noArgs,
+}
+
+// ValidateArguments checks the given arguments of a case which uses the named router test. Arguments which contain
+// expressions can only be checked when they're evaluated, so only the number of them is checked, and tests registered
+// outside of this package aren't checked at all.
+func ValidateArguments(test string, arguments []string) error {
+	test = strings.ToLower(test)
+	spec := xtestArgs[test]
+	if spec == nil {
+		return nil
+	}
+
+	if len(arguments) < spec.min || (spec.max >= 0 && len(arguments) > spec.max) {
+		return errors.Errorf("%s takes %s, got %d", test, spec.describeCount(), len(arguments))
+	}
+
+	// has_ward takes a district and state or neither
+	if test == "has_ward" && len(arguments) == 1 {
+		return errors.Errorf("%s takes a district and state or neither, got 1 argument", test)
+	}
+
+	// has_number_between's range can't be empty
+	if test == "has_number_between" && len(arguments) == 2 {
+		min, minOK := literalNumber(arguments[0])
+		max, maxOK := literalNumber(arguments[1])
+		if minOK && maxOK && min.Compare(max) > 0 {
+			return errors.Errorf("%s has min %s which is greater than max %s", test, arguments[0], arguments[1])
+		}
+	}
+
+	for i, arg := range arguments {
+		if err := spec.kindOf(i).check(arg); err != nil {
+			return errors.Errorf("%s argument %d: %s", test, i+1, err)
+		}
+	}
+	return nil
+}
+
+func (s *argsSpec) kindOf(i int) argKind {
+	if len(s.kinds) == 0 {
+		return argText
+	}
+	if i >= len(s.kinds) {
+		return s.kinds[len(s.kinds)-1]
+	}
+	return s.kinds[i]
+}
+
+func (s *argsSpec) describeCount() string {
+	switch {
+	case s.max < 0:
+		return "at least " + pluralizeArgs(s.min)
+	case s.min == s.max:
+		return pluralizeArgs(s.min)
+	default:
+		return fmt.Sprintf("%d to %d arguments", s.min, s.max)
+	}
+}
+
+func pluralizeArgs(n int) string {
+	if n == 1 {
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", n)
+}
+
+// checks that the given argument is of this kind, if it doesn't contain expressions
+func (k argKind) check(arg string) error {
+	if excellent.HasExpressions(arg, flows.RunContextTopLevels) {
+		return nil
+	}
+
+	switch k {
+	case argNumber:
+		if _, ok := literalNumber(arg); !ok {
+			return errors.Errorf("'%s' is not a valid number", arg)
+		}
+	case argDuration:
+		if _, xerr := types.ToXDuration(nil, types.NewXText(arg)); xerr != nil {
+			return errors.Errorf("'%s' is not a valid ISO 8601 duration", arg)
+		}
+	case argUUID:
+		if !uuidRegex.MatchString(arg) {
+			return errors.Errorf("'%s' is not a valid UUID", arg)
+		}
+	}
+	return nil
+}
+
+// parses the given argument as a number if it doesn't contain expressions
+func literalNumber(arg string) (types.XNumber, bool) {
+	if excellent.HasExpressions(arg, flows.RunContextTopLevels) {
+		return types.XNumberZero, false
+	}
+	num, xerr := types.ToXNumber(nil, types.NewXText(arg))
+	return num, xerr == nil
+}
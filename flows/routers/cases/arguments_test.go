@@ -0,0 +1,64 @@
+package cases_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/flows/routers/cases"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateArguments(t *testing.T) {
+	tcs := []struct {
+		test string
+		args []string
+		err  string
+	}{
+		{"has_text", nil, ""},
+		{"has_text", []string{"foo"}, "has_text takes 0 arguments, got 1"},
+		{"has_any_word", []string{"yes y"}, ""},
+		{"has_any_word", []string{}, "has_any_word takes 1 argument, got 0"},
+		{"HAS_ANY_WORD", []string{"yes", "y"}, "has_any_word takes 1 argument, got 2"},
+		{"has_category", []string{"Success", "Failure"}, ""},
+		{"has_category", []string{}, "has_category takes at least 1 argument, got 0"},
+		{"has_phone", []string{"RW", "US"}, "has_phone takes 0 to 1 arguments, got 2"},
+
+		{"has_number_between", []string{"1", "10"}, ""},
+		{"has_number_between", []string{"1.5", "@fields.max"}, ""},
+		{"has_number_between", []string{"@(fields.max + 1)", "0"}, ""},
+		{"has_number_between", []string{"1"}, "has_number_between takes 2 arguments, got 1"},
+		{"has_number_between", []string{"1", "ten"}, "has_number_between argument 2: 'ten' is not a valid number"},
+		{"has_number_between", []string{"10", "1"}, "has_number_between has min 10 which is greater than max 1"},
+		{"has_number_gt", []string{"10 apples"}, "has_number_gt argument 1: '10 apples' is not a valid number"},
+		{"has_intent", []string{"book_flight", "0.5"}, ""},
+		{"has_intent", []string{"book_flight", "likely"}, "has_intent argument 2: 'likely' is not a valid number"},
+		{"has_location_within", []string{"5", "-1.9441", "30.0619"}, ""},
+		{"has_location_within", []string{"5", "north", "30.0619"}, "has_location_within argument 2: 'north' is not a valid number"},
+
+		{"has_duration_lt", []string{"PT1H"}, ""},
+		{"has_duration_lt", []string{"1 hour"}, "has_duration_lt argument 1: '1 hour' is not a valid ISO 8601 duration"},
+
+		{"has_group", []string{"b7cf0d83-f1c9-411c-96fd-c511a4cfa86d", "Testers"}, ""},
+		{"has_group", []string{"@fields.group"}, ""},
+		{"has_group", []string{"Testers"}, "has_group argument 1: 'Testers' is not a valid UUID"},
+
+		{"has_ward", []string{"Gasabo", "Kigali"}, ""},
+		{"has_ward", []string{"Gasabo"}, "has_ward takes a district and state or neither, got 1 argument"},
+
+		// dates and patterns are left to be checked at runtime and by inspection
+		{"has_date_lt", []string{"tomorrow"}, ""},
+		{"has_pattern", []string{"^^.("}, ""},
+
+		// as are tests which this package doesn't know about
+		{"has_icecream", []string{"vanilla", "chocolate"}, ""},
+	}
+
+	for _, tc := range tcs {
+		err := cases.ValidateArguments(tc.test, tc.args)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, "error mismatch for %s(%v)", tc.test, tc.args)
+		} else {
+			assert.NoError(t, err, "unexpected error for %s(%v)", tc.test, tc.args)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package cases
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/nyaruka/goflow/envs"
+
+	"github.com/shopspring/decimal"
+)
+
+type numberWordKind int
+
+const (
+	numberWordValue      numberWordKind = iota // adds its value, e.g. "twenty"
+	numberWordMultiplier                       // multiplies the current group, e.g. "hundred"
+	numberWordScale                            // closes the current group at a scale, e.g. "thousand"
+	numberWordJoiner                           // allowed between other number words, e.g. "and"
+)
+
+type numberWord struct {
+	kind  numberWordKind
+	value int64
+}
+
+// a language's number words along with any phrases which need to be rewritten as single words before tokenizing
+type numberWords struct {
+	words   map[string]numberWord
+	phrases *strings.Replacer
+}
+
+func newNumberWords(values map[string]int64, multipliers map[string]int64, scales map[string]int64, joiners []string, phrases ...string) *numberWords {
+	w := &numberWords{words: make(map[string]numberWord), phrases: strings.NewReplacer(phrases...)}
+	for word, v := range values {
+		w.words[word] = numberWord{numberWordValue, v}
+	}
+	for word, v := range multipliers {
+		w.words[word] = numberWord{numberWordMultiplier, v}
+	}
+	for word, v := range scales {
+		w.words[word] = numberWord{numberWordScale, v}
+	}
+	for _, word := range joiners {
+		w.words[word] = numberWord{kind: numberWordJoiner}
+	}
+	return w
+}
+
+var spokenNumbers = map[envs.Language]*numberWords{
+	"eng": newNumberWords(
+		map[string]int64{
+			"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9,
+			"ten": 10, "eleven": 11, "twelve": 12, "thirteen": 13, "fourteen": 14, "fifteen": 15, "sixteen": 16,
+			"seventeen": 17, "eighteen": 18, "nineteen": 19, "twenty": 20, "thirty": 30, "forty": 40, "fifty": 50,
+			"sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+		},
+		map[string]int64{"hundred": 100},
+		map[string]int64{"thousand": 1000, "million": 1000000},
+		[]string{"and"},
+	),
+	"fra": newNumberWords(
+		map[string]int64{
+			"zéro": 0, "zero": 0, "un": 1, "une": 1, "deux": 2, "trois": 3, "quatre": 4, "cinq": 5, "six": 6, "sept": 7,
+			"huit": 8, "neuf": 9, "dix": 10, "onze": 11, "douze": 12, "treize": 13, "quatorze": 14, "quinze": 15,
+			"seize": 16, "vingt": 20, "trente": 30, "quarante": 40, "cinquante": 50, "soixante": 60,
+			"quatrevingt": 80, "quatrevingts": 80,
+		},
+		map[string]int64{"cent": 100, "cents": 100},
+		map[string]int64{"mille": 1000, "million": 1000000, "millions": 1000000},
+		[]string{"et"},
+		"quatre-vingt", "quatrevingt", "quatre vingt", "quatrevingt",
+	),
+	"por": newNumberWords(
+		map[string]int64{
+			"zero": 0, "um": 1, "uma": 1, "dois": 2, "duas": 2, "três": 3, "tres": 3, "quatro": 4, "cinco": 5, "seis": 6,
+			"sete": 7, "oito": 8, "nove": 9, "dez": 10, "onze": 11, "doze": 12, "treze": 13, "catorze": 14,
+			"quatorze": 14, "quinze": 15, "dezesseis": 16, "dezasseis": 16, "dezessete": 17, "dezassete": 17,
+			"dezoito": 18, "dezenove": 19, "dezanove": 19, "vinte": 20, "trinta": 30, "quarenta": 40, "cinquenta": 50,
+			"sessenta": 60, "setenta": 70, "oitenta": 80, "noventa": 90, "cem": 100, "cento": 100, "duzentos": 200,
+			"trezentos": 300, "quatrocentos": 400, "quinhentos": 500, "seiscentos": 600, "setecentos": 700,
+			"oitocentos": 800, "novecentos": 900,
+		},
+		nil,
+		map[string]int64{"mil": 1000, "milhão": 1000000, "milhao": 1000000, "milhões": 1000000, "milhoes": 1000000},
+		[]string{"e"},
+	),
+	"spa": newNumberWords(
+		map[string]int64{
+			"cero": 0, "uno": 1, "una": 1, "un": 1, "dos": 2, "tres": 3, "cuatro": 4, "cinco": 5, "seis": 6, "siete": 7,
+			"ocho": 8, "nueve": 9, "diez": 10, "once": 11, "doce": 12, "trece": 13, "catorce": 14, "quince": 15,
+			"dieciséis": 16, "dieciseis": 16, "diecisiete": 17, "dieciocho": 18, "diecinueve": 19, "veinte": 20,
+			"veintiuno": 21, "veintiún": 21, "veintiun": 21, "veintidós": 22, "veintidos": 22, "veintitrés": 23,
+			"veintitres": 23, "veinticuatro": 24, "veinticinco": 25, "veintiséis": 26, "veintiseis": 26,
+			"veintisiete": 27, "veintiocho": 28, "veintinueve": 29, "treinta": 30, "cuarenta": 40, "cincuenta": 50,
+			"sesenta": 60, "setenta": 70, "ochenta": 80, "noventa": 90, "cien": 100, "ciento": 100, "doscientos": 200,
+			"trescientos": 300, "cuatrocientos": 400, "quinientos": 500, "seiscientos": 600, "setecientos": 700,
+			"ochocientos": 800, "novecientos": 900,
+		},
+		nil,
+		map[string]int64{"mil": 1000, "millón": 1000000, "millon": 1000000, "millones": 1000000},
+		[]string{"y"},
+	),
+}
+
+var spokenNumberTokenRegex = regexp.MustCompile(`[\pL]+`)
+
+// FindSpokenNumbers finds the numbers written as words in the given text, e.g. "twenty five", in the given language.
+// Languages without number words never have any spoken numbers.
+func FindSpokenNumbers(text string, lang envs.Language) []decimal.Decimal {
+	words := spokenNumbers[lang]
+	if words == nil {
+		return nil
+	}
+
+	text = words.phrases.Replace(strings.ToLower(text))
+
+	nums := make([]decimal.Decimal, 0)
+	var total, group int64
+	var inNumber bool
+	var last *numberWord
+
+	finish := func() {
+		if inNumber {
+			nums = append(nums, decimal.NewFromInt(total+group))
+		}
+		total, group, inNumber, last = 0, 0, false, nil
+	}
+
+	for _, token := range spokenNumberTokenRegex.FindAllString(text, -1) {
+		word, isWord := words.words[token]
+		if !isWord || (word.kind == numberWordJoiner && !inNumber) {
+			finish()
+			continue
+		}
+
+		// a value can only follow another value if it completes it, e.g. "twenty" + "five", otherwise it's a new number
+		if word.kind == numberWordValue && last != nil && last.kind == numberWordValue && (last.value%10 != 0 || last.value <= word.value) {
+			finish()
+		}
+
+		switch word.kind {
+		case numberWordValue:
+			group += word.value
+		case numberWordMultiplier:
+			if group == 0 {
+				group = 1
+			}
+			group *= word.value
+		case numberWordScale:
+			if group == 0 {
+				group = 1
+			}
+			total += group * word.value
+			group = 0
+		}
+		inNumber = true
+		if word.kind != numberWordJoiner {
+			last = &word
+		}
+	}
+
+	finish()
+	return nums
+}
@@ -0,0 +1,51 @@
+package cases_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows/routers/cases"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSpokenNumbers(t *testing.T) {
+	tcs := []struct {
+		text     string
+		lang     envs.Language
+		expected []int64
+	}{
+		{"", "eng", []int64{}},
+		{"no numbers here", "eng", []int64{}},
+		{"forty two", "eng", []int64{42}},
+		{"I am Twenty-Five years old", "eng", []int64{25}},
+		{"one hundred and five", "eng", []int64{105}},
+		{"two thousand three hundred", "eng", []int64{2300}},
+		{"a million", "eng", []int64{1000000}},
+		{"cats and dogs", "eng", []int64{}},
+		{"one two three", "eng", []int64{1, 2, 3}},
+		{"between five and ten", "eng", []int64{5, 10}},
+		{"quatre-vingt-dix-neuf", "fra", []int64{99}},
+		{"soixante et onze", "fra", []int64{71}},
+		{"deux cent vingt", "fra", []int64{220}},
+		{"vinte e cinco", "por", []int64{25}},
+		{"dois mil e quinhentos", "por", []int64{2500}},
+		{"veinticinco", "spa", []int64{25}},
+		{"ciento treinta y dos", "spa", []int64{132}},
+		{"forty two", "spa", []int64{}},
+		{"forty two", "kin", nil},
+	}
+
+	for _, tc := range tcs {
+		var expected []decimal.Decimal
+		if tc.expected != nil {
+			expected = make([]decimal.Decimal, len(tc.expected))
+			for i, n := range tc.expected {
+				expected[i] = decimal.NewFromInt(n)
+			}
+		}
+
+		assert.Equal(t, expected, cases.FindSpokenNumbers(tc.text, tc.lang), "spoken numbers mismatch for '%s' in %s", tc.text, tc.lang)
+	}
+}
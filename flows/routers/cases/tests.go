@@ -50,10 +50,14 @@ func init() {
 		"has_date_eq": functions.TextAndDateFunction(HasDateEQ),
 		"has_date_gt": functions.TextAndDateFunction(HasDateGT),
 
-		"has_time":  functions.OneTextFunction(HasTime),
-		"has_phone": functions.InitialTextFunction(0, 1, HasPhone),
-		"has_email": functions.OneTextFunction(HasEmail),
-		"has_group": functions.MinAndMaxArgsCheck(2, 3, HasGroup),
+		"has_duration_lt": functions.TwoArgFunction(HasDurationLT),
+		"has_duration_gt": functions.TwoArgFunction(HasDurationGT),
+
+		"has_time":        functions.OneTextFunction(HasTime),
+		"has_phone":       functions.InitialTextFunction(0, 1, HasPhone),
+		"has_valid_phone": functions.InitialTextFunction(0, 1, HasValidPhone),
+		"has_email":       functions.OneTextFunction(HasEmail),
+		"has_group":       functions.MinAndMaxArgsCheck(2, 3, HasGroup),
 
 		"has_category":   functions.ObjectAndTextsFunction(HasCategory),
 		"has_intent":     functions.ObjectTextAndNumberFunction(HasIntent),
@@ -63,6 +67,8 @@ func init() {
 		"has_district": functions.MinAndMaxArgsCheck(1, 2, HasDistrict),
 		"has_ward":     HasWard,
 
+		"has_location_within": functions.NumArgsCheck(4, HasLocationWithin),
+
 		// for backward compatibility
 		"has_value": functions.OneTextFunction(HasText),
 	}
@@ -149,7 +155,9 @@ func HasError(env envs.Environment, value types.XValue) types.XValue {
 	return FalseResult
 }
 
-// HasGroup returns whether the `contact` is part of group with the passed in UUID
+// HasGroup returns whether the `contact` is part of group with the passed in UUID. If the engine is configured to
+// evaluate group queries inline, membership of a query based group is decided by checking its query against the
+// contact as it is now, so that changes made earlier in the same sprint are always taken into account.
 //
 //	@(has_group(contact.groups, "b7cf0d83-f1c9-411c-96fd-c511a4cfa86d").match) -> {name: Testers, uuid: b7cf0d83-f1c9-411c-96fd-c511a4cfa86d}
 //	@(has_group(array(), "97fe7029-3a15-4005-b0c7-277b884fc1d5")) -> false
@@ -167,6 +175,16 @@ func HasGroup(env envs.Environment, args ...types.XValue) types.XValue {
 		return xerr
 	}
 
+	// if the environment can evaluate group queries, query based groups are checked against the contact itself
+	if resolver := env.GroupResolver(); resolver != nil {
+		if name, member := resolver.CheckQueryBasedMembership(groupUUID.Native()); name != "" {
+			if !member {
+				return FalseResult
+			}
+			return NewTrueResult(types.NewXObject(map[string]types.XValue{"uuid": groupUUID, "name": types.NewXText(name)}))
+		}
+	}
+
 	for i := 0; i < array.Count(); i++ {
 		group, xerr := types.ToXObject(env, array.Get(i))
 		if xerr != nil {
@@ -302,11 +320,13 @@ func HasBeginning(env envs.Environment, text types.XText, beginning types.XText)
 
 // HasPattern tests whether `text` matches the regex `pattern`
 //
-// Both text values are trimmed of surrounding whitespace and matching is case-insensitive.
+// Both text values are trimmed of surrounding whitespace and matching is case-insensitive. The groups of the match are
+// returned as the extra by their number and, for named groups, also by their name.
 //
 //	@(has_pattern("Buy cheese please", "buy (\w+)")) -> true
 //	@(has_pattern("Buy cheese please", "buy (\w+)").match) -> Buy cheese
 //	@(has_pattern("Buy cheese please", "buy (\w+)").extra) -> {0: Buy cheese, 1: cheese}
+//	@(has_pattern("Buy cheese please", "buy (?P<item>\w+)").extra.item) -> cheese
 //	@(has_pattern("Sell cheese please", "buy (\w+)")) -> false
 //
 // @test has_pattern(text, pattern)
@@ -320,8 +340,11 @@ func HasPattern(env envs.Environment, text types.XText, pattern types.XText) typ
 	if matches != nil {
 		extra := make(map[string]types.XValue, len(matches))
 
-		for i, group := range matches {
-			extra[strconv.Itoa(i)] = types.NewXText(group)
+		for i, name := range regex.SubexpNames() {
+			extra[strconv.Itoa(i)] = types.NewXText(matches[i])
+			if name != "" {
+				extra[name] = types.NewXText(matches[i])
+			}
 		}
 		return NewTrueResultWithExtra(types.NewXText(matches[0]), types.NewXObject(extra))
 	}
@@ -329,7 +352,8 @@ func HasPattern(env envs.Environment, text types.XText, pattern types.XText) typ
 	return FalseResult
 }
 
-// HasNumber tests whether `text` contains a number
+// HasNumber tests whether `text` contains a number. Numbers are parsed using the number format of the environment,
+// which can also enable matching numbers written as words in the environment's default language.
 //
 //	@(has_number("the number is 42")) -> true
 //	@(has_number("the number is 42").match) -> 42
@@ -481,6 +505,32 @@ func HasDateGT(env envs.Environment, text types.XText, date types.XDateTime) typ
 	return testDate(env, text, date, isDateGTTest)
 }
 
+// HasDurationLT tests whether `value` is a duration shorter than `max`
+//
+//	@(has_duration_lt(duration(20, "m"), "PT1H")) -> true
+//	@(has_duration_lt(duration(20, "m"), "PT1H").match) -> PT20M
+//	@(has_duration_lt("P1D", duration(2, "h"))) -> false
+//	@(has_duration_lt("not a duration", "PT1H")) -> false
+//	@(has_duration_lt("PT20M", "not a duration")) -> ERROR
+//
+// @test has_duration_lt(value, max)
+func HasDurationLT(env envs.Environment, value types.XValue, max types.XValue) types.XValue {
+	return testDuration(env, value, max, isDurationLTTest)
+}
+
+// HasDurationGT tests whether `value` is a duration longer than `min`
+//
+//	@(has_duration_gt("P1D", duration(2, "h"))) -> true
+//	@(has_duration_gt("P1D", duration(2, "h")).match) -> P1D
+//	@(has_duration_gt(duration(20, "m"), "PT1H")) -> false
+//	@(has_duration_gt("not a duration", "PT1H")) -> false
+//	@(has_duration_gt("PT20M", "not a duration")) -> ERROR
+//
+// @test has_duration_gt(value, min)
+func HasDurationGT(env envs.Environment, value types.XValue, min types.XValue) types.XValue {
+	return testDuration(env, value, min, isDurationGTTest)
+}
+
 // HasTime tests whether `text` contains a time.
 //
 //	@(has_time("the time is 10:30")) -> true
@@ -550,6 +600,38 @@ func HasPhone(env envs.Environment, text types.XText, args ...types.XValue) type
 	return NewTrueResult(types.NewXText(numbers[0]))
 }
 
+// HasValidPhone tests whether `text` is a phone number which is valid in its country, which is stricter than
+// [test:has_phone] as it checks the number against the number ranges in use. The optional `country_code` argument
+// specifies the country to use for parsing, and defaults to the default country of the environment.
+//
+//	@(has_valid_phone("+250788383383")) -> true
+//	@(has_valid_phone("+250788383383").match) -> +250788383383
+//	@(has_valid_phone("0788 383 383", "RW").match) -> +250788383383
+//	@(has_valid_phone("+250188383383")) -> false
+//	@(has_valid_phone("my number is +250788383383")) -> false
+//
+// @test has_valid_phone(text, country_code)
+func HasValidPhone(env envs.Environment, text types.XText, args ...types.XValue) types.XValue {
+	var country types.XText
+	var xerr types.XError
+	if len(args) == 1 {
+		country, xerr = types.ToXText(env, args[0])
+		if xerr != nil {
+			return xerr
+		}
+	} else {
+		country = types.NewXText(string(env.DefaultCountry()))
+	}
+
+	number, err := utils.ParsePhone(text.Native(), country.Native())
+	if err != nil || !utils.IsValidPhone(number) {
+		return FalseResult
+	}
+
+	e164, _ := utils.FormatPhone(number, utils.PhoneFormatE164)
+	return NewTrueResult(types.NewXText(e164))
+}
+
 // HasCategory tests whether the category of a result on of the passed in `categories`
 //
 //	@(has_category(results.webhook, "Success", "Failure")) -> true
@@ -574,7 +656,9 @@ func HasCategory(env envs.Environment, resultObj *types.XObject, categories ...t
 	return FalseResult
 }
 
-// HasIntent tests whether any intent in a classification result has `name` and minimum `confidence`
+// HasIntent tests whether any intent in a classification result has `name` and minimum `confidence`. If the classifier
+// is calibrated then `confidence` is the minimum percentile, e.g. 0.9 means the confidence must be in the top 10% of
+// those reported by that classifier.
 //
 //	@(has_intent(results.intent, "book_flight", 0.5)) -> true
 //	@(has_intent(results.intent, "book_hotel", 0.2)) -> true
@@ -584,7 +668,8 @@ func HasIntent(env envs.Environment, result *types.XObject, name types.XText, co
 	return hasIntent(result, name, confidence, false)
 }
 
-// HasTopIntent tests whether the top intent in a classification result has `name` and minimum `confidence`
+// HasTopIntent tests whether the top intent in a classification result has `name` and minimum `confidence`, which is a
+// percentile if the classifier is calibrated.
 //
 //	@(has_top_intent(results.intent, "book_flight", 0.5)) -> true
 //	@(has_top_intent(results.intent, "book_hotel", 0.5)) -> false
@@ -594,11 +679,14 @@ func HasTopIntent(env envs.Environment, result *types.XObject, name types.XText,
 	return hasIntent(result, name, confidence, true)
 }
 
-// HasState tests whether a state name is contained in the `text`
+// HasState tests whether a state name is contained in the `text`. Names and aliases are also matched when they're
+// typed without accents, and the extra value has the `name` that matched and the `confidence` of the match.
 //
 //	@(has_state("Kigali").match) -> Rwanda > Kigali City
 //	@(has_state("¡Kigali!").match) -> Rwanda > Kigali City
 //	@(has_state("I live in Kigali").match) -> Rwanda > Kigali City
+//	@(has_state("I live in Kigali").extra.name) -> Kigali
+//	@(has_state("I live in Kigali").extra.confidence) -> 0.81
 //	@(has_state("Boston")) -> false
 //
 // @test has_state(text)
@@ -608,9 +696,9 @@ func HasState(env envs.Environment, text types.XText) types.XValue {
 		return types.NewXErrorf("can't find locations in environment which is not location enabled")
 	}
 
-	states := locations.FindLocationsFuzzy(text.Native(), flows.LocationLevelState, nil)
+	states := locations.MatchLocationsFuzzy(text.Native(), flows.LocationLevelState, nil)
 	if len(states) > 0 {
-		return NewTrueResult(types.NewXText(string(states[0].Path())))
+		return locationMatchResult(states[0])
 	}
 	return FalseResult
 }
@@ -645,17 +733,17 @@ func HasDistrict(env envs.Environment, args ...types.XValue) types.XValue {
 
 	states := locations.FindLocationsFuzzy(stateText.Native(), flows.LocationLevelState, nil)
 	if len(states) > 0 {
-		districts := locations.FindLocationsFuzzy(text.Native(), flows.LocationLevelDistrict, states[0])
+		districts := locations.MatchLocationsFuzzy(text.Native(), flows.LocationLevelDistrict, states[0])
 		if len(districts) > 0 {
-			return NewTrueResult(types.NewXText(string(districts[0].Path())))
+			return locationMatchResult(districts[0])
 		}
 	}
 
 	// try without a parent state - it's ok as long as we get a single match
 	if stateText.Empty() {
-		districts := locations.FindLocationsFuzzy(text.Native(), flows.LocationLevelDistrict, nil)
+		districts := locations.MatchLocationsFuzzy(text.Native(), flows.LocationLevelDistrict, nil)
 		if len(districts) == 1 {
-			return NewTrueResult(types.NewXText(string(districts[0].Path())))
+			return locationMatchResult(districts[0])
 		}
 	}
 
@@ -703,24 +791,95 @@ func HasWard(env envs.Environment, args ...types.XValue) types.XValue {
 	if len(states) > 0 {
 		districts := locations.FindLocationsFuzzy(districtText.Native(), flows.LocationLevelDistrict, states[0])
 		if len(districts) > 0 {
-			wards := locations.FindLocationsFuzzy(text.Native(), flows.LocationLevelWard, districts[0])
+			wards := locations.MatchLocationsFuzzy(text.Native(), flows.LocationLevelWard, districts[0])
 			if len(wards) > 0 {
-				return NewTrueResult(types.NewXText(string(wards[0].Path())))
+				return locationMatchResult(wards[0])
 			}
 		}
 	}
 
 	// try without a parent district - it's ok as long as we get a single match
 	if districtText.Empty() {
-		wards := locations.FindLocationsFuzzy(text.Native(), flows.LocationLevelWard, nil)
+		wards := locations.MatchLocationsFuzzy(text.Native(), flows.LocationLevelWard, nil)
 		if len(wards) == 1 {
-			return NewTrueResult(types.NewXText(string(wards[0].Path())))
+			return locationMatchResult(wards[0])
 		}
 	}
 
 	return FalseResult
 }
 
+// creates a result for a matched location, with the name or alias which matched and the confidence of the match as extra
+func locationMatchResult(match *envs.LocationMatch) types.XValue {
+	return NewTrueResultWithExtra(types.NewXText(string(match.Location.Path())), types.NewXObject(map[string]types.XValue{
+		"name":       types.NewXText(match.Name),
+		"confidence": types.NewXNumber(decimal.NewFromFloat(match.Confidence).Round(2)),
+	}))
+}
+
+// HasLocationWithin tests whether the location given by `text` is within `distance` kilometers of the
+// point at `latitude` and `longitude`. The `text` can be coordinates like `-1.9247,30.0633` or the path of a location
+// with coordinates, such as the value of a location field. The extra value is the distance in kilometers.
+//
+//	@(has_location_within("-1.9247,30.0633", 5, -1.9441, 30.0619).match) -> -1.9247,30.0633
+//	@(has_location_within("-1.9247,30.0633", 5, -1.9441, 30.0619).extra.distance) -> 2.16
+//	@(has_location_within("Rwanda > Kigali City > Gasabo > Gisozi", 5, -1.9441, 30.0619).match) -> Rwanda > Kigali City > Gasabo > Gisozi
+//	@(has_location_within("-1.9247,30.0633", 1, -1.9441, 30.0619)) -> false
+//	@(has_location_within("Boston", 5, -1.9441, 30.0619)) -> false
+//	@(has_location_within("-1.9247,30.0633", "far", -1.9441, 30.0619)) -> ERROR
+//
+// @test has_location_within(text, distance, latitude, longitude)
+func HasLocationWithin(env envs.Environment, args ...types.XValue) types.XValue {
+	text, xerr := types.ToXText(env, args[0])
+	if xerr != nil {
+		return xerr
+	}
+
+	nums := make([]float64, 3)
+	for i, arg := range args[1:] {
+		num, xerr := types.ToXNumber(env, arg)
+		if xerr != nil {
+			return xerr
+		}
+		nums[i], _ = num.Native().Float64()
+	}
+
+	center := utils.NewCoordinates(nums[1], nums[2])
+	if !center.Valid() {
+		return types.NewXErrorf("%s is not a valid latitude and longitude", center)
+	}
+
+	var match types.XText
+	var point utils.Coordinates
+
+	if coords, ok := utils.ParseCoordinates(text.Native()); ok {
+		match, point = types.NewXText(coords.String()), coords
+	} else if envs.IsPossibleLocationPath(text.Native()) {
+		locations := env.LocationResolver()
+		if locations == nil {
+			return types.NewXErrorf("can't find locations in environment which is not location enabled")
+		}
+
+		location := locations.LookupLocation(envs.LocationPath(text.Native()))
+		if location == nil || location.Coordinates() == nil {
+			return FalseResult
+		}
+		match, point = types.NewXText(string(location.Path())), *location.Coordinates()
+	} else {
+		return FalseResult
+	}
+
+	distance := point.DistanceTo(center)
+	if distance > nums[0] {
+		return FalseResult
+	}
+
+	extra := types.NewXObject(map[string]types.XValue{
+		"distance": types.NewXNumber(decimal.NewFromFloat(distance).Round(2)),
+	})
+	return NewTrueResultWithExtra(match, extra)
+}
+
 //------------------------------------------------------------------------------------------
 // Text Test Functions
 //------------------------------------------------------------------------------------------
@@ -847,7 +1006,7 @@ type decimalTest func(value decimal.Decimal, test1 decimal.Decimal, test2 decima
 
 func testNumber(env envs.Environment, str types.XText, testNum1 types.XNumber, testNum2 types.XNumber, testFunc decimalTest) types.XValue {
 	// create a number finding regex based on current environment
-	pattern := regexp.MustCompile(fmt.Sprintf(`[-+]?([\pN\%[1]s]+(\%[2]s[\pN]+)?|(\W|^)\%[2]s[\pN]+)`, env.NumberFormat().DigitGroupingSymbol, env.NumberFormat().DecimalSymbol))
+	pattern := regexp.MustCompile(fmt.Sprintf(`[-+]?([\pN\%[1]s%[3]s]+([\%[2]s%[4]s][\pN]+)?|(\W|^)[\%[2]s%[4]s][\pN]+)`, env.NumberFormat().DigitGroupingSymbol, env.NumberFormat().DecimalSymbol, arabicDigitGroupingSymbol, arabicDecimalSymbol))
 
 	// look for number like things in the input and use the first one that we can actually parse
 	for _, value := range pattern.FindAllString(str.Native(), -1) {
@@ -859,6 +1018,15 @@ func testNumber(env envs.Environment, str types.XText, testNum1 types.XNumber, t
 		}
 	}
 
+	// if enabled, fall back to looking for numbers written as words in the environment's language
+	if env.NumberFormat().SpokenNumbers {
+		for _, num := range FindSpokenNumbers(str.Native(), env.DefaultLanguage()) {
+			if testFunc(num, testNum1.Native(), testNum2.Native()) {
+				return NewTrueResult(types.NewXNumber(num))
+			}
+		}
+	}
+
 	return FalseResult
 }
 
@@ -931,6 +1099,43 @@ func isDateGTTest(value dates.Date, test dates.Date) bool {
 	return value.Compare(test) > 0
 }
 
+//------------------------------------------------------------------------------------------
+// Duration Test Functions
+//------------------------------------------------------------------------------------------
+
+type durationTest func(types.XDuration, types.XDuration) bool
+
+func testDuration(env envs.Environment, value types.XValue, test types.XValue, testFunc durationTest) types.XValue {
+	testDuration, xerr := types.ToXDuration(env, test)
+	if xerr != nil {
+		return xerr
+	}
+
+	if types.IsXError(value) {
+		return value
+	}
+
+	// values which aren't durations don't match
+	duration, xerr := types.ToXDuration(env, value)
+	if xerr != nil {
+		return FalseResult
+	}
+
+	if testFunc(duration, testDuration) {
+		return NewTrueResult(duration)
+	}
+
+	return FalseResult
+}
+
+func isDurationLTTest(value types.XDuration, test types.XDuration) bool {
+	return value.Compare(test) < 0
+}
+
+func isDurationGTTest(value types.XDuration, test types.XDuration) bool {
+	return value.Compare(test) > 0
+}
+
 //------------------------------------------------------------------------------------------
 // Result Test helpers
 //------------------------------------------------------------------------------------------
@@ -961,7 +1166,13 @@ func hasIntent(resultObj *types.XObject, name types.XText, confidence types.XNum
 
 	for _, intent := range intents {
 		intentName := types.NewXText(intent.Name)
-		if intentName.Equals(name) && intent.Confidence.GreaterThanOrEqual(confidence.Native()) {
+		// calibrated classifiers have thresholds which are percentiles
+		score := intent.Confidence
+		if intent.Calibrated != nil {
+			score = *intent.Calibrated
+		}
+
+		if intentName.Equals(name) && score.GreaterThanOrEqual(confidence.Native()) {
 			// build extra as a mapping of entity names to most likely values
 			extra := make(map[string]types.XValue, len(classification.Entities))
 			for entitiyName, possibilities := range classification.Entities {
@@ -27,6 +27,9 @@ var xj = func(s string) types.XValue { return types.JSONToXValue([]byte(s)) }
 var result = cases.NewTrueResult
 var resultWithExtra = cases.NewTrueResultWithExtra
 var falseResult = cases.FalseResult
+var locationResult = func(path, name, confidence string) types.XValue {
+	return cases.NewTrueResultWithExtra(xs(path), types.NewXObject(map[string]types.XValue{"name": xs(name), "confidence": xn(confidence)}))
+}
 var ERROR = types.NewXErrorf("any error")
 
 var kgl, _ = time.LoadLocation("Africa/Kigali")
@@ -43,7 +46,9 @@ var locationHierarchyJSON = `{
 					"name": "Gasabo",
 					"children": [
 						{
-							"name": "Gisozi"
+							"name": "Gisozi",
+							"latitude": -1.9247,
+							"longitude": 30.0633
 						},
 						{
 							"name": "Ndera"
@@ -52,7 +57,11 @@ var locationHierarchyJSON = `{
 				},
 				{
 					"name": "Nyarugenge",
-					"children": []
+					"children": [
+						{
+							"name": "Muhíma"
+						}
+					]
 				}
 			]
 		},
@@ -147,6 +156,7 @@ var testTests = []struct {
 	{"has_pattern", []types.XValue{xs(`hi there 😀`), xs("[\U0001F600-\U0001F64F]")}, resultWithExtra(xs("😀"), types.NewXObject(map[string]types.XValue{"0": xs("😀")}))},
 	{"has_pattern", []types.XValue{xs(`hi there`), xs("[\U0001F600-\U0001F64F]")}, falseResult},
 	{"has_pattern", []types.XValue{xs(`hi there 😂`), xs("[😀-🙏]")}, resultWithExtra(xs("😂"), types.NewXObject(map[string]types.XValue{"0": xs("😂")}))},
+	{"has_pattern", []types.XValue{xs("<html>x</html>"), xs(`<(?P<tag>\w+)>`)}, resultWithExtra(xs("<html>"), types.NewXObject(map[string]types.XValue{"0": xs("<html>"), "1": xs("html"), "tag": xs("html")}))},
 	{"has_pattern", []types.XValue{xs("<html>x</html>"), xs(`[`)}, ERROR},
 	{"has_pattern", []types.XValue{}, ERROR},
 
@@ -164,6 +174,8 @@ var testTests = []struct {
 	{"has_number", []types.XValue{xs(".51")}, result(xn("0.51"))},
 	{"has_number", []types.XValue{xs("١٢٣٤")}, result(xn("1234"))},
 	{"has_number", []types.XValue{xs("٠.٥")}, result(xn("0.5"))},
+	{"has_number", []types.XValue{xs("٣٫٥")}, result(xn("3.5"))},
+	{"has_number", []types.XValue{xs("۱۲۳")}, result(xn("123"))},
 	{"has_number", []types.XValue{xs("nothing here")}, falseResult},
 	{"has_number", []types.XValue{xs("lOO")}, falseResult}, // no longer do substitutions
 	{"has_number", []types.XValue{xs("one"), xs("two"), xs("three")}, ERROR},
@@ -251,6 +263,18 @@ var testTests = []struct {
 	{"has_date_gt", []types.XValue{xs("too"), xs("many"), xs("args")}, ERROR},
 	{"has_date_gt", []types.XValue{}, ERROR},
 
+	{"has_duration_lt", []types.XValue{xs("PT20M"), xs("PT1H")}, result(types.NewXDuration(20 * time.Minute))},
+	{"has_duration_lt", []types.XValue{types.NewXDuration(time.Hour), xs("PT1H")}, falseResult},
+	{"has_duration_lt", []types.XValue{xs("twenty minutes"), xs("PT1H")}, falseResult},
+	{"has_duration_lt", []types.XValue{xs("PT20M"), xs("xxx")}, ERROR},
+	{"has_duration_lt", []types.XValue{ERROR, xs("PT1H")}, ERROR},
+	{"has_duration_lt", []types.XValue{xs("PT20M")}, ERROR},
+
+	{"has_duration_gt", []types.XValue{xs("P1D"), types.NewXDuration(2 * time.Hour)}, result(types.NewXDuration(24 * time.Hour))},
+	{"has_duration_gt", []types.XValue{xs("PT2H"), xs("PT2H")}, falseResult},
+	{"has_duration_gt", []types.XValue{nil, xs("PT2H")}, falseResult},
+	{"has_duration_gt", []types.XValue{xs("P1D"), ERROR}, ERROR},
+
 	{"has_time", []types.XValue{xs("last time was 10:30")}, result(xt(dates.NewTimeOfDay(10, 30, 0, 0)))},
 	{"has_time", []types.XValue{xs("this isn't a valid time 59:77")}, falseResult},
 	{"has_time", []types.XValue{xs("no time at all")}, falseResult},
@@ -276,6 +300,16 @@ var testTests = []struct {
 	{"has_phone", []types.XValue{xs("too"), xs("many"), xs("args")}, ERROR},
 	{"has_phone", []types.XValue{}, ERROR},
 
+	{"has_valid_phone", []types.XValue{xs("+250788383383")}, result(xs("+250788383383"))},
+	{"has_valid_phone", []types.XValue{xs("tel:+250788383383")}, result(xs("+250788383383"))},
+	{"has_valid_phone", []types.XValue{xs("0788 383 383"), xs("RW")}, result(xs("+250788383383"))},
+	{"has_valid_phone", []types.XValue{xs("+250188383383")}, falseResult},
+	{"has_valid_phone", []types.XValue{xs("12345"), xs("RW")}, falseResult},
+	{"has_valid_phone", []types.XValue{xs("my number is +250788383383")}, falseResult},
+	{"has_valid_phone", []types.XValue{ERROR}, ERROR},
+	{"has_valid_phone", []types.XValue{xs("+250788383383"), ERROR}, ERROR},
+	{"has_valid_phone", []types.XValue{}, ERROR},
+
 	{
 		"has_group",
 		[]types.XValue{
@@ -290,26 +324,42 @@ var testTests = []struct {
 	{"has_group", []types.XValue{xa(), ERROR}, ERROR},
 	{"has_group", []types.XValue{}, ERROR},
 
-	{"has_state", []types.XValue{xs("kigali city")}, result(xs("Rwanda > Kigali City"))},
-	{"has_state", []types.XValue{xs("kigari")}, result(xs("Rwanda > Kigali City"))},
-	{"has_state", []types.XValue{xs("تروو")}, result(xs("Rwanda > Paktika"))},
+	{"has_state", []types.XValue{xs("kigali city")}, locationResult("Rwanda > Kigali City", "Kigali City", "1")},
+	{"has_state", []types.XValue{xs("kigari")}, locationResult("Rwanda > Kigali City", "Kigari", "0.9")},
+	{"has_state", []types.XValue{xs("I'm from Kigalì")}, locationResult("Rwanda > Kigali City", "Kigali", "0.63")},
+	{"has_state", []types.XValue{xs("تروو")}, locationResult("Rwanda > Paktika", "تروو", "0.9")},
 	{"has_state", []types.XValue{xs("غم ځپلې هلمند")}, falseResult},
 	{"has_state", []types.XValue{xs("\u063a\u0645 \u0681\u067e\u0644\u06d0 \u0647\u0644\u0645\u0646\u062f")}, falseResult},
 	{"has_state", []types.XValue{xs("xyz")}, falseResult},
 	{"has_state", []types.XValue{ERROR}, ERROR},
 
-	{"has_district", []types.XValue{xs("Gasabo"), xs("kigali")}, result(xs("Rwanda > Kigali City > Gasabo"))},
-	{"has_district", []types.XValue{xs("I live in gasabo"), xs("kigali")}, result(xs("Rwanda > Kigali City > Gasabo"))},
-	{"has_district", []types.XValue{xs("Gasabo")}, result(xs("Rwanda > Kigali City > Gasabo"))},
+	{"has_district", []types.XValue{xs("Gasabo"), xs("kigali")}, locationResult("Rwanda > Kigali City > Gasabo", "Gasabo", "1")},
+	{"has_district", []types.XValue{xs("I live in gasabo"), xs("kigali")}, locationResult("Rwanda > Kigali City > Gasabo", "Gasabo", "0.9")},
+	{"has_district", []types.XValue{xs("Gasabo")}, locationResult("Rwanda > Kigali City > Gasabo", "Gasabo", "1")},
 	{"has_district", []types.XValue{xs("xyz"), xs("kigali")}, falseResult},
 	{"has_district", []types.XValue{ERROR}, ERROR},
 
-	{"has_ward", []types.XValue{xs("Gisozi"), xs("Gasabo"), xs("kigali")}, result(xs("Rwanda > Kigali City > Gasabo > Gisozi"))},
-	{"has_ward", []types.XValue{xs("I live in gisozi"), xs("Gasabo"), xs("kigali")}, result(xs("Rwanda > Kigali City > Gasabo > Gisozi"))},
-	{"has_ward", []types.XValue{xs("Gisozi")}, result(xs("Rwanda > Kigali City > Gasabo > Gisozi"))},
+	{"has_ward", []types.XValue{xs("Gisozi"), xs("Gasabo"), xs("kigali")}, locationResult("Rwanda > Kigali City > Gasabo > Gisozi", "Gisozi", "1")},
+	{"has_ward", []types.XValue{xs("I live in gisozi"), xs("Gasabo"), xs("kigali")}, locationResult("Rwanda > Kigali City > Gasabo > Gisozi", "Gisozi", "0.9")},
+	{"has_ward", []types.XValue{xs("Gisozi")}, locationResult("Rwanda > Kigali City > Gasabo > Gisozi", "Gisozi", "1")},
+	{"has_ward", []types.XValue{xs("muhima"), xs("Nyarugenge"), xs("kigali")}, locationResult("Rwanda > Kigali City > Nyarugenge > Muhíma", "Muhíma", "0.8")},
+	{"has_ward", []types.XValue{xs("MUHÍMA")}, locationResult("Rwanda > Kigali City > Nyarugenge > Muhíma", "Muhíma", "1")},
 	{"has_ward", []types.XValue{xs("xyz"), xs("Gasabo"), xs("kigali")}, falseResult},
 	{"has_ward", []types.XValue{ERROR}, ERROR},
 
+	{"has_location_within", []types.XValue{xs("-1.9247,30.0633"), xn("5"), xn("-1.9441"), xn("30.0619")}, resultWithExtra(xs("-1.9247,30.0633"), types.NewXObject(map[string]types.XValue{"distance": xn("2.16")}))},
+	{"has_location_within", []types.XValue{xs(" -1.9247, 30.0633 "), xn("3"), xn("-1.9441"), xn("30.0619")}, resultWithExtra(xs("-1.9247,30.0633"), types.NewXObject(map[string]types.XValue{"distance": xn("2.16")}))},
+	{"has_location_within", []types.XValue{xs("rwanda > kigali city > gasabo > gisozi"), xn("5"), xn("-1.9441"), xn("30.0619")}, resultWithExtra(xs("Rwanda > Kigali City > Gasabo > Gisozi"), types.NewXObject(map[string]types.XValue{"distance": xn("2.16")}))},
+	{"has_location_within", []types.XValue{xs("-1.9247,30.0633"), xn("2"), xn("-1.9441"), xn("30.0619")}, falseResult},
+	{"has_location_within", []types.XValue{xs("Rwanda > Kigali City > Gasabo > Ndera"), xn("5"), xn("-1.9441"), xn("30.0619")}, falseResult}, // no coordinates
+	{"has_location_within", []types.XValue{xs("Rwanda > Boston"), xn("5"), xn("-1.9441"), xn("30.0619")}, falseResult},
+	{"has_location_within", []types.XValue{xs("95,30"), xn("5"), xn("-1.9441"), xn("30.0619")}, falseResult},
+	{"has_location_within", []types.XValue{xs("Gisozi"), xn("5"), xn("-1.9441"), xn("30.0619")}, falseResult},
+	{"has_location_within", []types.XValue{xs("-1.9247,30.0633"), xs("far"), xn("-1.9441"), xn("30.0619")}, ERROR},
+	{"has_location_within", []types.XValue{xs("-1.9247,30.0633"), xn("5"), xn("-100"), xn("30.0619")}, ERROR},
+	{"has_location_within", []types.XValue{ERROR, xn("5"), xn("-1.9441"), xn("30.0619")}, ERROR},
+	{"has_location_within", []types.XValue{xs("-1.9247,30.0633"), xn("5")}, ERROR},
+
 	{
 		"has_category",
 		[]types.XValue{
@@ -473,7 +523,7 @@ func TestTests(t *testing.T) {
 	locations, err := envs.ReadLocationHierarchy([]byte(locationHierarchyJSON))
 	require.NoError(t, err)
 
-	env = flows.NewEnvironment(env, flows.NewLocationAssets([]assets.LocationHierarchy{locations}))
+	env = flows.NewEnvironment(env, flows.NewLocationAssets([]assets.LocationHierarchy{locations}), nil)
 
 	for _, tc := range testTests {
 		testID := fmt.Sprintf("%s(%#v)", tc.name, tc.args)
@@ -577,3 +627,39 @@ func TestHasPhone(t *testing.T) {
 		test.AssertXEqual(t, expected, actual, "has_phone mismatch for input=%s country=%s", tc.input, tc.country)
 	}
 }
+
+func TestHasNumberLocales(t *testing.T) {
+	tests := []struct {
+		input    string
+		lang     envs.Language
+		format   *envs.NumberFormat
+		expected string
+	}{
+		{"it's 3,5 kg", "fra", &envs.NumberFormat{DecimalSymbol: ",", DigitGroupingSymbol: "."}, "3.5"},
+		{"1.234,5", "fra", &envs.NumberFormat{DecimalSymbol: ",", DigitGroupingSymbol: "."}, "1234.5"},
+		{"٣٬٥٠٠", "ara", envs.DefaultNumberFormat, "3500"},
+		{"twenty five", "eng", envs.DefaultNumberFormat, ""}, // spoken numbers must be enabled
+		{"twenty five", "eng", &envs.NumberFormat{DecimalSymbol: ".", DigitGroupingSymbol: ",", SpokenNumbers: true}, "25"},
+		{"3 or four", "eng", &envs.NumberFormat{DecimalSymbol: ".", DigitGroupingSymbol: ",", SpokenNumbers: true}, "3"},
+		{"soixante-dix", "fra", &envs.NumberFormat{DecimalSymbol: ",", DigitGroupingSymbol: ".", SpokenNumbers: true}, "70"},
+		{"veintitrés", "spa", &envs.NumberFormat{DecimalSymbol: ",", DigitGroupingSymbol: ".", SpokenNumbers: true}, "23"},
+		{"twenty five", "kin", &envs.NumberFormat{DecimalSymbol: ".", DigitGroupingSymbol: ",", SpokenNumbers: true}, ""},
+	}
+
+	for _, tc := range tests {
+		env := envs.NewBuilder().WithAllowedLanguages([]envs.Language{tc.lang}).WithNumberFormat(tc.format).Build()
+
+		var expected types.XValue = falseResult
+		if tc.expected != "" {
+			expected = cases.NewTrueResult(xn(tc.expected))
+		}
+
+		test.AssertXEqual(t, expected, cases.HasNumber(env, xs(tc.input)), "has_number mismatch for input=%s lang=%s", tc.input, tc.lang)
+	}
+
+	// spoken numbers are also tested against the value of other number tests
+	env := envs.NewBuilder().WithAllowedLanguages([]envs.Language{"eng"}).WithNumberFormat(&envs.NumberFormat{DecimalSymbol: ".", DigitGroupingSymbol: ",", SpokenNumbers: true}).Build()
+
+	test.AssertXEqual(t, cases.NewTrueResult(xn("15")), cases.HasNumberGT(env, xs("one or fifteen"), xn("10")))
+	test.AssertXEqual(t, falseResult, cases.HasNumberGT(env, xs("one or two"), xn("10")))
+}
@@ -32,8 +32,26 @@ var altNumerals = map[rune]rune{
 	'৭': '7',
 	'৮': '8',
 	'৯': '9',
+
+	// Extended Arabic-Indic (Persian, Urdu)
+	'۰': '0',
+	'۱': '1',
+	'۲': '2',
+	'۳': '3',
+	'۴': '4',
+	'۵': '5',
+	'۶': '6',
+	'۷': '7',
+	'۸': '8',
+	'۹': '9',
 }
 
+// Arabic script has its own separators which are used regardless of the environment's number format
+const (
+	arabicDecimalSymbol       = "٫"
+	arabicDigitGroupingSymbol = "٬"
+)
+
 func numeralMapper(r rune) rune {
 	n, mapped := altNumerals[r]
 	if mapped {
@@ -48,9 +66,11 @@ func ParseDecimal(val string, format *envs.NumberFormat) (decimal.Decimal, error
 
 	// remove digit grouping symbol
 	cleaned = strings.Replace(cleaned, format.DigitGroupingSymbol, "", -1)
+	cleaned = strings.Replace(cleaned, arabicDigitGroupingSymbol, "", -1)
 
 	// replace non-period decimal symbols
 	cleaned = strings.Replace(cleaned, format.DecimalSymbol, ".", -1)
+	cleaned = strings.Replace(cleaned, arabicDecimalSymbol, ".", -1)
 
 	// replace non-Arabic (0-9) numerals with their equivalents
 	cleaned = strings.Map(numeralMapper, cleaned)
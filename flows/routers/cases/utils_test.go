@@ -34,6 +34,10 @@ func TestParseDecimal(t *testing.T) {
 		{"١.٢٣٤,٥٦٧", dec("1234.567"), spaFormat},
 		{"٠.٨٩", dec("0.89"), envs.DefaultNumberFormat},
 		{".١٢٣٤", dec("0.1234"), envs.DefaultNumberFormat},
+		{"١٬٢٣٤٫٥٦٧", dec("1234.567"), envs.DefaultNumberFormat},
+
+		// Extended Arabic-Indic
+		{"۱۲۳۴", dec("1234"), envs.DefaultNumberFormat},
 
 		// Bengali
 		{"১,২৩৪.৫৬৭", dec("1234.567"), envs.DefaultNumberFormat},
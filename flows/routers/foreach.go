@@ -0,0 +1,265 @@
+package routers
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+func init() {
+	registerType(TypeForEach, readForEachRouter)
+}
+
+// TypeForEach is the type for a foreach router
+const TypeForEach string = "foreach"
+
+const (
+	// DefaultForEachMaxIterations is the number of items iterated over if a foreach router doesn't specify a cap
+	DefaultForEachMaxIterations = 20
+
+	// MaxForEachMaxIterations is the largest cap a foreach router can specify
+	MaxForEachMaxIterations = 100
+)
+
+// ForEachRouter is a router which iterates over the items of an array. The items are evaluated when the node is first
+// visited, and each time the node is visited after that, it takes its item category for the next item, with that
+// item available in the context as `@item` and its index as `@index`. The item category should lead to the nodes
+// which make up the body of the loop and which eventually lead back to this node. Once all items have been iterated
+// over, or the cap on iterations is reached, the router takes its done category instead. If the run leaves the body
+// of the loop for a node which can't lead back to this node, the loop ends, and a later visit starts a new one.
+//
+// If a result name is set, a result is saved for each iteration with the item as its value and the index in its
+// extra, and a final result is saved with the number of iterations as its value.
+type ForEachRouter struct {
+	baseRouter
+
+	items            string
+	maxIterations    int
+	itemCategoryUUID flows.CategoryUUID
+	doneCategoryUUID flows.CategoryUUID
+
+	bodyOnce sync.Once
+	body     map[flows.NodeUUID]bool
+}
+
+// NewForEach creates a new foreach router
+func NewForEach(resultName string, categories []flows.Category, items string, maxIterations int, itemCategoryUUID, doneCategoryUUID flows.CategoryUUID) *ForEachRouter {
+	return &ForEachRouter{
+		baseRouter:       newBaseRouter(TypeForEach, nil, resultName, categories),
+		items:            items,
+		maxIterations:    maxIterations,
+		itemCategoryUUID: itemCategoryUUID,
+		doneCategoryUUID: doneCategoryUUID,
+	}
+}
+
+// MaxIterations returns the maximum number of items which will be iterated over
+func (r *ForEachRouter) MaxIterations() int {
+	if r.maxIterations == 0 {
+		return DefaultForEachMaxIterations
+	}
+	return r.maxIterations
+}
+
+// Validate validates the arguments for this router
+func (r *ForEachRouter) Validate(flow flows.Flow, exits []flows.Exit) error {
+	if r.wait != nil {
+		return errors.New("foreach routers can't have a wait")
+	}
+	if !r.isValidCategory(r.itemCategoryUUID) {
+		return errors.Errorf("item category %s is not a valid category", r.itemCategoryUUID)
+	}
+	if !r.isValidCategory(r.doneCategoryUUID) {
+		return errors.Errorf("done category %s is not a valid category", r.doneCategoryUUID)
+	}
+	if r.itemCategoryUUID == r.doneCategoryUUID {
+		return errors.New("item and done categories must be different")
+	}
+
+	return r.validate(flow, exits)
+}
+
+// Route determines which exit to take from a node
+func (r *ForEachRouter) Route(run flows.Run, step flows.Step, logEvent flows.EventCallback) (flows.ExitUUID, string, error) {
+	loop := run.Loop(step.NodeUUID())
+
+	// if we're not already looping, this is the first visit so start a new loop, otherwise move to the next item
+	if loop == nil {
+		loop = flows.NewLoop(step.NodeUUID(), r.evaluateItems(run, step))
+		run.StartLoop(loop)
+	} else {
+		loop.Index++
+	}
+
+	if loop.Done() {
+		run.EndLoop(step.NodeUUID())
+
+		exit, err := r.routeToCategory(run, step, r.doneCategoryUUID, strconv.Itoa(len(loop.Items)), "", nil, logEvent)
+		return exit, "", err
+	}
+
+	item, _ := types.ToXText(run.Environment(), loop.Item())
+	extra := types.NewXObject(map[string]types.XValue{"index": types.NewXNumberFromInt(loop.Index)})
+
+	exit, err := r.routeToCategory(run, step, r.itemCategoryUUID, item.Native(), item.Native(), extra, logEvent)
+	return exit, item.Native(), err
+}
+
+// InLoop returns whether the node with the given UUID is part of the body of the loop of the given node, which is
+// this router's node. The body is the nodes which can be reached from the item category, and which can lead back to
+// this router's node.
+func (r *ForEachRouter) InLoop(flow flows.Flow, node flows.Node, uuid flows.NodeUUID) bool {
+	r.bodyOnce.Do(func() { r.body = r.loopBody(flow, node) })
+
+	return r.body[uuid]
+}
+
+// works out the nodes which make up the body of our loop
+func (r *ForEachRouter) loopBody(flow flows.Flow, node flows.Node) map[flows.NodeUUID]bool {
+	destinations := func(uuid flows.NodeUUID) []flows.NodeUUID {
+		n := flow.GetNode(uuid)
+		if n == nil {
+			return nil
+		}
+		dests := make([]flows.NodeUUID, 0, len(n.Exits()))
+		for _, e := range n.Exits() {
+			if e.DestinationUUID() != "" {
+				dests = append(dests, e.DestinationUUID())
+			}
+		}
+		return dests
+	}
+
+	// find the nodes which can be reached from the item category without going through this node
+	reachable := make(map[flows.NodeUUID]bool)
+	queue := make([]flows.NodeUUID, 0)
+	for _, c := range r.categories {
+		if c.UUID() == r.itemCategoryUUID {
+			for _, e := range node.Exits() {
+				if e.UUID() == c.ExitUUID() && e.DestinationUUID() != "" && e.DestinationUUID() != node.UUID() {
+					queue = append(queue, e.DestinationUUID())
+					reachable[e.DestinationUUID()] = true
+				}
+			}
+		}
+	}
+	for len(queue) > 0 {
+		uuid := queue[0]
+		queue = queue[1:]
+
+		for _, dest := range destinations(uuid) {
+			if dest != node.UUID() && !reachable[dest] {
+				reachable[dest] = true
+				queue = append(queue, dest)
+			}
+		}
+	}
+
+	// and keep those which can lead back to this node, going backwards from it
+	body := make(map[flows.NodeUUID]bool)
+	queue = append(queue, node.UUID())
+	for len(queue) > 0 {
+		uuid := queue[0]
+		queue = queue[1:]
+
+		for src := range reachable {
+			if !body[src] && slices.Contains(destinations(src), uuid) {
+				body[src] = true
+				queue = append(queue, src)
+			}
+		}
+	}
+	return body
+}
+
+// evaluates our items template to an array, returning up to our maximum number of items as JSON
+func (r *ForEachRouter) evaluateItems(run flows.Run, step flows.Step) []json.RawMessage {
+	value, err := run.EvaluateTemplateValue(r.items)
+	if err != nil {
+		run.LogError(step, err)
+	}
+
+	array, xerr := types.ToXArray(run.Environment(), value)
+	if xerr != nil {
+		run.LogError(step, xerr)
+	}
+
+	count := array.Count()
+	if count > r.MaxIterations() {
+		count = r.MaxIterations()
+	}
+
+	items := make([]json.RawMessage, count)
+	for i := range items {
+		asJSON, xerr := types.ToXJSON(array.Get(i))
+		if xerr != nil {
+			items[i] = json.RawMessage(`null`)
+		} else {
+			items[i] = json.RawMessage(asJSON.Native())
+		}
+	}
+	return items
+}
+
+// EnumerateTemplates enumerates all expressions on this object and its children
+func (r *ForEachRouter) EnumerateTemplates(localization flows.Localization, include func(envs.Language, string)) {
+	include(envs.NilLanguage, r.items)
+}
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type forEachRouterEnvelope struct {
+	baseRouterEnvelope
+
+	Items            string             `json:"items"                    validate:"required"`
+	MaxIterations    int                `json:"max_iterations,omitempty" validate:"omitempty,min=1,max=100"`
+	ItemCategoryUUID flows.CategoryUUID `json:"item_category_uuid"       validate:"required,uuid4"`
+	DoneCategoryUUID flows.CategoryUUID `json:"done_category_uuid"       validate:"required,uuid4"`
+}
+
+func readForEachRouter(data json.RawMessage) (flows.Router, error) {
+	e := &forEachRouterEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	r := &ForEachRouter{
+		items:            e.Items,
+		maxIterations:    e.MaxIterations,
+		itemCategoryUUID: e.ItemCategoryUUID,
+		doneCategoryUUID: e.DoneCategoryUUID,
+	}
+
+	if err := r.unmarshal(&e.baseRouterEnvelope); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// MarshalJSON marshals this router into JSON
+func (r *ForEachRouter) MarshalJSON() ([]byte, error) {
+	e := &forEachRouterEnvelope{
+		Items:            r.items,
+		MaxIterations:    r.maxIterations,
+		ItemCategoryUUID: r.itemCategoryUUID,
+		DoneCategoryUUID: r.doneCategoryUUID,
+	}
+
+	if err := r.marshal(&e.baseRouterEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
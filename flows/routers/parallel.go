@@ -0,0 +1,174 @@
+package routers
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerType(TypeParallel, readParallelRouter)
+}
+
+// TypeParallel is the type for a parallel router
+const TypeParallel string = "parallel"
+
+// ParallelRouter is a router which forks execution into branches. Each branch is a node whose actions are executed
+// concurrently with those of the other branches, e.g. to call several independent webhooks at once. Branch nodes can
+// only contain actions which call services and save results, can't have their own routers, and their exits can't
+// have destinations. Once all branches complete, their events and results are added to the run in the order of the
+// branches and the router takes its completed category. If a timeout is set and the branches haven't all completed
+// within it, their remaining service calls are cancelled and the router takes its timeout category instead.
+type ParallelRouter struct {
+	baseRouter
+
+	branches              []flows.NodeUUID
+	timeoutSeconds        int
+	completedCategoryUUID flows.CategoryUUID
+	timeoutCategoryUUID   flows.CategoryUUID
+}
+
+// NewParallel creates a new parallel router
+func NewParallel(resultName string, categories []flows.Category, branches []flows.NodeUUID, timeoutSeconds int, completedCategoryUUID, timeoutCategoryUUID flows.CategoryUUID) *ParallelRouter {
+	return &ParallelRouter{
+		baseRouter:            newBaseRouter(TypeParallel, nil, resultName, categories),
+		branches:              branches,
+		timeoutSeconds:        timeoutSeconds,
+		completedCategoryUUID: completedCategoryUUID,
+		timeoutCategoryUUID:   timeoutCategoryUUID,
+	}
+}
+
+// Branches returns the UUIDs of the branch nodes of this router
+func (r *ParallelRouter) Branches() []flows.NodeUUID { return r.branches }
+
+// BranchTimeout returns how long branches can take to complete, or zero for no limit
+func (r *ParallelRouter) BranchTimeout() time.Duration {
+	return time.Duration(r.timeoutSeconds) * time.Second
+}
+
+// Validate validates the arguments for this router
+func (r *ParallelRouter) Validate(flow flows.Flow, exits []flows.Exit) error {
+	if r.wait != nil {
+		return errors.New("parallel routers can't have a wait")
+	}
+
+	if !r.isValidCategory(r.completedCategoryUUID) {
+		return errors.Errorf("completed category %s is not a valid category", r.completedCategoryUUID)
+	}
+	if r.timeoutSeconds > 0 && r.timeoutCategoryUUID == "" {
+		return errors.New("timeout category is required if timeout is set")
+	}
+	if r.timeoutCategoryUUID != "" && !r.isValidCategory(r.timeoutCategoryUUID) {
+		return errors.Errorf("timeout category %s is not a valid category", r.timeoutCategoryUUID)
+	}
+
+	seen := make(map[flows.NodeUUID]bool, len(r.branches))
+
+	for _, uuid := range r.branches {
+		if seen[uuid] {
+			return errors.Errorf("branch node %s is repeated", uuid)
+		}
+		seen[uuid] = true
+
+		if err := r.validateBranch(flow, uuid); err != nil {
+			return err
+		}
+	}
+
+	return r.validate(flow, exits)
+}
+
+func (r *ParallelRouter) validateBranch(flow flows.Flow, uuid flows.NodeUUID) error {
+	node := flow.GetNode(uuid)
+	if node == nil {
+		return errors.Errorf("branch node %s doesn't exist", uuid)
+	}
+	if node.Router() != nil {
+		return errors.Errorf("branch node %s can't have a router", uuid)
+	}
+
+	for _, exit := range node.Exits() {
+		if exit.DestinationUUID() != "" {
+			return errors.Errorf("branch node %s can't have exits with destinations", uuid)
+		}
+	}
+
+	for _, action := range node.Actions() {
+		if concurrent, ok := action.(flows.ConcurrentAction); !ok || !concurrent.Concurrent() {
+			return errors.Errorf("action type '%s' can't be used in branch node %s", action.Type(), uuid)
+		}
+	}
+
+	return nil
+}
+
+// Route determines which exit to take from a node once all branches have completed
+func (r *ParallelRouter) Route(run flows.Run, step flows.Step, logEvent flows.EventCallback) (flows.ExitUUID, string, error) {
+	exit, err := r.routeToCategory(run, step, r.completedCategoryUUID, strconv.Itoa(len(r.branches)), "", nil, logEvent)
+	return exit, "", err
+}
+
+// RouteTimeout determines which exit to take from a node when its branches didn't complete in time
+func (r *ParallelRouter) RouteTimeout(run flows.Run, step flows.Step, logEvent flows.EventCallback) (flows.ExitUUID, error) {
+	if r.timeoutCategoryUUID == "" {
+		return "", errors.New("can't call route timeout on parallel router with no timeout category")
+	}
+
+	return r.routeToCategory(run, step, r.timeoutCategoryUUID, strconv.Itoa(len(r.branches)), "", nil, logEvent)
+}
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type parallelRouterEnvelope struct {
+	baseRouterEnvelope
+
+	Branches              []flows.NodeUUID   `json:"branches"                        validate:"required,min=1,dive,uuid4"`
+	TimeoutSeconds        int                `json:"timeout_seconds,omitempty"       validate:"omitempty,min=1"`
+	CompletedCategoryUUID flows.CategoryUUID `json:"completed_category_uuid"         validate:"required,uuid4"`
+	TimeoutCategoryUUID   flows.CategoryUUID `json:"timeout_category_uuid,omitempty" validate:"omitempty,uuid4"`
+}
+
+func readParallelRouter(data json.RawMessage) (flows.Router, error) {
+	e := &parallelRouterEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	r := &ParallelRouter{
+		branches:              e.Branches,
+		timeoutSeconds:        e.TimeoutSeconds,
+		completedCategoryUUID: e.CompletedCategoryUUID,
+		timeoutCategoryUUID:   e.TimeoutCategoryUUID,
+	}
+
+	if err := r.unmarshal(&e.baseRouterEnvelope); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// MarshalJSON marshals this router into JSON
+func (r *ParallelRouter) MarshalJSON() ([]byte, error) {
+	e := &parallelRouterEnvelope{
+		Branches:              r.branches,
+		TimeoutSeconds:        r.timeoutSeconds,
+		CompletedCategoryUUID: r.completedCategoryUUID,
+		TimeoutCategoryUUID:   r.timeoutCategoryUUID,
+	}
+
+	if err := r.marshal(&e.baseRouterEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
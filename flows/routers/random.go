@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/nyaruka/gocommon/jsonx"
-	"github.com/nyaruka/gocommon/random"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/utils"
 
@@ -37,7 +36,7 @@ func (r *RandomRouter) Validate(flow flows.Flow, exits []flows.Exit) error {
 // Route determines which exit to take from a node
 func (r *RandomRouter) Route(run flows.Run, step flows.Step, logEvent flows.EventCallback) (flows.ExitUUID, string, error) {
 	// pick a random category
-	rand := random.Decimal()
+	rand := run.Session().Sources().Random()
 	categoryNum := rand.Mul(decimal.New(int64(len(r.categories)), 0)).IntPart()
 	categoryUUID := r.categories[categoryNum].UUID()
 
@@ -31,6 +31,8 @@ type Case struct {
 	Type         string             `json:"type"                   validate:"required"`
 	Arguments    []string           `json:"arguments,omitempty"    engine:"localized,evaluated"`
 	CategoryUUID flows.CategoryUUID `json:"category_uuid"          validate:"required"`
+
+	test *types.XFunction // the test function if it's been resolved
 }
 
 // NewCase creates a new case
@@ -91,9 +93,15 @@ func NewSwitch(wait flows.Wait, resultName string, categories []flows.Category,
 	}
 }
 
+// Operand returns the template which is evaluated and tested against the cases
+func (r *SwitchRouter) Operand() string { return r.operand }
+
 // Cases returns the cases for this switch router
 func (r *SwitchRouter) Cases() []*Case { return r.cases }
 
+// DefaultCategoryUUID returns the category picked when no case matches
+func (r *SwitchRouter) DefaultCategoryUUID() flows.CategoryUUID { return r.defaultCategoryUUID }
+
 // Validate validates the arguments for this router
 func (r *SwitchRouter) Validate(flow flows.Flow, exits []flows.Exit) error {
 	// check the default category is valid
@@ -116,6 +124,17 @@ func (r *SwitchRouter) Validate(flow flows.Flow, exits []flows.Exit) error {
 	return r.validate(flow, exits)
 }
 
+// ResolveTests looks up the test function of each case so that it doesn't have to be looked up during routing
+func (r *SwitchRouter) ResolveTests() error {
+	for _, c := range r.cases {
+		c.test = cases.XTESTS[strings.ToLower(c.Type)]
+		if c.test == nil {
+			return errors.Errorf("unknown case test '%s'", c.Type)
+		}
+	}
+	return nil
+}
+
 // Route determines which exit to take from a node
 func (r *SwitchRouter) Route(run flows.Run, step flows.Step, logEvent flows.EventCallback) (flows.ExitUUID, string, error) {
 	env := run.Environment()
@@ -157,10 +176,11 @@ func (r *SwitchRouter) Route(run flows.Run, step flows.Step, logEvent flows.Even
 
 func (r *SwitchRouter) matchCase(run flows.Run, step flows.Step, operand types.XValue) (string, flows.CategoryUUID, *types.XObject, error) {
 	for _, c := range r.cases {
-		test := strings.ToLower(c.Type)
-
-		// try to look up our function
-		xtest := cases.XTESTS[test]
+		// try to look up our function if it hasn't already been resolved
+		xtest := c.test
+		if xtest == nil {
+			xtest = cases.XTESTS[strings.ToLower(c.Type)]
+		}
 		if xtest == nil {
 			return "", "", nil, errors.Errorf("unknown case test '%s'", c.Type)
 		}
@@ -202,7 +222,7 @@ func (r *SwitchRouter) matchCase(run flows.Run, step flows.Step, operand types.X
 
 			extraAsObject, isObject := extra.(*types.XObject)
 			if extra != nil && !isObject {
-				run.LogError(step, errors.Errorf("test %s returned non-object extra", strings.ToUpper(test)))
+				run.LogError(step, errors.Errorf("test %s returned non-object extra", strings.ToUpper(c.Type)))
 			}
 
 			resultAsStr, xerr := types.ToXText(run.Environment(), match)
@@ -223,6 +243,8 @@ func (r *SwitchRouter) EnumerateTemplates(localization flows.Localization, inclu
 	include(envs.NilLanguage, r.operand)
 
 	inspect.Templates(r.cases, localization, include)
+
+	r.baseRouter.EnumerateTemplates(localization, include)
 }
 
 // EnumerateDependencies enumerates all dependencies on this object and its children
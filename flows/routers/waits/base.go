@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"time"
 
-	"github.com/nyaruka/gocommon/dates"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/utils"
 
@@ -53,7 +52,7 @@ func (w *baseWait) Timeout() flows.Timeout { return w.timeout }
 func (w *baseWait) expiresOn(run flows.Run) *time.Time {
 	expiresAfterMins := run.Flow().ExpireAfterMinutes()
 	if expiresAfterMins > 0 {
-		dt := dates.Now().Add(time.Duration(int64(expiresAfterMins) * int64(time.Minute)))
+		dt := run.Session().Sources().Now().Add(time.Duration(int64(expiresAfterMins) * int64(time.Minute)))
 		return &dt
 	}
 	return nil
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"time"
 
-	"github.com/nyaruka/gocommon/dates"
 	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/gocommon/urns"
 	"github.com/nyaruka/goflow/flows"
@@ -74,7 +73,8 @@ func (w *DialWait) Begin(run flows.Run, log flows.EventCallback) bool {
 
 	// we don't want to expire the flow whilst the contact is in the forwarded call and appearing "inactive" in the
 	// flow so calculate an expiry guaranteed to be after the wait returns
-	expiresOn := dates.Now().Add(w.dialLimit + w.callLimit + time.Second*30)
+	expiresOn := run.Session().Sources().Now().Add(w.dialLimit + w.callLimit + time.Second*30)
+	run.SetExpiresOn(&expiresOn)
 
 	log(events.NewDialWait(urn, int(w.dialLimit/time.Second), int(w.callLimit/time.Second), &expiresOn))
 
@@ -0,0 +1,114 @@
+package waits
+
+import (
+	"encoding/json"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeDigits, readDigitsWait)
+
+	utils.RegisterValidatorAlias("dial_pad_key", "eq=0|eq=1|eq=2|eq=3|eq=4|eq=5|eq=6|eq=7|eq=8|eq=9|eq=*|eq=#", func(validator.FieldError) string {
+		return "is not a valid dial pad key"
+	})
+}
+
+// TypeDigits is the type of our digits wait
+const TypeDigits string = "digits"
+
+// DigitsWait is a wait which waits for the contact to enter digits on their dial pad during an IVR call. Gathering
+// digits stops when `max_digits` have been entered, the `finish_on_key` key is pressed or the timeout is reached.
+type DigitsWait struct {
+	baseWait
+
+	maxDigits   int
+	finishOnKey string
+}
+
+// NewDigitsWait creates a new digits wait
+func NewDigitsWait(timeout *Timeout, maxDigits int, finishOnKey string) *DigitsWait {
+	return &DigitsWait{
+		baseWait:    newBaseWait(TypeDigits, timeout),
+		maxDigits:   maxDigits,
+		finishOnKey: finishOnKey,
+	}
+}
+
+// MaxDigits returns the maximum number of digits to gather, or zero for no maximum
+func (w *DigitsWait) MaxDigits() int { return w.maxDigits }
+
+// FinishOnKey returns the key which ends gathering, if any
+func (w *DigitsWait) FinishOnKey() string { return w.finishOnKey }
+
+// AllowedFlowTypes returns the flow types which this wait is allowed to occur in
+func (w *DigitsWait) AllowedFlowTypes() []flows.FlowType {
+	return []flows.FlowType{flows.FlowTypeVoice}
+}
+
+// Begin beings waiting at this wait
+func (w *DigitsWait) Begin(run flows.Run, log flows.EventCallback) bool {
+	var timeoutSeconds *int
+	if w.timeout != nil {
+		seconds := w.timeout.Seconds()
+		timeoutSeconds = &seconds
+	}
+
+	expiresOn := w.expiresOn(run)
+	run.SetExpiresOn(expiresOn)
+
+	log(events.NewDigitsWait(w.maxDigits, w.finishOnKey, timeoutSeconds, expiresOn))
+
+	return true
+}
+
+// Accept returns whether this wait accepts the given resume
+func (w *DigitsWait) Accepts(resume flows.Resume) bool {
+	switch resume.Type() {
+	case resumes.TypeDigits, resumes.TypeRunExpiration, resumes.TypeExpiration:
+		return true
+	case resumes.TypeWaitTimeout:
+		return w.timeout != nil
+	}
+	return false
+}
+
+var _ flows.Wait = (*DigitsWait)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type digitsWaitEnvelope struct {
+	baseWaitEnvelope
+
+	MaxDigits   int    `json:"max_digits,omitempty"    validate:"omitempty,min=1,max=100"`
+	FinishOnKey string `json:"finish_on_key,omitempty" validate:"omitempty,dial_pad_key"`
+}
+
+func readDigitsWait(data json.RawMessage) (flows.Wait, error) {
+	e := &digitsWaitEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	w := &DigitsWait{maxDigits: e.MaxDigits, finishOnKey: e.FinishOnKey}
+
+	return w, w.unmarshal(&e.baseWaitEnvelope)
+}
+
+// MarshalJSON marshals this wait into JSON
+func (w *DigitsWait) MarshalJSON() ([]byte, error) {
+	e := &digitsWaitEnvelope{MaxDigits: w.maxDigits, FinishOnKey: w.finishOnKey}
+
+	if err := w.marshal(&e.baseWaitEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
@@ -0,0 +1,70 @@
+package waits_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/flows/routers/waits"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigitsWait(t *testing.T) {
+	session, _, err := test.CreateTestVoiceSession("")
+	require.NoError(t, err)
+	run := session.Runs()[0]
+
+	// finish key must be a dial pad key
+	_, err = waits.ReadWait([]byte(`{"type": "digits", "finish_on_key": "x"}`))
+	assert.EqualError(t, err, "field 'finish_on_key' is not a valid dial pad key")
+
+	_, err = waits.ReadWait([]byte(`{"type": "digits", "max_digits": -1}`))
+	assert.EqualError(t, err, "field 'max_digits' must be greater than or equal to 1")
+
+	// everything is optional
+	wait, err := waits.ReadWait([]byte(`{"type": "digits"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, waits.TypeDigits, wait.Type())
+	assert.Equal(t, 0, wait.(*waits.DigitsWait).MaxDigits())
+	assert.Equal(t, "", wait.(*waits.DigitsWait).FinishOnKey())
+	assert.Nil(t, wait.Timeout())
+	assert.Equal(t, `{"type":"digits"}`, string(jsonx.MustMarshal(wait)))
+
+	wait, err = waits.ReadWait([]byte(`{"type": "digits", "max_digits": 4, "finish_on_key": "#", "timeout": {"seconds": 10, "category_uuid": "2c2ba0ce-ba03-4a80-9bca-8fcd5d6ec8c5"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, wait.(*waits.DigitsWait).MaxDigits())
+	assert.Equal(t, "#", wait.(*waits.DigitsWait).FinishOnKey())
+	assert.Equal(t, 10, wait.Timeout().Seconds())
+	assert.Equal(t, []flows.FlowType{flows.FlowTypeVoice}, wait.AllowedFlowTypes())
+
+	// test marshalling definition wait
+	assert.Equal(t, `{"type":"digits","timeout":{"seconds":10,"category_uuid":"2c2ba0ce-ba03-4a80-9bca-8fcd5d6ec8c5"},"max_digits":4,"finish_on_key":"#"}`, string(jsonx.MustMarshal(wait)))
+
+	// try activating the wait
+	log := test.NewEventLog()
+	begun := wait.Begin(run, log.Log)
+
+	assert.True(t, begun)
+	assert.Equal(t, 1, len(log.Events))
+	assert.Equal(t, "digits_wait", log.Events[0].Type())
+
+	event := log.Events[0].(*events.DigitsWaitEvent)
+	assert.Equal(t, 4, event.MaxDigits)
+	assert.Equal(t, "#", event.FinishOnKey)
+	assert.Equal(t, 10, *event.TimeoutSeconds)
+
+	// check which resumes are accepted
+	assert.True(t, wait.Accepts(resumes.NewDigits(nil, nil, "1234")))
+	assert.True(t, wait.Accepts(resumes.NewWaitTimeout(nil, nil)))
+	assert.True(t, wait.Accepts(resumes.NewRunExpiration(nil, nil)))
+	assert.False(t, wait.Accepts(resumes.NewDial(nil, nil, flows.NewDial(flows.DialStatusAnswered, 5))))
+
+	// a wait without a timeout can't be resumed by one
+	wait = waits.NewDigitsWait(nil, 1, "")
+	assert.False(t, wait.Accepts(resumes.NewWaitTimeout(nil, nil)))
+}
@@ -2,8 +2,12 @@ package waits
 
 import (
 	"encoding/json"
+	"strings"
+	"time"
 
 	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
 	"github.com/nyaruka/goflow/flows/resumes"
@@ -21,6 +25,68 @@ func init() {
 // TypeMsg is the type of our message wait
 const TypeMsg string = "msg"
 
+// USSD puts a message wait into USSD mode, where the prompt must fit in a single USSD response and the network will end
+// the session if the contact doesn't reply quickly enough
+type USSD struct {
+	MaxResponseLength_ int `json:"max_response_length,omitempty" validate:"omitempty,min=20"`
+	SessionTimeout_    int `json:"session_timeout,omitempty"     validate:"omitempty,min=1"`
+}
+
+// NewUSSD creates a new USSD mode, where zero values mean use the defaults
+func NewUSSD(maxResponseLength, sessionTimeout int) *USSD {
+	return &USSD{MaxResponseLength_: maxResponseLength, SessionTimeout_: sessionTimeout}
+}
+
+// MaxResponseLength returns the maximum length of the prompt sent to the contact
+func (u *USSD) MaxResponseLength() int {
+	if u.MaxResponseLength_ > 0 {
+		return u.MaxResponseLength_
+	}
+	return flows.USSDMaxResponseLength
+}
+
+// SessionTimeout returns the number of seconds after which the network ends the session
+func (u *USSD) SessionTimeout() int {
+	if u.SessionTimeout_ > 0 {
+		return u.SessionTimeout_
+	}
+	return flows.USSDSessionTimeoutSeconds
+}
+
+// the run can't outlive the USSD session so expire it when the session times out, if that's sooner
+func (u *USSD) expiresOn(run flows.Run, flowExpiresOn *time.Time) *time.Time {
+	sessionEnd := run.Session().Sources().Now().Add(time.Duration(u.SessionTimeout()) * time.Second)
+	if flowExpiresOn != nil && flowExpiresOn.Before(sessionEnd) {
+		return flowExpiresOn
+	}
+	return &sessionEnd
+}
+
+// the number of attempts a contact gets to give valid input if a validation doesn't say
+const defaultValidationMaxAttempts = 3
+
+// Validation makes a message wait check the input it's resumed with, re-prompting the contact with an error message if
+// the test doesn't evaluate to something truthy. Once the contact has used up their attempts, the input is routed to the
+// router's invalid input category instead.
+type Validation struct {
+	Test        string `json:"test" validate:"required"`
+	Message     string `json:"message" validate:"required"`
+	MaxAttempts int    `json:"max_attempts,omitempty" validate:"omitempty,min=1"`
+}
+
+// NewValidation creates a new validation, where a zero max attempts means use the default
+func NewValidation(test, message string, maxAttempts int) *Validation {
+	return &Validation{Test: test, Message: message, MaxAttempts: maxAttempts}
+}
+
+// Attempts returns the number of attempts the contact gets to give valid input
+func (v *Validation) Attempts() int {
+	if v.MaxAttempts > 0 {
+		return v.MaxAttempts
+	}
+	return defaultValidationMaxAttempts
+}
+
 // MsgWait is a wait which waits for an incoming message (i.e. a msg_received event)
 type MsgWait struct {
 	baseWait
@@ -30,28 +96,47 @@ type MsgWait struct {
 	// an attachment of that type. In the case of other flow types this should be considered only a hint to the channel,
 	// which may or may not support prompting the contact for media of that type.
 	hint flows.Hint
+
+	// In USSD mode, a prompt which is too long for a USSD response is paginated into a menu with a "99. More" option,
+	// and the run expires when the USSD session times out.
+	ussd *USSD
+
+	// If there's a validation, input which fails its test gets a re-prompt rather than being routed
+	validation *Validation
 }
 
 // NewMsgWait creates a new message wait
-func NewMsgWait(timeout *Timeout, hint flows.Hint) *MsgWait {
+func NewMsgWait(timeout *Timeout, hint flows.Hint, ussd *USSD, validation *Validation) *MsgWait {
 	return &MsgWait{
-		baseWait: newBaseWait(TypeMsg, timeout),
-		hint:     hint,
+		baseWait:   newBaseWait(TypeMsg, timeout),
+		hint:       hint,
+		ussd:       ussd,
+		validation: validation,
 	}
 }
 
 // Hint returns the hint (optional)
 func (w *MsgWait) Hint() flows.Hint { return w.hint }
 
+// USSD returns the USSD mode (optional)
+func (w *MsgWait) USSD() *USSD { return w.ussd }
+
+// Validation returns the input validation (optional)
+func (w *MsgWait) Validation() *Validation { return w.validation }
+
 // AllowedFlowTypes returns the flow types which this wait is allowed to occur in
 func (w *MsgWait) AllowedFlowTypes() []flows.FlowType {
+	if w.ussd != nil {
+		return []flows.FlowType{flows.FlowTypeMessaging}
+	}
 	return []flows.FlowType{flows.FlowTypeMessaging, flows.FlowTypeMessagingOffline, flows.FlowTypeVoice}
 }
 
 // Begin beings waiting at this wait
 func (w *MsgWait) Begin(run flows.Run, log flows.EventCallback) bool {
-	// if we have a msg trigger and we're the first thing to happen... then we skip ourselves
-	triggerHasMsg := run.Session().Trigger().Type() == triggers.TypeMsg
+	// if we have a msg or email trigger and we're the first thing to happen... then we skip ourselves
+	triggerType := run.Session().Trigger().Type()
+	triggerHasMsg := triggerType == triggers.TypeMsg || triggerType == triggers.TypeEmail
 
 	if triggerHasMsg && len(run.Session().Runs()) == 1 && len(run.Path()) == 1 {
 		return false
@@ -63,7 +148,23 @@ func (w *MsgWait) Begin(run flows.Run, log flows.EventCallback) bool {
 		timeoutSeconds = &seconds
 	}
 
-	log(events.NewMsgWait(timeoutSeconds, w.expiresOn(run), w.hint))
+	expiresOn := w.expiresOn(run)
+	var morePages []string
+
+	if w.ussd != nil {
+		expiresOn = w.ussd.expiresOn(run, expiresOn)
+
+		// the prompt was paginated when it was sent, and we show the rest of its pages when asked
+		if prompt := lastPrompt(run); prompt != nil {
+			morePages = prompt.MorePages
+		}
+	}
+
+	run.SetExpiresOn(expiresOn)
+
+	event := events.NewMsgWait(timeoutSeconds, expiresOn, w.hint)
+	event.MorePages = morePages
+	log(event)
 
 	return true
 }
@@ -71,7 +172,7 @@ func (w *MsgWait) Begin(run flows.Run, log flows.EventCallback) bool {
 // Accept returns whether this wait accepts the given resume
 func (w *MsgWait) Accepts(resume flows.Resume) bool {
 	switch resume.Type() {
-	case resumes.TypeMsg, resumes.TypeRunExpiration:
+	case resumes.TypeMsg, resumes.TypeCallbackQuery, resumes.TypeEmail, resumes.TypeWhatsAppFlow, resumes.TypeRunExpiration, resumes.TypeExpiration:
 		return true
 	case resumes.TypeWaitTimeout:
 		return w.timeout != nil
@@ -79,7 +180,131 @@ func (w *MsgWait) Accepts(resume flows.Resume) bool {
 	return false
 }
 
-var _ flows.Wait = (*MsgWait)(nil)
+// Paginate splits the given prompt into pages which each fit in a USSD response, or returns nil if this wait isn't in
+// USSD mode
+func (w *MsgWait) Paginate(text string) []string {
+	if w.ussd == nil {
+		return nil
+	}
+	return flows.PaginateUSSDMenu(text, w.ussd.MaxResponseLength())
+}
+
+// ShowMore sends the next page of a paginated USSD prompt if the contact replied with the more option
+func (w *MsgWait) ShowMore(run flows.Run, resume flows.Resume, log flows.EventCallback) bool {
+	msgResume, isMsg := resume.(*resumes.MsgResume)
+	if w.ussd == nil || !isMsg || strings.TrimSpace(msgResume.Msg().Text()) != flows.USSDMoreOption {
+		return false
+	}
+
+	lastWait := lastEvent[*events.MsgWaitEvent](run)
+	lastPage := lastEvent[*events.MsgCreatedEvent](run)
+	if lastWait == nil || lastPage == nil || len(lastWait.MorePages) == 0 {
+		return false
+	}
+
+	prev := lastPage.Msg
+	page := flows.NewMsgOut(flows.MsgUUID(run.Session().Sources().NewUUID()), prev.URN(), prev.Channel(), lastWait.MorePages[0], nil, nil, nil, nil, prev.Topic(), prev.Locale(), prev.UnsendableReason())
+	pageEvent := events.NewMsgCreated(page)
+	pageEvent.Segments = nil
+	log(pageEvent)
+
+	expiresOn := w.ussd.expiresOn(run, w.expiresOn(run))
+	run.SetExpiresOn(expiresOn)
+
+	event := events.NewMsgWait(lastWait.TimeoutSeconds, expiresOn, w.hint)
+	event.MorePages = lastWait.MorePages[1:]
+	event.InvalidAttempts = lastWait.InvalidAttempts
+	log(event)
+
+	return true
+}
+
+// ValidateInput checks a message resume against this wait's validation, re-prompting the contact if they have attempts left
+func (w *MsgWait) ValidateInput(run flows.Run, resume flows.Resume, log flows.EventCallback) flows.InputValidity {
+	msgResume, isMsg := resume.(*resumes.MsgResume)
+	if w.validation == nil || !isMsg {
+		return flows.InputValid
+	}
+
+	result, err := run.EvaluateTemplateValue(w.validation.Test)
+	if err != nil {
+		log(events.NewError(err))
+	}
+	if types.Truthy(result) {
+		return flows.InputValid
+	}
+
+	lastWait := lastEvent[*events.MsgWaitEvent](run)
+	attempts := 1
+	if lastWait != nil {
+		attempts = lastWait.InvalidAttempts + 1
+	}
+	if attempts >= w.validation.Attempts() {
+		return flows.InputInvalid
+	}
+
+	text, err := run.EvaluateTemplate(w.validation.Message)
+	if err != nil {
+		log(events.NewError(err))
+	}
+
+	// reply on the same URN and channel as the original prompt, falling back to those of the invalid message
+	in := msgResume.Msg()
+	var msg *flows.MsgOut
+	if prompt := lastEvent[*events.MsgCreatedEvent](run); prompt != nil {
+		prev := prompt.Msg
+		msg = flows.NewMsgOut(flows.MsgUUID(run.Session().Sources().NewUUID()), prev.URN(), prev.Channel(), text, nil, nil, nil, nil, prev.Topic(), prev.Locale(), prev.UnsendableReason())
+	} else {
+		msg = flows.NewMsgOut(flows.MsgUUID(run.Session().Sources().NewUUID()), in.URN(), in.Channel(), text, nil, nil, nil, nil, flows.NilMsgTopic, envs.NilLocale, flows.NilUnsendableReason)
+	}
+	log(events.NewMsgCreated(msg))
+
+	var timeoutSeconds *int
+	if lastWait != nil {
+		timeoutSeconds = lastWait.TimeoutSeconds
+	}
+
+	expiresOn := w.expiresOn(run)
+	if w.ussd != nil {
+		expiresOn = w.ussd.expiresOn(run, expiresOn)
+	}
+	run.SetExpiresOn(expiresOn)
+
+	event := events.NewMsgWait(timeoutSeconds, expiresOn, w.hint)
+	event.InvalidAttempts = attempts
+	log(event)
+
+	return flows.InputRetry
+}
+
+// finds the last event of the given type in the run
+func lastEvent[E flows.Event](run flows.Run) E {
+	runEvents := run.Events()
+	for i := len(runEvents) - 1; i >= 0; i-- {
+		if typed, isType := runEvents[i].(E); isType {
+			return typed
+		}
+	}
+	var none E
+	return none
+}
+
+// finds the last message sent since the contact last replied, which is the prompt for a wait that's beginning
+func lastPrompt(run flows.Run) *events.MsgCreatedEvent {
+	runEvents := run.Events()
+	for i := len(runEvents) - 1; i >= 0; i-- {
+		switch typed := runEvents[i].(type) {
+		case *events.MsgCreatedEvent:
+			return typed
+		case *events.MsgReceivedEvent, *events.EmailReceivedEvent, *events.MsgWaitEvent:
+			return nil
+		}
+	}
+	return nil
+}
+
+var _ flows.PagedWait = (*MsgWait)(nil)
+var _ flows.ValidatingWait = (*MsgWait)(nil)
 
 //------------------------------------------------------------------------------------------
 // JSON Encoding / Decoding
@@ -88,7 +313,9 @@ var _ flows.Wait = (*MsgWait)(nil)
 type msgWaitEnvelope struct {
 	baseWaitEnvelope
 
-	Hint json.RawMessage `json:"hint,omitempty"`
+	Hint       json.RawMessage `json:"hint,omitempty"`
+	USSD       *USSD           `json:"ussd,omitempty" validate:"omitempty,dive"`
+	Validation *Validation     `json:"validation,omitempty" validate:"omitempty,dive"`
 }
 
 func readMsgWait(data json.RawMessage) (flows.Wait, error) {
@@ -97,7 +324,7 @@ func readMsgWait(data json.RawMessage) (flows.Wait, error) {
 		return nil, err
 	}
 
-	w := &MsgWait{}
+	w := &MsgWait{ussd: e.USSD, validation: e.Validation}
 
 	var err error
 	if e.Hint != nil {
@@ -111,7 +338,7 @@ func readMsgWait(data json.RawMessage) (flows.Wait, error) {
 
 // MarshalJSON marshals this wait into JSON
 func (w *MsgWait) MarshalJSON() ([]byte, error) {
-	e := &msgWaitEnvelope{}
+	e := &msgWaitEnvelope{USSD: w.ussd, Validation: w.validation}
 
 	if err := w.marshal(&e.baseWaitEnvelope); err != nil {
 		return nil, err
@@ -57,7 +57,7 @@ func TestMsgWait(t *testing.T) {
 	run := session.Runs()[0]
 
 	// no timeout or media
-	wait := waits.NewMsgWait(nil, nil)
+	wait := waits.NewMsgWait(nil, nil, nil, nil)
 	marshaled := jsonx.MustMarshal(wait)
 	assert.Equal(t, `{"type":"msg"}`, string(marshaled))
 
@@ -68,6 +68,8 @@ func TestMsgWait(t *testing.T) {
 	wait = waits.NewMsgWait(
 		waits.NewTimeout(5, flows.CategoryUUID("63fca57d-5ef6-4afd-9bcd-7bdcf653cea8")),
 		hints.NewImageHint(),
+		nil,
+		nil,
 	)
 
 	// test marsalling definition wait
@@ -88,6 +90,51 @@ func TestMsgWait(t *testing.T) {
 
 	// can end with timeout resume type
 	assert.True(t, wait.Accepts(resumes.NewWaitTimeout(nil, nil)))
+
+	// or with a WhatsApp flow submission
+	assert.True(t, wait.Accepts(resumes.NewWhatsAppFlow(nil, nil, &flows.WhatsAppFlowResponse{FlowToken: "2d611e17-fb22-457f-b802-b8f7ec5cda5b"})))
+
+	// or with an email
+	assert.True(t, wait.Accepts(resumes.NewEmail(nil, nil, &flows.EmailIn{UUID: "2d611e17-fb22-457f-b802-b8f7ec5cda5b", From: "bob@nyaruka.com"})))
+}
+
+func TestMsgWaitUSSD(t *testing.T) {
+	// USSD mode with defaults
+	wait := waits.NewMsgWait(nil, nil, waits.NewUSSD(0, 0), nil)
+	assert.Equal(t, `{"type":"msg","ussd":{}}`, string(jsonx.MustMarshal(wait)))
+	assert.Equal(t, 182, wait.USSD().MaxResponseLength())
+	assert.Equal(t, 180, wait.USSD().SessionTimeout())
+	assert.Equal(t, []flows.FlowType{flows.FlowTypeMessaging}, wait.AllowedFlowTypes())
+
+	// and with overrides
+	read, err := waits.ReadWait([]byte(`{"type":"msg","ussd":{"max_response_length":140,"session_timeout":60}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 140, read.(*waits.MsgWait).USSD().MaxResponseLength())
+	assert.Equal(t, 60, read.(*waits.MsgWait).USSD().SessionTimeout())
+
+	// max response length must leave room for a page
+	_, err = waits.ReadWait([]byte(`{"type":"msg","ussd":{"max_response_length":10}}`))
+	assert.EqualError(t, err, "field 'ussd.max_response_length' must be greater than or equal to 20")
+
+	// prompts are paginated to fit the max response length, but only in USSD mode
+	wait = waits.NewMsgWait(nil, nil, waits.NewUSSD(30, 0), nil)
+	assert.Equal(t, []string{"Pick one:\n1. Red\n99. More", "2. Green\n3. Blue"}, wait.Paginate("Pick one:\n1. Red\n2. Green\n3. Blue"))
+	assert.Equal(t, []string{"Pick one:\n1. Red"}, wait.Paginate("Pick one:\n1. Red"))
+	assert.Nil(t, waits.NewMsgWait(nil, nil, nil, nil).Paginate("Pick one:\n1. Red\n2. Green\n3. Blue"))
+}
+
+func TestMsgWaitValidation(t *testing.T) {
+	wait := waits.NewMsgWait(nil, nil, nil, waits.NewValidation("@(has_number(input.text))", "Please reply with a number", 0))
+	assert.Equal(t, `{"type":"msg","validation":{"test":"@(has_number(input.text))","message":"Please reply with a number"}}`, string(jsonx.MustMarshal(wait)))
+	assert.Equal(t, 3, wait.Validation().Attempts())
+
+	read, err := waits.ReadWait([]byte(`{"type":"msg","validation":{"test":"@(has_text(input.text))","message":"Try again","max_attempts":5}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 5, read.(*waits.MsgWait).Validation().Attempts())
+
+	// validations need both a test and a message
+	_, err = waits.ReadWait([]byte(`{"type":"msg","validation":{"test":"@(has_text(input.text))"}}`))
+	assert.EqualError(t, err, "field 'validation.message' is required")
 }
 
 func TestMsgWaitSkipIfInitial(t *testing.T) {
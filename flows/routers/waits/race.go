@@ -0,0 +1,240 @@
+package waits
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerType(TypeRace, readRaceWait)
+}
+
+// TypeRace is the type of our race wait
+const TypeRace string = "race"
+
+// RaceBranch is one of the waits in a race. If it has a category then the router routes straight to that category
+// when this branch wins, otherwise the router routes as normal.
+type RaceBranch struct {
+	wait         flows.Wait
+	categoryUUID flows.CategoryUUID
+}
+
+// NewRaceBranch creates a new race branch
+func NewRaceBranch(wait flows.Wait, categoryUUID flows.CategoryUUID) *RaceBranch {
+	return &RaceBranch{wait: wait, categoryUUID: categoryUUID}
+}
+
+// Wait returns the wait of this branch
+func (b *RaceBranch) Wait() flows.Wait { return b.wait }
+
+// CategoryUUID returns the category to route to if this branch wins
+func (b *RaceBranch) CategoryUUID() flows.CategoryUUID { return b.categoryUUID }
+
+// RaceWait is a wait which waits on several other waits at once, e.g. a message or a dial. The first of them to be
+// resumed wins and the others are cancelled. A race can have a timeout but the waits in it can't.
+type RaceWait struct {
+	baseWait
+
+	branches []*RaceBranch
+}
+
+// NewRaceWait creates a new race wait
+func NewRaceWait(timeout *Timeout, branches []*RaceBranch) *RaceWait {
+	return &RaceWait{
+		baseWait: newBaseWait(TypeRace, timeout),
+		branches: branches,
+	}
+}
+
+// Branches returns the branches of this race
+func (w *RaceWait) Branches() []*RaceBranch { return w.branches }
+
+// AllowedFlowTypes returns the flow types which this wait is allowed to occur in, i.e. those allowed by all its waits
+func (w *RaceWait) AllowedFlowTypes() []flows.FlowType {
+	allowed := make([]flows.FlowType, 0, 2)
+
+	for _, flowType := range []flows.FlowType{flows.FlowTypeMessaging, flows.FlowTypeMessagingOffline, flows.FlowTypeVoice} {
+		allowedByAll := true
+		for _, b := range w.branches {
+			if !flowType.Allows(b.wait) {
+				allowedByAll = false
+				break
+			}
+		}
+		if allowedByAll {
+			allowed = append(allowed, flowType)
+		}
+	}
+
+	return allowed
+}
+
+// Begin beings waiting at this wait
+func (w *RaceWait) Begin(run flows.Run, log flows.EventCallback) bool {
+	begun := make([]string, 0, len(w.branches))
+	var expiresOn *time.Time
+
+	for _, b := range w.branches {
+		if b.wait.Begin(run, log) {
+			begun = append(begun, b.wait.Type())
+
+			// the run shouldn't expire before the last of its waits could be resumed
+			if e := run.ExpiresOn(); e != nil && (expiresOn == nil || e.After(*expiresOn)) {
+				expiresOn = e
+			}
+		}
+	}
+
+	run.SetExpiresOn(expiresOn)
+
+	// if none of our waits could begin, there's nothing to wait for
+	if len(begun) == 0 {
+		return false
+	}
+
+	var timeoutSeconds *int
+	if w.timeout != nil {
+		seconds := w.timeout.Seconds()
+		timeoutSeconds = &seconds
+	}
+
+	log(events.NewRaceWait(begun, timeoutSeconds))
+
+	return true
+}
+
+// Accepts returns whether this wait accepts the given resume
+func (w *RaceWait) Accepts(resume flows.Resume) bool {
+	if resume.Type() == resumes.TypeWaitTimeout {
+		return w.timeout != nil
+	}
+
+	return w.winner(resume) != nil
+}
+
+// Finish ends this race with the given resume, returning the category of the winning branch, if it has one
+func (w *RaceWait) Finish(run flows.Run, resume flows.Resume, log flows.EventCallback) flows.CategoryUUID {
+	// an expiring run either exits or takes the router's expiration category so there's no race to finish
+	if resume.Type() == resumes.TypeRunExpiration || resume.Type() == resumes.TypeExpiration {
+		return ""
+	}
+
+	winner := w.winner(resume)
+	cancelled := make([]string, 0, len(w.branches))
+
+	for _, b := range w.branches {
+		if b != winner {
+			cancelled = append(cancelled, b.wait.Type())
+		}
+	}
+
+	log(events.NewRaceEnded(resume.Type(), cancelled))
+
+	if winner != nil {
+		return winner.categoryUUID
+	}
+	return ""
+}
+
+// Paginate splits the given prompt using the first paged wait in this race which splits prompts
+func (w *RaceWait) Paginate(text string) []string {
+	for _, b := range w.branches {
+		if paged, isPaged := b.wait.(flows.PagedWait); isPaged {
+			if pages := paged.Paginate(text); pages != nil {
+				return pages
+			}
+		}
+	}
+	return nil
+}
+
+// ShowMore lets any paged waits in this race show the next page of their prompt
+func (w *RaceWait) ShowMore(run flows.Run, resume flows.Resume, log flows.EventCallback) bool {
+	for _, b := range w.branches {
+		if paged, isPaged := b.wait.(flows.PagedWait); isPaged && paged.ShowMore(run, resume, log) {
+			return true
+		}
+	}
+	return false
+}
+
+// finds the branch whose wait accepts the given resume
+func (w *RaceWait) winner(resume flows.Resume) *RaceBranch {
+	for _, b := range w.branches {
+		if b.wait.Accepts(resume) {
+			return b
+		}
+	}
+	return nil
+}
+
+var _ flows.RacingWait = (*RaceWait)(nil)
+var _ flows.PagedWait = (*RaceWait)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type raceBranchEnvelope struct {
+	Wait         json.RawMessage    `json:"wait"                    validate:"required"`
+	CategoryUUID flows.CategoryUUID `json:"category_uuid,omitempty" validate:"omitempty,uuid4"`
+}
+
+type raceWaitEnvelope struct {
+	baseWaitEnvelope
+
+	Branches []*raceBranchEnvelope `json:"branches" validate:"required,min=2,dive"`
+}
+
+func readRaceWait(data json.RawMessage) (flows.Wait, error) {
+	e := &raceWaitEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	w := &RaceWait{branches: make([]*RaceBranch, len(e.Branches))}
+
+	for i, be := range e.Branches {
+		wait, err := ReadWait(be.Wait)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read wait of branch %d", i)
+		}
+		if wait.Type() == TypeRace {
+			return nil, errors.Errorf("branch %d can't be another race", i)
+		}
+		if !utils.IsNil(wait.Timeout()) {
+			return nil, errors.Errorf("branch %d can't have its own timeout", i)
+		}
+
+		w.branches[i] = &RaceBranch{wait: wait, categoryUUID: be.CategoryUUID}
+	}
+
+	return w, w.unmarshal(&e.baseWaitEnvelope)
+}
+
+// MarshalJSON marshals this wait into JSON
+func (w *RaceWait) MarshalJSON() ([]byte, error) {
+	e := &raceWaitEnvelope{Branches: make([]*raceBranchEnvelope, len(w.branches))}
+
+	for i, b := range w.branches {
+		waitJSON, err := jsonx.Marshal(b.wait)
+		if err != nil {
+			return nil, err
+		}
+		e.Branches[i] = &raceBranchEnvelope{Wait: waitJSON, CategoryUUID: b.categoryUUID}
+	}
+
+	if err := w.marshal(&e.baseWaitEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
@@ -0,0 +1,89 @@
+package waits_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/flows/routers/waits"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaceWait(t *testing.T) {
+	session, _, err := test.CreateTestVoiceSession("")
+	require.NoError(t, err)
+	run := session.Runs()[0]
+
+	// need at least two branches
+	_, err = waits.ReadWait([]byte(`{"type": "race", "branches": [{"wait": {"type": "msg"}}]}`))
+	assert.EqualError(t, err, "field 'branches' must have a minimum of 2 items")
+
+	// branches can't have their own timeouts
+	_, err = waits.ReadWait([]byte(`{"type": "race", "branches": [{"wait": {"type": "msg", "timeout": {"seconds": 10, "category_uuid": "5ce6c69a-fdfe-4594-ab71-26be534d31c3"}}}, {"wait": {"type": "dial", "phone": "+593979123456"}}]}`))
+	assert.EqualError(t, err, "branch 0 can't have its own timeout")
+
+	// or be races themselves
+	_, err = waits.ReadWait([]byte(`{"type": "race", "branches": [{"wait": {"type": "msg"}}, {"wait": {"type": "race", "branches": [{"wait": {"type": "msg"}}, {"wait": {"type": "msg"}}]}}]}`))
+	assert.EqualError(t, err, "branch 1 can't be another race")
+
+	wait, err := waits.ReadWait([]byte(`{
+		"type": "race",
+		"branches": [
+			{"wait": {"type": "msg"}},
+			{"wait": {"type": "dial", "phone": "+593979123456"}, "category_uuid": "0680b01f-ba0b-48f4-a688-d2f963130126"}
+		],
+		"timeout": {"seconds": 300, "category_uuid": "5ce6c69a-fdfe-4594-ab71-26be534d31c3"}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, waits.TypeRace, wait.Type())
+	assert.Equal(t, 2, len(wait.(*waits.RaceWait).Branches()))
+	assert.Equal(t, []flows.FlowType{flows.FlowTypeVoice}, wait.AllowedFlowTypes())
+
+	marshaled, err := jsonx.Marshal(wait)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"race","timeout":{"seconds":300,"category_uuid":"5ce6c69a-fdfe-4594-ab71-26be534d31c3"},"branches":[{"wait":{"type":"msg"}},{"wait":{"type":"dial","phone":"+593979123456","dial_limit_seconds":60,"call_limit_seconds":7200},"category_uuid":"0680b01f-ba0b-48f4-a688-d2f963130126"}]}`, string(marshaled))
+
+	// beginning the race begins all its waits
+	log := test.NewEventLog()
+	assert.True(t, wait.Begin(run, log.Log))
+	assert.Equal(t, 3, len(log.Events))
+	assert.Equal(t, "msg_wait", log.Events[0].Type())
+	assert.Equal(t, "dial_wait", log.Events[1].Type())
+	assert.Equal(t, "race_wait", log.Events[2].Type())
+	assert.Equal(t, []string{"msg", "dial"}, log.Events[2].(*events.RaceWaitEvent).Waits)
+	assert.Equal(t, 300, *log.Events[2].(*events.RaceWaitEvent).TimeoutSeconds)
+
+	// accepts resumes that any of its waits accept, and timeouts because it has one
+	dialResume := resumes.NewDial(nil, nil, flows.NewDial(flows.DialStatusAnswered, 5))
+	timeoutResume := resumes.NewWaitTimeout(nil, nil)
+	assert.True(t, wait.Accepts(dialResume))
+	assert.True(t, wait.Accepts(timeoutResume))
+
+	// finishing with a dial routes to the dial branch's category and cancels the msg wait
+	log = test.NewEventLog()
+	assert.Equal(t, flows.CategoryUUID("0680b01f-ba0b-48f4-a688-d2f963130126"), wait.(flows.RacingWait).Finish(run, dialResume, log.Log))
+	assert.Equal(t, 1, len(log.Events))
+	assert.Equal(t, "dial", log.Events[0].(*events.RaceEndedEvent).Winner)
+	assert.Equal(t, []string{"msg"}, log.Events[0].(*events.RaceEndedEvent).Cancelled)
+
+	// finishing with a timeout cancels everything and leaves routing to the router
+	log = test.NewEventLog()
+	assert.Equal(t, flows.CategoryUUID(""), wait.(flows.RacingWait).Finish(run, timeoutResume, log.Log))
+	assert.Equal(t, []string{"msg", "dial"}, log.Events[0].(*events.RaceEndedEvent).Cancelled)
+
+	// race without a timeout doesn't accept timeouts
+	wait, err = waits.ReadWait([]byte(`{"type": "race", "branches": [{"wait": {"type": "msg"}}, {"wait": {"type": "dial", "phone": "@(\"\")"}}]}`))
+	require.NoError(t, err)
+	assert.False(t, wait.Accepts(timeoutResume))
+
+	// race only waits on the waits which could begin
+	log = test.NewEventLog()
+	assert.True(t, wait.Begin(run, log.Log))
+	assert.Equal(t, "race_wait", log.Events[len(log.Events)-1].Type())
+	assert.Equal(t, []string{"msg"}, log.Events[len(log.Events)-1].(*events.RaceWaitEvent).Waits)
+}
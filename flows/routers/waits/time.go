@@ -0,0 +1,150 @@
+package waits
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerType(TypeTime, readTimeWait)
+}
+
+// TypeTime is the type of our time wait
+const TypeTime string = "time"
+
+// TimeWait is a wait which waits until a point in time. The until template can evaluate to a datetime, or to a delay
+// like 2d which is relative to when the wait begins.
+type TimeWait struct {
+	baseWait
+
+	until string
+}
+
+// NewTimeWait creates a new time wait
+func NewTimeWait(until string) *TimeWait {
+	return &TimeWait{
+		baseWait: newBaseWait(TypeTime, nil),
+		until:    until,
+	}
+}
+
+// Until returns the template for when this wait should resume
+func (w *TimeWait) Until() string { return w.until }
+
+// AllowedFlowTypes returns the flow types which this wait is allowed to occur in
+func (w *TimeWait) AllowedFlowTypes() []flows.FlowType {
+	return []flows.FlowType{flows.FlowTypeMessaging, flows.FlowTypeMessagingOffline}
+}
+
+// Begin beings waiting at this wait
+func (w *TimeWait) Begin(run flows.Run, log flows.EventCallback) bool {
+	until, err := run.EvaluateTemplate(w.until)
+	if err != nil {
+		log(events.NewError(err))
+	}
+
+	resumeOn, err := w.resumeOn(run, strings.TrimSpace(until))
+	if err != nil {
+		log(events.NewError(err))
+		return false
+	}
+
+	// if that time has already passed, there's nothing to wait for
+	if !resumeOn.After(run.Session().Sources().Now()) {
+		return false
+	}
+
+	// the run shouldn't expire before it's resumed so flow expiration starts counting from when we resume
+	var expiresOn *time.Time
+	if expiresAfterMins := run.Flow().ExpireAfterMinutes(); expiresAfterMins > 0 {
+		dt := resumeOn.Add(time.Duration(expiresAfterMins) * time.Minute)
+		expiresOn = &dt
+	}
+
+	run.SetExpiresOn(expiresOn)
+
+	log(events.NewSessionScheduled(resumeOn, expiresOn))
+
+	return true
+}
+
+// works out the time to resume at from an evaluated until template
+func (w *TimeWait) resumeOn(run flows.Run, until string) (time.Time, error) {
+	if until == "" {
+		return time.Time{}, errors.New("time wait evaluated to empty string")
+	}
+
+	if delay, err := flows.ParseTimerDelay(until); err == nil {
+		return run.Session().Sources().Now().Add(delay), nil
+	}
+
+	dt, xerr := types.ToXDateTime(run.Environment(), types.NewXText(until))
+	if xerr != nil {
+		return time.Time{}, errors.Errorf("time wait evaluated to '%s' which is not a valid datetime or delay", until)
+	}
+
+	resumeOn := dt.Native()
+	if resumeOn.Sub(run.Session().Sources().Now()) > flows.MaxTimerDelay {
+		return time.Time{}, errors.Errorf("time wait evaluated to '%s' which is more than 365 days away", until)
+	}
+
+	return resumeOn, nil
+}
+
+// Accepts returns whether this wait accepts the given resume
+func (w *TimeWait) Accepts(resume flows.Resume) bool {
+	switch resume.Type() {
+	case resumes.TypeTimeReached, resumes.TypeRunExpiration, resumes.TypeExpiration:
+		return true
+	}
+	return false
+}
+
+var _ flows.Wait = (*TimeWait)(nil)
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type timeWaitEnvelope struct {
+	baseWaitEnvelope
+
+	Until string `json:"until" validate:"required"`
+}
+
+func readTimeWait(data json.RawMessage) (flows.Wait, error) {
+	e := &timeWaitEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	// a time wait always ends at its own time so a timeout would never be reached
+	if e.Timeout != nil {
+		return nil, errors.New("time waits can't have a timeout")
+	}
+
+	w := &TimeWait{until: e.Until}
+
+	return w, w.unmarshal(&e.baseWaitEnvelope)
+}
+
+// MarshalJSON marshals this wait into JSON
+func (w *TimeWait) MarshalJSON() ([]byte, error) {
+	e := &timeWaitEnvelope{Until: w.until}
+
+	if err := w.marshal(&e.baseWaitEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
@@ -0,0 +1,88 @@
+package waits_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/flows/routers/waits"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeWait(t *testing.T) {
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	dates.SetNowSource(dates.NewFixedNowSource(time.Date(2018, 10, 18, 14, 20, 30, 0, time.UTC)))
+
+	session, _, err := test.CreateTestSession("", envs.RedactionPolicyNone)
+	require.NoError(t, err)
+	run := session.Runs()[0]
+
+	// until field required
+	_, err = waits.ReadWait([]byte(`{"type": "time"}`))
+	assert.EqualError(t, err, "field 'until' is required")
+
+	// and timeouts aren't allowed
+	_, err = waits.ReadWait([]byte(`{"type": "time", "until": "2d", "timeout": {"seconds": 60, "category_uuid": "2ad53e0e-4f84-4d0f-8ab6-7e5f8b1e7b5e"}}`))
+	assert.EqualError(t, err, "time waits can't have a timeout")
+
+	wait, err := waits.ReadWait([]byte(`{"type": "time", "until": "@(1 + 1)d"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, waits.TypeTime, wait.Type())
+	assert.Equal(t, "@(1 + 1)d", wait.(*waits.TimeWait).Until())
+
+	marshaled, err := jsonx.Marshal(wait)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"time","until":"@(1 + 1)d"}`, string(marshaled))
+
+	tcs := []struct {
+		until    string
+		begun    bool
+		resumeOn time.Time
+		err      string
+	}{
+		{"2d", true, time.Date(2018, 10, 20, 14, 20, 30, 0, time.UTC), ""},
+		{"@(1 + 1)h", true, time.Date(2018, 10, 18, 16, 20, 30, 0, time.UTC), ""},
+		{"2018-11-01T09:00:00Z", true, time.Date(2018, 11, 1, 9, 0, 0, 0, time.UTC), ""},
+		{"@(datetime_add(now(), 3, \"D\"))", true, time.Date(2018, 10, 21, 14, 20, 30, 0, time.UTC), ""},
+		{"2018-10-01T09:00:00Z", false, time.Time{}, ""},
+		{"2020-10-01T09:00:00Z", false, time.Time{}, "time wait evaluated to '2020-10-01T09:00:00Z' which is more than 365 days away"},
+		{"366d", false, time.Time{}, "time wait evaluated to '366d' which is not a valid datetime or delay"},
+		{"soon", false, time.Time{}, "time wait evaluated to 'soon' which is not a valid datetime or delay"},
+		{"@(\"\")", false, time.Time{}, "time wait evaluated to empty string"},
+	}
+
+	for _, tc := range tcs {
+		log := test.NewEventLog()
+		begun := waits.NewTimeWait(tc.until).Begin(run, log.Log)
+
+		assert.Equal(t, tc.begun, begun, "begun mismatch for %s", tc.until)
+
+		if tc.begun {
+			require.Equal(t, 1, len(log.Events), "expected single event for %s", tc.until)
+			event := log.Events[0].(*events.SessionScheduledEvent)
+			assert.Equal(t, tc.resumeOn, event.ResumeOn.UTC(), "resume_on mismatch for %s", tc.until)
+		} else if tc.err != "" {
+			require.Equal(t, 1, len(log.Events), "expected single event for %s", tc.until)
+			assert.Equal(t, tc.err, log.Events[0].(*events.ErrorEvent).Text, "error mismatch for %s", tc.until)
+		} else {
+			assert.Equal(t, 0, len(log.Events), "expected no events for %s", tc.until)
+		}
+	}
+
+	// this flow doesn't expire so neither does the wait
+	log := test.NewEventLog()
+	waits.NewTimeWait("2d").Begin(run, log.Log)
+
+	assert.Nil(t, log.Events[0].(*events.SessionScheduledEvent).ExpiresOn)
+
+	assert.True(t, wait.Accepts(resumes.NewTimeReached(nil, nil)))
+	assert.True(t, wait.Accepts(resumes.NewRunExpiration(nil, nil)))
+	assert.False(t, wait.Accepts(resumes.NewWaitTimeout(nil, nil)))
+}
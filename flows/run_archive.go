@@ -0,0 +1,44 @@
+package flows
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/nyaruka/goflow/assets"
+)
+
+// RunArchive is external storage for the runs of a session which have ended. Sessions which go on for months can
+// accumulate thousands of runs, and archiving the older ones keeps the size of the session bounded. Archived runs are
+// only loaded again if they're needed, e.g. a template references @child and the last child run has been archived.
+type RunArchive interface {
+	// StoreRuns stores the given marshaled runs of the given session
+	StoreRuns(SessionUUID, map[RunUUID]json.RawMessage) error
+
+	// LoadRun loads a marshaled run of the given session which was previously stored
+	LoadRun(SessionUUID, RunUUID) (json.RawMessage, error)
+}
+
+// ArchivedRun is what a session keeps of a run which has been moved to a run archive
+type ArchivedRun struct {
+	UUID          RunUUID               `json:"uuid" validate:"required,uuid4"`
+	ParentUUID    RunUUID               `json:"parent_uuid,omitempty" validate:"omitempty,uuid4"`
+	Flow          *assets.FlowReference `json:"flow" validate:"required"`
+	Status        RunStatus             `json:"status" validate:"required"`
+	Waits         int                   `json:"waits,omitempty"`
+	ReceivedInput bool                  `json:"received_input,omitempty"`
+}
+
+// NewArchivedRun creates a new archived run from the given run
+func NewArchivedRun(run Run) *ArchivedRun {
+	a := &ArchivedRun{UUID: run.UUID(), Flow: run.FlowReference(), Status: run.Status(), ReceivedInput: run.ReceivedInput()}
+
+	if parent := run.ParentInSession(); parent != nil {
+		a.ParentUUID = parent.UUID()
+	}
+	for _, e := range run.Events() {
+		if strings.HasSuffix(e.Type(), "_wait") {
+			a.Waits++
+		}
+	}
+	return a
+}
@@ -0,0 +1,178 @@
+package runs
+
+import (
+	"sort"
+
+	"github.com/nyaruka/goflow/flows"
+	"golang.org/x/exp/slices"
+)
+
+// ChangeType is the type of a change between two snapshots of a session
+type ChangeType string
+
+// the types of changes between two snapshots of a session
+const (
+	ChangeTypeSessionStatus       ChangeType = "session_status"
+	ChangeTypeContactName         ChangeType = "contact_name"
+	ChangeTypeContactLanguage     ChangeType = "contact_language"
+	ChangeTypeContactStatus       ChangeType = "contact_status"
+	ChangeTypeContactTimezone     ChangeType = "contact_timezone"
+	ChangeTypeContactURNAdded     ChangeType = "contact_urn_added"
+	ChangeTypeContactURNRemoved   ChangeType = "contact_urn_removed"
+	ChangeTypeContactGroupAdded   ChangeType = "contact_group_added"
+	ChangeTypeContactGroupRemoved ChangeType = "contact_group_removed"
+	ChangeTypeContactField        ChangeType = "contact_field"
+	ChangeTypeRunStarted          ChangeType = "run_started"
+	ChangeTypeRunStatus           ChangeType = "run_status"
+	ChangeTypeRunResult           ChangeType = "run_result"
+)
+
+// Change is a single difference between two snapshots of a session. Key is the UUID of the group, key of the field
+// or key of the result which changed, and Before and After are its text values in each snapshot.
+type Change struct {
+	Type    ChangeType    `json:"type"`
+	RunUUID flows.RunUUID `json:"run_uuid,omitempty"`
+	Key     string        `json:"key,omitempty"`
+	Before  string        `json:"before,omitempty"`
+	After   string        `json:"after,omitempty"`
+}
+
+// Diff compares two snapshots of a session, e.g. as read from the JSON saved before and after a sprint, and returns
+// the changes to the status of the session, the state of its contact, and the statuses and results of its runs. If
+// before is nil, then everything in after is considered a change.
+func Diff(before, after flows.Session) []*Change {
+	changes := make([]*Change, 0)
+	add := func(typ ChangeType, runUUID flows.RunUUID, key, b, a string) {
+		if b != a {
+			changes = append(changes, &Change{Type: typ, RunUUID: runUUID, Key: key, Before: b, After: a})
+		}
+	}
+
+	var beforeStatus flows.SessionStatus
+	var beforeContact *flows.Contact
+	beforeRuns := make(map[flows.RunUUID]flows.Run)
+	if before != nil {
+		beforeStatus = before.Status()
+		beforeContact = before.Contact()
+		for _, r := range before.Runs() {
+			beforeRuns[r.UUID()] = r
+		}
+	}
+
+	add(ChangeTypeSessionStatus, "", "", string(beforeStatus), string(after.Status()))
+
+	// contact state
+	bc, ac := snapshotContact(beforeContact), snapshotContact(after.Contact())
+
+	add(ChangeTypeContactName, "", "", bc.name, ac.name)
+	add(ChangeTypeContactLanguage, "", "", bc.language, ac.language)
+	add(ChangeTypeContactStatus, "", "", bc.status, ac.status)
+	add(ChangeTypeContactTimezone, "", "", bc.timezone, ac.timezone)
+
+	for _, urn := range bc.urns {
+		if !slices.Contains(ac.urns, urn) {
+			add(ChangeTypeContactURNRemoved, "", "", urn, "")
+		}
+	}
+	for _, urn := range ac.urns {
+		if !slices.Contains(bc.urns, urn) {
+			add(ChangeTypeContactURNAdded, "", "", "", urn)
+		}
+	}
+
+	for _, uuid := range sortedKeys(bc.groups) {
+		if _, isMember := ac.groups[uuid]; !isMember {
+			add(ChangeTypeContactGroupRemoved, "", uuid, bc.groups[uuid], "")
+		}
+	}
+	for _, uuid := range sortedKeys(ac.groups) {
+		if _, wasMember := bc.groups[uuid]; !wasMember {
+			add(ChangeTypeContactGroupAdded, "", uuid, "", ac.groups[uuid])
+		}
+	}
+
+	for _, key := range sortedKeys(bc.fields, ac.fields) {
+		add(ChangeTypeContactField, "", key, bc.fields[key], ac.fields[key])
+	}
+
+	// run statuses and results
+	for _, ar := range after.Runs() {
+		br := beforeRuns[ar.UUID()]
+		beforeResults := flows.Results{}
+
+		if br == nil {
+			changes = append(changes, &Change{Type: ChangeTypeRunStarted, RunUUID: ar.UUID(), Key: string(ar.FlowReference().UUID), After: string(ar.Status())})
+		} else {
+			add(ChangeTypeRunStatus, ar.UUID(), "", string(br.Status()), string(ar.Status()))
+			beforeResults = br.Results()
+		}
+
+		bv, av := resultValues(beforeResults), resultValues(ar.Results())
+		for _, key := range sortedKeys(bv, av) {
+			add(ChangeTypeRunResult, ar.UUID(), key, bv[key], av[key])
+		}
+	}
+
+	return changes
+}
+
+// the parts of a contact's state that we diff, as text
+type contactSnapshot struct {
+	name     string
+	language string
+	status   string
+	timezone string
+	urns     []string
+	groups   map[string]string // names by UUID
+	fields   map[string]string // text values by key
+}
+
+func snapshotContact(c *flows.Contact) *contactSnapshot {
+	s := &contactSnapshot{groups: make(map[string]string), fields: make(map[string]string)}
+	if c == nil {
+		return s
+	}
+
+	s.name = c.Name()
+	s.language = string(c.Language())
+	s.status = string(c.Status())
+	if c.Timezone() != nil {
+		s.timezone = c.Timezone().String()
+	}
+	for _, urn := range c.URNs() {
+		s.urns = append(s.urns, urn.URN().Identity().String())
+	}
+	for _, g := range c.Groups().All() {
+		s.groups[string(g.UUID())] = g.Name()
+	}
+	for key, v := range c.Fields() {
+		if v != nil {
+			s.fields[key] = v.Text.Native()
+		}
+	}
+	return s
+}
+
+func resultValues(results flows.Results) map[string]string {
+	values := make(map[string]string, len(results))
+	for key, r := range results {
+		values[key] = r.Value
+	}
+	return values
+}
+
+// gets the union of the keys of the given maps in sorted order
+func sortedKeys(maps ...map[string]string) []string {
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,63 @@
+package runs_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/flows/runs"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	sa, session, _ := test.NewSessionBuilder().WithAssetsPath("../../test/testdata/runner/two_questions.json").WithFlow("615b8a0f-588c-4d20-a05f-363b0b4ce6f4").MustBuild()
+	runUUID := session.Runs()[0].UUID()
+
+	// a new session is diffed against nothing
+	changes := runs.Diff(nil, session)
+	assert.Equal(t, []*runs.Change{
+		{Type: runs.ChangeTypeSessionStatus, After: "waiting"},
+		{Type: runs.ChangeTypeContactName, After: "Bob"},
+		{Type: runs.ChangeTypeContactLanguage, After: "eng"},
+		{Type: runs.ChangeTypeContactStatus, After: "active"},
+		{Type: runs.ChangeTypeContactURNAdded, After: "tel:+12065551212"},
+		{Type: runs.ChangeTypeRunStarted, RunUUID: runUUID, Key: "615b8a0f-588c-4d20-a05f-363b0b4ce6f4", After: "waiting"},
+	}, changes)
+
+	// nothing has changed between a session and a snapshot of itself
+	snapshot := func() flows.Session {
+		s, err := test.NewEngine().ReadSession(sa, jsonx.MustMarshal(session), assets.PanicOnMissing)
+		require.NoError(t, err)
+		return s
+	}
+	before := snapshot()
+	assert.Equal(t, []*runs.Change{}, runs.Diff(before, session))
+
+	// resume with an answer to the first question which saves a result and changes the contact's language
+	msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), "tel:+12065551212", nil, "blue", nil)
+	_, err := session.Resume(context.Background(), resumes.NewMsg(nil, nil, msg))
+	require.NoError(t, err)
+
+	assert.Equal(t, []*runs.Change{
+		{Type: runs.ChangeTypeContactLanguage, Before: "eng", After: "fra"},
+		{Type: runs.ChangeTypeRunResult, RunUUID: runUUID, Key: "favorite_color", After: "blue"},
+	}, runs.Diff(before, session))
+
+	// finish the flow
+	before = snapshot()
+	msg = flows.NewMsgIn(flows.MsgUUID(uuids.New()), "tel:+12065551212", nil, "pepsi", nil)
+	_, err = session.Resume(context.Background(), resumes.NewMsg(nil, nil, msg))
+	require.NoError(t, err)
+
+	changes = runs.Diff(before, session)
+	assert.Equal(t, &runs.Change{Type: runs.ChangeTypeSessionStatus, Before: "waiting", After: "completed"}, changes[0])
+	assert.Equal(t, &runs.Change{Type: runs.ChangeTypeRunStatus, RunUUID: runUUID, Before: "waiting", After: "completed"}, changes[1])
+	assert.Equal(t, &runs.Change{Type: runs.ChangeTypeRunResult, RunUUID: runUUID, Key: "soda", After: "pepsi"}, changes[2])
+}
@@ -3,8 +3,11 @@ package runs
 import (
 	"time"
 
+	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
+
+	"github.com/shopspring/decimal"
 	"golang.org/x/exp/slices"
 )
 
@@ -18,7 +21,7 @@ type runEnvironment struct {
 // creates a run environment based on the given run
 func newRunEnvironment(base envs.Environment, run *flowRun) envs.Environment {
 	return &runEnvironment{
-		flows.NewEnvironment(base, run.Session().Assets().Locations()),
+		flows.NewEnvironment(base, run.Session().Assets().Locations(), run.Session().Assets().LookupTables()),
 		run,
 	}
 }
@@ -33,6 +36,14 @@ func (e *runEnvironment) Timezone() *time.Location {
 	return e.Environment.Timezone()
 }
 
+// Now gets the current time from the sources of the run's session
+func (e *runEnvironment) Now() time.Time {
+	return e.run.Session().Sources().Now().In(e.Environment.Timezone())
+}
+
+// Random gets a random number from the sources of the run's session
+func (e *runEnvironment) Random() decimal.Decimal { return e.run.Session().Sources().Random() }
+
 func (e *runEnvironment) DefaultLanguage() envs.Language {
 	contact := e.run.Contact()
 
@@ -59,3 +70,35 @@ func (e *runEnvironment) DefaultCountry() envs.Country {
 func (e *runEnvironment) DefaultLocale() envs.Locale {
 	return envs.NewLocale(e.DefaultLanguage(), e.DefaultCountry())
 }
+
+func (e *runEnvironment) ExpressionsVersion() envs.ExpressionsVersion {
+	// if the flow of the run has opted into a later version of expressions, use that
+	if e.run.Flow() != nil {
+		return e.run.Flow().ExpressionsVersion()
+	}
+	return e.Environment.ExpressionsVersion()
+}
+
+// GroupResolver returns a resolver which evaluates group queries against the contact, if there is one and the engine
+// is configured to do that
+func (e *runEnvironment) GroupResolver() envs.GroupResolver {
+	engine := e.run.Session().Engine()
+	if e.run.Contact() == nil || engine == nil || !engine.InlineGroupQueries() {
+		return e.Environment.GroupResolver()
+	}
+	return &contactGroupResolver{env: e, run: e.run}
+}
+
+// resolves membership of query based groups from the current state of a run's contact
+type contactGroupResolver struct {
+	env envs.Environment
+	run *flowRun
+}
+
+func (r *contactGroupResolver) CheckQueryBasedMembership(groupUUID string) (string, bool) {
+	group := r.run.Session().Assets().Groups().Get(assets.GroupUUID(groupUUID))
+	if group == nil || !group.UsesQuery() {
+		return "", false
+	}
+	return group.Name(), group.CheckQueryBasedMembership(r.env, r.run.Contact())
+}
@@ -1,6 +1,7 @@
 package runs_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -23,6 +24,15 @@ var assetsJSON = `{
             "language": "eng",
             "type": "messaging",
             "nodes": []
+        },
+        {
+            "uuid": "d5be5c7a-1e6e-4ea7-8dd2-bc7c0d1cc1e4",
+            "name": "Test V2",
+            "spec_version": "13.1.0",
+            "language": "eng",
+            "type": "messaging",
+            "expressions_version": 2,
+            "nodes": []
         }
 	],
 	"channels": [
@@ -101,7 +111,7 @@ func TestRunEnvironment(t *testing.T) {
 	trigger := triggers.NewBuilder(env, assets.NewFlowReference("76f0a02f-3b75-4b86-9064-e9195e1b3a02", "Test"), contact).Manual().Build()
 	eng := engine.NewBuilder().Build()
 
-	session, _, err := eng.NewSession(sa, trigger)
+	session, _, err := eng.NewSession(context.Background(), sa, trigger)
 	require.NoError(t, err)
 
 	// environment on the session has the values we started with
@@ -135,3 +145,41 @@ func TestRunEnvironment(t *testing.T) {
 	assert.Equal(t, envs.Language("eng"), runEnv.DefaultLanguage())
 	assert.Equal(t, "en-US", runEnv.DefaultLocale().ToBCP47())
 }
+
+func TestRunExpressionsVersion(t *testing.T) {
+	env := envs.NewBuilder().Build()
+	source, err := static.NewSource([]byte(assetsJSON))
+	require.NoError(t, err)
+
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	contact, err := flows.ReadContact(sa, []byte(contactJSON), assets.IgnoreMissing)
+	require.NoError(t, err)
+
+	eng := engine.NewBuilder().Build()
+
+	tcs := []struct {
+		flow     *assets.FlowReference
+		version  envs.ExpressionsVersion
+		expected string
+	}{
+		{assets.NewFlowReference("76f0a02f-3b75-4b86-9064-e9195e1b3a02", "Test"), envs.ExpressionsVersion1, "false"},
+		{assets.NewFlowReference("d5be5c7a-1e6e-4ea7-8dd2-bc7c0d1cc1e4", "Test V2"), envs.ExpressionsVersion2, "true"},
+	}
+
+	for _, tc := range tcs {
+		trigger := triggers.NewBuilder(env, tc.flow, contact).Manual().Build()
+		session, _, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+
+		run := session.Runs()[0]
+		assert.Equal(t, envs.ExpressionsVersion1, session.Environment().ExpressionsVersion())
+		assert.Equal(t, tc.version, run.Environment().ExpressionsVersion(), "version mismatch for flow %s", tc.flow.Name)
+
+		// numbers are only compared numerically by flows which have opted into version 2
+		actual, err := run.EvaluateTemplate(`@("1.0" = 1)`)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, actual, "output mismatch for flow %s", tc.flow.Name)
+	}
+}
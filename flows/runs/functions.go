@@ -0,0 +1,105 @@
+package runs
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/functions"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+)
+
+func init() {
+	functions.RegisterXFunction("convert_money", functions.TwoArgFunction(ConvertMoney))
+}
+
+// ConvertMoney converts `money` to the ISO 4217 `currency` using the exchange rate service of the engine.
+//
+// The converted amount is rounded to the number of decimal places standard for the currency. Money can only be
+// converted if the engine has been given an exchange rate service, and each call to it is logged as a `service_called`
+// event.
+//
+//	@(convert_money(money(10, "USD"), "RWF")) -> RWF 12500.00
+//	@(convert_money(money(2500, "RWF"), "usd")) -> USD 2.00
+//	@(convert_money(money(10, "USD"), "XYZ")) -> ERROR
+//	@(convert_money(10, "RWF")) -> ERROR
+//
+// @function convert_money(money, currency)
+func ConvertMoney(env envs.Environment, value types.XValue, currency types.XValue) types.XValue {
+	money, xerr := types.ToXMoney(env, value)
+	if xerr != nil {
+		return xerr
+	}
+	code, xerr := types.ToXText(env, currency)
+	if xerr != nil {
+		return xerr
+	}
+
+	to := strings.ToUpper(strings.TrimSpace(code.Native()))
+	if !types.IsValidCurrency(to) {
+		return types.NewXErrorf("%s isn't a valid currency code", code.Native())
+	}
+	if to == money.Currency() {
+		return money
+	}
+
+	runEnv, isRunEnv := env.(*runEnvironment)
+	if !isRunEnv {
+		return types.NewXErrorf("no exchange rate service available")
+	}
+
+	run := runEnv.run
+	svc, err := run.Session().Engine().Services().ExchangeRate(run.Session().Assets())
+	if err != nil {
+		return types.NewXErrorf("no exchange rate service available")
+	}
+
+	ctx, cancel := serviceContext(run, flows.ServiceTypeExchangeRate)
+	defer cancel()
+
+	httpLogger := flows.NewHTTPLogger(ctx, httpLogSink(run))
+
+	rate, err := svc.Rate(ctx, money.Currency(), to, httpLogger.Log)
+
+	if len(httpLogger.Logs) > 0 {
+		run.Session().LogSprintEvent(run, events.NewExchangeRateCalled(httpLogger.Logs))
+	}
+	if err != nil {
+		return types.NewXError(err)
+	}
+
+	return types.NewXMoney(money.Amount().Mul(rate), to).Round(env)
+}
+
+// derives the context for a call to the given service from the context of the current sprint, which is cancelled when
+// the engine's timeout for that service is reached. If the sprint is being profiled, the call is timed until it's
+// cancelled.
+func serviceContext(run flows.Run, service flows.ServiceType) (context.Context, context.CancelFunc) {
+	var cancel context.CancelFunc
+	ctx := run.Session().SprintContext()
+	if timeout := run.Session().Engine().ServiceTimeout(service); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	if profiler := run.Session().Profiler(); profiler != nil {
+		stop := profiler.Start(flows.ProfileCategoryService, string(service))
+		return ctx, func() {
+			cancel()
+			stop()
+		}
+	}
+	return ctx, cancel
+}
+
+// gets the HTTP log sink for the session, if one is configured
+func httpLogSink(run flows.Run) flows.HTTPLogSink {
+	sink, err := run.Session().Engine().Services().HTTPLogSink(run.Session().Assets())
+	if err != nil {
+		return nil
+	}
+	return sink
+}
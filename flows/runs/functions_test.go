@@ -0,0 +1,78 @@
+package runs_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/triggers"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMoney(t *testing.T) {
+	env := envs.NewBuilder().WithDefaultCountry("US").Build()
+	source, err := static.NewSource([]byte(assetsJSON))
+	require.NoError(t, err)
+
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	require.NoError(t, err)
+
+	contact, err := flows.ReadContact(sa, []byte(contactJSON), assets.IgnoreMissing)
+	require.NoError(t, err)
+
+	trigger := triggers.NewBuilder(env, assets.NewFlowReference("76f0a02f-3b75-4b86-9064-e9195e1b3a02", "Test"), contact).Manual().Build()
+
+	// engine without an exchange rate service can't convert money
+	session, _, err := engine.NewBuilder().Build().NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	_, err = session.Runs()[0].EvaluateTemplate(`@(convert_money(money(10, "USD"), "RWF"))`)
+	assert.EqualError(t, err, `error evaluating @(convert_money(money(10, "USD"), "RWF")): error calling convert_money(...): no exchange rate service available`)
+
+	eng := engine.NewBuilder().
+		WithExchangeRateServiceFactory(func(flows.SessionAssets) (flows.ExchangeRateService, error) {
+			return test.NewExchangeRateService(), nil
+		}).
+		Build()
+	session, _, err = eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	run := session.Runs()[0]
+
+	tcs := []struct {
+		template string
+		expected string
+		err      string
+	}{
+		{`@(convert_money(money(10, "USD"), "RWF"))`, "RWF 12500.00", ""},
+		{`@(format_money(convert_money(money(10, "USD"), "RWF")))`, "RWF 12,500", ""},
+		{`@(convert_money(money(100, "RWF"), "eur"))`, "EUR 0.07", ""},
+		{`@(convert_money(money(10, "BRL"), "BRL"))`, "BRL 10.00", ""},
+		{`@(convert_money(money(10, "BRL"), "USD"))`, "", "error evaluating @(convert_money(money(10, \"BRL\"), \"USD\")): error calling convert_money(...): no exchange rate from BRL to USD"},
+		{`@(convert_money(money(10, "USD"), "XYZ"))`, "", "error evaluating @(convert_money(money(10, \"USD\"), \"XYZ\")): error calling convert_money(...): XYZ isn't a valid currency code"},
+		{`@(convert_money(10, "USD"))`, "", "error evaluating @(convert_money(10, \"USD\")): error calling convert_money(...): unable to convert 10 to money"},
+	}
+
+	for _, tc := range tcs {
+		actual, err := run.EvaluateTemplate(tc.template)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, "error mismatch for %s", tc.template)
+		} else {
+			assert.NoError(t, err, "unexpected error for %s", tc.template)
+			assert.Equal(t, tc.expected, actual, "output mismatch for %s", tc.template)
+		}
+	}
+
+	// each call to the exchange rate service is logged on the run
+	lastEvent := run.Events()[len(run.Events())-1].(*events.ServiceCalledEvent)
+	assert.Equal(t, "exchange_rate", lastEvent.Service)
+	assert.Equal(t, "http://rates.example.com/BRL/USD", lastEvent.HTTPLogs[0].URL)
+}
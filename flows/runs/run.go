@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"time"
 
-	"github.com/nyaruka/gocommon/dates"
 	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/gocommon/stringsx"
 	"github.com/nyaruka/gocommon/uuids"
@@ -19,6 +18,49 @@ import (
 	"github.com/pkg/errors"
 )
 
+func init() {
+	flows.RegisterContextType("root",
+		flows.NewContextProperty("contact", "contact", "the contact"),
+		flows.NewContextProperty("fields", "fields", "the custom field values of the contact"),
+		flows.NewContextProperty("urns", "urns", "the URN values of the contact"),
+		flows.NewContextProperty("related", "related", "the related contacts of the contact, by relation type"),
+		flows.NewContextProperty("results", "results", "the current run results"),
+		flows.NewContextProperty("input", "input", "the current input from the contact"),
+		flows.NewContextProperty("run", "run", "the current run"),
+		flows.NewContextProperty("child", "related_run", "the last child run"),
+		flows.NewContextProperty("parent", "related_run", "the parent of the run"),
+		flows.NewContextProperty("ticket", "ticket", "the last opened ticket for the contact"),
+		flows.NewContextProperty("cart", "cart", "the shopping cart of the session"),
+		flows.NewContextProperty("intents", "intents", "the intents of the last classification in the current sprint"),
+		flows.NewContextProperty("webhook", "any", "the parsed JSON response of the last webhook call"),
+		flows.NewContextProperty("node", "node", "the current node"),
+		flows.NewContextProperty("item", "any", "the current item of the innermost foreach loop"),
+		flows.NewContextProperty("index", "number", "the index of the current item of the innermost foreach loop"),
+		flows.NewContextProperty("globals", "globals", "the global values"),
+		flows.NewContextProperty("consts", "any", "the constants of the current flow"),
+		flows.NewContextProperty("trigger", "trigger", "the trigger that started this session"),
+		flows.NewContextProperty("resume", "resume", "the current resume that continued this session"),
+		flows.NewHiddenContextProperty("legacy_extra", "any"),
+	)
+
+	flows.RegisterContextType("run",
+		flows.NewContextProperty("__default__", "text", "the contact name and flow UUID"),
+		flows.NewContextProperty("uuid", "text", "the UUID of the run"),
+		flows.NewContextProperty("contact", "contact", "the contact of the run"),
+		flows.NewContextProperty("flow", "flow", "the flow of the run"),
+		flows.NewContextProperty("status", "text", "the current status of the run"),
+		flows.NewContextProperty("results", "results", "the results saved by the run"),
+		flows.NewHiddenContextProperty("path", "any"),
+		flows.NewContextProperty("created_on", "datetime", "the creation date of the run"),
+		flows.NewContextProperty("exited_on", "datetime", "the exit date of the run"),
+	)
+
+	flows.RegisterContextType("node",
+		flows.NewContextProperty("uuid", "text", "the UUID of the node"),
+		flows.NewContextProperty("visit_count", "number", "the count of visits to the node in this run"),
+	)
+}
+
 type flowRun struct {
 	uuid        flows.RunUUID
 	session     flows.Session
@@ -29,6 +71,7 @@ type flowRun struct {
 
 	parent  flows.Run
 	results flows.Results
+	returns map[string]json.RawMessage
 	path    Path
 	events  []flows.Event
 	status  flows.RunStatus
@@ -36,16 +79,24 @@ type flowRun struct {
 	createdOn  time.Time
 	modifiedOn time.Time
 	exitedOn   *time.Time
+	expiresOn  *time.Time
+
+	timers []*flows.Timer
+	loops  []*flows.Loop
 
 	webhook     types.XValue
+	constants   *types.XObject
 	legacyExtra *legacyExtra
+
+	// transient count of template evaluation errors
+	evaluationErrors int
 }
 
 // NewRun initializes a new context and flow run for the passed in flow and contact
 func NewRun(session flows.Session, flow flows.Flow, parent flows.Run) flows.Run {
-	now := dates.Now()
+	now := session.Sources().Now()
 	r := &flowRun{
-		uuid:       flows.RunUUID(uuids.New()),
+		uuid:       flows.RunUUID(session.Sources().NewUUID()),
 		session:    session,
 		flow:       flow,
 		flowRef:    flow.Reference(true),
@@ -76,32 +127,136 @@ func (r *flowRun) Events() []flows.Event                { return r.events }
 func (r *flowRun) Results() flows.Results { return r.results }
 func (r *flowRun) SaveResult(result *flows.Result) {
 	// truncate value if necessary
-	result.Value = stringsx.Truncate(result.Value, r.Environment().MaxValueLength())
+	result.Value = stringsx.Truncate(result.Value, r.Environment().TruncationPolicy().ResultValue)
 
 	r.results.Save(result)
-	r.modifiedOn = dates.Now()
+	r.modifiedOn = r.session.Sources().Now()
+	r.session.TemplateCache().Invalidate()
 
 	r.legacyExtra.addResult(result)
 }
 
+// Returns returns the values this run returned to its parent with an exit_with action
+func (r *flowRun) Returns() map[string]json.RawMessage { return r.returns }
+
+// SetReturns sets the values this run returns to its parent
+func (r *flowRun) SetReturns(returns map[string]json.RawMessage) {
+	r.returns = returns
+	r.modifiedOn = r.session.Sources().Now()
+}
+
 func (r *flowRun) Exit(status flows.RunStatus) {
-	now := dates.Now()
+	now := r.session.Sources().Now()
 
 	r.status = status
 	r.exitedOn = &now
+	r.expiresOn = nil
 	r.modifiedOn = now
+	r.session.TemplateCache().Invalidate()
 }
 func (r *flowRun) Status() flows.RunStatus { return r.status }
 func (r *flowRun) SetStatus(status flows.RunStatus) {
 	r.status = status
-	r.modifiedOn = dates.Now()
+	r.modifiedOn = r.session.Sources().Now()
+	r.session.TemplateCache().Invalidate()
+
+	// a run can only expire whilst it's waiting
+	if status != flows.RunStatusWaiting {
+		r.expiresOn = nil
+	}
 }
 
+// ExpiresOn returns when this run will expire if it's still waiting, or nil if it won't expire
+func (r *flowRun) ExpiresOn() *time.Time { return r.expiresOn }
+
+// SetExpiresOn sets when this run will expire if it's still waiting
+func (r *flowRun) SetExpiresOn(expiresOn *time.Time) { r.expiresOn = expiresOn }
+
 func (r *flowRun) Webhook() types.XValue {
 	return r.webhook
 }
 func (r *flowRun) SetWebhook(value types.XValue) {
 	r.webhook = value
+	r.session.TemplateCache().Invalidate()
+}
+
+// EvaluateConstants evaluates the constants of this run's flow. This is done once when the run starts, and the values
+// are then kept with the run so that they don't change as it progresses.
+func (r *flowRun) EvaluateConstants(logEvent flows.EventCallback) {
+	if r.flow == nil || len(r.flow.Constants()) == 0 {
+		return
+	}
+
+	templates := r.flow.Constants()
+	values := make(map[string]types.XValue, len(templates))
+
+	for _, name := range utils.SortedKeys(templates) {
+		value, err := r.EvaluateTemplateValue(templates[name])
+		if err == nil && types.IsXError(value) {
+			err = value.(types.XError)
+		}
+		if err != nil {
+			logEvent(events.NewError(errors.Wrapf(err, "unable to evaluate constant '%s'", name)))
+			value = nil
+		}
+		values[name] = value
+	}
+
+	r.constants = types.NewXObject(values)
+	r.session.TemplateCache().Invalidate()
+}
+
+// Timers returns the timers set by this run which haven't yet fired or been cancelled
+func (r *flowRun) Timers() []*flows.Timer { return r.timers }
+
+// SetTimer sets the given timer, replacing any existing timer with the same name
+func (r *flowRun) SetTimer(timer *flows.Timer) {
+	r.CancelTimer(timer.Name)
+	r.timers = append(r.timers, timer)
+	r.modifiedOn = r.session.Sources().Now()
+}
+
+// CancelTimer removes the timer with the given name, returning it if it existed
+func (r *flowRun) CancelTimer(name string) *flows.Timer {
+	for i, t := range r.timers {
+		if t.Name == name {
+			r.timers = append(r.timers[:i], r.timers[i+1:]...)
+			r.modifiedOn = r.session.Sources().Now()
+			return t
+		}
+	}
+	return nil
+}
+
+// Loops returns the foreach loops being iterated, from outermost to innermost
+func (r *flowRun) Loops() []*flows.Loop { return r.loops }
+
+// Loop returns the foreach loop being iterated by the given node, if there is one
+func (r *flowRun) Loop(nodeUUID flows.NodeUUID) *flows.Loop {
+	for _, l := range r.loops {
+		if l.NodeUUID == nodeUUID {
+			return l
+		}
+	}
+	return nil
+}
+
+// StartLoop starts the given foreach loop, which becomes the innermost loop of the run
+func (r *flowRun) StartLoop(loop *flows.Loop) {
+	r.EndLoop(loop.NodeUUID)
+	r.loops = append(r.loops, loop)
+	r.modifiedOn = r.session.Sources().Now()
+}
+
+// EndLoop ends the foreach loop of the given node, along with any loops started inside it
+func (r *flowRun) EndLoop(nodeUUID flows.NodeUUID) {
+	for i, l := range r.loops {
+		if l.NodeUUID == nodeUUID {
+			r.loops = r.loops[:i]
+			r.modifiedOn = r.session.Sources().Now()
+			return
+		}
+	}
 }
 
 // ParentInSession returns the parent of the run within the same session if one exists
@@ -140,8 +295,30 @@ func (r *flowRun) LogEvent(s flows.Step, event flows.Event) {
 		event.SetStepUUID(s.UUID())
 	}
 
+	// events of simulated sessions are stamped with the session's fixed time
+	if eng := r.session.Engine(); eng != nil && eng.Simulation() != nil {
+		event.SetCreatedOn(r.session.Sources().Now())
+	}
+
 	r.events = append(r.events, event)
-	r.modifiedOn = dates.Now()
+	r.modifiedOn = r.session.Sources().Now()
+
+	if !outputEvents[event.Type()] {
+		r.session.TemplateCache().Invalidate()
+	}
+}
+
+// events which only record things the engine has output and can't change the context of any run
+var outputEvents = map[string]bool{
+	events.TypeMsgCreated:       true,
+	events.TypeIVRCreated:       true,
+	events.TypeBroadcastCreated: true,
+	events.TypeEmailCreated:     true,
+	events.TypeEmailSent:        true,
+	events.TypeResthookCalled:   true,
+	events.TypeSessionTriggered: true,
+	events.TypeError:            true,
+	events.TypeWarning:          true,
 }
 
 func (r *flowRun) LogError(step flows.Step, err error) {
@@ -159,13 +336,13 @@ func (r *flowRun) findEvent(stepUUID flows.StepUUID, eType string) flows.Event {
 }
 
 func (r *flowRun) ReceivedInput() bool {
-	return r.findEvent("", events.TypeMsgReceived) != nil
+	return r.findEvent("", events.TypeMsgReceived) != nil || r.findEvent("", events.TypeEmailReceived) != nil
 }
 
 func (r *flowRun) Path() []flows.Step { return r.path }
 func (r *flowRun) CreateStep(node flows.Node) flows.Step {
-	now := dates.Now()
-	step := NewStep(node, now)
+	now := r.session.Sources().Now()
+	step := NewStep(flows.StepUUID(r.session.Sources().NewUUID()), node, now)
 	r.path = append(r.path, step)
 	r.modifiedOn = now
 	return step
@@ -195,28 +372,12 @@ func (r *flowRun) ModifiedOn() time.Time { return r.modifiedOn }
 func (r *flowRun) ExitedOn() *time.Time  { return r.exitedOn }
 
 // RootContext returns the root context for expression evaluation
-//
-//	contact:contact -> the contact
-//	fields:fields -> the custom field values of the contact
-//	urns:urns -> the URN values of the contact
-//	results:results -> the current run results
-//	input:input -> the current input from the contact
-//	run:run -> the current run
-//	child:related_run -> the last child run
-//	parent:related_run -> the parent of the run
-//	ticket:ticket -> the last opened ticket for the contact
-//	webhook:any -> the parsed JSON response of the last webhook call
-//	node:node -> the current node
-//	globals:globals -> the global values
-//	trigger:trigger -> the trigger that started this session
-//	resume:resume -> the current resume that continued this session
-//
-// @context root
 func (r *flowRun) RootContext(env envs.Environment) map[string]types.XValue {
-	var urns, fields, ticket, node types.XValue
+	var urns, fields, related, ticket, node types.XValue
 	if r.Contact() != nil {
 		urns = flows.ContextFunc(env, r.Contact().URNs().MapContext)
 		fields = flows.Context(env, r.Contact().Fields())
+		related = r.relatedContext(env)
 
 		tickets := r.Contact().Tickets()
 
@@ -225,6 +386,25 @@ func (r *flowRun) RootContext(env envs.Environment) map[string]types.XValue {
 		}
 	}
 
+	var item, index types.XValue
+	if len(r.loops) > 0 {
+		loop := r.loops[len(r.loops)-1]
+		if !loop.Done() {
+			item = loop.Item()
+			index = types.NewXNumberFromInt(loop.Index)
+		}
+	}
+
+	var intents types.XValue
+	if classification := r.Session().Classification(); classification != nil {
+		intents = flows.Context(env, classification)
+	}
+
+	var constants types.XValue = types.XObjectEmpty
+	if r.constants != nil {
+		constants = r.constants
+	}
+
 	var child = newRelatedRunContext(r.Session().GetCurrentChild(r))
 	var parent = newRelatedRunContext(r.Parent())
 
@@ -244,7 +424,10 @@ func (r *flowRun) RootContext(env envs.Environment) map[string]types.XValue {
 		"results": flows.Context(env, r.Results()),
 		"urns":    urns,
 		"fields":  fields,
+		"related": related,
 		"ticket":  ticket,
+		"cart":    flows.Context(env, r.Session().Cart()),
+		"intents": intents,
 
 		// other
 		"trigger":      flows.Context(env, r.Session().Trigger()),
@@ -252,23 +435,34 @@ func (r *flowRun) RootContext(env envs.Environment) map[string]types.XValue {
 		"input":        flows.Context(env, r.Session().Input()),
 		"globals":      flows.Context(env, r.Session().Assets().Globals()),
 		"webhook":      r.webhook,
+		"consts":       constants,
 		"node":         node,
+		"item":         item,
+		"index":        index,
 		"legacy_extra": r.legacyExtra.ToXValue(env),
 	}
 }
 
+// returns the context representation of the contacts related to the contact, which are only available if they were
+// loaded from the engine's contact provider at the start of the sprint
+func (r *flowRun) relatedContext(env envs.Environment) types.XValue {
+	relations := r.Contact().Relations()
+	keys := make([]string, 0, len(relations))
+	for k := range relations {
+		keys = append(keys, k)
+	}
+
+	return types.NewXLazyKeyedObject(keys, func(key string) types.XValue {
+		if relation := relations[key]; relation != nil {
+			if contact := r.Session().RelatedContact(relation.Contact().UUID); contact != nil {
+				return flows.Context(env, contact)
+			}
+		}
+		return nil
+	})
+}
+
 // Context returns the properties available in expressions
-//
-//	__default__:text -> the contact name and flow UUID
-//	uuid:text -> the UUID of the run
-//	contact:contact -> the contact of the run
-//	flow:flow -> the flow of the run
-//	status:text -> the current status of the run
-//	results:results -> the results saved by the run
-//	created_on:datetime -> the creation date of the run
-//	exited_on:datetime -> the exit date of the run
-//
-// @context run
 func (r *flowRun) Context(env envs.Environment) map[string]types.XValue {
 	var exitedOn types.XValue
 	if r.exitedOn != nil {
@@ -289,11 +483,6 @@ func (r *flowRun) Context(env envs.Environment) map[string]types.XValue {
 }
 
 // returns the context representation of the current node
-//
-//	uuid:text -> the UUID of the node
-//	visit_count:number -> the count of visits to the node in this run
-//
-// @context node
 func (r *flowRun) nodeContext(env envs.Environment) map[string]types.XValue {
 	_, node, _ := r.PathLocation()
 	visitCount := 0
@@ -311,19 +500,51 @@ func (r *flowRun) nodeContext(env envs.Environment) map[string]types.XValue {
 
 // EvaluateTemplate evaluates the given template in the context of this run
 func (r *flowRun) EvaluateTemplateValue(template string) (types.XValue, error) {
-	ctx := types.NewXObject(r.RootContext(r.Environment()))
+	value, err := r.Session().TemplateCache().EvaluateValue(r.UUID(), template, func() (types.XValue, error) {
+		defer r.Session().Profiler().Start(flows.ProfileCategoryTemplate, template)()
 
-	return excellent.EvaluateTemplateValue(r.Environment(), ctx, template)
+		ctx := types.NewXObject(r.RootContext(r.Environment()))
+
+		if compiled := r.flow.CompiledTemplate(template); compiled != nil {
+			return compiled.EvaluateValue(r.Environment(), ctx)
+		}
+		return r.Session().Arena().EvaluateTemplateValue(r.Environment(), ctx, template)
+	})
+	if err != nil {
+		r.evaluationErrors++
+	}
+	r.Session().DebugLog().Record(r, template, value, err)
+	return value, err
 }
 
 // EvaluateTemplateText evaluates the given template as text in the context of this run
 func (r *flowRun) EvaluateTemplateText(template string, escaping excellent.Escaping, truncate bool) (string, error) {
-	ctx := types.NewXObject(r.RootContext(r.Environment()))
+	evaluate := func() (string, error) {
+		defer r.Session().Profiler().Start(flows.ProfileCategoryTemplate, template)()
+
+		ctx := types.NewXObject(r.RootContext(r.Environment()))
+
+		if compiled := r.flow.CompiledTemplate(template); compiled != nil {
+			return compiled.Evaluate(r.Environment(), ctx, escaping)
+		}
+		return r.Session().Arena().EvaluateTemplate(r.Environment(), ctx, template, escaping)
+	}
 
-	value, err := excellent.EvaluateTemplate(r.Environment(), ctx, template, escaping)
+	// only unescaped evaluations are cached as we can't tell escaping functions apart
+	var value string
+	var err error
+	if escaping == nil {
+		value, err = r.Session().TemplateCache().EvaluateText(r.UUID(), template, evaluate)
+	} else {
+		value, err = evaluate()
+	}
+	if err != nil {
+		r.evaluationErrors++
+	}
 	if truncate {
 		value = stringsx.TruncateEllipsis(value, r.Session().Engine().MaxTemplateChars())
 	}
+	r.Session().DebugLog().Record(r, template, types.NewXText(value), err)
 	return value, err
 }
 
@@ -332,6 +553,11 @@ func (r *flowRun) EvaluateTemplate(template string) (string, error) {
 	return r.EvaluateTemplateText(template, nil, true)
 }
 
+// EvaluationErrors returns the number of template evaluation errors encountered by this run since it was loaded
+func (r *flowRun) EvaluationErrors() int {
+	return r.evaluationErrors
+}
+
 // get the ordered list of languages to be used for localization in this run
 func (r *flowRun) getLanguages() []envs.Language {
 	languages := make([]envs.Language, 0, 3)
@@ -353,6 +579,26 @@ func (r *flowRun) getLanguages() []envs.Language {
 	return append(languages, r.flow.Language())
 }
 
+// expands the given list of languages to include after each language the fallbacks declared for it by the flow
+func (r *flowRun) withFallbacks(languages []envs.Language) []envs.Language {
+	expanded := make([]envs.Language, 0, len(languages))
+	seen := make(map[envs.Language]bool, len(languages))
+	add := func(lang envs.Language) {
+		if !seen[lang] {
+			seen[lang] = true
+			expanded = append(expanded, lang)
+		}
+	}
+
+	for _, lang := range languages {
+		add(lang)
+		for _, fallback := range r.Flow().LanguageFallbacks(lang) {
+			add(fallback)
+		}
+	}
+	return expanded
+}
+
 // GetText is a convenience version of GetTextArray for a single text values
 func (r *flowRun) GetText(uuid uuids.UUID, key string, native string) (string, envs.Language) {
 	textArray, lang := r.getText(uuid, key, []string{native}, nil)
@@ -372,7 +618,7 @@ func (r *flowRun) getText(uuid uuids.UUID, key string, native []string, language
 		languages = r.getLanguages()
 	}
 
-	for _, lang := range languages {
+	for _, lang := range r.withFallbacks(languages) {
 		if lang == r.Flow().Language() {
 			return native, nativeLang
 		}
@@ -399,17 +645,22 @@ var _ flows.RunSummary = (*flowRun)(nil)
 //------------------------------------------------------------------------------------------
 
 type runEnvelope struct {
-	UUID       flows.RunUUID         `json:"uuid" validate:"required,uuid4"`
-	Flow       *assets.FlowReference `json:"flow" validate:"required,dive"`
-	Path       []*step               `json:"path" validate:"dive"`
-	Events     []json.RawMessage     `json:"events,omitempty"`
-	Results    flows.Results         `json:"results,omitempty" validate:"omitempty,dive"`
-	Status     flows.RunStatus       `json:"status" validate:"required"`
-	ParentUUID flows.RunUUID         `json:"parent_uuid,omitempty" validate:"omitempty,uuid4"`
+	UUID       flows.RunUUID              `json:"uuid" validate:"required,uuid4"`
+	Flow       *assets.FlowReference      `json:"flow" validate:"required,dive"`
+	Path       []*step                    `json:"path" validate:"dive"`
+	Events     []json.RawMessage          `json:"events,omitempty"`
+	Results    flows.Results              `json:"results,omitempty" validate:"omitempty,dive"`
+	Returns    map[string]json.RawMessage `json:"returns,omitempty"`
+	Status     flows.RunStatus            `json:"status" validate:"required"`
+	ParentUUID flows.RunUUID              `json:"parent_uuid,omitempty" validate:"omitempty,uuid4"`
+	Timers     []*flows.Timer             `json:"timers,omitempty" validate:"omitempty,dive"`
+	Loops      []*flows.Loop              `json:"loops,omitempty" validate:"omitempty,dive"`
+	Constants  json.RawMessage            `json:"constants,omitempty"`
 
 	CreatedOn  time.Time  `json:"created_on" validate:"required"`
 	ModifiedOn time.Time  `json:"modified_on" validate:"required"`
 	ExitedOn   *time.Time `json:"exited_on"`
+	ExpiresOn  *time.Time `json:"expires_on,omitempty"`
 }
 
 // ReadRun decodes a run from the passed in JSON. Parent run UUID is returned separately as the
@@ -430,6 +681,10 @@ func ReadRun(session flows.Session, data json.RawMessage, missing assets.Missing
 		createdOn:  e.CreatedOn,
 		modifiedOn: e.ModifiedOn,
 		exitedOn:   e.ExitedOn,
+		expiresOn:  e.ExpiresOn,
+		returns:    e.Returns,
+		timers:     e.Timers,
+		loops:      e.Loops,
 	}
 
 	// lookup actual flow
@@ -444,6 +699,12 @@ func ReadRun(session flows.Session, data json.RawMessage, missing assets.Missing
 		}
 	}
 
+	if e.Constants != nil {
+		if r.constants, err = types.ReadXObject(e.Constants); err != nil {
+			return nil, errors.Wrap(err, "unable to read constants")
+		}
+	}
+
 	if e.Results != nil {
 		r.results = e.Results
 	} else {
@@ -483,21 +744,35 @@ func (r *flowRun) MarshalJSON() ([]byte, error) {
 		CreatedOn:  r.createdOn,
 		ModifiedOn: r.modifiedOn,
 		ExitedOn:   r.exitedOn,
+		ExpiresOn:  r.expiresOn,
 		Results:    r.results,
+		Returns:    r.returns,
+		Timers:     r.timers,
+		Loops:      r.loops,
 	}
 
 	if r.parent != nil {
 		e.ParentUUID = r.parent.UUID()
 	}
 
+	if r.constants != nil {
+		if e.Constants, err = jsonx.Marshal(r.constants); err != nil {
+			return nil, errors.Wrap(err, "unable to marshal constants")
+		}
+	}
+
 	e.Path = make([]*step, len(r.path))
 	for i, s := range r.path {
 		e.Path[i] = s.(*step)
 	}
 
+	// mask anything in our events which the environment's redaction rules say shouldn't leave the engine
+	piiValues := flows.PIIValues(r.Environment().RedactionRules(), r.Results())
+	redactor := flows.NewRedactor(r.Environment(), r.Contact(), piiValues...)
+
 	e.Events = make([]json.RawMessage, len(r.events))
 	for i := range r.events {
-		if e.Events[i], err = jsonx.Marshal(r.events[i]); err != nil {
+		if e.Events[i], err = events.MarshalRedacted(r.events[i], redactor); err != nil {
 			return nil, errors.Wrapf(err, "unable to marshal event[type=%s]", r.events[i].Type())
 		}
 	}
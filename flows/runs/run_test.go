@@ -1,6 +1,8 @@
 package runs_test
 
 import (
+	"context"
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
@@ -14,11 +16,13 @@ import (
 	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
 	"github.com/nyaruka/goflow/flows/runs"
 	"github.com/nyaruka/goflow/flows/triggers"
 	"github.com/nyaruka/goflow/test"
 	"github.com/nyaruka/goflow/utils"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -180,6 +184,10 @@ func TestRunContext(t *testing.T) {
 		{`@parent.flow.name`, "Parent"},
 		{`@parent.status`, "active"},
 		{`@parent.fields`, "Age: 33\nGender: Female"},
+		{`@related.caregiver`, `Sarah Haggerty`},
+		{`@related.caregiver.uuid`, `0cb17b2a-3bfe-4a19-8c99-98ab9561045d`},
+		{`@related.caregiver.urn`, `tel:+12065551313`},
+		{`@related.household_head`, ``},
 		{`@node.uuid`, "c0781400-737f-4940-9a6c-1ec1c3df0325"},
 		{`@node.visit_count`, "1"},
 		{`@trigger.type`, "flow_action"},
@@ -243,7 +251,7 @@ func TestMissingRelatedRunContext(t *testing.T) {
 	require.NoError(t, err)
 
 	eng := test.NewEngine()
-	session, _, err := eng.NewSession(sa, trigger)
+	session, _, err := eng.NewSession(context.Background(), sa, trigger)
 	require.NoError(t, err)
 
 	run := session.Runs()[0]
@@ -277,7 +285,7 @@ func TestSaveResult(t *testing.T) {
 	require.NoError(t, err)
 
 	eng := test.NewEngine()
-	session, _, err := eng.NewSession(sa, trigger)
+	session, _, err := eng.NewSession(context.Background(), sa, trigger)
 	require.NoError(t, err)
 
 	run := session.Runs()[0]
@@ -308,6 +316,35 @@ func TestSaveResult(t *testing.T) {
 	assert.Equal(t, strings.Repeat("創", 640), run.Results().Get("response_1").Value)
 }
 
+func TestRedaction(t *testing.T) {
+	sa, err := test.CreateSessionAssets([]byte(sessionAssets), "")
+	require.NoError(t, err)
+
+	triggerJSON := strings.Replace(sessionTrigger, `"redaction_policy": "none",`, `"redaction_policy": "none", "redaction_rules": {"urn_schemes": ["tel"]},`, 1)
+	trigger, err := triggers.ReadTrigger(sa, []byte(triggerJSON), assets.IgnoreMissing)
+	require.NoError(t, err)
+
+	eng := test.NewEngine()
+	session, _, err := eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	run := session.Runs()[0]
+	event := events.NewError(errors.New("unable to send to +12065551212"))
+	run.LogEvent(nil, event)
+
+	// events are left as they were generated in memory...
+	assert.Equal(t, "unable to send to +12065551212", event.Text)
+
+	// but are redacted when the run is marshaled
+	runJSON, err := jsonx.Marshal(run)
+	require.NoError(t, err)
+	assert.NotContains(t, string(runJSON), "+12065551212")
+
+	run2, err := runs.ReadRun(session, runJSON, assets.IgnoreMissing)
+	require.NoError(t, err)
+	assert.Equal(t, "unable to send to ****************", run2.Events()[len(run2.Events())-1].(*events.ErrorEvent).Text)
+}
+
 func TestTranslation(t *testing.T) {
 	msgAction1 := []byte(`{
 		"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a",
@@ -438,3 +475,113 @@ func TestTranslation(t *testing.T) {
 		assert.Equal(t, tc.expectedQuickReplies, evt.Msg.QuickReplies(), "quick replies mismatch in test case '%s'", tc.description)
 	}
 }
+
+func TestTranslationFallbacks(t *testing.T) {
+	assetsJSON, _ := os.ReadFile("testdata/translation_assets.json")
+	assetsJSON = test.JSONReplace(assetsJSON, []string{"flows", "[0]", "nodes", "[0]", "actions", "[0]"}, []byte(`{
+		"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a",
+		"type": "send_msg",
+		"text": "Hello",
+		"quick_replies": ["yes", "no"]
+	}`))
+	assetsJSON = test.JSONReplace(assetsJSON, []string{"flows", "[0]", "language_fallbacks"}, []byte(`{"kin": ["spa"], "fra": ["spa"]}`))
+
+	tcs := []struct {
+		contactLang          envs.Language
+		expectedText         string
+		expectedQuickReplies []string
+	}{
+		{"kin", "Hola", []string{"si"}},         // no kin translations so uses spa ones
+		{"fra", "Bonjour", []string{"si"}},      // fra has text but not quick replies
+		{"spa", "Hola", []string{"si"}},         // spa translations used directly
+		{"eng", "Hello", []string{"yes", "no"}}, // flow language
+	}
+
+	for _, tc := range tcs {
+		env := envs.NewBuilder().WithAllowedLanguages([]envs.Language{"eng", "spa", "fra", "kin"}).Build()
+		_, _, sp := test.NewSessionBuilder().
+			WithEnvironment(env).
+			WithContact("2efa1803-ae4d-4a58-ba54-b523e53e40f3", 123, "Bob", tc.contactLang, "tel+1234567890").
+			WithAssetsJSON(assetsJSON).
+			MustBuild()
+
+		require.Len(t, sp.Events(), 1)
+		evt := sp.Events()[0].(*events.MsgCreatedEvent)
+
+		assert.Equal(t, tc.expectedText, evt.Msg.Text(), "msg text mismatch for contact language %s", tc.contactLang)
+		assert.Equal(t, tc.expectedQuickReplies, evt.Msg.QuickReplies(), "quick replies mismatch for contact language %s", tc.contactLang)
+	}
+}
+
+func TestConstants(t *testing.T) {
+	assetsJSON, _ := os.ReadFile("testdata/translation_assets.json")
+	assetsJSON = test.JSONReplace(assetsJSON, []string{"flows", "[0]", "nodes", "[0]", "actions", "[0]"}, []byte(`{
+		"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a",
+		"type": "send_msg",
+		"text": "@consts.greeting You have @(consts.max_tries - 1) tries left@consts.broken"
+	}`))
+	assetsJSON = test.JSONReplace(assetsJSON, []string{"flows", "[0]", "constants"}, []byte(`{"greeting": "Hi @contact.name!", "max_tries": "@(1 + 2)", "broken": "@(1 / 0)"}`))
+
+	_, session, sp := test.NewSessionBuilder().
+		WithContact("2efa1803-ae4d-4a58-ba54-b523e53e40f3", 123, "Bob", "eng", "tel+1234567890").
+		WithAssetsJSON(assetsJSON).
+		MustBuild()
+
+	require.Len(t, sp.Events(), 2)
+	assert.Equal(t, "unable to evaluate constant 'broken': division by zero", sp.Events()[0].(*events.ErrorEvent).Text)
+	assert.Equal(t, "Hi Bob! You have 2 tries left", sp.Events()[1].(*events.MsgCreatedEvent).Msg.Text())
+
+	// constants are saved with the run so that they're not evaluated again
+	run := session.Runs()[0]
+	runJSON, err := jsonx.Marshal(run)
+	require.NoError(t, err)
+
+	saved := &struct {
+		Constants json.RawMessage `json:"constants"`
+	}{}
+	require.NoError(t, jsonx.Unmarshal(runJSON, saved))
+	test.AssertEqualJSON(t, []byte(`{"broken": null, "greeting": "Hi Bob!", "max_tries": 3}`), saved.Constants, "constants mismatch")
+
+	// and they can't be changed by the run
+	session.Contact().SetName("Jim")
+
+	greeting, err := run.EvaluateTemplate("@consts.greeting")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi Bob!", greeting)
+}
+
+func TestExpiresOn(t *testing.T) {
+	dates.SetNowSource(dates.NewFixedNowSource(time.Date(2018, 10, 18, 14, 20, 30, 123456, time.UTC)))
+	defer dates.SetNowSource(dates.DefaultNowSource)
+
+	assetsJSON, _ := os.ReadFile("../../test/testdata/runner/two_questions.json")
+	assetsJSON = test.JSONReplace(assetsJSON, []string{"flows", "[0]", "expire_after_minutes"}, []byte(`30`))
+
+	sa, session, _ := test.NewSessionBuilder().
+		WithAssetsJSON(assetsJSON).
+		WithFlow("615b8a0f-588c-4d20-a05f-363b0b4ce6f4").
+		MustBuild()
+
+	expiresOn := time.Date(2018, 10, 18, 14, 50, 30, 123456, time.UTC)
+
+	// run is stamped with an expiry when it starts waiting
+	assert.Equal(t, flows.RunStatusWaiting, session.Runs()[0].Status())
+	assert.Equal(t, &expiresOn, session.Runs()[0].ExpiresOn())
+
+	// which is re-stamped when it waits again, and survives the session being marshaled
+	dates.SetNowSource(dates.NewFixedNowSource(time.Date(2018, 10, 18, 15, 0, 0, 0, time.UTC)))
+
+	session, _, err := test.ResumeSession(session, sa, "Blue")
+	require.NoError(t, err)
+
+	expiresOn = time.Date(2018, 10, 18, 15, 30, 0, 0, time.UTC)
+	assert.Equal(t, flows.RunStatusWaiting, session.Runs()[0].Status())
+	assert.Equal(t, &expiresOn, session.Runs()[0].ExpiresOn())
+
+	// and is cleared when it exits
+	_, err = session.Resume(context.Background(), resumes.NewExpiration(session.Environment(), session.Contact()))
+	require.NoError(t, err)
+
+	assert.Equal(t, flows.RunStatusExpired, session.Runs()[0].Status())
+	assert.Nil(t, session.Runs()[0].ExpiresOn())
+}
@@ -4,10 +4,10 @@ import (
 	"time"
 
 	"github.com/nyaruka/gocommon/jsonx"
-	"github.com/nyaruka/gocommon/uuids"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
 )
 
 type step struct {
@@ -18,9 +18,9 @@ type step struct {
 }
 
 // NewStep creates a new step
-func NewStep(node flows.Node, arrivedOn time.Time) flows.Step {
+func NewStep(uuid flows.StepUUID, node flows.Node, arrivedOn time.Time) flows.Step {
 	return &step{
-		stepUUID:  flows.StepUUID(uuids.New()),
+		stepUUID:  uuid,
 		nodeUUID:  node.UUID(),
 		arrivedOn: arrivedOn,
 	}
@@ -80,8 +80,8 @@ func (s *step) UnmarshalJSON(data []byte) error {
 	}
 
 	s.stepUUID = se.UUID
-	s.nodeUUID = se.NodeUUID
-	s.exitUUID = se.ExitUUID
+	s.nodeUUID = utils.Intern(se.NodeUUID)
+	s.exitUUID = utils.Intern(se.ExitUUID)
 	s.arrivedOn = se.ArrivedOn
 	return err
 }
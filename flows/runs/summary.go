@@ -12,6 +12,21 @@ import (
 	"github.com/nyaruka/goflow/utils"
 )
 
+func init() {
+	flows.RegisterContextType("related_run",
+		flows.NewContextProperty("__default__", "text", "the contact name and flow UUID"),
+		flows.NewContextProperty("uuid", "text", "the UUID of the run"),
+		flows.NewContextProperty("contact", "contact", "the contact of the run"),
+		flows.NewContextProperty("flow", "flow", "the flow of the run"),
+		flows.NewContextProperty("fields", "fields", "the custom field values of the run's contact"),
+		flows.NewContextProperty("urns", "urns", "the URN values of the run's contact"),
+		flows.NewContextProperty("results", "any", "the results saved by the run"),
+		flows.NewContextProperty("returns", "any", "the values returned by the run with an exit_with action"),
+		flows.NewContextProperty("status", "text", "the current status of the run"),
+		flows.NewHiddenContextProperty("run", "any"),
+	)
+}
+
 // concrete run summary which might be stored on a trigger or event
 type runSummary struct {
 	uuid    flows.RunUUID
@@ -20,6 +35,7 @@ type runSummary struct {
 	contact *flows.Contact
 	status  flows.RunStatus
 	results flows.Results
+	returns map[string]json.RawMessage
 }
 
 // creates a new run summary from the given run
@@ -31,14 +47,16 @@ func newRunSummaryFromRun(run flows.Run) flows.RunSummary {
 		contact: run.Contact().Clone(),
 		status:  run.Status(),
 		results: run.Results().Clone(),
+		returns: run.Returns(),
 	}
 }
 
-func (r *runSummary) UUID() flows.RunUUID     { return r.uuid }
-func (r *runSummary) Flow() flows.Flow        { return r.flow }
-func (r *runSummary) Contact() *flows.Contact { return r.contact }
-func (r *runSummary) Status() flows.RunStatus { return r.status }
-func (r *runSummary) Results() flows.Results  { return r.results }
+func (r *runSummary) UUID() flows.RunUUID                 { return r.uuid }
+func (r *runSummary) Flow() flows.Flow                    { return r.flow }
+func (r *runSummary) Contact() *flows.Contact             { return r.contact }
+func (r *runSummary) Status() flows.RunStatus             { return r.status }
+func (r *runSummary) Results() flows.Results              { return r.results }
+func (r *runSummary) Returns() map[string]json.RawMessage { return r.returns }
 
 var _ flows.RunSummary = (*runSummary)(nil)
 
@@ -55,17 +73,6 @@ func newRelatedRunContext(run flows.RunSummary) *relatedRunContext {
 }
 
 // Context returns the properties available in expressions for @parent and @child
-//
-//	__default__:text -> the contact name and flow UUID
-//	uuid:text -> the UUID of the run
-//	contact:contact -> the contact of the run
-//	flow:flow -> the flow of the run
-//	fields:fields -> the custom field values of the run's contact
-//	urns:urns -> the URN values of the run's contact
-//	results:any -> the results saved by the run
-//	status:text -> the current status of the run
-//
-// @context related_run
 func (c *relatedRunContext) Context(env envs.Environment) map[string]types.XValue {
 	var urns, fields types.XValue
 	if c.run.Contact() != nil {
@@ -73,6 +80,11 @@ func (c *relatedRunContext) Context(env envs.Environment) map[string]types.XValu
 		fields = flows.Context(env, c.run.Contact().Fields())
 	}
 
+	returns := make(map[string]types.XValue, len(c.run.Returns()))
+	for k, v := range c.run.Returns() {
+		returns[k] = types.JSONToXValue(v)
+	}
+
 	return map[string]types.XValue{
 		"__default__": types.NewXText(FormatRunSummary(env, c.run)),
 		"uuid":        types.NewXText(string(c.run.UUID())),
@@ -81,6 +93,7 @@ func (c *relatedRunContext) Context(env envs.Environment) map[string]types.XValu
 		"urns":        urns,
 		"fields":      fields,
 		"results":     flows.Context(env, c.run.Results()),
+		"returns":     types.NewXObject(returns),
 		"status":      types.NewXText(string(c.run.Status())),
 
 		// deprecated but used by a lot of flows for @child.run.status as that is what editor has
@@ -115,11 +128,12 @@ func FormatRunSummary(env envs.Environment, run flows.RunSummary) string {
 //------------------------------------------------------------------------------------------
 
 type runSummaryEnvelope struct {
-	UUID    flows.RunUUID         `json:"uuid" validate:"uuid4"`
-	Flow    *assets.FlowReference `json:"flow" validate:"required,dive"`
-	Contact json.RawMessage       `json:"contact"`
-	Status  flows.RunStatus       `json:"status" validate:"required"`
-	Results flows.Results         `json:"results"`
+	UUID    flows.RunUUID              `json:"uuid" validate:"uuid4"`
+	Flow    *assets.FlowReference      `json:"flow" validate:"required,dive"`
+	Contact json.RawMessage            `json:"contact"`
+	Status  flows.RunStatus            `json:"status" validate:"required"`
+	Results flows.Results              `json:"results"`
+	Returns map[string]json.RawMessage `json:"returns,omitempty"`
 }
 
 // ReadRunSummary reads a run summary from the given JSON
@@ -135,6 +149,7 @@ func ReadRunSummary(sessionAssets flows.SessionAssets, data json.RawMessage, mis
 		flowRef: e.Flow,
 		status:  e.Status,
 		results: e.Results,
+		returns: e.Returns,
 	}
 
 	// lookup the actual flow
@@ -161,6 +176,7 @@ func (r *runSummary) MarshalJSON() ([]byte, error) {
 	envelope.Flow = r.flowRef
 	envelope.Status = r.status
 	envelope.Results = r.results
+	envelope.Returns = r.returns
 
 	if r.contact != nil {
 		if envelope.Contact, err = r.contact.MarshalJSON(); err != nil {
@@ -0,0 +1,32 @@
+package flows
+
+import (
+	"github.com/nyaruka/gocommon/urns"
+)
+
+// SchemeCapabilities describes what messages sent to URNs of a particular scheme can contain
+type SchemeCapabilities struct {
+	MaxTextLength   int  // maximum number of characters of text, or zero if there's no limit
+	QuickReplies    bool // whether quick replies are supported
+	MaxQuickReplies int  // maximum number of quick replies, or zero if there's no limit
+	MaxAttachments  int  // maximum number of attachments, or zero if there's no limit
+}
+
+var registeredSchemeCapabilities = map[string]*SchemeCapabilities{}
+
+func init() {
+	RegisterSchemeCapabilities(urns.WeChatScheme, &SchemeCapabilities{MaxTextLength: 2048, QuickReplies: false, MaxAttachments: 1})
+	RegisterSchemeCapabilities(urns.ViberScheme, &SchemeCapabilities{MaxTextLength: 7000, QuickReplies: true, MaxQuickReplies: 24, MaxAttachments: 1})
+	RegisterSchemeCapabilities(urns.LineScheme, &SchemeCapabilities{MaxTextLength: 5000, QuickReplies: true, MaxQuickReplies: 13, MaxAttachments: 5})
+}
+
+// RegisterSchemeCapabilities registers the capabilities of the given URN scheme, replacing any existing ones
+func RegisterSchemeCapabilities(scheme string, caps *SchemeCapabilities) {
+	registeredSchemeCapabilities[scheme] = caps
+}
+
+// CapabilitiesForScheme returns the capabilities of the given URN scheme, or nil if messages to that scheme aren't
+// restricted by the engine
+func CapabilitiesForScheme(scheme string) *SchemeCapabilities {
+	return registeredSchemeCapabilities[scheme]
+}
@@ -1,6 +1,8 @@
 package flows
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -9,10 +11,30 @@ import (
 	"github.com/nyaruka/gocommon/urns"
 	"github.com/nyaruka/gocommon/uuids"
 	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/utils"
 
 	"github.com/shopspring/decimal"
 )
 
+// ServiceType is the type of a service provided to the engine
+type ServiceType string
+
+// service types
+const (
+	ServiceTypeEmail          ServiceType = "email"
+	ServiceTypeWebhook        ServiceType = "webhook"
+	ServiceTypeClassification ServiceType = "classification"
+	ServiceTypeTicket         ServiceType = "ticket"
+	ServiceTypeAirtime        ServiceType = "airtime"
+	ServiceTypeDataCollection ServiceType = "data_collection"
+	ServiceTypeCommerce       ServiceType = "commerce"
+	ServiceTypeCallRecording  ServiceType = "call_recording"
+	ServiceTypeCallTransfer   ServiceType = "call_transfer"
+	ServiceTypeCredential     ServiceType = "credential"
+	ServiceTypeExchangeRate   ServiceType = "exchange_rate"
+	ServiceTypeAttachment     ServiceType = "attachment"
+)
+
 // Services groups together interfaces for several services whose implementation is provided outside of the flow engine.
 type Services interface {
 	Email(SessionAssets) (EmailService, error)
@@ -20,11 +42,51 @@ type Services interface {
 	Classification(*Classifier) (ClassificationService, error)
 	Ticket(*Ticketer) (TicketService, error)
 	Airtime(SessionAssets) (AirtimeService, error)
+	DataCollection(SessionAssets) (DataCollectionService, error)
+	Commerce(SessionAssets) (CommerceService, error)
+	CallRecording(SessionAssets) (CallRecordingService, error)
+	CallTransfer(SessionAssets) (CallTransferService, error)
+	Credential(SessionAssets) (CredentialService, error)
+	ExchangeRate(SessionAssets) (ExchangeRateService, error)
+	Attachment(SessionAssets) (AttachmentService, error)
+	GroupMembership(SessionAssets) (GroupMembershipService, error)
+	HTTPLogSink(SessionAssets) (HTTPLogSink, error)
+}
+
+// CheckableService is a service which can check that it's usable, e.g. that its credentials are valid, so that problems
+// can be found before flows try to use it
+type CheckableService interface {
+	Check(ctx context.Context) error
 }
 
 // EmailService provides email functionality to the engine
 type EmailService interface {
-	Send(addresses []string, subject, body string) error
+	Send(ctx context.Context, addresses []string, subject, body string) error
+}
+
+// Email is an email to be sent by an email service
+type Email struct {
+	Addresses   []string
+	ReplyTo     string
+	Subject     string
+	Body        string
+	HTMLBody    string
+	Attachments []utils.Attachment
+}
+
+// RichEmailService is an email service which can also send emails with HTML bodies, attachments fetched from their
+// URLs, and reply-to addresses
+type RichEmailService interface {
+	EmailService
+
+	SendEmail(ctx context.Context, email *Email) error
+}
+
+// CredentialService provides named credentials to the engine, so that flows can refer to secrets like API tokens by name
+// rather than containing them
+type CredentialService interface {
+	// Authorization returns the Authorization header value for the named credential
+	Authorization(ctx context.Context, name string) (string, error)
 }
 
 // CallStatus represents the status of a call to an external service
@@ -47,8 +109,22 @@ const (
 // WebhookCall is the result of a webhook call
 type WebhookCall struct {
 	*httpx.Trace
-	ResponseJSON    []byte
-	ResponseCleaned bool // whether response had to be cleaned to make it valid JSON
+	ResponseJSON      []byte
+	ResponseCleaned   bool            // whether response had to be cleaned to make it valid JSON
+	ResponseFromXML   bool            // whether response was XML which was converted to JSON
+	ResponseTruncated bool            // whether response body was cut off because it exceeded the size limit
+	Breaker           *WebhookBreaker // set if this call changed the state of the circuit breaker for its host
+}
+
+// WebhookRequestUUID is the type of the UUIDs of webhook requests which are made asynchronously by the host
+type WebhookRequestUUID uuids.UUID
+
+// WebhookCallback is the response to a webhook request which the host made asynchronously, delivered to the engine
+// when the session is resumed
+type WebhookCallback struct {
+	RequestUUID WebhookRequestUUID `json:"request_uuid" validate:"required,uuid4"`
+	StatusCode  int                `json:"status_code"` // zero if the host couldn't connect
+	Body        string             `json:"body,omitempty"`
 }
 
 // WebhookService provides webhook functionality to the engine
@@ -56,10 +132,64 @@ type WebhookService interface {
 	Call(request *http.Request) (*WebhookCall, error)
 }
 
-// ExtractedIntent models an intent match
+// WebhookBreakerState is the state of the circuit breaker which protects a webhook host
+type WebhookBreakerState string
+
+// possible circuit breaker states
+const (
+	WebhookBreakerClosed   WebhookBreakerState = "closed"    // calls are made as normal
+	WebhookBreakerOpen     WebhookBreakerState = "open"      // calls are blocked after repeated failures
+	WebhookBreakerHalfOpen WebhookBreakerState = "half_open" // a single trial call is allowed to see if the host has recovered
+)
+
+// WebhookBreaker describes the circuit breaker for a webhook host
+type WebhookBreaker struct {
+	Host    string
+	State   WebhookBreakerState
+	RetryOn *time.Time // when an open breaker will next allow a trial call
+}
+
+// WebhookBlockedError is returned by a webhook service which refuses to make a call, either because the rate limit for
+// the host has been reached or because its circuit breaker isn't closed
+type WebhookBlockedError struct {
+	Host    string
+	Breaker *WebhookBreaker // nil if the call was blocked by the rate limit
+}
+
+func (e *WebhookBlockedError) Error() string {
+	if e.Breaker != nil {
+		return fmt.Sprintf("webhook calls to %s are temporarily disabled after repeated failures", e.Host)
+	}
+	return fmt.Sprintf("webhook calls to %s have exceeded the rate limit", e.Host)
+}
+
+// WebhookDeniedError is returned by a webhook service which refuses to make a call because the URL, or the address it
+// resolves to, isn't allowed by the service's host policy
+type WebhookDeniedError struct {
+	URL    string
+	Reason string
+}
+
+func (e *WebhookDeniedError) Error() string {
+	return fmt.Sprintf("webhook call to %s blocked: %s", e.URL, e.Reason)
+}
+
+// WebhookRequestTooLargeError is returned by a webhook service which refuses to make a call because the request body
+// exceeds its size limit
+type WebhookRequestTooLargeError struct {
+	Limit int
+}
+
+func (e *WebhookRequestTooLargeError) Error() string {
+	return fmt.Sprintf("webhook request body exceeds %d bytes limit", e.Limit)
+}
+
+// ExtractedIntent models an intent match. If the classifier is calibrated, calibrated is the percentile of the
+// confidence for that classifier, as a fraction between 0 and 1.
 type ExtractedIntent struct {
-	Name       string          `json:"name"`
-	Confidence decimal.Decimal `json:"confidence"`
+	Name       string           `json:"name"`
+	Confidence decimal.Decimal  `json:"confidence"`
+	Calibrated *decimal.Decimal `json:"calibrated,omitempty"`
 }
 
 // ExtractedEntity models an entity match
@@ -76,13 +206,13 @@ type Classification struct {
 
 // ClassificationService provides NLU functionality to the engine
 type ClassificationService interface {
-	Classify(env envs.Environment, input string, logHTTP HTTPLogCallback) (*Classification, error)
+	Classify(ctx context.Context, env envs.Environment, input string, logHTTP HTTPLogCallback) (*Classification, error)
 }
 
 // TicketService provides ticketing functionality to the engine
 type TicketService interface {
 	// Open tries to open a new ticket
-	Open(env envs.Environment, contact *Contact, topic *Topic, body string, assignee *User, logHTTP HTTPLogCallback) (*Ticket, error)
+	Open(ctx context.Context, env envs.Environment, contact *Contact, topic *Topic, body string, assignee *User, logHTTP HTTPLogCallback) (*Ticket, error)
 }
 
 // AirtimeTransferStatus is a status of a airtime transfer
@@ -102,12 +232,127 @@ type AirtimeTransfer struct {
 	Currency      string
 	DesiredAmount decimal.Decimal
 	ActualAmount  decimal.Decimal
+
+	// the balances of the account by currency after the transfer, if the service reported them
+	Balances map[string]decimal.Decimal
 }
 
 // AirtimeService provides airtime functionality to the engine
 type AirtimeService interface {
 	// Transfer transfers airtime to the given URN
-	Transfer(sender urns.URN, recipient urns.URN, amounts map[string]decimal.Decimal, logHTTP HTTPLogCallback) (*AirtimeTransfer, error)
+	Transfer(ctx context.Context, sender urns.URN, recipient urns.URN, amounts map[string]decimal.Decimal, logHTTP HTTPLogCallback) (*AirtimeTransfer, error)
+
+	// Balance returns the balance available for transfers in each currency the account holds
+	Balance(ctx context.Context, logHTTP HTTPLogCallback) (map[string]decimal.Decimal, error)
+}
+
+// DataQuery is a query of an external data collection
+type DataQuery struct {
+	Filter string // filter expression interpreted by the service, e.g. category = "shoes"
+	Sort   string // field to sort by, prefixed with - for descending order
+	Limit  int
+	Offset int
+}
+
+// DataPage is a page of rows returned by a query of an external data collection
+type DataPage struct {
+	Rows  []map[string]any
+	Total int // total number of matching rows across all pages
+}
+
+// DataCollectionService provides access to external data collections
+type DataCollectionService interface {
+	// Query returns the page of rows from the named collection which match the given query
+	Query(ctx context.Context, env envs.Environment, collection string, query *DataQuery, logHTTP HTTPLogCallback) (*DataPage, error)
+}
+
+// Product is an item from a catalog which can be added to a cart
+type Product struct {
+	ID       string
+	Name     string
+	Price    decimal.Decimal
+	Currency string
+}
+
+// CommerceService provides product lookups and order placement to the engine
+type CommerceService interface {
+	// LookupProduct looks up the product with the given ID
+	LookupProduct(ctx context.Context, env envs.Environment, productID string, logHTTP HTTPLogCallback) (*Product, error)
+
+	// PlaceOrder places the given order for the contact and returns the order reference
+	PlaceOrder(ctx context.Context, env envs.Environment, contact *Contact, order *Order, logHTTP HTTPLogCallback) (string, error)
+}
+
+// ExchangeRateService provides currency exchange rates to the engine
+type ExchangeRateService interface {
+	// Rate returns how many units of the target currency one unit of the source currency is worth
+	Rate(ctx context.Context, from, to string, logHTTP HTTPLogCallback) (decimal.Decimal, error)
+}
+
+// AttachmentRejectedReason is the reason an attachment service rejected an attachment
+type AttachmentRejectedReason string
+
+// possible reasons for rejecting an attachment
+const (
+	AttachmentRejectedReasonContentType AttachmentRejectedReason = "content_type"
+	AttachmentRejectedReasonSize        AttachmentRejectedReason = "size"
+	AttachmentRejectedReasonUnreachable AttachmentRejectedReason = "unreachable"
+)
+
+// AttachmentRejectedError is returned by an attachment service when an attachment can't be sent. Size and limit are
+// in bytes and are only set for attachments rejected for being too large.
+type AttachmentRejectedError struct {
+	Reason AttachmentRejectedReason
+	Size   int
+	Limit  int
+}
+
+// NewAttachmentRejectedError creates a new attachment rejected error
+func NewAttachmentRejectedError(reason AttachmentRejectedReason, size, limit int) *AttachmentRejectedError {
+	return &AttachmentRejectedError{Reason: reason, Size: size, Limit: limit}
+}
+
+func (e *AttachmentRejectedError) Error() string {
+	switch e.Reason {
+	case AttachmentRejectedReasonSize:
+		return fmt.Sprintf("attachment size of %d bytes exceeds limit of %d bytes", e.Size, e.Limit)
+	case AttachmentRejectedReasonUnreachable:
+		return "attachment URL isn't reachable"
+	}
+	return "attachment content type isn't supported"
+}
+
+// AttachmentService checks the attachments of outgoing messages before they're sent
+type AttachmentService interface {
+	// Process validates the given attachment and returns the attachment to send in its place, which may have a
+	// rewritten URL (e.g. a signed CDN URL), or a content type hinting that the media should be transcoded. Attachments
+	// which can't be sent are rejected with an *AttachmentRejectedError.
+	Process(ctx context.Context, attachment utils.Attachment) (utils.Attachment, error)
+}
+
+// GroupMembershipService decides whether contacts belong in query based groups, and is used to re-evaluate membership
+// when a contact changes. Hosts can provide their own to use an external query engine.
+type GroupMembershipService interface {
+	// Qualifies returns whether the given contact belongs in the given query based group
+	Qualifies(env envs.Environment, group *Group, contact *Contact) (bool, error)
+}
+
+// CallRecordingService provides control over the recording of IVR calls to the engine
+type CallRecordingService interface {
+	// StartRecording starts recording the given call
+	StartRecording(ctx context.Context, call *Call) error
+
+	// StopRecording stops recording the given call and returns the URL of the recorded media
+	StopRecording(ctx context.Context, call *Call) (string, error)
+}
+
+// CallTransferService provides forwarding of IVR calls to other numbers and conferences to the engine
+type CallTransferService interface {
+	// Forward forwards the given call to the given URN, returning once the forwarded call has ended
+	Forward(ctx context.Context, call *Call, urn urns.URN) (*CallTransfer, error)
+
+	// JoinConference joins the given call to the named conference, returning once the contact has left it
+	JoinConference(ctx context.Context, call *Call, conference string) (*CallTransfer, error)
 }
 
 // HTTPLogWithoutTime is an HTTP log no time and status added - used for webhook events which already encode the time
@@ -138,14 +383,32 @@ type HTTPLog struct {
 // HTTPLogCallback is a function that handles an HTTP log
 type HTTPLogCallback func(*HTTPLog)
 
-// HTTPLogger logs HTTP logs
+// HTTPLogSink is somewhere outside of the engine that HTTP logs can be sent for storage, e.g. to keep traces of calls
+// to services which are debugging aids rather than part of session history
+type HTTPLogSink interface {
+	Store(ctx context.Context, log *HTTPLog)
+}
+
+// HTTPLogger logs HTTP logs, and also sends them to a sink if it has one
 type HTTPLogger struct {
 	Logs []*HTTPLog
+
+	ctx  context.Context
+	sink HTTPLogSink
+}
+
+// NewHTTPLogger creates a new HTTP logger which sends logs to the given sink, which may be nil
+func NewHTTPLogger(ctx context.Context, sink HTTPLogSink) *HTTPLogger {
+	return &HTTPLogger{ctx: ctx, sink: sink}
 }
 
 // Log logs the given HTTP log
 func (l *HTTPLogger) Log(h *HTTPLog) {
 	l.Logs = append(l.Logs, h)
+
+	if l.sink != nil {
+		l.sink.Store(l.ctx, h)
+	}
 }
 
 // HTTPLogStatusResolver is a function that determines the status of an HTTP log from the response
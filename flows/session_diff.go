@@ -0,0 +1,134 @@
+package flows
+
+// SessionDiff is the set of changes made to a session by a sprint, which lets callers persist only what changed
+// rather than the whole session
+type SessionDiff struct {
+	New            bool          `json:"new,omitempty"`
+	Status         SessionStatus `json:"status,omitempty"`
+	PreviousStatus SessionStatus `json:"previous_status,omitempty"`
+	Runs           []*RunDiff    `json:"runs,omitempty"`
+	Contact        *ContactDiff  `json:"contact,omitempty"`
+}
+
+// IsEmpty returns whether this diff contains no changes
+func (d *SessionDiff) IsEmpty() bool {
+	return !d.New && d.Status == "" && len(d.Runs) == 0 && d.Contact == nil
+}
+
+// RunDiff is the set of changes made to a run by a sprint. Steps are those added to the path, and also the
+// previous last step if it was exited during the sprint.
+type RunDiff struct {
+	UUID           RunUUID   `json:"uuid"`
+	New            bool      `json:"new,omitempty"`
+	Status         RunStatus `json:"status,omitempty"`
+	PreviousStatus RunStatus `json:"previous_status,omitempty"`
+	Results        Results   `json:"results,omitempty"`
+	Steps          []Step    `json:"steps,omitempty"`
+}
+
+// SessionSnapshot records the state of a session so that it can later be diffed against how the session is then
+type SessionSnapshot struct {
+	new     bool
+	status  SessionStatus
+	runs    map[RunUUID]*runSnapshot
+	contact *Contact
+}
+
+type runSnapshot struct {
+	status   RunStatus
+	results  Results
+	pathLen  int
+	lastExit ExitUUID
+}
+
+// NewSessionSnapshot takes a snapshot of the given session. New sessions are those being started by the sprint, for
+// which the status is always reported as a change.
+func NewSessionSnapshot(session Session, isNew bool) *SessionSnapshot {
+	s := &SessionSnapshot{
+		new:     isNew,
+		status:  session.Status(),
+		runs:    make(map[RunUUID]*runSnapshot, len(session.Runs())),
+		contact: session.Contact().Clone(),
+	}
+	if isNew {
+		s.status = ""
+	}
+
+	for _, run := range session.Runs() {
+		path := run.Path()
+		rs := &runSnapshot{status: run.Status(), results: run.Results().Clone(), pathLen: len(path)}
+		if len(path) > 0 {
+			rs.lastExit = path[len(path)-1].ExitUUID()
+		}
+		s.runs[run.UUID()] = rs
+	}
+
+	return s
+}
+
+// Diff returns the changes made to the given session since this snapshot was taken
+func (s *SessionSnapshot) Diff(session Session) *SessionDiff {
+	diff := &SessionDiff{New: s.new}
+
+	if session.Status() != s.status {
+		diff.Status = session.Status()
+		diff.PreviousStatus = s.status
+	}
+
+	for _, run := range session.Runs() {
+		if rd := s.diffRun(run); rd != nil {
+			diff.Runs = append(diff.Runs, rd)
+		}
+	}
+
+	if s.contact != nil && session.Contact() != nil {
+		if cd := s.contact.Diff(session.Contact()); !cd.IsEmpty() {
+			diff.Contact = cd
+		}
+	}
+
+	return diff
+}
+
+// diffs the given run against its snapshot, returning nil if it hasn't changed
+func (s *SessionSnapshot) diffRun(run Run) *RunDiff {
+	before := s.runs[run.UUID()]
+	path := run.Path()
+
+	if before == nil {
+		return &RunDiff{UUID: run.UUID(), New: true, Status: run.Status(), Results: run.Results(), Steps: path}
+	}
+
+	diff := &RunDiff{UUID: run.UUID()}
+	changed := false
+
+	if run.Status() != before.status {
+		diff.Status = run.Status()
+		diff.PreviousStatus = before.status
+		changed = true
+	}
+
+	for key, result := range run.Results() {
+		if before.results[key] != result {
+			if diff.Results == nil {
+				diff.Results = make(Results)
+			}
+			diff.Results[key] = result
+			changed = true
+		}
+	}
+
+	from := before.pathLen
+	if from > 0 && from <= len(path) && path[from-1].ExitUUID() != before.lastExit {
+		from--
+	}
+	if from < len(path) {
+		diff.Steps = path[from:]
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return diff
+}
@@ -0,0 +1,79 @@
+package flows
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/random"
+	"github.com/nyaruka/gocommon/uuids"
+
+	"github.com/shopspring/decimal"
+)
+
+// Sources are where a session gets new UUIDs, random numbers and the current time from
+type Sources interface {
+	NewUUID() uuids.UUID
+	Random() decimal.Decimal
+	Now() time.Time
+}
+
+// DefaultSources are the process wide generators of UUIDs and random numbers, and the process wide clock
+var DefaultSources Sources = defaultSources{}
+
+type defaultSources struct{}
+
+func (defaultSources) NewUUID() uuids.UUID     { return uuids.New() }
+func (defaultSources) Random() decimal.Decimal { return random.Decimal() }
+func (defaultSources) Now() time.Time          { return dates.Now() }
+
+// sources which generate UUIDs and random numbers from a seed and whose clock is fixed, so that a session using them
+// is repeatable without affecting the other sessions in the process
+type seededSources struct {
+	uuids  uuids.Generator
+	random *rand.Rand
+	now    time.Time
+	mutex  sync.Mutex
+}
+
+// NewSeededSources creates new sources seeded with the given seed and whose clock is fixed at the given time
+func NewSeededSources(seed int64, now time.Time) Sources {
+	return &seededSources{uuids: uuids.NewSeededGenerator(seed), random: random.NewSeededGenerator(seed), now: now}
+}
+
+// NewUUID returns a new UUID from the seeded generator, which can be called concurrently, e.g. by forked branches
+func (s *seededSources) NewUUID() uuids.UUID {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.uuids.Next()
+}
+
+// Random returns a new random number in [0.0-1.0) from the seeded generator
+func (s *seededSources) Random() decimal.Decimal {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return decimal.NewFromFloat(s.random.Float64())
+}
+
+// Now returns the fixed time
+func (s *seededSources) Now() time.Time { return s.now }
+
+type sourcesKey struct{}
+
+// ContextWithSources returns a copy of the given context which carries the given sources, so that services called
+// with it can use the sources of the session calling them
+func ContextWithSources(ctx context.Context, sources Sources) context.Context {
+	return context.WithValue(ctx, sourcesKey{}, sources)
+}
+
+// SourcesFromContext returns the sources carried by the given context, or the default sources if it doesn't carry any
+func SourcesFromContext(ctx context.Context) Sources {
+	if sources, ok := ctx.Value(sourcesKey{}).(Sources); ok {
+		return sources
+	}
+	return DefaultSources
+}
@@ -78,12 +78,36 @@ func NewTemplateTranslation(t assets.TemplateTranslation) *TemplateTranslation {
 // Asset returns the underlying asset
 func (t *TemplateTranslation) Asset() assets.TemplateTranslation { return t.TemplateTranslation }
 
-var templateRegex = regexp.MustCompile(`({{\d+}})`)
+// Components returns the components of this translation. Translations without components are treated as having just a
+// body component whose content and params are those of the translation.
+func (t *TemplateTranslation) Components() []assets.TemplateComponent {
+	if cs := t.TemplateTranslation.Components(); len(cs) > 0 {
+		return cs
+	}
+	return []assets.TemplateComponent{&bodyComponent{content: t.Content(), paramCount: t.VariableCount()}}
+}
+
+// Component returns the component with the given name or nil if there isn't one
+func (t *TemplateTranslation) Component(name string) assets.TemplateComponent {
+	for _, c := range t.Components() {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
 
 // Substitute substitutes the passed in variables in our template
 func (t *TemplateTranslation) Substitute(vars []string) string {
-	s := string(t.Content())
-	for i, v := range vars {
+	return SubstituteTemplateParams(t.Content(), vars)
+}
+
+var templateRegex = regexp.MustCompile(`({{\d+}})`)
+
+// SubstituteTemplateParams substitutes the given params into template content like "Hi {{1}}"
+func SubstituteTemplateParams(content string, params []string) string {
+	s := content
+	for i, v := range params {
 		s = strings.ReplaceAll(s, fmt.Sprintf("{{%d}}", i+1), v)
 	}
 
@@ -93,6 +117,17 @@ func (t *TemplateTranslation) Substitute(vars []string) string {
 	return s
 }
 
+// the implicit body component of a translation without components
+type bodyComponent struct {
+	content    string
+	paramCount int
+}
+
+func (c *bodyComponent) Type() string    { return "body" }
+func (c *bodyComponent) Name() string    { return "body" }
+func (c *bodyComponent) Content() string { return c.content }
+func (c *bodyComponent) ParamCount() int { return c.paramCount }
+
 // TemplateAssets is our type for all the templates in an environment
 type TemplateAssets struct {
 	templates []*Template
@@ -0,0 +1,142 @@
+package flows
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/nyaruka/goflow/excellent"
+	"github.com/nyaruka/goflow/excellent/types"
+)
+
+// parts of the run context which change from step to step without anything being logged, so templates which use them
+// are never cached
+var uncacheableTopLevels = map[string]bool{"run": true, "child": true, "parent": true, "node": true, "item": true, "index": true}
+
+// functions whose values change between calls
+var uncacheableFunctions = map[string]bool{"now": true, "today": true, "rand": true, "rand_between": true}
+
+// TemplateCache remembers the results of evaluating templates against the contexts of the runs of a sprint, so that
+// templates which are used many times, e.g. greetings, are only parsed and evaluated once. Only text and primitive
+// values are cached because objects and arrays are lazy and shared with whoever evaluated them. Anything which might
+// change a context must invalidate the cache. A nil cache is valid and caches nothing.
+type TemplateCache struct {
+	mutex     sync.Mutex
+	size      int
+	version   int // incremented each time the cache is invalidated
+	entries   map[templateCacheKey]*templateCacheEntry
+	cacheable map[string]bool
+}
+
+type templateCacheKey struct {
+	run      RunUUID
+	template string
+	asValue  bool
+}
+
+type templateCacheEntry struct {
+	value types.XValue
+	text  string
+	err   error
+}
+
+// whether this entry can be shared, i.e. it doesn't hold a value which could be changed or resolved differently later
+func (e *templateCacheEntry) shareable() bool {
+	switch e.value.(type) {
+	case nil, types.XText, types.XNumber, types.XBoolean, types.XDate, types.XDateTime, types.XTime, types.XDuration, types.XMoney, types.XError:
+		return true
+	}
+	return false
+}
+
+// NewTemplateCache creates a new template cache which holds up to size evaluations
+func NewTemplateCache(size int) *TemplateCache {
+	return &TemplateCache{
+		size:      size,
+		entries:   make(map[templateCacheKey]*templateCacheEntry),
+		cacheable: make(map[string]bool),
+	}
+}
+
+// Invalidate discards all cached evaluations, e.g. because a result has been saved
+func (c *TemplateCache) Invalidate() {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.version++
+	for k := range c.entries {
+		delete(c.entries, k)
+	}
+}
+
+// EvaluateText evaluates the given template as text using the given function, unless it's already been evaluated
+// for the given run
+func (c *TemplateCache) EvaluateText(run RunUUID, template string, evaluate func() (string, error)) (string, error) {
+	entry := c.evaluate(templateCacheKey{run: run, template: template}, func() *templateCacheEntry {
+		text, err := evaluate()
+		return &templateCacheEntry{text: text, err: err}
+	})
+	return entry.text, entry.err
+}
+
+// EvaluateValue evaluates the given template as a value using the given function, unless it's already been evaluated
+// for the given run
+func (c *TemplateCache) EvaluateValue(run RunUUID, template string, evaluate func() (types.XValue, error)) (types.XValue, error) {
+	entry := c.evaluate(templateCacheKey{run: run, template: template, asValue: true}, func() *templateCacheEntry {
+		value, err := evaluate()
+		return &templateCacheEntry{value: value, err: err}
+	})
+	return entry.value, entry.err
+}
+
+func (c *TemplateCache) evaluate(key templateCacheKey, evaluate func() *templateCacheEntry) *templateCacheEntry {
+	if c == nil {
+		return evaluate()
+	}
+
+	c.mutex.Lock()
+	entry, version := c.entries[key], c.version
+	c.mutex.Unlock()
+
+	if entry != nil {
+		return entry
+	}
+
+	entry = evaluate()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// don't cache the evaluation if the context might have changed while we were evaluating
+	if c.version == version && len(c.entries) < c.size && entry.shareable() && c.isCacheable(key.template) {
+		c.entries[key] = entry
+	}
+	return entry
+}
+
+// checks whether the given template only uses parts of the context which are invalidated by changes
+func (c *TemplateCache) isCacheable(template string) bool {
+	cacheable, checked := c.cacheable[template]
+	if !checked {
+		cacheable = true
+
+		excellent.VisitTemplate(template, RunContextTopLevels, func(tokenType excellent.XTokenType, token string) error {
+			switch tokenType {
+			case excellent.IDENTIFIER, excellent.EXPRESSION:
+				excellent.Parse(token, func(path []string) {
+					name := strings.ToLower(path[0])
+					if uncacheableTopLevels[name] || uncacheableFunctions[name] {
+						cacheable = false
+					}
+				})
+			}
+			return nil
+		})
+
+		c.cacheable[template] = cacheable
+	}
+	return cacheable
+}
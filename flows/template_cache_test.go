@@ -0,0 +1,105 @@
+package flows_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateCache(t *testing.T) {
+	evaluations := 0
+	evaluateText := func(text string) func() (string, error) {
+		return func() (string, error) {
+			evaluations++
+			return text, nil
+		}
+	}
+	evaluateValue := func(value types.XValue) func() (types.XValue, error) {
+		return func() (types.XValue, error) {
+			evaluations++
+			return value, nil
+		}
+	}
+
+	// a nil cache evaluates every time
+	var cache *flows.TemplateCache
+	cache.Invalidate()
+
+	text, err := cache.EvaluateText("run1", "Hi @contact.name", evaluateText("Hi Bob"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi Bob", text)
+	cache.EvaluateText("run1", "Hi @contact.name", evaluateText("Hi Bob"))
+	assert.Equal(t, 2, evaluations)
+
+	cache = flows.NewTemplateCache(3)
+	evaluations = 0
+
+	// evaluations are cached by run and template
+	text, _ = cache.EvaluateText("run1", "Hi @contact.name", evaluateText("Hi Bob"))
+	assert.Equal(t, "Hi Bob", text)
+	text, _ = cache.EvaluateText("run1", "Hi @contact.name", evaluateText("Hi Jim"))
+	assert.Equal(t, "Hi Bob", text)
+	text, _ = cache.EvaluateText("run2", "Hi @contact.name", evaluateText("Hi Jim"))
+	assert.Equal(t, "Hi Jim", text)
+	assert.Equal(t, 2, evaluations)
+
+	// and separately for values and text
+	value, _ := cache.EvaluateValue("run1", "Hi @contact.name", evaluateValue(types.NewXText("Hi Bob")))
+	assert.Equal(t, types.NewXText("Hi Bob"), value)
+	value, _ = cache.EvaluateValue("run1", "Hi @contact.name", evaluateValue(types.NewXText("Hi Jim")))
+	assert.Equal(t, types.NewXText("Hi Bob"), value)
+	assert.Equal(t, 3, evaluations)
+
+	// but values which aren't primitives aren't cached because they're shared with the caller
+	obj := types.NewXObject(map[string]types.XValue{"name": types.NewXText("Bob")})
+	value, _ = cache.EvaluateValue("run1", "@contact", evaluateValue(obj))
+	assert.Same(t, obj, value)
+	value, _ = cache.EvaluateValue("run1", "@contact", evaluateValue(types.NewXArray()))
+	assert.Equal(t, types.NewXArray(), value)
+	assert.Equal(t, 5, evaluations)
+
+	// errors are cached too
+	cache.Invalidate()
+	_, err = cache.EvaluateText("run1", "@(1 / 0)", func() (string, error) { evaluations++; return "", errors.New("division by zero") })
+	assert.EqualError(t, err, "division by zero")
+	_, err = cache.EvaluateText("run1", "@(1 / 0)", evaluateText(""))
+	assert.EqualError(t, err, "division by zero")
+	assert.Equal(t, 6, evaluations)
+
+	// invalidating discards all evaluations
+	cache.Invalidate()
+	text, _ = cache.EvaluateText("run1", "Hi @contact.name", evaluateText("Hi Jim"))
+	assert.Equal(t, "Hi Jim", text)
+	assert.Equal(t, 7, evaluations)
+
+	// evaluations aren't cached if the cache is invalidated during them
+	cache.Invalidate()
+	cache.EvaluateText("run1", "@results.color", func() (string, error) { cache.Invalidate(); evaluations++; return "red", nil })
+	text, _ = cache.EvaluateText("run1", "@results.color", evaluateText("blue"))
+	assert.Equal(t, "blue", text)
+	assert.Equal(t, 9, evaluations)
+
+	// templates which use parts of the context which can change without invalidation aren't cached
+	for _, template := range []string{"@(now())", "@(RAND())", "@(format_date(today()))", "@run.path", "@node.visit_count", "@child.results", "@parent.uuid", "@item", "@(index + 1)"} {
+		cache.Invalidate()
+		evaluations = 0
+
+		cache.EvaluateText("run1", template, evaluateText("1"))
+		text, _ = cache.EvaluateText("run1", template, evaluateText("2"))
+		assert.Equal(t, "2", text, "expected template %s to not be cached", template)
+		assert.Equal(t, 2, evaluations)
+	}
+
+	// once the cache is full, new evaluations aren't cached
+	cache.Invalidate()
+	evaluations = 0
+
+	for _, template := range []string{"@fields.a", "@fields.b", "@fields.c", "@fields.d", "@fields.a", "@fields.b", "@fields.c", "@fields.d"} {
+		cache.EvaluateText("run1", template, evaluateText("x"))
+	}
+	assert.Equal(t, 5, evaluations)
+}
@@ -23,17 +23,42 @@ func TestTemplateTranslation(t *testing.T) {
 	channel := assets.NewChannelReference("0bce5fd3-c215-45a0-bcb8-2386eb194175", "Test Channel")
 
 	for i, tc := range tcs {
-		tt := NewTemplateTranslation(static.NewTemplateTranslation(*channel, envs.Locale("eng-US"), tc.Content, len(tc.Variables), "a6a8863e_7879_4487_ad24_5e2ea429027c"))
+		tt := NewTemplateTranslation(static.NewTemplateTranslation(*channel, envs.Locale("eng-US"), tc.Content, len(tc.Variables), "a6a8863e_7879_4487_ad24_5e2ea429027c", nil))
 		result := tt.Substitute(tc.Variables)
 		assert.Equal(t, tc.Expected, result, "%d: unexpected template substitution", i)
 	}
 }
 
+func TestTemplateTranslationComponents(t *testing.T) {
+	channel := assets.NewChannelReference("0bce5fd3-c215-45a0-bcb8-2386eb194175", "Test Channel")
+
+	// a translation without components has an implicit body component
+	tt := NewTemplateTranslation(static.NewTemplateTranslation(*channel, envs.Locale("eng"), "Hi {{1}}", 1, "", nil))
+	assert.Equal(t, 1, len(tt.Components()))
+	assert.Equal(t, "body", tt.Components()[0].Type())
+	assert.Equal(t, "body", tt.Components()[0].Name())
+	assert.Equal(t, "Hi {{1}}", tt.Components()[0].Content())
+	assert.Equal(t, 1, tt.Components()[0].ParamCount())
+	assert.Equal(t, "body", tt.Component("body").Name())
+	assert.Nil(t, tt.Component("header"))
+
+	tt = NewTemplateTranslation(static.NewTemplateTranslation(*channel, envs.Locale("eng"), "Hi {{1}}", 1, "", []*static.TemplateComponent{
+		static.NewTemplateComponent("header", "header", "Order {{1}}", 1),
+		static.NewTemplateComponent("body", "body", "Hi {{1}}", 1),
+	}))
+	assert.Equal(t, 2, len(tt.Components()))
+	assert.Equal(t, "header", tt.Components()[0].Name())
+	assert.Equal(t, "Order {{1}}", tt.Component("header").Content())
+
+	assert.Equal(t, "Order 123", SubstituteTemplateParams("Order {{1}}", []string{"123"}))
+	assert.Equal(t, "Order ", SubstituteTemplateParams("Order {{1}}", nil))
+}
+
 func TestTemplates(t *testing.T) {
 	channel1 := assets.NewChannelReference("0bce5fd3-c215-45a0-bcb8-2386eb194175", "Test Channel")
-	tt1 := static.NewTemplateTranslation(*channel1, envs.Locale("eng"), "Hello {{1}}", 1, "")
-	tt2 := static.NewTemplateTranslation(*channel1, envs.Locale("spa-EC"), "Que tal {{1}}", 1, "")
-	tt3 := static.NewTemplateTranslation(*channel1, envs.Locale("spa-ES"), "Hola {{1}}", 1, "")
+	tt1 := static.NewTemplateTranslation(*channel1, envs.Locale("eng"), "Hello {{1}}", 1, "", nil)
+	tt2 := static.NewTemplateTranslation(*channel1, envs.Locale("spa-EC"), "Que tal {{1}}", 1, "", nil)
+	tt3 := static.NewTemplateTranslation(*channel1, envs.Locale("spa-ES"), "Hola {{1}}", 1, "", nil)
 	template := NewTemplate(static.NewTemplate("c520cbda-e118-440f-aaf6-c0485088384f", "greeting", []*static.TemplateTranslation{tt1, tt2, tt3}))
 
 	tas := NewTemplateAssets([]assets.Template{template})
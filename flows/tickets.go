@@ -1,6 +1,8 @@
 package flows
 
 import (
+	"sort"
+
 	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/gocommon/uuids"
 	"github.com/nyaruka/goflow/assets"
@@ -9,6 +11,15 @@ import (
 	"github.com/nyaruka/goflow/utils"
 )
 
+func init() {
+	RegisterContextType("ticket",
+		NewContextProperty("uuid", "text", "the UUID of the ticket"),
+		NewContextProperty("topic", "topic", "the topic of the ticket"),
+		NewContextProperty("body", "text", "the body of the ticket"),
+		NewContextProperty("assignee", "user", "the user assigned to the ticket"),
+	)
+}
+
 // TicketUUID is the UUID of a ticket
 type TicketUUID uuids.UUID
 
@@ -48,12 +59,6 @@ func (t *Ticket) SetExternalID(id string) { t.externalID = id }
 func (t *Ticket) Assignee() *User         { return t.assignee }
 
 // Context returns the properties available in expressions
-//
-//	uuid:text -> the UUID of the ticket
-//	subject:text -> the subject of the ticket
-//	body:text -> the body of the ticket
-//
-// @context ticket
 func (t *Ticket) Context(env envs.Environment) map[string]types.XValue {
 	return map[string]types.XValue{
 		"uuid":     types.NewXText(string(t.uuid)),
@@ -225,3 +230,18 @@ func NewTicketerAssets(ticketers []assets.Ticketer) *TicketerAssets {
 func (s *TicketerAssets) Get(uuid assets.TicketerUUID) *Ticketer {
 	return s.byUUID[uuid]
 }
+
+// All returns all the ticketers sorted by name
+func (s *TicketerAssets) All() []*Ticketer {
+	all := make([]*Ticketer, 0, len(s.byUUID))
+	for _, x := range s.byUUID {
+		all = append(all, x)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Name() != all[j].Name() {
+			return all[i].Name() < all[j].Name()
+		}
+		return all[i].UUID() < all[j].UUID()
+	})
+	return all
+}
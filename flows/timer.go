@@ -0,0 +1,56 @@
+package flows
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Timer is a named timer set by a run. When it fires, the caller resumes the session with a timer_fired resume and the
+// run continues at the timer's node, wherever it was waiting.
+type Timer struct {
+	Name     string    `json:"name" validate:"required,max=64"`
+	NodeUUID NodeUUID  `json:"node_uuid" validate:"required,uuid4"`
+	FiresOn  time.Time `json:"fires_on" validate:"required"`
+}
+
+// NewTimer creates a new timer
+func NewTimer(name string, nodeUUID NodeUUID, firesOn time.Time) *Timer {
+	return &Timer{Name: name, NodeUUID: nodeUUID, FiresOn: firesOn}
+}
+
+// MaxTimerDelay is the longest a timer can be set for
+const MaxTimerDelay = 365 * 24 * time.Hour
+
+var timerDelayRegex = regexp.MustCompile(`^(\d+)\s*([smhdw])$`)
+
+var timerDelayUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseTimerDelay parses a timer delay like 30m, 2d or 1w into a duration
+func ParseTimerDelay(s string) (time.Duration, error) {
+	match := timerDelayRegex.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if match == nil {
+		return 0, errors.Errorf("'%s' is not a valid timer delay", s)
+	}
+
+	num, err := strconv.Atoi(match[1])
+	if err != nil || num <= 0 {
+		return 0, errors.Errorf("'%s' is not a valid timer delay", s)
+	}
+
+	unit := timerDelayUnits[match[2]]
+	if num > int(MaxTimerDelay/unit) {
+		return 0, errors.Errorf("'%s' is longer than the maximum timer delay of 365 days", s)
+	}
+
+	return time.Duration(num) * unit, nil
+}
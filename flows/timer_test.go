@@ -0,0 +1,42 @@
+package flows_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimerDelay(t *testing.T) {
+	tcs := []struct {
+		input string
+		delay time.Duration
+		err   string
+	}{
+		{"45s", 45 * time.Second, ""},
+		{"30m", 30 * time.Minute, ""},
+		{" 6 H ", 6 * time.Hour, ""},
+		{"2d", 48 * time.Hour, ""},
+		{"1w", 7 * 24 * time.Hour, ""},
+		{"", 0, "'' is not a valid timer delay"},
+		{"2", 0, "'2' is not a valid timer delay"},
+		{"0d", 0, "'0d' is not a valid timer delay"},
+		{"-1d", 0, "'-1d' is not a valid timer delay"},
+		{"1.5h", 0, "'1.5h' is not a valid timer delay"},
+		{"3y", 0, "'3y' is not a valid timer delay"},
+		{"365d", 365 * 24 * time.Hour, ""},
+		{"366d", 0, "'366d' is longer than the maximum timer delay of 365 days"},
+		{"99999999999999999999d", 0, "'99999999999999999999d' is not a valid timer delay"},
+	}
+
+	for _, tc := range tcs {
+		delay, err := flows.ParseTimerDelay(tc.input)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, "error mismatch for input %q", tc.input)
+		} else {
+			assert.NoError(t, err, "unexpected error for input %q", tc.input)
+			assert.Equal(t, tc.delay, delay, "delay mismatch for input %q", tc.input)
+		}
+	}
+}
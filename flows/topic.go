@@ -8,6 +8,15 @@ import (
 	"github.com/nyaruka/goflow/excellent/types"
 )
 
+func init() {
+	RegisterContextType("topic",
+		NewContextProperty("__default__", "text", "the name"),
+		NewContextProperty("uuid", "text", "the UUID of the topic"),
+		NewContextProperty("name", "text", "the name of the topic"),
+		NewContextProperty("queue", "text", "the queue of the topic"),
+	)
+}
+
 // Topic represents a ticket topic
 type Topic struct {
 	assets.Topic
@@ -30,18 +39,13 @@ func (t *Topic) Reference() *assets.TopicReference {
 }
 
 // Context returns the properties available in expressions
-//
-//	__default__:text -> the name
-//	uuid:text -> the UUID of the topic
-//	name:text -> the name of the topic
-//
-// @context topic
 func (t *Topic) Context(env envs.Environment) map[string]types.XValue {
 
 	return map[string]types.XValue{
 		"__default__": types.NewXText(t.Name()),
 		"uuid":        types.NewXText(string(t.UUID())),
 		"name":        types.NewXText(t.Name()),
+		"queue":       types.NewXText(t.Queue()),
 	}
 }
 
@@ -6,15 +6,31 @@ import (
 
 	"github.com/nyaruka/gocommon/dates"
 	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/urns"
 	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
 	"github.com/nyaruka/goflow/utils"
 
 	"github.com/pkg/errors"
 )
 
+func init() {
+	flows.RegisterContextType("trigger",
+		flows.NewContextProperty("type", "text", "the type of trigger that started this session"),
+		flows.NewContextProperty("params", "any", "the parameters passed to the trigger"),
+		flows.NewContextProperty("keyword", "text", "the keyword match if this is a keyword trigger"),
+		flows.NewContextProperty("user", "user", "the user who started this session if this is a manual trigger"),
+		flows.NewContextProperty("origin", "text", "the origin of this session if this is a manual trigger"),
+		flows.NewContextProperty("campaign", "any", "the UUID and name of the campaign if this is a campaign trigger"),
+		flows.NewContextProperty("ticket", "ticket", "the ticket if this is a ticket trigger"),
+		flows.NewContextProperty("batch", "any", "the UUID, position and total of the batch if this is a batch trigger"),
+		flows.NewContextProperty("thread", "text", "the key of the conversation thread this session belongs to"),
+	)
+}
+
 // ReadFunc is a function that can read a trigger from JSON
 type ReadFunc func(flows.SessionAssets, json.RawMessage, assets.MissingCallback) (flows.Trigger, error)
 
@@ -40,11 +56,13 @@ type baseTrigger struct {
 	batch       bool
 	params      *types.XObject
 	history     *flows.SessionHistory
+	profile     string
+	thread      string
 	triggeredOn time.Time
 }
 
 // create a new base trigger
-func newBaseTrigger(typeName string, env envs.Environment, flow *assets.FlowReference, contact *flows.Contact, call *flows.Call, batch bool, history *flows.SessionHistory) baseTrigger {
+func newBaseTrigger(typeName string, env envs.Environment, flow *assets.FlowReference, contact *flows.Contact, call *flows.Call, batch bool, history *flows.SessionHistory, profile, thread string) baseTrigger {
 	return baseTrigger{
 		type_:       typeName,
 		environment: env,
@@ -53,6 +71,8 @@ func newBaseTrigger(typeName string, env envs.Environment, flow *assets.FlowRefe
 		call:        call,
 		batch:       batch,
 		history:     history,
+		profile:     profile,
+		thread:      thread,
 		triggeredOn: dates.Now(),
 	}
 }
@@ -67,6 +87,8 @@ func (t *baseTrigger) Call() *flows.Call              { return t.call }
 func (t *baseTrigger) Batch() bool                    { return t.batch }
 func (t *baseTrigger) Params() *types.XObject         { return t.params }
 func (t *baseTrigger) History() *flows.SessionHistory { return t.history }
+func (t *baseTrigger) Profile() string                { return t.profile }
+func (t *baseTrigger) Thread() string                 { return t.thread }
 func (t *baseTrigger) TriggeredOn() time.Time         { return t.triggeredOn }
 
 // Initialize initializes the session
@@ -91,7 +113,12 @@ func (t *baseTrigger) Initialize(session flows.Session, logEvent flows.EventCall
 	}
 
 	if t.contact != nil {
-		session.SetContact(t.contact.Clone())
+		contact := t.contact.Clone()
+		contact.NormalizeURNs(session.Environment(), func(original, normalized urns.URN) {
+			logEvent(events.NewContactURNNormalized(original, normalized))
+		})
+
+		session.SetContact(contact)
 	}
 	return nil
 }
@@ -114,6 +141,8 @@ type Context struct {
 	origin   string
 	campaign types.XValue
 	ticket   types.XValue
+	batch    types.XValue
+	thread   string
 }
 
 func (c *Context) asMap() map[string]types.XValue {
@@ -125,6 +154,8 @@ func (c *Context) asMap() map[string]types.XValue {
 		"origin":   types.NewXText(c.origin),
 		"campaign": c.campaign,
 		"ticket":   c.ticket,
+		"batch":    c.batch,
+		"thread":   types.NewXText(c.thread),
 	}
 }
 
@@ -134,19 +165,10 @@ func (t *baseTrigger) context() *Context {
 		params = types.XObjectEmpty
 	}
 
-	return &Context{type_: t.type_, params: params}
+	return &Context{type_: t.type_, params: params, thread: t.thread}
 }
 
 // Context returns the properties available in expressions
-//
-//	type:text -> the type of trigger that started this session
-//	params:any -> the parameters passed to the trigger
-//	keyword:text -> the keyword match if this is a keyword trigger
-//	user:user -> the user who started this session if this is a manual trigger
-//	origin:text -> the origin of this session if this is a manual trigger
-//	ticket:ticket -> the ticket if this is a ticket trigger
-//
-// @context trigger
 func (t *baseTrigger) Context(env envs.Environment) map[string]types.XValue {
 	return t.context().asMap()
 }
@@ -160,6 +182,8 @@ type Builder struct {
 	environment envs.Environment
 	flow        *assets.FlowReference
 	contact     *flows.Contact
+	profile     string
+	thread      string
 }
 
 // NewBuilder creates a new trigger builder
@@ -171,6 +195,19 @@ func NewBuilder(env envs.Environment, flow *assets.FlowReference, contact *flows
 	}
 }
 
+// WithProfile sets the name of the engine profile which the session should be run with
+func (b *Builder) WithProfile(profile string) *Builder {
+	b.profile = profile
+	return b
+}
+
+// WithThread sets the key of the conversation thread, e.g. a ticket or order ID, which the session belongs to. Resumes
+// of the session must then carry the same key, which allows the contact to have several concurrent conversations.
+func (b *Builder) WithThread(thread string) *Builder {
+	b.thread = thread
+	return b
+}
+
 //------------------------------------------------------------------------------------------
 // JSON Encoding / Decoding
 //------------------------------------------------------------------------------------------
@@ -185,6 +222,8 @@ type baseTriggerEnvelope struct {
 	Batch       bool                  `json:"batch,omitempty"`
 	Params      json.RawMessage       `json:"params,omitempty"`
 	History     *flows.SessionHistory `json:"history,omitempty"`
+	Profile     string                `json:"profile,omitempty"`
+	Thread      string                `json:"thread,omitempty"`
 	TriggeredOn time.Time             `json:"triggered_on" validate:"required"`
 }
 
@@ -213,6 +252,8 @@ func (t *baseTrigger) unmarshal(sessionAssets flows.SessionAssets, e *baseTrigge
 	}
 	t.batch = e.Batch
 	t.history = e.History
+	t.profile = e.Profile
+	t.thread = e.Thread
 	t.triggeredOn = e.TriggeredOn
 
 	if e.Environment != nil {
@@ -242,6 +283,8 @@ func (t *baseTrigger) marshal(e *baseTriggerEnvelope) error {
 	e.Connection = t.call
 	e.Batch = t.batch
 	e.History = t.history
+	e.Profile = t.profile
+	e.Thread = t.thread
 	e.TriggeredOn = t.triggeredOn
 
 	if t.environment != nil {
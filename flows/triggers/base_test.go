@@ -1,6 +1,7 @@
 package triggers_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -18,8 +19,10 @@ import (
 	"github.com/nyaruka/goflow/excellent/types"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/events"
 	"github.com/nyaruka/goflow/flows/triggers"
 	"github.com/nyaruka/goflow/test"
+	"github.com/nyaruka/goflow/utils"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -83,7 +86,7 @@ func testTriggerType(t *testing.T, assetsJSON json.RawMessage, typeName string)
 
 		// start a session with this trigger
 		eng := engine.NewBuilder().Build()
-		session, sprint, err := eng.NewSession(sa, trigger)
+		session, sprint, err := eng.NewSession(context.Background(), sa, trigger)
 		assert.NoError(t, err)
 
 		assert.Equal(t, flows.FlowTypeMessaging, session.Type())
@@ -192,7 +195,7 @@ func TestTriggerMarshaling(t *testing.T) {
 	contact.AddURN(urns.URN("tel:+12065551212"), nil)
 
 	eng := engine.NewBuilder().Build()
-	session, _, err := eng.NewSession(sa, triggers.NewBuilder(env, flow, contact).Manual().Build())
+	session, _, err := eng.NewSession(context.Background(), sa, triggers.NewBuilder(env, flow, contact).Manual().Build())
 	require.NoError(t, err)
 
 	history := flows.NewChildHistory(session)
@@ -209,6 +212,13 @@ func TestTriggerMarshaling(t *testing.T) {
 		trigger  flows.Trigger
 		snapshot string
 	}{
+		{
+			triggers.NewBuilder(env, flow, contact).
+				Batch(triggers.NewBatch("b5e9a3a4-6ea5-4a0c-a1d6-2f4cd2f6bd1f", 3, 250)).
+				WithParams(types.NewXObject(map[string]types.XValue{"send_after": types.NewXText("2018-01-01T14:00:00Z")})).
+				Build(),
+			"batch",
+		},
 		{
 			triggers.NewBuilder(env, flow, contact).
 				Campaign(triggers.NewCampaignReference("8cd472c4-bb85-459a-8c9a-c04708af799e", "Reminders"), "8d339613-f0be-48b7-92ee-155f4c7576f8").
@@ -229,6 +239,19 @@ func TestTriggerMarshaling(t *testing.T) {
 				Build(),
 			"channel_new_conversation",
 		},
+		{
+			triggers.NewBuilder(env, flow, contact).
+				Email(&flows.EmailIn{
+					UUID:        "c8005ee3-4628-4d76-be66-906352cb1935",
+					From:        "bob@nyaruka.com",
+					Subject:     "Order #1234",
+					Body:        "Where is my order?",
+					HTMLBody:    "<p>Where is my order?</p>",
+					Attachments: []utils.Attachment{"image/jpeg:https://example.com/receipt.jpg"},
+				}).
+				Build(),
+			"email",
+		},
 		{
 			triggers.NewBuilder(env, flow, contact).
 				FlowAction(history, json.RawMessage(`{"uuid": "084e4bed-667c-425e-82f7-bdb625e6ec9e"}`)).
@@ -245,6 +268,8 @@ func TestTriggerMarshaling(t *testing.T) {
 		},
 		{
 			triggers.NewBuilder(env, flow, contact).
+				WithProfile("acme").
+				WithThread("ticket-123").
 				Manual().
 				WithParams(types.NewXObject(map[string]types.XValue{"foo": types.NewXText("bar")})).
 				WithUser(user).
@@ -276,6 +301,14 @@ func TestTriggerMarshaling(t *testing.T) {
 				Build(),
 			"msg",
 		},
+		{
+			triggers.NewBuilder(env, flow, contact).
+				Scheduled(time.Date(2018, 10, 20, 9, 45, 0, 0, time.UTC)).
+				WithParams(types.NewXObject(map[string]types.XValue{"foo": types.NewXText("bar")})).
+				AsBatch().
+				Build(),
+			"scheduled",
+		},
 		{
 			triggers.NewBuilder(env, flow, contact).
 				Ticket(ticket, triggers.TicketEventTypeClosed).
@@ -389,7 +422,7 @@ func TestTriggerSessionInitialization(t *testing.T) {
 	assert.Equal(t, params, trigger.Params())
 
 	eng := engine.NewBuilder().Build()
-	session, _, err := eng.NewSession(sa, trigger)
+	session, _, err := eng.NewSession(context.Background(), sa, trigger)
 	require.NoError(t, err)
 
 	assert.Equal(t, flows.FlowTypeMessaging, session.Type())
@@ -397,6 +430,25 @@ func TestTriggerSessionInitialization(t *testing.T) {
 	assert.Equal(t, env, session.Environment())
 	assert.Equal(t, flow, session.Runs()[0].FlowReference())
 
+	// contact URNs are normalized using the environment's default country
+	rwEnv := envs.NewBuilder().WithDefaultCountry("RW").Build()
+	contact = flows.NewEmptyContact(sa, "Bob", envs.Language("eng"), nil)
+	contact.AddURN(urns.URN("tel:0788 123 123"), nil)
+	contact.AddURN(urns.URN("tel:+250788123123"), nil)
+	contact.AddURN(urns.URN("mailto:Bob@Nyaruka.com"), nil)
+
+	trigger = triggers.NewBuilder(rwEnv, flow, contact).Manual().Build()
+
+	session, sprint, err := eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	assert.Equal(t, []urns.URN{"tel:+250788123123", "mailto:bob@nyaruka.com"}, session.Contact().URNs().RawURNs())
+	assert.Equal(t, []urns.URN{"tel:0788 123 123", "tel:+250788123123", "mailto:Bob@Nyaruka.com"}, trigger.Contact().URNs().RawURNs())
+	assert.Equal(t, events.TypeContactURNNormalized, sprint.Events()[0].Type())
+	assert.Equal(t, urns.URN("tel:0788 123 123"), sprint.Events()[0].(*events.ContactURNNormalizedEvent).Original)
+	assert.Equal(t, urns.URN("tel:+250788123123"), sprint.Events()[0].(*events.ContactURNNormalizedEvent).URN)
+	assert.Equal(t, urns.URN("mailto:bob@nyaruka.com"), sprint.Events()[1].(*events.ContactURNNormalizedEvent).URN)
+
 	// contact, environment and params are optional
 	trigger = triggers.NewBuilder(nil, flow, nil).Manual().Build()
 
@@ -405,7 +457,7 @@ func TestTriggerSessionInitialization(t *testing.T) {
 	assert.Nil(t, trigger.Contact())
 	assert.Nil(t, trigger.Params())
 
-	session, _, err = eng.NewSession(sa, trigger)
+	session, _, err = eng.NewSession(context.Background(), sa, trigger)
 	require.NoError(t, err)
 
 	assert.Equal(t, flows.FlowTypeMessaging, session.Type())
@@ -430,6 +482,7 @@ func TestTriggerContext(t *testing.T) {
 
 	params := types.NewXObject(map[string]types.XValue{"foo": types.NewXText("bar")})
 	trigger := triggers.NewBuilder(env, flow, contact).
+		WithThread("ticket-123").
 		Manual().
 		WithParams(params).
 		WithUser(user).
@@ -450,5 +503,29 @@ func TestTriggerContext(t *testing.T) {
 		"origin":   types.NewXText("api"),
 		"campaign": nil,
 		"ticket":   nil,
+		"batch":    nil,
+		"thread":   types.NewXText("ticket-123"),
 	}), flows.Context(env, trigger))
+
+	batchTrigger := triggers.NewBuilder(env, flow, contact).
+		Batch(triggers.NewBatch("b5e9a3a4-6ea5-4a0c-a1d6-2f4cd2f6bd1f", 3, 250)).
+		WithParams(params).
+		Build()
+
+	assert.True(t, batchTrigger.Batch())
+	test.AssertXEqual(t, types.NewXObject(map[string]types.XValue{
+		"type":     types.NewXText("batch"),
+		"params":   params,
+		"keyword":  types.XTextEmpty,
+		"user":     nil,
+		"origin":   types.XTextEmpty,
+		"campaign": nil,
+		"ticket":   nil,
+		"batch": types.NewXObject(map[string]types.XValue{
+			"uuid":     types.NewXText("b5e9a3a4-6ea5-4a0c-a1d6-2f4cd2f6bd1f"),
+			"position": types.NewXNumberFromInt(3),
+			"total":    types.NewXNumberFromInt(250),
+		}),
+		"thread": types.XTextEmpty,
+	}), flows.Context(env, batchTrigger))
 }
@@ -0,0 +1,154 @@
+package triggers
+
+import (
+	"encoding/json"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerType(TypeBatch, readBatchTrigger)
+}
+
+// TypeBatch is the type for sessions triggered as part of a batch of contacts, e.g. a broadcast
+const TypeBatch string = "batch"
+
+// BatchUUID is the type for batch UUIDs
+type BatchUUID uuids.UUID
+
+// Batch describes the batch that the contact was started as part of, and their position in it
+type Batch struct {
+	UUID     BatchUUID `json:"uuid" validate:"required,uuid4"`
+	Position int       `json:"position" validate:"required,min=1"`
+	Total    int       `json:"total" validate:"required,min=1"`
+}
+
+// NewBatch creates a new batch
+func NewBatch(uuid BatchUUID, position, total int) *Batch {
+	return &Batch{UUID: uuid, Position: position, Total: total}
+}
+
+// Context returns the properties available in expressions
+func (b *Batch) Context(env envs.Environment) map[string]types.XValue {
+	return map[string]types.XValue{
+		"uuid":     types.NewXText(string(b.UUID)),
+		"position": types.NewXNumberFromInt(b.Position),
+		"total":    types.NewXNumberFromInt(b.Total),
+	}
+}
+
+// BatchTrigger is used when a session was triggered for a contact as part of a batch of contacts, e.g. a broadcast. It
+// can carry parameters specific to the contact which are available as @trigger.params.
+//
+//	{
+//	  "type": "batch",
+//	  "flow": {"uuid": "50c3706e-fedb-42c0-8eab-dda3335714b7", "name": "Registration"},
+//	  "contact": {
+//	    "uuid": "9f7ede93-4b16-4692-80ad-b7dc54a1cd81",
+//	    "name": "Bob",
+//	    "created_on": "2018-01-01T12:00:00.000000Z"
+//	  },
+//	  "batch": {"uuid": "b5e9a3a4-6ea5-4a0c-a1d6-2f4cd2f6bd1f", "position": 3, "total": 250},
+//	  "params": {"send_after": "2018-01-01T14:00:00Z"},
+//	  "triggered_on": "2000-01-01T00:00:00.000000000-00:00"
+//	}
+//
+// @trigger batch
+type BatchTrigger struct {
+	baseTrigger
+	info *Batch
+}
+
+// Info returns the batch that the contact was started as part of
+func (t *BatchTrigger) Info() *Batch { return t.info }
+
+// Context for batch triggers always has non-nil params
+func (t *BatchTrigger) Context(env envs.Environment) map[string]types.XValue {
+	c := t.context()
+	c.batch = flows.Context(env, t.info)
+	return c.asMap()
+}
+
+var _ flows.Trigger = (*BatchTrigger)(nil)
+
+//------------------------------------------------------------------------------------------
+// Builder
+//------------------------------------------------------------------------------------------
+
+// BatchBuilder is a builder for batch type triggers
+type BatchBuilder struct {
+	t *BatchTrigger
+}
+
+// Batch returns a batch trigger builder
+func (b *Builder) Batch(batch *Batch) *BatchBuilder {
+	return &BatchBuilder{
+		t: &BatchTrigger{
+			baseTrigger: newBaseTrigger(TypeBatch, b.environment, b.flow, b.contact, nil, true, nil, b.profile, b.thread),
+			info:        batch,
+		},
+	}
+}
+
+// WithParams sets the per-contact params for the trigger
+func (b *BatchBuilder) WithParams(params *types.XObject) *BatchBuilder {
+	b.t.params = params
+	return b
+}
+
+// Build builds the trigger
+func (b *BatchBuilder) Build() *BatchTrigger {
+	return b.t
+}
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type batchTriggerEnvelope struct {
+	baseTriggerEnvelope
+	Batch *Batch `json:"batch" validate:"required"` // shadows the base batch flag which is implied for this type
+}
+
+func readBatchTrigger(sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Trigger, error) {
+	e := &batchTriggerEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+	if e.Batch.Position > e.Batch.Total {
+		return nil, errors.Errorf("batch position %d is greater than total %d", e.Batch.Position, e.Batch.Total)
+	}
+
+	t := &BatchTrigger{
+		info: e.Batch,
+	}
+	if err := t.unmarshal(sessionAssets, &e.baseTriggerEnvelope, missing); err != nil {
+		return nil, err
+	}
+
+	// sessions started as part of a batch are always batch sessions
+	t.batch = true
+
+	return t, nil
+}
+
+// MarshalJSON marshals this trigger into JSON
+func (t *BatchTrigger) MarshalJSON() ([]byte, error) {
+	e := &batchTriggerEnvelope{
+		Batch: t.info,
+	}
+
+	if err := t.marshal(&e.baseTriggerEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
@@ -94,7 +94,7 @@ type CampaignBuilder struct {
 func (b *Builder) Campaign(campaign *CampaignReference, eventUUID CampaignEventUUID) *CampaignBuilder {
 	return &CampaignBuilder{
 		t: &CampaignTrigger{
-			baseTrigger: newBaseTrigger(TypeCampaign, b.environment, b.flow, b.contact, nil, false, nil),
+			baseTrigger: newBaseTrigger(TypeCampaign, b.environment, b.flow, b.contact, nil, false, nil, b.profile, b.thread),
 			event:       &CampaignEvent{UUID: eventUUID, Campaign: campaign},
 		},
 	}
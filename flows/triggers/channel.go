@@ -73,7 +73,7 @@ type ChannelBuilder struct {
 func (b *Builder) Channel(channel *assets.ChannelReference, eventType ChannelEventType) *ChannelBuilder {
 	return &ChannelBuilder{
 		t: &ChannelTrigger{
-			baseTrigger: newBaseTrigger(TypeChannel, b.environment, b.flow, b.contact, nil, false, nil),
+			baseTrigger: newBaseTrigger(TypeChannel, b.environment, b.flow, b.contact, nil, false, nil, b.profile, b.thread),
 			event:       &ChannelEvent{Type: eventType, Channel: channel},
 		},
 	}
@@ -0,0 +1,118 @@
+package triggers
+
+import (
+	"encoding/json"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/inputs"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeEmail, readEmailTrigger)
+}
+
+// TypeEmail is the type for sessions triggered by an email
+const TypeEmail string = "email"
+
+// EmailTrigger is used when a session was triggered by an email being received from the contact, e.g. by a support
+// inbox. The email is the input of the session and is available in expressions as @input.email.
+//
+//	{
+//	  "type": "email",
+//	  "flow": {"uuid": "50c3706e-fedb-42c0-8eab-dda3335714b7", "name": "Registration"},
+//	  "contact": {
+//	    "uuid": "9f7ede93-4b16-4692-80ad-b7dc54a1cd81",
+//	    "name": "Bob",
+//	    "created_on": "2018-01-01T12:00:00.000000Z"
+//	  },
+//	  "email": {
+//	    "uuid": "2d611e17-fb22-457f-b802-b8f7ec5cda5b",
+//	    "from": "bob@nyaruka.com",
+//	    "subject": "Order #1234",
+//	    "body": "Where is my order?",
+//	    "attachments": ["image/jpeg:https://s3.amazon.com/mybucket/receipt.jpg"]
+//	  },
+//	  "triggered_on": "2000-01-01T00:00:00.000000000-00:00"
+//	}
+//
+// @trigger email
+type EmailTrigger struct {
+	baseTrigger
+	email *flows.EmailIn
+}
+
+// Email returns the email that triggered the session
+func (t *EmailTrigger) Email() *flows.EmailIn { return t.email }
+
+// InitializeRun performs additional initialization when we visit our first node
+func (t *EmailTrigger) InitializeRun(run flows.Run, logEvent flows.EventCallback) error {
+	run.Session().SetInput(inputs.NewEmail(t.email, t.triggeredOn, run.Flow().InputProcessing()))
+	logEvent(events.NewEmailReceived(t.email))
+
+	return t.baseTrigger.InitializeRun(run, logEvent)
+}
+
+var _ flows.Trigger = (*EmailTrigger)(nil)
+
+//------------------------------------------------------------------------------------------
+// Builder
+//------------------------------------------------------------------------------------------
+
+// EmailBuilder is a builder for email type triggers
+type EmailBuilder struct {
+	t *EmailTrigger
+}
+
+// Email returns an email trigger builder
+func (b *Builder) Email(email *flows.EmailIn) *EmailBuilder {
+	return &EmailBuilder{
+		t: &EmailTrigger{
+			baseTrigger: newBaseTrigger(TypeEmail, b.environment, b.flow, b.contact, nil, false, nil, b.profile, b.thread),
+			email:       email,
+		},
+	}
+}
+
+// Build builds the trigger
+func (b *EmailBuilder) Build() *EmailTrigger {
+	return b.t
+}
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type emailTriggerEnvelope struct {
+	baseTriggerEnvelope
+	Email *flows.EmailIn `json:"email" validate:"required,dive"`
+}
+
+func readEmailTrigger(sessionAssets flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Trigger, error) {
+	e := &emailTriggerEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	t := &EmailTrigger{email: e.Email}
+
+	if err := t.unmarshal(sessionAssets, &e.baseTriggerEnvelope, missing); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// MarshalJSON marshals this trigger into JSON
+func (t *EmailTrigger) MarshalJSON() ([]byte, error) {
+	e := &emailTriggerEnvelope{Email: t.email}
+
+	if err := t.marshal(&e.baseTriggerEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
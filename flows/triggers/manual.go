@@ -64,7 +64,7 @@ type ManualBuilder struct {
 // Manual returns a manual trigger builder
 func (b *Builder) Manual() *ManualBuilder {
 	return &ManualBuilder{
-		t: &ManualTrigger{baseTrigger: newBaseTrigger(TypeManual, b.environment, b.flow, b.contact, nil, false, nil)},
+		t: &ManualTrigger{baseTrigger: newBaseTrigger(TypeManual, b.environment, b.flow, b.contact, nil, false, nil, b.profile, b.thread)},
 	}
 }
 
@@ -75,7 +75,7 @@ func NewKeywordMatch(typeName KeywordMatchType, keyword string) *KeywordMatch {
 // InitializeRun performs additional initialization when we visit our first node
 func (t *MsgTrigger) InitializeRun(run flows.Run, logEvent flows.EventCallback) error {
 	// update our input
-	input := inputs.NewMsg(run.Session().Assets(), t.msg, t.triggeredOn)
+	input := inputs.NewMsg(run.Session().Assets(), t.msg, t.triggeredOn, run.Flow().InputProcessing())
 
 	run.Session().SetInput(input)
 	logEvent(events.NewMsgReceived(t.msg))
@@ -107,7 +107,7 @@ type MsgBuilder struct {
 func (b *Builder) Msg(msg *flows.MsgIn) *MsgBuilder {
 	return &MsgBuilder{
 		t: &MsgTrigger{
-			baseTrigger: newBaseTrigger(TypeMsg, b.environment, b.flow, b.contact, nil, false, nil),
+			baseTrigger: newBaseTrigger(TypeMsg, b.environment, b.flow, b.contact, nil, false, nil, b.profile, b.thread),
 			msg:         msg,
 		},
 	}
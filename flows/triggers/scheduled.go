@@ -0,0 +1,124 @@
+package triggers
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/excellent/types"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+)
+
+func init() {
+	registerType(TypeScheduled, readScheduledTrigger)
+}
+
+// TypeScheduled is the type for sessions triggered by a schedule
+const TypeScheduled string = "scheduled"
+
+// ScheduledTrigger is used when a session was triggered by a schedule firing. The `scheduled_on` is when the schedule
+// was due to fire which may be earlier than when the session was actually triggered.
+//
+//	{
+//	  "type": "scheduled",
+//	  "flow": {"uuid": "50c3706e-fedb-42c0-8eab-dda3335714b7", "name": "Registration"},
+//	  "contact": {
+//	    "uuid": "9f7ede93-4b16-4692-80ad-b7dc54a1cd81",
+//	    "name": "Bob",
+//	    "created_on": "2018-01-01T12:00:00.000000Z"
+//	  },
+//	  "scheduled_on": "2000-01-01T00:00:00.000000000-00:00",
+//	  "triggered_on": "2000-01-01T00:00:05.000000000-00:00"
+//	}
+//
+// @trigger scheduled
+type ScheduledTrigger struct {
+	baseTrigger
+
+	scheduledOn time.Time
+}
+
+// ScheduledOn returns when the schedule was due to fire
+func (t *ScheduledTrigger) ScheduledOn() time.Time { return t.scheduledOn }
+
+var _ flows.Trigger = (*ScheduledTrigger)(nil)
+
+//------------------------------------------------------------------------------------------
+// Builder
+//------------------------------------------------------------------------------------------
+
+// ScheduledBuilder is a builder for scheduled type triggers
+type ScheduledBuilder struct {
+	t *ScheduledTrigger
+}
+
+// Scheduled returns a scheduled trigger builder
+func (b *Builder) Scheduled(scheduledOn time.Time) *ScheduledBuilder {
+	return &ScheduledBuilder{
+		t: &ScheduledTrigger{
+			baseTrigger: newBaseTrigger(TypeScheduled, b.environment, b.flow, b.contact, nil, false, nil, b.profile, b.thread),
+			scheduledOn: scheduledOn,
+		},
+	}
+}
+
+// WithParams sets the params for the trigger
+func (b *ScheduledBuilder) WithParams(params *types.XObject) *ScheduledBuilder {
+	b.t.params = params
+	return b
+}
+
+// WithCall sets the call for the trigger
+func (b *ScheduledBuilder) WithCall(channel *assets.ChannelReference, urn urns.URN) *ScheduledBuilder {
+	b.t.call = flows.NewCall(channel, urn)
+	return b
+}
+
+// AsBatch sets batch mode on for the trigger
+func (b *ScheduledBuilder) AsBatch() *ScheduledBuilder {
+	b.t.batch = true
+	return b
+}
+
+// Build builds the trigger
+func (b *ScheduledBuilder) Build() *ScheduledTrigger {
+	return b.t
+}
+
+//------------------------------------------------------------------------------------------
+// JSON Encoding / Decoding
+//------------------------------------------------------------------------------------------
+
+type scheduledTriggerEnvelope struct {
+	baseTriggerEnvelope
+	ScheduledOn time.Time `json:"scheduled_on" validate:"required"`
+}
+
+func readScheduledTrigger(sa flows.SessionAssets, data json.RawMessage, missing assets.MissingCallback) (flows.Trigger, error) {
+	e := &scheduledTriggerEnvelope{}
+	if err := utils.UnmarshalAndValidate(data, e); err != nil {
+		return nil, err
+	}
+
+	t := &ScheduledTrigger{scheduledOn: e.ScheduledOn}
+
+	if err := t.unmarshal(sa, &e.baseTriggerEnvelope, missing); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// MarshalJSON marshals this trigger into JSON
+func (t *ScheduledTrigger) MarshalJSON() ([]byte, error) {
+	e := &scheduledTriggerEnvelope{ScheduledOn: t.scheduledOn}
+
+	if err := t.marshal(&e.baseTriggerEnvelope); err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(e)
+}
@@ -83,7 +83,7 @@ type TicketBuilder struct {
 func (b *Builder) Ticket(ticket *flows.Ticket, eventType TicketEventType) *TicketBuilder {
 	return &TicketBuilder{
 		t: &TicketTrigger{
-			baseTrigger: newBaseTrigger(TypeTicket, b.environment, b.flow, b.contact, nil, false, nil),
+			baseTrigger: newBaseTrigger(TypeTicket, b.environment, b.flow, b.contact, nil, false, nil, b.profile, b.thread),
 			event:       &TicketEvent{type_: eventType, ticket: ticket},
 		},
 	}
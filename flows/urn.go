@@ -25,7 +25,7 @@ func init() {
 
 // ValidateURN validates whether the field value is a valid URN
 func ValidateURN(fl validator.FieldLevel) bool {
-	err := urns.URN(fl.Field().String()).Validate()
+	err := utils.ValidateURN(urns.URN(fl.Field().String()))
 	return err == nil
 }
 
@@ -40,6 +40,7 @@ func ValidateURNScheme(fl validator.FieldLevel) bool {
 //   - _tel:+16303524567_
 //   - _twitterid:54784326227#nyaruka_
 //   - _telegram:34642632786#bobby_
+//   - _ext:employee:E1234_
 type ContactURN struct {
 	urn     urns.URN
 	channel *Channel
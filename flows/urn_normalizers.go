@@ -0,0 +1,38 @@
+package flows
+
+import (
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/envs"
+)
+
+// URNNormalizer normalizes URNs of a particular scheme, e.g. formatting phone numbers as E.164
+type URNNormalizer func(env envs.Environment, urn urns.URN) urns.URN
+
+var registeredURNNormalizers = map[string]URNNormalizer{}
+
+func init() {
+	RegisterURNNormalizer(urns.TelScheme, normalizeTelURN)
+}
+
+// RegisterURNNormalizer registers the normalizer for URNs of the given scheme, replacing any existing one. This lets
+// hosts change how URNs are normalized for schemes which have their own rules.
+func RegisterURNNormalizer(scheme string, normalizer URNNormalizer) {
+	registeredURNNormalizers[scheme] = normalizer
+}
+
+// NormalizeURN normalizes the given URN using the normalizer registered for its scheme. URNs of other schemes get the
+// default normalization which trims whitespace, lowercases email addresses and canonicalizes twitter handles.
+func NormalizeURN(env envs.Environment, urn urns.URN) urns.URN {
+	if urn.Scheme() == "" {
+		return urn
+	}
+	if normalizer := registeredURNNormalizers[urn.Scheme()]; normalizer != nil {
+		return normalizer(env, urn)
+	}
+	return urn.Normalize("")
+}
+
+// phone numbers are formatted as E.164 where they can be parsed using the environment's default country
+func normalizeTelURN(env envs.Environment, urn urns.URN) urns.URN {
+	return urn.Normalize(string(env.DefaultCountry()))
+}
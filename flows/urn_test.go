@@ -1,6 +1,7 @@
 package flows_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/nyaruka/gocommon/urns"
@@ -114,3 +115,31 @@ func TestURNList(t *testing.T) {
 		types.NewXText("tel:+250781111222"),
 	), urnList.ToXValue(env))
 }
+
+func TestNormalizeURN(t *testing.T) {
+	env := envs.NewBuilder().WithDefaultCountry("RW").Build()
+
+	tcs := []struct {
+		urn        urns.URN
+		normalized urns.URN
+	}{
+		{"tel:0788 123 123", "tel:+250788123123"},
+		{"tel:+250788123123", "tel:+250788123123"},
+		{"mailto:Bob@Nyaruka.com", "mailto:bob@nyaruka.com"},
+		{"twitter:@Bob_McFlows", "twitter:bob_mcflows"},
+		{"telegram: 12345678 ", "telegram:12345678"},
+		{"tel+1234567890", "tel+1234567890"}, // no scheme
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.normalized, flows.NormalizeURN(env, tc.urn), "normalize mismatch for %s", tc.urn)
+	}
+
+	// hosts can register their own normalizers for schemes
+	flows.RegisterURNNormalizer(urns.WhatsAppScheme, func(env envs.Environment, urn urns.URN) urns.URN {
+		return urns.URN("whatsapp:" + strings.TrimPrefix(urn.Path(), "+"))
+	})
+	defer flows.RegisterURNNormalizer(urns.WhatsAppScheme, nil)
+
+	assert.Equal(t, urns.URN("whatsapp:250788123123"), flows.NormalizeURN(env, "whatsapp:+250788123123"))
+}
@@ -7,6 +7,15 @@ import (
 	"github.com/nyaruka/goflow/utils"
 )
 
+func init() {
+	RegisterContextType("user",
+		NewContextProperty("__default__", "text", "the name or email"),
+		NewContextProperty("email", "text", "the email address of the user"),
+		NewContextProperty("name", "text", "the name of the user"),
+		NewContextProperty("first_name", "text", "the first name of the user"),
+	)
+}
+
 // User adds some functionality to user assets.
 type User struct {
 	assets.User
@@ -40,13 +49,6 @@ func (u *User) Format() string {
 }
 
 // Context returns the properties available in expressions
-//
-//	__default__:text -> the name or email
-//	email:text -> the email address of the user
-//	name:text -> the name of the user
-//	first_name:text -> the first name of the user
-//
-// @context user
 func (u *User) Context(env envs.Environment) map[string]types.XValue {
 	var firstName types.XText
 
@@ -0,0 +1,47 @@
+package flows
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nyaruka/gocommon/stringsx"
+)
+
+// USSDMaxResponseLength is the maximum length of a USSD response that networks can reliably deliver
+const USSDMaxResponseLength = 182
+
+// USSDSessionTimeoutSeconds is how long networks typically keep a USSD session open waiting for the contact to reply
+const USSDSessionTimeoutSeconds = 180
+
+// USSDMoreOption is what the contact replies with to see the next page of a paginated USSD menu
+const USSDMoreOption = "99"
+
+const ussdMoreLine = "\n" + USSDMoreOption + ". More"
+
+// PaginateUSSDMenu splits the given text into pages which each fit within the given maximum length. Text is only broken
+// between lines so that menu options are kept whole, and every page except the last ends with a "99. More" option. Any
+// single line which is too long to fit on a page is truncated.
+func PaginateUSSDMenu(text string, maxLength int) []string {
+	if utf8.RuneCountInString(text) <= maxLength {
+		return []string{text}
+	}
+
+	pageLength := maxLength - utf8.RuneCountInString(ussdMoreLine)
+	pages := make([]string, 0, 2)
+	current := ""
+
+	for i, line := range strings.Split(text, "\n") {
+		line = stringsx.Truncate(line, pageLength)
+
+		if i == 0 {
+			current = line
+		} else if utf8.RuneCountInString(current)+1+utf8.RuneCountInString(line) > pageLength {
+			pages = append(pages, current+ussdMoreLine)
+			current = line
+		} else {
+			current += "\n" + line
+		}
+	}
+
+	return append(pages, current)
+}
@@ -0,0 +1,40 @@
+package flows_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginateUSSDMenu(t *testing.T) {
+	menu := "Choose a service:\n1. Check balance\n2. Buy airtime\n3. Buy data\n4. Pay bill\n5. Transfer money"
+
+	tcs := []struct {
+		text      string
+		maxLength int
+		pages     []string
+	}{
+		{"", 182, []string{""}},
+		{menu, 182, []string{menu}},
+		{menu, 50, []string{
+			"Choose a service:\n1. Check balance\n99. More",
+			"2. Buy airtime\n3. Buy data\n4. Pay bill\n99. More",
+			"5. Transfer money",
+		}},
+		{"Choose:\n1. " + strings.Repeat("x", 30), 30, []string{
+			"Choose:\n99. More",
+			"1. xxxxxxxxxxxxxxxxxx",
+		}},
+		{strings.Repeat("x", 30) + "\n1. Yes", 20, []string{
+			"xxxxxxxxxxx\n99. More",
+			"1. Yes",
+		}},
+	}
+
+	for _, tc := range tcs {
+		pages := flows.PaginateUSSDMenu(tc.text, tc.maxLength)
+		assert.Equal(t, tc.pages, pages, "pages mismatch for text %q", tc.text)
+	}
+}
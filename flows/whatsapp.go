@@ -0,0 +1,46 @@
+package flows
+
+import (
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/envs"
+)
+
+// WhatsAppFlow is a WhatsApp Flow form sent to a contact. The flow token is echoed back by WhatsApp when the contact
+// submits the form, and is used to match the submission to the form that was sent.
+type WhatsAppFlow struct {
+	URN       urns.URN                 `json:"urn,omitempty" validate:"omitempty,urn"`
+	Channel   *assets.ChannelReference `json:"channel,omitempty"`
+	FlowID    string                   `json:"flow_id" validate:"required"`
+	FlowToken string                   `json:"flow_token" validate:"required"`
+	Screen    string                   `json:"screen" validate:"required"`
+	Body      string                   `json:"body"`
+	Button    string                   `json:"button"`
+	Data      map[string]string        `json:"data,omitempty"`
+	Results   map[string]string        `json:"results,omitempty"` // form field name to result name
+}
+
+// WhatsAppFlowResponse is the submission of a WhatsApp Flow form by a contact
+type WhatsAppFlowResponse struct {
+	FlowToken string            `json:"flow_token" validate:"required"`
+	Fields    map[string]string `json:"fields"`
+}
+
+// WhatsAppTemplateMsg is an approved WhatsApp template message sent to a contact, with the params of each of the
+// template's components resolved
+type WhatsAppTemplateMsg struct {
+	URN        urns.URN                     `json:"urn,omitempty" validate:"omitempty,urn"`
+	Channel    *assets.ChannelReference     `json:"channel,omitempty"`
+	Template   *assets.TemplateReference    `json:"template" validate:"required"`
+	Namespace  string                       `json:"namespace,omitempty"`
+	Locale     envs.Locale                  `json:"locale,omitempty"`
+	Components []*WhatsAppTemplateComponent `json:"components,omitempty"`
+	Text       string                       `json:"text"` // the body with params substituted
+}
+
+// WhatsAppTemplateComponent is a component of a template message with its resolved params
+type WhatsAppTemplateComponent struct {
+	Type   string   `json:"type"`
+	Name   string   `json:"name"`
+	Params []string `json:"params"`
+}
@@ -1,6 +1,7 @@
 package mobile
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 
@@ -280,7 +281,7 @@ func (s *Session) Assets() *SessionAssets {
 
 // Resume resumes this session
 func (s *Session) Resume(resume *Resume) (*Sprint, error) {
-	sprint, err := s.target.Resume(resume.target)
+	sprint, err := s.target.Resume(context.Background(), resume.target)
 	if err != nil {
 		return nil, err
 	}
@@ -308,7 +309,7 @@ func NewEngine() *Engine {
 
 // NewSession creates a new session
 func (e *Engine) NewSession(sa *SessionAssets, trigger *Trigger) (*SessionAndSprint, error) {
-	session, sprint, err := e.target.NewSession(sa.target, trigger.target)
+	session, sprint, err := e.target.NewSession(context.Background(), sa.target, trigger.target)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,195 @@
+// Package perf provides representative engine workloads which can be benchmarked, and helpers for comparing their
+// results against a saved baseline so that embedders and CI can catch engine performance regressions.
+package perf
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/pkg/errors"
+)
+
+// Workload is a representative engine workload which can be measured
+type Workload struct {
+	name    string
+	prepare func() (func() error, error)
+}
+
+// Name returns the name of this workload
+func (w *Workload) Name() string { return w.name }
+
+// Prepare sets up this workload and returns a function which performs a single operation of it
+func (w *Workload) Prepare() (func() error, error) { return w.prepare() }
+
+// Workloads returns all the workloads in a fixed order
+func Workloads() []*Workload {
+	return []*Workload{
+		{name: "large_contact", prepare: prepareLargeContact},
+		{name: "long_flow", prepare: prepareLongFlow},
+		{name: "webhook_extras", prepare: prepareWebhookExtras},
+	}
+}
+
+// Result is the measured performance of a single workload
+type Result struct {
+	Workload    string `json:"workload"`
+	Iterations  int    `json:"iterations"`
+	NsPerOp     int64  `json:"ns_per_op"`
+	AllocsPerOp int64  `json:"allocs_per_op"`
+	BytesPerOp  int64  `json:"bytes_per_op"`
+}
+
+// the minimum time for which a workload is run when it's measured, so that its timings are reliable
+const minMeasureTime = time.Second
+
+// Measure benchmarks the given workload outside of go test
+func Measure(w *Workload) (*Result, error) {
+	op, err := w.prepare()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error preparing workload %s", w.name)
+	}
+
+	// check the workload runs before we try to time it
+	if err := op(); err != nil {
+		return nil, errors.Wrapf(err, "error running workload %s", w.name)
+	}
+
+	// keep doubling the number of operations until they take long enough to time
+	var before, after runtime.MemStats
+	var elapsed time.Duration
+	n := 1
+	for {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+
+		for i := 0; i < n; i++ {
+			if err := op(); err != nil {
+				return nil, errors.Wrapf(err, "error running workload %s", w.name)
+			}
+		}
+
+		elapsed = time.Since(start)
+		runtime.ReadMemStats(&after)
+
+		if elapsed >= minMeasureTime {
+			break
+		}
+		n *= 2
+	}
+
+	return &Result{
+		Workload:    w.name,
+		Iterations:  n,
+		NsPerOp:     elapsed.Nanoseconds() / int64(n),
+		AllocsPerOp: int64(after.Mallocs-before.Mallocs) / int64(n),
+		BytesPerOp:  int64(after.TotalAlloc-before.TotalAlloc) / int64(n),
+	}, nil
+}
+
+// MeasureAll benchmarks all the workloads outside of go test
+func MeasureAll() ([]*Result, error) {
+	workloads := Workloads()
+	results := make([]*Result, len(workloads))
+
+	for i, w := range workloads {
+		result, err := Measure(w)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// Baseline is a set of previously saved results keyed by workload name
+type Baseline map[string]*Result
+
+// NewBaseline creates a new baseline from the given results
+func NewBaseline(results []*Result) Baseline {
+	b := make(Baseline, len(results))
+	for _, r := range results {
+		b[r.Workload] = r
+	}
+	return b
+}
+
+// ReadBaseline reads a baseline from a JSON array of results
+func ReadBaseline(data []byte) (Baseline, error) {
+	var results []*Result
+	if err := jsonx.Unmarshal(data, &results); err != nil {
+		return nil, errors.Wrap(err, "unable to read baseline")
+	}
+	return NewBaseline(results), nil
+}
+
+// Results returns the results in this baseline ordered by workload name, e.g. for saving as JSON
+func (b Baseline) Results() []*Result {
+	results := make([]*Result, 0, len(b))
+	for _, r := range b {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Workload < results[j].Workload })
+	return results
+}
+
+// Metric is a measurement which can regress
+type Metric string
+
+// metrics which are compared against baselines
+const (
+	MetricNsPerOp     Metric = "ns_per_op"
+	MetricAllocsPerOp Metric = "allocs_per_op"
+	MetricBytesPerOp  Metric = "bytes_per_op"
+)
+
+// Regression is a metric of a workload which has got worse than its baseline by more than the allowed tolerance
+type Regression struct {
+	Workload string `json:"workload"`
+	Metric   Metric `json:"metric"`
+	Baseline int64  `json:"baseline"`
+	Actual   int64  `json:"actual"`
+}
+
+// Change returns the relative change from the baseline, e.g. 0.25 if the metric is 25% worse
+func (r *Regression) Change() float64 {
+	if r.Baseline == 0 {
+		return 0
+	}
+	return float64(r.Actual-r.Baseline) / float64(r.Baseline)
+}
+
+func (r *Regression) String() string {
+	return fmt.Sprintf("%s %s regressed from %d to %d (+%.1f%%)", r.Workload, r.Metric, r.Baseline, r.Actual, r.Change()*100)
+}
+
+// Compare compares results against a baseline and returns the metrics which are worse by more than the given
+// tolerance, e.g. 0.1 allows each metric to be up to 10% worse. Workloads without a baseline are ignored.
+func Compare(baseline Baseline, results []*Result, tolerance float64) []*Regression {
+	regressions := make([]*Regression, 0)
+
+	for _, actual := range results {
+		base := baseline[actual.Workload]
+		if base == nil {
+			continue
+		}
+
+		for _, m := range []struct {
+			metric         Metric
+			base, measured int64
+		}{
+			{MetricNsPerOp, base.NsPerOp, actual.NsPerOp},
+			{MetricAllocsPerOp, base.AllocsPerOp, actual.AllocsPerOp},
+			{MetricBytesPerOp, base.BytesPerOp, actual.BytesPerOp},
+		} {
+			if float64(m.measured) > float64(m.base)*(1+tolerance) {
+				regressions = append(regressions, &Regression{Workload: actual.Workload, Metric: m.metric, Baseline: m.base, Actual: m.measured})
+			}
+		}
+	}
+
+	return regressions
+}
@@ -0,0 +1,86 @@
+package perf_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/perf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkloads(t *testing.T) {
+	workloads := perf.Workloads()
+	assert.Equal(t, 3, len(workloads))
+
+	for _, w := range workloads {
+		op, err := w.Prepare()
+		require.NoError(t, err, "error preparing workload %s", w.Name())
+
+		// operations can be repeated
+		assert.NoError(t, op(), "error running workload %s", w.Name())
+		assert.NoError(t, op(), "error re-running workload %s", w.Name())
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	result, err := perf.Measure(perf.Workloads()[0])
+	require.NoError(t, err)
+
+	assert.Equal(t, "large_contact", result.Workload)
+	assert.Greater(t, result.Iterations, 0)
+	assert.Greater(t, result.NsPerOp, int64(0))
+	assert.Greater(t, result.AllocsPerOp, int64(0))
+	assert.Greater(t, result.BytesPerOp, int64(0))
+}
+
+func TestCompare(t *testing.T) {
+	baseline, err := perf.ReadBaseline([]byte(`[
+		{"workload": "long_flow", "iterations": 100, "ns_per_op": 1000, "allocs_per_op": 50, "bytes_per_op": 2000},
+		{"workload": "large_contact", "iterations": 100, "ns_per_op": 3000, "allocs_per_op": 80, "bytes_per_op": 4000}
+	]`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"large_contact", "long_flow"}, []string{baseline.Results()[0].Workload, baseline.Results()[1].Workload})
+
+	_, err = perf.ReadBaseline([]byte(`{`))
+	assert.EqualError(t, err, "unable to read baseline: unexpected end of JSON input")
+
+	results := []*perf.Result{
+		{Workload: "long_flow", Iterations: 100, NsPerOp: 1300, AllocsPerOp: 52, BytesPerOp: 1500},
+		{Workload: "large_contact", Iterations: 100, NsPerOp: 3100, AllocsPerOp: 100, BytesPerOp: 4000},
+		{Workload: "webhook_extras", Iterations: 100, NsPerOp: 5000, AllocsPerOp: 90, BytesPerOp: 6000},
+	}
+
+	regressions := perf.Compare(baseline, results, 0.1)
+	assert.Equal(t, []*perf.Regression{
+		{Workload: "long_flow", Metric: perf.MetricNsPerOp, Baseline: 1000, Actual: 1300},
+		{Workload: "large_contact", Metric: perf.MetricAllocsPerOp, Baseline: 80, Actual: 100},
+	}, regressions)
+	assert.InDelta(t, 0.3, regressions[0].Change(), 0.0001)
+	assert.Equal(t, "long_flow ns_per_op regressed from 1000 to 1300 (+30.0%)", regressions[0].String())
+
+	// with a larger tolerance nothing has regressed
+	assert.Equal(t, []*perf.Regression{}, perf.Compare(baseline, results, 0.5))
+
+	// a baseline can be created from results and saved
+	saved := jsonx.MustMarshal(perf.NewBaseline(results[:1]).Results())
+	assert.Equal(t, `[{"workload":"long_flow","iterations":100,"ns_per_op":1300,"allocs_per_op":52,"bytes_per_op":1500}]`, string(saved))
+}
+
+func BenchmarkWorkloads(b *testing.B) {
+	for _, w := range perf.Workloads() {
+		b.Run(w.Name(), func(b *testing.B) {
+			op, err := w.Prepare()
+			require.NoError(b, err, "error preparing workload %s", w.Name())
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for n := 0; n < b.N; n++ {
+				if err := op(); err != nil {
+					b.Fatalf("error running workload %s: %s", w.Name(), err)
+				}
+			}
+		})
+	}
+}
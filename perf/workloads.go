@@ -0,0 +1,296 @@
+package perf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/definition"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/triggers"
+	"github.com/nyaruka/goflow/services/webhooks"
+	"github.com/pkg/errors"
+)
+
+const (
+	largeContactFields = 200
+	largeContactGroups = 50
+	largeContactURNs   = 20
+	longFlowNodes      = 100
+	webhookItems       = 500
+)
+
+// kinds of things we generate UUIDs for
+const (
+	kindFlow = iota + 1
+	kindNode
+	kindExit
+	kindAction
+	kindCategory
+	kindCase
+	kindField
+	kindGroup
+	kindContact
+)
+
+// generates a UUID for the given kind and index of thing, so that workloads are the same every time they're run
+func uuidFor(kind, i int) string {
+	return fmt.Sprintf("%08x-0000-4000-8000-%012x", kind, i)
+}
+
+// a contact with lots of URNs, groups and field values, run through a flow which reads and writes them
+func prepareLargeContact() (func() error, error) {
+	fields := make([]map[string]any, largeContactFields)
+	values := make(map[string]any, largeContactFields)
+	for i := range fields {
+		key := fmt.Sprintf("field_%d", i)
+		if i%2 == 0 {
+			fields[i] = map[string]any{"uuid": uuidFor(kindField, i), "key": key, "name": fmt.Sprintf("Field %d", i), "type": "text"}
+			values[key] = map[string]any{"text": fmt.Sprintf("Value %d", i)}
+		} else {
+			fields[i] = map[string]any{"uuid": uuidFor(kindField, i), "key": key, "name": fmt.Sprintf("Field %d", i), "type": "number"}
+			values[key] = map[string]any{"text": fmt.Sprint(i), "number": i}
+		}
+	}
+
+	groups := make([]map[string]any, largeContactGroups)
+	for i := range groups {
+		groups[i] = map[string]any{"uuid": uuidFor(kindGroup, i), "name": fmt.Sprintf("Group %d", i)}
+	}
+
+	urns := make([]string, largeContactURNs)
+	for i := range urns {
+		urns[i] = fmt.Sprintf("tel:+25078812%04d", i)
+	}
+
+	flow := newFlowBuilder("Large Contact")
+	flow.addActionNode(
+		sendMsgAction(flow.next(kindAction), "Hi @contact.first_name, you are in @(count(contact.groups)) groups and have @(count(contact.urns)) numbers"),
+		sendMsgAction(flow.next(kindAction), "@fields.field_0 @fields.field_1 @fields.field_198 @fields.field_199"),
+	)
+	flow.addActionNode(
+		map[string]any{"type": "set_contact_field", "uuid": flow.next(kindAction), "field": map[string]any{"key": "field_0", "name": "Field 0"}, "value": "@(upper(fields.field_198))"},
+		map[string]any{"type": "set_contact_field", "uuid": flow.next(kindAction), "field": map[string]any{"key": "field_1", "name": "Field 1"}, "value": "@(fields.field_199 + 1)"},
+		map[string]any{"type": "add_contact_groups", "uuid": flow.next(kindAction), "groups": []any{groups[0]}},
+	)
+
+	contactJSON := jsonx.MustMarshal(map[string]any{
+		"uuid":       uuidFor(kindContact, 1),
+		"name":       "Large Contact",
+		"language":   "eng",
+		"status":     "active",
+		"created_on": "2020-01-01T12:00:00.000000000-00:00",
+		"urns":       urns,
+		"groups":     groups[1:],
+		"fields":     values,
+	})
+
+	run, err := newFlowRunner(map[string]any{"flows": []any{flow.build()}, "fields": fields, "groups": groups}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error { return run(contactJSON) }, nil
+}
+
+// a flow which saves a result and routes on it at each of many nodes
+func prepareLongFlow() (func() error, error) {
+	flow := newFlowBuilder("Long Flow")
+	for i := 0; i < longFlowNodes; i++ {
+		flow.addSwitchNode(
+			map[string]any{"type": "set_run_result", "uuid": flow.next(kindAction), "name": fmt.Sprintf("Step %d", i), "value": fmt.Sprintf("@(%d * 3 + count(run.path))", i)},
+			fmt.Sprintf("@results.step_%d", i), "has_number_between", "0", "200",
+		)
+	}
+
+	run, err := newFlowRunner(map[string]any{"flows": []any{flow.build()}}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	contactJSON := jsonx.MustMarshal(map[string]any{"uuid": uuidFor(kindContact, 1), "name": "Bob", "status": "active", "created_on": "2020-01-01T12:00:00.000000000-00:00"})
+
+	return func() error { return run(contactJSON) }, nil
+}
+
+// a flow which calls a webhook with a large JSON response and then reads lots of values out of it
+func prepareWebhookExtras() (func() error, error) {
+	items := make([]map[string]any, webhookItems)
+	for i := range items {
+		items[i] = map[string]any{
+			"id":    i,
+			"name":  fmt.Sprintf("Item %d", i),
+			"price": float64(i) * 1.25,
+			"tags":  []string{"perf", fmt.Sprintf("tag%d", i%10)},
+			"stock": map[string]any{"warehouse": i * 3, "shop": i % 7},
+		}
+	}
+	body := jsonx.MustMarshal(map[string]any{"status": "ok", "total": webhookItems, "items": items})
+
+	flow := newFlowBuilder("Webhook Extras")
+	flow.addActionNode(
+		map[string]any{"type": "call_webhook", "uuid": flow.next(kindAction), "method": "GET", "url": "http://perf.example.com/items", "result_name": "Items"},
+	)
+	actions := make([]map[string]any, 0, 20)
+	for i := 0; i < 20; i++ {
+		n := i * webhookItems / 20
+		actions = append(actions, map[string]any{
+			"type":  "set_run_result",
+			"uuid":  flow.next(kindAction),
+			"name":  fmt.Sprintf("Item %d", i),
+			"value": fmt.Sprintf("@webhook.items[%d].name @webhook.items[%d].stock.warehouse @(join(webhook.items[%d].tags, \",\"))", n, n, n),
+		})
+	}
+	flow.addActionNode(actions...)
+	flow.addActionNode(
+		sendMsgAction(flow.next(kindAction), "@(count(webhook.items)) items, first is @(webhook.items[0].name), last is @(webhook.items[webhook.total - 1].name)"),
+	)
+
+	client := &http.Client{Transport: &cannedTransport{body: body}}
+	webhookFactory := webhooks.NewServiceFactory(client, nil, nil, map[string]string{"User-Agent": "goflow-perf"}, 10*1024*1024, 10*1024)
+
+	run, err := newFlowRunner(map[string]any{"flows": []any{flow.build()}}, webhookFactory)
+	if err != nil {
+		return nil, err
+	}
+
+	contactJSON := jsonx.MustMarshal(map[string]any{"uuid": uuidFor(kindContact, 1), "name": "Bob", "status": "active", "created_on": "2020-01-01T12:00:00.000000000-00:00"})
+
+	return func() error { return run(contactJSON) }, nil
+}
+
+// creates a function which starts a session in the first flow of the given assets for a contact, and marshals it
+func newFlowRunner(assetsJSON map[string]any, webhookFactory engine.WebhookServiceFactory) (func([]byte) error, error) {
+	env := envs.NewBuilder().WithAllowedLanguages([]envs.Language{"eng"}).WithDefaultCountry("RW").Build()
+
+	source, err := static.NewSource(jsonx.MustMarshal(assetsJSON))
+	if err != nil {
+		return nil, err
+	}
+	sa, err := engine.NewSessionAssets(env, source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := engine.NewBuilder().WithMaxStepsPerSprint(1000)
+	if webhookFactory != nil {
+		builder.WithWebhookServiceFactory(webhookFactory)
+	}
+	eng := builder.Build()
+
+	flowRef := assets.NewFlowReference(assets.FlowUUID(uuidFor(kindFlow, 1)), "")
+
+	return func(contactJSON []byte) error {
+		contact, err := flows.ReadContact(sa, contactJSON, assets.IgnoreMissing)
+		if err != nil {
+			return err
+		}
+
+		trigger := triggers.NewBuilder(env, flowRef, contact).Manual().Build()
+
+		session, _, err := eng.NewSession(context.Background(), sa, trigger)
+		if err != nil {
+			return err
+		}
+		if session.Status() != flows.SessionStatusCompleted {
+			return errors.Errorf("session ended with status %s", session.Status())
+		}
+
+		_, err = jsonx.Marshal(session)
+		return err
+	}, nil
+}
+
+// builds a flow definition of nodes which each lead to the next
+type flowBuilder struct {
+	name  string
+	nodes []map[string]any
+	uuids map[int]int
+}
+
+func newFlowBuilder(name string) *flowBuilder {
+	return &flowBuilder{name: name, uuids: make(map[int]int)}
+}
+
+// gets the next UUID for the given kind of thing
+func (b *flowBuilder) next(kind int) string {
+	b.uuids[kind]++
+	return uuidFor(kind, b.uuids[kind])
+}
+
+func (b *flowBuilder) addActionNode(actions ...map[string]any) {
+	b.nodes = append(b.nodes, map[string]any{
+		"uuid":    b.next(kindNode),
+		"actions": actions,
+		"exits":   []any{map[string]any{"uuid": b.next(kindExit)}},
+	})
+}
+
+func (b *flowBuilder) addSwitchNode(action map[string]any, operand, test string, args ...string) {
+	matchExit, otherExit := b.next(kindExit), b.next(kindExit)
+	matchCategory, otherCategory := b.next(kindCategory), b.next(kindCategory)
+
+	b.nodes = append(b.nodes, map[string]any{
+		"uuid":    b.next(kindNode),
+		"actions": []any{action},
+		"router": map[string]any{
+			"type":    "switch",
+			"operand": operand,
+			"cases":   []any{map[string]any{"uuid": b.next(kindCase), "type": test, "arguments": args, "category_uuid": matchCategory}},
+			"categories": []any{
+				map[string]any{"uuid": matchCategory, "name": "Match", "exit_uuid": matchExit},
+				map[string]any{"uuid": otherCategory, "name": "Other", "exit_uuid": otherExit},
+			},
+			"default_category_uuid": otherCategory,
+		},
+		"exits": []any{map[string]any{"uuid": matchExit}, map[string]any{"uuid": otherExit}},
+	})
+}
+
+// builds the flow definition with the exits of each node pointing to the next node
+func (b *flowBuilder) build() map[string]any {
+	for i, node := range b.nodes[:len(b.nodes)-1] {
+		for _, exit := range node["exits"].([]any) {
+			exit.(map[string]any)["destination_uuid"] = b.nodes[i+1]["uuid"]
+		}
+	}
+
+	return map[string]any{
+		"uuid":         uuidFor(kindFlow, 1),
+		"name":         b.name,
+		"spec_version": definition.CurrentSpecVersion.String(),
+		"language":     "eng",
+		"type":         "messaging",
+		"nodes":        b.nodes,
+	}
+}
+
+func sendMsgAction(uuid, text string) map[string]any {
+	return map[string]any{"type": "send_msg", "uuid": uuid, "text": text}
+}
+
+// HTTP transport which returns the same JSON response to every request without making any connections
+type cannedTransport struct {
+	body []byte
+}
+
+func (t *cannedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(t.body)),
+		ContentLength: int64(len(t.body)),
+		Request:       r,
+	}, nil
+}
@@ -0,0 +1,177 @@
+package africastalking
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/gocommon/jsonx"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	apiURL        = "https://api.africastalking.com/version1/"
+	sandboxAPIURL = "https://api.sandbox.africastalking.com/version1/"
+
+	// the username of the sandbox app, which has its own API
+	sandboxUsername = "sandbox"
+)
+
+// possible statuses of a recipient of an airtime request
+const (
+	StatusSent   = "Sent"
+	StatusFailed = "Failed"
+)
+
+// Client is an Africa's Talking client, see https://developers.africastalking.com/docs/airtime/sending for API docs
+type Client struct {
+	httpClient  *http.Client
+	httpRetries *httpx.RetryConfig
+	baseURL     string
+	username    string
+	apiKey      string
+}
+
+// NewClient creates a new Africa's Talking client
+func NewClient(httpClient *http.Client, httpRetries *httpx.RetryConfig, username, apiKey string) *Client {
+	baseURL := apiURL
+	if username == sandboxUsername {
+		baseURL = sandboxAPIURL
+	}
+
+	return &Client{httpClient: httpClient, httpRetries: httpRetries, baseURL: baseURL, username: username, apiKey: apiKey}
+}
+
+// Amount is an amount of a currency which the API encodes as a string like "KES 100.0000"
+type Amount struct {
+	Currency string
+	Value    decimal.Decimal
+}
+
+// ParseAmount parses an amount string like "KES 100.0000"
+func ParseAmount(s string) (Amount, error) {
+	currency, value, found := strings.Cut(strings.TrimSpace(s), " ")
+	if !found {
+		return Amount{}, errors.Errorf("invalid amount '%s'", s)
+	}
+
+	d, err := decimal.NewFromString(strings.TrimSpace(value))
+	if err != nil {
+		return Amount{}, errors.Errorf("invalid amount '%s'", s)
+	}
+
+	return Amount{Currency: currency, Value: d}, nil
+}
+
+// Recipient is a recipient of an airtime request
+type Recipient struct {
+	PhoneNumber  string          `json:"phoneNumber"`
+	CurrencyCode string          `json:"currencyCode"`
+	Amount       decimal.Decimal `json:"amount"`
+}
+
+// RecipientResponse is the result of sending airtime to a recipient
+type RecipientResponse struct {
+	PhoneNumber  string `json:"phoneNumber"`
+	Amount       string `json:"amount"`
+	Discount     string `json:"discount"`
+	Status       string `json:"status"`
+	RequestID    string `json:"requestId"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// SendResponse is the response from an airtime send request
+type SendResponse struct {
+	NumSent       int                  `json:"numSent"`
+	TotalAmount   string               `json:"totalAmount"`
+	TotalDiscount string               `json:"totalDiscount"`
+	ErrorMessage  string               `json:"errorMessage"`
+	Responses     []*RecipientResponse `json:"responses"`
+}
+
+// SendAirtime see https://developers.africastalking.com/docs/airtime/sending
+func (c *Client) SendAirtime(ctx context.Context, recipients []*Recipient) (*SendResponse, *httpx.Trace, error) {
+	recipientsJSON, err := jsonx.Marshal(recipients)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	form := url.Values{}
+	form.Set("username", c.username)
+	form.Set("recipients", string(recipientsJSON))
+
+	response := &SendResponse{}
+
+	trace, err := c.request(ctx, "POST", "airtime/send", form, response)
+	if err != nil {
+		return nil, trace, err
+	}
+
+	// the API returns errors like invalid credentials as a message with a 2XX status
+	if len(response.Responses) == 0 && response.ErrorMessage != "" && response.ErrorMessage != "None" {
+		return nil, trace, errors.New(response.ErrorMessage)
+	}
+
+	return response, trace, nil
+}
+
+// UserData is the data of the account
+type UserData struct {
+	Balance string `json:"balance"`
+}
+
+// User see https://developers.africastalking.com/docs/application
+func (c *Client) User(ctx context.Context) (*UserData, *httpx.Trace, error) {
+	response := &struct {
+		UserData *UserData `json:"UserData"`
+	}{}
+
+	trace, err := c.request(ctx, "GET", fmt.Sprintf("user?username=%s", url.QueryEscape(c.username)), nil, response)
+	if err != nil {
+		return nil, trace, err
+	}
+	if response.UserData == nil {
+		return nil, trace, errors.New("response contains no user data")
+	}
+
+	return response.UserData, trace, nil
+}
+
+func (c *Client) request(ctx context.Context, method, endpoint string, form url.Values, response any) (*httpx.Trace, error) {
+	headers := map[string]string{"Accept": "application/json", "apiKey": c.apiKey}
+	var body io.Reader
+
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+		headers["Content-Type"] = "application/x-www-form-urlencoded"
+	}
+
+	req, err := httpx.NewRequest(method, c.baseURL+endpoint, body, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.WithContext(ctx)
+
+	trace, err := httpx.DoTrace(c.httpClient, req, c.httpRetries, nil, -1)
+	if err != nil {
+		return trace, err
+	}
+
+	if trace.Response.StatusCode >= 400 {
+		// error responses are usually plain text, e.g. "The supplied authentication is invalid"
+		msg := strings.TrimSpace(string(trace.ResponseBody))
+		if msg == "" {
+			msg = fmt.Sprintf("request failed with status %d", trace.Response.StatusCode)
+		}
+		return trace, errors.New(msg)
+	}
+
+	return trace, jsonx.Unmarshal(trace.ResponseBody, response)
+}
@@ -0,0 +1,122 @@
+package africastalking_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/goflow/services/airtime/africastalking"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+const sendSuccessResponse = `{
+	"errorMessage": "None",
+	"numSent": 1,
+	"totalAmount": "KES 100.0000",
+	"totalDiscount": "KES 4.0000",
+	"responses": [
+		{
+			"phoneNumber": "+254711123456",
+			"errorMessage": "None",
+			"amount": "KES 100.0000",
+			"status": "Sent",
+			"requestId": "ATQid_SampleTxnId123",
+			"discount": "KES 4.0000"
+		}
+	]
+}`
+
+const sendFailedResponse = `{
+	"errorMessage": "None",
+	"numSent": 0,
+	"totalAmount": "KES 0.0000",
+	"totalDiscount": "KES 0.0000",
+	"responses": [
+		{
+			"phoneNumber": "+254711123456",
+			"errorMessage": "Insufficient Credit",
+			"amount": "KES 100.0000",
+			"status": "Failed",
+			"requestId": "None",
+			"discount": "KES 0.0000"
+		}
+	]
+}`
+
+func TestParseAmount(t *testing.T) {
+	amount, err := africastalking.ParseAmount("KES 100.0000")
+	assert.NoError(t, err)
+	assert.Equal(t, "KES", amount.Currency)
+	assert.Equal(t, decimal.RequireFromString("100.0000"), amount.Value)
+
+	_, err = africastalking.ParseAmount("100.0000")
+	assert.EqualError(t, err, "invalid amount '100.0000'")
+
+	_, err = africastalking.ParseAmount("KES abc")
+	assert.EqualError(t, err, "invalid amount 'KES abc'")
+}
+
+func TestClient(t *testing.T) {
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	mocks := httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://api.africastalking.com/version1/airtime/send": {
+			httpx.NewMockResponse(201, nil, []byte(sendSuccessResponse)),
+			httpx.NewMockResponse(401, nil, []byte(`The supplied authentication is invalid`)),
+			httpx.NewMockResponse(201, nil, []byte(`{"errorMessage": "A duplicate request was received within the last 5 minutes", "numSent": 0, "responses": []}`)),
+			httpx.MockConnectionError,
+		},
+		"https://api.africastalking.com/version1/user?username=myapp": {
+			httpx.NewMockResponse(200, nil, []byte(`{"UserData": {"balance": "KES 1785.50"}}`)),
+			httpx.NewMockResponse(200, nil, []byte(`{}`)),
+		},
+		"https://api.sandbox.africastalking.com/version1/user?username=sandbox": {
+			httpx.NewMockResponse(200, nil, []byte(`{"UserData": {"balance": "KES 0.00"}}`)),
+		},
+	})
+
+	httpx.SetRequestor(mocks)
+
+	cl := africastalking.NewClient(http.DefaultClient, nil, "myapp", "sesame")
+
+	recipients := []*africastalking.Recipient{{PhoneNumber: "+254711123456", CurrencyCode: "KES", Amount: decimal.RequireFromString("100")}}
+
+	resp, trace, err := cl.SendAirtime(context.Background(), recipients)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, resp.NumSent)
+	assert.Equal(t, 1, len(resp.Responses))
+	assert.Equal(t, "Sent", resp.Responses[0].Status)
+	assert.Equal(t, "ATQid_SampleTxnId123", resp.Responses[0].RequestID)
+	test.AssertSnapshot(t, "send_airtime", string(trace.RequestTrace))
+
+	_, trace, err = cl.SendAirtime(context.Background(), recipients)
+	assert.EqualError(t, err, "The supplied authentication is invalid")
+	assert.NotNil(t, trace)
+
+	_, _, err = cl.SendAirtime(context.Background(), recipients)
+	assert.EqualError(t, err, "A duplicate request was received within the last 5 minutes")
+
+	_, _, err = cl.SendAirtime(context.Background(), recipients)
+	assert.EqualError(t, err, "unable to connect to server")
+
+	user, trace, err := cl.User(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "KES 1785.50", user.Balance)
+	test.AssertSnapshot(t, "user", string(trace.RequestTrace))
+
+	_, _, err = cl.User(context.Background())
+	assert.EqualError(t, err, "response contains no user data")
+
+	// the sandbox app uses the sandbox API
+	cl = africastalking.NewClient(http.DefaultClient, nil, "sandbox", "sesame")
+
+	user, _, err = cl.User(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "KES 0.00", user.Balance)
+
+	assert.False(t, mocks.HasUnused())
+}
@@ -0,0 +1,126 @@
+package africastalking
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/gocommon/stringsx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// the currencies that airtime is sent in for each country the API supports
+var countryCurrencies = map[envs.Country]string{
+	"KE": "KES",
+	"UG": "UGX",
+	"TZ": "TZS",
+	"RW": "RWF",
+	"MW": "MWK",
+	"ZM": "ZMW",
+	"NG": "NGN",
+	"ET": "ETB",
+}
+
+type service struct {
+	client   *Client
+	redactor stringsx.Redactor
+}
+
+// NewService creates a new Africa's Talking airtime service
+func NewService(httpClient *http.Client, httpRetries *httpx.RetryConfig, username, apiKey string) flows.AirtimeService {
+	return &service{
+		client:   NewClient(httpClient, httpRetries, username, apiKey),
+		redactor: stringsx.NewRedactor(flows.RedactionMask, apiKey),
+	}
+}
+
+func (s *service) Transfer(ctx context.Context, sender urns.URN, recipient urns.URN, amounts map[string]decimal.Decimal, logHTTP flows.HTTPLogCallback) (*flows.AirtimeTransfer, error) {
+	transfer := &flows.AirtimeTransfer{
+		UUID:          uuids.New(),
+		Sender:        sender,
+		Recipient:     recipient,
+		DesiredAmount: decimal.Zero,
+		ActualAmount:  decimal.Zero,
+	}
+
+	country := envs.DeriveCountryFromTel(recipient.Path())
+	currency, supported := countryCurrencies[country]
+	if !supported {
+		return transfer, errors.Errorf("unable to send airtime to number %s in unsupported country", recipient.Path())
+	}
+
+	amount, hasAmount := amounts[currency]
+	if !hasAmount {
+		return transfer, errors.Errorf("no amount configured for transfers in %s", currency)
+	}
+
+	transfer.Currency = currency
+	transfer.DesiredAmount = amount
+
+	response, trace, err := s.client.SendAirtime(ctx, []*Recipient{{PhoneNumber: recipient.Path(), CurrencyCode: currency, Amount: amount}})
+	if trace != nil {
+		logHTTP(flows.NewHTTPLog(trace, flows.HTTPStatusFromCode, s.redactor))
+	}
+	if err != nil {
+		return transfer, errors.Wrap(err, "airtime request failed")
+	}
+
+	if len(response.Responses) == 0 {
+		return transfer, errors.Errorf("airtime request returned no result for number %s", recipient.Path())
+	}
+
+	result := response.Responses[0]
+	if result.Status != StatusSent {
+		return transfer, errors.Errorf("airtime transfer to %s ended with status %s: %s", recipient.Path(), result.Status, result.ErrorMessage)
+	}
+
+	// the amount sent should be what we asked for but use what the API reports if it's valid
+	transfer.ActualAmount = amount
+	if sent, err := ParseAmount(result.Amount); err == nil && sent.Currency == currency {
+		transfer.ActualAmount = sent.Value
+	}
+
+	return transfer, nil
+}
+
+// Check checks that our credentials are valid and that we have a positive balance to make transfers with
+func (s *service) Check(ctx context.Context) error {
+	balances, err := s.Balance(ctx, func(*flows.HTTPLog) {})
+	if err != nil {
+		return err
+	}
+
+	for _, b := range balances {
+		if b.IsPositive() {
+			return nil
+		}
+	}
+
+	return errors.New("no balance available for transfers")
+}
+
+func (s *service) Balance(ctx context.Context, logHTTP flows.HTTPLogCallback) (map[string]decimal.Decimal, error) {
+	user, trace, err := s.client.User(ctx)
+	if trace != nil {
+		logHTTP(flows.NewHTTPLog(trace, flows.HTTPStatusFromCode, s.redactor))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "balance lookup failed")
+	}
+
+	balance, err := ParseAmount(user.Balance)
+	if err != nil {
+		return nil, errors.Wrap(err, "balance lookup failed")
+	}
+
+	return map[string]decimal.Decimal{balance.Currency: balance.Value}, nil
+}
+
+var _ flows.AirtimeService = (*service)(nil)
+var _ flows.CheckableService = (*service)(nil)
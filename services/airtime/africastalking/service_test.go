@@ -0,0 +1,165 @@
+package africastalking_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/services/airtime/africastalking"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceWithSuccessfulTransfer(t *testing.T) {
+	defer uuids.SetGenerator(uuids.DefaultGenerator)
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	mocks := httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://api.africastalking.com/version1/airtime/send": {
+			httpx.NewMockResponse(201, nil, []byte(sendSuccessResponse)),
+		},
+	})
+
+	uuids.SetGenerator(uuids.NewSeededGenerator(12345))
+	dates.SetNowSource(dates.NewSequentialNowSource(time.Date(2019, 10, 7, 15, 21, 30, 123456789, time.UTC)))
+	httpx.SetRequestor(mocks)
+
+	svc := africastalking.NewService(http.DefaultClient, nil, "myapp", "sesame")
+
+	httpLogger := &flows.HTTPLogger{}
+
+	transfer, err := svc.Transfer(context.Background(),
+		urns.URN("tel:+254711000000"),
+		urns.URN("tel:+254711123456"),
+		map[string]decimal.Decimal{
+			"USD": decimal.RequireFromString("1"),
+			"KES": decimal.RequireFromString("100"),
+		},
+		httpLogger.Log,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, &flows.AirtimeTransfer{
+		UUID:          uuids.UUID("1ae96956-4b34-433e-8d1a-f05fe6923d6d"),
+		Sender:        urns.URN("tel:+254711000000"),
+		Recipient:     urns.URN("tel:+254711123456"),
+		Currency:      "KES",
+		DesiredAmount: decimal.RequireFromString("100"),
+		ActualAmount:  decimal.RequireFromString("100.0000"),
+	}, transfer)
+
+	assert.Equal(t, 1, len(httpLogger.Logs))
+	assert.Equal(t, flows.CallStatusSuccess, httpLogger.Logs[0].Status)
+	assert.NotContains(t, httpLogger.Logs[0].Request, "sesame")
+	assert.Contains(t, httpLogger.Logs[0].Request, "Apikey: ****************")
+
+	assert.False(t, mocks.HasUnused())
+}
+
+func TestServiceFailedTransfers(t *testing.T) {
+	defer uuids.SetGenerator(uuids.DefaultGenerator)
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	mocks := httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://api.africastalking.com/version1/airtime/send": {
+			httpx.MockConnectionError,
+			httpx.NewMockResponse(401, nil, []byte(`The supplied authentication is invalid`)),
+			httpx.NewMockResponse(201, nil, []byte(`{"errorMessage": "None", "numSent": 0, "responses": []}`)),
+			httpx.NewMockResponse(201, nil, []byte(sendFailedResponse)),
+		},
+	})
+
+	uuids.SetGenerator(uuids.NewSeededGenerator(12345))
+	httpx.SetRequestor(mocks)
+
+	svc := africastalking.NewService(http.DefaultClient, nil, "myapp", "sesame")
+
+	httpLogger := &flows.HTTPLogger{}
+	amounts := map[string]decimal.Decimal{"KES": decimal.RequireFromString("100")}
+
+	// try with a number in a country that isn't supported
+	transfer, err := svc.Transfer(context.Background(), urns.URN("tel:+254711000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
+	assert.EqualError(t, err, "unable to send airtime to number +593979123456 in unsupported country")
+	assert.Equal(t, decimal.Zero, transfer.DesiredAmount)
+	assert.Equal(t, decimal.Zero, transfer.ActualAmount)
+
+	// try without an amount in the currency of the recipient's country
+	transfer, err = svc.Transfer(context.Background(), urns.URN("tel:+254711000000"), urns.URN("tel:+256781123456"), amounts, httpLogger.Log)
+	assert.EqualError(t, err, "no amount configured for transfers in UGX")
+	assert.Equal(t, decimal.Zero, transfer.DesiredAmount)
+
+	assert.Equal(t, 0, len(httpLogger.Logs))
+
+	// try when request gives a connection error
+	transfer, err = svc.Transfer(context.Background(), urns.URN("tel:+254711000000"), urns.URN("tel:+254711123456"), amounts, httpLogger.Log)
+	assert.EqualError(t, err, "airtime request failed: unable to connect to server")
+	assert.Equal(t, "KES", transfer.Currency)
+	assert.Equal(t, decimal.RequireFromString("100"), transfer.DesiredAmount)
+	assert.Equal(t, decimal.Zero, transfer.ActualAmount)
+
+	// try when request is rejected
+	_, err = svc.Transfer(context.Background(), urns.URN("tel:+254711000000"), urns.URN("tel:+254711123456"), amounts, httpLogger.Log)
+	assert.EqualError(t, err, "airtime request failed: The supplied authentication is invalid")
+
+	// try when response doesn't include our recipient
+	_, err = svc.Transfer(context.Background(), urns.URN("tel:+254711000000"), urns.URN("tel:+254711123456"), amounts, httpLogger.Log)
+	assert.EqualError(t, err, "airtime request returned no result for number +254711123456")
+
+	// try when transfer to recipient fails
+	transfer, err = svc.Transfer(context.Background(), urns.URN("tel:+254711000000"), urns.URN("tel:+254711123456"), amounts, httpLogger.Log)
+	assert.EqualError(t, err, "airtime transfer to +254711123456 ended with status Failed: Insufficient Credit")
+	assert.Equal(t, decimal.Zero, transfer.ActualAmount)
+
+	assert.Equal(t, 4, len(httpLogger.Logs))
+	assert.Equal(t, flows.CallStatusConnectionError, httpLogger.Logs[0].Status)
+	assert.Equal(t, flows.CallStatusResponseError, httpLogger.Logs[1].Status)
+}
+
+func TestServiceCheck(t *testing.T) {
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://api.africastalking.com/version1/user?username=myapp": {
+			httpx.NewMockResponse(200, nil, []byte(`{"UserData": {"balance": "KES 1785.50"}}`)),
+			httpx.NewMockResponse(200, nil, []byte(`{"UserData": {"balance": "KES 0.00"}}`)),
+			httpx.NewMockResponse(401, nil, []byte(`The supplied authentication is invalid`)),
+		},
+	}))
+
+	svc := africastalking.NewService(http.DefaultClient, nil, "myapp", "sesame").(flows.CheckableService)
+
+	assert.NoError(t, svc.Check(context.Background()))
+	assert.EqualError(t, svc.Check(context.Background()), "no balance available for transfers")
+	assert.EqualError(t, svc.Check(context.Background()), "balance lookup failed: The supplied authentication is invalid")
+}
+
+func TestServiceBalance(t *testing.T) {
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://api.africastalking.com/version1/user?username=myapp": {
+			httpx.NewMockResponse(200, nil, []byte(`{"UserData": {"balance": "KES 1785.50"}}`)),
+			httpx.NewMockResponse(200, nil, []byte(`{"UserData": {"balance": "1785.50"}}`)),
+		},
+	}))
+
+	svc := africastalking.NewService(http.DefaultClient, nil, "myapp", "sesame")
+	httpLogger := &flows.HTTPLogger{}
+
+	balances, err := svc.Balance(context.Background(), httpLogger.Log)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]decimal.Decimal{"KES": decimal.RequireFromString("1785.50")}, balances)
+	assert.Equal(t, 1, len(httpLogger.Logs))
+	assert.Equal(t, "https://api.africastalking.com/version1/user?username=myapp", httpLogger.Logs[0].URL)
+
+	_, err = svc.Balance(context.Background(), httpLogger.Log)
+	assert.EqualError(t, err, "balance lookup failed: invalid amount '1785.50'")
+	assert.Equal(t, 2, len(httpLogger.Logs))
+}
@@ -2,6 +2,7 @@ package dtone
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -74,10 +75,10 @@ type Operator struct {
 }
 
 // LookupMobileNumber see https://dvs-api-doc.dtone.com/#tag/Mobile-Number
-func (c *Client) LookupMobileNumber(tel string) ([]*Operator, *httpx.Trace, error) {
+func (c *Client) LookupMobileNumber(ctx context.Context, tel string) ([]*Operator, *httpx.Trace, error) {
 	var response []*Operator
 
-	trace, err := c.request("GET", fmt.Sprintf("lookup/mobile-number/%s", tel), nil, &response)
+	trace, err := c.request(ctx, "GET", fmt.Sprintf("lookup/mobile-number/%s", tel), nil, &response)
 	if err != nil {
 		return nil, trace, err
 	}
@@ -112,12 +113,12 @@ type Product struct {
 }
 
 // Products see https://dvs-api-doc.dtone.com/#tag/Products
-func (c *Client) Products(_type string, operatorID int) ([]*Product, *httpx.Trace, error) {
+func (c *Client) Products(ctx context.Context, _type string, operatorID int) ([]*Product, *httpx.Trace, error) {
 	var response []*Product
 
 	// TODO endpoint could return more than 100 products in which case we need to page
 
-	trace, err := c.request("GET", fmt.Sprintf("products?type=%s&operator_id=%d&per_page=100", _type, operatorID), nil, &response)
+	trace, err := c.request(ctx, "GET", fmt.Sprintf("products?type=%s&operator_id=%d&per_page=100", _type, operatorID), nil, &response)
 	if err != nil {
 		return nil, trace, err
 	}
@@ -140,10 +141,11 @@ type Transaction struct {
 			Message string    `json:"message"`
 		}
 	} `json:"status"`
+	Balance *Balance `json:"balance"`
 }
 
 // TransactionSync see https://dvs-api-doc.dtone.com/#tag/Transactions
-func (c *Client) TransactionSync(externalID string, productID int, mobileNumber string) (*Transaction, *httpx.Trace, error) {
+func (c *Client) TransactionSync(ctx context.Context, externalID string, productID int, mobileNumber string) (*Transaction, *httpx.Trace, error) {
 	var response *Transaction
 
 	type creditPartyIdentifier struct {
@@ -164,7 +166,7 @@ func (c *Client) TransactionSync(externalID string, productID int, mobileNumber
 		},
 	}
 
-	trace, err := c.request("POST", "sync/transactions", payload, &response)
+	trace, err := c.request(ctx, "POST", "sync/transactions", payload, &response)
 	if err != nil {
 		return nil, trace, err
 	}
@@ -172,7 +174,27 @@ func (c *Client) TransactionSync(externalID string, productID int, mobileNumber
 	return response, trace, nil
 }
 
-func (c *Client) request(method, endpoint string, payload interface{}, response interface{}) (*httpx.Trace, error) {
+// Balance is the balance of an account
+type Balance struct {
+	ID        int             `json:"id"`
+	Available decimal.Decimal `json:"available"`
+	Unit      string          `json:"unit"`
+	UnitType  string          `json:"unit_type"`
+}
+
+// Balances see https://dvs-api-doc.dtone.com/#tag/Balances
+func (c *Client) Balances(ctx context.Context) ([]*Balance, *httpx.Trace, error) {
+	var response []*Balance
+
+	trace, err := c.request(ctx, "GET", "balances", nil, &response)
+	if err != nil {
+		return nil, trace, err
+	}
+
+	return response, trace, nil
+}
+
+func (c *Client) request(ctx context.Context, method, endpoint string, payload interface{}, response interface{}) (*httpx.Trace, error) {
 	url := apiURL + endpoint
 	headers := map[string]string{}
 	var body io.Reader
@@ -191,6 +213,8 @@ func (c *Client) request(method, endpoint string, payload interface{}, response
 		return nil, err
 	}
 
+	req = req.WithContext(ctx)
+
 	req.SetBasicAuth(c.key, c.secret)
 
 	trace, err := httpx.DoTrace(c.httpClient, req, c.httpRetries, nil, -1)
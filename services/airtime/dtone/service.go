@@ -1,6 +1,7 @@
 package dtone
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/nyaruka/gocommon/httpx"
@@ -26,7 +27,7 @@ func NewService(httpClient *http.Client, httpRetries *httpx.RetryConfig, key, se
 	}
 }
 
-func (s *service) Transfer(sender urns.URN, recipient urns.URN, amounts map[string]decimal.Decimal, logHTTP flows.HTTPLogCallback) (*flows.AirtimeTransfer, error) {
+func (s *service) Transfer(ctx context.Context, sender urns.URN, recipient urns.URN, amounts map[string]decimal.Decimal, logHTTP flows.HTTPLogCallback) (*flows.AirtimeTransfer, error) {
 	transfer := &flows.AirtimeTransfer{
 		UUID:          uuids.New(),
 		Sender:        sender,
@@ -35,7 +36,7 @@ func (s *service) Transfer(sender urns.URN, recipient urns.URN, amounts map[stri
 		ActualAmount:  decimal.Zero,
 	}
 
-	operators, trace, err := s.client.LookupMobileNumber(recipient.Path())
+	operators, trace, err := s.client.LookupMobileNumber(ctx, recipient.Path())
 	if trace != nil {
 		logHTTP(flows.NewHTTPLog(trace, flows.HTTPStatusFromCode, s.redactor))
 	}
@@ -56,7 +57,7 @@ func (s *service) Transfer(sender urns.URN, recipient urns.URN, amounts map[stri
 	}
 
 	// fetch available products for this operator
-	products, trace, err := s.client.Products("FIXED_VALUE_RECHARGE", operator.ID)
+	products, trace, err := s.client.Products(ctx, "FIXED_VALUE_RECHARGE", operator.ID)
 	if trace != nil {
 		logHTTP(flows.NewHTTPLog(trace, flows.HTTPStatusFromCode, s.redactor))
 	}
@@ -94,7 +95,7 @@ func (s *service) Transfer(sender urns.URN, recipient urns.URN, amounts map[stri
 	transfer.DesiredAmount = amounts[transfer.Currency]
 
 	// request synchronous confirmed transaction for this product
-	tx, trace, err := s.client.TransactionSync(string(transfer.UUID), product.ID, recipient.Path())
+	tx, trace, err := s.client.TransactionSync(ctx, string(transfer.UUID), product.ID, recipient.Path())
 	if trace != nil {
 		logHTTP(flows.NewHTTPLog(trace, flows.HTTPStatusFromCode, s.redactor))
 	}
@@ -102,6 +103,10 @@ func (s *service) Transfer(sender urns.URN, recipient urns.URN, amounts map[stri
 		return transfer, errors.Wrap(err, "transaction creation failed")
 	}
 
+	if tx.Balance != nil && tx.Balance.UnitType == "CURRENCY" {
+		transfer.Balances = map[string]decimal.Decimal{tx.Balance.Unit: tx.Balance.Available}
+	}
+
 	if tx.Status.Class.ID != StatusCIDConfirmed && tx.Status.Class.ID != StatusCIDSubmitted && tx.Status.Class.ID != StatusCIDCompleted {
 		return transfer, errors.Errorf("transaction to send product %d on operator %d ended with status %s", product.ID, operator.ID, tx.Status.Message)
 	}
@@ -110,3 +115,37 @@ func (s *service) Transfer(sender urns.URN, recipient urns.URN, amounts map[stri
 
 	return transfer, nil
 }
+
+// Check checks that our credentials are valid and that we have a positive balance to make transfers with
+func (s *service) Check(ctx context.Context) error {
+	balances, _, err := s.client.Balances(ctx)
+	if err != nil {
+		return errors.Wrap(err, "balance lookup failed")
+	}
+
+	for _, b := range balances {
+		if b.Available.IsPositive() {
+			return nil
+		}
+	}
+
+	return errors.New("no balance available for transfers")
+}
+
+func (s *service) Balance(ctx context.Context, logHTTP flows.HTTPLogCallback) (map[string]decimal.Decimal, error) {
+	balances, trace, err := s.client.Balances(ctx)
+	if trace != nil {
+		logHTTP(flows.NewHTTPLog(trace, flows.HTTPStatusFromCode, s.redactor))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "balance lookup failed")
+	}
+
+	byCurrency := make(map[string]decimal.Decimal, len(balances))
+	for _, b := range balances {
+		if b.UnitType == "CURRENCY" {
+			byCurrency[b.Unit] = byCurrency[b.Unit].Add(b.Available)
+		}
+	}
+	return byCurrency, nil
+}
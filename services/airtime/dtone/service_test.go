@@ -1,6 +1,7 @@
 package dtone_test
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -47,7 +48,7 @@ func TestServiceWithSuccessfulTranfer(t *testing.T) {
 
 	httpLogger := &flows.HTTPLogger{}
 
-	transfer, err := svc.Transfer(
+	transfer, err := svc.Transfer(context.Background(),
 		urns.URN("tel:+593979000000"),
 		urns.URN("tel:+593979123456"),
 		map[string]decimal.Decimal{
@@ -109,7 +110,7 @@ func TestServiceFailedTransfers(t *testing.T) {
 	amounts := map[string]decimal.Decimal{"USD": decimal.RequireFromString("3.5")}
 
 	// try when phone number lookup gives a connection error
-	transfer, err := svc.Transfer(urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
+	transfer, err := svc.Transfer(context.Background(), urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
 	assert.EqualError(t, err, "number lookup failed: unable to connect to server")
 	assert.Equal(t, urns.URN("tel:+593979000000"), transfer.Sender)
 	assert.Equal(t, urns.URN("tel:+593979123456"), transfer.Recipient)
@@ -117,32 +118,74 @@ func TestServiceFailedTransfers(t *testing.T) {
 	assert.Equal(t, decimal.Zero, transfer.ActualAmount)
 
 	// try when phone number lookup fails
-	transfer, err = svc.Transfer(urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
+	transfer, err = svc.Transfer(context.Background(), urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
 	assert.EqualError(t, err, "number lookup failed: Credit party mobile number is invalid")
 	assert.NotNil(t, transfer)
 
 	// try when phone number lookup returns no matches
-	transfer, err = svc.Transfer(urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
+	transfer, err = svc.Transfer(context.Background(), urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
 	assert.EqualError(t, err, "unable to find operator for number +593979123456")
 	assert.NotNil(t, transfer)
 
 	// try when product fetch fails
-	transfer, err = svc.Transfer(urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
+	transfer, err = svc.Transfer(context.Background(), urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
 	assert.EqualError(t, err, "product fetch failed: Product is not available in your account")
 	assert.NotNil(t, transfer)
 
 	// try when we can't find any suitable products
-	transfer, err = svc.Transfer(urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
+	transfer, err = svc.Transfer(context.Background(), urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
 	assert.EqualError(t, err, "unable to find a suitable product for operator 'Claro Ecuador'")
 	assert.NotNil(t, transfer)
 
 	// try when transaction request errors
-	transfer, err = svc.Transfer(urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
+	transfer, err = svc.Transfer(context.Background(), urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
 	assert.EqualError(t, err, "transaction creation failed: Something went wrong")
 	assert.NotNil(t, transfer)
 
 	// try when transaction is rejected
-	transfer, err = svc.Transfer(urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
+	transfer, err = svc.Transfer(context.Background(), urns.URN("tel:+593979000000"), urns.URN("tel:+593979123456"), amounts, httpLogger.Log)
 	assert.EqualError(t, err, "transaction to send product 6035 on operator 1596 ended with status REJECTED-OPERATOR-CURRENTLY-UNAVAILABLE")
-	assert.NotNil(t, transfer)
+	assert.Equal(t, map[string]decimal.Decimal{"USD": decimal.RequireFromString("2.5")}, transfer.Balances)
+}
+
+func TestServiceCheck(t *testing.T) {
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://dvs-api.dtone.com/v1/balances": {
+			httpx.NewMockResponse(200, nil, []byte(`[{"id": 1, "available": 0, "unit": "USD", "unit_type": "CURRENCY"}, {"id": 2, "available": 12.5, "unit": "EUR", "unit_type": "CURRENCY"}]`)),
+			httpx.NewMockResponse(200, nil, []byte(`[{"id": 1, "available": 0, "unit": "USD", "unit_type": "CURRENCY"}]`)),
+			httpx.NewMockResponse(401, nil, errorResp(1000401, "Unauthorized")),
+		},
+	}))
+
+	svc := dtone.NewService(http.DefaultClient, nil, "key123", "sesame").(flows.CheckableService)
+
+	assert.NoError(t, svc.Check(context.Background()))
+	assert.EqualError(t, svc.Check(context.Background()), "no balance available for transfers")
+	assert.EqualError(t, svc.Check(context.Background()), "balance lookup failed: Unauthorized")
+}
+
+func TestServiceBalance(t *testing.T) {
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://dvs-api.dtone.com/v1/balances": {
+			httpx.NewMockResponse(200, nil, []byte(`[{"id": 1, "available": 12.5, "unit": "USD", "unit_type": "CURRENCY"}, {"id": 2, "available": 3, "unit": "USD", "unit_type": "CURRENCY"}, {"id": 3, "available": 100, "unit": "GB", "unit_type": "DATA"}]`)),
+			httpx.NewMockResponse(401, nil, errorResp(1000401, "Unauthorized")),
+		},
+	}))
+
+	svc := dtone.NewService(http.DefaultClient, nil, "key123", "sesame")
+	httpLogger := &flows.HTTPLogger{}
+
+	balances, err := svc.Balance(context.Background(), httpLogger.Log)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]decimal.Decimal{"USD": decimal.RequireFromString("15.5")}, balances)
+	assert.Equal(t, 1, len(httpLogger.Logs))
+	assert.Equal(t, "https://dvs-api.dtone.com/v1/balances", httpLogger.Logs[0].URL)
+
+	_, err = svc.Balance(context.Background(), httpLogger.Log)
+	assert.EqualError(t, err, "balance lookup failed: Unauthorized")
+	assert.Equal(t, 2, len(httpLogger.Logs))
 }
@@ -0,0 +1,209 @@
+// Package chaos provides decorators for engine services which inject failures, e.g. timeouts, server errors, malformed
+// responses and slow responses, so that flows can be checked to degrade gracefully in tests and staging environments.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/goflow/flows"
+)
+
+// Fault is a type of failure which can be injected
+type Fault string
+
+// fault types
+const (
+	FaultTimeout     Fault = "timeout"
+	FaultServerError Fault = "server_error"
+	FaultMalformed   Fault = "malformed"
+	FaultSlow        Fault = "slow"
+)
+
+var allFaults = []Fault{FaultTimeout, FaultServerError, FaultMalformed, FaultSlow}
+
+// Window is a period of time during which faults are injected
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Config is the configuration of an injector, i.e. how often faults are injected, which faults and into which services
+type Config struct {
+	Seed     int64               `json:"seed"`
+	Rate     float64             `json:"rate"`               // probability of a call failing, from 0 to 1
+	Faults   []Fault             `json:"faults,omitempty"`   // faults to choose from, all if empty
+	Services []flows.ServiceType `json:"services,omitempty"` // services to inject faults into, all if empty
+	Schedule []*Window           `json:"schedule,omitempty"` // when to inject faults, always if empty
+	Delay    time.Duration       `json:"delay"`              // how long slow responses are delayed
+}
+
+// Injection is a record of a fault which was injected into a call to a service
+type Injection struct {
+	Service flows.ServiceType
+	Fault   Fault
+}
+
+// FaultError is the error returned by a call to a service into which a fault was injected
+type FaultError struct {
+	Service flows.ServiceType
+	Fault   Fault
+}
+
+func (e *FaultError) Error() string {
+	return fmt.Sprintf("injected %s fault in %s service", e.Fault, e.Service)
+}
+
+// Unwrap allows injected timeouts to be treated like real ones
+func (e *FaultError) Unwrap() error {
+	if e.Fault == FaultTimeout {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+// Injector decides which calls to services fail. Decisions are made by a random number generator seeded from the
+// config so that a sequence of calls always fails in the same way.
+type Injector struct {
+	config   *Config
+	faults   []Fault
+	services map[flows.ServiceType]bool
+
+	mutex    sync.Mutex
+	rand     *rand.Rand
+	injected []*Injection
+}
+
+// NewInjector creates a new injector with the given config
+func NewInjector(config *Config) *Injector {
+	faults := config.Faults
+	if len(faults) == 0 {
+		faults = allFaults
+	}
+
+	services := make(map[flows.ServiceType]bool, len(config.Services))
+	for _, s := range config.Services {
+		services[s] = true
+	}
+
+	return &Injector{
+		config:   config,
+		faults:   faults,
+		services: services,
+		rand:     rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// Injected returns the faults injected so far
+func (i *Injector) Injected() []*Injection {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	return append([]*Injection(nil), i.injected...)
+}
+
+// decides the fault to inject into a call to the given service, if any
+func (i *Injector) next(service flows.ServiceType) Fault {
+	if len(i.services) > 0 && !i.services[service] {
+		return ""
+	}
+	if !i.scheduled(dates.Now()) {
+		return ""
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.rand.Float64() >= i.config.Rate {
+		return ""
+	}
+
+	fault := i.faults[i.rand.Intn(len(i.faults))]
+	i.injected = append(i.injected, &Injection{Service: service, Fault: fault})
+	return fault
+}
+
+func (i *Injector) scheduled(now time.Time) bool {
+	if len(i.config.Schedule) == 0 {
+		return true
+	}
+	for _, w := range i.config.Schedule {
+		if !now.Before(w.Start) && now.Before(w.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// injects a fault into a call to the given service, returning an error if the call should fail. Slow faults only
+// delay the call, unless the context is done first.
+func (i *Injector) inject(ctx context.Context, service flows.ServiceType) error {
+	fault := i.next(service)
+	switch fault {
+	case "":
+		return nil
+	case FaultSlow:
+		return i.delay(ctx)
+	default:
+		return &FaultError{Service: service, Fault: fault}
+	}
+}
+
+func (i *Injector) delay(ctx context.Context) error {
+	select {
+	case <-time.After(i.config.Delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Transport returns an HTTP transport which injects faults into the requests made through the given transport, for
+// use with the HTTP client of the webhook service. Server errors and malformed responses are returned as actual
+// responses so they're handled the same as real ones.
+func (i *Injector) Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{injector: i, base: base}
+}
+
+type transport struct {
+	injector *Injector
+	base     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	switch t.injector.next(flows.ServiceTypeWebhook) {
+	case FaultTimeout:
+		return nil, &FaultError{Service: flows.ServiceTypeWebhook, Fault: FaultTimeout}
+	case FaultServerError:
+		return newResponse(request, http.StatusServiceUnavailable, `{"error": "service unavailable"}`), nil
+	case FaultMalformed:
+		return newResponse(request, http.StatusOK, `{"status": "ok", "results": [{"id": 1`), nil
+	case FaultSlow:
+		if err := t.injector.delay(request.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.base.RoundTrip(request)
+}
+
+func newResponse(request *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+}
@@ -0,0 +1,134 @@
+package chaos_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/services/chaos"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type exchangeRateService struct{}
+
+func (s *exchangeRateService) Rate(ctx context.Context, from, to string, logHTTP flows.HTTPLogCallback) (decimal.Decimal, error) {
+	return decimal.RequireFromString("1.5"), nil
+}
+
+func exchangeRateFactory(flows.SessionAssets) (flows.ExchangeRateService, error) {
+	return &exchangeRateService{}, nil
+}
+
+// calls the given service n times and returns the error of each call
+func callRates(t *testing.T, svc flows.ExchangeRateService, n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		_, errs[i] = svc.Rate(context.Background(), "USD", "RWF", nil)
+	}
+	return errs
+}
+
+func TestInjector(t *testing.T) {
+	config := &chaos.Config{Seed: 123, Rate: 0.5, Faults: []chaos.Fault{chaos.FaultTimeout, chaos.FaultServerError}}
+
+	injector := chaos.NewInjector(config)
+	svc, err := injector.ExchangeRateServiceFactory(exchangeRateFactory)(nil)
+	require.NoError(t, err)
+
+	errs := callRates(t, svc, 20)
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+
+			var faultErr *chaos.FaultError
+			require.True(t, errors.As(err, &faultErr))
+			assert.Equal(t, flows.ServiceTypeExchangeRate, faultErr.Service)
+			assert.Equal(t, faultErr.Fault == chaos.FaultTimeout, errors.Is(err, context.DeadlineExceeded))
+		}
+	}
+	assert.Greater(t, failed, 0)
+	assert.Less(t, failed, 20)
+	assert.Equal(t, failed, len(injector.Injected()))
+
+	// same seed gives the same failures
+	svc2, _ := chaos.NewInjector(config).ExchangeRateServiceFactory(exchangeRateFactory)(nil)
+	assert.Equal(t, errs, callRates(t, svc2, 20))
+
+	// no faults injected into services which aren't configured
+	injector = chaos.NewInjector(&chaos.Config{Seed: 123, Rate: 1, Services: []flows.ServiceType{flows.ServiceTypeWebhook}})
+	svc, _ = injector.ExchangeRateServiceFactory(exchangeRateFactory)(nil)
+	assert.Equal(t, []error{nil, nil, nil}, callRates(t, svc, 3))
+
+	// or outside of the schedule
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	dates.SetNowSource(dates.NewFixedNowSource(time.Date(2018, 10, 18, 14, 20, 30, 0, time.UTC)))
+
+	window := &chaos.Window{Start: time.Date(2018, 10, 18, 14, 0, 0, 0, time.UTC), End: time.Date(2018, 10, 18, 15, 0, 0, 0, time.UTC)}
+	injector = chaos.NewInjector(&chaos.Config{Seed: 123, Rate: 1, Faults: []chaos.Fault{chaos.FaultServerError}, Schedule: []*chaos.Window{window}})
+	svc, _ = injector.ExchangeRateServiceFactory(exchangeRateFactory)(nil)
+	assert.EqualError(t, callRates(t, svc, 1)[0], "injected server_error fault in exchange_rate service")
+
+	dates.SetNowSource(dates.NewFixedNowSource(time.Date(2018, 10, 18, 15, 20, 30, 0, time.UTC)))
+	assert.NoError(t, callRates(t, svc, 1)[0])
+
+	// slow faults delay calls but don't fail them unless the context is done first
+	injector = chaos.NewInjector(&chaos.Config{Seed: 123, Rate: 1, Faults: []chaos.Fault{chaos.FaultSlow}, Delay: time.Millisecond * 10})
+	svc, _ = injector.ExchangeRateServiceFactory(exchangeRateFactory)(nil)
+	rate, err := svc.Rate(context.Background(), "USD", "RWF", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.5", rate.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = svc.Rate(ctx, "USD", "RWF", nil)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	call := func(fault chaos.Fault) (*http.Response, string, error) {
+		injector := chaos.NewInjector(&chaos.Config{Rate: 1, Faults: []chaos.Fault{fault}, Delay: time.Millisecond})
+		client := &http.Client{Transport: injector.Transport(http.DefaultTransport)}
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body), nil
+	}
+
+	_, _, err := call(chaos.FaultTimeout)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	resp, body, err := call(chaos.FaultServerError)
+	assert.NoError(t, err)
+	assert.Equal(t, 503, resp.StatusCode)
+	assert.Equal(t, `{"error": "service unavailable"}`, body)
+
+	resp, body, err = call(chaos.FaultMalformed)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, `{"status": "ok", "results": [{"id": 1`, body)
+
+	resp, body, err = call(chaos.FaultSlow)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, `{"ok": true}`, body)
+}
@@ -0,0 +1,309 @@
+package chaos
+
+import (
+	"context"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+// EmailServiceFactory wraps the given factory so its services have faults injected
+func (i *Injector) EmailServiceFactory(f engine.EmailServiceFactory) engine.EmailServiceFactory {
+	return func(sa flows.SessionAssets) (flows.EmailService, error) {
+		s, err := f(sa)
+		if err != nil || s == nil {
+			return s, err
+		}
+		if rich, isRich := s.(flows.RichEmailService); isRich {
+			return &richEmailService{emailService{i, rich}, rich}, nil
+		}
+		return &emailService{i, s}, nil
+	}
+}
+
+// ClassificationServiceFactory wraps the given factory so its services have faults injected
+func (i *Injector) ClassificationServiceFactory(f engine.ClassificationServiceFactory) engine.ClassificationServiceFactory {
+	return func(c *flows.Classifier) (flows.ClassificationService, error) {
+		s, err := f(c)
+		if err != nil || s == nil {
+			return s, err
+		}
+		return &classificationService{i, s}, nil
+	}
+}
+
+// TicketServiceFactory wraps the given factory so its services have faults injected
+func (i *Injector) TicketServiceFactory(f engine.TicketServiceFactory) engine.TicketServiceFactory {
+	return func(t *flows.Ticketer) (flows.TicketService, error) {
+		s, err := f(t)
+		if err != nil || s == nil {
+			return s, err
+		}
+		return &ticketService{i, s}, nil
+	}
+}
+
+// AirtimeServiceFactory wraps the given factory so its services have faults injected
+func (i *Injector) AirtimeServiceFactory(f engine.AirtimeServiceFactory) engine.AirtimeServiceFactory {
+	return func(sa flows.SessionAssets) (flows.AirtimeService, error) {
+		s, err := f(sa)
+		if err != nil || s == nil {
+			return s, err
+		}
+		return &airtimeService{i, s}, nil
+	}
+}
+
+// DataCollectionServiceFactory wraps the given factory so its services have faults injected
+func (i *Injector) DataCollectionServiceFactory(f engine.DataCollectionServiceFactory) engine.DataCollectionServiceFactory {
+	return func(sa flows.SessionAssets) (flows.DataCollectionService, error) {
+		s, err := f(sa)
+		if err != nil || s == nil {
+			return s, err
+		}
+		return &dataCollectionService{i, s}, nil
+	}
+}
+
+// CommerceServiceFactory wraps the given factory so its services have faults injected
+func (i *Injector) CommerceServiceFactory(f engine.CommerceServiceFactory) engine.CommerceServiceFactory {
+	return func(sa flows.SessionAssets) (flows.CommerceService, error) {
+		s, err := f(sa)
+		if err != nil || s == nil {
+			return s, err
+		}
+		return &commerceService{i, s}, nil
+	}
+}
+
+// CallRecordingServiceFactory wraps the given factory so its services have faults injected
+func (i *Injector) CallRecordingServiceFactory(f engine.CallRecordingServiceFactory) engine.CallRecordingServiceFactory {
+	return func(sa flows.SessionAssets) (flows.CallRecordingService, error) {
+		s, err := f(sa)
+		if err != nil || s == nil {
+			return s, err
+		}
+		return &callRecordingService{i, s}, nil
+	}
+}
+
+// CallTransferServiceFactory wraps the given factory so its services have faults injected
+func (i *Injector) CallTransferServiceFactory(f engine.CallTransferServiceFactory) engine.CallTransferServiceFactory {
+	return func(sa flows.SessionAssets) (flows.CallTransferService, error) {
+		s, err := f(sa)
+		if err != nil || s == nil {
+			return s, err
+		}
+		return &callTransferService{i, s}, nil
+	}
+}
+
+// CredentialServiceFactory wraps the given factory so its services have faults injected
+func (i *Injector) CredentialServiceFactory(f engine.CredentialServiceFactory) engine.CredentialServiceFactory {
+	return func(sa flows.SessionAssets) (flows.CredentialService, error) {
+		s, err := f(sa)
+		if err != nil || s == nil {
+			return s, err
+		}
+		return &credentialService{i, s}, nil
+	}
+}
+
+// ExchangeRateServiceFactory wraps the given factory so its services have faults injected
+func (i *Injector) ExchangeRateServiceFactory(f engine.ExchangeRateServiceFactory) engine.ExchangeRateServiceFactory {
+	return func(sa flows.SessionAssets) (flows.ExchangeRateService, error) {
+		s, err := f(sa)
+		if err != nil || s == nil {
+			return s, err
+		}
+		return &exchangeRateService{i, s}, nil
+	}
+}
+
+// AttachmentServiceFactory wraps the given factory so its services have faults injected
+func (i *Injector) AttachmentServiceFactory(f engine.AttachmentServiceFactory) engine.AttachmentServiceFactory {
+	return func(sa flows.SessionAssets) (flows.AttachmentService, error) {
+		s, err := f(sa)
+		if err != nil || s == nil {
+			return s, err
+		}
+		return &attachmentService{i, s}, nil
+	}
+}
+
+type emailService struct {
+	injector *Injector
+	base     flows.EmailService
+}
+
+func (s *emailService) Send(ctx context.Context, addresses []string, subject, body string) error {
+	if err := s.injector.inject(ctx, flows.ServiceTypeEmail); err != nil {
+		return err
+	}
+	return s.base.Send(ctx, addresses, subject, body)
+}
+
+type richEmailService struct {
+	emailService
+	rich flows.RichEmailService
+}
+
+func (s *richEmailService) SendEmail(ctx context.Context, email *flows.Email) error {
+	if err := s.injector.inject(ctx, flows.ServiceTypeEmail); err != nil {
+		return err
+	}
+	return s.rich.SendEmail(ctx, email)
+}
+
+type classificationService struct {
+	injector *Injector
+	base     flows.ClassificationService
+}
+
+func (s *classificationService) Classify(ctx context.Context, env envs.Environment, input string, logHTTP flows.HTTPLogCallback) (*flows.Classification, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeClassification); err != nil {
+		return nil, err
+	}
+	return s.base.Classify(ctx, env, input, logHTTP)
+}
+
+type ticketService struct {
+	injector *Injector
+	base     flows.TicketService
+}
+
+func (s *ticketService) Open(ctx context.Context, env envs.Environment, contact *flows.Contact, topic *flows.Topic, body string, assignee *flows.User, logHTTP flows.HTTPLogCallback) (*flows.Ticket, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeTicket); err != nil {
+		return nil, err
+	}
+	return s.base.Open(ctx, env, contact, topic, body, assignee, logHTTP)
+}
+
+type airtimeService struct {
+	injector *Injector
+	base     flows.AirtimeService
+}
+
+func (s *airtimeService) Transfer(ctx context.Context, sender urns.URN, recipient urns.URN, amounts map[string]decimal.Decimal, logHTTP flows.HTTPLogCallback) (*flows.AirtimeTransfer, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeAirtime); err != nil {
+		return nil, err
+	}
+	return s.base.Transfer(ctx, sender, recipient, amounts, logHTTP)
+}
+
+func (s *airtimeService) Balance(ctx context.Context, logHTTP flows.HTTPLogCallback) (map[string]decimal.Decimal, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeAirtime); err != nil {
+		return nil, err
+	}
+	return s.base.Balance(ctx, logHTTP)
+}
+
+type dataCollectionService struct {
+	injector *Injector
+	base     flows.DataCollectionService
+}
+
+func (s *dataCollectionService) Query(ctx context.Context, env envs.Environment, collection string, query *flows.DataQuery, logHTTP flows.HTTPLogCallback) (*flows.DataPage, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeDataCollection); err != nil {
+		return nil, err
+	}
+	return s.base.Query(ctx, env, collection, query, logHTTP)
+}
+
+type commerceService struct {
+	injector *Injector
+	base     flows.CommerceService
+}
+
+func (s *commerceService) LookupProduct(ctx context.Context, env envs.Environment, productID string, logHTTP flows.HTTPLogCallback) (*flows.Product, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeCommerce); err != nil {
+		return nil, err
+	}
+	return s.base.LookupProduct(ctx, env, productID, logHTTP)
+}
+
+func (s *commerceService) PlaceOrder(ctx context.Context, env envs.Environment, contact *flows.Contact, order *flows.Order, logHTTP flows.HTTPLogCallback) (string, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeCommerce); err != nil {
+		return "", err
+	}
+	return s.base.PlaceOrder(ctx, env, contact, order, logHTTP)
+}
+
+type callRecordingService struct {
+	injector *Injector
+	base     flows.CallRecordingService
+}
+
+func (s *callRecordingService) StartRecording(ctx context.Context, call *flows.Call) error {
+	if err := s.injector.inject(ctx, flows.ServiceTypeCallRecording); err != nil {
+		return err
+	}
+	return s.base.StartRecording(ctx, call)
+}
+
+func (s *callRecordingService) StopRecording(ctx context.Context, call *flows.Call) (string, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeCallRecording); err != nil {
+		return "", err
+	}
+	return s.base.StopRecording(ctx, call)
+}
+
+type callTransferService struct {
+	injector *Injector
+	base     flows.CallTransferService
+}
+
+func (s *callTransferService) Forward(ctx context.Context, call *flows.Call, urn urns.URN) (*flows.CallTransfer, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeCallTransfer); err != nil {
+		return nil, err
+	}
+	return s.base.Forward(ctx, call, urn)
+}
+
+func (s *callTransferService) JoinConference(ctx context.Context, call *flows.Call, conference string) (*flows.CallTransfer, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeCallTransfer); err != nil {
+		return nil, err
+	}
+	return s.base.JoinConference(ctx, call, conference)
+}
+
+type credentialService struct {
+	injector *Injector
+	base     flows.CredentialService
+}
+
+func (s *credentialService) Authorization(ctx context.Context, name string) (string, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeCredential); err != nil {
+		return "", err
+	}
+	return s.base.Authorization(ctx, name)
+}
+
+type exchangeRateService struct {
+	injector *Injector
+	base     flows.ExchangeRateService
+}
+
+func (s *exchangeRateService) Rate(ctx context.Context, from, to string, logHTTP flows.HTTPLogCallback) (decimal.Decimal, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeExchangeRate); err != nil {
+		return decimal.Zero, err
+	}
+	return s.base.Rate(ctx, from, to, logHTTP)
+}
+
+type attachmentService struct {
+	injector *Injector
+	base     flows.AttachmentService
+}
+
+func (s *attachmentService) Process(ctx context.Context, attachment utils.Attachment) (utils.Attachment, error) {
+	if err := s.injector.inject(ctx, flows.ServiceTypeAttachment); err != nil {
+		return "", err
+	}
+	return s.base.Process(ctx, attachment)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/flows"
+)
+
+// CheckStatus is the result of checking a service
+type CheckStatus string
+
+// possible values for check statuses
+const (
+	CheckStatusOK          CheckStatus = "ok"          // service is usable
+	CheckStatusFailed      CheckStatus = "failed"      // service was checked and isn't usable
+	CheckStatusUnchecked   CheckStatus = "unchecked"   // service doesn't support checking
+	CheckStatusUnavailable CheckStatus = "unavailable" // no service is configured
+)
+
+// Result is the result of checking a single service
+type Result struct {
+	Service    flows.ServiceType           `json:"service"`
+	Classifier *assets.ClassifierReference `json:"classifier,omitempty"`
+	Ticketer   *assets.TicketerReference   `json:"ticketer,omitempty"`
+	Status     CheckStatus                 `json:"status"`
+	Message    string                      `json:"message,omitempty"`
+}
+
+// Check checks each of the services configured for the given session assets, including those of each classifier and
+// ticketer, so that a hosting system can tell users about problems like expired tokens before their flows fail
+func Check(ctx context.Context, svcs flows.Services, sa flows.SessionAssets) []*Result {
+	checks := []*Result{
+		check(ctx, flows.ServiceTypeEmail, wrap(svcs.Email(sa))),
+		check(ctx, flows.ServiceTypeWebhook, wrap(svcs.Webhook(sa))),
+		check(ctx, flows.ServiceTypeAirtime, wrap(svcs.Airtime(sa))),
+		check(ctx, flows.ServiceTypeDataCollection, wrap(svcs.DataCollection(sa))),
+		check(ctx, flows.ServiceTypeCommerce, wrap(svcs.Commerce(sa))),
+		check(ctx, flows.ServiceTypeCallRecording, wrap(svcs.CallRecording(sa))),
+		check(ctx, flows.ServiceTypeCallTransfer, wrap(svcs.CallTransfer(sa))),
+		check(ctx, flows.ServiceTypeCredential, wrap(svcs.Credential(sa))),
+		check(ctx, flows.ServiceTypeExchangeRate, wrap(svcs.ExchangeRate(sa))),
+	}
+
+	for _, classifier := range sa.Classifiers().All() {
+		c := check(ctx, flows.ServiceTypeClassification, wrap(svcs.Classification(classifier)))
+		c.Classifier = classifier.Reference()
+		checks = append(checks, c)
+	}
+
+	for _, ticketer := range sa.Ticketers().All() {
+		c := check(ctx, flows.ServiceTypeTicket, wrap(svcs.Ticket(ticketer)))
+		c.Ticketer = ticketer.Reference()
+		checks = append(checks, c)
+	}
+
+	return checks
+}
+
+// the result of a service factory, converted so that factories of different service types can be handled the same
+type resolved struct {
+	service any
+	err     error
+}
+
+func wrap[S any](svc S, err error) resolved {
+	return resolved{service: svc, err: err}
+}
+
+func check(ctx context.Context, service flows.ServiceType, r resolved) *Result {
+	c := &Result{Service: service}
+
+	if r.err != nil {
+		c.Status = CheckStatusUnavailable
+		c.Message = r.err.Error()
+		return c
+	}
+
+	checkable, ok := r.service.(flows.CheckableService)
+	if !ok {
+		c.Status = CheckStatusUnchecked
+		return c
+	}
+
+	if err := checkable.Check(ctx); err != nil {
+		c.Status = CheckStatusFailed
+		c.Message = err.Error()
+	} else {
+		c.Status = CheckStatusOK
+	}
+	return c
+}
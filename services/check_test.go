@@ -0,0 +1,95 @@
+package services_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/services"
+	"github.com/nyaruka/goflow/services/airtime/dtone"
+	"github.com/nyaruka/goflow/services/classification/wit"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://api.wit.ai/intents?v=20200513": {
+			httpx.NewMockResponse(401, nil, []byte(`{"error": "Bad auth, check token/params", "code": "no-auth"}`)),
+		},
+		"https://dvs-api.dtone.com/v1/balances": {
+			httpx.NewMockResponse(200, nil, []byte(`[{"id": 1, "available": 12.5, "unit": "USD", "unit_type": "CURRENCY"}]`)),
+		},
+	}))
+
+	source, err := static.NewSource([]byte(`{
+		"classifiers": [
+			{"uuid": "1c06c884-39dd-4ce4-ad9f-9a01cbe6c000", "name": "Booking", "type": "wit", "intents": ["book_flight"]},
+			{"uuid": "2da59ed7-2d52-4b83-a5c2-b2d2c2c84d0b", "name": "Archived", "type": "luis", "intents": []}
+		],
+		"ticketers": [
+			{"uuid": "d605bb96-258d-4097-ad0a-080937db2212", "name": "Support Tickets", "type": "mailgun"}
+		]
+	}`))
+	require.NoError(t, err)
+
+	sa, err := engine.NewSessionAssets(envs.NewBuilder().Build(), source, nil)
+	require.NoError(t, err)
+
+	eng := engine.NewBuilder().
+		WithAirtimeServiceFactory(func(flows.SessionAssets) (flows.AirtimeService, error) {
+			return dtone.NewService(http.DefaultClient, nil, "key123", "sesame"), nil
+		}).
+		WithClassificationServiceFactory(func(c *flows.Classifier) (flows.ClassificationService, error) {
+			if c.Type() == "wit" {
+				return wit.NewService(http.DefaultClient, nil, c, "123456789"), nil
+			}
+			return nil, errors.Errorf("no classification service available for %s", c.Reference())
+		}).
+		WithTicketServiceFactory(func(t *flows.Ticketer) (flows.TicketService, error) {
+			return test.NewTicketService(t), nil
+		}).
+		Build()
+
+	results := services.Check(context.Background(), eng.Services(), sa)
+
+	assert.Equal(t, []*services.Result{
+		{Service: flows.ServiceTypeEmail, Status: services.CheckStatusUnavailable, Message: "no email service factory configured"},
+		{Service: flows.ServiceTypeWebhook, Status: services.CheckStatusUnavailable, Message: "no webhook service factory configured"},
+		{Service: flows.ServiceTypeAirtime, Status: services.CheckStatusOK},
+		{Service: flows.ServiceTypeDataCollection, Status: services.CheckStatusUnavailable, Message: "no data collection service factory configured"},
+		{Service: flows.ServiceTypeCommerce, Status: services.CheckStatusUnavailable, Message: "no commerce service factory configured"},
+		{Service: flows.ServiceTypeCallRecording, Status: services.CheckStatusUnavailable, Message: "no call recording service factory configured"},
+		{Service: flows.ServiceTypeCallTransfer, Status: services.CheckStatusUnavailable, Message: "no call transfer service factory configured"},
+		{Service: flows.ServiceTypeCredential, Status: services.CheckStatusUnavailable, Message: "no credential service factory configured"},
+		{Service: flows.ServiceTypeExchangeRate, Status: services.CheckStatusUnavailable, Message: "no exchange rate service factory configured"},
+		{
+			Service:    flows.ServiceTypeClassification,
+			Classifier: assets.NewClassifierReference("2da59ed7-2d52-4b83-a5c2-b2d2c2c84d0b", "Archived"),
+			Status:     services.CheckStatusUnavailable,
+			Message:    "no classification service available for classifier[uuid=2da59ed7-2d52-4b83-a5c2-b2d2c2c84d0b,name=Archived]",
+		},
+		{
+			Service:    flows.ServiceTypeClassification,
+			Classifier: assets.NewClassifierReference("1c06c884-39dd-4ce4-ad9f-9a01cbe6c000", "Booking"),
+			Status:     services.CheckStatusFailed,
+			Message:    "wit.ai access token is not valid",
+		},
+		{
+			Service:  flows.ServiceTypeTicket,
+			Ticketer: assets.NewTicketerReference("d605bb96-258d-4097-ad0a-080937db2212", "Support Tickets"),
+			Status:   services.CheckStatusUnchecked,
+		},
+	}, results)
+}
@@ -1,6 +1,7 @@
 package bothub
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -56,7 +57,7 @@ func NewClient(httpClient *http.Client, httpRetries *httpx.RetryConfig, accessTo
 }
 
 // Parse does a parse of the given text in the given language (e.g. pt_br)
-func (c *Client) Parse(text, language string) (*ParseResponse, *httpx.Trace, error) {
+func (c *Client) Parse(ctx context.Context, text, language string) (*ParseResponse, *httpx.Trace, error) {
 	endpoint := fmt.Sprintf("%s/parse", apiBaseURL)
 
 	form := url.Values{}
@@ -75,6 +76,8 @@ func (c *Client) Parse(text, language string) (*ParseResponse, *httpx.Trace, err
 		return nil, nil, err
 	}
 
+	request = request.WithContext(ctx)
+
 	trace, err := httpx.DoTrace(c.httpClient, request, c.httpRetries, nil, -1)
 	if err != nil {
 		return nil, trace, err
@@ -1,15 +1,19 @@
 package bothub
 
 import (
+	"context"
 	"net/http"
-	"strings"
 
 	"github.com/nyaruka/gocommon/httpx"
 	"github.com/nyaruka/gocommon/stringsx"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/services/classification"
 )
 
+// bothub names locales like en_us
+var localeFormat = classification.LocaleFormat{Separator: "_", Lowercase: true}
+
 // a classification service implementation for a bothub.it bot
 type service struct {
 	client     *Client
@@ -26,10 +30,8 @@ func NewService(httpClient *http.Client, httpRetries *httpx.RetryConfig, classif
 	}
 }
 
-func (s *service) Classify(env envs.Environment, input string, logHTTP flows.HTTPLogCallback) (*flows.Classification, error) {
-	localeStr := strings.ReplaceAll(strings.ToLower(env.DefaultLocale().ToBCP47()), "-", "_") // en-US -> en_us
-
-	response, trace, err := s.client.Parse(input, localeStr)
+func (s *service) Classify(ctx context.Context, env envs.Environment, input string, logHTTP flows.HTTPLogCallback) (*flows.Classification, error) {
+	response, trace, err := s.client.Parse(ctx, input, localeFormat.Format(env.DefaultLocale()))
 	if trace != nil {
 		logHTTP(flows.NewHTTPLog(trace, flows.HTTPStatusFromCode, s.redactor))
 	}
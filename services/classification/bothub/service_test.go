@@ -1,6 +1,7 @@
 package bothub_test
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -73,7 +74,7 @@ func TestService(t *testing.T) {
 	env := envs.NewBuilder().WithAllowedLanguages([]envs.Language{"spa"}).WithDefaultCountry("US").Build()
 	httpLogger := &flows.HTTPLogger{}
 
-	classification, err := svc.Classify(env, "book my flight to Quito", httpLogger.Log)
+	classification, err := svc.Classify(context.Background(), env, "book my flight to Quito", httpLogger.Log)
 	assert.NoError(t, err)
 	assert.Equal(t, []flows.ExtractedIntent{
 		{Name: "book_flight", Confidence: decimal.RequireFromString(`0.9224673593230207`)},
@@ -0,0 +1,130 @@
+package dialogflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Intent is an intent defined in the agent
+type Intent struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// Match is the result of matching a query against the agent
+type Match struct {
+	Intent     *Intent         `json:"intent"`
+	Confidence decimal.Decimal `json:"confidence"`
+	MatchType  string          `json:"matchType"`
+}
+
+// QueryResult is the result of a conversational query
+type QueryResult struct {
+	Text         string                     `json:"text"`
+	LanguageCode string                     `json:"languageCode"`
+	Parameters   map[string]json.RawMessage `json:"parameters"`
+	Match        *Match                     `json:"match" validate:"required"`
+}
+
+// DetectIntentResponse is the response from a detectIntent request
+type DetectIntentResponse struct {
+	ResponseID  string       `json:"responseId"`
+	QueryResult *QueryResult `json:"queryResult" validate:"required"`
+}
+
+type textInput struct {
+	Text string `json:"text"`
+}
+
+type queryInput struct {
+	Text         textInput `json:"text"`
+	LanguageCode string    `json:"languageCode,omitempty"`
+}
+
+type detectIntentRequest struct {
+	QueryInput queryInput `json:"queryInput"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// Client is a basic DialogFlow CX client
+type Client struct {
+	httpClient  *http.Client
+	httpRetries *httpx.RetryConfig
+	baseURL     string
+	agentPath   string
+	accessToken string
+}
+
+// NewClient creates a new client for the given agent
+func NewClient(httpClient *http.Client, httpRetries *httpx.RetryConfig, projectID, location, agentID, accessToken string) *Client {
+	// agents outside of the global location have regional endpoints
+	baseURL := "https://dialogflow.googleapis.com"
+	if location != "global" {
+		baseURL = fmt.Sprintf("https://%s-dialogflow.googleapis.com", location)
+	}
+
+	return &Client{
+		httpClient:  httpClient,
+		httpRetries: httpRetries,
+		baseURL:     baseURL,
+		agentPath:   fmt.Sprintf("projects/%s/locations/%s/agents/%s", projectID, location, agentID),
+		accessToken: accessToken,
+	}
+}
+
+// DetectIntent matches the given text against the intents of the agent in the given language (e.g. en, pt-br)
+func (c *Client) DetectIntent(ctx context.Context, sessionID, text, languageCode string) (*DetectIntentResponse, *httpx.Trace, error) {
+	endpoint := fmt.Sprintf("%s/v3/%s/sessions/%s:detectIntent", c.baseURL, c.agentPath, sessionID)
+
+	payload := &detectIntentRequest{QueryInput: queryInput{Text: textInput{Text: text}, LanguageCode: languageCode}}
+	body := jsonx.MustMarshal(payload)
+
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": fmt.Sprintf("Bearer %s", c.accessToken),
+	}
+
+	request, err := httpx.NewRequest("POST", endpoint, bytes.NewReader(body), headers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request = request.WithContext(ctx)
+
+	trace, err := httpx.DoTrace(c.httpClient, request, c.httpRetries, nil, -1)
+	if err != nil {
+		return nil, trace, err
+	}
+
+	if trace.Response != nil && trace.Response.StatusCode == 200 {
+		response := &DetectIntentResponse{}
+		if err := utils.UnmarshalAndValidate(trace.ResponseBody, response); err != nil {
+			return nil, trace, err
+		}
+		return response, trace, nil
+	}
+
+	errResp := &errorResponse{}
+	if err := jsonx.Unmarshal(trace.ResponseBody, errResp); err == nil && errResp.Error.Message != "" {
+		return nil, trace, errors.Errorf("DialogFlow API request failed: %s", errResp.Error.Message)
+	}
+
+	return nil, trace, errors.New("DialogFlow API request failed")
+}
@@ -0,0 +1,86 @@
+package dialogflow_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/goflow/services/classification/dialogflow"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectIntent(t *testing.T) {
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://us-central1-dialogflow.googleapis.com/v3/projects/booking-123/locations/us-central1/agents/f51c8d5b-8e5c-4fd4-9a55-2a3bd2b7fd3d/sessions/2a6b3ab0-f3a7-4a1d-bdbf-4bcab4b0c7b4:detectIntent": {
+			httpx.NewMockResponse(200, nil, []byte(`xx`)), // non-JSON response
+			httpx.NewMockResponse(200, nil, []byte(`{}`)), // invalid JSON response
+			httpx.NewMockResponse(400, nil, []byte(`{"error": {"code": 400, "message": "com.google.apps.framework.request.BadRequestException: Invalid language code: xx", "status": "INVALID_ARGUMENT"}}`)),
+			httpx.NewMockResponse(503, nil, []byte(`Service Unavailable`)),
+			httpx.NewMockResponse(200, nil, []byte(`{
+				"responseId": "b7d43a2e-1e2b-4a7f-ae83-5de1ad35a86f",
+				"queryResult": {
+					"text": "book flight to Quito",
+					"languageCode": "en",
+					"parameters": {"destination": "Quito"},
+					"match": {
+						"intent": {
+							"name": "projects/booking-123/locations/us-central1/agents/f51c8d5b-8e5c-4fd4-9a55-2a3bd2b7fd3d/intents/9bb6de5b-1ad6-4d34-bd0a-5d1c8e1b0a8d",
+							"displayName": "book_flight"
+						},
+						"confidence": 0.91,
+						"matchType": "INTENT"
+					}
+				}
+			}`)),
+		},
+	}))
+
+	client := dialogflow.NewClient(http.DefaultClient, nil, "booking-123", "us-central1", "f51c8d5b-8e5c-4fd4-9a55-2a3bd2b7fd3d", "ya29.3246231")
+
+	response, trace, err := client.DetectIntent(context.Background(), "2a6b3ab0-f3a7-4a1d-bdbf-4bcab4b0c7b4", "book flight to Quito", "en")
+	assert.EqualError(t, err, `invalid character 'x' looking for beginning of value`)
+	test.AssertSnapshot(t, "detect_intent_request", string(trace.RequestTrace))
+	assert.Equal(t, "xx", string(trace.ResponseBody))
+	assert.Nil(t, response)
+
+	response, trace, err = client.DetectIntent(context.Background(), "2a6b3ab0-f3a7-4a1d-bdbf-4bcab4b0c7b4", "book flight to Quito", "en")
+	assert.EqualError(t, err, `field 'queryResult' is required`)
+	assert.NotNil(t, trace)
+	assert.Nil(t, response)
+
+	response, _, err = client.DetectIntent(context.Background(), "2a6b3ab0-f3a7-4a1d-bdbf-4bcab4b0c7b4", "book flight to Quito", "xx")
+	assert.EqualError(t, err, `DialogFlow API request failed: com.google.apps.framework.request.BadRequestException: Invalid language code: xx`)
+	assert.Nil(t, response)
+
+	response, _, err = client.DetectIntent(context.Background(), "2a6b3ab0-f3a7-4a1d-bdbf-4bcab4b0c7b4", "book flight to Quito", "en")
+	assert.EqualError(t, err, `DialogFlow API request failed`)
+	assert.Nil(t, response)
+
+	response, trace, err = client.DetectIntent(context.Background(), "2a6b3ab0-f3a7-4a1d-bdbf-4bcab4b0c7b4", "book flight to Quito", "en")
+	assert.NoError(t, err)
+	assert.NotNil(t, trace)
+	assert.Equal(t, "book flight to Quito", response.QueryResult.Text)
+	assert.Equal(t, "book_flight", response.QueryResult.Match.Intent.DisplayName)
+	assert.Equal(t, decimal.RequireFromString(`0.91`), response.QueryResult.Match.Confidence)
+	assert.Equal(t, "INTENT", response.QueryResult.Match.MatchType)
+
+	// agents in the global location use the global endpoint
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://dialogflow.googleapis.com/v3/projects/booking-123/locations/global/agents/f51c8d5b-8e5c-4fd4-9a55-2a3bd2b7fd3d/sessions/2a6b3ab0-f3a7-4a1d-bdbf-4bcab4b0c7b4:detectIntent": {
+			httpx.NewMockResponse(200, nil, []byte(`{"queryResult": {"text": "hi", "match": {"confidence": 0.3, "matchType": "NO_MATCH"}}}`)),
+		},
+	}))
+
+	client = dialogflow.NewClient(http.DefaultClient, nil, "booking-123", "global", "f51c8d5b-8e5c-4fd4-9a55-2a3bd2b7fd3d", "ya29.3246231")
+
+	response, _, err = client.DetectIntent(context.Background(), "2a6b3ab0-f3a7-4a1d-bdbf-4bcab4b0c7b4", "hi", "")
+	assert.NoError(t, err)
+	assert.Nil(t, response.QueryResult.Match.Intent)
+	assert.Equal(t, "NO_MATCH", response.QueryResult.Match.MatchType)
+}
@@ -0,0 +1,96 @@
+package dialogflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/stringsx"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/services/classification"
+)
+
+// DialogFlow names locales like en or pt-br
+var localeFormat = classification.LocaleFormat{Separator: "-", Lowercase: true}
+
+// a classification service implementation for a DialogFlow CX agent
+type service struct {
+	client     *Client
+	classifier *flows.Classifier
+	redactor   stringsx.Redactor
+}
+
+// NewService creates a new classification service
+func NewService(httpClient *http.Client, httpRetries *httpx.RetryConfig, classifier *flows.Classifier, projectID, location, agentID, accessToken string) flows.ClassificationService {
+	return &service{
+		client:     NewClient(httpClient, httpRetries, projectID, location, agentID, accessToken),
+		classifier: classifier,
+		redactor:   stringsx.NewRedactor(flows.RedactionMask, accessToken),
+	}
+}
+
+func (s *service) Classify(ctx context.Context, env envs.Environment, input string, logHTTP flows.HTTPLogCallback) (*flows.Classification, error) {
+	// each classification is a new conversation with the agent
+	sessionID := string(uuids.New())
+
+	response, trace, err := s.client.DetectIntent(ctx, sessionID, input, localeFormat.Format(env.DefaultLocale()))
+	if trace != nil {
+		logHTTP(flows.NewHTTPLog(trace, flows.HTTPStatusFromCode, s.redactor))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	match := response.QueryResult.Match
+
+	result := &flows.Classification{
+		Intents:  make([]flows.ExtractedIntent, 0, 1),
+		Entities: make(map[string][]flows.ExtractedEntity, len(response.QueryResult.Parameters)),
+	}
+
+	// CX only gives us the matched intent, if there was one
+	if match.Intent != nil && match.Intent.DisplayName != "" {
+		result.Intents = append(result.Intents, flows.ExtractedIntent{Name: match.Intent.DisplayName, Confidence: match.Confidence})
+	}
+
+	// parameters don't have their own confidences so use that of the match
+	for name, value := range response.QueryResult.Parameters {
+		if values := parameterValues(value); len(values) > 0 {
+			entities := make([]flows.ExtractedEntity, len(values))
+			for i, v := range values {
+				entities[i] = flows.ExtractedEntity{Value: v, Confidence: match.Confidence}
+			}
+			result.Entities[name] = entities
+		}
+	}
+
+	return result, nil
+}
+
+// converts a parameter value to a list of strings, e.g. "Quito" -> ["Quito"], ["Quito","Lima"] -> ["Quito","Lima"],
+// with values which aren't strings (e.g. numbers or dates) kept as JSON
+func parameterValues(value json.RawMessage) []string {
+	var asList []json.RawMessage
+	if err := jsonx.Unmarshal(value, &asList); err != nil {
+		asList = []json.RawMessage{value}
+	}
+
+	values := make([]string, 0, len(asList))
+	for _, item := range asList {
+		var asStr string
+		if string(item) == "null" {
+			continue
+		} else if err := jsonx.Unmarshal(item, &asStr); err == nil {
+			values = append(values, asStr)
+		} else {
+			values = append(values, string(item))
+		}
+	}
+	return values
+}
+
+var _ flows.ClassificationService = (*service)(nil)
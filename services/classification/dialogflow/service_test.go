@@ -0,0 +1,92 @@
+package dialogflow_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/services/classification/dialogflow"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService(t *testing.T) {
+	defer uuids.SetGenerator(uuids.DefaultGenerator)
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	uuids.SetGenerator(uuids.NewSeededGenerator(12345))
+	dates.SetNowSource(dates.NewSequentialNowSource(time.Date(2019, 10, 7, 15, 21, 30, 123456789, time.UTC)))
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://dialogflow.googleapis.com/v3/projects/booking-123/locations/global/agents/f51c8d5b-8e5c-4fd4-9a55-2a3bd2b7fd3d/sessions/e7187099-7d38-4f60-955c-325957214c42:detectIntent": {
+			httpx.NewMockResponse(200, nil, []byte(`{
+				"responseId": "b7d43a2e-1e2b-4a7f-ae83-5de1ad35a86f",
+				"queryResult": {
+					"text": "book 2 flights to Quito or Lima",
+					"languageCode": "es-us",
+					"parameters": {
+						"destination": ["Quito", "Lima"],
+						"passengers": 2,
+						"date": {"year": 2019, "month": 10, "day": 8},
+						"class": null
+					},
+					"match": {
+						"intent": {
+							"name": "projects/booking-123/locations/global/agents/f51c8d5b-8e5c-4fd4-9a55-2a3bd2b7fd3d/intents/9bb6de5b-1ad6-4d34-bd0a-5d1c8e1b0a8d",
+							"displayName": "book_flight"
+						},
+						"confidence": 0.91,
+						"matchType": "INTENT"
+					}
+				}
+			}`)),
+		},
+		"https://dialogflow.googleapis.com/v3/projects/booking-123/locations/global/agents/f51c8d5b-8e5c-4fd4-9a55-2a3bd2b7fd3d/sessions/59d74b86-3e2f-4a93-aece-b05d2fdcde0c:detectIntent": {
+			httpx.NewMockResponse(200, nil, []byte(`{"queryResult": {"text": "hello", "languageCode": "es-us", "match": {"confidence": 0.3, "matchType": "NO_MATCH"}}}`)),
+		},
+	}))
+
+	svc := dialogflow.NewService(
+		http.DefaultClient,
+		nil,
+		test.NewClassifier("Booking", "dialogflow", []string{"book_flight", "book_hotel"}),
+		"booking-123",
+		"global",
+		"f51c8d5b-8e5c-4fd4-9a55-2a3bd2b7fd3d",
+		"ya29.3246231",
+	)
+
+	env := envs.NewBuilder().WithAllowedLanguages([]envs.Language{"spa"}).WithDefaultCountry("US").Build()
+	httpLogger := &flows.HTTPLogger{}
+
+	classification, err := svc.Classify(context.Background(), env, "book 2 flights to Quito or Lima", httpLogger.Log)
+	assert.NoError(t, err)
+	assert.Equal(t, []flows.ExtractedIntent{
+		{Name: "book_flight", Confidence: decimal.RequireFromString(`0.91`)},
+	}, classification.Intents)
+	assert.Equal(t, map[string][]flows.ExtractedEntity{
+		"destination": {
+			{Value: "Quito", Confidence: decimal.RequireFromString(`0.91`)},
+			{Value: "Lima", Confidence: decimal.RequireFromString(`0.91`)},
+		},
+		"passengers": {{Value: "2", Confidence: decimal.RequireFromString(`0.91`)}},
+		"date":       {{Value: `{"year": 2019, "month": 10, "day": 8}`, Confidence: decimal.RequireFromString(`0.91`)}},
+	}, classification.Entities)
+
+	assert.Equal(t, 1, len(httpLogger.Logs))
+	test.AssertSnapshot(t, "detect_intent_request", httpLogger.Logs[0].Request)
+
+	// no match means no intents
+	classification, err = svc.Classify(context.Background(), env, "hello", httpLogger.Log)
+	assert.NoError(t, err)
+	assert.Equal(t, []flows.ExtractedIntent{}, classification.Intents)
+	assert.Equal(t, map[string][]flows.ExtractedEntity{}, classification.Entities)
+}
@@ -0,0 +1,37 @@
+package classification
+
+import (
+	"strings"
+
+	"github.com/nyaruka/goflow/envs"
+)
+
+// LocaleFormat describes how a classification service names locales
+type LocaleFormat struct {
+	Separator      string // between language and country, e.g. "-" or "_"
+	Lowercase      bool   // whether the country should be lowercased, e.g. en_us
+	RequireCountry bool   // whether a locale without a country should be omitted
+}
+
+// Format returns the code for the given locale in this format, or an empty string if the locale can't be represented,
+// e.g. eng-US -> en_US, por-BR -> pt-br
+func (f LocaleFormat) Format(locale envs.Locale) string {
+	code := locale.ToBCP47() // e.g. en-US
+	if code == "" {
+		return ""
+	}
+
+	lang, country, hasCountry := strings.Cut(code, "-")
+	if !hasCountry {
+		if f.RequireCountry {
+			return ""
+		}
+		return lang
+	}
+
+	if f.Lowercase {
+		country = strings.ToLower(country)
+	}
+
+	return lang + f.Separator + country
+}
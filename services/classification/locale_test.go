@@ -0,0 +1,36 @@
+package classification_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/services/classification"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocaleFormat(t *testing.T) {
+	bcp47 := classification.LocaleFormat{Separator: "-"}
+	underscored := classification.LocaleFormat{Separator: "_", Lowercase: true}
+	withCountry := classification.LocaleFormat{Separator: "_", RequireCountry: true}
+
+	tests := []struct {
+		locale      envs.Locale
+		bcp47       string
+		underscored string
+		withCountry string
+	}{
+		{envs.NilLocale, "", "", ""},
+		{"eng", "en", "en", ""},
+		{"eng-US", "en-US", "en_us", "en_US"},
+		{"por-BR", "pt-BR", "pt_br", "pt_BR"},
+		{"kin-RW", "rw-RW", "rw_rw", "rw_RW"},
+		{"cak-GT", "", "", ""}, // no 2-letter code
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.bcp47, bcp47.Format(tc.locale), "bcp47 mismatch for %s", tc.locale)
+		assert.Equal(t, tc.underscored, underscored.Format(tc.locale), "underscored mismatch for %s", tc.locale)
+		assert.Equal(t, tc.withCountry, withCountry.Format(tc.locale), "with country mismatch for %s", tc.locale)
+	}
+}
@@ -1,6 +1,7 @@
 package luis_test
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -73,19 +74,19 @@ func TestPredict(t *testing.T) {
 		"production",
 	)
 
-	response, trace, err := client.Predict("book flight to Quito")
+	response, trace, err := client.Predict(context.Background(), "book flight to Quito")
 	assert.EqualError(t, err, `invalid character 'x' looking for beginning of value`)
 	test.AssertSnapshot(t, "predict_request", string(trace.RequestTrace))
 	assert.Equal(t, "HTTP/1.0 200 OK\r\nContent-Length: 2\r\n\r\n", string(trace.ResponseTrace))
 	assert.Equal(t, "xx", string(trace.ResponseBody))
 	assert.Nil(t, response)
 
-	response, trace, err = client.Predict("book flight to Quito")
+	response, trace, err = client.Predict(context.Background(), "book flight to Quito")
 	assert.EqualError(t, err, `field 'prediction' is required`)
 	assert.NotNil(t, trace)
 	assert.Nil(t, response)
 
-	response, trace, err = client.Predict("book flight to Quito")
+	response, trace, err = client.Predict(context.Background(), "book flight to Quito")
 	assert.NoError(t, err)
 	assert.NotNil(t, trace)
 	assert.Equal(t, "book a flight to Quito", response.Query)
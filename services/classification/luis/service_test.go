@@ -1,6 +1,7 @@
 package luis_test
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -82,7 +83,7 @@ func TestService(t *testing.T) {
 	env := envs.NewBuilder().Build()
 	httpLogger := &flows.HTTPLogger{}
 
-	classification, err := svc.Classify(env, "book flight to Quito", httpLogger.Log)
+	classification, err := svc.Classify(context.Background(), env, "book flight to Quito", httpLogger.Log)
 	assert.NoError(t, err)
 	assert.Equal(t, []flows.ExtractedIntent{
 		{Name: "Book Flight", Confidence: dec(`0.9106805`)},
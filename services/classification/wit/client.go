@@ -1,12 +1,14 @@
 package wit
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 
 	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/gocommon/jsonx"
 	"github.com/nyaruka/goflow/utils"
 
 	"github.com/shopspring/decimal"
@@ -66,15 +68,27 @@ func NewClient(httpClient *http.Client, httpRetries *httpx.RetryConfig, accessTo
 	}
 }
 
-// Message gets the meaning of a message
-func (c *Client) Message(q string) (*MessageResponse, *httpx.Trace, error) {
+// messageContext is the optional context of a /message request
+type messageContext struct {
+	Locale string `json:"locale,omitempty"`
+}
+
+// Message gets the meaning of a message in the given locale (e.g. en_US) which may be empty
+func (c *Client) Message(ctx context.Context, q, locale string) (*MessageResponse, *httpx.Trace, error) {
 	endpoint := fmt.Sprintf("%s/message?v=%s&q=%s", apiBaseURL, version, url.QueryEscape(q))
 
+	if locale != "" {
+		msgContext := jsonx.MustMarshal(&messageContext{Locale: locale})
+		endpoint += "&context=" + url.QueryEscape(string(msgContext))
+	}
+
 	request, err := httpx.NewRequest("GET", endpoint, nil, c.headers)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	request = request.WithContext(ctx)
+
 	trace, err := httpx.DoTrace(c.httpClient, request, c.httpRetries, nil, -1)
 	if err != nil {
 		return nil, trace, err
@@ -90,3 +104,36 @@ func (c *Client) Message(q string) (*MessageResponse, *httpx.Trace, error) {
 
 	return nil, trace, errors.New("wit.ai API request failed")
 }
+
+// Intent is an intent defined in the app
+type Intent struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Intents gets the intents defined in the app
+func (c *Client) Intents(ctx context.Context) ([]*Intent, *httpx.Trace, error) {
+	endpoint := fmt.Sprintf("%s/intents?v=%s", apiBaseURL, version)
+
+	request, err := httpx.NewRequest("GET", endpoint, nil, c.headers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request = request.WithContext(ctx)
+
+	trace, err := httpx.DoTrace(c.httpClient, request, c.httpRetries, nil, -1)
+	if err != nil {
+		return nil, trace, err
+	}
+
+	if trace.Response != nil && trace.Response.StatusCode == 200 {
+		var response []*Intent
+		if err := jsonx.Unmarshal(trace.ResponseBody, &response); err != nil {
+			return nil, trace, err
+		}
+		return response, trace, nil
+	}
+
+	return nil, trace, errors.New("wit.ai API request failed")
+}
@@ -1,6 +1,7 @@
 package wit_test
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -59,19 +60,19 @@ func TestMessage(t *testing.T) {
 
 	client := wit.NewClient(http.DefaultClient, nil, "3246231")
 
-	response, trace, err := client.Message("Hello")
+	response, trace, err := client.Message(context.Background(), "Hello", "")
 	assert.EqualError(t, err, `invalid character 'x' looking for beginning of value`)
 	test.AssertSnapshot(t, "message_request", string(trace.RequestTrace))
 	assert.Equal(t, "HTTP/1.0 200 OK\r\nContent-Length: 2\r\n\r\n", string(trace.ResponseTrace))
 	assert.Equal(t, "xx", string(trace.ResponseBody))
 	assert.Nil(t, response)
 
-	response, trace, err = client.Message("Hello")
+	response, trace, err = client.Message(context.Background(), "Hello", "")
 	assert.EqualError(t, err, `field 'intents' is required`)
 	assert.NotNil(t, trace)
 	assert.Nil(t, response)
 
-	response, trace, err = client.Message("Hello")
+	response, trace, err = client.Message(context.Background(), "Hello", "")
 	assert.NoError(t, err)
 	assert.NotNil(t, trace)
 	assert.Equal(t, "I want to book a flight to Quito", response.Text)
@@ -1,6 +1,7 @@
 package wit
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
@@ -8,8 +9,14 @@ import (
 	"github.com/nyaruka/gocommon/stringsx"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/services/classification"
+
+	"github.com/pkg/errors"
 )
 
+// wit.ai names locales like en_US and only supports them with a country
+var localeFormat = classification.LocaleFormat{Separator: "_", RequireCountry: true}
+
 // a classification service implementation for a wit.ai app
 type service struct {
 	client     *Client
@@ -26,8 +33,8 @@ func NewService(httpClient *http.Client, httpRetries *httpx.RetryConfig, classif
 	}
 }
 
-func (s *service) Classify(env envs.Environment, input string, logHTTP flows.HTTPLogCallback) (*flows.Classification, error) {
-	response, trace, err := s.client.Message(input)
+func (s *service) Classify(ctx context.Context, env envs.Environment, input string, logHTTP flows.HTTPLogCallback) (*flows.Classification, error) {
+	response, trace, err := s.client.Message(ctx, input, localeFormat.Format(env.DefaultLocale()))
 	if trace != nil {
 		logHTTP(flows.NewHTTPLog(trace, flows.HTTPStatusFromCode, s.redactor))
 	}
@@ -59,4 +66,14 @@ func (s *service) Classify(env envs.Environment, input string, logHTTP flows.HTT
 	return result, nil
 }
 
+// Check checks that our access token is valid by fetching the app's intents
+func (s *service) Check(ctx context.Context) error {
+	_, trace, err := s.client.Intents(ctx)
+	if trace != nil && trace.Response != nil && trace.Response.StatusCode == http.StatusUnauthorized {
+		return errors.New("wit.ai access token is not valid")
+	}
+	return err
+}
+
 var _ flows.ClassificationService = (*service)(nil)
+var _ flows.CheckableService = (*service)(nil)
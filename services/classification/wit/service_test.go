@@ -1,6 +1,7 @@
 package wit_test
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -62,6 +63,9 @@ func TestService(t *testing.T) {
 				}
 			}`)),
 		},
+		"https://api.wit.ai/message?v=20200513&q=book+flight+to+Quito&context=%7B%22locale%22%3A%22en_US%22%7D": {
+			httpx.NewMockResponse(200, nil, []byte(`{"text": "book flight to Quito", "intents": [], "entities": {}, "traits": {}}`)),
+		},
 	}))
 
 	svc := wit.NewService(
@@ -74,7 +78,7 @@ func TestService(t *testing.T) {
 	env := envs.NewBuilder().Build()
 	httpLogger := &flows.HTTPLogger{}
 
-	classification, err := svc.Classify(env, "book flight to Quito", httpLogger.Log)
+	classification, err := svc.Classify(context.Background(), env, "book flight to Quito", httpLogger.Log)
 	assert.NoError(t, err)
 	assert.Equal(t, []flows.ExtractedIntent{
 		{Name: "book_flight", Confidence: decimal.RequireFromString(`0.9024`)},
@@ -86,4 +90,32 @@ func TestService(t *testing.T) {
 	assert.Equal(t, 1, len(httpLogger.Logs))
 	assert.Equal(t, "https://api.wit.ai/message?v=20200513&q=book+flight+to+Quito", httpLogger.Logs[0].URL)
 	assert.Equal(t, "GET /message?v=20200513&q=book+flight+to+Quito HTTP/1.1\r\nHost: api.wit.ai\r\nUser-Agent: Go-http-client/1.1\r\nAuthorization: Bearer ****************\r\nAccept-Encoding: gzip\r\n\r\n", httpLogger.Logs[0].Request)
+
+	// if environment has a country, locale is sent as context
+	env = envs.NewBuilder().WithAllowedLanguages([]envs.Language{"eng"}).WithDefaultCountry("US").Build()
+
+	classification, err = svc.Classify(context.Background(), env, "book flight to Quito", httpLogger.Log)
+	assert.NoError(t, err)
+	assert.Equal(t, []flows.ExtractedIntent{}, classification.Intents)
+	assert.Equal(t, 2, len(httpLogger.Logs))
+	assert.Equal(t, "https://api.wit.ai/message?v=20200513&q=book+flight+to+Quito&context=%7B%22locale%22%3A%22en_US%22%7D", httpLogger.Logs[1].URL)
+}
+
+func TestCheck(t *testing.T) {
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://api.wit.ai/intents?v=20200513": {
+			httpx.NewMockResponse(200, nil, []byte(`[{"id": "2690212494559269", "name": "book_flight"}]`)),
+			httpx.NewMockResponse(401, nil, []byte(`{"error": "Bad auth, check token/params", "code": "no-auth"}`)),
+			httpx.NewMockResponse(500, nil, []byte(`{}`)),
+		},
+	}))
+
+	svc := wit.NewService(http.DefaultClient, nil, test.NewClassifier("Booking", "wit", []string{"book_flight"}), "23532624376")
+	checkable := svc.(flows.CheckableService)
+
+	assert.NoError(t, checkable.Check(context.Background()))
+	assert.EqualError(t, checkable.Check(context.Background()), "wit.ai access token is not valid")
+	assert.EqualError(t, checkable.Check(context.Background()), "wit.ai API request failed")
 }
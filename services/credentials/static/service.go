@@ -0,0 +1,37 @@
+package static
+
+import (
+	"context"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+
+	"github.com/pkg/errors"
+)
+
+type service struct {
+	authorizations map[string]string
+}
+
+// NewServiceFactory creates a new static credential service factory
+func NewServiceFactory(authorizations map[string]string) engine.CredentialServiceFactory {
+	return func(flows.SessionAssets) (flows.CredentialService, error) {
+		return NewService(authorizations), nil
+	}
+}
+
+// NewService creates a new credential service which looks up credentials in a fixed map of names to Authorization
+// header values, e.g. loaded from deployment config
+func NewService(authorizations map[string]string) flows.CredentialService {
+	return &service{authorizations: authorizations}
+}
+
+func (s *service) Authorization(ctx context.Context, name string) (string, error) {
+	auth, exists := s.authorizations[name]
+	if !exists {
+		return "", errors.Errorf("no such credential '%s'", name)
+	}
+	return auth, nil
+}
+
+var _ flows.CredentialService = (*service)(nil)
@@ -0,0 +1,23 @@
+package static_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nyaruka/goflow/services/credentials/static"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	svc, err := static.NewServiceFactory(map[string]string{"shop_api": "Bearer sesame"})(nil)
+	require.NoError(t, err)
+
+	auth, err := svc.Authorization(context.Background(), "shop_api")
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer sesame", auth)
+
+	_, err = svc.Authorization(context.Background(), "bank_api")
+	assert.EqualError(t, err, "no such credential 'bank_api'")
+}
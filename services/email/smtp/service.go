@@ -1,34 +1,160 @@
 package smtp
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
 	"strings"
 
+	"github.com/nyaruka/gocommon/httpx"
 	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/utils"
+	"github.com/nyaruka/goflow/utils/netx"
 	"github.com/nyaruka/goflow/utils/smtpx"
+
+	"github.com/pkg/errors"
 )
 
+// DefaultMaxAttachmentBytes is the default limit on the total size of the attachments of an email
+const DefaultMaxAttachmentBytes = 10 * 1024 * 1024
+
 type service struct {
-	client  *smtpx.Client
-	retries *smtpx.RetryConfig
+	client             *smtpx.Client
+	retries            *smtpx.RetryConfig
+	httpClient         *http.Client
+	maxAttachmentBytes int
+	policy             *netx.HostPolicy
+	resolver           netx.Resolver
 }
 
 // NewService creates a new SMTP email service
 func NewService(smtpURL string, retries *smtpx.RetryConfig) (flows.EmailService, error) {
+	return NewGuardedService(smtpURL, retries, netx.DefaultClient, DefaultMaxAttachmentBytes, nil, nil)
+}
+
+// NewGuardedService creates a new SMTP email service which only connects to a server whose addresses, as found by the
+// given resolver or the default resolver of netx if that's nil, are allowed by the given policy. Attachments are
+// fetched with the given HTTP client, which should be guarded by the same policy, and can't total more than
+// maxAttachmentBytes.
+func NewGuardedService(smtpURL string, retries *smtpx.RetryConfig, httpClient *http.Client, maxAttachmentBytes int, policy *netx.HostPolicy, resolver netx.Resolver) (flows.EmailService, error) {
 	c, err := smtpx.NewClientFromURL(smtpURL)
 	if err != nil {
 		return nil, err
 	}
+	if policy != nil && resolver == nil {
+		resolver = netx.DefaultResolver
+	}
+
+	return &service{client: c, retries: retries, httpClient: httpClient, maxAttachmentBytes: maxAttachmentBytes, policy: policy, resolver: resolver}, nil
+}
 
-	return &service{client: c, retries: retries}, nil
+func (s *service) Send(ctx context.Context, addresses []string, subject, body string) error {
+	return s.SendEmail(ctx, &flows.Email{Addresses: addresses, Subject: subject, Body: body})
 }
 
-func (s *service) Send(addresses []string, subject, body string) error {
+func (s *service) SendEmail(ctx context.Context, email *flows.Email) error {
 	// sending blank emails is a good way to get flagged as a spammer so use placeholder if body is empty
+	body := email.Body
 	if strings.TrimSpace(body) == "" {
 		body = "(empty body)"
 	}
 
-	m := smtpx.NewMessage(addresses, subject, body, "")
+	// the SMTP client doesn't support cancellation so the best we can do is not start sending if we're already too late
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := s.checkHost(ctx); err != nil {
+		return err
+	}
+
+	attachments, err := s.fetchAttachments(ctx, email.Attachments)
+	if err != nil {
+		return err
+	}
+
+	m := smtpx.NewMessage(email.Addresses, email.Subject, body, email.HTMLBody).WithReplyTo(email.ReplyTo).WithAttachments(attachments)
 
 	return smtpx.Send(s.client, m, s.retries)
 }
+
+func (s *service) Check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := s.checkHost(ctx); err != nil {
+		return err
+	}
+
+	return errors.Wrap(s.client.Check(), "unable to connect to SMTP server")
+}
+
+// checks that our policy, if we have one, allows connecting to the server
+func (s *service) checkHost(ctx context.Context) error {
+	if s.policy == nil {
+		return nil
+	}
+	return s.policy.CheckHost(ctx, s.resolver, s.client.Host())
+}
+
+// fetches the given attachments, failing if any can't be fetched or together they're bigger than our limit
+func (s *service) fetchAttachments(ctx context.Context, attachments []utils.Attachment) ([]*smtpx.Attachment, error) {
+	fetched := make([]*smtpx.Attachment, 0, len(attachments))
+	remaining := s.maxAttachmentBytes
+
+	for _, a := range attachments {
+		data, err := s.fetchAttachment(ctx, a.URL(), remaining)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to fetch attachment %s", a.Redacted().URL())
+		}
+		remaining -= len(data)
+
+		fetched = append(fetched, &smtpx.Attachment{Filename: attachmentFilename(a.URL()), ContentType: a.ContentType(), Data: data})
+	}
+
+	return fetched, nil
+}
+
+func (s *service) fetchAttachment(ctx context.Context, rawURL string, maxBytes int) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpx.Do(s.httpClient, req, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, errors.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	// read one byte more than we're allowed so we know if the body was too big
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBytes {
+		return nil, errors.Errorf("attachments exceed limit of %d bytes", s.maxAttachmentBytes)
+	}
+
+	return data, nil
+}
+
+// gets a filename for an attachment from the last part of its URL path
+func attachmentFilename(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		if name := path.Base(u.Path); name != "." && name != "/" {
+			return name
+		}
+	}
+	return "attachment"
+}
+
+var _ flows.RichEmailService = (*service)(nil)
+var _ flows.CheckableService = (*service)(nil)
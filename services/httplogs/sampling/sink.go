@@ -0,0 +1,93 @@
+package sampling
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"github.com/nyaruka/gocommon/random"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+)
+
+// Rules determine which HTTP logs are kept
+type Rules struct {
+	KeepFailures     bool    // keep every log of a call that didn't succeed
+	SuccessRate      float64 // fraction of logs of successful calls to keep
+	KeepFirstPerHost bool    // keep the first log of each day for each host
+}
+
+// DefaultRules keeps all failures, the first call to each host each day, and 1% of other successful calls
+var DefaultRules = Rules{KeepFailures: true, SuccessRate: 0.01, KeepFirstPerHost: true}
+
+type sink struct {
+	sink  flows.HTTPLogSink
+	rules Rules
+
+	mutex sync.Mutex
+	day   string
+	hosts map[string]bool
+}
+
+// NewSinkFactory creates a new sampling HTTP log sink factory which shares a single sink between sessions
+func NewSinkFactory(s flows.HTTPLogSink, rules Rules) engine.HTTPLogSinkFactory {
+	sampler := NewSink(s, rules)
+
+	return func(flows.SessionAssets) (flows.HTTPLogSink, error) {
+		return sampler, nil
+	}
+}
+
+// NewSink creates a new HTTP log sink which passes a sample of the logs it receives on to the given sink, e.g. so that
+// a high volume deployment can keep useful traces without storing every call
+func NewSink(s flows.HTTPLogSink, rules Rules) flows.HTTPLogSink {
+	return &sink{sink: s, rules: rules, hosts: make(map[string]bool)}
+}
+
+func (s *sink) Store(ctx context.Context, log *flows.HTTPLog) {
+	if s.keep(log) {
+		s.sink.Store(ctx, log)
+	}
+}
+
+func (s *sink) keep(log *flows.HTTPLog) bool {
+	if log.Status != flows.CallStatusSuccess && s.rules.KeepFailures {
+		return true
+	}
+
+	first := s.isFirstForHost(log)
+
+	if first && s.rules.KeepFirstPerHost {
+		return true
+	}
+	if log.Status != flows.CallStatusSuccess {
+		return false
+	}
+
+	return random.Float64() < s.rules.SuccessRate
+}
+
+// checks whether the given log is the first we've seen for its host on the day it was created
+func (s *sink) isFirstForHost(log *flows.HTTPLog) bool {
+	u, err := url.Parse(log.URL)
+	if err != nil {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	day := log.CreatedOn.UTC().Format("2006-01-02")
+	if day != s.day {
+		s.day = day
+		s.hosts = make(map[string]bool)
+	}
+
+	if s.hosts[u.Host] {
+		return false
+	}
+	s.hosts[u.Host] = true
+	return true
+}
+
+var _ flows.HTTPLogSink = (*sink)(nil)
@@ -0,0 +1,73 @@
+package sampling_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/gocommon/random"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/services/httplogs/sampling"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	urls []string
+}
+
+func (s *recordingSink) Store(ctx context.Context, log *flows.HTTPLog) {
+	s.urls = append(s.urls, log.URL)
+}
+
+func newLog(url string, status flows.CallStatus, createdOn time.Time) *flows.HTTPLog {
+	return &flows.HTTPLog{
+		HTTPLogWithoutTime: &flows.HTTPLogWithoutTime{LogWithoutTime: &httpx.LogWithoutTime{URL: url}, Status: status},
+		CreatedOn:          createdOn,
+	}
+}
+
+func TestSink(t *testing.T) {
+	defer random.SetGenerator(random.DefaultGenerator)
+	random.SetGenerator(random.NewSeededGenerator(123456))
+
+	day1 := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2022, 6, 2, 10, 0, 0, 0, time.UTC)
+
+	rec := &recordingSink{}
+	sink, err := sampling.NewSinkFactory(rec, sampling.DefaultRules)(nil)
+	require.NoError(t, err)
+
+	sink.Store(context.Background(), newLog("http://a.com/1", flows.CallStatusSuccess, day1))       // first for host
+	sink.Store(context.Background(), newLog("http://a.com/2", flows.CallStatusSuccess, day1))       // sampled out
+	sink.Store(context.Background(), newLog("http://a.com/3", flows.CallStatusResponseError, day1)) // failure
+	sink.Store(context.Background(), newLog("http://b.com/1", flows.CallStatusConnectionError, day1))
+	sink.Store(context.Background(), newLog("http://b.com/2", flows.CallStatusSuccess, day1)) // first success for host
+	sink.Store(context.Background(), newLog("http://a.com/4", flows.CallStatusSuccess, day2)) // first for host on new day
+	sink.Store(context.Background(), newLog("http://a.com/5", flows.CallStatusSuccess, day2)) // sampled out
+
+	assert.Equal(t, []string{"http://a.com/1", "http://a.com/3", "http://b.com/1", "http://b.com/2", "http://a.com/4"}, rec.urls)
+
+	// with no sampling of successes, all of them are kept
+	rec = &recordingSink{}
+	sink = sampling.NewSink(rec, sampling.Rules{SuccessRate: 1})
+
+	sink.Store(context.Background(), newLog("http://a.com/1", flows.CallStatusSuccess, day1))
+	sink.Store(context.Background(), newLog("http://a.com/2", flows.CallStatusSuccess, day1))
+	sink.Store(context.Background(), newLog("http://a.com/3", flows.CallStatusResponseError, day1))
+
+	assert.Equal(t, []string{"http://a.com/1", "http://a.com/2"}, rec.urls)
+
+	// with a success rate of zero, only failures and first calls are kept
+	rec = &recordingSink{}
+	sink = sampling.NewSink(rec, sampling.Rules{KeepFailures: true, KeepFirstPerHost: true})
+
+	for i := 0; i < 100; i++ {
+		sink.Store(context.Background(), newLog("http://a.com/", flows.CallStatusSuccess, day1))
+	}
+	sink.Store(context.Background(), newLog("http://a.com/x", flows.CallStatusSubscriberGone, day1))
+
+	assert.Equal(t, []string{"http://a.com/", "http://a.com/x"}, rec.urls)
+}
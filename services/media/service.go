@@ -0,0 +1,197 @@
+// Package media provides an attachment service which checks that the media of outgoing messages can be fetched before
+// they're sent, and which can re-host that media somewhere else, e.g. on a CDN.
+package media
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/utils"
+	"github.com/nyaruka/goflow/utils/netx"
+
+	"github.com/pkg/errors"
+)
+
+// Rehoster copies media to another host, returning the URL it can be fetched from there
+type Rehoster interface {
+	Rehost(ctx context.Context, url, contentType string) (string, error)
+}
+
+// Config is the configuration of a media service
+type Config struct {
+	ContentTypes []string      // allowed content types, which can be prefixes like "image/", any if empty
+	MaxBytes     int           // maximum size of media, no limit if zero
+	CacheTTL     time.Duration // how long to remember checked URLs, not at all if zero
+}
+
+// checked media, which is cached by URL
+type media struct {
+	reachable   bool
+	contentType string
+	size        int
+	url         string // the re-hosted URL if media was re-hosted
+	expiresOn   time.Time
+}
+
+type cache struct {
+	mutex   sync.Mutex
+	entries map[string]*media
+}
+
+func (c *cache) get(url string) *media {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	m := c.entries[url]
+	if m != nil && dates.Now().Before(m.expiresOn) {
+		return m
+	}
+	delete(c.entries, url)
+	return nil
+}
+
+func (c *cache) set(url string, m *media, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	m.expiresOn = dates.Now().Add(ttl)
+	c.entries[url] = m
+}
+
+type service struct {
+	httpClient  *http.Client
+	httpRetries *httpx.RetryConfig
+	httpAccess  *httpx.AccessConfig
+	config      *Config
+	rehoster    Rehoster
+	cache       *cache
+}
+
+// NewServiceFactory creates a new media service factory whose services all share the same cache of checked URLs
+func NewServiceFactory(httpClient *http.Client, httpRetries *httpx.RetryConfig, httpAccess *httpx.AccessConfig, config *Config, rehoster Rehoster) engine.AttachmentServiceFactory {
+	svc := NewService(httpClient, httpRetries, httpAccess, config, rehoster)
+
+	return func(flows.SessionAssets) (flows.AttachmentService, error) {
+		return svc, nil
+	}
+}
+
+// NewService creates a new media service. If httpClient is nil, the default client of netx is used. If rehoster is nil,
+// attachments are sent with their original URLs.
+func NewService(httpClient *http.Client, httpRetries *httpx.RetryConfig, httpAccess *httpx.AccessConfig, config *Config, rehoster Rehoster) flows.AttachmentService {
+	if httpClient == nil {
+		httpClient = netx.DefaultClient
+	}
+
+	return &service{
+		httpClient:  httpClient,
+		httpRetries: httpRetries,
+		httpAccess:  httpAccess,
+		config:      config,
+		rehoster:    rehoster,
+		cache:       &cache{entries: make(map[string]*media)},
+	}
+}
+
+// Process checks the given attachment can be fetched and is allowed, returning it with its re-hosted URL if the service
+// re-hosts media. Attachments which aren't HTTP URLs, e.g. geo locations, are returned as is.
+func (s *service) Process(ctx context.Context, attachment utils.Attachment) (utils.Attachment, error) {
+	contentType, url := attachment.ToParts()
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return attachment, nil
+	}
+
+	m := s.cache.get(url)
+	if m == nil {
+		var err error
+		if m, err = s.check(ctx, url, contentType); err != nil {
+			return "", err
+		}
+		s.cache.set(url, m, s.config.CacheTTL)
+	}
+
+	if !m.reachable {
+		return "", flows.NewAttachmentRejectedError(flows.AttachmentRejectedReasonUnreachable, 0, 0)
+	}
+	if !s.isAllowed(m.contentType) {
+		return "", flows.NewAttachmentRejectedError(flows.AttachmentRejectedReasonContentType, 0, 0)
+	}
+	if s.config.MaxBytes > 0 && m.size > s.config.MaxBytes {
+		return "", flows.NewAttachmentRejectedError(flows.AttachmentRejectedReasonSize, m.size, s.config.MaxBytes)
+	}
+
+	return utils.Attachment(contentType + ":" + m.url), nil
+}
+
+// checks the media at the given URL, re-hosting it if it's reachable and we have a rehoster
+func (s *service) check(ctx context.Context, url, contentType string) (*media, error) {
+	response, err := s.fetchHeaders(ctx, http.MethodHead, url)
+
+	// not all servers support HEAD requests so fallback to a GET
+	if err == nil && (response.StatusCode == http.StatusMethodNotAllowed || response.StatusCode == http.StatusNotImplemented) {
+		response, err = s.fetchHeaders(ctx, http.MethodGet, url)
+	}
+	if err != nil || response.StatusCode >= 400 {
+		return &media{reachable: false}, nil
+	}
+
+	m := &media{reachable: true, contentType: contentType, url: url}
+
+	if mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type")); err == nil {
+		m.contentType = mediaType
+	}
+	if size, err := strconv.Atoi(response.Header.Get("Content-Length")); err == nil {
+		m.size = size
+	}
+
+	// no point re-hosting media which is going to be rejected
+	if s.rehoster != nil && s.isAllowed(m.contentType) && (s.config.MaxBytes <= 0 || m.size <= s.config.MaxBytes) {
+		if m.url, err = s.rehoster.Rehost(ctx, url, m.contentType); err != nil {
+			return nil, errors.Wrapf(err, "error re-hosting %s", url)
+		}
+	}
+
+	return m, nil
+}
+
+// makes a request to the given URL, returning the response without its body
+func (s *service) fetchHeaders(ctx context.Context, method, url string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := httpx.Do(s.httpClient, request, s.httpRetries, s.httpAccess)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+	return response, nil
+}
+
+func (s *service) isAllowed(contentType string) bool {
+	if len(s.config.ContentTypes) == 0 {
+		return true
+	}
+	for _, allowed := range s.config.ContentTypes {
+		if contentType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(contentType, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+var _ flows.AttachmentService = (*service)(nil)
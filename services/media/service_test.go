@@ -0,0 +1,117 @@
+package media_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/services/media"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRehoster struct {
+	rehosted []string
+	err      error
+}
+
+func (r *testRehoster) Rehost(ctx context.Context, url, contentType string) (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	r.rehosted = append(r.rehosted, url)
+	return "https://cdn.example.com/" + url[len("http://example.com/"):], nil
+}
+
+func TestService(t *testing.T) {
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+	defer dates.SetNowSource(dates.DefaultNowSource)
+
+	dates.SetNowSource(dates.NewFixedNowSource(time.Date(2018, 10, 18, 14, 20, 30, 0, time.UTC)))
+
+	mocks := httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"http://example.com/cat.jpg": {
+			httpx.NewMockResponse(200, map[string]string{"Content-Type": "image/jpeg", "Content-Length": "1000"}, nil),
+			httpx.NewMockResponse(200, map[string]string{"Content-Type": "image/jpeg", "Content-Length": "1000"}, nil),
+		},
+		"http://example.com/dead.jpg": {
+			httpx.NewMockResponse(404, nil, nil),
+		},
+		"http://example.com/down.jpg": {
+			httpx.MockConnectionError,
+		},
+		"http://example.com/nohead.png": {
+			httpx.NewMockResponse(405, nil, nil),
+			httpx.NewMockResponse(200, map[string]string{"Content-Type": "image/png; charset=binary"}, []byte(`...`)),
+		},
+		"http://example.com/big.mp4": {
+			httpx.NewMockResponse(200, map[string]string{"Content-Type": "video/mp4", "Content-Length": "50000"}, nil),
+		},
+		"http://example.com/doc.pdf": {
+			httpx.NewMockResponse(200, map[string]string{"Content-Type": "application/pdf"}, nil),
+		},
+	})
+	httpx.SetRequestor(mocks)
+
+	rehoster := &testRehoster{}
+	config := &media.Config{ContentTypes: []string{"image/", "video/mp4"}, MaxBytes: 20000, CacheTTL: time.Minute}
+	svc, err := media.NewServiceFactory(http.DefaultClient, nil, nil, config, rehoster)(nil)
+	require.NoError(t, err)
+
+	tcs := []struct {
+		attachment utils.Attachment
+		processed  utils.Attachment
+		rejected   flows.AttachmentRejectedReason
+	}{
+		{"image/jpeg:http://example.com/cat.jpg", "image/jpeg:https://cdn.example.com/cat.jpg", ""},
+		{"image/jpeg:http://example.com/cat.jpg", "image/jpeg:https://cdn.example.com/cat.jpg", ""}, // cached
+		{"image/jpeg:http://example.com/dead.jpg", "", flows.AttachmentRejectedReasonUnreachable},
+		{"image/jpeg:http://example.com/down.jpg", "", flows.AttachmentRejectedReasonUnreachable},
+		{"image:http://example.com/nohead.png", "image:https://cdn.example.com/nohead.png", ""},
+		{"video/mp4:http://example.com/big.mp4", "", flows.AttachmentRejectedReasonSize},
+		{"image/jpeg:http://example.com/doc.pdf", "", flows.AttachmentRejectedReasonContentType},
+		{"geo:-2.90875,-79.0117686", "geo:-2.90875,-79.0117686", ""},
+	}
+
+	for _, tc := range tcs {
+		processed, err := svc.Process(context.Background(), tc.attachment)
+
+		if tc.rejected != "" {
+			rejected, isRejected := err.(*flows.AttachmentRejectedError)
+			if assert.True(t, isRejected, "expected rejection for %s", tc.attachment) {
+				assert.Equal(t, tc.rejected, rejected.Reason, "rejection mismatch for %s", tc.attachment)
+			}
+		} else {
+			assert.NoError(t, err, "unexpected error for %s", tc.attachment)
+		}
+		assert.Equal(t, tc.processed, processed, "processed mismatch for %s", tc.attachment)
+	}
+
+	// only media which wasn't rejected is re-hosted
+	assert.Equal(t, []string{"http://example.com/cat.jpg", "http://example.com/nohead.png"}, rehoster.rehosted)
+
+	// once the cache has expired, URLs are checked again
+	dates.SetNowSource(dates.NewFixedNowSource(time.Date(2018, 10, 18, 14, 22, 30, 0, time.UTC)))
+
+	processed, err := svc.Process(context.Background(), "image/jpeg:http://example.com/cat.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, utils.Attachment("image/jpeg:https://cdn.example.com/cat.jpg"), processed)
+	assert.False(t, mocks.HasUnused())
+
+	// errors from the rehoster aren't rejections
+	mocks = httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"http://example.com/dog.jpg": {httpx.NewMockResponse(200, map[string]string{"Content-Type": "image/jpeg"}, nil)},
+	})
+	httpx.SetRequestor(mocks)
+
+	svc = media.NewService(http.DefaultClient, nil, nil, &media.Config{}, &testRehoster{err: errors.New("CDN is down")})
+	_, err = svc.Process(context.Background(), "image/jpeg:http://example.com/dog.jpg")
+	assert.EqualError(t, err, "error re-hosting http://example.com/dog.jpg: CDN is down")
+}
@@ -0,0 +1,200 @@
+package simulation
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// transport for the HTTP client of the simulated webhook service which responds with fixtures instead of making requests
+type webhookTransport struct {
+	fixtures map[string]*WebhookFixture
+	recorder *Recorder
+}
+
+func (t *webhookTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	url := request.URL.String()
+
+	t.recorder.record(flows.ServiceTypeWebhook, "%s %s", request.Method, url)
+
+	fixture := t.fixtures[url]
+	if fixture == nil {
+		return nil, errors.Errorf("no simulated response for %s", url)
+	}
+
+	response := &http.Response{
+		Status:        http.StatusText(fixture.Status),
+		StatusCode:    fixture.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(strings.NewReader(fixture.Body)),
+		ContentLength: int64(len(fixture.Body)),
+		Request:       request,
+	}
+	for k, v := range fixture.Headers {
+		response.Header.Set(k, v)
+	}
+	return response, nil
+}
+
+// simulated email service which doesn't send anything
+type emailService struct {
+	recorder *Recorder
+}
+
+func (s *emailService) Send(ctx context.Context, addresses []string, subject, body string) error {
+	s.recorder.record(flows.ServiceTypeEmail, "send '%s' to %s", subject, strings.Join(addresses, ", "))
+	return nil
+}
+
+func (s *emailService) SendEmail(ctx context.Context, email *flows.Email) error {
+	return s.Send(ctx, email.Addresses, email.Subject, email.Body)
+}
+
+// simulated classification service which always extracts the configured intents of the classifier
+type classificationService struct {
+	classifier *flows.Classifier
+	intents    []flows.ExtractedIntent
+	recorder   *Recorder
+}
+
+func (s *classificationService) Classify(ctx context.Context, env envs.Environment, input string, logHTTP flows.HTTPLogCallback) (*flows.Classification, error) {
+	s.recorder.record(flows.ServiceTypeClassification, "classify '%s' with %s", input, s.classifier.Name())
+
+	return &flows.Classification{Intents: s.intents}, nil
+}
+
+// simulated ticket service which opens tickets without an external ID
+type ticketService struct {
+	ticketer *flows.Ticketer
+	recorder *Recorder
+}
+
+func (s *ticketService) Open(ctx context.Context, env envs.Environment, contact *flows.Contact, topic *flows.Topic, body string, assignee *flows.User, logHTTP flows.HTTPLogCallback) (*flows.Ticket, error) {
+	s.recorder.record(flows.ServiceTypeTicket, "open ticket with %s", s.ticketer.Name())
+
+	uuid := flows.TicketUUID(flows.SourcesFromContext(ctx).NewUUID())
+
+	return flows.NewTicket(uuid, s.ticketer, topic, body, "", assignee), nil
+}
+
+// simulated airtime service whose transfers always succeed without sending anything
+type airtimeService struct {
+	recorder *Recorder
+}
+
+func (s *airtimeService) Transfer(ctx context.Context, sender urns.URN, recipient urns.URN, amounts map[string]decimal.Decimal, logHTTP flows.HTTPLogCallback) (*flows.AirtimeTransfer, error) {
+	s.recorder.record(flows.ServiceTypeAirtime, "transfer to %s", recipient)
+
+	if len(amounts) == 0 {
+		return nil, errors.New("no amounts to transfer")
+	}
+
+	// amounts is a map so pick the first currency alphabetically to be deterministic
+	currencies := make([]string, 0, len(amounts))
+	for currency := range amounts {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	return &flows.AirtimeTransfer{
+		Sender:        sender,
+		Recipient:     recipient,
+		Currency:      currencies[0],
+		DesiredAmount: amounts[currencies[0]],
+		ActualAmount:  amounts[currencies[0]],
+	}, nil
+}
+
+func (s *airtimeService) Balance(ctx context.Context, logHTTP flows.HTTPLogCallback) (map[string]decimal.Decimal, error) {
+	return map[string]decimal.Decimal{}, nil
+}
+
+// simulated data collection service whose collections are all empty
+type dataCollectionService struct {
+	recorder *Recorder
+}
+
+func (s *dataCollectionService) Query(ctx context.Context, env envs.Environment, collection string, query *flows.DataQuery, logHTTP flows.HTTPLogCallback) (*flows.DataPage, error) {
+	s.recorder.record(flows.ServiceTypeDataCollection, "query %s", collection)
+
+	return &flows.DataPage{Rows: []map[string]any{}, Total: 0}, nil
+}
+
+// simulated commerce service which has no products but accepts any order
+type commerceService struct {
+	recorder *Recorder
+}
+
+func (s *commerceService) LookupProduct(ctx context.Context, env envs.Environment, productID string, logHTTP flows.HTTPLogCallback) (*flows.Product, error) {
+	s.recorder.record(flows.ServiceTypeCommerce, "lookup product %s", productID)
+
+	return nil, errors.Errorf("no such product '%s'", productID)
+}
+
+func (s *commerceService) PlaceOrder(ctx context.Context, env envs.Environment, contact *flows.Contact, order *flows.Order, logHTTP flows.HTTPLogCallback) (string, error) {
+	s.recorder.record(flows.ServiceTypeCommerce, "place order of %d items", len(order.Items))
+
+	return "simulated", nil
+}
+
+// simulated call recording service which doesn't record anything
+type callRecordingService struct {
+	recorder *Recorder
+}
+
+func (s *callRecordingService) StartRecording(ctx context.Context, call *flows.Call) error {
+	s.recorder.record(flows.ServiceTypeCallRecording, "start recording")
+	return nil
+}
+
+func (s *callRecordingService) StopRecording(ctx context.Context, call *flows.Call) (string, error) {
+	s.recorder.record(flows.ServiceTypeCallRecording, "stop recording")
+	return "http://recordings.example.com/simulated.mp3", nil
+}
+
+// simulated call transfer service whose transfers always complete immediately
+type callTransferService struct {
+	recorder *Recorder
+}
+
+func (s *callTransferService) Forward(ctx context.Context, call *flows.Call, urn urns.URN) (*flows.CallTransfer, error) {
+	s.recorder.record(flows.ServiceTypeCallTransfer, "forward to %s", urn)
+	return flows.NewCallTransfer(flows.CallTransferStatusCompleted, 0), nil
+}
+
+func (s *callTransferService) JoinConference(ctx context.Context, call *flows.Call, conference string) (*flows.CallTransfer, error) {
+	s.recorder.record(flows.ServiceTypeCallTransfer, "join conference %s", conference)
+	return flows.NewCallTransfer(flows.CallTransferStatusCompleted, 0), nil
+}
+
+// simulated exchange rate service which can only convert between the same currency
+type exchangeRateService struct{}
+
+func (s *exchangeRateService) Rate(ctx context.Context, from, to string, logHTTP flows.HTTPLogCallback) (decimal.Decimal, error) {
+	if from != to {
+		return decimal.Zero, errors.Errorf("no exchange rate from %s to %s", from, to)
+	}
+	return decimal.NewFromInt(1), nil
+}
+
+var _ flows.EmailService = (*emailService)(nil)
+var _ flows.ClassificationService = (*classificationService)(nil)
+var _ flows.TicketService = (*ticketService)(nil)
+var _ flows.AirtimeService = (*airtimeService)(nil)
+var _ flows.DataCollectionService = (*dataCollectionService)(nil)
+var _ flows.CommerceService = (*commerceService)(nil)
+var _ flows.CallRecordingService = (*callRecordingService)(nil)
+var _ flows.CallTransferService = (*callTransferService)(nil)
+var _ flows.ExchangeRateService = (*exchangeRateService)(nil)
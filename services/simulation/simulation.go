@@ -0,0 +1,111 @@
+package simulation
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/services/credentials/static"
+	"github.com/nyaruka/goflow/services/webhooks"
+)
+
+// Config is the configuration of a simulation, i.e. how sessions are made deterministic and the canned responses of
+// the simulated services
+type Config struct {
+	Seed        int64                              `json:"seed"`
+	Now         time.Time                          `json:"now"`
+	Webhooks    map[string]*WebhookFixture         `json:"webhooks,omitempty"`    // keyed by URL
+	Intents     map[string][]flows.ExtractedIntent `json:"intents,omitempty"`     // keyed by classifier name
+	Credentials map[string]string                  `json:"credentials,omitempty"` // keyed by credential name
+}
+
+// WebhookFixture is the canned response to webhook calls to a URL
+type WebhookFixture struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// Configure makes the given engine builder use simulated services in place of all its service factories, and run
+// sessions deterministically. Calls to the simulated services are recorded by the given recorder if it isn't nil.
+func Configure(b *engine.Builder, config *Config, recorder *Recorder) *engine.Builder {
+	httpClient := NewWebhookClient(config.Webhooks, recorder)
+
+	return b.
+		WithSimulation(&flows.Simulation{Seed: config.Seed, Now: config.Now}).
+		WithEmailServiceFactory(func(flows.SessionAssets) (flows.EmailService, error) {
+			return &emailService{recorder: recorder}, nil
+		}).
+		WithWebhookServiceFactory(webhooks.NewServiceFactory(httpClient, nil, nil, map[string]string{"User-Agent": "goflow-simulation"}, 10000, 10000)).
+		WithClassificationServiceFactory(func(c *flows.Classifier) (flows.ClassificationService, error) {
+			return &classificationService{classifier: c, intents: config.Intents[c.Name()], recorder: recorder}, nil
+		}).
+		WithTicketServiceFactory(func(t *flows.Ticketer) (flows.TicketService, error) {
+			return &ticketService{ticketer: t, recorder: recorder}, nil
+		}).
+		WithAirtimeServiceFactory(func(flows.SessionAssets) (flows.AirtimeService, error) {
+			return &airtimeService{recorder: recorder}, nil
+		}).
+		WithDataCollectionServiceFactory(func(flows.SessionAssets) (flows.DataCollectionService, error) {
+			return &dataCollectionService{recorder: recorder}, nil
+		}).
+		WithCommerceServiceFactory(func(flows.SessionAssets) (flows.CommerceService, error) {
+			return &commerceService{recorder: recorder}, nil
+		}).
+		WithCallRecordingServiceFactory(func(flows.SessionAssets) (flows.CallRecordingService, error) {
+			return &callRecordingService{recorder: recorder}, nil
+		}).
+		WithCallTransferServiceFactory(func(flows.SessionAssets) (flows.CallTransferService, error) {
+			return &callTransferService{recorder: recorder}, nil
+		}).
+		WithCredentialServiceFactory(static.NewServiceFactory(config.Credentials)).
+		WithExchangeRateServiceFactory(func(flows.SessionAssets) (flows.ExchangeRateService, error) {
+			return &exchangeRateService{}, nil
+		})
+}
+
+// NewWebhookClient creates an HTTP client which responds to requests with the given fixtures, keyed by URL, instead of
+// making them. Requests to URLs without fixtures fail as if the host couldn't be reached. Requests are recorded by the
+// given recorder if it isn't nil.
+func NewWebhookClient(fixtures map[string]*WebhookFixture, recorder *Recorder) *http.Client {
+	return &http.Client{Transport: &webhookTransport{fixtures: fixtures, recorder: recorder}}
+}
+
+// Call is a call made to a simulated service
+type Call struct {
+	Service flows.ServiceType `json:"service"`
+	Summary string            `json:"summary"`
+}
+
+// Recorder records the calls made to simulated services
+type Recorder struct {
+	calls []*Call
+	mutex sync.Mutex
+}
+
+// NewRecorder creates a new empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{calls: make([]*Call, 0)}
+}
+
+// Calls returns the calls recorded so far
+func (r *Recorder) Calls() []*Call {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]*Call(nil), r.calls...)
+}
+
+func (r *Recorder) record(service flows.ServiceType, summary string, args ...any) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.calls = append(r.calls, &Call{Service: service, Summary: fmt.Sprintf(summary, args...)})
+}
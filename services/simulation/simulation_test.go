@@ -0,0 +1,218 @@
+package simulation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/flows/triggers"
+	"github.com/nyaruka/goflow/services/simulation"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var assetsJSON = `{
+	"classifiers": [
+		{"uuid": "1c06c884-39dd-4ce4-ad9f-9a01cbe6c000", "name": "Booking", "type": "wit", "intents": ["book_flight", "book_hotel"]}
+	],
+	"flows": [
+		{
+			"uuid": "5472a1c3-63e1-484f-8485-cc8ecb16a058",
+			"name": "Simulated",
+			"spec_version": "13.2.0",
+			"language": "eng",
+			"type": "messaging",
+			"nodes": [
+				{
+					"uuid": "8f2c1e69-6f0e-4a3b-8a8d-0d6a1b3e7f01",
+					"actions": [
+						{"uuid": "0a8467eb-911a-41db-8101-ccf415c48e6a", "type": "call_webhook", "method": "GET", "url": "http://example.com/hello", "result_name": "Hello"}
+					],
+					"exits": [{"uuid": "2b8bb5e2-6e8b-4d5d-bf6c-7c0c5d7e1e0a", "destination_uuid": "64373978-e8f6-4973-b6ff-a2993f3376fc"}]
+				},
+				{
+					"uuid": "64373978-e8f6-4973-b6ff-a2993f3376fc",
+					"router": {
+						"type": "random",
+						"result_name": "Coin",
+						"categories": [
+							{"uuid": "598ae7a5-2f81-48f1-afac-595262514aa1", "name": "Heads", "exit_uuid": "49a47f31-ec90-42b5-a0d8-6efb5b1fa57b"},
+							{"uuid": "c70fe86c-9aac-4cc2-a5cb-d35cbe3fed6e", "name": "Tails", "exit_uuid": "5bd6a427-2b9a-4a4d-ad3f-eb39eaaa7e5a"}
+						]
+					},
+					"exits": [
+						{"uuid": "49a47f31-ec90-42b5-a0d8-6efb5b1fa57b", "destination_uuid": "a6f0e1b5-8a4e-4c7d-9f4b-3a5d8d1e2c70"},
+						{"uuid": "5bd6a427-2b9a-4a4d-ad3f-eb39eaaa7e5a", "destination_uuid": "a6f0e1b5-8a4e-4c7d-9f4b-3a5d8d1e2c70"}
+					]
+				},
+				{
+					"uuid": "a6f0e1b5-8a4e-4c7d-9f4b-3a5d8d1e2c70",
+					"actions": [
+						{"uuid": "d0c3a5e4-52a4-4a8d-8a4e-0b1e5a6f7c81", "type": "send_msg", "text": "@results.hello.value @results.coin"}
+					],
+					"router": {
+						"type": "switch",
+						"wait": {"type": "msg"},
+						"operand": "@input.text",
+						"categories": [
+							{"uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1", "name": "All", "exit_uuid": "7a1e5f3c-0c5b-4d6e-9f2a-8b7c6d5e4f3a"}
+						],
+						"default_category_uuid": "6d2bd7a2-2ee6-4bc4-8d6a-8a14e9a2b7f1"
+					},
+					"exits": [{"uuid": "7a1e5f3c-0c5b-4d6e-9f2a-8b7c6d5e4f3a", "destination_uuid": "c2d3e4f5-a6b7-4c8d-9e0f-1a2b3c4d5e6f"}]
+				},
+				{
+					"uuid": "c2d3e4f5-a6b7-4c8d-9e0f-1a2b3c4d5e6f",
+					"actions": [
+						{"uuid": "3cd8f2db-8429-462e-ab93-8041dd23abf1", "type": "call_classifier", "classifier": {"uuid": "1c06c884-39dd-4ce4-ad9f-9a01cbe6c000", "name": "Booking"}, "input": "@input.text", "result_name": "Intent"},
+						{"uuid": "9c1d2e3f-4a5b-4c6d-8e7f-0a1b2c3d4e5f", "type": "call_webhook", "method": "GET", "url": "http://example.com/missing", "result_name": "Missing"}
+					],
+					"exits": [{"uuid": "e3f4a5b6-c7d8-4e9f-8a0b-1c2d3e4f5a6b"}]
+				}
+			]
+		}
+	]
+}`
+
+var triggerJSON = `{
+	"type": "manual",
+	"flow": {"uuid": "5472a1c3-63e1-484f-8485-cc8ecb16a058", "name": "Simulated"},
+	"contact": {
+		"uuid": "5d76d86b-3bb9-4d5a-b822-c9d86f5d8e4f",
+		"name": "Ryan Lewis",
+		"status": "active",
+		"created_on": "2018-06-20T11:40:30.123456789Z"
+	},
+	"triggered_on": "2022-02-03T13:45:30Z"
+}`
+
+var resumeJSON = `{
+	"type": "msg",
+	"resumed_on": "2022-02-03T13:46:30Z",
+	"msg": {"uuid": "2d611e17-fb22-457f-b802-b8f7ec5cda5b", "urn": "tel:+12065551212", "text": "I want to fly"}
+}`
+
+func TestSimulation(t *testing.T) {
+	config := &simulation.Config{
+		Seed: 123,
+		Now:  time.Date(2022, 2, 3, 13, 45, 30, 0, time.UTC),
+		Webhooks: map[string]*simulation.WebhookFixture{
+			"http://example.com/hello": {Status: 200, Headers: map[string]string{"Content-Type": "application/json"}, Body: `{"greeting": "hi"}`},
+		},
+		Intents: map[string][]flows.ExtractedIntent{
+			"Booking": {{Name: "book_flight", Confidence: decimal.RequireFromString("0.9")}},
+		},
+	}
+
+	source, err := static.NewSource([]byte(assetsJSON))
+	require.NoError(t, err)
+
+	sa, err := engine.NewSessionAssets(envs.NewBuilder().Build(), source, nil)
+	require.NoError(t, err)
+
+	// runs the flow to completion and returns everything it generated
+	simulate := func() (flows.Session, []byte, *simulation.Recorder) {
+		recorder := simulation.NewRecorder()
+		eng := simulation.Configure(engine.NewBuilder(), config, recorder).Build()
+
+		trigger, err := triggers.ReadTrigger(sa, []byte(triggerJSON), assets.PanicOnMissing)
+		require.NoError(t, err)
+
+		session, sprint1, err := eng.NewSession(context.Background(), sa, trigger)
+		require.NoError(t, err)
+		assert.Equal(t, flows.SessionStatusWaiting, session.Status())
+
+		resume, err := resumes.ReadResume(sa, []byte(resumeJSON), assets.PanicOnMissing)
+		require.NoError(t, err)
+
+		sprint2, err := session.Resume(context.Background(), resume)
+		require.NoError(t, err)
+		assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+
+		return session, jsonx.MustMarshal([]any{session, sprint1.Events(), sprint2.Events()}), recorder
+	}
+
+	session, output1, recorder := simulate()
+	_, output2, _ := simulate()
+
+	// simulating the same session twice gives the same result
+	assert.Equal(t, string(output1), string(output2))
+
+	assert.Equal(t, []*simulation.Call{
+		{Service: flows.ServiceTypeWebhook, Summary: "GET http://example.com/hello"},
+		{Service: flows.ServiceTypeClassification, Summary: "classify 'I want to fly' with Booking"},
+		{Service: flows.ServiceTypeWebhook, Summary: "GET http://example.com/missing"},
+	}, recorder.Calls())
+
+	results := session.Runs()[0].Results()
+	assert.Equal(t, "Success", results.Get("hello").Category)
+	assert.Equal(t, "book_flight", results.Get("intent").Value)
+	assert.Equal(t, "Failure", results.Get("missing").Category)
+
+	webhookCalls := make([]*events.WebhookCalledEvent, 0)
+	for _, e := range session.Runs()[0].Events() {
+		if e.Type() == events.TypeWebhookCalled {
+			webhookCalls = append(webhookCalls, e.(*events.WebhookCalledEvent))
+		}
+	}
+	require.Len(t, webhookCalls, 2)
+	assert.Equal(t, flows.CallStatusSuccess, webhookCalls[0].Status)
+	assert.Equal(t, config.Now, webhookCalls[0].CreatedOn())
+	assert.Equal(t, flows.CallStatusConnectionError, webhookCalls[1].Status)
+}
+
+// sink which records what the process wide clock said as each event was received
+type clockSink struct {
+	times []time.Time
+}
+
+func (s *clockSink) Receive(ctx context.Context, session flows.Session, event flows.Event) {
+	s.times = append(s.times, dates.Now())
+}
+
+func TestSimulationDoesntChangeProcessSources(t *testing.T) {
+	t0 := time.Date(2018, 4, 11, 13, 24, 30, 0, time.UTC)
+	dates.SetNowSource(dates.NewFixedNowSource(t0))
+	defer dates.SetNowSource(dates.DefaultNowSource)
+
+	config := &simulation.Config{Seed: 123, Now: time.Date(2022, 2, 3, 13, 45, 30, 0, time.UTC)}
+
+	source, err := static.NewSource([]byte(assetsJSON))
+	require.NoError(t, err)
+
+	sa, err := engine.NewSessionAssets(envs.NewBuilder().Build(), source, nil)
+	require.NoError(t, err)
+
+	trigger, err := triggers.ReadTrigger(sa, []byte(triggerJSON), assets.PanicOnMissing)
+	require.NoError(t, err)
+
+	sink := &clockSink{}
+	eng := simulation.Configure(engine.NewBuilder(), config, nil).WithEventSink(sink).Build()
+
+	session, sprint, err := eng.NewSession(context.Background(), sa, trigger)
+	require.NoError(t, err)
+
+	// the simulated session's events are stamped with its own fixed time
+	for _, e := range sprint.Events() {
+		assert.Equal(t, config.Now, e.CreatedOn())
+	}
+	assert.Equal(t, config.Now, session.Runs()[0].CreatedOn())
+
+	// but anything else in the process which asked for the time during the sprint got the process wide time
+	require.Len(t, sink.times, len(sprint.Events()))
+	for _, tm := range sink.times {
+		assert.Equal(t, t0, tm)
+	}
+	assert.Equal(t, t0, dates.Now())
+}
@@ -0,0 +1,113 @@
+package zendesk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+// Requester is the person a ticket is opened on behalf of
+type Requester struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+// Comment is a comment on a ticket
+type Comment struct {
+	Body string `json:"body"`
+}
+
+// NewTicket is a ticket to be created
+type NewTicket struct {
+	Subject       string     `json:"subject"`
+	Comment       Comment    `json:"comment"`
+	Requester     *Requester `json:"requester,omitempty"`
+	AssigneeEmail string     `json:"assignee_email,omitempty"`
+	ExternalID    string     `json:"external_id,omitempty"`
+	Tags          []string   `json:"tags,omitempty"`
+}
+
+// Ticket is a ticket which has been created
+type Ticket struct {
+	ID         int64  `json:"id" validate:"required"`
+	ExternalID string `json:"external_id"`
+	Status     string `json:"status"`
+}
+
+// response from the API containing a single ticket
+type ticketResponse struct {
+	Ticket *Ticket `json:"ticket" validate:"required"`
+}
+
+// error response from the API
+type errorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"description"`
+}
+
+// Client is a basic Zendesk client, see https://developer.zendesk.com/api-reference/ticketing/tickets/tickets/
+type Client struct {
+	httpClient  *http.Client
+	httpRetries *httpx.RetryConfig
+	subdomain   string
+	accessToken string
+}
+
+// NewClient creates a new client for the Zendesk account with the given subdomain, e.g. nyaruka for nyaruka.zendesk.com
+func NewClient(httpClient *http.Client, httpRetries *httpx.RetryConfig, subdomain, accessToken string) *Client {
+	return &Client{
+		httpClient:  httpClient,
+		httpRetries: httpRetries,
+		subdomain:   subdomain,
+		accessToken: accessToken,
+	}
+}
+
+// CreateTicket creates a new ticket
+func (c *Client) CreateTicket(ctx context.Context, ticket *NewTicket) (*Ticket, *httpx.Trace, error) {
+	endpoint := fmt.Sprintf("https://%s.zendesk.com/api/v2/tickets.json", c.subdomain)
+
+	payload := jsonx.MustMarshal(map[string]any{"ticket": ticket})
+
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": fmt.Sprintf("Bearer %s", c.accessToken),
+	}
+
+	request, err := httpx.NewRequest("POST", endpoint, bytes.NewReader(payload), headers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request = request.WithContext(ctx)
+
+	trace, err := httpx.DoTrace(c.httpClient, request, c.httpRetries, nil, -1)
+	if err != nil {
+		return nil, trace, err
+	}
+
+	if trace.Response.StatusCode >= 400 {
+		response := &errorResponse{}
+		if err := jsonx.Unmarshal(trace.ResponseBody, response); err != nil || response.Error == "" {
+			return nil, trace, errors.Errorf("Zendesk API request failed with status %d", trace.Response.StatusCode)
+		}
+		if response.Description != "" {
+			return nil, trace, errors.Errorf("%s: %s", response.Error, response.Description)
+		}
+		return nil, trace, errors.New(response.Error)
+	}
+
+	response := &ticketResponse{}
+	if err := utils.UnmarshalAndValidate(trace.ResponseBody, response); err != nil {
+		return nil, trace, err
+	}
+
+	return response.Ticket, trace, nil
+}
@@ -0,0 +1,51 @@
+package zendesk_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/goflow/services/ticket/zendesk"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTicket(t *testing.T) {
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://nyaruka.zendesk.com/api/v2/tickets.json": {
+			httpx.NewMockResponse(200, nil, []byte(`xx`)), // non-JSON response
+			httpx.NewMockResponse(201, nil, []byte(`{}`)), // invalid JSON response
+			httpx.NewMockResponse(401, nil, []byte(`{"error": "Couldn't authenticate you"}`)),
+			httpx.NewMockResponse(422, nil, []byte(`{"error": "RecordInvalid", "description": "Record validation errors"}`)),
+			httpx.NewMockResponse(500, nil, []byte(`Oops`)),
+			httpx.NewMockResponse(201, nil, []byte(`{"ticket": {"id": 35436, "external_id": "59d74b86-3e2f-4a93-aece-b05d2fdcde0c", "status": "new"}}`)),
+		},
+	}))
+
+	client := zendesk.NewClient(http.DefaultClient, nil, "nyaruka", "123456789")
+	newTicket := &zendesk.NewTicket{Subject: "Weather", Comment: zendesk.Comment{Body: "Where are my cookies?"}}
+
+	_, _, err := client.CreateTicket(context.Background(), newTicket)
+	assert.EqualError(t, err, "invalid character 'x' looking for beginning of value")
+
+	_, _, err = client.CreateTicket(context.Background(), newTicket)
+	assert.EqualError(t, err, "field 'ticket' is required")
+
+	_, trace, err := client.CreateTicket(context.Background(), newTicket)
+	assert.EqualError(t, err, "Couldn't authenticate you")
+	assert.Equal(t, 401, trace.Response.StatusCode)
+
+	_, _, err = client.CreateTicket(context.Background(), newTicket)
+	assert.EqualError(t, err, "RecordInvalid: Record validation errors")
+
+	_, _, err = client.CreateTicket(context.Background(), newTicket)
+	assert.EqualError(t, err, "Zendesk API request failed with status 500")
+
+	ticket, trace, err := client.CreateTicket(context.Background(), newTicket)
+	assert.NoError(t, err)
+	assert.Equal(t, &zendesk.Ticket{ID: 35436, ExternalID: "59d74b86-3e2f-4a93-aece-b05d2fdcde0c", Status: "new"}, ticket)
+	assert.Equal(t, "POST /api/v2/tickets.json HTTP/1.1\r\nHost: nyaruka.zendesk.com\r\nUser-Agent: Go-http-client/1.1\r\nContent-Length: 75\r\nAuthorization: Bearer 123456789\r\nContent-Type: application/json\r\nAccept-Encoding: gzip\r\n\r\n{\"ticket\":{\"subject\":\"Weather\",\"comment\":{\"body\":\"Where are my cookies?\"}}}", string(trace.RequestTrace))
+}
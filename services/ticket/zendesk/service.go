@@ -0,0 +1,90 @@
+package zendesk
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/gocommon/stringsx"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+)
+
+// subject used for tickets which don't have a topic
+const defaultSubject = "New Ticket"
+
+// a ticket service implementation for a Zendesk account
+type service struct {
+	client   *Client
+	ticketer *flows.Ticketer
+	redactor stringsx.Redactor
+}
+
+// NewService creates a new ticket service
+func NewService(httpClient *http.Client, httpRetries *httpx.RetryConfig, ticketer *flows.Ticketer, subdomain, accessToken string) flows.TicketService {
+	return &service{
+		client:   NewClient(httpClient, httpRetries, subdomain, accessToken),
+		ticketer: ticketer,
+		redactor: stringsx.NewRedactor(flows.RedactionMask, accessToken),
+	}
+}
+
+func (s *service) Open(ctx context.Context, env envs.Environment, contact *flows.Contact, topic *flows.Topic, body string, assignee *flows.User, logHTTP flows.HTTPLogCallback) (*flows.Ticket, error) {
+	ticket := flows.OpenTicket(s.ticketer, topic, body, assignee)
+
+	subject := defaultSubject
+	if topic != nil {
+		subject = topic.Name()
+	}
+
+	// the ticket's UUID is used as the external ID in Zendesk so that events about the ticket can be mapped back to it
+	newTicket := &NewTicket{
+		Subject:    subject,
+		Comment:    Comment{Body: body},
+		Requester:  requesterForContact(contact),
+		ExternalID: string(ticket.UUID()),
+	}
+	if assignee != nil {
+		newTicket.AssigneeEmail = assignee.Email()
+	}
+
+	// Zendesk triggers can route tickets to groups based on tags, so a topic's queue is added as one
+	if topic != nil && topic.Queue() != "" {
+		newTicket.Tags = []string{topic.Queue()}
+	}
+
+	created, trace, err := s.client.CreateTicket(ctx, newTicket)
+	if trace != nil {
+		logHTTP(flows.NewHTTPLog(trace, flows.HTTPStatusFromCode, s.redactor))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ticket.SetExternalID(strconv.FormatInt(created.ID, 10))
+	return ticket, nil
+}
+
+// creates a requester for the given contact, using their email address if they have one
+func requesterForContact(contact *flows.Contact) *Requester {
+	requester := &Requester{Name: contact.Name()}
+
+	if emails := contact.URNs().WithScheme(urns.EmailScheme); len(emails) > 0 {
+		requester.Email = emails[0].URN().Path()
+	}
+	if requester.Name == "" {
+		if requester.Email != "" {
+			requester.Name = requester.Email
+		} else if urn := contact.PreferredURN(); urn != nil {
+			requester.Name = urn.URN().Path()
+		} else {
+			requester.Name = string(contact.UUID())
+		}
+	}
+
+	return requester
+}
+
+var _ flows.TicketService = (*service)(nil)
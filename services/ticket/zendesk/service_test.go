@@ -0,0 +1,69 @@
+package zendesk_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/services/ticket/zendesk"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	defer uuids.SetGenerator(uuids.DefaultGenerator)
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	uuids.SetGenerator(uuids.NewSeededGenerator(12345))
+	dates.SetNowSource(dates.NewSequentialNowSource(time.Date(2019, 10, 7, 15, 21, 30, 123456789, time.UTC)))
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"https://nyaruka.zendesk.com/api/v2/tickets.json": {
+			httpx.NewMockResponse(401, nil, []byte(`{"error": "Couldn't authenticate you"}`)),
+			httpx.NewMockResponse(201, nil, []byte(`{"ticket": {"id": 35436, "external_id": "d2f852ec-7b4e-457f-ae7f-f8b243c49ff5", "status": "new"}}`)),
+		},
+	}))
+
+	_, session, _ := test.NewSessionBuilder().MustBuild()
+	env := session.Environment()
+	contact := session.Contact()
+
+	ticketer := flows.NewTicketer(static.NewTicketer("4ff4b62c-fcc3-4d4e-b3e7-a3c8bc6d1f4d", "Support", "zendesk"))
+	weather := session.Assets().Topics().Get("472a7a73-96cb-4736-b567-056d987cc5b4")
+	require.NotNil(t, weather)
+
+	svc := zendesk.NewService(http.DefaultClient, nil, ticketer, "nyaruka", "123456789")
+
+	httpLogger := &flows.HTTPLogger{}
+
+	// API errors are returned but the call is still logged
+	ticket, err := svc.Open(context.Background(), env, contact, weather, "Where are my cookies?", nil, httpLogger.Log)
+	assert.EqualError(t, err, "Couldn't authenticate you")
+	assert.Nil(t, ticket)
+	assert.Equal(t, 1, len(httpLogger.Logs))
+	assert.Equal(t, flows.CallStatusResponseError, httpLogger.Logs[0].Status)
+
+	httpLogger = &flows.HTTPLogger{}
+
+	ticket, err = svc.Open(context.Background(), env, contact, weather, "Where are my cookies?", nil, httpLogger.Log)
+	assert.NoError(t, err)
+	assert.Equal(t, ticketer, ticket.Ticketer())
+	assert.Equal(t, weather, ticket.Topic())
+	assert.Equal(t, "Where are my cookies?", ticket.Body())
+	assert.Equal(t, "35436", ticket.ExternalID())
+
+	// access token is redacted in the log of the call
+	assert.Equal(t, 1, len(httpLogger.Logs))
+	assert.Equal(t, "https://nyaruka.zendesk.com/api/v2/tickets.json", httpLogger.Logs[0].URL)
+	assert.NotContains(t, httpLogger.Logs[0].Request, "123456789")
+
+	test.AssertSnapshot(t, "create_ticket_request", httpLogger.Logs[0].Request)
+}
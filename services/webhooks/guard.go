@@ -0,0 +1,160 @@
+package webhooks
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/goflow/flows"
+)
+
+// GuardConfig configures the protection of webhook hosts
+type GuardConfig struct {
+	RateLimit        int           // max calls to a host per rate window, zero for no limit
+	RateWindow       time.Duration // window over which calls are counted for rate limiting
+	FailureThreshold int           // consecutive failures which trip the breaker open, zero for no breaker
+	OpenDuration     time.Duration // how long a tripped breaker stays open before allowing a trial call
+	MaxOpenDuration  time.Duration // open duration doubles each time a trial call fails, up to this if set
+}
+
+// NewGuardConfig creates a new guard config
+func NewGuardConfig(rateLimit int, rateWindow time.Duration, failureThreshold int, openDuration, maxOpenDuration time.Duration) *GuardConfig {
+	return &GuardConfig{
+		RateLimit:        rateLimit,
+		RateWindow:       rateWindow,
+		FailureThreshold: failureThreshold,
+		OpenDuration:     openDuration,
+		MaxOpenDuration:  maxOpenDuration,
+	}
+}
+
+type hostState struct {
+	windowStart time.Time
+	windowCalls int
+
+	breaker  flows.WebhookBreakerState
+	failures int
+	openedOn time.Time
+	openFor  time.Duration
+}
+
+// Guard applies rate limits and circuit breakers to webhook calls per host. It's safe for concurrent use and should be
+// shared by all webhook services so that its limits apply across sessions.
+type Guard struct {
+	config *GuardConfig
+	hosts  map[string]*hostState
+	mutex  sync.Mutex
+}
+
+// NewGuard creates a new guard with the given config
+func NewGuard(config *GuardConfig) *Guard {
+	return &Guard{config: config, hosts: make(map[string]*hostState)}
+}
+
+// Breaker returns the current state of the circuit breaker for the given host
+func (g *Guard) Breaker(host string) *flows.WebhookBreaker {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.breaker(host, g.state(host))
+}
+
+// checks whether a call can be made to the given host, returning an error if it should be blocked
+func (g *Guard) allow(host string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := dates.Now()
+	h := g.state(host)
+
+	trial := false
+
+	switch h.breaker {
+	case flows.WebhookBreakerOpen:
+		if now.Before(h.openedOn.Add(h.openFor)) {
+			return &flows.WebhookBlockedError{Host: host, Breaker: g.breaker(host, h)}
+		}
+		// time to let a trial call through
+		trial = true
+	case flows.WebhookBreakerHalfOpen:
+		// a trial call is already in progress
+		return &flows.WebhookBlockedError{Host: host, Breaker: g.breaker(host, h)}
+	}
+
+	if g.config.RateLimit > 0 {
+		if now.Sub(h.windowStart) >= g.config.RateWindow {
+			h.windowStart = now
+			h.windowCalls = 0
+		}
+		if h.windowCalls >= g.config.RateLimit {
+			return &flows.WebhookBlockedError{Host: host}
+		}
+		h.windowCalls++
+	}
+
+	if trial {
+		h.breaker = flows.WebhookBreakerHalfOpen
+	}
+
+	return nil
+}
+
+// records the outcome of a call to the given host, returning the breaker if its state changed
+func (g *Guard) record(host string, success bool) *flows.WebhookBreaker {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	h := g.state(host)
+	previous := h.breaker
+
+	if success {
+		h.breaker = flows.WebhookBreakerClosed
+		h.failures = 0
+		h.openFor = 0
+	} else {
+		h.failures++
+
+		if previous == flows.WebhookBreakerHalfOpen {
+			// trial call failed so back off for longer
+			h.openFor *= 2
+			if g.config.MaxOpenDuration > 0 && h.openFor > g.config.MaxOpenDuration {
+				h.openFor = g.config.MaxOpenDuration
+			}
+			h.breaker = flows.WebhookBreakerOpen
+			h.openedOn = dates.Now()
+		} else if g.config.FailureThreshold > 0 && h.failures >= g.config.FailureThreshold {
+			h.openFor = g.config.OpenDuration
+			h.breaker = flows.WebhookBreakerOpen
+			h.openedOn = dates.Now()
+		}
+	}
+
+	if h.breaker != previous {
+		return g.breaker(host, h)
+	}
+	return nil
+}
+
+func (g *Guard) state(host string) *hostState {
+	h := g.hosts[host]
+	if h == nil {
+		h = &hostState{breaker: flows.WebhookBreakerClosed}
+		g.hosts[host] = h
+	}
+	return h
+}
+
+func (g *Guard) breaker(host string, h *hostState) *flows.WebhookBreaker {
+	b := &flows.WebhookBreaker{Host: host, State: h.breaker}
+	if h.breaker == flows.WebhookBreakerOpen {
+		retryOn := h.openedOn.Add(h.openFor)
+		b.RetryOn = &retryOn
+	}
+	return b
+}
+
+// whether a response counts as a failure of the host for the purposes of the circuit breaker
+func isHostFailure(response *http.Response) bool {
+	return response == nil || response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+}
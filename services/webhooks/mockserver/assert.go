@@ -0,0 +1,51 @@
+package mockserver
+
+import (
+	"net/url"
+)
+
+// TestingT is the part of testing.T which assertions need
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// RequestsTo returns the requests received by the server with the given method and path, which is matched like
+// path.Match. An empty method matches any method.
+func (s *Server) RequestsTo(method, pattern string) []*Request {
+	matching := make([]*Request, 0)
+	for _, r := range s.Requests() {
+		u, _ := url.Parse(r.URL)
+		if matches(method, pattern, r.Method, u.Path) {
+			matching = append(matching, r)
+		}
+	}
+	return matching
+}
+
+// AssertRequested asserts that the server received the given number of requests with the given method and path
+func (s *Server) AssertRequested(t TestingT, method, pattern string, count int) bool {
+	t.Helper()
+
+	if actual := len(s.RequestsTo(method, pattern)); actual != count {
+		t.Errorf("expected %d requests to %s %s but got %d", count, method, pattern, actual)
+		return false
+	}
+	return true
+}
+
+// AssertRequestBody asserts that the last request received with the given method and path had the given body
+func (s *Server) AssertRequestBody(t TestingT, method, pattern string, body string) bool {
+	t.Helper()
+
+	requests := s.RequestsTo(method, pattern)
+	if len(requests) == 0 {
+		t.Errorf("expected a request to %s %s but got none", method, pattern)
+		return false
+	}
+	if actual := string(requests[len(requests)-1].Body); actual != body {
+		t.Errorf("expected request to %s %s to have body %q but got %q", method, pattern, body, actual)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package mockserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+)
+
+// handles a request according to its cmd query parameter, e.g. ?cmd=success for a JSON response or ?cmd=unavailable for
+// a 503. Without a command, the response has the content and content type given by the content and type parameters.
+func handleCommand(w http.ResponseWriter, r *http.Request) {
+	statusCode := http.StatusOK
+	contentType := r.URL.Query().Get("type")
+	data := []byte(r.URL.Query().Get("content"))
+
+	cmd := r.URL.Query().Get("cmd")
+	switch cmd {
+	case "success":
+		contentType = "text/plain; charset=utf-8"
+		data = []byte(`{ "ok": "true" }`)
+	case "binary":
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		sizeParam := r.URL.Query().Get("size")
+		if sizeParam == "" {
+			sizeParam = "10"
+		}
+		size, _ := strconv.Atoi(sizeParam)
+		data = make([]byte, size)
+		for i := 0; i < size; i++ {
+			data[i] = byte(i % 255)
+		}
+
+		w.Header().Set("Content-Length", sizeParam)
+	case "textjs":
+		contentType = "text/javascript; charset=iso-8859-1"
+		data = []byte(`{ "ok": "true" }`)
+	case "badjson":
+		contentType = "application/json"
+		data = []byte("{ \"bad\": \"null=\x00 escaped=\\u0000 double-escaped=\\\\u0000 badseq=\x80\x81\" }")
+	case "soap":
+		contentType = "text/xml; charset=utf-8"
+		data = []byte(`<?xml version="1.0" encoding="utf-8"?><soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><GetBalanceResponse><Balance currency="USD">12.50</Balance></GetBalanceResponse></soap:Body></soap:Envelope>`)
+	case "typeless":
+		w.Header().Set("Content-Type", "")
+	case "unavailable":
+		statusCode = http.StatusServiceUnavailable
+		data = []byte(`{ "errors": ["service unavailable"] }`)
+	case "badrequest":
+		statusCode = http.StatusBadRequest
+		data = []byte(`{ "errors": ["bad_request"] }`)
+	case "gone":
+		statusCode = http.StatusGone
+		data = []byte(`{ "errors": ["gone"] }`)
+	case "gzipped":
+		w.Header().Set("Content-Type", "application/x-gzip")
+		w.Header().Set("Content-Encoding", "gzip")
+		b := &bytes.Buffer{}
+		w := gzip.NewWriter(b)
+		w.Write(data)
+		w.Close()
+		data = b.Bytes()
+	}
+
+	w.Header().Set("Date", "Wed, 11 Apr 2018 18:24:30 GMT")
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	w.WriteHeader(statusCode)
+	w.Write(data)
+}
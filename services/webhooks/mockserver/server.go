@@ -0,0 +1,186 @@
+package mockserver
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"time"
+)
+
+// Response is a canned response to a request
+type Response struct {
+	Status  int
+	Headers map[string]string
+	Body    string
+	Latency time.Duration // added to the server's latency before this response is written
+}
+
+// Request is a request which was received by the server
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// a route which matches requests by method and path
+type route struct {
+	method    string
+	path      string
+	responses []*Response
+	served    int
+}
+
+func (r *route) matches(req *http.Request) bool {
+	return matches(r.method, r.path, req.Method, req.URL.Path)
+}
+
+// responses are served in order, with the last being repeated once the others are used up
+func (r *route) next() *Response {
+	resp := r.responses[len(r.responses)-1]
+	if r.served < len(r.responses) {
+		resp = r.responses[r.served]
+	}
+	r.served++
+	return resp
+}
+
+// checks whether a request method and path match the given method, which matches anything if empty, and path pattern
+func matches(method, pattern, reqMethod, reqPath string) bool {
+	if method != "" && method != reqMethod {
+		return false
+	}
+	matched, _ := path.Match(pattern, reqPath)
+	return matched
+}
+
+// Server is a mock HTTP server for webhooks. Requests are matched against its routes, and those which don't match any
+// are handled by the command handler, which responds according to their cmd query parameter. Every request is
+// captured so that tests can assert what was called.
+//
+//	server := mockserver.NewServer().
+//	  WithRoute("POST", "/orders", &mockserver.Response{Status: 201, Body: `{"id": 123}`}).
+//	  WithLatency(50 * time.Millisecond)
+//	server.Start()
+//	defer server.Close()
+type Server struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	routes   []*route
+	latency  time.Duration
+	requests []*Request
+}
+
+// NewServer creates a new server which isn't started. Use Start or StartTLS to start it.
+func NewServer() *Server {
+	s := &Server{}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// WithRoute adds a route for requests with the given method and path, which is matched like path.Match so may contain
+// wildcards. An empty method matches any method. Routes are tried in the order they were added.
+func (s *Server) WithRoute(method, pattern string, responses ...*Response) *Server {
+	if len(responses) == 0 {
+		panic("route must have at least one response")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.routes = append(s.routes, &route{method: method, path: pattern, responses: responses})
+	return s
+}
+
+// WithLatency adds the given latency to every response
+func (s *Server) WithLatency(latency time.Duration) *Server {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.latency = latency
+	return s
+}
+
+// ListenOn makes the server listen on the given local port, so that its URL is predictable. It must be called before
+// the server is started.
+func (s *Server) ListenOn(port int) error {
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return err
+	}
+	s.Listener.Close()
+	s.Listener = l
+	return nil
+}
+
+// Requests returns the requests received by the server so far
+func (s *Server) Requests() []*Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]*Request(nil), s.requests...)
+}
+
+// Reset clears the captured requests and starts every route's responses over
+func (s *Server) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests = nil
+	for _, r := range s.routes {
+		r.served = 0
+	}
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	s.mutex.Lock()
+	s.requests = append(s.requests, &Request{Method: r.Method, URL: r.URL.String(), Header: r.Header.Clone(), Body: body})
+
+	var resp *Response
+	for _, route := range s.routes {
+		if route.matches(r) {
+			resp = route.next()
+			break
+		}
+	}
+	latency := s.latency
+	s.mutex.Unlock()
+
+	if resp != nil {
+		latency += resp.Latency
+	}
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if resp == nil {
+		handleCommand(w, r)
+		return
+	}
+
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(resp.Body))
+}
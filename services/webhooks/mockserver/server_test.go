@@ -0,0 +1,149 @@
+package mockserver_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/goflow/services/webhooks/mockserver"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// records assertion failures instead of failing the test
+type mockT struct {
+	errors []string
+}
+
+func (t *mockT) Helper() {}
+
+func (t *mockT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestServer(t *testing.T) {
+	server := mockserver.NewServer().
+		WithRoute("POST", "/orders", &mockserver.Response{Status: 201, Body: `{"id": 1}`}, &mockserver.Response{Status: 503}).
+		WithRoute("", "/users/*", &mockserver.Response{Headers: map[string]string{"Content-Type": "application/json"}, Body: `{"name": "Bob"}`})
+	require.NoError(t, server.ListenOn(49990))
+	server.Start()
+	defer server.Close()
+
+	assert.Equal(t, "http://127.0.0.1:49990", server.URL)
+
+	call := func(method, path, body string) (int, string, string) {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get("Content-Type"), string(respBody)
+	}
+
+	// responses of a route are served in order with the last one repeated
+	status, _, body := call("POST", "/orders", `{"item": "shoes"}`)
+	assert.Equal(t, 201, status)
+	assert.Equal(t, `{"id": 1}`, body)
+
+	status, _, _ = call("POST", "/orders", `{"item": "socks"}`)
+	assert.Equal(t, 503, status)
+	status, _, _ = call("POST", "/orders", `{"item": "hat"}`)
+	assert.Equal(t, 503, status)
+
+	// patterns and routes for any method
+	status, contentType, body := call("DELETE", "/users/123", "")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "application/json", contentType)
+	assert.Equal(t, `{"name": "Bob"}`, body)
+
+	// unmatched requests are handled by commands
+	status, _, body = call("GET", "/orders?cmd=success", "")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, `{ "ok": "true" }`, body)
+
+	status, _, _ = call("GET", "/?cmd=unavailable", "")
+	assert.Equal(t, 503, status)
+
+	// requests are captured
+	requests := server.Requests()
+	require.Len(t, requests, 6)
+	assert.Equal(t, "POST", requests[0].Method)
+	assert.Equal(t, "/orders", requests[0].URL)
+	assert.Equal(t, `{"item": "shoes"}`, string(requests[0].Body))
+	assert.Equal(t, "/orders?cmd=success", requests[4].URL)
+
+	assert.Len(t, server.RequestsTo("POST", "/orders"), 3)
+	assert.Len(t, server.RequestsTo("", "/orders"), 4)
+	assert.True(t, server.AssertRequested(t, "DELETE", "/users/*", 1))
+	assert.True(t, server.AssertRequestBody(t, "POST", "/orders", `{"item": "hat"}`))
+
+	mt := &mockT{}
+	assert.False(t, server.AssertRequested(mt, "GET", "/users/*", 1))
+	assert.False(t, server.AssertRequestBody(mt, "POST", "/orders", `{"item": "shoes"}`))
+	assert.False(t, server.AssertRequestBody(mt, "PUT", "/orders", ""))
+	assert.Equal(t, []string{
+		"expected 1 requests to GET /users/* but got 0",
+		`expected request to POST /orders to have body "{\"item\": \"shoes\"}" but got "{\"item\": \"hat\"}"`,
+		"expected a request to PUT /orders but got none",
+	}, mt.errors)
+
+	// reset clears requests and starts responses over
+	server.Reset()
+	assert.Len(t, server.Requests(), 0)
+
+	status, _, _ = call("POST", "/orders", "")
+	assert.Equal(t, 201, status)
+}
+
+func TestServerLatency(t *testing.T) {
+	server := mockserver.NewServer().
+		WithRoute("GET", "/slow", &mockserver.Response{Latency: 50 * time.Millisecond}).
+		WithLatency(20 * time.Millisecond)
+	server.Start()
+	defer server.Close()
+
+	start := time.Now()
+	resp, err := http.Get(server.URL + "/fast?cmd=success")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	start = time.Now()
+	resp, err = http.Get(server.URL + "/slow")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.GreaterOrEqual(t, time.Since(start), 70*time.Millisecond)
+
+	// latency can cause client timeouts
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/slow", nil)
+	_, err = http.DefaultClient.Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestServerTLS(t *testing.T) {
+	server := mockserver.NewServer().WithRoute("GET", "/", &mockserver.Response{Body: "secure"})
+	server.StartTLS()
+	defer server.Close()
+
+	assert.True(t, strings.HasPrefix(server.URL, "https://"))
+
+	resp, err := server.Client().Get(server.URL + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "secure", string(body))
+
+	// clients which don't trust the server's certificate can't call it
+	_, err = http.Get(server.URL + "/")
+	assert.Error(t, err)
+}
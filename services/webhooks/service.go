@@ -3,36 +3,67 @@ package webhooks
 import (
 	"bytes"
 	"encoding/json"
+	"io"
+	"mime"
 	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
 
 	"github.com/nyaruka/gocommon/httpx"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/utils/netx"
+	"github.com/nyaruka/goflow/utils/xmlx"
+
+	"github.com/pkg/errors"
 )
 
 type service struct {
-	httpClient     *http.Client
-	httpRetries    *httpx.RetryConfig
-	httpAccess     *httpx.AccessConfig
-	defaultHeaders map[string]string
-	maxBodyBytes   int
+	httpClient      *http.Client
+	httpRetries     *httpx.RetryConfig
+	httpAccess      *httpx.AccessConfig
+	defaultHeaders  map[string]string
+	maxBodyBytes    int
+	maxRequestBytes int
+	guard           *Guard
 }
 
 // NewServiceFactory creates a new webhook service factory
-func NewServiceFactory(httpClient *http.Client, httpRetries *httpx.RetryConfig, httpAccess *httpx.AccessConfig, defaultHeaders map[string]string, maxBodyBytes int) engine.WebhookServiceFactory {
+func NewServiceFactory(httpClient *http.Client, httpRetries *httpx.RetryConfig, httpAccess *httpx.AccessConfig, defaultHeaders map[string]string, maxBodyBytes, maxRequestBytes int) engine.WebhookServiceFactory {
 	return func(flows.SessionAssets) (flows.WebhookService, error) {
-		return NewService(httpClient, httpRetries, httpAccess, defaultHeaders, maxBodyBytes), nil
+		return NewService(httpClient, httpRetries, httpAccess, defaultHeaders, maxBodyBytes, maxRequestBytes), nil
 	}
 }
 
-// NewService creates a new default webhook service
-func NewService(httpClient *http.Client, httpRetries *httpx.RetryConfig, httpAccess *httpx.AccessConfig, defaultHeaders map[string]string, maxBodyBytes int) flows.WebhookService {
+// NewGuardedServiceFactory creates a new webhook service factory whose services all share the given guard
+func NewGuardedServiceFactory(httpClient *http.Client, httpRetries *httpx.RetryConfig, httpAccess *httpx.AccessConfig, defaultHeaders map[string]string, maxBodyBytes, maxRequestBytes int, guard *Guard) engine.WebhookServiceFactory {
+	return func(flows.SessionAssets) (flows.WebhookService, error) {
+		return NewGuardedService(httpClient, httpRetries, httpAccess, defaultHeaders, maxBodyBytes, maxRequestBytes, guard), nil
+	}
+}
+
+// NewService creates a new default webhook service. Response bodies are cut off after maxBodyBytes, and requests with
+// bodies larger than maxRequestBytes aren't made. A limit of zero or less means no limit.
+func NewService(httpClient *http.Client, httpRetries *httpx.RetryConfig, httpAccess *httpx.AccessConfig, defaultHeaders map[string]string, maxBodyBytes, maxRequestBytes int) flows.WebhookService {
+	return NewGuardedService(httpClient, httpRetries, httpAccess, defaultHeaders, maxBodyBytes, maxRequestBytes, nil)
+}
+
+// NewGuardedService creates a new webhook service which uses the given guard to rate limit calls to each host and to
+// stop calling hosts which are repeatedly failing. If httpClient is nil, the default client of netx is used.
+func NewGuardedService(httpClient *http.Client, httpRetries *httpx.RetryConfig, httpAccess *httpx.AccessConfig, defaultHeaders map[string]string, maxBodyBytes, maxRequestBytes int, guard *Guard) flows.WebhookService {
+	if httpClient == nil {
+		httpClient = netx.DefaultClient
+	}
+
 	return &service{
-		httpClient:     httpClient,
-		httpRetries:    httpRetries,
-		httpAccess:     httpAccess,
-		defaultHeaders: defaultHeaders,
-		maxBodyBytes:   maxBodyBytes,
+		httpClient:      httpClient,
+		httpRetries:     httpRetries,
+		httpAccess:      httpAccess,
+		defaultHeaders:  defaultHeaders,
+		maxBodyBytes:    maxBodyBytes,
+		maxRequestBytes: maxRequestBytes,
+		guard:           guard,
 	}
 }
 
@@ -50,9 +81,37 @@ func (s *service) Call(request *http.Request) (*flows.WebhookCall, error) {
 		request.Header.Del("Accept-Encoding")
 	}
 
-	trace, err := httpx.DoTrace(s.httpClient, request, s.httpRetries, s.httpAccess, s.maxBodyBytes)
+	if err := s.checkRequestSize(request); err != nil {
+		return nil, err
+	}
+
+	host := request.URL.Host
+
+	if s.guard != nil {
+		if err := s.guard.allow(host); err != nil {
+			return nil, err
+		}
+	}
+
+	trace, truncated, err := s.doTrace(request)
+
+	// a call blocked by the host policy of our client never reached the host so isn't a failure of the host
+	var blocked *netx.BlockedError
+	if errors.As(err, &blocked) {
+		return nil, &flows.WebhookDeniedError{URL: request.URL.String(), Reason: blocked.Reason}
+	}
+
+	var breaker *flows.WebhookBreaker
+	if s.guard != nil {
+		var response *http.Response
+		if trace != nil {
+			response = trace.Response
+		}
+		breaker = s.guard.record(host, !isHostFailure(response))
+	}
+
 	if trace != nil {
-		call := &flows.WebhookCall{Trace: trace}
+		call := &flows.WebhookCall{Trace: trace, ResponseTruncated: truncated, Breaker: breaker}
 
 		// throw away any error that happened prior to getting a response.. these will be surfaced to the user
 		// as connection_error status on the response
@@ -60,8 +119,16 @@ func (s *service) Call(request *http.Request) (*flows.WebhookCall, error) {
 			return call, nil
 		}
 
-		if len(call.ResponseBody) > 0 {
+		// a body which was cut off is never going to be valid JSON or XML
+		if len(call.ResponseBody) > 0 && !truncated {
 			call.ResponseJSON, call.ResponseCleaned = ExtractJSON(call.ResponseBody)
+
+			// XML responses, e.g. from SOAP services, are converted to JSON so they can be used in the same way
+			if call.ResponseJSON == nil && isXML(trace.Response.Header.Get("Content-Type")) {
+				if asJSON, err := xmlx.ToJSON(call.ResponseBody); err == nil {
+					call.ResponseJSON, call.ResponseFromXML = asJSON, true
+				}
+			}
 		}
 
 		return call, err
@@ -70,6 +137,91 @@ func (s *service) Call(request *http.Request) (*flows.WebhookCall, error) {
 	return nil, err
 }
 
+// checks the request body isn't larger than our limit, reading it if its length isn't known
+func (s *service) checkRequestSize(request *http.Request) error {
+	if s.maxRequestBytes <= 0 || request.Body == nil || request.Body == http.NoBody {
+		return nil
+	}
+
+	if request.ContentLength <= 0 {
+		body, err := io.ReadAll(io.LimitReader(request.Body, int64(s.maxRequestBytes)+1))
+		request.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		request.ContentLength = int64(len(body))
+	}
+
+	if request.ContentLength > int64(s.maxRequestBytes) {
+		return &flows.WebhookRequestTooLargeError{Limit: s.maxRequestBytes}
+	}
+	return nil
+}
+
+// makes the given request like httpx.DoTrace, except that rather than failing when the response body exceeds our limit,
+// we stop reading at the limit and return what we have, along with whether it was cut off
+func (s *service) doTrace(request *http.Request) (*httpx.Trace, bool, error) {
+	requestTrace, err := httputil.DumpRequestOut(request, true)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// the call is timed by the clock of the session making it, which is fixed if that session is simulated
+	sources := flows.SourcesFromContext(request.Context())
+
+	trace := &httpx.Trace{Request: request, RequestTrace: requestTrace, StartTime: sources.Now()}
+	defer func() { trace.EndTime = sources.Now() }()
+
+	response, err := httpx.Do(s.httpClient, request, countRetries(s.httpRetries, &trace.Retries), s.httpAccess)
+	trace.Response = response
+	if err != nil {
+		return trace, false, err
+	}
+
+	defer response.Body.Close()
+
+	trace.ResponseTrace, err = httputil.DumpResponse(response, false)
+	if err != nil {
+		return trace, false, err
+	}
+
+	if s.maxBodyBytes <= 0 {
+		trace.ResponseBody, err = io.ReadAll(response.Body)
+		return trace, false, err
+	}
+
+	// read one byte more than the limit so we know if there was more, but no further
+	trace.ResponseBody, err = io.ReadAll(io.LimitReader(response.Body, int64(s.maxBodyBytes)+1))
+	if err != nil {
+		return trace, false, err
+	}
+
+	if len(trace.ResponseBody) > s.maxBodyBytes {
+		trace.ResponseBody = trace.ResponseBody[:s.maxBodyBytes]
+		return trace, true, nil
+	}
+	return trace, false, nil
+}
+
+// wraps the given retry config so that retries are counted
+func countRetries(retries *httpx.RetryConfig, count *int) *httpx.RetryConfig {
+	if retries == nil {
+		return nil
+	}
+
+	counting := *retries
+	counting.ShouldRetry = func(request *http.Request, response *http.Response, withDelay time.Duration) bool {
+		if retries.ShouldRetry(request, response, withDelay) {
+			*count++
+			return true
+		}
+		return false
+	}
+	return &counting
+}
+
 func ExtractJSON(body []byte) ([]byte, bool) {
 	// we make a best effort to turn the body into JSON, so we strip out:
 	//  1. any invalid UTF-8 sequences
@@ -86,4 +238,10 @@ func ExtractJSON(body []byte) ([]byte, bool) {
 	return nil, false
 }
 
+// checks whether the given content type is an XML media type
+func isXML(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/xml" || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
 var _ flows.WebhookService = (*service)(nil)
@@ -2,6 +2,7 @@ package webhooks_test
 
 import (
 	"bytes"
+	"io"
 	"net"
 	"net/http"
 	"strings"
@@ -16,7 +17,9 @@ import (
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/services/webhooks"
 	"github.com/nyaruka/goflow/test"
+	"github.com/nyaruka/goflow/utils/netx"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -44,9 +47,9 @@ func TestWebhookParsing(t *testing.T) {
 	require.NoError(t, err)
 
 	testCases := []struct {
-		call    call
-		webhook webhook
-		isError bool
+		call      call
+		webhook   webhook
+		truncated bool
 	}{
 		{
 			// successful GET
@@ -121,9 +124,9 @@ func TestWebhookParsing(t *testing.T) {
 				bodyJSON: `{ "errors": ["service unavailable"] }`,
 			},
 		}, {
-			// GET returning text body larger than allowed
-			call:    call{"GET", "http://127.0.0.1:49994/?cmd=binary&size=11000", ""},
-			isError: true,
+			// GET returning body larger than allowed
+			call:      call{"GET", "http://127.0.0.1:49994/?cmd=binary&size=11000", ""},
+			truncated: true,
 		}, {
 			// GET returning non-JSON body
 			call: call{"GET", "http://127.0.0.1:49994/?cmd=typeless&content=kthxbai", ""},
@@ -142,6 +145,24 @@ func TestWebhookParsing(t *testing.T) {
 				body:     `{"msg": "I'm JSON"}`,
 				bodyJSON: `{"msg": "I'm JSON"}`,
 			},
+		}, {
+			// GET returning XML body which is converted to JSON
+			call: call{"GET", "http://127.0.0.1:49994/?type=application%2Fsoap%2Bxml&content=%3Cbalance%20currency%3D%22USD%22%3E12.50%3C%2Fbalance%3E", ""},
+			webhook: webhook{
+				request:  "GET /?type=application%2Fsoap%2Bxml&content=%3Cbalance%20currency%3D%22USD%22%3E12.50%3C%2Fbalance%3E HTTP/1.1\r\nHost: 127.0.0.1:49994\r\nUser-Agent: goflow-testing\r\nAccept-Encoding: gzip\r\n\r\n",
+				response: "HTTP/1.1 200 OK\r\nContent-Length: 39\r\nContent-Type: application/soap+xml\r\nDate: Wed, 11 Apr 2018 18:24:30 GMT\r\n\r\n",
+				body:     `<balance currency="USD">12.50</balance>`,
+				bodyJSON: `{"balance":{"#text":"12.50","@currency":"USD"}}`,
+			},
+		}, {
+			// GET returning invalid XML body
+			call: call{"GET", "http://127.0.0.1:49994/?type=text%2Fxml&content=%3Cbalance%3E", ""},
+			webhook: webhook{
+				request:  "GET /?type=text%2Fxml&content=%3Cbalance%3E HTTP/1.1\r\nHost: 127.0.0.1:49994\r\nUser-Agent: goflow-testing\r\nAccept-Encoding: gzip\r\n\r\n",
+				response: "HTTP/1.1 200 OK\r\nContent-Length: 9\r\nContent-Type: text/xml\r\nDate: Wed, 11 Apr 2018 18:24:30 GMT\r\n\r\n",
+				body:     `<balance>`,
+				bodyJSON: ``,
+			},
 		}, {
 			// connection error
 			call: call{"POST", "http://127.0.0.1:55555/", ""},
@@ -161,11 +182,14 @@ func TestWebhookParsing(t *testing.T) {
 		svc, _ := session.Engine().Services().Webhook(session.Assets())
 		c, err := svc.Call(request)
 
-		if tc.isError {
-			assert.Error(t, err, "expected error for call %s", tc.call)
-		} else {
-			assert.NoError(t, err, "unexpected error fetching %s", tc.call)
+		assert.NoError(t, err, "unexpected error fetching %s", tc.call)
 
+		if tc.truncated {
+			assert.True(t, c.ResponseTruncated, "expected truncated response for call %s", tc.call)
+			assert.Equal(t, 10000, len(c.ResponseBody), "body length mismatch for call %s", tc.call)
+			assert.Nil(t, c.ResponseJSON, "unexpected body JSON for call %s", tc.call)
+		} else {
+			assert.False(t, c.ResponseTruncated, "unexpected truncated response for call %s", tc.call)
 			assert.Equal(t, tc.call.url, c.Request.URL.String(), "URL mismatch for call %s", tc.call)
 			assert.Equal(t, tc.call.method, c.Request.Method, "method mismatch for call %s", tc.call)
 			assert.Equal(t, tc.webhook.request, string(c.RequestTrace), "request trace mismatch for call %s", tc.call)
@@ -197,16 +221,67 @@ func TestRetries(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.Equal(t, 200, c.Response.StatusCode)
+	assert.Equal(t, 1, c.Retries)
 	assert.Equal(t, "GET / HTTP/1.1\r\nHost: temba.io\r\nUser-Agent: goflow-testing\r\nContent-Length: 4\r\nAccept-Encoding: gzip\r\n\r\nBODY", string(c.RequestTrace))
 	assert.Equal(t, "HTTP/1.0 200 OK\r\nContent-Length: 1\r\n\r\n", string(c.ResponseTrace))
 	assert.Equal(t, "b", string(c.ResponseBody))
 }
 
+func TestSizeLimits(t *testing.T) {
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"http://temba.io/": {
+			httpx.NewMockResponse(200, nil, []byte(`{"name": "Bob"}`)),
+			httpx.NewMockResponse(200, nil, []byte(`{"name": "`+strings.Repeat("X", 30)+`"}`)),
+			httpx.NewMockResponse(200, nil, []byte(`{"name": "Jim"}`)),
+		},
+	}))
+
+	svc := webhooks.NewService(http.DefaultClient, nil, nil, nil, 20, 10)
+
+	// response which fits is read in full
+	request, _ := http.NewRequest("POST", "http://temba.io/", strings.NewReader("0123456789"))
+	c, err := svc.Call(request)
+	require.NoError(t, err)
+	assert.Equal(t, `{"name": "Bob"}`, string(c.ResponseBody))
+	assert.Equal(t, `{"name": "Bob"}`, string(c.ResponseJSON))
+	assert.False(t, c.ResponseTruncated)
+
+	// response which doesn't is cut off and not extracted
+	request, _ = http.NewRequest("POST", "http://temba.io/", strings.NewReader("0123456789"))
+	c, err = svc.Call(request)
+	require.NoError(t, err)
+	assert.Equal(t, 200, c.Response.StatusCode)
+	assert.Equal(t, `{"name": "XXXXXXXXXX`, string(c.ResponseBody))
+	assert.Nil(t, c.ResponseJSON)
+	assert.True(t, c.ResponseTruncated)
+
+	// request which is too large isn't made
+	request, _ = http.NewRequest("POST", "http://temba.io/", strings.NewReader("0123456789A"))
+	c, err = svc.Call(request)
+	assert.EqualError(t, err, "webhook request body exceeds 10 bytes limit")
+	assert.Nil(t, c)
+
+	// even if its length isn't known up front
+	request, _ = http.NewRequest("POST", "http://temba.io/", io.MultiReader(strings.NewReader("01234"), strings.NewReader("56789A")))
+	c, err = svc.Call(request)
+	assert.EqualError(t, err, "webhook request body exceeds 10 bytes limit")
+	assert.Nil(t, c)
+
+	// but if it fits, it's read and sent
+	request, _ = http.NewRequest("POST", "http://temba.io/", io.MultiReader(strings.NewReader("01234"), strings.NewReader("5678")))
+	c, err = svc.Call(request)
+	require.NoError(t, err)
+	assert.Equal(t, "POST / HTTP/1.1\r\nHost: temba.io\r\nUser-Agent: Go-http-client/1.1\r\nContent-Length: 9\r\nAccept-Encoding: gzip\r\n\r\n012345678", string(c.RequestTrace))
+	assert.Equal(t, `{"name": "Jim"}`, string(c.ResponseBody))
+}
+
 func TestAccessRestrictions(t *testing.T) {
 	retries := httpx.NewFixedRetries(5, 10)
 	access := httpx.NewAccessConfig(10, []net.IP{net.IPv4(127, 0, 0, 1)}, nil)
 
-	factory := webhooks.NewServiceFactory(http.DefaultClient, retries, access, map[string]string{"User-Agent": "Foo"}, 12345)
+	factory := webhooks.NewServiceFactory(http.DefaultClient, retries, access, map[string]string{"User-Agent": "Foo"}, 12345, 0)
 	svc, err := factory(nil)
 	assert.NoError(t, err)
 
@@ -221,6 +296,32 @@ func TestAccessRestrictions(t *testing.T) {
 	assert.Equal(t, "", string(call.ResponseTrace))
 }
 
+func TestHostPolicy(t *testing.T) {
+	client := netx.NewGuardedClient(netx.DefaultDialer, netx.DefaultHostPolicy(), 0, time.Second)
+	svc := webhooks.NewService(client, nil, nil, nil, 1000, 0)
+
+	for _, u := range []string{"http://169.254.169.254/latest/meta-data/", "http://127.0.0.1:49999/", "http://[::1]/"} {
+		request, _ := http.NewRequest("GET", u, nil)
+		call, err := svc.Call(request)
+
+		// call isn't made so there's no trace, and error tells us why
+		assert.Nil(t, call)
+		var denied *flows.WebhookDeniedError
+		if assert.True(t, errors.As(err, &denied), "expected denied error for %s", u) {
+			assert.Equal(t, u, denied.URL)
+		}
+	}
+
+	// policy can also restrict which schemes can be used
+	policy := netx.DefaultHostPolicy()
+	policy.AllowedSchemes = []string{"https"}
+	svc = webhooks.NewService(netx.NewGuardedClient(netx.DefaultDialer, policy, 0, time.Second), nil, nil, nil, 1000, 0)
+
+	request, _ := http.NewRequest("GET", "http://example.com/", nil)
+	_, err := svc.Call(request)
+	assert.EqualError(t, err, "webhook call to http://example.com/ blocked: scheme http isn't allowed")
+}
+
 func TestGzipEncoding(t *testing.T) {
 	_, session, _ := test.NewSessionBuilder().MustBuild()
 
@@ -301,3 +402,87 @@ func TestWebhookResponseWithEscapes(t *testing.T) {
 	// check nothing became an escaped NULL
 	assert.NotContains(t, string(jsonx.MustMarshal(session)), `\u0000`)
 }
+
+func TestGuardedService(t *testing.T) {
+	defer dates.SetNowSource(dates.DefaultNowSource)
+	defer httpx.SetRequestor(httpx.DefaultRequestor)
+
+	t0 := time.Date(2019, 10, 7, 15, 21, 30, 0, time.UTC)
+	setNow := func(d time.Duration) { dates.SetNowSource(dates.NewFixedNowSource(t0.Add(d))) }
+	setNow(0)
+
+	httpx.SetRequestor(httpx.NewMockRequestor(map[string][]*httpx.MockResponse{
+		"http://ok.com/": {
+			httpx.NewMockResponse(200, nil, []byte("a")),
+			httpx.NewMockResponse(200, nil, []byte("b")),
+			httpx.NewMockResponse(200, nil, []byte("c")),
+			httpx.NewMockResponse(200, nil, []byte("d")),
+			httpx.NewMockResponse(200, nil, []byte("e")),
+		},
+		"http://fail.com/": {
+			httpx.NewMockResponse(500, nil, []byte("x")),
+			httpx.NewMockResponse(503, nil, []byte("x")),
+			httpx.NewMockResponse(502, nil, []byte("x")),
+			httpx.NewMockResponse(200, nil, []byte("y")),
+		},
+	}))
+
+	guard := webhooks.NewGuard(webhooks.NewGuardConfig(3, time.Minute, 2, 10*time.Second, time.Minute))
+	svc, err := webhooks.NewGuardedServiceFactory(http.DefaultClient, nil, nil, nil, 1024, 0, guard)(nil)
+	require.NoError(t, err)
+
+	doCall := func(url string) (*flows.WebhookCall, error) {
+		request, _ := http.NewRequest("GET", url, nil)
+		return svc.Call(request)
+	}
+
+	// host is rate limited after 3 calls in the window
+	for i := 0; i < 3; i++ {
+		c, err := doCall("http://ok.com/")
+		assert.NoError(t, err)
+		assert.Nil(t, c.Breaker)
+	}
+	c, err := doCall("http://ok.com/")
+	assert.EqualError(t, err, "webhook calls to ok.com have exceeded the rate limit")
+	assert.Nil(t, c)
+
+	// until the window passes
+	setNow(time.Minute)
+	_, err = doCall("http://ok.com/")
+	assert.NoError(t, err)
+
+	// first failure doesn't trip the breaker
+	c, err = doCall("http://fail.com/")
+	assert.NoError(t, err)
+	assert.Nil(t, c.Breaker)
+
+	// but a second does
+	retryOn := t0.Add(time.Minute + 10*time.Second)
+	c, err = doCall("http://fail.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, &flows.WebhookBreaker{Host: "fail.com", State: flows.WebhookBreakerOpen, RetryOn: &retryOn}, c.Breaker)
+
+	// and now calls are blocked
+	c, err = doCall("http://fail.com/")
+	assert.EqualError(t, err, "webhook calls to fail.com are temporarily disabled after repeated failures")
+	assert.Nil(t, c)
+	assert.Equal(t, flows.WebhookBreakerOpen, guard.Breaker("fail.com").State)
+
+	// other hosts are unaffected
+	_, err = doCall("http://ok.com/")
+	assert.NoError(t, err)
+
+	// once breaker times out, a trial call is allowed but fails so breaker reopens for twice as long
+	setNow(time.Minute + 10*time.Second)
+	retryOn = t0.Add(time.Minute + 30*time.Second)
+	c, err = doCall("http://fail.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, &flows.WebhookBreaker{Host: "fail.com", State: flows.WebhookBreakerOpen, RetryOn: &retryOn}, c.Breaker)
+
+	// next trial call succeeds and closes the breaker
+	setNow(2 * time.Minute)
+	c, err = doCall("http://fail.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, &flows.WebhookBreaker{Host: "fail.com", State: flows.WebhookBreakerClosed}, c.Breaker)
+	assert.Equal(t, &flows.WebhookBreaker{Host: "fail.com", State: flows.WebhookBreakerClosed}, guard.Breaker("fail.com"))
+}
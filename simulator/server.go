@@ -0,0 +1,232 @@
+package simulator
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/gocommon/uuids"
+	"github.com/nyaruka/goflow/assets"
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/flows/resumes"
+	"github.com/nyaruka/goflow/flows/triggers"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxBodyBytes is the default limit on the size of request bodies
+const DefaultMaxBodyBytes = 1024 * 1024 * 10
+
+// StartRequest is a request to start a new simulated session, e.g.
+//
+//	{
+//	  "assets_id": "c2f47ef9-9f52-4f5b-b2b1-c6ad5a8b8a4c",
+//	  "trigger": {"type": "manual", "flow": {...}, "contact": {...}, "triggered_on": "2000-01-01T00:00:00Z"}
+//	}
+type StartRequest struct {
+	AssetsID AssetsID        `json:"assets_id" validate:"required"`
+	Trigger  json.RawMessage `json:"trigger" validate:"required"`
+}
+
+// ResumeRequest is a request to resume an existing simulated session, e.g.
+//
+//	{
+//	  "assets_id": "c2f47ef9-9f52-4f5b-b2b1-c6ad5a8b8a4c",
+//	  "session_uuid": "8a3fa2c4-c4c8-4b69-9a09-ba0c41a5c8df",
+//	  "resume": {"type": "msg", "msg": {...}, "contact": {...}, "resumed_on": "2000-01-01T00:00:00Z"}
+//	}
+type ResumeRequest struct {
+	AssetsID    AssetsID          `json:"assets_id" validate:"required"`
+	SessionUUID flows.SessionUUID `json:"session_uuid" validate:"required,uuid4"`
+	Resume      json.RawMessage   `json:"resume" validate:"required"`
+}
+
+// AssetsResponse is the response to an assets upload
+type AssetsResponse struct {
+	AssetsID AssetsID `json:"assets_id"`
+}
+
+// SessionResponse is the response to a start or resume request
+type SessionResponse struct {
+	Session  flows.Session        `json:"session"`
+	Events   []flows.Event        `json:"events"`
+	Segments []flows.Segment      `json:"segments"`
+	Summary  *flows.SprintSummary `json:"summary"`
+}
+
+// ErrorResponse is the response when a request can't be completed
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server is an HTTP handler which exposes endpoints for uploading assets and starting and resuming sessions
+type Server struct {
+	engine       flows.Engine
+	store        SessionStore
+	maxBodyBytes int64
+	mux          *http.ServeMux
+}
+
+// NewServer creates a new simulation server using the given engine and store
+func NewServer(eng flows.Engine, store SessionStore) *Server {
+	s := &Server{engine: eng, store: store, maxBodyBytes: DefaultMaxBodyBytes, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("/assets", s.handle(s.handleAssets))
+	s.mux.HandleFunc("/start", s.handle(s.handleStart))
+	s.mux.HandleFunc("/resume", s.handle(s.handleResume))
+	return s
+}
+
+// SetMaxBodyBytes sets the limit on the size of request bodies
+func (s *Server) SetMaxBodyBytes(max int64) { s.maxBodyBytes = max }
+
+// ServeHTTP handles a request to the server
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// a handler function returns a response object and HTTP status
+type handlerFunc func(*http.Request) (interface{}, int, error)
+
+func (s *Server) handle(fn handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeResponse(w, http.StatusMethodNotAllowed, &ErrorResponse{Error: "method not allowed"})
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+
+		resp, status, err := fn(r)
+		if err != nil {
+			writeResponse(w, status, &ErrorResponse{Error: err.Error()})
+			return
+		}
+		writeResponse(w, status, resp)
+	}
+}
+
+func (s *Server) handleAssets(r *http.Request) (interface{}, int, error) {
+	var data json.RawMessage
+	if err := jsonx.UnmarshalWithLimit(r.Body, &data, s.maxBodyBytes); err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(err, "error reading request")
+	}
+
+	// check that the assets are readable before we store them
+	if _, err := static.NewSource(data); err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(err, "error reading assets")
+	}
+
+	id := AssetsID(uuids.New())
+	if err := s.store.SaveAssets(id, data); err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "error saving assets")
+	}
+
+	return &AssetsResponse{AssetsID: id}, http.StatusOK, nil
+}
+
+func (s *Server) handleStart(r *http.Request) (interface{}, int, error) {
+	request := &StartRequest{}
+	if err := utils.UnmarshalAndValidateWithLimit(r.Body, request, s.maxBodyBytes); err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(err, "error reading request")
+	}
+
+	sa, status, err := s.loadAssets(request.AssetsID)
+	if err != nil {
+		return nil, status, err
+	}
+
+	trigger, err := triggers.ReadTrigger(sa, request.Trigger, assets.IgnoreMissing)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(err, "error reading trigger")
+	}
+
+	session, sprint, err := s.engine.NewSession(r.Context(), sa, trigger)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(err, "error starting session")
+	}
+
+	return s.saveSession(session, sprint)
+}
+
+func (s *Server) handleResume(r *http.Request) (interface{}, int, error) {
+	request := &ResumeRequest{}
+	if err := utils.UnmarshalAndValidateWithLimit(r.Body, request, s.maxBodyBytes); err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(err, "error reading request")
+	}
+
+	sa, status, err := s.loadAssets(request.AssetsID)
+	if err != nil {
+		return nil, status, err
+	}
+
+	sessionJSON, err := s.store.GetSession(request.SessionUUID)
+	if err != nil {
+		return nil, statusForStoreError(err), errors.Wrapf(err, "error loading session %s", request.SessionUUID)
+	}
+
+	session, err := s.engine.ReadSession(sa, sessionJSON, assets.IgnoreMissing)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "error reading session")
+	}
+
+	resume, err := resumes.ReadResume(sa, request.Resume, assets.IgnoreMissing)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(err, "error reading resume")
+	}
+
+	sprint, err := session.Resume(r.Context(), resume)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(err, "error resuming session")
+	}
+
+	return s.saveSession(session, sprint)
+}
+
+func (s *Server) loadAssets(id AssetsID) (flows.SessionAssets, int, error) {
+	data, err := s.store.GetAssets(id)
+	if err != nil {
+		return nil, statusForStoreError(err), errors.Wrapf(err, "error loading assets %s", id)
+	}
+
+	source, err := static.NewSource(data)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "error reading assets")
+	}
+
+	sa, err := engine.NewSessionAssets(envs.NewBuilder().Build(), source, nil)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "error creating session assets")
+	}
+	return sa, 0, nil
+}
+
+func (s *Server) saveSession(session flows.Session, sprint flows.Sprint) (interface{}, int, error) {
+	sessionJSON, err := jsonx.Marshal(session)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "error marshaling session")
+	}
+
+	if err := s.store.SaveSession(session.UUID(), sessionJSON); err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "error saving session")
+	}
+
+	return &SessionResponse{Session: session, Events: sprint.Events(), Segments: sprint.Segments(), Summary: sprint.Summary()}, http.StatusOK, nil
+}
+
+func statusForStoreError(err error) int {
+	if errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func writeResponse(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jsonx.MustMarshal(value))
+}
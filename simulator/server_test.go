@@ -0,0 +1,112 @@
+package simulator_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/buger/jsonparser"
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/simulator"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const triggerJSON = `{
+	"type": "manual",
+	"flow": {"uuid": "615b8a0f-588c-4d20-a05f-363b0b4ce6f4", "name": "Two Questions"},
+	"contact": {
+		"uuid": "ba96bf7f-bc2a-4873-a7c7-254d1927c4e3",
+		"name": "Ben Haggerty",
+		"status": "active",
+		"language": "eng",
+		"created_on": "2000-01-01T00:00:00Z",
+		"urns": ["tel:+12065551212"]
+	},
+	"triggered_on": "2000-01-01T00:00:00Z"
+}`
+
+const resumeJSON = `{
+	"type": "msg",
+	"msg": {"uuid": "9bf91c2b-ce58-4cef-aacc-281e03f69ab5", "urn": "tel:+12065551212", "text": "I like blue!"},
+	"resumed_on": "2000-01-01T00:00:00Z"
+}`
+
+func post(t *testing.T, server http.Handler, path string, body []byte) (int, json.RawMessage) {
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+	return w.Code, w.Body.Bytes()
+}
+
+func TestServer(t *testing.T) {
+	assetsJSON, err := os.ReadFile("../test/testdata/runner/two_questions.json")
+	require.NoError(t, err)
+
+	store := simulator.NewMemoryStore()
+	server := simulator.NewServer(simulator.NewEngineBuilder(nil).Build(), store)
+
+	// only POST requests are supported
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/start", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	// upload invalid assets
+	status, body := post(t, server, "/assets", []byte(`{"flows": 123}`))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, string(body), "error reading assets")
+
+	// upload valid assets
+	status, body = post(t, server, "/assets", assetsJSON)
+	assert.Equal(t, http.StatusOK, status)
+
+	assetsID, err := jsonparser.GetString(body, "assets_id")
+	require.NoError(t, err)
+
+	// try to start with assets that don't exist
+	status, body = post(t, server, "/start", jsonx.MustMarshal(&simulator.StartRequest{AssetsID: "xyz", Trigger: []byte(triggerJSON)}))
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.JSONEq(t, `{"error": "error loading assets xyz: not found"}`, string(body))
+
+	// try to start without a trigger
+	status, body = post(t, server, "/start", []byte(`{"assets_id": "xyz"}`))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.JSONEq(t, `{"error": "error reading request: field 'trigger' is required"}`, string(body))
+
+	// start a session which should stop at the first question
+	status, body = post(t, server, "/start", jsonx.MustMarshal(&simulator.StartRequest{AssetsID: simulator.AssetsID(assetsID), Trigger: []byte(triggerJSON)}))
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	sessionStatus, _ := jsonparser.GetString(body, "session", "status")
+	assert.Equal(t, "waiting", sessionStatus)
+
+	sessionUUID, _ := jsonparser.GetString(body, "session", "uuid")
+	_, err = store.GetSession(flows.SessionUUID(sessionUUID))
+	assert.NoError(t, err)
+
+	// try to resume a session that doesn't exist
+	status, _ = post(t, server, "/resume", jsonx.MustMarshal(&simulator.ResumeRequest{
+		AssetsID:    simulator.AssetsID(assetsID),
+		SessionUUID: flows.SessionUUID("ffb2a3c6-0ec4-4d33-9ff4-4e3b3b1a1c8d"),
+		Resume:      []byte(resumeJSON),
+	}))
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// resume our session with an answer
+	status, body = post(t, server, "/resume", jsonx.MustMarshal(&simulator.ResumeRequest{
+		AssetsID:    simulator.AssetsID(assetsID),
+		SessionUUID: flows.SessionUUID(sessionUUID),
+		Resume:      []byte(resumeJSON),
+	}))
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	eventType, _ := jsonparser.GetString(body, "events", "[0]", "type")
+	assert.Equal(t, "msg_received", eventType)
+
+	numErrors, _ := jsonparser.GetInt(body, "summary", "errors")
+	assert.Equal(t, int64(0), numErrors)
+}
@@ -0,0 +1,183 @@
+package simulator
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/services/simulation"
+	"github.com/nyaruka/goflow/services/webhooks"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// limits on the size of webhook responses and requests
+const (
+	DefaultMaxWebhookBodyBytes    = 10000
+	DefaultMaxWebhookRequestBytes = 10000
+)
+
+// NewEngineBuilder creates an engine builder configured with stubbed versions of all services, so that simulations
+// don't call webhooks, send emails, open real tickets or transfer airtime. Webhook calls are answered with the given
+// fixtures, keyed by URL, and calls to other URLs fail as if the host couldn't be reached. Use WithLiveWebhooks to
+// make real webhook calls instead.
+func NewEngineBuilder(webhookFixtures map[string]*simulation.WebhookFixture) *engine.Builder {
+	httpClient := simulation.NewWebhookClient(webhookFixtures, nil)
+
+	return engine.NewBuilder().
+		WithEmailServiceFactory(func(flows.SessionAssets) (flows.EmailService, error) {
+			return &emailService{}, nil
+		}).
+		WithWebhookServiceFactory(newWebhookServiceFactory(httpClient, DefaultMaxWebhookBodyBytes, DefaultMaxWebhookRequestBytes)).
+		WithClassificationServiceFactory(func(c *flows.Classifier) (flows.ClassificationService, error) {
+			return &classificationService{}, nil
+		}).
+		WithTicketServiceFactory(func(t *flows.Ticketer) (flows.TicketService, error) {
+			return &ticketService{ticketer: t}, nil
+		}).
+		WithAirtimeServiceFactory(func(flows.SessionAssets) (flows.AirtimeService, error) {
+			return &airtimeService{}, nil
+		}).
+		WithDataCollectionServiceFactory(func(flows.SessionAssets) (flows.DataCollectionService, error) {
+			return &dataCollectionService{}, nil
+		}).
+		WithCommerceServiceFactory(func(flows.SessionAssets) (flows.CommerceService, error) {
+			return &commerceService{}, nil
+		}).
+		WithCallRecordingServiceFactory(func(flows.SessionAssets) (flows.CallRecordingService, error) {
+			return &callRecordingService{}, nil
+		}).
+		WithCallTransferServiceFactory(func(flows.SessionAssets) (flows.CallTransferService, error) {
+			return &callTransferService{}, nil
+		}).
+		WithCredentialServiceFactory(func(flows.SessionAssets) (flows.CredentialService, error) {
+			return &credentialService{}, nil
+		})
+}
+
+// WithLiveWebhooks makes the given simulator engine builder call webhooks for real with the given HTTP client, which
+// callers should only do if simulated flows are trusted to call the hosts they can reach
+func WithLiveWebhooks(b *engine.Builder, httpClient *http.Client, maxWebhookBodyBytes, maxWebhookRequestBytes int) *engine.Builder {
+	return b.WithWebhookServiceFactory(newWebhookServiceFactory(httpClient, maxWebhookBodyBytes, maxWebhookRequestBytes))
+}
+
+func newWebhookServiceFactory(httpClient *http.Client, maxWebhookBodyBytes, maxWebhookRequestBytes int) engine.WebhookServiceFactory {
+	return webhooks.NewServiceFactory(httpClient, nil, nil, map[string]string{"User-Agent": "goflow-simulator"}, maxWebhookBodyBytes, maxWebhookRequestBytes)
+}
+
+// email service which pretends to send emails
+type emailService struct{}
+
+func (s *emailService) Send(ctx context.Context, addresses []string, subject, body string) error {
+	return nil
+}
+
+func (s *emailService) SendEmail(ctx context.Context, email *flows.Email) error {
+	return nil
+}
+
+// classification service which never matches any intents
+type classificationService struct{}
+
+func (s *classificationService) Classify(ctx context.Context, env envs.Environment, input string, logHTTP flows.HTTPLogCallback) (*flows.Classification, error) {
+	return &flows.Classification{}, nil
+}
+
+// ticket service which opens tickets without contacting the ticketer
+type ticketService struct {
+	ticketer *flows.Ticketer
+}
+
+func (s *ticketService) Open(ctx context.Context, env envs.Environment, contact *flows.Contact, topic *flows.Topic, body string, assignee *flows.User, logHTTP flows.HTTPLogCallback) (*flows.Ticket, error) {
+	return flows.OpenTicket(s.ticketer, topic, body, assignee), nil
+}
+
+// airtime service which pretends to transfer the amount for the first configured currency
+type airtimeService struct{}
+
+func (s *airtimeService) Transfer(ctx context.Context, sender urns.URN, recipient urns.URN, amounts map[string]decimal.Decimal, logHTTP flows.HTTPLogCallback) (*flows.AirtimeTransfer, error) {
+	if len(amounts) == 0 {
+		return nil, errors.New("no amounts configured for transfer")
+	}
+
+	currencies := make([]string, 0, len(amounts))
+	for currency := range amounts {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	return &flows.AirtimeTransfer{
+		Sender:        sender,
+		Recipient:     recipient,
+		Currency:      currencies[0],
+		DesiredAmount: amounts[currencies[0]],
+		ActualAmount:  amounts[currencies[0]],
+	}, nil
+}
+
+// simulations don't have a real balance so nothing is known about what transfers are affordable
+func (s *airtimeService) Balance(ctx context.Context, logHTTP flows.HTTPLogCallback) (map[string]decimal.Decimal, error) {
+	return map[string]decimal.Decimal{}, nil
+}
+
+// data collection service which has no collections
+type dataCollectionService struct{}
+
+func (s *dataCollectionService) Query(ctx context.Context, env envs.Environment, collection string, query *flows.DataQuery, logHTTP flows.HTTPLogCallback) (*flows.DataPage, error) {
+	return nil, errors.Errorf("data collections aren't available in simulations")
+}
+
+// commerce service which has no products and can't place orders
+type commerceService struct{}
+
+func (s *commerceService) LookupProduct(ctx context.Context, env envs.Environment, productID string, logHTTP flows.HTTPLogCallback) (*flows.Product, error) {
+	return nil, errors.Errorf("product catalogs aren't available in simulations")
+}
+
+func (s *commerceService) PlaceOrder(ctx context.Context, env envs.Environment, contact *flows.Contact, order *flows.Order, logHTTP flows.HTTPLogCallback) (string, error) {
+	return "", errors.Errorf("orders can't be placed in simulations")
+}
+
+// call recording service which can't record since simulations don't have real calls
+type callRecordingService struct{}
+
+func (s *callRecordingService) StartRecording(ctx context.Context, call *flows.Call) error {
+	return errors.Errorf("calls can't be recorded in simulations")
+}
+
+func (s *callRecordingService) StopRecording(ctx context.Context, call *flows.Call) (string, error) {
+	return "", errors.Errorf("calls can't be recorded in simulations")
+}
+
+// call transfer service which can't transfer since simulations don't have real calls
+type callTransferService struct{}
+
+func (s *callTransferService) Forward(ctx context.Context, call *flows.Call, urn urns.URN) (*flows.CallTransfer, error) {
+	return nil, errors.Errorf("calls can't be forwarded in simulations")
+}
+
+func (s *callTransferService) JoinConference(ctx context.Context, call *flows.Call, conference string) (*flows.CallTransfer, error) {
+	return nil, errors.Errorf("conferences can't be joined in simulations")
+}
+
+// credential service which has no credentials so that simulations can't use real secrets
+type credentialService struct{}
+
+func (s *credentialService) Authorization(ctx context.Context, name string) (string, error) {
+	return "", errors.Errorf("credentials aren't available in simulations")
+}
+
+var _ flows.EmailService = (*emailService)(nil)
+var _ flows.ClassificationService = (*classificationService)(nil)
+var _ flows.TicketService = (*ticketService)(nil)
+var _ flows.AirtimeService = (*airtimeService)(nil)
+var _ flows.DataCollectionService = (*dataCollectionService)(nil)
+var _ flows.CommerceService = (*commerceService)(nil)
+var _ flows.CallRecordingService = (*callRecordingService)(nil)
+var _ flows.CallTransferService = (*callTransferService)(nil)
+var _ flows.CredentialService = (*credentialService)(nil)
@@ -0,0 +1,65 @@
+package simulator_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nyaruka/goflow/assets/static"
+	"github.com/nyaruka/goflow/envs"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/services/simulation"
+	"github.com/nyaruka/goflow/simulator"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooks(t *testing.T) {
+	requested := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"live": true}`))
+	}))
+	defer server.Close()
+
+	source, err := static.NewSource([]byte(`{}`))
+	require.NoError(t, err)
+
+	sa, err := engine.NewSessionAssets(envs.NewBuilder().Build(), source, nil)
+	require.NoError(t, err)
+
+	call := func(eng flows.Engine, url string) *flows.WebhookCall {
+		svc, err := eng.Services().Webhook(sa)
+		require.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+
+		c, _ := svc.Call(request)
+		return c
+	}
+
+	// by default webhook calls are answered with fixtures and never reach the host
+	eng := simulator.NewEngineBuilder(map[string]*simulation.WebhookFixture{
+		server.URL + "/fixture": {Status: 200, Headers: map[string]string{"Content-Type": "application/json"}, Body: `{"fixture": true}`},
+	}).Build()
+
+	c := call(eng, server.URL+"/fixture")
+	assert.Equal(t, 200, c.Response.StatusCode)
+	assert.Equal(t, `{"fixture": true}`, string(c.ResponseJSON))
+
+	c = call(eng, server.URL+"/other")
+	assert.Nil(t, c.Response)
+	assert.Equal(t, 0, requested)
+
+	// unless the caller opts in to live webhook calls
+	eng = simulator.WithLiveWebhooks(simulator.NewEngineBuilder(nil), http.DefaultClient, 10000, 10000).Build()
+
+	c = call(eng, server.URL+"/other")
+	assert.Equal(t, 200, c.Response.StatusCode)
+	assert.Equal(t, `{"live": true}`, string(c.ResponseJSON))
+	assert.Equal(t, 1, requested)
+}
@@ -0,0 +1,82 @@
+package simulator
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/pkg/errors"
+)
+
+// AssetsID is the identifier of a set of uploaded assets
+type AssetsID string
+
+// ErrNotFound is returned by stores when the requested item doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// SessionStore is the interface for something which can persist uploaded assets and sessions between requests
+type SessionStore interface {
+	GetAssets(AssetsID) (json.RawMessage, error)
+	SaveAssets(AssetsID, json.RawMessage) error
+	GetSession(flows.SessionUUID) (json.RawMessage, error)
+	SaveSession(flows.SessionUUID, json.RawMessage) error
+}
+
+// MemoryStore is a session store which keeps everything in memory
+type MemoryStore struct {
+	assets   map[AssetsID]json.RawMessage
+	sessions map[flows.SessionUUID]json.RawMessage
+	mutex    sync.RWMutex
+}
+
+// NewMemoryStore creates a new empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		assets:   make(map[AssetsID]json.RawMessage),
+		sessions: make(map[flows.SessionUUID]json.RawMessage),
+	}
+}
+
+// GetAssets gets the assets with the given id
+func (s *MemoryStore) GetAssets(id AssetsID) (json.RawMessage, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	data, found := s.assets[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+// SaveAssets saves the assets with the given id
+func (s *MemoryStore) SaveAssets(id AssetsID, data json.RawMessage) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.assets[id] = data
+	return nil
+}
+
+// GetSession gets the session with the given UUID
+func (s *MemoryStore) GetSession(uuid flows.SessionUUID) (json.RawMessage, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	data, found := s.sessions[uuid]
+	if !found {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+// SaveSession saves the session with the given UUID
+func (s *MemoryStore) SaveSession(uuid flows.SessionUUID, data json.RawMessage) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sessions[uuid] = data
+	return nil
+}
+
+var _ SessionStore = (*MemoryStore)(nil)
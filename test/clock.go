@@ -0,0 +1,140 @@
+package test
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/flows/resumes"
+)
+
+// Clock is a simulated clock for testing flows with time based waits. While in use it is the source of the current
+// time, and advancing it resumes sessions when anything they're waiting for falls due, i.e. wait timeouts, timers,
+// time waits and expirations. This lets flows which take days to complete be tested end-to-end in milliseconds.
+type Clock struct {
+	now time.Time
+}
+
+// NewClock creates a new clock at the given time and makes it the source of the current time
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	dates.SetNowSource(c)
+	return c
+}
+
+// Now returns the current time of this clock
+func (c *Clock) Now() time.Time { return c.now }
+
+// Stop restores the system clock as the source of the current time
+func (c *Clock) Stop() {
+	dates.SetNowSource(dates.DefaultNowSource)
+}
+
+// Advance moves this clock forward by the given duration. Each time something that the given session is waiting for
+// falls due, the clock stops at that time to resume the session. Returns the sprints from those resumes.
+func (c *Clock) Advance(ctx context.Context, session flows.Session, d time.Duration) ([]flows.Sprint, error) {
+	until := c.now.Add(d)
+	sprints := make([]flows.Sprint, 0)
+
+	for {
+		next := nextWakeup(session)
+		if next == nil || next.at.After(until) {
+			break
+		}
+
+		if next.at.After(c.now) {
+			c.now = next.at
+		}
+
+		sprint, err := session.Resume(ctx, next.resume(session))
+		if err != nil {
+			return sprints, err
+		}
+		sprints = append(sprints, sprint)
+	}
+
+	c.now = until
+	return sprints, nil
+}
+
+// something that a waiting session will be resumed by at a point in time
+type wakeup struct {
+	at     time.Time
+	resume func(flows.Session) flows.Resume
+}
+
+// works out the next thing that the given session will be resumed by, if anything
+func nextWakeup(session flows.Session) *wakeup {
+	if session.Status() != flows.SessionStatusWaiting {
+		return nil
+	}
+
+	var run flows.Run
+	for _, r := range session.Runs() {
+		if r.Status() == flows.RunStatusWaiting {
+			run = r
+		}
+	}
+	if run == nil {
+		return nil
+	}
+
+	wakeups := make([]*wakeup, 0)
+
+	for _, t := range run.Timers() {
+		name := t.Name
+		wakeups = append(wakeups, &wakeup{t.FiresOn, func(s flows.Session) flows.Resume {
+			return resumes.NewTimerFired(s.Environment(), nil, name)
+		}})
+	}
+
+	timeout := func(e flows.Event, seconds *int) {
+		if seconds != nil {
+			wakeups = append(wakeups, &wakeup{e.CreatedOn().Add(time.Duration(*seconds) * time.Second), func(s flows.Session) flows.Resume {
+				return resumes.NewWaitTimeout(s.Environment(), nil)
+			}})
+		}
+	}
+
+	// the run expires if it's still waiting when its expiry comes around
+	if run.ExpiresOn() != nil {
+		wakeups = append(wakeups, &wakeup{*run.ExpiresOn(), func(s flows.Session) flows.Resume {
+			return resumes.NewExpiration(s.Environment(), nil)
+		}})
+	}
+
+	// look for the event which started the current wait
+	evts := run.Events()
+waitLoop:
+	for i := len(evts) - 1; i >= 0; i-- {
+		switch typed := evts[i].(type) {
+		case *events.MsgWaitEvent:
+			timeout(typed, typed.TimeoutSeconds)
+			break waitLoop
+		case *events.DigitsWaitEvent:
+			timeout(typed, typed.TimeoutSeconds)
+			break waitLoop
+		case *events.RaceWaitEvent:
+			timeout(typed, typed.TimeoutSeconds)
+			break waitLoop
+		case *events.DialWaitEvent:
+			break waitLoop
+		case *events.SessionScheduledEvent:
+			wakeups = append(wakeups, &wakeup{typed.ResumeOn, func(s flows.Session) flows.Resume {
+				return resumes.NewTimeReached(s.Environment(), nil)
+			}})
+			break waitLoop
+		}
+	}
+
+	if len(wakeups) == 0 {
+		return nil
+	}
+
+	// timers come first if things fall due at the same time, as they're most specific
+	sort.SliceStable(wakeups, func(i, j int) bool { return wakeups[i].at.Before(wakeups[j].at) })
+	return wakeups[0]
+}
@@ -0,0 +1,114 @@
+package test_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/goflow/flows"
+	"github.com/nyaruka/goflow/flows/events"
+	"github.com/nyaruka/goflow/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dripAssetsJSON = `{
+	"flows": [
+		{
+			"uuid": "1b462ce8-983a-4393-b133-e15a0efdb70c",
+			"name": "Drip",
+			"spec_version": "13.0",
+			"language": "eng",
+			"type": "messaging",
+			"nodes": [
+				{
+					"uuid": "9a4d3b0c-2f1e-4c8d-b7a6-5e4f3d2c1b0a",
+					"actions": [{"uuid": "3f1b2a4c-5d6e-4f7a-8b9c-0d1e2f3a4b5c", "type": "send_msg", "text": "Day 1"}],
+					"router": {
+						"type": "switch",
+						"wait": {"type": "time", "until": "1d"},
+						"categories": [{"uuid": "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f", "name": "Done", "exit_uuid": "6d7e8f9a-0b1c-4d2e-9f3a-4b5c6d7e8f9a"}],
+						"default_category_uuid": "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f",
+						"operand": "@input.text",
+						"cases": []
+					},
+					"exits": [{"uuid": "6d7e8f9a-0b1c-4d2e-9f3a-4b5c6d7e8f9a", "destination_uuid": "7e8f9a0b-1c2d-4e3f-8a4b-5c6d7e8f9a0b"}]
+				},
+				{
+					"uuid": "7e8f9a0b-1c2d-4e3f-8a4b-5c6d7e8f9a0b",
+					"actions": [{"uuid": "8f9a0b1c-2d3e-4f4a-9b5c-6d7e8f9a0b1c", "type": "send_msg", "text": "Day 2, are you still there?"}],
+					"router": {
+						"type": "switch",
+						"wait": {"type": "msg", "timeout": {"seconds": 172800, "category_uuid": "0b1c2d3e-4f5a-4b6c-8d7e-8f9a0b1c2d3e"}},
+						"categories": [
+							{"uuid": "1c2d3e4f-5a6b-4c7d-9e8f-9a0b1c2d3e4f", "name": "All Responses", "exit_uuid": "2d3e4f5a-6b7c-4d8e-8f9a-0b1c2d3e4f5a"},
+							{"uuid": "0b1c2d3e-4f5a-4b6c-8d7e-8f9a0b1c2d3e", "name": "No Response", "exit_uuid": "3e4f5a6b-7c8d-4e9f-9a0b-1c2d3e4f5a6b"}
+						],
+						"default_category_uuid": "1c2d3e4f-5a6b-4c7d-9e8f-9a0b1c2d3e4f",
+						"operand": "@input.text",
+						"cases": []
+					},
+					"exits": [
+						{"uuid": "2d3e4f5a-6b7c-4d8e-8f9a-0b1c2d3e4f5a"},
+						{"uuid": "3e4f5a6b-7c8d-4e9f-9a0b-1c2d3e4f5a6b", "destination_uuid": "4f5a6b7c-8d9e-4f0a-8b1c-2d3e4f5a6b7c"}
+					]
+				},
+				{
+					"uuid": "4f5a6b7c-8d9e-4f0a-8b1c-2d3e4f5a6b7c",
+					"actions": [{"uuid": "5a6b7c8d-9e0f-4a1b-9c2d-3e4f5a6b7c8d", "type": "send_msg", "text": "We missed you"}],
+					"exits": [{"uuid": "6b7c8d9e-0f1a-4b2c-8d3e-4f5a6b7c8d9e"}]
+				}
+			]
+		}
+	]
+}`
+
+func TestClock(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	clock := test.NewClock(start)
+	defer clock.Stop()
+
+	_, session, sprint := test.NewSessionBuilder().WithAssetsJSON([]byte(dripAssetsJSON)).WithFlow("1b462ce8-983a-4393-b133-e15a0efdb70c").MustBuild()
+	assert.Equal(t, []string{"Day 1"}, sentMessages(sprint))
+	assert.Equal(t, flows.SessionStatusWaiting, session.Status())
+
+	// nothing is due yet
+	sprints, err := clock.Advance(ctx, session, 12*time.Hour)
+	require.NoError(t, err)
+	assert.Len(t, sprints, 0)
+	assert.Equal(t, start.Add(12*time.Hour), clock.Now())
+
+	// the time wait is reached...
+	sprints, err = clock.Advance(ctx, session, 24*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, sprints, 1)
+	assert.Equal(t, []string{"Day 2, are you still there?"}, sentMessages(sprints[0]))
+	assert.Equal(t, flows.SessionStatusWaiting, session.Status())
+	assert.Equal(t, start.Add(36*time.Hour), clock.Now())
+
+	// and then the msg wait times out at exactly two days after it began
+	sprints, err = clock.Advance(ctx, session, 7*24*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, sprints, 1)
+	assert.Equal(t, []string{"We missed you"}, sentMessages(sprints[0]))
+	assert.Equal(t, start.Add(72*time.Hour), sprints[0].Events()[0].CreatedOn())
+	assert.Equal(t, flows.SessionStatusCompleted, session.Status())
+
+	// once the session has ended, advancing the clock does nothing more
+	sprints, err = clock.Advance(ctx, session, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Len(t, sprints, 0)
+}
+
+func sentMessages(sprint flows.Sprint) []string {
+	texts := make([]string, 0)
+	for _, e := range sprint.Events() {
+		if typed, isMsg := e.(*events.MsgCreatedEvent); isMsg {
+			texts = append(texts, typed.Msg.Text())
+		}
+	}
+	return texts
+}
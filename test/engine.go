@@ -1,22 +1,41 @@
 package test
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/nyaruka/gocommon/httpx"
 	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/assets"
 	"github.com/nyaruka/goflow/envs"
 	"github.com/nyaruka/goflow/flows"
 	"github.com/nyaruka/goflow/flows/engine"
+	"github.com/nyaruka/goflow/services/credentials/static"
 	"github.com/nyaruka/goflow/services/webhooks"
 
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
+	"golang.org/x/exp/slices"
 )
 
+// ReferencedContacts are the other contacts which can be loaded as related contacts in testing
+var ReferencedContacts = []string{`{
+	"uuid": "0cb17b2a-3bfe-4a19-8c99-98ab9561045d",
+	"name": "Sarah Haggerty",
+	"language": "eng",
+	"status": "active",
+	"created_on": "2018-06-20T11:40:30.123456789-00:00",
+	"urns": ["tel:+12065551313"]
+}`}
+
+// Credentials are the named credentials available to flows in testing
+var Credentials = map[string]string{"shop_api": "Bearer sesame"}
+
 // NewEngine creates an engine instance for testing
 func NewEngine() flows.Engine {
 	retries := httpx.NewFixedRetries(1*time.Millisecond, 2*time.Millisecond)
@@ -25,7 +44,7 @@ func NewEngine() flows.Engine {
 		WithEmailServiceFactory(func(s flows.SessionAssets) (flows.EmailService, error) {
 			return newEmailService(), nil
 		}).
-		WithWebhookServiceFactory(webhooks.NewServiceFactory(http.DefaultClient, retries, nil, map[string]string{"User-Agent": "goflow-testing"}, 10000)).
+		WithWebhookServiceFactory(webhooks.NewServiceFactory(http.DefaultClient, retries, nil, map[string]string{"User-Agent": "goflow-testing"}, 10000, 10000)).
 		WithClassificationServiceFactory(func(c *flows.Classifier) (flows.ClassificationService, error) {
 			return newClassificationService(c), nil
 		}).
@@ -36,6 +55,23 @@ func NewEngine() flows.Engine {
 			return NewTicketService(t), nil
 		}).
 		WithAirtimeServiceFactory(func(flows.SessionAssets) (flows.AirtimeService, error) { return newAirtimeService("RWF"), nil }).
+		WithDataCollectionServiceFactory(func(flows.SessionAssets) (flows.DataCollectionService, error) {
+			return NewDataCollectionService(), nil
+		}).
+		WithCommerceServiceFactory(func(flows.SessionAssets) (flows.CommerceService, error) {
+			return NewCommerceService(), nil
+		}).
+		WithCallRecordingServiceFactory(func(flows.SessionAssets) (flows.CallRecordingService, error) {
+			return NewCallRecordingService(), nil
+		}).
+		WithCallTransferServiceFactory(func(flows.SessionAssets) (flows.CallTransferService, error) {
+			return NewCallTransferService(), nil
+		}).
+		WithCredentialServiceFactory(static.NewServiceFactory(Credentials)).
+		WithExchangeRateServiceFactory(func(flows.SessionAssets) (flows.ExchangeRateService, error) {
+			return NewExchangeRateService(), nil
+		}).
+		WithContactProvider(NewContactProvider(ReferencedContacts...)).
 		Build()
 }
 
@@ -46,7 +82,11 @@ func newEmailService() *emailService {
 	return &emailService{}
 }
 
-func (s *emailService) Send(addresses []string, subject, body string) error {
+func (s *emailService) Send(ctx context.Context, addresses []string, subject, body string) error {
+	return nil
+}
+
+func (s *emailService) SendEmail(ctx context.Context, email *flows.Email) error {
 	return nil
 }
 
@@ -59,7 +99,7 @@ func newClassificationService(classifier *flows.Classifier) *classificationServi
 	return &classificationService{classifier: classifier}
 }
 
-func (s *classificationService) Classify(env envs.Environment, input string, logHTTP flows.HTTPLogCallback) (*flows.Classification, error) {
+func (s *classificationService) Classify(ctx context.Context, env envs.Environment, input string, logHTTP flows.HTTPLogCallback) (*flows.Classification, error) {
 	classifierIntents := s.classifier.Intents()
 	extractedIntents := make([]flows.ExtractedIntent, len(s.classifier.Intents()))
 	confidence := decimal.RequireFromString("0.5")
@@ -107,7 +147,7 @@ func NewTicketService(ticketer *flows.Ticketer) flows.TicketService {
 	return &ticketService{ticketer: ticketer}
 }
 
-func (s *ticketService) Open(env envs.Environment, contact *flows.Contact, topic *flows.Topic, body string, assignee *flows.User, logHTTP flows.HTTPLogCallback) (*flows.Ticket, error) {
+func (s *ticketService) Open(ctx context.Context, env envs.Environment, contact *flows.Contact, topic *flows.Topic, body string, assignee *flows.User, logHTTP flows.HTTPLogCallback) (*flows.Ticket, error) {
 	if strings.Contains(body, "fail") {
 		logHTTP(&flows.HTTPLog{
 			HTTPLogWithoutTime: &flows.HTTPLogWithoutTime{
@@ -156,7 +196,7 @@ func newAirtimeService(currency string) *airtimeService {
 	return &airtimeService{fixedCurrency: currency}
 }
 
-func (s *airtimeService) Transfer(sender urns.URN, recipient urns.URN, amounts map[string]decimal.Decimal, logHTTP flows.HTTPLogCallback) (*flows.AirtimeTransfer, error) {
+func (s *airtimeService) Transfer(ctx context.Context, sender urns.URN, recipient urns.URN, amounts map[string]decimal.Decimal, logHTTP flows.HTTPLogCallback) (*flows.AirtimeTransfer, error) {
 	logHTTP(&flows.HTTPLog{
 		HTTPLogWithoutTime: &flows.HTTPLogWithoutTime{
 			LogWithoutTime: &httpx.LogWithoutTime{
@@ -188,4 +228,259 @@ func (s *airtimeService) Transfer(sender urns.URN, recipient urns.URN, amounts m
 	return transfer, nil
 }
 
+func (s *airtimeService) Balance(ctx context.Context, logHTTP flows.HTTPLogCallback) (map[string]decimal.Decimal, error) {
+	return map[string]decimal.Decimal{}, nil
+}
+
 var _ flows.AirtimeService = (*airtimeService)(nil)
+
+// implementation of a data collection service for testing which queries a fixed collection of products, and only
+// supports filters of the form field = value
+type dataCollectionService struct{}
+
+// NewDataCollectionService creates a new data collection service for testing
+func NewDataCollectionService() flows.DataCollectionService {
+	return &dataCollectionService{}
+}
+
+var testProducts = []map[string]any{
+	{"name": "Maize", "category": "cereal", "price": 350},
+	{"name": "Beans", "category": "legume", "price": 800},
+	{"name": "Rice", "category": "cereal", "price": 1200},
+	{"name": "Sorghum", "category": "cereal", "price": 500},
+}
+
+func (s *dataCollectionService) Query(ctx context.Context, env envs.Environment, collection string, query *flows.DataQuery, logHTTP flows.HTTPLogCallback) (*flows.DataPage, error) {
+	logHTTP(&flows.HTTPLog{
+		HTTPLogWithoutTime: &flows.HTTPLogWithoutTime{
+			LogWithoutTime: &httpx.LogWithoutTime{
+				URL:        fmt.Sprintf("http://data.example.com/%s", collection),
+				StatusCode: 200,
+				Request:    fmt.Sprintf("GET /%s HTTP/1.1\r\nHost: data.example.com\r\n\r\n", collection),
+				Response:   "HTTP/1.0 200 OK\r\nContent-Length: 15\r\n\r\n{\"status\":\"ok\"}",
+				ElapsedMS:  1,
+				Retries:    0,
+			},
+			Status: flows.CallStatusSuccess,
+		},
+		CreatedOn: time.Date(2019, 10, 16, 13, 59, 30, 123456789, time.UTC),
+	})
+
+	if collection != "products" {
+		return nil, errors.Errorf("no such collection '%s'", collection)
+	}
+
+	rows := make([]map[string]any, 0, len(testProducts))
+	if query.Filter != "" {
+		field, value, valid := strings.Cut(query.Filter, "=")
+		if !valid {
+			return nil, errors.Errorf("unsupported filter '%s'", query.Filter)
+		}
+		field, value = strings.TrimSpace(field), strings.Trim(strings.TrimSpace(value), `"`)
+
+		for _, row := range testProducts {
+			if fmt.Sprint(row[field]) == value {
+				rows = append(rows, row)
+			}
+		}
+	} else {
+		rows = append(rows, testProducts...)
+	}
+
+	if query.Sort != "" {
+		field := strings.TrimPrefix(query.Sort, "-")
+		desc := field != query.Sort
+
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i][field], rows[j][field]
+			if desc {
+				a, b = b, a
+			}
+			if ai, ok := a.(int); ok {
+				return ai < b.(int)
+			}
+			return fmt.Sprint(a) < fmt.Sprint(b)
+		})
+	}
+
+	total := len(rows)
+	if query.Offset < len(rows) {
+		rows = rows[query.Offset:]
+	} else {
+		rows = nil
+	}
+	if query.Limit > 0 && query.Limit < len(rows) {
+		rows = rows[:query.Limit]
+	}
+
+	return &flows.DataPage{Rows: rows, Total: total}, nil
+}
+
+var _ flows.DataCollectionService = (*dataCollectionService)(nil)
+
+// implementation of a commerce service for testing which has a fixed catalog of products priced in RWF
+type commerceService struct{}
+
+// NewCommerceService creates a new commerce service for testing
+func NewCommerceService() flows.CommerceService {
+	return &commerceService{}
+}
+
+var testCatalog = map[string]*flows.Product{
+	"maize": {ID: "maize", Name: "Maize", Price: decimal.RequireFromString("350"), Currency: "RWF"},
+	"beans": {ID: "beans", Name: "Beans", Price: decimal.RequireFromString("800"), Currency: "RWF"},
+	"rice":  {ID: "rice", Name: "Rice", Price: decimal.RequireFromString("1200"), Currency: "RWF"},
+	"pens":  {ID: "pens", Name: "Pens", Price: decimal.RequireFromString("2.50"), Currency: "USD"},
+}
+
+func (s *commerceService) LookupProduct(ctx context.Context, env envs.Environment, productID string, logHTTP flows.HTTPLogCallback) (*flows.Product, error) {
+	logHTTP(newCommerceLog(http.MethodGet, fmt.Sprintf("products/%s", productID)))
+
+	product := testCatalog[productID]
+	if product == nil {
+		return nil, errors.Errorf("no such product '%s'", productID)
+	}
+	return product, nil
+}
+
+func (s *commerceService) PlaceOrder(ctx context.Context, env envs.Environment, contact *flows.Contact, order *flows.Order, logHTTP flows.HTTPLogCallback) (string, error) {
+	logHTTP(newCommerceLog(http.MethodPost, "orders"))
+
+	if len(order.Items) == 0 {
+		return "", errors.New("can't place an empty order")
+	}
+	return "ORD-1001", nil
+}
+
+func newCommerceLog(method, path string) *flows.HTTPLog {
+	return &flows.HTTPLog{
+		HTTPLogWithoutTime: &flows.HTTPLogWithoutTime{
+			LogWithoutTime: &httpx.LogWithoutTime{
+				URL:        fmt.Sprintf("http://shop.example.com/%s", path),
+				StatusCode: 200,
+				Request:    fmt.Sprintf("%s /%s HTTP/1.1\r\nHost: shop.example.com\r\n\r\n", method, path),
+				Response:   "HTTP/1.0 200 OK\r\nContent-Length: 15\r\n\r\n{\"status\":\"ok\"}",
+				ElapsedMS:  1,
+				Retries:    0,
+			},
+			Status: flows.CallStatusSuccess,
+		},
+		CreatedOn: time.Date(2019, 10, 16, 13, 59, 30, 123456789, time.UTC),
+	}
+}
+
+var _ flows.CommerceService = (*commerceService)(nil)
+
+// implementation of an exchange rate service for testing which has fixed rates against the US dollar
+type exchangeRateService struct{}
+
+// NewExchangeRateService creates a new exchange rate service for testing
+func NewExchangeRateService() flows.ExchangeRateService {
+	return &exchangeRateService{}
+}
+
+// how many units of each currency one US dollar is worth
+var testExchangeRates = map[string]decimal.Decimal{
+	"USD": decimal.RequireFromString("1"),
+	"EUR": decimal.RequireFromString("0.9"),
+	"RWF": decimal.RequireFromString("1250"),
+}
+
+func (s *exchangeRateService) Rate(ctx context.Context, from, to string, logHTTP flows.HTTPLogCallback) (decimal.Decimal, error) {
+	logHTTP(&flows.HTTPLog{
+		HTTPLogWithoutTime: &flows.HTTPLogWithoutTime{
+			LogWithoutTime: &httpx.LogWithoutTime{
+				URL:        fmt.Sprintf("http://rates.example.com/%s/%s", from, to),
+				StatusCode: 200,
+				Request:    fmt.Sprintf("GET /%s/%s HTTP/1.1\r\nHost: rates.example.com\r\n\r\n", from, to),
+				Response:   "HTTP/1.0 200 OK\r\nContent-Length: 2\r\n\r\n{}",
+				ElapsedMS:  1,
+				Retries:    0,
+			},
+			Status: flows.CallStatusSuccess,
+		},
+		CreatedOn: time.Date(2019, 10, 16, 13, 59, 30, 123456789, time.UTC),
+	})
+
+	fromRate, fromExists := testExchangeRates[from]
+	toRate, toExists := testExchangeRates[to]
+	if !fromExists || !toExists {
+		return decimal.Zero, errors.Errorf("no exchange rate from %s to %s", from, to)
+	}
+	return toRate.Div(fromRate), nil
+}
+
+var _ flows.ExchangeRateService = (*exchangeRateService)(nil)
+
+// implementation of a call recording service for testing which stores recordings under the URN path
+type callRecordingService struct{}
+
+// NewCallRecordingService creates a new call recording service for testing
+func NewCallRecordingService() flows.CallRecordingService {
+	return &callRecordingService{}
+}
+
+func (s *callRecordingService) StartRecording(ctx context.Context, call *flows.Call) error {
+	return nil
+}
+
+func (s *callRecordingService) StopRecording(ctx context.Context, call *flows.Call) (string, error) {
+	return fmt.Sprintf("http://recordings.example.com/%s.mp3", call.URN().Path()), nil
+}
+
+var _ flows.CallRecordingService = (*callRecordingService)(nil)
+
+// implementation of a call transfer service for testing where numbers ending in 0000 and the "full" conference are
+// always busy
+type callTransferService struct{}
+
+// NewCallTransferService creates a new call transfer service for testing
+func NewCallTransferService() flows.CallTransferService {
+	return &callTransferService{}
+}
+
+func (s *callTransferService) Forward(ctx context.Context, call *flows.Call, urn urns.URN) (*flows.CallTransfer, error) {
+	if strings.HasSuffix(urn.Path(), "0000") {
+		return flows.NewCallTransfer(flows.CallTransferStatusBusy, 0), nil
+	}
+	return flows.NewCallTransfer(flows.CallTransferStatusCompleted, 42), nil
+}
+
+func (s *callTransferService) JoinConference(ctx context.Context, call *flows.Call, conference string) (*flows.CallTransfer, error) {
+	if conference == "full" {
+		return flows.NewCallTransfer(flows.CallTransferStatusBusy, 0), nil
+	}
+	return flows.NewCallTransfer(flows.CallTransferStatusCompleted, 120), nil
+}
+
+var _ flows.CallTransferService = (*callTransferService)(nil)
+
+// implementation of a contact provider for testing which reads contacts from JSON
+type contactProvider struct {
+	contacts []json.RawMessage
+}
+
+// NewContactProvider creates a new contact provider for testing from the given contact JSON
+func NewContactProvider(contacts ...string) flows.ContactProvider {
+	p := &contactProvider{}
+	for _, c := range contacts {
+		p.contacts = append(p.contacts, json.RawMessage(c))
+	}
+	return p
+}
+
+func (p *contactProvider) GetContacts(ctx context.Context, sa flows.SessionAssets, uuids []flows.ContactUUID) ([]*flows.Contact, error) {
+	contacts := make([]*flows.Contact, 0, len(uuids))
+	for _, data := range p.contacts {
+		contact, err := flows.ReadContact(sa, data, assets.IgnoreMissing)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(uuids, contact.UUID()) {
+			contacts = append(contacts, contact)
+		}
+	}
+	return contacts, nil
+}
+
+var _ flows.ContactProvider = (*contactProvider)(nil)
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -44,6 +45,13 @@ var sessionAssets = `{
             "address": "235326346322111",
             "schemes": ["facebook"],
             "roles": ["send", "receive"]
+        },
+        {
+            "uuid": "a4bf8a92-0b07-4b1d-a2a5-3c0ae4f1fa21",
+            "name": "WhatsApp Channel",
+            "address": "+12024560000",
+            "schemes": ["whatsapp"],
+            "roles": ["send", "receive"]
         }
     ],
     "classifiers": [
@@ -64,7 +72,8 @@ var sessionAssets = `{
     "topics": [
         {
             "uuid": "472a7a73-96cb-4736-b567-056d987cc5b4",
-            "name": "Weather"
+            "name": "Weather",
+            "queue": "weather-desk"
         },
         {
             "uuid": "daa356b6-32af-44f0-9d35-6126d55ec3e9",
@@ -255,12 +264,17 @@ var sessionAssets = `{
                 {
                     "name": "Kigali City",
                     "aliases": ["Kigali", "Kigari"],
+                    "boundary": [[-1.85, 29.95], [-1.85, 30.25], [-2.1, 30.25], [-2.1, 29.95]],
                     "children": [
                         {
                             "name": "Gasabo",
+                            "boundary": [[-1.85, 30.05], [-1.85, 30.25], [-1.97, 30.25], [-1.97, 30.05]],
                             "children": [
                                 {
-                                    "name": "Gisozi"
+                                    "name": "Gisozi",
+                                    "latitude": -1.9247,
+                                    "longitude": 30.0633,
+                                    "boundary": [[-1.91, 30.05], [-1.91, 30.08], [-1.94, 30.08], [-1.94, 30.05]]
                                 },
                                 {
                                     "name": "Ndera"
@@ -276,6 +290,21 @@ var sessionAssets = `{
             ]
         }
     ],
+    "lookup_tables": [
+        {
+            "key": "prices",
+            "name": "Prices",
+            "columns": ["item", "price", "unit"],
+            "rows": [
+                {"item": "Maize", "price": "350", "unit": "kg"},
+                {"item": "Beans", "price": "800", "unit": "kg"}
+            ]
+        }
+    ],
+    "relation_types": [
+        {"key": "caregiver", "name": "Caregiver"},
+        {"key": "household_head", "name": "Household Head"}
+    ],
     "resthooks": [
         {
             "slug": "new-registration", 
@@ -284,6 +313,24 @@ var sessionAssets = `{
             ]
         }
     ],
+    "templates": [
+        {
+            "uuid": "9c22b594-fcab-4b29-9bcb-ce4404894a80",
+            "name": "appointment",
+            "translations": [
+                {
+                    "channel": {"uuid": "57f1078f-88aa-46f4-a59a-948a5739c03d", "name": "My Android Phone"},
+                    "locale": "eng-US",
+                    "namespace": "2d40b45c_25cd_4965_9019_f05d0124c5fa",
+                    "content": "Hi {{1}}, your appointment is on {{2}}",
+                    "components": [
+                        {"type": "header", "name": "header", "content": "{{1}} appointment", "param_count": 1},
+                        {"type": "body", "name": "body", "content": "Hi {{1}}, your appointment is on {{2}}", "param_count": 2}
+                    ]
+                }
+            ]
+        }
+    ],
     "users": [
         {
             "email": "bob@nyaruka.com",
@@ -323,6 +370,9 @@ var sessionTrigger = `{
                 "text": "AACC55"
             }
         },
+        "relations": {
+            "caregiver": {"uuid": "0cb17b2a-3bfe-4a19-8c99-98ab9561045d", "name": "Sarah Haggerty"}
+        },
         "tickets": [
             {
                 "uuid": "e5f5a9b0-1c08-4e56-8f5c-92e00bc3cf52",
@@ -528,7 +578,7 @@ func CreateTestSession(testServerURL string, redact envs.RedactionPolicy) (flows
 
 	eng := NewEngine()
 
-	session, _, err := eng.NewSession(sa, trigger)
+	session, _, err := eng.NewSession(context.Background(), sa, trigger)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "error starting test session")
 	}
@@ -539,8 +589,18 @@ func CreateTestSession(testServerURL string, redact envs.RedactionPolicy) (flows
 		return nil, nil, errors.Wrap(err, "error reading resume")
 	}
 
-	sprint, err := session.Resume(resume)
-	return session, sprint.Events(), err
+	sprint, err := session.Resume(context.Background(), resume)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error resuming test session")
+	}
+
+	// give the session a cart so that commerce expressions and actions have something to work with
+	cart := flows.NewOrder("RWF")
+	cart.Add(testCatalog["maize"], 3)
+	cart.Add(testCatalog["beans"], 1)
+	session.SetCart(cart)
+
+	return session, sprint.Events(), nil
 }
 
 // CreateTestVoiceSession creates a standard example session for testing voice flows and actions
@@ -559,7 +619,7 @@ func CreateTestVoiceSession(testServerURL string) (flows.Session, []flows.Event,
 	}
 
 	eng := NewEngine()
-	session, sprint, err := eng.NewSession(sa, trigger)
+	session, sprint, err := eng.NewSession(context.Background(), sa, trigger)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "error starting test voice session")
 	}
@@ -633,6 +693,11 @@ func (b *SessionBuilder) WithEnvironment(env envs.Environment) *SessionBuilder {
 	return b
 }
 
+func (b *SessionBuilder) WithEngine(eng flows.Engine) *SessionBuilder {
+	b.engine = eng
+	return b
+}
+
 func (b *SessionBuilder) WithAssets(sa flows.SessionAssets) *SessionBuilder {
 	b.assets = sa
 	return b
@@ -723,7 +788,7 @@ func (b *SessionBuilder) Build() (flows.SessionAssets, flows.Session, flows.Spri
 		trigger = triggers.NewBuilder(b.env, flow.Reference(false), contact).Manual().Build()
 	}
 
-	s, sp, err := b.engine.NewSession(sa, trigger)
+	s, sp, err := b.engine.NewSession(context.Background(), sa, trigger)
 	return sa, s, sp, err
 }
 
@@ -753,7 +818,7 @@ func ResumeSession(session flows.Session, sa flows.SessionAssets, msgText string
 
 	msg := flows.NewMsgIn(flows.MsgUUID(uuids.New()), urns.NilURN, nil, msgText, nil)
 
-	sprint, err := session.Resume(resumes.NewMsg(session.Environment(), session.Contact(), msg))
+	sprint, err := session.Resume(context.Background(), resumes.NewMsg(session.Environment(), session.Contact(), msg))
 
 	return session, sprint, err
 }
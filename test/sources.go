@@ -0,0 +1,45 @@
+package test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/gocommon/uuids"
+)
+
+// NewSequentialNowSource creates a source of times 1 second after each other like dates.NewSequentialNowSource, but
+// which is safe for concurrent use, e.g. by the branches of a parallel router
+func NewSequentialNowSource(start time.Time) dates.NowSource {
+	return &lockedNowSource{source: dates.NewSequentialNowSource(start)}
+}
+
+// NewSeededUUIDGenerator creates a seeded UUID generator like uuids.NewSeededGenerator, but which is safe for
+// concurrent use, e.g. by the branches of a parallel router
+func NewSeededUUIDGenerator(seed int64) uuids.Generator {
+	return &lockedUUIDGenerator{generator: uuids.NewSeededGenerator(seed)}
+}
+
+type lockedNowSource struct {
+	mutex  sync.Mutex
+	source dates.NowSource
+}
+
+func (s *lockedNowSource) Now() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.source.Now()
+}
+
+type lockedUUIDGenerator struct {
+	mutex     sync.Mutex
+	generator uuids.Generator
+}
+
+func (g *lockedUUIDGenerator) Next() uuids.UUID {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.generator.Next()
+}
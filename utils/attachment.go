@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"net/url"
 	"regexp"
 	"strings"
 )
@@ -42,3 +43,16 @@ func (a Attachment) URL() string {
 	_, url := a.ToParts()
 	return url
 }
+
+// Redacted returns a copy of this attachment with any password in its URL replaced
+func (a Attachment) Redacted() Attachment {
+	contentType, rawURL := a.ToParts()
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return a
+	}
+	if contentType == "" {
+		return Attachment(u.Redacted())
+	}
+	return Attachment(contentType + ":" + u.Redacted())
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/pkg/errors"
+)
+
+// ExtNamespace describes a namespace of ext URNs such as `ext:employee:E1234`, which lets embedders keep the IDs of
+// their own identity systems apart and give them their own validation and display rules
+type ExtNamespace struct {
+	Label    string              // human readable name of the namespace, e.g. "Employee ID"
+	Validate func(string) error  // optional validation of IDs in this namespace
+	Format   func(string) string // optional formatting of IDs in this namespace for display
+}
+
+var registeredExtNamespaces = map[string]*ExtNamespace{}
+
+// RegisterExtNamespace registers a namespace of ext URNs, replacing any existing one with the same name
+func RegisterExtNamespace(name string, ns *ExtNamespace) {
+	registeredExtNamespaces[name] = ns
+}
+
+// GetExtNamespace returns the registered namespace with the given name, or nil if there is no such namespace
+func GetExtNamespace(name string) *ExtNamespace {
+	return registeredExtNamespaces[name]
+}
+
+// ParseExtURN splits the path of an ext URN into its namespace and ID. The namespace is empty if the URN isn't an ext
+// URN or doesn't have a namespace.
+func ParseExtURN(urn urns.URN) (string, string) {
+	scheme, path, _, _ := urn.ToParts()
+	if scheme != urns.ExternalScheme {
+		return "", path
+	}
+
+	if namespace, id, found := strings.Cut(path, ":"); found {
+		return namespace, id
+	}
+	return "", path
+}
+
+// ValidateURN validates the given URN, applying the rules of its namespace if it's a namespaced ext URN
+func ValidateURN(urn urns.URN) error {
+	if err := urn.Validate(); err != nil {
+		return err
+	}
+
+	namespace, id := ParseExtURN(urn)
+	if ns := registeredExtNamespaces[namespace]; ns != nil {
+		if id == "" {
+			return errors.Errorf("missing ID for ext namespace '%s'", namespace)
+		}
+		if ns.Validate != nil {
+			if err := ns.Validate(id); err != nil {
+				return errors.Wrapf(err, "invalid ID for ext namespace '%s'", namespace)
+			}
+		}
+	}
+	return nil
+}
+
+// FormatURN formats the given URN for display, using the formatting of its namespace if it's a namespaced ext URN
+// without a display value
+func FormatURN(urn urns.URN) string {
+	_, _, _, display := urn.ToParts()
+	namespace, id := ParseExtURN(urn)
+
+	if ns := registeredExtNamespaces[namespace]; ns != nil && display == "" {
+		if ns.Format != nil {
+			return ns.Format(id)
+		}
+		return id
+	}
+	return urn.Format()
+}
@@ -0,0 +1,66 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtNamespaces(t *testing.T) {
+	utils.RegisterExtNamespace("employee", &utils.ExtNamespace{
+		Label: "Employee ID",
+		Validate: func(id string) error {
+			if !strings.HasPrefix(id, "E") {
+				return errors.New("must start with E")
+			}
+			return nil
+		},
+		Format: func(id string) string { return "Employee #" + id[1:] },
+	})
+	utils.RegisterExtNamespace("member", &utils.ExtNamespace{Label: "Member ID"})
+	defer utils.RegisterExtNamespace("employee", nil)
+	defer utils.RegisterExtNamespace("member", nil)
+
+	assert.Equal(t, "Employee ID", utils.GetExtNamespace("employee").Label)
+	assert.Nil(t, utils.GetExtNamespace("xxx"))
+
+	tcs := []struct {
+		urn       urns.URN
+		namespace string
+		id        string
+		err       string
+		formatted string
+	}{
+		{"ext:employee:E1234", "employee", "E1234", "", "Employee #1234"},
+		{"ext:employee:E1234#Bob", "employee", "E1234", "", "Bob"},
+		{"ext:employee:1234", "employee", "1234", "invalid ID for ext namespace 'employee': must start with E", "Employee #234"},
+		{"ext:employee:", "employee", "", "missing ID for ext namespace 'employee'", ""},
+		{"ext:member:M-55", "member", "M-55", "", "M-55"},
+		{"ext:other:1234", "other", "1234", "", "other:1234"},
+		{"ext:1234", "", "1234", "", "1234"},
+		{"tel:+12065551212", "", "+12065551212", "", "(206) 555-1212"},
+		{"xyz:1234", "", "1234", "invalid scheme: 'xyz'", "1234"},
+	}
+
+	for _, tc := range tcs {
+		namespace, id := utils.ParseExtURN(tc.urn)
+		assert.Equal(t, tc.namespace, namespace, "namespace mismatch for %s", tc.urn)
+		assert.Equal(t, tc.id, id, "ID mismatch for %s", tc.urn)
+
+		err := utils.ValidateURN(tc.urn)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, "validation error mismatch for %s", tc.urn)
+		} else {
+			assert.NoError(t, err, "unexpected validation error for %s", tc.urn)
+		}
+
+		if tc.id != "" {
+			assert.Equal(t, tc.formatted, utils.FormatURN(tc.urn), "format mismatch for %s", tc.urn)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// mean radius of the earth in kilometers
+const earthRadiusKm = 6371.0
+
+var coordinatesRegex = regexp.MustCompile(`^\s*(-?\d+(?:\.\d+)?)\s*[,;\s]\s*(-?\d+(?:\.\d+)?)\s*$`)
+
+// Coordinates is a point on the earth given as a latitude and longitude in degrees
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// NewCoordinates creates new coordinates
+func NewCoordinates(latitude, longitude float64) Coordinates {
+	return Coordinates{Latitude: latitude, Longitude: longitude}
+}
+
+// ParseCoordinates tries to parse coordinates from text like "-1.9441,30.0619"
+func ParseCoordinates(s string) (Coordinates, bool) {
+	match := coordinatesRegex.FindStringSubmatch(s)
+	if match == nil {
+		return Coordinates{}, false
+	}
+
+	lat, _ := strconv.ParseFloat(match[1], 64)
+	lng, _ := strconv.ParseFloat(match[2], 64)
+
+	c := NewCoordinates(lat, lng)
+	return c, c.Valid()
+}
+
+// Valid returns whether these coordinates are within the valid ranges for latitude and longitude
+func (c Coordinates) Valid() bool {
+	return c.Latitude >= -90 && c.Latitude <= 90 && c.Longitude >= -180 && c.Longitude <= 180
+}
+
+// DistanceTo returns the great-circle distance in kilometers between these and the given coordinates
+func (c Coordinates) DistanceTo(other Coordinates) float64 {
+	lat1, lat2 := toRadians(c.Latitude), toRadians(other.Latitude)
+	dLat := lat2 - lat1
+	dLng := toRadians(other.Longitude - c.Longitude)
+
+	// haversine formula
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func (c Coordinates) String() string {
+	return fmt.Sprintf("%s,%s", strconv.FormatFloat(c.Latitude, 'f', -1, 64), strconv.FormatFloat(c.Longitude, 'f', -1, 64))
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// Polygon is a boundary given by its vertices, which is implicitly closed
+type Polygon []Coordinates
+
+// Contains returns whether the given coordinates are inside this polygon. Polygons are treated as planar which is
+// accurate enough for administrative boundaries that don't cross the antimeridian.
+func (p Polygon) Contains(c Coordinates) bool {
+	inside := false
+
+	// count how many edges a ray cast east from the point crosses
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		a, b := p[i], p[j]
+		if (a.Latitude > c.Latitude) != (b.Latitude > c.Latitude) {
+			crossing := a.Longitude + (c.Latitude-a.Latitude)*(b.Longitude-a.Longitude)/(b.Latitude-a.Latitude)
+			if c.Longitude < crossing {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// Centroid returns the average of the vertices of this polygon
+func (p Polygon) Centroid() Coordinates {
+	var lat, lng float64
+	for _, v := range p {
+		lat += v.Latitude
+		lng += v.Longitude
+	}
+	n := float64(len(p))
+	return NewCoordinates(lat/n, lng/n)
+}
@@ -0,0 +1,64 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoordinates(t *testing.T) {
+	tcs := []struct {
+		input    string
+		expected utils.Coordinates
+		valid    bool
+	}{
+		{"-1.9441,30.0619", utils.NewCoordinates(-1.9441, 30.0619), true},
+		{" -1.9441 , 30.0619 ", utils.NewCoordinates(-1.9441, 30.0619), true},
+		{"-1.9441 30.0619", utils.NewCoordinates(-1.9441, 30.0619), true},
+		{"51;-0", utils.NewCoordinates(51, 0), true},
+		{"91,30", utils.NewCoordinates(91, 30), false},
+		{"-1.9441,181", utils.NewCoordinates(-1.9441, 181), false},
+		{"-1.9441", utils.Coordinates{}, false},
+		{"Kigali", utils.Coordinates{}, false},
+		{"", utils.Coordinates{}, false},
+	}
+
+	for _, tc := range tcs {
+		actual, valid := utils.ParseCoordinates(tc.input)
+
+		assert.Equal(t, tc.valid, valid, "valid mismatch for input '%s'", tc.input)
+		assert.Equal(t, tc.expected, actual, "coordinates mismatch for input '%s'", tc.input)
+	}
+
+	assert.Equal(t, "-1.9441,30.0619", utils.NewCoordinates(-1.9441, 30.0619).String())
+
+	kigali := utils.NewCoordinates(-1.9441, 30.0619)
+	kampala := utils.NewCoordinates(0.3476, 32.5825)
+
+	assert.Equal(t, 0.0, kigali.DistanceTo(kigali))
+	assert.InDelta(t, 378.8, kigali.DistanceTo(kampala), 0.1)
+	assert.InDelta(t, kigali.DistanceTo(kampala), kampala.DistanceTo(kigali), 0.0001)
+}
+
+func TestPolygon(t *testing.T) {
+	// an L-shaped boundary
+	p := utils.Polygon{
+		utils.NewCoordinates(0, 0),
+		utils.NewCoordinates(0, 2),
+		utils.NewCoordinates(1, 2),
+		utils.NewCoordinates(1, 1),
+		utils.NewCoordinates(2, 1),
+		utils.NewCoordinates(2, 0),
+	}
+
+	assert.True(t, p.Contains(utils.NewCoordinates(0.5, 0.5)))
+	assert.True(t, p.Contains(utils.NewCoordinates(0.5, 1.5)))
+	assert.True(t, p.Contains(utils.NewCoordinates(1.5, 0.5)))
+	assert.False(t, p.Contains(utils.NewCoordinates(1.5, 1.5)))
+	assert.False(t, p.Contains(utils.NewCoordinates(-1, 0.5)))
+	assert.False(t, utils.Polygon{}.Contains(utils.NewCoordinates(0, 0)))
+
+	assert.Equal(t, utils.NewCoordinates(1, 1), p.Centroid())
+}
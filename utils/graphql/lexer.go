@@ -0,0 +1,253 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	typ    tokenType
+	value  string
+	line   int
+	column int
+}
+
+func (t *token) String() string {
+	switch t.typ {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return "string"
+	}
+	return fmt.Sprintf("'%s'", t.value)
+}
+
+// lexer splits a GraphQL document into tokens, skipping whitespace, commas and comments
+type lexer struct {
+	src    string
+	pos    int
+	line   int
+	column int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src, line: 1, column: 1}
+}
+
+func (l *lexer) errorf(format string, args ...any) error {
+	return errors.Errorf("syntax error at %d:%d: %s", l.line, l.column, fmt.Sprintf(format, args...))
+}
+
+func (l *lexer) peek(offset int) byte {
+	if l.pos+offset < len(l.src) {
+		return l.src[l.pos+offset]
+	}
+	return 0
+}
+
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.pos < len(l.src); i++ {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.column = 1
+		} else if l.src[l.pos]&0xC0 != 0x80 {
+			// only count the first byte of each UTF-8 sequence as a column
+			l.column++
+		}
+		l.pos++
+	}
+}
+
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.advance(1)
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.advance(1)
+			}
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"):
+			l.pos += len("\uFEFF")
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (*token, error) {
+	l.skipIgnored()
+
+	t := &token{line: l.line, column: l.column}
+	start := l.pos
+
+	if l.pos >= len(l.src) {
+		t.typ = tokenEOF
+		return t, nil
+	}
+
+	c := l.src[l.pos]
+
+	switch {
+	case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+		l.advance(1)
+		t.typ = tokenPunctuator
+	case c == '.':
+		if !strings.HasPrefix(l.src[l.pos:], "...") {
+			return nil, l.errorf("unexpected '.'")
+		}
+		l.advance(3)
+		t.typ = tokenPunctuator
+	case isNameStart(c):
+		for l.pos < len(l.src) && isNameContinue(l.src[l.pos]) {
+			l.advance(1)
+		}
+		t.typ = tokenName
+	case c == '-' || isDigit(c):
+		typ, err := l.readNumber()
+		if err != nil {
+			return nil, err
+		}
+		t.typ = typ
+	case c == '"':
+		if err := l.readString(); err != nil {
+			return nil, err
+		}
+		t.typ = tokenString
+	default:
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+		return nil, l.errorf("unexpected character %q", r)
+	}
+
+	t.value = l.src[start:l.pos]
+	return t, nil
+}
+
+func (l *lexer) readNumber() (tokenType, error) {
+	typ := tokenInt
+
+	if l.peek(0) == '-' {
+		l.advance(1)
+	}
+	if l.peek(0) == '0' {
+		l.advance(1)
+		if isDigit(l.peek(0)) {
+			return typ, l.errorf("invalid number, unexpected digit after 0")
+		}
+	} else if err := l.readDigits(); err != nil {
+		return typ, err
+	}
+
+	if l.peek(0) == '.' {
+		l.advance(1)
+		if err := l.readDigits(); err != nil {
+			return typ, err
+		}
+		typ = tokenFloat
+	}
+	if l.peek(0) == 'e' || l.peek(0) == 'E' {
+		l.advance(1)
+		if l.peek(0) == '+' || l.peek(0) == '-' {
+			l.advance(1)
+		}
+		if err := l.readDigits(); err != nil {
+			return typ, err
+		}
+		typ = tokenFloat
+	}
+
+	// a number can't be directly followed by a name or a dot
+	if c := l.peek(0); c == '.' || isNameStart(c) {
+		return typ, l.errorf("invalid number, unexpected %q", c)
+	}
+
+	return typ, nil
+}
+
+func (l *lexer) readDigits() error {
+	if !isDigit(l.peek(0)) {
+		return l.errorf("invalid number, expected digit")
+	}
+	for isDigit(l.peek(0)) {
+		l.advance(1)
+	}
+	return nil
+}
+
+func (l *lexer) readString() error {
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		l.advance(3)
+		for l.pos < len(l.src) {
+			if strings.HasPrefix(l.src[l.pos:], `\"""`) {
+				l.advance(4)
+			} else if strings.HasPrefix(l.src[l.pos:], `"""`) {
+				l.advance(3)
+				return nil
+			} else {
+				l.advance(1)
+			}
+		}
+		return l.errorf("unterminated string")
+	}
+
+	l.advance(1)
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '"':
+			l.advance(1)
+			return nil
+		case '\n', '\r':
+			return l.errorf("unterminated string")
+		case '\\':
+			l.advance(1)
+			switch esc := l.peek(0); esc {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				l.advance(1)
+			case 'u':
+				l.advance(1)
+				for i := 0; i < 4; i++ {
+					if !isHexDigit(l.peek(0)) {
+						return l.errorf("invalid unicode escape sequence")
+					}
+					l.advance(1)
+				}
+			default:
+				return l.errorf("invalid escape sequence")
+			}
+		default:
+			l.advance(1)
+		}
+	}
+	return l.errorf("unterminated string")
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
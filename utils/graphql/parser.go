@@ -0,0 +1,387 @@
+package graphql
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Validate parses the given GraphQL document and checks that it's a valid executable document, i.e. one or more
+// operations and fragments with no syntax errors, at most one anonymous operation, and no references to undefined
+// fragments. It doesn't have a schema so can't check that the fields or types exist.
+func Validate(query string) error {
+	p := &parser{lexer: newLexer(query), fragments: make(map[string]bool), spreads: make(map[string]*token)}
+	if err := p.advance(); err != nil {
+		return err
+	}
+	if err := p.parseDocument(); err != nil {
+		return err
+	}
+
+	anonymous := 0
+	names := make(map[string]bool, len(p.operations))
+	for _, op := range p.operations {
+		if op == "" {
+			anonymous++
+		} else if names[op] {
+			return errors.Errorf("operation '%s' is defined more than once", op)
+		}
+		names[op] = true
+	}
+	if anonymous > 0 && len(p.operations) > 1 {
+		return errors.New("anonymous operation must be the only operation in the document")
+	}
+
+	for name, t := range p.spreads {
+		if !p.fragments[name] {
+			return errors.Errorf("fragment '%s' used at %d:%d is not defined", name, t.line, t.column)
+		}
+	}
+
+	return nil
+}
+
+type parser struct {
+	lexer *lexer
+	token *token
+
+	operations []string // names of operations, empty if anonymous
+	fragments  map[string]bool
+	spreads    map[string]*token
+}
+
+func (p *parser) advance() error {
+	t, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = t
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return errors.Errorf("syntax error at %d:%d: %s", p.token.line, p.token.column, fmt.Sprintf(format, args...))
+}
+
+// whether the current token is the given punctuator
+func (p *parser) peek(punctuator string) bool {
+	return p.token.typ == tokenPunctuator && p.token.value == punctuator
+}
+
+// whether the current token is the given keyword
+func (p *parser) peekKeyword(keyword string) bool {
+	return p.token.typ == tokenName && p.token.value == keyword
+}
+
+// consumes the current token if it's the given punctuator
+func (p *parser) skip(punctuator string) (bool, error) {
+	if p.peek(punctuator) {
+		return true, p.advance()
+	}
+	return false, nil
+}
+
+// consumes the current token which must be the given punctuator
+func (p *parser) expect(punctuator string) error {
+	if !p.peek(punctuator) {
+		return p.errorf("expected '%s', found %s", punctuator, p.token)
+	}
+	return p.advance()
+}
+
+// consumes the current token which must be the given keyword
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.peekKeyword(keyword) {
+		return p.errorf("expected '%s', found %s", keyword, p.token)
+	}
+	return p.advance()
+}
+
+// consumes the current token which must be a name, returning it
+func (p *parser) expectName() (string, error) {
+	if p.token.typ != tokenName {
+		return "", p.errorf("expected name, found %s", p.token)
+	}
+	name := p.token.value
+	return name, p.advance()
+}
+
+// parses items between the given open and close punctuators, requiring at least one unless allowEmpty
+func (p *parser) many(open, close string, allowEmpty bool, item func() error) error {
+	if err := p.expect(open); err != nil {
+		return err
+	}
+	if !allowEmpty || !p.peek(close) {
+		if err := item(); err != nil {
+			return err
+		}
+	}
+	for {
+		if done, err := p.skip(close); err != nil || done {
+			return err
+		}
+		if err := item(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) parseDocument() error {
+	if p.token.typ == tokenEOF {
+		return p.errorf("expected operation or fragment, found %s", p.token)
+	}
+
+	for p.token.typ != tokenEOF {
+		if err := p.parseDefinition(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseDefinition() error {
+	if p.peek("{") {
+		p.operations = append(p.operations, "")
+		return p.parseSelectionSet()
+	}
+
+	if p.token.typ == tokenName {
+		switch p.token.value {
+		case "query", "mutation", "subscription":
+			return p.parseOperation()
+		case "fragment":
+			return p.parseFragment()
+		}
+	}
+
+	return p.errorf("expected operation or fragment, found %s", p.token)
+}
+
+func (p *parser) parseOperation() error {
+	// skip over the operation type
+	if err := p.advance(); err != nil {
+		return err
+	}
+
+	name := ""
+	if p.token.typ == tokenName {
+		name = p.token.value
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+
+	if p.peek("(") {
+		if err := p.many("(", ")", false, p.parseVariableDefinition); err != nil {
+			return err
+		}
+	}
+
+	if err := p.parseDirectives(true); err != nil {
+		return err
+	}
+
+	p.operations = append(p.operations, name)
+
+	return p.parseSelectionSet()
+}
+
+func (p *parser) parseFragment() error {
+	if err := p.expectKeyword("fragment"); err != nil {
+		return err
+	}
+
+	if p.peekKeyword("on") {
+		return p.errorf("expected fragment name, found %s", p.token)
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return err
+	}
+	if p.fragments[name] {
+		return errors.Errorf("fragment '%s' is defined more than once", name)
+	}
+	p.fragments[name] = true
+
+	if err := p.expectKeyword("on"); err != nil {
+		return err
+	}
+	if _, err := p.expectName(); err != nil {
+		return err
+	}
+	if err := p.parseDirectives(false); err != nil {
+		return err
+	}
+
+	return p.parseSelectionSet()
+}
+
+func (p *parser) parseVariableDefinition() error {
+	if err := p.parseVariable(); err != nil {
+		return err
+	}
+	if err := p.expect(":"); err != nil {
+		return err
+	}
+	if err := p.parseType(); err != nil {
+		return err
+	}
+	if isDefault, err := p.skip("="); err != nil {
+		return err
+	} else if isDefault {
+		if err := p.parseValue(true); err != nil {
+			return err
+		}
+	}
+	return p.parseDirectives(true)
+}
+
+func (p *parser) parseVariable() error {
+	if err := p.expect("$"); err != nil {
+		return err
+	}
+	_, err := p.expectName()
+	return err
+}
+
+func (p *parser) parseType() error {
+	if isList, err := p.skip("["); err != nil {
+		return err
+	} else if isList {
+		if err := p.parseType(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return err
+	}
+
+	_, err := p.skip("!")
+	return err
+}
+
+func (p *parser) parseSelectionSet() error {
+	return p.many("{", "}", false, p.parseSelection)
+}
+
+func (p *parser) parseSelection() error {
+	if p.peek("...") {
+		return p.parseFragmentSpread()
+	}
+	return p.parseField()
+}
+
+func (p *parser) parseField() error {
+	if _, err := p.expectName(); err != nil {
+		return err
+	}
+
+	// first name was an alias
+	if isAlias, err := p.skip(":"); err != nil {
+		return err
+	} else if isAlias {
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+	}
+
+	if err := p.parseArguments(false); err != nil {
+		return err
+	}
+	if err := p.parseDirectives(false); err != nil {
+		return err
+	}
+	if p.peek("{") {
+		return p.parseSelectionSet()
+	}
+	return nil
+}
+
+func (p *parser) parseFragmentSpread() error {
+	if err := p.expect("..."); err != nil {
+		return err
+	}
+
+	// a named fragment spread
+	if p.token.typ == tokenName && p.token.value != "on" {
+		p.spreads[p.token.value] = p.token
+		if err := p.advance(); err != nil {
+			return err
+		}
+		return p.parseDirectives(false)
+	}
+
+	// an inline fragment with an optional type condition
+	if p.peekKeyword("on") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+	}
+	if err := p.parseDirectives(false); err != nil {
+		return err
+	}
+	return p.parseSelectionSet()
+}
+
+func (p *parser) parseArguments(isConst bool) error {
+	if !p.peek("(") {
+		return nil
+	}
+
+	return p.many("(", ")", false, func() error {
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		return p.parseValue(isConst)
+	})
+}
+
+func (p *parser) parseDirectives(isConst bool) error {
+	for p.peek("@") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+		if err := p.parseArguments(isConst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseValue(isConst bool) error {
+	switch {
+	case p.peek("$"):
+		if isConst {
+			return p.errorf("unexpected variable in constant value")
+		}
+		return p.parseVariable()
+	case p.peek("["):
+		return p.many("[", "]", true, func() error { return p.parseValue(isConst) })
+	case p.peek("{"):
+		return p.many("{", "}", true, func() error {
+			if _, err := p.expectName(); err != nil {
+				return err
+			}
+			if err := p.expect(":"); err != nil {
+				return err
+			}
+			return p.parseValue(isConst)
+		})
+	case p.token.typ == tokenInt, p.token.typ == tokenFloat, p.token.typ == tokenString, p.token.typ == tokenName:
+		// names are booleans, null or enum values
+		return p.advance()
+	}
+
+	return p.errorf("expected value, found %s", p.token)
+}
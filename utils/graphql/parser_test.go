@@ -0,0 +1,58 @@
+package graphql_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/utils/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tcs := []struct {
+		query string
+		err   string
+	}{
+		{`{ shop { name } }`, ""},
+		{`query { shop { name } }`, ""},
+		{`query Orders($first: Int = 10, $status: [Status!]!) {
+			orders(first: $first, status: $status) @include(if: true) {
+				edges { node { id, total: totalPrice, ...OrderFields } }
+				... on Connection { pageInfo { hasNextPage } }
+				... @skip(if: false) { count }
+			}
+		}
+		fragment OrderFields on Order { createdAt }`, ""},
+		{`mutation Create { createCustomer(input: {name: "Bob", tags: ["vip", "new"], age: 3.5e2, active: true, note: null, tier: GOLD}) { id } }`, ""},
+		{"# a comment\nquery Blocks { field(text: \"\"\"multi\nline \\\"\"\" string\"\"\", esc: \"\\u00e9\\n\") }", ""},
+		{`subscription OnEvent { event { id } }`, ""},
+
+		{``, "syntax error at 1:1: expected operation or fragment, found end of query"},
+		{`   `, "syntax error at 1:4: expected operation or fragment, found end of query"},
+		{`shop { name }`, "syntax error at 1:1: expected operation or fragment, found 'shop'"},
+		{`{ shop { name }`, "syntax error at 1:16: expected name, found end of query"},
+		{`{ shop { } }`, "syntax error at 1:10: expected name, found '}'"},
+		{`{ shop(id: ) { name } }`, "syntax error at 1:12: expected value, found ')'"},
+		{`query ($id: ) { shop }`, "syntax error at 1:13: expected name, found ')'"},
+		{`query ($id: Int = $other) { shop }`, "syntax error at 1:19: unexpected variable in constant value"},
+		{`{ shop(name: "unterminated) }`, "syntax error at 1:30: unterminated string"},
+		{`{ shop(name: "bad\q") }`, "syntax error at 1:19: invalid escape sequence"},
+		{`{ shop(count: 012) }`, "syntax error at 1:16: invalid number, unexpected digit after 0"},
+		{`{ shop(count: 12abc) }`, "syntax error at 1:17: invalid number, unexpected 'a'"},
+		{`{ shop % }`, "syntax error at 1:8: unexpected character '%'"},
+		{`{ shop .. }`, "syntax error at 1:8: unexpected '.'"},
+		{`fragment on on Shop { name }`, "syntax error at 1:10: expected fragment name, found 'on'"},
+		{"{ a }\n{ b }", "anonymous operation must be the only operation in the document"},
+		{`query A { a } query A { b }`, "operation 'A' is defined more than once"},
+		{`query A { a } fragment F on T { b } fragment F on T { c }`, "fragment 'F' is defined more than once"},
+		{"{\n  shop { ...Missing }\n}", "fragment 'Missing' used at 2:13 is not defined"},
+	}
+
+	for _, tc := range tcs {
+		err := graphql.Validate(tc.query)
+		if tc.err == "" {
+			assert.NoError(t, err, "unexpected error for query %s", tc.query)
+		} else {
+			assert.EqualError(t, err, tc.err, "error mismatch for query %s", tc.query)
+		}
+	}
+}
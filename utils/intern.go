@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"sync"
+)
+
+// DefaultInterner is the interner used for values like node UUIDs and category names which are repeated across the
+// many sessions that a worker might be holding
+var DefaultInterner = NewInterner(100000)
+
+// Intern returns the canonical copy of the given string from the default interner
+func Intern[T ~string](s T) T {
+	return T(DefaultInterner.Intern(string(s)))
+}
+
+// Interner maps strings to canonical copies of themselves so that equal strings which have been read separately can
+// share memory. It holds at most size strings and is emptied when full, so it doesn't grow forever. A nil interner
+// returns strings as is.
+type Interner struct {
+	mutex   sync.Mutex
+	size    int
+	strings map[string]string
+}
+
+// NewInterner creates a new interner which holds up to size strings
+func NewInterner(size int) *Interner {
+	return &Interner{size: size, strings: make(map[string]string)}
+}
+
+// Intern returns the canonical copy of the given string
+func (i *Interner) Intern(s string) string {
+	if i == nil || s == "" {
+		return s
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if interned, exists := i.strings[s]; exists {
+		return interned
+	}
+
+	if len(i.strings) >= i.size {
+		i.strings = make(map[string]string)
+	}
+
+	i.strings[s] = s
+	return s
+}
@@ -0,0 +1,41 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/nyaruka/goflow/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterner(t *testing.T) {
+	// gets the address of the bytes of a string
+	data := func(s string) uintptr { return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data }
+
+	// strings built at runtime so that they're separate copies
+	foo1, foo2, bar := string([]byte("foo")), string([]byte("foo")), string([]byte("bar"))
+	assert.NotEqual(t, data(foo1), data(foo2))
+
+	interner := utils.NewInterner(2)
+
+	assert.Equal(t, "foo", interner.Intern(foo1))
+	assert.Equal(t, data(foo1), data(interner.Intern(foo2)))
+	assert.Equal(t, data(bar), data(interner.Intern(bar)))
+	assert.Equal(t, "", interner.Intern(""))
+
+	// interner is full so is emptied before the next new string is added
+	baz := string([]byte("baz"))
+	assert.Equal(t, data(baz), data(interner.Intern(baz)))
+	assert.Equal(t, data(foo2), data(interner.Intern(foo2)))
+
+	// a nil interner doesn't intern
+	var nilInterner *utils.Interner
+	assert.Equal(t, data(foo1), data(nilInterner.Intern(foo1)))
+	assert.Equal(t, data(foo2), data(nilInterner.Intern(foo2)))
+
+	// typed strings can be interned with the default interner
+	type thing string
+	assert.Equal(t, thing("foo"), utils.Intern(thing(foo1)))
+}
@@ -0,0 +1,236 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/gocommon/jsonx"
+
+	"github.com/pkg/errors"
+)
+
+// possible operation types, which are the subset of RFC 6902 that Diff produces
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+// Operation is a single JSON Patch (RFC 6902) operation
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Diff returns the operations which turn document a into document b. Items appended to arrays are added rather than
+// the array being replaced, which keeps patches small for documents like sessions which mostly grow.
+func Diff(a, b []byte) ([]*Operation, error) {
+	va, err := decode(a)
+	if err != nil {
+		return nil, err
+	}
+	vb, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]*Operation, 0)
+	diff(&ops, "", va, vb)
+	return ops, nil
+}
+
+// Apply applies the given operations to the given document
+func Apply(doc []byte, ops []*Operation) ([]byte, error) {
+	v, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range ops {
+		if v, err = apply(v, op); err != nil {
+			return nil, errors.Wrapf(err, "unable to apply %s operation at '%s'", op.Op, op.Path)
+		}
+	}
+
+	return jsonx.Marshal(v)
+}
+
+func diff(ops *[]*Operation, path string, a, b any) {
+	switch ta := a.(type) {
+	case map[string]any:
+		if tb, isMap := b.(map[string]any); isMap {
+			for _, k := range sortedKeys(ta) {
+				if _, exists := tb[k]; !exists {
+					*ops = append(*ops, &Operation{Op: OpRemove, Path: path + "/" + escape(k)})
+				}
+			}
+			for _, k := range sortedKeys(tb) {
+				if va, exists := ta[k]; exists {
+					diff(ops, path+"/"+escape(k), va, tb[k])
+				} else {
+					*ops = append(*ops, &Operation{Op: OpAdd, Path: path + "/" + escape(k), Value: jsonx.MustMarshal(tb[k])})
+				}
+			}
+			return
+		}
+	case []any:
+		if tb, isArray := b.([]any); isArray {
+			common := len(ta)
+			if len(tb) < common {
+				common = len(tb)
+			}
+			for i := 0; i < common; i++ {
+				diff(ops, path+"/"+strconv.Itoa(i), ta[i], tb[i])
+			}
+			for i := len(ta) - 1; i >= len(tb); i-- {
+				*ops = append(*ops, &Operation{Op: OpRemove, Path: path + "/" + strconv.Itoa(i)})
+			}
+			for i := len(ta); i < len(tb); i++ {
+				*ops = append(*ops, &Operation{Op: OpAdd, Path: path + "/-", Value: jsonx.MustMarshal(tb[i])})
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*ops = append(*ops, &Operation{Op: OpReplace, Path: path, Value: jsonx.MustMarshal(b)})
+	}
+}
+
+// applies the given operation to the given value, returning the new value
+func apply(v any, op *Operation) (any, error) {
+	if op.Op != OpAdd && op.Op != OpRemove && op.Op != OpReplace {
+		return nil, errors.Errorf("unsupported operation '%s'", op.Op)
+	}
+
+	var value any
+	if op.Op != OpRemove {
+		var err error
+		if value, err = decode(op.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	if op.Path == "" {
+		if op.Op == OpRemove {
+			return nil, errors.New("can't remove the whole document")
+		}
+		return value, nil
+	}
+	if !strings.HasPrefix(op.Path, "/") {
+		return nil, errors.New("path must start with /")
+	}
+
+	tokens := strings.Split(op.Path[1:], "/")
+	for i := range tokens {
+		tokens[i] = unescape(tokens[i])
+	}
+
+	return applyAt(v, tokens, op.Op, value)
+}
+
+func applyAt(v any, tokens []string, op string, value any) (any, error) {
+	token, last := tokens[0], len(tokens) == 1
+
+	switch typed := v.(type) {
+	case map[string]any:
+		if !last {
+			child, exists := typed[token]
+			if !exists {
+				return nil, errors.Errorf("no such property '%s'", token)
+			}
+			newChild, err := applyAt(child, tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			typed[token] = newChild
+			return typed, nil
+		}
+
+		_, exists := typed[token]
+		switch op {
+		case OpAdd:
+			typed[token] = value
+		case OpReplace:
+			if !exists {
+				return nil, errors.Errorf("no such property '%s'", token)
+			}
+			typed[token] = value
+		case OpRemove:
+			if !exists {
+				return nil, errors.Errorf("no such property '%s'", token)
+			}
+			delete(typed, token)
+		default:
+			return nil, errors.Errorf("unsupported operation '%s'", op)
+		}
+		return typed, nil
+
+	case []any:
+		if last && token == "-" && op == OpAdd {
+			return append(typed, value), nil
+		}
+
+		index, err := strconv.Atoi(token)
+		if err != nil || index < 0 || index > len(typed) || (index == len(typed) && !(last && op == OpAdd)) {
+			return nil, errors.Errorf("invalid array index '%s'", token)
+		}
+
+		if !last {
+			newChild, err := applyAt(typed[index], tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			typed[index] = newChild
+			return typed, nil
+		}
+
+		switch op {
+		case OpAdd:
+			typed = append(typed, nil)
+			copy(typed[index+1:], typed[index:])
+			typed[index] = value
+		case OpReplace:
+			typed[index] = value
+		case OpRemove:
+			typed = append(typed[:index], typed[index+1:]...)
+		default:
+			return nil, errors.Errorf("unsupported operation '%s'", op)
+		}
+		return typed, nil
+	}
+
+	return nil, errors.Errorf("can't navigate into '%s'", token)
+}
+
+// decodes JSON keeping numbers as they were written
+func decode(data []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var escaper = strings.NewReplacer("~", "~0", "/", "~1")
+var unescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+func escape(token string) string   { return escaper.Replace(token) }
+func unescape(token string) string { return unescaper.Replace(token) }
@@ -0,0 +1,61 @@
+package jsonpatch_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/test"
+	"github.com/nyaruka/goflow/utils/jsonpatch"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAndApply(t *testing.T) {
+	tcs := []struct {
+		a   string
+		b   string
+		ops string
+	}{
+		{`{"a": 1}`, `{"a": 1}`, `[]`},
+		{`{"a": 1}`, `{"a": 2}`, `[{"op": "replace", "path": "/a", "value": 2}]`},
+		{`{"a": 1, "b": 2}`, `{"b": 2, "c": null}`, `[{"op": "remove", "path": "/a"}, {"op": "add", "path": "/c", "value": null}]`},
+		{`{"a/b": {"c~d": 1}}`, `{"a/b": {"c~d": 2}}`, `[{"op": "replace", "path": "/a~1b/c~0d", "value": 2}]`},
+		{`{"l": [1, 2]}`, `{"l": [1, 3, 4, 5]}`, `[{"op": "replace", "path": "/l/1", "value": 3}, {"op": "add", "path": "/l/-", "value": 4}, {"op": "add", "path": "/l/-", "value": 5}]`},
+		{`{"l": [1, 2, 3]}`, `{"l": [1]}`, `[{"op": "remove", "path": "/l/2"}, {"op": "remove", "path": "/l/1"}]`},
+		{`{"l": [{"x": 1}]}`, `{"l": [{"x": 1, "y": [true]}]}`, `[{"op": "add", "path": "/l/0/y", "value": [true]}]`},
+		{`{"a": {"b": 1}}`, `{"a": [1]}`, `[{"op": "replace", "path": "/a", "value": [1]}]`},
+		{`{"n": 1.50}`, `{"n": 1.5}`, `[{"op": "replace", "path": "/n", "value": 1.5}]`},
+		{`[1]`, `"x"`, `[{"op": "replace", "path": "", "value": "x"}]`},
+	}
+
+	for _, tc := range tcs {
+		ops, err := jsonpatch.Diff([]byte(tc.a), []byte(tc.b))
+		require.NoError(t, err)
+		test.AssertEqualJSON(t, []byte(tc.ops), jsonx.MustMarshal(ops), "ops mismatch for %s -> %s", tc.a, tc.b)
+
+		patched, err := jsonpatch.Apply([]byte(tc.a), ops)
+		require.NoError(t, err)
+		test.AssertEqualJSON(t, []byte(tc.b), patched, "apply mismatch for %s -> %s", tc.a, tc.b)
+	}
+
+	// other operations which are valid RFC 6902 but which Diff doesn't produce
+	patched, err := jsonpatch.Apply([]byte(`{"l": [1, 3]}`), []*jsonpatch.Operation{{Op: "add", Path: "/l/1", Value: []byte(`2`)}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"l":[1,2,3]}`, string(patched))
+
+	_, err = jsonpatch.Apply([]byte(`{"a": 1}`), []*jsonpatch.Operation{{Op: "replace", Path: "/b", Value: []byte(`2`)}})
+	assert.EqualError(t, err, "unable to apply replace operation at '/b': no such property 'b'")
+
+	_, err = jsonpatch.Apply([]byte(`{"l": [1]}`), []*jsonpatch.Operation{{Op: "remove", Path: "/l/3"}})
+	assert.EqualError(t, err, "unable to apply remove operation at '/l/3': invalid array index '3'")
+
+	_, err = jsonpatch.Apply([]byte(`{"a": 1}`), []*jsonpatch.Operation{{Op: "move", Path: "/a"}})
+	assert.EqualError(t, err, "unable to apply move operation at '/a': unsupported operation 'move'")
+
+	_, err = jsonpatch.Apply([]byte(`{"a": 1}`), []*jsonpatch.Operation{{Op: "add", Path: "/a/b", Value: []byte(`2`)}})
+	assert.EqualError(t, err, "unable to apply add operation at '/a/b': can't navigate into 'b'")
+
+	_, err = jsonpatch.Diff([]byte(`{`), []byte(`{}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,280 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nyaruka/gocommon/jsonx"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+var simplePropertyName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var validTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true,
+}
+
+// Schema is a JSON Schema, limited to the keywords needed to describe the shape of API responses, i.e. type,
+// properties, required, additionalProperties, items, enum, minimum, maximum, minLength, maxLength, minItems, maxItems
+// and pattern. Other keywords are ignored.
+type Schema struct {
+	Type                 Types              `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []json.RawMessage  `json:"enum,omitempty"`
+	Minimum              json.Number        `json:"minimum,omitempty"`
+	Maximum              json.Number        `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+// Types is the allowed types of a schema, which is written as a single string if there's only one
+type Types []string
+
+// MarshalJSON marshals these types as a string if there's only one
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return jsonx.Marshal(t[0])
+	}
+	return jsonx.Marshal([]string(t))
+}
+
+// UnmarshalJSON unmarshals these types from a string or a list of strings
+func (t *Types) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		var single string
+		if err := json.Unmarshal(data, &single); err != nil {
+			return err
+		}
+		*t = Types{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// Violation is a way in which a document doesn't match a schema
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Check checks that this schema is itself valid
+func (s *Schema) Check() error {
+	return s.check("$")
+}
+
+func (s *Schema) check(path string) error {
+	for _, t := range s.Type {
+		if !validTypes[t] {
+			return errors.Errorf("%s: '%s' is not a valid type", path, t)
+		}
+	}
+	if _, err := regexp.Compile(s.Pattern); err != nil {
+		return errors.Errorf("%s: '%s' is not a valid pattern", path, s.Pattern)
+	}
+	if _, err := parseBound(s.Minimum); err != nil {
+		return errors.Errorf("%s: '%s' is not a valid minimum", path, s.Minimum)
+	}
+	if _, err := parseBound(s.Maximum); err != nil {
+		return errors.Errorf("%s: '%s' is not a valid maximum", path, s.Maximum)
+	}
+	for _, name := range sortedKeys(s.Properties) {
+		if err := s.Properties[name].check(propertyPath(path, name)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check(path + "[*]")
+	}
+	return nil
+}
+
+// Validate validates the given JSON document against this schema, returning the ways in which it doesn't match
+func (s *Schema) Validate(doc []byte) ([]*Violation, error) {
+	if err := s.Check(); err != nil {
+		return nil, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(doc))
+	d.UseNumber()
+
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, errors.Wrap(err, "unable to decode document")
+	}
+
+	violations := make([]*Violation, 0)
+	s.validate(&violations, "$", v)
+	return violations, nil
+}
+
+func (s *Schema) validate(violations *[]*Violation, path string, v any) {
+	violate := func(msg string, args ...any) {
+		*violations = append(*violations, &Violation{Path: path, Message: fmt.Sprintf(msg, args...)})
+	}
+
+	if len(s.Type) > 0 && !s.allowsType(v) {
+		violate("expected %s but found %s", strings.Join(s.Type, " or "), typeOf(v))
+		return
+	}
+
+	if len(s.Enum) > 0 && !s.inEnum(v) {
+		violate("value isn't one of the allowed values")
+	}
+
+	switch typed := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, found := typed[name]; !found {
+				violate("missing required property '%s'", name)
+			}
+		}
+		for _, name := range sortedKeys(typed) {
+			if prop := s.Properties[name]; prop != nil {
+				prop.validate(violations, propertyPath(path, name), typed[name])
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				violate("property '%s' isn't allowed", name)
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(typed) < *s.MinItems {
+			violate("expected at least %d items but found %d", *s.MinItems, len(typed))
+		}
+		if s.MaxItems != nil && len(typed) > *s.MaxItems {
+			violate("expected at most %d items but found %d", *s.MaxItems, len(typed))
+		}
+		if s.Items != nil {
+			for i, item := range typed {
+				s.Items.validate(violations, fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(typed)
+		if s.MinLength != nil && length < *s.MinLength {
+			violate("expected at least %d characters but found %d", *s.MinLength, length)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			violate("expected at most %d characters but found %d", *s.MaxLength, length)
+		}
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(typed) {
+			violate("value doesn't match pattern '%s'", s.Pattern)
+		}
+	case json.Number:
+		num, _ := decimal.NewFromString(string(typed))
+		if min, _ := parseBound(s.Minimum); min != nil && num.LessThan(*min) {
+			violate("expected a minimum of %s but found %s", s.Minimum, typed)
+		}
+		if max, _ := parseBound(s.Maximum); max != nil && num.GreaterThan(*max) {
+			violate("expected a maximum of %s but found %s", s.Maximum, typed)
+		}
+	}
+}
+
+func (s *Schema) allowsType(v any) bool {
+	actual := typeOf(v)
+	for _, t := range s.Type {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) inEnum(v any) bool {
+	for _, allowed := range s.Enum {
+		d := json.NewDecoder(bytes.NewReader(allowed))
+		d.UseNumber()
+
+		var a any
+		if d.Decode(&a) == nil && reflect.DeepEqual(normalize(a), normalize(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+// gets the JSON Schema type of a decoded value, where numbers without fractional parts are integers
+func typeOf(v any) string {
+	switch typed := v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		if num, err := decimal.NewFromString(string(typed)); err == nil && num.IsInteger() {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+// a number which has been normalized for comparison
+type normalizedNumber string
+
+// normalizes numbers in a decoded value so that e.g. 1 and 1.0 are equal
+func normalize(v any) any {
+	switch typed := v.(type) {
+	case map[string]any:
+		n := make(map[string]any, len(typed))
+		for k, item := range typed {
+			n[k] = normalize(item)
+		}
+		return n
+	case []any:
+		n := make([]any, len(typed))
+		for i, item := range typed {
+			n[i] = normalize(item)
+		}
+		return n
+	case json.Number:
+		num, _ := decimal.NewFromString(string(typed))
+		return normalizedNumber(num.String())
+	}
+	return v
+}
+
+// parses a minimum or maximum, which may be empty if the schema doesn't have one
+func parseBound(n json.Number) (*decimal.Decimal, error) {
+	if n == "" {
+		return nil, nil
+	}
+	d, err := decimal.NewFromString(string(n))
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func propertyPath(path, name string) string {
+	if simplePropertyName.MatchString(name) {
+		return path + "." + name
+	}
+	return fmt.Sprintf("%s[%s]", path, jsonx.MustMarshal(name))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,94 @@
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nyaruka/gocommon/jsonx"
+	"github.com/nyaruka/goflow/test"
+	"github.com/nyaruka/goflow/utils/jsonschema"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema(t *testing.T) {
+	schema := &jsonschema.Schema{}
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["id", "status"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"status": {"type": "string", "enum": ["active", "closed"]},
+			"score": {"type": ["number", "null"], "maximum": 10.5},
+			"code": {"type": "string", "pattern": "^[A-Z]{3}$", "minLength": 3},
+			"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+			"meta data": {"type": "object", "additionalProperties": false, "properties": {"x": {}}}
+		}
+	}`), schema)
+	require.NoError(t, err)
+	require.NoError(t, schema.Check())
+
+	// schema can be marshaled back to JSON without losing anything
+	test.AssertEqualJSON(t, []byte(`{
+		"type": "object",
+		"required": ["id", "status"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"status": {"type": "string", "enum": ["active", "closed"]},
+			"score": {"type": ["number", "null"], "maximum": 10.5},
+			"code": {"type": "string", "pattern": "^[A-Z]{3}$", "minLength": 3},
+			"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+			"meta data": {"type": "object", "additionalProperties": false, "properties": {"x": {}}}
+		}
+	}`), jsonx.MustMarshal(schema), "schema JSON mismatch")
+
+	tcs := []struct {
+		doc        string
+		violations []*jsonschema.Violation
+	}{
+		{`{"id": 23, "status": "active"}`, []*jsonschema.Violation{}},
+		{`{"id": 23.0, "status": "closed", "score": null, "code": "ABC", "tags": ["a", "b"], "meta data": {"x": 1}}`, []*jsonschema.Violation{}},
+		{`[]`, []*jsonschema.Violation{{Path: "$", Message: "expected object but found array"}}},
+		{`{"id": "23"}`, []*jsonschema.Violation{
+			{Path: "$", Message: "missing required property 'status'"},
+			{Path: "$.id", Message: "expected integer but found string"},
+		}},
+		{`{"id": 0, "status": "open", "score": 11}`, []*jsonschema.Violation{
+			{Path: "$.id", Message: "expected a minimum of 1 but found 0"},
+			{Path: "$.score", Message: "expected a maximum of 10.5 but found 11"},
+			{Path: "$.status", Message: "value isn't one of the allowed values"},
+		}},
+		{`{"id": 1.5, "status": "active", "code": "ab", "tags": ["a", 2, "c"], "meta data": {"y": true}}`, []*jsonschema.Violation{
+			{Path: "$.code", Message: "expected at least 3 characters but found 2"},
+			{Path: "$.code", Message: "value doesn't match pattern '^[A-Z]{3}$'"},
+			{Path: "$.id", Message: "expected integer but found number"},
+			{Path: `$["meta data"]`, Message: "property 'y' isn't allowed"},
+			{Path: "$.tags", Message: "expected at most 2 items but found 3"},
+			{Path: "$.tags[1]", Message: "expected string but found integer"},
+		}},
+	}
+
+	for _, tc := range tcs {
+		violations, err := schema.Validate([]byte(tc.doc))
+		require.NoError(t, err)
+		assert.Equal(t, tc.violations, violations, "violations mismatch for %s", tc.doc)
+	}
+
+	_, err = schema.Validate([]byte(`{`))
+	assert.EqualError(t, err, "unable to decode document: unexpected EOF")
+
+	// check catches invalid schemas
+	for _, tc := range []struct {
+		schema string
+		err    string
+	}{
+		{`{"type": "text"}`, "$: 'text' is not a valid type"},
+		{`{"properties": {"a": {"items": {"pattern": "[x"}}}}`, "$.a[*]: '[x' is not a valid pattern"},
+		{`{"minimum": 1e999999999999}`, "$: '1e999999999999' is not a valid minimum"},
+	} {
+		s := &jsonschema.Schema{}
+		require.NoError(t, json.Unmarshal([]byte(tc.schema), s))
+		assert.EqualError(t, s.Check(), tc.err)
+	}
+}
@@ -50,8 +50,8 @@ func Set[K constraints.Ordered](s []K) map[K]bool {
 	return m
 }
 
-// SortedKeys returns the keys of a set in lexical order
-func SortedKeys[K constraints.Ordered](m map[K]bool) []K {
+// SortedKeys returns the keys of a set or map in lexical order
+func SortedKeys[K constraints.Ordered, V any](m map[K]V) []K {
 	keys := maps.Keys(m)
 	slices.Sort(keys)
 	return keys
@@ -0,0 +1,69 @@
+package netx
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+)
+
+type cachedAddrs struct {
+	ips       []net.IP
+	expiresOn time.Time
+}
+
+// CachingResolver wraps another resolver and caches its results for as long as their TTLs allow, clamped to a minimum
+// and maximum. It's safe for concurrent use and should be shared so that all clients benefit from its cache.
+type CachingResolver struct {
+	resolver Resolver
+	minTTL   time.Duration
+	maxTTL   time.Duration
+	cache    map[string]*cachedAddrs
+	mutex    sync.Mutex
+}
+
+// NewCachingResolver creates a new caching resolver
+func NewCachingResolver(resolver Resolver, minTTL, maxTTL time.Duration) *CachingResolver {
+	return &CachingResolver{resolver: resolver, minTTL: minTTL, maxTTL: maxTTL, cache: make(map[string]*cachedAddrs)}
+}
+
+// Resolve returns the cached addresses of the given host if they haven't expired, and otherwise looks them up
+func (r *CachingResolver) Resolve(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	now := dates.Now()
+
+	r.mutex.Lock()
+	cached := r.cache[host]
+	r.mutex.Unlock()
+
+	if cached != nil && now.Before(cached.expiresOn) {
+		return cached.ips, cached.expiresOn.Sub(now), nil
+	}
+
+	ips, ttl, err := r.resolver.Resolve(ctx, host)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if ttl < r.minTTL {
+		ttl = r.minTTL
+	}
+	if r.maxTTL > 0 && ttl > r.maxTTL {
+		ttl = r.maxTTL
+	}
+
+	r.mutex.Lock()
+	r.cache[host] = &cachedAddrs{ips: ips, expiresOn: now.Add(ttl)}
+	r.mutex.Unlock()
+
+	return ips, ttl, nil
+}
+
+// Flush removes all cached addresses
+func (r *CachingResolver) Flush() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.cache = make(map[string]*cachedAddrs)
+}
@@ -0,0 +1,64 @@
+package netx_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/gocommon/dates"
+	"github.com/nyaruka/goflow/utils/netx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachingResolver(t *testing.T) {
+	now := time.Date(2018, 7, 6, 12, 30, 0, 0, time.UTC)
+	dates.SetNowSource(dates.NewFixedNowSource(now))
+	defer dates.SetNowSource(dates.DefaultNowSource)
+
+	mock := &mockResolver{ips: map[string][]net.IP{"example.com": {net.ParseIP("10.0.0.1")}}, ttl: 30 * time.Second}
+	resolver := netx.NewCachingResolver(mock, 10*time.Second, time.Minute)
+	ctx := context.Background()
+
+	ips, ttl, err := resolver.Resolve(ctx, "example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.1")}, ips)
+	assert.Equal(t, 30*time.Second, ttl)
+	assert.Equal(t, 1, mock.calls)
+
+	// second lookup comes from the cache
+	dates.SetNowSource(dates.NewFixedNowSource(now.Add(20 * time.Second)))
+
+	ips, ttl, err = resolver.Resolve(ctx, "example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.1")}, ips)
+	assert.Equal(t, 10*time.Second, ttl)
+	assert.Equal(t, 1, mock.calls)
+
+	// until the TTL passes
+	dates.SetNowSource(dates.NewFixedNowSource(now.Add(30 * time.Second)))
+
+	_, _, err = resolver.Resolve(ctx, "example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, mock.calls)
+
+	// errors aren't cached
+	_, _, err = resolver.Resolve(ctx, "unknown.com")
+	assert.EqualError(t, err, "lookup unknown.com: no such host")
+	_, _, err = resolver.Resolve(ctx, "unknown.com")
+	assert.Error(t, err)
+	assert.Equal(t, 4, mock.calls)
+
+	// TTLs are clamped to the min and max
+	mock.ttl = time.Second
+	resolver.Flush()
+
+	_, ttl, _ = resolver.Resolve(ctx, "example.com")
+	assert.Equal(t, 10*time.Second, ttl)
+
+	mock.ttl = time.Hour
+	resolver.Flush()
+
+	_, ttl, _ = resolver.Resolve(ctx, "example.com")
+	assert.Equal(t, time.Minute, ttl)
+}
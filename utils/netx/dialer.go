@@ -0,0 +1,204 @@
+package netx
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultResolver is a resolver which can be shared by clients that don't need their own. It queries the system's DNS
+// servers directly so that lookups are cached for as long as the TTLs of their records allow, up to an hour, and falls
+// back to the system resolver, whose lookups are cached for a minute, for hosts those servers don't know about.
+var DefaultResolver = NewCachingResolver(NewDNSResolver(systemDNSServers(), 5*time.Second, NewSystemResolver(time.Minute)), 0, time.Hour)
+
+// DefaultDialer is a dialer which can be shared by clients that don't need their own, which uses the default resolver
+var DefaultDialer = NewDialer(DefaultResolver, 30*time.Second, 300*time.Millisecond)
+
+// gets the system's DNS servers, which if they can't be read means every lookup falls back to the system resolver
+func systemDNSServers() []string {
+	servers, _ := ReadDNSServers("/etc/resolv.conf")
+	return servers
+}
+
+// Dialer makes connections using its own resolver, racing connection attempts to a host's IPv6 and IPv4 addresses
+// as described in RFC 8305 (Happy Eyeballs) so that a host with a broken address family doesn't stall every call.
+type Dialer struct {
+	resolver      Resolver
+	dialer        *net.Dialer
+	fallbackDelay time.Duration
+	policy        *HostPolicy
+}
+
+// NewDialer creates a new dialer which uses the given resolver. Each connection attempt times out after timeout, and
+// the next address is tried after fallbackDelay if the previous attempt hasn't yet succeeded or failed.
+func NewDialer(resolver Resolver, timeout, fallbackDelay time.Duration) *Dialer {
+	return &Dialer{
+		resolver:      resolver,
+		dialer:        &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second},
+		fallbackDelay: fallbackDelay,
+	}
+}
+
+// WithPolicy returns a copy of this dialer which only connects to addresses allowed by the given policy
+func (d *Dialer) WithPolicy(policy *HostPolicy) *Dialer {
+	c := *d
+	c.policy = policy
+	return &c
+}
+
+// DialContext connects to the given address, and can be used as the dial function of a transport
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	// nothing to resolve if host is already an IP address
+	if ip := net.ParseIP(host); ip != nil {
+		if d.policy != nil && !d.policy.AllowsIP(ip) {
+			return nil, &BlockedError{Host: host, Reason: "address isn't allowed"}
+		}
+		return d.dialer.DialContext(ctx, network, address)
+	}
+
+	ips, _, err := d.resolver.Resolve(ctx, host)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error resolving %s", host)
+	}
+
+	// only connect to the addresses allowed by our policy, which are the ones we've checked
+	if d.policy != nil {
+		if ips = d.policy.filterIPs(ips); len(ips) == 0 {
+			return nil, &BlockedError{Host: host, Reason: "resolves to addresses which aren't allowed"}
+		}
+	}
+
+	ips = filterIPs(network, ips)
+	if len(ips) == 0 {
+		return nil, errors.Errorf("no %s addresses found for %s", network, host)
+	}
+
+	return d.race(ctx, network, interleaveIPs(ips), port)
+}
+
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// attempts connections to the given addresses in order, starting each attempt after the fallback delay or as soon
+// as the previous attempt fails, and returns the first connection to succeed
+func (d *Dialer) race(ctx context.Context, network string, ips []net.IP, port string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, len(ips))
+	next, pending := 0, 0
+
+	startNext := func() {
+		ip := ips[next]
+		next++
+		pending++
+
+		go func() {
+			conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			results <- dialResult{conn, err}
+		}()
+	}
+
+	// closes any connections from attempts which complete after we've stopped waiting for them
+	abandon := func() {
+		go func(n int) {
+			for i := 0; i < n; i++ {
+				if r := <-results; r.conn != nil {
+					r.conn.Close()
+				}
+			}
+		}(pending)
+	}
+
+	startNext()
+
+	var firstErr error
+
+	for {
+		var fallback <-chan time.Time
+		var timer *time.Timer
+		if next < len(ips) {
+			timer = time.NewTimer(d.fallbackDelay)
+			fallback = timer.C
+		}
+
+		select {
+		case result := <-results:
+			pending--
+
+			if result.err == nil {
+				abandon()
+				return result.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = result.err
+			}
+
+			// no point waiting to try the next address if this one has failed
+			if next < len(ips) {
+				startNext()
+			} else if pending == 0 {
+				return nil, firstErr
+			}
+		case <-fallback:
+			startNext()
+		case <-ctx.Done():
+			abandon()
+			return nil, ctx.Err()
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// gets the addresses usable for the given network
+func filterIPs(network string, ips []net.IP) []net.IP {
+	filtered := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		isV4 := ip.To4() != nil
+		if (network == "tcp4" && !isV4) || (network == "tcp6" && isV4) {
+			continue
+		}
+		filtered = append(filtered, ip)
+	}
+	return filtered
+}
+
+// orders addresses so that address families alternate, starting with the family of the first address
+func interleaveIPs(ips []net.IP) []net.IP {
+	var v6, v4 []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+
+	first, second := v6, v4
+	if ips[0].To4() != nil {
+		first, second = v4, v6
+	}
+
+	interleaved := make([]net.IP, 0, len(ips))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			interleaved = append(interleaved, first[i])
+		}
+		if i < len(second) {
+			interleaved = append(interleaved, second[i])
+		}
+	}
+	return interleaved
+}
@@ -0,0 +1,69 @@
+package netx_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/goflow/utils/netx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	resolver := &mockResolver{ips: map[string][]net.IP{
+		// first address is from a discard prefix so attempts to it never connect
+		"slow.com":   {net.ParseIP("100::1"), net.ParseIP("127.0.0.1")},
+		"broken.com": {net.ParseIP("127.0.0.1").To16()},
+	}}
+
+	dialer := netx.NewDialer(resolver, time.Second, 50*time.Millisecond)
+	ctx := context.Background()
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort("slow.com", port))
+	require.NoError(t, err)
+	assert.Equal(t, server.Listener.Addr().String(), conn.RemoteAddr().String())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	conn.Close()
+
+	// IP addresses don't need resolving
+	conn, err = dialer.DialContext(ctx, "tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	conn.Close()
+
+	// no IPv6 addresses for a host with only an IPv4 one
+	_, err = dialer.DialContext(ctx, "tcp6", net.JoinHostPort("broken.com", port))
+	assert.EqualError(t, err, "no tcp6 addresses found for broken.com")
+
+	_, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort("unknown.com", port))
+	assert.EqualError(t, err, "error resolving unknown.com: lookup unknown.com: no such host")
+
+	// all attempts failing returns the first error
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	_, closedPort, _ := net.SplitHostPort(closed.Addr().String())
+	closed.Close()
+
+	_, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort("broken.com", closedPort))
+	assert.ErrorContains(t, err, "connection refused")
+
+	// and can be used by HTTP clients
+	client := netx.NewClient(dialer, 2, time.Second)
+
+	resp, err := client.Get("http://slow.com:" + port + "/")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "OK", string(body))
+}
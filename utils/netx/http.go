@@ -0,0 +1,46 @@
+package netx
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultClient is an HTTP client which can be shared by services that aren't given their own, which makes connections
+// with the default dialer
+var DefaultClient = NewClient(DefaultDialer, 0, 0)
+
+// NewTransport creates a new HTTP transport which makes connections with the given dialer and allows at most
+// maxConnsPerHost connections to each host at a time, or no limit if that's zero
+func NewTransport(dialer *Dialer, maxConnsPerHost int) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = dialer.DialContext
+	t.MaxConnsPerHost = maxConnsPerHost
+	return t
+}
+
+// NewClient creates a new HTTP client which uses a transport with the given dialer and connection limit
+func NewClient(dialer *Dialer, maxConnsPerHost int, timeout time.Duration) *http.Client {
+	return &http.Client{Transport: NewTransport(dialer, maxConnsPerHost), Timeout: timeout}
+}
+
+// NewGuardedClient creates a new HTTP client like NewClient, except that requests, including those made to follow
+// redirects, are only made if they're allowed by the given policy. Proxies aren't used since they would make the
+// connections that the policy is meant to check.
+func NewGuardedClient(dialer *Dialer, policy *HostPolicy, maxConnsPerHost int, timeout time.Duration) *http.Client {
+	t := NewTransport(dialer.WithPolicy(policy), maxConnsPerHost)
+	t.Proxy = nil
+
+	return &http.Client{Transport: &guardedTransport{policy: policy, transport: t}, Timeout: timeout}
+}
+
+type guardedTransport struct {
+	policy    *HostPolicy
+	transport http.RoundTripper
+}
+
+func (t *guardedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if err := t.policy.CheckURL(request.URL); err != nil {
+		return nil, err
+	}
+	return t.transport.RoundTrip(request)
+}
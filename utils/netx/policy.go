@@ -0,0 +1,155 @@
+package netx
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// networks which hosts shouldn't be able to reach by default, i.e. loopback, private (RFC 1918 and RFC 4193), shared
+// (RFC 6598) and link-local addresses, the last of which includes cloud metadata services such as 169.254.169.254
+var defaultDeniedNets = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+// HostPolicy decides which hosts outbound requests can be made to, so that URLs which come from flows can't be used to
+// reach internal services. Addresses are checked after they've been resolved, so a host can't get around the policy by
+// changing what its name resolves to between a check and a connection.
+type HostPolicy struct {
+	AllowedSchemes []string     // URL schemes which can be requested, or any if empty
+	AllowedNets    []*net.IPNet // networks which can be reached even though they're within a denied network
+	DeniedNets     []*net.IPNet // networks which can't be reached
+}
+
+// NewHostPolicy creates a new host policy
+func NewHostPolicy(allowedSchemes []string, allowedNets, deniedNets []*net.IPNet) *HostPolicy {
+	return &HostPolicy{AllowedSchemes: allowedSchemes, AllowedNets: allowedNets, DeniedNets: deniedNets}
+}
+
+// DefaultHostPolicy creates a new host policy which only allows HTTP and HTTPS requests to public addresses
+func DefaultHostPolicy() *HostPolicy {
+	denied, _ := ParseNets(defaultDeniedNets...)
+	return NewHostPolicy([]string{"http", "https"}, nil, denied)
+}
+
+// ParseNets parses the given CIDRs, e.g. 10.0.0.0/8, or single IP addresses as networks
+func ParseNets(cidrs ...string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, errors.Errorf("invalid IP address: %s", cidr)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets[i] = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid network: %s", cidr)
+		}
+		nets[i] = n
+	}
+	return nets, nil
+}
+
+// AllowsIP returns whether the given address can be connected to
+func (p *HostPolicy) AllowsIP(ip net.IP) bool {
+	if containsIP(p.AllowedNets, ip) {
+		return true
+	}
+	return !containsIP(p.DeniedNets, ip)
+}
+
+// CheckURL checks the scheme of the given URL, and its host if that's an IP address. Hosts which are names are checked
+// when they're resolved.
+func (p *HostPolicy) CheckURL(u *url.URL) error {
+	if len(p.AllowedSchemes) > 0 {
+		allowed := false
+		for _, s := range p.AllowedSchemes {
+			if strings.EqualFold(s, u.Scheme) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return &BlockedError{Host: u.Hostname(), Reason: fmt.Sprintf("scheme %s isn't allowed", u.Scheme)}
+		}
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !p.AllowsIP(ip) {
+		return &BlockedError{Host: u.Hostname(), Reason: "address isn't allowed"}
+	}
+	return nil
+}
+
+// CheckHost resolves the given host with the given resolver and checks that all of its addresses are allowed. It can
+// be used for clients which make their own connections, but unlike our dialer, can't protect against DNS rebinding.
+func (p *HostPolicy) CheckHost(ctx context.Context, resolver Resolver, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !p.AllowsIP(ip) {
+			return &BlockedError{Host: host, Reason: "address isn't allowed"}
+		}
+		return nil
+	}
+
+	ips, _, err := resolver.Resolve(ctx, host)
+	if err != nil {
+		return errors.Wrapf(err, "error resolving %s", host)
+	}
+	for _, ip := range ips {
+		if !p.AllowsIP(ip) {
+			return &BlockedError{Host: host, Reason: fmt.Sprintf("resolves to %s which isn't allowed", ip)}
+		}
+	}
+	return nil
+}
+
+// gets the addresses which can be connected to
+func (p *HostPolicy) filterIPs(ips []net.IP) []net.IP {
+	allowed := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if p.AllowsIP(ip) {
+			allowed = append(allowed, ip)
+		}
+	}
+	return allowed
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockedError is returned when a request or connection isn't allowed by a host policy
+type BlockedError struct {
+	Host   string
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("request to %s blocked: %s", e.Host, e.Reason)
+}
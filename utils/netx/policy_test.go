@@ -0,0 +1,113 @@
+package netx_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/goflow/utils/netx"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostPolicy(t *testing.T) {
+	_, err := netx.ParseNets("10.0.0.0/33")
+	assert.EqualError(t, err, "invalid network: 10.0.0.0/33: invalid CIDR address: 10.0.0.0/33")
+	_, err = netx.ParseNets("10.0.0.x")
+	assert.EqualError(t, err, "invalid IP address: 10.0.0.x")
+
+	policy := netx.DefaultHostPolicy()
+
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.5.4", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fd00::1", "fe80::1", "::ffff:127.0.0.1"} {
+		assert.False(t, policy.AllowsIP(net.ParseIP(ip)), "expected %s to be denied", ip)
+	}
+	for _, ip := range []string{"8.8.8.8", "172.32.0.1", "2001:4860:4860::8888"} {
+		assert.True(t, policy.AllowsIP(net.ParseIP(ip)), "expected %s to be allowed", ip)
+	}
+
+	// allowed networks take precedence over denied ones
+	allowed, err := netx.ParseNets("10.5.0.0/16", "192.168.1.10")
+	require.NoError(t, err)
+	policy.AllowedNets = allowed
+
+	assert.True(t, policy.AllowsIP(net.ParseIP("10.5.1.1")))
+	assert.True(t, policy.AllowsIP(net.ParseIP("192.168.1.10")))
+	assert.False(t, policy.AllowsIP(net.ParseIP("192.168.1.11")))
+
+	checkURL := func(u string) error {
+		parsed, err := url.Parse(u)
+		require.NoError(t, err)
+		return policy.CheckURL(parsed)
+	}
+
+	assert.NoError(t, checkURL("https://example.com/foo"))
+	assert.NoError(t, checkURL("HTTP://8.8.8.8/foo"))
+	assert.NoError(t, checkURL("http://10.5.0.1/foo"))
+	assert.EqualError(t, checkURL("ftp://example.com/foo"), "request to example.com blocked: scheme ftp isn't allowed")
+	assert.EqualError(t, checkURL("http://169.254.169.254/latest/meta-data/"), "request to 169.254.169.254 blocked: address isn't allowed")
+	assert.EqualError(t, checkURL("http://[::1]:8080/"), "request to ::1 blocked: address isn't allowed")
+
+	resolver := &mockResolver{ips: map[string][]net.IP{
+		"public.com":   {net.ParseIP("8.8.8.8")},
+		"internal.com": {net.ParseIP("8.8.8.8"), net.ParseIP("10.0.0.1")},
+	}}
+	ctx := context.Background()
+
+	assert.NoError(t, policy.CheckHost(ctx, resolver, "public.com"))
+	assert.NoError(t, policy.CheckHost(ctx, resolver, "8.8.4.4"))
+	assert.EqualError(t, policy.CheckHost(ctx, resolver, "internal.com"), "request to internal.com blocked: resolves to 10.0.0.1 which isn't allowed")
+	assert.EqualError(t, policy.CheckHost(ctx, resolver, "127.0.0.1"), "request to 127.0.0.1 blocked: address isn't allowed")
+	assert.EqualError(t, policy.CheckHost(ctx, resolver, "unknown.com"), "error resolving unknown.com: lookup unknown.com: no such host")
+}
+
+func TestGuardedClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	// a host which resolves to the loopback address where our server is listening
+	resolver := &mockResolver{ips: map[string][]net.IP{"rebound.com": {net.ParseIP("127.0.0.1")}}}
+	dialer := netx.NewDialer(resolver, time.Second, 50*time.Millisecond)
+
+	get := func(client *http.Client, u string) error {
+		resp, err := client.Get(u)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// by default the policy denies loopback addresses whether they're given as names or addresses
+	client := netx.NewGuardedClient(dialer, netx.DefaultHostPolicy(), 0, time.Second)
+
+	for _, u := range []string{"http://rebound.com:" + port + "/", server.URL + "/"} {
+		err := get(client, u)
+		var blocked *netx.BlockedError
+		assert.True(t, errors.As(err, &blocked), "expected blocked error for %s, got %v", u, err)
+	}
+
+	// unless they're explicitly allowed
+	policy := netx.DefaultHostPolicy()
+	policy.AllowedNets, _ = netx.ParseNets("127.0.0.1")
+	client = netx.NewGuardedClient(dialer, policy, 0, time.Second)
+
+	assert.NoError(t, get(client, "http://rebound.com:"+port+"/"))
+	assert.NoError(t, get(client, server.URL+"/"))
+
+	// redirects are checked too
+	err := get(client, server.URL+"/redirect")
+	assert.ErrorContains(t, err, "request to 169.254.169.254 blocked: address isn't allowed")
+}
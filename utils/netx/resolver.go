@@ -0,0 +1,235 @@
+package netx
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Resolver looks up the IP addresses of a host along with how long they can be cached for
+type Resolver interface {
+	Resolve(ctx context.Context, host string) ([]net.IP, time.Duration, error)
+}
+
+// NewSystemResolver creates a resolver which uses the system resolver. That doesn't tell us the TTLs of records so
+// addresses are always considered valid for the given TTL.
+func NewSystemResolver(ttl time.Duration) Resolver {
+	return &systemResolver{ttl: ttl}
+}
+
+type systemResolver struct {
+	ttl time.Duration
+}
+
+func (r *systemResolver) Resolve(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ips := make([]net.IP, len(addrs))
+	for i := range addrs {
+		ips[i] = addrs[i].IP
+	}
+	return ips, r.ttl, nil
+}
+
+// ReadDNSServers reads the addresses of the DNS servers from the given resolver config file, e.g. /etc/resolv.conf
+func ReadDNSServers(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	servers := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" && net.ParseIP(fields[1]) != nil {
+			servers = append(servers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+
+	return servers, scanner.Err()
+}
+
+// NewDNSResolver creates a resolver which queries the given DNS servers (e.g. 8.8.8.8:53) directly so that it knows the
+// TTLs of the records it finds. Servers are tried in order until one answers. If none of them have records for a host,
+// e.g. because it's only defined in the hosts file, the lookup is passed to the fallback resolver if there is one.
+func NewDNSResolver(servers []string, timeout time.Duration, fallback Resolver) Resolver {
+	return &dnsResolver{servers: servers, timeout: timeout, fallback: fallback}
+}
+
+type dnsResolver struct {
+	servers  []string
+	timeout  time.Duration
+	fallback Resolver
+}
+
+func (r *dnsResolver) Resolve(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	var ips []net.IP
+	var ttl time.Duration
+	var err error
+
+	for _, server := range r.servers {
+		if ips, ttl, err = r.resolveWith(ctx, server, host); err == nil {
+			break
+		}
+	}
+
+	if len(ips) == 0 && r.fallback != nil {
+		return r.fallback.Resolve(ctx, host)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ips) == 0 {
+		return nil, 0, errors.Errorf("no addresses found for %s", host)
+	}
+
+	return ips, ttl, nil
+}
+
+// looks up both the IPv6 and IPv4 addresses of a host with the given server
+func (r *dnsResolver) resolveWith(ctx context.Context, server, host string) ([]net.IP, time.Duration, error) {
+	ips6, ttl6, err := r.query(ctx, server, host, dnsmessage.TypeAAAA)
+	if err != nil {
+		return nil, 0, err
+	}
+	ips4, ttl4, err := r.query(ctx, server, host, dnsmessage.TypeA)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ttl := ttl4
+	if len(ips4) == 0 || (len(ips6) > 0 && ttl6 < ttl4) {
+		ttl = ttl6
+	}
+
+	return append(ips6, ips4...), ttl, nil
+}
+
+// queries the given server for records of the given type, returning the addresses found and the lowest TTL of the
+// records in the answer, which includes any CNAMEs followed to get to them
+func (r *dnsResolver) query(ctx context.Context, server, host string, qtype dnsmessage.Type) ([]net.IP, time.Duration, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "invalid host name %s", host)
+	}
+
+	// transaction IDs are unpredictable so that responses can't easily be spoofed
+	var id [2]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, 0, errors.Wrap(err, "error generating transaction ID")
+	}
+
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: binary.BigEndian.Uint16(id[:]), RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+
+	resp, err := r.exchange(ctx, "udp", server, query)
+	if err == nil && resp.Truncated {
+		resp, err = r.exchange(ctx, "tcp", server, query)
+	}
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "error querying %s", server)
+	}
+
+	if resp.RCode == dnsmessage.RCodeNameError {
+		return nil, 0, nil
+	}
+	if resp.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, errors.Errorf("error querying %s: %s", server, resp.RCode)
+	}
+
+	var ips []net.IP
+	var ttl time.Duration
+
+	for i, answer := range resp.Answers {
+		answerTTL := time.Duration(answer.Header.TTL) * time.Second
+		if i == 0 || answerTTL < ttl {
+			ttl = answerTTL
+		}
+
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, net.IP(body.AAAA[:]))
+		}
+	}
+
+	return ips, ttl, nil
+}
+
+// sends a query to a server and reads its response, over UDP or TCP
+func (r *dnsResolver) exchange(ctx context.Context, network, server string, query dnsmessage.Message) (*dnsmessage.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var buf []byte
+
+	if network == "tcp" {
+		// messages over TCP are prefixed with their length
+		if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(packed))), packed...)); err != nil {
+			return nil, err
+		}
+
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := conn.Write(packed); err != nil {
+			return nil, err
+		}
+
+		buf = make([]byte, 4096)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[:n]
+	}
+
+	resp := &dnsmessage.Message{}
+	if err := resp.Unpack(buf); err != nil {
+		return nil, err
+	}
+	if resp.ID != query.ID || !resp.Response {
+		return nil, errors.New("invalid response")
+	}
+
+	return resp, nil
+}
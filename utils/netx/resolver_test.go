@@ -0,0 +1,130 @@
+package netx_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/goflow/utils/netx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type mockResolver struct {
+	ips   map[string][]net.IP
+	ttl   time.Duration
+	calls int
+}
+
+func (r *mockResolver) Resolve(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	r.calls++
+	ips := r.ips[host]
+	if len(ips) == 0 {
+		return nil, 0, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return ips, r.ttl, nil
+}
+
+// starts a DNS server on a local UDP port which answers queries for A and AAAA records from the given records
+func startDNSServer(t *testing.T, records map[string][]net.IP, ttl uint32) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil {
+				continue
+			}
+
+			q := query.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, RCode: dnsmessage.RCodeNameError},
+				Questions: query.Questions,
+			}
+
+			if ips, found := records[q.Name.String()]; found {
+				resp.RCode = dnsmessage.RCodeSuccess
+
+				for _, ip := range ips {
+					header := dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: dnsmessage.ClassINET, TTL: ttl}
+
+					if ip4 := ip.To4(); ip4 != nil && q.Type == dnsmessage.TypeA {
+						body := &dnsmessage.AResource{}
+						copy(body.A[:], ip4)
+						resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: header, Body: body})
+					} else if ip4 == nil && q.Type == dnsmessage.TypeAAAA {
+						body := &dnsmessage.AAAAResource{}
+						copy(body.AAAA[:], ip)
+						resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: header, Body: body})
+					}
+				}
+			}
+
+			packed, _ := resp.Pack()
+			conn.WriteTo(packed, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestDNSResolver(t *testing.T) {
+	server := startDNSServer(t, map[string][]net.IP{
+		"example.com.": {net.ParseIP("93.184.216.34"), net.ParseIP("2606:2800:220:1:248:1893:25c8:1946")},
+		"v4.com.":      {net.ParseIP("10.0.0.1")},
+	}, 300)
+
+	fallback := &mockResolver{ips: map[string][]net.IP{"local": {net.ParseIP("127.0.0.1")}}, ttl: time.Minute}
+	resolver := netx.NewDNSResolver([]string{server}, time.Second, fallback)
+	ctx := context.Background()
+
+	ips, ttl, err := resolver.Resolve(ctx, "example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("2606:2800:220:1:248:1893:25c8:1946").To16(), net.ParseIP("93.184.216.34").To4()}, ips)
+	assert.Equal(t, 300*time.Second, ttl)
+
+	ips, ttl, err = resolver.Resolve(ctx, "v4.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.1").To4()}, ips)
+	assert.Equal(t, 300*time.Second, ttl)
+
+	// hosts the server doesn't know about are passed to the fallback
+	ips, ttl, err = resolver.Resolve(ctx, "local")
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("127.0.0.1")}, ips)
+	assert.Equal(t, time.Minute, ttl)
+	assert.Equal(t, 1, fallback.calls)
+
+	_, _, err = resolver.Resolve(ctx, "unknown.com")
+	assert.EqualError(t, err, "lookup unknown.com: no such host")
+
+	// without a fallback
+	resolver = netx.NewDNSResolver([]string{server}, time.Second, nil)
+
+	_, _, err = resolver.Resolve(ctx, "unknown.com")
+	assert.EqualError(t, err, "no addresses found for unknown.com")
+}
+
+func TestReadDNSServers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	os.WriteFile(path, []byte("# generated\nsearch example.com\nnameserver 10.0.0.2\nnameserver 2001:4860:4860::8888\nnameserver bogus\noptions ndots:1\n"), 0644)
+
+	servers, err := netx.ReadDNSServers(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2:53", "[2001:4860:4860::8888]:53"}, servers)
+
+	_, err = netx.ReadDNSServers(filepath.Join(t.TempDir(), "missing.conf"))
+	assert.Error(t, err)
+}
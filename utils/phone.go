@@ -5,6 +5,9 @@ import (
 	"strings"
 
 	"github.com/nyaruka/gocommon/urns"
+	"github.com/nyaruka/phonenumbers"
+
+	"github.com/pkg/errors"
 )
 
 var possiblePhone = regexp.MustCompile(`\+?[\d \.\-\(\)]{5,}`)
@@ -36,3 +39,66 @@ func FindPhoneNumbers(s, country string) []string {
 	}
 	return nums
 }
+
+// PhoneFormat is a format for displaying phone numbers
+type PhoneFormat string
+
+// supported phone number formats
+const (
+	PhoneFormatE164          PhoneFormat = "E164"
+	PhoneFormatNational      PhoneFormat = "national"
+	PhoneFormatInternational PhoneFormat = "international"
+)
+
+// ParsePhone parses the given text, which can be a tel URN, as a phone number. The given country is used for numbers
+// which don't include a country calling code.
+func ParsePhone(s, country string) (*phonenumbers.PhoneNumber, error) {
+	s = strings.TrimSpace(s)
+
+	if urn, err := urns.Parse(s); err == nil && urn.Scheme() == urns.TelScheme {
+		s = urn.Path()
+	}
+
+	if !onlyPhone.MatchString(s) {
+		return nil, errors.New("not a phone number")
+	}
+
+	parsed, err := phonenumbers.Parse(s, country)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse phone number")
+	}
+
+	if phonenumbers.IsPossibleNumberWithReason(parsed) != phonenumbers.IS_POSSIBLE {
+		return nil, errors.New("not a possible phone number")
+	}
+
+	return parsed, nil
+}
+
+// FormatPhone formats the given parsed phone number in the given format
+func FormatPhone(number *phonenumbers.PhoneNumber, format PhoneFormat) (string, error) {
+	switch format {
+	case PhoneFormatE164:
+		return phonenumbers.Format(number, phonenumbers.E164), nil
+	case PhoneFormatNational:
+		return phonenumbers.Format(number, phonenumbers.NATIONAL), nil
+	case PhoneFormatInternational:
+		return phonenumbers.Format(number, phonenumbers.INTERNATIONAL), nil
+	}
+	return "", errors.Errorf("%s is not a valid phone format", format)
+}
+
+// PhoneCountry returns the country code (e.g. RW) of the given parsed phone number, or empty if it can't be determined
+func PhoneCountry(number *phonenumbers.PhoneNumber) string {
+	country := phonenumbers.GetRegionCodeForNumber(number)
+	if country == "ZZ" {
+		return ""
+	}
+	return country
+}
+
+// IsValidPhone returns whether the given parsed phone number is a valid number in its country, which is stricter than
+// being a possible number as it checks the number against the number ranges in use
+func IsValidPhone(number *phonenumbers.PhoneNumber) bool {
+	return phonenumbers.IsValidNumber(number)
+}
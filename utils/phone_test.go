@@ -53,3 +53,52 @@ func TestFindPhoneNumbers(t *testing.T) {
 	assert.Equal(t, []string{"+12024561111"}, utils.FindPhoneNumbers("Hi my phone is +12024561111 thanks", ""))
 	assert.Equal(t, []string{}, utils.FindPhoneNumbers("Hi my phone is (202) 456-1111 thanks", ""))
 }
+
+func TestParsePhone(t *testing.T) {
+	tcs := []struct {
+		text       string
+		country    string
+		e164       string
+		national   string
+		numCountry string
+		valid      bool
+		err        string
+	}{
+		{text: "+250788383383", e164: "+250788383383", national: "0788 383 383", numCountry: "RW", valid: true},
+		{text: "tel:+250788383383", e164: "+250788383383", national: "0788 383 383", numCountry: "RW", valid: true},
+		{text: " 0788 383 383 ", country: "RW", e164: "+250788383383", national: "0788 383 383", numCountry: "RW", valid: true},
+		{text: "(202) 456-1111", country: "US", e164: "+12024561111", national: "(202) 456-1111", numCountry: "US", valid: true},
+		{text: "+250188383383", e164: "+250188383383", national: "188383383", numCountry: "RW", valid: false},
+		{text: "+12021234567", e164: "+12021234567", national: "(202) 123-4567", numCountry: "", valid: false},
+		{text: "0788383383", err: "unable to parse phone number: invalid country code"},
+		{text: "12345", country: "RW", err: "not a possible phone number"},
+		{text: "my number is +250788383383", err: "not a phone number"},
+		{text: "mailto:bob@nyaruka.com", err: "not a phone number"},
+	}
+
+	for _, tc := range tcs {
+		number, err := utils.ParsePhone(tc.text, tc.country)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, "error mismatch for %s", tc.text)
+			continue
+		}
+
+		assert.NoError(t, err, "unexpected error for %s", tc.text)
+
+		e164, _ := utils.FormatPhone(number, utils.PhoneFormatE164)
+		national, _ := utils.FormatPhone(number, utils.PhoneFormatNational)
+
+		assert.Equal(t, tc.e164, e164, "E164 mismatch for %s", tc.text)
+		assert.Equal(t, tc.national, national, "national mismatch for %s", tc.text)
+		assert.Equal(t, tc.numCountry, utils.PhoneCountry(number), "country mismatch for %s", tc.text)
+		assert.Equal(t, tc.valid, utils.IsValidPhone(number), "valid mismatch for %s", tc.text)
+	}
+
+	number, _ := utils.ParsePhone("+250788383383", "")
+	international, err := utils.FormatPhone(number, utils.PhoneFormatInternational)
+	assert.NoError(t, err)
+	assert.Equal(t, "+250 788 383 383", international)
+
+	_, err = utils.FormatPhone(number, "local")
+	assert.EqualError(t, err, "local is not a valid phone format")
+}
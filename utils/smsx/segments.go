@@ -0,0 +1,82 @@
+package smsx
+
+import (
+	"unicode/utf16"
+
+	"github.com/nyaruka/gocommon/gsm7"
+)
+
+// Encoding is the character encoding that a message will be sent with over SMS
+type Encoding string
+
+// possible encodings
+const (
+	EncodingGSM7 Encoding = "gsm7"
+	EncodingUCS2 Encoding = "ucs2"
+)
+
+// the maximum sizes of segments in septets for GSM7 and in UTF-16 code units for UCS2. Messages which don't fit in a
+// single segment are sent as multiple segments which each lose some space to a header.
+const (
+	gsm7SingleSize = 160
+	gsm7MultiSize  = 153
+	ucs2SingleSize = 70
+	ucs2MultiSize  = 67
+)
+
+// Segmentation describes how a message will be split into segments when sent over SMS
+type Segmentation struct {
+	Encoding Encoding `json:"encoding"`
+	Count    int      `json:"count"`
+	Segments []string `json:"segments"`
+}
+
+// IsGSM7 returns whether the given text can be sent entirely with the GSM7 encoding
+func IsGSM7(text string) bool {
+	return gsm7.IsValid(text)
+}
+
+// Segment works out the encoding of the given text and how it will be split into segments. Characters are never
+// split across segments, so extended GSM7 characters and UCS2 surrogate pairs are moved to the next segment if
+// they don't fit.
+func Segment(text string) *Segmentation {
+	encoding, size, singleSize, multiSize := EncodingUCS2, ucs2Size, ucs2SingleSize, ucs2MultiSize
+	if IsGSM7(text) {
+		encoding, size, singleSize, multiSize = EncodingGSM7, gsm7Size, gsm7SingleSize, gsm7MultiSize
+	}
+
+	total := 0
+	for _, r := range text {
+		total += size(r)
+	}
+	if total <= singleSize {
+		return &Segmentation{Encoding: encoding, Count: 1, Segments: []string{text}}
+	}
+
+	segments := make([]string, 0, total/multiSize+1)
+	start, used := 0, 0
+	for i, r := range text {
+		if s := size(r); used+s > multiSize {
+			segments = append(segments, text[start:i])
+			start, used = i, s
+		} else {
+			used += s
+		}
+	}
+	segments = append(segments, text[start:])
+
+	return &Segmentation{Encoding: encoding, Count: len(segments), Segments: segments}
+}
+
+// the number of septets used by the given character in GSM7, where extended characters are escaped
+func gsm7Size(r rune) int {
+	return len(gsm7.Encode(string(r)))
+}
+
+// the number of UTF-16 code units used by the given character in UCS2
+func ucs2Size(r rune) int {
+	if n := utf16.RuneLen(r); n > 0 {
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,48 @@
+package smsx_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nyaruka/goflow/utils/smsx"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegment(t *testing.T) {
+	assert.True(t, smsx.IsGSM7("Hello {world}"))
+	assert.False(t, smsx.IsGSM7("Hello 😁"))
+
+	tcs := []struct {
+		text     string
+		encoding smsx.Encoding
+		segments []string
+	}{
+		{"", smsx.EncodingGSM7, []string{""}},
+		{"Hello", smsx.EncodingGSM7, []string{"Hello"}},
+		{strings.Repeat("a", 160), smsx.EncodingGSM7, []string{strings.Repeat("a", 160)}},
+		{strings.Repeat("a", 161), smsx.EncodingGSM7, []string{strings.Repeat("a", 153), strings.Repeat("a", 8)}},
+
+		// extended characters take two septets and aren't split across segments
+		{strings.Repeat("{", 80), smsx.EncodingGSM7, []string{strings.Repeat("{", 80)}},
+		{strings.Repeat("{", 81), smsx.EncodingGSM7, []string{strings.Repeat("{", 76), strings.Repeat("{", 5)}},
+		{strings.Repeat("a", 152) + "{bbbbbbb", smsx.EncodingGSM7, []string{strings.Repeat("a", 152), "{bbbbbbb"}},
+
+		{"Hello ☺", smsx.EncodingUCS2, []string{"Hello ☺"}},
+		{strings.Repeat("☺", 70), smsx.EncodingUCS2, []string{strings.Repeat("☺", 70)}},
+		{strings.Repeat("☺", 71), smsx.EncodingUCS2, []string{strings.Repeat("☺", 67), strings.Repeat("☺", 4)}},
+
+		// characters outside the BMP take two code units and aren't split across segments
+		{strings.Repeat("😁", 35), smsx.EncodingUCS2, []string{strings.Repeat("😁", 35)}},
+		{"a" + strings.Repeat("😁", 35), smsx.EncodingUCS2, []string{"a" + strings.Repeat("😁", 33), strings.Repeat("😁", 2)}},
+	}
+
+	for _, tc := range tcs {
+		seg := smsx.Segment(tc.text)
+
+		assert.Equal(t, tc.encoding, seg.Encoding, "encoding mismatch for '%s'", tc.text)
+		assert.Equal(t, tc.segments, seg.Segments, "segments mismatch for '%s'", tc.text)
+		assert.Equal(t, len(tc.segments), seg.Count, "count mismatch for '%s'", tc.text)
+		assert.Equal(t, tc.text, strings.Join(seg.Segments, ""))
+	}
+}
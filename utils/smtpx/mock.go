@@ -38,7 +38,13 @@ func (s *MockSender) Send(c *Client, m *Message) error {
 		b.WriteString(fmt.Sprintf("RCPT TO:<%s>\n", r))
 	}
 	b.WriteString("DATA\n")
+	if m.replyTo != "" {
+		b.WriteString(fmt.Sprintf("Reply-To: %s\n", m.replyTo))
+	}
 	b.WriteString(fmt.Sprintf("%s\n", m.text))
+	for _, a := range m.attachments {
+		b.WriteString(fmt.Sprintf("[%s (%s, %d bytes)]\n", a.Filename, a.ContentType, len(a.Data)))
+	}
 	b.WriteString(".\n")
 	b.WriteString("QUIT\n")
 
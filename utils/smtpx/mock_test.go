@@ -31,6 +31,20 @@ func TestMockSender(t *testing.T) {
 		"HELO localhost\nMAIL FROM:<updates@temba.io>\nRCPT TO:<bob@nyaruka.com>\nRCPT TO:<jim@nyaruka.com>\nDATA\nHave a great weekend\n.\nQUIT\n",
 	}, sender.Logs())
 
+	// reply-to addresses and attachments are included
+	sender = smtpx.NewMockSender(nil)
+	smtpx.SetSender(sender)
+
+	msg3 := smtpx.NewMessage([]string{"bob@nyaruka.com"}, "Updates", "See attached", "").
+		WithReplyTo("support@temba.io").
+		WithAttachments([]*smtpx.Attachment{{Filename: "report.pdf", ContentType: "application/pdf", Data: []byte("%PDF")}})
+
+	err = smtpx.Send(c, msg3, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"HELO localhost\nMAIL FROM:<updates@temba.io>\nRCPT TO:<bob@nyaruka.com>\nDATA\nReply-To: support@temba.io\nSee attached\n[report.pdf (application/pdf, 4 bytes)]\n.\nQUIT\n",
+	}, sender.Logs())
+
 	// a sender which errors
 	sender = smtpx.NewMockSender(errors.New("oops can't send"), errors.New("421 Service not available, closing transmission channel"))
 	smtpx.SetSender(sender)
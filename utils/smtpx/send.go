@@ -4,12 +4,21 @@ import (
 	"time"
 )
 
+// Attachment is a file attached to a message
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
 // Message is email message
 type Message struct {
-	recipients []string
-	subject    string
-	text       string
-	html       string
+	recipients  []string
+	replyTo     string
+	subject     string
+	text        string
+	html        string
+	attachments []*Attachment
 }
 
 // NewMessage creates a new message
@@ -22,6 +31,18 @@ func NewMessage(recipients []string, subject, text, html string) *Message {
 	}
 }
 
+// WithReplyTo sets the address that replies to this message should be sent to
+func (m *Message) WithReplyTo(replyTo string) *Message {
+	m.replyTo = replyTo
+	return m
+}
+
+// WithAttachments sets the files attached to this message
+func (m *Message) WithAttachments(attachments []*Attachment) *Message {
+	m.attachments = attachments
+	return m
+}
+
 // Send an email using SMTP
 func Send(c *Client, m *Message, retries *RetryConfig) error {
 	var err error
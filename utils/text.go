@@ -3,6 +3,7 @@ package utils
 import (
 	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/blevesearch/segment"
 )
@@ -63,6 +64,38 @@ func PrefixOverlap(s1, s2 string) int {
 	return i
 }
 
+// EditDistance returns the Levenshtein distance between s1 and s2, i.e. the number of single character insertions,
+// deletions or substitutions needed to change one into the other
+func EditDistance(s1, s2 string) int {
+	r1 := []rune(s1)
+	r2 := []rune(s2)
+
+	prev := make([]int, len(r2)+1)
+	curr := make([]int, len(r2)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(r1); i++ {
+		curr[0] = i
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(r2)]
+}
+
 // StringSlices returns the slices of s defined by pairs of indexes in indices
 func StringSlices(s string, indices []int) []string {
 	slices := make([]string, 0, len(indices)/2)
@@ -96,3 +129,30 @@ func Indent(s string, prefix string) string {
 	}
 	return output.String()
 }
+
+// matches emoji symbols along with the modifiers, selectors and joiners used to build emoji sequences
+var emojiRegex = regexp.MustCompile(`[\p{So}\x{1F3FB}-\x{1F3FF}\x{FE0E}\x{FE0F}\x{200D}\x{20E3}]`)
+
+// StripEmojis removes any emojis from the given string
+func StripEmojis(s string) string {
+	return emojiRegex.ReplaceAllString(s, "")
+}
+
+// CollapseWhitespace replaces each run of whitespace characters in the given string with a single space
+func CollapseWhitespace(s string) string {
+	output := strings.Builder{}
+
+	space := false
+	for _, c := range s {
+		if unicode.IsSpace(c) {
+			if !space {
+				output.WriteRune(' ')
+			}
+			space = true
+		} else {
+			output.WriteRune(c)
+			space = false
+		}
+	}
+	return output.String()
+}
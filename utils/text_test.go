@@ -128,6 +128,16 @@ func TestPrefixOverlap(t *testing.T) {
 	assert.Equal(t, 4, utils.PrefixOverlap("25078", "25073254252"))
 }
 
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, utils.EditDistance("", ""))
+	assert.Equal(t, 3, utils.EditDistance("abc", ""))
+	assert.Equal(t, 3, utils.EditDistance("", "abc"))
+	assert.Equal(t, 0, utils.EditDistance("abc", "abc"))
+	assert.Equal(t, 1, utils.EditDistance("abc", "abd"))
+	assert.Equal(t, 3, utils.EditDistance("kitten", "sitting"))
+	assert.Equal(t, 1, utils.EditDistance("😄😟", "😄"))
+}
+
 func TestStringSlices(t *testing.T) {
 	assert.Equal(t, []string{"he", "hello", "world"}, utils.StringSlices("hello world", []int{0, 2, 0, 5, 6, 11}))
 }
@@ -148,3 +158,18 @@ func TestIndent(t *testing.T) {
 	assert.Equal(t, "  x\n\n  y", utils.Indent("x\n\ny", "  "))
 	assert.Equal(t, ">>>x", utils.Indent("x", ">>>"))
 }
+
+func TestStripEmojis(t *testing.T) {
+	assert.Equal(t, "", utils.StripEmojis(""))
+	assert.Equal(t, "hello", utils.StripEmojis("hello"))
+	assert.Equal(t, "yes ", utils.StripEmojis("yes 👍🏽"))
+	assert.Equal(t, "I  NY", utils.StripEmojis("I ❤️ NY"))
+	assert.Equal(t, "family: ", utils.StripEmojis("family: 👨‍👩‍👧"))
+	assert.Equal(t, "café ñ", utils.StripEmojis("café ñ"))
+}
+
+func TestCollapseWhitespace(t *testing.T) {
+	assert.Equal(t, "", utils.CollapseWhitespace(""))
+	assert.Equal(t, "a b", utils.CollapseWhitespace("a b"))
+	assert.Equal(t, " a b c ", utils.CollapseWhitespace("  a \t\n b  c  "))
+}
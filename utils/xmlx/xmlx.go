@@ -0,0 +1,136 @@
+package xmlx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/nyaruka/gocommon/jsonx"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html/charset"
+)
+
+// ToJSON converts the given XML document to JSON so that it can be used in the same places as a JSON document. The
+// result is an object with the root element as its only property. Elements with only text become strings, and other
+// elements become objects with a property for each child element, which is an array if that child is repeated.
+// Attributes become properties prefixed with @, and the text of an element with attributes or children is the #text
+// property. Namespace prefixes are dropped and whitespace around text is trimmed, e.g.
+//
+//	<soap:Envelope><soap:Body><Balance currency="USD">12.50</Balance></soap:Body></soap:Envelope>
+//
+// becomes
+//
+//	{"Envelope": {"Body": {"Balance": {"@currency": "USD", "#text": "12.50"}}}}
+func ToJSON(data []byte) ([]byte, error) {
+	root, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonx.Marshal(map[string]any{root.name: root.value()})
+}
+
+// Validate checks that the given data is a well-formed XML document
+func Validate(data []byte) error {
+	_, err := parse(data)
+	return err
+}
+
+type element struct {
+	name     string
+	attrs    []xml.Attr
+	children []*element
+	text     strings.Builder
+}
+
+func parse(data []byte) (*element, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	var root *element
+	var open []*element
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "unable to parse XML")
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			el := &element{name: t.Name.Local, attrs: t.Attr}
+
+			if len(open) > 0 {
+				parent := open[len(open)-1]
+				parent.children = append(parent.children, el)
+			} else if root == nil {
+				root = el
+			} else {
+				return nil, errors.New("unable to parse XML: document has more than one root element")
+			}
+
+			open = append(open, el)
+		case xml.EndElement:
+			open = open[:len(open)-1]
+		case xml.CharData:
+			if len(open) > 0 {
+				open[len(open)-1].text.Write(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("unable to parse XML: document has no root element")
+	}
+
+	return root, nil
+}
+
+func (e *element) value() any {
+	text := strings.TrimSpace(e.text.String())
+	attrs := e.attributes()
+
+	if len(attrs) == 0 && len(e.children) == 0 {
+		return text
+	}
+
+	obj := make(map[string]any, len(attrs)+len(e.children)+1)
+
+	for _, attr := range attrs {
+		obj["@"+attr.Name.Local] = attr.Value
+	}
+
+	for _, child := range e.children {
+		value := child.value()
+
+		switch existing := obj[child.name].(type) {
+		case nil:
+			obj[child.name] = value
+		case []any:
+			obj[child.name] = append(existing, value)
+		default:
+			obj[child.name] = []any{existing, value}
+		}
+	}
+
+	if text != "" {
+		obj["#text"] = text
+	}
+
+	return obj
+}
+
+// gets the attributes of this element, ignoring namespace declarations
+func (e *element) attributes() []xml.Attr {
+	attrs := make([]xml.Attr, 0, len(e.attrs))
+	for _, attr := range e.attrs {
+		if attr.Name.Space != "xmlns" && attr.Name.Local != "xmlns" {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,52 @@
+package xmlx_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/goflow/utils/xmlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToJSON(t *testing.T) {
+	tcs := []struct {
+		xml  string
+		json string
+		err  string
+	}{
+		{`<name>Bob</name>`, `{"name":"Bob"}`, ""},
+		{`<?xml version="1.0" encoding="UTF-8"?><empty/>`, `{"empty":""}`, ""},
+		{`<user id="123" active="true"><name>Bob</name></user>`, `{"user":{"@active":"true","@id":"123","name":"Bob"}}`, ""},
+		{`<price currency="USD"> 12.50 </price>`, `{"price":{"#text":"12.50","@currency":"USD"}}`, ""},
+		{
+			"<orders>\n  <order>1</order>\n  <order>2</order>\n  <order><id>3</id></order>\n  <total>3</total>\n</orders>",
+			`{"orders":{"order":["1","2",{"id":"3"}],"total":"3"}}`,
+			"",
+		},
+		{
+			`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns="http://temba.io/">
+				<soap:Body>
+					<GetBalanceResponse><Balance><![CDATA[<12.50>]]></Balance></GetBalanceResponse>
+				</soap:Body>
+			</soap:Envelope>`,
+			`{"Envelope":{"Body":{"GetBalanceResponse":{"Balance":"<12.50>"}}}}`,
+			"",
+		},
+		{`<note>caf&#233; &amp; bar</note>`, `{"note":"café & bar"}`, ""},
+		{"<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><note>caf\xe9</note>", `{"note":"café"}`, ""},
+		{``, "", "unable to parse XML: document has no root element"},
+		{`{"foo": "bar"}`, "", "unable to parse XML: document has no root element"},
+		{`<a>1</a><b>2</b>`, "", "unable to parse XML: document has more than one root element"},
+		{`<a><b>1</a>`, "", "unable to parse XML: XML syntax error on line 1: element <b> closed by </a>"},
+		{`<a>1`, "", "unable to parse XML: XML syntax error on line 1: unexpected EOF"},
+	}
+
+	for _, tc := range tcs {
+		actual, err := xmlx.ToJSON([]byte(tc.xml))
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, "error mismatch for %s", tc.xml)
+		} else {
+			assert.NoError(t, err, "unexpected error for %s", tc.xml)
+			assert.Equal(t, tc.json, string(actual), "JSON mismatch for %s", tc.xml)
+		}
+	}
+}
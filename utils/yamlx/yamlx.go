@@ -0,0 +1,280 @@
+package yamlx
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nyaruka/gocommon/jsonx"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// IsYAMLPath returns whether the given file path has a YAML extension
+func IsYAMLPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// ReadFile reads the given file, converting it to JSON if it has a YAML extension
+func ReadFile(path string) (json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if IsYAMLPath(path) {
+		return ToJSON(data)
+	}
+	return data, nil
+}
+
+// IsJSON returns whether the given data looks like a JSON object or array rather than YAML
+func IsJSON(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// ToJSON converts the given YAML document to JSON so that it can be read anywhere a JSON document can be. The order
+// of object properties is preserved, aliases are expanded and comments are dropped. Only string keys are supported.
+func ToJSON(data []byte) (json.RawMessage, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, errors.Wrap(err, "unable to parse YAML")
+	}
+	if len(doc.Content) == 0 {
+		return nil, errors.New("unable to parse YAML: document is empty")
+	}
+
+	b := &bytes.Buffer{}
+	if err := writeJSON(b, doc.Content[0]); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// FromJSON converts the given JSON document to YAML, preserving the order of object properties
+func FromJSON(data json.RawMessage) ([]byte, error) {
+	return Update(nil, data)
+}
+
+// Update converts the given JSON document to YAML like FromJSON, but keeps the comments and property order of the given
+// original YAML document where the values they describe still exist, so that changes to hand-written documents are
+// easy to review. New properties follow the existing ones. Items of sequences are matched by their uuid property if
+// they have one, and otherwise by their position.
+func Update(original []byte, data json.RawMessage) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	root, err := readJSON(dec)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read JSON")
+	}
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+
+	if len(original) > 0 {
+		old := &yaml.Node{}
+		if err := yaml.Unmarshal(original, old); err != nil {
+			return nil, errors.Wrap(err, "unable to parse original YAML")
+		}
+		merge(old, doc)
+	}
+
+	b := &bytes.Buffer{}
+	enc := yaml.NewEncoder(b)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// writes the given YAML node as JSON
+func writeJSON(b *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return writeJSON(b, node.Alias)
+
+	case yaml.MappingNode:
+		b.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Kind != yaml.ScalarNode {
+				return errors.Errorf("unable to convert YAML to JSON: line %d has a key which isn't a string", key.Line)
+			}
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.Write(jsonx.MustMarshal(key.Value))
+			b.WriteByte(':')
+			if err := writeJSON(b, value); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeJSON(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+
+	default:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		marshaled, err := jsonx.Marshal(value)
+		if err != nil {
+			return errors.Wrapf(err, "unable to convert YAML to JSON: line %d has a value which can't be JSON", node.Line)
+		}
+		b.Write(marshaled)
+	}
+	return nil
+}
+
+// reads the next JSON value from the given decoder as a YAML node
+func readJSON(dec *json.Decoder) (*yaml.Node, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch typed := token.(type) {
+	case json.Delim:
+		if typed == '{' {
+			node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := readJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, scalar("!!str", key.(string)), value)
+			}
+			_, err = dec.Token() // closing }
+			return node, err
+		}
+
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for dec.More() {
+			item, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, item)
+		}
+		_, err = dec.Token() // closing ]
+		return node, err
+
+	case string:
+		return scalar("!!str", typed), nil
+	case json.Number:
+		if strings.ContainsAny(string(typed), ".eE") {
+			return scalar("!!float", string(typed)), nil
+		}
+		return scalar("!!int", string(typed)), nil
+	case bool:
+		if typed {
+			return scalar("!!bool", "true"), nil
+		}
+		return scalar("!!bool", "false"), nil
+	default:
+		return scalar("!!null", "null"), nil
+	}
+}
+
+func scalar(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+// copies the comments and property order of the given old node and its descendants to the matching parts of the given
+// new node
+func merge(old, new *yaml.Node) {
+	new.HeadComment = old.HeadComment
+	new.LineComment = old.LineComment
+	new.FootComment = old.FootComment
+
+	if old.Kind != new.Kind {
+		return
+	}
+
+	switch old.Kind {
+	case yaml.DocumentNode:
+		if len(old.Content) > 0 && len(new.Content) > 0 {
+			merge(old.Content[0], new.Content[0])
+		}
+
+	case yaml.MappingNode:
+		existing := make([]*yaml.Node, 0, len(new.Content))
+		added := make([]*yaml.Node, 0)
+
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			if key, value := mappingValue(new, old.Content[i].Value); key != nil {
+				merge(old.Content[i], key)
+				merge(old.Content[i+1], value)
+				existing = append(existing, key, value)
+			}
+		}
+		for i := 0; i+1 < len(new.Content); i += 2 {
+			if key, _ := mappingValue(old, new.Content[i].Value); key == nil {
+				added = append(added, new.Content[i], new.Content[i+1])
+			}
+		}
+		new.Content = append(existing, added...)
+
+	case yaml.SequenceNode:
+		byUUID := make(map[string]*yaml.Node)
+		for _, item := range old.Content {
+			if uuid := itemUUID(item); uuid != "" {
+				byUUID[uuid] = item
+			}
+		}
+
+		for i, item := range new.Content {
+			if uuid := itemUUID(item); uuid != "" {
+				if oldItem := byUUID[uuid]; oldItem != nil {
+					merge(oldItem, item)
+				}
+			} else if i < len(old.Content) && itemUUID(old.Content[i]) == "" {
+				merge(old.Content[i], item)
+			}
+		}
+	}
+}
+
+// gets the key and value nodes of the given property of a mapping node
+func mappingValue(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// gets the value of the uuid property of a mapping node
+func itemUUID(node *yaml.Node) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	if _, value := mappingValue(node, "uuid"); value != nil && value.Kind == yaml.ScalarNode {
+		return value.Value
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package yamlx_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nyaruka/goflow/utils/yamlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToJSON(t *testing.T) {
+	tcs := []struct {
+		yaml string
+		json string
+		err  string
+	}{
+		{"name: Bob", `{"name":"Bob"}`, ""},
+		{"zed: 1\nalpha: 2.5\nmid: true\nnone: null", `{"zed":1,"alpha":2.5,"mid":true,"none":null}`, ""},
+		{"# a comment\nitems:\n  - one\n  - '2'\n  - 3", `{"items":["one","2",3]}`, ""},
+		{"base: &base\n  a: 1\ncopy: *base", `{"base":{"a":1},"copy":{"a":1}}`, ""},
+		{"text: |\n  line 1\n  line 2\n", `{"text":"line 1\nline 2\n"}`, ""},
+		{"[1, 2]", `[1,2]`, ""},
+		{"", "", "unable to parse YAML: document is empty"},
+		{"a: [", "", "unable to parse YAML: yaml: line 1: did not find expected node content"},
+		{"? [1, 2]\n: x", "", "unable to convert YAML to JSON: line 1 has a key which isn't a string"},
+	}
+
+	for _, tc := range tcs {
+		actual, err := yamlx.ToJSON([]byte(tc.yaml))
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, "error mismatch for %s", tc.yaml)
+		} else {
+			assert.NoError(t, err, "unexpected error for %s", tc.yaml)
+			assert.Equal(t, tc.json, string(actual), "JSON mismatch for %s", tc.yaml)
+		}
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	actual, err := yamlx.FromJSON([]byte(`{"zed": "true", "alpha": [1, 2.5, {"b": null, "a": false}], "text": "line 1\nline 2", "empty": {}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "zed: \"true\"\nalpha:\n  - 1\n  - 2.5\n  - b: null\n    a: false\ntext: |-\n  line 1\n  line 2\nempty: {}\n", string(actual))
+
+	// and it round trips back to the same JSON
+	converted, err := yamlx.ToJSON(actual)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"zed":"true","alpha":[1,2.5,{"b":null,"a":false}],"text":"line 1\nline 2","empty":{}}`, string(converted))
+
+	_, err = yamlx.FromJSON([]byte(`{"zed": `))
+	assert.EqualError(t, err, "unable to read JSON: EOF")
+}
+
+func TestUpdate(t *testing.T) {
+	original := []byte(`# the main flow
+name: Registration # shown to users
+nodes:
+  # asks for the name
+  - uuid: 8f2c1e69
+    text: What is your name?
+  # says goodbye
+  - uuid: 2b8bb5e2
+    text: Bye
+tags:
+  - one # first
+`)
+
+	// nodes are matched by UUID so their comments follow them when they're reordered or removed
+	updated, err := yamlx.Update(original, []byte(`{"name": "Signup", "nodes": [{"uuid": "2b8bb5e2", "text": "Goodbye"}, {"uuid": "a1b2c3d4", "text": "New"}], "tags": ["uno", "dos"]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `# the main flow
+name: Signup # shown to users
+nodes:
+  # says goodbye
+  - uuid: 2b8bb5e2
+    text: Goodbye
+  - uuid: a1b2c3d4
+    text: New
+tags:
+  - uno # first
+  - dos
+`, string(updated))
+
+	_, err = yamlx.Update([]byte("a: ["), []byte(`{}`))
+	assert.EqualError(t, err, "unable to parse original YAML: yaml: line 1: did not find expected node content")
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "flow.yml")
+	jsonPath := filepath.Join(dir, "flow.json")
+
+	require.NoError(t, os.WriteFile(yamlPath, []byte("name: Bob"), 0644))
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"name": "Bob"}`), 0644))
+
+	data, err := yamlx.ReadFile(yamlPath)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Bob"}`, string(data))
+
+	data, err = yamlx.ReadFile(jsonPath)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name": "Bob"}`, string(data))
+
+	_, err = yamlx.ReadFile(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+
+	assert.True(t, yamlx.IsYAMLPath("flows/main.YAML"))
+	assert.False(t, yamlx.IsYAMLPath("flows/main.json"))
+	assert.True(t, yamlx.IsJSON([]byte("  [1]")))
+	assert.False(t, yamlx.IsJSON([]byte("a: 1")))
+}